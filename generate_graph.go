@@ -20,7 +20,7 @@ func main() {
 		{Amount: decimal.NewFromFloat(120.00), Category: &models.Category{Name: "Utilities"}},
 	}
 
-	chartData, err := bot.GenerateExpenseChart(expenses, "January 2026")
+	chartData, err := bot.GenerateExpenseChart(expenses, "January 2026", nil)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
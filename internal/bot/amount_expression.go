@@ -0,0 +1,194 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+)
+
+// maxAmountExpressionLen bounds the length of an amount expression such as
+// "84.60/3" so the evaluator never sees more than a short calculation.
+const maxAmountExpressionLen = 30
+
+// maxExpenseAmount is the largest amount the amounts column can hold.
+var maxExpenseAmount = decimal.RequireFromString("9999999999.99")
+
+var (
+	errExpressionTooLong   = fmt.Errorf("expression is longer than %d characters", maxAmountExpressionLen)
+	errExpressionMalformed = errors.New("expression is not valid arithmetic")
+	errExpressionDivByZero = errors.New("expression divides by zero")
+	errAmountTooLarge      = errors.New("amount is too large")
+)
+
+// amountExpressionRegex matches a leading amount expression: numbers, each
+// optionally wrapped in parentheses, joined by at least one operator.
+var amountExpressionRegex = regexp.MustCompile(`^\(*\d[\d.,]*\)*(?:[+\-*/]\(*\d[\d.,]*\)*)+`)
+
+// dateShapeRegex matches tokens that read as a date or a range rather than
+// arithmetic: "2024/03/05", "2024-03-05" or "1-2".
+var dateShapeRegex = regexp.MustCompile(`^(?:\d+(?:-\d+)+|\d+/\d+/\d+)$`)
+
+// isAmountExpression reports whether token is arithmetic such as "84.60/3",
+// as opposed to a plain number like "5.50" or a date like "2024/03/05".
+func isAmountExpression(token string) bool {
+	return token != "" && amountExpressionRegex.FindString(token) == token && !dateShapeRegex.MatchString(token)
+}
+
+// amountExpressionError returns the user-facing message for an expression
+// evaluation error.
+func amountExpressionError(expr string, err error) string {
+	var reason string
+	switch {
+	case errors.Is(err, errExpressionDivByZero):
+		reason = "it divides by zero"
+	case errors.Is(err, errInvalidAmount):
+		reason = "the result must be greater than zero"
+	case errors.Is(err, errAmountTooLarge):
+		reason = "the result is too large"
+	case errors.Is(err, errExpressionTooLong):
+		reason = fmt.Sprintf("it is longer than %d characters", maxAmountExpressionLen)
+	default:
+		reason = "only numbers, + - * / and parentheses are allowed"
+	}
+	return fmt.Sprintf("❌ Can't use <code>%s</code> as an amount: %s.", botfmt.EscapeHTML(echoText(expr)), reason)
+}
+
+// evaluateAmountExpression evaluates a restricted arithmetic expression made
+// of numbers, + - * / and parentheses using decimal arithmetic. The result
+// is rounded to two decimal places and must be positive and within
+// maxExpenseAmount.
+func evaluateAmountExpression(expr string) (decimal.Decimal, error) {
+	if len(expr) > maxAmountExpressionLen {
+		return decimal.Zero, errExpressionTooLong
+	}
+
+	p := &expressionParser{input: strings.ReplaceAll(expr, ",", ".")}
+	result, err := p.parseSum()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if p.pos != len(p.input) {
+		return decimal.Zero, errExpressionMalformed
+	}
+
+	result = result.Round(2)
+	if result.LessThanOrEqual(decimal.Zero) {
+		return decimal.Zero, errInvalidAmount
+	}
+	if result.GreaterThan(maxExpenseAmount) {
+		return decimal.Zero, errAmountTooLarge
+	}
+	return result, nil
+}
+
+// expressionParser is a recursive descent parser over the grammar:
+//
+//	sum     = product { ("+" | "-") product }
+//	product = unary { ("*" | "/") unary }
+//	unary   = [ "-" | "+" ] primary
+//	primary = number | "(" sum ")"
+type expressionParser struct {
+	input string
+	pos   int
+}
+
+func (p *expressionParser) peek() byte {
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *expressionParser) parseSum() (decimal.Decimal, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return decimal.Zero, err
+		}
+		if op == '+' {
+			left = left.Add(right)
+		} else {
+			left = left.Sub(right)
+		}
+	}
+}
+
+func (p *expressionParser) parseProduct() (decimal.Decimal, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return decimal.Zero, err
+		}
+		if op == '*' {
+			left = left.Mul(right)
+			continue
+		}
+		if right.IsZero() {
+			return decimal.Zero, errExpressionDivByZero
+		}
+		left = left.Div(right)
+	}
+}
+
+func (p *expressionParser) parseUnary() (decimal.Decimal, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		value, err := p.parsePrimary()
+		return value.Neg(), err
+	case '+':
+		p.pos++
+	}
+	return p.parsePrimary()
+}
+
+func (p *expressionParser) parsePrimary() (decimal.Decimal, error) {
+	if p.peek() == '(' {
+		p.pos++
+		value, err := p.parseSum()
+		if err != nil {
+			return decimal.Zero, err
+		}
+		if p.peek() != ')' {
+			return decimal.Zero, errExpressionMalformed
+		}
+		p.pos++
+		return value, nil
+	}
+
+	start := p.pos
+	for c := p.peek(); (c >= '0' && c <= '9') || c == '.'; c = p.peek() {
+		p.pos++
+	}
+	if start == p.pos {
+		return decimal.Zero, errExpressionMalformed
+	}
+
+	value, err := decimal.NewFromString(p.input[start:p.pos])
+	if err != nil {
+		return decimal.Zero, errExpressionMalformed
+	}
+	return value, nil
+}
@@ -0,0 +1,193 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	dinnerExpressionTest = "84.60/3"
+	dinnerShareTest      = "28.20"
+	snacksExpressionTest = "12+4.5"
+	snacksTotalTest      = "16.50"
+)
+
+func TestEvaluateAmountExpression(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{name: "division", input: dinnerExpressionTest, want: dinnerShareTest},
+		{name: "addition", input: snacksExpressionTest, want: snacksTotalTest},
+		{name: "subtraction", input: "20-7.25", want: "12.75"},
+		{name: "multiplication", input: "3*4.10", want: "12.30"},
+		{name: "comma decimal", input: "5,50*2", want: "11.00"},
+		{name: "multiplication before addition", input: "2+3*4", want: "14.00"},
+		{name: "division before subtraction", input: "10-6/3", want: "8.00"},
+		{name: "left associative subtraction", input: "10-3-2", want: "5.00"},
+		{name: "left associative division", input: "100/5/2", want: "10.00"},
+		{name: "parentheses override precedence", input: "(2+3)*4", want: "20.00"},
+		{name: "nested parentheses", input: "((1+2)*(3+4))/7", want: "3.00"},
+		{name: "unary minus inside", input: "10*(-1+2)", want: "10.00"},
+		{name: "repeating division rounds", input: "10/3", want: "3.33"},
+		{name: "rounds half up", input: "0.125*1", want: "0.13"},
+		{name: "plain number", input: "42", want: "42.00"},
+		{name: "division by zero", input: "5/0", wantErr: errExpressionDivByZero},
+		{name: "division by zero expression", input: "5/(2-2)", wantErr: errExpressionDivByZero},
+		{name: "zero result", input: "5-5", wantErr: errInvalidAmount},
+		{name: "negative result", input: "5-10", wantErr: errInvalidAmount},
+		{name: "rounds to zero", input: "0.001*1", wantErr: errInvalidAmount},
+		{name: "above cap", input: "99999999*999", wantErr: errAmountTooLarge},
+		{name: "too long", input: strings.Repeat("1+", 15) + "1", wantErr: errExpressionTooLong},
+		{name: "trailing operator", input: "5+", wantErr: errExpressionMalformed},
+		{name: "double operator", input: "5*/2", wantErr: errExpressionMalformed},
+		{name: "unbalanced open", input: "(5+2", wantErr: errExpressionMalformed},
+		{name: "unbalanced close", input: "5+2)", wantErr: errExpressionMalformed},
+		{name: "empty parentheses", input: "()", wantErr: errExpressionMalformed},
+		{name: "double decimal point", input: "1.2.3+1", wantErr: errExpressionMalformed},
+		{name: "variable", input: "x+1", wantErr: errExpressionMalformed},
+		{name: "exponent notation", input: "1e5+1", wantErr: errExpressionMalformed},
+		{name: "empty", input: "", wantErr: errExpressionMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := evaluateAmountExpression(tt.input)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.StringFixed(2))
+		})
+	}
+}
+
+func TestIsAmountExpression(t *testing.T) {
+	t.Parallel()
+
+	for _, token := range []string{dinnerExpressionTest, snacksExpressionTest, "(10+5)*2", "10/4", "5.50-10", "5/(2-2)"} {
+		require.True(t, isAmountExpression(token), token)
+	}
+	for _, token := range []string{"", "5.50", "(5)", "5+", "+5", "1-2", "2024/03/05", "2024-03-05", "05/03/2024"} {
+		require.False(t, isAmountExpression(token), token)
+	}
+
+	for _, input := range []string{"1-2 Coffee", "2024/03/05 lunch"} {
+		result := ParseExpenseInput(input)
+		require.NotNil(t, result, input)
+		require.Empty(t, result.AmountExpression, input)
+		require.NoError(t, result.AmountError, input)
+	}
+}
+
+func TestParseExpenseInputAmountExpression(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		input      string
+		wantAmount string
+		wantExpr   string
+		wantDesc   string
+		wantCur    string
+		wantErr    error
+	}{
+		{
+			name:       "share of dinner",
+			input:      dinnerExpressionTest + " my share of dinner",
+			wantAmount: dinnerShareTest,
+			wantExpr:   dinnerExpressionTest,
+			wantDesc:   "my share of dinner",
+		},
+		{
+			name:       "currency prefix",
+			input:      "S$" + snacksExpressionTest + " snacks",
+			wantAmount: snacksTotalTest,
+			wantExpr:   snacksExpressionTest,
+			wantDesc:   "snacks",
+			wantCur:    currencyCodeSGD,
+		},
+		{
+			name:       "currency code after expression",
+			input:      "(10+5)*2 SGD taxi",
+			wantAmount: "30.00",
+			wantExpr:   "(10+5)*2",
+			wantDesc:   "taxi",
+			wantCur:    currencyCodeSGD,
+		},
+		{
+			name:       "description keeps trailing numbers",
+			input:      "6/2 coffee for 2",
+			wantAmount: "3.00",
+			wantExpr:   "6/2",
+			wantDesc:   "coffee for 2",
+		},
+		{
+			name:       "plain amount has no expression",
+			input:      "5.50 Coffee",
+			wantAmount: "5.50",
+			wantDesc:   "Coffee",
+		},
+		{
+			name:     "division by zero is reported",
+			input:    "10/0 lunch",
+			wantExpr: "10/0",
+			wantErr:  errExpressionDivByZero,
+		},
+		{
+			name:     "non-positive result is reported",
+			input:    "5.50-10 refund",
+			wantExpr: "5.50-10",
+			wantErr:  errInvalidAmount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			result := ParseExpenseInput(tt.input)
+			require.NotNil(t, result)
+			require.Equal(t, tt.wantExpr, result.AmountExpression)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, result.AmountError, tt.wantErr)
+				require.Empty(t, result.Description)
+				return
+			}
+			require.NoError(t, result.AmountError)
+			require.Equal(t, tt.wantAmount, result.Amount.StringFixed(2))
+			require.Equal(t, tt.wantDesc, result.Description)
+			require.Equal(t, tt.wantCur, result.Currency)
+		})
+	}
+}
+
+func TestParseAddCommandAmountExpression(t *testing.T) {
+	t.Parallel()
+
+	result := ParseAddCommandWithCategories("/add "+snacksExpressionTest+" snacks [Food]", []string{"Food"})
+	require.NotNil(t, result)
+	require.Equal(t, snacksTotalTest, result.Amount.StringFixed(2))
+	require.Equal(t, snacksExpressionTest, result.AmountExpression)
+	require.Equal(t, "snacks", result.Description)
+	require.Equal(t, "Food", result.CategoryName)
+}
+
+func TestAmountExpressionError(t *testing.T) {
+	t.Parallel()
+
+	require.Contains(t, amountExpressionError("5/0", errExpressionDivByZero), "divides by zero")
+	require.Contains(t, amountExpressionError("5-5", errInvalidAmount), "greater than zero")
+	require.Contains(t, amountExpressionError("<1>+", errExpressionMalformed), "&lt;1&gt;+")
+
+	long := amountExpressionError(strings.Repeat("1+", 2000), errExpressionTooLong)
+	require.Less(t, len(long), 300, "the expression is cut before it is echoed")
+	require.Contains(t, long, "…</code>")
+}
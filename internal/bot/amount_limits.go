@@ -0,0 +1,35 @@
+package bot
+
+import (
+	"fmt"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+)
+
+// aboveAmountHardCap reports whether amount exceeds the hard cap.
+func (b *Bot) aboveAmountHardCap(amount decimal.Decimal) bool {
+	return b.expenses().AboveHardCap(amount)
+}
+
+// amountAboveHardCapMsg explains why an amount was rejected outright. The
+// cap is compared with the amount as saved, so it is shown in that
+// amount's currency.
+func (b *Bot) amountAboveHardCapMsg(amount decimal.Decimal, currency string) string {
+	return fmt.Sprintf("❌ %s is above the maximum of %s per expense. The expense was not saved.",
+		botfmt.Money(amount, currency), botfmt.Money(b.expenses().HardCap(), currency))
+}
+
+// buildLargeAmountKeyboard creates the keyboard for confirming a large
+// amount. It reuses the draft confirm and cancel callbacks.
+func buildLargeAmountKeyboard(expenseID int) *models.InlineKeyboardMarkup {
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{Text: "✅ Save anyway", CallbackData: fmt.Sprintf("receipt_confirm_%d", expenseID)},
+				{Text: "❌ Cancel", CallbackData: fmt.Sprintf("receipt_cancel_%d", expenseID)},
+			},
+		},
+	}
+}
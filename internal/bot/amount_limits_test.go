@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const largeAmountPromptTest = "save anyway?"
+
+func TestAboveAmountHardCap(t *testing.T) {
+	t.Parallel()
+
+	require.False(t, (&Bot{}).aboveAmountHardCap(decimal.NewFromInt(5_000_000)), "no config means no cap")
+
+	b := &Bot{cfg: &config.Config{AmountHardCap: decimal.NewFromInt(1000)}}
+	require.False(t, b.aboveAmountHardCap(decimal.NewFromInt(1000)))
+	require.True(t, b.aboveAmountHardCap(decimal.RequireFromString("1000.01")))
+}
+
+func TestAmountAboveHardCapMsg(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{cfg: &config.Config{AmountHardCap: decimal.NewFromInt(1000)}}
+	require.Equal(t,
+		"❌ ¥2000 JPY is above the maximum of ¥1000 JPY per expense. The expense was not saved.",
+		b.amountAboveHardCapMsg(decimal.NewFromInt(2000), "JPY"))
+}
+
+func TestBuildLargeAmountKeyboard(t *testing.T) {
+	t.Parallel()
+
+	keyboard := buildLargeAmountKeyboard(42)
+	require.Len(t, keyboard.InlineKeyboard, 1)
+	require.Equal(t, "receipt_confirm_42", keyboard.InlineKeyboard[0][0].CallbackData)
+	require.Equal(t, "receipt_cancel_42", keyboard.InlineKeyboard[0][1].CallbackData)
+}
+
+func TestAmountLimits(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.cfg.AmountSoftLimit = decimal.NewFromInt(1000)
+	b.cfg.AmountHardCap = decimal.NewFromInt(1_000_000)
+
+	userID := int64(372001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Limit"}))
+
+	draftCount := func(t *testing.T) int {
+		t.Helper()
+		var count int
+		err := pool.QueryRow(ctx,
+			`SELECT COUNT(*) FROM expenses WHERE user_id = $1 AND status = 'draft'`, userID).Scan(&count)
+		require.NoError(t, err)
+		return count
+	}
+	confirmedCount := func(t *testing.T) int {
+		t.Helper()
+		expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 100)
+		require.NoError(t, err)
+		return len(expenses)
+	}
+	promptExpenseID := func(t *testing.T, mockBot *mocks.MockBot) int {
+		t.Helper()
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, largeAmountPromptTest)
+		keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+		var id int
+		_, err := fmt.Sscanf(keyboard.InlineKeyboard[0][0].CallbackData, "receipt_confirm_%d", &id)
+		require.NoError(t, err)
+		return id
+	}
+
+	t.Run("below threshold saves immediately", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleAddCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/add 999 Laptop stand"))
+
+		require.Contains(t, mockBot.LastSentMessage().Text, "Expense Added")
+		require.Equal(t, 1, confirmedCount(t))
+		require.Equal(t, 0, draftCount(t))
+	})
+
+	t.Run("above threshold asks and saves on confirm", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleAddCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/add 5500 Coffee"))
+
+		require.Contains(t, mockBot.LastSentMessage().Text, "That's S$5500.00 SGD")
+		id := promptExpenseID(t, mockBot)
+		require.Equal(t, 1, draftCount(t))
+		require.Equal(t, 1, confirmedCount(t))
+
+		b.handleReceiptCallbackCore(ctx, mockBot,
+			mocks.CallbackQueryUpdate(userID, userID, 1, fmt.Sprintf("receipt_confirm_%d", id)))
+
+		require.Equal(t, 0, draftCount(t))
+		require.Equal(t, 2, confirmedCount(t))
+	})
+
+	t.Run("above threshold discarded on cancel", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleAddCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/add 5500 Coffee"))
+		id := promptExpenseID(t, mockBot)
+
+		b.handleReceiptCallbackCore(ctx, mockBot,
+			mocks.CallbackQueryUpdate(userID, userID, 1, fmt.Sprintf("receipt_cancel_%d", id)))
+
+		require.Equal(t, 0, draftCount(t))
+		require.Equal(t, 2, confirmedCount(t))
+	})
+
+	t.Run("user override raises the threshold", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleSettingsCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/settings maxamount 6000"))
+		require.Contains(t, mockBot.LastSentMessage().Text, "above 6000.00")
+
+		b.handleAddCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/add 5500 Coffee"))
+		require.Contains(t, mockBot.LastSentMessage().Text, "Expense Added")
+		require.Equal(t, 3, confirmedCount(t))
+
+		b.handleSettingsCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/settings maxamount default"))
+		b.handleAddCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/add 5500 Coffee"))
+		promptExpenseID(t, mockBot)
+		require.Equal(t, 1, draftCount(t))
+	})
+
+	t.Run("above hard cap is rejected", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleAddCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/add 2000000 Yacht"))
+
+		require.Contains(t, mockBot.LastSentMessage().Text, "above the maximum of S$1000000.00 SGD")
+		require.Nil(t, mockBot.LastSentMessage().ReplyMarkup)
+		require.Equal(t, 1, draftCount(t))
+		require.Equal(t, 3, confirmedCount(t))
+	})
+}
@@ -0,0 +1,516 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
+)
+
+const (
+	apiExpensesPath       = "/api/v1/expenses"
+	apiHealthPath         = "/healthz"
+	apiMaxBodyBytes       = 16 << 10
+	apiRateLimit          = 30
+	apiRateWindow         = time.Minute
+	apiRateLimiterMaxKeys = 1024
+	// apiFailedAuthLimit caps the requests with a missing or unknown token
+	// a client address may make per apiRateWindow.
+	apiFailedAuthLimit = 10
+	// apiDuplicateWindow is how long a create without an Idempotency-Key
+	// that repeats the user's last API expense returns that expense
+	// instead of saving another, e.g. after a double tap in a Shortcut.
+	apiDuplicateWindow = time.Minute
+	// apiIdempotencyKeyHeader names the optional header that makes a create
+	// safe to retry; apiMaxIdempotencyKeyLen caps its length.
+	apiIdempotencyKeyHeader = "Idempotency-Key"
+	apiMaxIdempotencyKeyLen = 255
+
+	httpReadHeaderTimeout = 5 * time.Second
+	httpReadTimeout       = 10 * time.Second
+	httpWriteTimeout      = 30 * time.Second
+	httpShutdownTimeout   = 5 * time.Second
+)
+
+// apiAmount accepts an amount sent as a JSON number (12.5) or string
+// ("12.50", "84.60/3").
+type apiAmount string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *apiAmount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("invalid amount: %w", err)
+		}
+		*a = apiAmount(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid amount: %w", err)
+	}
+	*a = apiAmount(n)
+	return nil
+}
+
+// apiExpenseRequest is the body of POST /api/v1/expenses.
+type apiExpenseRequest struct {
+	Amount      apiAmount `json:"amount"`
+	Currency    string    `json:"currency"`
+	Description string    `json:"description"`
+	Category    string    `json:"category"`
+	Tags        []string  `json:"tags"`
+}
+
+// apiExpenseResponse is the created expense returned by the API.
+type apiExpenseResponse struct {
+	ID          int       `json:"id"`
+	Number      int64     `json:"number"`
+	Amount      string    `json:"amount"`
+	Currency    string    `json:"currency"`
+	Description string    `json:"description"`
+	Category    string    `json:"category,omitempty"`
+	Tags        []string  `json:"tags"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// apiErrorResponse is the body of every API error.
+type apiErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// apiRateLimiter is a fixed-window request limiter. The API keeps one keyed
+// by user, counted after the token is validated, and one keyed by client
+// address counting failed authentications, so made-up tokens cannot buy
+// fresh buckets.
+type apiRateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	buckets map[string]apiRateBucket
+}
+
+type apiRateBucket struct {
+	start time.Time
+	count int
+}
+
+func newAPIRateLimiter(limit int, window time.Duration) *apiRateLimiter {
+	return &apiRateLimiter{
+		limit:   limit,
+		window:  window,
+		buckets: make(map[string]apiRateBucket),
+	}
+}
+
+// allow records a request for key and reports whether it is within the
+// limit. When the limiter is full of keys still in their window, a new key
+// is refused rather than evicting one that is counting.
+func (l *apiRateLimiter) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.buckets[key]
+	if !ok && len(l.buckets) >= apiRateLimiterMaxKeys {
+		for k, bucket := range l.buckets {
+			if now.Sub(bucket.start) >= l.window {
+				delete(l.buckets, k)
+			}
+		}
+		if len(l.buckets) >= apiRateLimiterMaxKeys {
+			return false
+		}
+	}
+
+	if now.Sub(w.start) >= l.window {
+		w = apiRateBucket{start: now}
+	}
+	w.count++
+	l.buckets[key] = w
+	return w.count <= l.limit
+}
+
+// exceeded reports whether key used up its limit in the current window,
+// without recording a request.
+func (l *apiRateLimiter) exceeded(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.buckets[key]
+	return ok && now.Sub(w.start) < l.window && w.count >= l.limit
+}
+
+// apiLimiters are the rate limiters of the REST API.
+type apiLimiters struct {
+	users      *apiRateLimiter
+	failedAuth *apiRateLimiter
+}
+
+// apiHandler returns the HTTP handler serving the health check and the REST
+// API.
+func (b *Bot) apiHandler() http.Handler {
+	limiter := &apiLimiters{
+		users:      newAPIRateLimiter(apiRateLimit, apiRateWindow),
+		failedAuth: newAPIRateLimiter(apiFailedAuthLimit, apiRateWindow),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+apiHealthPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("POST "+apiExpensesPath, func(w http.ResponseWriter, r *http.Request) {
+		b.handleAPICreateExpense(w, r, limiter)
+	})
+	return mux
+}
+
+// serveHTTP runs the HTTP server until ctx is cancelled.
+func (b *Bot) serveHTTP(ctx context.Context, addr string) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           b.apiHandler(),
+		ReadHeaderTimeout: httpReadHeaderTimeout,
+		ReadTimeout:       httpReadTimeout,
+		WriteTimeout:      httpWriteTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), httpShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to shut down HTTP server")
+		}
+	}()
+
+	logger.Log.Info().Str("addr", addr).Msg("HTTP server listening")
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Log.Error().Err(err).Msg("HTTP server failed")
+	}
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// apiClientAddr is the address failed authentications are counted
+// against. Behind a reverse proxy every connection comes from the proxy, so
+// when HTTPTrustedProxyHeader is set the client address is the last entry
+// of that header, the one the proxy added; clients can forge earlier ones.
+// Otherwise, or when the header holds no valid address, it is the remote
+// host without its port.
+func (b *Bot) apiClientAddr(r *http.Request) string {
+	if b.cfg != nil && b.cfg.HTTPTrustedProxyHeader != "" {
+		values := r.Header.Values(b.cfg.HTTPTrustedProxyHeader)
+		if len(values) > 0 {
+			entries := strings.Split(values[len(values)-1], ",")
+			if ip := net.ParseIP(strings.TrimSpace(entries[len(entries)-1])); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// writeAPIRateLimited writes the response for a request over a limit.
+func writeAPIRateLimited(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", fmt.Sprintf("%.0f", apiRateWindow.Seconds()))
+	writeAPIError(w, http.StatusTooManyRequests, "rate limit exceeded")
+}
+
+// writeAPIJSON writes v as a JSON response with the given status.
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to write API response")
+	}
+}
+
+// writeAPIError writes an error response.
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	writeAPIJSON(w, status, apiErrorResponse{Error: msg})
+}
+
+// authenticateAPIRequest resolves the user owning the request's bearer
+// token. It writes the error response and returns nil when the request is
+// not allowed. Failed authentications are limited per client address and
+// valid requests per user.
+func (b *Bot) authenticateAPIRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+	limiter *apiLimiters,
+) *appmodels.User {
+	addr := b.apiClientAddr(r)
+	if limiter.failedAuth.exceeded(addr, b.now()) {
+		writeAPIRateLimited(w)
+		return nil
+	}
+	unauthorized := func(msg string) {
+		limiter.failedAuth.allow(addr, b.now())
+		writeAPIError(w, http.StatusUnauthorized, msg)
+	}
+
+	token := bearerToken(r)
+	if token == "" {
+		unauthorized("missing bearer token")
+		return nil
+	}
+
+	ctx := r.Context()
+	user, err := b.userRepo.GetUserByAPITokenHash(ctx, hashAPIToken(token))
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Log.Error().Err(err).Msg("Failed to look up API token")
+			writeAPIError(w, http.StatusInternalServerError, "internal error")
+			return nil
+		}
+		unauthorized("invalid token")
+		return nil
+	}
+
+	if !limiter.users.allow(strconv.FormatInt(user.ID, 10), b.now()) {
+		writeAPIRateLimited(w)
+		return nil
+	}
+
+	if !b.isAuthorized(ctx, user.ID, user.Username) {
+		writeAPIError(w, http.StatusUnauthorized, "user is not authorized")
+		return nil
+	}
+	return user
+}
+
+// validateAPIExpense turns a request into a ParsedExpense. The returned
+// message describes the first validation failure. Descriptions are
+// normalized like chat ones, but one too long for MAX_DESCRIPTION_LENGTH
+// is refused rather than shortened.
+func (b *Bot) validateAPIExpense(req *apiExpenseRequest, categories []appmodels.Category) (*ParsedExpense, string) {
+	if req.Amount == "" {
+		return nil, "amount is required"
+	}
+	amount, err := parseAmount(string(req.Amount))
+	if err != nil {
+		return nil, "amount is invalid: " + err.Error()
+	}
+	if amount.GreaterThan(maxExpenseAmount) {
+		return nil, "amount is too large"
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(req.Currency))
+	if currency != "" {
+		if _, ok := appmodels.SupportedCurrencies[currency]; !ok {
+			return nil, "unsupported currency: " + currency
+		}
+	}
+
+	description, truncated := b.normalizeDescription(req.Description)
+	if description == "" {
+		return nil, "description is required"
+	}
+	if truncated {
+		return nil, fmt.Sprintf("description is longer than %d characters", b.maxDescriptionLength())
+	}
+
+	categoryName := strings.TrimSpace(req.Category)
+	if categoryName != "" {
+		matched := ""
+		for i := range categories {
+			if strings.EqualFold(categories[i].Name, categoryName) {
+				matched = categories[i].Name
+				break
+			}
+		}
+		if matched == "" {
+			return nil, "unknown category: " + categoryName
+		}
+		categoryName = matched
+	}
+
+	if len(req.Tags) > maxTagsPerCommand {
+		return nil, fmt.Sprintf("at most %d tags are allowed", maxTagsPerCommand)
+	}
+	tags := make([]string, 0, len(req.Tags))
+	seen := make(map[string]bool, len(req.Tags))
+	for _, tag := range req.Tags {
+		name := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if !isValidTagName(name) {
+			return nil, "invalid tag: " + tag
+		}
+		if !seen[name] {
+			seen[name] = true
+			tags = append(tags, name)
+		}
+	}
+
+	return &ParsedExpense{
+		Amount:       amount,
+		Description:  description,
+		CategoryName: categoryName,
+		Currency:     currency,
+		Tags:         tags,
+		Source:       appmodels.ExpenseSourceAPI,
+	}, ""
+}
+
+// apiIdempotencyKey namespaces an Idempotency-Key header value, so it can
+// never match a key another entry point derived.
+func apiIdempotencyKey(header string) string {
+	return "api:" + header
+}
+
+// newAPIExpenseResponse renders an expense for the API.
+func newAPIExpenseResponse(expense *appmodels.Expense, tags []string) apiExpenseResponse {
+	resp := apiExpenseResponse{
+		ID:          expense.ID,
+		Number:      expense.UserExpenseNumber,
+		Amount:      expense.Amount.StringFixed(2),
+		Currency:    expense.Currency,
+		Description: expense.Description,
+		Tags:        tags,
+		CreatedAt:   expense.CreatedAt,
+	}
+	if expense.Category != nil {
+		resp.Category = expense.Category.Name
+	}
+	return resp
+}
+
+// writeAPIReplay answers a retried create with the expense the first
+// request saved, and 200 instead of 201.
+func (b *Bot) writeAPIReplay(ctx context.Context, w http.ResponseWriter, expense *appmodels.Expense) {
+	var names []string
+	tags, err := b.tagRepo.GetByExpenseID(ctx, expense.ID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expense.ID).Msg("Failed to load tags of replayed API expense")
+	}
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	writeAPIJSON(w, http.StatusOK, newAPIExpenseResponse(expense, names))
+}
+
+// handleAPICreateExpense handles POST /api/v1/expenses. A request with an
+// Idempotency-Key header the user sent before returns the expense saved
+// then, without creating another or notifying the chat again. So does a
+// request without one that repeats an API expense of the last
+// apiDuplicateWindow.
+func (b *Bot) handleAPICreateExpense(w http.ResponseWriter, r *http.Request, limiter *apiLimiters) {
+	user := b.authenticateAPIRequest(w, r, limiter)
+	if user == nil {
+		return
+	}
+
+	ctx := r.Context()
+	var req apiExpenseRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+
+	var idempotencyKey string
+	if header := strings.TrimSpace(r.Header.Get(apiIdempotencyKeyHeader)); header != "" {
+		if len(header) > apiMaxIdempotencyKeyLen {
+			writeAPIError(w, http.StatusBadRequest,
+				fmt.Sprintf("%s is longer than %d characters", apiIdempotencyKeyHeader, apiMaxIdempotencyKeyLen))
+			return
+		}
+		idempotencyKey = apiIdempotencyKey(header)
+		stored, err := b.expenseRepo.GetByIdempotencyKey(ctx, user.ID, idempotencyKey)
+		if err == nil {
+			b.writeAPIReplay(ctx, w, stored)
+			return
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Log.Error().Err(err).Msg("Failed to look up API idempotency key")
+			writeAPIError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+	}
+
+	categories, err := b.visibleCategories(ctx, user.ID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for API expense")
+		writeAPIError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	parsed, msg := b.validateAPIExpense(&req, categories)
+	if parsed == nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, msg)
+		return
+	}
+
+	expense, err := b.buildExpenseFromParsed(ctx, user.ID, parsed, categories)
+	if errors.Is(err, service.ErrAboveHardCap) {
+		writeAPIError(w, http.StatusUnprocessableEntity, "amount is above the maximum per expense")
+		return
+	}
+	if err == nil && idempotencyKey == "" {
+		duplicate, dupErr := b.expenseRepo.GetRecentDuplicate(ctx, expense, b.now().Add(-apiDuplicateWindow))
+		if dupErr == nil {
+			logger.Log.Info().
+				Str("user_hash", logger.HashUserID(user.ID)).
+				Int("expense_id", duplicate.ID).
+				Msg("Duplicate API expense returned")
+			b.writeAPIReplay(ctx, w, duplicate)
+			return
+		}
+		if !errors.Is(dupErr, pgx.ErrNoRows) {
+			err = dupErr
+		}
+	}
+	if err == nil {
+		expense.IdempotencyKey = idempotencyKey
+		_, err = b.persistParsedExpense(ctx, expense, parsed, service.CreateOptions{})
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create API expense")
+		writeAPIError(w, http.StatusInternalServerError, "failed to save expense")
+		return
+	}
+	if expense.Replayed {
+		// A concurrent request with the same key saved it first.
+		b.writeAPIReplay(ctx, w, expense)
+		return
+	}
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(user.ID)).
+		Int("expense_id", expense.ID).
+		Msg("Expense created via API")
+
+	if b.messageSender != nil {
+		b.sendExpenseAdded(ctx, b.messageSender, user.ID, expense, parsed)
+	}
+
+	writeAPIJSON(w, http.StatusCreated, newAPIExpenseResponse(expense, parsed.Tags))
+}
@@ -0,0 +1,379 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	apiTestDescription = "Shortcut coffee"
+	apiTestToken       = "ebk_test-token"
+)
+
+func TestAPIRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	limiter := newAPIRateLimiter(2, time.Minute)
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	require.True(t, limiter.allow("a", now))
+	require.True(t, limiter.allow("a", now.Add(time.Second)))
+	require.False(t, limiter.allow("a", now.Add(2*time.Second)))
+	require.True(t, limiter.allow("b", now), "limits are per key")
+	require.True(t, limiter.allow("a", now.Add(time.Minute)), "window resets")
+
+	require.False(t, limiter.exceeded("b", now))
+	require.True(t, limiter.allow("b", now))
+	require.True(t, limiter.exceeded("b", now), "exceeded does not count a request")
+	require.False(t, limiter.exceeded("b", now.Add(time.Minute)))
+}
+
+func TestAPIRateLimiterFull(t *testing.T) {
+	t.Parallel()
+
+	limiter := newAPIRateLimiter(1, time.Minute)
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := range apiRateLimiterMaxKeys {
+		require.True(t, limiter.allow(strconv.Itoa(i), now))
+	}
+
+	require.False(t, limiter.allow("new", now.Add(time.Second)), "a new key cannot evict counting ones")
+	require.False(t, limiter.allow("0", now.Add(time.Second)), "known keys keep their count")
+	require.True(t, limiter.allow("new", now.Add(time.Minute)), "expired keys make room")
+}
+
+func TestAPIFailedAuthLimit(t *testing.T) {
+	t.Parallel()
+
+	handler := (&Bot{}).apiHandler()
+	post := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodPost, apiExpensesPath, strings.NewReader(`{}`))
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for range apiFailedAuthLimit {
+		require.Equal(t, http.StatusUnauthorized, post("192.0.2.1:1000"))
+	}
+	require.Equal(t, http.StatusTooManyRequests, post("192.0.2.1:2000"), "other ports of the address count too")
+	require.Equal(t, http.StatusUnauthorized, post("192.0.2.2:1000"), "other addresses are not limited")
+}
+
+func TestAPIClientAddr(t *testing.T) {
+	t.Parallel()
+
+	proxied := &Bot{cfg: &config.Config{HTTPTrustedProxyHeader: "X-Forwarded-For"}}
+	tests := []struct {
+		name      string
+		b         *Bot
+		forwarded []string
+		want      string
+	}{
+		{name: "direct", b: &Bot{}, forwarded: []string{"198.51.100.7"}, want: "192.0.2.1"},
+		{name: "proxy header", b: proxied, forwarded: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "last entry is the one the proxy added", b: proxied, forwarded: []string{"203.0.113.9, 198.51.100.7"}, want: "198.51.100.7"},
+		{name: "last header wins", b: proxied, forwarded: []string{"203.0.113.9", "198.51.100.7"}, want: "198.51.100.7"},
+		{name: "missing header falls back", b: proxied, want: "192.0.2.1"},
+		{name: "invalid header falls back", b: proxied, forwarded: []string{"unknown"}, want: "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodPost, apiExpensesPath, nil)
+			req.RemoteAddr = "192.0.2.1:1000"
+			for _, v := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			require.Equal(t, tt.want, tt.b.apiClientAddr(req))
+		})
+	}
+}
+
+func TestAPIFailedAuthLimitBehindProxy(t *testing.T) {
+	t.Parallel()
+
+	handler := (&Bot{cfg: &config.Config{HTTPTrustedProxyHeader: "X-Real-IP"}}).apiHandler()
+	post := func(clientAddr string) int {
+		req := httptest.NewRequest(http.MethodPost, apiExpensesPath, strings.NewReader(`{}`))
+		req.RemoteAddr = "10.0.0.1:1000"
+		req.Header.Set("X-Real-IP", clientAddr)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for range apiFailedAuthLimit {
+		require.Equal(t, http.StatusUnauthorized, post("192.0.2.1"))
+	}
+	require.Equal(t, http.StatusTooManyRequests, post("192.0.2.1"))
+	require.Equal(t, http.StatusUnauthorized, post("192.0.2.2"), "clients behind the same proxy are limited apart")
+}
+
+func TestBearerToken(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "bearer", header: "Bearer abc", want: "abc"},
+		{name: "case insensitive scheme", header: "bearer abc", want: "abc"},
+		{name: "basic scheme", header: "Basic abc", want: ""},
+		{name: "missing", header: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodPost, apiExpensesPath, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			require.Equal(t, tt.want, bearerToken(req))
+		})
+	}
+}
+
+func TestValidateAPIExpense(t *testing.T) {
+	t.Parallel()
+
+	categories := []appmodels.Category{{ID: 1, Name: testCategoryFood}}
+	b := &Bot{cfg: &config.Config{MaxDescriptionLength: 10}}
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{
+			name:    "description too long",
+			body:    `{"amount":5,"description":"a very long lunch"}`,
+			wantErr: "description is longer than 10 characters",
+		},
+		{name: "missing amount", body: `{"description":"x"}`, wantErr: "amount is required"},
+		{name: "negative amount", body: `{"amount":-5,"description":"x"}`, wantErr: "amount is invalid"},
+		{name: "too large", body: `{"amount":"99999999999","description":"x"}`, wantErr: "too large"},
+		{name: "unknown currency", body: `{"amount":5,"currency":"XYZ","description":"x"}`, wantErr: "unsupported currency"},
+		{name: "missing description", body: `{"amount":5}`, wantErr: "description is required"},
+		{name: "unknown category", body: `{"amount":5,"description":"x","category":"Nope"}`, wantErr: "unknown category"},
+		{name: "invalid tag", body: `{"amount":5,"description":"x","tags":["1bad"]}`, wantErr: "invalid tag"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var req apiExpenseRequest
+			require.NoError(t, json.Unmarshal([]byte(tt.body), &req))
+			parsed, msg := b.validateAPIExpense(&req, categories)
+			require.Nil(t, parsed)
+			require.Contains(t, msg, tt.wantErr)
+		})
+	}
+
+	t.Run("valid request is normalized", func(t *testing.T) {
+		t.Parallel()
+		var req apiExpenseRequest
+		body := `{"amount":"84.60/3","currency":"sgd","description":" Dinner\n with  Anna ","category":"food","tags":["#Work","work"]}`
+		require.NoError(t, json.Unmarshal([]byte(body), &req))
+
+		parsed, msg := (&Bot{}).validateAPIExpense(&req, categories)
+		require.Empty(t, msg)
+		require.Equal(t, "28.20", parsed.Amount.StringFixed(2))
+		require.Equal(t, currencyCodeSGD, parsed.Currency)
+		require.Equal(t, "Dinner with Anna", parsed.Description)
+		require.Equal(t, testCategoryFood, parsed.CategoryName)
+		require.Equal(t, []string{"work"}, parsed.Tags)
+		require.Equal(t, appmodels.ExpenseSourceAPI, parsed.Source)
+	})
+}
+
+func TestAPICreateExpense(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.cfg.HTTPAddr = ":0"
+	userID := b.cfg.WhitelistedUserIDs[0]
+
+	err := b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "API"})
+	require.NoError(t, err)
+	require.NoError(t, b.userRepo.SetAPITokenHash(ctx, userID, hashAPIToken(apiTestToken)))
+
+	srv := httptest.NewServer(b.apiHandler())
+	t.Cleanup(srv.Close)
+
+	postWithKey := func(token, key, body string) (*http.Response, map[string]any) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+apiExpensesPath, strings.NewReader(body))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if key != "" {
+			req.Header.Set(apiIdempotencyKeyHeader, key)
+		}
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+
+		var decoded map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+		return resp, decoded
+	}
+	post := func(token, body string) (*http.Response, map[string]any) {
+		return postWithKey(token, "", body)
+	}
+
+	t.Run("missing token is unauthorized", func(t *testing.T) {
+		resp, body := post("", `{"amount":5,"description":"x"}`)
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		require.Contains(t, body["error"], "missing")
+	})
+
+	t.Run("unknown token is unauthorized", func(t *testing.T) {
+		resp, body := post("ebk_wrong", `{"amount":5,"description":"x"}`)
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		require.Equal(t, "invalid token", body["error"])
+	})
+
+	t.Run("malformed JSON is a bad request", func(t *testing.T) {
+		resp, _ := post(apiTestToken, `{"amount":`)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("validation failure is unprocessable", func(t *testing.T) {
+		resp, body := post(apiTestToken, `{"amount":0,"description":"x"}`)
+		require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		require.Contains(t, body["error"], "amount")
+	})
+
+	t.Run("creates expense visible in /list", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.messageSender = mockBot
+
+		resp, body := post(apiTestToken,
+			`{"amount":"4.50","currency":"SGD","description":"`+apiTestDescription+`","tags":["shortcut"]}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Equal(t, "4.50", body["amount"])
+		require.Equal(t, currencyCodeSGD, body["currency"])
+		require.Equal(t, apiTestDescription, body["description"])
+		require.Equal(t, []any{"shortcut"}, body["tags"])
+		require.Contains(t, mockBot.LastSentMessage().Text, "Expense Added")
+
+		saved, err := b.expenseRepo.GetByID(ctx, int(body["id"].(float64)))
+		require.NoError(t, err)
+		require.Equal(t, appmodels.ExpenseSourceAPI, saved.Source)
+
+		listBot := mocks.NewMockBot()
+		b.handleListCore(ctx, listBot, mocks.CommandUpdate(userID, userID, "/list"))
+		require.Contains(t, listBot.LastSentMessage().Text, apiTestDescription)
+	})
+
+	t.Run("a retry with the same idempotency key returns the first expense", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.messageSender = mockBot
+		body := `{"amount":"6.20","description":"Retried taxi","tags":["commute"]}`
+
+		resp, first := postWithKey(apiTestToken, "taxi-443", body)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		resp, retry := postWithKey(apiTestToken, "taxi-443", body)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, first["id"], retry["id"])
+		require.Equal(t, first["number"], retry["number"])
+		require.Equal(t, []any{"commute"}, retry["tags"])
+		require.Equal(t, 1, mockBot.SentMessageCount(), "the chat is told once")
+
+		resp, other := postWithKey(apiTestToken, "taxi-444", body)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.NotEqual(t, first["id"], other["id"])
+	})
+
+	t.Run("a repeated create without a key returns the first expense", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.messageSender = mockBot
+		body := `{"amount":"3.10","description":"Double tapped tea"}`
+
+		resp, first := post(apiTestToken, body)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		resp, again := post(apiTestToken, body)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, first["id"], again["id"])
+		require.Equal(t, 1, mockBot.SentMessageCount(), "the chat is told once")
+
+		b.nowFunc = func() time.Time { return time.Now().Add(apiDuplicateWindow + time.Second) }
+		t.Cleanup(func() { b.nowFunc = nil })
+		resp, later := post(apiTestToken, body)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.NotEqual(t, first["id"], later["id"])
+	})
+
+	t.Run("an overlong idempotency key is a bad request", func(t *testing.T) {
+		resp, body := postWithKey(apiTestToken, strings.Repeat("k", apiMaxIdempotencyKeyLen+1), `{"amount":5,"description":"x"}`)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Contains(t, body["error"], apiIdempotencyKeyHeader)
+	})
+
+	t.Run("revoked token is unauthorized", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleAPITokenCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/apitoken revoke"))
+		require.Contains(t, mockBot.LastSentMessage().Text, "revoked")
+
+		resp, _ := post(apiTestToken, `{"amount":5,"description":"x"}`)
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+func TestHandleAPITokenCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	userID := int64(500364)
+
+	err := b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Token"})
+	require.NoError(t, err)
+
+	t.Run("refuses when the API is disabled", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleAPITokenCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/apitoken"))
+		require.Equal(t, apiTokenDisabledMsg, mockBot.LastSentMessage().Text)
+	})
+
+	b.cfg.HTTPAddr = ":0"
+
+	t.Run("refuses in group chats", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		update := mocks.CommandUpdate(-100, userID, "/apitoken")
+		update.Message.Chat.Type = models.ChatTypeGroup
+		b.handleAPITokenCore(ctx, mockBot, update)
+		require.Equal(t, apiTokenPrivateMsg, mockBot.LastSentMessage().Text)
+	})
+
+	t.Run("issues a token stored only as a hash", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleAPITokenCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/apitoken"))
+
+		text := mockBot.LastSentMessage().Text
+		start := strings.Index(text, apiTokenPrefix)
+		require.Positive(t, start)
+		token := text[start : start+len(apiTokenPrefix)+2*apiTokenByteLen]
+
+		user, err := b.userRepo.GetUserByAPITokenHash(ctx, hashAPIToken(token))
+		require.NoError(t, err)
+		require.Equal(t, userID, user.ID)
+	})
+}
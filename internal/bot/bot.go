@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,9 +17,12 @@ import (
 	"gitlab.com/yelinaung/expense-bot/internal/database"
 	"gitlab.com/yelinaung/expense-bot/internal/exchange"
 	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	"gitlab.com/yelinaung/expense-bot/internal/geocode"
 	"gitlab.com/yelinaung/expense-bot/internal/logger"
 	"gitlab.com/yelinaung/expense-bot/internal/models"
 	"gitlab.com/yelinaung/expense-bot/internal/repository"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
+	"gitlab.com/yelinaung/expense-bot/internal/storage"
 	"gitlab.com/yelinaung/expense-bot/internal/telemetry"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -33,33 +37,135 @@ type pendingEdit struct {
 	ExpenseID int
 	EditType  string // "amount" or "category"
 	MessageID int    // Message ID to edit after update.
+
+	// For an expense held back until it has a description: who sent it,
+	// what was parsed and the message that sent it.
+	UserID          int64
+	Parsed          *ParsedExpense
+	SourceMessageID int
+
+	// ExpiresAt is when the edit stops waiting for input. Zero means it
+	// does not expire.
+	ExpiresAt time.Time
 }
 
 // Bot wraps the Telegram bot with application dependencies.
 type Bot struct {
 	bot              *bot.Bot
+	botID            int64  // The bot's own user ID, from its token.
+	botUsername      string // The bot's own username, from getMe.
 	cfg              *config.Config
 	db               database.PGXDB
 	userRepo         *repository.UserRepository
 	categoryRepo     *repository.CategoryRepository
 	expenseRepo      *repository.ExpenseRepository
+	expenseService   *service.ExpenseService
 	tagRepo          *repository.TagRepository
 	approvedUserRepo *repository.ApprovedUserRepository
 	bindingRepo      *repository.SuperadminBindingRepository
+	receiptScanRepo  *repository.ReceiptScanRepository
+	expenseAuditRepo *repository.ExpenseAuditRepository
+	budgetRepo       *repository.BudgetRepository
+	chatRepo         *repository.ChatRepository
+	splitRepo        *repository.SplitRepository
+	bankTemplateRepo *repository.BankTemplateRepository
+	variantRepo      *repository.PromptVariantRepository
+	receiptFileRepo  *repository.ReceiptFileRepository
+	geminiUsageRepo  *repository.GeminiUsageRepository
+	reviewRepo       *repository.MonthlyReviewRepository
+	importBatchRepo  *repository.ImportBatchRepository
+	sheetExportRepo  *repository.SheetExportRepository
+	outboxRepo       *repository.OutboxRepository
+	receiptStore     storage.ReceiptStore // nil when RECEIPT_STORAGE=none.
 	geminiClient     *gemini.Client
 
 	messageSender   TelegramAPI
 	exchangeService exchange.Converter
+	geocoder        geocode.ReverseGeocoder // nil when GEOCODER_URL is unset.
 	displayLocation *time.Location
 	nowFunc         func() time.Time
 
+	// chartGenerator renders category charts; nil uses GenerateExpenseChart.
+	chartGenerator func(expenses []models.Expense, period string) ([]byte, error)
+
 	pendingEdits   map[int64]*pendingEdit // key is chatID
 	pendingEditsMu sync.RWMutex
 
-	// Category cache to reduce database queries.
+	// Bulk recategorization previews waiting for confirmation, by nonce.
+	pendingRecats   map[string]*pendingRecategorization
+	pendingRecatsMu sync.Mutex
+
+	// /tagrange runs waiting for confirmation, by nonce.
+	pendingTagRanges   map[string]*pendingTagRange
+	pendingTagRangesMu sync.Mutex
+
+	// Chat expenses refused by a hard budget, by nonce.
+	pendingBudget   map[string]*pendingBudgetFallback
+	pendingBudgetMu sync.Mutex
+
+	// Chat expenses in a currency the user never used, by nonce.
+	pendingCurrency   map[string]*pendingNewCurrency
+	pendingCurrencyMu sync.Mutex
+
+	// Expenses built by /preview, waiting to be saved, by nonce.
+	pendingPreviews   map[string]*pendingPreview
+	pendingPreviewsMu sync.Mutex
+
+	// Photos of Telegram albums waiting to be scanned as one receipt.
+	// mediaGroupWait overrides mediaGroupWindow when non-zero.
+	mediaGroups    mediaGroupBuffer
+	mediaGroupWait time.Duration
+
+	// Receipts forwarded in a group from another user, waiting for the
+	// forwarder to say whose expense they are, by the question message.
+	// receiptOwnerWait overrides receiptOwnerTimeout when non-zero.
+	pendingOwners    map[receiptOwnerKey]*pendingReceiptOwner
+	pendingOwnersMu  sync.Mutex
+	receiptOwnerWait time.Duration
+
+	// Receipt scans running in the background; nil scans them inline.
+	receiptWorkers *receiptWorkerPool
+
+	// Telegram file download links, reused to skip getFile round trips.
+	fileLinks fileLinkCache
+
+	// Recent inline query answers, reused while the user is typing.
+	inlineStats inlineStatsCache
+
+	// /pin unlock windows and wrong PIN attempts.
+	pinLocks pinLocks
+
+	// AI category suggestions per user today, and those skipped.
+	aiSuggestQuota aiSuggestQuota
+
+	// Category cache to reduce database queries. The generation changes on
+	// every write, so a load that started before it cannot store stale rows.
 	categoryCache       []models.Category
 	categoryCacheExpiry time.Time
+	categoryCacheGen    uint64
 	categoryCacheMu     sync.RWMutex
+	categoryLoadMu      sync.Mutex
+
+	// Per-user most used first category orders.
+	categoryUsage categoryUsageCache
+	// Per-user hidden shared category IDs.
+	hiddenCategories categoryUsageCache
+
+	// When each user's activity was last written.
+	lastSeen lastSeenThrottle
+
+	// Prefixes of the registered command handlers, and the replies sent
+	// per chat for commands none of them matched.
+	commandPrefixes []string
+	unknownCommands unknownCommandLimiter
+
+	// The heavy report each user has running, so a repeated /report,
+	// /chart, /export or /statement waits for the first.
+	reportGuard reportGuard
+
+	// When New returned and how many handlers it registered, for /about.
+	startedAt    time.Time
+	handlerCount int
 
 	// OTel instrumentation (nil when disabled).
 	metrics    *telemetry.BotMetrics
@@ -80,18 +186,43 @@ func New(ctx context.Context, cfg *config.Config, db database.PGXDB) (*Bot, erro
 		tagRepo:          repository.NewTagRepository(db),
 		approvedUserRepo: repository.NewApprovedUserRepository(db),
 		bindingRepo:      bindingRepo,
+		receiptScanRepo:  repository.NewReceiptScanRepository(db),
+		expenseAuditRepo: repository.NewExpenseAuditRepository(db),
+		budgetRepo:       repository.NewBudgetRepository(db),
+		chatRepo:         repository.NewChatRepository(db),
+		splitRepo:        repository.NewSplitRepository(db),
+		bankTemplateRepo: repository.NewBankTemplateRepository(db),
+		variantRepo:      repository.NewPromptVariantRepository(db),
+		receiptFileRepo:  repository.NewReceiptFileRepository(db),
+		geminiUsageRepo:  repository.NewGeminiUsageRepository(db),
+		reviewRepo:       repository.NewMonthlyReviewRepository(db),
+		importBatchRepo:  repository.NewImportBatchRepository(db),
+		sheetExportRepo:  repository.NewSheetExportRepository(db),
+		outboxRepo:       repository.NewOutboxRepository(db),
 		pendingEdits:     make(map[int64]*pendingEdit),
 		exchangeService:  newExchangeService(cfg, transport, cacheMetricsFrom(metrics)),
+		geocoder:         newGeocoder(cfg, transport),
 		httpClient:       &http.Client{Timeout: 30 * time.Second, Transport: transport},
 		metrics:          metrics,
-		geminiClient:     initGeminiClient(ctx, cfg.GeminiAPIKey),
+	}
+	b.expenseService = service.NewExpenseService(b.expenseRepo, b.userRepo, cfg)
+
+	if cfg.ReceiptStorage != config.ReceiptStorageNone {
+		store, err := openReceiptStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+		b.receiptStore = store
 	}
 
 	middlewares := buildMiddlewares(b.whitelistMiddleware, b.metrics)
 
+	// The token is checked by checkTelegram below, which names the setting
+	// in its error instead of the library's bare getMe failure.
 	opts := []bot.Option{
 		bot.WithMiddlewares(middlewares...),
 		bot.WithDefaultHandler(b.defaultHandler),
+		bot.WithSkipGetMe(),
 	}
 	if cfg.OTelEnabled {
 		// Instrument the bot library's HTTP client so every Telegram API call
@@ -102,13 +233,28 @@ func New(ctx context.Context, cfg *config.Config, db database.PGXDB) (*Bot, erro
 
 	telegramBot, err := bot.New(cfg.TelegramBotToken, opts...)
 	if err != nil {
+		b.closeReceiptStore()
 		return nil, fmt.Errorf("failed to create bot: %w", err)
 	}
+	me, err := checkTelegram(ctx, telegramBot)
+	if err != nil {
+		b.closeReceiptStore()
+		return nil, err
+	}
+	b.geminiClient = preflightGemini(ctx, initGeminiClient(ctx, cfg.GeminiAPIKey))
+	if b.geminiClient != nil {
+		b.geminiClient.SetUsageRecorder(geminiUsageRecorder{repo: b.geminiUsageRepo})
+		b.geminiClient.SetPromptVariants(promptVariantSource{repo: b.variantRepo})
+	}
 
 	b.bot = telegramBot
+	b.botID = telegramBot.ID()
+	b.botUsername = me.Username
 	b.messageSender = telegramBot
 	b.displayLocation = loadDisplayLocation(cfg.ReminderTimezone)
 	b.nowFunc = time.Now
+	b.startedAt = b.now()
+	b.receiptWorkers = newReceiptWorkerPool(cfg.ReceiptWorkers)
 
 	b.registerHandlers()
 
@@ -219,6 +365,15 @@ func newExchangeService(cfg *config.Config, transport http.RoundTripper, cacheMe
 	return exchange.NewCachedService(client, cfg.ExchangeRateCacheTTL, cacheMetrics)
 }
 
+// newGeocoder returns the reverse geocoder for expense locations, or nil when
+// none is configured.
+func newGeocoder(cfg *config.Config, transport http.RoundTripper) geocode.ReverseGeocoder {
+	if cfg.GeocoderURL == "" {
+		return nil
+	}
+	return geocode.NewNominatimClient(cfg.GeocoderURL, 0, transport)
+}
+
 const (
 	// DraftExpirationTimeout is the default duration after which unconfirmed draft
 	// expenses are deleted. Overridable via the DRAFT_EXPIRATION env var.
@@ -245,41 +400,50 @@ func (b *Bot) Start(ctx context.Context) {
 
 	b.registerCommands(ctx)
 	b.cleanupExpiredDrafts(ctx)
+	b.cleanupStoredReceipts(ctx)
 
 	go b.startDraftCleanupLoop(ctx)
 	go b.startDailyReminderLoop(ctx)
 	go b.startWeeklyReportLoop(ctx)
+	go b.startMonthlyArchiveLoop(ctx)
+	go b.startSheetExportLoop(ctx)
+	go b.startOutboxLoop(ctx)
+	if b.apiEnabled() {
+		go b.serveHTTP(ctx, b.cfg.HTTPAddr)
+	}
 
 	logger.Log.Info().Msg("Bot started polling")
 	b.bot.Start(ctx)
+
+	b.drainReceiptWorkers(context.WithoutCancel(ctx))
+	b.closeReceiptStore()
+}
+
+// drainReceiptWorkers lets receipts that are queued or being scanned finish
+// after polling stopped, canceling them after receiptDrainTimeout. Receipts
+// still waiting for their owner and albums still being collected are
+// queued first.
+func (b *Bot) drainReceiptWorkers(ctx context.Context) {
+	b.flushPendingReceiptOwners()
+	b.mediaGroups.flushAll()
+	if b.receiptWorkers == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, receiptDrainTimeout)
+	defer cancel()
+
+	logger.Log.Info().Msg("Waiting for receipt scans to finish")
+	b.receiptWorkers.shutdown(ctx)
 }
 
 // registerCommands registers bot commands with Telegram so they appear in the menu.
+// The list comes from commandDefs, which also drives /help.
 func (b *Bot) registerCommands(ctx context.Context) {
-	commands := []tgmodels.BotCommand{
-		{Command: "add", Description: "Add an expense"},
-		{Command: "list", Description: "Show recent expenses"},
-		{Command: "review", Description: "Review recent spending"},
-		{Command: "habit", Description: "Show spending reflection summary"},
-		{Command: "today", Description: "Show today's expenses"},
-		{Command: "week", Description: "Show this week's expenses"},
-		{Command: "category", Description: "Filter expenses by category"},
-		{Command: "report", Description: "Generate CSV report (week/month)"},
-		{Command: "chart", Description: "Generate expense chart (week/month)"},
-		{Command: "categories", Description: "List all categories"},
-		{Command: "addcategory", Description: "Create a new category"},
-		{Command: "renamecategory", Description: "Rename a category"},
-		{Command: "deletecategory", Description: "Delete a category"},
-		{Command: editAction, Description: "Edit an expense"},
-		{Command: "delete", Description: "Delete an expense"},
-		{Command: "currency", Description: "Show your default currency"},
-		{Command: "setcurrency", Description: "Set default currency (e.g. USD, EUR)"},
-		{Command: "timezone", Description: "Show your timezone"},
-		{Command: "settimezone", Description: "Set your timezone (e.g. Asia/Tokyo)"},
-		{Command: "tag", Description: "Add tags to an expense"},
-		{Command: "untag", Description: "Remove a tag from an expense"},
-		{Command: "tags", Description: "List all tags or filter by tag"},
-		{Command: "help", Description: "Show all available commands"},
+	commands := make([]tgmodels.BotCommand, 0, len(commandDefs))
+	for _, def := range commandDefs {
+		if def.Menu != "" {
+			commands = append(commands, tgmodels.BotCommand{Command: def.Name, Description: def.Menu})
+		}
 	}
 
 	_, err := b.bot.SetMyCommands(ctx, &bot.SetMyCommandsParams{
@@ -292,6 +456,17 @@ func (b *Bot) registerCommands(ctx context.Context) {
 	logger.Log.Info().Int("count", len(commands)).Msg("Bot commands registered")
 }
 
+// expenses returns the expense service, building expenses with the bot's
+// current lookups. Bots assembled without New, as in tests, get one over
+// their own repositories.
+func (b *Bot) expenses() *service.ExpenseService {
+	expenses := b.expenseService
+	if expenses == nil {
+		expenses = service.NewExpenseService(b.expenseRepo, b.userRepo, b.cfg)
+	}
+	return expenses.WithPipeline(b.pipeline())
+}
+
 // draftExpiration returns the configured draft retention, falling back to the
 // default when unset (e.g. tests that construct a Bot without full config).
 func (b *Bot) draftExpiration() time.Duration {
@@ -306,7 +481,7 @@ func (b *Bot) cleanupExpiredDrafts(ctx context.Context) {
 	ctx, span := otel.Tracer("expense-bot/background").Start(ctx, "background.draft_cleanup")
 	defer span.End()
 	start := time.Now()
-	count, err := b.expenseRepo.DeleteExpiredDrafts(ctx, b.draftExpiration())
+	count, err := b.expenses().DeleteExpiredDrafts(ctx, b.draftExpiration())
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -330,7 +505,9 @@ func (b *Bot) cleanupExpiredDrafts(ctx context.Context) {
 	}
 }
 
-// startDraftCleanupLoop runs periodic cleanup of expired draft expenses.
+// startDraftCleanupLoop runs periodic cleanup of expired draft expenses
+// and of stored receipts whose expense was deleted, and leaves the groups
+// no authorized user claimed.
 func (b *Bot) startDraftCleanupLoop(ctx context.Context) {
 	ticker := time.NewTicker(DraftCleanupInterval)
 	defer ticker.Stop()
@@ -342,53 +519,191 @@ func (b *Bot) startDraftCleanupLoop(ctx context.Context) {
 			return
 		case <-ticker.C:
 			b.cleanupExpiredDrafts(ctx)
+			b.cleanupStoredReceipts(ctx)
+			b.leaveUnclaimedChats(ctx, b.messageSender)
 		}
 	}
 }
 
+// registerHandler registers a Telegram handler and counts it for /about.
+func (b *Bot) registerHandler(handlerType bot.HandlerType, pattern string, matchType bot.MatchType, f bot.HandlerFunc) {
+	b.bot.RegisterHandler(handlerType, pattern, matchType, f)
+	b.handlerCount++
+	if handlerType == bot.HandlerTypeMessageText && matchType == bot.MatchTypePrefix {
+		b.commandPrefixes = append(b.commandPrefixes, pattern)
+	}
+}
+
 // registerHandlers sets up command handlers.
 func (b *Bot) registerHandlers() {
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/start", bot.MatchTypePrefix, b.handleStart)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/help", bot.MatchTypePrefix, b.handleHelp)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/categories", bot.MatchTypePrefix, b.handleCategories)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/add", bot.MatchTypePrefix, b.handleAdd)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/list", bot.MatchTypePrefix, b.handleList)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/review", bot.MatchTypePrefix, b.handleReview)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/habit", bot.MatchTypePrefix, b.handleHabit)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/today", bot.MatchTypePrefix, b.handleToday)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/week", bot.MatchTypePrefix, b.handleWeek)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/category", bot.MatchTypePrefix, b.handleCategory)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/report", bot.MatchTypePrefix, b.handleReport)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/chart", bot.MatchTypePrefix, b.handleChart)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/addcategory", bot.MatchTypePrefix, b.handleAddCategory)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/renamecategory", bot.MatchTypePrefix, b.handleRenameCategory)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/deletecategory", bot.MatchTypePrefix, b.handleDeleteCategory)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/edit", bot.MatchTypePrefix, b.handleEdit)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/delete", bot.MatchTypePrefix, b.handleDelete)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/setcurrency", bot.MatchTypePrefix, b.handleSetCurrency)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/currency", bot.MatchTypePrefix, b.handleShowCurrency)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/settimezone", bot.MatchTypePrefix, b.handleSetTimezone)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/timezone", bot.MatchTypePrefix, b.handleShowTimezone)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/untag", bot.MatchTypePrefix, b.handleUntag)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/tags", bot.MatchTypePrefix, b.handleTags)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/tag", bot.MatchTypePrefix, b.handleTag)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/approve", bot.MatchTypePrefix, b.handleApprove)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/revoke", bot.MatchTypePrefix, b.handleRevoke)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/users", bot.MatchTypePrefix, b.handleUsers)
+	b.registerHandler(bot.HandlerTypeMessageText, "/start", bot.MatchTypePrefix, b.handleStart)
+	b.registerHandler(bot.HandlerTypeMessageText, "/help", bot.MatchTypePrefix, b.handleHelp)
+	b.registerHandler(bot.HandlerTypeMessageText, "/about", bot.MatchTypePrefix, b.handleAbout)
+	b.registerHandler(bot.HandlerTypeMessageText, "/categorytags", bot.MatchTypePrefix, b.handleCategoryTags)
+	b.registerHandler(bot.HandlerTypeMessageText, "/setcategorycolor", bot.MatchTypePrefix, b.handleSetCategoryColor)
+	b.registerHandler(bot.HandlerTypeMessageText, "/categories", bot.MatchTypePrefix, b.handleCategories)
+	b.registerHandler(bot.HandlerTypeMessageText, "/add", bot.MatchTypePrefix, b.handleAdd)
+	b.registerHandler(bot.HandlerTypeMessageText, "/refund", bot.MatchTypePrefix, b.handleRefund)
+	b.registerHandler(bot.HandlerTypeMessageText, "/list", bot.MatchTypePrefix, b.handleList)
+	b.registerHandler(bot.HandlerTypeMessageText, "/show", bot.MatchTypePrefix, b.handleShow)
+	b.registerHandler(bot.HandlerTypeMessageText, "/review", bot.MatchTypePrefix, b.handleReview)
+	b.registerHandler(bot.HandlerTypeMessageText, "/habit", bot.MatchTypePrefix, b.handleHabit)
+	b.registerHandler(bot.HandlerTypeMessageText, "/patterns", bot.MatchTypePrefix, b.handlePatterns)
+	b.registerHandler(bot.HandlerTypeMessageText, "/reimbursements", bot.MatchTypePrefix, b.handleReimbursements)
+	b.registerHandler(bot.HandlerTypeMessageText, "/today", bot.MatchTypePrefix, b.handleToday)
+	b.registerHandler(bot.HandlerTypeMessageText, "/week", bot.MatchTypePrefix, b.handleWeek)
+	b.registerHandler(bot.HandlerTypeMessageText, "/month", bot.MatchTypePrefix, b.handleMonth)
+	b.registerHandler(bot.HandlerTypeMessageText, "/parse", bot.MatchTypePrefix, b.handleParse)
+	b.registerHandler(bot.HandlerTypeMessageText, "/preview", bot.MatchTypePrefix, b.handlePreview)
+	b.registerHandler(bot.HandlerTypeMessageText, "/history", bot.MatchTypePrefix, b.handleHistory)
+	b.registerHandler(bot.HandlerTypeMessageText, "/total", bot.MatchTypePrefix, b.handleTotal)
+	b.registerHandler(bot.HandlerTypeMessageText, "/category", bot.MatchTypePrefix, b.handleCategory)
+	b.registerHandler(bot.HandlerTypeMessageText, "/uncategorized", bot.MatchTypePrefix, b.handleUncategorized)
+	b.registerHandler(bot.HandlerTypeMessageText, "/report", bot.MatchTypePrefix, b.handleReport)
+	b.registerHandler(bot.HandlerTypeMessageText, "/chart", bot.MatchTypePrefix, b.handleChart)
+	b.registerHandler(bot.HandlerTypeMessageText, "/statement", bot.MatchTypePrefix, b.handleStatement)
+	b.registerHandler(bot.HandlerTypeMessageText, "/year", bot.MatchTypePrefix, b.handleYear)
+	b.registerHandler(bot.HandlerTypeMessageText, "/archivechat", bot.MatchTypePrefix, b.handleArchiveChat)
+	b.registerHandler(bot.HandlerTypeMessageText, "/sheetexport", bot.MatchTypePrefix, b.handleSheetExport)
+	// The taxonomy and settings commands come before /export and /import,
+	// which would otherwise match them by prefix.
+	b.registerHandler(bot.HandlerTypeMessageText, exportSettingsCommand, bot.MatchTypePrefix, b.handleExportSettings)
+	b.registerHandler(bot.HandlerTypeMessageText, importSettingsCommand, bot.MatchTypePrefix, b.handleImportSettings)
+	b.registerHandler(bot.HandlerTypePhotoCaption, importSettingsCommand, bot.MatchTypePrefix, b.handleImportSettings)
+	b.registerHandler(bot.HandlerTypeMessageText, exportTaxonomyCommand, bot.MatchTypePrefix, b.handleExportTaxonomy)
+	b.registerHandler(bot.HandlerTypeMessageText, importTaxonomyCommand, bot.MatchTypePrefix, b.handleImportTaxonomy)
+	b.registerHandler(bot.HandlerTypePhotoCaption, importTaxonomyCommand, bot.MatchTypePrefix, b.handleImportTaxonomy)
+	b.registerHandler(bot.HandlerTypeMessageText, "/export", bot.MatchTypePrefix, b.handleExport)
+	b.registerHandler(bot.HandlerTypeMessageText, importCommand, bot.MatchTypePrefix, b.handleImport)
+	b.registerHandler(bot.HandlerTypePhotoCaption, importCommand, bot.MatchTypePrefix, b.handleImport)
+	b.registerHandler(bot.HandlerTypeMessageText, "/budget", bot.MatchTypePrefix, b.handleBudget)
+	b.registerHandler(bot.HandlerTypeMessageText, "/chatcategory", bot.MatchTypePrefix, b.handleChatCategory)
+	b.registerHandler(bot.HandlerTypeMessageText, "/balance", bot.MatchTypePrefix, b.handleBalance)
+	b.registerHandler(bot.HandlerTypeMessageText, "/settle", bot.MatchTypePrefix, b.handleSettle)
+	b.registerHandler(bot.HandlerTypeMessageText, "/dedupe", bot.MatchTypePrefix, b.handleDedupe)
+	b.registerHandler(bot.HandlerTypeMessageText, "/drafts", bot.MatchTypePrefix, b.handleDrafts)
+	b.registerHandler(bot.HandlerTypeMessageText, "/addcategory", bot.MatchTypePrefix, b.handleAddCategory)
+	b.registerHandler(bot.HandlerTypeMessageText, "/recategorize", bot.MatchTypePrefix, b.handleRecategorize)
+	b.registerHandler(bot.HandlerTypeMessageText, "/renamecategory", bot.MatchTypePrefix, b.handleRenameCategory)
+	b.registerHandler(bot.HandlerTypeMessageText, "/deletecategory", bot.MatchTypePrefix, b.handleDeleteCategory)
+	b.registerHandler(
+		bot.HandlerTypeMessageText, "/reordercategories", bot.MatchTypePrefix, b.handleReorderCategories,
+	)
+	b.registerHandler(bot.HandlerTypeMessageText, "/edit", bot.MatchTypePrefix, b.handleEdit)
+	b.registerHandler(bot.HandlerTypeMessageText, "/delete", bot.MatchTypePrefix, b.handleDelete)
+	b.registerHandler(bot.HandlerTypeMessageText, "/setcurrency", bot.MatchTypePrefix, b.handleSetCurrency)
+	b.registerHandler(bot.HandlerTypeMessageText, "/currency", bot.MatchTypePrefix, b.handleShowCurrency)
+	b.registerHandler(bot.HandlerTypeMessageText, "/settimezone", bot.MatchTypePrefix, b.handleSetTimezone)
+	b.registerHandler(bot.HandlerTypeMessageText, "/settings", bot.MatchTypePrefix, b.handleSettings)
+	b.registerHandler(bot.HandlerTypeMessageText, "/timezone", bot.MatchTypePrefix, b.handleShowTimezone)
+	b.registerHandler(bot.HandlerTypeMessageText, "/untag", bot.MatchTypePrefix, b.handleUntag)
+	b.registerHandler(bot.HandlerTypeMessageText, "/tagrange", bot.MatchTypePrefix, b.handleTagRange)
+	b.registerHandler(bot.HandlerTypeMessageText, "/tags", bot.MatchTypePrefix, b.handleTags)
+	b.registerHandler(bot.HandlerTypeMessageText, "/tag", bot.MatchTypePrefix, b.handleTag)
+	b.registerHandler(bot.HandlerTypeMessageText, "/approve", bot.MatchTypePrefix, b.handleApprove)
+	b.registerHandler(bot.HandlerTypeMessageText, "/revoke", bot.MatchTypePrefix, b.handleRevoke)
+	b.registerHandler(bot.HandlerTypeMessageText, "/users", bot.MatchTypePrefix, b.handleUsers)
+	b.registerHandler(bot.HandlerTypeMessageText, "/inspect", bot.MatchTypePrefix, b.handleInspect)
+	b.registerHandler(bot.HandlerTypeMessageText, "/ocrstats", bot.MatchTypePrefix, b.handleOCRStats)
+	b.registerHandler(bot.HandlerTypeMessageText, "/storagestats", bot.MatchTypePrefix, b.handleStorageStats)
+	// /storage comes after /storagestats, which it would otherwise match by
+	// prefix.
+	b.registerHandler(bot.HandlerTypeMessageText, "/storage", bot.MatchTypePrefix, b.handleStorageUsage)
+	b.registerHandler(bot.HandlerTypeMessageText, "/usagestats", bot.MatchTypePrefix, b.handleUsageStats)
+	b.registerHandler(bot.HandlerTypeMessageText, "/backfillrates", bot.MatchTypePrefix, b.handleBackfillRates)
+	b.registerHandler(bot.HandlerTypeMessageText, "/banktemplates", bot.MatchTypePrefix, b.handleBankTemplates)
+	b.registerHandler(bot.HandlerTypeMessageText, "/promptvariants", bot.MatchTypePrefix, b.handlePromptVariants)
+	b.registerHandler(bot.HandlerTypeMessageText, "/apitoken", bot.MatchTypePrefix, b.handleAPIToken)
+	b.registerHandler(bot.HandlerTypeMessageText, "/pin", bot.MatchTypePrefix, b.handlePIN)
+	if b.cfg.EnableDemoTools {
+		// Demo data commands only exist where they were switched on.
+		b.registerHandler(bot.HandlerTypeMessageText, "/seeddemo", bot.MatchTypePrefix, b.handleSeedDemo)
+		b.registerHandler(bot.HandlerTypeMessageText, "/wipedemo", bot.MatchTypePrefix, b.handleWipeDemo)
+	}
 
 	// Callback query handlers for receipt confirmation flow.
-	b.bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, "receipt_", bot.MatchTypePrefix, b.handleReceiptCallback)
-	b.bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, "edit_", bot.MatchTypePrefix, b.handleEditCallback)
-	b.bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, "set_category_", bot.MatchTypePrefix, b.handleSetCategoryCallback)
-	b.bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, "cancel_edit_", bot.MatchTypePrefix, b.handleCancelEditCallback)
-	b.bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, "create_category_", bot.MatchTypePrefix, b.handleCreateCategoryCallback)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, "receipt_", bot.MatchTypePrefix, b.handleReceiptCallback)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, receiptTagCallbackPrefix, bot.MatchTypePrefix, b.handleReceiptTagCallback,
+	)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, adjustAmountCallbackPrefix, bot.MatchTypePrefix, b.handleAdjustAmountCallback,
+	)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, "edit_", bot.MatchTypePrefix, b.handleEditCallback)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, "set_category_", bot.MatchTypePrefix, b.handleSetCategoryCallback)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, "cancel_edit_", bot.MatchTypePrefix, b.handleCancelEditCallback)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, "create_category_", bot.MatchTypePrefix, b.handleCreateCategoryCallback)
 
 	// Callback query handlers for inline expense actions.
-	b.bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, "edit_expense_", bot.MatchTypePrefix, b.handleExpenseActionCallback)
-	b.bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, "delete_expense_", bot.MatchTypePrefix, b.handleExpenseActionCallback)
-	b.bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, "confirm_delete_", bot.MatchTypePrefix, b.handleConfirmDeleteCallback)
-	b.bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, "back_to_expense_", bot.MatchTypePrefix, b.handleBackToExpenseCallback)
-	b.bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, "review_", bot.MatchTypePrefix, b.handleReviewCallback)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, "edit_expense_", bot.MatchTypePrefix, b.handleExpenseActionCallback)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, "delete_expense_", bot.MatchTypePrefix, b.handleExpenseActionCallback)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, "confirm_delete_", bot.MatchTypePrefix, b.handleConfirmDeleteCallback)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, "back_to_expense_", bot.MatchTypePrefix, b.handleBackToExpenseCallback)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, "review_", bot.MatchTypePrefix, b.handleReviewCallback)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, "list_", bot.MatchTypePrefix, b.handleListCallback)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, listSelectCallbackPrefix, bot.MatchTypePrefix,
+		b.handleListSelectCallback)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, "uncat_", bot.MatchTypePrefix, b.handleUncategorizedCallback)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, usersCallbackPrefix, bot.MatchTypePrefix, b.handleUsersCallback)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, describeSkipCallback, bot.MatchTypeExact, b.handleDescribeSkipCallback,
+	)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, manualReceiptCancelCallback, bot.MatchTypeExact,
+		b.handleManualReceiptCancelCallback,
+	)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, dedupeCallbackPrefix, bot.MatchTypePrefix, b.handleDedupeCallback)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, tagRangeCallbackPrefix, bot.MatchTypePrefix, b.handleTagRangeCallback,
+	)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, suggestedCategoryCallbackPrefix, bot.MatchTypePrefix,
+		b.handleSuggestedCategoryCallback,
+	)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, recategorizeCallbackPrefix, bot.MatchTypePrefix, b.handleRecategorizeCallback,
+	)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, aiReviewCallbackPrefix, bot.MatchTypePrefix, b.handleAIReviewCallback,
+	)
+	b.registerHandler(bot.HandlerTypeCallbackQueryData, periodCallbackPrefix, bot.MatchTypePrefix, b.handlePeriodCallback)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, reimbursedCallbackPrefix, bot.MatchTypePrefix, b.handleReimbursedCallback,
+	)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, merchantHistoryCallbackPrefix, bot.MatchTypePrefix,
+		b.handleMerchantHistoryCallback,
+	)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, tagSuggestCallbackPrefix, bot.MatchTypePrefix, b.handleTagSuggestCallback,
+	)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, budgetCallbackPrefix, bot.MatchTypePrefix, b.handleBudgetFallbackCallback,
+	)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, budgetSuggestCallbackPrefix, bot.MatchTypePrefix,
+		b.handleBudgetSuggestCallback,
+	)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, setTimezoneCallbackPrefix, bot.MatchTypePrefix,
+		b.handleSetTimezoneCallback,
+	)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, setCurrencyCallbackPrefix, bot.MatchTypePrefix,
+		b.handleSetCurrencyCallback,
+	)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, receiptOwnerCallbackPrefix, bot.MatchTypePrefix, b.handleReceiptOwnerCallback,
+	)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, newCurrencyCallbackPrefix, bot.MatchTypePrefix, b.handleNewCurrencyCallback,
+	)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, previewCallbackPrefix, bot.MatchTypePrefix, b.handlePreviewCallback,
+	)
+	b.registerHandler(
+		bot.HandlerTypeCallbackQueryData, categorySetCallbackPrefix, bot.MatchTypePrefix, b.handleCategorySetCallback,
+	)
 }
 
 // isAuthorized checks if a user is a superadmin or a DB-approved user.
@@ -437,6 +752,12 @@ func (b *Bot) isAuthorized(ctx context.Context, userID int64, username string) b
 // whitelistMiddleware checks chat allowlist and user authorization before processing.
 func (b *Bot) whitelistMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
 	return func(ctx context.Context, tgBot *bot.Bot, update *tgmodels.Update) {
+		// The bot's own membership changes carry no user to authorize.
+		if update.MyChatMember != nil {
+			b.handleMyChatMember(ctx, tgBot, update)
+			return
+		}
+
 		chatID := extractChatID(update)
 		if b.blockDisallowedChat(ctx, tgBot, chatID) {
 			return
@@ -451,15 +772,23 @@ func (b *Bot) whitelistMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
 		logUserAction(userID, username, update)
 
 		if b.blockUnauthorizedUser(ctx, tgBot, chatID, userID, username) {
+			if update.InlineQuery != nil && tgBot != nil {
+				answerInlineUnauthorized(ctx, tgBot, update.InlineQuery)
+			}
 			return
 		}
 
+		b.claimChat(ctx, chatID)
+
 		if err := b.ensureUserRegistered(ctx, update); err != nil {
 			logger.Log.Error().
 				Int64("user_id", userID).
 				Err(err).
 				Msg("Failed to register user")
+		} else {
+			b.touchLastSeen(ctx, userID)
 		}
+		b.cancelDescribeOnCommand(update)
 
 		next(ctx, tgBot, update)
 	}
@@ -551,6 +880,13 @@ func logUserAction(userID int64, username string, update *tgmodels.Update) {
 			Str("username", username).
 			Str("text", logger.SanitizeText(update.EditedMessage.Text)).
 			Msg("Edited message")
+
+	case update.InlineQuery != nil:
+		logger.Log.Debug().
+			Int64("user_id", userID).
+			Str("username", username).
+			Str("query", logger.SanitizeText(update.InlineQuery.Query)).
+			Msg("Inline query")
 	}
 }
 
@@ -565,6 +901,9 @@ func extractUsername(update *tgmodels.Update) string {
 	if update.EditedMessage != nil && update.EditedMessage.From != nil {
 		return update.EditedMessage.From.Username
 	}
+	if update.InlineQuery != nil && update.InlineQuery.From != nil {
+		return update.InlineQuery.From.Username
+	}
 	return ""
 }
 
@@ -611,6 +950,9 @@ func extractUserID(update *tgmodels.Update) int64 {
 	if update.EditedMessage != nil && update.EditedMessage.From != nil {
 		return update.EditedMessage.From.ID
 	}
+	if update.InlineQuery != nil && update.InlineQuery.From != nil {
+		return update.InlineQuery.From.ID
+	}
 	return 0
 }
 
@@ -630,7 +972,19 @@ func extractChatID(update *tgmodels.Update) int64 {
 
 // defaultHandler handles unrecognized messages, attempting free-text expense parsing.
 func (b *Bot) defaultHandler(ctx context.Context, tgBot *bot.Bot, update *tgmodels.Update) {
-	if update.Message == nil {
+	if update.EditedMessage != nil {
+		b.handleEditedMessage(ctx, tgBot, update)
+		return
+	}
+
+	if update.InlineQuery != nil {
+		b.handleInlineQueryCore(ctx, tgBot, update)
+		return
+	}
+
+	// Messages posted through inline mode, such as a shared total, are
+	// never expenses.
+	if update.Message == nil || update.Message.ViaBot != nil {
 		return
 	}
 
@@ -651,15 +1005,36 @@ func (b *Bot) defaultHandler(ctx context.Context, tgBot *bot.Bot, update *tgmode
 		return
 	}
 
+	if update.Message.Location != nil {
+		b.handleLocationCore(ctx, tgBot, update)
+		return
+	}
+
 	// Check for pending edit operations first.
 	if b.handlePendingEdit(ctx, tgBot, update) {
 		return
 	}
 
+	// A bare "?" asks for the quick totals and must never become an expense.
+	if b.handleQuickTotal(ctx, tgBot, update) {
+		return
+	}
+
+	if b.handleUnknownCommand(ctx, tgBot, update) {
+		return
+	}
+
 	if b.handleFreeTextExpense(ctx, tgBot, update) {
 		return
 	}
 
+	// A forward that is not an expense may be a bank notification. Forwards
+	// that match no bank template are ignored rather than answered.
+	if update.Message.ForwardOrigin != nil {
+		b.handleBankMessageCore(ctx, tgBot, update)
+		return
+	}
+
 	_, err := tgBot.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:    chatID,
 		Text:      "I didn't understand that. Use /help to see available commands, or send an expense like <code>5.50 Coffee</code>",
@@ -672,15 +1047,11 @@ func (b *Bot) defaultHandler(ctx context.Context, tgBot *bot.Bot, update *tgmode
 
 // downloadFile downloads a file from Telegram servers.
 func (b *Bot) downloadFile(ctx context.Context, tg TelegramAPI, fileID string) ([]byte, error) {
-	file, err := tg.GetFile(ctx, &bot.GetFileParams{
-		FileID: fileID,
-	})
+	downloadURL, err := b.fileDownloadURL(ctx, tg, fileID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get file info: %w", err)
+		return nil, err
 	}
 
-	downloadURL := tg.FileDownloadLink(file)
-
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create download request: %w", err)
@@ -697,6 +1068,8 @@ func (b *Bot) downloadFile(ctx context.Context, tg TelegramAPI, fileID string) (
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
+		// The link may have expired early; fetch a fresh one next time.
+		b.fileLinks.forget(fileID)
 		return nil, fmt.Errorf("download failed with status: %d", resp.StatusCode)
 	}
 
@@ -711,56 +1084,160 @@ func (b *Bot) downloadFile(ctx context.Context, tg TelegramAPI, fileID string) (
 	return data, nil
 }
 
+// fileDownloadURL returns the download link for a Telegram file, calling
+// getFile only when no unexpired link is cached.
+func (b *Bot) fileDownloadURL(ctx context.Context, tg TelegramAPI, fileID string) (string, error) {
+	downloadURL, hit, err := b.fileLinks.get(ctx, fileID, b.now(), func(ctx context.Context) (string, error) {
+		file, err := tg.GetFile(ctx, &bot.GetFileParams{FileID: fileID})
+		if err != nil {
+			return "", fmt.Errorf("failed to get file info: %w", err)
+		}
+		return tg.FileDownloadLink(file), nil
+	})
+	if b.metrics != nil {
+		counter := b.metrics.CacheMisses
+		if hit {
+			counter = b.metrics.CacheHits
+		}
+		counter.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("cache", "telegram_file_link")))
+	}
+	if err != nil {
+		return "", err
+	}
+	return downloadURL, nil
+}
+
 // getCategoriesWithCache returns categories from cache if valid, otherwise fetches from DB.
 func (b *Bot) getCategoriesWithCache(ctx context.Context) ([]models.Category, error) {
 	// Try reading from cache first.
-	b.categoryCacheMu.RLock()
-	if b.now().Before(b.categoryCacheExpiry) && b.categoryCache != nil {
-		categories := b.categoryCache
-		b.categoryCacheMu.RUnlock()
+	if categories, ok := b.cachedCategories(); ok {
 		logger.Log.Debug().Msg("Categories served from cache")
-		if b.metrics != nil {
-			b.metrics.CacheHits.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("cache", "categories")))
-		}
+		b.recordCategoryCacheHit(ctx)
 		return categories, nil
 	}
-	b.categoryCacheMu.RUnlock()
 
-	// Cache miss or expired, fetch from database.
-	b.categoryCacheMu.Lock()
-	defer b.categoryCacheMu.Unlock()
+	// Cache miss or expired. Only one load runs at a time; the others wait
+	// for it and are served from the cache it filled.
+	b.categoryLoadMu.Lock()
+	defer b.categoryLoadMu.Unlock()
 
-	// Double-check after acquiring write lock (another goroutine might have updated it).
-	if b.now().Before(b.categoryCacheExpiry) && b.categoryCache != nil {
+	if categories, ok := b.cachedCategories(); ok {
 		logger.Log.Debug().Msg("Categories served from cache after lock")
-		if b.metrics != nil {
-			b.metrics.CacheHits.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("cache", "categories")))
-		}
-		return b.categoryCache, nil
+		b.recordCategoryCacheHit(ctx)
+		return categories, nil
 	}
 
 	if b.metrics != nil {
 		b.metrics.CacheMisses.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("cache", "categories")))
 	}
 
+	b.categoryCacheMu.RLock()
+	gen := b.categoryCacheGen
+	b.categoryCacheMu.RUnlock()
+
 	categories, err := b.categoryRepo.GetAll(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch categories: %w", err)
 	}
 
-	// Update cache.
-	b.categoryCache = categories
-	b.categoryCacheExpiry = b.now().Add(CategoryCacheTTL)
+	if !b.storeCategories(gen, categories) {
+		// A category was written while loading. Prefer the cache, which has
+		// the write merged in, over what was read.
+		if cached, ok := b.cachedCategories(); ok {
+			return cached, nil
+		}
+		return categories, nil
+	}
 	logger.Log.Debug().Int("count", len(categories)).Msg("Categories cached")
 
 	return categories, nil
 }
 
+// cachedCategories returns the cached categories while they are fresh.
+func (b *Bot) cachedCategories() ([]models.Category, bool) {
+	b.categoryCacheMu.RLock()
+	defer b.categoryCacheMu.RUnlock()
+	if b.now().Before(b.categoryCacheExpiry) && b.categoryCache != nil {
+		return b.categoryCache, true
+	}
+	return nil, false
+}
+
+// recordCategoryCacheHit counts a category cache hit.
+func (b *Bot) recordCategoryCacheHit(ctx context.Context) {
+	if b.metrics != nil {
+		b.metrics.CacheHits.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("cache", "categories")))
+	}
+}
+
+// storeCategories caches categories loaded at generation gen. It reports
+// false, and keeps the cache, when a write happened since.
+func (b *Bot) storeCategories(gen uint64, categories []models.Category) bool {
+	b.categoryCacheMu.Lock()
+	defer b.categoryCacheMu.Unlock()
+	if gen != b.categoryCacheGen {
+		logger.Log.Debug().Msg("Discarded categories loaded before a write")
+		return false
+	}
+	b.categoryCache = categories
+	b.categoryCacheExpiry = b.now().Add(CategoryCacheTTL)
+	return true
+}
+
 // invalidateCategoryCache clears the category cache, forcing a refresh on next access.
 func (b *Bot) invalidateCategoryCache() {
 	b.categoryCacheMu.Lock()
 	defer b.categoryCacheMu.Unlock()
+	b.categoryCacheGen++
 	b.categoryCache = nil
 	b.categoryCacheExpiry = time.Time{}
 	logger.Log.Debug().Msg("Category cache invalidated")
 }
+
+// cacheCategory merges a created or updated category, as returned by the
+// database, into the cache, so it shows up without a reload.
+func (b *Bot) cacheCategory(category *models.Category) {
+	b.categoryCacheMu.Lock()
+	defer b.categoryCacheMu.Unlock()
+	b.categoryCacheGen++
+	if b.categoryCache == nil {
+		return
+	}
+	// Readers may still hold the old slice, so build a new one.
+	merged := make([]models.Category, 0, len(b.categoryCache)+1)
+	for i := range b.categoryCache {
+		if b.categoryCache[i].ID != category.ID {
+			merged = append(merged, b.categoryCache[i])
+		}
+	}
+	at := len(merged)
+	for i := range merged {
+		if categoryListedBefore(category, &merged[i]) {
+			at = i
+			break
+		}
+	}
+	b.categoryCache = slices.Insert(merged, at, *category)
+}
+
+// uncacheCategory drops a deleted category from the cache.
+func (b *Bot) uncacheCategory(id int) {
+	b.categoryCacheMu.Lock()
+	defer b.categoryCacheMu.Unlock()
+	b.categoryCacheGen++
+	if b.categoryCache == nil {
+		return
+	}
+	b.categoryCache = slices.DeleteFunc(slices.Clone(b.categoryCache), func(c models.Category) bool {
+		return c.ID == id
+	})
+}
+
+// categoryListedBefore reports whether a comes before c in GetAll order:
+// by sort order, then by name.
+func categoryListedBefore(a, c *models.Category) bool {
+	if a.SortOrder != c.SortOrder {
+		return a.SortOrder < c.SortOrder
+	}
+	return strings.ToLower(a.Name) < strings.ToLower(c.Name)
+}
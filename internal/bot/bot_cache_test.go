@@ -2,12 +2,15 @@ package bot
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
 )
 
 func TestGetCategoriesWithCache(t *testing.T) {
@@ -139,3 +142,125 @@ func TestInvalidateCategoryCache(t *testing.T) {
 		require.Len(t, categories2, len(categories1))
 	})
 }
+
+func TestCategoryCacheWrites(t *testing.T) {
+	t.Parallel()
+
+	newBot := func() *Bot {
+		return &Bot{
+			categoryCache: []models.Category{
+				{ID: 1, Name: "Food"},
+				{ID: 2, Name: "Transport"},
+			},
+			categoryCacheExpiry: time.Now().Add(time.Minute),
+		}
+	}
+	names := func(b *Bot) []string {
+		var out []string
+		for _, c := range b.categoryCache {
+			out = append(out, c.Name)
+		}
+		return out
+	}
+
+	t.Run("a create is merged in order", func(t *testing.T) {
+		t.Parallel()
+		b := newBot()
+		b.cacheCategory(&models.Category{ID: 3, Name: "Gifts"})
+		require.Equal(t, []string{"Food", "Gifts", "Transport"}, names(b))
+	})
+
+	t.Run("a rename replaces the row", func(t *testing.T) {
+		t.Parallel()
+		b := newBot()
+		b.cacheCategory(&models.Category{ID: 1, Name: "Zoo"})
+		require.Equal(t, []string{"Transport", "Zoo"}, names(b))
+	})
+
+	t.Run("a delete drops the row", func(t *testing.T) {
+		t.Parallel()
+		b := newBot()
+		held := b.categoryCache
+		b.uncacheCategory(1)
+		require.Equal(t, []string{"Transport"}, names(b))
+		require.Len(t, held, 2, "readers keep the slice they had")
+	})
+
+	t.Run("a load started before a write is not stored", func(t *testing.T) {
+		t.Parallel()
+		b := newBot()
+		gen := b.categoryCacheGen
+		b.cacheCategory(&models.Category{ID: 3, Name: "Gifts"})
+
+		stale := []models.Category{{ID: 1, Name: "Food"}, {ID: 2, Name: "Transport"}}
+		require.False(t, b.storeCategories(gen, stale))
+		require.Equal(t, []string{"Food", "Gifts", "Transport"}, names(b))
+
+		require.True(t, b.storeCategories(b.categoryCacheGen, stale))
+		require.Len(t, b.categoryCache, 2)
+	})
+
+	t.Run("writes to an empty cache leave it empty", func(t *testing.T) {
+		t.Parallel()
+		b := &Bot{}
+		b.cacheCategory(&models.Category{ID: 3, Name: "Gifts"})
+		b.uncacheCategory(3)
+		require.Nil(t, b.categoryCache)
+		require.Equal(t, uint64(2), b.categoryCacheGen)
+	})
+}
+
+func TestCategoryCache_ConcurrentCreates(t *testing.T) {
+	ctx := context.Background()
+	// Creates and loads run on several connections at once, which a single
+	// test transaction cannot do, so this uses the pool and cleans up.
+	pool := dbtest.TestPool(t)
+	b := setupTestBot(t, pool)
+
+	const userID = int64(427001)
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.WithoutCancel(ctx), `DELETE FROM categories WHERE owner_id = $1`, userID)
+	})
+
+	_, err := b.getCategoriesWithCache(ctx)
+	require.NoError(t, err)
+
+	const creates = 20
+	errs := make(chan error, creates*2)
+	var wg sync.WaitGroup
+	for i := range creates {
+		wg.Go(func() {
+			_, err := b.createUserCategory(ctx, userID, fmt.Sprintf("Stress 427 %02d", i))
+			errs <- err
+		})
+		wg.Go(func() {
+			if i%5 == 0 {
+				b.invalidateCategoryCache()
+			}
+			_, err := b.getCategoriesWithCache(ctx)
+			errs <- err
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	owned := func(categories []models.Category) []models.Category {
+		var out []models.Category
+		for _, c := range categories {
+			if c.OwnerID == userID {
+				out = append(out, c)
+			}
+		}
+		return out
+	}
+	cached, err := b.getCategoriesWithCache(ctx)
+	require.NoError(t, err)
+	stored, err := b.categoryRepo.GetAll(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, owned(stored), creates)
+	require.Equal(t, owned(stored), owned(cached))
+}
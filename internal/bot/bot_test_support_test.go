@@ -35,7 +35,7 @@ const (
 	testPhotoFileID           = "photo-file-id"
 	testProcessingVoiceText   = "Processing voice message"
 	testProcessingReceiptText = "Processing receipt"
-	testTodayExpensesText     = "Today's Expenses"
+	testPlaceholderMessageID  = 1000
 	testOriginalDescription   = "Original description"
 	testEditCommandPrefix     = "/edit "
 	testEditCommand           = "/edit"
@@ -89,6 +89,19 @@ func setupTestBot(t *testing.T, db database.PGXDB) *Bot {
 		expenseRepo:      repository.NewExpenseRepository(db),
 		tagRepo:          repository.NewTagRepository(db),
 		approvedUserRepo: repository.NewApprovedUserRepository(db),
+		receiptScanRepo:  repository.NewReceiptScanRepository(db),
+		expenseAuditRepo: repository.NewExpenseAuditRepository(db),
+		budgetRepo:       repository.NewBudgetRepository(db),
+		chatRepo:         repository.NewChatRepository(db),
+		splitRepo:        repository.NewSplitRepository(db),
+		bankTemplateRepo: repository.NewBankTemplateRepository(db),
+		variantRepo:      repository.NewPromptVariantRepository(db),
+		receiptFileRepo:  repository.NewReceiptFileRepository(db),
+		geminiUsageRepo:  repository.NewGeminiUsageRepository(db),
+		reviewRepo:       repository.NewMonthlyReviewRepository(db),
+		importBatchRepo:  repository.NewImportBatchRepository(db),
+		sheetExportRepo:  repository.NewSheetExportRepository(db),
+		outboxRepo:       repository.NewOutboxRepository(db),
 		geminiClient:     nil, // No Gemini client for cache tests
 		exchangeService:  &testExchangeService{},
 		messageSender:    nil, // Tests that need it will inject a mock
@@ -0,0 +1,207 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+// budgetConverter converts spending into the currency of a budget. Each
+// live rate is fetched at most once, so one is used per rendered message.
+type budgetConverter struct {
+	b     *Bot
+	rates map[string]decimal.NullDecimal
+}
+
+func (b *Bot) newBudgetConverter() *budgetConverter {
+	return &budgetConverter{b: b, rates: make(map[string]decimal.NullDecimal)}
+}
+
+// rate returns today's rate from one currency into another. It is invalid
+// when there is no exchange service or the lookup failed.
+func (c *budgetConverter) rate(ctx context.Context, from, to string) decimal.NullDecimal {
+	key := from + "/" + to
+	if rate, ok := c.rates[key]; ok {
+		return rate
+	}
+
+	var rate decimal.NullDecimal
+	if c.b.exchangeService != nil {
+		result, err := c.b.exchangeService.Convert(ctx, decimal.NewFromInt(1), from, to)
+		if err != nil {
+			logger.Log.Warn().
+				Err(err).
+				Str("source_currency", from).
+				Str("target_currency", to).
+				Msg("Live rate unavailable for budget; leaving spending out")
+		} else {
+			rate = decimal.NewNullDecimal(result.Rate)
+		}
+	}
+	c.rates[key] = rate
+	return rate
+}
+
+// convert returns total in currency: as it is when already in currency,
+// else the conversion stored with the expenses, else at today's rate. It
+// reports false when no rate is available.
+func (c *budgetConverter) convert(
+	ctx context.Context,
+	total *repository.CategoryCurrencyTotal,
+	currency string,
+) (decimal.Decimal, bool) {
+	switch {
+	case total.Currency == currency:
+		return total.Amount, true
+	case total.ConvertedCurrency == currency:
+		return total.ConvertedAmount, true
+	}
+	rate := c.rate(ctx, total.Currency, currency)
+	if !rate.Valid {
+		return decimal.Zero, false
+	}
+	return total.Amount.Mul(rate.Decimal).Round(appmodels.CurrencyMinorUnits(currency)), true
+}
+
+// convertExpense returns the amount of expense in currency, reporting false
+// when no rate is available.
+func (c *budgetConverter) convertExpense(
+	ctx context.Context,
+	expense *appmodels.Expense,
+	currency string,
+) (decimal.Decimal, bool) {
+	total := repository.CategoryCurrencyTotal{Currency: expense.Currency, Amount: expense.Amount}
+	if expense.ConvertedAmount.Valid {
+		total.ConvertedCurrency = expense.ConvertedCurrency
+		total.ConvertedAmount = expense.ConvertedAmount.Decimal
+	}
+	return c.convert(ctx, &total, currency)
+}
+
+// spending sums the totals of a category in currency. Currencies without a
+// rate are left out and returned, so the caller can say so.
+func (c *budgetConverter) spending(
+	ctx context.Context,
+	totals []repository.CategoryCurrencyTotal,
+	categoryID int,
+	currency string,
+) (decimal.Decimal, []string) {
+	spent := decimal.Zero
+	var unconverted []string
+	for i := range totals {
+		if totals[i].CategoryID != categoryID {
+			continue
+		}
+		amount, ok := c.convert(ctx, &totals[i], currency)
+		if !ok {
+			if !slices.Contains(unconverted, totals[i].Currency) {
+				unconverted = append(unconverted, totals[i].Currency)
+			}
+			continue
+		}
+		spent = spent.Add(amount)
+	}
+	return spent, unconverted
+}
+
+// budgetCurrency returns the currency of budget. Budgets always carry one;
+// the fallback only covers rows the currency migration has not reached.
+func budgetCurrency(budget *repository.Budget, defaultCurrency string) string {
+	if budget.Currency == "" {
+		return defaultCurrency
+	}
+	return budget.Currency
+}
+
+// convertBudgets moves the user's budgets that are not in their default
+// currency into it at today's rate. name limits it to one category.
+func (b *Bot) convertBudgets(ctx context.Context, userID int64, name string) string {
+	budgets, err := b.budgetRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to list budgets for conversion")
+		return budgetFailedMsg
+	}
+	if name != "" {
+		category, msg := b.budgetCategory(ctx, userID, name)
+		if category == nil {
+			return msg
+		}
+		budgets = slices.DeleteFunc(budgets, func(budget repository.Budget) bool {
+			return budget.CategoryID != category.ID
+		})
+		if len(budgets) == 0 {
+			return fmt.Sprintf("<b>%s</b> has no budget.", botfmt.EscapeHTML(category.Name))
+		}
+	}
+
+	currency := b.getUserDefaultCurrency(ctx, userID)
+	converter := b.newBudgetConverter()
+	var converted, failed []string
+	for i := range budgets {
+		budget := &budgets[i]
+		from := budgetCurrency(budget, currency)
+		if from == currency {
+			continue
+		}
+		label := botfmt.EscapeHTML(budget.CategoryName)
+		rate := converter.rate(ctx, from, currency)
+		if !rate.Valid {
+			failed = append(failed, label)
+			continue
+		}
+		amount := budget.Amount.Mul(rate.Decimal).Round(appmodels.CurrencyMinorUnits(currency))
+		if err := b.budgetRepo.Set(ctx, userID, budget.CategoryID, amount, currency, budget.Enforce); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to convert budget")
+			failed = append(failed, label)
+			continue
+		}
+		converted = append(converted, fmt.Sprintf("• %s: %s → %s",
+			label, botfmt.Money(budget.Amount, from), botfmt.Money(amount, currency)))
+	}
+
+	if len(converted) == 0 && len(failed) == 0 {
+		return fmt.Sprintf("All your budgets are already in %s.", currency)
+	}
+	var sb strings.Builder
+	if len(converted) > 0 {
+		fmt.Fprintf(&sb, "💱 <b>Budgets converted to %s</b> at today's rate\n\n%s",
+			currency, strings.Join(converted, "\n"))
+	}
+	if len(failed) > 0 {
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "❌ No exchange rate right now for: %s. Please try again later.", strings.Join(failed, ", "))
+	}
+	return sb.String()
+}
+
+// budgetCurrencyNote tells a user who just changed their default currency
+// that budgets in other currencies keep theirs. It returns "" when there
+// are none or the budgets cannot be loaded.
+func (b *Bot) budgetCurrencyNote(ctx context.Context, userID int64, currency string) string {
+	budgets, err := b.budgetRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to list budgets for currency change")
+		return ""
+	}
+	var kept []string
+	for i := range budgets {
+		if from := budgetCurrency(&budgets[i], currency); from != currency {
+			kept = append(kept, fmt.Sprintf("• %s: %s",
+				botfmt.EscapeHTML(budgets[i].CategoryName), botfmt.Money(budgets[i].Amount, from)))
+		}
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n\n💰 These budgets keep their currency:\n%s\n\n"+
+		"Use <code>/budget convert</code> to move them to %s at today's rate.", strings.Join(kept, "\n"), currency)
+}
@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+// staleCallbackReason explains why an inline button no longer applies.
+type staleCallbackReason int
+
+const (
+	staleExpenseGone staleCallbackReason = iota
+	staleExpenseConfirmed
+	staleNotOwner
+	staleLookupFailed
+)
+
+const (
+	staleExpenseGoneText      = "This expense no longer exists. It may have expired or been deleted."
+	staleExpenseConfirmedText = "This receipt was already confirmed. Use the buttons on the expense to change it."
+	staleNotOwnerText         = "You can only modify your own expenses."
+	staleLookupFailedText     = "Something went wrong while loading this expense. Please try again later."
+)
+
+// text returns the toast shown for the reason.
+func (r staleCallbackReason) text() string {
+	switch r {
+	case staleExpenseGone:
+		return staleExpenseGoneText
+	case staleExpenseConfirmed:
+		return staleExpenseConfirmedText
+	case staleNotOwner:
+		return staleNotOwnerText
+	case staleLookupFailed:
+		return staleLookupFailedText
+	}
+	return staleLookupFailedText
+}
+
+// removesKeyboard reports whether the buttons on the old message are dead
+// for good. Not-owner taps keep them because they still work for the owner,
+// and lookup failures keep them so the user can retry.
+func (r staleCallbackReason) removesKeyboard() bool {
+	return r == staleExpenseGone || r == staleExpenseConfirmed
+}
+
+// respondStaleCallback answers a callback whose buttons no longer match the
+// expense. The reason is shown as a toast, or as an alert for destructive
+// actions. The message text is left as is so the original card survives;
+// only the dead keyboard is removed.
+func respondStaleCallback(
+	ctx context.Context,
+	tg TelegramAPI,
+	query *models.CallbackQuery,
+	reason staleCallbackReason,
+	destructive bool,
+) {
+	_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: query.ID,
+		Text:            reason.text(),
+		ShowAlert:       destructive,
+	})
+
+	if !reason.removesKeyboard() || query.Message.Message == nil {
+		return
+	}
+
+	_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+		ChatID:    query.Message.Message.Chat.ID,
+		MessageID: query.Message.Message.ID,
+	})
+}
+
+// loadCallbackExpense loads the expense referenced by a callback and checks
+// that the caller owns it. On failure the callback is answered through
+// respondStaleCallback and false is returned. On success the callback is
+// left unanswered for the caller.
+func (b *Bot) loadCallbackExpense(
+	ctx context.Context,
+	tg TelegramAPI,
+	query *models.CallbackQuery,
+	expenseID int,
+	destructive bool,
+) (*appmodels.Expense, bool) {
+	expense, err := b.expenseRepo.GetByID(ctx, expenseID)
+	if err != nil {
+		reason := staleExpenseGone
+		if !errors.Is(err, pgx.ErrNoRows) {
+			reason = staleLookupFailed
+			logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expenseID).Msg("Failed to load callback expense")
+		}
+		respondStaleCallback(ctx, tg, query, reason, destructive)
+		return nil, false
+	}
+
+	if expense.UserID != query.From.ID {
+		logger.Log.Warn().
+			Str(logFieldUserHashCB, logger.HashUserID(query.From.ID)).
+			Int(logFieldExpenseIDCB, expenseID).
+			Msg(userMismatchMsgCB)
+		respondStaleCallback(ctx, tg, query, staleNotOwner, true)
+		return nil, false
+	}
+
+	return expense, true
+}
+
+// answerCallback acknowledges a callback without a toast.
+func answerCallback(ctx context.Context, tg TelegramAPI, query *models.CallbackQuery) {
+	_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: query.ID,
+	})
+}
@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestStaleCallbackReason(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		reason         staleCallbackReason
+		wantText       string
+		removeKeyboard bool
+	}{
+		{name: "gone", reason: staleExpenseGone, wantText: staleExpenseGoneText, removeKeyboard: true},
+		{name: "confirmed", reason: staleExpenseConfirmed, wantText: staleExpenseConfirmedText, removeKeyboard: true},
+		{name: "not owner", reason: staleNotOwner, wantText: staleNotOwnerText, removeKeyboard: false},
+		{name: "lookup failed", reason: staleLookupFailed, wantText: staleLookupFailedText, removeKeyboard: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.wantText, tt.reason.text())
+			require.Equal(t, tt.removeKeyboard, tt.reason.removesKeyboard())
+		})
+	}
+}
+
+func TestStaleCallbacks(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	ownerID := int64(500362)
+	otherID := int64(500363)
+	const chatID, messageID = int64(500362), 42
+
+	for _, id := range []int64{ownerID, otherID} {
+		err := b.userRepo.UpsertUser(ctx, &appmodels.User{ID: id, FirstName: "Stale"})
+		require.NoError(t, err)
+	}
+
+	createExpense := func(status appmodels.ExpenseStatus) *appmodels.Expense {
+		expense := &appmodels.Expense{
+			UserID:      ownerID,
+			Amount:      mustParseDecimal("12.50"),
+			Currency:    testCurrencySGD,
+			Description: "Stale receipt",
+			Status:      status,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		return expense
+	}
+
+	t.Run("deleted draft answers with toast and removes keyboard", func(t *testing.T) {
+		expense := createExpense(appmodels.ExpenseStatusDraft)
+		require.NoError(t, b.expenseRepo.Delete(ctx, expense.ID))
+
+		mockBot := mocks.NewMockBot()
+		update := mocks.CallbackQueryUpdate(chatID, ownerID, messageID, fmt.Sprintf("receipt_confirm_%d", expense.ID))
+		b.handleReceiptCallbackCore(ctx, mockBot, update)
+
+		require.Len(t, mockBot.AnsweredCallbacks, 1)
+		require.Equal(t, staleExpenseGoneText, mockBot.AnsweredCallbacks[0].Text)
+		require.False(t, mockBot.AnsweredCallbacks[0].ShowAlert)
+		require.Len(t, mockBot.EditedReplyMarkups, 1)
+		require.Equal(t, messageID, mockBot.EditedReplyMarkups[0].MessageID)
+		require.Nil(t, mockBot.EditedReplyMarkups[0].ReplyMarkup)
+		require.Zero(t, mockBot.EditedMessageCount())
+	})
+
+	t.Run("already confirmed receipt is not cancelled", func(t *testing.T) {
+		expense := createExpense(appmodels.ExpenseStatusConfirmed)
+
+		for _, action := range []string{"confirm", "cancel"} {
+			mockBot := mocks.NewMockBot()
+			update := mocks.CallbackQueryUpdate(chatID, ownerID, messageID, fmt.Sprintf("receipt_%s_%d", action, expense.ID))
+			b.handleReceiptCallbackCore(ctx, mockBot, update)
+
+			require.Len(t, mockBot.AnsweredCallbacks, 1)
+			require.Equal(t, staleExpenseConfirmedText, mockBot.AnsweredCallbacks[0].Text)
+			require.Equal(t, action == "cancel", mockBot.AnsweredCallbacks[0].ShowAlert)
+			require.Len(t, mockBot.EditedReplyMarkups, 1)
+			require.Zero(t, mockBot.EditedMessageCount())
+		}
+
+		stored, err := b.expenseRepo.GetByID(ctx, expense.ID)
+		require.NoError(t, err)
+		require.Equal(t, appmodels.ExpenseStatusConfirmed, stored.Status)
+	})
+
+	t.Run("not owner gets alert and keyboard is kept", func(t *testing.T) {
+		expense := createExpense(appmodels.ExpenseStatusConfirmed)
+
+		mockBot := mocks.NewMockBot()
+		update := mocks.CallbackQueryUpdate(chatID, otherID, messageID, fmt.Sprintf("delete_expense_%d", expense.ID))
+		b.handleExpenseActionCallbackCore(ctx, mockBot, update)
+
+		require.Len(t, mockBot.AnsweredCallbacks, 1)
+		require.Equal(t, staleNotOwnerText, mockBot.AnsweredCallbacks[0].Text)
+		require.True(t, mockBot.AnsweredCallbacks[0].ShowAlert)
+		require.Empty(t, mockBot.EditedReplyMarkups)
+		require.Zero(t, mockBot.EditedMessageCount())
+
+		_, err := b.expenseRepo.GetByID(ctx, expense.ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("set category on deleted expense is retired", func(t *testing.T) {
+		expense := createExpense(appmodels.ExpenseStatusDraft)
+		require.NoError(t, b.expenseRepo.Delete(ctx, expense.ID))
+
+		mockBot := mocks.NewMockBot()
+		update := mocks.CallbackQueryUpdate(chatID, ownerID, messageID, fmt.Sprintf("set_category_%d_1", expense.ID))
+		b.handleSetCategoryCallbackCore(ctx, mockBot, update)
+
+		require.Len(t, mockBot.AnsweredCallbacks, 1)
+		require.Equal(t, staleExpenseGoneText, mockBot.AnsweredCallbacks[0].Text)
+		require.Len(t, mockBot.EditedReplyMarkups, 1)
+		require.Zero(t, mockBot.EditedMessageCount())
+	})
+}
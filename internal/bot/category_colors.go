@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/go-analyze/charts"
+)
+
+// minChartContrast is the lowest contrast ratio against the chart
+// background a category color may have, the WCAG minimum for graphics.
+const minChartContrast = 3.0
+
+// uncategorizedChartColor is the neutral gray of uncategorized expenses.
+const uncategorizedChartColor = "#757575"
+
+var (
+	// chartBackground is the background of the light chart theme.
+	chartBackground = charts.ColorWhite
+
+	// categoryPalette holds the colors derived from category names. Each
+	// has at least minChartContrast against chartBackground and none is
+	// gray, which is kept for uncategorized expenses.
+	categoryPalette = []string{
+		"#1F77B4", // blue
+		"#D62728", // red
+		"#2E7D32", // green
+		"#9467BD", // purple
+		"#8C564B", // brown
+		"#E65100", // orange
+		"#00838F", // teal
+		"#C2185B", // pink
+		"#3949AB", // indigo
+		"#827717", // olive
+		"#6A1B9A", // violet
+		"#00695C", // pine
+	}
+
+	hexColorPattern = regexp.MustCompile(`^#?[0-9A-Fa-f]{6}$`)
+
+	errInvalidHexColor = errors.New("use a hex color like #1F77B4")
+)
+
+// categoryColor returns the chart color of a category: explicit when one was
+// set with /setcategorycolor, otherwise a palette color picked by a hash of
+// the name, so a category keeps its color across charts and runs.
+func categoryColor(name, explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if name == categoryUncategorized {
+		return uncategorizedChartColor
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strings.ToLower(name)))
+	return categoryPalette[h.Sum32()%uint32(len(categoryPalette))]
+}
+
+// parseCategoryColor validates a /setcategorycolor color and returns it as
+// #RRGGBB. Colors too light to see on the chart background are refused.
+func parseCategoryColor(s string) (string, error) {
+	if !hexColorPattern.MatchString(s) {
+		return "", errInvalidHexColor
+	}
+	hex := "#" + strings.ToUpper(strings.TrimPrefix(s, "#"))
+	if ratio := contrastRatio(charts.ColorFromHex(hex), chartBackground); ratio < minChartContrast {
+		return "", fmt.Errorf("%s is too light to see on the white chart background (contrast %.1f:1, needs %.0f:1)",
+			hex, ratio, minChartContrast)
+	}
+	return hex, nil
+}
+
+// relativeLuminance is the WCAG relative luminance of c.
+func relativeLuminance(c charts.Color) float64 {
+	channel := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(c.R) + 0.7152*channel(c.G) + 0.0722*channel(c.B)
+}
+
+// contrastRatio is the WCAG contrast ratio of two colors, from 1 to 21.
+func contrastRatio(a, b charts.Color) float64 {
+	la, lb := relativeLuminance(a), relativeLuminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// colorSquare returns the colored square emoji closest to a #RRGGBB color,
+// by hue, or black or white for grays.
+func colorSquare(hex string) string {
+	c := charts.ColorFromHex(hex)
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	maxC, minC := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	chroma := maxC - minC
+	if chroma < 0.15 {
+		if (maxC+minC)/2 < 0.6 {
+			return "⬛"
+		}
+		return "⬜"
+	}
+
+	var hue float64
+	switch maxC {
+	case r:
+		hue = math.Mod((g-b)/chroma+6, 6)
+	case g:
+		hue = (b-r)/chroma + 2
+	default:
+		hue = (r-g)/chroma + 4
+	}
+	hue *= 60
+
+	// Muted reds and oranges read as brown.
+	switch {
+	case (hue < 45 || hue >= 345) && chroma < 0.4:
+		return "🟫"
+	case hue < 15 || hue >= 330:
+		return "🟥"
+	case hue < 45:
+		return "🟧"
+	case hue < 70:
+		return "🟨"
+	case hue < 170:
+		return "🟩"
+	case hue < 250:
+		return "🟦"
+	default:
+		return "🟪"
+	}
+}
@@ -0,0 +1,175 @@
+package bot
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/go-analyze/charts"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestCategoryColor(t *testing.T) {
+	t.Parallel()
+
+	t.Run("stable per name", func(t *testing.T) {
+		t.Parallel()
+		// Pinned so a palette or hash change that recolors every chart is
+		// noticed.
+		require.Equal(t, "#C2185B", categoryColor("Transportation", ""))
+		require.Equal(t, categoryColor("Transportation", ""), categoryColor("transportation", ""))
+		for _, name := range []string{"Transportation", "Food - Dining Out", "Utilities", "Entertainment"} {
+			color := categoryColor(name, "")
+			require.Contains(t, categoryPalette, color, name)
+			require.Equal(t, color, categoryColor(name, ""), name)
+		}
+		require.Equal(t, uncategorizedChartColor, categoryColor(categoryUncategorized, ""))
+	})
+
+	t.Run("explicit color wins", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, "#123456", categoryColor("Transportation", "#123456"))
+		require.Equal(t, "#123456", categoryColor(categoryUncategorized, "#123456"))
+	})
+
+	t.Run("palette is readable on the chart background", func(t *testing.T) {
+		t.Parallel()
+		for _, color := range append(slices.Clone(categoryPalette), uncategorizedChartColor) {
+			require.GreaterOrEqual(t, contrastRatio(charts.ColorFromHex(color), chartBackground), minChartContrast, color)
+		}
+	})
+}
+
+func TestParseCategoryColor(t *testing.T) {
+	t.Parallel()
+
+	for input, want := range map[string]string{
+		"#1f77b4": "#1F77B4",
+		"1F77B4":  "#1F77B4",
+		"#000000": "#000000",
+	} {
+		got, err := parseCategoryColor(input)
+		require.NoError(t, err, input)
+		require.Equal(t, want, got)
+	}
+
+	for _, input := range []string{"", "#1F77B", "#1F77B45", "blue", "#GGGGGG", "##1F77B4"} {
+		_, err := parseCategoryColor(input)
+		require.ErrorIs(t, err, errInvalidHexColor, input)
+	}
+
+	_, err := parseCategoryColor("#FFFF99")
+	require.ErrorContains(t, err, "too light")
+}
+
+func TestColorSquare(t *testing.T) {
+	t.Parallel()
+
+	for hex, want := range map[string]string{
+		"#D62728": "🟥",
+		"#E65100": "🟧",
+		"#8C564B": "🟫",
+		"#FFD600": "🟨",
+		"#2E7D32": "🟩",
+		"#1F77B4": "🟦",
+		"#9467BD": "🟪",
+		"#757575": "⬛",
+		"#EEEEEE": "⬜",
+	} {
+		require.Equal(t, want, colorSquare(hex), hex)
+	}
+}
+
+func TestGenerateExpenseChartStableColors(t *testing.T) {
+	t.Parallel()
+
+	expenses := []appmodels.Expense{
+		{Amount: decimal.NewFromInt(30), Category: &appmodels.Category{Name: "Transportation"}},
+		{Amount: decimal.NewFromInt(20), Category: &appmodels.Category{Name: "Utilities"}},
+		{Amount: decimal.NewFromInt(20), Category: &appmodels.Category{Name: "Entertainment"}},
+	}
+	reversed := slices.Clone(expenses)
+	slices.Reverse(reversed)
+
+	first, err := GenerateExpenseChart(expenses, "Week", nil)
+	require.NoError(t, err)
+	second, err := GenerateExpenseChart(reversed, "Week", nil)
+	require.NoError(t, err)
+	require.Equal(t, first, second, "same categories render the same chart in any order")
+
+	recolored, err := GenerateExpenseChart(expenses, "Week", map[string]string{"Utilities": "#000000"})
+	require.NoError(t, err)
+	require.NotEqual(t, first, recolored)
+}
+
+func TestHandleSetCategoryColorCore_NoSender(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{}
+	mockBot := mocks.NewMockBot()
+	update := mocks.CommandUpdate(100, 100, "/setcategorycolor Food #ff0000")
+	update.Message.From = nil
+	b.handleSetCategoryColorCore(context.Background(), mockBot, update)
+	require.Zero(t, mockBot.SentMessageCount())
+}
+
+func TestSetCategoryColor(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(424001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Colors"}))
+	_, err := b.categoryRepo.CreateOwned(ctx, userID, "Commute 424")
+	require.NoError(t, err)
+	b.invalidateCategoryCache()
+
+	run := func(text string) string {
+		t.Helper()
+		mockBot := mocks.NewMockBot()
+		b.handleSetCategoryColorCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, text))
+		return mockBot.LastSentMessage().Text
+	}
+
+	require.Contains(t, run("/setcategorycolor"), "Usage:")
+	require.Contains(t, run("/setcategorycolor Commute 424 blue"), "use a hex color")
+	require.Contains(t, run("/setcategorycolor Commute 424 #FFFFFF"), "too light")
+	require.Contains(t, run("/setcategorycolor Nowhere #1F77B4"), "not found")
+
+	require.Contains(t, run("/setcategorycolor Commute 424 #1f77b4"), "now drawn in 🟦 #1F77B4")
+	require.Equal(t, "#1F77B4", b.categoryColors(ctx)["Commute 424"])
+
+	mockBot := mocks.NewMockBot()
+	b.handleCategoriesCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/categories"))
+	require.Contains(t, mockBot.LastSentMessage().Text, "🟦 Commute 424")
+
+	require.Contains(t, run(`/setcategorycolor "Commute 424" none`), "back to its default chart color")
+	require.NotContains(t, b.categoryColors(ctx), "Commute 424")
+
+	_, err = b.categoryRepo.Create(ctx, "Shared Commute 424")
+	require.NoError(t, err)
+	b.invalidateCategoryCache()
+	require.Contains(t, run("/setcategorycolor Shared Commute 424 #1F77B4"), "is a shared category")
+	require.NotContains(t, b.categoryColors(ctx), "Shared Commute 424")
+}
+
+func TestParseSetCategoryColorArgs(t *testing.T) {
+	t.Parallel()
+
+	name, color, ok := parseSetCategoryColorArgs(`"Food - Dining Out" #D62728`)
+	require.True(t, ok)
+	require.Equal(t, "Food - Dining Out", name)
+	require.Equal(t, "#D62728", color)
+
+	name, _, ok = parseSetCategoryColorArgs("Transportation none")
+	require.True(t, ok)
+	require.Equal(t, "Transportation", name)
+
+	for _, args := range []string{"", "#D62728", `"" #D62728`} {
+		_, _, ok := parseSetCategoryColorArgs(args)
+		require.False(t, ok, args)
+	}
+}
@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"fmt"
+
+	"github.com/go-telegram/bot/models"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+// Confirmation annotation for AI categories in the uncertain band.
+const (
+	aiUncertainNote       = "🤖 auto-categorized (medium confidence)"
+	aiUncertainButtonText = "🔁 Change"
+	aiUncertainButtonFmt  = "edit_category_%d"
+)
+
+// aiUncertainBand returns the configured confidence band in which applied
+// AI categories are flagged, or nil when flagging is off.
+func (b *Bot) aiUncertainBand() *repository.ConfidenceBand {
+	if b.cfg == nil || b.cfg.AIUncertainMin >= b.cfg.AIUncertainMax {
+		return nil
+	}
+	return &repository.ConfidenceBand{Min: b.cfg.AIUncertainMin, Max: b.cfg.AIUncertainMax}
+}
+
+// inConfidenceBand reports whether confidence lies in [band.Min, band.Max).
+func inConfidenceBand(confidence float64, band *repository.ConfidenceBand) bool {
+	return band != nil && confidence >= band.Min && confidence < band.Max
+}
+
+// uncertainAICategory reports whether expense's category was picked by
+// Gemini with a confidence in the uncertain band.
+func (b *Bot) uncertainAICategory(expense *appmodels.Expense) bool {
+	return expense.AICategorized && expense.AIConfidence != nil &&
+		inConfidenceBand(*expense.AIConfidence, b.aiUncertainBand())
+}
+
+// addChangeCategoryButton appends the button that opens the category
+// keyboard for an uncertain AI category.
+func addChangeCategoryButton(keyboard *models.InlineKeyboardMarkup, expenseID int) {
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []models.InlineKeyboardButton{
+		{Text: aiUncertainButtonText, CallbackData: fmt.Sprintf(aiUncertainButtonFmt, expenseID)},
+	})
+}
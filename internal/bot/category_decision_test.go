@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+func TestInConfidenceBand(t *testing.T) {
+	t.Parallel()
+
+	band := &repository.ConfidenceBand{Min: 0.5, Max: 0.7}
+	tests := []struct {
+		confidence float64
+		want       bool
+	}{
+		{confidence: 0.49, want: false},
+		{confidence: 0.5, want: true},
+		{confidence: 0.69, want: true},
+		{confidence: 0.7, want: false},
+		{confidence: 0.95, want: false},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, inConfidenceBand(tt.confidence, band), "confidence %.2f", tt.confidence)
+	}
+	require.False(t, inConfidenceBand(0.6, nil))
+}
+
+func TestAIUncertainBand(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, (&Bot{}).aiUncertainBand())
+	require.Nil(t, (&Bot{cfg: &config.Config{AIUncertainMin: 0.6, AIUncertainMax: 0.6}}).aiUncertainBand())
+	require.Equal(t, &repository.ConfidenceBand{Min: 0.5, Max: 0.7},
+		(&Bot{cfg: &config.Config{AIUncertainMin: 0.5, AIUncertainMax: 0.7}}).aiUncertainBand())
+}
+
+func TestSendExpenseAdded_UncertainAICategory(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{cfg: &config.Config{AIUncertainMin: 0.5, AIUncertainMax: 0.7}}
+	send := func(expense *appmodels.Expense) *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		// Inline tags skip the tag suggestion lookup.
+		b.sendExpenseAdded(context.Background(), mockBot, 1, expense, &ParsedExpense{Tags: []string{"x"}})
+		return mockBot
+	}
+	aiExpense := func(confidence float64) *appmodels.Expense {
+		return &appmodels.Expense{
+			ID:            42,
+			Amount:        mustParseDecimal("5.00"),
+			Currency:      currencyCodeSGD,
+			Category:      &appmodels.Category{ID: 1, Name: "Food"},
+			AICategorized: true,
+			AIConfidence:  &confidence,
+		}
+	}
+
+	t.Run("medium confidence is flagged with a change button", func(t *testing.T) {
+		t.Parallel()
+		msg := send(aiExpense(0.6)).LastSentMessage()
+		require.Contains(t, msg.Text, aiUncertainNote)
+		keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+		last := keyboard.InlineKeyboard[len(keyboard.InlineKeyboard)-1]
+		require.Equal(t, aiUncertainButtonText, last[0].Text)
+		require.Equal(t, "edit_category_42", last[0].CallbackData)
+	})
+
+	t.Run("high confidence stays unannotated", func(t *testing.T) {
+		t.Parallel()
+		msg := send(aiExpense(0.85)).LastSentMessage()
+		require.NotContains(t, msg.Text, aiUncertainNote)
+		require.Len(t, requireInlineKeyboard(t, msg.ReplyMarkup).InlineKeyboard,
+			len(buildExpenseReflectionKeyboard(42).InlineKeyboard))
+	})
+
+	t.Run("categories not picked by AI stay unannotated", func(t *testing.T) {
+		t.Parallel()
+		expense := aiExpense(0.6)
+		expense.AICategorized = false
+		require.NotContains(t, send(expense).LastSentMessage().Text, aiUncertainNote)
+	})
+}
+
+func TestUncertainAICategoryFlow(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.cfg.AIUncertainMin = 0.5
+	b.cfg.AIUncertainMax = 0.7
+
+	userID := int64(382001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Unsure"}))
+
+	suggest := func(confidence float64) {
+		b.geminiClient = gemini.NewClientWithGenerator(&botTestGenerator{
+			response: makeBotCategorySuggestionResponse(fmt.Sprintf(
+				`{"category":"Others","confidence":%.2f,"reasoning":"guess","matched":true,"new_category_name":""}`,
+				confidence)),
+		})
+	}
+	add := func(text string) *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		b.handleAddCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, text))
+		return mockBot
+	}
+
+	suggest(0.6)
+	msg := add("/add 8 Mystery gadget").LastSentMessage()
+	require.Contains(t, msg.Text, aiUncertainNote)
+
+	suggest(0.9)
+	msg = add("/add 9 Obvious thing").LastSentMessage()
+	require.NotContains(t, msg.Text, aiUncertainNote)
+
+	mockBot := mocks.NewMockBot()
+	b.handleUncategorizedCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/uncategorized"))
+	require.Equal(t, uncategorizedNoneMsg, mockBot.LastSentMessage().Text)
+
+	mockBot = mocks.NewMockBot()
+	b.handleUncategorizedCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/uncategorized --uncertain"))
+	text := mockBot.LastSentMessage().Text
+	require.Contains(t, text, "Mystery gadget")
+	require.NotContains(t, text, "Obvious thing")
+}
@@ -29,6 +29,20 @@ func MatchCategory(suggested string, categories []models.Category) *models.Categ
 	return findWordBasedCategoryMatch(suggested, categories)
 }
 
+// closestCategoryName returns the category name nearest to query, and false
+// when nothing is close enough to be a likely typo.
+func closestCategoryName(query string, categories []models.Category) (string, bool) {
+	names := make([]string, len(categories))
+	for i := range categories {
+		names[i] = categories[i].Name
+	}
+	closest := closestNames(query, names, 1)
+	if len(closest) == 0 {
+		return "", false
+	}
+	return closest[0], true
+}
+
 func findExactCategoryMatch(suggested string, categories []models.Category) *models.Category {
 	for i := range categories {
 		if strings.EqualFold(categories[i].Name, suggested) {
@@ -177,6 +177,22 @@ func TestMatchCategory_EmptyCategories(t *testing.T) {
 	require.Nil(t, result)
 }
 
+func TestClosestCategoryName(t *testing.T) {
+	t.Parallel()
+
+	categories := []models.Category{{Name: "Work Travel"}, {Name: "Food"}}
+
+	name, ok := closestCategoryName("wrok trvel", categories)
+	require.True(t, ok)
+	require.Equal(t, "Work Travel", name)
+
+	_, ok = closestCategoryName("Electronics", categories)
+	require.False(t, ok)
+
+	_, ok = closestCategoryName("Food", nil)
+	require.False(t, ok)
+}
+
 func TestExtractSignificantWords(t *testing.T) {
 	t.Parallel()
 
@@ -3,6 +3,7 @@ package bot
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/go-analyze/charts"
@@ -10,23 +11,40 @@ import (
 	"gitlab.com/yelinaung/expense-bot/internal/models"
 )
 
+// errNoChartSpending is returned when refunds cancel out all spending, so
+// there is nothing to chart.
+var errNoChartSpending = errors.New("no spending left after refunds")
+
 // GenerateExpenseChart creates a pie chart showing expense breakdown by category.
+// Each category is drawn in the color from colors, keyed by category name,
+// or in one derived from its name, so it looks the same in every chart.
 // Returns PNG image as bytes.
-func GenerateExpenseChart(expenses []models.Expense, period string) ([]byte, error) {
+func GenerateExpenseChart(expenses []models.Expense, period string, colors map[string]string) ([]byte, error) {
 	if len(expenses) == 0 {
 		return nil, errors.New("no expenses to chart")
 	}
 
-	// Aggregate expenses by category
-	categoryTotals := aggregateByCategory(expenses)
-
-	// Convert to chart values and names
-	values := make([]float64, 0, len(categoryTotals))
-	categoryNames := make([]string, 0, len(categoryTotals))
+	// Aggregate expenses by category, largest first. Categories that
+	// refunds brought to zero or below have no slice.
+	var totals []categoryTotal
+	for _, total := range aggregateByCategory(expenses) {
+		if total.Total.IsPositive() {
+			totals = append(totals, total)
+		}
+	}
+	if len(totals) == 0 {
+		return nil, errNoChartSpending
+	}
 
-	for categoryName, total := range categoryTotals {
-		categoryNames = append(categoryNames, categoryName)
-		values = append(values, total.InexactFloat64())
+	// Convert to chart values and colors
+	mixed := hasMixedCurrencies(totals)
+	categoryNames := make([]string, len(totals))
+	values := make([]float64, len(totals))
+	seriesColors := make([]charts.Color, len(totals))
+	for i, total := range totals {
+		categoryNames[i] = categoryTotalLabel(total, mixed)
+		values[i] = total.Total.InexactFloat64()
+		seriesColors[i] = charts.ColorFromHex(categoryColor(total.Name, colors[total.Name]))
 	}
 
 	opt := charts.NewPieChartOptionWithData(values)
@@ -36,6 +54,7 @@ func GenerateExpenseChart(expenses []models.Expense, period string) ([]byte, err
 		FontStyle:        charts.NewFontStyleWithSize(16),
 		SubtextFontStyle: charts.NewFontStyleWithSize(10),
 	}
+	opt.Theme = charts.GetDefaultTheme().WithSeriesColors(seriesColors)
 	opt.Padding = charts.NewBoxEqual(5)
 	opt.Legend = charts.LegendOption{
 		SeriesNames: categoryNames,
@@ -66,24 +85,56 @@ func GenerateExpenseChart(expenses []models.Expense, period string) ([]byte, err
 	return buf, nil
 }
 
-// aggregateByCategory groups expenses and returns category totals.
-func aggregateByCategory(expenses []models.Expense) map[string]decimal.Decimal {
-	categoryTotals := make(map[string]decimal.Decimal)
-
+// aggregateByCategory totals expenses by category and currency, largest
+// first with ties broken by name and currency. Each expense counts in its
+// stored default-currency conversion when it has one; amounts still in
+// different currencies are never added together.
+func aggregateByCategory(expenses []models.Expense) []categoryTotal {
+	type key struct{ name, currency string }
+	totals := make(map[key]decimal.Decimal)
 	for i := range expenses {
 		categoryName := categoryUncategorized
 		if expenses[i].Category != nil && expenses[i].Category.Name != "" {
 			categoryName = expenses[i].Category.Name
 		}
+		amount, currency := expenses[i].AmountInDefault()
+		k := key{categoryName, currency}
+		totals[k] = totals[k].Add(amount)
+	}
 
-		if existing, ok := categoryTotals[categoryName]; ok {
-			categoryTotals[categoryName] = existing.Add(expenses[i].Amount)
-		} else {
-			categoryTotals[categoryName] = expenses[i].Amount
+	result := make([]categoryTotal, 0, len(totals))
+	for k, total := range totals {
+		result = append(result, categoryTotal{Name: k.name, Currency: k.currency, Total: total})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if cmp := result[i].Total.Cmp(result[j].Total); cmp != 0 {
+			return cmp > 0
 		}
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Currency < result[j].Currency
+	})
+	return result
+}
+
+// categoryTotalLabel names a category total in a chart legend. The
+// currency is added when the totals span more than one.
+func categoryTotalLabel(total categoryTotal, mixedCurrencies bool) string {
+	if !mixedCurrencies {
+		return total.Name
 	}
+	return fmt.Sprintf("%s (%s)", total.Name, total.Currency)
+}
 
-	return categoryTotals
+// hasMixedCurrencies reports whether totals are in more than one currency.
+func hasMixedCurrencies(totals []categoryTotal) bool {
+	for i := range totals {
+		if totals[i].Currency != totals[0].Currency {
+			return true
+		}
+	}
+	return false
 }
 
 // generateChartFilename creates filename like "chart_week_2026-01-31.png".
@@ -98,6 +149,9 @@ func generateChartFilename(period string, loc *time.Location, now time.Time) str
 	case periodMonth:
 		start, _ := getMonthDateRangeAt(current)
 		return fmt.Sprintf("chart_month_%s.png", start.Format("2006-01"))
+	case periodPace:
+		start, _ := getMonthDateRangeAt(current)
+		return fmt.Sprintf("chart_pace_%s.png", start.Format("2006-01"))
 	default:
 		return fmt.Sprintf("chart_%s.png", current.Format("2006-01-02"))
 	}
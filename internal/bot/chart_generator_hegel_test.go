@@ -45,9 +45,9 @@ func TestHegelAggregateByCategoryConsistentWithHabitCategoryName(t *testing.T) {
 		}
 		result := aggregateByCategory([]models.Expense{expense})
 		wantKey := habitCategoryName(&expense)
-		_, ok := result[wantKey]
-		require.True(ht, ok,
-			"aggregateByCategory key %q missing (Category=%+v): got %v",
+		require.Len(ht, result, 1)
+		require.Equal(ht, wantKey, result[0].Name,
+			"aggregateByCategory name %q missing (Category=%+v): got %v",
 			wantKey, cat, result)
 	})
 }
@@ -67,7 +67,8 @@ func TestHegelAggregateByCategoryEmptyNameIsUncategorized(t *testing.T) {
 		})
 		require.Equal(ht, nilResult, emptyResult,
 			"nil Category and empty-name Category should aggregate identically")
-		require.Contains(ht, emptyResult, categoryUncategorized,
+		require.Len(ht, emptyResult, 1)
+		require.Equal(ht, categoryUncategorized, emptyResult[0].Name,
 			"empty-name Category should fall back to %q", categoryUncategorized)
 	})
 }
@@ -98,11 +98,40 @@ func TestGenerateExpenseChart(t *testing.T) {
 			period:      "Week",
 			expectError: false,
 		},
+		{
+			name: "skips a category refunds brought below zero",
+			expenses: []models.Expense{
+				{
+					ID:       1,
+					Amount:   decimal.NewFromFloat(40.00),
+					Category: &models.Category{ID: 1, Name: testCategoryFoodGroceries},
+				},
+				{
+					ID:       2,
+					Amount:   decimal.NewFromFloat(-60.00),
+					Category: &models.Category{ID: 2, Name: testCategoryFoodDiningOut},
+				},
+			},
+			period:      "Month",
+			expectError: false,
+		},
+		{
+			name: "fails when refunds cancel all spending",
+			expenses: []models.Expense{
+				{
+					ID:       1,
+					Amount:   decimal.NewFromFloat(-25.90),
+					Category: &models.Category{ID: 1, Name: testCategoryFoodGroceries},
+				},
+			},
+			period:      "Month",
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			buf, err := GenerateExpenseChart(tt.expenses, tt.period)
+			buf, err := GenerateExpenseChart(tt.expenses, tt.period, nil)
 			assertChartGenerationResult(t, buf, err, tt.expectError)
 		})
 	}
@@ -195,6 +224,32 @@ func TestAggregateByCategory(t *testing.T) {
 				testCategoryFoodDiningOut: "20.25",
 			},
 		},
+		{
+			name: "keeps currencies apart and uses stored conversions",
+			expenses: []models.Expense{
+				{
+					Amount:   decimal.NewFromFloat(10.00),
+					Currency: currencyCodeSGD,
+					Category: &models.Category{Name: testCategoryFoodDiningOut},
+				},
+				{
+					Amount:   decimal.NewFromFloat(20.00),
+					Currency: "EUR",
+					Category: &models.Category{Name: testCategoryFoodDiningOut},
+				},
+				{
+					Amount:            decimal.NewFromFloat(5.00),
+					Currency:          "EUR",
+					ConvertedAmount:   decimal.NewNullDecimal(decimal.NewFromFloat(7.50)),
+					ConvertedCurrency: currencyCodeSGD,
+					Category:          &models.Category{Name: testCategoryFoodDiningOut},
+				},
+			},
+			expected: map[string]string{
+				testCategoryFoodDiningOut + " SGD": "17.5",
+				testCategoryFoodDiningOut + " EUR": "20",
+			},
+		},
 		{
 			name:     "handles empty expense list",
 			expenses: []models.Expense{},
@@ -216,7 +271,14 @@ func TestAggregateByCategory(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := aggregateByCategory(tt.expenses)
+			result := make(map[string]decimal.Decimal)
+			for _, total := range aggregateByCategory(tt.expenses) {
+				key := total.Name
+				if total.Currency != "" {
+					key += " " + total.Currency
+				}
+				result[key] = total.Total
+			}
 
 			if len(result) != len(tt.expected) {
 				t.Errorf("expected %d categories, got %d", len(tt.expected), len(result))
@@ -238,6 +300,16 @@ func TestAggregateByCategory(t *testing.T) {
 	}
 }
 
+func TestCategoryTotalLabel(t *testing.T) {
+	sgd := categoryTotal{Name: testCategoryFoodDiningOut, Currency: currencyCodeSGD, Total: decimal.NewFromInt(10)}
+	eur := categoryTotal{Name: testCategoryFoodDiningOut, Currency: "EUR", Total: decimal.NewFromInt(20)}
+
+	require.False(t, hasMixedCurrencies([]categoryTotal{sgd, sgd}))
+	require.True(t, hasMixedCurrencies([]categoryTotal{sgd, eur}))
+	require.Equal(t, testCategoryFoodDiningOut, categoryTotalLabel(sgd, false))
+	require.Equal(t, testCategoryFoodDiningOut+" (EUR)", categoryTotalLabel(eur, true))
+}
+
 func TestGenerateChartFilename(t *testing.T) {
 	tests := []struct {
 		name     string
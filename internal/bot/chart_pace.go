@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-analyze/charts"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const periodPace = "pace"
+
+// paceChart is the data behind /chart pace, in one currency.
+type paceChart struct {
+	// Month is the first day of the month, in the user's location.
+	Month time.Time
+	// Daily holds the total of each day of the month.
+	Daily []decimal.Decimal
+	// Elapsed is the number of days so far, today included.
+	Elapsed int
+	// Goal is the sum of the user's budgets, zero when there are none.
+	Goal decimal.Decimal
+}
+
+// daysInMonth returns the number of days in the month starting at start.
+func daysInMonth(start time.Time) int {
+	return start.AddDate(0, 1, -1).Day()
+}
+
+// aggregateDailyTotals sums expenses per day of the month starting at
+// monthStart, by their time in its location. amount returns what an
+// expense counts for; expenses it rejects, or from other months, are left
+// out.
+func aggregateDailyTotals(
+	expenses []models.Expense,
+	monthStart time.Time,
+	amount func(*models.Expense) (decimal.Decimal, bool),
+) []decimal.Decimal {
+	daily := make([]decimal.Decimal, daysInMonth(monthStart))
+	for i := range expenses {
+		at := expenses[i].CreatedAt.In(monthStart.Location())
+		if at.Year() != monthStart.Year() || at.Month() != monthStart.Month() {
+			continue
+		}
+		value, ok := amount(&expenses[i])
+		if !ok {
+			continue
+		}
+		daily[at.Day()-1] = daily[at.Day()-1].Add(value)
+	}
+	return daily
+}
+
+// sumDecimals returns the total of the first n values.
+func sumDecimals(values []decimal.Decimal, n int) decimal.Decimal {
+	total := decimal.Zero
+	for i := 0; i < n && i < len(values); i++ {
+		total = total.Add(values[i])
+	}
+	return total
+}
+
+// projectMonthEnd extends the spending of the elapsed days over the whole
+// month at the same daily rate.
+func projectMonthEnd(spent decimal.Decimal, elapsed, days int) decimal.Decimal {
+	if elapsed <= 0 || elapsed >= days {
+		return spent
+	}
+	return spent.Mul(decimal.NewFromInt(int64(days))).Div(decimal.NewFromInt(int64(elapsed))).Round(2)
+}
+
+// spent returns the total of the elapsed days.
+func (p *paceChart) spent() decimal.Decimal {
+	return sumDecimals(p.Daily, p.Elapsed)
+}
+
+// projected returns the month-end total at the current run rate.
+func (p *paceChart) projected() decimal.Decimal {
+	return projectMonthEnd(p.spent(), p.Elapsed, len(p.Daily))
+}
+
+// dailyPace returns the daily spending that would end the month on the
+// goal.
+func (p *paceChart) dailyPace() decimal.Decimal {
+	return p.Goal.Div(decimal.NewFromInt(int64(len(p.Daily)))).Round(2)
+}
+
+// dailyAverage returns the average spending of the elapsed days.
+func (p *paceChart) dailyAverage() decimal.Decimal {
+	if p.Elapsed <= 0 {
+		return decimal.Zero
+	}
+	return p.spent().Div(decimal.NewFromInt(int64(p.Elapsed))).Round(2)
+}
+
+// paceSeries builds the series of a pace chart: the daily bars with the
+// budget pace, plus the cumulative actual and ideal spending on a second
+// axis. Without a goal, the bars get the month average line instead.
+func paceSeries(p *paceChart) charts.GenericSeriesList {
+	days := len(p.Daily)
+	bars := make([]float64, days)
+	for i := range p.Daily {
+		bars[i] = p.Daily[i].InexactFloat64()
+	}
+
+	constant := func(value decimal.Decimal) []float64 {
+		values := make([]float64, days)
+		for i := range values {
+			values[i] = value.InexactFloat64()
+		}
+		return values
+	}
+
+	if !p.Goal.IsPositive() {
+		return charts.GenericSeriesList{
+			{Type: charts.ChartTypeBar, Name: "Daily", Values: bars},
+			{Type: charts.ChartTypeLine, Name: "Average", Values: constant(p.dailyAverage())},
+		}
+	}
+
+	actual := make([]float64, days)
+	ideal := make([]float64, days)
+	running := decimal.Zero
+	for i := range days {
+		ideal[i] = p.Goal.Mul(decimal.NewFromInt(int64(i + 1))).Div(decimal.NewFromInt(int64(days))).InexactFloat64()
+		if i >= p.Elapsed {
+			actual[i] = charts.GetNullValue()
+			continue
+		}
+		running = running.Add(p.Daily[i])
+		actual[i] = running.InexactFloat64()
+	}
+	return charts.GenericSeriesList{
+		{Type: charts.ChartTypeBar, Name: "Daily", Values: bars},
+		{Type: charts.ChartTypeLine, Name: "Budget pace", Values: constant(p.dailyPace())},
+		{Type: charts.ChartTypeLine, Name: "Spent so far", Values: actual, YAxisIndex: 1},
+		{Type: charts.ChartTypeLine, Name: "Ideal", Values: ideal, YAxisIndex: 1},
+	}
+}
+
+// GeneratePaceChart renders a pace chart as PNG bytes.
+func GeneratePaceChart(p *paceChart) ([]byte, error) {
+	if len(p.Daily) == 0 {
+		return nil, errors.New("no days to chart")
+	}
+
+	labels := make([]string, len(p.Daily))
+	for i := range labels {
+		labels[i] = strconv.Itoa(i + 1)
+	}
+	seriesList := paceSeries(p)
+	names := make([]string, len(seriesList))
+	for i := range seriesList {
+		names[i] = seriesList[i].Name
+	}
+
+	yAxis := []charts.YAxisOption{{}}
+	if len(seriesList) > 2 {
+		yAxis = append(yAxis, charts.YAxisOption{})
+	}
+
+	painter, err := charts.Render(charts.ChartOption{
+		OutputFormat: charts.ChartOutputPNG,
+		Width:        600,
+		Height:       400,
+		Padding:      charts.NewBoxEqual(10),
+		Title: charts.TitleOption{
+			Text:      fmt.Sprintf("Daily Spending %s\n\n", p.Month.Format("January 2006")),
+			Offset:    charts.OffsetCenter,
+			FontStyle: charts.NewFontStyleWithSize(16),
+		},
+		Legend: charts.LegendOption{
+			SeriesNames: names,
+			Offset:      charts.OffsetStr{Top: charts.PositionBottom},
+			FontStyle:   charts.NewFontStyleWithSize(8),
+		},
+		XAxis: charts.XAxisOption{
+			Labels:         labels,
+			LabelFontStyle: charts.NewFontStyleWithSize(8),
+		},
+		YAxis:      yAxis,
+		SeriesList: seriesList,
+		Symbol:     charts.Symbol{Shape: charts.SymbolNone},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pace chart: %w", err)
+	}
+
+	buf, err := painter.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to render pace chart: %w", err)
+	}
+	return buf, nil
+}
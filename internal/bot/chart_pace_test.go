@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-analyze/charts"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestAggregateDailyTotals(t *testing.T) {
+	t.Parallel()
+
+	loc := time.FixedZone("GMT+8", 8*60*60)
+	start := time.Date(2026, 2, 1, 0, 0, 0, 0, loc)
+	expenses := []models.Expense{
+		{Amount: decimal.RequireFromString("4.50"), CreatedAt: time.Date(2026, 2, 1, 9, 0, 0, 0, loc)},
+		{Amount: decimal.RequireFromString("5.50"), CreatedAt: time.Date(2026, 2, 1, 20, 0, 0, 0, loc)},
+		// 23:30 UTC on 2 Feb is 3 Feb in GMT+8.
+		{Amount: decimal.RequireFromString("12.00"), CreatedAt: time.Date(2026, 2, 2, 23, 30, 0, 0, time.UTC)},
+		{Amount: decimal.RequireFromString("7.00"), CreatedAt: time.Date(2026, 2, 28, 12, 0, 0, 0, loc)},
+		{Amount: decimal.RequireFromString("99.00"), CreatedAt: time.Date(2026, 3, 1, 0, 0, 0, 0, loc)},
+		{Amount: decimal.RequireFromString("50.00"), Currency: "JPY", CreatedAt: time.Date(2026, 2, 5, 12, 0, 0, 0, loc)},
+	}
+
+	daily := aggregateDailyTotals(expenses, start, func(expense *models.Expense) (decimal.Decimal, bool) {
+		return expense.Amount, expense.Currency != "JPY"
+	})
+	require.Len(t, daily, 28)
+	require.True(t, decimal.RequireFromString("10.00").Equal(daily[0]))
+	require.True(t, daily[1].IsZero())
+	require.True(t, decimal.RequireFromString("12.00").Equal(daily[2]))
+	require.True(t, daily[4].IsZero(), "rejected amounts are left out")
+	require.True(t, decimal.RequireFromString("7.00").Equal(daily[27]))
+	require.True(t, decimal.RequireFromString("29.00").Equal(sumDecimals(daily, len(daily))))
+}
+
+func TestProjectMonthEnd(t *testing.T) {
+	t.Parallel()
+
+	spent := decimal.RequireFromString("300")
+	require.True(t, decimal.RequireFromString("930").Equal(projectMonthEnd(spent, 10, 31)))
+	require.True(t, decimal.RequireFromString("450").Equal(projectMonthEnd(spent, 20, 30)))
+	require.True(t, spent.Equal(projectMonthEnd(spent, 30, 30)), "a finished month is not projected")
+	require.True(t, spent.Equal(projectMonthEnd(spent, 0, 30)))
+	require.True(t, decimal.RequireFromString("333.33").Equal(projectMonthEnd(decimal.NewFromInt(100), 9, 30)))
+}
+
+func TestPaceSeries(t *testing.T) {
+	t.Parallel()
+
+	daily := make([]decimal.Decimal, 30)
+	daily[0] = decimal.NewFromInt(20)
+	daily[1] = decimal.NewFromInt(40)
+	daily[2] = decimal.NewFromInt(30)
+
+	t.Run("budget pace and cumulative lines", func(t *testing.T) {
+		t.Parallel()
+
+		pace := &paceChart{Daily: daily, Elapsed: 3, Goal: decimal.NewFromInt(600)}
+		series := paceSeries(pace)
+		require.Len(t, series, 4)
+		require.Equal(t, charts.ChartTypeBar, series[0].Type)
+		require.InDelta(t, 40.0, series[0].Values[1], 0.001)
+
+		require.Equal(t, "Budget pace", series[1].Name)
+		require.InDelta(t, 20.0, series[1].Values[0], 0.001)
+		require.InDelta(t, 20.0, series[1].Values[29], 0.001)
+
+		require.Equal(t, 1, series[2].YAxisIndex)
+		require.InDelta(t, 20.0, series[2].Values[0], 0.001)
+		require.InDelta(t, 90.0, series[2].Values[2], 0.001)
+		require.Equal(t, charts.GetNullValue(), series[2].Values[3], "no running total for days to come")
+
+		require.Equal(t, 1, series[3].YAxisIndex)
+		require.InDelta(t, 60.0, series[3].Values[2], 0.001)
+		require.InDelta(t, 600.0, series[3].Values[29], 0.001)
+
+		require.True(t, decimal.RequireFromString("900").Equal(pace.projected()))
+	})
+
+	t.Run("month average without a goal", func(t *testing.T) {
+		t.Parallel()
+
+		pace := &paceChart{Daily: daily, Elapsed: 3}
+		series := paceSeries(pace)
+		require.Len(t, series, 2)
+		require.Equal(t, charts.ChartTypeBar, series[0].Type)
+		require.Equal(t, "Average", series[1].Name)
+		require.InDelta(t, 30.0, series[1].Values[0], 0.001)
+		require.InDelta(t, 30.0, series[1].Values[29], 0.001)
+	})
+}
+
+func TestGeneratePaceChart(t *testing.T) {
+	t.Parallel()
+
+	daily := make([]decimal.Decimal, 31)
+	daily[0] = decimal.NewFromInt(12)
+	month := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, goal := range []decimal.Decimal{decimal.Zero, decimal.NewFromInt(500)} {
+		data, err := GeneratePaceChart(&paceChart{Month: month, Daily: daily, Elapsed: 17, Goal: goal})
+		require.NoError(t, err)
+		require.Equal(t, []byte("\x89PNG"), data[:4])
+	}
+
+	_, err := GeneratePaceChart(&paceChart{Month: month})
+	require.Error(t, err)
+}
+
+func TestFormatPaceCaption(t *testing.T) {
+	t.Parallel()
+
+	daily := make([]decimal.Decimal, 30)
+	daily[0] = decimal.NewFromInt(150)
+	pace := &paceChart{Month: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), Daily: daily, Elapsed: 10}
+
+	text := formatPaceCaption(pace, "SGD", 0)
+	require.Contains(t, text, "Daily Spending (September 2026)")
+	require.Contains(t, text, "in 10 of 30 days")
+	require.Contains(t, text, "Daily average:")
+	require.Contains(t, text, "/budget set")
+	require.NotContains(t, text, "exchange rate")
+
+	pace.Goal = decimal.NewFromInt(600)
+	text = formatPaceCaption(pace, "SGD", 2)
+	require.Contains(t, text, "Budget pace:")
+	require.Contains(t, text, "(75% of your budgets)")
+	require.Contains(t, text, "2 expenses without an exchange rate")
+}
@@ -4,25 +4,41 @@ import (
 	"bytes"
 	"encoding/csv"
 	"fmt"
+	"io"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
 	"gitlab.com/yelinaung/expense-bot/internal/models"
 )
 
 const (
+	periodDay   = "day"
 	periodWeek  = "week"
 	periodMonth = "month"
 
-	csvHeaderID          = "ID"
-	csvHeaderDate        = "Date"
-	csvHeaderAmount      = "Amount"
-	csvHeaderCurrency    = "Currency"
-	csvHeaderDescription = "Description"
-	csvHeaderMerchant    = "Merchant"
-	csvHeaderCategory    = "Category"
-	csvHeaderWorthIt     = "Worth It"
+	csvHeaderID           = "ID"
+	csvHeaderDate         = "Date"
+	csvHeaderAmount       = "Amount"
+	csvHeaderCurrency     = "Currency"
+	csvHeaderDescription  = "Description"
+	csvHeaderMerchant     = "Merchant"
+	csvHeaderCategory     = "Category"
+	csvHeaderWorthIt      = "Worth It"
+	csvHeaderReimbursable = "Reimbursable"
+	csvHeaderType         = "Type"
+	csvHeaderSource       = "Source"
+
+	// csvTypeExpense and csvTypeRefund fill the Type column. Refund rows
+	// also have a negative Amount.
+	csvTypeExpense = "Expense"
+	csvTypeRefund  = "Refund"
+
+	csvSummaryTotal    = "Total"
+	csvSummarySubtotal = "Subtotal"
 )
 
 var csvExpenseHeader = []string{
@@ -34,6 +50,9 @@ var csvExpenseHeader = []string{
 	csvHeaderMerchant,
 	csvHeaderCategory,
 	csvHeaderWorthIt,
+	csvHeaderReimbursable,
+	csvHeaderType,
+	csvHeaderSource,
 }
 
 // sanitizeCSVCell prefixes cell values that could be interpreted as
@@ -63,45 +82,170 @@ func worthItCSVCell(worthIt *bool) string {
 	return reviewNotWorthItLabel
 }
 
+// reimbursableCSVCell is empty for personal expenses, "Awaiting" for
+// reimbursable ones not paid back yet and the reimbursement date otherwise.
+func reimbursableCSVCell(expense *models.Expense) string {
+	switch {
+	case !expense.Reimbursable:
+		return ""
+	case expense.ReimbursedAt == nil:
+		return "Awaiting"
+	default:
+		return "Reimbursed " + expense.ReimbursedAt.Format("2006-01-02")
+	}
+}
+
+// typeCSVCell tells refunds from other expenses.
+func typeCSVCell(expense *models.Expense) string {
+	if expense.IsRefund() {
+		return csvTypeRefund
+	}
+	return csvTypeExpense
+}
+
 // GenerateExpensesCSV generates a CSV file from a list of expenses.
 func GenerateExpensesCSV(expenses []models.Expense) ([]byte, error) {
 	var buf bytes.Buffer
-	writer := csv.NewWriter(&buf)
+	done := false
+	_, err := GenerateExpensesCSVStream(&buf, func() ([]models.Expense, error) {
+		if done {
+			return nil, nil
+		}
+		done = true
+		return expenses, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GenerateExpensesCSVStream writes the same CSV as GenerateExpensesCSV to
+// w, pulling expenses from next one batch at a time until it returns an
+// empty batch. Only the current batch and the running totals are held in
+// memory. It returns the number of expense rows written.
+func GenerateExpensesCSVStream(w io.Writer, next func() ([]models.Expense, error)) (int, error) {
+	writer := csv.NewWriter(w)
 
 	// Write header
 	if err := writer.Write(csvExpenseHeader); err != nil {
-		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+		return 0, fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
-	// Write expense rows
-	for i := range expenses {
-		categoryName := categoryUncategorized
-		if expenses[i].Category != nil && expenses[i].Category.Name != "" {
-			categoryName = expenses[i].Category.Name
+	totals := make(map[string]decimal.Decimal)
+	subtotals := make(map[csvCategoryCurrency]decimal.Decimal)
+	count := 0
+	for {
+		expenses, err := next()
+		if err != nil {
+			return count, fmt.Errorf("failed to load expenses: %w", err)
+		}
+		if len(expenses) == 0 {
+			break
 		}
 
-		row := []string{
-			strconv.FormatInt(expenses[i].UserExpenseNumber, 10),
-			expenses[i].CreatedAt.Format("2006-01-02 15:04:05"),
-			expenses[i].Amount.StringFixed(2),
-			expenses[i].Currency,
-			sanitizeCSVCell(expenses[i].Description),
-			sanitizeCSVCell(expenses[i].Merchant),
-			sanitizeCSVCell(categoryName),
-			worthItCSVCell(expenses[i].WorthIt),
+		// Write expense rows
+		for i := range expenses {
+			categoryName := csvCategoryName(&expenses[i])
+			row := []string{
+				strconv.FormatInt(expenses[i].UserExpenseNumber, 10),
+				expenses[i].CreatedAt.Format("2006-01-02 15:04:05"),
+				expenses[i].Amount.StringFixed(2),
+				expenses[i].Currency,
+				sanitizeCSVCell(expenses[i].Description),
+				sanitizeCSVCell(expenses[i].Merchant),
+				sanitizeCSVCell(categoryName),
+				worthItCSVCell(expenses[i].WorthIt),
+				reimbursableCSVCell(&expenses[i]),
+				typeCSVCell(&expenses[i]),
+				string(expenses[i].Source),
+			}
+
+			if err := writer.Write(row); err != nil {
+				return count, fmt.Errorf("failed to write CSV row: %w", err)
+			}
+
+			totals[expenses[i].Currency] = totals[expenses[i].Currency].Add(expenses[i].Amount)
+			key := csvCategoryCurrency{category: categoryName, currency: expenses[i].Currency}
+			subtotals[key] = subtotals[key].Add(expenses[i].Amount)
 		}
+		count += len(expenses)
+	}
 
-		if err := writer.Write(row); err != nil {
-			return nil, fmt.Errorf("failed to write CSV row: %w", err)
+	if count > 0 {
+		if err := writeCSVSummary(writer, totals, subtotals); err != nil {
+			return count, err
 		}
 	}
 
 	writer.Flush()
 	if err := writer.Error(); err != nil {
-		return nil, fmt.Errorf("CSV writer error: %w", err)
+		return count, fmt.Errorf("CSV writer error: %w", err)
 	}
 
-	return buf.Bytes(), nil
+	return count, nil
+}
+
+func csvCategoryName(expense *models.Expense) string {
+	if expense.Category != nil && expense.Category.Name != "" {
+		return expense.Category.Name
+	}
+	return categoryUncategorized
+}
+
+// csvCategoryCurrency keys the per-category subtotals.
+type csvCategoryCurrency struct {
+	category string
+	currency string
+}
+
+// writeCSVSummary appends a blank separator row, a Total row per currency
+// and a Subtotal row per category and currency. The rows keep the expense
+// columns so amounts stay plain numbers in the Amount column.
+func writeCSVSummary(
+	writer *csv.Writer,
+	totals map[string]decimal.Decimal,
+	subtotals map[csvCategoryCurrency]decimal.Decimal,
+) error {
+	keys := make([]csvCategoryCurrency, 0, len(subtotals))
+	for key := range subtotals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].category != keys[j].category {
+			return keys[i].category < keys[j].category
+		}
+		return keys[i].currency < keys[j].currency
+	})
+
+	rows := [][]string{make([]string, len(csvExpenseHeader))}
+	for _, currency := range sortedCurrencyKeys(totals) {
+		rows = append(rows, csvSummaryRow(csvSummaryTotal, totals[currency], currency, ""))
+	}
+	for _, key := range keys {
+		rows = append(rows, csvSummaryRow(csvSummarySubtotal, subtotals[key], key.currency, key.category))
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write CSV summary: %w", err)
+	}
+	return nil
+}
+
+func csvSummaryRow(label string, amount decimal.Decimal, currency, category string) []string {
+	return []string{
+		label,
+		"",
+		botfmt.FormatAmount(amount, currency),
+		currency,
+		"",
+		"",
+		sanitizeCSVCell(category),
+		"",
+		"",
+		"",
+		"",
+	}
 }
 
 // generateReportFilename creates a descriptive filename for the CSV report.
@@ -112,7 +256,7 @@ func generateReportFilename(period string, loc *time.Location, now time.Time) st
 	switch period {
 	case periodWeek:
 		start, _ := getWeekDateRangeAt(current)
-		return fmt.Sprintf("expenses_week_%s.csv", start.Format("2006-01-02"))
+		return fmt.Sprintf("expenses_%s.csv", isoWeekID(start))
 	case periodMonth:
 		start, _ := getMonthDateRangeAt(current)
 		return fmt.Sprintf("expenses_month_%s.csv", start.Format("2006-01"))
@@ -120,3 +264,27 @@ func generateReportFilename(period string, loc *time.Location, now time.Time) st
 		return fmt.Sprintf("expenses_%s.csv", current.Format("2006-01-02"))
 	}
 }
+
+// reportCategoryFilename adds a category to a report filename, e.g.
+// "expenses_month_2026-03_work-travel.csv". Anything but ASCII letters and
+// digits becomes a dash, so the name stays a plain filename.
+func reportCategoryFilename(filename, category string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(category) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && sb.Len() > 0 {
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(sb.String(), "-")
+	if slug == "" {
+		slug = "category"
+	}
+	return strings.TrimSuffix(filename, ".csv") + "_" + slug + ".csv"
+}
@@ -96,8 +96,9 @@ func FuzzGenerateExpensesCSV(f *testing.F) {
 		if err != nil {
 			t.Fatalf("generated CSV does not parse back: %v\ndata: %q", err, data)
 		}
-		if len(records) != 2 {
-			t.Fatalf("got %d records, want 2 (header + row)", len(records))
+		// Header, the row, then a blank separator, one Total and one Subtotal.
+		if len(records) != 5 {
+			t.Fatalf("got %d records, want 5 (header + row + summary)", len(records))
 		}
 		for _, record := range records {
 			if len(record) != len(csvExpenseHeader) {
@@ -1,8 +1,6 @@
 package bot
 
 import (
-	"bytes"
-	"encoding/csv"
 	"strings"
 	"testing"
 	"time"
@@ -106,16 +104,14 @@ func TestGenerateExpensesCSVStructure(t *testing.T) {
 		data, err := GenerateExpensesCSV(exps)
 		require.NoError(t, err)
 
-		reader := csv.NewReader(bytes.NewReader(data))
-		rows, err := reader.ReadAll()
-		require.NoError(t, err)
+		rows := csvExpenseRecords(t, data)
 		require.Len(t, rows, n+1, "row count")
 		for _, row := range rows {
-			require.Len(t, row, 8, "field count")
+			require.Len(t, row, 11, "field count")
 		}
 		// Header fixed.
 		require.Equal(t,
-			[]string{"ID", "Date", "Amount", "Currency", "Description", "Merchant", "Category", "Worth It"},
+			[]string{"ID", "Date", "Amount", "Currency", "Description", "Merchant", "Category", "Worth It", "Reimbursable", "Type", "Source"},
 			rows[0])
 	})
 }
@@ -141,9 +137,7 @@ func TestGenerateExpensesCSVNeutralizesFormulas(t *testing.T) {
 		data, err := GenerateExpensesCSV(exps)
 		require.NoError(t, err)
 
-		reader := csv.NewReader(bytes.NewReader(data))
-		rows, err := reader.ReadAll()
-		require.NoError(t, err)
+		rows := csvExpenseRecords(t, data)
 		require.Len(t, rows, 2)
 
 		require.Equal(t, "'"+injected, rows[1][4], "description cell")
@@ -217,15 +211,13 @@ func TestHegelGenerateExpensesCSVStructure(t *testing.T) {
 		data, err := GenerateExpensesCSV(exps)
 		require.NoError(ht, err)
 
-		reader := csv.NewReader(bytes.NewReader(data))
-		rows, err := reader.ReadAll()
-		require.NoError(ht, err)
+		rows := csvExpenseRecords(ht, data)
 		require.Len(ht, rows, n+1, "row count")
 		for _, row := range rows {
-			require.Len(ht, row, 8, "field count")
+			require.Len(ht, row, 11, "field count")
 		}
 		require.Equal(ht,
-			[]string{"ID", "Date", "Amount", "Currency", "Description", "Merchant", "Category", "Worth It"},
+			[]string{"ID", "Date", "Amount", "Currency", "Description", "Merchant", "Category", "Worth It", "Reimbursable", "Type", "Source"},
 			rows[0])
 	})
 }
@@ -252,9 +244,7 @@ func TestHegelGenerateExpensesCSVNeutralizesFormulas(t *testing.T) {
 		data, err := GenerateExpensesCSV(exps)
 		require.NoError(ht, err)
 
-		reader := csv.NewReader(bytes.NewReader(data))
-		rows, err := reader.ReadAll()
-		require.NoError(ht, err)
+		rows := csvExpenseRecords(ht, data)
 		require.Len(ht, rows, 2)
 
 		require.Equal(ht, "'"+injected, rows[1][4], "description cell")
@@ -269,9 +259,7 @@ func TestHegelGenerateExpensesCSVNeutralizesFormulas(t *testing.T) {
 func csvCategoryColumn(t require.TestingT, expense *models.Expense) string {
 	data, err := GenerateExpensesCSV([]models.Expense{*expense})
 	require.NoError(t, err)
-	reader := csv.NewReader(bytes.NewReader(data))
-	rows, err := reader.ReadAll()
-	require.NoError(t, err)
+	rows := csvExpenseRecords(t, data)
 	require.Len(t, rows, 2)
 	require.Len(t, rows[1], 8)
 	return rows[1][6]
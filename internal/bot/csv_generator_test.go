@@ -1,7 +1,9 @@
 package bot
 
 import (
+	"bytes"
 	"encoding/csv"
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -11,6 +13,19 @@ import (
 	"gitlab.com/yelinaung/expense-bot/internal/models"
 )
 
+// csvExpenseRecords parses GenerateExpensesCSV output and returns the header
+// and expense rows, without the summary that follows the blank separator.
+func csvExpenseRecords(t require.TestingT, data []byte) [][]string {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	require.NoError(t, err)
+	for i, record := range records {
+		if strings.Join(record, "") == "" {
+			return records[:i]
+		}
+	}
+	return records
+}
+
 func TestGenerateExpensesCSV(t *testing.T) {
 	t.Parallel()
 
@@ -28,6 +43,7 @@ func TestGenerateExpensesCSV(t *testing.T) {
 				CreatedAt:         time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC),
 				Category:          &models.Category{Name: "Food"},
 				WorthIt:           &worthIt,
+				Source:            models.ExpenseSourceReceipt,
 			},
 			{
 				ID:                2,
@@ -45,15 +61,12 @@ func TestGenerateExpensesCSV(t *testing.T) {
 		require.NoError(t, err)
 		require.NotEmpty(t, csvData)
 
-		// Parse CSV
-		reader := csv.NewReader(strings.NewReader(string(csvData)))
-		records, err := reader.ReadAll()
-		require.NoError(t, err)
+		records := csvExpenseRecords(t, csvData)
 		require.Len(t, records, 3) // Header + 2 rows
 
 		// Verify header
 		header := records[0]
-		require.Equal(t, []string{"ID", "Date", "Amount", "Currency", "Description", "Merchant", "Category", "Worth It"}, header)
+		require.Equal(t, []string{"ID", "Date", "Amount", "Currency", "Description", "Merchant", "Category", "Worth It", "Reimbursable", "Type", "Source"}, header)
 
 		// Verify first row
 		row1 := records[1]
@@ -65,6 +78,7 @@ func TestGenerateExpensesCSV(t *testing.T) {
 		require.Empty(t, row1[5]) // Merchant
 		require.Equal(t, "Food", row1[6])
 		require.Equal(t, "Worth it", row1[7])
+		require.Equal(t, "receipt", row1[10])
 
 		// Verify second row
 		row2 := records[2]
@@ -124,6 +138,52 @@ func TestGenerateExpensesCSV(t *testing.T) {
 			"empty-name Category should fall back to Uncategorized, not an empty cell")
 	})
 
+	t.Run("appends per-currency totals and category subtotals", func(t *testing.T) {
+		t.Parallel()
+		at := time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC)
+		food := &models.Category{Name: "Food"}
+		expenses := []models.Expense{
+			{UserExpenseNumber: 1, Amount: decimal.RequireFromString("10.50"), Currency: "SGD", Category: food, CreatedAt: at},
+			{UserExpenseNumber: 2, Amount: decimal.RequireFromString("4.00"), Currency: "USD", Category: food, CreatedAt: at},
+			{UserExpenseNumber: 3, Amount: decimal.RequireFromString("2.25"), Currency: "SGD", CreatedAt: at},
+			{UserExpenseNumber: 4, Amount: decimal.RequireFromString("1.75"), Currency: "SGD", Category: food, CreatedAt: at},
+		}
+
+		csvData, err := GenerateExpensesCSV(expenses)
+		require.NoError(t, err)
+
+		records, err := csv.NewReader(bytes.NewReader(csvData)).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, [][]string{
+			{"", "", "", "", "", "", "", "", "", "", ""},
+			{csvSummaryTotal, "", "14.50", "SGD", "", "", "", "", "", "", ""},
+			{csvSummaryTotal, "", "4.00", "USD", "", "", "", "", "", "", ""},
+			{csvSummarySubtotal, "", "12.25", "SGD", "", "", "Food", "", "", "", ""},
+			{csvSummarySubtotal, "", "4.00", "USD", "", "", "Food", "", "", "", ""},
+			{csvSummarySubtotal, "", "2.25", "SGD", "", "", categoryUncategorized, "", "", "", ""},
+		}, records[len(expenses)+1:])
+	})
+
+	t.Run("marks refunds and subtracts them from totals", func(t *testing.T) {
+		t.Parallel()
+		at := time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC)
+		expenses := []models.Expense{
+			{UserExpenseNumber: 1, Amount: decimal.RequireFromString("40.00"), Currency: "SGD", CreatedAt: at},
+			{UserExpenseNumber: 2, Amount: decimal.RequireFromString("-25.90"), Currency: "SGD", CreatedAt: at},
+		}
+
+		csvData, err := GenerateExpensesCSV(expenses)
+		require.NoError(t, err)
+
+		records, err := csv.NewReader(bytes.NewReader(csvData)).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, csvHeaderType, records[0][9])
+		require.Equal(t, csvTypeExpense, records[1][9])
+		require.Equal(t, "-25.90", records[2][2])
+		require.Equal(t, csvTypeRefund, records[2][9])
+		require.Equal(t, []string{csvSummaryTotal, "", "14.10", "SGD", "", "", "", "", "", "", ""}, records[4])
+	})
+
 	t.Run("handles empty expense list", func(t *testing.T) {
 		t.Parallel()
 		expenses := []models.Expense{}
@@ -134,7 +194,7 @@ func TestGenerateExpensesCSV(t *testing.T) {
 		reader := csv.NewReader(strings.NewReader(string(csvData)))
 		records, err := reader.ReadAll()
 		require.NoError(t, err)
-		require.Len(t, records, 1) // Only header
+		require.Len(t, records, 1) // Only header, no summary
 	})
 
 	t.Run("handles special characters in description", func(t *testing.T) {
@@ -189,6 +249,77 @@ func TestGenerateExpensesCSV(t *testing.T) {
 	})
 }
 
+func TestGenerateExpensesCSVStream(t *testing.T) {
+	t.Parallel()
+
+	t.Run("streams many batches", func(t *testing.T) {
+		t.Parallel()
+		const total, batchSize = 10_000, 500
+
+		created := time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC)
+		sent, batches := 0, 0
+		next := func() ([]models.Expense, error) {
+			n := min(batchSize, total-sent)
+			batch := make([]models.Expense, n)
+			for i := range batch {
+				sent++
+				batch[i] = models.Expense{
+					ID:                sent,
+					UserExpenseNumber: int64(sent),
+					Amount:            decimal.NewFromInt(1),
+					Currency:          "SGD",
+					Description:       "Synthetic",
+					CreatedAt:         created,
+				}
+			}
+			batches++
+			return batch, nil
+		}
+
+		var buf bytes.Buffer
+		count, err := GenerateExpensesCSVStream(&buf, next)
+		require.NoError(t, err)
+		require.Equal(t, total, count)
+		require.Equal(t, total/batchSize+1, batches)
+
+		records := csvExpenseRecords(t, buf.Bytes())
+		require.Len(t, records, total+1)
+		require.Equal(t, "10000", records[total][0])
+		require.Contains(t, buf.String(), "Total,,10000.00,SGD")
+	})
+
+	t.Run("matches GenerateExpensesCSV", func(t *testing.T) {
+		t.Parallel()
+		expenses := []models.Expense{
+			{UserExpenseNumber: 1, Amount: decimal.NewFromInt(3), Currency: "SGD", Category: &models.Category{Name: "Food"}},
+			{UserExpenseNumber: 2, Amount: decimal.NewFromInt(4), Currency: "USD"},
+		}
+		want, err := GenerateExpensesCSV(expenses)
+		require.NoError(t, err)
+
+		remaining := [][]models.Expense{expenses[:1], expenses[1:]}
+		var buf bytes.Buffer
+		_, err = GenerateExpensesCSVStream(&buf, func() ([]models.Expense, error) {
+			if len(remaining) == 0 {
+				return nil, nil
+			}
+			batch := remaining[0]
+			remaining = remaining[1:]
+			return batch, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, string(want), buf.String())
+	})
+
+	t.Run("returns batch errors", func(t *testing.T) {
+		t.Parallel()
+		_, err := GenerateExpensesCSVStream(&bytes.Buffer{}, func() ([]models.Expense, error) {
+			return nil, errors.New("db down")
+		})
+		require.ErrorContains(t, err, "db down")
+	})
+}
+
 func TestSanitizeCSVCell(t *testing.T) {
 	t.Parallel()
 
@@ -218,6 +349,16 @@ func TestSanitizeCSVCell(t *testing.T) {
 	}
 }
 
+func TestReimbursableCSVCell(t *testing.T) {
+	t.Parallel()
+
+	reimbursedAt := time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)
+	require.Empty(t, reimbursableCSVCell(&models.Expense{}))
+	require.Equal(t, "Awaiting", reimbursableCSVCell(&models.Expense{Reimbursable: true}))
+	require.Equal(t, "Reimbursed 2026-03-05",
+		reimbursableCSVCell(&models.Expense{Reimbursable: true, ReimbursedAt: &reimbursedAt}))
+}
+
 func TestGetWeekDateRangeAt(t *testing.T) {
 	t.Parallel()
 
@@ -269,14 +410,21 @@ func TestGetMonthDateRangeAt(t *testing.T) {
 func TestGenerateReportFilename(t *testing.T) {
 	t.Parallel()
 
-	t.Run("generates week filename with start date", func(t *testing.T) {
+	t.Run("generates week filename with ISO week", func(t *testing.T) {
 		t.Parallel()
 		loc := time.UTC
 		now := time.Date(2026, 1, 14, 10, 30, 0, 0, loc)
 		filename := generateReportFilename("week", loc, now)
-		require.Contains(t, filename, "expenses_week_")
-		require.Contains(t, filename, ".csv")
-		require.Regexp(t, `expenses_week_\d{4}-\d{2}-\d{2}\.csv`, filename)
+		require.Equal(t, "expenses_2026-W03.csv", filename)
+	})
+
+	t.Run("names the ISO year at year boundaries", func(t *testing.T) {
+		t.Parallel()
+		loc := time.UTC
+		require.Equal(t, "expenses_2025-W01.csv",
+			generateReportFilename("week", loc, time.Date(2024, 12, 31, 10, 0, 0, 0, loc)))
+		require.Equal(t, "expenses_2020-W53.csv",
+			generateReportFilename("week", loc, time.Date(2021, 1, 2, 10, 0, 0, 0, loc)))
 	})
 
 	t.Run("generates month filename with year-month", func(t *testing.T) {
@@ -299,6 +447,17 @@ func TestGenerateReportFilename(t *testing.T) {
 	})
 }
 
+func TestReportCategoryFilename(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "expenses_month_2026-03_work-travel.csv",
+		reportCategoryFilename("expenses_month_2026-03.csv", "Work Travel"))
+	require.Equal(t, "expenses_2026-W09_food-dining-out.csv",
+		reportCategoryFilename("expenses_2026-W09.csv", "Food - Dining Out"))
+	require.Equal(t, "expenses_month_2026-03_category.csv",
+		reportCategoryFilename("expenses_month_2026-03.csv", "☕/../"))
+}
+
 func TestGetDayDateRangeAtDSTSafe(t *testing.T) {
 	t.Parallel()
 
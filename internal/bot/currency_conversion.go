@@ -2,61 +2,17 @@ package bot
 
 import (
 	"context"
-	"fmt"
-	"strings"
 
 	"github.com/shopspring/decimal"
 	"gitlab.com/yelinaung/expense-bot/internal/logger"
 	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
 )
 
-func normalizeCurrencyCode(code string) string {
-	return strings.ToUpper(strings.TrimSpace(code))
-}
-
-func getCurrencyOrCodeSymbol(code string) string {
-	symbol := appmodels.SupportedCurrencies[code]
-	if symbol == "" {
-		return code
-	}
-	return symbol
-}
-
-func appendOriginalAmountDescription(
-	description string,
-	originalAmount decimal.Decimal,
-	originalCurrency string,
-	convertedAmount decimal.Decimal,
-	convertedCurrency string,
-	rate decimal.Decimal,
-	rateDate string,
-) string {
-	metadata := fmt.Sprintf(
-		"[orig: %s %s -> %s %s @ %s (%s)]",
-		originalAmount.StringFixed(2),
-		originalCurrency,
-		convertedAmount.StringFixed(2),
-		convertedCurrency,
-		rate.StringFixed(4),
-		rateDate,
-	)
-	if strings.TrimSpace(description) == "" {
-		return metadata
-	}
-	return description + " " + metadata
-}
-
-func appendConversionUnavailableDescription(
-	description, originalCurrency, targetCurrency string,
-) string {
-	metadata := fmt.Sprintf("[fx_unavailable: kept %s, target %s]", originalCurrency, targetCurrency)
-	if strings.TrimSpace(description) == "" {
-		return metadata
-	}
-	return description + " " + metadata
-}
-
 func (b *Bot) getUserDefaultCurrency(ctx context.Context, userID int64) string {
+	if b.userRepo == nil {
+		return appmodels.DefaultCurrency
+	}
 	currency, err := b.userRepo.GetDefaultCurrency(ctx, userID)
 	if err != nil {
 		logger.Log.Debug().
@@ -66,63 +22,78 @@ func (b *Bot) getUserDefaultCurrency(ctx context.Context, userID int64) string {
 		return appmodels.DefaultCurrency
 	}
 
-	currency = normalizeCurrencyCode(currency)
+	currency = service.NormalizeCurrencyCode(currency)
 	if _, ok := appmodels.SupportedCurrencies[currency]; !ok {
 		return appmodels.DefaultCurrency
 	}
 	return currency
 }
 
-func (b *Bot) convertExpenseCurrency(
-	ctx context.Context,
-	userID int64,
-	amount decimal.Decimal,
-	sourceCurrency string,
-	description string,
-) (convertedAmount decimal.Decimal, finalCurrency, finalDescription string) {
-	defaultCurrency := b.getUserDefaultCurrency(ctx, userID)
-	source := normalizeCurrencyCode(sourceCurrency)
-	if source == "" {
-		source = defaultCurrency
+// keepConversion stores the conversion of an expense whose amount changed
+// again, at the rate it was recorded with; saving the new amount cleared
+// it. Expenses without a recorded conversion, or whose currency changed,
+// are left without one.
+func (b *Bot) keepConversion(ctx context.Context, before appmodels.Expense, expense *appmodels.Expense) {
+	expense.RateToDefault = decimal.NullDecimal{}
+	expense.ConvertedAmount = decimal.NullDecimal{}
+	expense.ConvertedCurrency = ""
+	if !before.RateToDefault.Valid || !before.ConvertedAmount.Valid || before.Currency != expense.Currency {
+		return
 	}
-	if _, ok := appmodels.SupportedCurrencies[source]; !ok {
-		logger.Log.Warn().
-			Str("source_currency", source).
-			Str("user_hash", logger.HashUserID(userID)).
-			Msg("Unsupported currency from input/LLM; using default currency")
-		source = defaultCurrency
+
+	// Drafts converted when they were saved are already in the default
+	// currency; backfilled ones still need the rate applied.
+	converted := expense.Amount
+	if before.ConvertedCurrency != expense.Currency {
+		converted = expense.Amount.Mul(before.RateToDefault.Decimal).
+			Round(appmodels.CurrencyMinorUnits(before.ConvertedCurrency))
 	}
-	if source == defaultCurrency {
-		return amount, defaultCurrency, description
+	if _, err := b.expenses().SetConversion(
+		ctx, expense.ID, before.RateToDefault.Decimal, converted, before.ConvertedCurrency,
+	); err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to keep expense conversion")
+		return
 	}
+	expense.RateToDefault = before.RateToDefault
+	expense.ConvertedAmount = decimal.NewNullDecimal(converted)
+	expense.ConvertedCurrency = before.ConvertedCurrency
+}
+
+// applyLiveConversions gives expenses that are not in the user's default
+// currency and have no recorded conversion one at today's rate, so report
+// totals can combine them. Nothing is saved: /backfillrates stores the
+// historical rate instead. Expenses whose rate cannot be fetched keep their
+// own currency.
+func (b *Bot) applyLiveConversions(ctx context.Context, userID int64, expenses []appmodels.Expense) {
 	if b.exchangeService == nil {
-		logger.Log.Warn().
-			Str("source_currency", source).
-			Str("target_currency", defaultCurrency).
-			Str("user_hash", logger.HashUserID(userID)).
-			Msg("Exchange service unavailable; saving original currency")
-		return amount, source, appendConversionUnavailableDescription(description, source, defaultCurrency)
+		return
 	}
-
-	result, err := b.exchangeService.Convert(ctx, amount, source, defaultCurrency)
-	if err != nil {
-		logger.Log.Warn().
-			Err(err).
-			Str("source_currency", source).
-			Str("target_currency", defaultCurrency).
-			Str("user_hash", logger.HashUserID(userID)).
-			Msg("Exchange lookup failed; saving original currency")
-		return amount, source, appendConversionUnavailableDescription(description, source, defaultCurrency)
+	defaultCurrency := b.getUserDefaultCurrency(ctx, userID)
+	rates := make(map[string]decimal.NullDecimal)
+	for i := range expenses {
+		e := &expenses[i]
+		if e.ConvertedAmount.Valid || e.Currency == defaultCurrency {
+			continue
+		}
+		rate, seen := rates[e.Currency]
+		if !seen {
+			result, err := b.exchangeService.Convert(ctx, decimal.NewFromInt(1), e.Currency, defaultCurrency)
+			if err != nil {
+				logger.Log.Warn().
+					Err(err).
+					Str("source_currency", e.Currency).
+					Str("target_currency", defaultCurrency).
+					Msg("Live rate unavailable for report; keeping original currency")
+			} else {
+				rate = decimal.NewNullDecimal(result.Rate)
+			}
+			rates[e.Currency] = rate
+		}
+		if !rate.Valid {
+			continue
+		}
+		e.ConvertedAmount = decimal.NewNullDecimal(
+			e.Amount.Mul(rate.Decimal).Round(appmodels.CurrencyMinorUnits(defaultCurrency)))
+		e.ConvertedCurrency = defaultCurrency
 	}
-
-	finalDescription = appendOriginalAmountDescription(
-		description,
-		amount,
-		source,
-		result.Amount,
-		defaultCurrency,
-		result.Rate,
-		result.RateDate.Format("2006-01-02"),
-	)
-	return result.Amount, defaultCurrency, finalDescription
 }
@@ -54,16 +54,10 @@ func TestConvertExpenseCurrency(t *testing.T) {
 		mockSvc := &mockExchangeService{}
 		b.exchangeService = mockSvc
 
-		amount, currency, description := b.convertExpenseCurrency(
-			ctx,
-			userID,
-			decimal.RequireFromString("18"),
-			"SGD",
-			"Lunch",
-		)
-		require.Equal(t, decimal.RequireFromString("18"), amount)
-		require.Equal(t, "SGD", currency)
-		require.Equal(t, "Lunch", description)
+		conversion := b.expenses().ConvertToDefault(ctx, userID, decimal.RequireFromString("18"), "SGD", "Lunch")
+		require.Equal(t, decimal.RequireFromString("18"), conversion.Amount)
+		require.Equal(t, "SGD", conversion.Currency)
+		require.Equal(t, "Lunch", conversion.Description)
 		require.Equal(t, 0, mockSvc.calls)
 	})
 
@@ -77,17 +71,13 @@ func TestConvertExpenseCurrency(t *testing.T) {
 		}
 		b.exchangeService = mockSvc
 
-		amount, currency, description := b.convertExpenseCurrency(
-			ctx,
-			userID,
-			decimal.RequireFromString("18"),
-			"USD",
-			valentineRosesDesc,
-		)
-		require.Equal(t, decimal.RequireFromString("24.30"), amount)
-		require.Equal(t, "SGD", currency)
-		require.Contains(t, description, valentineRosesDesc)
-		require.Contains(t, description, "[orig: 18.00 USD -> 24.30 SGD @ 1.3500 (2026-02-14)]")
+		conversion := b.expenses().ConvertToDefault(ctx, userID, decimal.RequireFromString("18"), "USD", valentineRosesDesc)
+		require.True(t, conversion.Rate.Valid)
+		require.True(t, decimal.RequireFromString("1.35").Equal(conversion.Rate.Decimal))
+		require.Equal(t, decimal.RequireFromString("24.30"), conversion.Amount)
+		require.Equal(t, "SGD", conversion.Currency)
+		require.Contains(t, conversion.Description, valentineRosesDesc)
+		require.Contains(t, conversion.Description, "[orig: 18.00 USD -> 24.30 SGD @ 1.3500 (2026-02-14)]")
 		require.Equal(t, 1, mockSvc.calls)
 	})
 
@@ -95,16 +85,10 @@ func TestConvertExpenseCurrency(t *testing.T) {
 		mockSvc := &mockExchangeService{err: errors.New("rate unavailable")}
 		b.exchangeService = mockSvc
 
-		amount, currency, description := b.convertExpenseCurrency(
-			ctx,
-			userID,
-			decimal.RequireFromString("18"),
-			"USD",
-			valentineRosesDesc,
-		)
-		require.Equal(t, decimal.RequireFromString("18"), amount)
-		require.Equal(t, "USD", currency)
-		require.Contains(t, description, fxUnavailableNote)
+		conversion := b.expenses().ConvertToDefault(ctx, userID, decimal.RequireFromString("18"), "USD", valentineRosesDesc)
+		require.Equal(t, decimal.RequireFromString("18"), conversion.Amount)
+		require.Equal(t, "USD", conversion.Currency)
+		require.Contains(t, conversion.Description, fxUnavailableNote)
 		require.Equal(t, 1, mockSvc.calls)
 	})
 }
@@ -122,6 +106,7 @@ func TestSaveExpenseCore_ConvertsCurrencyToDefault(t *testing.T) {
 		DefaultCurrency: "SGD",
 	})
 	require.NoError(t, err)
+	seedCurrencyHistory(ctx, t, b, userID, "USD")
 
 	b.exchangeService = &mockExchangeService{
 		result: exchange.ConversionResult{
@@ -152,6 +137,9 @@ func TestSaveExpenseCore_ConvertsCurrencyToDefault(t *testing.T) {
 	require.True(t, decimal.RequireFromString("24.30").Equal(expenses[0].Amount))
 	require.Contains(t, expenses[0].Description, "[orig: 18.00 USD -> 24.30 SGD @ 1.3500 (2026-02-14)]")
 	require.Equal(t, valentineRosesDesc, expenses[0].Merchant)
+	require.True(t, decimal.RequireFromString("1.35").Equal(expenses[0].RateToDefault.Decimal))
+	require.True(t, decimal.RequireFromString("24.30").Equal(expenses[0].ConvertedAmount.Decimal))
+	require.Equal(t, "SGD", expenses[0].ConvertedCurrency)
 }
 
 func TestSaveExpenseCore_ExchangeOutageDoesNotBlockSave(t *testing.T) {
@@ -167,6 +155,7 @@ func TestSaveExpenseCore_ExchangeOutageDoesNotBlockSave(t *testing.T) {
 		DefaultCurrency: "SGD",
 	})
 	require.NoError(t, err)
+	seedCurrencyHistory(ctx, t, b, userID, "USD")
 
 	b.exchangeService = &mockExchangeService{err: errors.New("service down")}
 
@@ -191,4 +180,6 @@ func TestSaveExpenseCore_ExchangeOutageDoesNotBlockSave(t *testing.T) {
 	require.True(t, decimal.RequireFromString("18").Equal(expenses[0].Amount))
 	require.Contains(t, expenses[0].Description, fxUnavailableNote)
 	require.Equal(t, valentineRosesDesc, expenses[0].Merchant)
+	require.False(t, expenses[0].RateToDefault.Valid)
+	require.False(t, expenses[0].ConvertedAmount.Valid)
 }
@@ -1,6 +1,19 @@
 package bot
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// errDateRangeReversed is returned when a date range ends before it
+	// starts.
+	errDateRangeReversed = errors.New("end date is before start date")
+	// errDateRangeTooLong is returned when a date range spans more days
+	// than allowed.
+	errDateRangeTooLong = errors.New("date range is too long")
+)
 
 // normalizeLocation returns loc, or runtime local timezone when loc is nil.
 func normalizeLocation(loc *time.Location) *time.Location {
@@ -85,3 +98,100 @@ func getPreviousWeekRangeAt(current time.Time) (time.Time, time.Time) {
 	start, end := getWeekDateRangeAt(current)
 	return start.AddDate(0, 0, -7), end.AddDate(0, 0, -7)
 }
+
+// resolveMonthArgAt resolves an optional YYYY-MM argument to a month range
+// as [start, end). An empty argument selects the current month. Months
+// after the current one are rejected. current must already be in the
+// desired display location.
+func resolveMonthArgAt(arg string, current time.Time) (time.Time, time.Time, error) {
+	if arg == "" {
+		start, end := getMonthDateRangeAt(current)
+		return start, end, nil
+	}
+
+	month, err := time.ParseInLocation("2006-01", arg, current.Location())
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid month %q: %w", arg, err)
+	}
+
+	currentStart, _ := getMonthDateRangeAt(current)
+	if month.After(currentStart) {
+		return time.Time{}, time.Time{}, fmt.Errorf("month %q is in the future", arg)
+	}
+
+	start, end := getMonthDateRangeAt(month)
+	return start, end, nil
+}
+
+// resolveYearArgAt resolves an optional YYYY argument to a year range as
+// [start, end). An empty argument selects the current year. Years after
+// the current one are rejected. current must already be in the desired
+// display location.
+func resolveYearArgAt(arg string, current time.Time) (time.Time, time.Time, error) {
+	year := current.Year()
+	if arg != "" {
+		parsed, err := time.ParseInLocation("2006", arg, current.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid year %q: %w", arg, err)
+		}
+		if parsed.Year() > year {
+			return time.Time{}, time.Time{}, fmt.Errorf("year %q is in the future", arg)
+		}
+		year = parsed.Year()
+	}
+
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, current.Location())
+	return start, start.AddDate(1, 0, 0), nil
+}
+
+// resolveDateRangeArgsAt resolves two inclusive YYYY-MM-DD arguments to a
+// [start, end) range in loc, so end is midnight after the last day. Ranges
+// that end before they start or span more than maxDays days are rejected.
+func resolveDateRangeArgsAt(startArg, endArg string, loc *time.Location, maxDays int) (time.Time, time.Time, error) {
+	loc = normalizeLocation(loc)
+	start, err := time.ParseInLocation(time.DateOnly, startArg, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date %q: %w", startArg, err)
+	}
+	last, err := time.ParseInLocation(time.DateOnly, endArg, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date %q: %w", endArg, err)
+	}
+	if last.Before(start) {
+		return time.Time{}, time.Time{}, errDateRangeReversed
+	}
+
+	end := last.AddDate(0, 0, 1)
+	if end.After(start.AddDate(0, 0, maxDays)) {
+		return time.Time{}, time.Time{}, errDateRangeTooLong
+	}
+	return start, end, nil
+}
+
+// periodRange returns the [start, end) range of the day, week, or month
+// that is offset periods away from the one containing now, in loc. Negative
+// offsets go back in time. Weeks start on weekStart.
+func periodRange(
+	periodType string,
+	offset int,
+	weekStart time.Weekday,
+	loc *time.Location,
+	now time.Time,
+) (time.Time, time.Time, error) {
+	current := now.In(normalizeLocation(loc))
+	switch periodType {
+	case periodDay:
+		start, _ := getDayDateRangeAt(current)
+		start = start.AddDate(0, 0, offset)
+		return start, start.AddDate(0, 0, 1), nil
+	case periodWeek:
+		today, _ := getDayDateRangeAt(current)
+		daysSinceStart := (int(current.Weekday()) - int(weekStart) + 7) % 7
+		start := today.AddDate(0, 0, 7*offset-daysSinceStart)
+		return start, start.AddDate(0, 0, 7), nil
+	case periodMonth:
+		start := time.Date(current.Year(), current.Month()+time.Month(offset), 1, 0, 0, 0, 0, current.Location())
+		return start, start.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unknown period type %q", periodType)
+}
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// maxDescriptionLength returns the configured description and merchant
+// limit, falling back to the default when unset (e.g. tests that construct
+// a Bot without full config).
+func (b *Bot) maxDescriptionLength() int {
+	if b.cfg != nil && b.cfg.MaxDescriptionLength > 0 {
+		return b.cfg.MaxDescriptionLength
+	}
+	return appmodels.DefaultMaxDescriptionLength
+}
+
+// normalizeDescription cleans a description or merchant and cuts it to the
+// configured length. The second result reports whether it was cut.
+func (b *Bot) normalizeDescription(text string) (string, bool) {
+	return appmodels.NormalizeText(text, b.maxDescriptionLength())
+}
+
+// descriptionTruncatedNote tells the user a long description was shortened.
+func (b *Bot) descriptionTruncatedNote() string {
+	return fmt.Sprintf("✂️ Description truncated to %d characters.", b.maxDescriptionLength())
+}
+
+// maxEchoLength is the longest piece of user text, in characters, repeated
+// back in a reply such as "Category … not found".
+const maxEchoLength = 64
+
+// echoText prepares user text for repeating back in a reply: whitespace is
+// collapsed and text longer than maxEchoLength is cut with an ellipsis.
+func echoText(text string) string {
+	echo, _ := appmodels.NormalizeText(text, maxEchoLength)
+	return echo
+}
+
+// maxFreeTextLength returns the configured free-text limit, falling back to
+// the default when unset.
+func (b *Bot) maxFreeTextLength() int {
+	if b.cfg != nil && b.cfg.MaxFreeTextLength > 0 {
+		return b.cfg.MaxFreeTextLength
+	}
+	return config.DefaultMaxFreeTextLength
+}
+
+// tooLongForFreeText reports whether a message is over the free-text limit
+// and must not be run through the expense or bank parsers.
+func (b *Bot) tooLongForFreeText(text string) bool {
+	limit := b.maxFreeTextLength()
+	// A character takes at least one byte, so short messages skip counting.
+	return len(text) > limit && utf8.RuneCountInString(text) > limit
+}
@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+)
+
+func TestEchoText(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "Coffee beans", echoText("  Coffee\n beans "))
+
+	echo := echoText(strings.Repeat("word ", 600))
+	require.Equal(t, maxEchoLength, utf8.RuneCountInString(echo))
+	require.True(t, strings.HasSuffix(echo, "…"))
+
+	echo = echoText(strings.Repeat("咖啡", 100))
+	require.True(t, utf8.ValidString(echo))
+	require.Equal(t, maxEchoLength, utf8.RuneCountInString(echo))
+}
+
+func TestTooLongForFreeText(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{cfg: &config.Config{MaxFreeTextLength: 100}}
+	require.False(t, b.tooLongForFreeText(strings.Repeat("a", 100)))
+	require.True(t, b.tooLongForFreeText(strings.Repeat("a", 101)))
+	require.False(t, b.tooLongForFreeText(strings.Repeat("é", 100)), "counts characters, not bytes")
+
+	require.Equal(t, config.DefaultMaxFreeTextLength, (&Bot{}).maxFreeTextLength())
+}
+
+// TestOversizedMessagesSkipParsing checks that long messages return before
+// any repository is used; the bot below has none, so reaching the parser
+// would panic.
+func TestOversizedMessagesSkipParsing(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := &Bot{cfg: &config.Config{MaxFreeTextLength: config.DefaultMaxFreeTextLength}}
+	article := "12.50 " + strings.Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit. ", 300)
+
+	t.Run("free text", func(t *testing.T) {
+		t.Parallel()
+		update := mocks.MessageUpdate(1, 1, article)
+		require.False(t, b.handleFreeTextExpense(ctx, nil, update))
+	})
+
+	t.Run("forwarded bank message", func(t *testing.T) {
+		t.Parallel()
+		mockBot := mocks.NewMockBot()
+		b.handleBankMessageCore(ctx, mockBot, forwardedUpdate(1, 1, article))
+		require.Zero(t, mockBot.SentMessageCount())
+	})
+
+	t.Run("photo caption", func(t *testing.T) {
+		t.Parallel()
+		mockBot := mocks.NewMockBot()
+		update := forwardedUpdate(1, 1, "")
+		update.Message.Caption = article
+		b.handleBankMessageCore(ctx, mockBot, update)
+		require.Zero(t, mockBot.SentMessageCount())
+	})
+
+	t.Run("edited message", func(t *testing.T) {
+		t.Parallel()
+		mockBot := mocks.NewMockBot()
+		update := mocks.MessageUpdate(1, 1, article)
+		update.EditedMessage, update.Message = update.Message, nil
+		update.EditedMessage.Date = int(time.Now().Unix())
+		b.handleEditedMessageCore(ctx, mockBot, update)
+		require.Zero(t, mockBot.SentMessageCount())
+	})
+}
@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/exchange"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
+)
+
+// expensePipeline gives the expense service the lookups that live on the
+// bot: user settings, exchange rates, Gemini with its quotas, and budgets.
+type expensePipeline struct {
+	b *Bot
+}
+
+// pipeline returns the lookups the expense service builds new expenses
+// with. Steps whose dependency is missing are left off.
+func (b *Bot) pipeline() service.Pipeline {
+	p := expensePipeline{b: b}
+	pipeline := service.Pipeline{Currencies: p, Categories: p}
+	if b.geminiClient != nil {
+		pipeline.AI = p
+	}
+	if b.tagRepo != nil {
+		pipeline.Tags = b.tagRepo
+	}
+	if b.budgetRepo != nil {
+		pipeline.Budgets = p
+	}
+	return pipeline
+}
+
+func (p expensePipeline) DefaultCurrency(ctx context.Context, userID int64) string {
+	return p.b.getUserDefaultCurrency(ctx, userID)
+}
+
+func (p expensePipeline) Exchange() exchange.Converter {
+	return p.b.exchangeService
+}
+
+// Unavailable checks the user's AI setting, the AI minimum amount and the
+// monthly token budget.
+func (p expensePipeline) Unavailable(ctx context.Context, expense *appmodels.Expense) string {
+	b := p.b
+	if !b.aiEnabled(ctx, expense.UserID) {
+		return service.AIStatusTurnedOff
+	}
+	minAmount, _ := b.aiSuggestLimits()
+	if b.belowAISuggestMinimum(ctx, expense, minAmount) {
+		b.aiSuggestQuota.skipBelowMinimum()
+		logger.Log.Debug().
+			Str("user_hash", logger.HashUserID(expense.UserID)).
+			Str("amount", expense.Amount.String()).
+			Msg("AI category suggestion skipped: amount below minimum")
+		return service.AIStatusBelowMinAmount
+	}
+	if b.aiBudgetExhausted(ctx) {
+		return service.AIStatusBudgetExhausted
+	}
+	return ""
+}
+
+func (p expensePipeline) TakeQuota(ctx context.Context, userID int64) bool {
+	b := p.b
+	_, dailyCap := b.aiSuggestLimits()
+	if !b.aiSuggestQuota.take(userID, b.aiSuggestDay(), dailyCap, b.aiSuggestStored(ctx, userID)) {
+		logger.Log.Info().
+			Str("user_hash", logger.HashUserID(userID)).
+			Int("daily_cap", dailyCap).
+			Msg("AI category suggestion skipped: daily cap reached")
+		return false
+	}
+	return true
+}
+
+func (p expensePipeline) SuggestCategory(
+	ctx context.Context,
+	userID int64,
+	description string,
+	categories []string,
+) (*gemini.CategorySuggestion, error) {
+	suggestion, err := p.b.geminiClient.SuggestCategory(gemini.WithUsageUser(ctx, userID), description, categories)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest category: %w", err)
+	}
+	return suggestion, nil
+}
+
+// CreateCategory creates a category Gemini suggested. When another request
+// created it first, the existing category is returned.
+func (p expensePipeline) CreateCategory(ctx context.Context, userID int64, name string) (*appmodels.Category, error) {
+	category, err := p.b.createUserCategory(ctx, userID, name)
+	if err != nil {
+		if existing, getErr := p.b.findUserCategory(ctx, userID, name); getErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	return category, nil
+}
+
+// HardBudget loads the budget of the expense's category. Lookup failures
+// are logged and let the expense through.
+func (p expensePipeline) HardBudget(ctx context.Context, expense *appmodels.Expense) *service.HardBudget {
+	converter := p.b.newBudgetConverter()
+	status, err := p.b.loadBudgetStatus(ctx, converter, expense.UserID, *expense.CategoryID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Log.Warn().Err(err).Int("category_id", *expense.CategoryID).Msg("Failed to check budget")
+		}
+		return nil
+	}
+	if !status.blocks() {
+		return nil
+	}
+	amount, ok := converter.convertExpense(ctx, expense, status.Currency)
+	if !ok {
+		return nil
+	}
+	return &service.HardBudget{
+		CategoryID:   status.CategoryID,
+		CategoryName: status.CategoryName,
+		Currency:     status.Currency,
+		Limit:        status.Amount,
+		Spent:        status.Spent,
+		Expense:      amount,
+	}
+}
@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// fileLinkTTL is how long a Telegram file download link is reused. Telegram
+// guarantees links for at least an hour; the margin keeps a link fetched
+// just before expiry from being handed out.
+const fileLinkTTL = 55 * time.Minute
+
+type fileLinkEntry struct {
+	url       string
+	expiresAt time.Time
+}
+
+type fileLinkCall struct {
+	done chan struct{}
+	url  string
+	err  error
+}
+
+// fileLinkCache maps Telegram file IDs to download links so re-downloading
+// a file skips the getFile call. Concurrent lookups of the same file share
+// one getFile call. The zero value is ready to use.
+type fileLinkCache struct {
+	mu          sync.Mutex
+	links       map[string]fileLinkEntry
+	inFlight    map[string]*fileLinkCall
+	lastCleanup time.Time
+}
+
+// get returns the cached link for fileID, or calls fetch once for all
+// concurrent callers. hit reports whether the link came from the cache.
+// Failed fetches are not cached.
+func (c *fileLinkCache) get(
+	ctx context.Context,
+	fileID string,
+	now time.Time,
+	fetch func(context.Context) (string, error),
+) (url string, hit bool, err error) {
+	c.mu.Lock()
+	if c.links == nil {
+		c.links = make(map[string]fileLinkEntry)
+		c.inFlight = make(map[string]*fileLinkCall)
+	}
+	if entry, ok := c.links[fileID]; ok && now.Before(entry.expiresAt) {
+		c.mu.Unlock()
+		return entry.url, true, nil
+	}
+
+	call, waiting := c.inFlight[fileID]
+	if !waiting {
+		call = &fileLinkCall{done: make(chan struct{})}
+		c.inFlight[fileID] = call
+		// Fetch detached from this caller so one cancelled request cannot
+		// fail the others waiting on the same file.
+		go c.fetchAndBroadcast(context.WithoutCancel(ctx), fileID, now, fetch, call)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return "", false, ctx.Err()
+	case <-call.done:
+		return call.url, false, call.err
+	}
+}
+
+func (c *fileLinkCache) fetchAndBroadcast(
+	ctx context.Context,
+	fileID string,
+	now time.Time,
+	fetch func(context.Context) (string, error),
+	call *fileLinkCall,
+) {
+	url, err := fetch(ctx)
+
+	c.mu.Lock()
+	if err == nil {
+		c.links[fileID] = fileLinkEntry{url: url, expiresAt: now.Add(fileLinkTTL)}
+		c.cleanupExpiredLocked(now)
+	}
+	call.url = url
+	call.err = err
+	delete(c.inFlight, fileID)
+	close(call.done)
+	c.mu.Unlock()
+}
+
+// forget drops the cached link for fileID, e.g. after Telegram rejected it.
+func (c *fileLinkCache) forget(fileID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.links, fileID)
+}
+
+func (c *fileLinkCache) cleanupExpiredLocked(now time.Time) {
+	if !c.lastCleanup.IsZero() && now.Sub(c.lastCleanup) < fileLinkTTL {
+		return
+	}
+	for fileID, entry := range c.links {
+		if !now.Before(entry.expiresAt) {
+			delete(c.links, fileID)
+		}
+	}
+	c.lastCleanup = now
+}
@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+)
+
+// countingFileBot counts GetFile calls and holds each one until release is
+// closed, so concurrent downloads overlap.
+type countingFileBot struct {
+	*mocks.MockBot
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (c *countingFileBot) GetFile(ctx context.Context, params *tgbot.GetFileParams) (*tgmodels.File, error) {
+	c.calls.Add(1)
+	<-c.release
+	return c.MockBot.GetFile(ctx, params)
+}
+
+func TestBotDownloadFile_CachesLink(t *testing.T) {
+	t.Parallel()
+	const fileID = "file-link-1"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("photo"))
+	}))
+	defer server.Close()
+
+	tg := &countingFileBot{MockBot: mocks.NewMockBot(), release: make(chan struct{})}
+	tg.FileDownloadLinkToReturn = server.URL
+
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	var offset atomic.Int64
+	b := &Bot{nowFunc: func() time.Time { return start.Add(time.Duration(offset.Load())) }}
+
+	var wg sync.WaitGroup
+	results := make([][]byte, 2)
+	errs := make([]error, 2)
+	for i := range 2 {
+		wg.Go(func() {
+			results[i], errs[i] = b.downloadFile(context.Background(), tg, fileID)
+		})
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(tg.release)
+	wg.Wait()
+	for i := range 2 {
+		require.NoError(t, errs[i])
+		require.Equal(t, []byte("photo"), results[i])
+	}
+	require.Equal(t, int32(1), tg.calls.Load())
+
+	_, err := b.downloadFile(context.Background(), tg, fileID)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), tg.calls.Load())
+
+	offset.Store(int64(fileLinkTTL))
+	_, err = b.downloadFile(context.Background(), tg, fileID)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), tg.calls.Load())
+}
+
+func TestFileLinkCache_ErrorsAreNotCached(t *testing.T) {
+	t.Parallel()
+
+	var cache fileLinkCache
+	now := time.Now()
+	calls := 0
+	fetch := func(context.Context) (string, error) {
+		calls++
+		if calls == 1 {
+			return "", errors.New("boom")
+		}
+		return "https://example.com/file", nil
+	}
+
+	_, hit, err := cache.get(context.Background(), "f", now, fetch)
+	require.Error(t, err)
+	require.False(t, hit)
+
+	url, hit, err := cache.get(context.Background(), "f", now, fetch)
+	require.NoError(t, err)
+	require.False(t, hit)
+	require.Equal(t, "https://example.com/file", url)
+
+	_, hit, err = cache.get(context.Background(), "f", now, fetch)
+	require.NoError(t, err)
+	require.True(t, hit)
+	require.Equal(t, 2, calls)
+
+	cache.forget("f")
+	_, hit, err = cache.get(context.Background(), "f", now, fetch)
+	require.NoError(t, err)
+	require.False(t, hit)
+	require.Equal(t, 3, calls)
+}
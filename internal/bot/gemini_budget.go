@@ -0,0 +1,293 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+const (
+	aiBudgetExhaustedMsg = "AI budget exhausted for this month."
+	usageStatsEmptyMsg   = "🤖 No Gemini calls this month."
+)
+
+// geminiUsageRecorder stores the token usage the Gemini client reports.
+type geminiUsageRecorder struct {
+	repo *repository.GeminiUsageRepository
+}
+
+// RecordUsage implements gemini.UsageRecorder. Failures are logged only:
+// the call itself already succeeded.
+func (r geminiUsageRecorder) RecordUsage(ctx context.Context, usage gemini.Usage) {
+	err := r.repo.Record(ctx, repository.GeminiUsage{
+		UserID:       usage.UserID,
+		Feature:      usage.Feature,
+		PromptTokens: usage.PromptTokens,
+		OutputTokens: usage.OutputTokens,
+		TotalTokens:  usage.TotalTokens,
+	})
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("feature", usage.Feature).Msg("Failed to record Gemini usage")
+	}
+}
+
+// geminiMonthStart returns the start of the current budget month in the
+// display time zone.
+func (b *Bot) geminiMonthStart() time.Time {
+	start, _ := getMonthDateRangeAt(b.now().In(b.displayLocation))
+	return start
+}
+
+// geminiTokenBudget returns the monthly token budget, or zero when there is
+// none.
+func (b *Bot) geminiTokenBudget() int64 {
+	if b.cfg == nil {
+		return 0
+	}
+	return b.cfg.GeminiMonthlyTokenBudget
+}
+
+// aiBudgetExhausted reports whether this month's Gemini tokens are used up.
+// A failed lookup counts as not exhausted, so a database hiccup does not
+// switch AI features off.
+func (b *Bot) aiBudgetExhausted(ctx context.Context) bool {
+	budget := b.geminiTokenBudget()
+	if budget == 0 || b.geminiUsageRepo == nil {
+		return false
+	}
+	used, err := b.geminiUsageRepo.TotalSince(ctx, b.geminiMonthStart())
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to check Gemini token budget")
+		return false
+	}
+	return used >= budget
+}
+
+// aiSuggestQuota counts each user's AI category suggestions of the current
+// day, and the suggestions skipped for a small amount or the daily cap
+// since the bot started. A user's daily count starts from the suggestions
+// stored in gemini_usage, so a restart does not reset the cap. The zero
+// value is ready to use.
+type aiSuggestQuota struct {
+	mu           sync.Mutex
+	day          string
+	calls        map[int64]int
+	belowMinimum int64
+	overDailyCap int64
+}
+
+// take reserves one of the limit suggestions userID gets on day, or reports
+// false when they are used up. stored is the day's suggestions recorded in
+// gemini_usage; it seeds the user's count the first time they are seen on
+// day, and the count kept here, which includes calls still in flight,
+// decides after that. A zero limit never refuses.
+func (q *aiSuggestQuota) take(userID int64, day string, limit, stored int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.day != day || q.calls == nil {
+		q.day = day
+		q.calls = make(map[int64]int)
+	}
+	if _, seen := q.calls[userID]; !seen {
+		q.calls[userID] = stored
+	}
+	if limit > 0 && q.calls[userID] >= limit {
+		q.overDailyCap++
+		return false
+	}
+	q.calls[userID]++
+	return true
+}
+
+// used returns how many suggestions userID had on day, falling back to
+// the stored count for a user take has not seen that day.
+func (q *aiSuggestQuota) used(userID int64, day string, stored int) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if count, seen := q.calls[userID]; seen && q.day == day {
+		return count
+	}
+	return stored
+}
+
+// skipBelowMinimum counts a suggestion skipped for a small amount.
+func (q *aiSuggestQuota) skipBelowMinimum() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.belowMinimum++
+}
+
+// skipped returns the suggestions skipped for a small amount and over the
+// daily cap.
+func (q *aiSuggestQuota) skipped() (belowMinimum, overDailyCap int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.belowMinimum, q.overDailyCap
+}
+
+// aiSuggestLimits returns the smallest amount worth an AI category
+// suggestion and the suggestions each user gets per day. Zero turns either
+// limit off.
+func (b *Bot) aiSuggestLimits() (decimal.Decimal, int) {
+	if b.cfg == nil {
+		return decimal.Zero, 0
+	}
+	return b.cfg.AISuggestMinAmount, b.cfg.AISuggestDailyCap
+}
+
+// aiSuggestDay is the day the daily suggestion cap counts, in the display
+// time zone.
+func (b *Bot) aiSuggestDay() string {
+	return b.now().In(normalizeLocation(b.displayLocation)).Format("2006-01-02")
+}
+
+// aiSuggestStored returns the category suggestions gemini_usage recorded
+// for userID since aiSuggestDay began. Without the repository, or when the
+// lookup fails, it returns zero and only this process's count applies.
+func (b *Bot) aiSuggestStored(ctx context.Context, userID int64) int {
+	if b.geminiUsageRepo == nil {
+		return 0
+	}
+	now := b.now().In(normalizeLocation(b.displayLocation))
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	count, err := b.geminiUsageRepo.CountForUserSince(ctx, userID, gemini.FeatureSuggest, dayStart)
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(userID)).
+			Msg("Failed to count AI category suggestions")
+		return 0
+	}
+	return count
+}
+
+// belowAISuggestMinimum reports whether an expense is too small for an AI
+// category suggestion. The minimum is in the user's default currency, so
+// the amount compared is the converted one; an expense left in another
+// currency, because no rate was available, is never below it.
+func (b *Bot) belowAISuggestMinimum(ctx context.Context, expense *appmodels.Expense, minAmount decimal.Decimal) bool {
+	if !minAmount.IsPositive() {
+		return false
+	}
+	amount, currency := expense.AmountInDefault()
+	if currency != b.getUserDefaultCurrency(ctx, expense.UserID) {
+		return false
+	}
+	return amount.LessThan(minAmount)
+}
+
+// aiSuggestSettingsText describes the AI category limits for /settings.
+func (b *Bot) aiSuggestSettingsText(ctx context.Context, userID int64) string {
+	if b.geminiClient == nil {
+		return "not configured"
+	}
+	if !b.aiEnabled(ctx, userID) {
+		return "off"
+	}
+	minAmount, dailyCap := b.aiSuggestLimits()
+	amountText := "any amount"
+	if minAmount.IsPositive() {
+		amountText = "amounts from " + botfmt.Money(minAmount, b.getUserDefaultCurrency(ctx, userID))
+	}
+	used := b.aiSuggestQuota.used(userID, b.aiSuggestDay(), b.aiSuggestStored(ctx, userID))
+	if dailyCap == 0 {
+		return fmt.Sprintf("%s, %d used today (no daily cap)", amountText, used)
+	}
+	return fmt.Sprintf("%s, %d of %d used today", amountText, used, dailyCap)
+}
+
+// formatAISuggestSkips reports the AI category suggestions skipped since
+// the bot started, or nothing when there were none.
+func formatAISuggestSkips(belowMinimum, overDailyCap int64) string {
+	if belowMinimum == 0 && overDailyCap == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n\nCategory suggestions skipped since start: %d below the minimum amount, "+
+		"%d over the daily cap", belowMinimum, overDailyCap)
+}
+
+// formatUsageStats renders this month's Gemini token usage per feature.
+func formatUsageStats(usage []repository.GeminiFeatureUsage, budget int64) string {
+	if len(usage) == 0 {
+		return usageStatsEmptyMsg
+	}
+
+	var total int64
+	for _, u := range usage {
+		total += u.TotalTokens
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🤖 <b>Gemini usage this month</b>\n\n")
+	if budget > 0 {
+		percent := total * 100 / budget
+		fmt.Fprintf(&sb, "Total: %d of %d tokens (%d%%)\n", total, budget, percent)
+		if total >= budget {
+			sb.WriteString("⚠️ " + aiBudgetExhaustedMsg + " AI features are off until the month rolls over.\n")
+		}
+	} else {
+		fmt.Fprintf(&sb, "Total: %d tokens (no monthly budget)\n", total)
+	}
+	sb.WriteString("\n")
+	for _, u := range usage {
+		fmt.Fprintf(&sb, "<code>%s</code>: %d tokens in %d calls\n", u.Feature, u.TotalTokens, u.Calls)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// handleUsageStats handles the /usagestats command.
+func (b *Bot) handleUsageStats(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleUsageStatsCore(ctx, tgBot, update)
+}
+
+// handleUsageStatsCore is the testable implementation of handleUsageStats.
+func (b *Bot) handleUsageStatsCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+
+	if !b.cfg.IsSuperAdmin(update.Message.From.ID, update.Message.From.Username) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   onlySuperadminsMsg,
+		})
+		return
+	}
+
+	usage, err := b.geminiUsageRepo.ByFeatureSince(ctx, b.geminiMonthStart())
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch Gemini usage")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to fetch Gemini usage.",
+		})
+		return
+	}
+
+	// The per-source breakdown is a side note; failing to count it leaves
+	// it out rather than failing the command.
+	var sources string
+	if counts, err := b.expenseRepo.CountBySource(ctx, 0); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to count expenses by source")
+	} else if by := formatSourceCounts(counts); by != "" {
+		sources = "\n\nExpenses by source: " + by
+	}
+
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text: formatUsageStats(usage, b.geminiTokenBudget()) + formatAISuggestSkips(b.aiSuggestQuota.skipped()) +
+			sources,
+		ParseMode: models.ParseModeHTML,
+	})
+}
@@ -0,0 +1,316 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
+	"google.golang.org/genai"
+)
+
+func TestFormatUsageStats(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, usageStatsEmptyMsg, formatUsageStats(nil, 1000))
+
+	usage := []repository.GeminiFeatureUsage{
+		{Feature: gemini.FeatureReceipt, Calls: 2, TotalTokens: 700},
+		{Feature: gemini.FeatureSuggest, Calls: 5, TotalTokens: 100},
+	}
+	text := formatUsageStats(usage, 1000)
+	require.Contains(t, text, "Total: 800 of 1000 tokens (80%)")
+	require.Contains(t, text, "<code>receipt</code>: 700 tokens in 2 calls")
+	require.Contains(t, text, "<code>suggest</code>: 100 tokens in 5 calls")
+	require.NotContains(t, text, aiBudgetExhaustedMsg)
+
+	require.Contains(t, formatUsageStats(usage, 800), aiBudgetExhaustedMsg)
+	require.Contains(t, formatUsageStats(usage, 0), "Total: 800 tokens (no monthly budget)")
+}
+
+func TestHandleUsageStatsCore_NoSender(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{cfg: &config.Config{}}
+	mockBot := mocks.NewMockBot()
+	update := mocks.CommandUpdate(100, 100, "/usagestats")
+	update.Message.From = nil
+	b.handleUsageStatsCore(context.Background(), mockBot, update)
+	require.Zero(t, mockBot.SentMessageCount())
+}
+
+func TestGeminiTokenBudget(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.cfg.WhitelistedUserIDs = []int64{100}
+	b.cfg.GeminiMonthlyTokenBudget = 1000
+
+	userID := int64(421001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Budget"}))
+
+	response := makeBotCategorySuggestionResponse(
+		`{"category":"Others","confidence":0.9,"reasoning":"guess","matched":true,"new_category_name":""}`)
+	response.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:     550,
+		CandidatesTokenCount: 50,
+		TotalTokenCount:      600,
+	}
+	b.geminiClient = gemini.NewClientWithGenerator(&botTestGenerator{response: response})
+	b.geminiClient.SetUsageRecorder(geminiUsageRecorder{repo: b.geminiUsageRepo})
+
+	used := func() int64 {
+		total, err := b.geminiUsageRepo.TotalSince(ctx, b.geminiMonthStart())
+		require.NoError(t, err)
+		return total
+	}
+	add := func(text string) {
+		b.handleAddCore(ctx, mocks.NewMockBot(), mocks.CommandUpdate(userID, userID, text))
+	}
+
+	t.Run("records usage of each call", func(t *testing.T) {
+		add("/add 5 Mystery gadget")
+		require.Equal(t, int64(600), used())
+		require.False(t, b.aiBudgetExhausted(ctx))
+
+		var recordedUser int64
+		var feature string
+		err := pool.QueryRow(ctx, `SELECT user_id, feature FROM gemini_usage ORDER BY id DESC LIMIT 1`).
+			Scan(&recordedUser, &feature)
+		require.NoError(t, err)
+		require.Equal(t, userID, recordedUser)
+		require.Equal(t, gemini.FeatureSuggest, feature)
+	})
+
+	t.Run("cuts AI features off once the budget is used", func(t *testing.T) {
+		add("/add 6 Another gadget")
+		require.Equal(t, int64(1200), used())
+		require.True(t, b.aiBudgetExhausted(ctx))
+
+		add("/add 7 Third gadget")
+		require.Equal(t, int64(1200), used(), "category suggestion is skipped")
+
+		mockBot := mocks.NewMockBot()
+		b.handlePhotoCore(ctx, mockBot, mocks.PhotoUpdate(userID, userID, "budget-photo"))
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		require.Contains(t, mockBot.LastSentMessage().Text, aiBudgetExhaustedMsg)
+		require.Contains(t, mockBot.LastSentMessage().Text, "/add")
+	})
+
+	t.Run("usagestats shows the breakdown", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleUsageStatsCore(ctx, mockBot, mocks.CommandUpdate(999, 999, "/usagestats"))
+		require.Equal(t, onlySuperadminsMsg, mockBot.LastSentMessage().Text)
+
+		b.handleUsageStatsCore(ctx, mockBot, mocks.CommandUpdate(100, 100, "/usagestats"))
+		text := mockBot.LastSentMessage().Text
+		require.Contains(t, text, "Total: 1200 of 1000 tokens (120%)")
+		require.Contains(t, text, "<code>suggest</code>: 1200 tokens in 2 calls")
+		require.Contains(t, text, "Expenses by source: command")
+	})
+
+	t.Run("the budget resets when the month rolls over", func(t *testing.T) {
+		nextMonth := b.geminiMonthStart().AddDate(0, 1, 1)
+		b.nowFunc = func() time.Time { return nextMonth }
+		t.Cleanup(func() { b.nowFunc = time.Now })
+
+		require.False(t, b.aiBudgetExhausted(ctx))
+		mockBot := mocks.NewMockBot()
+		b.handleUsageStatsCore(ctx, mockBot, mocks.CommandUpdate(100, 100, "/usagestats"))
+		require.True(t, strings.HasPrefix(mockBot.LastSentMessage().Text, usageStatsEmptyMsg))
+	})
+}
+
+// suggestCountingGenerator answers category suggestions with "Food" and
+// counts the calls. With forbid set, any call fails the test.
+type suggestCountingGenerator struct {
+	t      *testing.T
+	forbid bool
+	calls  int
+}
+
+func (g *suggestCountingGenerator) GenerateContent(
+	_ context.Context,
+	_ string,
+	_ []*genai.Content,
+	_ *genai.GenerateContentConfig,
+) (*genai.GenerateContentResponse, error) {
+	g.calls++
+	if g.forbid {
+		g.t.Error("Gemini must not be called")
+	}
+	return makeBotCategorySuggestionResponse(
+		`{"category":"Food","confidence":0.9,"reasoning":"meal","matched":true,"new_category_name":""}`), nil
+}
+
+func TestDecideCategory_AILimits(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 4, 23, 30, 0, 0, time.UTC)
+	newBot := func(generator *suggestCountingGenerator, dailyCap int) *Bot {
+		return &Bot{
+			cfg: &config.Config{
+				AISuggestMinAmount: mustParseDecimal("5"),
+				AISuggestDailyCap:  dailyCap,
+			},
+			geminiClient:    gemini.NewClientWithGenerator(generator),
+			displayLocation: time.UTC,
+			nowFunc:         func() time.Time { return now },
+		}
+	}
+	categories := []appmodels.Category{{ID: 1, Name: "Food"}, {ID: 2, Name: "Others"}}
+	suggestIn := func(b *Bot, amount, currency string) (*appmodels.Expense, service.CategoryDecision, bool) {
+		expense := &appmodels.Expense{
+			UserID:      439001,
+			Amount:      mustParseDecimal(amount),
+			Currency:    currency,
+			Description: "Lunch",
+		}
+		decision := b.expenses().DecideCategory(context.Background(), expense,
+			service.ParsedInput{Description: "Lunch"}, categories, service.AIApply)
+		return expense, decision, decision.Source == service.CategorySourceAI
+	}
+	suggest := func(b *Bot, amount string) (*appmodels.Expense, service.CategoryDecision, bool) {
+		return suggestIn(b, amount, appmodels.DefaultCurrency)
+	}
+
+	t.Run("below the minimum amount", func(t *testing.T) {
+		t.Parallel()
+		generator := &suggestCountingGenerator{t: t, forbid: true}
+		b := newBot(generator, 50)
+
+		expense, decision, applied := suggest(b, "4.99")
+		require.False(t, applied)
+		require.Equal(t, 2, *expense.CategoryID, "falls back to Others")
+		require.Equal(t, service.AIStatusBelowMinAmount, decision.AIStatus)
+		require.Zero(t, generator.calls)
+
+		belowMinimum, overDailyCap := b.aiSuggestQuota.skipped()
+		require.Equal(t, int64(1), belowMinimum)
+		require.Zero(t, overDailyCap)
+		require.Zero(t, b.aiSuggestQuota.used(439001, b.aiSuggestDay(), 0), "skipped calls do not count")
+	})
+
+	t.Run("at or above the minimum amount", func(t *testing.T) {
+		t.Parallel()
+		generator := &suggestCountingGenerator{t: t}
+		b := newBot(generator, 50)
+
+		expense, decision, applied := suggest(b, "5")
+		require.True(t, applied)
+		require.Equal(t, service.CategorySourceAI, decision.Source)
+		require.NotNil(t, expense.CategoryID)
+		require.Equal(t, 1, *expense.CategoryID)
+		require.Equal(t, 1, generator.calls)
+	})
+
+	t.Run("daily cap resets the next day", func(t *testing.T) {
+		t.Parallel()
+		generator := &suggestCountingGenerator{t: t}
+		b := newBot(generator, 2)
+		clock := now
+		b.nowFunc = func() time.Time { return clock }
+
+		for range 2 {
+			_, _, applied := suggest(b, "12")
+			require.True(t, applied)
+		}
+		_, decision, applied := suggest(b, "12")
+		require.False(t, applied)
+		require.Equal(t, service.AIStatusDailyCapReached, decision.AIStatus)
+		require.Equal(t, 2, generator.calls)
+
+		belowMinimum, overDailyCap := b.aiSuggestQuota.skipped()
+		require.Zero(t, belowMinimum)
+		require.Equal(t, int64(1), overDailyCap)
+
+		clock = now.Add(time.Hour)
+		_, _, applied = suggest(b, "12")
+		require.True(t, applied)
+		require.Equal(t, 3, generator.calls)
+		require.Equal(t, 1, b.aiSuggestQuota.used(439001, b.aiSuggestDay(), 0))
+	})
+
+	t.Run("the minimum is in the default currency", func(t *testing.T) {
+		t.Parallel()
+		generator := &suggestCountingGenerator{t: t}
+		b := newBot(generator, 50)
+
+		_, _, applied := suggestIn(b, "500", "JPY")
+		require.True(t, applied, "an unconverted amount is not compared")
+
+		expense := &appmodels.Expense{
+			UserID:            439001,
+			Amount:            mustParseDecimal("4"),
+			Currency:          "USD",
+			ConvertedAmount:   decimal.NewNullDecimal(mustParseDecimal("5.40")),
+			ConvertedCurrency: appmodels.DefaultCurrency,
+		}
+		require.False(t, b.belowAISuggestMinimum(context.Background(), expense, mustParseDecimal("5")))
+		expense.ConvertedAmount = decimal.NewNullDecimal(mustParseDecimal("4.90"))
+		require.True(t, b.belowAISuggestMinimum(context.Background(), expense, mustParseDecimal("5")))
+	})
+
+	t.Run("zero turns both limits off", func(t *testing.T) {
+		t.Parallel()
+		generator := &suggestCountingGenerator{t: t}
+		b := newBot(generator, 0)
+		b.cfg.AISuggestMinAmount = mustParseDecimal("0")
+
+		for range 3 {
+			_, _, applied := suggest(b, "0.50")
+			require.True(t, applied)
+		}
+		require.Equal(t, 3, generator.calls)
+	})
+}
+
+func TestAISuggestSettingsText(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	require.Equal(t, "not configured", (&Bot{}).aiSuggestSettingsText(ctx, 1))
+
+	b := &Bot{
+		cfg:             &config.Config{AISuggestMinAmount: mustParseDecimal("5"), AISuggestDailyCap: 50},
+		geminiClient:    gemini.NewClientWithGenerator(&botTestGenerator{}),
+		displayLocation: time.UTC,
+		nowFunc:         func() time.Time { return now },
+	}
+	require.True(t, b.aiSuggestQuota.take(1, b.aiSuggestDay(), 50, 0))
+	require.Equal(t, "amounts from S$5.00 SGD, 1 of 50 used today", b.aiSuggestSettingsText(ctx, 1))
+
+	b.cfg.AISuggestMinAmount = mustParseDecimal("0")
+	b.cfg.AISuggestDailyCap = 0
+	require.Equal(t, "any amount, 0 used today (no daily cap)", b.aiSuggestSettingsText(ctx, 2))
+}
+
+func TestAISuggestQuotaStored(t *testing.T) {
+	t.Parallel()
+
+	var q aiSuggestQuota
+	require.Equal(t, 1, q.used(1, "2026-03-04", 1))
+	require.True(t, q.take(1, "2026-03-04", 2, 1), "suggestions stored before a restart count")
+	require.False(t, q.take(1, "2026-03-04", 2, 1), "calls not stored yet count too")
+	require.Equal(t, 2, q.used(1, "2026-03-04", 1))
+
+	require.True(t, q.take(1, "2026-03-05", 2, 0), "the next day starts over")
+	require.Equal(t, 1, q.used(1, "2026-03-05", 0))
+}
+
+func TestFormatAISuggestSkips(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, formatAISuggestSkips(0, 0))
+	require.Equal(t, "\n\nCategory suggestions skipped since start: 3 below the minimum amount, 1 over the daily cap",
+		formatAISuggestSkips(3, 1))
+}
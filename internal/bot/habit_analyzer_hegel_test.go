@@ -7,6 +7,7 @@ import (
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
 	"hegel.dev/go/hegel"
 
 	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
@@ -224,7 +225,7 @@ func TestHegelFormatHabitSummaryRobustness(t *testing.T) {
 		summary := analyzeExpenseHabit(len(expenses), expenses, loc, label)
 		out := formatHabitSummary(&summary)
 
-		require.Contains(ht, out, escapeHTML(label))
+		require.Contains(ht, out, botfmt.EscapeHTML(label))
 		require.Contains(ht, out,
 			"Reviewed: "+strconv.Itoa(summary.ReviewedCount)+"/"+strconv.Itoa(summary.TotalCount))
 	})
@@ -1,3 +1,5 @@
 package bot
 
-const categoryUncategorized = "Uncategorized"
+import "gitlab.com/yelinaung/expense-bot/internal/botfmt"
+
+const categoryUncategorized = botfmt.Uncategorized
@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+const (
+	// aboutPingTimeout bounds the database check of /about.
+	aboutPingTimeout = 2 * time.Second
+
+	dbStatusOK       = "ok"
+	dbStatusDegraded = "degraded"
+)
+
+// formatUptime renders a duration as days, hours and minutes, e.g.
+// "3d 4h 5m". Durations under a minute show as "<1m".
+func formatUptime(d time.Duration) string {
+	if d < time.Minute {
+		return "<1m"
+	}
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// databaseStatus pings the database with aboutPingTimeout and returns
+// dbStatusOK or dbStatusDegraded. Failures are logged, not returned.
+func (b *Bot) databaseStatus(ctx context.Context) string {
+	ctx, cancel := context.WithTimeout(ctx, aboutPingTimeout)
+	defer cancel()
+
+	var err error
+	if pinger, ok := b.db.(database.Pinger); ok {
+		err = pinger.Ping(ctx)
+	} else {
+		var one int
+		err = b.db.QueryRow(ctx, "SELECT 1").Scan(&one)
+	}
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Database ping for /about failed")
+		return dbStatusDegraded
+	}
+	return dbStatusOK
+}
+
+// aboutText renders /about. Admins also get connection pool statistics,
+// whether Gemini is configured and how updates are received.
+func (b *Bot) aboutText(ctx context.Context, admin bool) string {
+	build := b.cfg.Build
+	var sb strings.Builder
+	sb.WriteString("🤖 <b>About</b>\n\n")
+	fmt.Fprintf(&sb, "Version: <code>%s</code>\n", botfmt.EscapeHTML(valueOr(build.Version, "dev")))
+	fmt.Fprintf(&sb, "Commit: <code>%s</code>\n", botfmt.EscapeHTML(valueOr(build.Commit, "none")))
+	fmt.Fprintf(&sb, "Built: %s\n", botfmt.EscapeHTML(valueOr(build.Date, "unknown")))
+	fmt.Fprintf(&sb, "Go: %s\n", runtime.Version())
+	fmt.Fprintf(&sb, "Uptime: %s\n", formatUptime(b.now().Sub(b.startedAt)))
+	fmt.Fprintf(&sb, "Database: %s\n", b.databaseStatus(ctx))
+	fmt.Fprintf(&sb, "Handlers: %d", b.handlerCount)
+
+	if !admin {
+		return sb.String()
+	}
+
+	sb.WriteString("\n\n<b>Operations</b>\n")
+	if statter, ok := b.db.(database.PoolStatter); ok {
+		stat := statter.Stat()
+		fmt.Fprintf(&sb, "Pool: %d acquired, %d idle, %d max\n",
+			stat.AcquiredConns(), stat.IdleConns(), stat.MaxConns())
+	}
+	gemini := "no"
+	if b.geminiClient != nil {
+		gemini = "yes"
+	}
+	fmt.Fprintf(&sb, "Gemini configured: %s\n", gemini)
+	// The bot always long-polls; Start deletes any webhook first.
+	sb.WriteString("Updates: polling")
+	return sb.String()
+}
+
+// valueOr returns s, or fallback when s is empty.
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// handleAbout handles the /about command.
+func (b *Bot) handleAbout(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleAboutCore(ctx, tgBot, update)
+}
+
+// handleAboutCore is the testable implementation of handleAbout.
+func (b *Bot) handleAboutCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	admin := b.cfg.IsSuperAdmin(update.Message.From.ID, update.Message.From.Username)
+	_, err := tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    update.Message.Chat.ID,
+		Text:      b.aboutText(ctx, admin),
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to send /about response")
+	}
+}
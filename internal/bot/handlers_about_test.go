@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+)
+
+// pingDB is a database that only answers pings.
+type pingDB struct {
+	database.PGXDB
+	err error
+}
+
+func (d pingDB) Ping(context.Context) error { return d.err }
+
+func TestFormatUptime(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "<1m", formatUptime(30*time.Second))
+	require.Equal(t, "5m", formatUptime(5*time.Minute+10*time.Second))
+	require.Equal(t, "2h 0m", formatUptime(2*time.Hour))
+	require.Equal(t, "3d 4h 5m", formatUptime(76*time.Hour+5*time.Minute))
+}
+
+func TestHandleAboutCore(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	newBot := func(pingErr error) *Bot {
+		return &Bot{
+			cfg: &config.Config{
+				WhitelistedUserIDs: []int64{100},
+				Build:              config.BuildInfo{Version: "v1.4.0", Commit: "abc1234", Date: "2026-10-01"},
+			},
+			db:           pingDB{err: pingErr},
+			nowFunc:      func() time.Time { return now },
+			startedAt:    now.Add(-26 * time.Hour),
+			handlerCount: 42,
+		}
+	}
+	about := func(b *Bot, userID int64) string {
+		mockBot := mocks.NewMockBot()
+		b.handleAboutCore(context.Background(), mockBot, mocks.CommandUpdate(userID, userID, "/about"))
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		return mockBot.LastSentMessage().Text
+	}
+
+	t.Run("member", func(t *testing.T) {
+		t.Parallel()
+
+		text := about(newBot(nil), 200)
+		require.Contains(t, text, "Version: <code>v1.4.0</code>")
+		require.Contains(t, text, "Commit: <code>abc1234</code>")
+		require.Contains(t, text, "Built: 2026-10-01")
+		require.Contains(t, text, "Go: go")
+		require.Contains(t, text, "Uptime: 1d 2h 0m")
+		require.Contains(t, text, "Database: ok")
+		require.Contains(t, text, "Handlers: 42")
+		require.NotContains(t, text, "Operations")
+		require.NotContains(t, text, "Gemini")
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		t.Parallel()
+
+		text := about(newBot(nil), 100)
+		require.Contains(t, text, "Version: <code>v1.4.0</code>")
+		require.Contains(t, text, "Operations")
+		require.Contains(t, text, "Gemini configured: no")
+		require.Contains(t, text, "Updates: polling")
+	})
+
+	t.Run("ping failure degrades", func(t *testing.T) {
+		t.Parallel()
+
+		text := about(newBot(errors.New("connection refused")), 100)
+		require.Contains(t, text, "Database: degraded")
+		require.Contains(t, text, "Handlers: 42")
+	})
+}
@@ -51,6 +51,17 @@ func TestHandleAddCategoryCore(t *testing.T) {
 		require.Contains(t, msg.Text, "created")
 	})
 
+	t.Run("warns about names starting with a number", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		update := mocks.CommandUpdate(chatID, userID, withCommandArg(testAddCategoryCommand, "50 Coffee 800"))
+
+		b.handleAddCategoryCore(ctx, mockBot, update)
+
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, "created")
+		require.Contains(t, msg.Text, "[50 Coffee 800]")
+	})
+
 	t.Run("handles bot mention in command", func(t *testing.T) {
 		mockBot := mocks.NewMockBot()
 		update := mocks.CommandUpdate(chatID, userID, withCommandArg(withBotMention(testAddCategoryCommand), "My Category 800"))
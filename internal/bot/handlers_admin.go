@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
 	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
 )
 
 const (
@@ -99,7 +102,7 @@ func (b *Bot) handleApproveCore(ctx context.Context, tg TelegramAPI, update *mod
 	}
 	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:    chatID,
-		Text:      fmt.Sprintf("User <code>@%s</code> has been approved.", escapeHTML(targetUsername)),
+		Text:      fmt.Sprintf("User <code>@%s</code> has been approved.", botfmt.EscapeHTML(targetUsername)),
 		ParseMode: models.ParseModeHTML,
 	})
 }
@@ -181,17 +184,124 @@ func (b *Bot) handleRevokeCore(ctx context.Context, tg TelegramAPI, update *mode
 	}
 	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:    chatID,
-		Text:      fmt.Sprintf("User <code>@%s</code> has been revoked.", escapeHTML(targetUsername)),
+		Text:      fmt.Sprintf("User <code>@%s</code> has been revoked.", botfmt.EscapeHTML(targetUsername)),
 		ParseMode: models.ParseModeHTML,
 	})
 }
 
+const (
+	// usersPageSize is how many approved users one /users page lists.
+	usersPageSize        = 10
+	usersCallbackPrefix  = "users_"
+	usersPageCallbackFmt = usersCallbackPrefix + "%d_%s"
+	// maxUsersFilterLength keeps the filter, which the page buttons carry,
+	// within Telegram's 64-byte callback data.
+	maxUsersFilterLength = 32
+	usersDateLayout      = "2 Jan 2006"
+)
+
+// formatUserStats formats one approved user of the /users listing.
+func formatUserStats(u *repository.UserStats, loc *time.Location) string {
+	var sb strings.Builder
+	sb.WriteString("  ")
+	if u.FirstName != "" {
+		fmt.Fprintf(&sb, "<b>%s</b> ", botfmt.EscapeHTML(u.FirstName))
+	}
+	if u.Username != "" {
+		fmt.Fprintf(&sb, "@%s ", botfmt.EscapeHTML(u.Username))
+	}
+	if u.UserID != 0 {
+		fmt.Fprintf(&sb, "(<code>%d</code>)", u.UserID)
+	}
+
+	lastSeen := "never"
+	if u.LastSeenAt != nil {
+		lastSeen = u.LastSeenAt.In(loc).Format(usersDateLayout + " 15:04")
+	}
+	fmt.Fprintf(&sb, "\n    Approved %s · Last seen %s · %d expenses\n",
+		u.ApprovedAt.In(loc).Format(usersDateLayout), lastSeen, u.ExpenseCount)
+	return strings.TrimRight(sb.String(), " ")
+}
+
+// buildUsersKeyboard builds the page navigation row of the /users listing.
+// It returns nil when everything fits on one page.
+func buildUsersKeyboard(filter string, page, totalPages int) *models.InlineKeyboardMarkup {
+	var nav []models.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, models.InlineKeyboardButton{
+			Text:         uncategorizedPrevButtonText,
+			CallbackData: fmt.Sprintf(usersPageCallbackFmt, page-1, filter),
+		})
+	}
+	if page < totalPages-1 {
+		nav = append(nav, models.InlineKeyboardButton{
+			Text:         uncategorizedNextButtonText,
+			CallbackData: fmt.Sprintf(usersPageCallbackFmt, page+1, filter),
+		})
+	}
+	if len(nav) == 0 {
+		return nil
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{nav}}
+}
+
+// buildUsersView renders one page of approved users matching filter, most
+// recently active first. The superadmins are listed above the first page
+// when there is no filter. The page is clamped to the available range.
+func (b *Bot) buildUsersView(
+	ctx context.Context,
+	filter string,
+	page int,
+) (string, *models.InlineKeyboardMarkup, error) {
+	count, err := b.userRepo.CountWithStats(ctx, filter)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to count approved users: %w", err)
+	}
+	totalPages := max(1, (count+usersPageSize-1)/usersPageSize)
+	page = max(0, min(page, totalPages-1))
+
+	users, err := b.userRepo.ListWithStats(ctx, filter, usersPageSize, page*usersPageSize)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list approved users: %w", err)
+	}
+
+	var sb strings.Builder
+	if filter == "" && page == 0 {
+		sb.WriteString("<b>Superadmins:</b>\n")
+		for _, id := range b.cfg.WhitelistedUserIDs {
+			fmt.Fprintf(&sb, superadminIDLineFmt, id)
+		}
+		for _, u := range b.cfg.WhitelistedUsernames {
+			fmt.Fprintf(&sb, superadminUsernameLineFmt, botfmt.EscapeHTML(u))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("<b>Approved Users:</b>")
+	if filter != "" {
+		fmt.Fprintf(&sb, " matching <code>%s</code>", botfmt.EscapeHTML(filter))
+	}
+	if totalPages > 1 {
+		fmt.Fprintf(&sb, " (%d, page %d/%d)", count, page+1, totalPages)
+	}
+	sb.WriteString("\n")
+	if len(users) == 0 {
+		sb.WriteString("  (none)\n")
+	}
+	for i := range users {
+		sb.WriteString(formatUserStats(&users[i], b.displayLocation))
+	}
+
+	return sb.String(), buildUsersKeyboard(filter, page, totalPages), nil
+}
+
 // handleUsers handles the /users command to list authorized users.
 func (b *Bot) handleUsers(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
 	b.handleUsersCore(ctx, tgBot, update)
 }
 
-// handleUsersCore is the testable implementation of handleUsers.
+// handleUsersCore is the testable implementation of handleUsers. An
+// argument, as in "/users alice", filters by username, first name or ID.
 func (b *Bot) handleUsersCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
 	if update.Message == nil {
 		return
@@ -209,16 +319,16 @@ func (b *Bot) handleUsersCore(ctx context.Context, tg TelegramAPI, update *model
 		return
 	}
 
-	var sb strings.Builder
-	sb.WriteString("<b>Superadmins:</b>\n")
-	for _, id := range b.cfg.WhitelistedUserIDs {
-		fmt.Fprintf(&sb, superadminIDLineFmt, id)
-	}
-	for _, u := range b.cfg.WhitelistedUsernames {
-		fmt.Fprintf(&sb, superadminUsernameLineFmt, escapeHTML(u))
+	filter := strings.TrimPrefix(extractAdminArgs(update.Message.Text), "@")
+	if len(filter) > maxUsersFilterLength {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("The filter can be at most %d characters.", maxUsersFilterLength),
+		})
+		return
 	}
 
-	approved, err := b.approvedUserRepo.GetAll(ctx)
+	text, keyboard, err := b.buildUsersView(ctx, filter, 0)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to get approved users")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
@@ -228,26 +338,59 @@ func (b *Bot) handleUsersCore(ctx context.Context, tg TelegramAPI, update *model
 		return
 	}
 
-	sb.WriteString("\n<b>Approved Users:</b>\n")
-	if len(approved) == 0 {
-		sb.WriteString("  (none)\n")
-	} else {
-		for i := range approved {
-			u := approved[i]
-			switch {
-			case u.UserID != 0 && u.Username != "":
-				fmt.Fprintf(&sb, "  ID: <code>%d</code> (@%s)\n", u.UserID, escapeHTML(u.Username))
-			case u.UserID != 0:
-				fmt.Fprintf(&sb, superadminIDLineFmt, u.UserID)
-			default:
-				fmt.Fprintf(&sb, superadminUsernameLineFmt, escapeHTML(u.Username))
-			}
-		}
-	}
-
-	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+	params := &bot.SendMessageParams{
 		ChatID:    chatID,
-		Text:      sb.String(),
+		Text:      text,
 		ParseMode: models.ParseModeHTML,
+	}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+	_, _ = tg.SendMessage(ctx, params)
+}
+
+// handleUsersCallback handles /users pagination.
+func (b *Bot) handleUsersCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleUsersCallbackCore(ctx, tgBot, update)
+}
+
+// handleUsersCallbackCore is the testable implementation of
+// handleUsersCallback. It re-renders the listing in place.
+func (b *Bot) handleUsersCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+
+	_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
 	})
+
+	from := update.CallbackQuery.From
+	if !b.cfg.IsSuperAdmin(from.ID, from.Username) {
+		return
+	}
+
+	pageArg, filter, _ := strings.Cut(strings.TrimPrefix(update.CallbackQuery.Data, usersCallbackPrefix), "_")
+	page, err := strconv.Atoi(pageArg)
+	if err != nil {
+		return
+	}
+
+	text, keyboard, err := b.buildUsersView(ctx, filter, page)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to refresh approved users")
+		return
+	}
+
+	msg := update.CallbackQuery.Message.Message
+	params := &bot.EditMessageTextParams{
+		ChatID:    msg.Chat.ID,
+		MessageID: msg.ID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+	_, _ = tg.EditMessageText(ctx, params)
 }
@@ -2,7 +2,10 @@ package bot
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
@@ -198,8 +201,12 @@ func TestHandleUsersCore(t *testing.T) {
 	b := &Bot{
 		cfg:              cfg,
 		approvedUserRepo: repository.NewApprovedUserRepository(tx),
+		userRepo:         repository.NewUserRepository(tx),
 		pendingEdits:     make(map[int64]*pendingEdit),
+		displayLocation:  time.UTC,
 	}
+	_, err := tx.Exec(ctx, `DELETE FROM approved_users`)
+	require.NoError(t, err)
 
 	t.Run(nonSuperadminRejectedAdminTest, func(t *testing.T) {
 		mockBot := mocks.NewMockBot()
@@ -243,5 +250,63 @@ func TestHandleUsersCore(t *testing.T) {
 		require.Contains(t, msg, "Approved Users")
 		require.Contains(t, msg, "55555")
 		require.Contains(t, msg, "@frank")
+		require.Contains(t, msg, "Last seen never")
+		require.Contains(t, msg, "0 expenses")
+		require.Nil(t, mockBot.LastSentMessage().ReplyMarkup, "one page has no buttons")
 	})
+
+	t.Run("pages through more than ten users", func(t *testing.T) {
+		for i := range usersPageSize + 2 {
+			require.NoError(t, b.approvedUserRepo.ApproveByUsername(ctx, fmt.Sprintf("pageuser%02d", i), 100))
+		}
+
+		mockBot := mocks.NewMockBot()
+		b.handleUsersCore(ctx, mockBot, mocks.CommandUpdate(1, 100, usersCommandAdminTest))
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, superadminsTextAdminTest)
+		require.Contains(t, msg.Text, "page 1/2")
+		require.Equal(t, usersPageSize, strings.Count(msg.Text, "Last seen never"))
+		kb := requireInlineKeyboard(t, msg.ReplyMarkup)
+		require.Equal(t, "users_1_", kb.InlineKeyboard[0][0].CallbackData)
+
+		callback := mocks.NewUpdateBuilder().WithCallbackQuery("cb", 1, 100, 7, "users_1_").Build()
+		b.handleUsersCallbackCore(ctx, mockBot, callback)
+		edited := mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, "page 2/2")
+		require.NotContains(t, edited.Text, superadminsTextAdminTest)
+		kb = requireInlineKeyboard(t, edited.ReplyMarkup)
+		require.Equal(t, "users_0_", kb.InlineKeyboard[0][0].CallbackData)
+	})
+
+	t.Run("filters by argument", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleUsersCore(ctx, mockBot, mocks.CommandUpdate(1, 100, "/users @FRANK"))
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, "matching <code>FRANK</code>")
+		require.Contains(t, msg.Text, "@frank")
+		require.NotContains(t, msg.Text, "pageuser")
+		require.NotContains(t, msg.Text, superadminsTextAdminTest)
+		require.Nil(t, msg.ReplyMarkup)
+
+		b.handleUsersCore(ctx, mockBot, mocks.CommandUpdate(1, 100, "/users nobody"))
+		require.Contains(t, mockBot.LastSentMessage().Text, "(none)")
+	})
+
+	t.Run("callback from non-superadmin is ignored", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		callback := mocks.NewUpdateBuilder().WithCallbackQuery("cb", 1, 999, 7, "users_1_").Build()
+		b.handleUsersCallbackCore(ctx, mockBot, callback)
+		require.Zero(t, mockBot.EditedMessageCount())
+	})
+}
+
+func TestBuildUsersKeyboard(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, buildUsersKeyboard("", 0, 1))
+
+	kb := buildUsersKeyboard("al_ice", 1, 3)
+	require.Len(t, kb.InlineKeyboard, 1)
+	require.Equal(t, "users_0_al_ice", kb.InlineKeyboard[0][0].CallbackData)
+	require.Equal(t, "users_2_al_ice", kb.InlineKeyboard[0][1].CallbackData)
 }
@@ -0,0 +1,201 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+const (
+	// categoryModeAIReview marks category callbacks started from the AI
+	// review digest so the handlers return to the digest.
+	categoryModeAIReview = "air"
+
+	aiReviewWindowDays     = 7
+	aiReviewLimit          = 10
+	aiReviewCallbackPrefix = "aireview_"
+	aiReviewOKFmt          = "aireview_ok_%d"
+	aiReviewShowData       = "aireview_show"
+	aiReviewChangeFmt      = "edit_category_%d_" + categoryModeAIReview
+	aiReviewNoneMsg        = "✅ All AI-picked categories from the last 7 days are reviewed."
+)
+
+// aiReviewSince returns the start of the AI review lookback window.
+func aiReviewSince(now time.Time) time.Time {
+	return now.AddDate(0, 0, -aiReviewWindowDays)
+}
+
+// aiAcceptanceLine summarizes how many of this month's AI-picked categories
+// the user kept, or returns "" when there were none.
+func aiAcceptanceLine(acceptance repository.AIAcceptance) string {
+	if acceptance.Total == 0 {
+		return ""
+	}
+	percent := (acceptance.Accepted*100 + acceptance.Total/2) / acceptance.Total
+	return fmt.Sprintf("You've accepted %d%% of AI suggestions this month (%d of %d).",
+		percent, acceptance.Accepted, acceptance.Total)
+}
+
+// buildAIReviewText renders the digest: the acceptance line, then the
+// expenses grouped under their category. expenses must be ordered by
+// category; total is how many are waiting for review in all.
+func buildAIReviewText(
+	expenses []appmodels.Expense,
+	total int,
+	acceptance repository.AIAcceptance,
+	loc *time.Location,
+) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🤖 <b>Review AI Categories</b> (last %d days)\n", aiReviewWindowDays)
+	if line := aiAcceptanceLine(acceptance); line != "" {
+		sb.WriteString(line + "\n")
+	}
+
+	group := ""
+	for i := range expenses {
+		item := expenses[i]
+		if name := getCategoryName(&item); i == 0 || name != group {
+			group = name
+			fmt.Fprintf(&sb, "\n<b>%s</b>\n", botfmt.EscapeHTML(name))
+		}
+		item.Category = nil
+		sb.WriteString(botfmt.ExpenseListItem(&item, nil, loc))
+	}
+
+	if total > len(expenses) {
+		fmt.Fprintf(&sb, "Showing %d of %d. Review these to see the rest.", len(expenses), total)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// buildAIReviewKeyboard adds a "looks right" and a "change" button per
+// expense of the digest.
+func buildAIReviewKeyboard(expenses []appmodels.Expense) *models.InlineKeyboardMarkup {
+	rows := make([][]models.InlineKeyboardButton, len(expenses))
+	for i := range expenses {
+		number := expenses[i].UserExpenseNumber
+		rows[i] = []models.InlineKeyboardButton{
+			{Text: fmt.Sprintf("✓ #%d looks right", number), CallbackData: fmt.Sprintf(aiReviewOKFmt, expenses[i].ID)},
+			{Text: fmt.Sprintf("✎ change #%d", number), CallbackData: fmt.Sprintf(aiReviewChangeFmt, expenses[i].ID)},
+		}
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// buildAIReviewView loads the user's AI-categorized expenses of the last
+// aiReviewWindowDays that are still flagged and renders the digest. A nil
+// keyboard means there is nothing left to review.
+func (b *Bot) buildAIReviewView(ctx context.Context, userID int64) (string, *models.InlineKeyboardMarkup, error) {
+	now := b.now()
+	since := aiReviewSince(now)
+	count, err := b.expenseRepo.CountAICategorizedByUserID(ctx, userID, since)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to count AI-categorized expenses: %w", err)
+	}
+	if count == 0 {
+		return aiReviewNoneMsg, nil, nil
+	}
+
+	expenses, err := b.expenseRepo.GetAICategorizedByUserID(ctx, userID, since, aiReviewLimit)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch AI-categorized expenses: %w", err)
+	}
+
+	loc := b.locationForUser(ctx, userID)
+	monthStart, _ := getMonthDateRangeAt(now.In(loc))
+	acceptance, err := b.expenseRepo.GetAIAcceptance(ctx, userID, monthStart)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to count AI category acceptance: %w", err)
+	}
+
+	return buildAIReviewText(expenses, count, acceptance, loc), buildAIReviewKeyboard(expenses), nil
+}
+
+// sendAIReviewDigest sends the AI review digest when the user has
+// AI-categorized expenses to review. It returns whether one was sent.
+func (b *Bot) sendAIReviewDigest(ctx context.Context, userID int64) (bool, error) {
+	text, keyboard, err := b.buildAIReviewView(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if keyboard == nil {
+		return false, nil
+	}
+
+	_, err = b.messageSender.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      userID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: keyboard,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to send AI review digest: %w", err)
+	}
+	return true, nil
+}
+
+// refreshAIReviewCore re-renders the AI review digest in place.
+func (b *Bot) refreshAIReviewCore(ctx context.Context, tg TelegramAPI, chatID int64, messageID int, userID int64) {
+	text, keyboard, err := b.buildAIReviewView(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to refresh AI review digest")
+		return
+	}
+
+	params := &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+	_, _ = tg.EditMessageText(ctx, params)
+}
+
+// handleAIReviewCallback handles the buttons of the AI review digest.
+func (b *Bot) handleAIReviewCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleAIReviewCallbackCore(ctx, tgBot, update)
+}
+
+// handleAIReviewCallbackCore is the testable implementation of
+// handleAIReviewCallback. Callback data is aireview_ok_<expenseID>, which
+// keeps the AI-picked category, or aireview_show, which redraws the digest
+// after the category keyboard. The "change" button goes through the edit_
+// category flow with categoryModeAIReview.
+func (b *Bot) handleAIReviewCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+	userID := query.From.ID
+
+	if query.Data != aiReviewShowData {
+		expenseID, err := strconv.Atoi(strings.TrimPrefix(query.Data, "aireview_ok_"))
+		if err != nil {
+			answerCallback(ctx, tg, query)
+			return
+		}
+		if _, err := b.expenses().MarkAIReviewed(ctx, userID, expenseID); err != nil {
+			logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expenseID).Msg("Failed to clear AI-categorized flag")
+			_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+				CallbackQueryID: query.ID,
+				Text:            "❌ Something went wrong. Please try again.",
+			})
+			return
+		}
+	}
+	answerCallback(ctx, tg, query)
+
+	b.refreshAIReviewCore(ctx, tg, query.Message.Message.Chat.ID, query.Message.Message.ID, userID)
+}
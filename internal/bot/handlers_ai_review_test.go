@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+func TestAIAcceptanceLine(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, aiAcceptanceLine(repository.AIAcceptance{}))
+	require.Equal(t, "You've accepted 92% of AI suggestions this month (23 of 25).",
+		aiAcceptanceLine(repository.AIAcceptance{Accepted: 23, Total: 25}))
+	require.Contains(t, aiAcceptanceLine(repository.AIAcceptance{Accepted: 2, Total: 3}), "67%")
+}
+
+func TestBuildAIReviewText(t *testing.T) {
+	t.Parallel()
+
+	food := &appmodels.Category{ID: 1, Name: "Food & Drinks"}
+	transport := &appmodels.Category{ID: 2, Name: "Transportation"}
+	created := time.Date(2024, 3, 4, 12, 30, 0, 0, time.UTC)
+	expenses := []appmodels.Expense{
+		{
+			ID: 10, UserExpenseNumber: 1, Amount: mustParseDecimal("5.50"), Currency: testCurrencySGD,
+			Description: "Coffee", Category: food, CreatedAt: created,
+		},
+		{
+			ID: 11, UserExpenseNumber: 2, Amount: mustParseDecimal("12.00"), Currency: testCurrencySGD,
+			Description: "Lunch", Category: food, CreatedAt: created,
+		},
+		{
+			ID: 12, UserExpenseNumber: 3, Amount: mustParseDecimal("18.00"), Currency: testCurrencySGD,
+			Description: "Taxi", Category: transport, CreatedAt: created,
+		},
+	}
+
+	text := buildAIReviewText(expenses, 3, repository.AIAcceptance{Accepted: 9, Total: 10}, time.UTC)
+	require.Contains(t, text, "You've accepted 90% of AI suggestions this month (9 of 10).")
+	require.Contains(t, text, "\n<b>Food &amp; Drinks</b>\n#1 ")
+	require.Contains(t, text, "\n<b>Transportation</b>\n#3 ")
+	require.NotContains(t, text, "[Food", "the group header replaces the category tag")
+	require.NotContains(t, text, "Showing")
+
+	text = buildAIReviewText(expenses[:1], 4, repository.AIAcceptance{}, time.UTC)
+	require.NotContains(t, text, "You've accepted")
+	require.Contains(t, text, "Showing 1 of 4.")
+
+	keyboard := buildAIReviewKeyboard(expenses)
+	require.Len(t, keyboard.InlineKeyboard, 3)
+	require.Equal(t, "aireview_ok_10", keyboard.InlineKeyboard[0][0].CallbackData)
+	require.Equal(t, "edit_category_10_air", keyboard.InlineKeyboard[0][1].CallbackData)
+}
+
+func TestAIReviewFlow(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(417001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Review"}))
+
+	categories, err := b.categoryRepo.GetAll(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(categories), 2)
+	picked, other := categories[0], categories[1]
+
+	newExpense := func(description string, aiCategorized bool) *appmodels.Expense {
+		expense := &appmodels.Expense{
+			UserID:        userID,
+			Amount:        mustParseDecimal("8.00"),
+			Currency:      testCurrencySGD,
+			Description:   description,
+			CategoryID:    &picked.ID,
+			AICategorized: aiCategorized,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		return expense
+	}
+	tap := func(handle func(context.Context, TelegramAPI, *models.Update), data string) *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		handle(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 7, data))
+		return mockBot
+	}
+	flagged := func(expenseID int) bool {
+		expense, err := b.expenseRepo.GetByID(ctx, expenseID)
+		require.NoError(t, err)
+		return expense.AICategorized
+	}
+
+	t.Run("sends nothing without AI-categorized expenses", func(t *testing.T) {
+		newExpense("Manual", false)
+		mockBot := mocks.NewMockBot()
+		b.messageSender = mockBot
+
+		sent, err := b.sendAIReviewDigest(ctx, userID)
+		require.NoError(t, err)
+		require.False(t, sent)
+		require.Zero(t, mockBot.SentMessageCount())
+	})
+
+	coffee := newExpense("Coffee", true)
+	bagel := newExpense("Bagel", true)
+
+	t.Run("digest lists flagged expenses with buttons", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.messageSender = mockBot
+
+		sent, err := b.sendAIReviewDigest(ctx, userID)
+		require.NoError(t, err)
+		require.True(t, sent)
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, "Review AI Categories")
+		require.Contains(t, msg.Text, "You've accepted 100% of AI suggestions this month (2 of 2).")
+		require.Contains(t, msg.Text, "<b>"+picked.Name+"</b>")
+		require.Contains(t, msg.Text, "Coffee")
+		require.Contains(t, msg.Text, "Bagel")
+		require.NotContains(t, msg.Text, "Manual")
+
+		keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+		require.Len(t, keyboard.InlineKeyboard, 2)
+	})
+
+	t.Run("looks right clears the flag and refreshes", func(t *testing.T) {
+		mockBot := tap(b.handleAIReviewCallbackCore, fmt.Sprintf(aiReviewOKFmt, coffee.ID))
+		require.False(t, flagged(coffee.ID))
+		require.True(t, flagged(bagel.ID))
+
+		edited := mockBot.LastEditedMessage()
+		require.NotContains(t, edited.Text, "Coffee")
+		require.Contains(t, edited.Text, "Bagel")
+		require.Len(t, requireInlineKeyboard(t, edited.ReplyMarkup).InlineKeyboard, 1)
+	})
+
+	t.Run("another user's expense stays flagged", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		update := mocks.CallbackQueryUpdate(userID+1, userID+1, 7, fmt.Sprintf(aiReviewOKFmt, bagel.ID))
+		b.handleAIReviewCallbackCore(ctx, mockBot, update)
+		require.True(t, flagged(bagel.ID))
+	})
+
+	t.Run("change opens the category keyboard and returns to the digest", func(t *testing.T) {
+		mockBot := tap(b.handleEditCallbackCore, fmt.Sprintf(aiReviewChangeFmt, bagel.ID))
+		keyboard := requireInlineKeyboard(t, mockBot.LastEditedMessage().ReplyMarkup)
+		lastRow := keyboard.InlineKeyboard[len(keyboard.InlineKeyboard)-1]
+		require.Equal(t, aiReviewShowData, lastRow[0].CallbackData)
+
+		mockBot = tap(b.handleSetCategoryCallbackCore, setCategoryCallbackData(bagel.ID, other.ID, categoryModeAIReview))
+		require.False(t, flagged(bagel.ID))
+		require.Equal(t, aiReviewNoneMsg, mockBot.LastEditedMessage().Text)
+
+		acceptance, err := b.expenseRepo.GetAIAcceptance(ctx, userID, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, repository.AIAcceptance{Accepted: 1, Total: 2}, acceptance)
+	})
+
+	t.Run("keeping the same category counts as reviewed", func(t *testing.T) {
+		tea := newExpense("Tea", true)
+		mockBot := tap(b.handleSetCategoryCallbackCore, setCategoryCallbackData(tea.ID, picked.ID, categoryModeAIReview))
+		require.False(t, flagged(tea.ID))
+		require.Equal(t, aiReviewNoneMsg, mockBot.LastEditedMessage().Text)
+	})
+
+	t.Run("back button redraws the digest", func(t *testing.T) {
+		newExpense("Juice", true)
+		mockBot := tap(b.handleAIReviewCallbackCore, aiReviewShowData)
+		edited := mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, "Juice")
+		require.Contains(t, edited.Text, "You've accepted 75% of AI suggestions this month (3 of 4).")
+	})
+}
@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+const (
+	apiTokenPrefix    = "ebk_"
+	apiTokenByteLen   = 32
+	apiTokenRevokeArg = "revoke"
+
+	apiTokenDisabledMsg = "❌ The REST API is not enabled on this bot."
+	apiTokenPrivateMsg  = "🔒 Use /apitoken in a private chat with the bot so the token stays secret."
+	apiTokenFailMsg     = "❌ Failed to update API token. Please try again."
+)
+
+// generateAPIToken returns a new random API token.
+func generateAPIToken() (string, error) {
+	buf := make([]byte, apiTokenByteLen)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	return apiTokenPrefix + hex.EncodeToString(buf), nil
+}
+
+// hashAPIToken returns the hex SHA-256 of token. Only the hash is stored, so
+// a database leak does not expose usable tokens.
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiEnabled reports whether the HTTP server hosting the REST API is
+// configured.
+func (b *Bot) apiEnabled() bool {
+	return b.cfg != nil && b.cfg.HTTPAddr != ""
+}
+
+// handleAPIToken handles the /apitoken command.
+func (b *Bot) handleAPIToken(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleAPITokenCore(ctx, tgBot, update)
+}
+
+// handleAPITokenCore is the testable implementation of handleAPIToken.
+// Without arguments it issues a new token, replacing any previous one;
+// "/apitoken revoke" removes it.
+func (b *Bot) handleAPITokenCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || !b.sensitiveCommandAllowed(ctx, tg, update.Message) {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	args := strings.TrimSpace(extractCommandArgs(update.Message.Text, "/apitoken"))
+
+	if strings.EqualFold(args, apiTokenRevokeArg) {
+		b.revokeAPITokenCore(ctx, tg, chatID, userID)
+		return
+	}
+
+	if !b.apiEnabled() {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   apiTokenDisabledMsg,
+		})
+		return
+	}
+
+	if update.Message.Chat.Type != models.ChatTypePrivate {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   apiTokenPrivateMsg,
+		})
+		return
+	}
+
+	token, err := generateAPIToken()
+	if err == nil {
+		err = b.userRepo.SetAPITokenHash(ctx, userID, hashAPIToken(token))
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to issue API token")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   apiTokenFailMsg,
+		})
+		return
+	}
+
+	logger.Log.Info().Str("user_hash", logger.HashUserID(userID)).Msg("API token issued")
+
+	text := fmt.Sprintf(`🔑 <b>Your API token</b>
+
+<code>%s</code>
+
+Send it as <code>Authorization: Bearer &lt;token&gt;</code> to <code>POST /api/v1/expenses</code>.
+This token is shown only once and replaces any previous one. Use <code>/apitoken revoke</code> to disable it.`, token)
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	})
+}
+
+// revokeAPITokenCore removes the user's API token.
+func (b *Bot) revokeAPITokenCore(ctx context.Context, tg TelegramAPI, chatID, userID int64) {
+	cleared, err := b.userRepo.ClearAPITokenHash(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to revoke API token")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   apiTokenFailMsg,
+		})
+		return
+	}
+
+	text := "ℹ️ You don't have an API token."
+	if cleared {
+		logger.Log.Info().Str("user_hash", logger.HashUserID(userID)).Msg("API token revoked")
+		text = "✅ API token revoked."
+	}
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	})
+}
@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	archiveChatOff = "off"
+
+	archiveChatUsage = "Usage:\n" +
+		"<code>/archivechat &lt;chat_id&gt;</code> - Also send your monthly CSV to this chat on the 1st\n" +
+		"<code>/archivechat off</code> - Stop archiving\n\n" +
+		"Add me to the chat first. Channel and group IDs start with -100."
+)
+
+// isChatMember reports whether a chat member entry belongs to someone
+// currently in the chat.
+func isChatMember(member *models.ChatMember) bool {
+	switch member.Type {
+	case models.ChatMemberTypeOwner, models.ChatMemberTypeAdministrator, models.ChatMemberTypeMember:
+		return true
+	case models.ChatMemberTypeRestricted:
+		return member.Restricted != nil && member.Restricted.IsMember
+	default:
+		return false
+	}
+}
+
+// chatHasMember reports whether userID is currently in chatID. Lookup
+// failures, such as a chat the bot cannot see, count as not a member.
+func chatHasMember(ctx context.Context, tg TelegramAPI, chatID, userID int64) bool {
+	member, err := tg.GetChatMember(ctx, &bot.GetChatMemberParams{ChatID: chatID, UserID: userID})
+	if err != nil {
+		logger.Log.Debug().Err(err).Int64("chat_id", chatID).Msg("Failed to look up chat member")
+		return false
+	}
+	return isChatMember(member)
+}
+
+// handleArchiveChat handles the /archivechat command.
+func (b *Bot) handleArchiveChat(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleArchiveChatCore(ctx, tgBot, update)
+}
+
+// handleArchiveChatCore is the testable implementation of
+// handleArchiveChat. It shows, sets or clears the chat that receives the
+// user's monthly CSV. A chat is only accepted when both the bot and the
+// user are in it and a test message goes through.
+func (b *Bot) handleArchiveChatCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || !b.sensitiveCommandAllowed(ctx, tg, update.Message) {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	arg := strings.TrimSpace(extractCommandArgs(update.Message.Text, "/archivechat"))
+	if arg == "" {
+		current, err := b.userRepo.GetArchiveChatID(ctx, userID)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to get archive chat")
+			send("❌ Failed to load your archive chat. Please try again.")
+			return
+		}
+		status := "🗄️ Archiving is off."
+		if current != 0 {
+			status = fmt.Sprintf("🗄️ Monthly CSVs are archived to chat <code>%d</code>.", current)
+		}
+		send(status + "\n\n" + archiveChatUsage)
+		return
+	}
+
+	if strings.EqualFold(arg, archiveChatOff) {
+		if err := b.userRepo.SetArchiveChatID(ctx, userID, 0); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to clear archive chat")
+			send("❌ Failed to turn archiving off. Please try again.")
+			return
+		}
+		send("✅ Archiving is off.")
+		return
+	}
+
+	target, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || target == 0 {
+		send(archiveChatUsage)
+		return
+	}
+
+	if !chatHasMember(ctx, tg, target, b.botID) {
+		send(fmt.Sprintf("❌ I'm not in chat <code>%d</code>. Add me to it, then try again.", target))
+		return
+	}
+	if target != userID && !chatHasMember(ctx, tg, target, userID) {
+		send(fmt.Sprintf("❌ You're not in chat <code>%d</code>.", target))
+		return
+	}
+
+	from := update.Message.From
+	owner := &appmodels.User{ID: from.ID, Username: from.Username, FirstName: from.FirstName, LastName: from.LastName}
+	_, err = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    target,
+		Text:      "🗄️ This chat will receive monthly expense CSVs for " + archiveUserLabel(owner) + ".",
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		logger.Log.Warn().Err(err).Int64("chat_id", target).Msg("Archive chat test message failed")
+		send(fmt.Sprintf("❌ I couldn't post in chat <code>%d</code>. Make sure I'm allowed to send messages there.", target))
+		return
+	}
+
+	if err := b.userRepo.SetArchiveChatID(ctx, userID, target); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to set archive chat")
+		send("❌ Failed to save your archive chat. Please try again.")
+		return
+	}
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(userID)).
+		Msg("Archive chat set")
+	send(fmt.Sprintf("✅ Your monthly CSV will also be sent to chat <code>%d</code> on the 1st of each month.", target))
+}
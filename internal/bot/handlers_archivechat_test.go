@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestIsChatMember(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, isChatMember(&models.ChatMember{Type: models.ChatMemberTypeOwner}))
+	require.True(t, isChatMember(&models.ChatMember{Type: models.ChatMemberTypeAdministrator}))
+	require.True(t, isChatMember(&models.ChatMember{Type: models.ChatMemberTypeMember}))
+	require.True(t, isChatMember(&models.ChatMember{
+		Type:       models.ChatMemberTypeRestricted,
+		Restricted: &models.ChatMemberRestricted{IsMember: true},
+	}))
+	require.False(t, isChatMember(&models.ChatMember{
+		Type:       models.ChatMemberTypeRestricted,
+		Restricted: &models.ChatMemberRestricted{IsMember: false},
+	}))
+	require.False(t, isChatMember(&models.ChatMember{Type: models.ChatMemberTypeLeft}))
+	require.False(t, isChatMember(&models.ChatMember{Type: models.ChatMemberTypeBanned}))
+}
+
+func TestHandleArchiveChatCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.botID = 999
+
+	userID := int64(408101)
+	archiveID := int64(-1004081010000)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Archie"}))
+
+	run := func(mockBot *mocks.MockBot, text string) {
+		update := mocks.CommandUpdate(userID, userID, text)
+		update.Message.From.FirstName = "Archie"
+		b.handleArchiveChatCore(ctx, mockBot, update)
+	}
+
+	t.Run("shows the setting and usage", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		run(mockBot, "/archivechat")
+		require.Contains(t, mockBot.LastSentMessage().Text, "Archiving is off")
+		require.Contains(t, mockBot.LastSentMessage().Text, archiveChatUsage)
+	})
+
+	t.Run("rejects a non-numeric chat", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		run(mockBot, "/archivechat finance")
+		require.Equal(t, archiveChatUsage, mockBot.LastSentMessage().Text)
+	})
+
+	t.Run("requires the bot in the chat", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		mockBot.ChatMemberStatus = map[int64]models.ChatMemberType{b.botID: models.ChatMemberTypeBanned}
+		run(mockBot, "/archivechat -1004081010000")
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		require.Contains(t, mockBot.LastSentMessage().Text, "I'm not in chat")
+
+		current, err := b.userRepo.GetArchiveChatID(ctx, userID)
+		require.NoError(t, err)
+		require.Zero(t, current)
+	})
+
+	t.Run("requires the user in the chat", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		mockBot.ChatMemberStatus = map[int64]models.ChatMemberType{userID: models.ChatMemberTypeLeft}
+		run(mockBot, "/archivechat -1004081010000")
+		require.Contains(t, mockBot.LastSentMessage().Text, "You're not in chat")
+	})
+
+	t.Run("fails when the test message fails", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		mockBot.SendMessageError = errors.New("forbidden")
+		run(mockBot, "/archivechat -1004081010000")
+
+		current, err := b.userRepo.GetArchiveChatID(ctx, userID)
+		require.NoError(t, err)
+		require.Zero(t, current)
+	})
+
+	t.Run("verifies and saves the chat", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		run(mockBot, "/archivechat -1004081010000")
+
+		require.Equal(t, 2, mockBot.SentMessageCount())
+		test := mockBot.SentMessages[0]
+		require.Equal(t, archiveID, test.ChatID)
+		require.Contains(t, test.Text, "monthly expense CSVs for Archie")
+		require.Contains(t, mockBot.LastSentMessage().Text, "on the 1st of each month")
+
+		current, err := b.userRepo.GetArchiveChatID(ctx, userID)
+		require.NoError(t, err)
+		require.Equal(t, archiveID, current)
+
+		mockBot = mocks.NewMockBot()
+		run(mockBot, "/archivechat")
+		require.Contains(t, mockBot.LastSentMessage().Text, "-1004081010000")
+	})
+
+	t.Run("turns archiving off", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		run(mockBot, "/archivechat OFF")
+		require.Contains(t, mockBot.LastSentMessage().Text, "Archiving is off")
+
+		current, err := b.userRepo.GetArchiveChatID(ctx, userID)
+		require.NoError(t, err)
+		require.Zero(t, current)
+	})
+}
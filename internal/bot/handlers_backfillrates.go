@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/exchange"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// rateBackfillBatchSize is how many expenses /backfillrates loads per query.
+const rateBackfillBatchSize = 100
+
+const backfillUnsupportedMsg = "❌ The exchange rate provider does not offer historical rates."
+
+// rateBackfillResult counts what one /backfillrates run did.
+type rateBackfillResult struct {
+	Filled  int
+	Failed  int
+	Batches int
+}
+
+// rateKey identifies one historical rate lookup.
+type rateKey struct {
+	From, To, Date string
+}
+
+// backfillRates stores a conversion to the owner's default currency for
+// every expense that lacks one, at the rate published on the day the
+// expense was created. Expenses are read batchSize at a time in ID order.
+// Rates are looked up once per currency pair and day; expenses whose rate
+// cannot be fetched are counted as failed and skipped.
+func (b *Bot) backfillRates(
+	ctx context.Context,
+	hist exchange.HistoricalConverter,
+	batchSize int,
+) (rateBackfillResult, error) {
+	var result rateBackfillResult
+	rates := make(map[rateKey]decimal.NullDecimal)
+	afterID := 0
+	for {
+		batch, err := b.expenseRepo.ListMissingConversions(ctx, afterID, batchSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to list expenses missing conversions: %w", err)
+		}
+		if len(batch) == 0 {
+			return result, nil
+		}
+		result.Batches++
+
+		for _, m := range batch {
+			afterID = m.ExpenseID
+			date := m.CreatedAt.UTC()
+			key := rateKey{From: m.Currency, To: m.DefaultCurrency, Date: date.Format("2006-01-02")}
+			rate, seen := rates[key]
+			if !seen {
+				conv, err := hist.ConvertOn(ctx, decimal.NewFromInt(1), m.Currency, m.DefaultCurrency, date)
+				if errors.Is(err, exchange.ErrHistoricalUnsupported) {
+					return result, fmt.Errorf("failed to backfill rates: %w", err)
+				}
+				if err != nil {
+					logger.Log.Warn().
+						Err(err).
+						Str("source_currency", m.Currency).
+						Str("target_currency", m.DefaultCurrency).
+						Str("date", key.Date).
+						Msg("Historical rate unavailable; skipping expenses")
+				} else {
+					rate = decimal.NewNullDecimal(conv.Rate)
+				}
+				rates[key] = rate
+			}
+			if !rate.Valid {
+				result.Failed++
+				continue
+			}
+
+			converted := m.Amount.Mul(rate.Decimal).Round(appmodels.CurrencyMinorUnits(m.DefaultCurrency))
+			updated, err := b.expenses().SetConversion(ctx, m.ExpenseID, rate.Decimal, converted, m.DefaultCurrency)
+			if err != nil {
+				return result, fmt.Errorf("failed to store conversion: %w", err)
+			}
+			if updated {
+				result.Filled++
+			}
+		}
+	}
+}
+
+// handleBackfillRates handles the /backfillrates command that stores
+// historical conversions for expenses recorded before they were captured.
+func (b *Bot) handleBackfillRates(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleBackfillRatesCore(ctx, tgBot, update)
+}
+
+// handleBackfillRatesCore is the testable implementation of
+// handleBackfillRates.
+func (b *Bot) handleBackfillRatesCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+
+	if !b.cfg.IsSuperAdmin(update.Message.From.ID, update.Message.From.Username) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   onlySuperadminsMsg,
+		})
+		return
+	}
+
+	hist, ok := b.exchangeService.(exchange.HistoricalConverter)
+	if !ok {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   backfillUnsupportedMsg,
+		})
+		return
+	}
+
+	result, err := b.backfillRates(ctx, hist, rateBackfillBatchSize)
+	if errors.Is(err, exchange.ErrHistoricalUnsupported) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   backfillUnsupportedMsg,
+		})
+		return
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Int("filled", result.Filled).Msg("Failed to backfill exchange rates")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("❌ Backfill stopped after storing %d conversions.", result.Filled),
+		})
+		return
+	}
+
+	text := fmt.Sprintf("💱 Stored historical conversions for %d expenses.", result.Filled)
+	if result.Failed > 0 {
+		text += fmt.Sprintf("\n⚠️ %d expenses were skipped because their rate was unavailable. "+
+			"Run /backfillrates again later.", result.Failed)
+	}
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	})
+}
@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/exchange"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// historicalExchangeService converts at fixed per-currency rates and
+// records which historical lookups were made.
+type historicalExchangeService struct {
+	testExchangeService
+	rates   map[string]decimal.Decimal
+	lookups []string
+}
+
+func (s *historicalExchangeService) ConvertOn(
+	_ context.Context,
+	amount decimal.Decimal,
+	fromCurrency, _ string,
+	date time.Time,
+) (exchange.ConversionResult, error) {
+	s.lookups = append(s.lookups, fromCurrency+"@"+date.Format("2006-01-02"))
+	rate, ok := s.rates[fromCurrency]
+	if !ok {
+		return exchange.ConversionResult{}, errors.New("no rate")
+	}
+	return exchange.ConversionResult{Amount: amount.Mul(rate), Rate: rate, RateDate: date}, nil
+}
+
+func TestBackfillRates(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	userID := int64(394001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{
+		ID: userID, FirstName: "Backfill", DefaultCurrency: currencyCodeSGD,
+	}))
+
+	create := func(amount, currency string, day int) *appmodels.Expense {
+		e := &appmodels.Expense{UserID: userID, Amount: decimal.RequireFromString(amount), Currency: currency}
+		require.NoError(t, b.expenseRepo.Create(ctx, e))
+		_, err := pool.Exec(ctx, `UPDATE expenses SET created_at = $2 WHERE id = $1`,
+			e.ID, time.Date(2025, 1, day, 10, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		return e
+	}
+	usd1 := create("10", "USD", 15)
+	usd2 := create("20", "USD", 15)
+	usd3 := create("5", "USD", 16)
+	jpy := create("1000", currencyCodeJPY, 15)
+	thb := create("100", currencyCodeTHB, 15)
+	sgd := create("7", currencyCodeSGD, 15)
+	stored := &appmodels.Expense{
+		UserID:            userID,
+		Amount:            decimal.RequireFromString("3"),
+		Currency:          "USD",
+		RateToDefault:     decimal.NewNullDecimal(decimal.RequireFromString("1.30")),
+		ConvertedAmount:   decimal.NewNullDecimal(decimal.RequireFromString("3.90")),
+		ConvertedCurrency: currencyCodeSGD,
+	}
+	require.NoError(t, b.expenseRepo.Create(ctx, stored))
+
+	hist := &historicalExchangeService{rates: map[string]decimal.Decimal{
+		"USD":           decimal.RequireFromString("1.35"),
+		currencyCodeJPY: decimal.RequireFromString("0.00873"),
+	}}
+	result, err := b.backfillRates(ctx, hist, 2)
+	require.NoError(t, err)
+	require.Equal(t, rateBackfillResult{Filled: 4, Failed: 1, Batches: 3}, result)
+	require.Equal(t, []string{"USD@2025-01-15", "USD@2025-01-16", "JPY@2025-01-15", "THB@2025-01-15"},
+		hist.lookups, "rates are fetched once per currency and day")
+
+	conversion := func(e *appmodels.Expense) *appmodels.Expense {
+		got, err := b.expenseRepo.GetByID(ctx, e.ID)
+		require.NoError(t, err)
+		return got
+	}
+	for _, tt := range []struct {
+		expense *appmodels.Expense
+		want    string
+	}{
+		{expense: usd1, want: "13.50"},
+		{expense: usd2, want: "27.00"},
+		{expense: usd3, want: "6.75"},
+		{expense: jpy, want: "8.73"},
+		{expense: stored, want: "3.90"},
+	} {
+		got := conversion(tt.expense)
+		require.True(t, got.ConvertedAmount.Valid, tt.want)
+		require.True(t, decimal.RequireFromString(tt.want).Equal(got.ConvertedAmount.Decimal), tt.want)
+		require.Equal(t, currencyCodeSGD, got.ConvertedCurrency)
+	}
+	require.False(t, conversion(thb).ConvertedAmount.Valid, "rate unavailable")
+	require.False(t, conversion(sgd).ConvertedAmount.Valid, "already in the default currency")
+
+	t.Run("reports prefer the stored rate", func(t *testing.T) {
+		expenses := []appmodels.Expense{*conversion(usd1), *conversion(thb)}
+		b.exchangeService = &mockExchangeService{result: exchange.ConversionResult{
+			Rate: decimal.RequireFromString("0.04"),
+		}}
+		b.applyLiveConversions(ctx, userID, expenses)
+		totals := sumExpenseAmountsByCurrency(expenses)
+		require.True(t, decimal.RequireFromString("17.50").Equal(totals[currencyCodeSGD]), totals)
+		require.Len(t, totals, 1)
+	})
+}
+
+func TestHandleBackfillRatesCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	t.Run("superadmin only", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleBackfillRatesCore(ctx, mockBot, mocks.CommandUpdate(999, 999, "/backfillrates"))
+		require.Equal(t, onlySuperadminsMsg, mockBot.LastSentMessage().Text)
+	})
+
+	t.Run("needs a historical provider", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleBackfillRatesCore(ctx, mockBot, mocks.CommandUpdate(123456, 123456, "/backfillrates"))
+		require.Equal(t, backfillUnsupportedMsg, mockBot.LastSentMessage().Text)
+	})
+
+	t.Run("reports the result", func(t *testing.T) {
+		b.exchangeService = &historicalExchangeService{}
+		mockBot := mocks.NewMockBot()
+		b.handleBackfillRatesCore(ctx, mockBot, mocks.CommandUpdate(123456, 123456, "/backfillrates"))
+		require.Contains(t, mockBot.LastSentMessage().Text, "Stored historical conversions for 0 expenses")
+	})
+}
+
+func TestSumExpenseAmountsByCurrency_PrefersStoredConversion(t *testing.T) {
+	t.Parallel()
+
+	expenses := []appmodels.Expense{
+		{Amount: decimal.RequireFromString("10"), Currency: currencyCodeSGD},
+		{
+			Amount:            decimal.RequireFromString("20"),
+			Currency:          "USD",
+			ConvertedAmount:   decimal.NewNullDecimal(decimal.RequireFromString("27")),
+			ConvertedCurrency: currencyCodeSGD,
+		},
+		{Amount: decimal.RequireFromString("100"), Currency: currencyCodeTHB},
+	}
+	totals := sumExpenseAmountsByCurrency(expenses)
+	require.True(t, decimal.RequireFromString("37").Equal(totals[currencyCodeSGD]))
+	require.True(t, decimal.RequireFromString("100").Equal(totals[currencyCodeTHB]))
+}
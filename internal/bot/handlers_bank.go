@@ -0,0 +1,356 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
+)
+
+const (
+	// maxBankTemplateLength bounds the patterns admins can add.
+	maxBankTemplateLength = 500
+
+	bankGroupAmount   = "amount"
+	bankGroupCurrency = "currency"
+	bankGroupMerchant = "merchant"
+	bankGroupDate     = "date"
+
+	bankTemplatesUsage = "Usage:\n" +
+		"<code>/banktemplates list</code>\n" +
+		"<code>/banktemplates add &lt;name&gt; &lt;regex&gt;</code>\n" +
+		"<code>/banktemplates delete &lt;name&gt;</code>\n\n" +
+		"The regex needs an <code>(?P&lt;amount&gt;…)</code> group and may have " +
+		"<code>currency</code>, <code>merchant</code> and <code>date</code> groups."
+)
+
+// bankTemplateNameRegex matches the names of bank templates.
+var bankTemplateNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,29}$`)
+
+// bankDateLayouts are the date formats bank messages use, day first.
+// Layouts without a year take the most recent matching day.
+var bankDateLayouts = []struct {
+	layout  string
+	hasYear bool
+}{
+	{layout: "2006-01-02", hasYear: true},
+	{layout: "2/1/2006", hasYear: true},
+	{layout: "2-1-2006", hasYear: true},
+	{layout: "2/1/06", hasYear: true},
+	{layout: "2 Jan 2006", hasYear: true},
+	{layout: "2/1"},
+	{layout: "2-1"},
+	{layout: "2 Jan"},
+}
+
+// bankTemplate is a compiled bank message template.
+type bankTemplate struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// bankMessage is an expense read from a bank notification.
+type bankMessage struct {
+	Template string
+	Amount   decimal.Decimal
+	Currency string
+	Merchant string
+	Date     time.Time
+}
+
+// compileBankTemplate compiles a bank template pattern. It must have an
+// "amount" group.
+func compileBankTemplate(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > maxBankTemplateLength {
+		return nil, fmt.Errorf("the regex is longer than %d characters", maxBankTemplateLength)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	if re.SubexpIndex(bankGroupAmount) < 0 {
+		return nil, errors.New("the regex has no (?P<amount>…) group")
+	}
+	return re, nil
+}
+
+// loadBankTemplates returns the stored bank templates that compile.
+func (b *Bot) loadBankTemplates(ctx context.Context) ([]bankTemplate, error) {
+	stored, err := b.bankTemplateRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bank templates: %w", err)
+	}
+
+	templates := make([]bankTemplate, 0, len(stored))
+	for _, tmpl := range stored {
+		re, err := compileBankTemplate(tmpl.Pattern)
+		if err != nil {
+			logger.Log.Warn().Err(err).Str("template", tmpl.Name).Msg("Skipping invalid bank template")
+			continue
+		}
+		templates = append(templates, bankTemplate{name: tmpl.Name, re: re})
+	}
+	return templates, nil
+}
+
+// parseBankMessage reads an expense from text with the first template that
+// matches it and yields a positive amount. now is used to pick the year of
+// dates that have none. It returns nil when no template matches.
+func parseBankMessage(text string, templates []bankTemplate, now time.Time) *bankMessage {
+	for _, tmpl := range templates {
+		match := tmpl.re.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+		group := func(name string) string {
+			if i := tmpl.re.SubexpIndex(name); i >= 0 {
+				return strings.TrimSpace(match[i])
+			}
+			return ""
+		}
+
+		amount, err := decimal.NewFromString(strings.ReplaceAll(group(bankGroupAmount), ",", ""))
+		if err != nil || !amount.IsPositive() {
+			continue
+		}
+		return &bankMessage{
+			Template: tmpl.name,
+			Amount:   amount,
+			Currency: bankCurrencyCode(group(bankGroupCurrency)),
+			Merchant: group(bankGroupMerchant),
+			Date:     parseBankDate(group(bankGroupDate), now),
+		}
+	}
+	return nil
+}
+
+// bankCurrencyCode turns the currency a bank message shows into a currency
+// code. A bare "$" is ambiguous and, like in typed expenses, leaves the
+// user's default currency.
+func bankCurrencyCode(raw string) string {
+	if raw == "" || raw == "$" {
+		return ""
+	}
+	if code, ok := currencySymbolToCode[raw]; ok {
+		return code
+	}
+	return service.NormalizeCurrencyCode(raw)
+}
+
+// parseBankDate parses the date of a bank message. A date without a year is
+// taken as the latest such day that is not after now. It returns the zero
+// time when s is empty or not a date.
+func parseBankDate(s string, now time.Time) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	for _, l := range bankDateLayouts {
+		date, err := time.ParseInLocation(l.layout, s, now.Location())
+		if err != nil {
+			continue
+		}
+		if l.hasYear {
+			return date
+		}
+		date = date.AddDate(now.Year(), 0, 0)
+		if date.After(now) {
+			date = date.AddDate(-1, 0, 0)
+		}
+		return date
+	}
+	return time.Time{}
+}
+
+// handleBankMessageCore turns a forwarded bank notification into a draft
+// expense with the receipt confirm, edit and cancel buttons. Messages that
+// match no bank template are ignored without a reply.
+func (b *Bot) handleBankMessageCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	text := update.Message.Text
+	if text == "" {
+		text = update.Message.Caption
+	}
+	if text == "" || b.tooLongForFreeText(text) {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+
+	templates, err := b.loadBankTemplates(ctx)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load bank templates")
+		return
+	}
+	msg := parseBankMessage(text, templates, b.now().In(b.displayLocation))
+	if msg == nil {
+		logger.Log.Debug().Int64("chat_id", chatID).Msg("Forwarded message matched no bank template")
+		return
+	}
+
+	categories, err := b.visibleCategories(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for bank message")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to fetch categories. Please try again.",
+		})
+		return
+	}
+
+	merchant := msg.Merchant
+	if merchant == "" {
+		merchant = "Unknown merchant"
+	}
+	parsed := &ParsedExpense{
+		Amount:      msg.Amount,
+		Currency:    msg.Currency,
+		Description: merchant,
+		Source:      appmodels.ExpenseSourceBank,
+	}
+	expense, err := b.buildExpenseFromParsed(ctx, userID, parsed, categories)
+	if err != nil {
+		text := failedSaveExpenseMsg
+		if errors.Is(err, service.ErrAboveHardCap) {
+			text = b.amountAboveHardCapMsg(expense.Amount, expense.Currency)
+		}
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+		return
+	}
+
+	if _, err := b.expenses().CreateDraft(ctx, expense); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create draft from bank message")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   failedSaveExpenseMsg,
+		})
+		return
+	}
+
+	logger.Log.Info().
+		Int("expense_id", expense.ID).
+		Str("template", msg.Template).
+		Str("user_hash", logger.HashUserID(userID)).
+		Msg("Draft created from bank message")
+
+	reply := botfmt.BankMessageCard(expense, msg.Date)
+	if parsed.DescriptionTruncated {
+		reply += "\n\n" + b.descriptionTruncatedNote()
+	}
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        reply,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: buildReceiptConfirmationKeyboard(expense.ID, expense.Currency),
+	})
+}
+
+// formatBankTemplates lists bank templates with their patterns.
+func formatBankTemplates(templates []repository.BankTemplate) string {
+	if len(templates) == 0 {
+		return "🏦 No bank templates. Add one with <code>/banktemplates add &lt;name&gt; &lt;regex&gt;</code>."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🏦 <b>Bank Templates</b>\n")
+	for _, tmpl := range templates {
+		fmt.Fprintf(&sb, "\n<b>%s</b>\n<code>%s</code>\n", botfmt.EscapeHTML(tmpl.Name), botfmt.EscapeHTML(tmpl.Pattern))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// handleBankTemplates handles the /banktemplates command.
+func (b *Bot) handleBankTemplates(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleBankTemplatesCore(ctx, tgBot, update)
+}
+
+// handleBankTemplatesCore is the testable implementation of
+// handleBankTemplates. Superadmins list, add and delete the templates that
+// read forwarded bank messages.
+func (b *Bot) handleBankTemplatesCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	if !b.cfg.IsSuperAdmin(userID, update.Message.From.Username) {
+		send(onlySuperadminsMsg)
+		return
+	}
+
+	action, rest, _ := strings.Cut(extractAdminArgs(update.Message.Text), " ")
+	name, pattern, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	name = strings.ToLower(name)
+	pattern = strings.TrimSpace(pattern)
+
+	switch strings.ToLower(action) {
+	case "", "list":
+		templates, err := b.bankTemplateRepo.List(ctx)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to list bank templates")
+			send("❌ Failed to fetch bank templates.")
+			return
+		}
+		send(formatBankTemplates(templates))
+	case "add":
+		if !bankTemplateNameRegex.MatchString(name) || pattern == "" {
+			send(bankTemplatesUsage)
+			return
+		}
+		if _, err := compileBankTemplate(pattern); err != nil {
+			send("❌ " + botfmt.EscapeHTML(err.Error()))
+			return
+		}
+		if err := b.bankTemplateRepo.Save(ctx, name, pattern, userID); err != nil {
+			logger.Log.Error().Err(err).Str("template", name).Msg("Failed to save bank template")
+			send("❌ Failed to save the bank template.")
+			return
+		}
+		send(fmt.Sprintf("✅ Bank template <b>%s</b> saved.", botfmt.EscapeHTML(name)))
+	case "delete":
+		if name == "" {
+			send(bankTemplatesUsage)
+			return
+		}
+		deleted, err := b.bankTemplateRepo.Delete(ctx, name)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("template", name).Msg("Failed to delete bank template")
+			send("❌ Failed to delete the bank template.")
+			return
+		}
+		if !deleted {
+			send(fmt.Sprintf("No bank template named <b>%s</b>.", botfmt.EscapeHTML(name)))
+			return
+		}
+		send(fmt.Sprintf("🗑 Bank template <b>%s</b> deleted.", botfmt.EscapeHTML(name)))
+	default:
+		send(bankTemplatesUsage)
+	}
+}
@@ -0,0 +1,186 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const testBankMessage = "You spent SGD 23.90 at NTUC FAIRPRICE on 12/03"
+
+func defaultBankTemplates(t *testing.T) []bankTemplate {
+	t.Helper()
+
+	templates := make([]bankTemplate, 0, len(database.DefaultBankTemplates))
+	for _, tmpl := range database.DefaultBankTemplates {
+		re, err := compileBankTemplate(tmpl.Pattern)
+		require.NoError(t, err, tmpl.Name)
+		templates = append(templates, bankTemplate{name: tmpl.Name, re: re})
+	}
+	return templates
+}
+
+func forwardedUpdate(chatID, userID int64, text string) *models.Update {
+	update := mocks.CommandUpdate(chatID, userID, text)
+	update.Message.ForwardOrigin = &models.MessageOrigin{
+		Type:                    models.MessageOriginTypeHiddenUser,
+		MessageOriginHiddenUser: &models.MessageOriginHiddenUser{SenderUserName: "My Bank"},
+	}
+	return update
+}
+
+func TestParseBankMessage(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	custom, err := compileBankTemplate(`Debit (?P<amount>[\d.]+) @ (?P<merchant>\w+)`)
+	require.NoError(t, err)
+	templates := append(defaultBankTemplates(t), bankTemplate{name: "custom", re: custom})
+
+	tests := []struct {
+		name     string
+		text     string
+		template string
+		amount   string
+		currency string
+		merchant string
+		date     time.Time
+	}{
+		{
+			name: "spent with short date", text: testBankMessage, template: "you-spent",
+			amount: "23.90", currency: currencyCodeSGD, merchant: "NTUC FAIRPRICE",
+			date: time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "card charged with trailing text",
+			text:     "Your card ending 1234 was charged USD 1,015.00 at AMAZON.COM on 2026-03-12. Not you? Call us.",
+			template: "card-charged", amount: "1015", currency: currencyCodeUSD, merchant: "AMAZON.COM",
+			date: time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "transaction to a payee with a month name",
+			text:     "DBS Alert: A transaction of S$4.50 was made to GRAB on 20 Dec.",
+			template: "transaction-of", amount: "4.5", currency: currencyCodeSGD, merchant: "GRAB",
+			date: time.Date(2025, 12, 20, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "bare dollar and no date", text: "You have spent $8 at Kopi Corner", template: "you-spent",
+			amount: "8", merchant: "Kopi Corner",
+		},
+		{
+			name: "custom template", text: "Debit 12.00 @ Starbucks", template: "custom",
+			amount: "12", merchant: "Starbucks",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			msg := parseBankMessage(tt.text, templates, now)
+			require.NotNil(t, msg)
+			require.Equal(t, tt.template, msg.Template)
+			require.True(t, mustParseDecimal(tt.amount).Equal(msg.Amount), "amount %s", msg.Amount)
+			require.Equal(t, tt.currency, msg.Currency)
+			require.Equal(t, tt.merchant, msg.Merchant)
+			require.True(t, tt.date.Equal(msg.Date), "date %s", msg.Date)
+		})
+	}
+
+	for _, text := range []string{"Your OTP is 123456", "Meeting at 5pm", "You spent SGD 0.00 at Nowhere"} {
+		require.Nil(t, parseBankMessage(text, templates, now), text)
+	}
+}
+
+func TestCompileBankTemplate(t *testing.T) {
+	t.Parallel()
+
+	_, err := compileBankTemplate(`Paid (?P<amount>\d+`)
+	require.ErrorContains(t, err, "invalid regex")
+	_, err = compileBankTemplate(`Paid (\d+)`)
+	require.ErrorContains(t, err, "amount")
+	_, err = compileBankTemplate(`(?P<amount>\d+)` + strings.Repeat("x", maxBankTemplateLength))
+	require.ErrorContains(t, err, "longer than")
+}
+
+func TestParseBankDate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC), parseBankDate("28-02", now))
+	require.Equal(t, time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC), parseBankDate("2 mar", now))
+	require.Equal(t, time.Date(2024, 7, 5, 0, 0, 0, 0, time.UTC), parseBankDate("5/7/24", now))
+	require.True(t, parseBankDate("yesterday", now).IsZero())
+	require.True(t, parseBankDate("", now).IsZero())
+}
+
+func TestHandleBankMessageCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(401001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{
+		ID: userID, FirstName: "Bank", DefaultCurrency: currencyCodeSGD,
+	}))
+
+	t.Run("matching forward creates a draft", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleBankMessageCore(ctx, mockBot, forwardedUpdate(userID, userID, testBankMessage))
+
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		sent := mockBot.LastSentMessage()
+		require.Contains(t, sent.Text, "Bank Message Read")
+		require.Contains(t, sent.Text, "NTUC FAIRPRICE")
+		requireInlineKeyboard(t, sent.ReplyMarkup)
+
+		var status, amount string
+		err := pool.QueryRow(ctx, `
+			SELECT status, amount::text FROM expenses WHERE user_id = $1 ORDER BY id DESC LIMIT 1
+		`, userID).Scan(&status, &amount)
+		require.NoError(t, err)
+		require.Equal(t, string(appmodels.ExpenseStatusDraft), status)
+		require.Equal(t, "23.90", amount)
+	})
+
+	t.Run("non-matching forward is ignored", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleBankMessageCore(ctx, mockBot, forwardedUpdate(userID, userID, "Lunch at 1pm tomorrow?"))
+		require.Zero(t, mockBot.SentMessageCount())
+	})
+}
+
+func TestHandleBankTemplatesCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.cfg = &config.Config{WhitelistedUserIDs: []int64{100}}
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: 100, FirstName: "Admin"}))
+
+	run := func(userID int64, text string) string {
+		mockBot := mocks.NewMockBot()
+		b.handleBankTemplatesCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, text))
+		return mockBot.LastSentMessage().Text
+	}
+
+	require.Equal(t, onlySuperadminsMsg, run(999, "/banktemplates"))
+	require.Contains(t, run(100, "/banktemplates"), "you-spent")
+	require.Equal(t, bankTemplatesUsage, run(100, "/banktemplates add"))
+	require.Contains(t, run(100, `/banktemplates add mybank Paid (\d+)`), "amount")
+	require.Contains(t, run(100, `/banktemplates add mybank Paid (?P<amount>\d+) at (?P<merchant>\w+)`), "saved")
+	require.Contains(t, run(100, "/banktemplates list"), "mybank")
+
+	mockBot := mocks.NewMockBot()
+	b.handleBankMessageCore(ctx, mockBot, forwardedUpdate(100, 100, "Paid 42 at Bookshop"))
+	require.Contains(t, mockBot.LastSentMessage().Text, "Bookshop")
+
+	require.Contains(t, run(100, "/banktemplates delete mybank"), "deleted")
+	require.Contains(t, run(100, "/banktemplates delete mybank"), "No bank template")
+	require.NotContains(t, run(100, "/banktemplates list"), "mybank")
+}
@@ -0,0 +1,678 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
+)
+
+const (
+	// budgetPeriodLayout formats the key of a monthly budget period.
+	budgetPeriodLayout = "2006-01"
+	// budgetWarnPercent is the share of a budget from which new expenses in
+	// the category show how much of it is spent.
+	budgetWarnPercent = 80
+	// budgetFallbackTTL is how long the "Save as Uncategorized" button of a
+	// blocked chat expense stays valid.
+	budgetFallbackTTL = 10 * time.Minute
+
+	// budgetButtonMaxBytes caps the text of category buttons that carry a
+	// budget hint, so long names are shortened instead of wrapping.
+	budgetButtonMaxBytes = 64
+
+	budgetHardFlag = "hard"
+
+	budgetCallbackPrefix     = "budgetfb_"
+	budgetFallbackNewFmt     = "budgetfb_new_%s"
+	budgetFallbackDraftFmt   = "budgetfb_draft_%d"
+	budgetFallbackButtonText = "📂 Save as Uncategorized"
+
+	budgetUsageMsg = "Usage:\n" +
+		"<code>/budget</code> — show this month's budgets\n" +
+		"<code>/budget set Category 200</code> — set a monthly budget\n" +
+		"<code>/budget set Category 200 hard</code> — also block expenses over it\n" +
+		"<code>/budget set Category 200 USD</code> — set it in another currency\n" +
+		"<code>/budget remove Category</code> — remove a budget\n" +
+		"<code>/budget override Category</code> — lift a hard budget until the end of the month, once per month\n" +
+		"<code>/budget convert [Category]</code> — move budgets into your default currency at today's rate\n" +
+		"<code>/budget suggest</code> — suggest budgets from your average monthly spending"
+	budgetFailedMsg = "❌ Failed to update the budget. Please try again."
+
+	// pendingExpenseExpiredMsg answers buttons of an unsaved chat expense
+	// that is no longer kept in memory.
+	pendingExpenseExpiredMsg = "This expense has expired. Please send it again."
+)
+
+var (
+	errBudgetFallbackExpired  = errors.New("budget fallback expired or already used")
+	errBudgetFallbackNotOwner = errors.New("budget fallback belongs to another user")
+)
+
+// budgetStatus is a category budget with its spending in the current
+// period, converted into the budget currency. Unconverted lists the
+// currencies whose spending had no exchange rate and is left out of Spent.
+type budgetStatus struct {
+	repository.Budget
+	Spent       decimal.Decimal
+	Period      string
+	Unconverted []string
+}
+
+// blocks reports whether the budget currently refuses new expenses.
+func (s *budgetStatus) blocks() bool {
+	return s.Enforce && s.OverridePeriod != s.Period
+}
+
+// pendingBudgetFallback is a chat expense refused by a hard budget, kept
+// until the user saves it as Uncategorized or it expires. Nothing is
+// stored in the database until then.
+type pendingBudgetFallback struct {
+	UserID    int64
+	Expense   *appmodels.Expense
+	Parsed    *ParsedExpense
+	ExpiresAt time.Time
+}
+
+// budgetPeriod returns the current budget period of a user: the calendar
+// month in their timezone as [start, end), and its key.
+func (b *Bot) budgetPeriod(ctx context.Context, userID int64) (time.Time, time.Time, string) {
+	start, end := getMonthDateRangeAt(b.now().In(b.locationForUser(ctx, userID)))
+	return start, end, start.Format(budgetPeriodLayout)
+}
+
+// loadBudgetStatus loads the budget of a category together with this
+// period's spending in the budget currency. The error wraps pgx.ErrNoRows
+// when the category has no budget.
+func (b *Bot) loadBudgetStatus(
+	ctx context.Context,
+	converter *budgetConverter,
+	userID int64,
+	categoryID int,
+) (*budgetStatus, error) {
+	budget, err := b.budgetRepo.GetByCategory(ctx, userID, categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load budget: %w", err)
+	}
+	budget.Currency = budgetCurrency(budget, b.getUserDefaultCurrency(ctx, userID))
+
+	start, end, period := b.budgetPeriod(ctx, userID)
+	totals, err := b.expenseRepo.GetCategoryCurrencyTotalsByUserIDAndDateRange(ctx, userID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load budget spending: %w", err)
+	}
+	spent, unconverted := converter.spending(ctx, totals, categoryID, budget.Currency)
+	return &budgetStatus{Budget: *budget, Spent: spent, Period: period, Unconverted: unconverted}, nil
+}
+
+// budgetBlockedText explains why an expense was refused.
+func budgetBlockedText(status *service.HardBudget, expense *appmodels.Expense) string {
+	return fmt.Sprintf("🚫 <b>%s budget reached</b>\n\n"+
+		"%s left of %s this month, so this %s expense was not saved.\n\n"+
+		"Save it as Uncategorized instead?",
+		botfmt.EscapeHTML(status.CategoryName),
+		botfmt.Money(status.Remaining(), status.Currency),
+		botfmt.Money(status.Limit, status.Currency),
+		botfmt.Money(expense.Amount, expense.Currency))
+}
+
+// budgetWarningLine describes the category budget once this period's
+// spending reached budgetWarnPercent of it. It returns "" below that, for
+// refunds, and when the category has no budget.
+func (b *Bot) budgetWarningLine(ctx context.Context, expense *appmodels.Expense) string {
+	if expense.CategoryID == nil || expense.IsRefund() {
+		return ""
+	}
+
+	status, err := b.loadBudgetStatus(ctx, b.newBudgetConverter(), expense.UserID, *expense.CategoryID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Log.Warn().Err(err).Int("category_id", *expense.CategoryID).Msg("Failed to load budget for warning")
+		}
+		return ""
+	}
+
+	threshold := status.Amount.Mul(decimal.NewFromInt(budgetWarnPercent)).Div(decimal.NewFromInt(100))
+	if status.Spent.LessThan(threshold) {
+		return ""
+	}
+
+	spent := botfmt.Money(status.Spent, status.Currency)
+	limit := botfmt.Money(status.Amount, status.Currency)
+	name := botfmt.EscapeHTML(status.CategoryName)
+	if status.Spent.GreaterThan(status.Amount) {
+		return fmt.Sprintf("⚠️ Over the %s budget: %s of %s spent this month.", name, spent, limit)
+	}
+	return fmt.Sprintf("📊 %s of the %s %s budget spent this month.", spent, limit, name)
+}
+
+// sendBudgetBlocked refuses a chat expense that would go over a hard
+// budget. The expense is kept in memory behind a "Save as Uncategorized"
+// button; nothing is saved unless the user taps it.
+func (b *Bot) sendBudgetBlocked(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	status *service.HardBudget,
+	expense *appmodels.Expense,
+	parsed *ParsedExpense,
+) {
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(expense.UserID)).
+		Int("category_id", status.CategoryID).
+		Msg("Expense blocked by hard budget")
+
+	params := &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      budgetBlockedText(status, expense),
+		ParseMode: models.ParseModeHTML,
+	}
+	nonce, err := b.storePendingBudgetFallback(&pendingBudgetFallback{
+		UserID:    expense.UserID,
+		Expense:   expense,
+		Parsed:    parsed,
+		ExpiresAt: b.now().Add(budgetFallbackTTL),
+	})
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to store blocked expense")
+	} else {
+		params.ReplyMarkup = &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{{
+				{Text: budgetFallbackButtonText, CallbackData: fmt.Sprintf(budgetFallbackNewFmt, nonce)},
+			}},
+		}
+	}
+	_, _ = tg.SendMessage(ctx, params)
+}
+
+// buildBudgetBlockedDraftKeyboard offers to confirm a blocked receipt draft
+// as Uncategorized, or to go back to the receipt.
+func buildBudgetBlockedDraftKeyboard(expenseID int) *models.InlineKeyboardMarkup {
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{{Text: budgetFallbackButtonText, CallbackData: fmt.Sprintf(budgetFallbackDraftFmt, expenseID)}},
+			{{Text: "⬅️ Back", CallbackData: fmt.Sprintf("receipt_back_%d", expenseID)}},
+		},
+	}
+}
+
+// storePendingBudgetFallback remembers a blocked expense under a new nonce.
+func (b *Bot) storePendingBudgetFallback(pending *pendingBudgetFallback) (string, error) {
+	nonce, err := newCallbackNonce()
+	if err != nil {
+		return "", err
+	}
+
+	b.pendingBudgetMu.Lock()
+	defer b.pendingBudgetMu.Unlock()
+	if b.pendingBudget == nil {
+		b.pendingBudget = make(map[string]*pendingBudgetFallback)
+	}
+	now := b.now()
+	for key, p := range b.pendingBudget {
+		if now.After(p.ExpiresAt) {
+			delete(b.pendingBudget, key)
+		}
+	}
+	b.pendingBudget[nonce] = pending
+	return nonce, nil
+}
+
+// takePendingBudgetFallback removes and returns the blocked expense for
+// nonce if it belongs to userID and has not expired. Each nonce works only
+// once.
+func (b *Bot) takePendingBudgetFallback(nonce string, userID int64) (*pendingBudgetFallback, error) {
+	b.pendingBudgetMu.Lock()
+	defer b.pendingBudgetMu.Unlock()
+
+	pending, ok := b.pendingBudget[nonce]
+	if !ok {
+		return nil, errBudgetFallbackExpired
+	}
+	if pending.UserID != userID {
+		return nil, errBudgetFallbackNotOwner
+	}
+	delete(b.pendingBudget, nonce)
+	if b.now().After(pending.ExpiresAt) {
+		return nil, errBudgetFallbackExpired
+	}
+	return pending, nil
+}
+
+// clearExpenseCategory turns a built expense into an Uncategorized one.
+func clearExpenseCategory(expense *appmodels.Expense) {
+	expense.CategoryID = nil
+	expense.Category = nil
+	expense.AICategorized = false
+	expense.AIConfidence = nil
+}
+
+// handleBudgetFallbackCallback handles the "Save as Uncategorized" button
+// of an expense refused by a hard budget.
+func (b *Bot) handleBudgetFallbackCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleBudgetFallbackCallbackCore(ctx, tgBot, update)
+}
+
+// handleBudgetFallbackCallbackCore is the testable implementation of
+// handleBudgetFallbackCallback. Chat expenses are saved from memory;
+// receipt drafts are confirmed without their category.
+func (b *Bot) handleBudgetFallbackCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	chatID := query.Message.Message.Chat.ID
+	messageID := query.Message.Message.ID
+
+	kind, ref, ok := strings.Cut(strings.TrimPrefix(query.Data, budgetCallbackPrefix), "_")
+	if !ok {
+		answerCallback(ctx, tg, query)
+		return
+	}
+
+	if kind == "draft" {
+		expenseID, err := strconv.Atoi(ref)
+		if err != nil {
+			answerCallback(ctx, tg, query)
+			return
+		}
+		expense, ok := b.loadCallbackExpense(ctx, tg, query, expenseID, false)
+		if !ok {
+			return
+		}
+		if expense.Status != appmodels.ExpenseStatusDraft {
+			respondStaleCallback(ctx, tg, query, staleExpenseConfirmed, false)
+			return
+		}
+		answerCallback(ctx, tg, query)
+		clearExpenseCategory(expense)
+		b.handleConfirmReceiptCore(ctx, tg, chatID, messageID, expense)
+		return
+	}
+
+	pending, err := b.takePendingBudgetFallback(ref, query.From.ID)
+	if errors.Is(err, errBudgetFallbackNotOwner) {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            staleNotOwnerText,
+		})
+		return
+	}
+	if err != nil {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            pendingExpenseExpiredMsg,
+		})
+		_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+		})
+		return
+	}
+	answerCallback(ctx, tg, query)
+
+	// The refusal stays in the chat for reference; its button is used up.
+	_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+	})
+
+	clearExpenseCategory(pending.Expense)
+	b.storeBuiltExpense(ctx, tg, chatID, pending.UserID, pending.Expense, pending.Parsed)
+}
+
+// handleBudget handles the /budget command.
+func (b *Bot) handleBudget(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleBudgetCore(ctx, tgBot, update)
+}
+
+// handleBudgetCore is the testable implementation of handleBudget. It lists
+// this month's budgets, sets, removes or overrides one, or suggests them.
+func (b *Bot) handleBudgetCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	args := extractCommandArgs(update.Message.Text, "/budget")
+	action, rest, _ := strings.Cut(args, " ")
+	rest = strings.TrimSpace(rest)
+
+	switch strings.ToLower(action) {
+	case "":
+		send(b.formatBudgets(ctx, userID))
+	case "set":
+		send(b.setBudget(ctx, userID, rest))
+	case "remove":
+		send(b.removeBudget(ctx, userID, rest))
+	case "override":
+		send(b.overrideBudget(ctx, userID, update.Message.From.Username, rest))
+	case "convert":
+		send(b.convertBudgets(ctx, userID, rest))
+	case "suggest":
+		b.sendBudgetSuggestions(ctx, tg, chatID, userID)
+	default:
+		send(budgetUsageMsg)
+	}
+}
+
+// budgetCategory resolves a category name for a budget subcommand. On
+// failure it returns nil and the message to send.
+func (b *Bot) budgetCategory(ctx context.Context, userID int64, name string) (*appmodels.Category, string) {
+	if name == "" {
+		return nil, budgetUsageMsg
+	}
+	categories, err := b.visibleCategories(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for budget")
+		return nil, "❌ Failed to fetch categories. Please try again."
+	}
+	_, category := findCategoryByName(categories, name)
+	if category == nil {
+		return nil, fmt.Sprintf("❌ Category \"%s\" not found. Use /categories to see the list.",
+			botfmt.EscapeHTML(echoText(name)))
+	}
+	return category, ""
+}
+
+// budgetSetArgs is a parsed "/budget set". Currency is empty when none was
+// given.
+type budgetSetArgs struct {
+	Name     string
+	Amount   decimal.Decimal
+	Currency string
+	Enforce  bool
+}
+
+// parseBudgetSetArgs splits "Category 200 [CUR] [hard]" into its parts.
+func parseBudgetSetArgs(args string) (budgetSetArgs, bool) {
+	var parsed budgetSetArgs
+	fields := strings.Fields(args)
+	parsed.Enforce = len(fields) > 0 && strings.EqualFold(fields[len(fields)-1], budgetHardFlag)
+	if parsed.Enforce {
+		fields = fields[:len(fields)-1]
+	}
+	if len(fields) > 2 {
+		if code := strings.ToUpper(fields[len(fields)-1]); appmodels.SupportedCurrencies[code] != "" {
+			parsed.Currency = code
+			fields = fields[:len(fields)-1]
+		}
+	}
+	if len(fields) < 2 {
+		return budgetSetArgs{}, false
+	}
+
+	amount, err := decimal.NewFromString(fields[len(fields)-1])
+	if err != nil || !amount.IsPositive() {
+		return budgetSetArgs{}, false
+	}
+	parsed.Name = strings.Join(fields[:len(fields)-1], " ")
+	parsed.Amount = amount.Round(2)
+	return parsed, true
+}
+
+// setBudget sets a budget in the given currency, or in the user's default
+// currency at this moment. The currency stays with the budget when the
+// default currency changes later.
+func (b *Bot) setBudget(ctx context.Context, userID int64, args string) string {
+	parsed, ok := parseBudgetSetArgs(args)
+	if !ok {
+		return budgetUsageMsg
+	}
+	category, msg := b.budgetCategory(ctx, userID, parsed.Name)
+	if category == nil {
+		return msg
+	}
+	return b.saveBudget(ctx, userID, category, &parsed)
+}
+
+// saveBudget sets the budget of category as parsed and returns the reply,
+// for /budget set and the buttons of /budget suggest alike.
+func (b *Bot) saveBudget(
+	ctx context.Context,
+	userID int64,
+	category *appmodels.Category,
+	parsed *budgetSetArgs,
+) string {
+	currency := parsed.Currency
+	if currency == "" {
+		currency = b.getUserDefaultCurrency(ctx, userID)
+	}
+
+	if err := b.budgetRepo.Set(ctx, userID, category.ID, parsed.Amount, currency, parsed.Enforce); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to set budget")
+		return budgetFailedMsg
+	}
+
+	text := fmt.Sprintf("✅ Monthly budget for <b>%s</b> set to %s.",
+		botfmt.EscapeHTML(category.Name), botfmt.Money(parsed.Amount, currency))
+	if parsed.Enforce {
+		text += "\n\n🔒 Expenses that would go over it are refused."
+	}
+	return text
+}
+
+func (b *Bot) removeBudget(ctx context.Context, userID int64, name string) string {
+	category, msg := b.budgetCategory(ctx, userID, name)
+	if category == nil {
+		return msg
+	}
+
+	removed, err := b.budgetRepo.Delete(ctx, userID, category.ID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to remove budget")
+		return budgetFailedMsg
+	}
+	if !removed {
+		return fmt.Sprintf("<b>%s</b> has no budget.", botfmt.EscapeHTML(category.Name))
+	}
+	return fmt.Sprintf("🗑️ Budget for <b>%s</b> removed.", botfmt.EscapeHTML(category.Name))
+}
+
+// overrideBudget lifts a hard budget until the end of the period. Budgets
+// belong to one user, who may override them; superadmins may override
+// another user's budget by putting the user ID first.
+func (b *Bot) overrideBudget(ctx context.Context, userID int64, username, args string) string {
+	targetID := userID
+	if first, rest, ok := strings.Cut(args, " "); ok && b.cfg.IsSuperAdmin(userID, username) {
+		if id, err := strconv.ParseInt(first, 10, 64); err == nil {
+			targetID, args = id, strings.TrimSpace(rest)
+		}
+	}
+
+	category, msg := b.budgetCategory(ctx, userID, args)
+	if category == nil {
+		return msg
+	}
+	name := botfmt.EscapeHTML(category.Name)
+
+	status, err := b.loadBudgetStatus(ctx, b.newBudgetConverter(), targetID, category.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Sprintf("<b>%s</b> has no budget.", name)
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load budget for override")
+		return budgetFailedMsg
+	}
+	if !status.Enforce {
+		return fmt.Sprintf("The <b>%s</b> budget is not a hard budget, so there is nothing to override.", name)
+	}
+
+	overridden, err := b.budgetRepo.SetOverride(ctx, targetID, category.ID, status.Period)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to override budget")
+		return budgetFailedMsg
+	}
+	if !overridden {
+		return fmt.Sprintf("❌ The <b>%s</b> budget was already overridden this month.", name)
+	}
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(targetID)).
+		Str("actor_hash", logger.HashUserID(userID)).
+		Int("category_id", category.ID).
+		Msg("Budget overridden")
+	return fmt.Sprintf("🔓 The <b>%s</b> budget is lifted until the end of the month.", name)
+}
+
+// formatBudgets renders this month's budgets with their spending in each
+// budget's own currency. Budgets in another currency than the user's
+// default also show the limit in it, and point to /budget convert.
+func (b *Bot) formatBudgets(ctx context.Context, userID int64) string {
+	budgets, err := b.budgetRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to list budgets")
+		return "❌ Failed to load budgets. Please try again."
+	}
+	if len(budgets) == 0 {
+		return "No budgets yet.\n\n" + budgetUsageMsg
+	}
+
+	start, end, period := b.budgetPeriod(ctx, userID)
+	totals, err := b.expenseRepo.GetCategoryCurrencyTotalsByUserIDAndDateRange(ctx, userID, start, end)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load budget spending")
+		return "❌ Failed to load budgets. Please try again."
+	}
+	defaultCurrency := b.getUserDefaultCurrency(ctx, userID)
+	converter := b.newBudgetConverter()
+
+	var sb strings.Builder
+	foreign := false
+	fmt.Fprintf(&sb, "💰 <b>Budgets for %s</b>\n", start.Format("January 2006"))
+	for i := range budgets {
+		budget := &budgets[i]
+		currency := budgetCurrency(budget, defaultCurrency)
+		spent, unconverted := converter.spending(ctx, totals, budget.CategoryID, currency)
+
+		marker := ""
+		switch {
+		case budget.Enforce && budget.OverridePeriod == period:
+			marker = " 🔓"
+		case budget.Enforce:
+			marker = " 🔒"
+		}
+		inDefault := ""
+		if currency != defaultCurrency {
+			foreign = true
+			total := repository.CategoryCurrencyTotal{Currency: currency, Amount: budget.Amount}
+			if limit, ok := converter.convert(ctx, &total, defaultCurrency); ok {
+				inDefault = fmt.Sprintf(" (≈ %s)", botfmt.Money(limit, defaultCurrency))
+			}
+		}
+		fmt.Fprintf(&sb, "\n• %s: %s of %s%s%s",
+			botfmt.EscapeHTML(budget.CategoryName),
+			botfmt.Money(spent, currency),
+			botfmt.Money(budget.Amount, currency),
+			inDefault,
+			marker)
+		if len(unconverted) > 0 {
+			fmt.Fprintf(&sb, "\n  ⚠️ Leaves out %s spending (no exchange rate)", strings.Join(unconverted, ", "))
+		}
+	}
+	sb.WriteString("\n\n🔒 hard budget · 🔓 overridden this month")
+	if foreign {
+		fmt.Fprintf(&sb, "\n\nSome budgets are not in your default currency (%s). "+
+			"<code>/budget convert</code> moves them to %s at today's rate.", defaultCurrency, defaultCurrency)
+	}
+	return sb.String()
+}
+
+// categoryBudgetHints returns the budget hint of each of the user's budgeted
+// categories, keyed by category ID, e.g. "(left: S$82)". This month's
+// spending is loaded with one query, so a keyboard is rendered with two
+// queries however many categories it has. Lookup failures are logged and
+// leave the buttons without hints.
+func (b *Bot) categoryBudgetHints(ctx context.Context, userID int64) map[int]string {
+	if b.budgetRepo == nil {
+		return nil
+	}
+	budgets, err := b.budgetRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to list budgets for category keyboard")
+		return nil
+	}
+	if len(budgets) == 0 {
+		return nil
+	}
+
+	start, end, _ := b.budgetPeriod(ctx, userID)
+	totals, err := b.expenseRepo.GetCategoryCurrencyTotalsByUserIDAndDateRange(ctx, userID, start, end)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to load budget spending for category keyboard")
+		return nil
+	}
+
+	defaultCurrency := b.getUserDefaultCurrency(ctx, userID)
+	converter := b.newBudgetConverter()
+	hints := make(map[int]string, len(budgets))
+	for i := range budgets {
+		currency := budgetCurrency(&budgets[i], defaultCurrency)
+		spent, _ := converter.spending(ctx, totals, budgets[i].CategoryID, currency)
+		hints[budgets[i].CategoryID] = budgetHint(budgets[i].Amount, spent, currency)
+	}
+	return hints
+}
+
+// budgetHint describes what is left of a budget in whole currency units,
+// e.g. "(left: S$82)" or "(over by S$15)". What is left is rounded down and
+// an overrun rounded up, so the hint never looks better than it is.
+func budgetHint(limit, spent decimal.Decimal, currency string) string {
+	symbol := botfmt.CurrencySymbol(currency)
+	left := limit.Sub(spent)
+	if left.IsNegative() {
+		return fmt.Sprintf("(over by %s%s)", symbol, left.Neg().RoundCeil(0).String())
+	}
+	return fmt.Sprintf("(left: %s%s)", symbol, left.RoundFloor(0).String())
+}
+
+// categoryButtonText labels a category button, adding the budget hint when
+// there is one. The name is shortened so the label stays within
+// budgetButtonMaxBytes; names without a hint are left as they are.
+func categoryButtonText(name, hint string) string {
+	if hint == "" {
+		return name
+	}
+	suffix := " " + hint
+	return truncateUTF8(name, budgetButtonMaxBytes-len(suffix)) + suffix
+}
+
+// truncateUTF8 shortens s to at most maxBytes bytes without splitting a
+// character, marking a cut with an ellipsis.
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	const ellipsis = "…"
+	cut := maxBytes - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	if cut <= 0 {
+		return ellipsis
+	}
+	return strings.TrimRight(s[:cut], " -") + ellipsis
+}
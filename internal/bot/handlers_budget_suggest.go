@@ -0,0 +1,347 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+const (
+	// budgetSuggestMonths is how many full months before the current one
+	// /budget suggest averages.
+	budgetSuggestMonths = 6
+	// budgetSuggestMinMonths is how many months with spending a category
+	// needs before a budget is suggested for it.
+	budgetSuggestMinMonths = 2
+
+	budgetSuggestCallbackPrefix = "budgetsug_"
+	// budgetSuggestCallbackFmt carries the user, category, amount and
+	// currency of a suggestion.
+	budgetSuggestCallbackFmt = budgetSuggestCallbackPrefix + "%d_%d_%s_%s"
+
+	budgetSuggestFailedMsg = "❌ Failed to load your spending. Please try again."
+)
+
+// budgetSuggestStep is what suggested budgets are rounded to.
+var budgetSuggestStep = decimal.NewFromInt(10)
+
+// categoryMonthSpend is a category's spending in one month, keyed as
+// "2006-01", in the currency of the suggestions.
+type categoryMonthSpend struct {
+	CategoryID int
+	Month      string
+	Amount     decimal.Decimal
+}
+
+// budgetSuggestion is the average monthly spending of a category over the
+// months it had spending in. Amount is the suggested budget, zero when
+// there were fewer than budgetSuggestMinMonths such months.
+type budgetSuggestion struct {
+	CategoryID int
+	Months     int
+	Average    decimal.Decimal
+	Amount     decimal.Decimal
+}
+
+// budgetSuggestMonthKeys returns the budgetSuggestMonths full months
+// before the one starting at periodStart, oldest first.
+func budgetSuggestMonthKeys(periodStart time.Time) []string {
+	months := make([]string, budgetSuggestMonths)
+	for i := range months {
+		months[i] = periodStart.AddDate(0, i-budgetSuggestMonths, 0).Format(budgetPeriodLayout)
+	}
+	return months
+}
+
+// roundBudgetAmount rounds an average to the nearest budgetSuggestStep,
+// and up to one step when it is smaller.
+func roundBudgetAmount(average decimal.Decimal) decimal.Decimal {
+	amount := average.Div(budgetSuggestStep).Round(0).Mul(budgetSuggestStep)
+	if amount.LessThan(budgetSuggestStep) {
+		return budgetSuggestStep
+	}
+	return amount
+}
+
+// suggestBudgets averages each category's spending over the months in
+// months where it was above zero, so months without spending, and months
+// that refunds cancelled out, do not pull the average down. Spending in
+// other months, such as the current partial one, is ignored. Suggestions
+// come largest first, followed by the categories without enough data.
+func suggestBudgets(spend []categoryMonthSpend, months []string) []budgetSuggestion {
+	byCategory := make(map[int]map[string]decimal.Decimal)
+	for _, s := range spend {
+		if !slices.Contains(months, s.Month) {
+			continue
+		}
+		if byCategory[s.CategoryID] == nil {
+			byCategory[s.CategoryID] = make(map[string]decimal.Decimal)
+		}
+		byCategory[s.CategoryID][s.Month] = byCategory[s.CategoryID][s.Month].Add(s.Amount)
+	}
+
+	suggestions := make([]budgetSuggestion, 0, len(byCategory))
+	for categoryID, byMonth := range byCategory {
+		total := decimal.Zero
+		count := 0
+		for _, amount := range byMonth {
+			if amount.IsPositive() {
+				total = total.Add(amount)
+				count++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		suggestion := budgetSuggestion{
+			CategoryID: categoryID,
+			Months:     count,
+			Average:    total.Div(decimal.NewFromInt(int64(count))).Round(2),
+		}
+		if count >= budgetSuggestMinMonths {
+			suggestion.Amount = roundBudgetAmount(suggestion.Average)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	slices.SortFunc(suggestions, func(a, b budgetSuggestion) int {
+		if c := b.Amount.Cmp(a.Amount); c != 0 {
+			return c
+		}
+		if c := b.Average.Cmp(a.Average); c != 0 {
+			return c
+		}
+		return a.CategoryID - b.CategoryID
+	})
+	return suggestions
+}
+
+// monthCount is n with "month" or "months".
+func monthCount(n int) string {
+	if n == 1 {
+		return "1 month"
+	}
+	return fmt.Sprintf("%d months", n)
+}
+
+// budgetSuggestSpend converts monthly totals into currency. Currencies
+// without a rate are left out and returned, so the caller can say so.
+func budgetSuggestSpend(
+	ctx context.Context,
+	converter *budgetConverter,
+	totals []repository.MonthlyCategoryCurrencyTotal,
+	currency string,
+) ([]categoryMonthSpend, []string) {
+	spend := make([]categoryMonthSpend, 0, len(totals))
+	var unconverted []string
+	for i := range totals {
+		amount, ok := converter.convert(ctx, &totals[i].CategoryCurrencyTotal, currency)
+		if !ok {
+			if !slices.Contains(unconverted, totals[i].Currency) {
+				unconverted = append(unconverted, totals[i].Currency)
+			}
+			continue
+		}
+		spend = append(spend, categoryMonthSpend{
+			CategoryID: totals[i].CategoryID,
+			Month:      totals[i].Month,
+			Amount:     amount,
+		})
+	}
+	return spend, unconverted
+}
+
+// sendBudgetSuggestions sends /budget suggest: a budget per category from
+// its average monthly spending over the last full months, in the user's
+// default currency, each with a button that sets it.
+func (b *Bot) sendBudgetSuggestions(ctx context.Context, tg TelegramAPI, chatID, userID int64) {
+	send := func(text string, keyboard *models.InlineKeyboardMarkup) {
+		params := &bot.SendMessageParams{ChatID: chatID, Text: text, ParseMode: models.ParseModeHTML}
+		if keyboard != nil {
+			params.ReplyMarkup = keyboard
+		}
+		_, _ = tg.SendMessage(ctx, params)
+	}
+
+	periodStart, _, _ := b.budgetPeriod(ctx, userID)
+	windowStart := periodStart.AddDate(0, -budgetSuggestMonths, 0)
+	totals, err := b.expenseRepo.GetMonthlyCategoryCurrencyTotals(
+		ctx, userID, windowStart, periodStart, periodStart.Location().String())
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load spending for budget suggestions")
+		send(budgetSuggestFailedMsg, nil)
+		return
+	}
+	categories, err := b.visibleCategories(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for budget suggestions")
+		send(budgetSuggestFailedMsg, nil)
+		return
+	}
+	budgets, err := b.budgetRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to list budgets for budget suggestions")
+	}
+
+	currency := b.getUserDefaultCurrency(ctx, userID)
+	spend, unconverted := budgetSuggestSpend(ctx, b.newBudgetConverter(), totals, currency)
+	suggestions := suggestBudgets(spend, budgetSuggestMonthKeys(periodStart))
+
+	names := make(map[int]string, len(categories))
+	for i := range categories {
+		names[categories[i].ID] = categories[i].Name
+	}
+	current := make(map[int]*repository.Budget, len(budgets))
+	for i := range budgets {
+		current[budgets[i].CategoryID] = &budgets[i]
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "💡 <b>Budget suggestions</b>\nAverage monthly spending from %s to %s, "+
+		"skipping months without any, rounded to the nearest %s.\n",
+		windowStart.Format("Jan 2006"), periodStart.AddDate(0, -1, 0).Format("Jan 2006"),
+		botfmt.Money(budgetSuggestStep, currency))
+
+	var rows [][]models.InlineKeyboardButton
+	listed := 0
+	for i := range suggestions {
+		suggestion := &suggestions[i]
+		name, ok := names[suggestion.CategoryID]
+		if !ok {
+			continue
+		}
+		listed++
+		if suggestion.Amount.IsZero() {
+			fmt.Fprintf(&sb, "\n• <b>%s</b>: not enough data (%s)",
+				botfmt.EscapeHTML(name), monthCount(suggestion.Months))
+			continue
+		}
+
+		fmt.Fprintf(&sb, "\n• <b>%s</b>: %s a month over %s → <b>%s</b>",
+			botfmt.EscapeHTML(name), botfmt.Money(suggestion.Average, currency),
+			monthCount(suggestion.Months), botfmt.Money(suggestion.Amount, currency))
+		if budget, ok := current[suggestion.CategoryID]; ok {
+			fmt.Fprintf(&sb, " (now %s)", botfmt.Money(budget.Amount, budgetCurrency(budget, currency)))
+		}
+		label := fmt.Sprintf("Set %s · %s%s", name,
+			botfmt.CurrencySymbol(currency), botfmt.FormatAmount(suggestion.Amount, currency))
+		rows = append(rows, []models.InlineKeyboardButton{{
+			Text: truncateUTF8(label, budgetButtonMaxBytes),
+			CallbackData: fmt.Sprintf(budgetSuggestCallbackFmt,
+				userID, suggestion.CategoryID, suggestion.Amount.String(), currency),
+		}})
+	}
+
+	if listed == 0 {
+		send(fmt.Sprintf("No categorized spending from %s to %s to suggest budgets from.",
+			windowStart.Format("Jan 2006"), periodStart.AddDate(0, -1, 0).Format("Jan 2006")), nil)
+		return
+	}
+	if len(unconverted) > 0 {
+		fmt.Fprintf(&sb, "\n\n⚠️ Leaves out %s spending (no exchange rate)", strings.Join(unconverted, ", "))
+	}
+
+	var keyboard *models.InlineKeyboardMarkup
+	if len(rows) > 0 {
+		keyboard = &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+	}
+	send(sb.String(), keyboard)
+}
+
+// parseBudgetSuggestCallback reads budgetsug_<user>_<category>_<amount>_<currency>.
+func parseBudgetSuggestCallback(data string) (int64, int, decimal.Decimal, string, bool) {
+	parts := strings.Split(strings.TrimPrefix(data, budgetSuggestCallbackPrefix), "_")
+	if len(parts) != 4 {
+		return 0, 0, decimal.Zero, "", false
+	}
+	userID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, decimal.Zero, "", false
+	}
+	categoryID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, decimal.Zero, "", false
+	}
+	amount, err := decimal.NewFromString(parts[2])
+	if err != nil || !amount.IsPositive() {
+		return 0, 0, decimal.Zero, "", false
+	}
+	if appmodels.SupportedCurrencies[parts[3]] == "" {
+		return 0, 0, decimal.Zero, "", false
+	}
+	return userID, categoryID, amount, parts[3], true
+}
+
+// handleBudgetSuggestCallback handles the "Set" buttons of /budget suggest.
+func (b *Bot) handleBudgetSuggestCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleBudgetSuggestCallbackCore(ctx, tgBot, update)
+}
+
+// handleBudgetSuggestCallbackCore sets the suggested budget the same way
+// /budget set does. Only the user the suggestions were for can use them.
+func (b *Bot) handleBudgetSuggestCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	ownerID, categoryID, amount, currency, ok := parseBudgetSuggestCallback(query.Data)
+	if !ok {
+		answerCallback(ctx, tg, query)
+		return
+	}
+	if ownerID != query.From.ID {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            staleNotOwnerText,
+		})
+		return
+	}
+
+	categories, err := b.visibleCategories(ctx, ownerID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for budget suggestion")
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            budgetFailedMsg,
+		})
+		return
+	}
+	var category *appmodels.Category
+	for i := range categories {
+		if categories[i].ID == categoryID {
+			category = &categories[i]
+			break
+		}
+	}
+	if category == nil {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            "This category no longer exists.",
+		})
+		return
+	}
+	answerCallback(ctx, tg, query)
+
+	text := b.saveBudget(ctx, ownerID, category, &budgetSetArgs{
+		Name:     category.Name,
+		Amount:   amount,
+		Currency: currency,
+	})
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    query.Message.Message.Chat.ID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	})
+}
@@ -0,0 +1,198 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestBudgetSuggestMonthKeys(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, []string{"2025-09", "2025-10", "2025-11", "2025-12", "2026-01", "2026-02"},
+		budgetSuggestMonthKeys(start))
+}
+
+func TestRoundBudgetAmount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		average string
+		want    string
+	}{
+		{"404.99", "400"},
+		{"405", "410"},
+		{"123.45", "120"},
+		{"10", "10"},
+		{"4.99", "10"},
+		{"0.50", "10"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, roundBudgetAmount(mustParseDecimal(tt.average)).String(), tt.average)
+	}
+}
+
+func TestSuggestBudgets(t *testing.T) {
+	t.Parallel()
+
+	months := []string{"2025-09", "2025-10", "2025-11", "2025-12", "2026-01", "2026-02"}
+	spend := func(categoryID int, month, amount string) categoryMonthSpend {
+		return categoryMonthSpend{CategoryID: categoryID, Month: month, Amount: mustParseDecimal(amount)}
+	}
+
+	suggestions := suggestBudgets([]categoryMonthSpend{
+		// Food: two currency groups in one month add up, and the current
+		// month is ignored.
+		spend(1, "2025-10", "300"),
+		spend(1, "2025-10", "100"),
+		spend(1, "2025-12", "410"),
+		spend(1, "2026-03", "5000"),
+		// Transport: a month refunds cancelled out does not count.
+		spend(2, "2025-09", "45"),
+		spend(2, "2025-11", "50"),
+		spend(2, "2025-11", "-50"),
+		spend(2, "2026-02", "55"),
+		// Gifts: one month is not enough.
+		spend(3, "2026-01", "80"),
+		// Refunds only.
+		spend(4, "2026-01", "-20"),
+		// Older than the window.
+		spend(5, "2025-08", "100"),
+	}, months)
+
+	require.Equal(t, []budgetSuggestion{
+		{CategoryID: 1, Months: 2, Average: decimal.RequireFromString("405"), Amount: decimal.RequireFromString("410")},
+		{CategoryID: 2, Months: 2, Average: decimal.RequireFromString("50"), Amount: decimal.RequireFromString("50")},
+		{CategoryID: 3, Months: 1, Average: decimal.RequireFromString("80")},
+	}, normalizeSuggestions(suggestions))
+
+	require.Empty(t, suggestBudgets(nil, months))
+}
+
+// normalizeSuggestions drops the exponents decimal arithmetic leaves
+// behind, so suggestions compare with require.Equal.
+func normalizeSuggestions(suggestions []budgetSuggestion) []budgetSuggestion {
+	for i := range suggestions {
+		suggestions[i].Average = decimal.RequireFromString(suggestions[i].Average.String())
+		if suggestions[i].Amount.IsZero() {
+			suggestions[i].Amount = decimal.Decimal{}
+		} else {
+			suggestions[i].Amount = decimal.RequireFromString(suggestions[i].Amount.String())
+		}
+	}
+	return suggestions
+}
+
+func TestParseBudgetSuggestCallback(t *testing.T) {
+	t.Parallel()
+
+	userID, categoryID, amount, currency, ok := parseBudgetSuggestCallback(
+		fmt.Sprintf(budgetSuggestCallbackFmt, 453, 7, "410", currencyCodeSGD))
+	require.True(t, ok)
+	require.Equal(t, int64(453), userID)
+	require.Equal(t, 7, categoryID)
+	require.Equal(t, "410", amount.String())
+	require.Equal(t, currencyCodeSGD, currency)
+
+	for _, data := range []string{
+		"budgetsug_453_7_410",
+		"budgetsug_x_7_410_SGD",
+		"budgetsug_453_7_-10_SGD",
+		"budgetsug_453_7_0_SGD",
+		"budgetsug_453_7_410_XXX",
+	} {
+		_, _, _, _, ok := parseBudgetSuggestCallback(data)
+		require.False(t, ok, data)
+	}
+}
+
+func TestBudgetSuggest(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	b.nowFunc = func() time.Time { return now }
+	t.Cleanup(func() { b.nowFunc = time.Now })
+
+	userID := int64(453001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{
+		ID: userID, FirstName: "Planner", DefaultCurrency: currencyCodeSGD,
+	}))
+	food, err := b.categoryRepo.Create(ctx, "Suggest Food")
+	require.NoError(t, err)
+	gifts, err := b.categoryRepo.Create(ctx, "Suggest Gifts")
+	require.NoError(t, err)
+	b.invalidateCategoryCache()
+
+	add := func(categoryID int, amount string, at time.Time) {
+		t.Helper()
+		expense := &appmodels.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal(amount),
+			Currency:    currencyCodeSGD,
+			Description: "Spending",
+			CategoryID:  &categoryID,
+			Status:      appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		_, err := pool.Exec(ctx, testUpdateExpenseTimeSQL, at, expense.ID)
+		require.NoError(t, err)
+	}
+	add(food.ID, "400", time.Date(2025, 10, 10, 12, 0, 0, 0, time.UTC))
+	add(food.ID, "410", time.Date(2025, 12, 10, 12, 0, 0, 0, time.UTC))
+	add(food.ID, "900", time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC))
+	add(gifts.ID, "80", time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+
+	mockBot := mocks.NewMockBot()
+	b.handleBudgetCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/budget suggest"))
+	msg := mockBot.LastSentMessage()
+	require.Contains(t, msg.Text, "Sep 2025 to Feb 2026")
+	require.Contains(t, msg.Text, "<b>Suggest Food</b>: S$405.00 SGD a month over 2 months → <b>S$410.00 SGD</b>")
+	require.Contains(t, msg.Text, "<b>Suggest Gifts</b>: not enough data (1 month)")
+
+	keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+	require.Len(t, keyboard.InlineKeyboard, 1, "only categories with enough data get a button")
+	button := keyboard.InlineKeyboard[0][0]
+	require.Equal(t, "Set Suggest Food · S$410.00", button.Text)
+
+	t.Run("only the user can accept", func(t *testing.T) {
+		stranger := mocks.NewMockBot()
+		b.handleBudgetSuggestCallbackCore(ctx, stranger, mocks.CallbackQueryUpdate(1, 1, 1, button.CallbackData))
+		require.Equal(t, staleNotOwnerText, stranger.AnsweredCallbacks[0].Text)
+		_, err := b.budgetRepo.GetByCategory(ctx, userID, food.ID)
+		require.Error(t, err)
+	})
+
+	t.Run("accepting works like /budget set", func(t *testing.T) {
+		tapped := mocks.NewMockBot()
+		b.handleBudgetSuggestCallbackCore(ctx, tapped, mocks.CallbackQueryUpdate(userID, userID, 1, button.CallbackData))
+		fromButton := tapped.LastSentMessage().Text
+
+		budget, err := b.budgetRepo.GetByCategory(ctx, userID, food.ID)
+		require.NoError(t, err)
+		require.Equal(t, "410.00", budget.Amount.StringFixed(2))
+		require.Equal(t, currencyCodeSGD, budget.Currency)
+
+		typed := mocks.NewMockBot()
+		b.handleBudgetCore(ctx, typed, mocks.CommandUpdate(userID, userID, "/budget set Suggest Food 410"))
+		require.Equal(t, typed.LastSentMessage().Text, fromButton)
+
+		again := mocks.NewMockBot()
+		b.handleBudgetCore(ctx, again, mocks.CommandUpdate(userID, userID, "/budget suggest"))
+		require.Contains(t, again.LastSentMessage().Text, "(now S$410.00 SGD)")
+	})
+
+	t.Run("no spending", func(t *testing.T) {
+		empty := mocks.NewMockBot()
+		b.handleBudgetCore(ctx, empty, mocks.CommandUpdate(453002, 453002, "/budget suggest"))
+		require.Contains(t, empty.LastSentMessage().Text, "No categorized spending")
+	})
+}
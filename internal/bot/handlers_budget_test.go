@@ -0,0 +1,356 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/exchange"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const budgetReachedTest = "Treats budget reached"
+
+func TestParseBudgetSetArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		args     string
+		name     string
+		amount   string
+		currency string
+		enforce  bool
+		expectOK bool
+	}{
+		{args: "Food - Dining Out 200", name: "Food - Dining Out", amount: "200", expectOK: true},
+		{args: "Treats 20.5 HARD", name: "Treats", amount: "20.5", enforce: true, expectOK: true},
+		{args: "Travel 500 usd", name: "Travel", amount: "500", currency: "USD", expectOK: true},
+		{args: "Travel 500 EUR hard", name: "Travel", amount: "500", currency: "EUR", enforce: true, expectOK: true},
+		{args: "Treats", expectOK: false},
+		{args: "Treats -5", expectOK: false},
+		{args: "Treats abc hard", expectOK: false},
+		{args: "Treats USD", expectOK: false},
+		{args: "", expectOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			t.Parallel()
+			parsed, ok := parseBudgetSetArgs(tt.args)
+			require.Equal(t, tt.expectOK, ok)
+			if !tt.expectOK {
+				return
+			}
+			require.Equal(t, tt.name, parsed.Name)
+			require.True(t, mustParseDecimal(tt.amount).Equal(parsed.Amount))
+			require.Equal(t, tt.currency, parsed.Currency)
+			require.Equal(t, tt.enforce, parsed.Enforce)
+		})
+	}
+}
+
+func TestHardBudget(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(384001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Kid"}))
+	category, err := b.categoryRepo.Create(ctx, "Treats")
+	require.NoError(t, err)
+	b.invalidateCategoryCache()
+
+	command := func(text string) *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		update := mocks.CommandUpdate(userID, userID, text)
+		if strings.HasPrefix(text, "/budget") {
+			b.handleBudgetCore(ctx, mockBot, update)
+		} else {
+			b.handleAddCore(ctx, mockBot, update)
+		}
+		return mockBot
+	}
+	confirmed := func() []appmodels.Expense {
+		expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 50)
+		require.NoError(t, err)
+		return expenses
+	}
+	tap := func(data string) *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		b.handleBudgetFallbackCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 1, data))
+		return mockBot
+	}
+
+	require.Contains(t, command("/budget set Treats 20 hard").LastSentMessage().Text, "set to")
+	require.Contains(t, command("/add 16 Candy [Treats]").LastSentMessage().Text, "📊")
+	require.Len(t, confirmed(), 1)
+
+	t.Run("blocks expenses over the cap", func(t *testing.T) {
+		msg := command("/add 10 Toy [Treats]").LastSentMessage()
+		require.Contains(t, msg.Text, budgetReachedTest)
+		require.Contains(t, msg.Text, "4.00 SGD left")
+		require.Len(t, confirmed(), 1)
+
+		keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+		require.Equal(t, budgetFallbackButtonText, keyboard.InlineKeyboard[0][0].Text)
+
+		t.Run("fallback saves as uncategorized once", func(t *testing.T) {
+			data := keyboard.InlineKeyboard[0][0].CallbackData
+
+			require.Nil(t, tap(fmt.Sprintf(budgetFallbackNewFmt, "other")).LastSentMessage())
+			stranger := mocks.NewMockBot()
+			b.handleBudgetFallbackCallbackCore(ctx, stranger, mocks.CallbackQueryUpdate(1, 1, 1, data))
+			require.Equal(t, staleNotOwnerText, stranger.AnsweredCallbacks[0].Text)
+
+			first := tap(data)
+			require.Contains(t, first.LastSentMessage().Text, "Toy")
+			expenses := confirmed()
+			require.Len(t, expenses, 2)
+			require.Equal(t, "Toy", expenses[0].Description)
+			require.Nil(t, expenses[0].CategoryID)
+
+			second := tap(data)
+			require.Equal(t, pendingExpenseExpiredMsg, second.AnsweredCallbacks[0].Text)
+			require.Len(t, confirmed(), 2)
+		})
+	})
+
+	t.Run("blocks receipt confirmation", func(t *testing.T) {
+		draft := &appmodels.Expense{
+			UserID:     userID,
+			Amount:     mustParseDecimal("8.00"),
+			Currency:   currencyCodeSGD,
+			CategoryID: &category.ID,
+			Status:     appmodels.ExpenseStatusDraft,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, draft))
+
+		mockBot := mocks.NewMockBot()
+		b.handleReceiptCallbackCore(ctx, mockBot,
+			mocks.CallbackQueryUpdate(userID, userID, 1, fmt.Sprintf("receipt_confirm_%d", draft.ID)))
+		edited := mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, budgetReachedTest)
+		stored, err := b.expenseRepo.GetByID(ctx, draft.ID)
+		require.NoError(t, err)
+		require.Equal(t, appmodels.ExpenseStatusDraft, stored.Status)
+
+		keyboard := requireInlineKeyboard(t, edited.ReplyMarkup)
+		tap(keyboard.InlineKeyboard[0][0].CallbackData)
+		stored, err = b.expenseRepo.GetByID(ctx, draft.ID)
+		require.NoError(t, err)
+		require.Equal(t, appmodels.ExpenseStatusConfirmed, stored.Status)
+		require.Nil(t, stored.CategoryID)
+	})
+
+	t.Run("override lifts the cap once per month", func(t *testing.T) {
+		require.Contains(t, command("/budget override Treats").LastSentMessage().Text, "lifted")
+		require.Contains(t, command("/budget override Treats").LastSentMessage().Text, "already overridden")
+
+		msg := command("/add 10 Toy [Treats]").LastSentMessage()
+		require.NotContains(t, msg.Text, budgetReachedTest)
+		require.Contains(t, msg.Text, "Over the Treats budget")
+		require.Contains(t, command("/budget").LastSentMessage().Text, "Treats")
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		require.Contains(t, command("/budget remove Treats").LastSentMessage().Text, "removed")
+		require.Contains(t, command("/budget remove Treats").LastSentMessage().Text, "has no budget")
+	})
+}
+
+func TestBudgetHint(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "(left: S$82)", budgetHint(mustParseDecimal("200"), mustParseDecimal("117.50"), "SGD"))
+	require.Equal(t, "(left: $0)", budgetHint(mustParseDecimal("50"), mustParseDecimal("50"), "USD"))
+	require.Equal(t, "(left: $50)", budgetHint(mustParseDecimal("50"), mustParseDecimal("0"), "USD"))
+	require.Equal(t, "(over by $15)", budgetHint(mustParseDecimal("100"), mustParseDecimal("114.20"), "USD"))
+	require.Equal(t, "(over by $1)", budgetHint(mustParseDecimal("100"), mustParseDecimal("100.01"), "USD"))
+}
+
+func TestCategoryButtonText(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "Food - Dining Out", categoryButtonText("Food - Dining Out", ""))
+	require.Equal(t, "Food - Dining Out (left: $82)", categoryButtonText("Food - Dining Out", "(left: $82)"))
+
+	long := strings.Repeat("Household ", 10)
+	require.Equal(t, long, categoryButtonText(long, ""), "names without a budget are unchanged")
+
+	text := categoryButtonText(long, "(over by S$1500)")
+	require.LessOrEqual(t, len(text), budgetButtonMaxBytes)
+	require.True(t, strings.HasSuffix(text, "… (over by S$1500)"))
+
+	text = categoryButtonText(strings.Repeat("食", 30), "(left: ¥100)")
+	require.LessOrEqual(t, len(text), budgetButtonMaxBytes)
+	require.True(t, utf8.ValidString(text))
+}
+
+func TestShowCategorySelectionCore_BudgetHints(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(409001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Hinter"}))
+	require.NoError(t, b.userRepo.UpdateDefaultCurrency(ctx, userID, currencyCodeSGD))
+	groceries, err := b.categoryRepo.Create(ctx, "Hint Groceries")
+	require.NoError(t, err)
+	fun, err := b.categoryRepo.Create(ctx, "Hint Fun")
+	require.NoError(t, err)
+	plain, err := b.categoryRepo.Create(ctx, "Hint Plain")
+	require.NoError(t, err)
+	b.invalidateCategoryCache()
+
+	require.NoError(t, b.budgetRepo.Set(ctx, userID, groceries.ID, mustParseDecimal("200"), currencyCodeSGD, false))
+	require.NoError(t, b.budgetRepo.Set(ctx, userID, fun.ID, mustParseDecimal("50"), currencyCodeSGD, false))
+	for _, expense := range []appmodels.Expense{
+		{Amount: mustParseDecimal("117.50"), CategoryID: &groceries.ID},
+		{Amount: mustParseDecimal("65"), CategoryID: &fun.ID},
+		{Amount: mustParseDecimal("10"), CategoryID: &plain.ID},
+	} {
+		expense.UserID = userID
+		expense.Currency = currencyCodeSGD
+		expense.Status = appmodels.ExpenseStatusConfirmed
+		require.NoError(t, b.expenseRepo.Create(ctx, &expense))
+	}
+
+	mockBot := mocks.NewMockBot()
+	b.showCategorySelectionCore(ctx, mockBot, userID, 1, &appmodels.Expense{ID: 1, UserID: userID}, categoryModeList)
+
+	labels := map[int]string{}
+	for _, row := range requireInlineKeyboard(t, mockBot.LastEditedMessage().ReplyMarkup).InlineKeyboard {
+		for _, button := range row {
+			for _, id := range []int{groceries.ID, fun.ID, plain.ID} {
+				if button.CallbackData == setCategoryCallbackData(1, id, categoryModeList) {
+					labels[id] = button.Text
+				}
+			}
+		}
+	}
+	require.Equal(t, "Hint Groceries (left: S$82)", labels[groceries.ID])
+	require.Equal(t, "Hint Fun (over by S$15)", labels[fun.ID])
+	require.Equal(t, "Hint Plain", labels[plain.ID])
+}
+
+// rateTable converts with fixed rates keyed "FROM/TO" and fails for
+// pairs it does not know.
+type rateTable map[string]string
+
+func (r rateTable) Convert(
+	_ context.Context,
+	amount decimal.Decimal,
+	fromCurrency, toCurrency string,
+) (exchange.ConversionResult, error) {
+	rate, ok := r[fromCurrency+"/"+toCurrency]
+	if !ok {
+		return exchange.ConversionResult{}, errors.New("rate unavailable")
+	}
+	return exchange.ConversionResult{Amount: amount.Mul(mustParseDecimal(rate)), Rate: mustParseDecimal(rate)}, nil
+}
+
+func TestBudgetCurrency(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.exchangeService = rateTable{"USD/SGD": "1.35", "SGD/USD": "0.74", "USD/JPY": "149.637"}
+
+	userID := int64(411001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Traveller"}))
+	require.NoError(t, b.userRepo.UpdateDefaultCurrency(ctx, userID, currencyCodeSGD))
+	category, err := b.categoryRepo.Create(ctx, "Trip")
+	require.NoError(t, err)
+	b.invalidateCategoryCache()
+
+	command := func(text string) string {
+		mockBot := mocks.NewMockBot()
+		update := mocks.CommandUpdate(userID, userID, text)
+		if strings.HasPrefix(text, "/budget") {
+			b.handleBudgetCore(ctx, mockBot, update)
+		} else {
+			b.handleSetCurrencyCore(ctx, mockBot, update)
+		}
+		return mockBot.LastSentMessage().Text
+	}
+	spend := func(amount, currency string) *appmodels.Expense {
+		expense := &appmodels.Expense{
+			UserID:     userID,
+			Amount:     mustParseDecimal(amount),
+			Currency:   currency,
+			CategoryID: &category.ID,
+			Status:     appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		return expense
+	}
+
+	require.Contains(t, command("/budget set Trip 100 hard"), "set to S$100.00 SGD")
+
+	t.Run("mixed currencies consume one budget", func(t *testing.T) {
+		spend("30", currencyCodeSGD)
+		spend("20", "USD") // No stored conversion: 27 SGD at today's rate.
+		stored := spend("1000", "JPY")
+		_, err := b.expenseRepo.SetConversion(ctx, stored.ID, mustParseDecimal("0.009"), mustParseDecimal("9"),
+			currencyCodeSGD)
+		require.NoError(t, err)
+
+		require.Contains(t, command("/budget"), "Trip: S$66.00 SGD of S$100.00 SGD")
+
+		status := b.expenses().BlockingBudget(ctx, &appmodels.Expense{
+			UserID: userID, Amount: mustParseDecimal("30"), Currency: "USD", CategoryID: &category.ID,
+		})
+		require.NotNil(t, status, "40.50 SGD would go over what is left")
+		require.Contains(t, budgetBlockedText(status, &appmodels.Expense{Amount: mustParseDecimal("30"), Currency: "USD"}),
+			"S$34.00 SGD left of S$100.00 SGD")
+		require.Nil(t, b.expenses().BlockingBudget(ctx, &appmodels.Expense{
+			UserID: userID, Amount: mustParseDecimal("25"), Currency: "USD", CategoryID: &category.ID,
+		}))
+	})
+
+	t.Run("spending without a rate is left out", func(t *testing.T) {
+		spend("500", "EUR")
+
+		text := command("/budget")
+		require.Contains(t, text, "Trip: S$66.00 SGD of S$100.00 SGD")
+		require.Contains(t, text, "Leaves out EUR spending")
+		require.Nil(t, b.expenses().BlockingBudget(ctx, &appmodels.Expense{
+			UserID: userID, Amount: mustParseDecimal("100"), Currency: "EUR", CategoryID: &category.ID,
+		}), "expenses that cannot be converted are let through")
+	})
+
+	t.Run("changing the default currency keeps the budget currency", func(t *testing.T) {
+		text := command("/setcurrency USD")
+		require.Contains(t, text, "Trip: S$100.00 SGD")
+		require.Contains(t, text, "/budget convert")
+
+		budget, err := b.budgetRepo.GetByCategory(ctx, userID, category.ID)
+		require.NoError(t, err)
+		require.Equal(t, currencyCodeSGD, budget.Currency)
+		require.True(t, mustParseDecimal("100").Equal(budget.Amount))
+
+		text = command("/budget")
+		require.Contains(t, text, "Trip: S$66.00 SGD of S$100.00 SGD (≈ $74.00 USD) 🔒")
+		require.Contains(t, text, "not in your default currency (USD)")
+
+		require.Contains(t, command("/budget convert"), "Trip: S$100.00 SGD → $74.00 USD")
+		budget, err = b.budgetRepo.GetByCategory(ctx, userID, category.ID)
+		require.NoError(t, err)
+		require.Equal(t, "USD", budget.Currency)
+		require.True(t, mustParseDecimal("74").Equal(budget.Amount))
+		require.True(t, budget.Enforce)
+		require.Equal(t, "All your budgets are already in USD.", command("/budget convert"))
+	})
+
+	t.Run("converted amounts use the currency's minor units", func(t *testing.T) {
+		command("/setcurrency JPY")
+		require.Contains(t, command("/budget convert"), "Trip: $74.00 USD → ¥11073 JPY")
+		budget, err := b.budgetRepo.GetByCategory(ctx, userID, category.ID)
+		require.NoError(t, err)
+		require.True(t, mustParseDecimal("11073").Equal(budget.Amount), budget.Amount.String())
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/go-telegram/bot/models"
 	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
 
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
 	"gitlab.com/yelinaung/expense-bot/internal/logger"
 )
 
@@ -60,29 +61,27 @@ func (b *Bot) handleEditCallbackCore(ctx context.Context, tg TelegramAPI, update
 		return
 	}
 
-	userID := update.CallbackQuery.From.ID
 	chatID := update.CallbackQuery.Message.Message.Chat.ID
 	messageID := update.CallbackQuery.Message.Message.ID
 
-	_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
-		CallbackQueryID: update.CallbackQuery.ID,
-	})
-
 	parts := strings.Split(data, "_")
 	if len(parts) < 3 {
+		answerCallback(ctx, tg, update.CallbackQuery)
 		return
 	}
 
 	action := parts[1]
 	expenseID, err := strconv.Atoi(parts[2])
 	if err != nil {
+		answerCallback(ctx, tg, update.CallbackQuery)
 		return
 	}
 
-	expense, err := b.expenseRepo.GetByID(ctx, expenseID)
-	if err != nil || expense.UserID != userID {
+	expense, ok := b.loadCallbackExpense(ctx, tg, update.CallbackQuery, expenseID, false)
+	if !ok {
 		return
 	}
+	answerCallback(ctx, tg, update.CallbackQuery)
 
 	switch action {
 	case editTypeAmountCB:
@@ -94,8 +93,15 @@ func (b *Bot) handleEditCallbackCore(ctx context.Context, tg TelegramAPI, update
 	case editTypeDescriptionCB:
 		b.promptEditDescriptionCore(ctx, tg, chatID, messageID, expense)
 
+	case editTypeReimbursableCB:
+		b.toggleReimbursableCore(ctx, tg, chatID, messageID, expense)
+
 	case logFieldCategoryCB:
-		b.showCategorySelectionCore(ctx, tg, chatID, messageID, expense)
+		mode := ""
+		if len(parts) > 3 {
+			mode = parts[3]
+		}
+		b.showCategorySelectionCore(ctx, tg, chatID, messageID, expense, mode)
 	}
 }
 
@@ -118,10 +124,10 @@ func (b *Bot) promptEditAmountCore(
 
 	text := fmt.Sprintf(`💰 <b>Edit Amount</b>
 
-Current amount: $%s SGD
+Current amount: %s
 
 Please type the new amount (e.g., <code>25.50</code>):`,
-		expense.Amount.StringFixed(2))
+		botfmt.Money(expense.Amount, expense.Currency))
 
 	keyboard := &models.InlineKeyboardMarkup{
 		InlineKeyboard: [][]models.InlineKeyboardButton{
@@ -161,7 +167,7 @@ func (b *Bot) promptEditDescriptionCore(
 Current description: %s
 
 Please type the new description:`,
-		escapeHTML(expense.Description))
+		botfmt.EscapeHTML(expense.Description))
 
 	keyboard := &models.InlineKeyboardMarkup{
 		InlineKeyboard: [][]models.InlineKeyboardButton{
@@ -241,6 +247,14 @@ func (b *Bot) handlePendingEditCore(ctx context.Context, tg TelegramAPI, update
 	if !exists {
 		return false
 	}
+	if !pending.ExpiresAt.IsZero() && b.now().After(pending.ExpiresAt) {
+		b.pendingEditsMu.Lock()
+		if b.pendingEdits[chatID] == pending {
+			delete(b.pendingEdits, chatID)
+		}
+		b.pendingEditsMu.Unlock()
+		return false
+	}
 
 	switch pending.EditType {
 	case editTypeAmountCB:
@@ -251,6 +265,16 @@ func (b *Bot) handlePendingEditCore(ctx context.Context, tg TelegramAPI, update
 		return b.processMerchantEditCore(ctx, tg, chatID, userID, pending, update.Message.Text)
 	case logFieldCategoryCB:
 		return b.processCategoryCreateCore(ctx, tg, chatID, userID, pending, update.Message.Text)
+	case editTypeTipCB:
+		return b.processTipEditCore(ctx, tg, chatID, userID, pending, update.Message.Text)
+	case editTypeCurrencyCB:
+		return b.processCurrencyEditCore(ctx, tg, chatID, userID, pending, update.Message.Text)
+	case editTypeTagCB:
+		return b.processReceiptTagCore(ctx, tg, chatID, userID, pending, update.Message.Text)
+	case editTypeDescribe:
+		return b.processDescribeCore(ctx, tg, chatID, userID, pending, update.Message.Text)
+	case editTypeManualReceipt:
+		return b.processManualReceiptCore(ctx, tg, chatID, userID, pending, update.Message.Text)
 	}
 
 	return false
@@ -279,7 +303,7 @@ func (b *Bot) processAmountEditCore(
 	if err != nil {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:    chatID,
-			Text:      "❌ Invalid amount. Please enter a valid number (e.g., 25.50).",
+			Text:      "❌ Invalid amount. Please enter a valid number (e.g., 25.50 or 84.60/3).",
 			ParseMode: models.ParseModeHTML,
 		})
 		return true
@@ -302,8 +326,19 @@ func (b *Bot) processAmountEditCore(
 	}
 
 	// Update the expense amount.
+	b.loadExpenseCategory(ctx, expense)
+	b.loadExpenseTags(ctx, expense)
+	before := *expense
+	// A refund stays one, as with /edit.
+	if before.IsRefund() {
+		amount = amount.Neg()
+	}
 	expense.Amount = amount
-	if err := b.expenseRepo.Update(ctx, expense); err != nil {
+	if !botfmt.ExpenseChanged(&before, expense) {
+		b.sendDraftUnchanged(ctx, tg, chatID, pending.MessageID, expense)
+		return true
+	}
+	if err := b.expenses().EditFields(ctx, expense); err != nil {
 		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to update amount")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
@@ -316,30 +351,12 @@ func (b *Bot) processAmountEditCore(
 		Int(logFieldExpenseIDCB, expense.ID).
 		Str("new_amount", amount.String()).
 		Msg("Amount updated via pending edit")
+	b.auditExpenseUpdate(ctx, tg, userID, before, expense)
 
 	// Show updated confirmation message.
-	categoryText := categoryUncategorized
-	if expense.Category != nil {
-		categoryText = escapeHTML(expense.Category.Name)
-	} else if expense.CategoryID != nil {
-		cat, err := b.categoryRepo.GetByID(ctx, *expense.CategoryID)
-		if err == nil {
-			categoryText = escapeHTML(cat.Name)
-		}
-	}
+	keyboard := buildReceiptConfirmationKeyboard(expense.ID, expense.Currency)
 
-	keyboard := buildReceiptConfirmationKeyboard(expense.ID)
-
-	text := fmt.Sprintf(`📸 <b>Amount Updated!</b>
-
-💰 Amount: $%s SGD
-🏪 Merchant: %s
-📁 Category: %s
-
-Amount updated. Confirm to save.`,
-		expense.Amount.StringFixed(2),
-		escapeHTML(expense.Merchant),
-		categoryText)
+	text := botfmt.ReceiptDraftEditCard(&before, expense, botfmt.DraftAmountUpdated)
 
 	// Edit the original message.
 	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
@@ -366,7 +383,7 @@ func (b *Bot) processDescriptionEditCore(
 	delete(b.pendingEdits, chatID)
 	b.pendingEditsMu.Unlock()
 
-	description := strings.TrimSpace(input)
+	description, truncated := b.normalizeDescription(input)
 	if description == "" {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
@@ -390,8 +407,19 @@ func (b *Bot) processDescriptionEditCore(
 		return true
 	}
 
+	b.loadExpenseCategory(ctx, expense)
+	before := *expense
 	expense.Description = description
-	if err := b.expenseRepo.Update(ctx, expense); err != nil {
+	if !botfmt.ExpenseChanged(&before, expense) {
+		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      chatID,
+			MessageID:   pending.MessageID,
+			Text:        botfmt.NothingChangedText(expense),
+			ReplyMarkup: buildExpenseActionKeyboard(expense.ID),
+		})
+		return true
+	}
+	if err := b.expenses().EditFields(ctx, expense); err != nil {
 		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to update description")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
@@ -404,47 +432,19 @@ func (b *Bot) processDescriptionEditCore(
 		Int(logFieldExpenseIDCB, expense.ID).
 		Str("new_description", logger.SanitizeDescription(description)).
 		Msg("Description updated via pending edit")
+	b.auditExpenseUpdate(ctx, tg, userID, before, expense)
 
-	categoryText := categoryUncategorized
-	if expense.Category != nil {
-		categoryText = escapeHTML(expense.Category.Name)
-	} else if expense.CategoryID != nil {
-		cat, err := b.categoryRepo.GetByID(ctx, *expense.CategoryID)
-		if err == nil {
-			categoryText = escapeHTML(cat.Name)
-		}
-	}
-
-	keyboard := &models.InlineKeyboardMarkup{
-		InlineKeyboard: [][]models.InlineKeyboardButton{
-			{
-				{Text: editExpenseButtonTextCB, CallbackData: fmt.Sprintf(editExpenseCallbackFmtCB, expense.ID)},
-				{Text: deleteExpenseButtonTextCB, CallbackData: fmt.Sprintf(deleteExpenseCallbackFmtCB, expense.ID)},
-			},
-		},
+	text := botfmt.DescriptionUpdatedCard(&before, expense)
+	if truncated {
+		text += "\n\n" + b.descriptionTruncatedNote()
 	}
 
-	currencySymbol := getCurrencyOrCodeSymbol(expense.Currency)
-
-	text := fmt.Sprintf(`✅ <b>Description Updated!</b>
-
-💰 Amount: %s%s %s
-📝 Description: %s
-📁 Category: %s
-🆔 #%d`,
-		currencySymbol,
-		expense.Amount.StringFixed(2),
-		expense.Currency,
-		escapeHTML(expense.Description),
-		categoryText,
-		expense.UserExpenseNumber)
-
 	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      chatID,
 		MessageID:   pending.MessageID,
 		Text:        text,
 		ParseMode:   models.ParseModeHTML,
-		ReplyMarkup: keyboard,
+		ReplyMarkup: buildExpenseActionKeyboard(expense.ID),
 	})
 
 	return true
@@ -463,7 +463,7 @@ func (b *Bot) processMerchantEditCore(
 	delete(b.pendingEdits, chatID)
 	b.pendingEditsMu.Unlock()
 
-	merchant := strings.TrimSpace(input)
+	merchant, truncated := b.normalizeDescription(input)
 	if merchant == "" {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
@@ -487,9 +487,16 @@ func (b *Bot) processMerchantEditCore(
 		return true
 	}
 
+	b.loadExpenseCategory(ctx, expense)
+	b.loadExpenseTags(ctx, expense)
+	before := *expense
 	expense.Merchant = merchant
 	expense.Description = merchant
-	if err := b.expenseRepo.Update(ctx, expense); err != nil {
+	if !botfmt.ExpenseChanged(&before, expense) {
+		b.sendDraftUnchanged(ctx, tg, chatID, pending.MessageID, expense)
+		return true
+	}
+	if err := b.expenses().EditFields(ctx, expense); err != nil {
 		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to update merchant")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
@@ -502,29 +509,14 @@ func (b *Bot) processMerchantEditCore(
 		Int(logFieldExpenseIDCB, expense.ID).
 		Str("new_merchant", merchant).
 		Msg("Merchant updated via pending edit")
+	b.auditExpenseUpdate(ctx, tg, userID, before, expense)
 
-	categoryText := categoryUncategorized
-	if expense.Category != nil {
-		categoryText = escapeHTML(expense.Category.Name)
-	} else if expense.CategoryID != nil {
-		cat, err := b.categoryRepo.GetByID(ctx, *expense.CategoryID)
-		if err == nil {
-			categoryText = escapeHTML(cat.Name)
-		}
-	}
-
-	keyboard := buildReceiptConfirmationKeyboard(expense.ID)
-
-	text := fmt.Sprintf(`📸 <b>Merchant Updated!</b>
+	keyboard := buildReceiptConfirmationKeyboard(expense.ID, expense.Currency)
 
-💰 Amount: $%s SGD
-🏪 Merchant: %s
-📁 Category: %s
-
-Merchant updated. Confirm to save.`,
-		expense.Amount.StringFixed(2),
-		escapeHTML(expense.Merchant),
-		categoryText)
+	text := botfmt.ReceiptDraftEditCard(&before, expense, botfmt.DraftMerchantUpdated)
+	if truncated {
+		text += "\n\n" + b.descriptionTruncatedNote()
+	}
 
 	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      chatID,
@@ -537,6 +529,24 @@ Merchant updated. Confirm to save.`,
 	return true
 }
 
+// sendDraftUnchanged re-renders a draft after an inline edit that left it as
+// it was, so its confirmation buttons come back.
+func (b *Bot) sendDraftUnchanged(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	messageID int,
+	expense *appmodels.Expense,
+) {
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   messageID,
+		Text:        botfmt.ReceiptDraftCard(expense, botfmt.DraftUnchanged) + "\n\n" + botfmt.NothingChangedText(expense),
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: buildReceiptConfirmationKeyboard(expense.ID, expense.Currency),
+	})
+}
+
 // handleCancelEditCallback handles cancel edit button presses.
 func (b *Bot) handleCancelEditCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
 	b.handleCancelEditCallbackCore(ctx, tgBot, update)
@@ -581,28 +591,35 @@ func (b *Bot) handleCancelEditCallbackCore(ctx context.Context, tg TelegramAPI,
 	b.handleEditReceiptCore(ctx, tg, chatID, messageID, expense)
 }
 
-// showCategorySelectionCore shows category selection buttons.
+// showCategorySelectionCore shows category selection buttons. The mode is
+// carried in the callback data so the follow-up handlers know which view to
+// restore: an empty mode returns to the receipt view, categoryModeList to
+// the /list edit view, categoryModeUncategorized or categoryModeUncertain
+// to /uncategorized and categoryModeAIReview to the AI review digest.
 func (b *Bot) showCategorySelectionCore(
 	ctx context.Context,
 	tg TelegramAPI,
 	chatID int64,
 	messageID int,
 	expense *appmodels.Expense,
+	mode string,
 ) {
-	categories, err := b.getCategoriesWithCache(ctx)
+	categories, err := b.categoriesForUser(ctx, expense.UserID)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to fetch categories")
 		return
 	}
 
+	hints := b.categoryBudgetHints(ctx, expense.UserID)
+
 	var rows [][]models.InlineKeyboardButton
 	currentRow := make([]models.InlineKeyboardButton, 0, 2)
 
 	for i := range categories {
 		cat := categories[i]
 		btn := models.InlineKeyboardButton{
-			Text:         cat.Name,
-			CallbackData: fmt.Sprintf("set_category_%d_%d", expense.ID, cat.ID),
+			Text:         categoryButtonText(cat.Name, hints[cat.ID]),
+			CallbackData: setCategoryCallbackData(expense.ID, cat.ID, mode),
 		}
 		currentRow = append(currentRow, btn)
 		if len(currentRow) == 2 {
@@ -614,10 +631,29 @@ func (b *Bot) showCategorySelectionCore(
 		rows = append(rows, currentRow)
 	}
 
-	rows = append(rows, []models.InlineKeyboardButton{
-		{Text: "➕ Create New", CallbackData: fmt.Sprintf("create_category_%d", expense.ID)},
-		{Text: backButtonTextCB, CallbackData: fmt.Sprintf("receipt_edit_%d", expense.ID)},
-	})
+	switch mode {
+	case categoryModeList:
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: backButtonTextCB, CallbackData: listEditCallback},
+		})
+	case categoryModeUncategorized:
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: backButtonTextCB, CallbackData: fmt.Sprintf(uncategorizedPageCallbackFmt, 0)},
+		})
+	case categoryModeUncertain:
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: backButtonTextCB, CallbackData: fmt.Sprintf(uncertainPageCallbackFmt, 0)},
+		})
+	case categoryModeAIReview:
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: backButtonTextCB, CallbackData: aiReviewShowData},
+		})
+	default:
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: "➕ Create New", CallbackData: fmt.Sprintf("create_category_%d", expense.ID)},
+			{Text: backButtonTextCB, CallbackData: fmt.Sprintf("receipt_edit_%d", expense.ID)},
+		})
+	}
 
 	keyboard := &models.InlineKeyboardMarkup{
 		InlineKeyboard: rows,
@@ -628,7 +664,7 @@ func (b *Bot) showCategorySelectionCore(
 Current: %s
 
 		Choose a new category:`,
-		escapeHTML(getCategoryName(expense)))
+		botfmt.EscapeHTML(getCategoryName(expense)))
 
 	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      chatID,
@@ -639,6 +675,16 @@ Current: %s
 	})
 }
 
+// setCategoryCallbackData builds the set_category callback data, appending
+// the view mode when one is set.
+func setCategoryCallbackData(expenseID, categoryID int, mode string) string {
+	data := fmt.Sprintf("set_category_%d_%d", expenseID, categoryID)
+	if mode != "" {
+		data += "_" + mode
+	}
+	return data
+}
+
 // getCategoryName returns the category name for an expense.
 func getCategoryName(expense *appmodels.Expense) string {
 	if expense.Category != nil {
@@ -663,39 +709,44 @@ func (b *Bot) handleSetCategoryCallbackCore(ctx context.Context, tg TelegramAPI,
 	chatID := update.CallbackQuery.Message.Message.Chat.ID
 	messageID := update.CallbackQuery.Message.Message.ID
 
-	_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
-		CallbackQueryID: update.CallbackQuery.ID,
-	})
-
 	parts := strings.Split(data, "_")
 	if len(parts) < 4 {
+		answerCallback(ctx, tg, update.CallbackQuery)
 		return
 	}
 
 	expenseID, err := strconv.Atoi(parts[2])
 	if err != nil {
+		answerCallback(ctx, tg, update.CallbackQuery)
 		return
 	}
 
 	categoryID, err := strconv.Atoi(parts[3])
 	if err != nil {
+		answerCallback(ctx, tg, update.CallbackQuery)
 		return
 	}
 
-	expense, err := b.expenseRepo.GetByID(ctx, expenseID)
-	if err != nil || expense.UserID != userID {
+	expense, ok := b.loadCallbackExpense(ctx, tg, update.CallbackQuery, expenseID, false)
+	if !ok {
 		return
 	}
+	answerCallback(ctx, tg, update.CallbackQuery)
 
 	category, err := b.categoryRepo.GetByID(ctx, categoryID)
 	if err != nil {
 		logger.Log.Error().Err(err).Int(logFieldCategoryIDCB, categoryID).Msg("Category not found")
 		return
 	}
+	if category.OwnerID != 0 && category.OwnerID != userID {
+		logger.Log.Warn().Int(logFieldCategoryIDCB, categoryID).Msg("Category belongs to another user")
+		return
+	}
 
+	before := *expense
 	expense.CategoryID = &categoryID
 	expense.Category = category
-	if err := b.expenseRepo.Update(ctx, expense); err != nil {
+	if err := b.expenses().EditFields(ctx, expense); err != nil {
 		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to update category")
 		return
 	}
@@ -704,19 +755,40 @@ func (b *Bot) handleSetCategoryCallbackCore(ctx context.Context, tg TelegramAPI,
 		Int(logFieldExpenseIDCB, expense.ID).
 		Str(logFieldCategoryCB, category.Name).
 		Msg("Category updated via callback")
+	b.auditExpenseUpdate(ctx, tg, userID, before, expense)
 
-	keyboard := buildReceiptConfirmationKeyboard(expense.ID)
+	mode := ""
+	if len(parts) > 4 {
+		mode = parts[4]
+	}
+	switch mode {
+	case categoryModeList:
+		b.refreshListCore(ctx, tg, chatID, messageID, userID, true)
+		return
+	case categoryModeUncategorized, categoryModeUncertain:
+		b.refreshUncategorizedCore(ctx, tg, chatID, messageID, userID, 0, mode == categoryModeUncertain)
+		return
+	case categoryModeAIReview:
+		// Picking the category Gemini chose keeps the flag in Update, but
+		// it still counts as reviewed.
+		if _, err := b.expenses().MarkAIReviewed(ctx, userID, expense.ID); err != nil {
+			logger.Log.Warn().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to clear AI-categorized flag")
+		}
+		b.refreshAIReviewCore(ctx, tg, chatID, messageID, userID)
+		return
+	}
 
-	text := fmt.Sprintf(`📸 <b>Receipt Updated!</b>
+	// Confirmed expenses reach the category keyboard through their inline
+	// Edit button, so restore the expense card rather than a receipt draft.
+	if expense.Status == appmodels.ExpenseStatusConfirmed {
+		b.editToConfirmation(ctx, tg, chatID, messageID, expense)
+		return
+	}
 
-💰 Amount: $%s SGD
-🏪 Merchant: %s
-📁 Category: %s
+	b.loadExpenseTags(ctx, expense)
+	keyboard := buildReceiptConfirmationKeyboard(expense.ID, expense.Currency)
 
-Category updated. Confirm to save.`,
-		expense.Amount.StringFixed(2),
-		escapeHTML(expense.Merchant),
-		escapeHTML(category.Name))
+	text := botfmt.ReceiptDraftCard(expense, botfmt.DraftCategoryUpdated)
 
 	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      chatID,
@@ -772,7 +844,7 @@ func (b *Bot) processCategoryCreateCore(
 		return true
 	}
 
-	category, err := b.categoryRepo.Create(ctx, categoryName)
+	category, err := b.createUserCategory(ctx, userID, categoryName)
 	if err != nil {
 		logger.Log.Error().Err(err).Str("name", categoryName).Msg("Failed to create category")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
@@ -782,12 +854,10 @@ func (b *Bot) processCategoryCreateCore(
 		return true
 	}
 
-	// Invalidate category cache after successful creation.
-	b.invalidateCategoryCache()
-
+	before := *expense
 	expense.CategoryID = &category.ID
 	expense.Category = category
-	if err := b.expenseRepo.Update(ctx, expense); err != nil {
+	if err := b.expenses().EditFields(ctx, expense); err != nil {
 		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to update expense category")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
@@ -801,19 +871,12 @@ func (b *Bot) processCategoryCreateCore(
 		Int(logFieldCategoryIDCB, category.ID).
 		Str("category_name", category.Name).
 		Msg("New category created and assigned")
+	b.auditExpenseUpdate(ctx, tg, userID, before, expense)
 
-	keyboard := buildReceiptConfirmationKeyboard(expense.ID)
-
-	text := fmt.Sprintf(`📸 <b>Category Created!</b>
+	b.loadExpenseTags(ctx, expense)
+	keyboard := buildReceiptConfirmationKeyboard(expense.ID, expense.Currency)
 
-💰 Amount: $%s SGD
-🏪 Merchant: %s
-📁 Category: %s
-
-New category created. Confirm to save.`,
-		expense.Amount.StringFixed(2),
-		expense.Merchant,
-		category.Name)
+	text := botfmt.ReceiptDraftCard(expense, botfmt.DraftCategoryCreated)
 
 	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      chatID,
@@ -922,13 +985,10 @@ func (b *Bot) handleExpenseActionCallbackCore(ctx context.Context, tg TelegramAP
 		Int64("user_id", userID).
 		Msg("Processing expense action callback")
 
-	_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
-		CallbackQueryID: update.CallbackQuery.ID,
-	})
-
 	parts := strings.Split(data, "_")
 	if len(parts) < 3 {
 		logger.Log.Error().Str(logFieldDataCB, data).Msg("Invalid callback data format")
+		answerCallback(ctx, tg, update.CallbackQuery)
 		return
 	}
 
@@ -936,29 +996,18 @@ func (b *Bot) handleExpenseActionCallbackCore(ctx context.Context, tg TelegramAP
 	expenseID, err := strconv.Atoi(parts[2])
 	if err != nil {
 		logger.Log.Error().Err(err).Str(logFieldDataCB, data).Msg("Failed to parse expense ID")
+		answerCallback(ctx, tg, update.CallbackQuery)
 		return
 	}
 
-	expense, err := b.expenseRepo.GetByID(ctx, expenseID)
-	if err != nil {
-		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expenseID).Msg(expenseNotFoundLogMsgCB)
-		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
-			ChatID:    chatID,
-			MessageID: messageID,
-			Text:      expenseNotFoundMsgCB,
-		})
+	expense, ok := b.loadCallbackExpense(ctx, tg, update.CallbackQuery, expenseID, action == actionDeleteExpenseCB)
+	if !ok {
 		return
 	}
-
-	if expense.UserID != userID {
-		logger.Log.Warn().Str(logFieldUserHashCB, logger.HashUserID(userID)).Int(logFieldExpenseIDCB, expenseID).Msg(userMismatchMsgCB)
-		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
-			CallbackQueryID: update.CallbackQuery.ID,
-			Text:            "❌ You can only modify your own expenses.",
-			ShowAlert:       true,
-		})
+	if action == actionDeleteExpenseCB && !b.deleteButtonAllowed(ctx, tg, update.CallbackQuery) {
 		return
 	}
+	answerCallback(ctx, tg, update.CallbackQuery)
 
 	switch action {
 	case actionEditExpenseCB:
@@ -976,23 +1025,7 @@ func (b *Bot) handleInlineEditExpenseCore(
 	messageID int,
 	expense *appmodels.Expense,
 ) {
-	categoryText := categoryUncategorized
-	if expense.Category != nil {
-		categoryText = escapeHTML(expense.Category.Name)
-	}
-
-	text := fmt.Sprintf(`✏️ <b>Edit Expense #%d</b>
-
-Current Details:
-💰 Amount: $%s SGD
-📝 Description: %s
-📁 Category: %s
-
-What would you like to edit?`,
-		expense.UserExpenseNumber,
-		expense.Amount.StringFixed(2),
-		escapeHTML(expense.Description),
-		categoryText)
+	text := botfmt.ExpenseEditMenuCard(expense)
 
 	keyboard := &models.InlineKeyboardMarkup{
 		InlineKeyboard: [][]models.InlineKeyboardButton{
@@ -1005,6 +1038,12 @@ What would you like to edit?`,
 			{
 				{Text: "📁 Category", CallbackData: fmt.Sprintf("edit_category_%d", expense.ID)},
 			},
+			{
+				{
+					Text:         reimbursableButtonText(expense),
+					CallbackData: fmt.Sprintf("edit_%s_%d", editTypeReimbursableCB, expense.ID),
+				},
+			},
 			{
 				{Text: backButtonTextCB, CallbackData: fmt.Sprintf(backToExpenseCallbackFmtCB, expense.ID)},
 			},
@@ -1028,18 +1067,7 @@ func (b *Bot) handleInlineDeleteExpenseCore(
 	messageID int,
 	expense *appmodels.Expense,
 ) {
-	text := fmt.Sprintf(`🗑️ <b>Delete Expense?</b>
-
-Are you sure you want to delete this expense?
-
-💰 $%s SGD
-📝 %s
-🆔 #%d
-
-This action cannot be undone.`,
-		expense.Amount.StringFixed(2),
-		escapeHTML(expense.Description),
-		expense.UserExpenseNumber)
+	text := botfmt.DeleteConfirmCard(expense)
 
 	keyboard := &models.InlineKeyboardMarkup{
 		InlineKeyboard: [][]models.InlineKeyboardButton{
@@ -1073,35 +1101,29 @@ func (b *Bot) handleConfirmDeleteCallbackCore(ctx context.Context, tg TelegramAP
 	}
 
 	data := update.CallbackQuery.Data
-	userID := update.CallbackQuery.From.ID
 	chatID := update.CallbackQuery.Message.Message.Chat.ID
 	messageID := update.CallbackQuery.Message.Message.ID
 
-	_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
-		CallbackQueryID: update.CallbackQuery.ID,
-	})
-
 	parts := strings.Split(data, "_")
 	if len(parts) < 3 {
+		answerCallback(ctx, tg, update.CallbackQuery)
 		return
 	}
 
 	expenseID, err := strconv.Atoi(parts[2])
 	if err != nil {
+		answerCallback(ctx, tg, update.CallbackQuery)
 		return
 	}
 
-	expense, err := b.expenseRepo.GetByID(ctx, expenseID)
-	if err != nil || expense.UserID != userID {
-		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
-			ChatID:    chatID,
-			MessageID: messageID,
-			Text:      "❌ Expense not found or unauthorized.",
-		})
+	expense, ok := b.loadCallbackExpense(ctx, tg, update.CallbackQuery, expenseID, true)
+	if !ok || !b.deleteButtonAllowed(ctx, tg, update.CallbackQuery) {
 		return
 	}
+	answerCallback(ctx, tg, update.CallbackQuery)
 
-	if err := b.expenseRepo.Delete(ctx, expenseID); err != nil {
+	sourceChatID := b.expenseSourceChat(ctx, expenseID)
+	if err := b.expenses().Delete(ctx, expenseID); err != nil {
 		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expenseID).Msg("Failed to delete expense")
 		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
 			ChatID:    chatID,
@@ -1115,6 +1137,7 @@ func (b *Bot) handleConfirmDeleteCallbackCore(ctx context.Context, tg TelegramAP
 		Int64("chat_id", chatID).
 		Int(logFieldExpenseIDCB, expenseID).
 		Msg("Expense deleted via inline button")
+	b.auditExpenseDelete(ctx, tg, update.CallbackQuery.From.ID, expense, sourceChatID)
 
 	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:    chatID,
@@ -1173,25 +1196,7 @@ func (b *Bot) handleBackToExpenseCallbackCore(ctx context.Context, tg TelegramAP
 		}
 	}
 
-	categoryText := categoryUncategorized
-	if expense.Category != nil {
-		categoryText = escapeHTML(expense.Category.Name)
-	}
-
-	descText := ""
-	if expense.Description != "" {
-		descText = "\n📝 " + escapeHTML(expense.Description)
-	}
-
-	text := fmt.Sprintf(`✅ <b>Expense Added</b>
-
-💰 $%s SGD%s
-📁 %s
-🆔 #%d`,
-		expense.Amount.StringFixed(2),
-		descText,
-		categoryText,
-		expense.UserExpenseNumber)
+	text := botfmt.ExpenseAddedCard(expense, nil, botfmt.ExpenseAddedOptions{})
 
 	keyboard := &models.InlineKeyboardMarkup{
 		InlineKeyboard: [][]models.InlineKeyboardButton{
@@ -1210,3 +1215,18 @@ func (b *Bot) handleBackToExpenseCallbackCore(ctx context.Context, tg TelegramAP
 		ReplyMarkup: keyboard,
 	})
 }
+
+// loadExpenseCategory fills in expense.Category from its CategoryID when the
+// repository returned the expense without it. Lookup failures leave the
+// expense uncategorized for display.
+func (b *Bot) loadExpenseCategory(ctx context.Context, expense *appmodels.Expense) {
+	if expense.Category != nil || expense.CategoryID == nil {
+		return
+	}
+	category, err := b.categoryRepo.GetByID(ctx, *expense.CategoryID)
+	if err != nil {
+		logger.Log.Debug().Err(err).Int(logFieldCategoryIDCB, *expense.CategoryID).Msg("Failed to load expense category")
+		return
+	}
+	expense.Category = category
+}
@@ -412,6 +412,7 @@ func TestHandlePendingEditCore(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, "New description", updated.Description)
 		require.Equal(t, "Original merchant", updated.Merchant)
+		require.Contains(t, mockBot.LastEditedMessage().Text, "Old description → New description")
 	})
 }
 
@@ -541,11 +542,39 @@ func TestProcessAmountEditCore(t *testing.T) {
 
 		require.Len(t, mockBot.EditedMessages, 1)
 		require.Contains(t, mockBot.EditedMessages[0].Text, "Amount Updated")
-		require.Contains(t, mockBot.EditedMessages[0].Text, "$45.50 SGD")
+		require.Contains(t, mockBot.EditedMessages[0].Text, "S$20.00 SGD → S$45.50 SGD")
 
 		updated, err := b.expenseRepo.GetByID(ctx, expense.ID)
 		require.NoError(t, err)
 		require.Equal(t, "45.50", updated.Amount.StringFixed(2))
+
+		mockBot = mocks.NewMockBot()
+		require.True(t, b.processAmountEditCore(ctx, mockBot, 12345, userID, pending, "45.5"))
+		require.Len(t, mockBot.EditedMessages, 1)
+		require.Contains(t, mockBot.EditedMessages[0].Text, "Nothing changed")
+		require.NotContains(t, mockBot.EditedMessages[0].Text, "→")
+		requireInlineKeyboard(t, mockBot.EditedMessages[0].ReplyMarkup)
+	})
+
+	t.Run("a refund stays a refund", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+
+		expense := &appmodels.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal("-12.00"),
+			Currency:    testCurrencySGD,
+			Description: "Returned shoes",
+			Status:      appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+
+		pending := &pendingEdit{ExpenseID: expense.ID, EditType: amountTypeCBT, MessageID: 100}
+		require.True(t, b.processAmountEditCore(ctx, mockBot, 12345, userID, pending, "15"))
+
+		updated, err := b.expenseRepo.GetByID(ctx, expense.ID)
+		require.NoError(t, err)
+		require.Equal(t, "-15.00", updated.Amount.StringFixed(2))
+		require.True(t, updated.IsRefund())
 	})
 }
 
@@ -643,7 +672,7 @@ func TestShowCategorySelectionCore(t *testing.T) {
 		err := b.expenseRepo.Create(ctx, expense)
 		require.NoError(t, err)
 
-		b.showCategorySelectionCore(ctx, mockBot, 12345, 100, expense)
+		b.showCategorySelectionCore(ctx, mockBot, 12345, 100, expense, "")
 
 		require.Len(t, mockBot.EditedMessages, 1)
 		require.Contains(t, mockBot.EditedMessages[0].Text, selectCategoryTextCBT)
@@ -968,7 +997,7 @@ func TestProcessMerchantEditCore(t *testing.T) {
 
 		require.Len(t, mockBot.EditedMessages, 1)
 		require.Contains(t, mockBot.EditedMessages[0].Text, "Merchant Updated")
-		require.Contains(t, mockBot.EditedMessages[0].Text, newRestaurantTextCBT)
+		require.Contains(t, mockBot.EditedMessages[0].Text, oldNameTextCBT+" → "+newRestaurantTextCBT)
 
 		updated, err := b.expenseRepo.GetByID(ctx, expense.ID)
 		require.NoError(t, err)
@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+const setCategoryColorUsage = "Usage:\n" +
+	"<code>/setcategorycolor Transportation #1F77B4</code> - Draw a category in that color in charts\n" +
+	"<code>/setcategorycolor Transportation none</code> - Go back to the color picked from its name"
+
+// categoryColorClear is the argument that removes a category's color.
+const categoryColorClear = "none"
+
+// parseSetCategoryColorArgs splits `<category> <color>` at the last field.
+// The category name may be quoted.
+func parseSetCategoryColorArgs(args string) (name, color string, ok bool) {
+	fields := strings.Fields(args)
+	if len(fields) < 2 {
+		return "", "", false
+	}
+	name = strings.Trim(strings.Join(fields[:len(fields)-1], " "), `"`)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", "", false
+	}
+	return name, fields[len(fields)-1], true
+}
+
+// handleSetCategoryColor handles the /setcategorycolor command.
+func (b *Bot) handleSetCategoryColor(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleSetCategoryColorCore(ctx, tgBot, update)
+}
+
+// handleSetCategoryColorCore is the testable implementation of
+// handleSetCategoryColor. It sets or clears the color a category is drawn
+// in by every chart.
+func (b *Bot) handleSetCategoryColorCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	name, rawColor, ok := parseSetCategoryColorArgs(extractCommandArgs(update.Message.Text, "/setcategorycolor"))
+	if !ok {
+		send(setCategoryColorUsage)
+		return
+	}
+
+	color := ""
+	if !strings.EqualFold(rawColor, categoryColorClear) {
+		parsed, err := parseCategoryColor(rawColor)
+		if err != nil {
+			send("❌ " + botfmt.EscapeHTML(err.Error()) + ".\n\n" + setCategoryColorUsage)
+			return
+		}
+		color = parsed
+	}
+
+	cat, err := b.findUserCategory(ctx, update.Message.From.ID, name)
+	if err != nil {
+		send(fmt.Sprintf("❌ Category '%s' not found.\n\nUse /categories to see all categories.",
+			botfmt.EscapeHTML(echoText(name))))
+		return
+	}
+	if cat.OwnerID == 0 {
+		send(sharedCategoryReadOnlyMsg(cat.Name, "recolored"))
+		return
+	}
+
+	if err := b.categoryRepo.SetColor(ctx, cat.ID, color); err != nil {
+		logger.Log.Error().Err(err).Int("category_id", cat.ID).Msg("Failed to set category color")
+		send("❌ Failed to save the color. Please try again.")
+		return
+	}
+	b.invalidateCategoryCache()
+
+	logger.Log.Info().Int("category_id", cat.ID).Str("color", color).Msg("Category color set")
+	effective := categoryColor(cat.Name, color)
+	if color == "" {
+		send(fmt.Sprintf("✅ <b>%s</b> is back to its default chart color %s %s.",
+			botfmt.EscapeHTML(cat.Name), colorSquare(effective), effective))
+		return
+	}
+	send(fmt.Sprintf("✅ <b>%s</b> is now drawn in %s %s in charts.",
+		botfmt.EscapeHTML(cat.Name), colorSquare(effective), effective))
+}
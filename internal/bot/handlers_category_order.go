@@ -0,0 +1,262 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	// categoryUsageWindow is how far back expenses count towards the most
+	// used first category order.
+	categoryUsageWindow = 90 * 24 * time.Hour
+	// categoryUsageCacheTTL is how long a user's category usage is reused.
+	categoryUsageCacheTTL = 10 * time.Minute
+
+	reorderCategoriesUsage = "Send <code>/reordercategories 3,1,2</code> to move those categories to the top " +
+		"in that order. The others keep their order below them."
+)
+
+// categoryUsageEntry is a cached most used first list of category IDs.
+type categoryUsageEntry struct {
+	ids     []int
+	expires time.Time
+}
+
+// categoryUsageCache keeps each user's category usage order for
+// categoryUsageCacheTTL. The zero value is ready to use.
+type categoryUsageCache struct {
+	mu      sync.Mutex
+	entries map[int64]categoryUsageEntry
+}
+
+func (c *categoryUsageCache) get(userID int64, now time.Time) ([]int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userID]
+	if !ok || !now.Before(entry.expires) {
+		return nil, false
+	}
+	return entry.ids, true
+}
+
+func (c *categoryUsageCache) set(userID int64, ids []int, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[int64]categoryUsageEntry)
+	}
+	c.entries[userID] = categoryUsageEntry{ids: ids, expires: expires}
+}
+
+func (c *categoryUsageCache) invalidate(userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, userID)
+}
+
+// visibleCategories returns the categories a user sees, in the shared
+// order: the shared ones they did not hide and their own. When the hidden
+// categories cannot be loaded every shared category is shown.
+func (b *Bot) visibleCategories(ctx context.Context, userID int64) ([]appmodels.Category, error) {
+	categories, err := b.getCategoriesWithCache(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hiddenIDs, ok := b.hiddenCategories.get(userID, b.now())
+	if !ok && b.categoryRepo != nil {
+		hiddenIDs, err = b.categoryRepo.GetHiddenIDs(ctx, userID)
+		if err != nil {
+			logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to get hidden categories")
+		} else {
+			b.hiddenCategories.set(userID, hiddenIDs, b.now().Add(categoryUsageCacheTTL))
+		}
+	}
+	hidden := make(map[int]bool, len(hiddenIDs))
+	for _, id := range hiddenIDs {
+		hidden[id] = true
+	}
+
+	visible := make([]appmodels.Category, 0, len(categories))
+	for i := range categories {
+		if categories[i].Visible(userID, hidden) {
+			visible = append(visible, categories[i])
+		}
+	}
+	return visible, nil
+}
+
+// categoriesForUser returns the categories a user sees in the order their
+// category keyboards show them: most used first when the user turned that
+// on in /settings, otherwise the shared order set with /reordercategories.
+func (b *Bot) categoriesForUser(ctx context.Context, userID int64) ([]appmodels.Category, error) {
+	categories, err := b.visibleCategories(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	byUsage, err := b.userRepo.GetCategoriesByUsage(ctx, userID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to get category order setting")
+		return categories, nil
+	}
+	if !byUsage {
+		return categories, nil
+	}
+
+	usage, ok := b.categoryUsage.get(userID, b.now())
+	if !ok {
+		usage, err = b.categoryRepo.UsageOrder(ctx, userID, b.now().Add(-categoryUsageWindow))
+		if err != nil {
+			logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to get category usage")
+			return categories, nil
+		}
+		b.categoryUsage.set(userID, usage, b.now().Add(categoryUsageCacheTTL))
+	}
+	return orderCategoriesByUsage(categories, usage), nil
+}
+
+// orderCategoriesByUsage moves the categories in usage, a most used first
+// list of IDs, to the front. The rest keep their order.
+func orderCategoriesByUsage(categories []appmodels.Category, usage []int) []appmodels.Category {
+	rank := make(map[int]int, len(usage))
+	for i, id := range usage {
+		rank[id] = i
+	}
+	ordered := slices.Clone(categories)
+	slices.SortStableFunc(ordered, func(a, b appmodels.Category) int {
+		ra, aUsed := rank[a.ID]
+		rb, bUsed := rank[b.ID]
+		switch {
+		case aUsed && bUsed:
+			return ra - rb
+		case aUsed:
+			return -1
+		case bUsed:
+			return 1
+		}
+		return 0
+	})
+	return ordered
+}
+
+// parseCategoryPositions reads the 1-based positions of
+// "/reordercategories 3,1,2" for a list of count categories and returns
+// them 0-based.
+func parseCategoryPositions(args string, count int) ([]int, error) {
+	fields := strings.FieldsFunc(args, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(fields) == 0 {
+		return nil, errors.New("no category numbers given")
+	}
+
+	positions := make([]int, 0, len(fields))
+	for _, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > count {
+			return nil, fmt.Errorf("%q is not a number from 1 to %d", field, count)
+		}
+		if slices.Contains(positions, n-1) {
+			return nil, fmt.Errorf("%d is listed twice", n)
+		}
+		positions = append(positions, n-1)
+	}
+	return positions, nil
+}
+
+// reorderCategories moves the categories at positions to the front in that
+// order, followed by the others in their current order.
+func reorderCategories(categories []appmodels.Category, positions []int) []appmodels.Category {
+	ordered := make([]appmodels.Category, 0, len(categories))
+	for _, pos := range positions {
+		ordered = append(ordered, categories[pos])
+	}
+	for i := range categories {
+		if !slices.Contains(positions, i) {
+			ordered = append(ordered, categories[i])
+		}
+	}
+	return ordered
+}
+
+// numberedCategoryList formats categories as a numbered list.
+func numberedCategoryList(categories []appmodels.Category) string {
+	var sb strings.Builder
+	for i := range categories {
+		fmt.Fprintf(&sb, "\n%d. %s", i+1, botfmt.EscapeHTML(categories[i].Name))
+	}
+	return sb.String()
+}
+
+// handleReorderCategories handles the /reordercategories command.
+func (b *Bot) handleReorderCategories(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleReorderCategoriesCore(ctx, tgBot, update)
+}
+
+// handleReorderCategoriesCore is the testable implementation of
+// handleReorderCategories. Without arguments it shows the numbered order;
+// with numbers it moves those categories to the top.
+func (b *Bot) handleReorderCategoriesCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	categories, err := b.visibleCategories(ctx, update.Message.From.ID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for reorder")
+		send("❌ Failed to fetch categories. Please try again.")
+		return
+	}
+	if len(categories) == 0 {
+		send("No categories yet. Add one with <code>/addcategory &lt;name&gt;</code>.")
+		return
+	}
+
+	args := extractCommandArgs(update.Message.Text, "/reordercategories")
+	if args == "" {
+		send("📁 <b>Category Order</b>\n" + numberedCategoryList(categories) + "\n\n" + reorderCategoriesUsage)
+		return
+	}
+
+	positions, err := parseCategoryPositions(args, len(categories))
+	if err != nil {
+		send(fmt.Sprintf("❌ %s.\n\n%s", botfmt.EscapeHTML(err.Error()), reorderCategoriesUsage))
+		return
+	}
+
+	ordered := reorderCategories(categories, positions)
+	ids := make([]int, len(ordered))
+	for i := range ordered {
+		ids[i] = ordered[i].ID
+	}
+	if err := b.categoryRepo.Reorder(ctx, ids); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to reorder categories")
+		send("❌ Failed to reorder categories. Please try again.")
+		return
+	}
+	b.invalidateCategoryCache()
+
+	send("✅ <b>Categories reordered</b>\n" + numberedCategoryList(ordered))
+}
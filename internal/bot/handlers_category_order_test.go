@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestParseCategoryPositions(t *testing.T) {
+	t.Parallel()
+
+	positions, err := parseCategoryPositions("3,1, 2", 4)
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 0, 1}, positions)
+
+	for _, args := range []string{"", "5", "0", "x", "1,1"} {
+		_, err := parseCategoryPositions(args, 4)
+		require.Error(t, err, "args %q", args)
+	}
+}
+
+func TestReorderAndUsageOrder(t *testing.T) {
+	t.Parallel()
+
+	categories := []appmodels.Category{{ID: 1, Name: "A"}, {ID: 2, Name: "B"}, {ID: 3, Name: "C"}, {ID: 4, Name: "D"}}
+	names := func(categories []appmodels.Category) []string {
+		out := make([]string, len(categories))
+		for i := range categories {
+			out[i] = categories[i].Name
+		}
+		return out
+	}
+
+	require.Equal(t, []string{"C", "A", "B", "D"}, names(reorderCategories(categories, []int{2, 0})))
+	require.Equal(t, []string{"D", "B", "A", "C"}, names(orderCategoriesByUsage(categories, []int{4, 2})))
+	require.Equal(t, []string{"A", "B", "C", "D"}, names(categories), "the input is not modified")
+}
+
+func TestNumberedCategoryList(t *testing.T) {
+	t.Parallel()
+
+	categories := []appmodels.Category{{ID: 1, Name: "Food & Drinks"}, {ID: 2, Name: "<Misc>"}}
+	require.Equal(t, "\n1. Food &amp; Drinks\n2. &lt;Misc&gt;", numberedCategoryList(categories))
+}
+
+func TestReorderCategories(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(399001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Sorter"}))
+
+	send := func(text string) string {
+		mockBot := mocks.NewMockBot()
+		b.handleReorderCategoriesCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, text))
+		return mockBot.LastSentMessage().Text
+	}
+	keyboardNames := func() []string {
+		mockBot := mocks.NewMockBot()
+		b.showCategorySelectionCore(ctx, mockBot, userID, 1, &appmodels.Expense{ID: 1, UserID: userID}, "")
+		var names []string
+		for _, row := range requireInlineKeyboard(t, mockBot.LastEditedMessage().ReplyMarkup).InlineKeyboard {
+			for _, button := range row {
+				names = append(names, button.Text)
+			}
+		}
+		return names
+	}
+
+	categories, err := b.categoryRepo.GetAll(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(categories), 3)
+	first, third := categories[0], categories[2]
+
+	t.Run("shows the numbered order", func(t *testing.T) {
+		text := send("/reordercategories")
+		require.Contains(t, text, "1. "+first.Name)
+		require.Contains(t, text, "3. "+third.Name)
+	})
+
+	t.Run("rejects bad numbers", func(t *testing.T) {
+		require.Contains(t, send("/reordercategories 1,1"), "listed twice")
+		require.Contains(t, send("/reordercategories 999"), "not a number from 1")
+	})
+
+	t.Run("persists the new order", func(t *testing.T) {
+		text := send("/reordercategories 3,1")
+		require.Contains(t, text, "1. "+third.Name)
+		require.Contains(t, text, "2. "+first.Name)
+
+		reordered, err := b.categoryRepo.GetAll(ctx)
+		require.NoError(t, err)
+		require.Equal(t, third.ID, reordered[0].ID)
+		require.Equal(t, first.ID, reordered[1].ID)
+		require.Len(t, reordered, len(categories))
+
+		require.Equal(t, []string{third.Name, first.Name}, keyboardNames()[:2])
+	})
+
+	t.Run("new categories go to the end", func(t *testing.T) {
+		created, err := b.categoryRepo.Create(ctx, "AAA Reorder Test")
+		require.NoError(t, err)
+		b.invalidateCategoryCache()
+
+		reordered, err := b.categoryRepo.GetAll(ctx)
+		require.NoError(t, err)
+		require.Equal(t, created.ID, reordered[len(reordered)-1].ID)
+	})
+
+	t.Run("most used first", func(t *testing.T) {
+		used := categories[len(categories)-1]
+		for range 2 {
+			require.NoError(t, b.expenseRepo.Create(ctx, &appmodels.Expense{
+				UserID: userID, Amount: decimal.NewFromInt(5), Currency: currencyCodeSGD,
+				CategoryID: &used.ID, Status: appmodels.ExpenseStatusConfirmed,
+			}))
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, &appmodels.Expense{
+			UserID: userID, Amount: decimal.NewFromInt(5), Currency: currencyCodeSGD,
+			CategoryID: &first.ID, Status: appmodels.ExpenseStatusConfirmed,
+		}))
+
+		require.Equal(t, third.Name, keyboardNames()[0], "the shared order by default")
+
+		require.NoError(t, b.userRepo.UpdateCategoriesByUsage(ctx, userID, true))
+		require.Equal(t, []string{used.Name, first.Name, third.Name}, keyboardNames()[:3])
+	})
+}
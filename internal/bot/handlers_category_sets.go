@@ -0,0 +1,162 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// Starting category sets a new user picks from on /start.
+const (
+	categorySetCallbackPrefix = "catset_"
+	categorySetDefault        = "default"
+	categorySetMinimal        = "minimal"
+	categorySetEmpty          = "empty"
+
+	categorySetPromptMsg = "\n\n📁 <b>Which categories do you want to start with?</b>\n" +
+		"You can add your own any time with /addcategory."
+)
+
+// errCategoryNotFound is returned when a user has no category of a name.
+var errCategoryNotFound = errors.New("category not found")
+
+// categorySetDoneMsgs confirm each starting category set.
+var categorySetDoneMsgs = map[string]string{
+	categorySetDefault: "✅ You have the default categories. See them with /categories.",
+	categorySetMinimal: "✅ You start with a minimal set of categories. See them with /categories.",
+	categorySetEmpty:   "✅ You start with no categories. Add your own with /addcategory.",
+}
+
+// findUserCategory returns the category a user sees with the given name,
+// ignoring case, or errCategoryNotFound.
+func (b *Bot) findUserCategory(ctx context.Context, userID int64, name string) (*appmodels.Category, error) {
+	categories, err := b.visibleCategories(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, category := findCategoryByName(categories, name); category != nil {
+		return category, nil
+	}
+	return nil, errCategoryNotFound
+}
+
+// unhideSharedCategory shows a user the shared category with the given name
+// again, or returns errCategoryNotFound when there is none.
+func (b *Bot) unhideSharedCategory(ctx context.Context, userID int64, name string) (*appmodels.Category, error) {
+	category, err := b.categoryRepo.GetByName(ctx, name)
+	if err != nil || category.OwnerID != 0 {
+		return nil, errCategoryNotFound
+	}
+	if _, err := b.categoryRepo.Unhide(ctx, userID, category.ID); err != nil {
+		return nil, fmt.Errorf("failed to show category: %w", err)
+	}
+	b.hiddenCategories.invalidate(userID)
+	return category, nil
+}
+
+// createUserCategory gives a user a category they do not see yet: the
+// shared one of that name if they hid it, otherwise a new private one.
+func (b *Bot) createUserCategory(ctx context.Context, userID int64, name string) (*appmodels.Category, error) {
+	category, err := b.unhideSharedCategory(ctx, userID, name)
+	if !errors.Is(err, errCategoryNotFound) {
+		return category, err
+	}
+	category, err = b.categoryRepo.CreateOwned(ctx, userID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create category: %w", err)
+	}
+	b.cacheCategory(category)
+	return category, nil
+}
+
+// needsCategorySet reports whether a user still has to pick a starting
+// category set. Lookup failures count as no.
+func (b *Bot) needsCategorySet(ctx context.Context, userID int64) bool {
+	chosen, err := b.userRepo.CategoriesChosen(ctx, userID)
+	return err == nil && !chosen
+}
+
+// buildCategorySetKeyboard offers the starting category sets.
+func buildCategorySetKeyboard() *models.InlineKeyboardMarkup {
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+		{{Text: "📁 Use default categories", CallbackData: categorySetCallbackPrefix + categorySetDefault}},
+		{{Text: "🌱 Minimal set", CallbackData: categorySetCallbackPrefix + categorySetMinimal}},
+		{{Text: "📭 Start empty", CallbackData: categorySetCallbackPrefix + categorySetEmpty}},
+	}}
+}
+
+// applyCategorySet shows a user the shared categories of a starting set
+// and hides the others. Their own categories are kept.
+func (b *Bot) applyCategorySet(ctx context.Context, userID int64, set string) error {
+	var err error
+	switch set {
+	case categorySetDefault:
+		err = b.categoryRepo.UnhideAll(ctx, userID)
+	case categorySetMinimal:
+		err = b.categoryRepo.HideSharedExcept(ctx, userID, b.cfg.MinimalCategories)
+	default:
+		err = b.categoryRepo.HideSharedExcept(ctx, userID, nil)
+	}
+	b.hiddenCategories.invalidate(userID)
+	if err != nil {
+		return fmt.Errorf("failed to apply category set %s: %w", set, err)
+	}
+	return nil
+}
+
+// handleCategorySetCallback handles the starting category set buttons.
+func (b *Bot) handleCategorySetCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleCategorySetCallbackCore(ctx, tgBot, update)
+}
+
+// handleCategorySetCallbackCore applies the picked set once. Later presses
+// only remove the buttons.
+func (b *Bot) handleCategorySetCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+	set := strings.TrimPrefix(query.Data, categorySetCallbackPrefix)
+	doneMsg, ok := categorySetDoneMsgs[set]
+	if !ok {
+		answerCallback(ctx, tg, query)
+		return
+	}
+
+	userID := query.From.ID
+	chatID := query.Message.Message.Chat.ID
+	_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+		ChatID:    chatID,
+		MessageID: query.Message.Message.ID,
+	})
+	if !b.needsCategorySet(ctx, userID) {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            "Your categories are already set up.",
+		})
+		return
+	}
+
+	if err := b.applyCategorySet(ctx, userID, set); err != nil {
+		logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to apply category set")
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            "❌ Failed to set up your categories. Please try /start again.",
+			ShowAlert:       true,
+		})
+		return
+	}
+	if _, err := b.userRepo.ClaimCategoryChoice(ctx, userID); err != nil {
+		logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to record category set")
+	}
+	logger.Log.Info().Str("user_hash", logger.HashUserID(userID)).Str("set", set).Msg("Starting category set picked")
+
+	answerCallback(ctx, tg, query)
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: doneMsg})
+}
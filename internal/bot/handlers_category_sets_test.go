@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestBuildCategorySetKeyboard(t *testing.T) {
+	t.Parallel()
+
+	kb := buildCategorySetKeyboard()
+	require.Len(t, kb.InlineKeyboard, 3)
+	for _, row := range kb.InlineKeyboard {
+		set := row[0].CallbackData[len(categorySetCallbackPrefix):]
+		require.Contains(t, categorySetDoneMsgs, set)
+	}
+}
+
+func TestCategorySets(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.cfg.MinimalCategories = []string{"Food - Grocery", "Transportation"}
+
+	const aliceID = int64(426101)
+	const bobID = int64(426102)
+	for _, id := range []int64{aliceID, bobID} {
+		require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: id, FirstName: "New"}))
+	}
+
+	command := func(userID int64, handler func(context.Context, TelegramAPI, *tgmodels.Update), text string) *mocks.SentMessage {
+		mockBot := mocks.NewMockBot()
+		handler(ctx, mockBot, mocks.CommandUpdate(userID, userID, text))
+		return mockBot.LastSentMessage()
+	}
+	pick := func(userID int64, set string) *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		b.handleCategorySetCallbackCore(ctx, mockBot,
+			mocks.CallbackQueryUpdate(userID, userID, 1, categorySetCallbackPrefix+set))
+		return mockBot
+	}
+	categories := func(userID int64) string {
+		return command(userID, b.handleCategoriesCore, "/categories").Text
+	}
+
+	t.Run("start offers the sets to new users", func(t *testing.T) {
+		msg := command(aliceID, b.handleStartCore, "/start")
+		require.Contains(t, msg.Text, "Which categories do you want to start with?")
+		kb := requireInlineKeyboard(t, msg.ReplyMarkup)
+		require.Len(t, kb.InlineKeyboard, 3)
+	})
+
+	t.Run("minimal set", func(t *testing.T) {
+		mockBot := pick(aliceID, categorySetMinimal)
+		require.Equal(t, categorySetDoneMsgs[categorySetMinimal], mockBot.LastSentMessage().Text)
+		require.Len(t, mockBot.EditedReplyMarkups, 1)
+
+		text := categories(aliceID)
+		require.Contains(t, text, "Food - Grocery")
+		require.Contains(t, text, "Transportation")
+		require.NotContains(t, text, "Entertainment")
+
+		msg := command(aliceID, b.handleStartCore, "/start")
+		require.Nil(t, msg.ReplyMarkup, "the choice is made once")
+	})
+
+	t.Run("later presses change nothing", func(t *testing.T) {
+		mockBot := pick(aliceID, categorySetDefault)
+		require.Len(t, mockBot.AnsweredCallbacks, 1)
+		require.Contains(t, mockBot.AnsweredCallbacks[0].Text, "already set up")
+		require.NotContains(t, categories(aliceID), "Entertainment")
+	})
+
+	t.Run("empty set and private categories", func(t *testing.T) {
+		pick(bobID, categorySetEmpty)
+		require.Equal(t, "No categories found.", categories(bobID))
+
+		msg := command(bobID, b.handleAddCategoryCore, "/addcategory Pets 426")
+		require.Contains(t, msg.Text, "created")
+		require.Contains(t, categories(bobID), "Pets 426")
+		require.NotContains(t, categories(aliceID), "Pets 426", "private to bob")
+
+		msg = command(aliceID, b.handleAddCategoryCore, "/addcategory Pets 426")
+		require.Contains(t, msg.Text, "created", "alice gets her own")
+		msg = command(aliceID, b.handleAddCategoryCore, "/addcategory Pets 426")
+		require.Contains(t, msg.Text, "already exists")
+	})
+
+	t.Run("adding a hidden shared category shows it again", func(t *testing.T) {
+		msg := command(bobID, b.handleAddCategoryCore, "/addcategory Entertainment")
+		require.Contains(t, msg.Text, "created")
+
+		text := categories(bobID)
+		require.Contains(t, text, "Entertainment")
+		require.NotContains(t, text, "Transportation")
+
+		shared, err := b.categoryRepo.GetByName(ctx, "Entertainment")
+		require.NoError(t, err)
+		require.Zero(t, shared.OwnerID, "no private copy was made")
+	})
+
+	t.Run("expenses only match categories the user sees", func(t *testing.T) {
+		b.handleAddCore(ctx, mocks.NewMockBot(), mocks.CommandUpdate(aliceID, aliceID, "/add 12 Lunch Entertainment"))
+		expenses, err := b.expenseRepo.GetByUserID(ctx, aliceID, 1)
+		require.NoError(t, err)
+		require.Len(t, expenses, 1)
+		if expenses[0].Category != nil {
+			require.NotEqual(t, "Entertainment", expenses[0].Category.Name)
+		}
+	})
+}
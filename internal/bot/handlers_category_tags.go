@@ -0,0 +1,176 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const categoryTagsUsage = "Usage:\n" +
+	"<code>/categorytags</code> - List default tags\n" +
+	"<code>/categorytags \"Work Travel\" #reimbursable #travel</code> - Tag every expense in a category\n" +
+	"<code>/categorytags \"Work Travel\" none</code> - Stop tagging it"
+
+// categoryTagsClear is the argument that removes a category's default tags.
+const categoryTagsClear = "none"
+
+// parseCategoryTagsArgs splits `"<category>" #tag1 #tag2` or
+// `"<category>" none` into the category name and tags. The quotes may be
+// left out when the name has no "#". clear reports the "none" form.
+func parseCategoryTagsArgs(args string) (name string, tags []string, clear, ok bool) {
+	args = strings.TrimSpace(args)
+	var rest string
+	if strings.HasPrefix(args, `"`) {
+		end := strings.Index(args[1:], `"`)
+		if end < 0 {
+			return "", nil, false, false
+		}
+		name, rest = args[1:end+1], args[end+2:]
+	} else {
+		fields := strings.Fields(args)
+		i := slices.IndexFunc(fields, func(f string) bool {
+			return strings.HasPrefix(f, "#") || strings.EqualFold(f, categoryTagsClear)
+		})
+		if i < 0 {
+			return "", nil, false, false
+		}
+		name, rest = strings.Join(fields[:i], " "), strings.Join(fields[i:], " ")
+	}
+
+	name = strings.TrimSpace(name)
+	fields := strings.Fields(rest)
+	if name == "" || len(fields) == 0 {
+		return "", nil, false, false
+	}
+	if len(fields) == 1 && strings.EqualFold(fields[0], categoryTagsClear) {
+		return name, nil, true, true
+	}
+	return name, fields, false, true
+}
+
+// applyCategoryDefaultTags adds the default tags of an expense's category to
+// the saved expense. Tags it already has are left alone. It returns the
+// names of the default tags.
+func (b *Bot) applyCategoryDefaultTags(ctx context.Context, expense *appmodels.Expense) []string {
+	names := b.expenses().DefaultTags(ctx, expense.CategoryID)
+	if len(names) == 0 {
+		return nil
+	}
+	if err := b.addTagsByName(ctx, expense.ID, names); err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expense.ID).Msg("Failed to apply category default tags")
+		return nil
+	}
+	return names
+}
+
+// formatCategoryDefaults lists the categories that have default tags.
+func formatCategoryDefaults(categories []appmodels.Category, defaults map[int][]appmodels.Tag) string {
+	var sb strings.Builder
+	for i := range categories {
+		tags := defaults[categories[i].ID]
+		if len(tags) == 0 {
+			continue
+		}
+		names := make([]string, len(tags))
+		for j := range tags {
+			names[j] = tags[j].Name
+		}
+		fmt.Fprintf(&sb, "\n📁 %s: %s", botfmt.EscapeHTML(categories[i].Name), describeParsedTags(names))
+	}
+	if sb.Len() == 0 {
+		return "🏷️ No category has default tags.\n\n" + categoryTagsUsage
+	}
+	return "🏷️ <b>Category Default Tags</b>\n" + sb.String()
+}
+
+// handleCategoryTags handles the /categorytags command.
+func (b *Bot) handleCategoryTags(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleCategoryTagsCore(ctx, tgBot, update)
+}
+
+// handleCategoryTagsCore is the testable implementation of
+// handleCategoryTags. It lists, sets or clears the tags added to every
+// expense saved in a category.
+func (b *Bot) handleCategoryTagsCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	args := extractCommandArgs(update.Message.Text, "/categorytags")
+	if args == "" {
+		categories, err := b.visibleCategories(ctx, update.Message.From.ID)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to fetch categories for default tags")
+			send("❌ Failed to fetch categories. Please try again.")
+			return
+		}
+		defaults, err := b.tagRepo.GetAllCategoryDefaults(ctx)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to fetch category default tags")
+			send("❌ Failed to fetch default tags. Please try again.")
+			return
+		}
+		send(formatCategoryDefaults(categories, defaults))
+		return
+	}
+
+	name, tagNames, clearTags, ok := parseCategoryTagsArgs(args)
+	if !ok {
+		send(categoryTagsUsage)
+		return
+	}
+	if len(tagNames) > maxTagsPerCommand {
+		send(fmt.Sprintf("❌ Too many tags. Maximum %d tags per command.", maxTagsPerCommand))
+		return
+	}
+
+	cat, err := b.findUserCategory(ctx, update.Message.From.ID, name)
+	if err != nil {
+		send(fmt.Sprintf("❌ Category '%s' not found.\n\nUse /categories to see all categories.",
+			botfmt.EscapeHTML(echoText(name))))
+		return
+	}
+
+	var tagIDs []int
+	var added []string
+	if !clearTags {
+		tagIDs, added, err = b.resolveTagIDs(ctx, tagNames)
+		if err != nil {
+			send(err.Error())
+			return
+		}
+		if len(tagIDs) == 0 {
+			send("❌ No valid tags provided.")
+			return
+		}
+	}
+
+	if err := b.tagRepo.SetCategoryDefaults(ctx, cat.ID, tagIDs); err != nil {
+		logger.Log.Error().Err(err).Int("category_id", cat.ID).Msg("Failed to set category default tags")
+		send("❌ Failed to save default tags. Please try again.")
+		return
+	}
+
+	logger.Log.Info().Int("category_id", cat.ID).Int("tags", len(tagIDs)).Msg("Category default tags set")
+	if clearTags {
+		send(fmt.Sprintf("✅ Expenses in <b>%s</b> no longer get default tags.", botfmt.EscapeHTML(cat.Name)))
+		return
+	}
+	send(fmt.Sprintf("✅ Expenses in <b>%s</b> now get %s.", botfmt.EscapeHTML(cat.Name), strings.Join(added, " ")))
+}
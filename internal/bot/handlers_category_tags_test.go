@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestParseCategoryTagsArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		args  string
+		name  string
+		tags  []string
+		clear bool
+		ok    bool
+	}{
+		{
+			args: `"Work Travel" #reimbursable #travel`, name: "Work Travel",
+			tags: []string{"#reimbursable", "#travel"}, ok: true,
+		},
+		{args: `"Work Travel" none`, name: "Work Travel", clear: true, ok: true},
+		{args: "Work Travel #reimbursable", name: "Work Travel", tags: []string{"#reimbursable"}, ok: true},
+		{args: "Work Travel NONE", name: "Work Travel", clear: true, ok: true},
+		{args: `"Work Travel"`},
+		{args: `"Work Travel #a`},
+		{args: "#reimbursable"},
+		{args: "Work Travel"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			t.Parallel()
+			name, tags, clearTags, ok := parseCategoryTagsArgs(tt.args)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.name, name)
+			require.Equal(t, tt.tags, tags)
+			require.Equal(t, tt.clear, clearTags)
+		})
+	}
+}
+
+func TestCategoryDefaultTags(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(406001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{
+		ID: userID, FirstName: "Defaults", DefaultCurrency: currencyCodeSGD,
+	}))
+	cat, err := b.categoryRepo.Create(ctx, "Work Travel 406")
+	require.NoError(t, err)
+	b.invalidateCategoryCache()
+
+	run := func(text string) string {
+		t.Helper()
+		mockBot := mocks.NewMockBot()
+		b.handleCategoryTagsCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, text))
+		return mockBot.LastSentMessage().Text
+	}
+	expenseTags := func(expenseID int) []string {
+		t.Helper()
+		tags, err := b.tagRepo.GetByExpenseID(ctx, expenseID)
+		require.NoError(t, err)
+		names := make([]string, len(tags))
+		for i := range tags {
+			names[i] = tags[i].Name
+		}
+		return names
+	}
+
+	require.Equal(t, categoryTagsUsage, run("/categorytags Work Travel 406"))
+	require.Contains(t, run(`/categorytags "Nope 406" #a`), "not found")
+	require.Contains(t, run(`/categorytags "Work Travel 406" #Reimbursable #travel`), "#reimbursable #travel")
+	require.Contains(t, run("/categorytags"), "Work Travel 406")
+
+	t.Run("text expense gets the defaults once", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleAddCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/add 40 Taxi #travel Work Travel 406"))
+
+		sent := mockBot.LastSentMessage().Text
+		require.Contains(t, sent, "Expense Added")
+		require.Contains(t, sent, "travel, reimbursable")
+
+		expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 1)
+		require.NoError(t, err)
+		require.Len(t, expenses, 1)
+		require.Equal(t, cat.ID, *expenses[0].CategoryID)
+		require.Equal(t, []string{"reimbursable", "travel"}, expenseTags(expenses[0].ID))
+	})
+
+	t.Run("confirmed receipt gets the defaults", func(t *testing.T) {
+		expense := &appmodels.Expense{
+			UserID: userID, Amount: mustParseDecimal("12"), Currency: currencyCodeSGD,
+			Description: testReceiptText, Merchant: testReceiptText,
+			CategoryID: &cat.ID, Status: appmodels.ExpenseStatusDraft,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		require.NoError(t, b.addTagsByName(ctx, expense.ID, []string{"travel"}))
+
+		mockBot := mocks.NewMockBot()
+		b.handleConfirmReceiptCore(ctx, mockBot, userID, 100, expense)
+
+		require.Contains(t, mockBot.LastEditedMessage().Text, "🏷️ #reimbursable #travel")
+		require.Equal(t, []string{"reimbursable", "travel"}, expenseTags(expense.ID))
+	})
+
+	t.Run("cleared defaults are not applied", func(t *testing.T) {
+		require.Contains(t, run(`/categorytags "Work Travel 406" none`), "no longer")
+
+		expense := &appmodels.Expense{
+			UserID: userID, Amount: mustParseDecimal("9"), Currency: currencyCodeSGD,
+			Description: testReceiptText, CategoryID: &cat.ID, Status: appmodels.ExpenseStatusDraft,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		mockBot := mocks.NewMockBot()
+		b.handleConfirmReceiptCore(ctx, mockBot, userID, 101, expense)
+		require.NotContains(t, mockBot.LastEditedMessage().Text, "🏷️")
+		require.Empty(t, expenseTags(expense.ID))
+	})
+}
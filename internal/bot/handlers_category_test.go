@@ -77,8 +77,8 @@ func TestHandleCategoryCore(t *testing.T) {
 		require.Equal(t, 1, mockBot.SentMessageCount())
 		msg := mockBot.LastSentMessage()
 		require.Contains(t, msg.Text, "Test Food Category 999 Expenses")
-		require.Contains(t, msg.Text, "Total: $31.50") // 3 * 10.50
-		require.Contains(t, msg.Text, "#")             // Should contain expense IDs
+		require.Contains(t, msg.Text, "Total: S$31.50 SGD") // 3 * 10.50
+		require.Contains(t, msg.Text, "#")                  // Should contain expense IDs
 		require.Contains(t, msg.Text, "Lunch")
 	})
 
@@ -91,7 +91,7 @@ func TestHandleCategoryCore(t *testing.T) {
 		require.Equal(t, 1, mockBot.SentMessageCount())
 		msg := mockBot.LastSentMessage()
 		require.Contains(t, msg.Text, "Test Transport Category 999 Expenses")
-		require.Contains(t, msg.Text, "Total: $5.00")
+		require.Contains(t, msg.Text, "Total: S$5.00 SGD")
 		require.Contains(t, msg.Text, "Bus")
 	})
 
@@ -136,7 +136,7 @@ func TestHandleCategoryCore(t *testing.T) {
 		require.Equal(t, 1, mockBot.SentMessageCount())
 		msg := mockBot.LastSentMessage()
 		require.Contains(t, msg.Text, "Empty Category 999 Expenses")
-		require.Contains(t, msg.Text, "Total: $0.00")
+		require.Contains(t, msg.Text, "Total: nothing yet")
 		require.Contains(t, msg.Text, "No expenses found")
 	})
 
@@ -3,13 +3,17 @@ package bot
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
 	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
 	"gitlab.com/yelinaung/expense-bot/internal/telemetry"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -22,6 +26,32 @@ const (
 	periodLabelMonth = "Month"
 )
 
+// generateChart renders a category chart with the configured generator.
+func (b *Bot) generateChart(ctx context.Context, expenses []appmodels.Expense, period string) ([]byte, error) {
+	if b.chartGenerator != nil {
+		return b.chartGenerator(expenses, period)
+	}
+	return GenerateExpenseChart(expenses, period, b.categoryColors(ctx))
+}
+
+// categoryColors maps category names to the colors set with
+// /setcategorycolor. Charts fall back to derived colors when the
+// categories cannot be loaded.
+func (b *Bot) categoryColors(ctx context.Context) map[string]string {
+	categories, err := b.getCategoriesWithCache(ctx)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to load category colors for chart")
+		return nil
+	}
+	colors := make(map[string]string, len(categories))
+	for i := range categories {
+		if categories[i].Color != "" {
+			colors[categories[i].Name] = categories[i].Color
+		}
+	}
+	return colors
+}
+
 // handleChart handles the /chart command to generate visual expense breakdown charts.
 func (b *Bot) handleChart(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
 	b.handleChartCore(ctx, tgBot, update)
@@ -42,8 +72,9 @@ func (b *Bot) handleChartCore(ctx context.Context, tg TelegramAPI, update *model
 	args := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/chart"))
 	if args == "" {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:    chatID,
-			Text:      "❌ Please specify chart type.\n\nUsage: <code>/chart week</code> or <code>/chart month</code>",
+			ChatID: chatID,
+			Text: "❌ Please specify chart type.\n\nUsage: <code>/chart week</code>, <code>/chart month</code> " +
+				"or <code>/chart pace</code>" + seeHelp("chart"),
 			ParseMode: models.ParseModeHTML,
 		})
 		return
@@ -56,21 +87,34 @@ func (b *Bot) handleChartCore(ctx context.Context, tg TelegramAPI, update *model
 	case periodWeek:
 		startDate, endDate = getWeekDateRangeAt(current)
 		period = periodLabelWeek
-		title = fmt.Sprintf("Weekly Expenses (%s to %s)",
-			startDate.Format("Jan 2"), endDate.AddDate(0, 0, -1).Format("Jan 2, 2006"))
+		title = fmt.Sprintf("Weekly Expenses (%s)", formatWeekLabelWithYear(startDate, endDate, safeLoc))
 	case periodMonth:
 		startDate, endDate = getMonthDateRangeAt(current)
 		period = periodLabelMonth
 		title = fmt.Sprintf("Monthly Expenses (%s)", startDate.Format("January 2006"))
+	case periodPace:
+		release, ok := b.beginReport(ctx, tg, update.Message, "chart")
+		if !ok {
+			return
+		}
+		defer release()
+		b.sendPaceChart(ctx, tg, chatID, userID)
+		return
 	default:
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:    chatID,
-			Text:      "❌ Invalid chart type. Use <code>week</code> or <code>month</code>.",
+			Text:      "❌ Invalid chart type. Use <code>week</code>, <code>month</code> or <code>pace</code>.",
 			ParseMode: models.ParseModeHTML,
 		})
 		return
 	}
 
+	release, ok := b.beginReport(ctx, tg, update.Message, "chart")
+	if !ok {
+		return
+	}
+	defer release()
+
 	logger.Log.Info().
 		Int64("user_id", userID).
 		Str("period", period).
@@ -104,7 +148,15 @@ func (b *Bot) handleChartCore(ctx context.Context, tg TelegramAPI, update *model
 		attribute.String("chart.period", period),
 		attribute.Int("chart.expense_count", len(expenses)),
 	)
-	chartData, err := GenerateExpenseChart(expenses, period)
+	chartData, err := b.generateChart(ctx, expenses, period)
+	if errors.Is(err, errNoChartSpending) {
+		genSpan.End()
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("📊 Refunds cancel out your spending for %s.", strings.ToLower(period)),
+		})
+		return
+	}
 	if err != nil {
 		genSpan.RecordError(err)
 		genSpan.SetStatus(codes.Error, "chart generation failed")
@@ -132,8 +184,7 @@ func (b *Bot) handleChartCore(ctx context.Context, tg TelegramAPI, update *model
 	// Format period range for caption
 	var periodRange string
 	if period == periodLabelWeek {
-		periodRange = fmt.Sprintf("%s to %s",
-			startDate.Format("Jan 2"), endDate.AddDate(0, 0, -1).Format("Jan 2, 2006"))
+		periodRange = formatWeekLabelWithYear(startDate, endDate, safeLoc)
 	} else {
 		periodRange = startDate.Format("January 2006")
 	}
@@ -141,7 +192,7 @@ func (b *Bot) handleChartCore(ctx context.Context, tg TelegramAPI, update *model
 	// Send chart as document
 	filename := generateChartFilename(strings.ToLower(args), b.displayLocation, now)
 	caption := fmt.Sprintf("📊 <b>%s</b>\n\nTotal: $%s SGD\nCount: %d expenses\nPeriod: %s",
-		title, total.StringFixed(2), len(expenses), periodRange)
+		title, botfmt.FormatAmount(total, appmodels.DefaultCurrency), len(expenses), periodRange)
 
 	sendCtx, sendSpan := telemetry.StartSpan(
 		ctx, "telegram.send_document",
@@ -174,3 +225,114 @@ func (b *Bot) handleChartCore(ctx context.Context, tg TelegramAPI, update *model
 		Str("total", total.String()).
 		Msg("Chart generated successfully")
 }
+
+// sendPaceChart sends /chart pace: this month's daily spending in the
+// user's default currency against the pace that would end the month on
+// the sum of their budgets, or against the daily average without budgets.
+func (b *Bot) sendPaceChart(ctx context.Context, tg TelegramAPI, chatID, userID int64) {
+	sendFailed := func() {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   failedGenerateChartMsg,
+		})
+	}
+
+	start, end, _ := b.budgetPeriod(ctx, userID)
+	expenses, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, userID, start, end)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch expenses for pace chart")
+		sendFailed()
+		return
+	}
+	if len(expenses) == 0 {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "📊 No expenses found for month.",
+		})
+		return
+	}
+
+	currency := b.getUserDefaultCurrency(ctx, userID)
+	converter := b.newBudgetConverter()
+	unconverted := 0
+	pace := &paceChart{
+		Month: start,
+		Daily: aggregateDailyTotals(expenses, start, func(expense *appmodels.Expense) (decimal.Decimal, bool) {
+			amount, ok := converter.convertExpense(ctx, expense, currency)
+			if !ok {
+				unconverted++
+			}
+			return amount, ok
+		}),
+		Elapsed: b.now().In(start.Location()).Day(),
+	}
+	if goal, ok := b.monthlyBudgetGoal(ctx, converter, userID, currency); ok {
+		pace.Goal = goal
+	}
+
+	_, genSpan := telemetry.StartSpan(
+		ctx, "chart.generate",
+		attribute.String("chart.period", periodPace),
+		attribute.Int("chart.expense_count", len(expenses)),
+	)
+	chartData, err := GeneratePaceChart(pace)
+	if err != nil {
+		genSpan.RecordError(err)
+		genSpan.SetStatus(codes.Error, "chart generation failed")
+		genSpan.End()
+		logger.Log.Error().Err(err).Msg("Failed to generate pace chart")
+		sendFailed()
+		return
+	}
+	genSpan.SetAttributes(attribute.Int("chart.size_bytes", len(chartData)))
+	genSpan.End()
+
+	_, err = tg.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID: chatID,
+		Document: &models.InputFileUpload{
+			Filename: generateChartFilename(periodPace, start.Location(), b.now()),
+			Data:     bytes.NewReader(chartData),
+		},
+		Caption:   formatPaceCaption(pace, currency, unconverted),
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to send pace chart document")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to send chart. Please try again.",
+		})
+		return
+	}
+
+	logger.Log.Info().
+		Int64("user_id", userID).
+		Int("expense_count", len(expenses)).
+		Bool("has_goal", pace.Goal.IsPositive()).
+		Msg("Pace chart generated successfully")
+}
+
+// formatPaceCaption describes a pace chart, ending with where the month
+// is heading at the current run rate.
+func formatPaceCaption(pace *paceChart, currency string, unconverted int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📈 <b>Daily Spending (%s)</b>\n", pace.Month.Format("January 2006"))
+	fmt.Fprintf(&sb, "\nSpent: %s in %d of %d days",
+		botfmt.Money(pace.spent(), currency), pace.Elapsed, len(pace.Daily))
+
+	projected := pace.projected()
+	if pace.Goal.IsPositive() {
+		fmt.Fprintf(&sb, "\nBudget pace: %s/day of %s",
+			botfmt.Money(pace.dailyPace(), currency), botfmt.Money(pace.Goal, currency))
+		fmt.Fprintf(&sb, "\nProjected month end: <b>%s</b> (%s%% of your budgets)",
+			botfmt.Money(projected, currency), percentOf(projected, pace.Goal))
+	} else {
+		fmt.Fprintf(&sb, "\nDaily average: %s", botfmt.Money(pace.dailyAverage(), currency))
+		fmt.Fprintf(&sb, "\nProjected month end: <b>%s</b>", botfmt.Money(projected, currency))
+		sb.WriteString("\n\nSet a budget with <code>/budget set</code> to see your pace.")
+	}
+	if unconverted > 0 {
+		fmt.Fprintf(&sb, "\n\n⚠️ %d expenses without an exchange rate are left out.", unconverted)
+	}
+	return sb.String()
+}
@@ -125,6 +125,21 @@ func TestHandleChartCore(t *testing.T) {
 		require.Contains(t, doc.Caption, fmt.Sprintf("%d expenses", totalMonthlyExpenseCount))
 	})
 
+	t.Run("generates pace chart without budgets", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		update := mocks.CommandUpdate(chatID, userID, "/chart pace")
+
+		b.handleChartCore(ctx, mockBot, update)
+
+		require.Equal(t, 1, mockBot.SentDocumentCount())
+		doc := mockBot.LastSentDocument()
+		require.Contains(t, doc.Filename, "chart_pace_")
+		require.Contains(t, doc.Caption, "Daily Spending")
+		require.Contains(t, doc.Caption, "Daily average:")
+		require.Contains(t, doc.Caption, "Projected month end:")
+		require.NotContains(t, doc.Caption, "Budget pace")
+	})
+
 	t.Run("sends failure message when document send fails", func(t *testing.T) {
 		mockBot := mocks.NewMockBot()
 		mockBot.SendDocumentError = errors.New("telegram send failed")
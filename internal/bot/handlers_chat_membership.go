@@ -0,0 +1,173 @@
+package bot
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// unclaimedChatGrace is how long the bot stays in a group a stranger added
+// it to, waiting for an authorized user to use it there.
+const unclaimedChatGrace = time.Hour
+
+const (
+	groupIntroMsg = "👋 Hi! I track expenses for the people who use me here.\n\n" +
+		"• Send an amount and a description, like <code>5.50 Coffee</code>\n" +
+		"• <code>/chatcategory Food - Dining Out</code> sets a default category for this chat\n" +
+		"• <code>/archivechat</code> in a private chat sends your monthly CSV here\n\n" +
+		"Send /help for everything else."
+	groupPendingMsg = "👋 Hi! I only work for approved users. " +
+		"If one of them sends a command here within an hour I'll stay; otherwise I'll leave."
+)
+
+// botJoined reports whether a my_chat_member change added the bot to the
+// chat, and botRemoved whether it took the bot out.
+func botJoined(change *models.ChatMemberUpdated) bool {
+	return !chatMemberPresent(change.OldChatMember.Type) && chatMemberPresent(change.NewChatMember.Type)
+}
+
+func botRemoved(change *models.ChatMemberUpdated) bool {
+	return chatMemberPresent(change.OldChatMember.Type) && !chatMemberPresent(change.NewChatMember.Type)
+}
+
+// chatMemberPresent reports whether a member with this status is in the
+// chat. Restricted members may be too, but the bot cannot post then.
+func chatMemberPresent(status models.ChatMemberType) bool {
+	switch status {
+	case models.ChatMemberTypeOwner, models.ChatMemberTypeAdministrator, models.ChatMemberTypeMember:
+		return true
+	default:
+		return false
+	}
+}
+
+// handleMyChatMember handles my_chat_member updates, which Telegram sends
+// when the bot is added to or removed from a chat.
+func (b *Bot) handleMyChatMember(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleMyChatMemberCore(ctx, tgBot, update)
+}
+
+// handleMyChatMemberCore stays in a group when an authorized user added it
+// or administers it and leaves chats that are not allowed. Other groups are
+// pending until claimed or left after unclaimedChatGrace. Removal marks the
+// chat left so scheduled deliveries skip it. In private chats it pauses or
+// resumes scheduled messages when the user blocks or unblocks the bot.
+func (b *Bot) handleMyChatMemberCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	change := update.MyChatMember
+	if change == nil {
+		return
+	}
+	if change.Chat.Type == models.ChatTypePrivate {
+		b.handlePrivateChatMember(ctx, change)
+		return
+	}
+	chatID := change.Chat.ID
+
+	switch {
+	case botRemoved(change):
+		if err := b.chatRepo.MarkLeft(ctx, chatID); err != nil {
+			logger.Log.Error().Err(err).Int64("chat_id", chatID).Msg("Failed to mark chat left")
+			return
+		}
+		logger.Log.Info().Int64("chat_id", chatID).Str("status", string(change.NewChatMember.Type)).
+			Msg("Bot removed from chat")
+	case botJoined(change):
+		b.handleBotJoined(ctx, tg, change)
+	}
+}
+
+// handleBotJoined decides what to do with a chat the bot was just added to.
+func (b *Bot) handleBotJoined(ctx context.Context, tg TelegramAPI, change *models.ChatMemberUpdated) {
+	chatID := change.Chat.ID
+	if !b.isChatAllowed(chatID) {
+		logger.Log.Warn().Int64("chat_id", chatID).Msg("Added to a disallowed chat; leaving")
+		b.leaveChat(ctx, tg, chatID)
+		return
+	}
+
+	status := appmodels.ChatStatusPending
+	text := groupPendingMsg
+	if b.isAuthorized(ctx, change.From.ID, change.From.Username) || b.hasAuthorizedAdmin(ctx, tg, chatID) {
+		status = appmodels.ChatStatusActive
+		text = groupIntroMsg
+	}
+	if err := b.chatRepo.RecordJoin(ctx, chatID, change.From.ID, status); err != nil {
+		logger.Log.Error().Err(err).Int64("chat_id", chatID).Msg("Failed to record chat join")
+		return
+	}
+	logger.Log.Info().Int64("chat_id", chatID).Str("status", string(status)).
+		Str("added_by_hash", logger.HashUserID(change.From.ID)).Msg("Bot added to chat")
+
+	if change.Chat.Type == models.ChatTypeChannel {
+		return
+	}
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: text, ParseMode: models.ParseModeHTML})
+}
+
+// hasAuthorizedAdmin reports whether an authorized user administers the
+// chat. Lookup failures count as no.
+func (b *Bot) hasAuthorizedAdmin(ctx context.Context, tg TelegramAPI, chatID int64) bool {
+	admins, err := tg.GetChatAdministrators(ctx, &bot.GetChatAdministratorsParams{ChatID: chatID})
+	if err != nil {
+		logger.Log.Warn().Err(err).Int64("chat_id", chatID).Msg("Failed to list chat administrators")
+		return false
+	}
+	for i := range admins {
+		var user *models.User
+		switch {
+		case admins[i].Owner != nil:
+			user = admins[i].Owner.User
+		case admins[i].Administrator != nil:
+			user = &admins[i].Administrator.User
+		}
+		if user != nil && !user.IsBot && b.isAuthorized(ctx, user.ID, user.Username) {
+			return true
+		}
+	}
+	return false
+}
+
+// claimChat makes a pending group active once an authorized user uses the
+// bot there.
+func (b *Bot) claimChat(ctx context.Context, chatID int64) {
+	if !isGroupChat(chatID) || b.chatRepo == nil {
+		return
+	}
+	claimed, err := b.chatRepo.ClaimPending(ctx, chatID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Int64("chat_id", chatID).Msg("Failed to claim chat")
+		return
+	}
+	if claimed {
+		logger.Log.Info().Int64("chat_id", chatID).Msg("Pending chat claimed")
+	}
+}
+
+// leaveUnclaimedChats leaves the groups that stayed pending for longer than
+// unclaimedChatGrace.
+func (b *Bot) leaveUnclaimedChats(ctx context.Context, tg TelegramAPI) {
+	chatIDs, err := b.chatRepo.GetPendingJoinedBefore(ctx, b.now().Add(-unclaimedChatGrace))
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch unclaimed chats")
+		return
+	}
+	for _, chatID := range chatIDs {
+		logger.Log.Info().Int64("chat_id", chatID).Msg("Leaving chat no authorized user claimed")
+		b.leaveChat(ctx, tg, chatID)
+	}
+}
+
+// leaveChat leaves a chat and marks it left. The my_chat_member update that
+// follows finds it left already.
+func (b *Bot) leaveChat(ctx context.Context, tg TelegramAPI, chatID int64) {
+	if _, err := tg.LeaveChat(ctx, &bot.LeaveChatParams{ChatID: chatID}); err != nil {
+		logger.Log.Warn().Err(err).Int64("chat_id", chatID).Msg("Failed to leave chat")
+	}
+	if err := b.chatRepo.MarkLeft(ctx, chatID); err != nil {
+		logger.Log.Error().Err(err).Int64("chat_id", chatID).Msg("Failed to mark chat left")
+	}
+}
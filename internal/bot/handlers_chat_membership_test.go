@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// myChatMemberUpdate builds the update Telegram sends when from changes the
+// bot's membership in a group from one status to another.
+func myChatMemberUpdate(chatID, fromID int64, from, to tgmodels.ChatMemberType) *tgmodels.Update {
+	return &tgmodels.Update{MyChatMember: &tgmodels.ChatMemberUpdated{
+		Chat:          tgmodels.Chat{ID: chatID, Type: tgmodels.ChatTypeSupergroup},
+		From:          tgmodels.User{ID: fromID},
+		OldChatMember: tgmodels.ChatMember{Type: from},
+		NewChatMember: tgmodels.ChatMember{Type: to},
+	}}
+}
+
+func TestBotMembershipTransitions(t *testing.T) {
+	t.Parallel()
+
+	added := myChatMemberUpdate(-1, 1, tgmodels.ChatMemberTypeLeft, tgmodels.ChatMemberTypeMember).MyChatMember
+	require.True(t, botJoined(added))
+	require.False(t, botRemoved(added))
+
+	kicked := myChatMemberUpdate(-1, 1, tgmodels.ChatMemberTypeMember, tgmodels.ChatMemberTypeBanned).MyChatMember
+	require.True(t, botRemoved(kicked))
+	require.False(t, botJoined(kicked))
+
+	promoted := myChatMemberUpdate(-1, 1, tgmodels.ChatMemberTypeMember, tgmodels.ChatMemberTypeAdministrator)
+	require.False(t, botJoined(promoted.MyChatMember))
+	require.False(t, botRemoved(promoted.MyChatMember))
+}
+
+func TestHandleMyChatMember(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	const authorizedID = int64(123456)
+	const strangerID = int64(425201)
+	status := func(chatID int64) appmodels.ChatStatus {
+		t.Helper()
+		got, err := b.chatRepo.GetStatus(ctx, chatID)
+		require.NoError(t, err)
+		return got
+	}
+	join := func(mockBot *mocks.MockBot, chatID, fromID int64) {
+		b.handleMyChatMemberCore(ctx, mockBot,
+			myChatMemberUpdate(chatID, fromID, tgmodels.ChatMemberTypeLeft, tgmodels.ChatMemberTypeMember))
+	}
+
+	t.Run("added by an authorized user", func(t *testing.T) {
+		chatID := int64(-425001)
+		mockBot := mocks.NewMockBot()
+		join(mockBot, chatID, authorizedID)
+
+		require.Equal(t, appmodels.ChatStatusActive, status(chatID))
+		require.Equal(t, groupIntroMsg, mockBot.LastSentMessage().Text)
+		require.Empty(t, mockBot.LeftChats)
+	})
+
+	t.Run("added by a stranger in a group an authorized user runs", func(t *testing.T) {
+		chatID := int64(-425002)
+		mockBot := mocks.NewMockBot()
+		mockBot.ChatAdministrators = []tgmodels.ChatMember{{
+			Type:  tgmodels.ChatMemberTypeOwner,
+			Owner: &tgmodels.ChatMemberOwner{User: &tgmodels.User{ID: authorizedID}},
+		}}
+		join(mockBot, chatID, strangerID)
+
+		require.Equal(t, appmodels.ChatStatusActive, status(chatID))
+		require.Equal(t, groupIntroMsg, mockBot.LastSentMessage().Text)
+	})
+
+	t.Run("added by a stranger", func(t *testing.T) {
+		chatID := int64(-425003)
+		mockBot := mocks.NewMockBot()
+		mockBot.GetChatAdministratorsError = errors.New("forbidden")
+		join(mockBot, chatID, strangerID)
+
+		require.Equal(t, appmodels.ChatStatusPending, status(chatID))
+		require.Equal(t, groupPendingMsg, mockBot.LastSentMessage().Text)
+
+		b.nowFunc = func() time.Time { return time.Now().Add(unclaimedChatGrace / 2) }
+		b.leaveUnclaimedChats(ctx, mockBot)
+		require.Empty(t, mockBot.LeftChats, "still within the grace period")
+
+		b.nowFunc = func() time.Time { return time.Now().Add(unclaimedChatGrace + time.Minute) }
+		b.leaveUnclaimedChats(ctx, mockBot)
+		require.Equal(t, []any{chatID}, mockBot.LeftChats)
+		require.Equal(t, appmodels.ChatStatusLeft, status(chatID))
+		b.nowFunc = nil
+	})
+
+	t.Run("stranger's chat claimed by an authorized user", func(t *testing.T) {
+		chatID := int64(-425004)
+		mockBot := mocks.NewMockBot()
+		join(mockBot, chatID, strangerID)
+		require.Equal(t, appmodels.ChatStatusPending, status(chatID))
+
+		b.claimChat(ctx, chatID)
+		require.Equal(t, appmodels.ChatStatusActive, status(chatID))
+
+		b.nowFunc = func() time.Time { return time.Now().Add(2 * unclaimedChatGrace) }
+		b.leaveUnclaimedChats(ctx, mockBot)
+		require.NotContains(t, mockBot.LeftChats, any(chatID))
+		b.nowFunc = nil
+	})
+
+	t.Run("kicked", func(t *testing.T) {
+		chatID := int64(-425005)
+		mockBot := mocks.NewMockBot()
+		join(mockBot, chatID, authorizedID)
+		require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: authorizedID, FirstName: "Owner"}))
+		require.NoError(t, b.userRepo.SetArchiveChatID(ctx, authorizedID, chatID))
+
+		mockBot = mocks.NewMockBot()
+		b.handleMyChatMemberCore(ctx, mockBot,
+			myChatMemberUpdate(chatID, authorizedID, tgmodels.ChatMemberTypeMember, tgmodels.ChatMemberTypeBanned))
+		require.Equal(t, appmodels.ChatStatusLeft, status(chatID))
+		require.Zero(t, mockBot.SentMessageCount())
+
+		users, err := b.userRepo.GetAuthorizedUsersForArchive(ctx, []int64{authorizedID}, nil)
+		require.NoError(t, err)
+		require.Empty(t, users, "the monthly archive skips the chat")
+
+		join(mockBot, chatID, authorizedID)
+		require.Equal(t, appmodels.ChatStatusActive, status(chatID), "added back")
+	})
+
+	t.Run("added to a chat that is not allowed", func(t *testing.T) {
+		chatID := int64(-425006)
+		b.cfg.AllowedChatIDs = []int64{-425001}
+		defer func() { b.cfg.AllowedChatIDs = nil }()
+
+		mockBot := mocks.NewMockBot()
+		join(mockBot, chatID, authorizedID)
+		require.Equal(t, []any{chatID}, mockBot.LeftChats)
+		require.Equal(t, appmodels.ChatStatusLeft, status(chatID))
+		require.Zero(t, mockBot.SentMessageCount())
+	})
+
+	t.Run("private chats send nothing", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		update := myChatMemberUpdate(authorizedID, authorizedID, tgmodels.ChatMemberTypeMember, tgmodels.ChatMemberTypeBanned)
+		update.MyChatMember.Chat.Type = tgmodels.ChatTypePrivate
+		b.handleMyChatMemberCore(ctx, mockBot, update)
+		require.Zero(t, mockBot.SentMessageCount())
+	})
+}
@@ -0,0 +1,150 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+const (
+	chatCategoryOff      = "off"
+	chatCategoryUsageMsg = "Usage: <code>/chatcategory &lt;category&gt;</code> or " +
+		"<code>/chatcategory off</code>"
+	chatCategoryFailedMsg = "❌ Failed to update the chat category. Please try again."
+	chatCategoryNoneMsg   = "This chat has no default category.\n\n" + chatCategoryUsageMsg
+)
+
+// chatDefaultCategoryID returns the default category of a group chat, or 0
+// when it has none. Private chats never have one.
+func (b *Bot) chatDefaultCategoryID(ctx context.Context, chatID int64) int {
+	if !isGroupChat(chatID) || b.chatRepo == nil {
+		return 0
+	}
+	categoryID, err := b.chatRepo.GetDefaultCategoryID(ctx, chatID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Log.Warn().Err(err).Int64("chat_id", chatID).Msg("Failed to load chat default category")
+		}
+		return 0
+	}
+	return categoryID
+}
+
+// isChatAdmin reports whether a user may change a group chat's settings:
+// the chat's creator or administrators, or a configured superadmin.
+func (b *Bot) isChatAdmin(ctx context.Context, tg TelegramAPI, chatID, userID int64, username string) bool {
+	if b.cfg != nil && b.cfg.IsSuperAdmin(userID, username) {
+		return true
+	}
+	member, err := tg.GetChatMember(ctx, &bot.GetChatMemberParams{ChatID: chatID, UserID: userID})
+	if err != nil {
+		logger.Log.Warn().Err(err).Int64("chat_id", chatID).Msg("Failed to look up chat member")
+		return false
+	}
+	return member.Type == models.ChatMemberTypeOwner || member.Type == models.ChatMemberTypeAdministrator
+}
+
+// handleChatCategory handles the /chatcategory command.
+func (b *Bot) handleChatCategory(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleChatCategoryCore(ctx, tgBot, update)
+}
+
+// handleChatCategoryCore is the testable implementation of
+// handleChatCategory. It shows a group chat's default category, and lets
+// group admins set or clear it.
+func (b *Bot) handleChatCategoryCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	if !isGroupChat(chatID) {
+		send("ℹ️ /chatcategory only works in group chats.")
+		return
+	}
+
+	args := extractCommandArgs(update.Message.Text, "/chatcategory")
+	if args == "" {
+		send(b.describeChatCategory(ctx, chatID))
+		return
+	}
+
+	from := update.Message.From
+	if !b.isChatAdmin(ctx, tg, chatID, from.ID, from.Username) {
+		send("⛔ Only group admins can change the chat category.")
+		return
+	}
+
+	if strings.EqualFold(args, chatCategoryOff) {
+		if _, err := b.chatRepo.ClearDefaultCategory(ctx, chatID); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to clear chat default category")
+			send(chatCategoryFailedMsg)
+			return
+		}
+		send("✅ Expenses in this chat no longer have a default category.")
+		return
+	}
+
+	categories, err := b.visibleCategories(ctx, from.ID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for chat category")
+		send(failedFetchCategoriesMsg)
+		return
+	}
+	_, category := findCategoryByName(categories, args)
+	if category == nil {
+		send(fmt.Sprintf("❌ Category \"%s\" not found. Use /categories to see the list.",
+			botfmt.EscapeHTML(echoText(args))))
+		return
+	}
+
+	if err := b.chatRepo.SetDefaultCategory(ctx, chatID, category.ID); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to set chat default category")
+		send(chatCategoryFailedMsg)
+		return
+	}
+
+	logger.Log.Info().
+		Int64("chat_id", chatID).
+		Str("user_hash", logger.HashUserID(from.ID)).
+		Int("category_id", category.ID).
+		Msg("Chat default category set")
+	send(fmt.Sprintf("✅ Expenses logged in this chat now default to <b>%s</b>.",
+		botfmt.EscapeHTML(category.Name)))
+}
+
+// describeChatCategory renders a group chat's current default category.
+func (b *Bot) describeChatCategory(ctx context.Context, chatID int64) string {
+	categoryID := b.chatDefaultCategoryID(ctx, chatID)
+	if categoryID == 0 {
+		return chatCategoryNoneMsg
+	}
+
+	categories, err := b.getCategoriesWithCache(ctx)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for chat category")
+		return failedFetchCategoriesMsg
+	}
+	for i := range categories {
+		if categories[i].ID == categoryID {
+			return fmt.Sprintf("📂 Expenses logged in this chat default to <b>%s</b>.\n\n%s",
+				botfmt.EscapeHTML(categories[i].Name), chatCategoryUsageMsg)
+		}
+	}
+	return chatCategoryNoneMsg
+}
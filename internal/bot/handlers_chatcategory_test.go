@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestIsChatAdmin(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{cfg: &config.Config{WhitelistedUserIDs: []int64{1}}}
+	mockBot := mocks.NewMockBot()
+	mockBot.ChatMemberStatus = map[int64]models.ChatMemberType{
+		2: models.ChatMemberTypeOwner,
+		3: models.ChatMemberTypeAdministrator,
+	}
+	ctx := context.Background()
+
+	require.True(t, b.isChatAdmin(ctx, mockBot, -10, 1, ""), "superadmin")
+	require.True(t, b.isChatAdmin(ctx, mockBot, -10, 2, ""))
+	require.True(t, b.isChatAdmin(ctx, mockBot, -10, 3, ""))
+	require.False(t, b.isChatAdmin(ctx, mockBot, -10, 4, ""))
+
+	mockBot.GetChatMemberError = errors.New("telegram down")
+	require.False(t, b.isChatAdmin(ctx, mockBot, -10, 3, ""))
+}
+
+func TestHandleChatCategoryCore_PrivateChat(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{}
+	mockBot := mocks.NewMockBot()
+	b.handleChatCategoryCore(context.Background(), mockBot, mocks.CommandUpdate(5, 5, "/chatcategory Food - Grocery"))
+	require.Contains(t, mockBot.LastSentMessage().Text, "only works in group chats")
+	require.Zero(t, b.chatDefaultCategoryID(context.Background(), 5))
+}
+
+func TestHandleChatCategoryCore_NoSender(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{}
+	mockBot := mocks.NewMockBot()
+	update := mocks.CommandUpdate(-10, 5, "/chatcategory off")
+	update.Message.From = nil
+	b.handleChatCategoryCore(context.Background(), mockBot, update)
+	require.Zero(t, mockBot.SentMessageCount())
+}
+
+func TestChatCategory(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	chatID := int64(-387001)
+	adminID, memberID := int64(387001), int64(387002)
+	for _, id := range []int64{adminID, memberID} {
+		require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: id, FirstName: "Group"}))
+	}
+
+	mockBot := mocks.NewMockBot()
+	mockBot.ChatMemberStatus = map[int64]models.ChatMemberType{adminID: models.ChatMemberTypeAdministrator}
+	command := func(userID int64, text string) string {
+		b.handleChatCategoryCore(ctx, mockBot, mocks.CommandUpdate(chatID, userID, text))
+		return mockBot.LastSentMessage().Text
+	}
+	add := func(chat int64, text string) *appmodels.Expense {
+		b.handleAddCore(ctx, mockBot, mocks.CommandUpdate(chat, memberID, text))
+		expenses, err := b.expenseRepo.GetByUserID(ctx, memberID, 1)
+		require.NoError(t, err)
+		require.Len(t, expenses, 1)
+		require.NotNil(t, expenses[0].Category)
+		return &expenses[0]
+	}
+
+	require.Contains(t, command(memberID, "/chatcategory"), "no default category")
+	require.Contains(t, command(memberID, "/chatcategory Food - Grocery"), "Only group admins")
+	require.Contains(t, command(adminID, "/chatcategory Nope"), "not found")
+	require.Contains(t, command(adminID, "/chatcategory food - grocery"), foodGroceryCatMatch)
+	require.Contains(t, command(memberID, "/chatcategory"), foodGroceryCatMatch)
+
+	t.Run("chat default applies when no category is named", func(t *testing.T) {
+		require.Equal(t, foodGroceryCatMatch, add(chatID, "/add 5 Apples").Category.Name)
+	})
+
+	t.Run("named category wins", func(t *testing.T) {
+		require.Equal(t, "Transportation", add(chatID, "/add 5 Taxi [Transportation]").Category.Name)
+	})
+
+	t.Run("private chats ignore the group default", func(t *testing.T) {
+		require.NotEqual(t, foodGroceryCatMatch, add(memberID, "/add 5 Pears").Category.Name)
+	})
+
+	t.Run("off clears the default", func(t *testing.T) {
+		require.Contains(t, command(adminID, "/chatcategory off"), "no longer")
+		require.NotEqual(t, foodGroceryCatMatch, add(chatID, "/add 5 Plums").Category.Name)
+	})
+}
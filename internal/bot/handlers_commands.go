@@ -3,19 +3,23 @@ package bot
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
 	"gitlab.com/yelinaung/expense-bot/internal/database"
-	"gitlab.com/yelinaung/expense-bot/internal/gemini"
 	"gitlab.com/yelinaung/expense-bot/internal/logger"
 	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
 	"gitlab.com/yelinaung/expense-bot/internal/repository"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
 	"go.opentelemetry.io/otel/attribute"
 	otelmetric "go.opentelemetry.io/otel/metric"
 )
@@ -48,7 +52,7 @@ func formatGreeting(firstName string) string {
 	if firstName == "" {
 		return ""
 	}
-	return ", " + escapeHTML(firstName)
+	return ", " + botfmt.EscapeHTML(firstName)
 }
 
 // handleStart handles the /start command.
@@ -80,93 +84,21 @@ I'm your personal expense tracker bot. I help you track your daily expenses.
 Use /help to see all available commands.`,
 		formatGreeting(firstName))
 
-	logger.Log.Debug().Int64("chat_id", update.Message.Chat.ID).Msg("Sending /start response")
-	_, err := tg.SendMessage(ctx, &bot.SendMessageParams{
+	params := &bot.SendMessageParams{
 		ChatID:    update.Message.Chat.ID,
 		Text:      text,
 		ParseMode: models.ParseModeHTML,
-	})
-	if err != nil {
-		logger.Log.Error().Err(err).Msg("Failed to send /start response")
 	}
-}
-
-// handleHelp handles the /help command.
-func (b *Bot) handleHelp(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
-	b.handleHelpCore(ctx, tgBot, update)
-}
-
-// handleHelpCore is the testable implementation of handleHelp.
-func (b *Bot) handleHelpCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
-	if update.Message == nil {
-		return
+	if update.Message.From != nil && !isGroupChat(update.Message.Chat.ID) &&
+		b.needsCategorySet(ctx, update.Message.From.ID) {
+		params.Text += categorySetPromptMsg
+		params.ReplyMarkup = buildCategorySetKeyboard()
 	}
 
-	text := `📚 <b>Available Commands</b>
-
-<b>Expense Tracking:</b>
-• <code>/add &lt;amount&gt; &lt;description&gt; [category]</code> - Add an expense
-• Just send a message like <code>5.50 Coffee</code> to quickly add
-• Use currency: <code>$10 Lunch</code>, <code>€5 Coffee</code>, <code>50 THB Taxi</code>
-• Send a receipt photo to extract expenses automatically
-• Send a voice message like <code>spent five fifty on coffee</code>
-
-<b>Managing Expenses:</b>
-• <code>/edit &lt;id&gt; &lt;amount&gt; &lt;description&gt; [category]</code> - Edit an expense
-• <code>/delete &lt;id&gt;</code> - Delete an expense
-
-<b>Viewing Expenses:</b>
-• <code>/list</code> - Show recent expenses
-• <code>/today</code> - Show today's expenses
-• <code>/week</code> - Show this week's expenses
-• <code>/category &lt;name&gt;</code> - Filter expenses by category
-• <code>/review</code> - Review recent spending as worth it or not worth it
-
-<b>Reports:</b>
-• <code>/report week</code> - Generate weekly CSV report
-• <code>/report month</code> - Generate monthly CSV report
-• <code>/chart week</code> - Generate weekly expense chart
-• <code>/chart month</code> - Generate monthly expense chart
-• <code>/habit</code> - Show this month's spending reflection
-• <code>/habit week</code> or <code>/habit 90d</code> - Change reflection period
-
-<b>Categories:</b>
-• <code>/categories</code> - List all categories
-• <code>/addcategory &lt;name&gt;</code> - Create a new category
-• <code>/renamecategory Old -&gt; New</code> - Rename a category
-• <code>/deletecategory &lt;name&gt;</code> - Delete a category
-
-<b>Currency:</b>
-• <code>/currency</code> - Show your default currency
-• <code>/setcurrency &lt;code&gt;</code> - Set default currency (e.g., USD, EUR, THB)
-
-<b>Timezone:</b>
-• <code>/timezone</code> - Show your timezone
-• <code>/settimezone &lt;tz&gt;</code> - Set timezone (e.g., Asia/Tokyo, America/New_York)
-
-<b>Tags:</b>
-• Add tags inline: <code>5.50 Coffee #work #meeting</code>
-• <code>/tag &lt;id&gt; #tag1 [#tag2] ...</code> - Add tags to expense
-• <code>/untag &lt;id&gt; #tag</code> - Remove tag from expense
-• <code>/tags</code> - List all tags
-• <code>/tags #name</code> - Filter expenses by tag
-
-<b>Admin:</b>
-• <code>/approve &lt;user_id&gt;</code> or <code>/approve @username</code> - Approve a user
-• <code>/revoke &lt;user_id&gt;</code> or <code>/revoke @username</code> - Revoke a user
-• <code>/users</code> - List all authorized users
-
-<b>Other:</b>
-• <code>/help</code> - Show this help message`
-
-	logger.Log.Debug().Int64("chat_id", update.Message.Chat.ID).Msg("Sending /help response")
-	_, err := tg.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:    update.Message.Chat.ID,
-		Text:      text,
-		ParseMode: models.ParseModeHTML,
-	})
+	logger.Log.Debug().Int64("chat_id", update.Message.Chat.ID).Msg("Sending /start response")
+	_, err := tg.SendMessage(ctx, params)
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("Failed to send /help response")
+		logger.Log.Error().Err(err).Msg("Failed to send /start response")
 	}
 }
 
@@ -177,11 +109,11 @@ func (b *Bot) handleCategories(ctx context.Context, tgBot *bot.Bot, update *mode
 
 // handleCategoriesCore is the testable implementation of handleCategories.
 func (b *Bot) handleCategoriesCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
-	if update.Message == nil {
+	if update.Message == nil || update.Message.From == nil {
 		return
 	}
 
-	categories, err := b.getCategoriesWithCache(ctx)
+	categories, err := b.visibleCategories(ctx, update.Message.From.ID)
 	if err != nil {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: update.Message.Chat.ID,
@@ -201,7 +133,8 @@ func (b *Bot) handleCategoriesCore(ctx context.Context, tg TelegramAPI, update *
 	var sb strings.Builder
 	sb.WriteString("📁 <b>Expense Categories</b>\n\n")
 	for i := range categories {
-		fmt.Fprintf(&sb, "%d. %s\n", i+1, escapeHTML(categories[i].Name))
+		fmt.Fprintf(&sb, "%d. %s %s\n", i+1,
+			colorSquare(categoryColor(categories[i].Name, categories[i].Color)), botfmt.EscapeHTML(categories[i].Name))
 	}
 
 	logger.Log.Debug().Int64("chat_id", update.Message.Chat.ID).Msg("Sending /categories response")
@@ -215,6 +148,35 @@ func (b *Bot) handleCategoriesCore(ctx context.Context, tg TelegramAPI, update *
 	}
 }
 
+// categoryNameError returns why name cannot be used for a new category, or
+// "" when it can.
+func categoryNameError(name string) string {
+	// Reject category names containing control characters.
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return "❌ Category name cannot contain control characters (newlines, tabs, etc.)."
+		}
+	}
+
+	// Reject category names that are too long.
+	if len(name) > appmodels.MaxCategoryNameLength {
+		return fmt.Sprintf("❌ Category name is too long (max %d characters).", appmodels.MaxCategoryNameLength)
+	}
+	return ""
+}
+
+// categoryNameWarning returns a note for a category name the parser may
+// confuse with an amount, or "" when there is none. Such names still
+// match literally at the end of a message, but brackets are unambiguous.
+func categoryNameWarning(name string) string {
+	first, _ := utf8.DecodeRuneInString(name)
+	if !unicode.IsDigit(first) {
+		return ""
+	}
+	return fmt.Sprintf("\n\n⚠️ Names starting with a number can look like an amount. "+
+		"Use brackets to pick it for sure, e.g. <code>12.50 Lunch [%s]</code>", botfmt.EscapeHTML(name))
+}
+
 // handleAddCategory handles the /addcategory command to create a new category.
 func (b *Bot) handleAddCategory(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
 	b.handleAddCategoryCore(ctx, tgBot, update)
@@ -222,7 +184,7 @@ func (b *Bot) handleAddCategory(ctx context.Context, tgBot *bot.Bot, update *mod
 
 // handleAddCategoryCore is the testable implementation of handleAddCategory.
 func (b *Bot) handleAddCategoryCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
-	if update.Message == nil {
+	if update.Message == nil || update.Message.From == nil {
 		return
 	}
 
@@ -232,50 +194,47 @@ func (b *Bot) handleAddCategoryCore(ctx context.Context, tg TelegramAPI, update
 
 	if args == "" {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:    chatID,
-			Text:      "❌ Please provide a category name.\n\nUsage: <code>/addcategory Food - Dining Out</code>",
+			ChatID: chatID,
+			Text: "❌ Please provide a category name.\n\nUsage: <code>/addcategory Food - Dining Out</code>" +
+				seeHelp("addcategory"),
 			ParseMode: models.ParseModeHTML,
 		})
 		return
 	}
 
-	// Reject category names containing control characters.
-	for _, r := range args {
-		if unicode.IsControl(r) {
-			_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID: chatID,
-				Text:   "❌ Category name cannot contain control characters (newlines, tabs, etc.).",
-			})
-			return
-		}
+	if errText := categoryNameError(args); errText != "" {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   errText,
+		})
+		return
 	}
 
-	// Reject category names that are too long.
-	if len(args) > appmodels.MaxCategoryNameLength {
+	userID := update.Message.From.ID
+	if _, err := b.findUserCategory(ctx, userID, args); err == nil {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
-			Text:   fmt.Sprintf("❌ Category name is too long (max %d characters).", appmodels.MaxCategoryNameLength),
+			Text:   fmt.Sprintf("❌ Failed to create category '%s'. It already exists.", echoText(args)),
 		})
 		return
 	}
 
-	cat, err := b.categoryRepo.Create(ctx, args)
+	cat, err := b.createUserCategory(ctx, userID, args)
 	if err != nil {
 		logger.Log.Error().Err(err).Str("name", args).Msg("Failed to create category")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
-			Text:   fmt.Sprintf("❌ Failed to create category '%s'. It may already exist.", args),
+			Text:   fmt.Sprintf("❌ Failed to create category '%s'. It may already exist.", echoText(args)),
 		})
 		return
 	}
 
-	b.invalidateCategoryCache()
-
 	logger.Log.Info().Int("category_id", cat.ID).Str("name", cat.Name).Msg("Category created")
 
 	_, err = tg.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:    chatID,
-		Text:      fmt.Sprintf("✅ Category '<b>%s</b>' created.", escapeHTML(cat.Name)),
+		ChatID: chatID,
+		Text: fmt.Sprintf("✅ Category '<b>%s</b>' created.", botfmt.EscapeHTML(cat.Name)) +
+			categoryNameWarning(cat.Name),
 		ParseMode: models.ParseModeHTML,
 	})
 	if err != nil {
@@ -290,7 +249,7 @@ func (b *Bot) handleRenameCategory(ctx context.Context, tgBot *bot.Bot, update *
 
 // handleRenameCategoryCore is the testable implementation of handleRenameCategory.
 func (b *Bot) handleRenameCategoryCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
-	if update.Message == nil {
+	if update.Message == nil || update.Message.From == nil {
 		return
 	}
 
@@ -314,8 +273,9 @@ func (b *Bot) handleRenameCategoryCore(ctx context.Context, tg TelegramAPI, upda
 
 	if oldName == "" || newName == "" {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:    chatID,
-			Text:      "❌ Both old and new category names are required.\n\nUsage: <code>/renamecategory Old Name -&gt; New Name</code>",
+			ChatID: chatID,
+			Text: "❌ Both old and new category names are required.\n\n" +
+				"Usage: <code>/renamecategory Old Name -&gt; New Name</code>" + seeHelp("renamecategory"),
 			ParseMode: models.ParseModeHTML,
 		})
 		return
@@ -342,17 +302,26 @@ func (b *Bot) handleRenameCategoryCore(ctx context.Context, tg TelegramAPI, upda
 	}
 
 	// Find existing category by old name.
-	cat, err := b.categoryRepo.GetByName(ctx, oldName)
+	userID := update.Message.From.ID
+	cat, err := b.findUserCategory(ctx, userID, oldName)
 	if err != nil {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
-			Text:   fmt.Sprintf("❌ Category '%s' not found.\n\nUse /categories to see all categories.", oldName),
+			Text:   fmt.Sprintf("❌ Category '%s' not found.\n\nUse /categories to see all categories.", echoText(oldName)),
+		})
+		return
+	}
+	if cat.OwnerID == 0 {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      sharedCategoryReadOnlyMsg(cat.Name, "renamed"),
+			ParseMode: models.ParseModeHTML,
 		})
 		return
 	}
 
 	// Check if new name already exists.
-	existing, err := b.categoryRepo.GetByName(ctx, newName)
+	existing, err := b.findUserCategory(ctx, userID, newName)
 	if err == nil && existing.ID != cat.ID {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
@@ -361,7 +330,8 @@ func (b *Bot) handleRenameCategoryCore(ctx context.Context, tg TelegramAPI, upda
 		return
 	}
 
-	if err := b.categoryRepo.Update(ctx, cat.ID, newName); err != nil {
+	renamed, err := b.categoryRepo.Update(ctx, cat.ID, newName)
+	if err != nil {
 		logger.Log.Error().Err(err).Str("old_name", oldName).Str("new_name", newName).Msg("Failed to rename category")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
@@ -370,13 +340,13 @@ func (b *Bot) handleRenameCategoryCore(ctx context.Context, tg TelegramAPI, upda
 		return
 	}
 
-	b.invalidateCategoryCache()
+	b.cacheCategory(renamed)
 
 	logger.Log.Info().Int("category_id", cat.ID).Str("old_name", oldName).Str("new_name", newName).Msg("Category renamed")
 
 	_, err = tg.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:    chatID,
-		Text:      fmt.Sprintf("✅ Category '<b>%s</b>' renamed to '<b>%s</b>'.", escapeHTML(oldName), escapeHTML(newName)),
+		Text:      fmt.Sprintf("✅ Category '<b>%s</b>' renamed to '<b>%s</b>'.", botfmt.EscapeHTML(oldName), botfmt.EscapeHTML(newName)),
 		ParseMode: models.ParseModeHTML,
 	})
 	if err != nil {
@@ -400,10 +370,10 @@ func (b *Bot) deleteCategoryWithExpenses(ctx context.Context, categoryID int) (i
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	txExpRepo := repository.NewExpenseRepository(tx)
+	txExpenses := b.expenses().WithStore(repository.NewExpenseRepository(tx))
 	txCatRepo := repository.NewCategoryRepository(tx)
 
-	affected, err := txExpRepo.NullifyCategoryOnExpenses(ctx, categoryID)
+	affected, err := txExpenses.ClearCategory(ctx, categoryID)
 	if err != nil {
 		return 0, fmt.Errorf("nullify expenses: %w", err)
 	}
@@ -418,7 +388,7 @@ func (b *Bot) deleteCategoryWithExpenses(ctx context.Context, categoryID int) (i
 
 // deleteCategorySequential performs nullify+delete without a transaction.
 func (b *Bot) deleteCategorySequential(ctx context.Context, categoryID int) (int64, error) {
-	affected, err := b.expenseRepo.NullifyCategoryOnExpenses(ctx, categoryID)
+	affected, err := b.expenses().ClearCategory(ctx, categoryID)
 	if err != nil {
 		return 0, fmt.Errorf("nullify expenses: %w", err)
 	}
@@ -428,6 +398,47 @@ func (b *Bot) deleteCategorySequential(ctx context.Context, categoryID int) (int
 	return affected, nil
 }
 
+// hideSharedCategoryForUser hides a shared category from one user and
+// uncategorizes their expenses in it, leaving it to everyone else. Like
+// deleteCategoryWithExpenses it runs in a transaction when it can.
+// Returns the number of expenses that were uncategorized.
+func (b *Bot) hideSharedCategoryForUser(ctx context.Context, userID int64, categoryID int) (int64, error) {
+	expenses, categories := b.expenses(), b.categoryRepo
+	var tx pgx.Tx
+	if beginner, ok := b.db.(database.TxBeginner); ok {
+		var err error
+		if tx, err = beginner.Begin(ctx); err != nil {
+			return 0, fmt.Errorf("begin tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		expenses = expenses.WithStore(repository.NewExpenseRepository(tx))
+		categories = repository.NewCategoryRepository(tx)
+	}
+
+	affected, err := expenses.ClearCategoryForUser(ctx, userID, categoryID)
+	if err != nil {
+		return 0, fmt.Errorf("nullify expenses: %w", err)
+	}
+	if _, err := categories.Hide(ctx, userID, categoryID); err != nil {
+		return 0, fmt.Errorf("hide category: %w", err)
+	}
+	if tx != nil {
+		if err := tx.Commit(ctx); err != nil {
+			return 0, fmt.Errorf("commit tx: %w", err)
+		}
+	}
+	b.hiddenCategories.invalidate(userID)
+	return affected, nil
+}
+
+// sharedCategoryReadOnlyMsg refuses to change a shared category, which
+// every user sees, for one of them.
+func sharedCategoryReadOnlyMsg(name, change string) string {
+	return fmt.Sprintf("❌ '%s' is a shared category, so it can't be %s. "+
+		"Add your own with /addcategory, and hide this one with <code>/deletecategory %s</code>.",
+		botfmt.EscapeHTML(name), change, botfmt.EscapeHTML(name))
+}
+
 // handleDeleteCategory handles the /deletecategory command.
 func (b *Bot) handleDeleteCategory(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
 	b.handleDeleteCategoryCore(ctx, tgBot, update)
@@ -435,7 +446,7 @@ func (b *Bot) handleDeleteCategory(ctx context.Context, tgBot *bot.Bot, update *
 
 // handleDeleteCategoryCore is the testable implementation of handleDeleteCategory.
 func (b *Bot) handleDeleteCategoryCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
-	if update.Message == nil {
+	if update.Message == nil || update.Message.From == nil {
 		return
 	}
 
@@ -445,26 +456,33 @@ func (b *Bot) handleDeleteCategoryCore(ctx context.Context, tg TelegramAPI, upda
 
 	if args == "" {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:    chatID,
-			Text:      "❌ Please provide a category name.\n\nUsage: <code>/deletecategory Food - Dining Out</code>",
+			ChatID: chatID,
+			Text: "❌ Please provide a category name.\n\nUsage: <code>/deletecategory Food - Dining Out</code>" +
+				seeHelp("deletecategory"),
 			ParseMode: models.ParseModeHTML,
 		})
 		return
 	}
 
 	// Find the category.
-	cat, err := b.categoryRepo.GetByName(ctx, args)
+	cat, err := b.findUserCategory(ctx, update.Message.From.ID, args)
 	if err != nil {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
-			Text:   fmt.Sprintf("❌ Category '%s' not found.\n\nUse /categories to see all categories.", args),
+			Text:   fmt.Sprintf("❌ Category '%s' not found.\n\nUse /categories to see all categories.", echoText(args)),
 		})
 		return
 	}
 
-	// Nullify category on affected expenses and delete inside a transaction
-	// so both succeed or both roll back.
-	affected, err := b.deleteCategoryWithExpenses(ctx, cat.ID)
+	// A shared category is only hidden from the user; their own category
+	// is deleted. Either way expenses are uncategorized in the same
+	// transaction.
+	var affected int64
+	if cat.OwnerID == 0 {
+		affected, err = b.hideSharedCategoryForUser(ctx, update.Message.From.ID, cat.ID)
+	} else {
+		affected, err = b.deleteCategoryWithExpenses(ctx, cat.ID)
+	}
 	if err != nil {
 		logger.Log.Error().Err(err).Int("category_id", cat.ID).Msg("Failed to delete category")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
@@ -474,11 +492,22 @@ func (b *Bot) handleDeleteCategoryCore(ctx context.Context, tg TelegramAPI, upda
 		return
 	}
 
-	b.invalidateCategoryCache()
+	if cat.OwnerID != 0 {
+		b.uncacheCategory(cat.ID)
+	}
 
-	logger.Log.Info().Int("category_id", cat.ID).Str("name", cat.Name).Int64("affected_expenses", affected).Msg("Category deleted")
+	logger.Log.Info().
+		Int("category_id", cat.ID).
+		Str("name", cat.Name).
+		Bool("shared", cat.OwnerID == 0).
+		Int64("affected_expenses", affected).
+		Msg("Category deleted")
 
-	text := fmt.Sprintf("✅ Category '<b>%s</b>' deleted.", escapeHTML(cat.Name))
+	text := fmt.Sprintf("✅ Category '<b>%s</b>' deleted.", botfmt.EscapeHTML(cat.Name))
+	if cat.OwnerID == 0 {
+		text = fmt.Sprintf("✅ Category '<b>%s</b>' hidden. It is shared, so other users still see it; "+
+			"<code>/addcategory %s</code> shows it again.", botfmt.EscapeHTML(cat.Name), botfmt.EscapeHTML(cat.Name))
+	}
 	if affected > 0 {
 		text += fmt.Sprintf("\n\n%d expense(s) have been uncategorized.", affected)
 	}
@@ -500,6 +529,19 @@ func (b *Bot) handleAdd(ctx context.Context, tgBot *bot.Bot, update *models.Upda
 
 // handleAddCore is the testable implementation of handleAdd.
 func (b *Bot) handleAddCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	b.addFromCommand(ctx, tg, update, ParseAddCommandWithCategories,
+		"❌ Invalid format. Use: <code>/add 5.50 Coffee [category]</code>")
+}
+
+// addFromCommand saves the expense an /add style command describes, read
+// with parse. invalidMsg is the reply when the command cannot be parsed.
+func (b *Bot) addFromCommand(
+	ctx context.Context,
+	tg TelegramAPI,
+	update *models.Update,
+	parse func(string, []string) *ParsedExpense,
+	invalidMsg string,
+) {
 	if update.Message == nil {
 		return
 	}
@@ -507,7 +549,7 @@ func (b *Bot) handleAddCore(ctx context.Context, tg TelegramAPI, update *models.
 	chatID := update.Message.Chat.ID
 	userID := update.Message.From.ID
 
-	categories, err := b.getCategoriesWithCache(ctx)
+	categories, err := b.visibleCategories(ctx, userID)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to fetch categories for parsing")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
@@ -522,21 +564,39 @@ func (b *Bot) handleAddCore(ctx context.Context, tg TelegramAPI, update *models.
 		categoryNames[i] = categories[i].Name
 	}
 
-	parsed := ParseAddCommandWithCategories(update.Message.Text, categoryNames)
+	text, split := extractSplitDirective(update.Message.Text)
+	parsed := parse(text, categoryNames)
+	if parsed != nil && parsed.AmountError != nil {
+		b.sendAmountExpressionError(ctx, tg, chatID, parsed)
+		return
+	}
 	if parsed == nil {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:    chatID,
-			Text:      "❌ Invalid format. Use: <code>/add 5.50 Coffee [category]</code>",
+			Text:      invalidMsg,
 			ParseMode: models.ParseModeHTML,
 		})
 		return
 	}
+	parsed.Source = appmodels.ExpenseSourceCommand
+	if split != nil && !b.applySplit(ctx, tg, chatID, userID, split, parsed) {
+		return
+	}
 
-	b.saveExpenseCore(ctx, tg, chatID, userID, parsed, categories)
+	if expense := b.saveExpenseCore(ctx, tg, chatID, userID, parsed, categories); expense != nil {
+		b.linkSourceMessage(ctx, expense.ID, chatID, update.Message.ID)
+	}
 }
 
 // handleFreeTextExpense handles free-text expense input like "5.50 Coffee".
 func (b *Bot) handleFreeTextExpense(ctx context.Context, tgBot *bot.Bot, update *models.Update) bool {
+	return b.handleFreeTextExpenseCore(ctx, tgBot, update)
+}
+
+// handleFreeTextExpenseCore is the testable implementation of
+// handleFreeTextExpense. It reports whether the message was taken as an
+// expense.
+func (b *Bot) handleFreeTextExpenseCore(ctx context.Context, tg TelegramAPI, update *models.Update) bool {
 	if update.Message == nil || update.Message.Text == "" {
 		return false
 	}
@@ -545,8 +605,12 @@ func (b *Bot) handleFreeTextExpense(ctx context.Context, tgBot *bot.Bot, update
 	if strings.HasPrefix(text, "/") {
 		return false
 	}
+	if b.tooLongForFreeText(text) {
+		logger.Log.Debug().Int("length", len(text)).Msg("Message too long for free-text parsing")
+		return false
+	}
 
-	categories, err := b.getCategoriesWithCache(ctx)
+	categories, err := b.visibleCategories(ctx, update.Message.From.ID)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to fetch categories for free-text parsing")
 		return false
@@ -557,18 +621,43 @@ func (b *Bot) handleFreeTextExpense(ctx context.Context, tgBot *bot.Bot, update
 		categoryNames[i] = categories[i].Name
 	}
 
+	text, split := extractSplitDirective(text)
 	parsed := ParseExpenseInputWithCategories(text, categoryNames)
 	if parsed == nil {
 		return false
 	}
+	parsed.Source = appmodels.ExpenseSourceText
 
 	chatID := update.Message.Chat.ID
 	userID := update.Message.From.ID
 
-	b.saveExpense(ctx, tgBot, chatID, userID, parsed, categories)
+	if parsed.AmountError != nil {
+		b.sendAmountExpressionError(ctx, tg, chatID, parsed)
+		return true
+	}
+	if split != nil && !b.applySplit(ctx, tg, chatID, userID, split, parsed) {
+		return true
+	}
+	if needsDescription(parsed.Description) {
+		b.promptDescriptionCore(ctx, tg, chatID, userID, update.Message.ID, parsed)
+		return true
+	}
+
+	if expense := b.saveExpenseCore(ctx, tg, chatID, userID, parsed, categories); expense != nil {
+		b.linkSourceMessage(ctx, expense.ID, chatID, update.Message.ID)
+	}
 	return true
 }
 
+// sendAmountExpressionError explains why an amount expression was rejected.
+func (b *Bot) sendAmountExpressionError(ctx context.Context, tg TelegramAPI, chatID int64, parsed *ParsedExpense) {
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      amountExpressionError(parsed.AmountExpression, parsed.AmountError),
+		ParseMode: models.ParseModeHTML,
+	})
+}
+
 // saveExpense creates and saves an expense to the database.
 func (b *Bot) saveExpense(
 	ctx context.Context,
@@ -577,11 +666,14 @@ func (b *Bot) saveExpense(
 	userID int64,
 	parsed *ParsedExpense,
 	categories []appmodels.Category,
-) {
-	b.saveExpenseCore(ctx, tgBot, chatID, userID, parsed, categories)
+) *appmodels.Expense {
+	return b.saveExpenseCore(ctx, tgBot, chatID, userID, parsed, categories)
 }
 
-// saveExpenseCore is the testable implementation of saveExpense.
+// saveExpenseCore is the testable implementation of saveExpense. An
+// expense in a currency the user has never used is held back until the user
+// picks the currency. It returns the saved expense, or nil when nothing was
+// saved.
 func (b *Bot) saveExpenseCore(
 	ctx context.Context,
 	tg TelegramAPI,
@@ -589,272 +681,233 @@ func (b *Bot) saveExpenseCore(
 	userID int64,
 	parsed *ParsedExpense,
 	categories []appmodels.Category,
-) {
-	merchant := parsed.Description
-	amount, currency, description := b.convertExpenseCurrency(
-		ctx,
-		userID,
-		parsed.Amount,
-		parsed.Currency,
-		parsed.Description,
-	)
-
-	expense := &appmodels.Expense{
-		UserID:      userID,
-		Amount:      amount,
-		Currency:    currency,
-		Description: description,
-		Merchant:    merchant,
-	}
-
-	b.assignExpenseCategory(ctx, expense, parsed, categories)
-
-	if err := b.expenseRepo.Create(ctx, expense); err != nil {
-		logger.Log.Error().Err(err).Msg("Failed to create expense")
-		if b.metrics != nil {
-			b.metrics.ExpenseOps.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("operation", "add"), attribute.String("status", "error")))
+) *appmodels.Expense {
+	if b.promptNewCurrency(ctx, tg, chatID, userID, parsed) {
+		return nil
+	}
+	return b.buildAndStoreExpense(ctx, tg, chatID, userID, parsed, categories)
+}
+
+// buildAndStoreExpense builds and saves a chat expense. Expenses logged in
+// a group chat with a default category get that category unless the
+// message names one. It returns the saved expense, or nil when nothing was
+// saved.
+func (b *Bot) buildAndStoreExpense(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	userID int64,
+	parsed *ParsedExpense,
+	categories []appmodels.Category,
+) *appmodels.Expense {
+	parsed.ChatCategoryID = b.chatDefaultCategoryID(ctx, chatID)
+	expense, err := b.buildExpenseFromParsed(ctx, userID, parsed, categories)
+	if err != nil {
+		text := failedSaveExpenseMsg
+		if errors.Is(err, service.ErrAboveHardCap) {
+			text = b.amountAboveHardCapMsg(expense.Amount, expense.Currency)
 		}
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+		return nil
+	}
+
+	return b.storeBuiltExpense(ctx, tg, chatID, userID, expense, parsed)
+}
+
+// storeBuiltExpense saves a built chat expense and sends its confirmation.
+// Expenses that would go over a hard category budget are refused, and
+// amounts above the user's soft limit are saved as a draft behind the
+// large-amount prompt. It returns the saved expense, or nil when nothing
+// was saved.
+func (b *Bot) storeBuiltExpense(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	userID int64,
+	expense *appmodels.Expense,
+	parsed *ParsedExpense,
+) *appmodels.Expense {
+	result, err := b.persistParsedExpense(ctx, expense, parsed, service.CreateOptions{
+		ConfirmLarge:  true,
+		EnforceBudget: true,
+	})
+	var overBudget *service.OverBudgetError
+	if errors.As(err, &overBudget) {
+		b.sendBudgetBlocked(ctx, tg, chatID, overBudget.Budget, expense, parsed)
+		return nil
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create expense")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
 			Text:   failedSaveExpenseMsg,
 		})
-		return
+		return nil
 	}
-
-	if b.metrics != nil {
-		b.metrics.ExpenseOps.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("operation", "add"), attribute.String("status", "ok")))
-		f, _ := expense.Amount.Float64()
-		b.metrics.ExpenseAmount.Record(ctx, f, otelmetric.WithAttributes(attribute.String("currency", expense.Currency)))
+	needsConfirmation := result.NeedsConfirmation
+	if len(parsed.SplitUserIDs) > 0 && !b.recordSplit(ctx, chatID, expense, parsed.SplitUserIDs) {
+		parsed.SplitUserIDs = nil
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "⚠️ The expense was saved, but splitting it failed. Delete it and try again.",
+		})
 	}
 
-	b.saveInlineTags(ctx, expense.ID, parsed.Tags)
-
 	logger.Log.Debug().
 		Int64("chat_id", chatID).
 		Int64("user_id", userID).
 		Str("amount", expense.Amount.String()).
 		Str("description", expense.Description).
+		Bool("needs_confirmation", needsConfirmation).
 		Msg("Expense created")
 
-	text := buildExpenseAddedMessage(expense, parsed.Tags)
-
-	keyboard := buildExpenseReflectionKeyboard(expense.ID)
-
-	if _, err := tg.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:      chatID,
-		Text:        text,
-		ParseMode:   models.ParseModeHTML,
-		ReplyMarkup: keyboard,
-	}); err != nil {
-		logger.Log.Error().Err(err).Msg("Failed to send expense confirmation")
+	if needsConfirmation {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:      chatID,
+			Text:        botfmt.LargeAmountConfirmCard(expense),
+			ParseMode:   models.ParseModeHTML,
+			ReplyMarkup: buildLargeAmountKeyboard(expense.ID),
+		})
+		return expense
 	}
+
+	b.sendExpenseAdded(ctx, tg, chatID, expense, parsed)
+	return expense
 }
 
-func (b *Bot) assignExpenseCategory(
+// buildExpenseFromParsed converts and categorizes a parsed expense without
+// saving it. It returns the expense together with service.ErrAboveHardCap
+// when the converted amount is above the hard cap.
+func (b *Bot) buildExpenseFromParsed(
 	ctx context.Context,
-	expense *appmodels.Expense,
+	userID int64,
 	parsed *ParsedExpense,
 	categories []appmodels.Category,
-) {
-	if b.assignParsedCategory(expense, parsed.CategoryName, categories) {
-		return
-	}
-	if b.assignAICategorySuggestion(ctx, expense, parsed.Description, categories) {
-		return
-	}
-	if fallback := MatchCategory("Others", categories); fallback != nil {
-		expense.CategoryID = &fallback.ID
-		expense.Category = fallback
-	}
-}
-
-func (b *Bot) assignParsedCategory(
-	expense *appmodels.Expense,
-	categoryName string,
-	categories []appmodels.Category,
-) bool {
-	if categoryName == "" {
-		return false
-	}
-	for i := range categories {
-		if strings.EqualFold(categories[i].Name, categoryName) {
-			expense.CategoryID = &categories[i].ID
-			expense.Category = &categories[i]
-			return true
-		}
-	}
-	return false
+) (*appmodels.Expense, error) {
+	expense, _, err := b.buildExpenseWithMode(ctx, userID, parsed, categories, service.AIApply)
+	return expense, err
 }
 
-func (b *Bot) assignAICategorySuggestion(
+// buildExpenseWithMode is buildExpenseFromParsed with the category decided
+// in the given mode, which is also returned. The category is not decided
+// for amounts above the hard cap.
+func (b *Bot) buildExpenseWithMode(
 	ctx context.Context,
-	expense *appmodels.Expense,
-	description string,
+	userID int64,
+	parsed *ParsedExpense,
 	categories []appmodels.Category,
-) bool {
-	if b.geminiClient == nil || description == "" {
-		return false
-	}
-
-	categoryNames := make([]string, len(categories))
-	for i := range categories {
-		categoryNames[i] = categories[i].Name
-	}
+	mode service.AIMode,
+) (*appmodels.Expense, service.CategoryDecision, error) {
+	var truncated bool
+	parsed.Description, truncated = b.normalizeDescription(parsed.Description)
+	parsed.DescriptionTruncated = parsed.DescriptionTruncated || truncated
 
-	suggestion, err := b.geminiClient.SuggestCategory(ctx, description, categoryNames)
+	expense, decision, err := b.expenses().Build(ctx, parsed.input(userID), categories, mode)
 	if err != nil {
-		logger.Log.Debug().Err(err).
-			Str("description", logger.SanitizeDescription(description)).
-			Msg("Failed to get AI category suggestion")
-		return false
-	}
-	if suggestion == nil || suggestion.Confidence <= 0.5 {
-		return false
+		return expense, decision, fmt.Errorf("failed to build expense: %w", err)
 	}
-	if suggestion.Matched {
-		return b.applyMatchedSuggestion(expense, description, suggestion, categories)
-	}
-	if suggestion.NewCategoryName != "" && suggestion.Confidence >= 0.8 {
-		return b.applyNewCategorySuggestion(ctx, expense, description, suggestion, categories)
-	}
-	return false
+	return expense, decision, nil
 }
 
-func (b *Bot) applyMatchedSuggestion(
-	expense *appmodels.Expense,
-	description string,
-	suggestion *gemini.CategorySuggestion,
-	categories []appmodels.Category,
-) bool {
-	for i := range categories {
-		if !strings.EqualFold(categories[i].Name, suggestion.Category) {
-			continue
-		}
-		expense.CategoryID = &categories[i].ID
-		expense.Category = &categories[i]
-		logger.Log.Info().
-			Str("description", logger.SanitizeDescription(description)).
-			Str("suggested_category", suggestion.Category).
-			Float64("confidence", suggestion.Confidence).
-			Str("reasoning", suggestion.Reasoning).
-			Msg("AI category suggestion applied")
-		return true
+// input returns what the expense service builds userID's expense from.
+func (p *ParsedExpense) input(userID int64) service.ParsedInput {
+	return service.ParsedInput{
+		UserID:         userID,
+		Amount:         p.Amount,
+		Currency:       p.Currency,
+		Description:    p.Description,
+		Refund:         p.Refund,
+		Source:         p.Source,
+		CategoryName:   p.CategoryName,
+		ChatCategoryID: p.ChatCategoryID,
 	}
-	return false
 }
 
-func (b *Bot) applyNewCategorySuggestion(
+// persistParsedExpense saves a built expense with the inline tags of
+// parsed, which then holds every tag the expense was saved with.
+func (b *Bot) persistParsedExpense(
 	ctx context.Context,
 	expense *appmodels.Expense,
-	description string,
-	suggestion *gemini.CategorySuggestion,
-	categories []appmodels.Category,
-) bool {
-	newCategory := suggestion.NewCategoryName
-	if !isValidAutoCreatedCategoryName(newCategory) {
-		logger.Log.Warn().
-			Str("description", logger.SanitizeDescription(description)).
-			Str("new_category", newCategory).
-			Msg("AI suggested invalid new category name; skipping auto-create")
-		return false
-	}
-
-	for i := range categories {
-		if strings.EqualFold(categories[i].Name, newCategory) {
-			expense.CategoryID = &categories[i].ID
-			expense.Category = &categories[i]
-			return true
-		}
+	parsed *ParsedExpense,
+	opts service.CreateOptions,
+) (*service.CreateResult, error) {
+	opts.Tags = parsed.Tags
+	result, err := b.expenses().CreateFromParsed(ctx, expense, opts)
+	var overBudget *service.OverBudgetError
+	if errors.As(err, &overBudget) {
+		return nil, err
 	}
-
-	cat, err := b.categoryRepo.Create(ctx, newCategory)
 	if err != nil {
-		existing, getErr := b.categoryRepo.GetByName(ctx, newCategory)
-		if getErr == nil {
-			expense.CategoryID = &existing.ID
-			expense.Category = existing
-			return true
+		if b.metrics != nil {
+			b.metrics.ExpenseOps.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("operation", "add"), attribute.String("status", "error")))
 		}
-		logger.Log.Warn().Err(err).
-			Str("new_category", newCategory).
-			Msg("Failed to auto-create category from AI suggestion")
-		return false
+		return nil, fmt.Errorf("failed to create expense: %w", err)
 	}
 
-	expense.CategoryID = &cat.ID
-	expense.Category = cat
-	b.invalidateCategoryCache()
-	logger.Log.Info().
-		Str("description", logger.SanitizeDescription(description)).
-		Str("new_category", newCategory).
-		Float64("confidence", suggestion.Confidence).
-		Msg("Auto-created category from AI suggestion")
-	return true
-}
-
-func isValidAutoCreatedCategoryName(name string) bool {
-	if strings.TrimSpace(name) == "" || len(name) > appmodels.MaxCategoryNameLength {
-		return false
-	}
-	for _, r := range name {
-		if unicode.IsControl(r) {
-			return false
-		}
+	if b.metrics != nil {
+		b.metrics.ExpenseOps.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("operation", "add"), attribute.String("status", "ok")))
+		f, _ := expense.Amount.Float64()
+		b.metrics.ExpenseAmount.Record(ctx, f, otelmetric.WithAttributes(attribute.String("currency", expense.Currency)))
 	}
-	return true
+	parsed.Tags = result.Tags
+	return result, nil
 }
 
-func (b *Bot) saveInlineTags(ctx context.Context, expenseID int, tags []string) {
+// sendExpenseAdded sends the "Expense Added" confirmation with the
+// reflection keyboard. Expenses saved without inline tags get the tags the
+// user usually applies to the same description, either added right away
+// (/settings autotag on) or offered behind a button. Categories Gemini
+// picked with medium confidence are flagged with a "Change" button, split
+// expenses show each person's share, and categories close to or over their
+// budget get a note.
+func (b *Bot) sendExpenseAdded(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	expense *appmodels.Expense,
+	parsed *ParsedExpense,
+) {
+	tags := parsed.Tags
+	var suggested []string
 	if len(tags) == 0 {
-		return
+		tags, suggested = b.applyTagSuggestions(ctx, expense)
 	}
-	tagIDs := make([]int, 0, len(tags))
-	for _, name := range tags {
-		tag, err := b.tagRepo.GetOrCreate(ctx, name)
-		if err != nil {
-			logger.Log.Warn().Err(err).Str("tag", name).Msg("Failed to create tag")
-			continue
-		}
-		tagIDs = append(tagIDs, tag.ID)
+
+	text := botfmt.ExpenseAddedCard(expense, tags, botfmt.ExpenseAddedOptions{
+		Expression: parsed.AmountExpression,
+	})
+
+	keyboard := buildExpenseReflectionKeyboard(expense.ID)
+	if b.uncertainAICategory(expense) {
+		text += "\n\n" + aiUncertainNote
+		addChangeCategoryButton(keyboard, expense.ID)
 	}
-	if len(tagIDs) == 0 {
-		return
+	if len(suggested) > 0 {
+		text += "\n\n" + tagSuggestionLine(suggested)
+		addTagSuggestionButton(keyboard, expense.ID)
 	}
-	if err := b.tagRepo.SetExpenseTags(ctx, expenseID, tagIDs); err != nil {
-		logger.Log.Warn().Err(err).Int("expense_id", expenseID).Msg("Failed to set expense tags")
+	if len(parsed.SplitUserIDs) > 0 {
+		text += "\n\n" + splitSummaryLine(expense, parsed.SplitUserIDs)
 	}
-}
-
-func buildExpenseAddedMessage(expense *appmodels.Expense, parsedTags []string) string {
-	categoryText := categoryUncategorized
-	if expense.Category != nil {
-		categoryText = escapeHTML(expense.Category.Name)
+	if parsed.DescriptionTruncated {
+		text += "\n\n" + b.descriptionTruncatedNote()
 	}
-	descText := ""
-	if expense.Description != "" {
-		descText = "\n📝 " + escapeHTML(expense.Description)
+	if line := b.budgetWarningLine(ctx, expense); line != "" {
+		text += "\n\n" + line
 	}
-	currencySymbol := getCurrencyOrCodeSymbol(expense.Currency)
-	text := fmt.Sprintf(`✅ <b>Expense Added</b>
-
-💰 %s%s %s%s
-📁 %s
-🆔 #%d`,
-		currencySymbol,
-		expense.Amount.StringFixed(2),
-		expense.Currency,
-		descText,
-		categoryText,
-		expense.UserExpenseNumber)
 
-	if len(parsedTags) == 0 {
-		return text
-	}
-	escapedTags := make([]string, len(parsedTags))
-	for i, tag := range parsedTags {
-		escapedTags[i] = escapeHTML(tag)
+	if _, err := tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: keyboard,
+	}); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to send expense confirmation")
 	}
-	return text + "\n🏷️ " + strings.Join(escapedTags, ", ")
 }
 
 // handleList handles the /list command to show recent expenses.
@@ -871,7 +924,16 @@ func (b *Bot) handleListCore(ctx context.Context, tg TelegramAPI, update *models
 	chatID := update.Message.Chat.ID
 	userID := update.Message.From.ID
 
-	expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 10)
+	switch args := extractCommandArgs(update.Message.Text, "/list"); {
+	case strings.EqualFold(args, listEditArg):
+		b.sendListEditCore(ctx, tg, chatID, userID)
+		return
+	case strings.EqualFold(args, listSelectArg):
+		b.sendListSelectCore(ctx, tg, chatID, userID)
+		return
+	}
+
+	expenses, err := b.expenseRepo.GetByUserID(ctx, userID, listRecentLimit)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to fetch expenses")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
@@ -881,7 +943,7 @@ func (b *Bot) handleListCore(ctx context.Context, tg TelegramAPI, update *models
 		return
 	}
 
-	b.sendExpenseListCore(ctx, tg, chatID, expenses, "📋 <b>Recent Expenses</b>")
+	b.sendExpenseListCore(ctx, tg, chatID, userID, expenses, listRecentHeader)
 }
 
 // handleToday handles the /today command to show today's expenses.
@@ -891,37 +953,7 @@ func (b *Bot) handleToday(ctx context.Context, tgBot *bot.Bot, update *models.Up
 
 // handleTodayCore is the testable implementation of handleToday.
 func (b *Bot) handleTodayCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
-	if update.Message == nil {
-		return
-	}
-
-	chatID := update.Message.Chat.ID
-	userID := update.Message.From.ID
-
-	current := b.now().In(normalizeLocation(b.displayLocation))
-	startOfDay, endOfDay := getDayDateRangeAt(current)
-
-	expenses, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, userID, startOfDay, endOfDay)
-	if err != nil {
-		logger.Log.Error().Err(err).Msg("Failed to fetch today's expenses")
-		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: chatID,
-			Text:   failedFetchExpensesMsg,
-		})
-		return
-	}
-
-	total, err := b.expenseRepo.GetTotalByUserIDAndDateRange(ctx, userID, startOfDay, endOfDay)
-	if err != nil {
-		logger.Log.Error().Err(err).Msg("Failed to calculate today's total")
-		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: chatID,
-			Text:   failedFetchExpensesMsg,
-		})
-		return
-	}
-	header := fmt.Sprintf("📅 <b>Today's Expenses</b> (Total: $%s)", total.StringFixed(2))
-	b.sendExpenseListCore(ctx, tg, chatID, expenses, header)
+	b.sendPeriodViewCore(ctx, tg, update, periodDay)
 }
 
 // handleWeek handles the /week command to show this week's expenses.
@@ -931,37 +963,7 @@ func (b *Bot) handleWeek(ctx context.Context, tgBot *bot.Bot, update *models.Upd
 
 // handleWeekCore is the testable implementation of handleWeek.
 func (b *Bot) handleWeekCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
-	if update.Message == nil {
-		return
-	}
-
-	chatID := update.Message.Chat.ID
-	userID := update.Message.From.ID
-
-	current := b.now().In(normalizeLocation(b.displayLocation))
-	startOfWeek, endOfWeek := getWeekDateRangeAt(current)
-
-	expenses, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, userID, startOfWeek, endOfWeek)
-	if err != nil {
-		logger.Log.Error().Err(err).Msg("Failed to fetch week's expenses")
-		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: chatID,
-			Text:   failedFetchExpensesMsg,
-		})
-		return
-	}
-
-	total, err := b.expenseRepo.GetTotalByUserIDAndDateRange(ctx, userID, startOfWeek, endOfWeek)
-	if err != nil {
-		logger.Log.Error().Err(err).Msg("Failed to calculate week's total")
-		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: chatID,
-			Text:   failedFetchExpensesMsg,
-		})
-		return
-	}
-	header := fmt.Sprintf("📆 <b>This Week's Expenses</b> (Total: $%s)", total.StringFixed(2))
-	b.sendExpenseListCore(ctx, tg, chatID, expenses, header)
+	b.sendPeriodViewCore(ctx, tg, update, periodWeek)
 }
 
 // handleCategory handles the /category command to filter expenses by category.
@@ -982,15 +984,16 @@ func (b *Bot) handleCategoryCore(ctx context.Context, tg TelegramAPI, update *mo
 	args := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/category"))
 	if args == "" {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:    chatID,
-			Text:      "❌ Please provide a category name.\n\nUsage: <code>/category Food - Dining Out</code>",
+			ChatID: chatID,
+			Text: "❌ Please provide a category name.\n\nUsage: <code>/category Food - Dining Out</code>" +
+				seeHelp("category"),
 			ParseMode: models.ParseModeHTML,
 		})
 		return
 	}
 
 	// Find matching category
-	categories, err := b.getCategoriesWithCache(ctx)
+	categories, err := b.visibleCategories(ctx, userID)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to fetch categories")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
@@ -1010,8 +1013,11 @@ func (b *Bot) handleCategoryCore(ctx context.Context, tg TelegramAPI, update *mo
 
 	if matchedCategory == nil {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:    chatID,
-			Text:      fmt.Sprintf("❌ Category '%s' not found.\n\nUse /categories to see all available categories.", escapeHTML(args)),
+			ChatID: chatID,
+			Text: fmt.Sprintf(
+				"❌ Category '%s' not found.\n\nUse /categories to see all available categories.",
+				botfmt.EscapeHTML(echoText(args)),
+			),
 			ParseMode: models.ParseModeHTML,
 		})
 		return
@@ -1028,7 +1034,7 @@ func (b *Bot) handleCategoryCore(ctx context.Context, tg TelegramAPI, update *mo
 		return
 	}
 
-	total, err := b.expenseRepo.GetTotalByUserIDAndCategory(ctx, userID, matchedCategory.ID)
+	totals, err := b.expenseRepo.GetTotalsByUserIDAndCategory(ctx, userID, matchedCategory.ID)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to calculate category total")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
@@ -1037,8 +1043,9 @@ func (b *Bot) handleCategoryCore(ctx context.Context, tg TelegramAPI, update *mo
 		})
 		return
 	}
-	header := fmt.Sprintf("📁 <b>%s Expenses</b> (Total: $%s)", escapeHTML(matchedCategory.Name), total.StringFixed(2))
-	b.sendExpenseListCore(ctx, tg, chatID, expenses, header)
+	header := fmt.Sprintf("📁 <b>%s Expenses</b> (Total: %s)",
+		botfmt.EscapeHTML(matchedCategory.Name), formatInlineCurrencyTotals(totals))
+	b.sendExpenseListCore(ctx, tg, chatID, userID, expenses, header)
 
 	logger.Log.Info().
 		Int64("user_id", userID).
@@ -1048,11 +1055,12 @@ func (b *Bot) handleCategoryCore(ctx context.Context, tg TelegramAPI, update *mo
 		Msg("Category filter applied")
 }
 
-// sendExpenseListCore formats and sends a list of expenses.
+// sendExpenseListCore formats and sends a list of expenses in the format
+// userID picked.
 func (b *Bot) sendExpenseListCore(
 	ctx context.Context,
 	tg TelegramAPI,
-	chatID int64,
+	chatID, userID int64,
 	expenses []appmodels.Expense,
 	header string,
 ) {
@@ -1061,19 +1069,10 @@ func (b *Bot) sendExpenseListCore(
 		return
 	}
 
-	expenseIDs := make([]int, len(expenses))
-	for i := range expenses {
-		expenseIDs[i] = expenses[i].ID
-	}
-	tagsByExpense, err := b.tagRepo.GetByExpenseIDs(ctx, expenseIDs)
-	if err != nil {
-		logger.Log.Warn().Err(err).Msg("Failed to batch-load tags for expense list")
-	}
-
-	text := b.buildExpenseListMessage(header, expenses, tagsByExpense)
+	text := b.expenseListText(ctx, userID, expenses, header)
 
 	logger.Log.Debug().Int64("chat_id", chatID).Int("count", len(expenses)).Msg("Sending expense list")
-	_, err = tg.SendMessage(ctx, &bot.SendMessageParams{
+	_, err := tg.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:    chatID,
 		Text:      text,
 		ParseMode: models.ParseModeHTML,
@@ -1094,60 +1093,6 @@ func (b *Bot) sendEmptyExpenseList(ctx context.Context, tg TelegramAPI, chatID i
 	}
 }
 
-func (b *Bot) buildExpenseListMessage(
-	header string,
-	expenses []appmodels.Expense,
-	tagsByExpense map[int][]appmodels.Tag,
-) string {
-	var sb strings.Builder
-	sb.WriteString(header)
-	sb.WriteString("\n\n")
-	for i := range expenses {
-		sb.WriteString(b.formatExpenseListItem(&expenses[i], tagsByExpense[expenses[i].ID]))
-	}
-	return sb.String()
-}
-
-func (b *Bot) formatExpenseListItem(exp *appmodels.Expense, tags []appmodels.Tag) string {
-	categoryText := ""
-	if exp.Category != nil {
-		categoryText = fmt.Sprintf(" [%s]", escapeHTML(exp.Category.Name))
-	}
-
-	tagText := ""
-	if len(tags) > 0 {
-		names := make([]string, len(tags))
-		for i := range tags {
-			names[i] = "#" + escapeHTML(tags[i].Name)
-		}
-		tagText = " " + strings.Join(names, " ")
-	}
-
-	descText := ""
-	if exp.Merchant != "" {
-		descText = " - " + escapeHTML(exp.Merchant)
-	} else if exp.Description != "" {
-		descText = " - " + escapeHTML(exp.Description)
-	}
-
-	currencySymbol := appmodels.SupportedCurrencies[exp.Currency]
-	if currencySymbol == "" {
-		currencySymbol = exp.Currency
-	}
-
-	return fmt.Sprintf(
-		"#%d %s%s %s%s%s%s\n<i>%s</i>\n\n",
-		exp.UserExpenseNumber,
-		currencySymbol,
-		exp.Amount.StringFixed(2),
-		exp.Currency,
-		descText,
-		categoryText,
-		tagText,
-		exp.CreatedAt.In(b.displayLocation).Format("Jan 2 15:04"),
-	)
-}
-
 // handleReport handles the /report command to generate CSV reports.
 func (b *Bot) handleReport(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
 	b.handleReportCore(ctx, tgBot, update)
@@ -1155,7 +1100,7 @@ func (b *Bot) handleReport(ctx context.Context, tgBot *bot.Bot, update *models.U
 
 // handleReportCore is the testable implementation of handleReport.
 func (b *Bot) handleReportCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
-	if update.Message == nil {
+	if update.Message == nil || update.Message.From == nil || !b.sensitiveCommandAllowed(ctx, tg, update.Message) {
 		return
 	}
 
@@ -1164,11 +1109,12 @@ func (b *Bot) handleReportCore(ctx context.Context, tg TelegramAPI, update *mode
 	now := b.now()
 	current := now.In(normalizeLocation(b.displayLocation))
 
-	args := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/report"))
+	args, categoryQuery := parseReportArgs(strings.TrimPrefix(update.Message.Text, "/report"))
 	if args == "" {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:    chatID,
-			Text:      "❌ Please specify report type.\n\nUsage: <code>/report week</code> or <code>/report month</code>",
+			ChatID: chatID,
+			Text: "❌ Please specify report type.\n\nUsage: <code>/report week</code> or <code>/report month</code>" +
+				seeHelp("report"),
 			ParseMode: models.ParseModeHTML,
 		})
 		return
@@ -1181,8 +1127,7 @@ func (b *Bot) handleReportCore(ctx context.Context, tg TelegramAPI, update *mode
 	case periodWeek:
 		startDate, endDate = getWeekDateRangeAt(current)
 		period = periodWeek
-		title = fmt.Sprintf("Weekly Expenses (%s to %s)",
-			startDate.Format("Jan 2"), endDate.AddDate(0, 0, -1).Format("Jan 2, 2006"))
+		title = fmt.Sprintf("Weekly Expenses (%s)", formatWeekLabelWithYear(startDate, endDate, current.Location()))
 	case periodMonth:
 		startDate, endDate = getMonthDateRangeAt(current)
 		period = periodMonth
@@ -1196,15 +1141,35 @@ func (b *Bot) handleReportCore(ctx context.Context, tg TelegramAPI, update *mode
 		return
 	}
 
+	var category *appmodels.Category
+	if categoryQuery != "" {
+		var errText string
+		if category, errText = b.reportCategory(ctx, userID, categoryQuery); category == nil {
+			_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:    chatID,
+				Text:      errText,
+				ParseMode: models.ParseModeHTML,
+			})
+			return
+		}
+		title += " · " + botfmt.EscapeHTML(category.Name)
+	}
+
+	release, ok := b.beginReport(ctx, tg, update.Message, "report")
+	if !ok {
+		return
+	}
+	defer release()
+
 	logger.Log.Info().
 		Int64("user_id", userID).
 		Str("period", period).
 		Time("start", startDate).
 		Time("end", endDate).
+		Bool("category_filter", category != nil).
 		Msg("Generating expense report")
 
-	// Fetch expenses
-	expenses, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, userID, startDate, endDate)
+	expenses, err := b.reportExpenses(ctx, userID, startDate, endDate, category)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to fetch expenses for report")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
@@ -1215,9 +1180,13 @@ func (b *Bot) handleReportCore(ctx context.Context, tg TelegramAPI, update *mode
 	}
 
 	if len(expenses) == 0 {
+		text := fmt.Sprintf("📊 No expenses found for %s.", period)
+		if category != nil {
+			text = fmt.Sprintf("📊 No %s expenses found for %s.", botfmt.EscapeHTML(category.Name), period)
+		}
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:    chatID,
-			Text:      fmt.Sprintf("📊 No expenses found for %s.", period),
+			Text:      text,
 			ParseMode: models.ParseModeHTML,
 		})
 		return
@@ -1234,25 +1203,25 @@ func (b *Bot) handleReportCore(ctx context.Context, tg TelegramAPI, update *mode
 		return
 	}
 
-	total, err := b.expenseRepo.GetTotalByUserIDAndDateRange(ctx, userID, startDate, endDate)
-	if err != nil {
-		logger.Log.Error().Err(err).Msg("Failed to calculate report total")
-		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: chatID,
-			Text:   "❌ Failed to generate report. Please try again.",
-		})
-		return
-	}
-
 	// Send CSV file
 	filename := generateReportFilename(period, b.displayLocation, now)
-	caption := fmt.Sprintf("📊 <b>%s</b>\n\nTotal Expenses: $%s SGD\nCount: %d",
-		title, total.StringFixed(2), len(expenses))
+	if category != nil {
+		filename = reportCategoryFilename(filename, category.Name)
+	}
+	b.applyLiveConversions(ctx, userID, expenses)
+	totals := sumExpenseAmountsByCurrency(expenses)
+	var caption strings.Builder
+	if period == periodMonth {
+		caption.WriteString(b.monthlyReviewHeader(ctx, userID, startDate))
+	}
+	fmt.Fprintf(&caption, "📊 <b>%s</b>\n\nTotal Expenses:\n", title)
+	appendCurrencyTotals(&caption, totals)
+	fmt.Fprintf(&caption, "Count: %d", len(expenses))
 
 	_, err = tg.SendDocument(ctx, &bot.SendDocumentParams{
 		ChatID:    chatID,
 		Document:  &models.InputFileUpload{Filename: filename, Data: bytes.NewReader(csvData)},
-		Caption:   caption,
+		Caption:   caption.String(),
 		ParseMode: models.ParseModeHTML,
 	})
 	if err != nil {
@@ -1268,10 +1237,66 @@ func (b *Bot) handleReportCore(ctx context.Context, tg TelegramAPI, update *mode
 		Int64("user_id", userID).
 		Str("period", period).
 		Int("expense_count", len(expenses)).
-		Str("total", total.String()).
+		Int("currencies", len(totals)).
 		Msg("Report generated successfully")
 }
 
+// parseReportArgs splits the arguments of /report into the period and an
+// optional category after it, which may be quoted: month "Work Travel".
+func parseReportArgs(args string) (string, string) {
+	period, category, _ := strings.Cut(strings.TrimSpace(args), " ")
+	category = strings.TrimSpace(category)
+	for _, quotes := range [][2]string{{`"`, `"`}, {"“", "”"}, {"'", "'"}} {
+		inner, ok := strings.CutPrefix(category, quotes[0])
+		if inner, found := strings.CutSuffix(inner, quotes[1]); ok && found {
+			return period, strings.TrimSpace(inner)
+		}
+	}
+	return period, category
+}
+
+// reportCategory resolves the category filter of /report with the same
+// fuzzy matching as Gemini suggestions. On failure it returns nil and the
+// message to send, naming the closest category when one is near.
+func (b *Bot) reportCategory(ctx context.Context, userID int64, query string) (*appmodels.Category, string) {
+	categories, err := b.visibleCategories(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for report")
+		return nil, failedFetchCategoriesMsg
+	}
+	if category := MatchCategory(query, categories); category != nil {
+		return category, ""
+	}
+
+	text := fmt.Sprintf("❌ Category \"%s\" not found.", botfmt.EscapeHTML(echoText(query)))
+	if name, ok := closestCategoryName(query, categories); ok {
+		text += fmt.Sprintf(" Did you mean <code>%s</code>?", botfmt.EscapeHTML(name))
+	}
+	return nil, text + "\n\nUse /categories to see all available categories."
+}
+
+// reportExpenses returns the confirmed expenses of a report period, only
+// those in category when it is set.
+func (b *Bot) reportExpenses(
+	ctx context.Context,
+	userID int64,
+	startDate, endDate time.Time,
+	category *appmodels.Category,
+) ([]appmodels.Expense, error) {
+	if category != nil {
+		expenses, err := b.expenseRepo.GetByUserIDDateRangeAndCategory(ctx, userID, startDate, endDate, category.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch category report: %w", err)
+		}
+		return expenses, nil
+	}
+	expenses, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, userID, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch report: %w", err)
+	}
+	return expenses, nil
+}
+
 // handleEdit handles the /edit command to modify an expense.
 func (b *Bot) handleEdit(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
 	b.handleEditCore(ctx, tgBot, update)
@@ -1301,7 +1326,7 @@ func (b *Bot) handleEditCore(ctx context.Context, tg TelegramAPI, update *models
 		return
 	}
 
-	categories, err := b.getCategoriesWithCache(ctx)
+	categories, err := b.visibleCategories(ctx, userID)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to fetch categories for edit")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
@@ -1312,6 +1337,10 @@ func (b *Bot) handleEditCore(ctx context.Context, tg TelegramAPI, update *models
 	}
 
 	parsed := parseEditExpenseValues(newValues, expense, categories)
+	if parsed != nil && parsed.AmountError != nil {
+		b.sendAmountExpressionError(ctx, tg, chatID, parsed)
+		return
+	}
 	if parsed == nil {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:    chatID,
@@ -1320,9 +1349,18 @@ func (b *Bot) handleEditCore(ctx context.Context, tg TelegramAPI, update *models
 		})
 		return
 	}
+	parsed.Description, parsed.DescriptionTruncated = b.normalizeDescription(parsed.Description)
+	before := *expense
 	applyParsedEdit(expense, parsed, categories)
+	if !botfmt.ExpenseChanged(&before, expense) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   botfmt.NothingChangedText(expense),
+		})
+		return
+	}
 
-	if err := b.expenseRepo.Update(ctx, expense); err != nil {
+	if err := b.expenses().EditFields(ctx, expense); err != nil {
 		logger.Log.Error().Err(err).Int64("expense_num", expenseNum).Msg("Failed to update expense")
 		if b.metrics != nil {
 			b.metrics.ExpenseOps.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("operation", editAction), attribute.String("status", "error")))
@@ -1342,20 +1380,27 @@ func (b *Bot) handleEditCore(ctx context.Context, tg TelegramAPI, update *models
 		Int64("chat_id", chatID).
 		Int64("expense_num", expenseNum).
 		Msg("Expense updated")
+	b.auditExpenseUpdate(ctx, tg, userID, before, expense)
 
-	sendEditConfirmation(ctx, tg, chatID, expense)
+	var note string
+	if parsed.DescriptionTruncated {
+		note = b.descriptionTruncatedNote()
+	}
+	sendEditConfirmation(ctx, tg, chatID, &before, expense, note)
 }
 
 func parseEditCommand(text string) (int64, string, string) {
 	args := extractCommandArgs(text, "/edit")
 	if args == "" {
-		return 0, "", "❌ Usage: <code>/edit &lt;id&gt; &lt;amount&gt; &lt;description&gt; [category]</code>"
+		return 0, "", "❌ Usage: <code>/edit &lt;id&gt; &lt;amount&gt; &lt;description&gt; [category]</code>" +
+			seeHelp(editAction)
 	}
 
 	parts := strings.SplitN(args, " ", 2)
 	expenseNum, err := strconv.ParseInt(parts[0], 10, 64)
 	if err != nil {
-		return 0, "", "❌ Invalid expense ID. Use: <code>/edit &lt;id&gt; &lt;amount&gt; &lt;description&gt;</code>"
+		return 0, "", "❌ Invalid expense ID. Use: <code>/edit &lt;id&gt; &lt;amount&gt; &lt;description&gt;</code>" +
+			seeHelp(editAction)
 	}
 	if len(parts) < 2 {
 		return 0, "", "❌ Please provide new values: <code>/edit &lt;id&gt; &lt;amount&gt; &lt;description&gt;</code>"
@@ -1411,7 +1456,12 @@ func applyParsedEdit(
 	parsed *ParsedExpense,
 	categories []appmodels.Category,
 ) {
+	// A refund stays one when only its amount is edited.
+	refund := parsed.Refund || expense.IsRefund()
 	expense.Amount = parsed.Amount
+	if refund {
+		expense.Amount = parsed.Amount.Neg()
+	}
 	if parsed.Currency != "" {
 		expense.Currency = parsed.Currency
 	}
@@ -1431,30 +1481,18 @@ func applyParsedEdit(
 	}
 }
 
-func sendEditConfirmation(ctx context.Context, tg TelegramAPI, chatID int64, expense *appmodels.Expense) {
-	categoryText := categoryUncategorized
-	if expense.Category != nil {
-		categoryText = escapeHTML(expense.Category.Name)
-	}
-
-	currencySymbol := appmodels.SupportedCurrencies[expense.Currency]
-	if currencySymbol == "" {
-		currencySymbol = expense.Currency
+func sendEditConfirmation(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	before, expense *appmodels.Expense,
+	note string,
+) {
+	text := botfmt.ExpenseUpdatedCard(before, expense)
+	if note != "" {
+		text += "\n\n" + note
 	}
 
-	text := fmt.Sprintf(`✅ <b>Expense Updated</b>
-
-🆔 #%d
-💰 %s%s %s
-📝 %s
-📁 %s`,
-		expense.UserExpenseNumber,
-		currencySymbol,
-		expense.Amount.StringFixed(2),
-		expense.Currency,
-		escapeHTML(expense.Description),
-		categoryText)
-
 	_, err := tg.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:    chatID,
 		Text:      text,
@@ -1472,7 +1510,7 @@ func (b *Bot) handleDelete(ctx context.Context, tgBot *bot.Bot, update *models.U
 
 // handleDeleteCore is the testable implementation of handleDelete.
 func (b *Bot) handleDeleteCore(ctx context.Context, tgBot TelegramAPI, update *models.Update) {
-	if update.Message == nil {
+	if update.Message == nil || !b.sensitiveCommandAllowed(ctx, tgBot, update.Message) {
 		return
 	}
 
@@ -1484,7 +1522,7 @@ func (b *Bot) handleDeleteCore(ctx context.Context, tgBot TelegramAPI, update *m
 	if args == "" {
 		_, _ = tgBot.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:    chatID,
-			Text:      "❌ Usage: <code>/delete &lt;id&gt;</code>",
+			Text:      "❌ Usage: <code>/delete &lt;id&gt;</code>" + seeHelp("delete"),
 			ParseMode: models.ParseModeHTML,
 		})
 		return
@@ -1517,7 +1555,8 @@ func (b *Bot) handleDeleteCore(ctx context.Context, tgBot TelegramAPI, update *m
 		return
 	}
 
-	if err := b.expenseRepo.Delete(ctx, expense.ID); err != nil {
+	sourceChatID := b.expenseSourceChat(ctx, expense.ID)
+	if err := b.expenses().Delete(ctx, expense.ID); err != nil {
 		logger.Log.Error().Err(err).Int64("expense_num", expenseNum).Msg("Failed to delete expense")
 		if b.metrics != nil {
 			b.metrics.ExpenseOps.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("operation", "delete"), attribute.String("status", "error")))
@@ -1537,6 +1576,7 @@ func (b *Bot) handleDeleteCore(ctx context.Context, tgBot TelegramAPI, update *m
 		Int64("chat_id", chatID).
 		Int64("expense_num", expenseNum).
 		Msg("Expense deleted")
+	b.auditExpenseDelete(ctx, tgBot, userID, expense, sourceChatID)
 
 	text := fmt.Sprintf("✅ Expense #%d deleted.", expenseNum)
 	_, err = tgBot.SendMessage(ctx, &bot.SendMessageParams{
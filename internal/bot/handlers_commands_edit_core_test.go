@@ -2,6 +2,7 @@ package bot
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"testing"
 
@@ -59,7 +60,8 @@ func TestHandleEditCore(t *testing.T) {
 
 		require.Equal(t, 1, mockBot.SentMessageCount())
 		require.Contains(t, mockBot.LastSentMessage().Text, "Expense Updated")
-		require.Contains(t, mockBot.LastSentMessage().Text, "20.50")
+		require.Contains(t, mockBot.LastSentMessage().Text, "S$10.00 SGD → S$20.50 SGD")
+		require.Contains(t, mockBot.LastSentMessage().Text, "before → after")
 
 		updated, err := b.expenseRepo.GetByID(ctx, expense.ID)
 		require.NoError(t, err)
@@ -67,6 +69,30 @@ func TestHandleEditCore(t *testing.T) {
 		require.Equal(t, "after", updated.Description)
 	})
 
+	t.Run("no-op edit says nothing changed", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := &appmodels.Expense{
+			UserID:      userID,
+			Amount:      decimal.RequireFromString("12.00"),
+			Currency:    "SGD",
+			Description: "same",
+			Merchant:    "same",
+			Status:      appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+
+		cmd := testEditCommandPrefix + strconv.FormatInt(expense.UserExpenseNumber, 10) + " 12 same"
+		b.handleEditCore(ctx, mockBot, mocks.CommandUpdate(chatID, userID, cmd))
+
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		require.Equal(t, fmt.Sprintf("ℹ️ Nothing changed on expense #%d.", expense.UserExpenseNumber),
+			mockBot.LastSentMessage().Text)
+
+		unchanged, err := b.expenseRepo.GetByID(ctx, expense.ID)
+		require.NoError(t, err)
+		require.True(t, expense.UpdatedAt.Equal(unchanged.UpdatedAt))
+	})
+
 	t.Run("invalid edit values return format error", func(t *testing.T) {
 		mockBot := mocks.NewMockBot()
 		expense := &appmodels.Expense{
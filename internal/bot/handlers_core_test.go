@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/go-telegram/bot/models"
 	"github.com/stretchr/testify/require"
@@ -87,6 +89,29 @@ func TestHandleAddCore(t *testing.T) {
 		require.Contains(t, msg.Text, expenseAddedTextCore)
 		require.Contains(t, msg.Text, "$5.50 SGD")
 		require.Contains(t, msg.Text, "Coffee")
+
+		expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 1)
+		require.NoError(t, err)
+		require.Len(t, expenses, 1)
+		require.Equal(t, appmodels.ExpenseSourceCommand, expenses[0].Source)
+	})
+
+	t.Run("long description is truncated with a note", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		userID := int64(100009)
+
+		err := b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, Username: "longdesc", FirstName: "Long"})
+		require.NoError(t, err)
+
+		update := mocks.CommandUpdate(12345, userID, "/add 5.50 "+strings.Repeat("咖啡 ", 150))
+		b.handleAddCore(ctx, mockBot, update)
+
+		require.Contains(t, mockBot.LastSentMessage().Text, "Description truncated to 200 characters")
+		expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 1)
+		require.NoError(t, err)
+		require.Len(t, expenses, 1)
+		require.Equal(t, 200, utf8.RuneCountInString(expenses[0].Description))
+		require.True(t, strings.HasSuffix(expenses[0].Description, "…"))
 	})
 
 	t.Run("invalid format sends error message", func(t *testing.T) {
@@ -512,7 +537,9 @@ func TestHandleWeekCore(t *testing.T) {
 		b.handleWeekCore(ctx, mockBot, update)
 		require.Equal(t, 1, mockBot.SentMessageCount())
 		msg := mockBot.LastSentMessage()
-		require.Contains(t, msg.Text, "This Week's Expenses")
+		start, end, err := periodRange(periodWeek, 0, periodWeekStart, b.displayLocation, b.now())
+		require.NoError(t, err)
+		require.Contains(t, msg.Text, formatWeekLabel(start, end, b.displayLocation))
 		require.Contains(t, msg.Text, totalLabelCoreTest)
 		require.Contains(t, msg.Text, "$30.00")
 	})
@@ -587,7 +614,9 @@ func TestHandleWeekCore(t *testing.T) {
 		require.Equal(t, 1, mockBot.SentMessageCount())
 
 		msg := mockBot.LastSentMessage()
-		require.Contains(t, msg.Text, "This Week's Expenses")
+		start, end, err := periodRange(periodWeek, 0, periodWeekStart, b.displayLocation, b.now())
+		require.NoError(t, err)
+		require.Contains(t, msg.Text, formatWeekLabel(start, end, b.displayLocation))
 		require.Contains(t, msg.Text, "Monday Local")
 		require.NotContains(t, msg.Text, "Sunday Local")
 		require.Contains(t, msg.Text, "$12.00")
@@ -3,16 +3,23 @@ package bot
 import (
 	"context"
 	"fmt"
-	"sort"
 	"strings"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
 	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
 
 	"gitlab.com/yelinaung/expense-bot/internal/logger"
 )
 
+const (
+	setCurrencyCallbackPrefix = "setcur_"
+	// setCurrencyCallbackFmt carries the user the suggestion is for and the
+	// currency code.
+	setCurrencyCallbackFmt = setCurrencyCallbackPrefix + "%d_%s"
+)
+
 // handleSetCurrency handles the /setcurrency command.
 func (b *Bot) handleSetCurrency(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
 	b.handleSetCurrencyCore(ctx, tgBot, update)
@@ -44,30 +51,96 @@ func (b *Bot) handleSetCurrencyCore(ctx context.Context, tg TelegramAPI, update
 
 	// Validate currency
 	if _, ok := appmodels.SupportedCurrencies[currency]; !ok {
-		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:    chatID,
-			Text:      fmt.Sprintf("❌ Unknown currency: <code>%s</code>\n\nUse /setcurrency to see supported currencies.", currency),
-			ParseMode: models.ParseModeHTML,
-		})
+		text := fmt.Sprintf("❌ Unknown currency: <code>%s</code>", botfmt.EscapeHTML(echoText(currency)))
+		params := &bot.SendMessageParams{ChatID: chatID, ParseMode: models.ParseModeHTML}
+		if suggestions := suggestCurrencies(currency); len(suggestions) > 0 {
+			params.Text = text + "\n\nDid you mean one of these?"
+			params.ReplyMarkup = inputSuggestionKeyboard(setCurrencyCallbackFmt, userID, suggestions)
+		} else {
+			params.Text = text + "\n\nUse /setcurrency to see supported currencies."
+		}
+		_, _ = tg.SendMessage(ctx, params)
 		return
 	}
 
-	// Update user's default currency
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      b.setDefaultCurrency(ctx, userID, currency),
+		ParseMode: models.ParseModeHTML,
+	})
+}
+
+// setDefaultCurrency stores a supported currency as the user's default and
+// returns the reply.
+func (b *Bot) setDefaultCurrency(ctx context.Context, userID int64, currency string) string {
 	if err := b.userRepo.UpdateDefaultCurrency(ctx, userID, currency); err != nil {
 		logger.Log.Error().Err(err).Int64("user_id", userID).Str("currency", currency).Msg("Failed to update default currency")
-		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: chatID,
-			Text:   "❌ Failed to update currency. Please try again.",
-		})
-		return
+		return "❌ Failed to update currency. Please try again."
 	}
 
 	symbol := appmodels.SupportedCurrencies[currency]
 	logger.Log.Info().Int64("user_id", userID).Str("currency", currency).Msg("Default currency updated")
 
-	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:    chatID,
-		Text:      fmt.Sprintf("✅ Default currency set to <b>%s</b> (%s)\n\nNew expenses will use this currency unless you specify otherwise.", currency, symbol),
+	text := fmt.Sprintf("✅ Default currency set to <b>%s</b> (%s)\n\nNew expenses will use this currency unless you specify otherwise.", currency, symbol)
+	return text + b.budgetCurrencyNote(ctx, userID, currency)
+}
+
+// suggestCurrencies returns up to maxInputSuggestions supported currencies
+// for query, the way suggestTimezones does: codes starting with it first,
+// else the codes it is a likely typo of, so "SDG" suggests SGD.
+func suggestCurrencies(query string) []string {
+	query = strings.ToUpper(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+	codes := supportedCurrencyCodes()
+
+	var prefixed []string
+	for _, code := range codes {
+		if strings.HasPrefix(code, query) {
+			prefixed = append(prefixed, code)
+			if len(prefixed) == maxInputSuggestions {
+				break
+			}
+		}
+	}
+	if len(prefixed) > 0 {
+		return prefixed
+	}
+	return closestNames(query, codes, maxInputSuggestions)
+}
+
+// handleSetCurrencyCallback handles the suggestion buttons of /setcurrency.
+func (b *Bot) handleSetCurrencyCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleSetCurrencyCallbackCore(ctx, tgBot, update)
+}
+
+// handleSetCurrencyCallbackCore sets the picked currency. Only the user who
+// got the suggestions can pick one.
+func (b *Bot) handleSetCurrencyCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	userID, currency, ok := parseInputSuggestionCallback(query.Data, setCurrencyCallbackPrefix)
+	if _, supported := appmodels.SupportedCurrencies[currency]; !ok || !supported {
+		answerCallback(ctx, tg, query)
+		return
+	}
+	if userID != query.From.ID {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            staleNotOwnerText,
+		})
+		return
+	}
+	answerCallback(ctx, tg, query)
+
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    query.Message.Message.Chat.ID,
+		MessageID: query.Message.Message.ID,
+		Text:      b.setDefaultCurrency(ctx, userID, currency),
 		ParseMode: models.ParseModeHTML,
 	})
 }
@@ -120,14 +193,7 @@ func (b *Bot) buildCurrencyListMessage() string {
 	sb.WriteString("Usage: <code>/setcurrency USD</code>\n\n")
 	sb.WriteString("<b>Supported currencies:</b>\n")
 
-	// Sort currencies alphabetically
-	codes := make([]string, 0, len(appmodels.SupportedCurrencies))
-	for code := range appmodels.SupportedCurrencies {
-		codes = append(codes, code)
-	}
-	sort.Strings(codes)
-
-	for _, code := range codes {
+	for _, code := range supportedCurrencyCodes() {
 		symbol := appmodels.SupportedCurrencies[code]
 		fmt.Fprintf(&sb, "• %s (%s)\n", code, symbol)
 	}
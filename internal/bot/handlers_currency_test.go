@@ -26,6 +26,7 @@ func TestHandleSetCurrencyCore(t *testing.T) {
 		userRepo:     userRepo,
 		categoryRepo: categoryRepo,
 		expenseRepo:  expenseRepo,
+		budgetRepo:   repository.NewBudgetRepository(tx),
 	}
 
 	user := &models.User{ID: 12345, Username: "currencyuser", FirstName: "Currency", LastName: "User"}
@@ -65,6 +66,29 @@ func TestHandleSetCurrencyCore(t *testing.T) {
 		require.Contains(t, msg.Text, "XYZ")
 	})
 
+	t.Run("suggests corrections and applies the picked one", func(t *testing.T) {
+		mockBot.Reset()
+
+		b.handleSetCurrencyCore(ctx, mockBot, mocks.CommandUpdate(12345, user.ID, "/setcurrency SDG"))
+
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, "Unknown currency: <code>SDG</code>")
+		keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+		require.Equal(t, "SGD", keyboard.InlineKeyboard[0][0].Text)
+		data := keyboard.InlineKeyboard[0][0].CallbackData
+
+		stranger := mocks.NewMockBot()
+		b.handleSetCurrencyCallbackCore(ctx, stranger, mocks.CallbackQueryUpdate(12345, 999, 1, data))
+		require.Equal(t, staleNotOwnerText, stranger.AnsweredCallbacks[0].Text)
+
+		mockBot.Reset()
+		b.handleSetCurrencyCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(12345, user.ID, 1, data))
+		require.Contains(t, mockBot.LastEditedMessage().Text, "Default currency set to <b>SGD</b>")
+		currency, err := userRepo.GetDefaultCurrency(ctx, user.ID)
+		require.NoError(t, err)
+		require.Equal(t, "SGD", currency)
+	})
+
 	t.Run("shows currency list when no arguments", func(t *testing.T) {
 		mockBot.Reset()
 
@@ -248,3 +272,13 @@ func TestCurrencyHandlerWrappers(t *testing.T) {
 		b.handleShowCurrency(ctx, tgBot, &tgmodels.Update{})
 	})
 }
+
+func TestSuggestCurrencies(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "SGD", suggestCurrencies("SDG")[0], "a swapped letter is one typo")
+	require.Equal(t, []string{"SGD"}, suggestCurrencies("sgdd"))
+	require.Equal(t, []string{"USD"}, suggestCurrencies("US"))
+	require.LessOrEqual(t, len(suggestCurrencies("S")), maxInputSuggestions)
+	require.Empty(t, suggestCurrencies("QQQ"))
+}
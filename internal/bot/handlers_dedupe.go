@@ -0,0 +1,467 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+const (
+	// dedupeSimilarityThreshold is the minimum normalized description
+	// similarity for two same-day, same-amount expenses to count as
+	// duplicates.
+	dedupeSimilarityThreshold = 0.8
+	// dedupeMaxGroups caps how many duplicate groups one run presents.
+	dedupeMaxGroups = 20
+
+	dedupeCallbackPrefix = "dedupe_"
+	dedupeKeepFmt        = "dedupe_keep_%s_%d_%d"
+	dedupePageFmt        = "dedupe_page_%s_%d_%d"
+	dedupeSkipFmt        = "dedupe_skip_%s_%d_%d"
+	dedupeKeepButtonFmt  = "Keep #%d, delete others"
+	dedupeKeepAllText    = "✅ Keep all"
+	dedupeMonthKeyLayout = "2006-01"
+	dedupeDayLayout      = "02 Jan"
+
+	dedupeUsageMsg   = "❌ Invalid month.\n\nUsage: <code>/dedupe</code> or <code>/dedupe YYYY-MM</code>"
+	dedupeFailMsg    = "❌ Failed to look for duplicates. Please try again."
+	dedupeChangedMsg = "This duplicate group has changed. Showing the current list."
+)
+
+// normalizeDedupeDescription lowercases s and collapses whitespace so that
+// "Coffee  " and "coffee" compare equal.
+func normalizeDedupeDescription(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// descriptionSimilarity returns a similarity ratio in [0, 1] between two
+// normalized descriptions based on their Levenshtein distance.
+func descriptionSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshteinDistance(ra, rb))/float64(longest)
+}
+
+// levenshteinDistance returns the edit distance between a and b.
+func levenshteinDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// dedupeDescription is the text compared for duplicates: the merchant when
+// set, since that is what lists show, otherwise the description.
+func dedupeDescription(exp *appmodels.Expense) string {
+	if exp.Merchant != "" {
+		return normalizeDedupeDescription(exp.Merchant)
+	}
+	return normalizeDedupeDescription(exp.Description)
+}
+
+// groupDuplicateExpenses groups candidates that share amount, currency and
+// local day in loc and whose descriptions are similar. Within a bucket an
+// expense joins the first group whose first member it resembles. Only groups
+// with two or more expenses are returned, at most dedupeMaxGroups, ordered
+// by day and then by the lowest expense ID.
+func groupDuplicateExpenses(candidates []appmodels.Expense, loc *time.Location) [][]appmodels.Expense {
+	type bucketKey struct {
+		day      string
+		amount   string
+		currency string
+	}
+
+	var keys []bucketKey
+	buckets := make(map[bucketKey][][]appmodels.Expense)
+	for i := range candidates {
+		exp := candidates[i]
+		key := bucketKey{
+			day:      exp.CreatedAt.In(loc).Format(time.DateOnly),
+			amount:   exp.Amount.StringFixed(2),
+			currency: exp.Currency,
+		}
+		if _, ok := buckets[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		groups := buckets[key]
+		desc := dedupeDescription(&exp)
+		placed := false
+		for g := range groups {
+			if descriptionSimilarity(desc, dedupeDescription(&groups[g][0])) >= dedupeSimilarityThreshold {
+				groups[g] = append(groups[g], exp)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			groups = append(groups, []appmodels.Expense{exp})
+		}
+		buckets[key] = groups
+	}
+
+	var result [][]appmodels.Expense
+	for _, key := range keys {
+		for _, group := range buckets[key] {
+			if len(group) < 2 {
+				continue
+			}
+			result = append(result, group)
+			if len(result) == dedupeMaxGroups {
+				return result
+			}
+		}
+	}
+	return result
+}
+
+// loadDuplicateGroups finds the user's duplicate groups in the month named by
+// monthArg (empty for the current month).
+func (b *Bot) loadDuplicateGroups(
+	ctx context.Context,
+	userID int64,
+	monthArg string,
+) ([][]appmodels.Expense, time.Time, error) {
+	current := b.now().In(normalizeLocation(b.displayLocation))
+	startDate, endDate, err := resolveMonthArgAt(monthArg, current)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	candidates, err := b.expenseRepo.GetDuplicateCandidates(
+		ctx, userID, startDate, endDate, current.Location().String())
+	if err != nil {
+		return nil, startDate, fmt.Errorf("failed to fetch duplicate candidates: %w", err)
+	}
+
+	return groupDuplicateExpenses(candidates, current.Location()), startDate, nil
+}
+
+// buildDedupeKeyboard builds one "keep this one" button per expense, a Keep
+// all button that moves on to the next group, and page navigation. Every
+// button carries userID, the user who ran /dedupe.
+func buildDedupeKeyboard(
+	group []appmodels.Expense,
+	monthKey string,
+	page, totalPages int,
+	userID int64,
+) *models.InlineKeyboardMarkup {
+	rows := make([][]models.InlineKeyboardButton, 0, len(group)+2)
+	for i := range group {
+		rows = append(rows, []models.InlineKeyboardButton{{
+			Text:         fmt.Sprintf(dedupeKeepButtonFmt, group[i].UserExpenseNumber),
+			CallbackData: fmt.Sprintf(dedupeKeepFmt, monthKey, group[i].ID, userID),
+		}})
+	}
+	rows = append(rows, []models.InlineKeyboardButton{{
+		Text:         dedupeKeepAllText,
+		CallbackData: fmt.Sprintf(dedupeSkipFmt, monthKey, page+1, userID),
+	}})
+
+	var nav []models.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, models.InlineKeyboardButton{
+			Text:         uncategorizedPrevButtonText,
+			CallbackData: fmt.Sprintf(dedupePageFmt, monthKey, page-1, userID),
+		})
+	}
+	if page < totalPages-1 {
+		nav = append(nav, models.InlineKeyboardButton{
+			Text:         uncategorizedNextButtonText,
+			CallbackData: fmt.Sprintf(dedupePageFmt, monthKey, page+1, userID),
+		})
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// buildDedupeView renders one duplicate group of userID per page. A nil
+// keyboard means there is nothing left to review.
+func (b *Bot) buildDedupeView(
+	groups [][]appmodels.Expense,
+	startDate time.Time,
+	page int,
+	userID int64,
+) (string, *models.InlineKeyboardMarkup) {
+	monthLabel := startDate.Format(statementMonthLayout)
+	if len(groups) == 0 {
+		return fmt.Sprintf("✅ No duplicate expenses found for %s.", monthLabel), nil
+	}
+	if page >= len(groups) {
+		return fmt.Sprintf("✅ Finished reviewing duplicates for %s.", monthLabel), nil
+	}
+	page = max(0, page)
+
+	group := groups[page]
+	first := &group[0]
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🔁 <b>Possible Duplicates</b> (%s)\n", monthLabel)
+	fmt.Fprintf(&sb, "Group %d/%d · %s · %s%s %s\n\n",
+		page+1, len(groups),
+		first.CreatedAt.In(normalizeLocation(b.displayLocation)).Format(dedupeDayLayout),
+		botfmt.EscapeHTML(botfmt.CurrencySymbol(first.Currency)), botfmt.FormatAmount(first.Amount, first.Currency), botfmt.EscapeHTML(first.Currency))
+	for i := range group {
+		sb.WriteString(botfmt.ExpenseListItem(&group[i], nil, b.displayLocation))
+	}
+	if len(groups) == dedupeMaxGroups {
+		fmt.Fprintf(&sb, "\nShowing the first %d groups. Run /dedupe again after cleaning up.", dedupeMaxGroups)
+	}
+
+	monthKey := startDate.Format(dedupeMonthKeyLayout)
+	return sb.String(), buildDedupeKeyboard(group, monthKey, page, len(groups), userID)
+}
+
+// handleDedupe handles the /dedupe command.
+func (b *Bot) handleDedupe(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleDedupeCore(ctx, tgBot, update)
+}
+
+// handleDedupeCore is the testable implementation of handleDedupe. It looks
+// for confirmed expenses in the month with the same amount, currency and day
+// and similar descriptions, and presents each group for cleanup.
+func (b *Bot) handleDedupeCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	monthArg := strings.TrimSpace(extractCommandArgs(update.Message.Text, "/dedupe"))
+
+	current := b.now().In(normalizeLocation(b.displayLocation))
+	if _, _, err := resolveMonthArgAt(monthArg, current); err != nil {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      dedupeUsageMsg,
+			ParseMode: models.ParseModeHTML,
+		})
+		return
+	}
+
+	groups, startDate, err := b.loadDuplicateGroups(ctx, userID, monthArg)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load duplicate groups")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   dedupeFailMsg,
+		})
+		return
+	}
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(userID)).
+		Str("month", startDate.Format(dedupeMonthKeyLayout)).
+		Int("groups", len(groups)).
+		Msg("Dedupe scan")
+
+	text, keyboard := b.buildDedupeView(groups, startDate, 0, userID)
+	params := &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+	_, _ = tg.SendMessage(ctx, params)
+}
+
+// handleDedupeCallback handles the /dedupe keep and page buttons.
+func (b *Bot) handleDedupeCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleDedupeCallbackCore(ctx, tgBot, update)
+}
+
+// parseDedupeCallback splits dedupe callback data into its action, month,
+// value and the user who ran /dedupe. Keyboards sent before the requester
+// was recorded have none, and report 0.
+func parseDedupeCallback(data string) (action, monthKey string, value int, owner int64, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(data, dedupeCallbackPrefix), "_")
+	if len(parts) != 3 && len(parts) != 4 {
+		return "", "", 0, 0, false
+	}
+	value, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return "", "", 0, 0, false
+	}
+	if len(parts) == 4 {
+		owner, err = strconv.ParseInt(parts[3], 10, 64)
+		if err != nil {
+			return "", "", 0, 0, false
+		}
+	}
+	return parts[0], parts[1], value, owner, true
+}
+
+// handleDedupeCallbackCore is the testable implementation of
+// handleDedupeCallback. Callback data is
+// dedupe_keep_<month>_<expenseID>_<userID>,
+// dedupe_skip_<month>_<nextPage>_<userID> for Keep all, or
+// dedupe_page_<month>_<page>_<userID>. Only the user who ran /dedupe may
+// use the buttons. Groups are recomputed on every tap so buttons never act
+// on a stale view.
+func (b *Bot) handleDedupeCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	action, monthKey, value, owner, ok := parseDedupeCallback(query.Data)
+	if !ok {
+		answerCallback(ctx, tg, query)
+		return
+	}
+	if owner != 0 && owner != query.From.ID {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            staleNotOwnerText,
+			ShowAlert:       true,
+		})
+		return
+	}
+
+	userID := query.From.ID
+	groups, startDate, err := b.loadDuplicateGroups(ctx, userID, monthKey)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load duplicate groups")
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            dedupeFailMsg,
+		})
+		return
+	}
+
+	page := value
+	switch action {
+	case "keep":
+		if !b.deleteButtonAllowed(ctx, tg, query) {
+			return
+		}
+		page = b.keepDuplicateCore(ctx, tg, query, groups, value)
+		groups, startDate, err = b.loadDuplicateGroups(ctx, userID, monthKey)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to reload duplicate groups")
+			return
+		}
+	case "skip":
+		logger.Log.Info().
+			Str("user_hash", logger.HashUserID(userID)).
+			Str("month", monthKey).
+			Int("group", value-1).
+			Msg("Duplicate group kept")
+		answerCallback(ctx, tg, query)
+	case "page":
+		answerCallback(ctx, tg, query)
+	default:
+		answerCallback(ctx, tg, query)
+		return
+	}
+
+	text, keyboard := b.buildDedupeView(groups, startDate, page, userID)
+	params := &bot.EditMessageTextParams{
+		ChatID:    query.Message.Message.Chat.ID,
+		MessageID: query.Message.Message.ID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+	_, _ = tg.EditMessageText(ctx, params)
+}
+
+// keepDuplicateCore keeps keepID and deletes the other expenses of its group,
+// recording each deletion in the expense history. It answers the callback
+// and returns the page to show next, which is the position the resolved
+// group had.
+func (b *Bot) keepDuplicateCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	query *models.CallbackQuery,
+	groups [][]appmodels.Expense,
+	keepID int,
+) int {
+	userID := query.From.ID
+	for page, group := range groups {
+		var deleteIDs []int
+		var doomed []*appmodels.Expense
+		found := false
+		for i := range group {
+			if group[i].ID == keepID {
+				found = true
+				continue
+			}
+			deleteIDs = append(deleteIDs, group[i].ID)
+			doomed = append(doomed, &group[i])
+		}
+		if !found {
+			continue
+		}
+
+		// The source chats go with the expenses, so they are looked up
+		// first for the history notices.
+		sourceChats := make([]int64, len(doomed))
+		for i, expense := range doomed {
+			sourceChats[i] = b.expenseSourceChat(ctx, expense.ID)
+		}
+
+		deleted, err := b.expenses().DeleteMany(ctx, userID, deleteIDs)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to delete duplicate expenses")
+			_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+				CallbackQueryID: query.ID,
+				Text:            dedupeFailMsg,
+			})
+			return page
+		}
+
+		logger.Log.Info().
+			Str("user_hash", logger.HashUserID(userID)).
+			Int("kept_expense_id", keepID).
+			Ints("deleted_expense_ids", deleteIDs).
+			Msg("Duplicate expenses deleted")
+		for i, expense := range doomed {
+			b.auditExpenseDelete(ctx, tg, userID, expense, sourceChats[i])
+		}
+
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            fmt.Sprintf("🗑 Deleted %d duplicate(s).", deleted),
+		})
+		return page
+	}
+
+	_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: query.ID,
+		Text:            dedupeChangedMsg,
+	})
+	return 0
+}
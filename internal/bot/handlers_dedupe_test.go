@@ -0,0 +1,268 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	dedupeCoffeeTest   = "Coffee"
+	dedupeMonthKeyTest = "2026-03"
+)
+
+func dedupeExpense(id int, amount, desc string, at time.Time) appmodels.Expense {
+	return appmodels.Expense{
+		ID:                id,
+		UserExpenseNumber: int64(id),
+		Amount:            mustParseDecimal(amount),
+		Currency:          currencyCodeSGD,
+		Description:       desc,
+		CreatedAt:         at,
+	}
+}
+
+func TestDescriptionSimilarity(t *testing.T) {
+	t.Parallel()
+
+	require.InDelta(t, 1.0, descriptionSimilarity("", ""), 0.001)
+	require.InDelta(t, 1.0, descriptionSimilarity("coffee", "coffee"), 0.001)
+	require.InDelta(t, 5.0/6.0, descriptionSimilarity("coffee", "cofee"), 0.001)
+	require.Less(t, descriptionSimilarity("coffee", "taxi"), dedupeSimilarityThreshold)
+	require.Equal(t, "grab to work", normalizeDedupeDescription("  Grab   TO work "))
+}
+
+func TestGroupDuplicateExpenses(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC)
+	nextDay := day.AddDate(0, 0, 1)
+
+	t.Run("groups same amount, day and similar description", func(t *testing.T) {
+		t.Parallel()
+		candidates := []appmodels.Expense{
+			dedupeExpense(1, "4.50", dedupeCoffeeTest, day),
+			dedupeExpense(2, "4.50", " coffee ", day.Add(2*time.Hour)),
+			dedupeExpense(3, "4.50", "Cofee", day.Add(3*time.Hour)),
+			dedupeExpense(4, "4.50", "Taxi", day.Add(4*time.Hour)),
+		}
+		groups := groupDuplicateExpenses(candidates, time.UTC)
+		require.Len(t, groups, 1)
+		ids := make([]int, 0, len(groups[0]))
+		for i := range groups[0] {
+			ids = append(ids, groups[0][i].ID)
+		}
+		require.Equal(t, []int{1, 2, 3}, ids)
+	})
+
+	t.Run("different day, amount or currency are not duplicates", func(t *testing.T) {
+		t.Parallel()
+		usd := dedupeExpense(4, "4.50", dedupeCoffeeTest, day)
+		usd.Currency = currencyCodeUSD
+		candidates := []appmodels.Expense{
+			dedupeExpense(1, "4.50", dedupeCoffeeTest, day),
+			dedupeExpense(2, "4.50", dedupeCoffeeTest, nextDay),
+			dedupeExpense(3, "4.60", dedupeCoffeeTest, day),
+			usd,
+		}
+		require.Empty(t, groupDuplicateExpenses(candidates, time.UTC))
+	})
+
+	t.Run("local day decides the bucket", func(t *testing.T) {
+		t.Parallel()
+		loc := time.FixedZone("GMT+8", 8*60*60)
+		// 15:30 and 16:30 UTC fall on different days in GMT+8.
+		candidates := []appmodels.Expense{
+			dedupeExpense(1, "4.50", dedupeCoffeeTest, time.Date(2026, 3, 4, 15, 30, 0, 0, time.UTC)),
+			dedupeExpense(2, "4.50", dedupeCoffeeTest, time.Date(2026, 3, 4, 16, 30, 0, 0, time.UTC)),
+		}
+		require.Len(t, groupDuplicateExpenses(candidates, time.UTC), 1)
+		require.Empty(t, groupDuplicateExpenses(candidates, loc))
+	})
+
+	t.Run("caps the number of groups", func(t *testing.T) {
+		t.Parallel()
+		var candidates []appmodels.Expense
+		for i := range dedupeMaxGroups + 5 {
+			at := day.Add(time.Duration(i) * time.Minute)
+			amount := fmt.Sprintf("%d.00", i+1)
+			candidates = append(candidates,
+				dedupeExpense(2*i, amount, dedupeCoffeeTest, at),
+				dedupeExpense(2*i+1, amount, dedupeCoffeeTest, at))
+		}
+		require.Len(t, groupDuplicateExpenses(candidates, time.UTC), dedupeMaxGroups)
+	})
+}
+
+func TestBuildDedupeKeyboard(t *testing.T) {
+	t.Parallel()
+
+	group := []appmodels.Expense{{ID: 7, UserExpenseNumber: 3}, {ID: 8, UserExpenseNumber: 4}}
+	keyboard := buildDedupeKeyboard(group, dedupeMonthKeyTest, 1, 3, 366)
+
+	require.Len(t, keyboard.InlineKeyboard, 4)
+	require.Equal(t, "Keep #3, delete others", keyboard.InlineKeyboard[0][0].Text)
+	require.Equal(t, "dedupe_keep_2026-03_7_366", keyboard.InlineKeyboard[0][0].CallbackData)
+	require.Equal(t, "dedupe_skip_2026-03_2_366", keyboard.InlineKeyboard[2][0].CallbackData)
+	require.Len(t, keyboard.InlineKeyboard[3], 2)
+	require.Equal(t, "dedupe_page_2026-03_0_366", keyboard.InlineKeyboard[3][0].CallbackData)
+	require.Equal(t, "dedupe_page_2026-03_2_366", keyboard.InlineKeyboard[3][1].CallbackData)
+}
+
+func TestParseDedupeCallback(t *testing.T) {
+	t.Parallel()
+
+	action, monthKey, value, owner, ok := parseDedupeCallback("dedupe_keep_2026-03_7_366")
+	require.True(t, ok)
+	require.Equal(t, "keep", action)
+	require.Equal(t, dedupeMonthKeyTest, monthKey)
+	require.Equal(t, 7, value)
+	require.Equal(t, int64(366), owner)
+
+	_, _, value, owner, ok = parseDedupeCallback("dedupe_page_2026-03_2")
+	require.True(t, ok, "keyboards without a requester still work")
+	require.Equal(t, 2, value)
+	require.Zero(t, owner)
+
+	for _, data := range []string{"dedupe_keep_x", "dedupe_keep_2026-03_x_1", "dedupe_keep_2026-03_1_x", "dedupe_a_b_1_2_3"} {
+		_, _, _, _, ok = parseDedupeCallback(data)
+		require.False(t, ok, data)
+	}
+}
+
+func TestDedupeCallbackRejectsOtherUsers(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{}
+	for _, data := range []string{
+		fmt.Sprintf(dedupeKeepFmt, dedupeMonthKeyTest, 7, 366),
+		fmt.Sprintf(dedupeSkipFmt, dedupeMonthKeyTest, 1, 366),
+		fmt.Sprintf(dedupePageFmt, dedupeMonthKeyTest, 1, 366),
+	} {
+		mockBot := mocks.NewMockBot()
+		b.handleDedupeCallbackCore(context.Background(), mockBot, mocks.CallbackQueryUpdate(999001, 999001, 10, data))
+
+		require.Len(t, mockBot.AnsweredCallbacks, 1, data)
+		require.Equal(t, staleNotOwnerText, mockBot.AnsweredCallbacks[0].Text, data)
+		require.True(t, mockBot.AnsweredCallbacks[0].ShowAlert, data)
+		require.Empty(t, mockBot.EditedMessages, data)
+	}
+}
+
+func TestDedupeFlow(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.nowFunc = func() time.Time { return time.Date(2026, 3, 20, 12, 0, 0, 0, time.UTC) }
+	userID := int64(500366)
+
+	err := b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Dedupe"})
+	require.NoError(t, err)
+
+	createAt := func(desc, amount string, at time.Time) *appmodels.Expense {
+		expense := &appmodels.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal(amount),
+			Currency:    currencyCodeSGD,
+			Description: desc,
+			Status:      appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		_, err := pool.Exec(ctx, `UPDATE expenses SET created_at = $2 WHERE id = $1`, expense.ID, at)
+		require.NoError(t, err)
+		return expense
+	}
+
+	day := time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC)
+	coffee1 := createAt(dedupeCoffeeTest, "4.50", day)
+	coffee2 := createAt("coffee ", "4.50", day.Add(time.Hour))
+	createAt("Lunch", "4.50", day.Add(2*time.Hour))
+	taxi1 := createAt("Taxi home", "18.00", day.AddDate(0, 0, 1))
+	taxi2 := createAt("taxi  home", "18.00", day.AddDate(0, 0, 1).Add(time.Hour))
+	createAt(dedupeCoffeeTest, "4.50", day.AddDate(0, 1, 0))
+
+	t.Run("lists groups for the month", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleDedupeCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/dedupe "+dedupeMonthKeyTest))
+
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, "Group 1/2")
+		require.Contains(t, msg.Text, dedupeCoffeeTest)
+		require.NotContains(t, msg.Text, "Lunch")
+		keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+		require.Equal(t, fmt.Sprintf(dedupeKeepFmt, dedupeMonthKeyTest, coffee1.ID, userID),
+			keyboard.InlineKeyboard[0][0].CallbackData)
+	})
+
+	t.Run("rejects an invalid month", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleDedupeCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/dedupe soon"))
+		require.Equal(t, dedupeUsageMsg, mockBot.LastSentMessage().Text)
+	})
+
+	t.Run("keep all moves on without deleting", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		data := fmt.Sprintf(dedupeSkipFmt, dedupeMonthKeyTest, 1, userID)
+		b.handleDedupeCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 10, data))
+
+		edited := mockBot.LastEditedMessage()
+		require.NotNil(t, edited)
+		require.Contains(t, edited.Text, "Group 2/2")
+		require.Contains(t, edited.Text, "Taxi home")
+
+		_, err := b.expenseRepo.GetByID(ctx, coffee2.ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("keep one deletes the others in its group", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		data := fmt.Sprintf(dedupeKeepFmt, dedupeMonthKeyTest, taxi2.ID, userID)
+		b.handleDedupeCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 10, data))
+
+		require.Len(t, mockBot.AnsweredCallbacks, 1)
+		require.Contains(t, mockBot.AnsweredCallbacks[0].Text, "Deleted 1")
+
+		_, err := b.expenseRepo.GetByID(ctx, taxi1.ID)
+		require.Error(t, err)
+		_, err = b.expenseRepo.GetByID(ctx, taxi2.ID)
+		require.NoError(t, err)
+		_, err = b.expenseRepo.GetByID(ctx, coffee2.ID)
+		require.NoError(t, err, "other groups are untouched")
+
+		edited := mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, "Finished reviewing duplicates")
+	})
+
+	t.Run("stale keep button does nothing", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		data := fmt.Sprintf(dedupeKeepFmt, dedupeMonthKeyTest, taxi1.ID, userID)
+		b.handleDedupeCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 10, data))
+
+		require.Equal(t, dedupeChangedMsg, mockBot.AnsweredCallbacks[0].Text)
+		edited := mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, "Group 1/1")
+	})
+
+	t.Run("other users cannot act on the groups from an old keyboard", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		data := fmt.Sprintf("dedupe_keep_%s_%d", dedupeMonthKeyTest, coffee1.ID)
+		b.handleDedupeCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(999001, 999001, 10, data))
+
+		require.Equal(t, dedupeChangedMsg, mockBot.AnsweredCallbacks[0].Text)
+		_, err := b.expenseRepo.GetByID(ctx, coffee2.ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("callback with malformed data is only answered", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		update := mocks.CallbackQueryUpdate(userID, userID, 10, "dedupe_keep_x")
+		b.handleDedupeCallbackCore(ctx, mockBot, update)
+		require.Len(t, mockBot.AnsweredCallbacks, 1)
+		require.Empty(t, mockBot.EditedMessages)
+	})
+}
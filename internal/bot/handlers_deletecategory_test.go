@@ -48,7 +48,7 @@ func TestHandleDeleteCategoryCore(t *testing.T) {
 	})
 
 	t.Run("deletes category with no expenses", func(t *testing.T) {
-		cat, err := b.categoryRepo.Create(ctx, "Delete Me 910")
+		cat, err := b.categoryRepo.CreateOwned(ctx, userID, "Delete Me 910")
 		require.NoError(t, err)
 		require.NotNil(t, cat)
 		b.invalidateCategoryCache()
@@ -65,7 +65,7 @@ func TestHandleDeleteCategoryCore(t *testing.T) {
 	})
 
 	t.Run("deletes category and uncategorizes expenses", func(t *testing.T) {
-		cat, err := b.categoryRepo.Create(ctx, "Has Expenses 910")
+		cat, err := b.categoryRepo.CreateOwned(ctx, userID, "Has Expenses 910")
 		require.NoError(t, err)
 		b.invalidateCategoryCache()
 
@@ -96,8 +96,42 @@ func TestHandleDeleteCategoryCore(t *testing.T) {
 		require.Nil(t, updated.CategoryID)
 	})
 
+	t.Run("hides a shared category for the caller only", func(t *testing.T) {
+		otherID := int64(910002)
+		require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: otherID, FirstName: "Other"}))
+		cat, err := b.categoryRepo.Create(ctx, "Shared 910")
+		require.NoError(t, err)
+		b.invalidateCategoryCache()
+
+		mine := &appmodels.Expense{UserID: userID, Amount: mustParseDecimal("4.00"), Currency: "SGD", CategoryID: &cat.ID}
+		require.NoError(t, b.expenseRepo.Create(ctx, mine))
+		theirs := &appmodels.Expense{UserID: otherID, Amount: mustParseDecimal("6.00"), Currency: "SGD", CategoryID: &cat.ID}
+		require.NoError(t, b.expenseRepo.Create(ctx, theirs))
+
+		mockBot := mocks.NewMockBot()
+		b.handleDeleteCategoryCore(ctx, mockBot, mocks.CommandUpdate(chatID, userID, "/deletecategory shared 910"))
+		text := mockBot.LastSentMessage().Text
+		require.Contains(t, text, "hidden")
+		require.Contains(t, text, "1 expense(s) have been uncategorized")
+
+		stored, err := b.categoryRepo.GetByID(ctx, cat.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Shared 910", stored.Name)
+		_, err = b.findUserCategory(ctx, userID, "Shared 910")
+		require.ErrorIs(t, err, errCategoryNotFound)
+		_, err = b.findUserCategory(ctx, otherID, "Shared 910")
+		require.NoError(t, err)
+
+		updated, err := b.expenseRepo.GetByID(ctx, mine.ID)
+		require.NoError(t, err)
+		require.Nil(t, updated.CategoryID)
+		untouched, err := b.expenseRepo.GetByID(ctx, theirs.ID)
+		require.NoError(t, err)
+		require.Equal(t, &cat.ID, untouched.CategoryID)
+	})
+
 	t.Run("handles bot mention in command", func(t *testing.T) {
-		cat, err := b.categoryRepo.Create(ctx, "Mention Del 910")
+		cat, err := b.categoryRepo.CreateOwned(ctx, userID, "Mention Del 910")
 		require.NoError(t, err)
 		require.NotNil(t, cat)
 		b.invalidateCategoryCache()
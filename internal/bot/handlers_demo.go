@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/devtools"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+const (
+	demoToolsDisabledMsg = "⛔ Demo tools are disabled. Set ENABLE_DEMO_TOOLS=true to use them."
+	seedDemoUsageMsg     = "Usage: <code>/seeddemo [days]</code> with 1 to %d days."
+)
+
+// demoToolsAllowed reports whether the demo commands may run for the
+// sender, and tells them why not otherwise.
+func (b *Bot) demoToolsAllowed(ctx context.Context, tg TelegramAPI, message *models.Message) bool {
+	text := ""
+	switch {
+	case !b.cfg.EnableDemoTools:
+		text = demoToolsDisabledMsg
+	case !b.cfg.IsSuperAdmin(message.From.ID, message.From.Username):
+		text = onlySuperadminsMsg
+	default:
+		return true
+	}
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: message.Chat.ID,
+		Text:   text,
+	})
+	return false
+}
+
+// handleSeedDemo handles the /seeddemo command.
+func (b *Bot) handleSeedDemo(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleSeedDemoCore(ctx, tgBot, update)
+}
+
+// handleSeedDemoCore is the testable implementation of handleSeedDemo. It
+// fills the superadmin's own history with made-up expenses.
+func (b *Bot) handleSeedDemoCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || !b.demoToolsAllowed(ctx, tg, update.Message) {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+
+	days := devtools.DefaultDemoDays
+	if args := extractCommandArgs(update.Message.Text, "/seeddemo"); args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n < 1 || n > devtools.MaxDemoDays {
+			_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:    chatID,
+				Text:      fmt.Sprintf(seedDemoUsageMsg, devtools.MaxDemoDays),
+				ParseMode: models.ParseModeHTML,
+			})
+			return
+		}
+		days = n
+	}
+
+	result, err := devtools.SeedDemo(ctx, b.db, devtools.DemoOptions{
+		UserID: userID,
+		Days:   days,
+		Now:    b.now(),
+		Seed:   uint64(b.now().UnixNano()), //nolint:gosec // Only varies the demo data between runs.
+	})
+	if err != nil {
+		logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to seed demo data")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to seed demo data.",
+		})
+		return
+	}
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(userID)).
+		Int("confirmed", result.Confirmed).
+		Int("drafts", result.Drafts).
+		Msg("Seeded demo data")
+
+	loc := b.locationForUser(ctx, userID)
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text: fmt.Sprintf("🧪 <b>Demo data added</b>\n\n"+
+			"%d expenses from %s to %s, %d of them tagged, plus %d drafts.\n\n"+
+			"Remove them with /wipedemo.",
+			result.Confirmed,
+			result.From.In(loc).Format("Jan 2, 2006"),
+			result.To.In(loc).Format("Jan 2, 2006"),
+			result.Tagged,
+			result.Drafts),
+		ParseMode: models.ParseModeHTML,
+	})
+}
+
+// handleWipeDemo handles the /wipedemo command.
+func (b *Bot) handleWipeDemo(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleWipeDemoCore(ctx, tgBot, update)
+}
+
+// handleWipeDemoCore is the testable implementation of handleWipeDemo. It
+// removes the expenses /seeddemo added and nothing else.
+func (b *Bot) handleWipeDemoCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || !b.demoToolsAllowed(ctx, tg, update.Message) {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+
+	deleted, err := devtools.WipeDemo(ctx, b.db, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to wipe demo data")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to remove demo data.",
+		})
+		return
+	}
+
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   fmt.Sprintf("🧹 Removed %d demo expenses.", deleted),
+	})
+}
@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestDemoToolsAllowed(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	mockBot := mocks.NewMockBot()
+
+	b := &Bot{cfg: &config.Config{WhitelistedUserIDs: []int64{1}}}
+	b.handleSeedDemoCore(ctx, mockBot, mocks.CommandUpdate(1, 1, "/seeddemo"))
+	require.Equal(t, demoToolsDisabledMsg, mockBot.LastSentMessage().Text)
+
+	b.cfg.EnableDemoTools = true
+	b.handleWipeDemoCore(ctx, mockBot, mocks.CommandUpdate(2, 2, "/wipedemo"))
+	require.Equal(t, onlySuperadminsMsg, mockBot.LastSentMessage().Text)
+}
+
+func TestSeedAndWipeDemo(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.cfg.EnableDemoTools = true
+
+	adminID := b.cfg.WhitelistedUserIDs[0]
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: adminID, FirstName: "Admin"}))
+	require.NoError(t, b.expenseRepo.Create(ctx, &appmodels.Expense{
+		UserID:      adminID,
+		Amount:      decimal.NewFromInt(4),
+		Currency:    currencyCodeSGD,
+		Description: "Real coffee",
+	}))
+
+	mockBot := mocks.NewMockBot()
+	b.handleSeedDemoCore(ctx, mockBot, mocks.CommandUpdate(adminID, adminID, "/seeddemo 0"))
+	require.Contains(t, mockBot.LastSentMessage().Text, "Usage")
+
+	b.handleSeedDemoCore(ctx, mockBot, mocks.CommandUpdate(adminID, adminID, "/seeddemo 5"))
+	require.Contains(t, mockBot.LastSentMessage().Text, "Demo data added")
+	expenses, err := b.expenseRepo.GetByUserID(ctx, adminID, 100)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(expenses), 6)
+
+	b.handleWipeDemoCore(ctx, mockBot, mocks.CommandUpdate(adminID, adminID, "/wipedemo"))
+	require.Contains(t, mockBot.LastSentMessage().Text, "Removed")
+	expenses, err = b.expenseRepo.GetByUserID(ctx, adminID, 100)
+	require.NoError(t, err)
+	require.Len(t, expenses, 1)
+	require.Equal(t, "Real coffee", expenses[0].Description)
+}
@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
+)
+
+const (
+	// describeTTL is how long the bot waits for the description of an
+	// expense sent without one.
+	describeTTL = 10 * time.Minute
+
+	editTypeDescribe     = "describe"
+	describeSkipCallback = "describe_skip"
+)
+
+// needsDescription reports whether a parsed description is too short to
+// tell the expense apart later.
+func needsDescription(description string) bool {
+	return utf8.RuneCountInString(strings.TrimSpace(description)) <= 1
+}
+
+// promptDescriptionCore holds back an expense sent without a usable
+// description and asks what it was for. The next message from the user
+// becomes the description; the Skip button saves the expense as it is.
+// sourceMessageID is the message that sent the expense.
+func (b *Bot) promptDescriptionCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID, userID int64,
+	sourceMessageID int,
+	parsed *ParsedExpense,
+) {
+	currency := service.NormalizeCurrencyCode(parsed.Currency)
+	if currency == "" {
+		currency = b.getUserDefaultCurrency(ctx, userID)
+	}
+
+	kind := ""
+	if parsed.Refund {
+		kind = " refund"
+	}
+	msg, err := tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text: fmt.Sprintf("🤔 What was this %s%s for? Reply with a description.",
+			symbolAmount(parsed.Amount, currency), kind),
+		ParseMode: models.ParseModeHTML,
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{{
+				{Text: "Skip", CallbackData: describeSkipCallback},
+			}},
+		},
+	})
+	messageID := 0
+	if err == nil && msg != nil {
+		messageID = msg.ID
+	}
+
+	b.pendingEditsMu.Lock()
+	b.pendingEdits[chatID] = &pendingEdit{
+		EditType:        editTypeDescribe,
+		MessageID:       messageID,
+		UserID:          userID,
+		Parsed:          parsed,
+		SourceMessageID: sourceMessageID,
+		ExpiresAt:       b.now().Add(describeTTL),
+	}
+	b.pendingEditsMu.Unlock()
+}
+
+// cancelDescribeOnCommand drops a description capture or a typed receipt
+// when the user sends a command instead, so the command runs normally.
+func (b *Bot) cancelDescribeOnCommand(update *models.Update) {
+	if update.Message == nil || !strings.HasPrefix(update.Message.Text, "/") {
+		return
+	}
+
+	b.pendingEditsMu.Lock()
+	defer b.pendingEditsMu.Unlock()
+	pending, ok := b.pendingEdits[update.Message.Chat.ID]
+	if ok && (pending.EditType == editTypeDescribe || pending.EditType == editTypeManualReceipt) {
+		delete(b.pendingEdits, update.Message.Chat.ID)
+	}
+}
+
+// processDescribeCore saves a held-back expense with input as its
+// description. Messages from other chat members are not captured, and
+// commands cancel the capture; both are left to the normal handlers.
+func (b *Bot) processDescribeCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	userID int64,
+	pending *pendingEdit,
+	input string,
+) bool {
+	if userID != pending.UserID {
+		return false
+	}
+
+	b.pendingEditsMu.Lock()
+	delete(b.pendingEdits, chatID)
+	b.pendingEditsMu.Unlock()
+
+	if strings.HasPrefix(input, "/") {
+		return false
+	}
+
+	pending.Parsed.Description = strings.TrimSpace(input)
+	b.saveDescribedExpense(ctx, tg, chatID, pending)
+	return true
+}
+
+// saveDescribedExpense removes the Skip button and saves the held-back
+// expense.
+func (b *Bot) saveDescribedExpense(ctx context.Context, tg TelegramAPI, chatID int64, pending *pendingEdit) {
+	if pending.MessageID != 0 {
+		_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+			ChatID:    chatID,
+			MessageID: pending.MessageID,
+		})
+	}
+
+	categories, err := b.visibleCategories(ctx, pending.UserID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for described expense")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   failedFetchCategoriesMsg,
+		})
+		return
+	}
+
+	expense := b.saveExpenseCore(ctx, tg, chatID, pending.UserID, pending.Parsed, categories)
+	if expense != nil && pending.SourceMessageID != 0 {
+		b.linkSourceMessage(ctx, expense.ID, chatID, pending.SourceMessageID)
+	}
+}
+
+// handleDescribeSkipCallback handles the Skip button of a description
+// question.
+func (b *Bot) handleDescribeSkipCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleDescribeSkipCallbackCore(ctx, tgBot, update)
+}
+
+// handleDescribeSkipCallbackCore is the testable implementation of
+// handleDescribeSkipCallback. It saves the held-back expense without a
+// description.
+func (b *Bot) handleDescribeSkipCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	chatID := query.Message.Message.Chat.ID
+	messageID := query.Message.Message.ID
+
+	b.pendingEditsMu.Lock()
+	pending, ok := b.pendingEdits[chatID]
+	ok = ok && pending.EditType == editTypeDescribe && pending.MessageID == messageID
+	if ok && pending.UserID != query.From.ID {
+		b.pendingEditsMu.Unlock()
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            staleNotOwnerText,
+		})
+		return
+	}
+	if ok {
+		delete(b.pendingEdits, chatID)
+	}
+	b.pendingEditsMu.Unlock()
+
+	if !ok || b.now().After(pending.ExpiresAt) {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            pendingExpenseExpiredMsg,
+		})
+		_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+		})
+		return
+	}
+	answerCallback(ctx, tg, query)
+
+	pending.Parsed.Description = ""
+	b.saveDescribedExpense(ctx, tg, chatID, pending)
+}
@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestNeedsDescription(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, needsDescription(""))
+	require.True(t, needsDescription("  "))
+	require.True(t, needsDescription("x"))
+	require.True(t, needsDescription("☕"))
+	require.False(t, needsDescription("ok"))
+	require.False(t, needsDescription("Coffee"))
+}
+
+// countExpenses returns how many expenses userID has with description.
+func countExpenses(ctx context.Context, t *testing.T, pool database.PGXDB, userID int64, description string) int {
+	t.Helper()
+
+	var count int
+	err := pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM expenses WHERE user_id = $1 AND description = $2
+	`, userID, description).Scan(&count)
+	require.NoError(t, err)
+	return count
+}
+
+func TestDescriptionCapture(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(403001)
+	chatID := userID
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{
+		ID: userID, FirstName: "Describe", DefaultCurrency: currencyCodeSGD,
+	}))
+
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	b.nowFunc = func() time.Time { return now }
+	t.Cleanup(func() { b.nowFunc = time.Now })
+
+	prompt := func(mockBot *mocks.MockBot) int {
+		t.Helper()
+		parsed := &ParsedExpense{Amount: decimal.RequireFromString("12.50")}
+		b.promptDescriptionCore(ctx, mockBot, chatID, userID, 1, parsed)
+
+		sent := mockBot.LastSentMessage()
+		require.Contains(t, sent.Text, "What was this $12.50 for?")
+		kb := requireInlineKeyboard(t, sent.ReplyMarkup)
+		require.Equal(t, describeSkipCallback, kb.InlineKeyboard[0][0].CallbackData)
+		return mockBot.NextMessageID - 1
+	}
+
+	t.Run("next message becomes the description", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		prompt(mockBot)
+		require.Zero(t, countExpenses(ctx, t, pool, userID, "Parking"))
+
+		handled := b.handlePendingEditCore(ctx, mockBot, mocks.CommandUpdate(chatID, userID, "Parking"))
+		require.True(t, handled)
+		require.Equal(t, 1, countExpenses(ctx, t, pool, userID, "Parking"))
+		require.Len(t, mockBot.EditedReplyMarkups, 1, "the Skip button is removed")
+
+		b.pendingEditsMu.RLock()
+		_, pending := b.pendingEdits[chatID]
+		b.pendingEditsMu.RUnlock()
+		require.False(t, pending)
+	})
+
+	t.Run("messages from other members are not captured", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		prompt(mockBot)
+
+		handled := b.handlePendingEditCore(ctx, mockBot, mocks.CommandUpdate(chatID, userID+1, "Not mine"))
+		require.False(t, handled)
+		require.Zero(t, countExpenses(ctx, t, pool, userID, "Not mine"))
+		b.cancelDescribeOnCommand(mocks.CommandUpdate(chatID, userID, "/cancel"))
+	})
+
+	t.Run("skip saves without a description", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		messageID := prompt(mockBot)
+		before := countExpenses(ctx, t, pool, userID, "")
+
+		callback := mocks.NewUpdateBuilder().
+			WithCallbackQuery("cb", chatID, userID, messageID, describeSkipCallback).Build()
+		b.handleDescribeSkipCallbackCore(ctx, mockBot, callback)
+		require.Equal(t, before+1, countExpenses(ctx, t, pool, userID, ""))
+		require.Empty(t, mockBot.AnsweredCallbacks[0].Text)
+
+		b.handleDescribeSkipCallbackCore(ctx, mockBot, callback)
+		require.Equal(t, before+1, countExpenses(ctx, t, pool, userID, ""), "a second tap saves nothing")
+		require.Equal(t, pendingExpenseExpiredMsg, mockBot.AnsweredCallbacks[1].Text)
+	})
+
+	t.Run("capture expires", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		prompt(mockBot)
+		now = now.Add(describeTTL + time.Second)
+
+		handled := b.handlePendingEditCore(ctx, mockBot, mocks.CommandUpdate(chatID, userID, "Too late"))
+		require.False(t, handled, "the message is routed normally")
+		require.Zero(t, countExpenses(ctx, t, pool, userID, "Too late"))
+
+		b.pendingEditsMu.RLock()
+		_, pending := b.pendingEdits[chatID]
+		b.pendingEditsMu.RUnlock()
+		require.False(t, pending)
+	})
+
+	t.Run("commands cancel the capture", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		prompt(mockBot)
+
+		b.cancelDescribeOnCommand(mocks.CommandUpdate(chatID, userID, "/list"))
+		handled := b.handlePendingEditCore(ctx, mockBot, mocks.CommandUpdate(chatID, userID, "Afterwards"))
+		require.False(t, handled)
+
+		prompt(mockBot)
+		handled = b.handlePendingEditCore(ctx, mockBot, mocks.CommandUpdate(chatID, userID, "/unknown"))
+		require.False(t, handled)
+		require.Zero(t, countExpenses(ctx, t, pool, userID, "/unknown"))
+	})
+}
@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	// draftsListLimit is how many drafts /drafts shows, oldest first.
+	draftsListLimit = 10
+	// draftExpiryWarning is how close to deletion a draft must be before
+	// /drafts warns about it.
+	draftExpiryWarning = time.Hour
+
+	draftsNoneMsg = "✅ No pending drafts."
+)
+
+// formatDraftAge renders a draft's age or lifetime, e.g. "3h 20m". Days are
+// only used from two days on, so the default lifetime reads "24h".
+func formatDraftAge(age time.Duration) string {
+	hours, minutes := int(age.Hours()), int(age.Minutes())%60
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%d min", minutes)
+	case age < 48*time.Hour && minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	case age < 48*time.Hour:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case hours%24 == 0:
+		return fmt.Sprintf("%dd", hours/24)
+	default:
+		return fmt.Sprintf("%dd %dh", hours/24, hours%24)
+	}
+}
+
+// draftExpiryNote warns about a draft created at createdAt that the cleanup
+// job deletes within draftExpiryWarning, or returns "" when it has longer.
+func draftExpiryNote(createdAt, now time.Time, expiration time.Duration) string {
+	remaining := createdAt.Add(expiration).Sub(now)
+	switch {
+	case remaining > draftExpiryWarning:
+		return ""
+	case remaining <= 0:
+		return "⚠️ expires at the next cleanup"
+	default:
+		// Round up so a draft with 30 seconds left never reads "0 min".
+		return fmt.Sprintf("⚠️ expires in %d min", int((remaining+time.Minute-1)/time.Minute))
+	}
+}
+
+// draftListItem renders one draft of /drafts with its amount, merchant and
+// age.
+func draftListItem(expense *appmodels.Expense, now time.Time, expiration time.Duration) string {
+	merchant := expense.Merchant
+	if merchant == "" {
+		merchant = expense.Description
+	}
+	if merchant == "" {
+		merchant = "—"
+	}
+
+	status := formatDraftAge(now.Sub(expense.CreatedAt)) + " old"
+	if note := draftExpiryNote(expense.CreatedAt, now, expiration); note != "" {
+		status += " · " + note
+	}
+	return fmt.Sprintf("📝 <b>Draft</b> · %s\n🏪 %s\n🕒 %s",
+		botfmt.Money(expense.Amount, expense.Currency), botfmt.EscapeHTML(merchant), status)
+}
+
+// handleDrafts handles the /drafts command.
+func (b *Bot) handleDrafts(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleDraftsCore(ctx, tgBot, update)
+}
+
+// handleDraftsCore is the testable implementation of handleDrafts. It sends
+// a header and then one message per draft carrying the receipt Confirm,
+// Edit and Cancel buttons, so each draft is handled by the receipt
+// callbacks exactly as its original confirmation message was.
+func (b *Bot) handleDraftsCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+
+	drafts, err := b.expenseRepo.GetDraftsByUserID(ctx, userID, draftsListLimit+1)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch drafts")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   failedFetchExpensesMsg,
+		})
+		return
+	}
+	if len(drafts) == 0 {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   draftsNoneMsg,
+		})
+		return
+	}
+
+	expiration := b.draftExpiration()
+	header := fmt.Sprintf("📝 <b>Pending drafts</b>\nUnconfirmed drafts are deleted %s after they are created.",
+		formatDraftAge(expiration))
+	if len(drafts) > draftsListLimit {
+		drafts = drafts[:draftsListLimit]
+		header += fmt.Sprintf("\nShowing the %d oldest.", draftsListLimit)
+	}
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      header,
+		ParseMode: models.ParseModeHTML,
+	})
+
+	now := b.now()
+	for i := range drafts {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:      chatID,
+			Text:        draftListItem(&drafts[i], now, expiration),
+			ParseMode:   models.ParseModeHTML,
+			ReplyMarkup: buildReceiptConfirmationKeyboard(drafts[i].ID, drafts[i].Currency),
+		})
+	}
+}
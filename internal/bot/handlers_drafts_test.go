@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestFormatDraftAge(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		age  time.Duration
+		want string
+	}{
+		{age: 20 * time.Second, want: "just now"},
+		{age: 45 * time.Minute, want: "45 min"},
+		{age: 3*time.Hour + 20*time.Minute, want: "3h 20m"},
+		{age: 24 * time.Hour, want: "24h"},
+		{age: 72 * time.Hour, want: "3d"},
+		{age: 50 * time.Hour, want: "2d 2h"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, formatDraftAge(tt.age), tt.age.String())
+	}
+}
+
+func TestDraftExpiryNote(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	expiration := 24 * time.Hour
+
+	require.Empty(t, draftExpiryNote(created, created.Add(2*time.Hour), expiration))
+	require.Empty(t, draftExpiryNote(created, created.Add(22*time.Hour+59*time.Minute), expiration))
+	require.Equal(t, "⚠️ expires in 60 min", draftExpiryNote(created, created.Add(23*time.Hour), expiration))
+	require.Equal(t, "⚠️ expires in 40 min", draftExpiryNote(created, created.Add(23*time.Hour+20*time.Minute), expiration))
+	require.Equal(t, "⚠️ expires in 1 min", draftExpiryNote(created, created.Add(24*time.Hour-30*time.Second), expiration))
+	require.Equal(t, "⚠️ expires at the next cleanup", draftExpiryNote(created, created.Add(25*time.Hour), expiration))
+}
+
+func TestHandleDraftsCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(419001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Drafts"}))
+
+	listDrafts := func() *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		b.handleDraftsCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/drafts"))
+		return mockBot
+	}
+	newExpense := func(merchant string, status appmodels.ExpenseStatus) *appmodels.Expense {
+		expense := &appmodels.Expense{
+			UserID:   userID,
+			Amount:   mustParseDecimal("14.20"),
+			Currency: currencyCodeSGD,
+			Merchant: merchant,
+			Status:   status,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		return expense
+	}
+
+	t.Run("empty state", func(t *testing.T) {
+		newExpense("Confirmed Cafe", appmodels.ExpenseStatusConfirmed)
+		mockBot := listDrafts()
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		require.Equal(t, draftsNoneMsg, mockBot.LastSentMessage().Text)
+	})
+
+	older := newExpense("Guardian", appmodels.ExpenseStatusDraft)
+	newer := newExpense("Kopitiam", appmodels.ExpenseStatusDraft)
+	_, err := pool.Exec(ctx, testUpdateExpenseTimeSQL, older.CreatedAt.Add(-23*time.Hour), older.ID)
+	require.NoError(t, err)
+	b.nowFunc = func() time.Time { return older.CreatedAt.Add(20 * time.Minute) }
+	t.Cleanup(func() { b.nowFunc = nil })
+
+	t.Run("lists drafts oldest first with an expiry warning", func(t *testing.T) {
+		mockBot := listDrafts()
+		require.Equal(t, 3, mockBot.SentMessageCount())
+		require.Contains(t, mockBot.SentMessages[0].Text, "Pending drafts")
+		require.Contains(t, mockBot.SentMessages[0].Text, "deleted 24h after")
+
+		first := mockBot.SentMessages[1]
+		require.Contains(t, first.Text, "Guardian")
+		require.Contains(t, first.Text, "S$14.20 SGD")
+		require.Contains(t, first.Text, "23h 20m old · ⚠️ expires in 40 min")
+
+		second := mockBot.SentMessages[2]
+		require.Contains(t, second.Text, "Kopitiam")
+		require.NotContains(t, second.Text, "expires")
+		require.NotContains(t, mockBot.SentMessages[1].Text+second.Text, "Confirmed Cafe")
+	})
+
+	t.Run("buttons reuse the receipt callbacks", func(t *testing.T) {
+		mockBot := listDrafts()
+		keyboard := requireInlineKeyboard(t, mockBot.SentMessages[1].ReplyMarkup)
+		require.Equal(t, buildReceiptConfirmationKeyboard(older.ID, older.Currency), keyboard)
+		confirm := keyboard.InlineKeyboard[0][0].CallbackData
+
+		keyboard = requireInlineKeyboard(t, mockBot.SentMessages[2].ReplyMarkup)
+		require.Equal(t, buildReceiptConfirmationKeyboard(newer.ID, newer.Currency), keyboard)
+		cancel := keyboard.InlineKeyboard[0][2].CallbackData
+
+		mockBot = mocks.NewMockBot()
+		b.handleReceiptCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 10, confirm))
+		expense, err := b.expenseRepo.GetByID(ctx, older.ID)
+		require.NoError(t, err)
+		require.Equal(t, appmodels.ExpenseStatusConfirmed, expense.Status)
+
+		b.handleReceiptCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 11, cancel))
+		_, err = b.expenseRepo.GetByID(ctx, newer.ID)
+		require.Error(t, err)
+
+		require.Equal(t, draftsNoneMsg, listDrafts().LastSentMessage().Text)
+	})
+}
@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
+)
+
+// editedMessageWindow is how long after sending a free-text expense its
+// message can still be edited to correct the expense. Older edits are
+// ignored.
+const editedMessageWindow = 24 * time.Hour
+
+// linkSourceMessage remembers that a chat message created an expense.
+// Failures are logged only: the expense is already saved.
+func (b *Bot) linkSourceMessage(ctx context.Context, expenseID int, chatID int64, messageID int) {
+	if err := b.expenses().LinkSourceMessage(ctx, expenseID, chatID, messageID); err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expenseID).Msg("Failed to link expense to source message")
+	}
+}
+
+// handleEditedMessage handles edits of messages sent to the bot.
+func (b *Bot) handleEditedMessage(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleEditedMessageCore(ctx, tgBot, update)
+}
+
+// handleEditedMessageCore is the testable implementation of
+// handleEditedMessage. When the edited message created a free-text expense
+// within the last 24 hours, the edited text is parsed again and the
+// expense's amount, description and category follow it. A new amount
+// above the user's limit turns the expense back into a draft that must be
+// confirmed, as it would be when logged. Edits of other messages are
+// ignored.
+func (b *Bot) handleEditedMessageCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	msg := update.EditedMessage
+	if msg == nil || msg.From == nil || msg.Text == "" || strings.HasPrefix(msg.Text, "/") ||
+		b.tooLongForFreeText(msg.Text) {
+		return
+	}
+
+	sentAt := time.Unix(int64(msg.Date), 0)
+	if b.now().Sub(sentAt) > editedMessageWindow {
+		return
+	}
+
+	chatID := msg.Chat.ID
+	userID := msg.From.ID
+
+	expenseID, err := b.expenseRepo.GetIDBySourceMessage(ctx, chatID, msg.ID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Log.Error().Err(err).Int64("chat_id", chatID).Msg("Failed to look up edited message")
+		}
+		return
+	}
+
+	expense, err := b.expenseRepo.GetByID(ctx, expenseID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expenseID).Msg("Failed to load expense for edited message")
+		return
+	}
+	if expense.UserID != userID {
+		return
+	}
+
+	categories, err := b.visibleCategories(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for edited message")
+		return
+	}
+	categoryNames := make([]string, len(categories))
+	for i := range categories {
+		categoryNames[i] = categories[i].Name
+	}
+
+	// Who shares a split expense is fixed when it is logged.
+	input, _ := extractSplitDirective(msg.Text)
+	parsed := ParseExpenseInputWithCategories(input, categoryNames)
+	if parsed == nil || parsed.AmountError != nil {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text: fmt.Sprintf("⚠️ I couldn't read an expense from your edit, so expense #%d was left unchanged.",
+				expense.UserExpenseNumber),
+		})
+		return
+	}
+
+	rebuilt, err := b.buildExpenseFromParsed(ctx, userID, parsed, categories)
+	if err != nil {
+		text := failedSaveExpenseMsg
+		if errors.Is(err, service.ErrAboveHardCap) {
+			text = b.amountAboveHardCapMsg(rebuilt.Amount, rebuilt.Currency)
+		}
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+		return
+	}
+
+	before := *expense
+	expense.Amount = rebuilt.Amount
+	expense.Currency = rebuilt.Currency
+	expense.Description = rebuilt.Description
+	expense.Merchant = rebuilt.Merchant
+	expense.CategoryID = rebuilt.CategoryID
+	expense.Category = rebuilt.Category
+	needsConfirmation := !expense.Amount.Equal(before.Amount) &&
+		b.expenses().NeedsConfirmation(ctx, userID, expense.Amount)
+	if needsConfirmation {
+		expense.Status = appmodels.ExpenseStatusDraft
+	}
+
+	if err := b.expenses().EditFields(ctx, expense); err != nil {
+		logger.Log.Error().Err(err).Int("expense_id", expense.ID).Msg("Failed to update expense from edited message")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   failedSaveExpenseMsg,
+		})
+		return
+	}
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(userID)).
+		Int("expense_id", expense.ID).
+		Msg("Expense updated from edited message")
+	b.auditExpenseUpdate(ctx, tg, userID, before, expense)
+
+	if needsConfirmation {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:      chatID,
+			Text:        botfmt.LargeAmountConfirmCard(expense),
+			ParseMode:   models.ParseModeHTML,
+			ReplyMarkup: buildLargeAmountKeyboard(expense.ID),
+		})
+		return
+	}
+
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text: fmt.Sprintf("✏️ Expense #%d updated from your edit: %s · %s",
+			expense.UserExpenseNumber,
+			botfmt.Money(expense.Amount, expense.Currency),
+			botfmt.EscapeHTML(expense.Description)),
+		ParseMode: models.ParseModeHTML,
+	})
+}
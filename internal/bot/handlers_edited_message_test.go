@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	editedExpenseTextTest  = "5.50 Latte"
+	editedOriginalDescTest = "Coffee"
+)
+
+func TestHandleEditedMessageCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	b.nowFunc = func() time.Time { return now }
+
+	userID := int64(375001)
+	chatID := int64(375001)
+	err := b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Edit"})
+	require.NoError(t, err)
+
+	createLinked := func(messageID int) *appmodels.Expense {
+		expense := &appmodels.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal("55.00"),
+			Currency:    currencyCodeSGD,
+			Description: editedOriginalDescTest,
+			Status:      appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		require.NoError(t, b.expenseRepo.LinkSourceMessage(ctx, expense.ID, chatID, messageID))
+		return expense
+	}
+
+	editUpdate := func(messageID int, text string, sentAt time.Time) *mocks.MockBot {
+		update := mocks.NewUpdateBuilder().WithEditedMessage(chatID, userID, text).Build()
+		update.EditedMessage.ID = messageID
+		update.EditedMessage.Date = int(sentAt.Unix())
+		mockBot := mocks.NewMockBot()
+		b.handleEditedMessageCore(ctx, mockBot, update)
+		return mockBot
+	}
+
+	t.Run("re-parses the edited text", func(t *testing.T) {
+		expense := createLinked(10)
+
+		mockBot := editUpdate(10, editedExpenseTextTest, now.Add(-time.Minute))
+
+		require.Contains(t, mockBot.LastSentMessage().Text, "updated from your edit")
+		updated, err := b.expenseRepo.GetByID(ctx, expense.ID)
+		require.NoError(t, err)
+		require.True(t, updated.Amount.Equal(mustParseDecimal("5.50")))
+		require.Equal(t, "Latte", updated.Description)
+		require.NotNil(t, updated.CategoryID)
+	})
+
+	t.Run("edit above the limit asks to confirm", func(t *testing.T) {
+		expense := createLinked(14)
+		require.NoError(t, b.userRepo.UpdateMaxAmount(ctx, userID, decimal.NewNullDecimal(mustParseDecimal("100"))))
+		t.Cleanup(func() {
+			require.NoError(t, b.userRepo.UpdateMaxAmount(ctx, userID, decimal.NullDecimal{}))
+		})
+
+		mockBot := editUpdate(14, "250 Laptop stand", now.Add(-time.Minute))
+
+		sent := mockBot.LastSentMessage()
+		require.Contains(t, sent.Text, "save anyway?")
+		require.NotNil(t, sent.ReplyMarkup)
+		updated, err := b.expenseRepo.GetByID(ctx, expense.ID)
+		require.NoError(t, err)
+		require.True(t, updated.Amount.Equal(mustParseDecimal("250")))
+		require.Equal(t, appmodels.ExpenseStatusDraft, updated.Status)
+	})
+
+	t.Run("unparseable edit leaves the expense alone", func(t *testing.T) {
+		expense := createLinked(11)
+
+		mockBot := editUpdate(11, "oops never mind", now.Add(-time.Minute))
+
+		require.Contains(t, mockBot.LastSentMessage().Text, "left unchanged")
+		unchanged, err := b.expenseRepo.GetByID(ctx, expense.ID)
+		require.NoError(t, err)
+		require.True(t, unchanged.Amount.Equal(mustParseDecimal("55.00")))
+		require.Equal(t, editedOriginalDescTest, unchanged.Description)
+	})
+
+	t.Run("message that never created an expense", func(t *testing.T) {
+		mockBot := editUpdate(12, editedExpenseTextTest, now.Add(-time.Minute))
+		require.Equal(t, 0, mockBot.SentMessageCount())
+	})
+
+	t.Run("edits after the window are ignored", func(t *testing.T) {
+		expense := createLinked(13)
+
+		mockBot := editUpdate(13, editedExpenseTextTest, now.Add(-editedMessageWindow-time.Minute))
+
+		require.Equal(t, 0, mockBot.SentMessageCount())
+		unchanged, err := b.expenseRepo.GetByID(ctx, expense.ID)
+		require.NoError(t, err)
+		require.Equal(t, editedOriginalDescTest, unchanged.Description)
+	})
+}
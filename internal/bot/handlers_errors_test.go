@@ -378,12 +378,12 @@ func TestCategoryRepositoryErrors(t *testing.T) {
 		require.Contains(t, err.Error(), "duplicate key value")
 	})
 
-	t.Run("update non-existent category succeeds silently", func(t *testing.T) {
+	t.Run("update non-existent category fails", func(t *testing.T) {
 		testBot, ctx, _ := setupHandlerErrorTest(t)
 
-		// Update doesn't check rows affected
-		err := testBot.categoryRepo.Update(ctx, 99999, "NewName")
-		require.NoError(t, err)
+		// Update returns the updated row, and there is none.
+		_, err := testBot.categoryRepo.Update(ctx, 99999, "NewName")
+		require.Error(t, err)
 	})
 
 	t.Run("delete non-existent category succeeds silently", func(t *testing.T) {
@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	// exportBatchSize is how many expenses /export loads per query.
+	exportBatchSize = 500
+
+	exportFailedMsg = "❌ Failed to export expenses. Please try again."
+	exportEmptyMsg  = "📦 No expenses to export yet."
+)
+
+// writeExpensesExport writes every confirmed expense of a user as CSV to w,
+// walking the expenses with keyset pagination so memory use does not grow
+// with the number of expenses, followed by their monthly reviews. It
+// returns the number of expenses written.
+func (b *Bot) writeExpensesExport(ctx context.Context, w io.Writer, userID int64) (int, error) {
+	afterID := 0
+	count, err := GenerateExpensesCSVStream(w, func() ([]appmodels.Expense, error) {
+		expenses, err := b.expenseRepo.GetAllByUserIDCursor(ctx, userID, afterID, exportBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch expenses page: %w", err)
+		}
+		if len(expenses) > 0 {
+			afterID = expenses[len(expenses)-1].ID
+		}
+		return expenses, nil
+	})
+	if err != nil {
+		return count, fmt.Errorf("failed to write export: %w", err)
+	}
+	if count > 0 {
+		if err := b.writeMonthlyReviewsCSV(ctx, w, userID); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// handleExport handles the /export command.
+func (b *Bot) handleExport(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleExportCore(ctx, tgBot, update)
+}
+
+// handleExportCore is the testable implementation of handleExport. It
+// sends all of the user's confirmed expenses as one CSV file. The CSV is
+// spooled to a temporary file and uploaded from there, so exports of any
+// size run in constant memory.
+func (b *Bot) handleExportCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || !b.sensitiveCommandAllowed(ctx, tg, update.Message) {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	sendText := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: text})
+	}
+
+	release, ok := b.beginReport(ctx, tg, update.Message, "export")
+	if !ok {
+		return
+	}
+	defer release()
+
+	file, err := os.CreateTemp("", "expense-export-*.csv")
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create export file")
+		sendText(exportFailedMsg)
+		return
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	buffered := bufio.NewWriter(file)
+	count, err := b.writeExpensesExport(ctx, buffered, userID)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to export expenses")
+		sendText(exportFailedMsg)
+		return
+	}
+
+	if count == 0 {
+		sendText(exportEmptyMsg)
+		return
+	}
+
+	filename := fmt.Sprintf("expenses_all_%s.csv", b.now().In(b.locationForUser(ctx, userID)).Format("2006-01-02"))
+	if _, err := tg.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID:    chatID,
+		Document:  &models.InputFileUpload{Filename: filename, Data: file},
+		Caption:   fmt.Sprintf("📦 <b>All expenses</b>\n\nCount: %d", count),
+		ParseMode: models.ParseModeHTML,
+	}); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to send export document")
+		sendText("❌ Failed to send export. Please try again.")
+		return
+	}
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(userID)).
+		Int("expense_count", count).
+		Msg("Expenses exported")
+}
@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestHandleExportCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(383001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Exporter"}))
+
+	export := func() *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		b.handleExportCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/export"))
+		return mockBot
+	}
+
+	t.Run("nothing to export", func(t *testing.T) {
+		mockBot := export()
+		require.Empty(t, mockBot.SentDocuments)
+		require.Equal(t, exportEmptyMsg, mockBot.LastSentMessage().Text)
+	})
+
+	const total = exportBatchSize + 3
+	for i := range total {
+		require.NoError(t, b.expenseRepo.Create(ctx, &appmodels.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal("1.00"),
+			Currency:    currencyCodeSGD,
+			Description: fmt.Sprintf("Item %d", i),
+		}))
+	}
+	require.NoError(t, b.expenseRepo.Create(ctx, &appmodels.Expense{
+		UserID:   userID,
+		Amount:   mustParseDecimal("9.00"),
+		Currency: currencyCodeSGD,
+		Status:   appmodels.ExpenseStatusDraft,
+	}))
+
+	t.Run("exports every confirmed expense across pages", func(t *testing.T) {
+		mockBot := export()
+		require.Len(t, mockBot.SentDocuments, 1)
+		doc := mockBot.SentDocuments[0]
+		require.Contains(t, doc.Filename, "expenses_all_")
+		require.Contains(t, doc.Caption, fmt.Sprintf("Count: %d", total))
+
+		records := csvExpenseRecords(t, doc.Data)
+		require.Len(t, records, total+1)
+		require.Equal(t, "Item 0", records[1][4])
+		require.Equal(t, fmt.Sprintf("Item %d", total-1), records[total][4])
+	})
+}
@@ -168,3 +168,20 @@ func callHandleFreeTextExpense(
 	})
 	return true
 }
+
+func TestHandleFreeTextExpenseCoreRecordsSource(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	userID := int64(455001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &models.User{ID: userID, FirstName: "Text"}))
+
+	mockBot := mocks.NewMockBot()
+	require.True(t, b.handleFreeTextExpenseCore(ctx, mockBot, mocks.MessageUpdate(userID, userID, "5.50 Coffee")))
+	require.Contains(t, mockBot.LastSentMessage().Text, "Coffee")
+
+	expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 1)
+	require.NoError(t, err)
+	require.Len(t, expenses, 1)
+	require.Equal(t, models.ExpenseSourceText, expenses[0].Source)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/go-telegram/bot/models"
 	"github.com/jackc/pgx/v5"
 	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
 	"gitlab.com/yelinaung/expense-bot/internal/logger"
 	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
 )
@@ -47,6 +48,11 @@ func (b *Bot) handleReviewCore(ctx context.Context, tg TelegramAPI, update *mode
 		return
 	}
 
+	if args := extractCommandArgs(update.Message.Text, "/review"); args != "" {
+		b.handleMonthlyReviewCore(ctx, tg, update.Message, args)
+		return
+	}
+
 	chatID := update.Message.Chat.ID
 	userID := update.Message.From.ID
 	expenses, err := b.expenseRepo.GetUnreviewedByUserID(ctx, userID, 1)
@@ -214,7 +220,7 @@ func (b *Bot) handleReviewDriverCallback(
 	}
 
 	driver := string(spendingDrivers[callback.driverIndex])
-	if err := b.expenseRepo.UpdateReflection(ctx, callback.expenseID, userID, &callback.worthIt, driver); err != nil {
+	if err := b.expenses().Reflect(ctx, callback.expenseID, userID, &callback.worthIt, driver); err != nil {
 		logger.Log.Error().Err(err).Int("expense_id", callback.expenseID).Msg("Failed to update reflection")
 		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
 			ChatID:    chatID,
@@ -246,7 +252,7 @@ func (b *Bot) editToConfirmation(
 	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      chatID,
 		MessageID:   messageID,
-		Text:        buildExpenseAddedMessage(expense, nil),
+		Text:        botfmt.ExpenseAddedCard(expense, nil, botfmt.ExpenseAddedOptions{}),
 		ParseMode:   models.ParseModeHTML,
 		ReplyMarkup: buildExpenseActionKeyboard(expense.ID),
 	})
@@ -313,7 +319,7 @@ func (b *Bot) dismissReflectionButtons(
 		return
 	}
 	if text == "" {
-		text = buildExpenseAddedMessage(expense, nil)
+		text = botfmt.ExpenseAddedCard(expense, nil, botfmt.ExpenseAddedOptions{})
 	}
 	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      chatID,
@@ -479,7 +485,7 @@ func parseDriverCallback(data string) reviewDriverCallback {
 func formatReviewPrompt(expense *appmodels.Expense, loc *time.Location) string {
 	categoryText := categoryUncategorized
 	if expense.Category != nil {
-		categoryText = escapeHTML(expense.Category.Name)
+		categoryText = botfmt.EscapeHTML(expense.Category.Name)
 	}
 	description := expense.Description
 	if description == "" {
@@ -495,12 +501,12 @@ func formatReviewPrompt(expense *appmodels.Expense, loc *time.Location) string {
 %s
 %s
 %s`,
-		escapeHTML(getCurrencyOrCodeSymbol(expense.Currency)),
-		escapeHTML(expense.Amount.StringFixed(2)),
-		escapeHTML(expense.Currency),
-		escapeHTML(description),
+		botfmt.EscapeHTML(botfmt.CurrencySymbol(expense.Currency)),
+		botfmt.FormatAmount(expense.Amount, expense.Currency),
+		botfmt.EscapeHTML(expense.Currency),
+		botfmt.EscapeHTML(description),
 		categoryText,
-		escapeHTML(expense.CreatedAt.In(normalizeLocation(loc)).Format("02 Jan 2006 15:04")),
+		botfmt.EscapeHTML(expense.CreatedAt.In(normalizeLocation(loc)).Format("02 Jan 2006 15:04")),
 	)
 }
 
@@ -522,7 +528,7 @@ func habitPeriodRange(period string, current time.Time) (time.Time, time.Time, s
 
 func formatHabitSummary(summary *habitSummary) string {
 	var sb strings.Builder
-	fmt.Fprintf(&sb, "<b>Spending Reflection</b>\n%s\n\n", escapeHTML(summary.PeriodLabel))
+	fmt.Fprintf(&sb, "<b>Spending Reflection</b>\n%s\n\n", botfmt.EscapeHTML(summary.PeriodLabel))
 	fmt.Fprintf(&sb, "Reviewed: %d/%d\n", summary.ReviewedCount, summary.TotalCount)
 	fmt.Fprintf(&sb, "Worth it: %d\n", summary.WorthItCount)
 	fmt.Fprintf(&sb, "Not worth it: %d\n\n", summary.NotWorthItCount)
@@ -535,7 +541,7 @@ func formatHabitSummary(summary *habitSummary) string {
 	fmt.Fprintf(&sb, "\nBest-value category: %s\n", habitCategoryOrFallback(summary.BestValueCategory))
 	fmt.Fprintf(&sb, "Most-regretted category: %s\n", habitCategoryOrFallback(summary.MostRegrettedCategory))
 	if summary.TopDriver != "" {
-		fmt.Fprintf(&sb, "Top driver: %s\n", escapeHTML(string(summary.TopDriver)))
+		fmt.Fprintf(&sb, "Top driver: %s\n", botfmt.EscapeHTML(string(summary.TopDriver)))
 	} else {
 		sb.WriteString("Top driver: Not enough data\n")
 	}
@@ -545,7 +551,7 @@ func formatHabitSummary(summary *habitSummary) string {
 		sb.WriteString("Not-worth-it weekday: Not enough data\n")
 	}
 
-	fmt.Fprintf(&sb, "\n%s", escapeHTML(formatHabitInsight(summary)))
+	fmt.Fprintf(&sb, "\n%s", botfmt.EscapeHTML(formatHabitInsight(summary)))
 	return sb.String()
 }
 
@@ -570,9 +576,9 @@ func appendCurrencyTotals(sb *strings.Builder, totals map[string]decimal.Decimal
 	for _, currency := range sortedCurrencyKeys(totals) {
 		fmt.Fprintf(
 			sb, "  %s: %s%s\n",
-			escapeHTML(currency),
-			escapeHTML(getCurrencyOrCodeSymbol(currency)),
-			totals[currency].StringFixed(2),
+			botfmt.EscapeHTML(currency),
+			botfmt.EscapeHTML(botfmt.CurrencySymbol(currency)),
+			botfmt.FormatAmount(totals[currency], currency),
 		)
 	}
 }
@@ -581,7 +587,7 @@ func habitCategoryOrFallback(category string) string {
 	if category == "" {
 		return "Not enough data"
 	}
-	return escapeHTML(category)
+	return botfmt.EscapeHTML(category)
 }
 
 func (b *Bot) locationForUser(ctx context.Context, userID int64) *time.Location {
@@ -0,0 +1,450 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+// telegramMessageLimit is the most characters Telegram accepts in one
+// message.
+const telegramMessageLimit = 4096
+
+// Help topic names, also used by usage errors that point at /help.
+const (
+	helpTopicAdd        = "add"
+	helpTopicManage     = "manage"
+	helpTopicBudget     = "budget"
+	helpTopicView       = "view"
+	helpTopicReports    = "reports"
+	helpTopicCategories = "categories"
+	helpTopicCurrency   = "currency"
+	helpTopicTimezone   = "timezone"
+	helpTopicSettings   = "settings"
+	helpTopicTags       = "tags"
+	helpTopicSplit      = "split"
+	helpTopicAdmin      = "admin"
+	helpTopicOther      = "other"
+)
+
+// helpTopic is one section of /help.
+type helpTopic struct {
+	Name  string
+	Title string
+	// Notes are help lines not tied to a single command, shown first.
+	Notes []string
+}
+
+// commandDef describes a command for both Telegram's command menu and
+// /help, so the two cannot drift apart.
+type commandDef struct {
+	Name  string
+	Topic string
+	// Menu is the command menu description; empty keeps the command out of
+	// the menu.
+	Menu string
+	// Help lines in HTML, one per usage.
+	Help []string
+}
+
+var helpTopics = []helpTopic{
+	{Name: helpTopicAdd, Title: "Adding Expenses", Notes: []string{
+		"Just send a message like <code>5.50 Coffee</code> to quickly add",
+		"Use currency: <code>$10 Lunch</code>, <code>€5 Coffee</code>, <code>50 THB Taxi</code>",
+		"Do the math inline: <code>84.60/3 my share of dinner</code>",
+		"Start with <code>refund</code> to log money back: <code>refund 25.90 Amazon return</code>",
+		"Send a receipt photo to extract expenses automatically",
+		"Send a voice message like <code>spent five fifty on coffee</code>",
+	}},
+	{Name: helpTopicManage, Title: "Managing Expenses"},
+	{Name: helpTopicBudget, Title: "Budgets"},
+	{Name: helpTopicView, Title: "Viewing Expenses", Notes: []string{
+		"Type <code>@bot today</code>, <code>week</code>, <code>month</code> or a category in any chat to share a total",
+	}},
+	{Name: helpTopicReports, Title: "Reports"},
+	{Name: helpTopicCategories, Title: "Categories"},
+	{Name: helpTopicCurrency, Title: "Currency"},
+	{Name: helpTopicTimezone, Title: "Timezone"},
+	{Name: helpTopicSettings, Title: "Settings"},
+	{Name: helpTopicTags, Title: "Tags", Notes: []string{
+		"Add tags inline: <code>5.50 Coffee #work #meeting</code>",
+	}},
+	{Name: helpTopicSplit, Title: "Splitting in Groups", Notes: []string{
+		"End a group expense with <code>@split</code> to share it with everyone who logged expenses here lately",
+		"<code>@split 3</code> splits with the two most recent others, " +
+			"<code>@split @alice @bob</code> with the people named; you are always included",
+	}},
+	{Name: helpTopicAdmin, Title: "Admin"},
+	{Name: helpTopicOther, Title: "Other"},
+}
+
+var commandDefs = []commandDef{
+	{Name: "add", Topic: helpTopicAdd, Menu: "Add an expense", Help: []string{
+		"<code>/add &lt;amount&gt; &lt;description&gt; [category]</code> - Add an expense",
+	}},
+	{Name: "refund", Topic: helpTopicAdd, Menu: "Record a refund that lowers your totals", Help: []string{
+		"<code>/refund &lt;amount&gt; &lt;description&gt; [category]</code> - Record money you got back, " +
+			"e.g. <code>/refund 25.90 Amazon return</code>",
+	}},
+	{Name: "parse", Topic: helpTopicAdd, Menu: "Explain how a message is parsed, without saving", Help: []string{
+		"<code>/parse [--ai] &lt;text&gt;</code> - See how a message would be parsed, without saving",
+	}},
+	{Name: "preview", Topic: helpTopicAdd, Menu: "Preview an expense's budget impact before saving", Help: []string{
+		"<code>/preview &lt;amount&gt; &lt;description&gt; [category]</code> - See what an expense would do to " +
+			"your budgets, with a button to save it",
+	}},
+	{Name: editAction, Topic: helpTopicManage, Menu: "Edit an expense", Help: []string{
+		"<code>/edit &lt;id&gt; &lt;amount&gt; &lt;description&gt; [category]</code> - Edit an expense",
+	}},
+	{Name: "delete", Topic: helpTopicManage, Menu: "Delete an expense", Help: []string{
+		"<code>/delete &lt;id&gt;</code> - Delete an expense",
+	}},
+	{Name: "history", Topic: helpTopicManage, Menu: "Show the change history of an expense", Help: []string{
+		"<code>/history &lt;id&gt;</code> - Show edits and deletion of an expense",
+	}},
+	{Name: "dedupe", Topic: helpTopicManage, Menu: "Find and merge duplicate expenses", Help: []string{
+		"<code>/dedupe [YYYY-MM]</code> - Find and clean up duplicate expenses",
+	}},
+	{Name: "drafts", Topic: helpTopicManage, Menu: "List unconfirmed receipt drafts", Help: []string{
+		"<code>/drafts</code> - List unconfirmed drafts to confirm, edit or cancel them",
+	}},
+	{Name: "storage", Topic: helpTopicManage, Menu: "Show how much data you have stored", Help: []string{
+		"<code>/storage</code> - Count your expenses, by how they came in, drafts, tags, receipts and audit entries",
+		"<code>/storage all</code> - The same for all users (superadmins)",
+	}},
+	{Name: "reimbursements", Topic: helpTopicManage, Menu: "List expenses awaiting reimbursement", Help: []string{
+		"<code>/reimbursements</code> - List reimbursable expenses not paid back yet and mark them reimbursed",
+		"Add <code>#reimbursable</code> to an expense, or use 💼 in its edit menu, to mark it",
+	}},
+	{Name: "budget", Topic: helpTopicBudget, Menu: "Show or set monthly category budgets", Help: []string{
+		"<code>/budget</code> - Show this month's budgets",
+		"<code>/budget set &lt;category&gt; &lt;amount&gt; [currency] [hard]</code> - Set a monthly budget; " +
+			"hard refuses expenses over it",
+		"<code>/budget remove &lt;category&gt;</code> - Remove a budget",
+		"<code>/budget override &lt;category&gt;</code> - Lift a hard budget for the rest of the month",
+		"<code>/budget convert [category]</code> - Move budgets into your default currency",
+		"<code>/budget suggest</code> - Suggest budgets from your average monthly spending",
+	}},
+	{Name: "list", Topic: helpTopicView, Menu: "Show recent expenses", Help: []string{
+		"<code>/list</code> - Show recent expenses",
+		"<code>/list edit</code> - Change categories of recent expenses",
+		"<code>/list select</code> - Tick up to 10 recent expenses and delete them together",
+	}},
+	{Name: "show", Topic: helpTopicView, Menu: "Show one expense", Help: []string{
+		"<code>/show &lt;number&gt;</code> - Show an expense with its tags and location",
+	}},
+	{Name: "today", Topic: helpTopicView, Menu: "Show today's expenses", Help: []string{
+		"<code>/today</code> - Show today's expenses; <code>/today all</code> includes unpaid reimbursables",
+	}},
+	{Name: "week", Topic: helpTopicView, Menu: "Show this week's expenses", Help: []string{
+		"<code>/week</code> - Show this week's expenses",
+	}},
+	{Name: "month", Topic: helpTopicView, Menu: "Show this month's expenses", Help: []string{
+		"<code>/month</code> - Show this month's expenses",
+	}},
+	{Name: "total", Topic: helpTopicView, Menu: "Show today's and this week's totals", Help: []string{
+		"<code>/total</code> or <code>?</code> - Today's and this week's totals",
+	}},
+	{Name: "category", Topic: helpTopicView, Menu: "Filter expenses by category", Help: []string{
+		"<code>/category &lt;name&gt;</code> - Filter expenses by category",
+	}},
+	{Name: "uncategorized", Topic: helpTopicView, Menu: "List expenses without a category", Help: []string{
+		"<code>/uncategorized</code> - List expenses without a category",
+		"<code>/uncategorized --uncertain</code> - Also list medium-confidence AI categories",
+		"<code>/uncategorized threshold &lt;n&gt;</code> - Warn weekly above n uncategorized (0 = off)",
+	}},
+	{Name: "review", Topic: helpTopicView, Menu: "Review recent spending", Help: []string{
+		"<code>/review</code> - Review recent spending as worth it or not worth it",
+		"<code>/review YYYY-MM &lt;note&gt;</code> - Attach a one-line verdict to a month, shown on its statement",
+		"<code>/review YYYY-MM</code> - Show a month's note; add <code>clear</code> to remove it",
+	}},
+	{Name: "report", Topic: helpTopicReports, Menu: "Generate CSV report (week/month)", Help: []string{
+		"<code>/report week</code> - Generate weekly CSV report",
+		"<code>/report month</code> - Generate monthly CSV report",
+		"<code>/report month \"Work Travel\"</code> - Only one category's expenses",
+	}},
+	{Name: "chart", Topic: helpTopicReports, Menu: "Generate expense chart (week/month/pace)", Help: []string{
+		"<code>/chart week</code> - Generate weekly expense chart",
+		"<code>/chart month</code> - Generate monthly expense chart",
+		"<code>/chart pace</code> - Daily spending this month against your budget pace",
+	}},
+	{Name: "year", Topic: helpTopicReports, Menu: "Yearly summary by month", Help: []string{
+		"<code>/year [YYYY]</code> - Month-by-month totals, top categories and biggest expense, " +
+			"compared with the year before",
+	}},
+	{Name: "statement", Topic: helpTopicReports, Menu: "Monthly statement: CSV, chart and summary", Help: []string{
+		"<code>/statement [YYYY-MM]</code> - Monthly statement with CSV, chart and summary",
+	}},
+	{Name: "sheetexport", Topic: helpTopicReports, Menu: "Daily CSV of new expenses for a spreadsheet", Help: []string{
+		"<code>/sheetexport on|off</code> - Send the previous day's new expenses as a CSV every day",
+	}},
+	{Name: "archivechat", Topic: helpTopicReports, Menu: "Send monthly CSVs to an archive chat", Help: []string{
+		"<code>/archivechat &lt;chat_id&gt;|off</code> - Also send your monthly CSV to a chat on the 1st",
+	}},
+	{Name: "export", Topic: helpTopicReports, Menu: "Export all expenses as CSV", Help: []string{
+		"<code>/export</code> - Export all expenses as CSV",
+	}},
+	{Name: "import", Topic: helpTopicReports, Menu: "Import expenses from a CSV file", Help: []string{
+		"<code>/import [--force]</code> - Send as the caption of a CSV file to import its expenses",
+	}},
+	{Name: "habit", Topic: helpTopicReports, Menu: "Show spending reflection summary", Help: []string{
+		"<code>/habit</code> - Show this month's spending reflection",
+		"<code>/habit week</code> or <code>/habit 90d</code> - Change reflection period",
+	}},
+	{Name: "patterns", Topic: helpTopicReports, Menu: "Spending by weekday and time of day", Help: []string{
+		"<code>/patterns</code> - Average spend per weekday and time of day over the last 90 days",
+	}},
+	{Name: "categories", Topic: helpTopicCategories, Menu: "List all categories", Help: []string{
+		"<code>/categories</code> - List all categories",
+	}},
+	{Name: "addcategory", Topic: helpTopicCategories, Menu: "Create a new category", Help: []string{
+		"<code>/addcategory &lt;name&gt;</code> - Create a new category",
+	}},
+	{Name: "renamecategory", Topic: helpTopicCategories, Menu: "Rename a category", Help: []string{
+		"<code>/renamecategory Old -&gt; New</code> - Rename a category",
+	}},
+	{Name: "deletecategory", Topic: helpTopicCategories, Menu: "Delete a category", Help: []string{
+		"<code>/deletecategory &lt;name&gt;</code> - Delete a category",
+	}},
+	{Name: "reordercategories", Topic: helpTopicCategories, Menu: "Change the order of categories", Help: []string{
+		"<code>/reordercategories 3,1,2</code> - Move categories to the top of the keyboards",
+	}},
+	{Name: "categorytags", Topic: helpTopicCategories, Menu: "Tag every expense in a category", Help: []string{
+		"<code>/categorytags</code> - List the default tags of categories",
+		"<code>/categorytags \"Work Travel\" #reimbursable</code> - Tag every new expense in a category",
+		"<code>/categorytags \"Work Travel\" none</code> - Clear a category's default tags",
+	}},
+	{Name: "setcategorycolor", Topic: helpTopicCategories, Menu: "Set a category's chart color", Help: []string{
+		"<code>/setcategorycolor &lt;name&gt; #1F77B4|none</code> - Color a category in every chart",
+	}},
+	{Name: "exporttaxonomy", Topic: helpTopicCategories, Menu: "Export categories and tags as JSON", Help: []string{
+		"<code>/exporttaxonomy</code> - Export your categories and tags, without expenses, as a JSON file",
+	}},
+	{Name: "importtaxonomy", Topic: helpTopicCategories, Menu: "Import categories and tags from JSON", Help: []string{
+		"<code>/importtaxonomy</code> - Send as the caption of an /exporttaxonomy file to add missing categories and tags",
+	}},
+	{Name: "recategorize", Topic: helpTopicCategories, Menu: "Move matching expenses to a category", Help: []string{
+		"<code>/recategorize \"pattern\" &lt;category&gt;</code> - Move matching expenses to a category",
+	}},
+	{Name: "chatcategory", Topic: helpTopicCategories, Menu: "Set a group chat's default category", Help: []string{
+		"<code>/chatcategory &lt;name&gt;|off</code> - Default category for a group chat (group admins)",
+	}},
+	{Name: "currency", Topic: helpTopicCurrency, Menu: "Show your default currency", Help: []string{
+		"<code>/currency</code> - Show your default currency",
+	}},
+	{Name: "setcurrency", Topic: helpTopicCurrency, Menu: "Set default currency (e.g. USD, EUR)", Help: []string{
+		"<code>/setcurrency &lt;code&gt;</code> - Set default currency (e.g., USD, EUR, THB)",
+	}},
+	{Name: "timezone", Topic: helpTopicTimezone, Menu: "Show your timezone", Help: []string{
+		"<code>/timezone</code> - Show your timezone",
+	}},
+	{Name: "settimezone", Topic: helpTopicTimezone, Menu: "Set your timezone (e.g. Asia/Tokyo)", Help: []string{
+		"<code>/settimezone &lt;tz&gt;</code> - Set timezone (e.g., Asia/Tokyo, America/New_York)",
+	}},
+	{Name: "settings", Topic: helpTopicSettings, Menu: "Show or change your settings", Help: []string{
+		"<code>/settings</code> - Show your settings",
+		"<code>/settings maxamount &lt;amount&gt;</code> - Ask before saving expenses above this amount",
+		"<code>/settings autotag on|off</code> - Apply suggested tags without asking",
+		"<code>/settings format html|mono</code> - Show lists, totals and statements as aligned columns",
+		"<code>/settings ai on|off</code> - Let Gemini read your receipts, voice messages and descriptions",
+	}},
+	{Name: "exportsettings", Topic: helpTopicSettings, Menu: "Export your settings and budgets as JSON", Help: []string{
+		"<code>/exportsettings</code> - Export your settings and budgets as a JSON file to move them to another bot",
+	}},
+	{Name: "importsettings", Topic: helpTopicSettings, Menu: "Import settings and budgets from JSON", Help: []string{
+		"<code>/importsettings</code> - Send as the caption of an /exportsettings file to apply its settings and budgets",
+	}},
+	{Name: "pin", Topic: helpTopicSettings, Menu: "Lock sensitive commands with a PIN", Help: []string{
+		"<code>/pin set &lt;pin&gt;</code> - Lock /delete, /export, /report, /statement, /dedupe and /apitoken behind a 4-8 digit PIN",
+		"<code>/pin unlock &lt;pin&gt;</code> - Unlock them in this chat for 5 minutes",
+		"<code>/pin off &lt;pin&gt;</code> - Remove the PIN",
+	}},
+	{Name: "tag", Topic: helpTopicTags, Menu: "Add tags to an expense", Help: []string{
+		"<code>/tag &lt;id&gt; #tag1 [#tag2] ...</code> - Add tags to expense",
+	}},
+	{Name: "untag", Topic: helpTopicTags, Menu: "Remove a tag from an expense", Help: []string{
+		"<code>/untag &lt;id&gt; #tag</code> - Remove tag from expense",
+	}},
+	{Name: "tagrange", Topic: helpTopicTags, Menu: "Tag all expenses between two dates", Help: []string{
+		"<code>/tagrange YYYY-MM-DD YYYY-MM-DD #tag</code> - Tag all expenses in a date range",
+	}},
+	{Name: "tags", Topic: helpTopicTags, Menu: "List all tags or filter by tag", Help: []string{
+		"<code>/tags</code> - List all tags",
+		"<code>/tags #name</code> - Filter expenses by tag",
+	}},
+	{Name: "balance", Topic: helpTopicSplit, Menu: "Show who owes whom in this group", Help: []string{
+		"<code>/balance</code> - Show who owes whom from split expenses",
+	}},
+	{Name: "settle", Topic: helpTopicSplit, Menu: "Record a repayment in this group", Help: []string{
+		"<code>/settle @user &lt;amount&gt; [currency]</code> - Record that you paid someone back",
+	}},
+	{Name: "approve", Topic: helpTopicAdmin, Help: []string{
+		"<code>/approve &lt;user_id&gt;</code> or <code>/approve @username</code> - Approve a user",
+	}},
+	{Name: "revoke", Topic: helpTopicAdmin, Help: []string{
+		"<code>/revoke &lt;user_id&gt;</code> or <code>/revoke @username</code> - Revoke a user",
+	}},
+	{Name: "users", Topic: helpTopicAdmin, Help: []string{
+		"<code>/users [filter]</code> - List authorized users with their activity, " +
+			"optionally filtered by username, first name or ID",
+	}},
+	{Name: "inspect", Topic: helpTopicAdmin, Help: []string{
+		"<code>/inspect &lt;user&gt; list</code> or <code>/inspect &lt;user&gt; show &lt;id&gt;</code> - " +
+			"Read-only view of a user's expenses",
+	}},
+	{Name: "ocrstats", Topic: helpTopicAdmin, Help: []string{
+		"<code>/ocrstats</code> - Receipt scans and corrections per Gemini model, prompt version and prompt variant",
+	}},
+	{Name: "storagestats", Topic: helpTopicAdmin, Help: []string{
+		"<code>/storagestats</code> - Stored receipt images and the space they take",
+	}},
+	{Name: "usagestats", Topic: helpTopicAdmin, Help: []string{
+		"<code>/usagestats</code> - Gemini tokens used this month, per feature, against the monthly budget",
+	}},
+	{Name: "backfillrates", Topic: helpTopicAdmin, Help: []string{
+		"<code>/backfillrates</code> - Store historical exchange rates for older foreign-currency expenses",
+	}},
+	{Name: "banktemplates", Topic: helpTopicAdmin, Help: []string{
+		"<code>/banktemplates list|add &lt;name&gt; &lt;regex&gt;|delete &lt;name&gt;</code> - " +
+			"Manage the patterns that read forwarded bank messages",
+	}},
+	{Name: "promptvariants", Topic: helpTopicAdmin, Help: []string{
+		"<code>/promptvariants list|add &lt;name&gt; &lt;template&gt;|activate &lt;name&gt;|share &lt;name&gt; &lt;percent&gt;</code> - " +
+			"Try alternative receipt prompts on a share of scans",
+	}},
+	{Name: "apitoken", Topic: helpTopicOther, Menu: "Create or revoke your REST API token", Help: []string{
+		"<code>/apitoken</code> - Create a token for the REST API " +
+			"(<code>/apitoken revoke</code> to disable it)",
+	}},
+	{Name: "about", Topic: helpTopicOther, Menu: "Show the bot version and status", Help: []string{
+		"<code>/about</code> - Show the version, uptime and database status",
+	}},
+	{Name: "help", Topic: helpTopicOther, Menu: "Show help topics", Help: []string{
+		"<code>/help</code> - Show the help topics",
+		"<code>/help &lt;topic or command&gt;</code> - Show one topic, e.g. <code>/help report</code>",
+	}},
+}
+
+// seeHelp is appended to usage errors to point at the matching /help topic.
+func seeHelp(command string) string {
+	return "\n\nSee /help " + command
+}
+
+// findHelpTopic returns the topic named query, or the topic of the command
+// named query. The leading slash of a command is optional.
+func findHelpTopic(query string) (helpTopic, bool) {
+	query = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(query)), "/")
+	topicName := query
+	for _, def := range commandDefs {
+		if def.Name == query {
+			topicName = def.Topic
+			break
+		}
+	}
+	for _, topic := range helpTopics {
+		if topic.Name == topicName {
+			return topic, true
+		}
+	}
+	return helpTopic{}, false
+}
+
+// closestHelpName returns the topic or command name nearest to query, and
+// false when nothing is close enough to be a likely typo.
+func closestHelpName(query string) (string, bool) {
+	query = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(query)), "/")
+	names := make([]string, 0, len(helpTopics)+len(commandDefs))
+	for _, topic := range helpTopics {
+		names = append(names, topic.Name)
+	}
+	for _, def := range commandDefs {
+		names = append(names, def.Name)
+	}
+
+	closest := closestNames(query, names, 1)
+	if len(closest) == 0 {
+		return "", false
+	}
+	return closest[0], true
+}
+
+// formatHelpIndex renders bare /help: one line per topic with its commands.
+func formatHelpIndex() string {
+	var sb strings.Builder
+	sb.WriteString("📚 <b>Help</b>\n\n")
+	sb.WriteString("Send an expense like <code>5.50 Coffee</code>, or pick a topic:\n")
+	for _, topic := range helpTopics {
+		fmt.Fprintf(&sb, "\n• <code>/help %s</code> - %s:", topic.Name, topic.Title)
+		for _, def := range commandDefs {
+			if def.Topic == topic.Name {
+				sb.WriteString(" /" + def.Name)
+			}
+		}
+	}
+	sb.WriteString("\n\nYou can also ask about one command, e.g. <code>/help edit</code>.")
+	return sb.String()
+}
+
+// formatHelpTopic renders one /help topic.
+func formatHelpTopic(topic helpTopic) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📚 <b>%s</b>\n", topic.Title)
+	for _, note := range topic.Notes {
+		sb.WriteString("\n• " + note)
+	}
+	for _, def := range commandDefs {
+		if def.Topic != topic.Name {
+			continue
+		}
+		for _, line := range def.Help {
+			sb.WriteString("\n• " + line)
+		}
+	}
+	sb.WriteString("\n\nAll topics: /help")
+	return sb.String()
+}
+
+// helpText answers /help with the given arguments.
+func helpText(args string) string {
+	if args == "" {
+		return formatHelpIndex()
+	}
+	if topic, ok := findHelpTopic(args); ok {
+		return formatHelpTopic(topic)
+	}
+	text := fmt.Sprintf("❓ There is no help topic <code>%s</code>.", botfmt.EscapeHTML(args))
+	if name, ok := closestHelpName(args); ok {
+		text += fmt.Sprintf(" Did you mean <code>/help %s</code>?", name)
+	}
+	return text + "\n\nAll topics: /help"
+}
+
+// handleHelp handles the /help command.
+func (b *Bot) handleHelp(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleHelpCore(ctx, tgBot, update)
+}
+
+// handleHelpCore is the testable implementation of handleHelp. Bare /help
+// lists the topics; /help <topic or command> shows one of them.
+func (b *Bot) handleHelpCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	text := helpText(extractCommandArgs(update.Message.Text, "/help"))
+
+	logger.Log.Debug().Int64("chat_id", update.Message.Chat.ID).Msg("Sending /help response")
+	_, err := tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    update.Message.Chat.ID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to send /help response")
+	}
+}
@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+)
+
+func TestHelpIndex(t *testing.T) {
+	t.Parallel()
+
+	text := helpText("")
+	require.Less(t, utf8.RuneCountInString(text), telegramMessageLimit)
+	for _, topic := range helpTopics {
+		require.Contains(t, text, "<code>/help "+topic.Name+"</code>")
+	}
+	for _, def := range commandDefs {
+		require.Contains(t, text, "/"+def.Name)
+	}
+}
+
+func TestHelpTopics(t *testing.T) {
+	t.Parallel()
+
+	topicNames := make(map[string]bool, len(helpTopics))
+	for _, topic := range helpTopics {
+		topicNames[topic.Name] = true
+
+		text := helpText(topic.Name)
+		require.Contains(t, text, "<b>"+topic.Title+"</b>")
+		require.Less(t, utf8.RuneCountInString(text), telegramMessageLimit)
+		for _, def := range commandDefs {
+			if def.Topic == topic.Name {
+				require.Contains(t, text, "/"+def.Name, "topic %q", topic.Name)
+			}
+		}
+	}
+
+	seen := make(map[string]bool, len(commandDefs))
+	for _, def := range commandDefs {
+		require.True(t, topicNames[def.Topic], "command %q has unknown topic %q", def.Name, def.Topic)
+		require.NotEmpty(t, def.Help, "command %q has no help", def.Name)
+		require.False(t, seen[def.Name], "duplicate command %q", def.Name)
+		seen[def.Name] = true
+	}
+}
+
+func TestHelpCommandLookup(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		query string
+		title string
+	}{
+		{query: editAction, title: "Managing Expenses"},
+		{query: "/report", title: "Reports"},
+		{query: "Tags", title: "Tags"},
+		{query: "untag", title: "Tags"},
+		{query: "setcurrency", title: "Currency"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			t.Parallel()
+			topic, ok := findHelpTopic(tt.query)
+			require.True(t, ok)
+			require.Equal(t, tt.title, topic.Title)
+		})
+	}
+}
+
+func TestHelpFuzzySuggestion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "repot", want: "report"},
+		{query: "/delet", want: "delete"},
+		{query: "budgte", want: "budget"},
+		{query: "timzone", want: "timezone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			t.Parallel()
+			_, ok := findHelpTopic(tt.query)
+			require.False(t, ok)
+			name, ok := closestHelpName(tt.query)
+			require.True(t, ok)
+			require.Equal(t, tt.want, name)
+			require.Contains(t, helpText(tt.query), "Did you mean <code>/help "+tt.want+"</code>?")
+		})
+	}
+
+	t.Run("nothing close", func(t *testing.T) {
+		t.Parallel()
+		_, ok := closestHelpName("xylophone")
+		require.False(t, ok)
+		text := helpText("<xylophone>")
+		require.Contains(t, text, "&lt;xylophone&gt;")
+		require.NotContains(t, text, "Did you mean")
+	})
+}
+
+func TestHandleHelpCore_Topic(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{}
+	mockBot := mocks.NewMockBot()
+	b.handleHelpCore(context.Background(), mockBot, mocks.CommandUpdate(1, 1, "/help edit"))
+	text := mockBot.LastSentMessage().Text
+	require.Contains(t, text, "<b>Managing Expenses</b>")
+	require.Contains(t, text, "/delete")
+	require.NotContains(t, text, "/setcurrency")
+}
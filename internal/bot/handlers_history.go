@@ -0,0 +1,284 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+const (
+	// auditNoticeDelay is how old a group-chat expense must be before edits
+	// and deletions are announced in the group. Quick fixes right after
+	// adding an expense stay quiet.
+	auditNoticeDelay = 10 * time.Minute
+
+	auditUnknownActor = "Someone"
+	historyUsageMsg   = "❌ Usage: <code>/history &lt;id&gt;</code>\n\nSee /help history"
+	historyTimeLayout = "02 Jan 15:04"
+)
+
+// isGroupChat reports whether a Telegram chat ID belongs to a group or
+// supergroup. Telegram gives those negative IDs.
+func isGroupChat(chatID int64) bool {
+	return chatID < 0
+}
+
+// auditMoney renders an amount for the audit trail as plain text.
+func auditMoney(expense *appmodels.Expense) string {
+	return botfmt.CurrencySymbol(expense.Currency) + botfmt.FormatAmount(expense.Amount, expense.Currency) + " " + expense.Currency
+}
+
+// auditCategoryName returns the name of an expense's category, looking it
+// up when only the ID is loaded.
+func (b *Bot) auditCategoryName(ctx context.Context, expense *appmodels.Expense) string {
+	if expense.Category != nil {
+		return expense.Category.Name
+	}
+	if expense.CategoryID == nil {
+		return botfmt.Uncategorized
+	}
+	categories, err := b.getCategoriesWithCache(ctx)
+	if err == nil {
+		for i := range categories {
+			if categories[i].ID == *expense.CategoryID {
+				return categories[i].Name
+			}
+		}
+	}
+	return botfmt.Uncategorized
+}
+
+// expenseSourceChat returns the chat an expense was created in, or 0 when
+// it is unknown.
+func (b *Bot) expenseSourceChat(ctx context.Context, expenseID int) int64 {
+	chatID, err := b.expenseRepo.GetSourceChatID(ctx, expenseID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Log.Warn().Err(err).Int("expense_id", expenseID).Msg("Failed to look up expense source chat")
+		}
+		return 0
+	}
+	return chatID
+}
+
+// auditExpenseUpdate records what changed between before and after when a
+// confirmed expense is edited, and announces the change in the group chat
+// the expense came from when it is older than auditNoticeDelay. The
+// shares of a split expense follow a changed amount.
+func (b *Bot) auditExpenseUpdate(
+	ctx context.Context,
+	tg TelegramAPI,
+	actorID int64,
+	before appmodels.Expense,
+	after *appmodels.Expense,
+) {
+	b.resplitExpense(ctx, before, after)
+
+	if before.Status != appmodels.ExpenseStatusConfirmed {
+		return
+	}
+
+	entry := func(field, oldValue, newValue string) repository.ExpenseAuditEntry {
+		return repository.ExpenseAuditEntry{
+			ExpenseID:     after.ID,
+			UserID:        after.UserID,
+			ExpenseNumber: after.UserExpenseNumber,
+			ActorID:       actorID,
+			Field:         field,
+			OldValue:      oldValue,
+			NewValue:      newValue,
+		}
+	}
+
+	var entries []repository.ExpenseAuditEntry
+	if !before.Amount.Equal(after.Amount) || before.Currency != after.Currency {
+		entries = append(entries, entry(repository.ExpenseAuditAmount, auditMoney(&before), auditMoney(after)))
+	}
+	if before.Description != after.Description {
+		entries = append(entries, entry(repository.ExpenseAuditDescription, before.Description, after.Description))
+	}
+	if oldName, newName := b.auditCategoryName(ctx, &before), b.auditCategoryName(ctx, after); oldName != newName {
+		entries = append(entries, entry(repository.ExpenseAuditCategory, oldName, newName))
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	b.recordAudit(ctx, tg, after, entries, b.expenseSourceChat(ctx, after.ID))
+}
+
+// auditExpenseDelete records the deletion of a confirmed expense.
+// sourceChatID must be looked up before the expense is deleted, because the
+// source message link goes with it.
+func (b *Bot) auditExpenseDelete(
+	ctx context.Context,
+	tg TelegramAPI,
+	actorID int64,
+	expense *appmodels.Expense,
+	sourceChatID int64,
+) {
+	if expense.Status != appmodels.ExpenseStatusConfirmed {
+		return
+	}
+
+	b.recordAudit(ctx, tg, expense, []repository.ExpenseAuditEntry{{
+		ExpenseID:     expense.ID,
+		UserID:        expense.UserID,
+		ExpenseNumber: expense.UserExpenseNumber,
+		ActorID:       actorID,
+		Field:         repository.ExpenseAuditDeleted,
+		OldValue:      auditMoney(expense) + " · " + expense.Description,
+	}}, sourceChatID)
+}
+
+// recordAudit stores audit entries and posts the group notice when due.
+// Failures are logged only; the change itself already happened.
+func (b *Bot) recordAudit(
+	ctx context.Context,
+	tg TelegramAPI,
+	expense *appmodels.Expense,
+	entries []repository.ExpenseAuditEntry,
+	sourceChatID int64,
+) {
+	if err := b.expenseAuditRepo.Record(ctx, entries); err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expense.ID).Msg("Failed to record expense audit")
+	}
+
+	if !isGroupChat(sourceChatID) || b.now().Sub(expense.CreatedAt) <= auditNoticeDelay {
+		return
+	}
+
+	actor := b.auditActorName(ctx, entries[0].ActorID)
+	if _, err := tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    sourceChatID,
+		Text:      formatAuditNotice(actor, expense.UserExpenseNumber, entries),
+		ParseMode: models.ParseModeHTML,
+	}); err != nil {
+		logger.Log.Warn().Err(err).Int64("chat_id", sourceChatID).Msg("Failed to send audit notice")
+	}
+}
+
+// auditActorName returns the first name of the user who made a change.
+func (b *Bot) auditActorName(ctx context.Context, actorID int64) string {
+	user, err := b.userRepo.GetUserByID(ctx, actorID)
+	if err != nil || user.FirstName == "" {
+		return auditUnknownActor
+	}
+	return user.FirstName
+}
+
+// describeAuditChange renders one audit entry, e.g. "$15.50 USD → $25.50
+// USD" or "category Others → Transportation".
+func describeAuditChange(entry repository.ExpenseAuditEntry) string {
+	oldValue, newValue := botfmt.EscapeHTML(entry.OldValue), botfmt.EscapeHTML(entry.NewValue)
+	switch entry.Field {
+	case repository.ExpenseAuditAmount:
+		return oldValue + " → " + newValue
+	case repository.ExpenseAuditDeleted:
+		return "deleted (" + oldValue + ")"
+	default:
+		return entry.Field + " " + oldValue + " → " + newValue
+	}
+}
+
+// formatAuditNotice renders the group notice for a change, e.g.
+// "ℹ️ Alice changed #12: $15.50 USD → $25.50 USD".
+func formatAuditNotice(actor string, expenseNumber int64, entries []repository.ExpenseAuditEntry) string {
+	actor = botfmt.EscapeHTML(actor)
+	if entries[0].Field == repository.ExpenseAuditDeleted {
+		return fmt.Sprintf("ℹ️ %s deleted #%d (%s)", actor, expenseNumber, botfmt.EscapeHTML(entries[0].OldValue))
+	}
+
+	changes := make([]string, len(entries))
+	for i, entry := range entries {
+		changes[i] = describeAuditChange(entry)
+	}
+	return fmt.Sprintf("ℹ️ %s changed #%d: %s", actor, expenseNumber, strings.Join(changes, "; "))
+}
+
+// formatHistory renders the audit trail of one expense. Times are shown in
+// loc.
+func formatHistory(
+	expenseNumber int64,
+	entries []repository.ExpenseAuditEntry,
+	actorName func(int64) string,
+	loc *time.Location,
+) string {
+	if len(entries) == 0 {
+		return fmt.Sprintf("No changes recorded for expense #%d.", expenseNumber)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🕓 <b>History of #%d</b>\n", expenseNumber)
+	for _, entry := range entries {
+		fmt.Fprintf(&sb, "\n• %s — %s: %s",
+			entry.CreatedAt.In(loc).Format(historyTimeLayout),
+			botfmt.EscapeHTML(actorName(entry.ActorID)),
+			describeAuditChange(entry))
+	}
+	return sb.String()
+}
+
+// handleHistory handles the /history command.
+func (b *Bot) handleHistory(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleHistoryCore(ctx, tgBot, update)
+}
+
+// handleHistoryCore is the testable implementation of handleHistory. It
+// lists the recorded edits and deletion of one of the user's expenses,
+// including expenses that no longer exist.
+func (b *Bot) handleHistoryCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+
+	args := strings.TrimPrefix(extractCommandArgs(update.Message.Text, "/history"), "#")
+	expenseNumber, err := strconv.ParseInt(args, 10, 64)
+	if err != nil || expenseNumber <= 0 {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      historyUsageMsg,
+			ParseMode: models.ParseModeHTML,
+		})
+		return
+	}
+
+	entries, err := b.expenseAuditRepo.ListByExpenseNumber(ctx, userID, expenseNumber)
+	if err != nil {
+		logger.Log.Error().Err(err).Int64("expense_num", expenseNumber).Msg("Failed to load expense history")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to load history. Please try again.",
+		})
+		return
+	}
+
+	names := make(map[int64]string)
+	actorName := func(actorID int64) string {
+		if name, ok := names[actorID]; ok {
+			return name
+		}
+		names[actorID] = b.auditActorName(ctx, actorID)
+		return names[actorID]
+	}
+
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      formatHistory(expenseNumber, entries, actorName, b.locationForUser(ctx, userID)),
+		ParseMode: models.ParseModeHTML,
+	})
+}
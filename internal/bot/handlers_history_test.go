@@ -0,0 +1,192 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+const historyAmountChangeTest = "15.50 SGD → S$25.50 SGD"
+
+func TestFormatAuditNotice(t *testing.T) {
+	t.Parallel()
+
+	entries := []repository.ExpenseAuditEntry{
+		{Field: repository.ExpenseAuditAmount, OldValue: "$15.50 USD", NewValue: "$25.50 USD"},
+		{Field: repository.ExpenseAuditCategory, OldValue: "Others", NewValue: "Food & Drink"},
+	}
+	require.Equal(t,
+		"ℹ️ Alice changed #12: $15.50 USD → $25.50 USD; category Others → Food &amp; Drink",
+		formatAuditNotice("Alice", 12, entries))
+
+	deleted := []repository.ExpenseAuditEntry{
+		{Field: repository.ExpenseAuditDeleted, OldValue: "$25.50 USD · <Lunch>"},
+	}
+	require.Equal(t, "ℹ️ Bob deleted #3 ($25.50 USD · &lt;Lunch&gt;)", formatAuditNotice("Bob", 3, deleted))
+}
+
+func TestFormatHistory(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 3, 4, 6, 5, 0, 0, time.UTC)
+	entries := []repository.ExpenseAuditEntry{
+		{ActorID: 1, Field: repository.ExpenseAuditDescription, OldValue: "Coffee", NewValue: "Latte", CreatedAt: at},
+		{ActorID: 1, Field: repository.ExpenseAuditDeleted, OldValue: "$5.00 USD · Latte", CreatedAt: at.Add(time.Hour)},
+	}
+	loc := time.FixedZone("UTC+8", 8*60*60)
+
+	got := formatHistory(7, entries, func(int64) string { return "Alice" }, loc)
+	require.Contains(t, got, "<b>History of #7</b>")
+	require.Contains(t, got, "• 04 Mar 14:05 — Alice: description Coffee → Latte")
+	require.Contains(t, got, "• 04 Mar 15:05 — Alice: deleted ($5.00 USD · Latte)")
+
+	require.Equal(t, "No changes recorded for expense #7.", formatHistory(7, nil, nil, loc))
+}
+
+func TestExpenseAuditTrail(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(380101)
+	groupChatID := int64(-100380101)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Alice"}))
+
+	messageID := 0
+	create := func(chatID int64) *appmodels.Expense {
+		expense := &appmodels.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal("15.50"),
+			Currency:    currencyCodeSGD,
+			Description: "Groceries",
+			Status:      appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		messageID++
+		require.NoError(t, b.expenseRepo.LinkSourceMessage(ctx, expense.ID, chatID, messageID))
+		return expense
+	}
+	after := func(d time.Duration) {
+		b.nowFunc = func() time.Time { return time.Now().Add(d) }
+	}
+	sentTo := func(mockBot *mocks.MockBot, chatID int64) []string {
+		var texts []string
+		for _, msg := range mockBot.SentMessages {
+			if msg.ChatID == chatID {
+				texts = append(texts, msg.Text)
+			}
+		}
+		return texts
+	}
+	history := func(expense *appmodels.Expense) []repository.ExpenseAuditEntry {
+		entries, err := b.expenseAuditRepo.ListByExpenseNumber(ctx, userID, expense.UserExpenseNumber)
+		require.NoError(t, err)
+		return entries
+	}
+	edit := func(expense *appmodels.Expense, values string) *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		b.handleEditCore(ctx, mockBot,
+			mocks.CommandUpdate(userID, userID, fmt.Sprintf("/edit %d %s", expense.UserExpenseNumber, values)))
+		return mockBot
+	}
+
+	t.Run("late amount edit in a group is announced", func(t *testing.T) {
+		expense := create(groupChatID)
+		after(auditNoticeDelay + time.Minute)
+
+		mockBot := edit(expense, "25.50 Groceries")
+
+		notices := sentTo(mockBot, groupChatID)
+		require.Len(t, notices, 1)
+		require.Contains(t, notices[0], fmt.Sprintf("ℹ️ Alice changed #%d: ", expense.UserExpenseNumber))
+		require.Contains(t, notices[0], historyAmountChangeTest)
+		require.Len(t, sentTo(mockBot, userID), 1)
+
+		entries := history(expense)
+		require.Len(t, entries, 1)
+		require.Equal(t, repository.ExpenseAuditAmount, entries[0].Field)
+		require.Equal(t, userID, entries[0].ActorID)
+	})
+
+	t.Run("quick fixes are recorded without a notice", func(t *testing.T) {
+		expense := create(groupChatID)
+		after(auditNoticeDelay - time.Minute)
+
+		mockBot := edit(expense, "25.50 Groceries")
+
+		require.Empty(t, sentTo(mockBot, groupChatID))
+		require.Len(t, history(expense), 1)
+	})
+
+	t.Run("category change", func(t *testing.T) {
+		expense := create(groupChatID)
+		after(auditNoticeDelay + time.Minute)
+		categories, err := b.getCategoriesWithCache(ctx)
+		require.NoError(t, err)
+
+		mockBot := mocks.NewMockBot()
+		b.handleSetCategoryCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 1,
+			fmt.Sprintf("set_category_%d_%d", expense.ID, categories[0].ID)))
+
+		entries := history(expense)
+		require.Len(t, entries, 1)
+		require.Equal(t, repository.ExpenseAuditCategory, entries[0].Field)
+		require.Equal(t, "Uncategorized", entries[0].OldValue)
+		require.Equal(t, categories[0].Name, entries[0].NewValue)
+		require.Len(t, sentTo(mockBot, groupChatID), 1)
+	})
+
+	t.Run("delete is recorded and announced", func(t *testing.T) {
+		expense := create(groupChatID)
+		after(auditNoticeDelay + time.Minute)
+
+		mockBot := mocks.NewMockBot()
+		b.handleDeleteCore(ctx, mockBot,
+			mocks.CommandUpdate(userID, userID, fmt.Sprintf("/delete %d", expense.UserExpenseNumber)))
+
+		notices := sentTo(mockBot, groupChatID)
+		require.Len(t, notices, 1)
+		require.Contains(t, notices[0], fmt.Sprintf("Alice deleted #%d", expense.UserExpenseNumber))
+
+		entries := history(expense)
+		require.Len(t, entries, 1)
+		require.Equal(t, repository.ExpenseAuditDeleted, entries[0].Field)
+		require.Contains(t, entries[0].OldValue, "15.50 SGD · Groceries")
+	})
+
+	t.Run("private chat expenses get no notice", func(t *testing.T) {
+		expense := create(userID)
+		after(auditNoticeDelay + time.Minute)
+
+		mockBot := edit(expense, "25.50 Groceries")
+
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		require.Len(t, history(expense), 1)
+	})
+
+	t.Run("history lists the trail", func(t *testing.T) {
+		expense := create(userID)
+		edit(expense, "25.50 Weekly groceries")
+
+		mockBot := mocks.NewMockBot()
+		b.handleHistoryCore(ctx, mockBot,
+			mocks.CommandUpdate(userID, userID, fmt.Sprintf("/history #%d", expense.UserExpenseNumber)))
+
+		text := mockBot.LastSentMessage().Text
+		require.Contains(t, text, fmt.Sprintf("<b>History of #%d</b>", expense.UserExpenseNumber))
+		require.Contains(t, text, "Alice: S$"+historyAmountChangeTest)
+		require.Contains(t, text, "Alice: description Groceries → Weekly groceries")
+	})
+
+	t.Run("history usage", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleHistoryCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/history abc"))
+		require.Equal(t, historyUsageMsg, mockBot.LastSentMessage().Text)
+	})
+}
@@ -0,0 +1,514 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
+)
+
+const (
+	importCommand   = "/import"
+	importForceFlag = "--force"
+	// importMaxRows caps how many expense rows one file may hold.
+	importMaxRows = 5000
+
+	importUsageMsg = "📥 Send a CSV file with the caption <code>/import</code>, or reply to one with " +
+		"<code>/import</code>.\n\nThe file needs Date and Amount columns, like the one /export sends. " +
+		"Currency, Description, Merchant, Category and Type are read when present."
+	importFailedMsg   = "❌ Failed to import expenses. Please try again."
+	importNoHeaderMsg = "❌ The file needs a header row with Date and Amount columns, like the one /export sends."
+	importNoRowsMsg   = "📭 No expenses found in this file."
+	importDateLayout  = "Jan 2, 2006"
+)
+
+// importDateLayouts are the Date cell formats /import reads, the /export
+// one first.
+var importDateLayouts = []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+var (
+	errImportHeader  = errors.New("missing Date or Amount column")
+	errImportTooLong = fmt.Errorf("file has more than %d rows", importMaxRows)
+)
+
+// importRow is one expense read from an import file.
+type importRow struct {
+	CreatedAt   time.Time
+	Amount      decimal.Decimal
+	Currency    string
+	Description string
+	Merchant    string
+	Category    string
+}
+
+// importFile is a parsed import file. Invalid counts the rows without a
+// valid date, amount or currency.
+type importFile struct {
+	Rows    []importRow
+	Invalid int
+}
+
+// importSummary counts what an import did with the rows of a file.
+type importSummary struct {
+	Imported  int
+	Duplicate int
+	Invalid   int
+}
+
+// importColumns holds the index of each known header, or -1.
+type importColumns struct {
+	id, date, amount, currency, description, merchant, category, kind int
+}
+
+// importSkippedLabels mark the summary and review rows /export appends in
+// the ID column. Like empty rows, they are not counted as invalid.
+var importSkippedLabels = map[string]bool{
+	csvSummaryTotal:    true,
+	csvSummarySubtotal: true,
+	csvMonthlyReview:   true,
+}
+
+// normalizeImportCSV strips what differs between copies of the same file
+// saved by different tools: a byte order mark, CRLF line endings, trailing
+// spaces and blank lines.
+func normalizeImportCSV(data []byte) []byte {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	lines := strings.Split(string(data), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimRight(line, " \t\r"); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return []byte(strings.Join(kept, "\n"))
+}
+
+// importFileHash identifies a file by the SHA-256 of its normalized
+// contents.
+func importFileHash(normalized []byte) string {
+	sum := sha256.Sum256(normalized)
+	return hex.EncodeToString(sum[:])
+}
+
+// importIdempotencyKey names row i of the file with the given hash, so
+// importing the same row again returns the expense it created before.
+func importIdempotencyKey(hash string, i int) string {
+	return fmt.Sprintf("import:%s:%d", hash, i)
+}
+
+// unsanitizeCSVCell undoes sanitizeCSVCell, so exported formula-like text
+// imports as it was written.
+func unsanitizeCSVCell(s string) string {
+	rest, ok := strings.CutPrefix(s, "'")
+	if !ok {
+		return strings.TrimSpace(s)
+	}
+	if sanitizeCSVCell(rest) == s {
+		return strings.TrimSpace(rest)
+	}
+	return strings.TrimSpace(s)
+}
+
+// findImportColumns locates the known headers, ignoring case.
+func findImportColumns(header []string) (importColumns, error) {
+	cols := importColumns{-1, -1, -1, -1, -1, -1, -1, -1}
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case strings.ToLower(csvHeaderID):
+			cols.id = i
+		case strings.ToLower(csvHeaderDate):
+			cols.date = i
+		case strings.ToLower(csvHeaderAmount):
+			cols.amount = i
+		case strings.ToLower(csvHeaderCurrency):
+			cols.currency = i
+		case strings.ToLower(csvHeaderDescription):
+			cols.description = i
+		case strings.ToLower(csvHeaderMerchant):
+			cols.merchant = i
+		case strings.ToLower(csvHeaderCategory):
+			cols.category = i
+		case strings.ToLower(csvHeaderType):
+			cols.kind = i
+		}
+	}
+	if cols.date < 0 || cols.amount < 0 {
+		return cols, errImportHeader
+	}
+	return cols, nil
+}
+
+// parseImportCSV reads the expense rows of a normalized CSV file. Dates
+// are read in loc and a missing currency falls back to defaultCurrency.
+func parseImportCSV(normalized []byte, loc *time.Location, defaultCurrency string) (*importFile, error) {
+	reader := csv.NewReader(bytes.NewReader(normalized))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errImportHeader
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	cols, err := findImportColumns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &importFile{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV row: %w", err)
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" ||
+			(cols.id >= 0 && cols.id < len(record) && importSkippedLabels[record[cols.id]]) {
+			continue
+		}
+		if len(file.Rows)+file.Invalid >= importMaxRows {
+			return nil, errImportTooLong
+		}
+		row, ok := parseImportRow(record, cols, loc, defaultCurrency)
+		if !ok {
+			file.Invalid++
+			continue
+		}
+		file.Rows = append(file.Rows, row)
+	}
+	return file, nil
+}
+
+// parseImportRow reads one record, reporting false when it has no valid
+// date, amount or currency. Amounts must be positive, except in rows whose
+// Type is Refund, which are imported negative whatever their sign.
+func parseImportRow(record []string, cols importColumns, loc *time.Location, defaultCurrency string) (importRow, bool) {
+	cell := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return unsanitizeCSVCell(record[i])
+	}
+
+	var row importRow
+	createdAt, ok := parseImportDate(cell(cols.date), loc)
+	if !ok {
+		return row, false
+	}
+	amount, err := decimal.NewFromString(strings.ReplaceAll(cell(cols.amount), ",", ""))
+	if err != nil || !appmodels.AmountExponentInRange(amount) || amount.IsZero() {
+		return row, false
+	}
+	switch {
+	case strings.EqualFold(cell(cols.kind), csvTypeRefund):
+		amount = amount.Abs().Neg()
+	case amount.IsNegative():
+		return row, false
+	}
+	currency := strings.ToUpper(cell(cols.currency))
+	if currency == "" {
+		currency = defaultCurrency
+	}
+	if _, ok := appmodels.SupportedCurrencies[currency]; !ok {
+		return row, false
+	}
+
+	row = importRow{
+		CreatedAt:   createdAt,
+		Amount:      amount,
+		Currency:    currency,
+		Description: cell(cols.description),
+		Merchant:    cell(cols.merchant),
+		Category:    cell(cols.category),
+	}
+	return row, true
+}
+
+func parseImportDate(value string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range importDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// importDedupeKey identifies an expense by its day in loc, amount,
+// currency and description, ignoring case and repeated spaces.
+func importDedupeKey(
+	createdAt time.Time,
+	amount decimal.Decimal,
+	currency, description string,
+	loc *time.Location,
+) string {
+	return strings.Join([]string{
+		createdAt.In(loc).Format("2006-01-02"),
+		amount.String(),
+		currency,
+		strings.Join(strings.Fields(strings.ToLower(description)), " "),
+	}, "|")
+}
+
+// importDocument returns the file an /import message is about: its own
+// attachment or the one it replies to.
+func importDocument(msg *models.Message) *models.Document {
+	if msg.Document != nil {
+		return msg.Document
+	}
+	if msg.ReplyToMessage != nil {
+		return msg.ReplyToMessage.Document
+	}
+	return nil
+}
+
+// handleImport handles the /import command.
+func (b *Bot) handleImport(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleImportCore(ctx, tgBot, update)
+}
+
+// handleImportCore is the testable implementation of handleImport. It
+// downloads the CSV file the command came with and imports its expenses.
+func (b *Bot) handleImportCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+	msg := update.Message
+	sendHTML := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: msg.Chat.ID, Text: text, ParseMode: models.ParseModeHTML})
+	}
+
+	doc := importDocument(msg)
+	if doc == nil {
+		sendHTML(importUsageMsg)
+		return
+	}
+	if doc.FileSize > maxDownloadBytes {
+		sendHTML("❌ The file is too large to import.")
+		return
+	}
+	data, err := b.downloadFile(ctx, tg, doc.FileID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to download import file")
+		sendHTML(importFailedMsg)
+		return
+	}
+
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+	force := false
+	for _, arg := range strings.Fields(extractCommandArgs(text, importCommand)) {
+		force = force || arg == importForceFlag
+	}
+	b.importCSVCore(ctx, tg, msg.Chat.ID, msg.From.ID, data, force)
+}
+
+// importCSVCore imports the expenses of a CSV file for a user. A file
+// that was imported before is refused unless force is set. Rows matching
+// an expense the user already has are skipped either way.
+func (b *Bot) importCSVCore(ctx context.Context, tg TelegramAPI, chatID, userID int64, data []byte, force bool) {
+	sendHTML := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: text, ParseMode: models.ParseModeHTML})
+	}
+
+	normalized := normalizeImportCSV(data)
+	hash := importFileHash(normalized)
+	loc := b.locationForUser(ctx, userID)
+
+	if !force {
+		batch, err := b.importBatchRepo.GetLatest(ctx, userID, hash)
+		switch {
+		case err == nil:
+			sendHTML(fmt.Sprintf("⚠️ This file was already imported on %s (%d expense(s)).\n\n"+
+				"Send it again with <code>/import %s</code> to import it anyway. "+
+				"Expenses you already have are still skipped.",
+				batch.CreatedAt.In(loc).Format(importDateLayout), batch.RowCount, importForceFlag))
+			return
+		case !errors.Is(err, repository.ErrImportBatchNotFound):
+			logger.Log.Error().Err(err).Msg("Failed to look up import batch")
+			sendHTML(importFailedMsg)
+			return
+		}
+	}
+
+	defaultCurrency, err := b.userRepo.GetDefaultCurrency(ctx, userID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to get default currency for import")
+		defaultCurrency = appmodels.DefaultCurrency
+	}
+	file, err := parseImportCSV(normalized, loc, defaultCurrency)
+	switch {
+	case errors.Is(err, errImportHeader):
+		sendHTML(importNoHeaderMsg)
+		return
+	case errors.Is(err, errImportTooLong):
+		sendHTML(fmt.Sprintf("❌ The file has more than %d rows. Split it and import the parts.", importMaxRows))
+		return
+	case err != nil:
+		logger.Log.Warn().Err(err).Msg("Failed to parse import file")
+		sendHTML("❌ Could not read the file as CSV.")
+		return
+	}
+	if len(file.Rows) == 0 {
+		sendHTML(importNoRowsMsg)
+		return
+	}
+
+	summary, err := b.importRows(ctx, userID, hash, file, loc)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to import expenses")
+		sendHTML(importFailedMsg)
+		return
+	}
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(userID)).
+		Int("imported", summary.Imported).
+		Int("duplicates", summary.Duplicate).
+		Int("invalid", summary.Invalid).
+		Bool("force", force).
+		Msg("Expenses imported")
+	sendHTML(formatImportSummary(summary))
+}
+
+// importRows saves the rows of a file that do not match an existing
+// expense and records the file, in one transaction when the database
+// supports it.
+func (b *Bot) importRows(
+	ctx context.Context,
+	userID int64,
+	hash string,
+	file *importFile,
+	loc *time.Location,
+) (importSummary, error) {
+	summary := importSummary{Invalid: file.Invalid}
+	existing, err := b.importExistingKeys(ctx, userID, file.Rows, loc)
+	if err != nil {
+		return summary, err
+	}
+	categories, err := b.visibleCategories(ctx, userID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to load categories for import")
+	}
+
+	db := b.db
+	var tx pgx.Tx
+	if beginner, ok := b.db.(database.TxBeginner); ok {
+		tx, err = beginner.Begin(ctx)
+		if err != nil {
+			return summary, fmt.Errorf("begin tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		db = tx
+	}
+	expenses := b.expenses().WithStore(repository.NewExpenseRepository(db))
+
+	for i, row := range file.Rows {
+		row.Description, _ = b.normalizeDescription(row.Description)
+		row.Merchant, _ = b.normalizeDescription(row.Merchant)
+		if existing[importDedupeKey(row.CreatedAt, row.Amount, row.Currency, row.Description, loc)] {
+			summary.Duplicate++
+			continue
+		}
+		categoryID, _ := findCategoryByName(categories, row.Category)
+		expense := &appmodels.Expense{
+			UserID:         userID,
+			Amount:         row.Amount,
+			Currency:       row.Currency,
+			Description:    row.Description,
+			Merchant:       row.Merchant,
+			CategoryID:     categoryID,
+			CreatedAt:      row.CreatedAt,
+			IdempotencyKey: importIdempotencyKey(hash, i),
+		}
+		err := expenses.Import(ctx, expense)
+		if errors.Is(err, service.ErrAboveHardCap) {
+			summary.Invalid++
+			continue
+		}
+		if err != nil {
+			return summary, fmt.Errorf("import row: %w", err)
+		}
+		if expense.Replayed {
+			summary.Duplicate++
+			continue
+		}
+		summary.Imported++
+	}
+
+	if err := repository.NewImportBatchRepository(db).Record(ctx, userID, hash, summary.Imported); err != nil {
+		return summary, err
+	}
+	if tx != nil {
+		if err := tx.Commit(ctx); err != nil {
+			return summary, fmt.Errorf("commit tx: %w", err)
+		}
+	}
+	return summary, nil
+}
+
+// importExistingKeys returns the dedupe keys of the user's confirmed
+// expenses on the days the rows cover.
+func (b *Bot) importExistingKeys(
+	ctx context.Context,
+	userID int64,
+	rows []importRow,
+	loc *time.Location,
+) (map[string]bool, error) {
+	first, last := rows[0].CreatedAt, rows[0].CreatedAt
+	for _, row := range rows[1:] {
+		if row.CreatedAt.Before(first) {
+			first = row.CreatedAt
+		}
+		if row.CreatedAt.After(last) {
+			last = row.CreatedAt
+		}
+	}
+	first = first.In(loc)
+	start := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc)
+	last = last.In(loc)
+	end := time.Date(last.Year(), last.Month(), last.Day()+1, 0, 0, 0, 0, loc)
+
+	expenses, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, userID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("load existing expenses: %w", err)
+	}
+	keys := make(map[string]bool, len(expenses))
+	for i := range expenses {
+		e := &expenses[i]
+		keys[importDedupeKey(e.CreatedAt, e.Amount, e.Currency, e.Description, loc)] = true
+	}
+	return keys, nil
+}
+
+// formatImportSummary reports the imported, duplicate and unreadable rows.
+func formatImportSummary(summary importSummary) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "✅ Imported %d expense(s).", summary.Imported)
+	if summary.Duplicate > 0 {
+		fmt.Fprintf(&sb, "\n⏭ Skipped %d already recorded (same date, amount, currency and description).",
+			summary.Duplicate)
+	}
+	if summary.Invalid > 0 {
+		fmt.Fprintf(&sb, "\n⚠️ Ignored %d row(s) without a valid date, amount or currency.", summary.Invalid)
+	}
+	return sb.String()
+}
@@ -0,0 +1,195 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestImportFileHash(t *testing.T) {
+	t.Parallel()
+
+	plain := "Date,Amount\n2026-03-01,5.50\n2026-03-02,4.00"
+	variants := []string{
+		plain,
+		"\ufeffDate,Amount\r\n2026-03-01,5.50\r\n2026-03-02,4.00\r\n",
+		"Date,Amount  \n\n2026-03-01,5.50\n2026-03-02,4.00\n\n",
+	}
+	want := importFileHash(normalizeImportCSV([]byte(plain)))
+	for _, variant := range variants {
+		require.Equal(t, want, importFileHash(normalizeImportCSV([]byte(variant))), "%q", variant)
+	}
+	require.NotEqual(t, want, importFileHash(normalizeImportCSV([]byte(plain+"\n2026-03-03,1.00"))))
+}
+
+func TestParseImportCSV(t *testing.T) {
+	t.Parallel()
+
+	loc := time.FixedZone("SGT", 8*60*60)
+
+	t.Run("export layout", func(t *testing.T) {
+		t.Parallel()
+
+		data := "ID,Date,Amount,Currency,Description,Merchant,Category,Worth It,Reimbursable\n" +
+			"1,2026-03-01 08:15:00,5.50,SGD,Coffee,Cafe,Food - Dining Out,,\n" +
+			"2,2026-03-02 12:00:00,12.00,usd,'=SUM(A1),,Unknown,,\n" +
+			"3,not a date,3.00,SGD,Bad date,,,,\n" +
+			"4,2026-03-03,-1,SGD,Negative,,,,\n" +
+			"5,2026-03-03,1,XXX,Unknown currency,,,,\n" +
+			",,,,,,,,\n" +
+			"Total,,17.50,SGD,,,,,\n" +
+			"Subtotal,,5.50,SGD,,,Food - Dining Out,,\n" +
+			"Review,2026-03,,,Quiet month,,,,\n"
+		file, err := parseImportCSV(normalizeImportCSV([]byte(data)), loc, currencyCodeSGD)
+		require.NoError(t, err)
+		require.Len(t, file.Rows, 2)
+		require.Equal(t, 3, file.Invalid, "summary, review and blank rows are not invalid")
+
+		require.Equal(t, time.Date(2026, 3, 1, 8, 15, 0, 0, loc), file.Rows[0].CreatedAt)
+		require.True(t, mustParseDecimal("5.50").Equal(file.Rows[0].Amount))
+		require.Equal(t, "Coffee", file.Rows[0].Description)
+		require.Equal(t, "Cafe", file.Rows[0].Merchant)
+		require.Equal(t, "Food - Dining Out", file.Rows[0].Category)
+
+		require.Equal(t, "USD", file.Rows[1].Currency)
+		require.Equal(t, "=SUM(A1)", file.Rows[1].Description, "the formula prefix is removed")
+	})
+
+	t.Run("refunds", func(t *testing.T) {
+		t.Parallel()
+
+		data := "Date,Amount,Description,Type\n" +
+			"2026-03-01,-25.90,Amazon return,Refund\n" +
+			"2026-03-02,10.00,Partial refund,refund\n" +
+			"2026-03-03,-5.00,Negative expense,Expense\n" +
+			"2026-03-04,0,Nothing,Refund\n"
+		file, err := parseImportCSV([]byte(data), loc, currencyCodeSGD)
+		require.NoError(t, err)
+		require.Len(t, file.Rows, 2)
+		require.Equal(t, 2, file.Invalid)
+		require.True(t, mustParseDecimal("-25.90").Equal(file.Rows[0].Amount))
+		require.True(t, mustParseDecimal("-10.00").Equal(file.Rows[1].Amount), "refund rows are negative")
+	})
+
+	t.Run("minimal columns in any order", func(t *testing.T) {
+		t.Parallel()
+
+		file, err := parseImportCSV([]byte("amount,date\n\"1,234.50\",2026-01-05"), loc, "EUR")
+		require.NoError(t, err)
+		require.Len(t, file.Rows, 1)
+		require.True(t, mustParseDecimal("1234.50").Equal(file.Rows[0].Amount))
+		require.Equal(t, "EUR", file.Rows[0].Currency, "a missing currency is the default")
+	})
+
+	t.Run("missing headers", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := parseImportCSV([]byte("Description,Amount\nCoffee,5"), loc, currencyCodeSGD)
+		require.ErrorIs(t, err, errImportHeader)
+		_, err = parseImportCSV(nil, loc, currencyCodeSGD)
+		require.ErrorIs(t, err, errImportHeader)
+	})
+}
+
+func TestImportDedupeKey(t *testing.T) {
+	t.Parallel()
+
+	loc := time.FixedZone("SGT", 8*60*60)
+	morning := time.Date(2026, 3, 1, 9, 0, 0, 0, loc)
+	key := importDedupeKey(morning, mustParseDecimal("5.5"), currencyCodeSGD, "Iced  Coffee", loc)
+
+	require.Equal(t, key, importDedupeKey(morning.Add(10*time.Hour), mustParseDecimal("5.50"),
+		currencyCodeSGD, "iced coffee", loc), "time of day, trailing zeros, case and spacing are ignored")
+	require.Equal(t, key, importDedupeKey(morning.UTC(), mustParseDecimal("5.50"),
+		currencyCodeSGD, "Iced Coffee", loc), "days are compared in the user's timezone")
+	require.NotEqual(t, key, importDedupeKey(morning.AddDate(0, 0, 1), mustParseDecimal("5.50"),
+		currencyCodeSGD, "Iced Coffee", loc))
+	require.NotEqual(t, key, importDedupeKey(morning, mustParseDecimal("5.50"), "USD", "Iced Coffee", loc))
+}
+
+func TestFormatImportSummary(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "✅ Imported 3 expense(s).", formatImportSummary(importSummary{Imported: 3}))
+
+	text := formatImportSummary(importSummary{Imported: 1, Duplicate: 2, Invalid: 4})
+	require.Contains(t, text, "Skipped 2 already recorded")
+	require.Contains(t, text, "Ignored 4 row(s)")
+}
+
+func TestImportCSV(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(433201)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Importer"}))
+
+	// One expense the file also holds, recorded before the import.
+	loc := b.locationForUser(ctx, userID)
+	existing := &appmodels.Expense{
+		UserID:      userID,
+		Amount:      mustParseDecimal("5.50"),
+		Currency:    currencyCodeSGD,
+		Description: "Coffee",
+		CreatedAt:   time.Date(2026, 3, 1, 18, 0, 0, 0, loc),
+	}
+	require.NoError(t, b.expenseRepo.CreateImported(ctx, existing))
+
+	data := []byte("ID,Date,Amount,Currency,Description\n" +
+		"1,2026-03-01 08:15:00,5.50,SGD,coffee\n" +
+		"2,2026-03-01 12:00:00,12.00,SGD,Lunch\n" +
+		"3,2026-03-02 19:30:00,30.00,SGD,Dinner\n" +
+		"4,someday,1.00,SGD,Broken\n")
+	count := func() int {
+		expenses, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, userID,
+			time.Date(2026, 3, 1, 0, 0, 0, 0, loc), time.Date(2026, 3, 3, 0, 0, 0, 0, loc))
+		require.NoError(t, err)
+		return len(expenses)
+	}
+	importCSV := func(body []byte, force bool) string {
+		mockBot := mocks.NewMockBot()
+		b.importCSVCore(ctx, mockBot, userID, userID, body, force)
+		return mockBot.LastSentMessage().Text
+	}
+
+	t.Run("rows matching existing expenses are skipped", func(t *testing.T) {
+		text := importCSV(data, false)
+		require.Contains(t, text, "Imported 2 expense(s)")
+		require.Contains(t, text, "Skipped 1 already recorded")
+		require.Contains(t, text, "Ignored 1 row(s)")
+		require.Equal(t, 3, count())
+	})
+
+	t.Run("the same file is refused", func(t *testing.T) {
+		withBOM := []byte("\ufeff" + strings.ReplaceAll(string(data), "\n", "\r\n"))
+		text := importCSV(withBOM, false)
+		require.Contains(t, text, "already imported on")
+		require.Contains(t, text, "(2 expense(s))")
+		require.Equal(t, 3, count())
+	})
+
+	t.Run("force imports it again, still skipping duplicates", func(t *testing.T) {
+		text := importCSV(data, true)
+		require.Contains(t, text, "Imported 0 expense(s)")
+		require.Contains(t, text, "Skipped 3 already recorded")
+		require.Equal(t, 3, count())
+
+		batch, err := b.importBatchRepo.GetLatest(ctx, userID, importFileHash(normalizeImportCSV(data)))
+		require.NoError(t, err)
+		require.Equal(t, 0, batch.RowCount, "the forced import is recorded too")
+	})
+
+	t.Run("a changed file imports only its new rows", func(t *testing.T) {
+		more := append(append([]byte{}, data...), []byte("5,2026-03-02 21:00:00,8.00,SGD,Dessert\n")...)
+		text := importCSV(more, false)
+		require.Contains(t, text, "Imported 1 expense(s)")
+		require.Contains(t, text, "Skipped 3 already recorded")
+		require.Equal(t, 4, count())
+	})
+}
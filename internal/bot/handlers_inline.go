@@ -0,0 +1,258 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// inlineStatsTTL is how long inline answers are reused per user and
+	// query text. Telegram sends a new query on every keystroke.
+	inlineStatsTTL = 5 * time.Second
+	// inlineMaxCategoryResults caps the categories offered for one query.
+	inlineMaxCategoryResults = 5
+
+	inlinePeriodToday = "today"
+	inlinePeriodWeek  = "week"
+	inlinePeriodMonth = "month"
+
+	inlineNotAuthorizedTitle = "⛔ Not authorized"
+	inlineNotAuthorizedText  = "You are not authorized to use this bot."
+)
+
+// inlinePeriods lists the period keywords in the order they are offered.
+var inlinePeriods = []string{inlinePeriodToday, inlinePeriodWeek, inlinePeriodMonth}
+
+// inlineStatsQuery is one total an inline query asks for: a period, or
+// this month's spending in a category.
+type inlineStatsQuery struct {
+	Period   string
+	Category *appmodels.Category
+}
+
+type inlineStatsEntry struct {
+	results   []models.InlineQueryResult
+	expiresAt time.Time
+}
+
+// inlineStatsCache keeps recent inline answers per user and query text. The
+// zero value is ready to use.
+type inlineStatsCache struct {
+	mu      sync.Mutex
+	entries map[string]inlineStatsEntry
+}
+
+func inlineStatsKey(userID int64, text string) string {
+	return strconv.FormatInt(userID, 10) + ":" + text
+}
+
+// get returns the cached results for key if they have not expired.
+func (c *inlineStatsCache) get(key string, now time.Time) ([]models.InlineQueryResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.results, true
+}
+
+// put stores results for key and drops expired entries.
+func (c *inlineStatsCache) put(key string, results []models.InlineQueryResult, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]inlineStatsEntry)
+	}
+	for k, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = inlineStatsEntry{results: results, expiresAt: now.Add(inlineStatsTTL)}
+}
+
+// resolveInlineQuery maps inline query text to the totals it asks for.
+// Empty text offers every period. Otherwise period keywords and category
+// names that start with the text match, so results show up while typing.
+func resolveInlineQuery(text string, categories []appmodels.Category) []inlineStatsQuery {
+	text = strings.ToLower(strings.TrimSpace(text))
+
+	var queries []inlineStatsQuery
+	for _, period := range inlinePeriods {
+		if strings.HasPrefix(period, text) {
+			queries = append(queries, inlineStatsQuery{Period: period})
+		}
+	}
+	if text == "" {
+		return queries
+	}
+
+	matched := 0
+	for i := range categories {
+		if matched == inlineMaxCategoryResults {
+			break
+		}
+		if strings.HasPrefix(strings.ToLower(categories[i].Name), text) {
+			queries = append(queries, inlineStatsQuery{Period: inlinePeriodMonth, Category: &categories[i]})
+			matched++
+		}
+	}
+	return queries
+}
+
+// inlineStatsArticle renders one total as an inline article. The message
+// it posts is the same line as the description.
+func inlineStatsArticle(query inlineStatsQuery, expenses []appmodels.Expense) *models.InlineQueryResultArticle {
+	var id, title, icon string
+	switch {
+	case query.Category != nil:
+		id = "category_" + strconv.Itoa(query.Category.ID)
+		title = query.Category.Name + " this month"
+		icon = "📂"
+	case query.Period == inlinePeriodToday:
+		id, title, icon = inlinePeriodToday, "Today", "📅"
+	case query.Period == inlinePeriodWeek:
+		id, title, icon = inlinePeriodWeek, "This week", "📆"
+	default:
+		id, title, icon = inlinePeriodMonth, "This month", "🗓️"
+	}
+
+	total := formatInlineCurrencyTotals(sumExpenseAmountsByCurrency(expenses))
+	return &models.InlineQueryResultArticle{
+		ID:          id,
+		Title:       title,
+		Description: total,
+		InputMessageContent: &models.InputTextMessageContent{
+			MessageText: fmt.Sprintf("%s %s: %s", icon, botfmt.EscapeHTML(title), total),
+			ParseMode:   models.ParseModeHTML,
+		},
+	}
+}
+
+// buildInlineStatsResults computes the articles for queries. One range of
+// expenses covering this week and this month is loaded and split per
+// query.
+func (b *Bot) buildInlineStatsResults(
+	ctx context.Context,
+	userID int64,
+	queries []inlineStatsQuery,
+) ([]models.InlineQueryResult, error) {
+	current := b.now().In(normalizeLocation(b.displayLocation))
+	ranges := map[string][2]time.Time{}
+	for _, period := range inlinePeriods {
+		var start, end time.Time
+		switch period {
+		case inlinePeriodToday:
+			start, end = getDayDateRangeAt(current)
+		case inlinePeriodWeek:
+			start, end = getWeekDateRangeAt(current)
+		default:
+			start, end = getMonthDateRangeAt(current)
+		}
+		ranges[period] = [2]time.Time{start, end}
+	}
+
+	from, to := ranges[inlinePeriodMonth][0], ranges[inlinePeriodMonth][1]
+	if week := ranges[inlinePeriodWeek]; week[0].Before(from) {
+		from = week[0]
+	}
+	if week := ranges[inlinePeriodWeek]; week[1].After(to) {
+		to = week[1]
+	}
+	expenses, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch expenses for inline query: %w", err)
+	}
+
+	results := make([]models.InlineQueryResult, 0, len(queries))
+	for _, query := range queries {
+		window := ranges[query.Period]
+		var matched []appmodels.Expense
+		for i := range expenses {
+			exp := &expenses[i]
+			if exp.CreatedAt.Before(window[0]) || !exp.CreatedAt.Before(window[1]) {
+				continue
+			}
+			if query.Category != nil && (exp.CategoryID == nil || *exp.CategoryID != query.Category.ID) {
+				continue
+			}
+			matched = append(matched, *exp)
+		}
+		results = append(results, inlineStatsArticle(query, matched))
+	}
+	return results, nil
+}
+
+// handleInlineQueryCore answers "@bot today" style inline queries with the
+// user's own totals. Answers are personal, and are cached for a few seconds
+// per user and query text.
+func (b *Bot) handleInlineQueryCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.InlineQuery
+	if query == nil || query.From == nil {
+		return
+	}
+
+	userID := query.From.ID
+	text := strings.ToLower(strings.TrimSpace(query.Query))
+	key := inlineStatsKey(userID, text)
+
+	results, hit := b.inlineStats.get(key, b.now())
+	if b.metrics != nil {
+		counter := b.metrics.CacheMisses
+		if hit {
+			counter = b.metrics.CacheHits
+		}
+		counter.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("cache", "inline_stats")))
+	}
+	if !hit {
+		categories, err := b.visibleCategories(ctx, userID)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to fetch categories for inline query")
+			return
+		}
+		results, err = b.buildInlineStatsResults(ctx, userID, resolveInlineQuery(text, categories))
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to answer inline query")
+			return
+		}
+		b.inlineStats.put(key, results, b.now())
+	}
+
+	if _, err := tg.AnswerInlineQuery(ctx, &bot.AnswerInlineQueryParams{
+		InlineQueryID: query.ID,
+		Results:       results,
+		CacheTime:     int(inlineStatsTTL / time.Second),
+		IsPersonal:    true,
+	}); err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to send inline query answer")
+	}
+}
+
+// answerInlineUnauthorized answers an inline query from an unauthorized
+// user with a single "not authorized" result.
+func answerInlineUnauthorized(ctx context.Context, tg TelegramAPI, query *models.InlineQuery) {
+	_, _ = tg.AnswerInlineQuery(ctx, &bot.AnswerInlineQueryParams{
+		InlineQueryID: query.ID,
+		Results: []models.InlineQueryResult{&models.InlineQueryResultArticle{
+			ID:    "unauthorized",
+			Title: inlineNotAuthorizedTitle,
+			InputMessageContent: &models.InputTextMessageContent{
+				MessageText: inlineNotAuthorizedText,
+			},
+		}},
+		IsPersonal: true,
+	})
+}
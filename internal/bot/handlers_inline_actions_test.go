@@ -113,9 +113,11 @@ func TestHandleExpenseActionCallbackCore(t *testing.T) {
 
 		b.handleExpenseActionCallbackCore(ctx, mockBot, update)
 
-		// Should have answered callback and edited message with error.
-		require.GreaterOrEqual(t, mockBot.AnsweredCallbackCount(), 1)
-		require.GreaterOrEqual(t, mockBot.EditedMessageCount(), 1)
+		// Should answer with a toast and drop the dead keyboard.
+		require.Equal(t, 1, mockBot.AnsweredCallbackCount())
+		require.Equal(t, staleExpenseGoneText, mockBot.AnsweredCallbacks[0].Text)
+		require.Len(t, mockBot.EditedReplyMarkups, 1)
+		require.Equal(t, 0, mockBot.EditedMessageCount())
 	})
 
 	t.Run("handles user mismatch", func(t *testing.T) {
@@ -128,8 +130,10 @@ func TestHandleExpenseActionCallbackCore(t *testing.T) {
 
 		b.handleExpenseActionCallbackCore(ctx, mockBot, update)
 
-		// Should have answered callback twice (initial answer + ShowAlert).
-		require.GreaterOrEqual(t, mockBot.AnsweredCallbackCount(), 2)
+		// Should answer once with an alert and keep the owner's keyboard.
+		require.Equal(t, 1, mockBot.AnsweredCallbackCount())
+		require.True(t, mockBot.AnsweredCallbacks[0].ShowAlert)
+		require.Empty(t, mockBot.EditedReplyMarkups)
 		require.Equal(t, 0, mockBot.EditedMessageCount())
 	})
 }
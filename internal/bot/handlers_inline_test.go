@@ -0,0 +1,195 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// inlineArticle returns the article at index i of an inline answer.
+func inlineArticle(t *testing.T, answer mocks.AnsweredInlineQuery, i int) *tgmodels.InlineQueryResultArticle {
+	t.Helper()
+	require.Greater(t, len(answer.Results), i)
+	article, ok := answer.Results[i].(*tgmodels.InlineQueryResultArticle)
+	require.True(t, ok)
+	return article
+}
+
+func inlineMessageText(t *testing.T, article *tgmodels.InlineQueryResultArticle) string {
+	t.Helper()
+	content, ok := article.InputMessageContent.(*tgmodels.InputTextMessageContent)
+	require.True(t, ok)
+	return content.MessageText
+}
+
+func TestResolveInlineQuery(t *testing.T) {
+	t.Parallel()
+
+	categories := []appmodels.Category{
+		{ID: 1, Name: testCategoryFoodDiningOut},
+		{ID: 2, Name: foodGroceryCatMatch},
+		{ID: 3, Name: "Transportation"},
+		{ID: 4, Name: "Mortgage"},
+	}
+	describe := func(queries []inlineStatsQuery) []string {
+		out := make([]string, len(queries))
+		for i, q := range queries {
+			out[i] = q.Period
+			if q.Category != nil {
+				out[i] = q.Category.Name
+			}
+		}
+		return out
+	}
+
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "", want: []string{inlinePeriodToday, inlinePeriodWeek, inlinePeriodMonth}},
+		{text: " TODAY ", want: []string{inlinePeriodToday}},
+		{text: "w", want: []string{inlinePeriodWeek}},
+		{text: "m", want: []string{inlinePeriodMonth, "Mortgage"}},
+		{text: "food", want: []string{testCategoryFoodDiningOut, foodGroceryCatMatch}},
+		{text: "t", want: []string{inlinePeriodToday, "Transportation"}},
+		{text: "xyz", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.want, describe(resolveInlineQuery(tt.text, categories)))
+		})
+	}
+
+	t.Run("category matches are for this month", func(t *testing.T) {
+		t.Parallel()
+		queries := resolveInlineQuery("trans", categories)
+		require.Len(t, queries, 1)
+		require.Equal(t, inlinePeriodMonth, queries[0].Period)
+	})
+}
+
+func TestInlineStatsCache(t *testing.T) {
+	t.Parallel()
+
+	var cache inlineStatsCache
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	key := inlineStatsKey(1, inlinePeriodToday)
+
+	_, ok := cache.get(key, now)
+	require.False(t, ok)
+
+	results := []tgmodels.InlineQueryResult{&tgmodels.InlineQueryResultArticle{ID: inlinePeriodToday}}
+	cache.put(key, results, now)
+
+	got, ok := cache.get(key, now.Add(inlineStatsTTL-time.Millisecond))
+	require.True(t, ok)
+	require.Equal(t, results, got)
+
+	_, ok = cache.get(inlineStatsKey(2, inlinePeriodToday), now)
+	require.False(t, ok, "answers are per user")
+
+	_, ok = cache.get(key, now.Add(inlineStatsTTL))
+	require.False(t, ok)
+}
+
+func TestAnswerInlineUnauthorized(t *testing.T) {
+	t.Parallel()
+
+	mockBot := mocks.NewMockBot()
+	update := mocks.InlineQueryUpdate(1, inlinePeriodToday)
+	answerInlineUnauthorized(context.Background(), mockBot, update.InlineQuery)
+
+	require.Len(t, mockBot.AnsweredInline, 1)
+	answer := mockBot.AnsweredInline[0]
+	require.Len(t, answer.Results, 1)
+	require.Equal(t, inlineNotAuthorizedTitle, inlineArticle(t, answer, 0).Title)
+	require.Equal(t, int64(1), extractUserID(update))
+}
+
+func TestInlineQueryStats(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.displayLocation = time.UTC
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC) // Wednesday.
+	b.nowFunc = func() time.Time { return now }
+
+	userID := int64(385001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Inline"}))
+	categories, err := b.getCategoriesWithCache(ctx)
+	require.NoError(t, err)
+	category := categories[0]
+
+	createAt := func(amount string, categoryID *int, at time.Time) {
+		expense := &appmodels.Expense{
+			UserID:     userID,
+			Amount:     mustParseDecimal(amount),
+			Currency:   currencyCodeSGD,
+			CategoryID: categoryID,
+			Status:     appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		_, err := pool.Exec(ctx, testUpdateExpenseTimeSQL, at, expense.ID)
+		require.NoError(t, err)
+	}
+	createAt("3.00", &category.ID, now.Add(-time.Hour))
+	createAt("7.00", nil, now.AddDate(0, 0, -2))
+	createAt("5.00", &category.ID, now.AddDate(0, 0, -3)) // Previous week, same month.
+
+	ask := func(text string) mocks.AnsweredInlineQuery {
+		mockBot := mocks.NewMockBot()
+		b.handleInlineQueryCore(ctx, mockBot, mocks.InlineQueryUpdate(userID, text))
+		require.Len(t, mockBot.AnsweredInline, 1)
+		return mockBot.AnsweredInline[0]
+	}
+
+	t.Run("periods", func(t *testing.T) {
+		answer := ask("")
+		require.True(t, answer.IsPersonal)
+		require.Equal(t, "📅 Today: S$3.00 SGD", inlineMessageText(t, inlineArticle(t, answer, 0)))
+		require.Equal(t, "📆 This week: S$10.00 SGD", inlineMessageText(t, inlineArticle(t, answer, 1)))
+		require.Equal(t, "🗓️ This month: S$15.00 SGD", inlineMessageText(t, inlineArticle(t, answer, 2)))
+	})
+
+	t.Run("category", func(t *testing.T) {
+		article := inlineArticle(t, ask(category.Name), 0)
+		require.Contains(t, inlineMessageText(t, article), "S$8.00 SGD")
+	})
+
+	t.Run("answers are cached briefly", func(t *testing.T) {
+		require.Equal(t, "S$3.00 SGD", inlineArticle(t, ask(inlinePeriodToday), 0).Description)
+		createAt("2.00", nil, now.Add(-time.Minute))
+
+		require.Equal(t, "S$3.00 SGD", inlineArticle(t, ask(inlinePeriodToday), 0).Description)
+
+		now = now.Add(inlineStatsTTL)
+		require.Equal(t, "S$5.00 SGD", inlineArticle(t, ask(inlinePeriodToday), 0).Description)
+	})
+
+	t.Run("posting a result creates no expense", func(t *testing.T) {
+		client := &recordingHTTPClient{}
+		tgBot, err := tgbot.New(
+			"123:TESTTOKEN",
+			tgbot.WithSkipGetMe(),
+			tgbot.WithHTTPClient(time.Second, client),
+			tgbot.WithServerURL("http://example.com"),
+		)
+		require.NoError(t, err)
+
+		update := mocks.MessageUpdate(userID, userID, "📅 Today: S$5.00 SGD")
+		update.Message.ViaBot = &tgmodels.User{ID: 42, IsBot: true}
+		b.defaultHandler(ctx, tgBot, update)
+
+		require.Empty(t, client.bodies)
+		expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 10)
+		require.NoError(t, err)
+		require.Len(t, expenses, 4)
+	})
+}
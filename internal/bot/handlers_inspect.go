@@ -0,0 +1,170 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	inspectActionList = "list"
+	inspectActionShow = "show"
+
+	inspectUsageMsg = "Usage:\n" +
+		"<code>/inspect &lt;user_id|@username&gt; list</code>\n" +
+		"<code>/inspect &lt;user_id|@username&gt; show &lt;expense number&gt;</code>"
+	inspectUserNotFoundMsg = "❌ User not found."
+)
+
+// parseInspectArgs splits "/inspect <user> <action> [number]" into its parts.
+// number is 0 unless the action is show.
+func parseInspectArgs(text string) (string, string, int64, bool) {
+	fields := strings.Fields(extractAdminArgs(text))
+	if len(fields) < 2 {
+		return "", "", 0, false
+	}
+	target, action := fields[0], strings.ToLower(fields[1])
+	switch action {
+	case inspectActionList:
+		return target, action, 0, len(fields) == 2
+	case inspectActionShow:
+		if len(fields) != 3 {
+			return "", "", 0, false
+		}
+		number, err := strconv.ParseInt(strings.TrimPrefix(fields[2], "#"), 10, 64)
+		if err != nil || number <= 0 {
+			return "", "", 0, false
+		}
+		return target, action, number, true
+	}
+	return "", "", 0, false
+}
+
+// inspectTargetLabel names the inspected user in the admin view label.
+func inspectTargetLabel(user *appmodels.User) string {
+	if user.Username != "" {
+		return "@" + botfmt.EscapeHTML(user.Username)
+	}
+	return strconv.FormatInt(user.ID, 10)
+}
+
+// resolveInspectTarget looks the target up by Telegram ID, or by username
+// when the argument is not numeric.
+func (b *Bot) resolveInspectTarget(ctx context.Context, arg string) (*appmodels.User, error) {
+	if id, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		user, err := b.userRepo.GetUserByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve inspect target by id: %w", err)
+		}
+		return user, nil
+	}
+	user, err := b.userRepo.GetUserByUsername(ctx, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve inspect target by username: %w", err)
+	}
+	return user, nil
+}
+
+// handleInspect handles the /inspect command that lets superadmins read
+// another user's expenses for support.
+func (b *Bot) handleInspect(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleInspectCore(ctx, tgBot, update)
+}
+
+// handleInspectCore is the testable implementation of handleInspect. The
+// output is read-only: no edit or delete keyboards are attached.
+func (b *Bot) handleInspectCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	adminID := update.Message.From.ID
+
+	if !b.cfg.IsSuperAdmin(adminID, update.Message.From.Username) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   onlySuperadminsMsg,
+		})
+		return
+	}
+
+	targetArg, action, number, ok := parseInspectArgs(update.Message.Text)
+	if !ok {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      inspectUsageMsg,
+			ParseMode: models.ParseModeHTML,
+		})
+		return
+	}
+
+	target, err := b.resolveInspectTarget(ctx, targetArg)
+	if err != nil {
+		logger.Log.Warn().Err(err).Int64("admin_id", adminID).Str("target", targetArg).Msg("Inspect target not found")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   inspectUserNotFoundMsg,
+		})
+		return
+	}
+
+	logger.Log.Info().
+		Int64("admin_id", adminID).
+		Int64(targetIDField, target.ID).
+		Str("command", action).
+		Int64("expense_number", number).
+		Msg("Admin inspected user data")
+
+	label := fmt.Sprintf(" <i>(admin view of %s)</i>", inspectTargetLabel(target))
+	if action == inspectActionList {
+		b.sendInspectList(ctx, tg, chatID, adminID, target.ID, label)
+		return
+	}
+	b.sendInspectExpense(ctx, tg, chatID, target.ID, number, label)
+}
+
+// sendInspectList sends the target's /list output with the admin label, in
+// the admin's format.
+func (b *Bot) sendInspectList(ctx context.Context, tg TelegramAPI, chatID, adminID, targetID int64, label string) {
+	expenses, err := b.expenseRepo.GetByUserID(ctx, targetID, listRecentLimit)
+	if err != nil {
+		logger.Log.Error().Err(err).Int64(targetIDField, targetID).Msg("Failed to fetch expenses for inspect")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   failedFetchExpensesMsg,
+		})
+		return
+	}
+	b.sendExpenseListCore(ctx, tg, chatID, adminID, expenses, listRecentHeader+label)
+}
+
+// sendInspectExpense sends a single expense of the target with the admin
+// label.
+func (b *Bot) sendInspectExpense(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID, targetID, number int64,
+	label string,
+) {
+	expense, err := b.expenseRepo.GetByUserAndNumber(ctx, targetID, number)
+	if err != nil {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("❌ Expense #%d not found.", number),
+		})
+		return
+	}
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      strings.TrimSpace(label) + "\n\n" + b.expenseDetailText(ctx, expense),
+		ParseMode: models.ParseModeHTML,
+	})
+}
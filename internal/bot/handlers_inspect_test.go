@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	inspectAliceTest     = "alice_inspect"
+	inspectAdminViewTest = "(admin view of @alice_inspect)"
+)
+
+func TestParseInspectArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text       string
+		wantTarget string
+		wantAction string
+		wantNumber int64
+		wantOK     bool
+	}{
+		{"/inspect @alice list", "@alice", inspectActionList, 0, true},
+		{"/inspect 42 LIST", "42", inspectActionList, 0, true},
+		{"/inspect @alice show 12", "@alice", inspectActionShow, 12, true},
+		{"/inspect @alice show #12", "@alice", inspectActionShow, 12, true},
+		{"/inspect", "", "", 0, false},
+		{"/inspect @alice", "", "", 0, false},
+		{"/inspect @alice show", "", "", 0, false},
+		{"/inspect @alice show x", "", "", 0, false},
+		{"/inspect @alice show 0", "", "", 0, false},
+		{"/inspect @alice list extra", "@alice", inspectActionList, 0, false},
+		{"/inspect @alice delete 3", "", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			t.Parallel()
+			target, action, number, ok := parseInspectArgs(tt.text)
+			require.Equal(t, tt.wantOK, ok)
+			if !ok {
+				return
+			}
+			require.Equal(t, tt.wantTarget, target)
+			require.Equal(t, tt.wantAction, action)
+			require.Equal(t, tt.wantNumber, number)
+		})
+	}
+}
+
+func TestHandleInspectCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.cfg = &config.Config{WhitelistedUserIDs: []int64{100}}
+
+	targetID := int64(368100)
+	err := b.userRepo.UpsertUser(ctx, &appmodels.User{ID: targetID, Username: inspectAliceTest, FirstName: "Alice"})
+	require.NoError(t, err)
+	expense := &appmodels.Expense{
+		UserID:      targetID,
+		Amount:      mustParseDecimal("7.20"),
+		Currency:    currencyCodeSGD,
+		Description: "Kopi <b>peng</b>",
+		Status:      appmodels.ExpenseStatusConfirmed,
+	}
+	require.NoError(t, b.expenseRepo.Create(ctx, expense))
+
+	t.Run("non-superadmin rejected", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleInspectCore(ctx, mockBot, mocks.CommandUpdate(999, 999, "/inspect @"+inspectAliceTest+" list"))
+		require.Equal(t, onlySuperadminsMsg, mockBot.LastSentMessage().Text)
+	})
+
+	t.Run("usage on bad arguments", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleInspectCore(ctx, mockBot, mocks.CommandUpdate(100, 100, "/inspect @"+inspectAliceTest))
+		require.Equal(t, inspectUsageMsg, mockBot.LastSentMessage().Text)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleInspectCore(ctx, mockBot, mocks.CommandUpdate(100, 100, "/inspect @nobody_368 list"))
+		require.Equal(t, inspectUserNotFoundMsg, mockBot.LastSentMessage().Text)
+	})
+
+	t.Run("list is labeled and read-only", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleInspectCore(ctx, mockBot, mocks.CommandUpdate(100, 100, "/inspect @"+inspectAliceTest+" list"))
+
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, inspectAdminViewTest)
+		require.Contains(t, msg.Text, "Kopi &lt;b&gt;peng&lt;/b&gt;")
+		require.Nil(t, msg.ReplyMarkup)
+	})
+
+	t.Run("show by user id is labeled and read-only", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		text := "/inspect 368100 show " + strconv.FormatInt(expense.UserExpenseNumber, 10)
+		b.handleInspectCore(ctx, mockBot, mocks.CommandUpdate(100, 100, text))
+
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, inspectAdminViewTest)
+		require.Contains(t, msg.Text, "7.20 SGD")
+		require.Nil(t, msg.ReplyMarkup)
+	})
+
+	t.Run("show missing expense", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleInspectCore(ctx, mockBot, mocks.CommandUpdate(100, 100, "/inspect 368100 show 9999"))
+		require.Contains(t, mockBot.LastSentMessage().Text, "not found")
+	})
+}
@@ -0,0 +1,170 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// listRecentLimit is how many recent expenses /list, /list edit and
+// /inspect show.
+const listRecentLimit = 10
+
+const (
+	// categoryModeList marks category callbacks started from /list edit so
+	// the handlers return to the refreshed list instead of the receipt view.
+	categoryModeList = "list"
+
+	listEditArg              = "edit"
+	listEditCallback         = "list_edit"
+	listDoneCallback         = "list_done"
+	listSetCategoryFmt       = "edit_category_%d_" + categoryModeList
+	listSetCategoryButtonFmt = "#%d Set category"
+	listEditDoneButtonText   = "✅ Done"
+	listEditHeader           = "🛠 <b>Edit Categories</b>\nTap an expense to change its category."
+	listRecentHeader         = "📋 <b>Recent Expenses</b>"
+)
+
+// buildListEditKeyboard builds one "Set category" button per expense plus a
+// Done button that restores the plain list.
+func buildListEditKeyboard(expenses []appmodels.Expense) *models.InlineKeyboardMarkup {
+	rows := make([][]models.InlineKeyboardButton, 0, len(expenses)+1)
+	for i := range expenses {
+		rows = append(rows, []models.InlineKeyboardButton{{
+			Text:         fmt.Sprintf(listSetCategoryButtonFmt, expenses[i].UserExpenseNumber),
+			CallbackData: fmt.Sprintf(listSetCategoryFmt, expenses[i].ID),
+		}})
+	}
+	rows = append(rows, []models.InlineKeyboardButton{
+		{Text: listEditDoneButtonText, CallbackData: listDoneCallback},
+	})
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// loadRecentExpensesForList fetches the recent expenses and their tags.
+func (b *Bot) loadRecentExpensesForList(
+	ctx context.Context,
+	userID int64,
+) ([]appmodels.Expense, map[int][]appmodels.Tag, error) {
+	expenses, err := b.expenseRepo.GetByUserID(ctx, userID, listRecentLimit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch expenses: %w", err)
+	}
+
+	if len(expenses) == 0 {
+		return expenses, nil, nil
+	}
+
+	expenseIDs := make([]int, len(expenses))
+	for i := range expenses {
+		expenseIDs[i] = expenses[i].ID
+	}
+	tagsByExpense, err := b.tagRepo.GetByExpenseIDs(ctx, expenseIDs)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to batch-load tags for expense list")
+	}
+
+	return expenses, tagsByExpense, nil
+}
+
+// sendListEditCore sends the recent expenses with per-expense category
+// buttons.
+func (b *Bot) sendListEditCore(ctx context.Context, tg TelegramAPI, chatID, userID int64) {
+	expenses, tagsByExpense, err := b.loadRecentExpensesForList(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load list edit view")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   failedFetchExpensesMsg,
+		})
+		return
+	}
+
+	if len(expenses) == 0 {
+		b.sendEmptyExpenseList(ctx, tg, chatID, listEditHeader)
+		return
+	}
+
+	_, err = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        b.expenseListMessage(ctx, userID, listEditHeader, expenses, tagsByExpense),
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: buildListEditKeyboard(expenses),
+	})
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to send list edit view")
+	}
+}
+
+// refreshListCore re-renders the recent expenses in place. When editMode is
+// set the per-expense category buttons are kept, otherwise the keyboard is
+// removed.
+func (b *Bot) refreshListCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	messageID int,
+	userID int64,
+	editMode bool,
+) {
+	expenses, tagsByExpense, err := b.loadRecentExpensesForList(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to refresh expense list")
+		return
+	}
+
+	header := listRecentHeader
+	if editMode {
+		header = listEditHeader
+	}
+
+	text := header + "\n\nNo expenses found."
+	if len(expenses) > 0 {
+		text = b.expenseListMessage(ctx, userID, header, expenses, tagsByExpense)
+	}
+
+	params := &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}
+	if editMode && len(expenses) > 0 {
+		params.ReplyMarkup = buildListEditKeyboard(expenses)
+	}
+
+	if _, err := tg.EditMessageText(ctx, params); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to edit expense list")
+	}
+}
+
+// handleListCallback handles the list_edit and list_done buttons.
+func (b *Bot) handleListCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleListCallbackCore(ctx, tgBot, update)
+}
+
+// handleListCallbackCore is the testable implementation of handleListCallback.
+func (b *Bot) handleListCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+
+	userID := update.CallbackQuery.From.ID
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	messageID := update.CallbackQuery.Message.Message.ID
+
+	_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+	})
+
+	switch update.CallbackQuery.Data {
+	case listEditCallback:
+		b.refreshListCore(ctx, tg, chatID, messageID, userID, true)
+	case listDoneCallback:
+		b.refreshListCore(ctx, tg, chatID, messageID, userID, false)
+	}
+}
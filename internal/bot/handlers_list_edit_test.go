@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	listEditChatIDTest    = int64(12345)
+	listEditMessageIDTest = 200
+	listEditDescTest      = "List Edit Lunch"
+)
+
+func listEditCallbackUpdate(userID int64, data string) *models.Update {
+	return &models.Update{
+		CallbackQuery: &models.CallbackQuery{
+			ID:   callbackIDHandlers,
+			From: models.User{ID: userID},
+			Data: data,
+			Message: models.MaybeInaccessibleMessage{
+				Message: &models.Message{
+					ID:   listEditMessageIDTest,
+					Chat: models.Chat{ID: listEditChatIDTest},
+				},
+			},
+		},
+	}
+}
+
+func TestBuildListEditKeyboard(t *testing.T) {
+	t.Parallel()
+
+	expenses := []appmodels.Expense{
+		{ID: 41, UserExpenseNumber: 12},
+		{ID: 42, UserExpenseNumber: 13},
+	}
+
+	keyboard := buildListEditKeyboard(expenses)
+	require.Len(t, keyboard.InlineKeyboard, 3)
+	require.Equal(t, "#12 Set category", keyboard.InlineKeyboard[0][0].Text)
+	require.Equal(t, "edit_category_41_list", keyboard.InlineKeyboard[0][0].CallbackData)
+	require.Equal(t, "#13 Set category", keyboard.InlineKeyboard[1][0].Text)
+	require.Equal(t, "edit_category_42_list", keyboard.InlineKeyboard[1][0].CallbackData)
+	require.Equal(t, listDoneCallback, keyboard.InlineKeyboard[2][0].CallbackData)
+}
+
+func TestSetCategoryCallbackData(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "set_category_1_2", setCategoryCallbackData(1, 2, ""))
+	require.Equal(t, "set_category_1_2_list", setCategoryCallbackData(1, 2, categoryModeList))
+}
+
+func TestListEditCategoryRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	userID := int64(500301)
+	otherUserID := int64(500302)
+
+	for _, id := range []int64{userID, otherUserID} {
+		err := b.userRepo.UpsertUser(ctx, &appmodels.User{ID: id, FirstName: "ListEdit"})
+		require.NoError(t, err)
+	}
+
+	categories, err := b.categoryRepo.GetAll(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, categories)
+	target := categories[0]
+
+	expense := &appmodels.Expense{
+		UserID:      userID,
+		Amount:      mustParseDecimal("12.00"),
+		Currency:    testCurrencySGD,
+		Description: listEditDescTest,
+		Status:      appmodels.ExpenseStatusConfirmed,
+	}
+	require.NoError(t, b.expenseRepo.Create(ctx, expense))
+
+	t.Run("list edit shows set category buttons", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		update := &models.Update{
+			Message: &models.Message{
+				Text: "/list edit",
+				Chat: models.Chat{ID: listEditChatIDTest},
+				From: &models.User{ID: userID},
+			},
+		}
+
+		b.handleListCore(ctx, mockBot, update)
+
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, "Edit Categories")
+		require.Contains(t, msg.Text, listEditDescTest)
+		keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+		require.Equal(t, fmt.Sprintf("edit_category_%d_list", expense.ID), keyboard.InlineKeyboard[0][0].CallbackData)
+	})
+
+	t.Run("category keyboard carries list mode", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		update := listEditCallbackUpdate(userID, fmt.Sprintf("edit_category_%d_list", expense.ID))
+
+		b.handleEditCallbackCore(ctx, mockBot, update)
+
+		require.Len(t, mockBot.EditedMessages, 1)
+		require.Contains(t, mockBot.EditedMessages[0].Text, selectCategoryTextCBT)
+		keyboard := requireInlineKeyboard(t, mockBot.EditedMessages[0].ReplyMarkup)
+		require.Equal(t,
+			fmt.Sprintf("set_category_%d_%d_list", expense.ID, target.ID),
+			keyboard.InlineKeyboard[0][0].CallbackData)
+		lastRow := keyboard.InlineKeyboard[len(keyboard.InlineKeyboard)-1]
+		require.Len(t, lastRow, 1)
+		require.Equal(t, listEditCallback, lastRow[0].CallbackData)
+	})
+
+	t.Run("other user cannot set category", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		update := listEditCallbackUpdate(otherUserID,
+			fmt.Sprintf("set_category_%d_%d_list", expense.ID, target.ID))
+
+		b.handleSetCategoryCallbackCore(ctx, mockBot, update)
+
+		require.Empty(t, mockBot.EditedMessages)
+		unchanged, err := b.expenseRepo.GetByID(ctx, expense.ID)
+		require.NoError(t, err)
+		require.Nil(t, unchanged.CategoryID)
+	})
+
+	t.Run("setting category returns to refreshed list", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		update := listEditCallbackUpdate(userID,
+			fmt.Sprintf("set_category_%d_%d_list", expense.ID, target.ID))
+
+		b.handleSetCategoryCallbackCore(ctx, mockBot, update)
+
+		require.Len(t, mockBot.EditedMessages, 1)
+		edited := mockBot.EditedMessages[0]
+		require.Equal(t, listEditMessageIDTest, edited.MessageID)
+		require.Contains(t, edited.Text, "Edit Categories")
+		require.Contains(t, edited.Text, botfmt.EscapeHTML(target.Name))
+		require.NotContains(t, edited.Text, "Receipt Updated")
+		requireInlineKeyboard(t, edited.ReplyMarkup)
+
+		updated, err := b.expenseRepo.GetByID(ctx, expense.ID)
+		require.NoError(t, err)
+		require.NotNil(t, updated.CategoryID)
+		require.Equal(t, target.ID, *updated.CategoryID)
+	})
+
+	t.Run("done restores plain list", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		update := listEditCallbackUpdate(userID, listDoneCallback)
+
+		b.handleListCallbackCore(ctx, mockBot, update)
+
+		require.Len(t, mockBot.AnsweredCallbacks, 1)
+		require.Len(t, mockBot.EditedMessages, 1)
+		require.Contains(t, mockBot.EditedMessages[0].Text, "Recent Expenses")
+		require.Nil(t, mockBot.EditedMessages[0].ReplyMarkup)
+	})
+}
@@ -0,0 +1,377 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// /list select shows the recent expenses as checkboxes for deleting several
+// at once. The selection lives only in the keyboard: each tap re-renders it
+// with the checkmark flipped, and Delete reads the ticked buttons back. The
+// Delete and Cancel buttons carry the requester's user ID.
+const (
+	listSelectArg          = "select"
+	listSelectLimit        = 20
+	listSelectMax          = 10
+	listSelectDescMaxBytes = 24
+
+	listSelectCallbackPrefix = "select_"
+	listSelectToggleFmt      = listSelectCallbackPrefix + "exp_%d"
+	listSelectTogglePrefix   = listSelectCallbackPrefix + "exp_"
+	listSelectDeletePrefix   = listSelectCallbackPrefix + "del"
+	listSelectDeleteFmt      = listSelectDeletePrefix + "_%d"
+	listSelectCancelPrefix   = listSelectCallbackPrefix + "cancel"
+	listSelectCancelFmt      = listSelectCancelPrefix + "_%d"
+
+	listSelectUnchecked = "☐"
+	listSelectChecked   = "☑"
+
+	listSelectHeader       = "🗑 <b>Delete Expenses</b>\nTick up to 10 expenses, then tap Delete selected."
+	listSelectCapMsg       = "You can select up to 10 expenses at a time."
+	listSelectNoneMsg      = "Select at least one expense first."
+	listSelectCancelledMsg = "🗑 <b>Delete Expenses</b>\nCancelled. Nothing was deleted."
+)
+
+var (
+	// errListSelectFull is returned when ticking would pass listSelectMax.
+	errListSelectFull = errors.New("selection is full")
+	// errListSelectMissing is returned when the keyboard has no checkbox for
+	// the expense.
+	errListSelectMissing = errors.New("expense is not in the selection")
+)
+
+// listSelectButtonText labels an expense checkbox, e.g.
+// "☐ #12 $5.50 SGD Coffee".
+func listSelectButtonText(expense *appmodels.Expense, checked bool) string {
+	box := listSelectUnchecked
+	if checked {
+		box = listSelectChecked
+	}
+	text := fmt.Sprintf("%s #%d %s", box, expense.UserExpenseNumber, auditMoney(expense))
+	if expense.Description != "" {
+		text += " " + truncateUTF8(expense.Description, listSelectDescMaxBytes)
+	}
+	return text
+}
+
+// listSelectActionRow builds the Delete selected and Cancel buttons of
+// userID's selection.
+func listSelectActionRow(userID int64, selected int) []models.InlineKeyboardButton {
+	return []models.InlineKeyboardButton{
+		{Text: fmt.Sprintf("🗑 Delete selected (%d)", selected), CallbackData: fmt.Sprintf(listSelectDeleteFmt, userID)},
+		{Text: "✖️ Cancel", CallbackData: fmt.Sprintf(listSelectCancelFmt, userID)},
+	}
+}
+
+// listSelectActionOwner parses the requester from Delete or Cancel callback
+// data. Keyboards sent before the requester was recorded have none, and
+// report 0. It reports false when data is not the given action.
+func listSelectActionOwner(data, prefix string) (int64, bool) {
+	rest, ok := strings.CutPrefix(data, prefix)
+	if !ok {
+		return 0, false
+	}
+	if rest == "" {
+		return 0, true
+	}
+	idStr, ok := strings.CutPrefix(rest, "_")
+	if !ok {
+		return 0, false
+	}
+	userID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return userID, true
+}
+
+// buildListSelectKeyboard builds one unticked checkbox per expense plus the
+// action row of userID's selection.
+func buildListSelectKeyboard(expenses []appmodels.Expense, userID int64) *models.InlineKeyboardMarkup {
+	rows := make([][]models.InlineKeyboardButton, 0, len(expenses)+1)
+	for i := range expenses {
+		rows = append(rows, []models.InlineKeyboardButton{{
+			Text:         listSelectButtonText(&expenses[i], false),
+			CallbackData: fmt.Sprintf(listSelectToggleFmt, expenses[i].ID),
+		}})
+	}
+	rows = append(rows, listSelectActionRow(userID, 0))
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// listSelectedIDs returns the expense IDs ticked in a /list select keyboard.
+func listSelectedIDs(kb *models.InlineKeyboardMarkup) []int {
+	if kb == nil {
+		return nil
+	}
+	var ids []int
+	for _, row := range kb.InlineKeyboard {
+		for _, button := range row {
+			idStr, ok := strings.CutPrefix(button.CallbackData, listSelectTogglePrefix)
+			if !ok || !strings.HasPrefix(button.Text, listSelectChecked) {
+				continue
+			}
+			if id, err := strconv.Atoi(idStr); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// toggleListSelection returns a copy of kb with the checkbox of expenseID
+// flipped and the Delete count updated. The action row keeps its
+// requester.
+func toggleListSelection(kb *models.InlineKeyboardMarkup, expenseID int) (*models.InlineKeyboardMarkup, error) {
+	if kb == nil {
+		return nil, errListSelectMissing
+	}
+	data := fmt.Sprintf(listSelectToggleFmt, expenseID)
+	selected := len(listSelectedIDs(kb))
+	toggled := false
+	var owner int64
+	rows := make([][]models.InlineKeyboardButton, 0, len(kb.InlineKeyboard))
+	for _, row := range kb.InlineKeyboard {
+		if len(row) > 0 {
+			if userID, ok := listSelectActionOwner(row[0].CallbackData, listSelectDeletePrefix); ok {
+				owner = userID
+				continue
+			}
+		}
+		row = append([]models.InlineKeyboardButton(nil), row...)
+		for i := range row {
+			if row[i].CallbackData != data {
+				continue
+			}
+			if rest, ok := strings.CutPrefix(row[i].Text, listSelectChecked); ok {
+				row[i].Text = listSelectUnchecked + rest
+				selected--
+			} else if rest, ok := strings.CutPrefix(row[i].Text, listSelectUnchecked); ok {
+				if selected >= listSelectMax {
+					return nil, errListSelectFull
+				}
+				row[i].Text = listSelectChecked + rest
+				selected++
+			}
+			toggled = true
+		}
+		rows = append(rows, row)
+	}
+	if !toggled {
+		return nil, errListSelectMissing
+	}
+	rows = append(rows, listSelectActionRow(owner, selected))
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}, nil
+}
+
+// sendListSelectCore sends the recent expenses as checkboxes.
+func (b *Bot) sendListSelectCore(ctx context.Context, tg TelegramAPI, chatID, userID int64) {
+	expenses, err := b.expenseRepo.GetByUserID(ctx, userID, listSelectLimit)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load list select view")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   failedFetchExpensesMsg,
+		})
+		return
+	}
+
+	if len(expenses) == 0 {
+		b.sendEmptyExpenseList(ctx, tg, chatID, listSelectHeader)
+		return
+	}
+
+	_, err = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        listSelectHeader,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: buildListSelectKeyboard(expenses, userID),
+	})
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to send list select view")
+	}
+}
+
+// handleListSelectCallback handles the /list select checkboxes and buttons.
+func (b *Bot) handleListSelectCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleListSelectCallbackCore(ctx, tgBot, update)
+}
+
+// handleListSelectCallbackCore is the testable implementation of
+// handleListSelectCallback.
+func (b *Bot) handleListSelectCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+	msg := query.Message.Message
+
+	if owner, ok := listSelectActionOwner(query.Data, listSelectCancelPrefix); ok {
+		if owner != 0 && owner != query.From.ID {
+			_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+				CallbackQueryID: query.ID,
+				Text:            staleNotOwnerText,
+			})
+			return
+		}
+		answerCallback(ctx, tg, query)
+		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    msg.Chat.ID,
+			MessageID: msg.ID,
+			Text:      listSelectCancelledMsg,
+			ParseMode: models.ParseModeHTML,
+		})
+		return
+	}
+	if owner, ok := listSelectActionOwner(query.Data, listSelectDeletePrefix); ok {
+		if owner != 0 && owner != query.From.ID {
+			_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+				CallbackQueryID: query.ID,
+				Text:            staleNotOwnerText,
+			})
+			return
+		}
+		b.deleteListSelectionCore(ctx, tg, query)
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(query.Data, listSelectTogglePrefix):
+		expenseID, err := strconv.Atoi(strings.TrimPrefix(query.Data, listSelectTogglePrefix))
+		if err != nil {
+			answerCallback(ctx, tg, query)
+			return
+		}
+		b.toggleListSelectionCore(ctx, tg, query, expenseID)
+	default:
+		answerCallback(ctx, tg, query)
+	}
+}
+
+// toggleListSelectionCore flips one checkbox after checking that the
+// tapping user owns the expense. Unlike the single-expense buttons, a gone
+// expense keeps the keyboard so the rest of the selection survives.
+func (b *Bot) toggleListSelectionCore(ctx context.Context, tg TelegramAPI, query *models.CallbackQuery, expenseID int) {
+	expense, err := b.expenseRepo.GetByID(ctx, expenseID)
+	if err != nil || expense.UserID != query.From.ID {
+		reason := staleExpenseGone
+		if err == nil {
+			reason = staleNotOwner
+		}
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            reason.text(),
+		})
+		return
+	}
+	msg := query.Message.Message
+	kb, toggleErr := toggleListSelection(msg.ReplyMarkup, expenseID)
+	if errors.Is(toggleErr, errListSelectFull) {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            listSelectCapMsg,
+		})
+		return
+	}
+	answerCallback(ctx, tg, query)
+	if toggleErr != nil {
+		return
+	}
+	_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+		ChatID:      msg.Chat.ID,
+		MessageID:   msg.ID,
+		ReplyMarkup: kb,
+	})
+}
+
+// deleteListSelectionCore deletes the ticked expenses the user owns in one
+// statement, so either all of them go or none, and replaces the list with
+// a summary. Ticked IDs of other users' expenses are ignored.
+func (b *Bot) deleteListSelectionCore(ctx context.Context, tg TelegramAPI, query *models.CallbackQuery) {
+	msg := query.Message.Message
+	ids := listSelectedIDs(msg.ReplyMarkup)
+	if len(ids) == 0 {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            listSelectNoneMsg,
+		})
+		return
+	}
+	if len(ids) > listSelectMax {
+		ids = ids[:listSelectMax]
+	}
+	if !b.deleteButtonAllowed(ctx, tg, query) {
+		return
+	}
+
+	userID := query.From.ID
+	owned, sourceChats := b.loadOwnedExpenses(ctx, userID, ids)
+	ownedIDs := make([]int, len(owned))
+	for i := range owned {
+		ownedIDs[i] = owned[i].ID
+	}
+
+	deleted, err := b.expenses().DeleteMany(ctx, userID, ownedIDs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to delete selected expenses")
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            failedDeleteExpenseMsg,
+			ShowAlert:       true,
+		})
+		return
+	}
+	answerCallback(ctx, tg, query)
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(userID)).
+		Ints("deleted_expense_ids", ownedIDs).
+		Msg("Selected expenses deleted")
+	for i := range owned {
+		b.auditExpenseDelete(ctx, tg, userID, &owned[i], sourceChats[i])
+	}
+
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    msg.Chat.ID,
+		MessageID: msg.ID,
+		Text:      listSelectSummary(owned, deleted),
+		ParseMode: models.ParseModeHTML,
+	})
+}
+
+// loadOwnedExpenses loads the expenses with the given IDs that belong to
+// the user, along with the chat each came from. Missing expenses and those
+// of other users are left out.
+func (b *Bot) loadOwnedExpenses(ctx context.Context, userID int64, ids []int) ([]appmodels.Expense, []int64) {
+	owned := make([]appmodels.Expense, 0, len(ids))
+	sourceChats := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		expense, err := b.expenseRepo.GetByID(ctx, id)
+		if err != nil || expense.UserID != userID {
+			continue
+		}
+		owned = append(owned, *expense)
+		sourceChats = append(sourceChats, b.expenseSourceChat(ctx, id))
+	}
+	return owned, sourceChats
+}
+
+// listSelectSummary reports which expense numbers were deleted.
+func listSelectSummary(owned []appmodels.Expense, deleted int64) string {
+	if deleted == 0 {
+		return "🗑 <b>Delete Expenses</b>\nNothing was deleted."
+	}
+	numbers := make([]string, len(owned))
+	for i := range owned {
+		numbers[i] = fmt.Sprintf("#%d", owned[i].UserExpenseNumber)
+	}
+	return fmt.Sprintf("🗑 <b>Delete Expenses</b>\n✅ Deleted %d expense(s): %s.", deleted, strings.Join(numbers, ", "))
+}
@@ -0,0 +1,186 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestToggleListSelection(t *testing.T) {
+	t.Parallel()
+
+	expenses := make([]appmodels.Expense, listSelectMax+1)
+	for i := range expenses {
+		expenses[i] = appmodels.Expense{ID: 100 + i, UserExpenseNumber: int64(i + 1), Currency: testCurrencySGD}
+	}
+	kb := buildListSelectKeyboard(expenses, 431001)
+	require.Len(t, kb.InlineKeyboard, len(expenses)+1)
+	require.Equal(t, "select_exp_100", kb.InlineKeyboard[0][0].CallbackData)
+	require.Empty(t, listSelectedIDs(kb))
+
+	t.Run("tick and untick", func(t *testing.T) {
+		t.Parallel()
+
+		ticked, err := toggleListSelection(kb, 101)
+		require.NoError(t, err)
+		require.Equal(t, []int{101}, listSelectedIDs(ticked))
+		require.Equal(t, "🗑 Delete selected (1)", ticked.InlineKeyboard[len(expenses)][0].Text)
+		require.Equal(t, "select_del_431001", ticked.InlineKeyboard[len(expenses)][0].CallbackData,
+			"the action row keeps its requester")
+		require.Empty(t, listSelectedIDs(kb), "the original keyboard is unchanged")
+
+		unticked, err := toggleListSelection(ticked, 101)
+		require.NoError(t, err)
+		require.Empty(t, listSelectedIDs(unticked))
+		require.Equal(t, "🗑 Delete selected (0)", unticked.InlineKeyboard[len(expenses)][0].Text)
+	})
+
+	t.Run("cap", func(t *testing.T) {
+		t.Parallel()
+
+		full := kb
+		for i := range listSelectMax {
+			var err error
+			full, err = toggleListSelection(full, 100+i)
+			require.NoError(t, err)
+		}
+		require.Len(t, listSelectedIDs(full), listSelectMax)
+
+		_, err := toggleListSelection(full, 100+listSelectMax)
+		require.ErrorIs(t, err, errListSelectFull)
+
+		fewer, err := toggleListSelection(full, 100)
+		require.NoError(t, err, "unticking still works when full")
+		require.Len(t, listSelectedIDs(fewer), listSelectMax-1)
+	})
+
+	t.Run("expense not in the keyboard", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := toggleListSelection(kb, 999)
+		require.ErrorIs(t, err, errListSelectMissing)
+		_, err = toggleListSelection(nil, 100)
+		require.ErrorIs(t, err, errListSelectMissing)
+	})
+}
+
+func TestListSelectDelete(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	userID := int64(431001)
+	otherUserID := int64(431002)
+
+	for _, id := range []int64{userID, otherUserID} {
+		require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: id, FirstName: "Select"}))
+	}
+	create := func(owner int64, desc string) *appmodels.Expense {
+		expense := &appmodels.Expense{
+			UserID:      owner,
+			Amount:      mustParseDecimal("4.00"),
+			Currency:    testCurrencySGD,
+			Description: desc,
+			Status:      appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		return expense
+	}
+	junk1 := create(userID, "Junk one")
+	junk2 := create(userID, "Junk two")
+	keep := create(userID, "Keep me")
+	others := create(otherUserID, "Not yours")
+
+	exists := func(id int) bool {
+		_, err := b.expenseRepo.GetByID(ctx, id)
+		return err == nil
+	}
+	callback := func(from int64, data string, kb *models.InlineKeyboardMarkup) *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		update := listEditCallbackUpdate(from, data)
+		update.CallbackQuery.Message.Message.ReplyMarkup = kb
+		b.handleListSelectCallbackCore(ctx, mockBot, update)
+		return mockBot
+	}
+	lastMarkup := func(mockBot *mocks.MockBot) *models.InlineKeyboardMarkup {
+		require.Len(t, mockBot.EditedReplyMarkups, 1)
+		return requireInlineKeyboard(t, mockBot.EditedReplyMarkups[0].ReplyMarkup)
+	}
+
+	mockBot := mocks.NewMockBot()
+	b.handleListCore(ctx, mockBot, mocks.CommandUpdate(listEditChatIDTest, userID, "/list select"))
+	kb := requireInlineKeyboard(t, mockBot.LastSentMessage().ReplyMarkup)
+	require.Len(t, kb.InlineKeyboard, 4, "three expenses and the action row")
+
+	t.Run("toggling re-renders the checkmarks", func(t *testing.T) {
+		kb = lastMarkup(callback(userID, fmt.Sprintf(listSelectToggleFmt, junk1.ID), kb))
+		kb = lastMarkup(callback(userID, fmt.Sprintf(listSelectToggleFmt, junk2.ID), kb))
+		require.ElementsMatch(t, []int{junk1.ID, junk2.ID}, listSelectedIDs(kb))
+	})
+
+	t.Run("toggling someone else's expense is refused", func(t *testing.T) {
+		mockBot := callback(userID, fmt.Sprintf(listSelectToggleFmt, others.ID), kb)
+		require.Empty(t, mockBot.EditedReplyMarkups)
+		require.Equal(t, staleNotOwnerText, mockBot.AnsweredCallbacks[0].Text)
+
+		mockBot = callback(otherUserID, fmt.Sprintf(listSelectToggleFmt, junk1.ID), kb)
+		require.Empty(t, mockBot.EditedReplyMarkups)
+	})
+
+	t.Run("delete removes only the user's ticked expenses", func(t *testing.T) {
+		tampered := &models.InlineKeyboardMarkup{InlineKeyboard: append([][]models.InlineKeyboardButton{{{
+			Text:         listSelectChecked + " forged",
+			CallbackData: fmt.Sprintf(listSelectToggleFmt, others.ID),
+		}}}, kb.InlineKeyboard...)}
+
+		mockBot := callback(userID, fmt.Sprintf(listSelectDeleteFmt, userID), tampered)
+		require.Len(t, mockBot.EditedMessages, 1)
+		require.Contains(t, mockBot.EditedMessages[0].Text, "Deleted 2 expense(s)")
+		require.Contains(t, mockBot.EditedMessages[0].Text, fmt.Sprintf("#%d", junk1.UserExpenseNumber))
+		require.Contains(t, mockBot.EditedMessages[0].Text, fmt.Sprintf("#%d", junk2.UserExpenseNumber))
+
+		require.False(t, exists(junk1.ID))
+		require.False(t, exists(junk2.ID))
+		require.True(t, exists(keep.ID))
+		require.True(t, exists(others.ID), "other users' expenses survive a forged keyboard")
+	})
+
+	t.Run("another user pressing delete or cancel is refused", func(t *testing.T) {
+		ticked, err := toggleListSelection(buildListSelectKeyboard([]appmodels.Expense{*keep}, userID), keep.ID)
+		require.NoError(t, err)
+
+		mockBot := callback(otherUserID, fmt.Sprintf(listSelectDeleteFmt, userID), ticked)
+		require.Empty(t, mockBot.EditedMessages)
+		require.Equal(t, staleNotOwnerText, mockBot.AnsweredCallbacks[0].Text)
+		require.True(t, exists(keep.ID))
+
+		mockBot = callback(otherUserID, fmt.Sprintf(listSelectCancelFmt, userID), ticked)
+		require.Empty(t, mockBot.EditedMessages)
+		require.Equal(t, staleNotOwnerText, mockBot.AnsweredCallbacks[0].Text)
+	})
+
+	t.Run("older keyboards without a requester delete only the tapper's", func(t *testing.T) {
+		ticked, err := toggleListSelection(buildListSelectKeyboard([]appmodels.Expense{*keep}, userID), keep.ID)
+		require.NoError(t, err)
+
+		mockBot := callback(otherUserID, listSelectDeletePrefix, ticked)
+		require.Contains(t, mockBot.EditedMessages[0].Text, "Nothing was deleted")
+		require.True(t, exists(keep.ID))
+	})
+
+	t.Run("delete with nothing ticked", func(t *testing.T) {
+		mockBot := callback(userID, fmt.Sprintf(listSelectDeleteFmt, userID), buildListSelectKeyboard([]appmodels.Expense{*keep}, userID))
+		require.Empty(t, mockBot.EditedMessages)
+		require.Equal(t, listSelectNoneMsg, mockBot.AnsweredCallbacks[0].Text)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		mockBot := callback(userID, fmt.Sprintf(listSelectCancelFmt, userID), kb)
+		require.Equal(t, listSelectCancelledMsg, mockBot.EditedMessages[0].Text)
+		require.True(t, exists(keep.ID))
+	})
+}
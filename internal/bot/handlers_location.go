@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// locationWindow is how soon after adding an expense a location sent
+// without a reply is attached to it.
+const locationWindow = time.Minute
+
+// confirmationNumberRegex reads the expense number from the bot's expense
+// cards, e.g. "🆔 #42" or "Expense #42".
+var confirmationNumberRegex = regexp.MustCompile(`(?:🆔 |Expense )#(\d+)`)
+
+// errNoLocationTarget means a location message matched no expense.
+var errNoLocationTarget = errors.New("no expense to attach the location to")
+
+// handleLocationCore attaches a shared location to an expense: the one
+// whose message or confirmation the location replies to, otherwise the
+// sender's expense added in this chat within locationWindow.
+func (b *Bot) handleLocationCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	msg := update.Message
+	if msg == nil || msg.From == nil || msg.Location == nil {
+		return
+	}
+
+	chatID := msg.Chat.ID
+	userID := msg.From.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	expense, err := b.locationTarget(ctx, msg)
+	if err != nil {
+		if !errors.Is(err, errNoLocationTarget) {
+			logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to find expense for location")
+			send("❌ Failed to attach the location. Please try again.")
+			return
+		}
+		send("📍 No expense added in the last minute. Reply to an expense's confirmation with the location " +
+			"to attach it.")
+		return
+	}
+
+	loc := appmodels.ExpenseLocation{Latitude: msg.Location.Latitude, Longitude: msg.Location.Longitude}
+	label := ""
+	if msg.Venue != nil {
+		label = msg.Venue.Title
+	}
+	if label == "" && b.geocoder != nil {
+		label, err = b.geocoder.Reverse(ctx, loc.Latitude, loc.Longitude)
+		if err != nil {
+			logger.Log.Warn().Err(err).Int("expense_id", expense.ID).Msg("Failed to geocode expense location")
+		}
+	}
+	loc.Label, _ = b.normalizeDescription(label)
+
+	if err := b.expenses().SetLocation(ctx, expense.ID, loc); err != nil {
+		logger.Log.Error().Err(err).Int("expense_id", expense.ID).Msg("Failed to attach location")
+		send("❌ Failed to attach the location. Please try again.")
+		return
+	}
+
+	logger.Log.Info().
+		Int("expense_id", expense.ID).
+		Str("user_hash", logger.HashUserID(userID)).
+		Bool("labeled", loc.Label != "").
+		Msg("Location attached to expense")
+	send(fmt.Sprintf("📍 Location attached to #%d\n%s", expense.UserExpenseNumber, botfmt.LocationLine(loc)))
+}
+
+// locationTarget finds the sender's expense a location message is for. It
+// returns errNoLocationTarget when there is none.
+func (b *Bot) locationTarget(ctx context.Context, msg *models.Message) (*appmodels.Expense, error) {
+	userID := msg.From.ID
+
+	if reply := msg.ReplyToMessage; reply != nil {
+		// A reply to the message that created the expense.
+		expenseID, err := b.expenseRepo.GetIDBySourceMessage(ctx, msg.Chat.ID, reply.ID)
+		switch {
+		case err == nil:
+			expense, err := b.expenseRepo.GetByID(ctx, expenseID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load replied expense: %w", err)
+			}
+			if expense.UserID != userID {
+				return nil, errNoLocationTarget
+			}
+			return expense, nil
+		case !errors.Is(err, pgx.ErrNoRows):
+			return nil, err
+		}
+
+		// A reply to the bot's confirmation, which shows the number. Other
+		// messages may quote a number too, so only the bot's own count.
+		fromBot := reply.From != nil && reply.From.ID == b.botID
+		if m := confirmationNumberRegex.FindStringSubmatch(reply.Text + reply.Caption); fromBot && m != nil {
+			number, err := strconv.ParseInt(m[1], 10, 64)
+			if err != nil {
+				return nil, errNoLocationTarget
+			}
+			expense, err := b.expenseRepo.GetByUserAndNumber(ctx, userID, number)
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return nil, errNoLocationTarget
+				}
+				return nil, err
+			}
+			return expense, nil
+		}
+	}
+
+	expenseID, err := b.expenseRepo.GetLatestIDInChat(ctx, userID, msg.Chat.ID, b.now().Add(-locationWindow))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errNoLocationTarget
+		}
+		return nil, err
+	}
+	expense, err := b.expenseRepo.GetByID(ctx, expenseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load latest expense: %w", err)
+	}
+	return expense, nil
+}
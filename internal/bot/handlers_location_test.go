@@ -0,0 +1,150 @@
+package bot
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// stubGeocoder names every location label.
+type stubGeocoder struct{ label string }
+
+func (g stubGeocoder) Reverse(context.Context, float64, float64) (string, error) {
+	return g.label, nil
+}
+
+func locationUpdate(chatID, userID int64, reply *models.Message) *models.Update {
+	update := mocks.CommandUpdate(chatID, userID, "")
+	update.Message.Location = &models.Location{Latitude: 1.2834, Longitude: 103.8607}
+	update.Message.ReplyToMessage = reply
+	return update
+}
+
+func TestHandleLocationCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(405101)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{
+		ID: userID, FirstName: "Location", DefaultCurrency: currencyCodeSGD,
+	}))
+
+	create := func() *appmodels.Expense {
+		t.Helper()
+		expense := &appmodels.Expense{
+			UserID: userID, Amount: decimal.NewFromInt(8), Currency: currencyCodeSGD,
+			Description: "Lunch", Status: appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		return expense
+	}
+	location := func(id int) (appmodels.ExpenseLocation, bool) {
+		t.Helper()
+		loc, ok, err := b.expenseRepo.GetLocation(ctx, id)
+		require.NoError(t, err)
+		return loc, ok
+	}
+
+	t.Run("no recent expense", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleLocationCore(ctx, mockBot, locationUpdate(userID, userID, nil))
+		require.Contains(t, mockBot.LastSentMessage().Text, "No expense added in the last minute")
+	})
+
+	t.Run("attaches to the expense added within the window", func(t *testing.T) {
+		expense := create()
+		mockBot := mocks.NewMockBot()
+		b.handleLocationCore(ctx, mockBot, locationUpdate(userID, userID, nil))
+
+		require.Contains(t, mockBot.LastSentMessage().Text, "📍 Location attached to #")
+		loc, ok := location(expense.ID)
+		require.True(t, ok)
+		require.InDelta(t, 1.2834, loc.Latitude, 1e-9)
+		require.Empty(t, loc.Label)
+	})
+
+	t.Run("outside the window needs a reply", func(t *testing.T) {
+		create()
+		b.nowFunc = func() time.Time { return time.Now().Add(2 * locationWindow) }
+		t.Cleanup(func() { b.nowFunc = time.Now })
+
+		mockBot := mocks.NewMockBot()
+		b.handleLocationCore(ctx, mockBot, locationUpdate(userID, userID, nil))
+		require.Contains(t, mockBot.LastSentMessage().Text, "Reply to an expense's confirmation")
+	})
+
+	t.Run("reply to a confirmation attaches with a place name", func(t *testing.T) {
+		target := create()
+		create()
+		b.geocoder = stubGeocoder{label: "Lau Pa Sat"}
+		t.Cleanup(func() { b.geocoder = nil })
+
+		b.botID = 405999
+		confirmation := &models.Message{ID: 900, From: &models.User{ID: b.botID}, Text: "✅ Expense Added\n🆔 #" +
+			strconv.FormatInt(target.UserExpenseNumber, 10)}
+		mockBot := mocks.NewMockBot()
+		b.handleLocationCore(ctx, mockBot, locationUpdate(userID, userID, confirmation))
+
+		sent := mockBot.LastSentMessage().Text
+		require.Contains(t, sent, "Lau Pa Sat")
+		loc, ok := location(target.ID)
+		require.True(t, ok)
+		require.Equal(t, "Lau Pa Sat", loc.Label)
+	})
+
+	t.Run("a number quoted by another message is ignored", func(t *testing.T) {
+		target := create()
+		create()
+
+		quote := &models.Message{ID: 902, From: &models.User{ID: userID}, Text: "🆔 #" +
+			strconv.FormatInt(target.UserExpenseNumber, 10)}
+		mockBot := mocks.NewMockBot()
+		b.handleLocationCore(ctx, mockBot, locationUpdate(userID, userID, quote))
+
+		_, ok := location(target.ID)
+		require.False(t, ok)
+	})
+
+	t.Run("reply to the source message", func(t *testing.T) {
+		target := create()
+		b.linkSourceMessage(ctx, target.ID, userID, 901)
+		create()
+
+		update := locationUpdate(userID, userID, &models.Message{ID: 901, Text: "8 lunch"})
+		update.Message.Venue = &models.Venue{Location: *update.Message.Location, Title: "Maxwell Food Centre"}
+		mockBot := mocks.NewMockBot()
+		b.handleLocationCore(ctx, mockBot, update)
+
+		loc, ok := location(target.ID)
+		require.True(t, ok)
+		require.Equal(t, "Maxwell Food Centre", loc.Label)
+	})
+
+	t.Run("show renders the location", func(t *testing.T) {
+		expense := create()
+		require.NoError(t, b.expenseRepo.SetLocation(ctx, expense.ID, appmodels.ExpenseLocation{
+			Latitude: 1.2834, Longitude: 103.8607, Label: "Lau Pa Sat",
+		}))
+
+		mockBot := mocks.NewMockBot()
+		b.handleShowCore(ctx, mockBot, mocks.CommandUpdate(userID, userID,
+			"/show "+strconv.FormatInt(expense.UserExpenseNumber, 10)))
+		sent := mockBot.LastSentMessage().Text
+		require.Contains(t, sent, "Expense #")
+		require.Contains(t, sent, `<a href="https://www.google.com/maps?q=1.28340,103.86070">Lau Pa Sat</a>`)
+
+		b.handleShowCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/show abc"))
+		require.Equal(t, showUsage, mockBot.LastSentMessage().Text)
+
+		b.handleShowCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/show 999999"))
+		require.Equal(t, "❌ Expense #999999 not found.", mockBot.LastSentMessage().Text)
+	})
+}
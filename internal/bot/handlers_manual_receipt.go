@@ -0,0 +1,173 @@
+package bot
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	editTypeManualReceipt       = "manual_receipt"
+	manualReceiptCancelCallback = "manual_receipt_cancel"
+
+	// manualReceiptDescription is used when a receipt is typed as an
+	// amount alone.
+	manualReceiptDescription = "Receipt"
+
+	manualReceiptPromptMsg = "🔒 AI is off for you, so this receipt was not read.\n\n" +
+		"Type the total to add it, with a description if you like, e.g. <code>12.50 Lunch</code>."
+	manualReceiptInvalidMsg = "❌ Invalid amount. Type the receipt total, e.g. <code>12.50</code> " +
+		"or <code>12.50 Lunch</code>."
+	manualReceiptCancelledMsg = "Receipt not added."
+)
+
+// offerManualReceiptCore asks a user who turned AI off to type the receipt
+// they sent, without sending the photo anywhere. The next message from the
+// user is parsed as the expense; Cancel drops it. messageID is the photo
+// message the expense is linked to. A non-zero placeholderID is the
+// "Processing receipt..." message to turn into the prompt.
+func (b *Bot) offerManualReceiptCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID, userID int64,
+	messageID, placeholderID int,
+) {
+	msg, err := replyToReceipt(ctx, tg, placeholderID, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      manualReceiptPromptMsg,
+		ParseMode: models.ParseModeHTML,
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{{
+				{Text: editCancelText, CallbackData: manualReceiptCancelCallback},
+			}},
+		},
+	})
+	promptID := 0
+	if err == nil && msg != nil {
+		promptID = msg.ID
+	}
+
+	b.pendingEditsMu.Lock()
+	b.pendingEdits[chatID] = &pendingEdit{
+		EditType:        editTypeManualReceipt,
+		MessageID:       promptID,
+		UserID:          userID,
+		SourceMessageID: messageID,
+		ExpiresAt:       b.now().Add(describeTTL),
+	}
+	b.pendingEditsMu.Unlock()
+}
+
+// processManualReceiptCore saves the receipt a user typed after sending a
+// photo with AI off. Messages from other chat members are left to the
+// normal handlers, as are commands, which cancel the entry. Input that does
+// not parse keeps the entry open so the user can try again.
+func (b *Bot) processManualReceiptCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	userID int64,
+	pending *pendingEdit,
+	input string,
+) bool {
+	if userID != pending.UserID {
+		return false
+	}
+	if strings.HasPrefix(input, "/") {
+		b.pendingEditsMu.Lock()
+		delete(b.pendingEdits, chatID)
+		b.pendingEditsMu.Unlock()
+		return false
+	}
+
+	categories, err := b.visibleCategories(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for manual receipt")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   failedFetchCategoriesMsg,
+		})
+		return true
+	}
+
+	categoryNames := make([]string, len(categories))
+	for i := range categories {
+		categoryNames[i] = categories[i].Name
+	}
+
+	parsed := ParseExpenseInputWithCategories(strings.TrimSpace(input), categoryNames)
+	if parsed == nil || parsed.AmountError != nil {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      manualReceiptInvalidMsg,
+			ParseMode: models.ParseModeHTML,
+		})
+		return true
+	}
+	if parsed.Description == "" {
+		parsed.Description = manualReceiptDescription
+	}
+	parsed.Source = appmodels.ExpenseSourceReceipt
+
+	b.pendingEditsMu.Lock()
+	delete(b.pendingEdits, chatID)
+	b.pendingEditsMu.Unlock()
+
+	if pending.MessageID != 0 {
+		_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+			ChatID:    chatID,
+			MessageID: pending.MessageID,
+		})
+	}
+
+	expense := b.saveExpenseCore(ctx, tg, chatID, userID, parsed, categories)
+	if expense != nil && pending.SourceMessageID != 0 {
+		b.linkSourceMessage(ctx, expense.ID, chatID, pending.SourceMessageID)
+	}
+	return true
+}
+
+// handleManualReceiptCancelCallback handles the Cancel button of a typed
+// receipt prompt.
+func (b *Bot) handleManualReceiptCancelCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleManualReceiptCancelCallbackCore(ctx, tgBot, update)
+}
+
+// handleManualReceiptCancelCallbackCore is the testable implementation of
+// handleManualReceiptCancelCallback.
+func (b *Bot) handleManualReceiptCancelCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	chatID := query.Message.Message.Chat.ID
+	messageID := query.Message.Message.ID
+
+	b.pendingEditsMu.Lock()
+	pending, ok := b.pendingEdits[chatID]
+	ok = ok && pending.EditType == editTypeManualReceipt && pending.MessageID == messageID
+	if ok && pending.UserID != query.From.ID {
+		b.pendingEditsMu.Unlock()
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            staleNotOwnerText,
+		})
+		return
+	}
+	if ok {
+		delete(b.pendingEdits, chatID)
+	}
+	b.pendingEditsMu.Unlock()
+
+	answerCallback(ctx, tg, query)
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+		Text:      manualReceiptCancelledMsg,
+	})
+}
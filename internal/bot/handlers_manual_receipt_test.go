@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
+	"google.golang.org/genai"
+)
+
+// aiOffGenerator fails the test when Gemini is called at all.
+type aiOffGenerator struct {
+	t *testing.T
+}
+
+func (g *aiOffGenerator) GenerateContent(
+	_ context.Context,
+	_ string,
+	_ []*genai.Content,
+	_ *genai.GenerateContentConfig,
+) (*genai.GenerateContentResponse, error) {
+	g.t.Error("Gemini was called for a user with AI off")
+	return nil, errors.New("gemini called")
+}
+
+func TestAIEnabledWithoutRepository(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, (&Bot{}).aiEnabled(context.Background(), 1))
+}
+
+func TestManualReceiptCancelCallback(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := &Bot{pendingEdits: make(map[int64]*pendingEdit), nowFunc: time.Now}
+	mockBot := mocks.NewMockBot()
+	b.offerManualReceiptCore(ctx, mockBot, 100, 7, 1, 0)
+
+	sent := mockBot.LastSentMessage()
+	require.Equal(t, manualReceiptPromptMsg, sent.Text)
+	kb := requireInlineKeyboard(t, sent.ReplyMarkup)
+	require.Equal(t, manualReceiptCancelCallback, kb.InlineKeyboard[0][0].CallbackData)
+	promptID := mockBot.NextMessageID - 1
+
+	b.handleManualReceiptCancelCallbackCore(ctx, mockBot,
+		mocks.CallbackQueryUpdate(100, 8, promptID, manualReceiptCancelCallback))
+	require.Len(t, b.pendingEdits, 1, "only the sender can cancel")
+
+	b.handleManualReceiptCancelCallbackCore(ctx, mockBot,
+		mocks.CallbackQueryUpdate(100, 7, promptID, manualReceiptCancelCallback))
+	require.Empty(t, b.pendingEdits)
+	require.Equal(t, manualReceiptCancelledMsg, mockBot.LastEditedMessage().Text)
+}
+
+func TestAIOffSkipsGemini(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(450001)
+	chatID := userID
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{
+		ID: userID, FirstName: "Private", DefaultCurrency: currencyCodeSGD,
+	}))
+	b.geminiClient = gemini.NewClientWithGenerator(&aiOffGenerator{t: t})
+	t.Cleanup(func() { b.geminiClient = nil })
+
+	mockBot := mocks.NewMockBot()
+	b.handleSettingsCore(ctx, mockBot, mocks.CommandUpdate(chatID, userID, "/settings ai off"))
+	require.Contains(t, mockBot.LastSentMessage().Text, "AI is off")
+	require.False(t, b.aiEnabled(ctx, userID))
+
+	b.handleSettingsCore(ctx, mockBot, mocks.CommandUpdate(chatID, userID, "/settings"))
+	require.Contains(t, mockBot.LastSentMessage().Text, "🔒 AI: off")
+	require.Contains(t, mockBot.LastSentMessage().Text, "🤖 AI categories: off")
+
+	t.Run("photo offers manual entry", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		mockBot.GetFileError = errors.New("the photo must not be downloaded")
+		photo := mocks.PhotoUpdate(chatID, userID, "receipt-file")
+		b.handlePhotoCore(ctx, mockBot, photo)
+
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		require.Equal(t, manualReceiptPromptMsg, mockBot.LastSentMessage().Text)
+
+		handled := b.handlePendingEditCore(ctx, mockBot, mocks.MessageUpdate(chatID, userID, "lots"))
+		require.True(t, handled)
+		require.Equal(t, manualReceiptInvalidMsg, mockBot.LastSentMessage().Text)
+
+		handled = b.handlePendingEditCore(ctx, mockBot, mocks.MessageUpdate(chatID, userID, "23.40 Groceries"))
+		require.True(t, handled)
+		require.Contains(t, mockBot.LastSentMessage().Text, "Expense Added")
+
+		expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 1)
+		require.NoError(t, err)
+		require.Len(t, expenses, 1)
+		require.Equal(t, "23.40", expenses[0].Amount.StringFixed(2))
+		require.Equal(t, "Groceries", expenses[0].Description)
+		require.False(t, expenses[0].AICategorized)
+
+		b.handlePhotoCore(ctx, mockBot, photo)
+		require.True(t, b.handlePendingEditCore(ctx, mockBot, mocks.MessageUpdate(chatID, userID, "8")))
+		require.Equal(t, 1, countExpenses(ctx, t, pool, userID, manualReceiptDescription))
+	})
+
+	t.Run("queued scan is not sent", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		mockBot.GetFileError = errors.New("the photo must not be downloaded")
+		b.scanReceiptPhotos(ctx, mockBot, chatID, userID, 1, 0, []string{"receipt-file"})
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		require.Equal(t, manualReceiptPromptMsg, mockBot.LastSentMessage().Text)
+
+		b.pendingEditsMu.Lock()
+		delete(b.pendingEdits, chatID)
+		b.pendingEditsMu.Unlock()
+	})
+
+	t.Run("voice is not read", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleVoiceCore(ctx, mockBot, mocks.VoiceUpdate(chatID, userID, "voice-file", 3))
+		require.Contains(t, mockBot.LastSentMessage().Text, "AI is off")
+		require.Equal(t, 1, mockBot.SentMessageCount())
+	})
+
+	t.Run("no category suggestion", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleAddCore(ctx, mockBot, mocks.CommandUpdate(chatID, userID, "/add 9.90 Ramen"))
+		require.Contains(t, mockBot.LastSentMessage().Text, "Expense Added")
+
+		expense := &appmodels.Expense{UserID: userID, Amount: mustParseDecimal("9.90")}
+		decision := b.expenses().DecideCategory(ctx, expense, service.ParsedInput{Description: "Ramen"}, nil, service.AIApply)
+		require.Equal(t, service.AIStatusTurnedOff, decision.AIStatus)
+	})
+
+	b.handleSettingsCore(ctx, mockBot, mocks.CommandUpdate(chatID, userID, "/settings ai on"))
+	require.True(t, b.aiEnabled(ctx, userID))
+}
@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+const (
+	merchantHistoryCallbackPrefix = "merchant_history_"
+	merchantHistoryCallbackFmt    = merchantHistoryCallbackPrefix + "%d"
+	merchantHistoryButtonText     = "📈 History at this merchant"
+
+	// merchantHistoryLimit is how many of the latest expenses at a merchant
+	// the history shows.
+	merchantHistoryLimit = 10
+
+	merchantHistoryNoMerchantMsg = "This expense has no merchant."
+	merchantHistoryFailedMsg     = "❌ Failed to load the merchant history."
+)
+
+// buildMerchantHistoryRow is the row with the merchant history button of an
+// expense card.
+func buildMerchantHistoryRow(expenseID int) []models.InlineKeyboardButton {
+	return []models.InlineKeyboardButton{{
+		Text:         merchantHistoryButtonText,
+		CallbackData: fmt.Sprintf(merchantHistoryCallbackFmt, expenseID),
+	}}
+}
+
+// merchantHistoryFooter summarises lifetime spending at a merchant, one
+// line per currency: visits, total and the average per visit.
+func merchantHistoryFooter(totals []repository.MerchantTotal) string {
+	lines := make([]string, 0, len(totals)+1)
+	lines = append(lines, "<b>Lifetime</b>")
+	for _, total := range totals {
+		if total.Count <= 0 {
+			continue
+		}
+		average := total.Amount.Div(decimal.NewFromInt(int64(total.Count))).Round(2)
+		visits := "visits"
+		if total.Count == 1 {
+			visits = "visit"
+		}
+		lines = append(lines, fmt.Sprintf("%d %s · %s total · %s average",
+			total.Count, visits, botfmt.Money(total.Amount, total.Currency), botfmt.Money(average, total.Currency)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleMerchantHistoryCallback handles the merchant history button.
+func (b *Bot) handleMerchantHistoryCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleMerchantHistoryCallbackCore(ctx, tgBot, update)
+}
+
+// handleMerchantHistoryCallbackCore sends the owner's latest confirmed
+// expenses at the merchant of the tapped expense, with lifetime totals.
+// Merchants match ignoring case and whitespace.
+func (b *Bot) handleMerchantHistoryCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	expenseID, err := strconv.Atoi(strings.TrimPrefix(query.Data, merchantHistoryCallbackPrefix))
+	if err != nil {
+		answerCallback(ctx, tg, query)
+		return
+	}
+	expense, ok := b.loadCallbackExpense(ctx, tg, query, expenseID, false)
+	if !ok {
+		return
+	}
+
+	merchant := strings.TrimSpace(expense.Merchant)
+	if merchant == "" {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            merchantHistoryNoMerchantMsg,
+		})
+		return
+	}
+	answerCallback(ctx, tg, query)
+
+	chatID := query.Message.Message.Chat.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	userID := query.From.ID
+	expenses, err := b.expenseRepo.GetByUserAndMerchant(ctx, userID, merchant, merchantHistoryLimit)
+	if err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expenseID).Msg("Failed to load merchant history")
+		send(merchantHistoryFailedMsg)
+		return
+	}
+	if len(expenses) == 0 || (len(expenses) == 1 && expenses[0].ID == expense.ID) {
+		send(fmt.Sprintf("📈 First visit to <b>%s</b>, there are no other expenses there yet.",
+			botfmt.EscapeHTML(merchant)))
+		return
+	}
+
+	totals, err := b.expenseRepo.GetMerchantTotals(ctx, userID, merchant)
+	if err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expenseID).Msg("Failed to load merchant totals")
+		send(merchantHistoryFailedMsg)
+		return
+	}
+
+	header := fmt.Sprintf("📈 <b>History at %s</b>", botfmt.EscapeHTML(merchant))
+	send(b.expenseListText(ctx, userID, expenses, header) + "\n\n" + merchantHistoryFooter(totals))
+}
@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+func TestMerchantHistoryFooter(t *testing.T) {
+	t.Parallel()
+
+	footer := merchantHistoryFooter([]repository.MerchantTotal{
+		{Currency: currencyCodeSGD, Count: 3, Amount: mustParseDecimal("30.01")},
+		{Currency: "USD", Count: 1, Amount: mustParseDecimal("7")},
+		{Currency: "EUR", Count: 0, Amount: mustParseDecimal("0")},
+	})
+	require.Equal(t, "<b>Lifetime</b>\n"+
+		"3 visits · S$30.01 SGD total · S$10.00 SGD average\n"+
+		"1 visit · $7.00 USD total · $7.00 USD average", footer)
+
+	footer = merchantHistoryFooter([]repository.MerchantTotal{
+		{Currency: currencyCodeSGD, Count: 2, Amount: mustParseDecimal("-5.01")},
+	})
+	require.Contains(t, footer, "2 visits · -S$5.01 SGD total · -S$2.51 SGD average", "refunds round half away from zero")
+}
+
+func TestBuildMerchantHistoryRow(t *testing.T) {
+	t.Parallel()
+
+	row := buildMerchantHistoryRow(42)
+	require.Len(t, row, 1)
+	require.Equal(t, merchantHistoryButtonText, row[0].Text)
+	require.Equal(t, "merchant_history_42", row[0].CallbackData)
+}
+
+func TestMerchantHistoryCallback(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(451001)
+	otherUserID := int64(451002)
+	for _, id := range []int64{userID, otherUserID} {
+		require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{
+			ID: id, FirstName: "Diner", DefaultCurrency: currencyCodeSGD,
+		}))
+	}
+
+	add := func(merchant, amount string, status appmodels.ExpenseStatus) *appmodels.Expense {
+		t.Helper()
+		expense := &appmodels.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal(amount),
+			Currency:    currencyCodeSGD,
+			Description: "Dinner",
+			Merchant:    merchant,
+			Status:      status,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		return expense
+	}
+	tap := func(mockBot *mocks.MockBot, fromID int64, expenseID int) {
+		b.handleMerchantHistoryCallbackCore(ctx, mockBot,
+			mocks.CallbackQueryUpdate(userID, fromID, 5, fmt.Sprintf(merchantHistoryCallbackFmt, expenseID)))
+	}
+
+	t.Run("first visit", func(t *testing.T) {
+		draft := add("<Fish & Co>", "12.00", appmodels.ExpenseStatusDraft)
+		mockBot := mocks.NewMockBot()
+		tap(mockBot, userID, draft.ID)
+		require.Contains(t, mockBot.LastSentMessage().Text, "First visit to <b>&lt;Fish &amp; Co&gt;</b>")
+	})
+
+	t.Run("history with footer", func(t *testing.T) {
+		add("Ramen Bar", "10.00", appmodels.ExpenseStatusConfirmed)
+		add(" ramen  BAR", "20.00", appmodels.ExpenseStatusConfirmed)
+		draft := add("Ramen bar", "30.00", appmodels.ExpenseStatusDraft)
+
+		mockBot := mocks.NewMockBot()
+		tap(mockBot, userID, draft.ID)
+		text := mockBot.LastSentMessage().Text
+		require.Contains(t, text, "History at Ramen bar")
+		require.Contains(t, text, "2 visits · S$30.00 SGD total · S$15.00 SGD average")
+		require.NotContains(t, text, "30.00 SGD average", "the draft is not counted")
+
+		confirmation := buildReceiptConfirmationKeyboard(draft.ID, draft.Currency)
+		require.Equal(t, buildMerchantHistoryRow(draft.ID), confirmation.InlineKeyboard[2])
+	})
+
+	t.Run("only the owner can open it", func(t *testing.T) {
+		expense := add("Ramen Bar", "5.00", appmodels.ExpenseStatusConfirmed)
+		mockBot := mocks.NewMockBot()
+		tap(mockBot, otherUserID, expense.ID)
+		require.Equal(t, 0, mockBot.SentMessageCount())
+	})
+
+	t.Run("no merchant", func(t *testing.T) {
+		expense := add("", "5.00", appmodels.ExpenseStatusConfirmed)
+		mockBot := mocks.NewMockBot()
+		tap(mockBot, userID, expense.ID)
+		require.Equal(t, 0, mockBot.SentMessageCount())
+	})
+
+	t.Run("show card offers the button", func(t *testing.T) {
+		expense := add("Ramen Bar", "6.00", appmodels.ExpenseStatusConfirmed)
+		mockBot := mocks.NewMockBot()
+		b.handleShowCore(ctx, mockBot,
+			mocks.CommandUpdate(userID, userID, fmt.Sprintf("/show %d", expense.UserExpenseNumber)))
+		keyboard := requireInlineKeyboard(t, mockBot.SentMessages[0].ReplyMarkup)
+		require.Equal(t, buildMerchantHistoryRow(expense.ID), keyboard.InlineKeyboard[0])
+	})
+}
@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+const (
+	// monthlyReviewMaxLength is the longest monthly review note, in
+	// characters.
+	monthlyReviewMaxLength = 300
+	monthlyReviewClearArg  = "clear"
+	monthlyReviewKeyLayout = "2006-01"
+	// csvMonthlyReview labels the review rows at the end of /export.
+	csvMonthlyReview = "Review"
+
+	monthlyReviewUsageMsg = "❌ Invalid month.\n\nUsage: <code>/review YYYY-MM</code>, " +
+		"<code>/review YYYY-MM &lt;note&gt;</code> or <code>/review YYYY-MM clear</code>"
+	monthlyReviewFailMsg = "❌ Failed to save the review. Please try again."
+)
+
+// handleMonthlyReviewCore shows, sets or clears the note of a month for
+// "/review YYYY-MM [note|clear]".
+func (b *Bot) handleMonthlyReviewCore(ctx context.Context, tg TelegramAPI, msg *models.Message, args string) {
+	chatID := msg.Chat.ID
+	userID := msg.From.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: text, ParseMode: models.ParseModeHTML})
+	}
+
+	monthArg, note, _ := strings.Cut(args, " ")
+	current := b.now().In(b.locationForUser(ctx, userID))
+	start, _, err := resolveMonthArgAt(monthArg, current)
+	if err != nil {
+		send(monthlyReviewUsageMsg)
+		return
+	}
+	month := start.Format(monthlyReviewKeyLayout)
+	label := start.Format(statementMonthLayout)
+	// Reviews are one line, so line breaks are folded into spaces.
+	note = strings.Join(strings.Fields(note), " ")
+
+	switch {
+	case note == "":
+		saved, err := b.reviewRepo.Get(ctx, userID, month)
+		switch {
+		case err != nil:
+			logger.Log.Error().Err(err).Msg("Failed to get monthly review")
+			send("❌ Failed to load the review. Please try again.")
+		case saved == "":
+			send(fmt.Sprintf("📝 No review for %s yet.\n\nAdd one with <code>/review %s &lt;note&gt;</code>.",
+				label, month))
+		default:
+			send(fmt.Sprintf("📝 <b>Review for %s</b>\n%s", label, botfmt.EscapeHTML(saved)))
+		}
+	case strings.EqualFold(note, monthlyReviewClearArg):
+		removed, err := b.reviewRepo.Delete(ctx, userID, month)
+		switch {
+		case err != nil:
+			logger.Log.Error().Err(err).Msg("Failed to clear monthly review")
+			send(monthlyReviewFailMsg)
+		case !removed:
+			send(fmt.Sprintf("📝 No review for %s to clear.", label))
+		default:
+			send(fmt.Sprintf("🗑 Review for %s cleared.", label))
+		}
+	case utf8.RuneCountInString(note) > monthlyReviewMaxLength:
+		send(fmt.Sprintf("❌ Reviews are limited to %d characters.", monthlyReviewMaxLength))
+	default:
+		if err := b.reviewRepo.Set(ctx, userID, month, note); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to set monthly review")
+			send(monthlyReviewFailMsg)
+			return
+		}
+		logger.Log.Info().Str("user_hash", logger.HashUserID(userID)).Str("month", month).Msg("Monthly review saved")
+		send(fmt.Sprintf("✅ Review for %s saved. It is shown on /statement %s.\n\n%s",
+			label, month, botfmt.EscapeHTML(note)))
+	}
+}
+
+// monthlyReviewHeader returns a user's note for the month starting at
+// month as the first lines of a statement or report, or "" when there is
+// none or it cannot be loaded.
+func (b *Bot) monthlyReviewHeader(ctx context.Context, userID int64, month time.Time) string {
+	if b.reviewRepo == nil {
+		return ""
+	}
+	note, err := b.reviewRepo.Get(ctx, userID, month.Format(monthlyReviewKeyLayout))
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to load monthly review")
+		return ""
+	}
+	if note == "" {
+		return ""
+	}
+	return fmt.Sprintf("📝 <i>%s</i>\n\n", botfmt.EscapeHTML(note))
+}
+
+// writeMonthlyReviewsCSV appends a user's monthly reviews to an export
+// after a blank row, one Review row per month with the note in the
+// Description column.
+func (b *Bot) writeMonthlyReviewsCSV(ctx context.Context, w io.Writer, userID int64) error {
+	reviews, err := b.reviewRepo.List(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load monthly reviews: %w", err)
+	}
+	if len(reviews) == 0 {
+		return nil
+	}
+
+	rows := [][]string{make([]string, len(csvExpenseHeader))}
+	for _, review := range reviews {
+		row := make([]string, len(csvExpenseHeader))
+		row[0] = csvMonthlyReview
+		row[1] = review.Month
+		row[4] = sanitizeCSVCell(review.Note)
+		rows = append(rows, row)
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write monthly reviews: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestMonthlyReview(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.nowFunc = func() time.Time { return time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC) }
+	b.chartGenerator = func([]appmodels.Expense, string) ([]byte, error) { return []byte("png"), nil }
+
+	const userID = int64(429101)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Reviewer"}))
+
+	review := func(text string) string {
+		mockBot := mocks.NewMockBot()
+		b.handleReviewCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, text))
+		return mockBot.LastSentMessage().Text
+	}
+
+	t.Run("show without a note", func(t *testing.T) {
+		require.Contains(t, review("/review 2026-05"), "No review for May 2026 yet")
+	})
+
+	t.Run("invalid and future months", func(t *testing.T) {
+		require.Contains(t, review("/review 2026-13 fine"), "Usage")
+		require.Contains(t, review("/review 2026-07 fine"), "Usage")
+	})
+
+	t.Run("set, show and overwrite", func(t *testing.T) {
+		require.Contains(t, review("/review 2026-05 Over on dining"), "Review for May 2026 saved")
+		require.Contains(t, review("/review 2026-05 Over on <b>dining</b>,\nfine otherwise"), "saved")
+
+		text := review("/review 2026-05")
+		require.Contains(t, text, "Review for May 2026")
+		require.Contains(t, text, "Over on &lt;b&gt;dining&lt;/b&gt;, fine otherwise")
+	})
+
+	t.Run("notes are limited to 300 characters", func(t *testing.T) {
+		require.Contains(t, review("/review 2026-05 "+strings.Repeat("é", 301)), "limited to 300 characters")
+		require.Contains(t, review("/review 2026-05"), "fine otherwise", "the old note is kept")
+	})
+
+	expense := &appmodels.Expense{
+		UserID:      userID,
+		Amount:      mustParseDecimal("12.00"),
+		Currency:    testCurrencySGD,
+		Description: testLunchDesc,
+		Status:      appmodels.ExpenseStatusConfirmed,
+	}
+	require.NoError(t, b.expenseRepo.Create(ctx, expense))
+	_, err := pool.Exec(ctx, testUpdateExpenseTimeSQL, time.Date(2026, 5, 12, 9, 0, 0, 0, time.UTC), expense.ID)
+	require.NoError(t, err)
+
+	t.Run("statement starts with the note", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleStatementCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/statement 2026-05"))
+		summary := mockBot.LastSentMessage().Text
+		require.True(t, strings.HasPrefix(summary, "📝 <i>Over on &lt;b&gt;dining&lt;/b&gt;, fine otherwise</i>"))
+		require.Contains(t, summary, "Statement for May 2026")
+	})
+
+	t.Run("export ends with the notes", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleExportCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/export"))
+		require.Len(t, mockBot.SentDocuments, 1)
+		records, err := csv.NewReader(bytes.NewReader(mockBot.SentDocuments[0].Data)).ReadAll()
+		require.NoError(t, err)
+		last := records[len(records)-1]
+		require.Equal(t, []string{csvMonthlyReview, "2026-05"}, last[:2])
+		require.Equal(t, "Over on <b>dining</b>, fine otherwise", last[4])
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		require.Contains(t, review("/review 2026-05 clear"), "Review for May 2026 cleared")
+		require.Contains(t, review("/review 2026-05 clear"), "No review for May 2026 to clear")
+
+		mockBot := mocks.NewMockBot()
+		b.handleStatementCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/statement 2026-05"))
+		require.NotContains(t, mockBot.LastSentMessage().Text, "📝")
+	})
+}
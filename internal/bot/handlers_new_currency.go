@@ -0,0 +1,216 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
+)
+
+const (
+	// newCurrencyTTL is how long the currency choice of a held-back chat
+	// expense stays valid.
+	newCurrencyTTL = 10 * time.Minute
+
+	newCurrencyCallbackPrefix = "newcur_"
+	newCurrencyKeepFmt        = "newcur_keep_%s"
+	newCurrencyDefaultFmt     = "newcur_default_%s"
+	newCurrencyKeep           = "keep"
+	newCurrencyDefault        = "default"
+)
+
+var (
+	errNewCurrencyExpired  = errors.New("currency choice expired or already used")
+	errNewCurrencyNotOwner = errors.New("currency choice belongs to another user")
+)
+
+// pendingNewCurrency is a chat expense in a currency the user has never
+// used, kept until the user picks the currency or it expires. Nothing is
+// stored in the database until then.
+type pendingNewCurrency struct {
+	UserID          int64
+	Parsed          *ParsedExpense
+	DefaultCurrency string
+	ExpiresAt       time.Time
+}
+
+// symbolAmount formats an amount with its currency symbol only, e.g.
+// "฿5.50".
+func symbolAmount(amount decimal.Decimal, currency string) string {
+	return botfmt.EscapeHTML(botfmt.CurrencySymbol(currency)) + botfmt.FormatAmount(amount, currency)
+}
+
+// promptNewCurrency holds back an expense whose currency differs from the
+// user's default and was never used by them before, which is often a typo
+// such as "5.50 Coffee THB". It asks which currency was meant and reports
+// whether it did. Lookup failures let the expense through.
+func (b *Bot) promptNewCurrency(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID, userID int64,
+	parsed *ParsedExpense,
+) bool {
+	currency := service.NormalizeCurrencyCode(parsed.Currency)
+	if _, ok := appmodels.SupportedCurrencies[currency]; !ok {
+		return false
+	}
+	defaultCurrency := b.getUserDefaultCurrency(ctx, userID)
+	if currency == defaultCurrency {
+		return false
+	}
+
+	used, err := b.expenseRepo.HasCurrencyHistory(ctx, userID, currency)
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to check currency history")
+		return false
+	}
+	if used {
+		return false
+	}
+
+	nonce, err := b.storePendingNewCurrency(&pendingNewCurrency{
+		UserID:          userID,
+		Parsed:          parsed,
+		DefaultCurrency: defaultCurrency,
+		ExpiresAt:       b.now().Add(newCurrencyTTL),
+	})
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to hold back expense in a new currency")
+		return false
+	}
+
+	asTyped := symbolAmount(parsed.Amount, currency)
+	asDefault := symbolAmount(parsed.Amount, defaultCurrency)
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text: fmt.Sprintf("🤔 First time using %s — save as %s or did you mean %s?",
+			currency, asTyped, asDefault),
+		ParseMode: models.ParseModeHTML,
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{{
+				{Text: "Save as " + asTyped, CallbackData: fmt.Sprintf(newCurrencyKeepFmt, nonce)},
+				{Text: "Use " + asDefault, CallbackData: fmt.Sprintf(newCurrencyDefaultFmt, nonce)},
+			}},
+		},
+	})
+	return true
+}
+
+// storePendingNewCurrency remembers a held-back expense under a new nonce.
+func (b *Bot) storePendingNewCurrency(pending *pendingNewCurrency) (string, error) {
+	nonce, err := newCallbackNonce()
+	if err != nil {
+		return "", err
+	}
+
+	b.pendingCurrencyMu.Lock()
+	defer b.pendingCurrencyMu.Unlock()
+	if b.pendingCurrency == nil {
+		b.pendingCurrency = make(map[string]*pendingNewCurrency)
+	}
+	now := b.now()
+	for key, p := range b.pendingCurrency {
+		if now.After(p.ExpiresAt) {
+			delete(b.pendingCurrency, key)
+		}
+	}
+	b.pendingCurrency[nonce] = pending
+	return nonce, nil
+}
+
+// takePendingNewCurrency removes and returns the held-back expense for
+// nonce if it belongs to userID and has not expired. Each nonce works only
+// once.
+func (b *Bot) takePendingNewCurrency(nonce string, userID int64) (*pendingNewCurrency, error) {
+	b.pendingCurrencyMu.Lock()
+	defer b.pendingCurrencyMu.Unlock()
+
+	pending, ok := b.pendingCurrency[nonce]
+	if !ok {
+		return nil, errNewCurrencyExpired
+	}
+	if pending.UserID != userID {
+		return nil, errNewCurrencyNotOwner
+	}
+	delete(b.pendingCurrency, nonce)
+	if b.now().After(pending.ExpiresAt) {
+		return nil, errNewCurrencyExpired
+	}
+	return pending, nil
+}
+
+// handleNewCurrencyCallback handles the currency choice of an expense held
+// back by promptNewCurrency.
+func (b *Bot) handleNewCurrencyCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleNewCurrencyCallbackCore(ctx, tgBot, update)
+}
+
+// handleNewCurrencyCallbackCore is the testable implementation of
+// handleNewCurrencyCallback. It saves the expense in the typed currency or
+// in the user's default currency.
+func (b *Bot) handleNewCurrencyCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	chatID := query.Message.Message.Chat.ID
+	messageID := query.Message.Message.ID
+
+	choice, nonce, ok := strings.Cut(strings.TrimPrefix(query.Data, newCurrencyCallbackPrefix), "_")
+	if !ok || (choice != newCurrencyKeep && choice != newCurrencyDefault) {
+		answerCallback(ctx, tg, query)
+		return
+	}
+
+	pending, err := b.takePendingNewCurrency(nonce, query.From.ID)
+	if errors.Is(err, errNewCurrencyNotOwner) {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            staleNotOwnerText,
+		})
+		return
+	}
+	if err != nil {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            pendingExpenseExpiredMsg,
+		})
+		_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+		})
+		return
+	}
+	answerCallback(ctx, tg, query)
+
+	// The question stays in the chat for reference; its buttons are used up.
+	_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+	})
+
+	if choice == newCurrencyDefault {
+		pending.Parsed.Currency = pending.DefaultCurrency
+	}
+
+	categories, err := b.visibleCategories(ctx, pending.UserID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for new currency expense")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   failedFetchCategoriesMsg,
+		})
+		return
+	}
+	b.buildAndStoreExpense(ctx, tg, chatID, pending.UserID, pending.Parsed, categories)
+}
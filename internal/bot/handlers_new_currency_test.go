@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// seedCurrencyHistory gives a user a confirmed expense in currency so new
+// expenses in it are saved without asking.
+func seedCurrencyHistory(ctx context.Context, t *testing.T, b *Bot, userID int64, currency string) {
+	t.Helper()
+	require.NoError(t, b.expenseRepo.Create(ctx, &appmodels.Expense{
+		UserID:      userID,
+		Amount:      decimal.NewFromInt(1),
+		Currency:    currency,
+		Description: "Earlier",
+		Status:      appmodels.ExpenseStatusConfirmed,
+	}))
+}
+
+func TestTakePendingNewCurrency(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	b := &Bot{nowFunc: func() time.Time { return now }}
+	nonce, err := b.storePendingNewCurrency(&pendingNewCurrency{UserID: 1, ExpiresAt: now.Add(newCurrencyTTL)})
+	require.NoError(t, err)
+
+	_, err = b.takePendingNewCurrency(nonce, 2)
+	require.ErrorIs(t, err, errNewCurrencyNotOwner)
+
+	pending, err := b.takePendingNewCurrency(nonce, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), pending.UserID)
+
+	_, err = b.takePendingNewCurrency(nonce, 1)
+	require.ErrorIs(t, err, errNewCurrencyExpired)
+
+	nonce, err = b.storePendingNewCurrency(&pendingNewCurrency{UserID: 1, ExpiresAt: now.Add(-time.Second)})
+	require.NoError(t, err)
+	_, err = b.takePendingNewCurrency(nonce, 1)
+	require.ErrorIs(t, err, errNewCurrencyExpired)
+}
+
+func TestNewCurrencyPrompt(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(388001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{
+		ID:              userID,
+		FirstName:       "Typo",
+		DefaultCurrency: currencyCodeSGD,
+	}))
+
+	add := func(text string) *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		b.handleAddCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, text))
+		return mockBot
+	}
+	tap := func(data string, from int64) *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		b.handleNewCurrencyCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, from, 1, data))
+		return mockBot
+	}
+	saved := func() []appmodels.Expense {
+		expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 10)
+		require.NoError(t, err)
+		return expenses
+	}
+	buttons := func(mockBot *mocks.MockBot) (string, string) {
+		keyboard := requireInlineKeyboard(t, mockBot.LastSentMessage().ReplyMarkup)
+		require.Len(t, keyboard.InlineKeyboard[0], 2)
+		return keyboard.InlineKeyboard[0][0].CallbackData, keyboard.InlineKeyboard[0][1].CallbackData
+	}
+
+	t.Run("default currency is never questioned", func(t *testing.T) {
+		add("/add 2 Bun SGD")
+		require.Len(t, saved(), 1)
+	})
+
+	t.Run("first use asks, and the typed currency can be kept", func(t *testing.T) {
+		mockBot := add("/add 5.50 Coffee THB")
+		msg := mockBot.LastSentMessage()
+		require.Equal(t, "🤔 First time using THB — save as ฿5.50 or did you mean S$5.50?", msg.Text)
+		require.Len(t, saved(), 1)
+
+		keep, _ := buttons(mockBot)
+		stranger := tap(keep, 1)
+		require.Equal(t, staleNotOwnerText, stranger.AnsweredCallbacks[0].Text)
+		require.Len(t, saved(), 1)
+
+		tap(keep, userID)
+		expenses := saved()
+		require.Len(t, expenses, 2)
+		require.Contains(t, expenses[0].Description, "[orig: 5.50 THB")
+
+		require.Equal(t, pendingExpenseExpiredMsg, tap(keep, userID).AnsweredCallbacks[0].Text)
+		require.Len(t, saved(), 2)
+	})
+
+	t.Run("established currency saves without asking", func(t *testing.T) {
+		mockBot := add("/add 3 Tea THB")
+		require.NotContains(t, mockBot.LastSentMessage().Text, "First time")
+		require.Len(t, saved(), 3)
+	})
+
+	t.Run("default button saves in the default currency", func(t *testing.T) {
+		_, useDefault := buttons(add("/add 4 Noodles USD"))
+		require.Len(t, saved(), 3)
+
+		tap(useDefault, userID)
+		expenses := saved()
+		require.Len(t, expenses, 4)
+		require.Equal(t, currencyCodeSGD, expenses[0].Currency)
+		require.True(t, mustParseDecimal("4").Equal(expenses[0].Amount))
+		require.NotContains(t, expenses[0].Description, "orig")
+
+		require.Contains(t, add("/add 4 Noodles USD").LastSentMessage().Text, "First time using USD")
+	})
+
+	t.Run("receipts are not questioned", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := b.saveReceiptDraft(ctx, mockBot, userID, userID, "", &gemini.ReceiptData{
+			Amount:   mustParseDecimal("1200"),
+			Currency: currencyCodeJPY,
+			Merchant: "Ramen",
+		})
+		require.NotNil(t, expense)
+		require.NotContains(t, mockBot.LastSentMessage().Text, "First time")
+	})
+}
@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+const ocrStatsEmptyMsg = "📷 No receipts have been scanned yet."
+
+// formatOCRStats renders receipt scan counts per model, prompt version and
+// prompt variant, with the share of confirmed drafts that were corrected.
+func formatOCRStats(stats []repository.ReceiptScanStats) string {
+	if len(stats) == 0 {
+		return ocrStatsEmptyMsg
+	}
+
+	var sb strings.Builder
+	sb.WriteString("📷 <b>Receipt OCR by model</b>\n")
+	for _, s := range stats {
+		fmt.Fprintf(&sb, "\n<code>%s</code> · <code>%s</code>",
+			botfmt.EscapeHTML(s.Model), botfmt.EscapeHTML(s.PromptVersion))
+		if s.PromptVariant != "" {
+			fmt.Fprintf(&sb, " · variant <b>%s</b>", botfmt.EscapeHTML(s.PromptVariant))
+		}
+		fmt.Fprintf(&sb, "\n  Scans: %d, confirmed: %d, deleted: %d\n", s.Scans, s.Confirmed, s.Deleted)
+		if s.Confirmed > 0 {
+			fmt.Fprintf(&sb, "  Corrected: %d (%.0f%% of confirmed)\n", s.Corrected, s.CorrectionRate()*100)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// handleOCRStats handles the /ocrstats command that shows which Gemini
+// models, prompt versions and prompt variants parsed receipts.
+func (b *Bot) handleOCRStats(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleOCRStatsCore(ctx, tgBot, update)
+}
+
+// handleOCRStatsCore is the testable implementation of handleOCRStats.
+func (b *Bot) handleOCRStatsCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+
+	if !b.cfg.IsSuperAdmin(update.Message.From.ID, update.Message.From.Username) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   onlySuperadminsMsg,
+		})
+		return
+	}
+
+	stats, err := b.receiptScanRepo.StatsByModel(ctx)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch receipt scan stats")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to fetch OCR stats.",
+		})
+		return
+	}
+
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      formatOCRStats(stats),
+		ParseMode: models.ParseModeHTML,
+	})
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+func TestFormatOCRStats(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, ocrStatsEmptyMsg, formatOCRStats(nil))
+
+	text := formatOCRStats([]repository.ReceiptScanStats{
+		{Model: "gemini-2.5-flash", PromptVersion: "receipt-v1", Scans: 3, Confirmed: 2, Deleted: 1, Corrected: 1},
+		{Model: "gemini-2.5-flash", PromptVersion: "receipt-v1", PromptVariant: "terse", Scans: 1},
+	})
+	require.Equal(t,
+		"📷 <b>Receipt OCR by model</b>\n\n"+
+			"<code>gemini-2.5-flash</code> · <code>receipt-v1</code>\n"+
+			"  Scans: 3, confirmed: 2, deleted: 1\n"+
+			"  Corrected: 1 (50% of confirmed)\n\n"+
+			"<code>gemini-2.5-flash</code> · <code>receipt-v1</code> · variant <b>terse</b>\n"+
+			"  Scans: 1, confirmed: 0, deleted: 0",
+		text)
+}
+
+func TestHandleOCRStatsCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.cfg = &config.Config{WhitelistedUserIDs: []int64{100}}
+
+	userID := int64(371100)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Scan"}))
+	expense := &appmodels.Expense{
+		UserID:   userID,
+		Amount:   mustParseDecimal("12.00"),
+		Currency: currencyCodeSGD,
+		Status:   appmodels.ExpenseStatusConfirmed,
+	}
+	require.NoError(t, b.expenseRepo.Create(ctx, expense))
+	require.NoError(t, b.receiptScanRepo.Record(ctx, repository.ReceiptScan{
+		ExpenseID:     expense.ID,
+		UserID:        userID,
+		Model:         "gemini-2.5-flash",
+		PromptVersion: "receipt-v1",
+	}))
+
+	t.Run("non-superadmin rejected", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleOCRStatsCore(ctx, mockBot, mocks.CommandUpdate(999, 999, "/ocrstats"))
+		require.Equal(t, onlySuperadminsMsg, mockBot.LastSentMessage().Text)
+	})
+
+	t.Run("grouped by model and prompt version", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleOCRStatsCore(ctx, mockBot, mocks.CommandUpdate(100, 100, "/ocrstats"))
+		require.Contains(t, mockBot.LastSentMessage().Text,
+			"<code>gemini-2.5-flash</code> · <code>receipt-v1</code>\n  Scans: 1, confirmed: 1, deleted: 0")
+	})
+}
@@ -0,0 +1,207 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
+)
+
+const (
+	parseAIFlag   = "--ai"
+	parseUsageMsg = "Usage: <code>/parse 10 EUR lunch with Anna #friends</code>\n" +
+		"Add <code>--ai</code> to also ask Gemini for a category: <code>/parse --ai 12 ramen</code>\n\n" +
+		"Shows how a message would be read, without saving anything."
+	parseNotExpenseMsg = "❌ This would not be recorded as an expense: no amount was found."
+	parseNoneValue     = "—"
+)
+
+// parseParseArgs splits "/parse [--ai] <text>" into the text and whether
+// Gemini should be asked.
+func parseParseArgs(text string) (string, bool) {
+	args := extractCommandArgs(text, "/parse")
+	if rest, ok := strings.CutPrefix(args, parseAIFlag); ok && (rest == "" || rest[0] == ' ') {
+		return strings.TrimSpace(rest), true
+	}
+	return args, false
+}
+
+// explainExpenseInput builds userID's expense from text the way a chat
+// message would be, without saving anything, and renders a breakdown of
+// every extracted field. Gemini is only asked when useAI is set, and a
+// suggested new category is never created.
+func (b *Bot) explainExpenseInput(
+	ctx context.Context,
+	userID int64,
+	text string,
+	categories []appmodels.Category,
+	useAI bool,
+) string {
+	categoryNames := make([]string, len(categories))
+	for i := range categories {
+		categoryNames[i] = categories[i].Name
+	}
+
+	parsed := ParseExpenseInputWithCategories(text, categoryNames)
+	if parsed == nil {
+		return parseNotExpenseMsg
+	}
+	if parsed.AmountError != nil {
+		return amountExpressionError(parsed.AmountExpression, parsed.AmountError)
+	}
+
+	mode := service.AISkip
+	if useAI {
+		mode = service.AIPreview
+	}
+	expense, decision, err := b.buildExpenseWithMode(ctx, userID, parsed, categories, mode)
+	if errors.Is(err, service.ErrAboveHardCap) {
+		return b.amountAboveHardCapMsg(expense.Amount, expense.Currency)
+	}
+	tags := service.MergeTags(parsed.Tags, b.expenses().DefaultTags(ctx, expense.CategoryID))
+
+	defaultCurrency := b.expenses().DefaultCurrency(ctx, userID)
+	currency, supported := service.ResolveSourceCurrency(parsed.Currency, defaultCurrency)
+
+	var sb strings.Builder
+	sb.WriteString("🔍 <b>Parse preview</b> <i>(nothing saved)</i>\n")
+	fmt.Fprintf(&sb, "Input: <code>%s</code>\n\n", botfmt.EscapeHTML(text))
+
+	fmt.Fprintf(&sb, "<b>Amount:</b> %s\n", botfmt.FormatAmount(parsed.Amount, currency))
+	if parsed.Refund {
+		sb.WriteString("<b>Type:</b> " + botfmt.RefundLabel + "\n")
+	}
+	if parsed.AmountExpression != "" {
+		fmt.Fprintf(&sb, "<b>Expression:</b> <code>%s</code>\n", botfmt.EscapeHTML(parsed.AmountExpression))
+	}
+	fmt.Fprintf(&sb, "<b>Currency:</b> %s\n", describeParsedCurrency(parsed.Currency, currency, defaultCurrency, supported))
+	fmt.Fprintf(&sb, "<b>Description:</b> %s\n", valueOrNone(botfmt.EscapeHTML(parsed.Description)))
+	if parsed.DescriptionTruncated {
+		sb.WriteString(b.descriptionTruncatedNote() + "\n")
+	}
+	fmt.Fprintf(&sb, "<b>Tags:</b> %s\n", describeParsedTags(tags))
+	fmt.Fprintf(&sb, "<b>Category in message:</b> %s\n", valueOrNone(botfmt.EscapeHTML(parsed.CategoryName)))
+
+	categoryName := parseNoneValue
+	if expense.Category != nil {
+		categoryName = botfmt.EscapeHTML(expense.Category.Name)
+	}
+	fmt.Fprintf(&sb, "<b>Category:</b> %s <i>(%s)</i>\n", categoryName, decision.Source)
+	fmt.Fprintf(&sb, "<b>AI:</b> %s", describeAIDecision(decision, useAI))
+
+	return sb.String()
+}
+
+// valueOrNone returns value, or a dash when it is empty.
+func valueOrNone(value string) string {
+	if value == "" {
+		return parseNoneValue
+	}
+	return value
+}
+
+// describeParsedCurrency explains where the currency came from and whether
+// the amount would be converted when saved.
+func describeParsedCurrency(parsedCurrency, currency, defaultCurrency string, supported bool) string {
+	var source string
+	switch {
+	case parsedCurrency == "":
+		source = currency + " (your default)"
+	case !supported:
+		source = fmt.Sprintf("%s (%s is not supported, using your default)",
+			currency, botfmt.EscapeHTML(service.NormalizeCurrencyCode(parsedCurrency)))
+	default:
+		source = currency + " (from the message)"
+	}
+	if currency != defaultCurrency {
+		source += " → converted to " + defaultCurrency + " when saved"
+	}
+	return source
+}
+
+// describeParsedTags renders inline tags as "#a #b".
+func describeParsedTags(tags []string) string {
+	if len(tags) == 0 {
+		return parseNoneValue
+	}
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = "#" + botfmt.EscapeHTML(tag)
+	}
+	return strings.Join(parts, " ")
+}
+
+// describeAIDecision renders the Gemini step of a category decision.
+func describeAIDecision(decision service.CategoryDecision, useAI bool) string {
+	if decision.AIStatus == service.AIStatusSkippedDryRun && !useAI {
+		return service.AIStatusSkippedDryRun + " (use <code>/parse --ai</code>)"
+	}
+
+	var sb strings.Builder
+	if s := decision.Suggestion; s != nil {
+		name := s.Category
+		if !s.Matched && s.NewCategoryName != "" {
+			name = s.NewCategoryName + " (new)"
+		}
+		fmt.Fprintf(&sb, "suggested %s, confidence %.2f", botfmt.EscapeHTML(name), s.Confidence)
+		if s.Reasoning != "" {
+			fmt.Fprintf(&sb, " — %s", botfmt.EscapeHTML(s.Reasoning))
+		}
+	}
+	if decision.AIStatus != "" {
+		if sb.Len() > 0 {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(decision.AIStatus)
+	}
+	return sb.String()
+}
+
+// handleParse handles the /parse command that explains how a message would
+// be read as an expense without saving it.
+func (b *Bot) handleParse(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleParseCore(ctx, tgBot, update)
+}
+
+// handleParseCore is the testable implementation of handleParse.
+func (b *Bot) handleParseCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+
+	text, useAI := parseParseArgs(update.Message.Text)
+	if text == "" {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      parseUsageMsg,
+			ParseMode: models.ParseModeHTML,
+		})
+		return
+	}
+
+	categories, err := b.visibleCategories(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for /parse")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   failedFetchCategoriesMsg,
+		})
+		return
+	}
+
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      b.explainExpenseInput(ctx, userID, text, categories, useAI),
+		ParseMode: models.ParseModeHTML,
+	})
+}
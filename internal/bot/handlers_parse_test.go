@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	parseCategoryOthersTest = "<b>Category:</b> Others <i>(fallback to Others)</i>"
+	parseAISkippedTest      = "<b>AI:</b> skipped in dry-run"
+)
+
+func parseTestCategories() []appmodels.Category {
+	return []appmodels.Category{
+		{ID: 1, Name: "Food - Dining Out"},
+		{ID: 2, Name: "Transportation"},
+		{ID: 3, Name: "Others"},
+	}
+}
+
+func TestParseParseArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input     string
+		wantText  string
+		wantUseAI bool
+	}{
+		{"/parse 5 coffee", "5 coffee", false},
+		{"/parse --ai 5 coffee", "5 coffee", true},
+		{"/parse@expense_bot --ai 5 coffee", "5 coffee", true},
+		{"/parse --aisle 5", "--aisle 5", false},
+		{"/parse", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+			text, useAI := parseParseArgs(tt.input)
+			require.Equal(t, tt.wantText, text)
+			require.Equal(t, tt.wantUseAI, useAI)
+		})
+	}
+}
+
+func TestExplainExpenseInput(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "currency code and tag",
+			input: "10 EUR lunch with Anna #friends",
+			want: []string{
+				"<b>Amount:</b> 10.00",
+				"<b>Currency:</b> EUR (from the message) → converted to SGD when saved",
+				"<b>Description:</b> lunch with Anna",
+				"<b>Tags:</b> #friends",
+				"<b>Category in message:</b> —",
+				parseCategoryOthersTest,
+				parseAISkippedTest,
+			},
+		},
+		{
+			name:  "bracket category",
+			input: "5.50 Coffee [Food - Dining Out]",
+			want: []string{
+				"<b>Currency:</b> SGD (your default)",
+				"<b>Description:</b> Coffee",
+				"<b>Category in message:</b> Food - Dining Out",
+				"<b>Category:</b> Food - Dining Out <i>(named in the message)</i>",
+				"<b>AI:</b> not needed",
+			},
+		},
+		{
+			name:  "amount expression",
+			input: "84.60/3 dinner",
+			want: []string{
+				"<b>Amount:</b> 28.20",
+				"<b>Expression:</b> <code>84.60/3</code>",
+				parseCategoryOthersTest,
+			},
+		},
+		{
+			name:  "escapes input",
+			input: "3 <b>tea</b>",
+			want:  []string{"Input: <code>3 &lt;b&gt;tea&lt;/b&gt;</code>"},
+		},
+		{
+			name:  "not an expense",
+			input: "hello there",
+			want:  []string{parseNotExpenseMsg},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			b := &Bot{}
+			got := b.explainExpenseInput(context.Background(), 1, tt.input, parseTestCategories(), false)
+			for _, want := range tt.want {
+				require.Contains(t, got, want)
+			}
+		})
+	}
+}
+
+func TestExplainExpenseInputWithAI(t *testing.T) {
+	t.Parallel()
+
+	t.Run("AI not configured", func(t *testing.T) {
+		t.Parallel()
+		b := &Bot{}
+		got := b.explainExpenseInput(context.Background(), 1, "12 ramen", parseTestCategories(), true)
+		require.Contains(t, got, "<b>AI:</b> not configured")
+		require.Contains(t, got, parseCategoryOthersTest)
+	})
+
+	t.Run("new category suggestion is previewed, not created", func(t *testing.T) {
+		t.Parallel()
+		// categoryRepo is nil, so creating a category would panic.
+		b := &Bot{geminiClient: gemini.NewClientWithGenerator(&botTestGenerator{
+			response: makeBotCategorySuggestionResponse(makeCategorySuggestionPayload(
+				"Recurring software subscription", false, "Subscriptions",
+			)),
+		})}
+		got := b.explainExpenseInput(context.Background(), 1, "19.99 ChatGPT monthly", parseTestCategories(), true)
+		require.Contains(t, got, "<b>Category:</b> Subscriptions <i>(AI suggested a new category)</i>")
+		require.Contains(t, got, "suggested Subscriptions (new), confidence 0.95 — Recurring software subscription")
+	})
+}
+
+func TestExplainExpenseInputLimits(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{cfg: &config.Config{AmountHardCap: decimal.NewFromInt(1000), MaxDescriptionLength: 10}}
+
+	t.Run("above hard cap", func(t *testing.T) {
+		t.Parallel()
+		got := b.explainExpenseInput(context.Background(), 1, "2000 laptop", parseTestCategories(), false)
+		require.Contains(t, got, "is above the maximum")
+		require.NotContains(t, got, "Parse preview")
+	})
+
+	t.Run("long description is truncated", func(t *testing.T) {
+		t.Parallel()
+		got := b.explainExpenseInput(context.Background(), 1, "5 a very long lunch description", parseTestCategories(), false)
+		require.Contains(t, got, "<b>Description:</b> a very lo…")
+		require.Contains(t, got, b.descriptionTruncatedNote())
+	})
+}
@@ -0,0 +1,249 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+const (
+	// patternDays is how many days, up to and including today, /patterns
+	// looks back.
+	patternDays = 90
+	// patternBarWidth is the width of the longest /patterns bar, in
+	// characters.
+	patternBarWidth = 10
+
+	noPatternsMsg = "📊 No expenses in the last 90 days yet, so there is no spending pattern to show."
+)
+
+// patternBarEighths are the partial block characters, by eighths filled.
+var patternBarEighths = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// dayPart is a coarse time of day used by /patterns.
+type dayPart int
+
+const (
+	dayPartMorning   dayPart = iota // 05:00-11:59
+	dayPartAfternoon                // 12:00-16:59
+	dayPartEvening                  // 17:00-21:59
+	dayPartNight                    // 22:00-04:59
+	dayPartCount
+)
+
+var dayPartNames = [dayPartCount]string{"Morning", "Afternoon", "Evening", "Night"}
+
+// dayPartOf returns the part of the day a local hour falls in.
+func dayPartOf(hour int) dayPart {
+	switch {
+	case hour >= 5 && hour < 12:
+		return dayPartMorning
+	case hour >= 12 && hour < 17:
+		return dayPartAfternoon
+	case hour >= 17 && hour < 22:
+		return dayPartEvening
+	default:
+		return dayPartNight
+	}
+}
+
+// patternStat is the spending of one weekday or part of the day.
+type patternStat struct {
+	Count int
+	Total decimal.Decimal
+	// Days is how many times a weekday occurs in the period; unused for
+	// parts of the day.
+	Days int
+}
+
+// Average returns the spend per occurrence of the weekday.
+func (s patternStat) Average() decimal.Decimal {
+	if s.Days == 0 {
+		return decimal.Zero
+	}
+	return s.Total.Div(decimal.NewFromInt(int64(s.Days)))
+}
+
+// spendingPatterns is a user's spending by weekday and part of the day in
+// one currency. OtherCount counts the expenses in other currencies, which
+// are left out.
+type spendingPatterns struct {
+	Currency   string
+	Weekdays   [7]patternStat
+	DayParts   [dayPartCount]patternStat
+	OtherCount int
+}
+
+// patternCurrency picks the currency /patterns reports in: preferred when
+// any expense is in it, else the one with the most expenses.
+func patternCurrency(totals []repository.SpendingPatternTotal, preferred string) string {
+	counts := make(map[string]int)
+	for _, total := range totals {
+		counts[total.Currency] += total.Count
+	}
+	if counts[preferred] > 0 {
+		return preferred
+	}
+
+	currencies := make([]string, 0, len(counts))
+	for currency := range counts {
+		currencies = append(currencies, currency)
+	}
+	if len(currencies) == 0 {
+		return preferred
+	}
+	sort.Slice(currencies, func(i, j int) bool {
+		if counts[currencies[i]] != counts[currencies[j]] {
+			return counts[currencies[i]] > counts[currencies[j]]
+		}
+		return currencies[i] < currencies[j]
+	})
+	return currencies[0]
+}
+
+// buildSpendingPatterns folds the per-hour totals of [start, end) into
+// weekdays and parts of the day. start and end must be in the user's
+// location so weekdays are counted in local days.
+func buildSpendingPatterns(
+	totals []repository.SpendingPatternTotal,
+	preferredCurrency string,
+	start, end time.Time,
+) spendingPatterns {
+	patterns := spendingPatterns{Currency: patternCurrency(totals, preferredCurrency)}
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		patterns.Weekdays[day.Weekday()].Days++
+	}
+
+	for _, total := range totals {
+		if total.Currency != patterns.Currency {
+			patterns.OtherCount += total.Count
+			continue
+		}
+		weekday := &patterns.Weekdays[total.Weekday]
+		weekday.Count += total.Count
+		weekday.Total = weekday.Total.Add(total.Amount)
+
+		part := &patterns.DayParts[dayPartOf(total.Hour)]
+		part.Count += total.Count
+		part.Total = part.Total.Add(total.Amount)
+	}
+	return patterns
+}
+
+// patternBar renders value as a bar of block characters, patternBarWidth
+// long for maxValue.
+func patternBar(value, maxValue decimal.Decimal) string {
+	if !maxValue.IsPositive() || !value.IsPositive() {
+		return ""
+	}
+	eighths := value.Mul(decimal.NewFromInt(patternBarWidth * 8)).Div(maxValue).Round(0).IntPart()
+	if eighths == 0 {
+		eighths = 1
+	}
+	return strings.Repeat("█", int(eighths/8)) + patternBarEighths[eighths%8]
+}
+
+// formatPatternRows renders one MonoTable row per stat, with a bar
+// proportional to the largest value and the largest marked.
+func formatPatternRows(
+	labels []string,
+	stats []patternStat,
+	value func(patternStat) decimal.Decimal,
+	currency string,
+) string {
+	maxIndex := 0
+	for i := range stats {
+		if value(stats[i]).GreaterThan(value(stats[maxIndex])) {
+			maxIndex = i
+		}
+	}
+	maxValue := value(stats[maxIndex])
+
+	rows := make([][]string, len(stats))
+	for i := range stats {
+		v := value(stats[i])
+		rows[i] = []string{
+			labels[i],
+			patternBar(v, maxValue),
+			botfmt.CurrencySymbol(currency) + botfmt.FormatAmount(v, currency),
+			fmt.Sprintf("%d×", stats[i].Count),
+		}
+		if i == maxIndex && maxValue.IsPositive() {
+			rows[i] = append(rows[i], "← highest")
+		}
+	}
+	align := []botfmt.Align{botfmt.AlignLeft, botfmt.AlignLeft, botfmt.AlignRight, botfmt.AlignRight}
+	return botfmt.MonoTable(rows, align)
+}
+
+// formatSpendingPatterns renders the weekday and time of day breakdowns,
+// with weekdays starting at weekStart.
+func formatSpendingPatterns(patterns *spendingPatterns, weekStart time.Weekday) string {
+	var labels []string
+	var weekdays []patternStat
+	for i := range 7 {
+		weekday := (weekStart + time.Weekday(i)) % 7
+		labels = append(labels, weekday.String()[:3])
+		weekdays = append(weekdays, patterns.Weekdays[weekday])
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📊 <b>Spending patterns</b> (last %d days, %s)\n\n", patternDays,
+		botfmt.EscapeHTML(patterns.Currency))
+	sb.WriteString("<b>By weekday</b>, average per day and number of expenses:\n")
+	sb.WriteString(formatPatternRows(labels, weekdays, patternStat.Average, patterns.Currency))
+	sb.WriteString("\n\n<b>By time of day</b>, total and number of expenses:\n")
+	sb.WriteString(formatPatternRows(dayPartNames[:], patterns.DayParts[:],
+		func(s patternStat) decimal.Decimal { return s.Total }, patterns.Currency))
+	if patterns.OtherCount > 0 {
+		fmt.Fprintf(&sb, "\n\n%d expenses in other currencies are not included.", patterns.OtherCount)
+	}
+	return sb.String()
+}
+
+// handlePatterns handles the /patterns command.
+func (b *Bot) handlePatterns(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handlePatternsCore(ctx, tgBot, update)
+}
+
+// handlePatternsCore shows the user's spending by weekday and time of day
+// over the last patternDays days, in their timezone.
+func (b *Bot) handlePatternsCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+
+	loc := b.locationForUser(ctx, userID)
+	_, end := getDayDateRangeAt(b.now().In(loc))
+	start := end.AddDate(0, 0, -patternDays)
+
+	totals, err := b.expenseRepo.GetSpendingPatternTotals(ctx, userID, start, end, loc.String())
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch spending patterns")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: failedFetchExpensesMsg})
+		return
+	}
+	if len(totals) == 0 {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: noPatternsMsg})
+		return
+	}
+
+	patterns := buildSpendingPatterns(totals, b.getUserDefaultCurrency(ctx, userID), start, end)
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      formatSpendingPatterns(&patterns, periodWeekStart),
+		ParseMode: models.ParseModeHTML,
+	})
+}
@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+func TestDayPartOf(t *testing.T) {
+	t.Parallel()
+
+	for hour, want := range map[int]dayPart{
+		0: dayPartNight, 4: dayPartNight, 5: dayPartMorning, 11: dayPartMorning,
+		12: dayPartAfternoon, 16: dayPartAfternoon, 17: dayPartEvening, 21: dayPartEvening,
+		22: dayPartNight, 23: dayPartNight,
+	} {
+		require.Equal(t, want, dayPartOf(hour), "hour %d", hour)
+	}
+}
+
+func TestBuildSpendingPatterns(t *testing.T) {
+	t.Parallel()
+
+	// 2026-03-02 is a Monday; 14 days hold two of each weekday.
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 14)
+	totals := []repository.SpendingPatternTotal{
+		{Weekday: time.Friday, Hour: 23, Currency: "SGD", Count: 2, Amount: decimal.RequireFromString("30")},
+		{Weekday: time.Friday, Hour: 8, Currency: "SGD", Count: 1, Amount: decimal.RequireFromString("6")},
+		{Weekday: time.Monday, Hour: 13, Currency: "SGD", Count: 1, Amount: decimal.RequireFromString("9")},
+		{Weekday: time.Monday, Hour: 13, Currency: "EUR", Count: 4, Amount: decimal.RequireFromString("40")},
+	}
+
+	patterns := buildSpendingPatterns(totals, "SGD", start, end)
+	require.Equal(t, "SGD", patterns.Currency)
+	require.Equal(t, 4, patterns.OtherCount)
+	for _, weekday := range patterns.Weekdays {
+		require.Equal(t, 2, weekday.Days)
+	}
+	require.Equal(t, 3, patterns.Weekdays[time.Friday].Count)
+	require.Equal(t, "18", patterns.Weekdays[time.Friday].Average().String())
+	require.Equal(t, "4.5", patterns.Weekdays[time.Monday].Average().String())
+	require.Equal(t, "30", patterns.DayParts[dayPartNight].Total.String())
+	require.Equal(t, "6", patterns.DayParts[dayPartMorning].Total.String())
+	require.Equal(t, "9", patterns.DayParts[dayPartAfternoon].Total.String())
+	require.Zero(t, patterns.DayParts[dayPartEvening].Count)
+
+	t.Run("falls back to the most used currency", func(t *testing.T) {
+		t.Parallel()
+		patterns := buildSpendingPatterns(totals, "USD", start, end)
+		require.Equal(t, "EUR", patterns.Currency)
+		require.Equal(t, 3+1, patterns.OtherCount)
+	})
+
+	t.Run("renders bars against the highest day", func(t *testing.T) {
+		t.Parallel()
+		text := formatSpendingPatterns(&patterns, time.Monday)
+		require.Contains(t, text, "(last 90 days, SGD)")
+		require.Contains(t, text, "Fri  ██████████  S$18.00  3×  ← highest")
+		require.Contains(t, text, "Mon  ██▌ ")
+		require.Contains(t, text, "Night      ██████████  S$30.00  2×  ← highest")
+		require.Contains(t, text, "4 expenses in other currencies are not included.")
+		require.Less(t, strings.Index(text, "Mon"), strings.Index(text, "Sun"))
+
+		sundayFirst := formatSpendingPatterns(&patterns, time.Sunday)
+		require.Less(t, strings.Index(sundayFirst, "Sun"), strings.Index(sundayFirst, "Mon"))
+	})
+}
+
+func TestHandlePatternsCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.nowFunc = func() time.Time { return time.Date(2026, 3, 16, 12, 0, 0, 0, time.UTC) }
+
+	upsert := func(userID int64, timezone string) {
+		require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{
+			ID: userID, FirstName: "Pattern", Timezone: timezone, DefaultCurrency: testCurrencySGD,
+		}))
+	}
+	patterns := func(userID int64) string {
+		mockBot := mocks.NewMockBot()
+		b.handlePatternsCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/patterns"))
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		return mockBot.LastSentMessage().Text
+	}
+
+	t.Run("nil message", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handlePatternsCore(ctx, mockBot, &tgmodels.Update{})
+		require.Zero(t, mockBot.SentMessageCount())
+	})
+
+	t.Run("empty period", func(t *testing.T) {
+		userID := int64(422101)
+		upsert(userID, "Asia/Singapore")
+		createHabitTestExpense(t, ctx, b, userID, "Long ago", "12.00", time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC))
+		require.Equal(t, noPatternsMsg, patterns(userID))
+	})
+
+	// Friday 2026-03-13 23:30 UTC is Saturday morning in Singapore and
+	// Friday evening in New York.
+	lateFriday := time.Date(2026, 3, 13, 23, 30, 0, 0, time.UTC)
+
+	t.Run("buckets in the user's timezone", func(t *testing.T) {
+		singapore := int64(422102)
+		upsert(singapore, "Asia/Singapore")
+		createHabitTestExpense(t, ctx, b, singapore, "Brunch", "26.00", lateFriday)
+		text := patterns(singapore)
+		require.Contains(t, text, "Sat  ██████████  S$2.00  1×  ← highest")
+		require.Contains(t, text, "Morning    ██████████  S$26.00  1×  ← highest")
+
+		newYork := int64(422103)
+		upsert(newYork, "America/New_York")
+		createHabitTestExpense(t, ctx, b, newYork, "Dinner", "26.00", lateFriday)
+		text = patterns(newYork)
+		require.Contains(t, text, "Fri  ██████████  S$2.00  1×  ← highest")
+		require.Contains(t, text, "Evening    ██████████  S$26.00  1×  ← highest")
+	})
+}
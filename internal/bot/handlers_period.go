@@ -0,0 +1,365 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	// periodCallbackPrefix starts the previous/next buttons on /today,
+	// /week, and /month. The data is period_<type>_<offset>_<requester>,
+	// followed by _all when the view includes reimbursable expenses
+	// awaiting reimbursement.
+	periodCallbackPrefix = "period_"
+	periodCallbackFmt    = periodCallbackPrefix + "%s_%d_%d"
+	// periodAllArg is the argument, e.g. "/today all", and callback suffix
+	// that include reimbursable expenses awaiting reimbursement.
+	periodAllArg = "all"
+
+	periodPrevButtonText = "⬅️ Previous"
+	periodNextButtonText = "Next ➡️"
+	periodNotOwnerText   = "Only the person who asked for this view can page through it."
+	logFieldPeriod       = "period"
+
+	// periodWeekStart is the first day of the week for /week navigation,
+	// matching getWeekDateRangeAt.
+	periodWeekStart = time.Monday
+)
+
+// periodHeaderEmoji returns the icon shown before a period view title.
+func periodHeaderEmoji(periodType string) string {
+	switch periodType {
+	case periodWeek:
+		return "📆"
+	case periodMonth:
+		return "🗓"
+	}
+	return "📅"
+}
+
+// yearSuffix returns ", 2025" when t falls in a different year than
+// current, so older periods stay unambiguous.
+func yearSuffix(t, current time.Time) string {
+	if t.Year() == current.Year() {
+		return ""
+	}
+	return fmt.Sprintf(", %d", t.Year())
+}
+
+// isoWeek returns the ISO year and week number of the week starting at
+// start. Weeks that do not start on Monday take the ISO week holding most
+// of their days, the one of their fourth day.
+func isoWeek(start time.Time) (int, int) {
+	return start.AddDate(0, 0, 3).ISOWeek()
+}
+
+// formatWeekLabel renders a [start, end) week in loc as
+// "Week 11 · Mar 10–16", or "Week 9 · Feb 23–Mar 1" when it spans two
+// months.
+func formatWeekLabel(start, end time.Time, loc *time.Location) string {
+	safeLoc := normalizeLocation(loc)
+	start = start.In(safeLoc)
+	last := weekLastDay(end, safeLoc)
+	_, week := isoWeek(start)
+	if start.Month() == last.Month() && start.Year() == last.Year() {
+		return fmt.Sprintf("Week %d · %s–%d", week, start.Format("Jan 2"), last.Day())
+	}
+	return fmt.Sprintf("Week %d · %s–%s", week, start.Format("Jan 2"), last.Format("Jan 2"))
+}
+
+// formatWeekLabelWithYear is formatWeekLabel followed by the year the week
+// ends in, for outputs that are kept or shared, like reports and charts.
+func formatWeekLabelWithYear(start, end time.Time, loc *time.Location) string {
+	return fmt.Sprintf("%s, %d", formatWeekLabel(start, end, loc), weekLastDay(end, normalizeLocation(loc)).Year())
+}
+
+// weekLastDay returns a time on the last day of a week ending at end. Half
+// a day before end rather than a whole one, so a week measured as 7×24
+// hours across a DST change, which ends an hour off midnight, still ends on
+// the right day.
+func weekLastDay(end time.Time, loc *time.Location) time.Time {
+	return end.In(loc).Add(-12 * time.Hour)
+}
+
+// isoWeekID renders the ISO week starting at start as "2024-W11", safe to
+// use in filenames.
+func isoWeekID(start time.Time) string {
+	year, week := isoWeek(start)
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// periodTitle names the period shown by a view. The current day and month
+// keep the familiar "Today's Expenses" style titles; weeks and other
+// periods name their dates.
+func periodTitle(periodType string, offset int, start, end, current time.Time) string {
+	switch periodType {
+	case periodWeek:
+		return formatWeekLabel(start, end, start.Location()) + yearSuffix(weekLastDay(end, start.Location()), current)
+	case periodMonth:
+		if offset == 0 {
+			return "This Month's Expenses"
+		}
+		return "Expenses for " + start.Format("January 2006")
+	}
+	switch offset {
+	case 0:
+		return "Today's Expenses"
+	case -1:
+		return "Yesterday's Expenses"
+	}
+	return "Expenses on " + start.Format("Mon, Jan 2") + yearSuffix(start, current)
+}
+
+// periodCommands maps each period type to the command that shows it.
+var periodCommands = map[string]string{
+	periodDay:   "/today",
+	periodWeek:  "/week",
+	periodMonth: "/month",
+}
+
+// periodArgsIncludeAll reports whether a /today, /week or /month message
+// asks to include reimbursable expenses awaiting reimbursement, as in
+// "/today all".
+func periodArgsIncludeAll(text string) bool {
+	fields := strings.Fields(text)
+	return len(fields) > 1 && strings.EqualFold(fields[1], periodAllArg)
+}
+
+// periodCallbackData renders the callback data of a period view button.
+func periodCallbackData(periodType string, offset int, requesterID int64, includeAll bool) string {
+	data := fmt.Sprintf(periodCallbackFmt, periodType, offset, requesterID)
+	if includeAll {
+		data += "_" + periodAllArg
+	}
+	return data
+}
+
+// buildPeriodKeyboard builds the previous/next buttons for a period view.
+// The Next button is left out on the current period so the view never
+// moves into the future.
+func buildPeriodKeyboard(
+	periodType string,
+	offset int,
+	requesterID int64,
+	includeAll bool,
+) *models.InlineKeyboardMarkup {
+	row := []models.InlineKeyboardButton{{
+		Text:         periodPrevButtonText,
+		CallbackData: periodCallbackData(periodType, offset-1, requesterID, includeAll),
+	}}
+	if offset < 0 {
+		row = append(row, models.InlineKeyboardButton{
+			Text:         periodNextButtonText,
+			CallbackData: periodCallbackData(periodType, offset+1, requesterID, includeAll),
+		})
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{row}}
+}
+
+// parsePeriodCallback splits period_<type>_<offset>_<requester>[_all]
+// callback data. Offsets after the current period are rejected.
+func parsePeriodCallback(data string) (string, int, int64, bool, bool) {
+	parts := strings.Split(strings.TrimPrefix(data, periodCallbackPrefix), "_")
+	includeAll := len(parts) == 4 && parts[3] == periodAllArg
+	if includeAll {
+		parts = parts[:3]
+	}
+	if len(parts) != 3 {
+		return "", 0, 0, false, false
+	}
+	periodType := parts[0]
+	if _, ok := periodCommands[periodType]; !ok {
+		return "", 0, 0, false, false
+	}
+	offset, err := strconv.ParseInt(parts[1], 10, 32)
+	if err != nil || offset > 0 {
+		return "", 0, 0, false, false
+	}
+	requesterID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", 0, 0, false, false
+	}
+	return periodType, int(offset), requesterID, includeAll, true
+}
+
+// renderPeriodView loads a user's expenses for the period offset periods
+// away from the current one and renders the list text with its header.
+// Reimbursable expenses awaiting reimbursement are left out of the list and
+// total unless includeAll is set.
+func (b *Bot) renderPeriodView(
+	ctx context.Context,
+	userID int64,
+	periodType string,
+	offset int,
+	includeAll bool,
+) (string, error) {
+	now := b.now()
+	start, end, err := periodRange(periodType, offset, periodWeekStart, b.displayLocation, now)
+	if err != nil {
+		return "", err
+	}
+
+	expenses, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, userID, start, end)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s expenses: %w", periodType, err)
+	}
+
+	hidden := 0
+	if !includeAll {
+		expenses, hidden = excludeAwaitingReimbursement(expenses)
+	}
+	current := now.In(normalizeLocation(b.displayLocation))
+	header := fmt.Sprintf("%s <b>%s</b> (Total: %s)",
+		periodHeaderEmoji(periodType),
+		periodTitle(periodType, offset, start, end, current),
+		formatInlineCurrencyTotals(sumExpenseAmountsByCurrency(expenses)))
+
+	text := b.expenseListText(ctx, userID, expenses, header)
+	if hidden > 0 {
+		text = strings.TrimRight(text, "\n") + "\n\n" +
+			hiddenReimbursementsNote(hidden, periodCommands[periodType]+" "+periodAllArg)
+	}
+	return text, nil
+}
+
+// expenseListText renders expenses under header in the format userID
+// picked, or the empty-list text when there are none.
+func (b *Bot) expenseListText(
+	ctx context.Context,
+	userID int64,
+	expenses []appmodels.Expense,
+	header string,
+) string {
+	if len(expenses) == 0 {
+		return header + "\n\nNo expenses found."
+	}
+
+	expenseIDs := make([]int, len(expenses))
+	for i := range expenses {
+		expenseIDs[i] = expenses[i].ID
+	}
+	tagsByExpense, err := b.tagRepo.GetByExpenseIDs(ctx, expenseIDs)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to batch-load tags for expense list")
+	}
+
+	return b.expenseListMessage(ctx, userID, header, expenses, tagsByExpense)
+}
+
+// expenseListMessage renders a non-empty expense list in the format userID
+// picked with /settings format. The mono layout leaves out categories and
+// tags to keep the columns narrow.
+func (b *Bot) expenseListMessage(
+	ctx context.Context,
+	userID int64,
+	header string,
+	expenses []appmodels.Expense,
+	tagsByExpense map[int][]appmodels.Tag,
+) string {
+	if b.displayFormat(ctx, userID) == botfmt.FormatMono {
+		return botfmt.ExpenseListMono(header, expenses, b.displayLocation)
+	}
+	return botfmt.ExpenseListMessage(header, expenses, tagsByExpense, b.displayLocation)
+}
+
+// sendPeriodViewCore sends the current day, week, or month view with
+// previous/next buttons.
+func (b *Bot) sendPeriodViewCore(ctx context.Context, tg TelegramAPI, update *models.Update, periodType string) {
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	includeAll := periodArgsIncludeAll(update.Message.Text)
+
+	text, err := b.renderPeriodView(ctx, userID, periodType, 0, includeAll)
+	if err != nil {
+		logger.Log.Error().Err(err).Str(logFieldPeriod, periodType).Msg("Failed to load period view")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   failedFetchExpensesMsg,
+		})
+		return
+	}
+
+	_, err = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: buildPeriodKeyboard(periodType, 0, userID, includeAll),
+	})
+	if err != nil {
+		logger.Log.Error().Err(err).Str(logFieldPeriod, periodType).Msg("Failed to send period view")
+	}
+}
+
+// handleMonth handles the /month command to show this month's expenses.
+func (b *Bot) handleMonth(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleMonthCore(ctx, tgBot, update)
+}
+
+// handleMonthCore is the testable implementation of handleMonth.
+func (b *Bot) handleMonthCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	b.sendPeriodViewCore(ctx, tg, update, periodMonth)
+}
+
+// handlePeriodCallback handles the previous/next buttons on period views.
+func (b *Bot) handlePeriodCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handlePeriodCallbackCore(ctx, tgBot, update)
+}
+
+// handlePeriodCallbackCore is the testable implementation of
+// handlePeriodCallback. Only the user who ran the command can page through
+// the view, which matters in group chats where others see the buttons.
+func (b *Bot) handlePeriodCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	periodType, offset, requesterID, includeAll, ok := parsePeriodCallback(query.Data)
+	if !ok {
+		answerCallback(ctx, tg, query)
+		return
+	}
+
+	if query.From.ID != requesterID {
+		logger.Log.Warn().
+			Str(logFieldUserHashCB, logger.HashUserID(query.From.ID)).
+			Msg("Period navigation tapped by another user")
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            periodNotOwnerText,
+		})
+		return
+	}
+
+	text, err := b.renderPeriodView(ctx, requesterID, periodType, offset, includeAll)
+	if err != nil {
+		logger.Log.Error().Err(err).Str(logFieldPeriod, periodType).Int("offset", offset).Msg("Failed to load period view")
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            failedFetchExpensesMsg,
+		})
+		return
+	}
+
+	answerCallback(ctx, tg, query)
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      query.Message.Message.Chat.ID,
+		MessageID:   query.Message.Message.ID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: buildPeriodKeyboard(periodType, offset, requesterID, includeAll),
+	})
+}
@@ -0,0 +1,273 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestPeriodRange(t *testing.T) {
+	t.Parallel()
+
+	loc := time.FixedZone("GMT+8", 8*60*60)
+	// Wednesday 2026-03-04 01:00 in GMT+8 is still Tuesday in UTC.
+	now := time.Date(2026, 3, 3, 17, 0, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, loc) }
+
+	tests := []struct {
+		name       string
+		periodType string
+		offset     int
+		weekStart  time.Weekday
+		wantStart  time.Time
+		wantEnd    time.Time
+	}{
+		{"today in user timezone", periodDay, 0, time.Monday, day(2026, 3, 4), day(2026, 3, 5)},
+		{"four days back across month", periodDay, -4, time.Monday, day(2026, 2, 28), day(2026, 3, 1)},
+		{"this week from Monday", periodWeek, 0, time.Monday, day(2026, 3, 2), day(2026, 3, 9)},
+		{"last week", periodWeek, -1, time.Monday, day(2026, 2, 23), day(2026, 3, 2)},
+		{"week from Sunday", periodWeek, 0, time.Sunday, day(2026, 3, 1), day(2026, 3, 8)},
+		{"this month", periodMonth, 0, time.Monday, day(2026, 3, 1), day(2026, 4, 1)},
+		{"month back across year", periodMonth, -3, time.Monday, day(2025, 12, 1), day(2026, 1, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start, end, err := periodRange(tt.periodType, tt.offset, tt.weekStart, loc, now)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantStart, start)
+			require.Equal(t, tt.wantEnd, end)
+		})
+	}
+
+	t.Run("matches the /week helper", func(t *testing.T) {
+		t.Parallel()
+		for d := range 14 {
+			current := time.Date(2026, 3, 1+d, 12, 0, 0, 0, loc)
+			start, end, err := periodRange(periodWeek, 0, time.Monday, loc, current)
+			require.NoError(t, err)
+			wantStart, wantEnd := getWeekDateRangeAt(current)
+			require.Equal(t, wantStart, start)
+			require.Equal(t, wantEnd, end)
+		}
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := periodRange("year", 0, time.Monday, loc, now)
+		require.Error(t, err)
+	})
+}
+
+func TestPeriodTitle(t *testing.T) {
+	t.Parallel()
+
+	current := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+	require.Equal(t, "Today's Expenses", periodTitle(periodDay, 0, day(2026, 3, 12), day(2026, 3, 13), current))
+	require.Equal(t, "Yesterday's Expenses", periodTitle(periodDay, -1, day(2026, 3, 11), day(2026, 3, 12), current))
+	require.Equal(t, "Expenses on Mon, Mar 9", periodTitle(periodDay, -3, day(2026, 3, 9), day(2026, 3, 10), current))
+	require.Equal(t, "Week 11 · Mar 9–15", periodTitle(periodWeek, 0, day(2026, 3, 9), day(2026, 3, 16), current))
+	require.Equal(t, "Week 10 · Mar 2–8", periodTitle(periodWeek, -1, day(2026, 3, 2), day(2026, 3, 9), current))
+	require.Equal(t, "Week 9 · Feb 23–Mar 1", periodTitle(periodWeek, -2, day(2026, 2, 23), day(2026, 3, 2), current))
+	require.Equal(t, "Week 1 · Dec 29–Jan 4", periodTitle(periodWeek, -10, day(2025, 12, 29), day(2026, 1, 5), current))
+	require.Equal(t, "Week 52 · Dec 22–28, 2025",
+		periodTitle(periodWeek, -11, day(2025, 12, 22), day(2025, 12, 29), current))
+	require.Equal(t, "This Month's Expenses", periodTitle(periodMonth, 0, day(2026, 3, 1), day(2026, 4, 1), current))
+	require.Equal(t, "Expenses for February 2026",
+		periodTitle(periodMonth, -1, day(2026, 2, 1), day(2026, 3, 1), current))
+}
+
+func TestFormatWeekLabel(t *testing.T) {
+	t.Parallel()
+
+	utc := time.UTC
+	day := func(y int, m time.Month, d int, loc *time.Location) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+	week := func(start time.Time) string {
+		return formatWeekLabel(start, start.AddDate(0, 0, 7), start.Location())
+	}
+
+	t.Run("year boundaries", func(t *testing.T) {
+		t.Parallel()
+		// 2020 has 53 ISO weeks; Jan 1–3, 2021 still belong to week 53.
+		require.Equal(t, "Week 53 · Dec 28–Jan 3", week(day(2020, 12, 28, utc)))
+		require.Equal(t, "2020-W53", isoWeekID(day(2020, 12, 28, utc)))
+		require.Equal(t, "Week 1 · Jan 4–10", week(day(2021, 1, 4, utc)))
+		// Dec 29, 2025 starts week 1 of 2026.
+		require.Equal(t, "Week 1 · Dec 29–Jan 4", week(day(2025, 12, 29, utc)))
+		require.Equal(t, "2026-W01", isoWeekID(day(2025, 12, 29, utc)))
+		require.Equal(t, "Week 52 · Dec 22–28", week(day(2025, 12, 22, utc)))
+		require.Equal(t, "Week 1 · Dec 29–Jan 4, 2026",
+			formatWeekLabelWithYear(day(2025, 12, 29, utc), day(2026, 1, 5, utc), utc))
+	})
+
+	t.Run("weeks starting on Sunday", func(t *testing.T) {
+		t.Parallel()
+		// The ISO week of the Wednesday, which holds most of the days.
+		require.Equal(t, "Week 11 · Mar 8–14", week(day(2026, 3, 8, utc)))
+		require.Equal(t, "Week 53 · Dec 27–Jan 2", week(day(2020, 12, 27, utc)))
+	})
+
+	t.Run("DST changes", func(t *testing.T) {
+		t.Parallel()
+		ny, err := time.LoadLocation("America/New_York")
+		require.NoError(t, err)
+
+		// Clocks go forward on Sunday, Mar 8, 2026.
+		spring := day(2026, 3, 2, ny)
+		require.Equal(t, "Week 10 · Mar 2–8", week(spring))
+		require.Equal(t, "Week 10 · Mar 2–8", formatWeekLabel(spring, spring.Add(7*24*time.Hour), ny))
+
+		// Clocks go back on Sunday, Nov 1, 2026.
+		fall := day(2026, 10, 26, ny)
+		require.Equal(t, "Week 44 · Oct 26–Nov 1", week(fall))
+		require.Equal(t, "Week 44 · Oct 26–Nov 1", formatWeekLabel(fall, fall.Add(7*24*time.Hour), ny))
+	})
+
+	t.Run("renders in the given location", func(t *testing.T) {
+		t.Parallel()
+		sgt := time.FixedZone("SGT", 8*60*60)
+		start := day(2026, 3, 9, sgt)
+		require.Equal(t, "Week 11 · Mar 9–15",
+			formatWeekLabel(start.UTC(), start.AddDate(0, 0, 7).UTC(), sgt))
+	})
+}
+
+func TestBuildPeriodKeyboard(t *testing.T) {
+	t.Parallel()
+
+	current := buildPeriodKeyboard(periodWeek, 0, 42, false)
+	require.Len(t, current.InlineKeyboard, 1)
+	require.Len(t, current.InlineKeyboard[0], 1, "Next is hidden on the current period")
+	require.Equal(t, periodPrevButtonText, current.InlineKeyboard[0][0].Text)
+	require.Equal(t, "period_week_-1_42", current.InlineKeyboard[0][0].CallbackData)
+
+	past := buildPeriodKeyboard(periodMonth, -2, 42, false)
+	require.Len(t, past.InlineKeyboard[0], 2)
+	require.Equal(t, "period_month_-3_42", past.InlineKeyboard[0][0].CallbackData)
+	require.Equal(t, periodNextButtonText, past.InlineKeyboard[0][1].Text)
+	require.Equal(t, "period_month_-1_42", past.InlineKeyboard[0][1].CallbackData)
+}
+
+func TestParsePeriodCallback(t *testing.T) {
+	t.Parallel()
+
+	periodType, offset, requesterID, includeAll, ok := parsePeriodCallback("period_day_-7_99")
+	require.True(t, ok)
+	require.Equal(t, periodDay, periodType)
+	require.Equal(t, -7, offset)
+	require.Equal(t, int64(99), requesterID)
+	require.False(t, includeAll)
+
+	_, offset, _, includeAll, ok = parsePeriodCallback("period_week_-1_99_all")
+	require.True(t, ok)
+	require.Equal(t, -1, offset)
+	require.True(t, includeAll)
+
+	for _, data := range []string{
+		"period_week_1_99",
+		"period_year_-1_99",
+		"period_week_x_99",
+		"period_week_-1",
+		"period_week_-1_x",
+		"period_week_-99999999999_99",
+		"period_week_-1_99_some",
+	} {
+		_, _, _, _, ok := parsePeriodCallback(data)
+		require.False(t, ok, data)
+	}
+}
+
+func TestHandlePeriodCallbackNotOwner(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{}
+	mockBot := mocks.NewMockBot()
+	b.handlePeriodCallbackCore(context.Background(), mockBot,
+		mocks.CallbackQueryUpdate(-100, 7, 1, "period_week_-1_42"))
+
+	require.Len(t, mockBot.AnsweredCallbacks, 1)
+	require.Equal(t, periodNotOwnerText, mockBot.AnsweredCallbacks[0].Text)
+	require.Empty(t, mockBot.EditedMessages)
+}
+
+func TestPeriodNavigation(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.displayLocation = time.UTC
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC) // Wednesday.
+	b.nowFunc = func() time.Time { return now }
+
+	userID := int64(374001)
+	err := b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Period"})
+	require.NoError(t, err)
+
+	createAt := func(description string, at time.Time) {
+		expense := &appmodels.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal("4.00"),
+			Currency:    currencyCodeSGD,
+			Description: description,
+			Status:      appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		_, err := pool.Exec(ctx, testUpdateExpenseTimeSQL, at, expense.ID)
+		require.NoError(t, err)
+	}
+	createAt("This week lunch", now.Add(-time.Hour))
+	createAt("Last week lunch", now.AddDate(0, 0, -7))
+
+	t.Run("week view has only a previous button", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleWeekCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/week"))
+
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, "This week lunch")
+		require.NotContains(t, msg.Text, "Last week lunch")
+		kb := requireInlineKeyboard(t, msg.ReplyMarkup)
+		require.Len(t, kb.InlineKeyboard[0], 1)
+		require.Equal(t, fmt.Sprintf("period_week_-1_%d", userID), kb.InlineKeyboard[0][0].CallbackData)
+	})
+
+	t.Run("previous edits the message in place", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		data := fmt.Sprintf("period_week_-1_%d", userID)
+		b.handlePeriodCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 55, data))
+
+		require.Empty(t, mockBot.SentMessages)
+		require.Len(t, mockBot.EditedMessages, 1)
+		edited := mockBot.EditedMessages[0]
+		require.Equal(t, 55, edited.MessageID)
+		require.Contains(t, edited.Text, "Week 9 · Feb 23–Mar 1")
+		require.Contains(t, edited.Text, "Last week lunch")
+		require.NotContains(t, edited.Text, "This week lunch")
+		kb := requireInlineKeyboard(t, edited.ReplyMarkup)
+		require.Len(t, kb.InlineKeyboard[0], 2)
+	})
+
+	t.Run("month view", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleMonthCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/month"))
+
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, "This Month's Expenses")
+		require.Contains(t, msg.Text, "This week lunch")
+		require.NotContains(t, msg.Text, "Last week lunch")
+	})
+
+	t.Run("nil message", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleMonthCore(ctx, mockBot, &models.Update{})
+		require.Equal(t, 0, mockBot.SentMessageCount())
+	})
+}
@@ -0,0 +1,353 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// pinUnlockWindow is how long /pin unlock keeps sensitive commands
+	// available in the chat it was sent in.
+	pinUnlockWindow = 5 * time.Minute
+	// pinMaxFailures wrong PINs within pinFailureWindow block further
+	// attempts until the oldest one ages out.
+	pinMaxFailures   = 3
+	pinFailureWindow = 10 * time.Minute
+
+	pinMinLength = 4
+	pinMaxLength = 8
+
+	pinSetArg    = "set"
+	pinUnlockArg = "unlock"
+	pinOffArg    = "off"
+
+	pinUsageMsg = "Usage:\n" +
+		"<code>/pin set 1234</code> - Lock /delete, /export, /report, /statement, /dedupe and /apitoken behind a PIN\n" +
+		"<code>/pin unlock 1234</code> - Unlock them in this chat for 5 minutes\n" +
+		"<code>/pin off 1234</code> - Remove the PIN" +
+		"\n\nSee /help pin"
+	pinInvalidMsg   = "❌ A PIN is 4 to 8 digits."
+	pinPrivateMsg   = "🔒 Use /pin set in a private chat with the bot so the PIN stays secret."
+	pinNotSetMsg    = "ℹ️ You don't have a PIN. Set one with <code>/pin set 1234</code>."
+	pinWrongMsg     = "❌ Wrong PIN."
+	pinFailMsg      = "❌ Failed to update your PIN. Please try again."
+	pinCheckFailMsg = "❌ Failed to check your PIN. Please try again."
+	pinLockedMsg    = "🔒 This is locked with your PIN. Send /pin unlock followed by your PIN first."
+	pinChangeMsg    = "🔒 You already have a PIN. Send <code>/pin unlock &lt;pin&gt;</code> first to change it."
+)
+
+var errPINRateLimited = errors.New("too many wrong PINs")
+
+// pinKey scopes an unlock to one user in one chat, so unlocking in a group
+// does not unlock the private chat and the other way round.
+type pinKey struct {
+	UserID int64
+	ChatID int64
+}
+
+// pinLocks tracks unlocked windows and wrong PIN attempts in memory. A
+// restart locks everyone again. The zero value is ready to use.
+type pinLocks struct {
+	mu       sync.Mutex
+	unlocked map[pinKey]time.Time
+	failures map[int64][]time.Time
+}
+
+// unlock opens the unlock window for key until now plus pinUnlockWindow.
+func (p *pinLocks) unlock(key pinKey, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.unlocked == nil {
+		p.unlocked = make(map[pinKey]time.Time)
+	}
+	p.unlocked[key] = now.Add(pinUnlockWindow)
+	delete(p.failures, key.UserID)
+}
+
+// isUnlocked reports whether key has an open unlock window.
+func (p *pinLocks) isUnlocked(key pinKey, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	expiresAt, ok := p.unlocked[key]
+	if ok && !now.Before(expiresAt) {
+		delete(p.unlocked, key)
+		return false
+	}
+	return ok
+}
+
+// lockUser closes every unlock window of userID.
+func (p *pinLocks) lockUser(userID int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key := range p.unlocked {
+		if key.UserID == userID {
+			delete(p.unlocked, key)
+		}
+	}
+	delete(p.failures, userID)
+}
+
+// recentFailures drops attempts older than pinFailureWindow and returns
+// the ones left. The caller must hold p.mu.
+func (p *pinLocks) recentFailures(userID int64, now time.Time) []time.Time {
+	attempts := p.failures[userID]
+	kept := attempts[:0]
+	for _, at := range attempts {
+		if now.Sub(at) < pinFailureWindow {
+			kept = append(kept, at)
+		}
+	}
+	if len(kept) == 0 {
+		delete(p.failures, userID)
+		return nil
+	}
+	p.failures[userID] = kept
+	return kept
+}
+
+// retryAfter returns how long userID must wait before trying another PIN,
+// or zero when an attempt is allowed.
+func (p *pinLocks) retryAfter(userID int64, now time.Time) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.retryAfterLocked(userID, now)
+}
+
+// retryAfterLocked is retryAfter for callers holding p.mu.
+func (p *pinLocks) retryAfterLocked(userID int64, now time.Time) time.Duration {
+	attempts := p.recentFailures(userID, now)
+	if len(attempts) < pinMaxFailures {
+		return 0
+	}
+	return attempts[len(attempts)-pinMaxFailures].Add(pinFailureWindow).Sub(now)
+}
+
+// attempt runs compare for userID unless they have too many recent wrong
+// PINs, counting a failure when it reports false. The check, compare and
+// count share one locked section, so concurrent guesses cannot all pass
+// the check before any failure is counted.
+func (p *pinLocks) attempt(userID int64, now time.Time, compare func() bool) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.retryAfterLocked(userID, now) > 0 {
+		return false, errPINRateLimited
+	}
+	if compare() {
+		return true, nil
+	}
+	if p.failures == nil {
+		p.failures = make(map[int64][]time.Time)
+	}
+	p.failures[userID] = append(p.recentFailures(userID, now), now)
+	return false, nil
+}
+
+// validPIN reports whether pin is 4 to 8 ASCII digits.
+func validPIN(pin string) bool {
+	if len(pin) < pinMinLength || len(pin) > pinMaxLength {
+		return false
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// verifyPIN checks pin against the user's stored hash, counting wrong
+// attempts. It returns errPINRateLimited without checking when the user has
+// too many recent wrong attempts.
+func (b *Bot) verifyPIN(userID int64, hash, pin string) (bool, error) {
+	ok, err := b.pinLocks.attempt(userID, b.now(), func() bool {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pin)) == nil
+	})
+	if err == nil && !ok {
+		logger.Log.Warn().Str("user_hash", logger.HashUserID(userID)).Msg("Wrong PIN entered")
+	}
+	return ok, err
+}
+
+// pinRateLimitedMsg tells the user how long to wait before trying again.
+func (b *Bot) pinRateLimitedMsg(userID int64) string {
+	wait := b.pinLocks.retryAfter(userID, b.now())
+	minutes := max(1, int((wait+time.Minute-1)/time.Minute))
+	return fmt.Sprintf("⏳ Too many wrong PINs. Try again in %d min.", minutes)
+}
+
+// sensitiveCommandAllowed reports whether a PIN-locked command may run for
+// the sender of message. Users without a PIN are never locked. Otherwise
+// the user must have unlocked this chat with /pin unlock; if not, they are
+// told so and false is returned.
+func (b *Bot) sensitiveCommandAllowed(ctx context.Context, tg TelegramAPI, message *models.Message) bool {
+	text, ok := b.pinLockCheck(ctx, message.From.ID, message.Chat.ID)
+	if !ok {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: message.Chat.ID,
+			Text:   text,
+		})
+	}
+	return ok
+}
+
+// deleteButtonAllowed applies the PIN lock to the inline delete buttons.
+// When locked, the callback is answered with an alert and false is
+// returned; otherwise the callback is left unanswered for the caller.
+func (b *Bot) deleteButtonAllowed(ctx context.Context, tg TelegramAPI, query *models.CallbackQuery) bool {
+	text, ok := b.pinLockCheck(ctx, query.From.ID, query.Message.Message.Chat.ID)
+	if !ok {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            text,
+			ShowAlert:       true,
+		})
+	}
+	return ok
+}
+
+// loadPINHash returns the user's PIN hash, or an empty string when the user
+// has no PIN or no users row yet. Errors are logged.
+func (b *Bot) loadPINHash(ctx context.Context, userID int64) (string, error) {
+	hash, err := b.userRepo.GetPINHash(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to load PIN")
+		return "", fmt.Errorf("failed to load PIN: %w", err)
+	}
+	return hash, nil
+}
+
+// pinLockCheck is the shared part of sensitiveCommandAllowed and
+// deleteButtonAllowed. It returns the reason, without HTML, when the action
+// is not allowed.
+func (b *Bot) pinLockCheck(ctx context.Context, userID, chatID int64) (string, bool) {
+	hash, err := b.loadPINHash(ctx, userID)
+	if err != nil {
+		return pinCheckFailMsg, false
+	}
+	if hash == "" || b.pinLocks.isUnlocked(pinKey{UserID: userID, ChatID: chatID}, b.now()) {
+		return "", true
+	}
+	return pinLockedMsg, false
+}
+
+// handlePIN handles the /pin command.
+func (b *Bot) handlePIN(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handlePINCore(ctx, tgBot, update)
+}
+
+// handlePINCore is the testable implementation of handlePIN.
+func (b *Bot) handlePINCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	args := strings.Fields(extractCommandArgs(update.Message.Text, "/pin"))
+	if len(args) != 2 {
+		send(pinUsageMsg)
+		return
+	}
+	action, pin := strings.ToLower(args[0]), args[1]
+	if action != pinSetArg && action != pinUnlockArg && action != pinOffArg {
+		send(pinUsageMsg)
+		return
+	}
+	if !validPIN(pin) {
+		send(pinInvalidMsg)
+		return
+	}
+
+	hash, err := b.loadPINHash(ctx, userID)
+	if err != nil {
+		send(pinCheckFailMsg)
+		return
+	}
+
+	if action == pinSetArg {
+		b.setPINCore(ctx, update.Message, hash, pin, send)
+		return
+	}
+
+	if hash == "" {
+		send(pinNotSetMsg)
+		return
+	}
+	ok, err := b.verifyPIN(userID, hash, pin)
+	switch {
+	case errors.Is(err, errPINRateLimited):
+		logger.Log.Warn().Str("user_hash", logger.HashUserID(userID)).Msg("PIN attempt rate limited")
+		send(b.pinRateLimitedMsg(userID))
+		return
+	case !ok:
+		send(pinWrongMsg)
+		return
+	}
+
+	if action == pinUnlockArg {
+		b.pinLocks.unlock(pinKey{UserID: userID, ChatID: chatID}, b.now())
+		send(fmt.Sprintf("🔓 Unlocked in this chat for %d minutes.", int(pinUnlockWindow/time.Minute)))
+		return
+	}
+
+	if err := b.userRepo.ClearPINHash(ctx, userID); err != nil {
+		logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to remove PIN")
+		send(pinFailMsg)
+		return
+	}
+	b.pinLocks.lockUser(userID)
+	logger.Log.Info().Str("user_hash", logger.HashUserID(userID)).Msg("PIN removed")
+	send("✅ PIN removed. Sensitive commands no longer ask for it.")
+}
+
+// setPINCore stores a new PIN. Changing an existing PIN needs an open
+// unlock window in the same chat.
+func (b *Bot) setPINCore(ctx context.Context, message *models.Message, currentHash, pin string, send func(string)) {
+	userID := message.From.ID
+	if message.Chat.Type != models.ChatTypePrivate {
+		send(pinPrivateMsg)
+		return
+	}
+	if currentHash != "" && !b.pinLocks.isUnlocked(pinKey{UserID: userID, ChatID: message.Chat.ID}, b.now()) {
+		send(pinChangeMsg)
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err == nil {
+		err = b.userRepo.SetPINHash(ctx, userID, string(hash))
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to set PIN")
+		send(pinFailMsg)
+		return
+	}
+
+	// A new PIN starts locked, also for windows opened with the old one.
+	b.pinLocks.lockUser(userID)
+	logger.Log.Info().Str("user_hash", logger.HashUserID(userID)).Msg("PIN set")
+	send("✅ PIN set. /delete, /export, /report, /statement, /dedupe and /apitoken now need " +
+		"<code>/pin unlock &lt;pin&gt;</code> first.\n\nYou may want to delete your message with the PIN.")
+}
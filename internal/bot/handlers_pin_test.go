@@ -0,0 +1,224 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestValidPIN(t *testing.T) {
+	t.Parallel()
+
+	for _, pin := range []string{"1234", "00000000"} {
+		require.True(t, validPIN(pin), pin)
+	}
+	for _, pin := range []string{"", "123", "123456789", "12a4", "１２３４"} {
+		require.False(t, validPIN(pin), pin)
+	}
+}
+
+func TestPINLocks_UnlockWindow(t *testing.T) {
+	t.Parallel()
+
+	var locks pinLocks
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	key := pinKey{UserID: 1, ChatID: 10}
+
+	require.False(t, locks.isUnlocked(key, now))
+	locks.unlock(key, now)
+	require.True(t, locks.isUnlocked(key, now.Add(pinUnlockWindow-time.Second)))
+	require.False(t, locks.isUnlocked(key, now.Add(pinUnlockWindow)))
+	require.False(t, locks.isUnlocked(key, now))
+
+	t.Run("does not leak across users or chats", func(t *testing.T) {
+		t.Parallel()
+		var locks pinLocks
+		locks.unlock(key, now)
+		require.False(t, locks.isUnlocked(pinKey{UserID: 2, ChatID: 10}, now))
+		require.False(t, locks.isUnlocked(pinKey{UserID: 1, ChatID: 11}, now))
+	})
+
+	t.Run("lockUser closes every chat", func(t *testing.T) {
+		t.Parallel()
+		var locks pinLocks
+		other := pinKey{UserID: 1, ChatID: 11}
+		stranger := pinKey{UserID: 2, ChatID: 10}
+		locks.unlock(key, now)
+		locks.unlock(other, now)
+		locks.unlock(stranger, now)
+		locks.lockUser(1)
+		require.False(t, locks.isUnlocked(key, now))
+		require.False(t, locks.isUnlocked(other, now))
+		require.True(t, locks.isUnlocked(stranger, now))
+	})
+}
+
+func TestPINLocks_RateLimit(t *testing.T) {
+	t.Parallel()
+
+	var locks pinLocks
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	wrong := func() bool { return false }
+
+	for i := range pinMaxFailures {
+		require.Zero(t, locks.retryAfter(1, now), "attempt %d", i+1)
+		ok, err := locks.attempt(1, now.Add(time.Duration(i)*time.Minute), wrong)
+		require.NoError(t, err)
+		require.False(t, ok)
+	}
+	require.Equal(t, pinFailureWindow-2*time.Minute, locks.retryAfter(1, now.Add(2*time.Minute)))
+	require.Zero(t, locks.retryAfter(2, now), "other users are not limited")
+
+	compared := false
+	_, err := locks.attempt(1, now.Add(2*time.Minute), func() bool { compared = true; return true })
+	require.ErrorIs(t, err, errPINRateLimited)
+	require.False(t, compared, "a limited user's PIN is not checked")
+
+	// The first failure ages out, so one more attempt is allowed.
+	require.Zero(t, locks.retryAfter(1, now.Add(pinFailureWindow)))
+	_, err = locks.attempt(1, now.Add(pinFailureWindow), wrong)
+	require.NoError(t, err)
+	require.Positive(t, locks.retryAfter(1, now.Add(pinFailureWindow)))
+}
+
+func TestPINLocks_ConcurrentAttempts(t *testing.T) {
+	t.Parallel()
+
+	var locks pinLocks
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	var compares atomic.Int32
+	for range 10 {
+		wg.Go(func() {
+			_, _ = locks.attempt(1, now, func() bool {
+				compares.Add(1)
+				return false
+			})
+		})
+	}
+	wg.Wait()
+	require.Equal(t, int32(pinMaxFailures), compares.Load(), "only the allowed guesses are checked")
+}
+
+func TestHandlePINCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	b.nowFunc = func() time.Time { return now }
+
+	userID := int64(393001)
+	otherID := int64(393002)
+	groupID := int64(-393001)
+	for _, id := range []int64{userID, otherID} {
+		require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: id, FirstName: "Pin"}))
+	}
+
+	pin := func(chatID int64, text string) string {
+		mockBot := mocks.NewMockBot()
+		update := mocks.CommandUpdate(chatID, userID, text)
+		if chatID < 0 {
+			update.Message.Chat.Type = models.ChatTypeGroup
+		}
+		b.handlePINCore(ctx, mockBot, update)
+		return mockBot.LastSentMessage().Text
+	}
+	deleteAs := func(chatID, fromID int64) string {
+		mockBot := mocks.NewMockBot()
+		b.handleDeleteCore(ctx, mockBot, mocks.CommandUpdate(chatID, fromID, "/delete"))
+		return mockBot.LastSentMessage().Text
+	}
+
+	t.Run("set needs a private chat and a valid PIN", func(t *testing.T) {
+		require.Equal(t, pinPrivateMsg, pin(groupID, "/pin set 1234"))
+		require.Equal(t, pinInvalidMsg, pin(userID, "/pin set 12"))
+		require.Equal(t, pinNotSetMsg, pin(userID, "/pin unlock 1234"))
+		require.Contains(t, deleteAs(userID, userID), "Usage")
+
+		require.Contains(t, pin(userID, "/pin set 1234"), "PIN set")
+		hash, err := b.userRepo.GetPINHash(ctx, userID)
+		require.NoError(t, err)
+		require.NotContains(t, hash, "1234")
+	})
+
+	t.Run("sensitive commands are locked, others are not", func(t *testing.T) {
+		require.Equal(t, pinLockedMsg, deleteAs(userID, userID))
+
+		mockBot := mocks.NewMockBot()
+		b.handleExportCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/export"))
+		require.Equal(t, pinLockedMsg, mockBot.LastSentMessage().Text)
+		require.Empty(t, mockBot.SentDocuments)
+
+		mockBot = mocks.NewMockBot()
+		b.handleReportCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/report month"))
+		require.Equal(t, pinLockedMsg, mockBot.LastSentMessage().Text)
+		require.Empty(t, mockBot.SentDocuments)
+
+		for _, tc := range []struct {
+			text   string
+			handle func(context.Context, TelegramAPI, *models.Update)
+		}{
+			{exportSettingsCommand, b.handleExportSettingsCore},
+			{importSettingsCommand, b.handleImportSettingsCore},
+			{exportTaxonomyCommand, b.handleExportTaxonomyCore},
+			{importTaxonomyCommand, b.handleImportTaxonomyCore},
+		} {
+			mockBot = mocks.NewMockBot()
+			tc.handle(ctx, mockBot, mocks.CommandUpdate(userID, userID, tc.text))
+			require.Equal(t, pinLockedMsg, mockBot.LastSentMessage().Text, tc.text)
+			require.Empty(t, mockBot.SentDocuments, tc.text)
+		}
+
+		mockBot = mocks.NewMockBot()
+		b.handleDedupeCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 1, "dedupe_keep_2026-03_1"))
+		require.Len(t, mockBot.AnsweredCallbacks, 1)
+		require.Equal(t, pinLockedMsg, mockBot.AnsweredCallbacks[0].Text)
+		require.Zero(t, mockBot.EditedMessageCount())
+
+		mockBot = mocks.NewMockBot()
+		b.handleListCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/list"))
+		require.NotEqual(t, pinLockedMsg, mockBot.LastSentMessage().Text)
+
+		require.Contains(t, deleteAs(otherID, otherID), "Usage", "users without a PIN are unaffected")
+	})
+
+	t.Run("wrong PINs are rate limited", func(t *testing.T) {
+		for range pinMaxFailures {
+			require.Equal(t, pinWrongMsg, pin(userID, "/pin unlock 9999"))
+		}
+		require.Contains(t, pin(userID, "/pin unlock 1234"), "Too many wrong PINs")
+		require.Equal(t, pinLockedMsg, deleteAs(userID, userID))
+
+		now = now.Add(pinFailureWindow)
+	})
+
+	t.Run("unlock opens one chat for a while", func(t *testing.T) {
+		require.Contains(t, pin(userID, "/pin unlock 1234"), "Unlocked")
+		require.Contains(t, deleteAs(userID, userID), "Usage")
+		require.Equal(t, pinLockedMsg, deleteAs(groupID, userID))
+
+		now = now.Add(pinUnlockWindow)
+		require.Equal(t, pinLockedMsg, deleteAs(userID, userID))
+	})
+
+	t.Run("changing the PIN needs an unlock", func(t *testing.T) {
+		require.Equal(t, pinChangeMsg, pin(userID, "/pin set 5678"))
+		pin(userID, "/pin unlock 1234")
+		require.Contains(t, pin(userID, "/pin set 5678"), "PIN set")
+		require.Equal(t, pinLockedMsg, deleteAs(userID, userID))
+	})
+
+	t.Run("off needs the PIN", func(t *testing.T) {
+		require.Equal(t, pinWrongMsg, pin(userID, "/pin off 1234"))
+		require.Contains(t, pin(userID, "/pin off 5678"), "PIN removed")
+		require.Contains(t, deleteAs(userID, userID), "Usage")
+	})
+}
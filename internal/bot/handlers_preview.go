@@ -0,0 +1,376 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
+)
+
+const (
+	// previewTTL is how long the "Save it" button of a preview stays valid.
+	previewTTL = 10 * time.Minute
+
+	previewCallbackPrefix = "preview_save_"
+	previewSaveButtonText = "💾 Save it"
+
+	previewUsageMsg = "Usage: <code>/preview 300 New headphones Electronics</code>\n\n" +
+		"Shows what an expense would do to your budgets, without saving it."
+)
+
+var (
+	errPreviewExpired  = errors.New("preview expired or already used")
+	errPreviewNotOwner = errors.New("preview belongs to another user")
+)
+
+// pendingPreview is an expense built by /preview, kept until the user
+// saves it or it expires. Nothing is stored in the database until then.
+type pendingPreview struct {
+	UserID          int64
+	Expense         *appmodels.Expense
+	Parsed          *ParsedExpense
+	Suggestion      *gemini.CategorySuggestion
+	SourceMessageID int
+	ExpiresAt       time.Time
+}
+
+// handlePreview handles the /preview command.
+func (b *Bot) handlePreview(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handlePreviewCore(ctx, tgBot, update)
+}
+
+// handlePreviewCore is the testable implementation of handlePreview. It
+// builds an expense like /add without saving it, Gemini being asked in the
+// dry-run mode of /parse, and shows what it would do to the category budget
+// and to the month's total against all budgets. The expense is kept behind
+// a "Save it" button.
+func (b *Bot) handlePreviewCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	send := func(text string, markup models.ReplyMarkup) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:      chatID,
+			Text:        text,
+			ParseMode:   models.ParseModeHTML,
+			ReplyMarkup: markup,
+		})
+	}
+
+	args := extractCommandArgs(update.Message.Text, "/preview")
+	if args == "" {
+		send(previewUsageMsg, nil)
+		return
+	}
+
+	categories, err := b.visibleCategories(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for /preview")
+		send(failedFetchCategoriesMsg, nil)
+		return
+	}
+	categoryNames := make([]string, len(categories))
+	for i := range categories {
+		categoryNames[i] = categories[i].Name
+	}
+
+	parsed := ParseAddCommandWithCategories(args, categoryNames)
+	if parsed == nil {
+		send(parseNotExpenseMsg, nil)
+		return
+	}
+	if parsed.AmountError != nil {
+		b.sendAmountExpressionError(ctx, tg, chatID, parsed)
+		return
+	}
+	parsed.Source = appmodels.ExpenseSourceCommand
+
+	parsed.ChatCategoryID = b.chatDefaultCategoryID(ctx, chatID)
+	expense, decision, err := b.buildExpenseWithMode(ctx, userID, parsed, categories, service.AIPreview)
+	if errors.Is(err, service.ErrAboveHardCap) {
+		send(b.amountAboveHardCapMsg(expense.Amount, expense.Currency), nil)
+		return
+	}
+
+	text := b.formatPreview(ctx, expense, parsed, decision)
+	nonce, err := b.storePendingPreview(&pendingPreview{
+		UserID:          userID,
+		Expense:         expense,
+		Parsed:          parsed,
+		Suggestion:      decision.Suggestion,
+		SourceMessageID: update.Message.ID,
+		ExpiresAt:       b.now().Add(previewTTL),
+	})
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to store preview")
+		send(text, nil)
+		return
+	}
+	send(text, &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{{
+			{Text: previewSaveButtonText, CallbackData: previewCallbackPrefix + nonce},
+		}},
+	})
+}
+
+// formatPreview renders a built expense with its budget impact.
+func (b *Bot) formatPreview(
+	ctx context.Context,
+	expense *appmodels.Expense,
+	parsed *ParsedExpense,
+	decision service.CategoryDecision,
+) string {
+	var sb strings.Builder
+	sb.WriteString("🔍 <b>Preview</b> <i>(nothing saved)</i>\n\n")
+	fmt.Fprintf(&sb, "<b>Amount:</b> %s\n", botfmt.Money(expense.Amount, expense.Currency))
+	fmt.Fprintf(&sb, "<b>Description:</b> %s\n", valueOrNone(botfmt.EscapeHTML(expense.Description)))
+	fmt.Fprintf(&sb, "<b>Tags:</b> %s\n", describeParsedTags(parsed.Tags))
+
+	categoryName := parseNoneValue
+	if expense.Category != nil {
+		categoryName = botfmt.EscapeHTML(expense.Category.Name)
+	}
+	fmt.Fprintf(&sb, "<b>Category:</b> %s <i>(%s)</i>\n", categoryName, decision.Source)
+	fmt.Fprintf(&sb, "<b>AI:</b> %s\n", describeAIDecision(decision, true))
+
+	converter := b.newBudgetConverter()
+	if line := b.previewCategoryBudgetLine(ctx, converter, expense); line != "" {
+		sb.WriteString("\n" + line)
+	}
+	if line := b.previewMonthlyLine(ctx, converter, expense); line != "" {
+		sb.WriteString("\n" + line)
+	}
+	return sb.String()
+}
+
+// previewCategoryBudgetLine describes where the expense would put its
+// category budget, e.g. "would put Electronics at 112% of budget". It
+// returns "" when the category has no budget or the expense cannot be
+// converted into the budget currency.
+func (b *Bot) previewCategoryBudgetLine(
+	ctx context.Context,
+	converter *budgetConverter,
+	expense *appmodels.Expense,
+) string {
+	if expense.CategoryID == nil {
+		return ""
+	}
+
+	status, err := b.loadBudgetStatus(ctx, converter, expense.UserID, *expense.CategoryID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Log.Warn().Err(err).Int("category_id", *expense.CategoryID).Msg("Failed to load budget for preview")
+		}
+		return ""
+	}
+	amount, ok := converter.convertExpense(ctx, expense, status.Currency)
+	if !ok || !status.Amount.IsPositive() {
+		return ""
+	}
+
+	after := status.Spent.Add(amount)
+	line := fmt.Sprintf("📊 Would put %s at %s%% of budget (%s of %s).",
+		botfmt.EscapeHTML(status.CategoryName),
+		percentOf(after, status.Amount),
+		botfmt.Money(after, status.Currency),
+		botfmt.Money(status.Amount, status.Currency))
+	if status.blocks() && after.GreaterThan(status.Amount) {
+		line += "\n🚫 This is a hard budget, so saving it would be refused."
+	}
+	return line
+}
+
+// previewMonthlyLine describes where the expense would put this month's
+// total against the sum of all the user's budgets, in their default
+// currency. It returns "" when the user has no budgets, or one of them
+// cannot be converted. Spending without an exchange rate is left out.
+func (b *Bot) previewMonthlyLine(ctx context.Context, converter *budgetConverter, expense *appmodels.Expense) string {
+	defaultCurrency := b.getUserDefaultCurrency(ctx, expense.UserID)
+	goal, ok := b.monthlyBudgetGoal(ctx, converter, expense.UserID, defaultCurrency)
+	if !ok {
+		return ""
+	}
+
+	start, end, _ := b.budgetPeriod(ctx, expense.UserID)
+	totals, err := b.expenseRepo.GetCategoryCurrencyTotalsByUserIDAndDateRange(ctx, expense.UserID, start, end)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to load monthly spending for preview")
+		return ""
+	}
+	spent, _ := converter.convertExpense(ctx, expense, defaultCurrency)
+	for i := range totals {
+		if amount, ok := converter.convert(ctx, &totals[i], defaultCurrency); ok {
+			spent = spent.Add(amount)
+		}
+	}
+
+	return fmt.Sprintf("📅 Monthly total at %s%% of your budgets (%s of %s).",
+		percentOf(spent, goal),
+		botfmt.Money(spent, defaultCurrency),
+		botfmt.Money(goal, defaultCurrency))
+}
+
+// monthlyBudgetGoal sums all the user's budgets in currency. It is not ok
+// when the user has no budgets, or one of them cannot be converted.
+func (b *Bot) monthlyBudgetGoal(
+	ctx context.Context,
+	converter *budgetConverter,
+	userID int64,
+	currency string,
+) (decimal.Decimal, bool) {
+	budgets, err := b.budgetRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to list budgets for monthly goal")
+		return decimal.Zero, false
+	}
+
+	goal := decimal.Zero
+	for i := range budgets {
+		total := repository.CategoryCurrencyTotal{
+			Currency: budgetCurrency(&budgets[i], currency),
+			Amount:   budgets[i].Amount,
+		}
+		amount, ok := converter.convert(ctx, &total, currency)
+		if !ok {
+			return decimal.Zero, false
+		}
+		goal = goal.Add(amount)
+	}
+	return goal, goal.IsPositive()
+}
+
+// percentOf returns part as a whole percentage of total, e.g. "112".
+func percentOf(part, total decimal.Decimal) string {
+	return part.Mul(decimal.NewFromInt(100)).Div(total).Round(0).String()
+}
+
+// storePendingPreview remembers a previewed expense under a new nonce.
+func (b *Bot) storePendingPreview(pending *pendingPreview) (string, error) {
+	nonce, err := newCallbackNonce()
+	if err != nil {
+		return "", err
+	}
+
+	b.pendingPreviewsMu.Lock()
+	defer b.pendingPreviewsMu.Unlock()
+	if b.pendingPreviews == nil {
+		b.pendingPreviews = make(map[string]*pendingPreview)
+	}
+	now := b.now()
+	for key, p := range b.pendingPreviews {
+		if now.After(p.ExpiresAt) {
+			delete(b.pendingPreviews, key)
+		}
+	}
+	b.pendingPreviews[nonce] = pending
+	return nonce, nil
+}
+
+// takePendingPreview removes and returns the previewed expense for nonce if
+// it belongs to userID and has not expired. Each nonce works only once.
+func (b *Bot) takePendingPreview(nonce string, userID int64) (*pendingPreview, error) {
+	b.pendingPreviewsMu.Lock()
+	defer b.pendingPreviewsMu.Unlock()
+
+	pending, ok := b.pendingPreviews[nonce]
+	if !ok {
+		return nil, errPreviewExpired
+	}
+	if pending.UserID != userID {
+		return nil, errPreviewNotOwner
+	}
+	delete(b.pendingPreviews, nonce)
+	if b.now().After(pending.ExpiresAt) {
+		return nil, errPreviewExpired
+	}
+	return pending, nil
+}
+
+// handlePreviewCallback handles the "Save it" button of a preview.
+func (b *Bot) handlePreviewCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handlePreviewCallbackCore(ctx, tgBot, update)
+}
+
+// handlePreviewCallbackCore is the testable implementation of
+// handlePreviewCallback. It saves the previewed expense as it was built,
+// without asking Gemini again; a suggested new category is created now.
+// The currency was shown in the preview, so a first-time currency is not
+// asked about, but a hard budget is checked again.
+func (b *Bot) handlePreviewCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	chatID := query.Message.Message.Chat.ID
+	messageID := query.Message.Message.ID
+
+	pending, err := b.takePendingPreview(strings.TrimPrefix(query.Data, previewCallbackPrefix), query.From.ID)
+	if errors.Is(err, errPreviewNotOwner) {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            staleNotOwnerText,
+		})
+		return
+	}
+	if err != nil {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            "This preview has expired. Please send /preview again.",
+		})
+		_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+		})
+		return
+	}
+	answerCallback(ctx, tg, query)
+
+	// The preview stays in the chat for reference; its button is used up.
+	_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+	})
+
+	expense := pending.Expense
+	if expense.Category != nil && expense.CategoryID == nil {
+		b.createPreviewedCategory(ctx, pending)
+	}
+
+	if saved := b.storeBuiltExpense(ctx, tg, chatID, pending.UserID, expense, pending.Parsed); saved != nil {
+		b.linkSourceMessage(ctx, saved.ID, chatID, pending.SourceMessageID)
+	}
+}
+
+// createPreviewedCategory creates the new category Gemini suggested for a
+// previewed expense. When that fails the expense falls back to Others, as
+// it would have when added directly.
+func (b *Bot) createPreviewedCategory(ctx context.Context, pending *pendingPreview) {
+	expense := pending.Expense
+	categories, err := b.visibleCategories(ctx, pending.UserID)
+	if err == nil && pending.Suggestion != nil &&
+		b.expenses().ApplyNewCategory(ctx, expense, pending.Parsed.Description, pending.Suggestion, categories) {
+		return
+	}
+
+	clearExpenseCategory(expense)
+	if fallback := MatchCategory(gemini.CategoryOthers, categories); fallback != nil {
+		expense.CategoryID = &fallback.ID
+		expense.Category = fallback
+	}
+}
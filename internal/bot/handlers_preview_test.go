@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestPercentOf(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "112", percentOf(mustParseDecimal("560"), mustParseDecimal("500")))
+	require.Equal(t, "93", percentOf(mustParseDecimal("560"), mustParseDecimal("600")))
+	require.Equal(t, "0", percentOf(mustParseDecimal("0"), mustParseDecimal("50")))
+}
+
+func TestTakePendingPreview(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	b := &Bot{nowFunc: func() time.Time { return now }}
+	nonce, err := b.storePendingPreview(&pendingPreview{UserID: 1, ExpiresAt: now.Add(previewTTL)})
+	require.NoError(t, err)
+
+	_, err = b.takePendingPreview(nonce, 2)
+	require.ErrorIs(t, err, errPreviewNotOwner)
+
+	pending, err := b.takePendingPreview(nonce, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), pending.UserID)
+
+	_, err = b.takePendingPreview(nonce, 1)
+	require.ErrorIs(t, err, errPreviewExpired)
+
+	nonce, err = b.storePendingPreview(&pendingPreview{UserID: 1, ExpiresAt: now.Add(-time.Second)})
+	require.NoError(t, err)
+	_, err = b.takePendingPreview(nonce, 1)
+	require.ErrorIs(t, err, errPreviewExpired)
+}
+
+func TestHandlePreviewCore_NoSender(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{}
+	mockBot := mocks.NewMockBot()
+	update := mocks.CommandUpdate(100, 100, "/preview 5 Coffee")
+	update.Message.From = nil
+	b.handlePreviewCore(context.Background(), mockBot, update)
+	require.Zero(t, mockBot.SentMessageCount())
+}
+
+func TestPreview(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(435001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{
+		ID:              userID,
+		FirstName:       "Previewer",
+		DefaultCurrency: currencyCodeSGD,
+	}))
+	electronics, err := b.categoryRepo.Create(ctx, "Preview Electronics")
+	require.NoError(t, err)
+	travel, err := b.categoryRepo.Create(ctx, "Preview Travel")
+	require.NoError(t, err)
+	b.invalidateCategoryCache()
+	require.NoError(t, b.budgetRepo.Set(ctx, userID, electronics.ID, mustParseDecimal("500"), currencyCodeSGD, false))
+	require.NoError(t, b.budgetRepo.Set(ctx, userID, travel.ID, mustParseDecimal("100"), currencyCodeSGD, false))
+	require.NoError(t, b.expenseRepo.Create(ctx, &appmodels.Expense{
+		UserID:      userID,
+		Amount:      mustParseDecimal("260"),
+		Currency:    currencyCodeSGD,
+		Description: "Keyboard",
+		CategoryID:  &electronics.ID,
+		Status:      appmodels.ExpenseStatusConfirmed,
+	}))
+
+	const input = "300 New headphones Preview Electronics"
+	preview := func() (*mocks.MockBot, string) {
+		mockBot := mocks.NewMockBot()
+		b.handlePreviewCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/preview "+input))
+		keyboard := requireInlineKeyboard(t, mockBot.LastSentMessage().ReplyMarkup)
+		return mockBot, keyboard.InlineKeyboard[0][0].CallbackData
+	}
+	tap := func(data string, from int64) *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		b.handlePreviewCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, from, 1, data))
+		return mockBot
+	}
+	saved := func() []appmodels.Expense {
+		expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 10)
+		require.NoError(t, err)
+		return expenses
+	}
+
+	t.Run("preview shows the budget impact without saving", func(t *testing.T) {
+		mockBot, _ := preview()
+		text := mockBot.LastSentMessage().Text
+		require.Contains(t, text, "<b>Category:</b> Preview Electronics")
+		require.Contains(t, text, "📊 Would put Preview Electronics at 112% of budget (S$560.00 SGD of S$500.00 SGD).")
+		require.Contains(t, text, "📅 Monthly total at 93% of your budgets (S$560.00 SGD of S$600.00 SGD).")
+		require.Len(t, saved(), 1)
+	})
+
+	t.Run("saving a preview matches a direct add", func(t *testing.T) {
+		_, data := preview()
+		require.Equal(t, staleNotOwnerText, tap(data, 1).AnsweredCallbacks[0].Text)
+		require.Len(t, saved(), 1)
+
+		mockBot := tap(data, userID)
+		require.Len(t, mockBot.EditedReplyMarkups, 1, "the button is removed")
+		fromPreview := saved()[0]
+
+		b.handleAddCore(ctx, mocks.NewMockBot(), mocks.CommandUpdate(userID, userID, "/add "+input))
+		direct := saved()[0]
+		require.NotEqual(t, fromPreview.ID, direct.ID)
+		require.True(t, direct.Amount.Equal(fromPreview.Amount))
+		require.Equal(t, direct.Currency, fromPreview.Currency)
+		require.Equal(t, direct.Description, fromPreview.Description)
+		require.Equal(t, direct.Status, fromPreview.Status)
+		require.NotNil(t, fromPreview.CategoryID)
+		require.Equal(t, *direct.CategoryID, *fromPreview.CategoryID)
+		require.Equal(t, electronics.ID, *fromPreview.CategoryID)
+
+		require.Contains(t, tap(data, userID).AnsweredCallbacks[0].Text, "expired")
+		require.Len(t, saved(), 3)
+	})
+
+	t.Run("stale buttons expire", func(t *testing.T) {
+		_, data := preview()
+		b.nowFunc = func() time.Time { return time.Now().Add(previewTTL + time.Minute) }
+		defer func() { b.nowFunc = nil }()
+
+		mockBot := tap(data, userID)
+		require.Equal(t, "This preview has expired. Please send /preview again.", mockBot.AnsweredCallbacks[0].Text)
+		require.Len(t, mockBot.EditedReplyMarkups, 1)
+		require.Len(t, saved(), 3)
+	})
+}
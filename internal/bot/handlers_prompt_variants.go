@@ -0,0 +1,232 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+const (
+	// promptVariantPreviewLength is how much of a template /promptvariants
+	// list shows.
+	promptVariantPreviewLength = 80
+
+	promptVariantsUsage = "Usage:\n" +
+		"<code>/promptvariants list</code>\n" +
+		"<code>/promptvariants add &lt;name&gt; &lt;template&gt;</code>\n" +
+		"<code>/promptvariants activate &lt;name&gt;</code>\n" +
+		"<code>/promptvariants deactivate &lt;name&gt;</code>\n" +
+		"<code>/promptvariants share &lt;name&gt; &lt;percent&gt;</code>\n\n" +
+		"The template needs <code>{categories}</code> and must ask for amount, currency, " +
+		"merchant, date, suggested_category and confidence."
+)
+
+// promptVariantNameRegex matches the names of prompt variants.
+var promptVariantNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,29}$`)
+
+// promptVariantSource gives the Gemini client the active prompt variants.
+type promptVariantSource struct {
+	repo *repository.PromptVariantRepository
+}
+
+// ActivePromptVariants implements gemini.PromptVariantSource. Templates
+// that no longer validate are skipped.
+func (s promptVariantSource) ActivePromptVariants(ctx context.Context) ([]gemini.PromptVariant, error) {
+	stored, err := s.repo.ListActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prompt variants: %w", err)
+	}
+
+	variants := make([]gemini.PromptVariant, 0, len(stored))
+	for _, v := range stored {
+		if err := gemini.ValidatePromptTemplate(v.Template); err != nil {
+			logger.Log.Warn().Err(err).Str("variant", v.Name).Msg("Skipping invalid prompt variant")
+			continue
+		}
+		variants = append(variants, gemini.PromptVariant{Name: v.Name, Template: v.Template, Share: v.Share})
+	}
+	return variants, nil
+}
+
+// activePromptShare returns the share the active variants other than
+// name take together.
+func activePromptShare(variants []repository.PromptVariant, name string) int {
+	total := 0
+	for _, v := range variants {
+		if v.Active && v.Name != name {
+			total += v.Share
+		}
+	}
+	return total
+}
+
+// formatPromptVariants lists prompt variants with their state, share and
+// the start of their template, and the share left to the built-in prompt.
+func formatPromptVariants(variants []repository.PromptVariant) string {
+	if len(variants) == 0 {
+		return "🧪 No prompt variants. Add one with <code>/promptvariants add &lt;name&gt; &lt;template&gt;</code>."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🧪 <b>Receipt Prompt Variants</b>\n")
+	for _, v := range variants {
+		state := "inactive"
+		if v.Active {
+			state = "active"
+		}
+		preview := strings.Join(strings.Fields(v.Template), " ")
+		if runes := []rune(preview); len(runes) > promptVariantPreviewLength {
+			preview = string(runes[:promptVariantPreviewLength]) + "…"
+		}
+		fmt.Fprintf(&sb, "\n<b>%s</b> · %s · %d%%\n<code>%s</code>\n",
+			botfmt.EscapeHTML(v.Name), state, v.Share, botfmt.EscapeHTML(preview))
+	}
+	fmt.Fprintf(&sb, "\nBuilt-in prompt: %d%%", gemini.MaxPromptVariantShare-activePromptShare(variants, ""))
+	return sb.String()
+}
+
+// handlePromptVariants handles the /promptvariants command.
+func (b *Bot) handlePromptVariants(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handlePromptVariantsCore(ctx, tgBot, update)
+}
+
+// handlePromptVariantsCore is the testable implementation of
+// handlePromptVariants. Superadmins list, add and turn on receipt prompt
+// variants and set the share of scans each serves. Active shares never
+// add up to more than 100%.
+func (b *Bot) handlePromptVariantsCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	if !b.cfg.IsSuperAdmin(userID, update.Message.From.Username) {
+		send(onlySuperadminsMsg)
+		return
+	}
+
+	action, rest, _ := strings.Cut(extractAdminArgs(update.Message.Text), " ")
+	rest = strings.TrimSpace(rest)
+	// The template may start on the next line, so the name ends at any
+	// white space.
+	name, arg := rest, ""
+	if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+		name, arg = rest[:i], strings.TrimSpace(rest[i:])
+	}
+	name = strings.ToLower(name)
+	action = strings.ToLower(action)
+
+	variants, err := b.variantRepo.List(ctx)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to list prompt variants")
+		send("❌ Failed to fetch prompt variants.")
+		return
+	}
+	find := func() *repository.PromptVariant {
+		for i := range variants {
+			if variants[i].Name == name {
+				return &variants[i]
+			}
+		}
+		send(fmt.Sprintf("No prompt variant named <b>%s</b>.", botfmt.EscapeHTML(name)))
+		return nil
+	}
+	fitsShare := func(share int) bool {
+		if used := activePromptShare(variants, name); used+share > gemini.MaxPromptVariantShare {
+			send(fmt.Sprintf("❌ Active variants already take %d%%, so <b>%s</b> can have at most %d%%.",
+				used, botfmt.EscapeHTML(name), gemini.MaxPromptVariantShare-used))
+			return false
+		}
+		return true
+	}
+
+	switch action {
+	case "", "list":
+		send(formatPromptVariants(variants))
+	case "add":
+		if !promptVariantNameRegex.MatchString(name) || arg == "" {
+			send(promptVariantsUsage)
+			return
+		}
+		if err := gemini.ValidatePromptTemplate(arg); err != nil {
+			send("❌ " + botfmt.EscapeHTML(err.Error()))
+			return
+		}
+		added, err := b.variantRepo.Add(ctx, name, arg, userID)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("variant", name).Msg("Failed to add prompt variant")
+			send("❌ Failed to save the prompt variant.")
+			return
+		}
+		if !added {
+			send(fmt.Sprintf("❌ A prompt variant named <b>%s</b> exists. Pick another name.", botfmt.EscapeHTML(name)))
+			return
+		}
+		send(fmt.Sprintf("✅ Prompt variant <b>%s</b> added. Give it a share with "+
+			"<code>/promptvariants share %s &lt;percent&gt;</code>, then activate it.",
+			botfmt.EscapeHTML(name), botfmt.EscapeHTML(name)))
+	case "activate", "deactivate":
+		if name == "" {
+			send(promptVariantsUsage)
+			return
+		}
+		v := find()
+		if v == nil {
+			return
+		}
+		active := action == "activate"
+		if active && !fitsShare(v.Share) {
+			return
+		}
+		if _, err := b.variantRepo.SetActive(ctx, name, active); err != nil {
+			logger.Log.Error().Err(err).Str("variant", name).Msg("Failed to update prompt variant")
+			send("❌ Failed to update the prompt variant.")
+			return
+		}
+		if active {
+			send(fmt.Sprintf("✅ Prompt variant <b>%s</b> now serves %d%% of receipt scans.", botfmt.EscapeHTML(name), v.Share))
+			return
+		}
+		send(fmt.Sprintf("⏸ Prompt variant <b>%s</b> deactivated.", botfmt.EscapeHTML(name)))
+	case "share":
+		share, err := strconv.Atoi(strings.TrimSuffix(arg, "%"))
+		if name == "" || err != nil || share < 0 || share > gemini.MaxPromptVariantShare {
+			send(promptVariantsUsage)
+			return
+		}
+		v := find()
+		if v == nil {
+			return
+		}
+		if v.Active && !fitsShare(share) {
+			return
+		}
+		if _, err := b.variantRepo.SetShare(ctx, name, share); err != nil {
+			logger.Log.Error().Err(err).Str("variant", name).Msg("Failed to update prompt variant share")
+			send("❌ Failed to update the prompt variant.")
+			return
+		}
+		send(fmt.Sprintf("✅ Prompt variant <b>%s</b> share set to %d%%.", botfmt.EscapeHTML(name), share))
+	default:
+		send(promptVariantsUsage)
+	}
+}
@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+const testPromptTemplate = "Read the receipt.\n" +
+	"Return amount, currency, merchant, date, confidence and suggested_category (one of {categories})."
+
+func TestFormatPromptVariants(t *testing.T) {
+	t.Parallel()
+
+	require.Contains(t, formatPromptVariants(nil), "No prompt variants")
+
+	text := formatPromptVariants([]repository.PromptVariant{
+		{Name: "terse", Template: testPromptTemplate, Active: true, Share: 30},
+		{Name: "draft", Template: "short {categories}", Share: 50},
+	})
+	require.Equal(t,
+		"🧪 <b>Receipt Prompt Variants</b>\n\n"+
+			"<b>terse</b> · active · 30%\n"+
+			"<code>Read the receipt. Return amount, currency, merchant, date, confidence and sugges…</code>\n\n"+
+			"<b>draft</b> · inactive · 50%\n"+
+			"<code>short {categories}</code>\n\n"+
+			"Built-in prompt: 70%",
+		text)
+}
+
+func TestHandlePromptVariantsCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.cfg = &config.Config{WhitelistedUserIDs: []int64{100}}
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: 100, FirstName: "Admin"}))
+
+	run := func(userID int64, text string) string {
+		mockBot := mocks.NewMockBot()
+		b.handlePromptVariantsCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, text))
+		return mockBot.LastSentMessage().Text
+	}
+	source := promptVariantSource{repo: b.variantRepo}
+
+	require.Equal(t, onlySuperadminsMsg, run(999, "/promptvariants"))
+	require.Contains(t, run(100, "/promptvariants"), "No prompt variants")
+	require.Equal(t, promptVariantsUsage, run(100, "/promptvariants add terse"))
+	require.Equal(t, "❌ the template is missing {categories}, suggested_category",
+		run(100, "/promptvariants add terse amount currency merchant date confidence"))
+
+	require.Contains(t, run(100, "/promptvariants add terse\n"+testPromptTemplate), "added")
+	require.Contains(t, run(100, "/promptvariants add terse "+testPromptTemplate), "exists")
+	require.Contains(t, run(100, "/promptvariants add strict "+testPromptTemplate), "added")
+	require.Contains(t, run(100, "/promptvariants share nope 10"), "No prompt variant named")
+	require.Equal(t, promptVariantsUsage, run(100, "/promptvariants share terse 101"))
+
+	require.Contains(t, run(100, "/promptvariants share terse 60%"), "set to 60%")
+	require.Contains(t, run(100, "/promptvariants activate terse"), "serves 60%")
+	require.Contains(t, run(100, "/promptvariants share strict 50"), "set to 50%",
+		"inactive variants can hold any share")
+	require.Contains(t, run(100, "/promptvariants activate strict"), "can have at most 40%")
+	require.Contains(t, run(100, "/promptvariants share strict 40"), "set to 40%")
+	require.Contains(t, run(100, "/promptvariants activate strict"), "serves 40%")
+	require.Contains(t, run(100, "/promptvariants share terse 70"), "can have at most 60%")
+
+	variants, err := source.ActivePromptVariants(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []gemini.PromptVariant{
+		{Name: "terse", Template: testPromptTemplate, Share: 60},
+		{Name: "strict", Template: testPromptTemplate, Share: 40},
+	}, variants)
+
+	require.Contains(t, run(100, "/promptvariants deactivate strict"), "deactivated")
+	list := run(100, "/promptvariants list")
+	require.Contains(t, list, "<b>strict</b> · inactive · 40%")
+	require.Contains(t, list, "Built-in prompt: 40%")
+
+	t.Run("variants that no longer validate are skipped", func(t *testing.T) {
+		_, err := pool.Exec(ctx, `UPDATE prompt_variants SET template = 'broken' WHERE name = 'terse'`)
+		require.NoError(t, err)
+		variants, err := source.ActivePromptVariants(ctx)
+		require.NoError(t, err)
+		require.Empty(t, variants)
+	})
+}
@@ -0,0 +1,404 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+const (
+	// recategorizeMinPatternLen guards against accidental mass updates from
+	// very short patterns.
+	recategorizeMinPatternLen = 3
+	// recategorizePreviewSize is how many sample matches the prompt shows.
+	recategorizePreviewSize = 5
+	// recategorizeTTL is how long a confirmation prompt stays valid.
+	recategorizeTTL = 10 * time.Minute
+
+	recategorizeCallbackPrefix = "recat_"
+	recategorizeYesFmt         = "recat_yes_%s"
+	recategorizeNoFmt          = "recat_no_%s"
+
+	recategorizeUsageMsg = "Usage: <code>/recategorize \"pattern\" Category</code>\n\n" +
+		"Example: <code>/recategorize \"grab\" Transportation</code>"
+	recategorizeExpiredMsg = "This recategorization has expired. Run /recategorize again."
+)
+
+var (
+	errRecategorizeExpired  = errors.New("recategorization expired or already used")
+	errRecategorizeNotOwner = errors.New("recategorization belongs to another user")
+)
+
+// pendingRecategorization is a previewed bulk recategorization waiting for
+// the user to confirm it.
+type pendingRecategorization struct {
+	UserID       int64
+	Pattern      string
+	CategoryID   int
+	CategoryName string
+	ExpiresAt    time.Time
+}
+
+// parseRecategorizeArgs splits `"pattern" Category` into its parts. The
+// pattern may be unquoted when it is a single word.
+func parseRecategorizeArgs(args string) (string, string, bool) {
+	args = strings.TrimSpace(args)
+	var pattern, rest string
+	if strings.HasPrefix(args, `"`) {
+		end := strings.Index(args[1:], `"`)
+		if end < 0 {
+			return "", "", false
+		}
+		pattern, rest = args[1:end+1], args[end+2:]
+	} else {
+		var found bool
+		pattern, rest, found = strings.Cut(args, " ")
+		if !found {
+			return "", "", false
+		}
+	}
+	pattern, rest = strings.TrimSpace(pattern), strings.TrimSpace(rest)
+	if pattern == "" || rest == "" {
+		return "", "", false
+	}
+	return pattern, rest, true
+}
+
+// newCallbackNonce returns a random token identifying one pending
+// confirmation in callback data.
+func newCallbackNonce() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// storePendingRecategorization remembers a preview under a new nonce.
+func (b *Bot) storePendingRecategorization(pending *pendingRecategorization) (string, error) {
+	nonce, err := newCallbackNonce()
+	if err != nil {
+		return "", err
+	}
+
+	b.pendingRecatsMu.Lock()
+	defer b.pendingRecatsMu.Unlock()
+	if b.pendingRecats == nil {
+		b.pendingRecats = make(map[string]*pendingRecategorization)
+	}
+	now := b.now()
+	for key, p := range b.pendingRecats {
+		if now.After(p.ExpiresAt) {
+			delete(b.pendingRecats, key)
+		}
+	}
+	b.pendingRecats[nonce] = pending
+	return nonce, nil
+}
+
+// takePendingRecategorization removes and returns the preview for nonce if
+// it belongs to userID and has not expired. Each nonce works only once.
+func (b *Bot) takePendingRecategorization(nonce string, userID int64) (*pendingRecategorization, error) {
+	b.pendingRecatsMu.Lock()
+	defer b.pendingRecatsMu.Unlock()
+
+	pending, ok := b.pendingRecats[nonce]
+	if !ok {
+		return nil, errRecategorizeExpired
+	}
+	if pending.UserID != userID {
+		return nil, errRecategorizeNotOwner
+	}
+	delete(b.pendingRecats, nonce)
+	if b.now().After(pending.ExpiresAt) {
+		return nil, errRecategorizeExpired
+	}
+	return pending, nil
+}
+
+// buildRecategorizePreview renders the confirmation prompt for matches.
+func buildRecategorizePreview(pattern, categoryName string, matches []appmodels.Expense) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🔁 <b>Move to %s?</b>\n\n", botfmt.EscapeHTML(categoryName))
+	fmt.Fprintf(&sb, "%d expense(s) match \"%s\".\n", len(matches), botfmt.EscapeHTML(pattern))
+	sb.WriteString("Total: " + formatInlineCurrencyTotals(sumExpenseAmountsByCurrency(matches)) + "\n\n")
+
+	for i := range matches[:min(len(matches), recategorizePreviewSize)] {
+		exp := &matches[i]
+		text := exp.Description
+		if text == "" {
+			text = exp.Merchant
+		}
+		fmt.Fprintf(&sb, "• #%d %s — %s (%s)\n",
+			exp.UserExpenseNumber,
+			botfmt.EscapeHTML(text),
+			botfmt.Money(exp.Amount, exp.Currency),
+			botfmt.CategoryName(exp.Category))
+	}
+	if extra := len(matches) - recategorizePreviewSize; extra > 0 {
+		fmt.Fprintf(&sb, "…and %d more\n", extra)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// buildRecategorizeResult renders how many expenses moved and from where.
+func buildRecategorizeResult(categoryName string, changed map[string]int64) string {
+	var total int64
+	names := make([]string, 0, len(changed))
+	for name, count := range changed {
+		total += count
+		names = append(names, name)
+	}
+	if total == 0 {
+		return "Nothing to change: no matching expenses are left outside " + botfmt.EscapeHTML(categoryName) + "."
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "✅ Moved %d expense(s) to <b>%s</b>.\n\nFrom:", total, botfmt.EscapeHTML(categoryName))
+	for _, name := range names {
+		label := botfmt.Uncategorized
+		if name != "" {
+			label = botfmt.EscapeHTML(name)
+		}
+		fmt.Fprintf(&sb, "\n• %s: %d", label, changed[name])
+	}
+	return sb.String()
+}
+
+// handleRecategorize handles the /recategorize command.
+func (b *Bot) handleRecategorize(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleRecategorizeCore(ctx, tgBot, update)
+}
+
+// handleRecategorizeCore is the testable implementation of
+// handleRecategorize. It previews the matches and asks for confirmation.
+func (b *Bot) handleRecategorizeCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	pattern, categoryName, ok := parseRecategorizeArgs(extractCommandArgs(update.Message.Text, "/recategorize"))
+	if !ok {
+		send(recategorizeUsageMsg)
+		return
+	}
+	if utf8.RuneCountInString(pattern) < recategorizeMinPatternLen {
+		send(fmt.Sprintf("❌ The pattern must be at least %d characters.", recategorizeMinPatternLen))
+		return
+	}
+
+	categories, err := b.visibleCategories(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch categories for recategorize")
+		send("❌ Failed to fetch categories. Please try again.")
+		return
+	}
+	categoryID, category := findCategoryByName(categories, categoryName)
+	if category == nil {
+		send(fmt.Sprintf("❌ Category \"%s\" not found. Use /categories to see the list.",
+			botfmt.EscapeHTML(echoText(categoryName))))
+		return
+	}
+
+	matches, err := b.expenseRepo.GetByDescriptionLike(ctx, userID, pattern, *categoryID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to find expenses to recategorize")
+		send(failedFetchExpensesMsg)
+		return
+	}
+	if len(matches) == 0 {
+		send(fmt.Sprintf("No expenses outside <b>%s</b> match \"%s\".",
+			botfmt.EscapeHTML(category.Name), botfmt.EscapeHTML(pattern)))
+		return
+	}
+
+	nonce, err := b.storePendingRecategorization(&pendingRecategorization{
+		UserID:       userID,
+		Pattern:      pattern,
+		CategoryID:   category.ID,
+		CategoryName: category.Name,
+		ExpiresAt:    b.now().Add(recategorizeTTL),
+	})
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to store recategorize preview")
+		send("❌ Something went wrong. Please try again.")
+		return
+	}
+
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      buildRecategorizePreview(pattern, category.Name, matches),
+		ParseMode: models.ParseModeHTML,
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{{
+				{Text: fmt.Sprintf("✅ Move %d", len(matches)), CallbackData: fmt.Sprintf(recategorizeYesFmt, nonce)},
+				{Text: "❌ Cancel", CallbackData: fmt.Sprintf(recategorizeNoFmt, nonce)},
+			}},
+		},
+	})
+}
+
+// handleRecategorizeCallback handles the recategorize confirm and cancel
+// buttons.
+func (b *Bot) handleRecategorizeCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleRecategorizeCallbackCore(ctx, tgBot, update)
+}
+
+// handleRecategorizeCallbackCore is the testable implementation of
+// handleRecategorizeCallback.
+func (b *Bot) handleRecategorizeCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	action, nonce, ok := strings.Cut(strings.TrimPrefix(query.Data, recategorizeCallbackPrefix), "_")
+	if !ok || (action != "yes" && action != "no") {
+		answerCallback(ctx, tg, query)
+		return
+	}
+
+	chatID := query.Message.Message.Chat.ID
+	messageID := query.Message.Message.ID
+
+	pending, err := b.takePendingRecategorization(nonce, query.From.ID)
+	if errors.Is(err, errRecategorizeNotOwner) {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            staleNotOwnerText,
+		})
+		return
+	}
+	if err != nil {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            recategorizeExpiredMsg,
+		})
+		// The buttons are dead for good, so remove them.
+		_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+		})
+		return
+	}
+	answerCallback(ctx, tg, query)
+
+	if action == "no" {
+		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+			Text:      "Recategorization canceled. Nothing was changed.",
+		})
+		return
+	}
+
+	changed, err := b.recategorizeAudited(ctx, pending)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to recategorize expenses")
+		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+			Text:      "❌ Failed to update expenses. Nothing was changed.",
+		})
+		return
+	}
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(pending.UserID)).
+		Int("category_id", pending.CategoryID).
+		Int("previous_categories", len(changed)).
+		Msg("Expenses recategorized by pattern")
+
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+		Text:      buildRecategorizeResult(pending.CategoryName, countRecategorized(changed)),
+		ParseMode: models.ParseModeHTML,
+	})
+}
+
+// recategorizeAudited moves the matching expenses and records a category
+// entry in the expense history for each, in one transaction, so a bulk
+// change cannot skip the history. No group notices are sent for it.
+func (b *Bot) recategorizeAudited(
+	ctx context.Context,
+	pending *pendingRecategorization,
+) ([]repository.RecategorizedExpense, error) {
+	expenses, audit := b.expenses(), b.expenseAuditRepo
+	var tx pgx.Tx
+	if beginner, ok := b.db.(database.TxBeginner); ok {
+		var err error
+		if tx, err = beginner.Begin(ctx); err != nil {
+			return nil, fmt.Errorf("begin tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		expenses = expenses.WithStore(repository.NewExpenseRepository(tx))
+		audit = repository.NewExpenseAuditRepository(tx)
+	}
+
+	changed, err := expenses.Recategorize(ctx, pending.UserID, pending.Pattern, pending.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]repository.ExpenseAuditEntry, len(changed))
+	for i, e := range changed {
+		oldName := e.OldCategory
+		if oldName == "" {
+			oldName = botfmt.Uncategorized
+		}
+		entries[i] = repository.ExpenseAuditEntry{
+			ExpenseID:     e.ID,
+			UserID:        pending.UserID,
+			ExpenseNumber: e.ExpenseNumber,
+			ActorID:       pending.UserID,
+			Field:         repository.ExpenseAuditCategory,
+			OldValue:      oldName,
+			NewValue:      pending.CategoryName,
+		}
+	}
+	if err := audit.Record(ctx, entries); err != nil {
+		return nil, fmt.Errorf("record audit: %w", err)
+	}
+	if tx != nil {
+		if err := tx.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("commit tx: %w", err)
+		}
+	}
+	return changed, nil
+}
+
+// countRecategorized counts moved expenses by their old category name.
+func countRecategorized(changed []repository.RecategorizedExpense) map[string]int64 {
+	counts := make(map[string]int64)
+	for _, e := range changed {
+		counts[e.OldCategory]++
+	}
+	return counts
+}
@@ -0,0 +1,210 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	recatPatternTest  = "grab"
+	recatCategoryTest = "Transportation"
+)
+
+func TestParseRecategorizeArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		args         string
+		wantPattern  string
+		wantCategory string
+		wantOK       bool
+	}{
+		{`"grab" Transportation`, recatPatternTest, recatCategoryTest, true},
+		{`"grab car" Food - Dining Out`, "grab car", "Food - Dining Out", true},
+		{`grab Transportation`, recatPatternTest, recatCategoryTest, true},
+		{`"grab"`, "", "", false},
+		{`"grab Transportation`, "", "", false},
+		{`grab`, "", "", false},
+		{``, "", "", false},
+		{`"" Transportation`, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			t.Parallel()
+			pattern, category, ok := parseRecategorizeArgs(tt.args)
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.wantPattern, pattern)
+			require.Equal(t, tt.wantCategory, category)
+		})
+	}
+}
+
+func TestPendingRecategorizationNonce(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	b := &Bot{nowFunc: func() time.Time { return now }}
+
+	store := func() string {
+		nonce, err := b.storePendingRecategorization(&pendingRecategorization{
+			UserID:    1,
+			Pattern:   recatPatternTest,
+			ExpiresAt: now.Add(recategorizeTTL),
+		})
+		require.NoError(t, err)
+		require.Len(t, nonce, 16)
+		return nonce
+	}
+
+	nonce := store()
+	_, err := b.takePendingRecategorization(nonce, 2)
+	require.ErrorIs(t, err, errRecategorizeNotOwner)
+
+	pending, err := b.takePendingRecategorization(nonce, 1)
+	require.NoError(t, err)
+	require.Equal(t, recatPatternTest, pending.Pattern)
+
+	_, err = b.takePendingRecategorization(nonce, 1)
+	require.ErrorIs(t, err, errRecategorizeExpired, "a nonce works only once")
+
+	_, err = b.takePendingRecategorization("unknown", 1)
+	require.ErrorIs(t, err, errRecategorizeExpired)
+
+	expired := store()
+	now = now.Add(recategorizeTTL + time.Second)
+	_, err = b.takePendingRecategorization(expired, 1)
+	require.ErrorIs(t, err, errRecategorizeExpired)
+}
+
+func TestBuildRecategorizeResult(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t,
+		"✅ Moved 3 expense(s) to <b>Transportation</b>.\n\nFrom:\n• Uncategorized: 1\n• Food &amp; Drinks: 2",
+		buildRecategorizeResult(recatCategoryTest, map[string]int64{"": 1, "Food & Drinks": 2}))
+	require.Contains(t, buildRecategorizeResult(recatCategoryTest, nil), "Nothing to change")
+}
+
+func TestHandleRecategorizeCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(373101)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Recat"}))
+
+	categories, err := b.getCategoriesWithCache(ctx)
+	require.NoError(t, err)
+	transportID, transport := findCategoryByName(categories, recatCategoryTest)
+	require.NotNil(t, transport)
+
+	for i := range 7 {
+		require.NoError(t, b.expenseRepo.Create(ctx, &appmodels.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal("10.00"),
+			Currency:    currencyCodeSGD,
+			Description: "Grab ride " + strings.Repeat("x", i),
+			Status:      appmodels.ExpenseStatusConfirmed,
+		}))
+	}
+	require.NoError(t, b.expenseRepo.Create(ctx, &appmodels.Expense{
+		UserID:      userID,
+		Amount:      mustParseDecimal("4.00"),
+		Currency:    currencyCodeSGD,
+		Description: "Coffee",
+		Status:      appmodels.ExpenseStatusConfirmed,
+	}))
+
+	callbackData := func(t *testing.T, mockBot *mocks.MockBot, col int) string {
+		t.Helper()
+		keyboard := requireInlineKeyboard(t, mockBot.LastSentMessage().ReplyMarkup)
+		return keyboard.InlineKeyboard[0][col].CallbackData
+	}
+
+	t.Run("rejects short patterns", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleRecategorizeCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, `/recategorize "gr" Transportation`))
+		require.Contains(t, mockBot.LastSentMessage().Text, "at least 3 characters")
+		require.Nil(t, mockBot.LastSentMessage().ReplyMarkup)
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleRecategorizeCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, `/recategorize "grab" Nope`))
+		require.Contains(t, mockBot.LastSentMessage().Text, "not found")
+	})
+
+	t.Run("cancel changes nothing", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleRecategorizeCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, `/recategorize "grab" transportation`))
+		data := callbackData(t, mockBot, 1)
+
+		b.handleRecategorizeCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 1, data))
+		require.Contains(t, mockBot.EditedMessages[len(mockBot.EditedMessages)-1].Text, "Nothing was changed")
+
+		moved, err := b.expenseRepo.GetByUserIDAndCategory(ctx, userID, *transportID, 20)
+		require.NoError(t, err)
+		require.Empty(t, moved)
+	})
+
+	t.Run("preview then confirm moves all matches", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleRecategorizeCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, `/recategorize "GRAB" Transportation`))
+
+		preview := mockBot.LastSentMessage().Text
+		require.Contains(t, preview, "7 expense(s) match")
+		require.Contains(t, preview, "Total: S$70.00 SGD")
+		require.Equal(t, recategorizePreviewSize, strings.Count(preview, "• #"))
+		require.Contains(t, preview, "…and 2 more")
+		require.NotContains(t, preview, "Coffee")
+
+		data := callbackData(t, mockBot, 0)
+		b.handleRecategorizeCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 1, data))
+		require.Contains(t, mockBot.EditedMessages[len(mockBot.EditedMessages)-1].Text, "Moved 7 expense(s)")
+
+		moved, err := b.expenseRepo.GetByUserIDAndCategory(ctx, userID, *transportID, 20)
+		require.NoError(t, err)
+		require.Len(t, moved, 7)
+
+		t.Run("replayed nonce is rejected", func(t *testing.T) {
+			replay := mocks.NewMockBot()
+			b.handleRecategorizeCallbackCore(ctx, replay, mocks.CallbackQueryUpdate(userID, userID, 1, data))
+			require.Empty(t, replay.EditedMessages)
+			require.Len(t, replay.EditedReplyMarkups, 1, "dead buttons are removed")
+		})
+	})
+
+	t.Run("callback from another user is rejected", func(t *testing.T) {
+		require.NoError(t, b.expenseRepo.Create(ctx, &appmodels.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal("3.00"),
+			Currency:    currencyCodeSGD,
+			Description: "Coffee beans",
+			Status:      appmodels.ExpenseStatusConfirmed,
+		}))
+		mockBot := mocks.NewMockBot()
+		b.handleRecategorizeCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, `/recategorize coffee Transportation`))
+		data := callbackData(t, mockBot, 0)
+
+		other := mocks.NewMockBot()
+		b.handleRecategorizeCallbackCore(ctx, other, &tgmodels.Update{CallbackQuery: &tgmodels.CallbackQuery{
+			ID:      "cb",
+			From:    tgmodels.User{ID: 999},
+			Data:    data,
+			Message: mocks.CallbackQueryUpdate(userID, userID, 1, data).CallbackQuery.Message,
+		}})
+		require.Empty(t, other.EditedMessages)
+		require.Empty(t, other.EditedReplyMarkups, "the owner's buttons stay")
+
+		moved, err := b.expenseRepo.GetByUserIDAndCategory(ctx, userID, *transportID, 20)
+		require.NoError(t, err)
+		require.Len(t, moved, 7)
+	})
+}
@@ -6,20 +6,27 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
 	"gitlab.com/yelinaung/expense-bot/internal/gemini"
 	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
 
 	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/codes"
 )
 
-// buildReceiptConfirmationKeyboard creates the inline keyboard for receipt confirmation.
-func buildReceiptConfirmationKeyboard(expenseID int) *models.InlineKeyboardMarkup {
+// receiptProcessingText is the placeholder shown while a receipt is read.
+const receiptProcessingText = "📷 Processing receipt..."
+
+// buildReceiptConfirmationKeyboard creates the inline keyboard for receipt
+// confirmation. The amount tweaks offered depend on the draft's currency.
+func buildReceiptConfirmationKeyboard(expenseID int, currency string) *models.InlineKeyboardMarkup {
 	return &models.InlineKeyboardMarkup{
 		InlineKeyboard: [][]models.InlineKeyboardButton{
 			{
@@ -27,10 +34,47 @@ func buildReceiptConfirmationKeyboard(expenseID int) *models.InlineKeyboardMarku
 				{Text: "✏️ Edit", CallbackData: fmt.Sprintf("receipt_edit_%d", expenseID)},
 				{Text: "❌ Cancel", CallbackData: fmt.Sprintf("receipt_cancel_%d", expenseID)},
 			},
+			buildReceiptAdjustRow(expenseID, currency),
+			buildMerchantHistoryRow(expenseID),
 		},
 	}
 }
 
+// rebuildReceiptKeyboard is the confirmation keyboard for a refreshed draft
+// card. The total-or-items picks, tip and suggested-category rows the card
+// showed are kept, so an edit on the card does not drop them.
+func rebuildReceiptKeyboard(expense *appmodels.Expense, current *models.InlineKeyboardMarkup) *models.InlineKeyboardMarkup {
+	keyboard := buildReceiptConfirmationKeyboard(expense.ID, expense.Currency)
+	if keyboardHasCallback(current, fmt.Sprintf(receiptTipCallbackFmt, expense.ID)) {
+		keyboard = buildReceiptTipKeyboard(expense.ID, expense.Currency)
+	}
+	if keyboardHasCallback(current, fmt.Sprintf(receiptItemsCallbackFmt, expense.ID)) {
+		keyboard.InlineKeyboard = [][]models.InlineKeyboardButton{
+			current.InlineKeyboard[0],
+			keyboard.InlineKeyboard[0][1:],
+		}
+	}
+	if keyboardHasCallback(current, fmt.Sprintf(suggestedCategoryUseFmt, expense.ID)) {
+		keyboard = withSuggestedCategoryButtons(keyboard, expense.ID)
+	}
+	return keyboard
+}
+
+// keyboardHasCallback reports whether a keyboard has a button with data.
+func keyboardHasCallback(keyboard *models.InlineKeyboardMarkup, data string) bool {
+	if keyboard == nil {
+		return false
+	}
+	for _, row := range keyboard.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData == data {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // handlePhoto handles photo messages for receipt OCR.
 func (b *Bot) handlePhoto(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
 	b.handlePhotoCore(ctx, tgBot, update)
@@ -59,9 +103,26 @@ func (b *Bot) handlePhotoCore(ctx context.Context, tg TelegramAPI, update *model
 		})
 		return
 	}
+	if b.aiBudgetExhausted(ctx) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text: "📷 Receipt OCR is not available: " + aiBudgetExhaustedMsg +
+				" Please add expenses manually using /add or send text like <code>5.50 Coffee</code>",
+			ParseMode: models.ParseModeHTML,
+		})
+		return
+	}
 
 	largestPhoto := update.Message.Photo[len(update.Message.Photo)-1]
 
+	if groupID := update.Message.MediaGroupID; groupID != "" {
+		b.bufferMediaGroupPhoto(ctx, tg, chatID, userID, update.Message.ID, groupID, largestPhoto.FileID)
+		return
+	}
+	if b.askReceiptOwner(ctx, tg, update.Message, largestPhoto.FileID) {
+		return
+	}
+
 	logger.Log.Debug().
 		Int64("chat_id", chatID).
 		Int64("user_id", userID).
@@ -70,13 +131,208 @@ func (b *Bot) handlePhotoCore(ctx context.Context, tg TelegramAPI, update *model
 		Int("height", largestPhoto.Height).
 		Msg("Downloading photo")
 
-	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID: chatID,
-		Text:   "📷 Processing receipt...",
+	b.processReceiptPhotos(ctx, tg, chatID, userID, update.Message.ID, []string{largestPhoto.FileID})
+}
+
+// bufferMediaGroupPhoto collects a photo of a Telegram album. The first
+// photo of an album schedules a scan of all photos received within the
+// window, so a long receipt sent as several photos becomes one draft.
+func (b *Bot) bufferMediaGroupPhoto(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID, userID int64,
+	messageID int,
+	groupID, fileID string,
+) {
+	key := mediaGroupKey{chatID: chatID, groupID: groupID}
+	isNew, ok := b.mediaGroups.add(key, userID, messageID, fileID, gemini.MaxReceiptImages)
+	if !ok {
+		logger.Log.Warn().
+			Int64("chat_id", chatID).
+			Str("media_group_id", groupID).
+			Msg("Too many photos in media group; ignoring extra photo")
+		return
+	}
+	if !isNew {
+		return
+	}
+
+	window := b.mediaGroupWait
+	if window <= 0 {
+		window = mediaGroupWindow
+	}
+	flushCtx := context.WithoutCancel(ctx)
+	b.mediaGroups.schedule(key, window, func() {
+		defer b.mediaGroups.done()
+		group, ok := b.mediaGroups.take(key)
+		if !ok {
+			return
+		}
+		logger.Log.Info().
+			Int64("chat_id", chatID).
+			Str("media_group_id", groupID).
+			Int("photo_count", len(group.fileIDs)).
+			Msg("Scanning media group as one receipt")
+		b.processReceiptPhotos(flushCtx, tg, chatID, group.userID, group.messageID, group.fileIDs)
 	})
+}
+
+// processReceiptPhotos sends the "Processing receipt..." placeholder and
+// queues the scan of one receipt on the worker pool. messageID is the photo
+// message the draft is linked to. Without a pool the scan runs right away.
+// Users who turned AI off are asked to type the receipt instead.
+func (b *Bot) processReceiptPhotos(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID, userID int64,
+	messageID int,
+	fileIDs []string,
+) {
+	if !b.aiEnabled(ctx, userID) {
+		b.offerManualReceiptCore(ctx, tg, chatID, userID, messageID, 0)
+		return
+	}
+
+	var placeholderID int
+	if msg, err := tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   receiptProcessingText,
+	}); err == nil && msg != nil {
+		placeholderID = msg.ID
+	}
+	b.queueReceiptScan(ctx, tg, chatID, userID, messageID, placeholderID, fileIDs)
+}
+
+// queueReceiptScan queues the scan of one receipt on the worker pool, or
+// runs it right away without a pool. placeholderID is the message the
+// draft card replaces.
+func (b *Bot) queueReceiptScan(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID, userID int64,
+	messageID, placeholderID int,
+	fileIDs []string,
+) {
+	scan := func(ctx context.Context) {
+		b.scanReceiptPhotos(ctx, tg, chatID, userID, messageID, placeholderID, fileIDs)
+	}
+	if b.receiptWorkers == nil {
+		scan(ctx)
+		return
+	}
+	if err := b.receiptWorkers.enqueue(ctx, userID, scan); err != nil {
+		logger.Log.Warn().Err(err).
+			Int64("chat_id", chatID).
+			Int64("user_id", userID).
+			Msg("Receipt not queued")
+		_, _ = replyToReceipt(ctx, tg, placeholderID, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   receiptNotQueuedText(err),
+		})
+	}
+}
+
+// receiptNotQueuedText explains why a receipt was not queued for scanning.
+func receiptNotQueuedText(err error) string {
+	switch {
+	case errors.Is(err, errReceiptUserBusy):
+		return "⏳ Your earlier receipts are still being read. Please send this one again once they are done."
+	case errors.Is(err, errReceiptPoolClosed):
+		return "⏳ The bot is restarting. Please send the receipt again in a minute."
+	default:
+		return "⏳ Too many receipts are being read right now. Please try again in a minute."
+	}
+}
+
+// scanReceiptPhotos downloads the photos of one receipt, scans them in a
+// single Gemini request and turns the placeholder into the draft
+// confirmation card or an error.
+func (b *Bot) scanReceiptPhotos(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID, userID int64,
+	messageID, placeholderID int,
+	fileIDs []string,
+) {
+	// AI may have been turned off while the scan was queued.
+	if !b.aiEnabled(ctx, userID) {
+		b.offerManualReceiptCore(ctx, tg, chatID, userID, messageID, placeholderID)
+		return
+	}
+
+	images := make([]gemini.ReceiptImage, 0, len(fileIDs))
+	for _, fileID := range fileIDs {
+		imageBytes, ok := b.downloadReceiptPhoto(ctx, tg, chatID, userID, placeholderID, fileID)
+		if !ok {
+			return
+		}
+		images = append(images, gemini.ReceiptImage{Data: imageBytes, MIMEType: "image/jpeg"})
+	}
+
+	receiptData, err := b.geminiClient.ParseReceiptImages(gemini.WithUsageUser(ctx, userID), images)
+	if err != nil {
+		logger.Log.Error().Err(err).
+			Int64("chat_id", chatID).
+			Int64("user_id", userID).
+			Msg("Failed to parse receipt")
+		sendReceiptParseError(ctx, tg, chatID, placeholderID, err)
+		return
+	}
+
+	key := receiptIdempotencyKey(chatID, messageID)
+	expense := b.saveReceiptDraftInto(ctx, tg, chatID, userID, placeholderID, key, fileIDs[0], receiptData)
+	if expense != nil && !expense.Replayed {
+		b.linkSourceMessage(ctx, expense.ID, chatID, messageID)
+		b.storeReceiptImage(ctx, expense, images[0].Data)
+	}
+}
+
+// receiptIdempotencyKey names the receipt sent in a message: for an album,
+// the first photo's message. Scanning the same message again, e.g. when
+// the update is delivered twice, then returns the draft saved before.
+func receiptIdempotencyKey(chatID int64, messageID int) string {
+	return fmt.Sprintf("receipt:%d:%d", chatID, messageID)
+}
+
+// replyToReceipt edits the placeholder into params when there is one, and
+// sends params as a new message when there is not or the edit fails.
+func replyToReceipt(
+	ctx context.Context,
+	tg TelegramAPI,
+	placeholderID int,
+	params *bot.SendMessageParams,
+) (*models.Message, error) {
+	if placeholderID != 0 {
+		msg, err := tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      params.ChatID,
+			MessageID:   placeholderID,
+			Text:        params.Text,
+			ParseMode:   params.ParseMode,
+			ReplyMarkup: params.ReplyMarkup,
+		})
+		if err == nil {
+			return msg, nil
+		}
+		logger.Log.Debug().Err(err).Int("message_id", placeholderID).Msg("Failed to edit receipt placeholder")
+	}
+	msg, err := tg.SendMessage(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send receipt reply: %w", err)
+	}
+	return msg, nil
+}
 
+// downloadReceiptPhoto downloads one receipt photo. On failure the user is
+// told in place of the placeholder and false is returned.
+func (b *Bot) downloadReceiptPhoto(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID, userID int64,
+	placeholderID int,
+	fileID string,
+) ([]byte, bool) {
 	dlCtx, dlSpan := otel.Tracer("expense-bot/telegram").Start(ctx, "telegram.download_file")
-	imageBytes, err := b.downloadFile(dlCtx, tg, largestPhoto.FileID)
+	imageBytes, err := b.downloadFile(dlCtx, tg, fileID)
 	if err != nil {
 		dlSpan.RecordError(err)
 		dlSpan.SetStatus(codes.Error, err.Error())
@@ -85,11 +341,11 @@ func (b *Bot) handlePhotoCore(ctx context.Context, tg TelegramAPI, update *model
 			Int64("chat_id", chatID).
 			Int64("user_id", userID).
 			Msg("Failed to download photo")
-		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		_, _ = replyToReceipt(ctx, tg, placeholderID, &bot.SendMessageParams{
 			ChatID: chatID,
 			Text:   "❌ Failed to download photo. Please try again.",
 		})
-		return
+		return nil, false
 	}
 	dlSpan.End()
 
@@ -99,16 +355,35 @@ func (b *Bot) handlePhotoCore(ctx context.Context, tg TelegramAPI, update *model
 		Int("size_bytes", len(imageBytes)).
 		Msg("Photo downloaded successfully")
 
-	receiptData, err := b.geminiClient.ParseReceipt(ctx, imageBytes, "image/jpeg")
-	if err != nil {
-		logger.Log.Error().Err(err).
-			Int64("chat_id", chatID).
-			Int64("user_id", userID).
-			Msg("Failed to parse receipt")
-		sendReceiptParseError(ctx, tg, chatID, err)
-		return
-	}
+	return imageBytes, true
+}
 
+// saveReceiptDraft stores scanned receipt data as a draft expense and sends
+// the confirmation card. receiptFileID is the photo kept with the expense.
+// It returns the draft, or nil when nothing was saved.
+func (b *Bot) saveReceiptDraft(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID, userID int64,
+	receiptFileID string,
+	receiptData *gemini.ReceiptData,
+) *appmodels.Expense {
+	return b.saveReceiptDraftInto(ctx, tg, chatID, userID, 0, "", receiptFileID, receiptData)
+}
+
+// saveReceiptDraftInto is saveReceiptDraft showing the card, or why nothing
+// was saved, in place of the placeholder message when placeholderID is set.
+// A non-empty idempotencyKey makes the save safe to repeat: a draft saved
+// before under the same key is shown again instead of adding another.
+func (b *Bot) saveReceiptDraftInto(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID, userID int64,
+	placeholderID int,
+	idempotencyKey string,
+	receiptFileID string,
+	receiptData *gemini.ReceiptData,
+) *appmodels.Expense {
 	isPartial := receiptData.IsPartial()
 
 	logger.Log.Info().
@@ -120,57 +395,104 @@ func (b *Bot) handlePhotoCore(ctx context.Context, tg TelegramAPI, update *model
 		Float64("confidence", receiptData.Confidence).
 		Bool("partial", isPartial).
 		Msg("Receipt parsed")
+	logger.Log.Debug().
+		Int64("user_id", userID).
+		Str("model", receiptData.Model).
+		Str("prompt_version", receiptData.PromptVersion).
+		Msg("Receipt parser metadata")
 
-	categories, err := b.getCategoriesWithCache(ctx)
+	categories, err := b.visibleCategories(ctx, userID)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to fetch categories for receipt")
-		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		_, _ = replyToReceipt(ctx, tg, placeholderID, &bot.SendMessageParams{
 			ChatID: chatID,
 			Text:   "❌ Failed to fetch categories. Please try again.",
 		})
-		return
+		return nil
 	}
 	categoryID, category := findCategoryByName(categories, receiptData.SuggestedCategory)
+	var suggestion string
+	if category == nil {
+		suggestion = newCategorySuggestion(receiptData.SuggestedCategory, categories)
+	}
 
 	// Use sensible defaults for partial data.
-	merchant := receiptData.Merchant
+	merchant, merchantTruncated := b.normalizeDescription(receiptData.Merchant)
 	if merchant == "" {
 		merchant = "Unknown merchant"
 	}
-	amount, currency, description := b.convertExpenseCurrency(
-		ctx,
-		userID,
-		receiptData.Amount,
-		receiptData.Currency,
-		merchant,
-	)
-
+	conversion := b.expenses().ConvertToDefault(ctx, userID, receiptChargedAmount(receiptData), receiptData.Currency, merchant)
 	expense := &appmodels.Expense{
-		UserID:        userID,
-		Amount:        amount,
-		Currency:      currency,
-		Description:   description,
-		Merchant:      merchant,
-		CategoryID:    categoryID,
-		Category:      category,
-		ReceiptFileID: largestPhoto.FileID,
-		Status:        appmodels.ExpenseStatusDraft,
-	}
-
-	if err := b.expenseRepo.Create(ctx, expense); err != nil {
+		UserID:         userID,
+		Amount:         conversion.Amount,
+		Currency:       conversion.Currency,
+		Description:    conversion.Description,
+		Merchant:       merchant,
+		CategoryID:     categoryID,
+		Category:       category,
+		ReceiptFileID:  receiptFileID,
+		IdempotencyKey: idempotencyKey,
+	}
+	conversion.Record(expense)
+
+	created, err := b.expenses().CreateDraftFromReceipt(ctx, expense)
+	if errors.Is(err, service.ErrAboveHardCap) {
+		_, _ = replyToReceipt(ctx, tg, placeholderID, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      b.amountAboveHardCapMsg(conversion.Amount, conversion.Currency),
+			ParseMode: models.ParseModeHTML,
+		})
+		return nil
+	}
+	if err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to create draft expense")
-		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		_, _ = replyToReceipt(ctx, tg, placeholderID, &bot.SendMessageParams{
 			ChatID: chatID,
 			Text:   failedSaveExpenseMsg,
 		})
-		return
+		return nil
+	}
+	itemsSum, itemsMismatch := receiptItemsMismatch(receiptData, expense.Amount, conversion.Rate)
+	if !expense.Replayed {
+		var offered decimal.NullDecimal
+		if itemsMismatch {
+			offered = decimal.NewNullDecimal(itemsSum)
+		}
+		b.recordReceiptScan(ctx, expense, receiptData, suggestion, offered)
 	}
 
-	text := buildReceiptConfirmationText(expense, receiptData.Date, isPartial)
+	text := botfmt.ReceiptScannedCard(expense, receiptData.Date, isPartial)
+	if breakdown := receiptBreakdownLine(receiptData, expense.Currency); breakdown != "" {
+		text += "\n\n" + breakdown
+	}
+	if receiptData.ImageCount > 1 {
+		text += "\n\n" + botfmt.ReceiptImageCountNote(receiptData.ImageCount)
+	}
+	if merchantTruncated {
+		text += "\n\n" + b.descriptionTruncatedNote()
+	}
+	if created.NeedsConfirmation {
+		text += "\n\n" + botfmt.LargeAmountWarning(expense)
+	}
+	if suggestion != "" {
+		text += "\n\n" + suggestedCategoryHint(suggestion)
+	}
+	if itemsMismatch {
+		text += "\n\n" + receiptItemsMismatchLine(expense.Amount, itemsSum, expense.Currency)
+	}
 
-	keyboard := buildReceiptConfirmationKeyboard(expense.ID)
+	keyboard := buildReceiptConfirmationKeyboard(expense.ID, expense.Currency)
+	switch {
+	case itemsMismatch:
+		keyboard = buildReceiptItemsKeyboard(expense, itemsSum)
+	case receiptHasOnlySubtotal(receiptData):
+		keyboard = buildReceiptTipKeyboard(expense.ID, expense.Currency)
+	}
+	if suggestion != "" {
+		keyboard = withSuggestedCategoryButtons(keyboard, expense.ID)
+	}
 
-	msg, err := tg.SendMessage(ctx, &bot.SendMessageParams{
+	msg, err := replyToReceipt(ctx, tg, placeholderID, &bot.SendMessageParams{
 		ChatID:      chatID,
 		Text:        text,
 		ParseMode:   models.ParseModeHTML,
@@ -178,7 +500,7 @@ func (b *Bot) handlePhotoCore(ctx context.Context, tg TelegramAPI, update *model
 	})
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to send receipt confirmation")
-		return
+		return expense
 	}
 
 	logger.Log.Debug().
@@ -187,14 +509,44 @@ func (b *Bot) handlePhotoCore(ctx context.Context, tg TelegramAPI, update *model
 		Int("message_id", msg.ID).
 		Bool("partial", isPartial).
 		Msg("Receipt confirmation sent with inline keyboard")
+
+	return expense
+}
+
+// recordReceiptScan stores which model and prompt produced a receipt draft,
+// the draft's scanned values, and the new category and items sum offered on
+// it, if any. Failures are logged only; the draft is still usable.
+func (b *Bot) recordReceiptScan(
+	ctx context.Context,
+	expense *appmodels.Expense,
+	data *gemini.ReceiptData,
+	suggestion string,
+	itemsSum decimal.NullDecimal,
+) {
+	err := b.receiptScanRepo.Record(ctx, repository.ReceiptScan{
+		ExpenseID:         expense.ID,
+		UserID:            expense.UserID,
+		Model:             data.Model,
+		PromptVersion:     data.PromptVersion,
+		PromptVariant:     data.PromptVariant,
+		SuggestedCategory: suggestion,
+		Amount:            expense.Amount,
+		Currency:          expense.Currency,
+		Merchant:          expense.Merchant,
+		ValuesRecorded:    true,
+		ItemsSum:          itemsSum,
+	})
+	if err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expense.ID).Msg("Failed to record receipt scan")
+	}
 }
 
-func sendReceiptParseError(ctx context.Context, tg TelegramAPI, chatID int64, err error) {
+func sendReceiptParseError(ctx context.Context, tg TelegramAPI, chatID int64, placeholderID int, err error) {
 	text := "❌ Could not read this receipt. Please add manually: <code>/add &lt;amount&gt; &lt;description&gt;</code>"
 	if errors.Is(err, gemini.ErrParseTimeout) {
 		text = "⏱️ Receipt processing timed out. Please try again or add manually: <code>/add &lt;amount&gt; &lt;description&gt;</code>"
 	}
-	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+	_, _ = replyToReceipt(ctx, tg, placeholderID, &bot.SendMessageParams{
 		ChatID:    chatID,
 		Text:      text,
 		ParseMode: models.ParseModeHTML,
@@ -213,51 +565,6 @@ func findCategoryByName(
 	return nil, nil
 }
 
-func buildReceiptConfirmationText(
-	expense *appmodels.Expense,
-	receiptDate time.Time,
-	isPartial bool,
-) string {
-	categoryText := categoryUncategorized
-	if expense.Category != nil {
-		categoryText = escapeHTML(expense.Category.Name)
-	}
-	dateText := "Unknown"
-	if !receiptDate.IsZero() {
-		dateText = receiptDate.Format("02 Jan 2006")
-	}
-	currencySymbol := getCurrencyOrCodeSymbol(expense.Currency)
-	if isPartial {
-		return fmt.Sprintf(`⚠️ <b>Partial Extraction - Please Verify</b>
-
-💰 Amount: %s%s %s
-🏪 Merchant: %s
-📅 Date: %s
-📁 Category: %s
-
-<i>Some data could not be extracted. Please edit or confirm.</i>`,
-			currencySymbol,
-			expense.Amount.StringFixed(2),
-			expense.Currency,
-			escapeHTML(expense.Merchant),
-			dateText,
-			categoryText)
-	}
-
-	return fmt.Sprintf(`📸 <b>Receipt Scanned!</b>
-
-💰 Amount: %s%s %s
-🏪 Merchant: %s
-📅 Date: %s
-📁 Category: %s`,
-		currencySymbol,
-		expense.Amount.StringFixed(2),
-		expense.Currency,
-		escapeHTML(expense.Merchant),
-		dateText,
-		categoryText)
-}
-
 // handleReceiptCallback handles receipt confirmation button presses.
 func (b *Bot) handleReceiptCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
 	b.handleReceiptCallbackCore(ctx, tgBot, update)
@@ -279,13 +586,10 @@ func (b *Bot) handleReceiptCallbackCore(ctx context.Context, tg TelegramAPI, upd
 		Int64("user_id", userID).
 		Msg("Processing receipt callback")
 
-	_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
-		CallbackQueryID: update.CallbackQuery.ID,
-	})
-
 	parts := strings.Split(data, "_")
 	if len(parts) < 3 {
 		logger.Log.Error().Str("data", data).Msg("Invalid callback data format")
+		answerCallback(ctx, tg, update.CallbackQuery)
 		return
 	}
 
@@ -293,25 +597,30 @@ func (b *Bot) handleReceiptCallbackCore(ctx context.Context, tg TelegramAPI, upd
 	expenseID, err := strconv.Atoi(parts[2])
 	if err != nil {
 		logger.Log.Error().Err(err).Str("data", data).Msg("Failed to parse expense ID")
+		answerCallback(ctx, tg, update.CallbackQuery)
 		return
 	}
 
-	expense, err := b.expenseRepo.GetByID(ctx, expenseID)
-	if err != nil {
-		logger.Log.Error().Err(err).Int("expense_id", expenseID).Msg("Expense not found")
-		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
-			ChatID:    chatID,
-			MessageID: messageID,
-			Text:      "❌ This receipt draft is no longer available — it may have expired. Please send the receipt photo again to rescan it.",
-		})
+	destructive := action == "cancel"
+	expense, ok := b.loadCallbackExpense(ctx, tg, update.CallbackQuery, expenseID, destructive)
+	if !ok {
 		return
 	}
 
-	if expense.UserID != userID {
-		logger.Log.Warn().Int64("user_id", userID).Int("expense_id", expenseID).Msg("User mismatch")
+	// Confirm, cancel, adding a tip, picking tags, the amount or the currency
+	// only apply to drafts. Once confirmed, the expense has its own action
+	// buttons, so the old receipt card is retired.
+	isDraft := expense.Status == appmodels.ExpenseStatusDraft
+	draftOnly := action == "confirm" || action == "cancel" || action == receiptTipAction ||
+		action == receiptTagsAction || action == receiptTotalAction || action == receiptItemsAction ||
+		action == receiptCurrencyAction || action == receiptConvertAction
+	if !isDraft && draftOnly {
+		respondStaleCallback(ctx, tg, update.CallbackQuery, staleExpenseConfirmed, destructive)
 		return
 	}
 
+	answerCallback(ctx, tg, update.CallbackQuery)
+
 	switch action {
 	case "confirm":
 		b.handleConfirmReceiptCore(ctx, tg, chatID, messageID, expense)
@@ -319,7 +628,21 @@ func (b *Bot) handleReceiptCallbackCore(ctx context.Context, tg TelegramAPI, upd
 		b.handleCancelReceiptCore(ctx, tg, chatID, messageID, expense)
 	case editAction:
 		b.handleEditReceiptCore(ctx, tg, chatID, messageID, expense)
+	case receiptTipAction:
+		b.promptReceiptTipCore(ctx, tg, chatID, messageID, expense)
+	case receiptTagsAction:
+		b.showReceiptTagsCore(ctx, tg, chatID, messageID, expense)
+	case receiptTotalAction, receiptItemsAction:
+		b.pickReceiptAmountCore(ctx, tg, chatID, messageID, expense, action)
+	case receiptCurrencyAction:
+		b.pickReceiptCurrencyCore(ctx, tg, chatID, messageID, expense, parts)
+	case receiptConvertAction:
+		b.convertReceiptAmountCore(ctx, tg, chatID, messageID, expense, parts)
 	case "back":
+		if !isDraft {
+			b.editToConfirmation(ctx, tg, chatID, messageID, expense)
+			return
+		}
 		b.handleBackToReceiptCore(ctx, tg, chatID, messageID, expense)
 	}
 }
@@ -332,28 +655,12 @@ func (b *Bot) handleBackToReceiptCore(
 	messageID int,
 	expense *appmodels.Expense,
 ) {
-	categoryText := categoryUncategorized
-	if expense.Category != nil {
-		categoryText = escapeHTML(expense.Category.Name)
-	} else if expense.CategoryID != nil {
-		cat, err := b.categoryRepo.GetByID(ctx, *expense.CategoryID)
-		if err == nil {
-			categoryText = escapeHTML(cat.Name)
-		}
-	}
-
-	text := fmt.Sprintf(`📸 <b>Receipt Scanned!</b>
+	b.loadExpenseCategory(ctx, expense)
+	b.loadExpenseTags(ctx, expense)
 
-💰 Amount: %s%s %s
-🏪 Merchant: %s
-📁 Category: %s`,
-		getCurrencyOrCodeSymbol(expense.Currency),
-		expense.Amount.StringFixed(2),
-		expense.Currency,
-		escapeHTML(expense.Merchant),
-		categoryText)
+	text := botfmt.ReceiptDraftCard(expense, botfmt.DraftUnchanged)
 
-	keyboard := buildReceiptConfirmationKeyboard(expense.ID)
+	keyboard := buildReceiptConfirmationKeyboard(expense.ID, expense.Currency)
 
 	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      chatID,
@@ -364,7 +671,9 @@ func (b *Bot) handleBackToReceiptCore(
 	})
 }
 
-// handleConfirmReceiptCore confirms a draft expense.
+// handleConfirmReceiptCore confirms a draft expense. A draft that would go
+// over a hard category budget stays a draft, and the user is offered to
+// confirm it as Uncategorized instead.
 func (b *Bot) handleConfirmReceiptCore(
 	ctx context.Context,
 	tg TelegramAPI,
@@ -372,8 +681,18 @@ func (b *Bot) handleConfirmReceiptCore(
 	messageID int,
 	expense *appmodels.Expense,
 ) {
-	expense.Status = appmodels.ExpenseStatusConfirmed
-	if err := b.expenseRepo.Update(ctx, expense); err != nil {
+	if budget := b.expenses().BlockingBudget(ctx, expense); budget != nil {
+		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      chatID,
+			MessageID:   messageID,
+			Text:        budgetBlockedText(budget, expense),
+			ParseMode:   models.ParseModeHTML,
+			ReplyMarkup: buildBudgetBlockedDraftKeyboard(expense.ID),
+		})
+		return
+	}
+
+	if _, err := b.expenses().Confirm(ctx, expense); err != nil {
 		logger.Log.Error().Err(err).Int("expense_id", expense.ID).Msg("Failed to confirm expense")
 		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
 			ChatID:    chatID,
@@ -383,37 +702,12 @@ func (b *Bot) handleConfirmReceiptCore(
 		return
 	}
 
-	categoryText := categoryUncategorized
-	if expense.Category != nil {
-		categoryText = escapeHTML(expense.Category.Name)
-	} else if expense.CategoryID != nil {
-		cat, err := b.categoryRepo.GetByID(ctx, *expense.CategoryID)
-		if err == nil {
-			categoryText = escapeHTML(cat.Name)
-		}
-	}
+	b.loadExpenseCategory(ctx, expense)
 
-	currencyCode := expense.Currency
-	if currencyCode == "" {
-		currencyCode = appmodels.DefaultCurrency
+	text := botfmt.ExpenseConfirmedCard(expense, b.displayLocation)
+	if defaults := b.applyCategoryDefaultTags(ctx, expense); len(defaults) > 0 {
+		text += "\n🏷️ " + describeParsedTags(defaults)
 	}
-	currencySymbol := getCurrencyOrCodeSymbol(currencyCode)
-
-	text := fmt.Sprintf(`✅ <b>Expense Confirmed!</b>
-
-💰 Amount: %s%s %s
-🏪 Merchant: %s
-📁 Category: %s
-🗓️ Date: %s
-
-Expense #%d has been saved.`,
-		currencySymbol,
-		expense.Amount.StringFixed(2),
-		currencyCode,
-		escapeHTML(expense.Merchant),
-		categoryText,
-		expense.CreatedAt.In(b.displayLocation).Format("02 Jan 2006"),
-		expense.UserExpenseNumber)
 
 	logger.Log.Info().
 		Int("expense_id", expense.ID).
@@ -437,7 +731,7 @@ func (b *Bot) handleCancelReceiptCore(
 	messageID int,
 	expense *appmodels.Expense,
 ) {
-	if err := b.expenseRepo.Delete(ctx, expense.ID); err != nil {
+	if err := b.expenses().Delete(ctx, expense.ID); err != nil {
 		logger.Log.Error().Err(err).Int("expense_id", expense.ID).Msg("Failed to delete expense")
 		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
 			ChatID:    chatID,
@@ -474,6 +768,10 @@ func (b *Bot) handleEditReceiptCore(
 			},
 			{
 				{Text: "📁 Edit Category", CallbackData: fmt.Sprintf("edit_category_%d", expense.ID)},
+				{Text: receiptTagsButtonText, CallbackData: fmt.Sprintf(receiptTagsCallbackFmt, expense.ID)},
+			},
+			{
+				{Text: receiptCurrencyButtonText, CallbackData: fmt.Sprintf(receiptCurrencyCallbackFmt, expense.ID)},
 			},
 			{
 				{Text: "⬅️ Back", CallbackData: fmt.Sprintf("receipt_back_%d", expense.ID)},
@@ -481,28 +779,10 @@ func (b *Bot) handleEditReceiptCore(
 		},
 	}
 
-	categoryText := categoryUncategorized
-	if expense.Category != nil {
-		categoryText = escapeHTML(expense.Category.Name)
-	} else if expense.CategoryID != nil {
-		cat, err := b.categoryRepo.GetByID(ctx, *expense.CategoryID)
-		if err == nil {
-			categoryText = escapeHTML(cat.Name)
-		}
-	}
-
-	text := fmt.Sprintf(`✏️ <b>Edit Expense</b>
-
-💰 Amount: %s%s %s
-🏪 Merchant: %s
-📁 Category: %s
+	b.loadExpenseCategory(ctx, expense)
+	b.loadExpenseTags(ctx, expense)
 
-Select what to edit:`,
-		getCurrencyOrCodeSymbol(expense.Currency),
-		expense.Amount.StringFixed(2),
-		expense.Currency,
-		escapeHTML(expense.Merchant),
-		categoryText)
+	text := botfmt.ReceiptDraftCard(expense, botfmt.DraftEditMenu)
 
 	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      chatID,
@@ -0,0 +1,154 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	adjustAmountCallbackPrefix = "adjust_amount_"
+	adjustAmountCallbackFmt    = "adjust_amount_%d_%d"
+	adjustAmountTooLowText     = "The amount has to stay above zero."
+)
+
+// receiptAmountAdjustments are the amount tweaks offered on a draft card,
+// in hundredths of the draft's currency. Callbacks carrying any other
+// delta are refused.
+var receiptAmountAdjustments = []struct {
+	label string
+	cents int
+}{
+	{"+0.10", 10},
+	{"-0.10", -10},
+	{"+1", 100},
+	{"-1", -100},
+}
+
+// receiptAdjustmentFits reports whether a delta in hundredths can be
+// written in currency, so +0.10 fits SGD and KWD but not JPY.
+func receiptAdjustmentFits(cents int, currency string) bool {
+	delta := decimal.New(int64(cents), -2)
+	return delta.Equal(delta.Truncate(appmodels.CurrencyMinorUnits(currency)))
+}
+
+// buildReceiptAdjustRow returns the row of amount tweaks for a draft card,
+// leaving out the ones finer than the currency's minor unit.
+func buildReceiptAdjustRow(expenseID int, currency string) []models.InlineKeyboardButton {
+	row := make([]models.InlineKeyboardButton, 0, len(receiptAmountAdjustments))
+	for _, adjustment := range receiptAmountAdjustments {
+		if !receiptAdjustmentFits(adjustment.cents, currency) {
+			continue
+		}
+		row = append(row, models.InlineKeyboardButton{
+			Text:         adjustment.label,
+			CallbackData: fmt.Sprintf(adjustAmountCallbackFmt, expenseID, adjustment.cents),
+		})
+	}
+	return row
+}
+
+// parseAdjustAmountCallback reads the expense ID and delta in cents from
+// adjust_amount_<id>_<cents>. Only the offered deltas are accepted.
+func parseAdjustAmountCallback(data string) (int, int, bool) {
+	idText, centsText, ok := strings.Cut(strings.TrimPrefix(data, adjustAmountCallbackPrefix), "_")
+	if !ok {
+		return 0, 0, false
+	}
+	expenseID, err := strconv.Atoi(idText)
+	if err != nil || expenseID <= 0 {
+		return 0, 0, false
+	}
+	cents, err := strconv.Atoi(centsText)
+	if err != nil {
+		return 0, 0, false
+	}
+	for _, adjustment := range receiptAmountAdjustments {
+		if adjustment.cents == cents {
+			return expenseID, cents, true
+		}
+	}
+	return 0, 0, false
+}
+
+func (b *Bot) handleAdjustAmountCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleAdjustAmountCallbackCore(ctx, tgBot, update)
+}
+
+// handleAdjustAmountCallbackCore adds the tapped delta to a draft's amount
+// and refreshes the card with the keyboard it had. Taps accumulate, since
+// each one starts from the stored amount, and the amount never drops to
+// zero or below. Deltas finer than the draft's currency allows, left on
+// a card whose currency changed since, are ignored.
+func (b *Bot) handleAdjustAmountCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	expenseID, cents, ok := parseAdjustAmountCallback(query.Data)
+	if !ok {
+		logger.Log.Warn().Str("data", query.Data).Msg("Invalid amount adjustment callback")
+		answerCallback(ctx, tg, query)
+		return
+	}
+
+	expense, ok := b.loadCallbackExpense(ctx, tg, query, expenseID, false)
+	if !ok {
+		return
+	}
+	if expense.Status != appmodels.ExpenseStatusDraft {
+		respondStaleCallback(ctx, tg, query, staleExpenseConfirmed, false)
+		return
+	}
+	if !receiptAdjustmentFits(cents, expense.Currency) {
+		answerCallback(ctx, tg, query)
+		return
+	}
+
+	amount := expense.Amount.Add(decimal.New(int64(cents), -2))
+	if !amount.IsPositive() {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            adjustAmountTooLowText,
+		})
+		return
+	}
+	answerCallback(ctx, tg, query)
+
+	b.loadExpenseCategory(ctx, expense)
+	b.loadExpenseTags(ctx, expense)
+	before := *expense
+	expense.Amount = amount
+	if err := b.expenses().EditFields(ctx, expense); err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to adjust amount")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: query.Message.Message.Chat.ID,
+			Text:   "❌ Failed to update amount. Please try again.",
+		})
+		return
+	}
+
+	logger.Log.Info().
+		Int(logFieldExpenseIDCB, expense.ID).
+		Int("delta_cents", cents).
+		Str("new_amount", amount.String()).
+		Msg("Amount adjusted via button")
+	b.auditExpenseUpdate(ctx, tg, query.From.ID, before, expense)
+
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      query.Message.Message.Chat.ID,
+		MessageID:   query.Message.Message.ID,
+		Text:        botfmt.ReceiptDraftEditCard(&before, expense, botfmt.DraftAmountUpdated),
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: rebuildReceiptKeyboard(expense, query.Message.Message.ReplyMarkup),
+	})
+}
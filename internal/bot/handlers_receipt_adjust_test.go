@@ -0,0 +1,205 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestBuildReceiptAdjustRow(t *testing.T) {
+	t.Parallel()
+
+	row := buildReceiptAdjustRow(7, currencyCodeSGD)
+	require.Len(t, row, 4)
+	require.Equal(t, "+0.10", row[0].Text)
+	require.Equal(t, "adjust_amount_7_10", row[0].CallbackData)
+	require.Equal(t, "-0.10", row[1].Text)
+	require.Equal(t, "adjust_amount_7_-10", row[1].CallbackData)
+	require.Equal(t, "+1", row[2].Text)
+	require.Equal(t, "adjust_amount_7_100", row[2].CallbackData)
+	require.Equal(t, "-1", row[3].Text)
+	require.Equal(t, "adjust_amount_7_-100", row[3].CallbackData)
+
+	require.Equal(t, row, buildReceiptAdjustRow(7, "KWD"))
+
+	row = buildReceiptAdjustRow(7, "JPY")
+	require.Len(t, row, 2, "JPY has no tenths")
+	require.Equal(t, "adjust_amount_7_100", row[0].CallbackData)
+	require.Equal(t, "adjust_amount_7_-100", row[1].CallbackData)
+}
+
+func TestRebuildReceiptKeyboard(t *testing.T) {
+	t.Parallel()
+
+	expense := &appmodels.Expense{ID: 7, Currency: currencyCodeSGD}
+	require.Equal(t, buildReceiptConfirmationKeyboard(7, currencyCodeSGD), rebuildReceiptKeyboard(expense, nil))
+
+	tip := buildReceiptTipKeyboard(7, currencyCodeSGD)
+	require.Equal(t, tip, rebuildReceiptKeyboard(expense, tip))
+
+	suggested := withSuggestedCategoryButtons(buildReceiptTipKeyboard(7, currencyCodeSGD), 7)
+	require.Equal(t, suggested, rebuildReceiptKeyboard(expense, suggested))
+
+	items := buildReceiptItemsKeyboard(&appmodels.Expense{ID: 7, Amount: mustParseDecimal("12.00"),
+		Currency: currencyCodeSGD}, mustParseDecimal("10.00"))
+	require.Equal(t, items, rebuildReceiptKeyboard(expense, items))
+
+	other := buildReceiptTipKeyboard(8, currencyCodeSGD)
+	require.Equal(t, buildReceiptConfirmationKeyboard(7, currencyCodeSGD), rebuildReceiptKeyboard(expense, other))
+}
+
+func TestParseAdjustAmountCallback(t *testing.T) {
+	t.Parallel()
+
+	expenseID, cents, ok := parseAdjustAmountCallback("adjust_amount_7_-100")
+	require.True(t, ok)
+	require.Equal(t, 7, expenseID)
+	require.Equal(t, -100, cents)
+
+	for _, data := range []string{
+		"adjust_amount_7",
+		"adjust_amount_7_",
+		"adjust_amount_x_10",
+		"adjust_amount_0_10",
+		"adjust_amount_-7_10",
+		"adjust_amount_7_1.5",
+		"adjust_amount_7_1000000",
+		"adjust_amount_7_10_10",
+	} {
+		_, _, ok := parseAdjustAmountCallback(data)
+		require.False(t, ok, data)
+	}
+}
+
+func TestAdjustAmountCallback(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(441001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Adjuster"}))
+
+	draftIn := func(t *testing.T, mockBot *mocks.MockBot, amount, currency string) *appmodels.Expense {
+		t.Helper()
+		expense := b.saveReceiptDraft(ctx, mockBot, userID, userID, "", &gemini.ReceiptData{
+			Amount:   mustParseDecimal(amount),
+			Currency: currency,
+			Merchant: "Hawker",
+		})
+		require.NotNil(t, expense)
+		return expense
+	}
+	draft := func(t *testing.T, mockBot *mocks.MockBot, amount string) *appmodels.Expense {
+		t.Helper()
+		return draftIn(t, mockBot, amount, currencyCodeSGD)
+	}
+	tap := func(mockBot *mocks.MockBot, fromID int64, expenseID, cents int) {
+		data := fmt.Sprintf(adjustAmountCallbackFmt, expenseID, cents)
+		b.handleAdjustAmountCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(fromID, fromID, 5, data))
+	}
+	stored := func(t *testing.T, expenseID int) *appmodels.Expense {
+		t.Helper()
+		expense, err := b.expenseRepo.GetByID(ctx, expenseID)
+		require.NoError(t, err)
+		return expense
+	}
+
+	t.Run("taps accumulate", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := draft(t, mockBot, "20.00")
+
+		tap(mockBot, userID, expense.ID, 100)
+		tap(mockBot, userID, expense.ID, 10)
+		tap(mockBot, userID, expense.ID, 10)
+		tap(mockBot, userID, expense.ID, -10)
+		require.True(t, mustParseDecimal("21.10").Equal(stored(t, expense.ID).Amount))
+
+		edited := mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, "21.10")
+		keyboard := requireInlineKeyboard(t, edited.ReplyMarkup)
+		require.Equal(t, buildReceiptAdjustRow(expense.ID, currencyCodeSGD), keyboard.InlineKeyboard[1])
+		require.Equal(t, 4, mockBot.AnsweredCallbackCount())
+	})
+
+	t.Run("the tip row stays on the card", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := draft(t, mockBot, "20.00")
+
+		update := mocks.CallbackQueryUpdate(userID, userID, 5, fmt.Sprintf(adjustAmountCallbackFmt, expense.ID, 100))
+		update.CallbackQuery.Message.Message.ReplyMarkup = buildReceiptTipKeyboard(expense.ID, expense.Currency)
+		b.handleAdjustAmountCallbackCore(ctx, mockBot, update)
+
+		keyboard := requireInlineKeyboard(t, mockBot.LastEditedMessage().ReplyMarkup)
+		require.Equal(t, buildReceiptTipKeyboard(expense.ID, expense.Currency), keyboard)
+	})
+
+	t.Run("tenths are not offered or applied in JPY", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := draftIn(t, mockBot, "1200", "JPY")
+
+		tap(mockBot, userID, expense.ID, 10)
+		require.True(t, mustParseDecimal("1200").Equal(stored(t, expense.ID).Amount))
+		require.Zero(t, mockBot.EditedMessageCount())
+
+		tap(mockBot, userID, expense.ID, 100)
+		require.True(t, mustParseDecimal("1201").Equal(stored(t, expense.ID).Amount))
+		keyboard := requireInlineKeyboard(t, mockBot.LastEditedMessage().ReplyMarkup)
+		require.Equal(t, buildReceiptAdjustRow(expense.ID, "JPY"), keyboard.InlineKeyboard[1])
+	})
+
+	t.Run("amount stays above zero", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := draft(t, mockBot, "0.50")
+
+		tap(mockBot, userID, expense.ID, -100)
+		require.True(t, mustParseDecimal("0.50").Equal(stored(t, expense.ID).Amount))
+		require.Zero(t, mockBot.EditedMessageCount())
+		require.Len(t, mockBot.AnsweredCallbacks, 1)
+		require.Equal(t, adjustAmountTooLowText, mockBot.AnsweredCallbacks[0].Text)
+
+		for range 4 {
+			tap(mockBot, userID, expense.ID, -10)
+		}
+		require.True(t, mustParseDecimal("0.10").Equal(stored(t, expense.ID).Amount))
+		tap(mockBot, userID, expense.ID, -10)
+		require.True(t, mustParseDecimal("0.10").Equal(stored(t, expense.ID).Amount))
+	})
+
+	t.Run("confirm saves the adjusted amount", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := draft(t, mockBot, "12.00")
+
+		tap(mockBot, userID, expense.ID, -100)
+		tap(mockBot, userID, expense.ID, -10)
+		confirm := fmt.Sprintf("receipt_confirm_%d", expense.ID)
+		b.handleReceiptCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 5, confirm))
+
+		saved := stored(t, expense.ID)
+		require.Equal(t, appmodels.ExpenseStatusConfirmed, saved.Status)
+		require.True(t, mustParseDecimal("10.90").Equal(saved.Amount))
+
+		edited := mockBot.LastEditedMessage()
+		if edited.ReplyMarkup != nil {
+			for _, row := range requireInlineKeyboard(t, edited.ReplyMarkup).InlineKeyboard {
+				require.NotEqual(t, buildReceiptAdjustRow(expense.ID, currencyCodeSGD), row, "adjustments end with the draft")
+			}
+		}
+
+		tap(mockBot, userID, expense.ID, 100)
+		require.True(t, mustParseDecimal("10.90").Equal(stored(t, expense.ID).Amount))
+		require.Equal(t, staleExpenseConfirmedText, mockBot.AnsweredCallbacks[len(mockBot.AnsweredCallbacks)-1].Text)
+	})
+
+	t.Run("another user's draft is left alone", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := draft(t, mockBot, "8.00")
+
+		tap(mockBot, userID+1, expense.ID, 100)
+		require.True(t, mustParseDecimal("8.00").Equal(stored(t, expense.ID).Amount))
+	})
+}
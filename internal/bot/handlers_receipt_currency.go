@@ -0,0 +1,371 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/service"
+)
+
+const (
+	editTypeCurrencyCB          = "currency"
+	receiptCurrencyAction       = "currency"
+	receiptConvertAction        = "currconv"
+	receiptCurrencyOther        = "other"
+	receiptCurrencyCallbackFmt  = "receipt_" + receiptCurrencyAction + "_%d"
+	receiptCurrencyPickFmt      = "receipt_" + receiptCurrencyAction + "_%d_%s"
+	receiptConvertCallbackFmt   = "receipt_" + receiptConvertAction + "_%d_%s_%s"
+	receiptCurrencyButtonText   = "💱 Currency"
+	receiptCurrencyOtherText    = "Other…"
+	receiptCurrencyButtonsInRow = 4
+
+	receiptCurrencyFailedMsg = "❌ Failed to update currency. Please try again."
+)
+
+// receiptCommonCurrencies are the currencies offered as buttons when
+// changing a receipt draft's currency, after the user's default currency.
+// The rest of appmodels.SupportedCurrencies can be typed in.
+var receiptCommonCurrencies = []string{"SGD", "USD", "EUR", "GBP", "JPY", "MYR", "THB", "AUD"}
+
+// supportedCurrencyCodes returns the supported currency codes sorted
+// alphabetically.
+func supportedCurrencyCodes() []string {
+	codes := make([]string, 0, len(appmodels.SupportedCurrencies))
+	for code := range appmodels.SupportedCurrencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// buildReceiptCurrencyKeyboard lists the user's default currency and the
+// common currencies, marking the draft's current one, followed by a row to
+// type another code and to go back to the edit menu.
+func buildReceiptCurrencyKeyboard(expenseID int, current, defaultCurrency string) *models.InlineKeyboardMarkup {
+	codes := make([]string, 0, len(receiptCommonCurrencies)+1)
+	if _, ok := appmodels.SupportedCurrencies[defaultCurrency]; ok {
+		codes = append(codes, defaultCurrency)
+	}
+	for _, code := range receiptCommonCurrencies {
+		if code != defaultCurrency {
+			codes = append(codes, code)
+		}
+	}
+
+	var rows [][]models.InlineKeyboardButton
+	row := make([]models.InlineKeyboardButton, 0, receiptCurrencyButtonsInRow)
+	for _, code := range codes {
+		text := code
+		if code == current {
+			text = "✓ " + text
+		}
+		row = append(row, models.InlineKeyboardButton{
+			Text:         text,
+			CallbackData: fmt.Sprintf(receiptCurrencyPickFmt, expenseID, code),
+		})
+		if len(row) == receiptCurrencyButtonsInRow {
+			rows = append(rows, row)
+			row = make([]models.InlineKeyboardButton, 0, receiptCurrencyButtonsInRow)
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, []models.InlineKeyboardButton{
+		{Text: receiptCurrencyOtherText, CallbackData: fmt.Sprintf(receiptCurrencyPickFmt, expenseID, receiptCurrencyOther)},
+		{Text: backButtonTextCB, CallbackData: fmt.Sprintf("receipt_%s_%d", editAction, expenseID)},
+	})
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// pickReceiptCurrencyCore handles the currency buttons of a receipt draft.
+// Without a code in parts[3] of the callback data it shows the currency
+// picker; "other" asks for a typed code and anything else is applied.
+func (b *Bot) pickReceiptCurrencyCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	messageID int,
+	expense *appmodels.Expense,
+	parts []string,
+) {
+	if len(parts) < 4 {
+		b.loadExpenseCategory(ctx, expense)
+		b.loadExpenseTags(ctx, expense)
+		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+			Text:      botfmt.ReceiptDraftCard(expense, botfmt.DraftCurrencyMenu),
+			ParseMode: models.ParseModeHTML,
+			ReplyMarkup: buildReceiptCurrencyKeyboard(
+				expense.ID, expense.Currency, b.getUserDefaultCurrency(ctx, expense.UserID),
+			),
+		})
+		return
+	}
+
+	if parts[3] == receiptCurrencyOther {
+		b.promptReceiptCurrencyCore(ctx, tg, chatID, messageID, expense)
+		return
+	}
+	code := service.NormalizeCurrencyCode(parts[3])
+	if _, ok := appmodels.SupportedCurrencies[code]; !ok {
+		logger.Log.Error().Str("currency", code).Int(logFieldExpenseIDCB, expense.ID).Msg("Invalid receipt currency")
+		return
+	}
+	b.applyReceiptCurrency(ctx, tg, chatID, messageID, expense, code)
+}
+
+// promptReceiptCurrencyCore asks for the code of a currency that is not on
+// the picker.
+func (b *Bot) promptReceiptCurrencyCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	messageID int,
+	expense *appmodels.Expense,
+) {
+	b.pendingEditsMu.Lock()
+	b.pendingEdits[chatID] = &pendingEdit{
+		ExpenseID: expense.ID,
+		EditType:  editTypeCurrencyCB,
+		MessageID: messageID,
+	}
+	b.pendingEditsMu.Unlock()
+
+	text := fmt.Sprintf(`💱 <b>Other Currency</b>
+
+Current: %s
+
+Please type the currency code (e.g., <code>CNY</code>):`,
+		botfmt.Money(expense.Amount, expense.Currency))
+
+	keyboard := &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{Text: editCancelText, CallbackData: fmt.Sprintf(cancelEditCallback, expense.ID)},
+			},
+		},
+	}
+
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   messageID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: keyboard,
+	})
+}
+
+// processCurrencyEditCore applies a typed currency code to a receipt draft.
+// A draft confirmed while the code was being typed is left alone.
+func (b *Bot) processCurrencyEditCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	userID int64,
+	pending *pendingEdit,
+	input string,
+) bool {
+	b.pendingEditsMu.Lock()
+	delete(b.pendingEdits, chatID)
+	b.pendingEditsMu.Unlock()
+
+	code := service.NormalizeCurrencyCode(input)
+	if _, ok := appmodels.SupportedCurrencies[code]; !ok {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text: fmt.Sprintf("❌ Unknown currency: <code>%s</code>\n\nSupported: %s",
+				botfmt.EscapeHTML(echoText(code)), strings.Join(supportedCurrencyCodes(), ", ")),
+			ParseMode: models.ParseModeHTML,
+		})
+		return true
+	}
+
+	expense, err := b.expenseRepo.GetByID(ctx, pending.ExpenseID)
+	if err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, pending.ExpenseID).Msg(expenseNotFoundForEditLogMsgCB)
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   expenseNotFoundMsgCB,
+		})
+		return true
+	}
+
+	if expense.UserID != userID {
+		logger.Log.Warn().
+			Str(logFieldUserHashCB, logger.HashUserID(userID)).
+			Int(logFieldExpenseIDCB, pending.ExpenseID).
+			Msg(userMismatchOnEditMsgCB)
+		return true
+	}
+	if expense.Status != appmodels.ExpenseStatusDraft {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   staleExpenseConfirmedText,
+		})
+		return true
+	}
+
+	b.applyReceiptCurrency(ctx, tg, chatID, pending.MessageID, expense, code)
+	return true
+}
+
+// applyReceiptCurrency changes a receipt draft's currency, keeping the
+// amount as it is. When today's rate between the old and the new currency
+// is available, the refreshed card also offers to convert the amount.
+func (b *Bot) applyReceiptCurrency(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	messageID int,
+	expense *appmodels.Expense,
+	code string,
+) {
+	b.loadExpenseCategory(ctx, expense)
+	b.loadExpenseTags(ctx, expense)
+	before := *expense
+	expense.Currency = code
+	if !botfmt.ExpenseChanged(&before, expense) {
+		b.sendDraftUnchanged(ctx, tg, chatID, messageID, expense)
+		return
+	}
+	if err := b.expenses().EditFields(ctx, expense); err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to update receipt currency")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   receiptCurrencyFailedMsg,
+		})
+		return
+	}
+
+	logger.Log.Info().
+		Int(logFieldExpenseIDCB, expense.ID).
+		Str("currency", code).
+		Msg("Receipt currency updated")
+	b.auditExpenseUpdate(ctx, tg, expense.UserID, before, expense)
+
+	text := botfmt.ReceiptDraftEditCard(&before, expense, botfmt.DraftCurrencyUpdated)
+	keyboard := buildReceiptConfirmationKeyboard(expense.ID, expense.Currency)
+	if b.exchangeService != nil {
+		result, err := b.exchangeService.Convert(ctx, expense.Amount, before.Currency, code)
+		if err != nil {
+			logger.Log.Debug().Err(err).Str("from", before.Currency).Str("to", code).Msg("No rate for receipt currency")
+			text += "\n\nℹ️ No exchange rate is available right now, so the amount was kept as it is."
+		} else {
+			converted := result.Amount.Round(appmodels.CurrencyMinorUnits(code))
+			text += fmt.Sprintf("\n\n💱 Convert the amount too? %s ≈ %s at today's rate (approximate).",
+				symbolAmount(expense.Amount, before.Currency), symbolAmount(converted, code))
+			keyboard.InlineKeyboard = append([][]models.InlineKeyboardButton{{
+				{
+					Text: "🔁 Convert ≈ " + symbolAmount(converted, code),
+					CallbackData: fmt.Sprintf(receiptConvertCallbackFmt,
+						expense.ID, before.Currency, expense.Amount.String()),
+				},
+				{
+					Text:         "Keep " + botfmt.FormatAmount(expense.Amount, code),
+					CallbackData: fmt.Sprintf("receipt_back_%d", expense.ID),
+				},
+			}}, keyboard.InlineKeyboard...)
+		}
+	}
+
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   messageID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: keyboard,
+	})
+}
+
+// convertReceiptAmountCore converts a receipt draft's amount from the
+// currency in parts[3] of the callback data to its current currency at
+// today's rate. The amount in parts[4] guards against converting twice: a
+// draft whose amount or currency changed since the offer is only shown
+// again. A converted amount above the hard cap is not applied.
+func (b *Bot) convertReceiptAmountCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	messageID int,
+	expense *appmodels.Expense,
+	parts []string,
+) {
+	if len(parts) < 5 || b.exchangeService == nil {
+		b.handleBackToReceiptCore(ctx, tg, chatID, messageID, expense)
+		return
+	}
+	from := service.NormalizeCurrencyCode(parts[3])
+	offered, err := decimal.NewFromString(parts[4])
+	if err != nil || from == expense.Currency || !offered.Equal(expense.Amount) {
+		b.handleBackToReceiptCore(ctx, tg, chatID, messageID, expense)
+		return
+	}
+
+	b.loadExpenseCategory(ctx, expense)
+	b.loadExpenseTags(ctx, expense)
+	result, err := b.exchangeService.Convert(ctx, expense.Amount, from, expense.Currency)
+	if err != nil {
+		logger.Log.Debug().Err(err).Str("from", from).Str("to", expense.Currency).Msg("No rate for receipt conversion")
+		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+			Text: botfmt.ReceiptDraftCard(expense, botfmt.DraftUnchanged) +
+				"\n\nℹ️ No exchange rate is available right now, so the amount was kept as it is.",
+			ParseMode:   models.ParseModeHTML,
+			ReplyMarkup: buildReceiptConfirmationKeyboard(expense.ID, expense.Currency),
+		})
+		return
+	}
+
+	converted := result.Amount.Round(appmodels.CurrencyMinorUnits(expense.Currency))
+	if b.aboveAmountHardCap(converted) {
+		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+			Text: botfmt.ReceiptDraftCard(expense, botfmt.DraftUnchanged) +
+				fmt.Sprintf("\n\nℹ️ %s is above the maximum per expense, so the amount was kept as it is.",
+					botfmt.Money(converted, expense.Currency)),
+			ParseMode:   models.ParseModeHTML,
+			ReplyMarkup: buildReceiptConfirmationKeyboard(expense.ID, expense.Currency),
+		})
+		return
+	}
+
+	before := *expense
+	expense.Amount = converted
+	if err := b.expenses().EditFields(ctx, expense); err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to convert receipt amount")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to update amount. Please try again.",
+		})
+		return
+	}
+
+	logger.Log.Info().
+		Int(logFieldExpenseIDCB, expense.ID).
+		Str("from", from).
+		Str("to", expense.Currency).
+		Msg("Receipt amount converted")
+	b.auditExpenseUpdate(ctx, tg, expense.UserID, before, expense)
+
+	text := botfmt.ReceiptDraftEditCard(&before, expense, botfmt.DraftAmountUpdated) +
+		fmt.Sprintf("\n\n💱 Converted at 1 %s ≈ %s %s (approximate).", from, result.Rate.StringFixed(4), expense.Currency)
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   messageID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: buildReceiptConfirmationKeyboard(expense.ID, expense.Currency),
+	})
+}
@@ -0,0 +1,181 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestBuildReceiptCurrencyKeyboard(t *testing.T) {
+	t.Parallel()
+
+	keyboard := buildReceiptCurrencyKeyboard(7, "USD", "THB")
+	require.Len(t, keyboard.InlineKeyboard, 3)
+	first := keyboard.InlineKeyboard[0]
+	require.Len(t, first, receiptCurrencyButtonsInRow)
+	require.Equal(t, "THB", first[0].Text, "the default currency comes first")
+	require.Equal(t, "receipt_currency_7_THB", first[0].CallbackData)
+	require.Equal(t, "SGD", first[1].Text)
+	require.Equal(t, "✓ USD", first[2].Text)
+
+	last := keyboard.InlineKeyboard[2]
+	require.Equal(t, "receipt_currency_7_other", last[0].CallbackData)
+	require.Equal(t, "receipt_edit_7", last[1].CallbackData)
+
+	codes := 0
+	for _, row := range keyboard.InlineKeyboard[:2] {
+		codes += len(row)
+	}
+	require.Equal(t, len(receiptCommonCurrencies), codes, "THB is not listed twice")
+}
+
+func TestReceiptCurrency(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(437001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{
+		ID:              userID,
+		FirstName:       "Traveller",
+		DefaultCurrency: currencyCodeSGD,
+	}))
+
+	draft := func(t *testing.T, mockBot *mocks.MockBot) *appmodels.Expense {
+		t.Helper()
+		expense := b.saveReceiptDraft(ctx, mockBot, userID, userID, "", &gemini.ReceiptData{
+			Amount:   mustParseDecimal("20.00"),
+			Currency: currencyCodeSGD,
+			Merchant: "Airport Cafe",
+		})
+		require.NotNil(t, expense)
+		return expense
+	}
+	tap := func(mockBot *mocks.MockBot, data string) {
+		b.handleReceiptCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 5, data))
+	}
+	stored := func(t *testing.T, id int) *appmodels.Expense {
+		t.Helper()
+		expense, err := b.expenseRepo.GetByID(ctx, id)
+		require.NoError(t, err)
+		return expense
+	}
+
+	t.Run("currency only", func(t *testing.T) {
+		b.exchangeService = nil
+		mockBot := mocks.NewMockBot()
+		expense := draft(t, mockBot)
+
+		tap(mockBot, fmt.Sprintf(receiptCurrencyCallbackFmt, expense.ID))
+		edited := mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, "Pick the receipt's currency")
+		require.Equal(t, "✓ SGD", requireInlineKeyboard(t, edited.ReplyMarkup).InlineKeyboard[0][0].Text)
+
+		tap(mockBot, fmt.Sprintf(receiptCurrencyPickFmt, expense.ID, "USD"))
+		edited = mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, "Currency Updated")
+		require.NotContains(t, edited.Text, "Convert")
+		require.Len(t, requireInlineKeyboard(t, edited.ReplyMarkup).InlineKeyboard, 3)
+
+		saved := stored(t, expense.ID)
+		require.Equal(t, "USD", saved.Currency)
+		require.True(t, mustParseDecimal("20").Equal(saved.Amount))
+		require.Equal(t, appmodels.ExpenseStatusDraft, saved.Status)
+	})
+
+	t.Run("currency and converted amount", func(t *testing.T) {
+		b.exchangeService = rateTable{"SGD/USD": "0.74"}
+		mockBot := mocks.NewMockBot()
+		expense := draft(t, mockBot)
+
+		tap(mockBot, fmt.Sprintf(receiptCurrencyPickFmt, expense.ID, "USD"))
+		edited := mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, "Convert the amount too? S$20.00 ≈ $14.80 at today's rate (approximate).")
+		offer := requireInlineKeyboard(t, edited.ReplyMarkup).InlineKeyboard[0]
+		require.Equal(t, "🔁 Convert ≈ $14.80", offer[0].Text)
+		require.Equal(t, fmt.Sprintf("receipt_back_%d", expense.ID), offer[1].CallbackData)
+		require.True(t, mustParseDecimal("20").Equal(stored(t, expense.ID).Amount), "the amount is kept until asked")
+
+		tap(mockBot, offer[0].CallbackData)
+		edited = mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, "Converted at 1 SGD ≈ 0.7400 USD (approximate).")
+		saved := stored(t, expense.ID)
+		require.Equal(t, "USD", saved.Currency)
+		require.True(t, mustParseDecimal("14.80").Equal(saved.Amount), "got %s", saved.Amount)
+
+		// A second tap on the same offer does not convert again.
+		tap(mockBot, offer[0].CallbackData)
+		require.True(t, mustParseDecimal("14.80").Equal(stored(t, expense.ID).Amount))
+	})
+
+	t.Run("typed code", func(t *testing.T) {
+		b.exchangeService = nil
+		mockBot := mocks.NewMockBot()
+		expense := draft(t, mockBot)
+
+		other := fmt.Sprintf(receiptCurrencyPickFmt, expense.ID, receiptCurrencyOther)
+		tap(mockBot, other)
+		require.Contains(t, mockBot.LastEditedMessage().Text, "type the currency code")
+
+		require.True(t, b.handlePendingEditCore(ctx, mockBot, mocks.MessageUpdate(userID, userID, "XYZ")))
+		text := mockBot.LastSentMessage().Text
+		require.Contains(t, text, "Unknown currency: <code>XYZ</code>")
+		require.Contains(t, text, "CNY")
+		require.Equal(t, currencyCodeSGD, stored(t, expense.ID).Currency)
+
+		tap(mockBot, other)
+		require.True(t, b.handlePendingEditCore(ctx, mockBot, mocks.MessageUpdate(userID, userID, " cny ")))
+		require.Contains(t, mockBot.LastEditedMessage().Text, "Currency Updated")
+		require.Equal(t, "CNY", stored(t, expense.ID).Currency)
+	})
+
+	t.Run("converted amount above the hard cap", func(t *testing.T) {
+		b.exchangeService = rateTable{"SGD/JPY": "20000"}
+		b.cfg.AmountHardCap = decimal.NewFromInt(100_000)
+		t.Cleanup(func() { b.cfg.AmountHardCap = decimal.Zero })
+		mockBot := mocks.NewMockBot()
+		expense := draft(t, mockBot)
+
+		tap(mockBot, fmt.Sprintf(receiptCurrencyPickFmt, expense.ID, "JPY"))
+		offer := requireInlineKeyboard(t, mockBot.LastEditedMessage().ReplyMarkup).InlineKeyboard[0]
+		tap(mockBot, offer[0].CallbackData)
+		require.Contains(t, mockBot.LastEditedMessage().Text, "is above the maximum per expense")
+		require.True(t, mustParseDecimal("20").Equal(stored(t, expense.ID).Amount), "the amount is kept")
+	})
+
+	t.Run("typed code after confirming", func(t *testing.T) {
+		b.exchangeService = nil
+		mockBot := mocks.NewMockBot()
+		expense := draft(t, mockBot)
+
+		tap(mockBot, fmt.Sprintf(receiptCurrencyPickFmt, expense.ID, receiptCurrencyOther))
+		tap(mockBot, fmt.Sprintf("receipt_confirm_%d", expense.ID))
+		require.Equal(t, appmodels.ExpenseStatusConfirmed, stored(t, expense.ID).Status)
+
+		require.True(t, b.handlePendingEditCore(ctx, mockBot, mocks.MessageUpdate(userID, userID, "EUR")))
+		require.Equal(t, staleExpenseConfirmedText, mockBot.LastSentMessage().Text)
+		require.Equal(t, currencyCodeSGD, stored(t, expense.ID).Currency)
+	})
+
+	t.Run("rate unavailable", func(t *testing.T) {
+		b.exchangeService = rateTable{}
+		mockBot := mocks.NewMockBot()
+		expense := draft(t, mockBot)
+
+		tap(mockBot, fmt.Sprintf(receiptCurrencyPickFmt, expense.ID, "EUR"))
+		edited := mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, "Currency Updated")
+		require.Contains(t, edited.Text, "No exchange rate is available right now")
+		require.Len(t, requireInlineKeyboard(t, edited.ReplyMarkup).InlineKeyboard, 3, "no conversion is offered")
+
+		saved := stored(t, expense.ID)
+		require.Equal(t, "EUR", saved.Currency)
+		require.True(t, mustParseDecimal("20").Equal(saved.Amount))
+	})
+}
@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	receiptTotalAction      = "total"
+	receiptItemsAction      = "items"
+	receiptTotalCallbackFmt = "receipt_" + receiptTotalAction + "_%d"
+	receiptItemsCallbackFmt = "receipt_" + receiptItemsAction + "_%d"
+)
+
+// receiptItemsMismatch returns the items sum a receipt draft's user should
+// be offered instead of amount, the total it was saved with. Drafts that
+// were converted from another currency are not checked, since their
+// description already records the converted total.
+func receiptItemsMismatch(
+	data *gemini.ReceiptData,
+	amount decimal.Decimal,
+	rate decimal.NullDecimal,
+) (decimal.Decimal, bool) {
+	if rate.Valid {
+		return decimal.Zero, false
+	}
+	return data.ItemsDisagree(amount)
+}
+
+// receiptItemsMismatchLine shows both amounts a receipt could have been.
+func receiptItemsMismatchLine(total, itemsSum decimal.Decimal, currency string) string {
+	return fmt.Sprintf("⚠️ Total read: %s / items sum: %s\nWhich one did you pay?",
+		symbolAmount(total, currency), symbolAmount(itemsSum, currency))
+}
+
+// buildReceiptItemsKeyboard asks which amount is right before the draft can
+// be confirmed: the two picks replace the Confirm button, and the amount
+// adjustments wait until one is picked.
+func buildReceiptItemsKeyboard(expense *appmodels.Expense, itemsSum decimal.Decimal) *models.InlineKeyboardMarkup {
+	keyboard := buildReceiptConfirmationKeyboard(expense.ID, expense.Currency)
+	keyboard.InlineKeyboard = [][]models.InlineKeyboardButton{keyboard.InlineKeyboard[0][1:]}
+	picks := []models.InlineKeyboardButton{
+		{
+			Text:         "Total " + symbolAmount(expense.Amount, expense.Currency),
+			CallbackData: fmt.Sprintf(receiptTotalCallbackFmt, expense.ID),
+		},
+		{
+			Text:         "Items " + symbolAmount(itemsSum, expense.Currency),
+			CallbackData: fmt.Sprintf(receiptItemsCallbackFmt, expense.ID),
+		},
+	}
+	keyboard.InlineKeyboard = append([][]models.InlineKeyboardButton{picks}, keyboard.InlineKeyboard...)
+	return keyboard
+}
+
+// pickReceiptAmountCore settles a receipt draft whose items disagreed with
+// its total. Picking the total keeps the amount; picking the items sum
+// saves the sum recorded with the receipt scan instead, unless it is above
+// the hard cap. Both return the draft to the normal confirmation keyboard.
+func (b *Bot) pickReceiptAmountCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	messageID int,
+	expense *appmodels.Expense,
+	action string,
+) {
+	before := *expense
+	update := botfmt.DraftUnchanged
+	var note string
+	if action == receiptItemsAction {
+		scan, err := b.receiptScanRepo.GetByExpenseID(ctx, expense.ID)
+		if err != nil || !scan.ItemsSum.Valid {
+			logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("No receipt items sum to use")
+			_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatID,
+				Text:   "❌ Failed to update amount. Please try again.",
+			})
+			return
+		}
+		itemsSum := scan.ItemsSum.Decimal
+		switch {
+		case b.aboveAmountHardCap(itemsSum):
+			note = fmt.Sprintf("\n\nℹ️ %s is above the maximum per expense, so the amount was kept as it is.",
+				botfmt.Money(itemsSum, expense.Currency))
+		case !itemsSum.Equal(expense.Amount):
+			expense.Amount = itemsSum
+			if err := b.expenses().EditFields(ctx, expense); err != nil {
+				logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to use receipt items sum")
+				_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: chatID,
+					Text:   "❌ Failed to update amount. Please try again.",
+				})
+				return
+			}
+			update = botfmt.DraftAmountUpdated
+			b.auditExpenseUpdate(ctx, tg, expense.UserID, before, expense)
+		}
+	}
+
+	logger.Log.Info().
+		Int(logFieldExpenseIDCB, expense.ID).
+		Str("picked", action).
+		Msg("Receipt amount picked")
+
+	b.loadExpenseCategory(ctx, expense)
+	b.loadExpenseTags(ctx, expense)
+	before.Category, before.Tags = expense.Category, expense.Tags
+
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   messageID,
+		Text:        botfmt.ReceiptDraftEditCard(&before, expense, update) + note,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: buildReceiptConfirmationKeyboard(expense.ID, expense.Currency),
+	})
+}
@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestBuildReceiptItemsKeyboard(t *testing.T) {
+	t.Parallel()
+
+	expense := &appmodels.Expense{ID: 7, Amount: mustParseDecimal("54.60"), Currency: currencyCodeSGD}
+	keyboard := buildReceiptItemsKeyboard(expense, mustParseDecimal("45.60"))
+	require.Len(t, keyboard.InlineKeyboard, 2)
+
+	picks := keyboard.InlineKeyboard[0]
+	require.Equal(t, "Total S$54.60", picks[0].Text)
+	require.Equal(t, "receipt_total_7", picks[0].CallbackData)
+	require.Equal(t, "Items S$45.60", picks[1].Text)
+	require.Equal(t, "receipt_items_7", picks[1].CallbackData)
+
+	for _, button := range keyboard.InlineKeyboard[1] {
+		require.NotEqual(t, "receipt_confirm_7", button.CallbackData, "confirming waits for a pick")
+	}
+}
+
+func TestReceiptItemsMismatch(t *testing.T) {
+	t.Parallel()
+
+	data := &gemini.ReceiptData{
+		Confidence: 0.5,
+		Items:      []gemini.ReceiptItem{{Name: "Laksa", Amount: mustParseDecimal("45.60")}},
+	}
+	sum, mismatch := receiptItemsMismatch(data, mustParseDecimal("54.60"), decimal.NullDecimal{})
+	require.True(t, mismatch)
+	require.True(t, mustParseDecimal("45.60").Equal(sum))
+
+	_, mismatch = receiptItemsMismatch(data, mustParseDecimal("54.60"), decimal.NewNullDecimal(mustParseDecimal("1.35")))
+	require.False(t, mismatch, "converted drafts are not checked")
+}
+
+func TestReceiptItemsCrossCheck(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(432001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Scanner"}))
+
+	scan := func(mockBot *mocks.MockBot, amount string) *appmodels.Expense {
+		expense := b.saveReceiptDraft(ctx, mockBot, userID, userID, "", &gemini.ReceiptData{
+			Amount:     mustParseDecimal(amount),
+			Currency:   currencyCodeSGD,
+			Merchant:   "Hawker",
+			Confidence: 0.5,
+			Items: []gemini.ReceiptItem{
+				{Name: "Laksa", Amount: mustParseDecimal("18.00")},
+				{Name: "Noodles", Amount: mustParseDecimal("27.60")},
+			},
+		})
+		require.NotNil(t, expense)
+		return expense
+	}
+	stored := func(id int) *appmodels.Expense {
+		expense, err := b.expenseRepo.GetByID(ctx, id)
+		require.NoError(t, err)
+		return expense
+	}
+
+	t.Run("items that agree show the normal card", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := scan(mockBot, "45.60")
+
+		msg := mockBot.LastSentMessage()
+		require.NotContains(t, msg.Text, "items sum")
+		keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+		require.Equal(t, fmt.Sprintf("receipt_confirm_%d", expense.ID), keyboard.InlineKeyboard[0][0].CallbackData)
+	})
+
+	t.Run("disagreeing items, pick the total", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := scan(mockBot, "54.60")
+
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, "Total read: S$54.60 / items sum: S$45.60")
+		keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+		data := keyboard.InlineKeyboard[0][0].CallbackData
+		require.Equal(t, fmt.Sprintf(receiptTotalCallbackFmt, expense.ID), data)
+
+		b.handleReceiptCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 5, data))
+		edited := mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, "S$54.60")
+		keyboard = requireInlineKeyboard(t, edited.ReplyMarkup)
+		require.Equal(t, fmt.Sprintf("receipt_confirm_%d", expense.ID), keyboard.InlineKeyboard[0][0].CallbackData)
+		require.True(t, mustParseDecimal("54.60").Equal(stored(expense.ID).Amount))
+	})
+
+	t.Run("disagreeing items, pick the items sum", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := scan(mockBot, "54.60")
+
+		keyboard := requireInlineKeyboard(t, mockBot.LastSentMessage().ReplyMarkup)
+		data := keyboard.InlineKeyboard[0][1].CallbackData
+		b.handleReceiptCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 5, data))
+
+		edited := mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, "S$45.60")
+		keyboard = requireInlineKeyboard(t, edited.ReplyMarkup)
+		require.Equal(t, fmt.Sprintf("receipt_confirm_%d", expense.ID), keyboard.InlineKeyboard[0][0].CallbackData)
+
+		saved := stored(expense.ID)
+		require.True(t, mustParseDecimal("45.60").Equal(saved.Amount))
+		require.Equal(t, appmodels.ExpenseStatusDraft, saved.Status)
+	})
+
+	t.Run("the items sum comes from the scan, not the button", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := scan(mockBot, "54.60")
+
+		forged := fmt.Sprintf(receiptItemsCallbackFmt, expense.ID) + "_99999"
+		b.handleReceiptCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 5, forged))
+		require.True(t, mustParseDecimal("45.60").Equal(stored(expense.ID).Amount))
+	})
+
+	t.Run("an items sum above the hard cap is not used", func(t *testing.T) {
+		b.cfg.AmountHardCap = decimal.NewFromInt(45)
+		t.Cleanup(func() { b.cfg.AmountHardCap = decimal.Zero })
+		mockBot := mocks.NewMockBot()
+		expense := scan(mockBot, "40.00")
+
+		data := requireInlineKeyboard(t, mockBot.LastSentMessage().ReplyMarkup).InlineKeyboard[0][1].CallbackData
+		b.handleReceiptCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 5, data))
+		require.Contains(t, mockBot.LastEditedMessage().Text, "is above the maximum per expense")
+		require.True(t, mustParseDecimal("40.00").Equal(stored(expense.ID).Amount))
+	})
+}
@@ -0,0 +1,227 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+const (
+	// receiptOwnerTimeout is how long the forwarder of a receipt has to say
+	// whose expense it is before it is saved as theirs.
+	receiptOwnerTimeout = 2 * time.Minute
+
+	receiptOwnerCallbackPrefix = "rcptowner_"
+	receiptOwnerMine           = "mine"
+	receiptOwnerOrigin         = "origin"
+
+	receiptOwnerQuestionText    = "🧾 Whose expense is this receipt?"
+	receiptOwnerNotForwarderMsg = "Only the person who forwarded the receipt can answer."
+	receiptOwnerAnsweredMsg     = "This receipt was already assigned."
+)
+
+// receiptOwnerKey identifies an ownership question by its message.
+type receiptOwnerKey struct {
+	chatID    int64
+	messageID int
+}
+
+// pendingReceiptOwner is a receipt forwarded in a group from another user
+// of the bot, held until the forwarder says whose expense it is. messageID
+// is the forwarded photo message. timeout scans it for the forwarder; the
+// timer runs it, or flushPendingReceiptOwners on shutdown.
+type pendingReceiptOwner struct {
+	forwarderID int64
+	originID    int64
+	messageID   int
+	fileID      string
+	timer       *time.Timer
+	timeout     func()
+}
+
+// receiptOwnerName names the original sender on the ownership button.
+func receiptOwnerName(user *models.User) string {
+	switch {
+	case user.FirstName != "":
+		return user.FirstName + "'s"
+	case user.Username != "":
+		return "@" + user.Username + "'s"
+	default:
+		return "Theirs"
+	}
+}
+
+// askReceiptOwner asks whose expense a receipt photo is when it was
+// forwarded in a group chat from another authorized user, and reports
+// whether it did. Without an answer the receipt is scanned for the
+// forwarder once receiptOwnerTimeout passes. Other photos, and forwards
+// whose sender is hidden or unknown to the bot, are left to the caller.
+func (b *Bot) askReceiptOwner(ctx context.Context, tg TelegramAPI, msg *models.Message, fileID string) bool {
+	chatID := msg.Chat.ID
+	if !isGroupChat(chatID) || msg.From == nil || msg.ForwardOrigin == nil ||
+		msg.ForwardOrigin.MessageOriginUser == nil {
+		return false
+	}
+	origin := &msg.ForwardOrigin.MessageOriginUser.SenderUser
+	if origin.ID == msg.From.ID || origin.IsBot || !b.isAuthorized(ctx, origin.ID, origin.Username) {
+		return false
+	}
+
+	question, err := tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   receiptOwnerQuestionText,
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{{
+				{Text: "Mine", CallbackData: receiptOwnerCallbackPrefix + receiptOwnerMine},
+				{Text: receiptOwnerName(origin), CallbackData: receiptOwnerCallbackPrefix + receiptOwnerOrigin},
+			}},
+		},
+	})
+	if err != nil || question == nil {
+		logger.Log.Warn().Err(err).Int64("chat_id", chatID).Msg("Failed to ask whose receipt it is")
+		return false
+	}
+
+	key := receiptOwnerKey{chatID: chatID, messageID: question.ID}
+	pending := &pendingReceiptOwner{
+		forwarderID: msg.From.ID,
+		originID:    origin.ID,
+		messageID:   msg.ID,
+		fileID:      fileID,
+	}
+
+	wait := b.receiptOwnerWait
+	if wait <= 0 {
+		wait = receiptOwnerTimeout
+	}
+	scanCtx := context.WithoutCancel(ctx)
+
+	// The timer is set under the lock, so a quick answer always finds it.
+	b.pendingOwnersMu.Lock()
+	if b.pendingOwners == nil {
+		b.pendingOwners = make(map[receiptOwnerKey]*pendingReceiptOwner)
+	}
+	b.pendingOwners[key] = pending
+	pending.timeout = func() {
+		if timedOut := b.takePendingReceiptOwner(key); timedOut != nil {
+			b.scanOwnedReceipt(scanCtx, tg, key, timedOut, timedOut.forwarderID)
+		}
+	}
+	pending.timer = time.AfterFunc(wait, pending.timeout)
+	b.pendingOwnersMu.Unlock()
+	return true
+}
+
+// flushPendingReceiptOwners scans every receipt still waiting for an owner
+// for its forwarder, as the timeout would, so a shutdown does not drop
+// them. It runs before the receipt workers drain.
+func (b *Bot) flushPendingReceiptOwners() {
+	b.pendingOwnersMu.Lock()
+	var timeouts []func()
+	for _, pending := range b.pendingOwners {
+		if pending.timer.Stop() {
+			timeouts = append(timeouts, pending.timeout)
+		}
+	}
+	b.pendingOwnersMu.Unlock()
+
+	for _, timeout := range timeouts {
+		timeout()
+	}
+}
+
+// takePendingReceiptOwner removes and returns the receipt waiting on the
+// question at key, or nil when it was already answered or timed out.
+func (b *Bot) takePendingReceiptOwner(key receiptOwnerKey) *pendingReceiptOwner {
+	b.pendingOwnersMu.Lock()
+	defer b.pendingOwnersMu.Unlock()
+
+	pending, ok := b.pendingOwners[key]
+	if !ok {
+		return nil
+	}
+	delete(b.pendingOwners, key)
+	return pending
+}
+
+// scanOwnedReceipt turns the ownership question into the "Processing
+// receipt..." placeholder and scans the receipt as ownerID's expense.
+func (b *Bot) scanOwnedReceipt(
+	ctx context.Context,
+	tg TelegramAPI,
+	key receiptOwnerKey,
+	pending *pendingReceiptOwner,
+	ownerID int64,
+) {
+	if !b.aiEnabled(ctx, ownerID) {
+		b.offerManualReceiptCore(ctx, tg, key.chatID, ownerID, pending.messageID, key.messageID)
+		return
+	}
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    key.chatID,
+		MessageID: key.messageID,
+		Text:      receiptProcessingText,
+	})
+	b.queueReceiptScan(ctx, tg, key.chatID, ownerID, pending.messageID, key.messageID, []string{pending.fileID})
+}
+
+// handleReceiptOwnerCallback handles the answer to askReceiptOwner.
+func (b *Bot) handleReceiptOwnerCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleReceiptOwnerCallbackCore(ctx, tgBot, update)
+}
+
+// handleReceiptOwnerCallbackCore is the testable implementation of
+// handleReceiptOwnerCallback. Only the forwarder can answer; the receipt
+// is then scanned as their expense or the original sender's.
+func (b *Bot) handleReceiptOwnerCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	choice := strings.TrimPrefix(query.Data, receiptOwnerCallbackPrefix)
+	if choice != receiptOwnerMine && choice != receiptOwnerOrigin {
+		answerCallback(ctx, tg, query)
+		return
+	}
+	key := receiptOwnerKey{chatID: query.Message.Message.Chat.ID, messageID: query.Message.Message.ID}
+
+	b.pendingOwnersMu.Lock()
+	pending, ok := b.pendingOwners[key]
+	notForwarder := ok && pending.forwarderID != query.From.ID
+	if ok && !notForwarder {
+		delete(b.pendingOwners, key)
+		pending.timer.Stop()
+	}
+	b.pendingOwnersMu.Unlock()
+
+	switch {
+	case !ok:
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            receiptOwnerAnsweredMsg,
+		})
+		_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+			ChatID:    key.chatID,
+			MessageID: key.messageID,
+		})
+		return
+	case notForwarder:
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            receiptOwnerNotForwarderMsg,
+		})
+		return
+	}
+	answerCallback(ctx, tg, query)
+
+	ownerID := pending.forwarderID
+	if choice == receiptOwnerOrigin {
+		ownerID = pending.originID
+	}
+	b.scanOwnedReceipt(ctx, tg, key, pending, ownerID)
+}
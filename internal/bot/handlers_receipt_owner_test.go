@@ -0,0 +1,202 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"google.golang.org/genai"
+)
+
+const (
+	testOwnerGroupChatID = int64(-456001)
+	testOwnerForwarderID = int64(456101)
+	testOwnerOriginID    = int64(456102)
+)
+
+// forwardedPhotoUpdate is a photo forwarded by userID, sent by origin.
+func forwardedPhotoUpdate(chatID, userID int64, messageID int, origin *models.MessageOrigin) *models.Update {
+	update := mocks.PhotoUpdate(chatID, userID, testPhotoFileID)
+	update.Message.ID = messageID
+	update.Message.ForwardOrigin = origin
+	return update
+}
+
+func userOrigin(userID int64, firstName string) *models.MessageOrigin {
+	return &models.MessageOrigin{
+		Type: models.MessageOriginTypeUser,
+		MessageOriginUser: &models.MessageOriginUser{
+			Type:       models.MessageOriginTypeUser,
+			SenderUser: models.User{ID: userID, FirstName: firstName},
+		},
+	}
+}
+
+func TestAskReceiptOwnerFallsBack(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		chatID int64
+		origin *models.MessageOrigin
+	}{
+		{name: "not a forward", chatID: testOwnerGroupChatID},
+		{name: "forward in a private chat", chatID: testOwnerForwarderID, origin: userOrigin(testOwnerOriginID, "Alice")},
+		{name: "forward of own photo", chatID: testOwnerGroupChatID, origin: userOrigin(testOwnerForwarderID, "Me")},
+		{
+			name:   "hidden sender",
+			chatID: testOwnerGroupChatID,
+			origin: &models.MessageOrigin{
+				Type:                    models.MessageOriginTypeHiddenUser,
+				MessageOriginHiddenUser: &models.MessageOriginHiddenUser{SenderUserName: "Alice"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			b := &Bot{
+				cfg:          &config.Config{WhitelistedUserIDs: []int64{testOwnerForwarderID, testOwnerOriginID}},
+				geminiClient: gemini.NewClientWithGenerator(&botTestGenerator{}),
+			}
+			mockBot := mocks.NewMockBot()
+			mockBot.GetFileError = errors.New("get file failed")
+
+			b.handlePhotoCore(context.Background(), mockBot,
+				forwardedPhotoUpdate(tt.chatID, testOwnerForwarderID, 1, tt.origin))
+
+			require.Equal(t, 1, mockBot.SentMessageCount())
+			require.Contains(t, mockBot.SentMessages[0].Text, testProcessingReceiptText, "the receipt is read right away")
+			require.Empty(t, b.pendingOwners)
+		})
+	}
+}
+
+func TestReceiptOwnerFlushedOnShutdown(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{
+		cfg:              &config.Config{WhitelistedUserIDs: []int64{testOwnerForwarderID, testOwnerOriginID}},
+		geminiClient:     gemini.NewClientWithGenerator(&botTestGenerator{}),
+		receiptOwnerWait: time.Hour,
+	}
+	mockBot := mocks.NewMockBot()
+	mockBot.GetFileError = errors.New("get file failed")
+
+	b.handlePhotoCore(context.Background(), mockBot,
+		forwardedPhotoUpdate(testOwnerGroupChatID, testOwnerForwarderID, 1, userOrigin(testOwnerOriginID, "Alice")))
+	require.Len(t, b.pendingOwners, 1)
+	require.Equal(t, receiptOwnerQuestionText, mockBot.SentMessages[0].Text)
+
+	b.drainReceiptWorkers(context.Background())
+	require.Empty(t, b.pendingOwners)
+	require.Equal(t, receiptProcessingText, mockBot.EditedMessages[0].Text,
+		"the receipt is scanned for the forwarder instead of waiting out the timer")
+}
+
+func TestReceiptOwner(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.cfg.WhitelistedUserIDs = append(b.cfg.WhitelistedUserIDs, testOwnerForwarderID, testOwnerOriginID)
+	for _, id := range []int64{testOwnerForwarderID, testOwnerOriginID} {
+		require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: id, FirstName: "Owner"}))
+	}
+	b.geminiClient = gemini.NewClientWithGenerator(&botTestGenerator{
+		response: &genai.GenerateContentResponse{
+			Candidates: []*genai.Candidate{{
+				Content: &genai.Content{Parts: []*genai.Part{{
+					Text: `{"amount":"23.40","currency":"SGD","merchant":"Bistro","date":"2026-02-26","confidence":0.9}`,
+				}}},
+			}},
+		},
+	})
+	b.httpClient = &http.Client{
+		Transport: receiptRoundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("fake-image-bytes")),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	draftOwner := func(messageID int) int64 {
+		var userID int64
+		err := pool.QueryRow(ctx, `SELECT user_id FROM expenses WHERE idempotency_key = $1`,
+			receiptIdempotencyKey(testOwnerGroupChatID, messageID)).Scan(&userID)
+		require.NoError(t, err)
+		return userID
+	}
+	forward := func(messageID int) (*mocks.MockBot, int) {
+		mockBot := mocks.NewMockBot()
+		b.handlePhotoCore(ctx, mockBot,
+			forwardedPhotoUpdate(testOwnerGroupChatID, testOwnerForwarderID, messageID, userOrigin(testOwnerOriginID, "Alice")))
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		question := mockBot.SentMessages[0]
+		require.Equal(t, receiptOwnerQuestionText, question.Text)
+		keyboard, ok := question.ReplyMarkup.(*models.InlineKeyboardMarkup)
+		require.True(t, ok)
+		require.Equal(t, "Mine", keyboard.InlineKeyboard[0][0].Text)
+		require.Equal(t, "Alice's", keyboard.InlineKeyboard[0][1].Text)
+		return mockBot, testPlaceholderMessageID
+	}
+	answer := func(mockBot *mocks.MockBot, from int64, questionID int, choice string) {
+		b.handleReceiptOwnerCallbackCore(ctx, mockBot,
+			mocks.CallbackQueryUpdate(testOwnerGroupChatID, from, questionID, receiptOwnerCallbackPrefix+choice))
+	}
+
+	t.Run("the original sender's button saves the draft as theirs", func(t *testing.T) {
+		mockBot, questionID := forward(9001)
+
+		answer(mockBot, testOwnerOriginID, questionID, receiptOwnerOrigin)
+		require.Equal(t, receiptOwnerNotForwarderMsg, mockBot.AnsweredCallbacks[0].Text)
+		require.Equal(t, 0, mockBot.EditedMessageCount(), "only the forwarder can answer")
+
+		answer(mockBot, testOwnerForwarderID, questionID, receiptOwnerOrigin)
+		edited := mockBot.LastEditedMessage()
+		require.Equal(t, questionID, edited.MessageID, "the question becomes the draft card")
+		require.Contains(t, edited.Text, "Receipt Scanned")
+		require.Equal(t, testOwnerOriginID, draftOwner(9001))
+
+		answer(mockBot, testOwnerForwarderID, questionID, receiptOwnerMine)
+		require.Equal(t, receiptOwnerAnsweredMsg, mockBot.AnsweredCallbacks[len(mockBot.AnsweredCallbacks)-1].Text)
+	})
+
+	t.Run("mine saves the draft as the forwarder's", func(t *testing.T) {
+		mockBot, questionID := forward(9002)
+		answer(mockBot, testOwnerForwarderID, questionID, receiptOwnerMine)
+		require.Equal(t, testOwnerForwarderID, draftOwner(9002))
+	})
+
+	t.Run("no answer saves the draft as the forwarder's", func(t *testing.T) {
+		b.receiptOwnerWait = 20 * time.Millisecond
+		t.Cleanup(func() { b.receiptOwnerWait = 0 })
+
+		mockBot, questionID := forward(9003)
+		require.Eventually(t, func() bool {
+			edited := mockBot.LastEditedMessage()
+			return edited != nil && strings.Contains(edited.Text, "Receipt Scanned")
+		}, 2*time.Second, 10*time.Millisecond)
+		require.Equal(t, questionID, mockBot.LastEditedMessage().MessageID)
+		require.Equal(t, testOwnerForwarderID, draftOwner(9003))
+	})
+
+	t.Run("a sender who is not a user is not asked about", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handlePhotoCore(ctx, mockBot,
+			forwardedPhotoUpdate(testOwnerGroupChatID, testOwnerForwarderID, 9004, userOrigin(456199, "Stranger")))
+		require.Contains(t, mockBot.SentMessages[0].Text, testProcessingReceiptText)
+		require.Equal(t, testOwnerForwarderID, draftOwner(9004))
+	})
+}
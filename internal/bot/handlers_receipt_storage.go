@@ -0,0 +1,214 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+	"gitlab.com/yelinaung/expense-bot/internal/storage"
+)
+
+// receiptCleanupBatch is how many stored receipts of deleted expenses one
+// cleanup run removes at most.
+const receiptCleanupBatch = 100
+
+// openReceiptStore opens the receipt storage selected by RECEIPT_STORAGE.
+// It is only called when storage is on.
+func openReceiptStore(cfg *config.Config) (storage.ReceiptStore, error) {
+	store, err := storage.NewDiskStore(cfg.ReceiptStorageDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open receipt storage: %w", err)
+	}
+	return store, nil
+}
+
+// closeReceiptStore releases the receipt storage, when there is one.
+func (b *Bot) closeReceiptStore() {
+	if b.receiptStore == nil {
+		return
+	}
+	if err := b.receiptStore.Close(); err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to close receipt storage")
+	}
+}
+
+// storeReceiptImage keeps the image of a receipt draft in the receipt
+// storage, when there is one. Failures are logged; the expense then falls
+// back to its Telegram file ID.
+func (b *Bot) storeReceiptImage(ctx context.Context, expense *appmodels.Expense, image []byte) {
+	if b.receiptStore == nil || len(image) == 0 {
+		return
+	}
+
+	key := storage.ReceiptKey(expense.UserID, expense.ID)
+	if err := b.receiptStore.Put(ctx, key, image); err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expense.ID).Msg("Failed to store receipt image")
+		return
+	}
+	err := b.receiptFileRepo.Record(ctx, repository.ReceiptFile{
+		ExpenseID:  expense.ID,
+		UserID:     expense.UserID,
+		StorageKey: key,
+		SizeBytes:  int64(len(image)),
+	})
+	if err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expense.ID).Msg("Failed to record stored receipt")
+		_ = b.receiptStore.Delete(ctx, key)
+	}
+}
+
+// storedReceiptImage returns the stored image of an expense's receipt, or
+// nil when none is stored.
+func (b *Bot) storedReceiptImage(ctx context.Context, expenseID int) []byte {
+	if b.receiptStore == nil {
+		return nil
+	}
+	file, err := b.receiptFileRepo.GetByExpenseID(ctx, expenseID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Log.Warn().Err(err).Int("expense_id", expenseID).Msg("Failed to look up stored receipt")
+		}
+		return nil
+	}
+	image, err := b.receiptStore.Get(ctx, file.StorageKey)
+	if err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expenseID).Msg("Failed to read stored receipt")
+		return nil
+	}
+	return image
+}
+
+// sendReceiptImage sends the receipt photo of an expense: the stored copy
+// when there is one, else the Telegram file. Expenses without a receipt
+// send nothing.
+func (b *Bot) sendReceiptImage(ctx context.Context, tg TelegramAPI, chatID int64, expense *appmodels.Expense) {
+	var photo models.InputFile
+	if image := b.storedReceiptImage(ctx, expense.ID); image != nil {
+		photo = &models.InputFileUpload{
+			Filename: fmt.Sprintf("receipt_%d.jpg", expense.UserExpenseNumber),
+			Data:     bytes.NewReader(image),
+		}
+	} else if expense.ReceiptFileID != "" {
+		photo = &models.InputFileString{Data: expense.ReceiptFileID}
+	} else {
+		return
+	}
+
+	_, err := tg.SendPhoto(ctx, &bot.SendPhotoParams{
+		ChatID:  chatID,
+		Photo:   photo,
+		Caption: fmt.Sprintf("🧾 Receipt of #%d", expense.UserExpenseNumber),
+	})
+	if err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expense.ID).Msg("Failed to send receipt photo")
+	}
+}
+
+// cleanupStoredReceipts deletes the stored images of deleted expenses. A
+// record is only dropped once its image is gone, so failures are retried
+// on the next run.
+func (b *Bot) cleanupStoredReceipts(ctx context.Context) {
+	if b.receiptStore == nil {
+		return
+	}
+
+	orphans, err := b.receiptFileRepo.ListOrphans(ctx, receiptCleanupBatch)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to list stored receipts for cleanup")
+		return
+	}
+
+	removed := 0
+	for i := range orphans {
+		if err := b.receiptStore.Delete(ctx, orphans[i].StorageKey); err != nil {
+			logger.Log.Warn().Err(err).Int("receipt_file_id", orphans[i].ID).Msg("Failed to delete stored receipt")
+			continue
+		}
+		if err := b.receiptFileRepo.Delete(ctx, orphans[i].ID); err != nil {
+			logger.Log.Warn().Err(err).Int("receipt_file_id", orphans[i].ID).Msg("Failed to drop stored receipt record")
+			continue
+		}
+		removed++
+	}
+	if removed > 0 {
+		logger.Log.Info().Int("count", removed).Msg("Cleaned up stored receipts of deleted expenses")
+	}
+}
+
+// formatByteSize renders a byte count with a binary unit, e.g. "1.5 MiB".
+func formatByteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatStorageStats renders the /storagestats summary.
+func formatStorageStats(backend string, stats *repository.ReceiptFileStats) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🗄️ <b>Receipt storage</b>: %s\n", backend)
+	fmt.Fprintf(&sb, "\nFiles: %d (%s) from %d users", stats.Files, formatByteSize(stats.Bytes), stats.Users)
+	if stats.Files > 0 {
+		fmt.Fprintf(&sb, "\nAverage: %s", formatByteSize(stats.Bytes/int64(stats.Files)))
+	}
+	if stats.Orphans > 0 {
+		fmt.Fprintf(&sb, "\nAwaiting cleanup: %d (%s)", stats.Orphans, formatByteSize(stats.OrphanBytes))
+	}
+	return sb.String()
+}
+
+// handleStorageStats handles the /storagestats command.
+func (b *Bot) handleStorageStats(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleStorageStatsCore(ctx, tgBot, update)
+}
+
+// handleStorageStatsCore is the testable implementation of
+// handleStorageStats. It shows superadmins how many receipt images are
+// stored and how much space they take.
+func (b *Bot) handleStorageStatsCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	if !b.cfg.IsSuperAdmin(update.Message.From.ID, update.Message.From.Username) {
+		send(onlySuperadminsMsg)
+		return
+	}
+	if b.receiptStore == nil {
+		send("🗄️ Receipt storage is off. Receipts are only kept by Telegram. " +
+			"Set <code>RECEIPT_STORAGE=disk</code> to keep copies.")
+		return
+	}
+
+	stats, err := b.receiptFileRepo.Stats(ctx)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch receipt storage stats")
+		send("❌ Failed to fetch storage stats.")
+		return
+	}
+	send(formatStorageStats(b.receiptStore.Backend(), stats))
+}
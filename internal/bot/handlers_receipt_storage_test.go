@@ -0,0 +1,151 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+	"gitlab.com/yelinaung/expense-bot/internal/storage"
+)
+
+func TestFormatByteSize(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "0 B", formatByteSize(0))
+	require.Equal(t, "1023 B", formatByteSize(1023))
+	require.Equal(t, "1.0 KiB", formatByteSize(1024))
+	require.Equal(t, "1.5 MiB", formatByteSize(3*512*1024))
+	require.Equal(t, "2.0 GiB", formatByteSize(2<<30))
+}
+
+func TestFormatStorageStats(t *testing.T) {
+	t.Parallel()
+
+	text := formatStorageStats("disk", &repository.ReceiptFileStats{
+		Files: 4, Bytes: 4096, Users: 2, Orphans: 1, OrphanBytes: 512,
+	})
+	require.Contains(t, text, "<b>Receipt storage</b>: disk")
+	require.Contains(t, text, "Files: 4 (4.0 KiB) from 2 users")
+	require.Contains(t, text, "Average: 1.0 KiB")
+	require.Contains(t, text, "Awaiting cleanup: 1 (512 B)")
+
+	empty := formatStorageStats("disk", &repository.ReceiptFileStats{})
+	require.Contains(t, empty, "Files: 0 (0 B) from 0 users")
+	require.NotContains(t, empty, "Average")
+	require.NotContains(t, empty, "Awaiting cleanup")
+}
+
+func TestHandleStorageStatsCore_NoSender(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{cfg: &config.Config{}}
+	mockBot := mocks.NewMockBot()
+	update := mocks.CommandUpdate(100, 100, "/storagestats")
+	update.Message.From = nil
+	b.handleStorageStatsCore(context.Background(), mockBot, update)
+	require.Zero(t, mockBot.SentMessageCount())
+}
+
+func TestCloseReceiptStore(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{}
+	b.closeReceiptStore()
+
+	store, err := storage.NewDiskStore(t.TempDir())
+	require.NoError(t, err)
+	b.receiptStore = store
+	b.closeReceiptStore()
+	require.Error(t, store.Put(context.Background(), "1/1.jpg", []byte("jpeg")), "the root is released")
+}
+
+func TestReceiptStorage(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.cfg = &config.Config{WhitelistedUserIDs: []int64{100}}
+
+	dir := filepath.Join(t.TempDir(), "receipts")
+	store, err := storage.NewDiskStore(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	userID := int64(410100)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Stored"}))
+	newExpense := func(fileID string) *appmodels.Expense {
+		expense := &appmodels.Expense{
+			UserID:        userID,
+			Amount:        mustParseDecimal("8.50"),
+			Currency:      currencyCodeSGD,
+			ReceiptFileID: fileID,
+			Status:        appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		return expense
+	}
+	show := func(expense *appmodels.Expense) *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		text := fmt.Sprintf("/show %d", expense.UserExpenseNumber)
+		b.handleShowCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, text))
+		return mockBot
+	}
+
+	t.Run("falls back to the Telegram file when storage is off", func(t *testing.T) {
+		b.receiptStore = nil
+		expense := newExpense("tg-file-1")
+		b.storeReceiptImage(ctx, expense, []byte("jpeg"))
+
+		mockBot := show(expense)
+		require.Len(t, mockBot.SentPhotos, 1)
+		require.Equal(t, "tg-file-1", mockBot.SentPhotos[0].FileID)
+		require.Nil(t, mockBot.SentPhotos[0].Data)
+	})
+
+	t.Run("no photo without a receipt", func(t *testing.T) {
+		b.receiptStore = store
+		mockBot := show(newExpense(""))
+		require.Empty(t, mockBot.SentPhotos)
+	})
+
+	t.Run("serves the stored image and cleans it up after delete", func(t *testing.T) {
+		b.receiptStore = store
+		expense := newExpense("tg-file-2")
+		b.storeReceiptImage(ctx, expense, []byte("stored-jpeg"))
+
+		mockBot := show(expense)
+		require.Len(t, mockBot.SentPhotos, 1)
+		require.Equal(t, []byte("stored-jpeg"), mockBot.SentPhotos[0].Data)
+		require.Equal(t, fmt.Sprintf("🧾 Receipt of #%d", expense.UserExpenseNumber), mockBot.SentPhotos[0].Caption)
+
+		key := storage.ReceiptKey(userID, expense.ID)
+		require.FileExists(t, filepath.Join(dir, filepath.FromSlash(key)))
+
+		require.NoError(t, b.expenseRepo.Delete(ctx, expense.ID))
+		b.cleanupStoredReceipts(ctx)
+		require.NoFileExists(t, filepath.Join(dir, filepath.FromSlash(key)))
+	})
+
+	t.Run("storagestats", func(t *testing.T) {
+		b.receiptStore = store
+		b.storeReceiptImage(ctx, newExpense("tg-file-3"), []byte("twelve bytes"))
+
+		mockBot := mocks.NewMockBot()
+		b.handleStorageStatsCore(ctx, mockBot, mocks.CommandUpdate(999, 999, "/storagestats"))
+		require.Equal(t, onlySuperadminsMsg, mockBot.LastSentMessage().Text)
+
+		mockBot = mocks.NewMockBot()
+		b.handleStorageStatsCore(ctx, mockBot, mocks.CommandUpdate(100, 100, "/storagestats"))
+		require.Contains(t, mockBot.LastSentMessage().Text, "<b>Receipt storage</b>: disk")
+
+		b.receiptStore = nil
+		mockBot = mocks.NewMockBot()
+		b.handleStorageStatsCore(ctx, mockBot, mocks.CommandUpdate(100, 100, "/storagestats"))
+		require.Contains(t, mockBot.LastSentMessage().Text, "Receipt storage is off")
+	})
+}
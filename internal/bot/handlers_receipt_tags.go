@@ -0,0 +1,314 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	editTypeTagCB            = "tag"
+	receiptTagsAction        = "tags"
+	receiptTagsCallbackFmt   = "receipt_tags_%d"
+	receiptTagsButtonText    = "🏷️ Tags"
+	receiptTagCallbackPrefix = "rtag_"
+	receiptTagToggleFmt      = "rtag_%d_%d"
+	receiptTagNewFmt         = "rtag_%d_new"
+	receiptTagNewButtonText  = "➕ New tag"
+
+	// receiptTagButtonLimit caps how many of the user's tags the toggle
+	// keyboard lists. Tags already on the draft are always shown.
+	receiptTagButtonLimit = 20
+)
+
+// loadExpenseTags fills in expense.Tags for display. Lookup failures leave
+// the expense without tags.
+func (b *Bot) loadExpenseTags(ctx context.Context, expense *appmodels.Expense) {
+	tags, err := b.tagRepo.GetByExpenseID(ctx, expense.ID)
+	if err != nil {
+		logger.Log.Debug().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to load expense tags")
+		return
+	}
+	expense.Tags = tags
+}
+
+// buildReceiptTagKeyboard lists tags two per row, marking those in
+// selected, followed by rows to add a new tag and to go back.
+func buildReceiptTagKeyboard(expenseID int, tags []appmodels.Tag, selected map[int]bool) *models.InlineKeyboardMarkup {
+	var rows [][]models.InlineKeyboardButton
+	row := make([]models.InlineKeyboardButton, 0, 2)
+	for _, tag := range tags {
+		text := "#" + tag.Name
+		if selected[tag.ID] {
+			text = "✅ " + text
+		}
+		row = append(row, models.InlineKeyboardButton{
+			Text:         text,
+			CallbackData: fmt.Sprintf(receiptTagToggleFmt, expenseID, tag.ID),
+		})
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = make([]models.InlineKeyboardButton, 0, 2)
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows,
+		[]models.InlineKeyboardButton{
+			{Text: receiptTagNewButtonText, CallbackData: fmt.Sprintf(receiptTagNewFmt, expenseID)},
+		},
+		[]models.InlineKeyboardButton{
+			{Text: backButtonTextCB, CallbackData: fmt.Sprintf("receipt_back_%d", expenseID)},
+		},
+	)
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// receiptTagChoices returns the tags offered for a draft: every tag already
+// on it, then the user's other tags by name up to receiptTagButtonLimit.
+func (b *Bot) receiptTagChoices(ctx context.Context, expense *appmodels.Expense) ([]appmodels.Tag, error) {
+	userTags, err := b.tagRepo.GetAllByUserID(ctx, expense.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user tags: %w", err)
+	}
+
+	choices := make([]appmodels.Tag, 0, receiptTagButtonLimit)
+	seen := make(map[int]bool, len(expense.Tags))
+	for _, tag := range expense.Tags {
+		choices = append(choices, tag)
+		seen[tag.ID] = true
+	}
+	for _, tag := range userTags {
+		if len(choices) >= receiptTagButtonLimit {
+			break
+		}
+		if !seen[tag.ID] {
+			choices = append(choices, tag)
+		}
+	}
+	return choices, nil
+}
+
+// showReceiptTagsCore shows the tag toggle keyboard for a receipt draft.
+// Users without any tags are asked for a new one straight away.
+func (b *Bot) showReceiptTagsCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	messageID int,
+	expense *appmodels.Expense,
+) {
+	b.loadExpenseCategory(ctx, expense)
+	b.loadExpenseTags(ctx, expense)
+
+	choices, err := b.receiptTagChoices(ctx, expense)
+	if err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to load tags for receipt")
+		return
+	}
+	if len(choices) == 0 {
+		b.promptReceiptNewTagCore(ctx, tg, chatID, messageID, expense)
+		return
+	}
+
+	selected := make(map[int]bool, len(expense.Tags))
+	for _, tag := range expense.Tags {
+		selected[tag.ID] = true
+	}
+
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   messageID,
+		Text:        botfmt.ReceiptDraftCard(expense, botfmt.DraftTagsMenu),
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: buildReceiptTagKeyboard(expense.ID, choices, selected),
+	})
+}
+
+// promptReceiptNewTagCore asks for the name of a tag to add to a receipt
+// draft.
+func (b *Bot) promptReceiptNewTagCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	messageID int,
+	expense *appmodels.Expense,
+) {
+	b.pendingEditsMu.Lock()
+	b.pendingEdits[chatID] = &pendingEdit{
+		ExpenseID: expense.ID,
+		EditType:  editTypeTagCB,
+		MessageID: messageID,
+	}
+	b.pendingEditsMu.Unlock()
+
+	text := `🏷️ <b>New Tag</b>
+
+Please type the tag to add (e.g., <code>#work</code>):`
+
+	keyboard := &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{Text: editCancelText, CallbackData: fmt.Sprintf(cancelEditCallback, expense.ID)},
+			},
+		},
+	}
+
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   messageID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: keyboard,
+	})
+}
+
+// processReceiptTagCore adds the typed tags to a receipt draft and returns
+// to the tag keyboard.
+func (b *Bot) processReceiptTagCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	userID int64,
+	pending *pendingEdit,
+	input string,
+) bool {
+	b.pendingEditsMu.Lock()
+	delete(b.pendingEdits, chatID)
+	b.pendingEditsMu.Unlock()
+
+	fields := strings.Fields(input)
+	if len(fields) == 0 || len(fields) > maxTagsPerCommand {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("❌ Please type between 1 and %d tags, e.g. #work.", maxTagsPerCommand),
+		})
+		return true
+	}
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		name := strings.TrimPrefix(field, "#")
+		if !isValidTagName(name) {
+			_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:    chatID,
+				Text:      fmt.Sprintf("❌ Invalid tag name: %s", botfmt.EscapeHTML(echoText(name))),
+				ParseMode: models.ParseModeHTML,
+			})
+			return true
+		}
+		names = append(names, strings.ToLower(name))
+	}
+
+	expense, err := b.expenseRepo.GetByID(ctx, pending.ExpenseID)
+	if err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, pending.ExpenseID).Msg(expenseNotFoundForEditLogMsgCB)
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   expenseNotFoundMsgCB,
+		})
+		return true
+	}
+
+	if expense.UserID != userID {
+		logger.Log.Warn().Str(logFieldUserHashCB, logger.HashUserID(userID)).Int(logFieldExpenseIDCB, pending.ExpenseID).Msg(userMismatchOnEditMsgCB)
+		return true
+	}
+
+	if err := b.addTagsByName(ctx, expense.ID, names); err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to tag receipt draft")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to add tags. Please try again.",
+		})
+		return true
+	}
+
+	logger.Log.Info().
+		Int(logFieldExpenseIDCB, expense.ID).
+		Int("tag_count", len(names)).
+		Msg("Tags added to receipt draft")
+
+	b.showReceiptTagsCore(ctx, tg, chatID, pending.MessageID, expense)
+	return true
+}
+
+// handleReceiptTagCallback handles taps on the receipt tag keyboard.
+func (b *Bot) handleReceiptTagCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleReceiptTagCallbackCore(ctx, tgBot, update)
+}
+
+// handleReceiptTagCallbackCore is the testable implementation of
+// handleReceiptTagCallback. Callback data is rtag_<expense>_<tag> to toggle
+// a tag or rtag_<expense>_new to type a new one. Tags are linked to the
+// draft right away; canceling the receipt deletes the draft and its links.
+func (b *Bot) handleReceiptTagCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(query.Data, receiptTagCallbackPrefix), "_")
+	if len(parts) != 2 {
+		answerCallback(ctx, tg, query)
+		return
+	}
+	expenseID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		answerCallback(ctx, tg, query)
+		return
+	}
+
+	expense, ok := b.loadCallbackExpense(ctx, tg, query, expenseID, false)
+	if !ok {
+		return
+	}
+	if expense.Status != appmodels.ExpenseStatusDraft {
+		respondStaleCallback(ctx, tg, query, staleExpenseConfirmed, false)
+		return
+	}
+
+	chatID := query.Message.Message.Chat.ID
+	messageID := query.Message.Message.ID
+
+	if parts[1] == "new" {
+		answerCallback(ctx, tg, query)
+		b.promptReceiptNewTagCore(ctx, tg, chatID, messageID, expense)
+		return
+	}
+
+	tagID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		answerCallback(ctx, tg, query)
+		return
+	}
+
+	b.loadExpenseTags(ctx, expense)
+	attached := false
+	for _, tag := range expense.Tags {
+		if tag.ID == tagID {
+			attached = true
+			break
+		}
+	}
+	if attached {
+		err = b.tagRepo.RemoveTagFromExpense(ctx, expense.ID, tagID)
+	} else {
+		err = b.tagRepo.AddTagsToExpense(ctx, expense.ID, []int{tagID})
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to toggle receipt tag")
+		respondStaleCallback(ctx, tg, query, staleLookupFailed, false)
+		return
+	}
+
+	answerCallback(ctx, tg, query)
+	b.showReceiptTagsCore(ctx, tg, chatID, messageID, expense)
+}
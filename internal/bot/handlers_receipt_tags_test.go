@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestBuildReceiptTagKeyboard(t *testing.T) {
+	t.Parallel()
+
+	tags := []appmodels.Tag{{ID: 1, Name: "work"}, {ID: 2, Name: "home"}, {ID: 3, Name: "trip"}}
+	keyboard := buildReceiptTagKeyboard(9, tags, map[int]bool{2: true})
+	require.Len(t, keyboard.InlineKeyboard, 4)
+	require.Equal(t, "#work", keyboard.InlineKeyboard[0][0].Text)
+	require.Equal(t, "✅ #home", keyboard.InlineKeyboard[0][1].Text)
+	require.Equal(t, "rtag_9_2", keyboard.InlineKeyboard[0][1].CallbackData)
+	require.Len(t, keyboard.InlineKeyboard[1], 1)
+	require.Equal(t, "rtag_9_new", keyboard.InlineKeyboard[2][0].CallbackData)
+	require.Equal(t, "receipt_back_9", keyboard.InlineKeyboard[3][0].CallbackData)
+}
+
+func TestReceiptTags(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(395001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Tagger"}))
+
+	newDraft := func(mockBot *mocks.MockBot) *appmodels.Expense {
+		expense := b.saveReceiptDraft(ctx, mockBot, userID, userID, "", &gemini.ReceiptData{
+			Amount:   mustParseDecimal("18.00"),
+			Currency: currencyCodeSGD,
+			Merchant: "Cafe",
+		})
+		require.NotNil(t, expense)
+		return expense
+	}
+	tap := func(mockBot *mocks.MockBot, data string) *mocks.EditedMessage {
+		update := mocks.CallbackQueryUpdate(userID, userID, 5, data)
+		if strings.HasPrefix(data, receiptTagCallbackPrefix) {
+			b.handleReceiptTagCallbackCore(ctx, mockBot, update)
+		} else {
+			b.handleReceiptCallbackCore(ctx, mockBot, update)
+		}
+		return mockBot.LastEditedMessage()
+	}
+	tagNames := func(expenseID int) []string {
+		tags, err := b.tagRepo.GetByExpenseID(ctx, expenseID)
+		require.NoError(t, err)
+		names := make([]string, len(tags))
+		for i, tag := range tags {
+			names[i] = tag.Name
+		}
+		return names
+	}
+
+	t.Run("zero tags goes straight to typing a new one", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := newDraft(mockBot)
+
+		menu := tap(mockBot, fmt.Sprintf("receipt_edit_%d", expense.ID))
+		require.Contains(t, fmt.Sprint(requireInlineKeyboard(t, menu.ReplyMarkup).InlineKeyboard), receiptTagsButtonText)
+
+		edited := tap(mockBot, fmt.Sprintf(receiptTagsCallbackFmt, expense.ID))
+		require.Contains(t, edited.Text, "New Tag")
+
+		require.True(t, b.handlePendingEditCore(ctx, mockBot, mocks.MessageUpdate(userID, userID, "#bad-tag!")))
+		require.Contains(t, mockBot.LastSentMessage().Text, "Invalid tag name")
+		require.Empty(t, tagNames(expense.ID))
+
+		tap(mockBot, fmt.Sprintf(receiptTagNewFmt, expense.ID))
+		require.True(t, b.handlePendingEditCore(ctx, mockBot, mocks.MessageUpdate(userID, userID, "#Work")))
+		edited = mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, "🏷️ Tags: #work")
+		require.Equal(t, "✅ #work", requireInlineKeyboard(t, edited.ReplyMarkup).InlineKeyboard[0][0].Text)
+		require.Equal(t, []string{"work"}, tagNames(expense.ID))
+	})
+
+	t.Run("attach then confirm", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := newDraft(mockBot)
+
+		edited := tap(mockBot, fmt.Sprintf(receiptTagsCallbackFmt, expense.ID))
+		button := requireInlineKeyboard(t, edited.ReplyMarkup).InlineKeyboard[0][0]
+		require.Equal(t, "#work", button.Text)
+
+		edited = tap(mockBot, button.CallbackData)
+		require.Equal(t, "✅ #work", requireInlineKeyboard(t, edited.ReplyMarkup).InlineKeyboard[0][0].Text)
+
+		edited = tap(mockBot, fmt.Sprintf("receipt_back_%d", expense.ID))
+		require.Contains(t, edited.Text, "🏷️ Tags: #work")
+
+		edited = tap(mockBot, fmt.Sprintf("receipt_confirm_%d", expense.ID))
+		require.Contains(t, edited.Text, "Expense Confirmed")
+		require.Equal(t, []string{"work"}, tagNames(expense.ID))
+
+		tagged, err := b.tagRepo.GetByName(ctx, "work")
+		require.NoError(t, err)
+		confirmed, err := b.tagRepo.GetExpensesByTagID(ctx, userID, tagged.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, confirmed, 1)
+		require.Equal(t, expense.ID, confirmed[0].ID)
+
+		b.handleReceiptTagCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 5, button.CallbackData))
+		require.Equal(t, staleExpenseConfirmedText, mockBot.AnsweredCallbacks[len(mockBot.AnsweredCallbacks)-1].Text)
+		require.Equal(t, []string{"work"}, tagNames(expense.ID), "confirmed expenses keep their tags")
+	})
+
+	t.Run("toggling twice removes the tag", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := newDraft(mockBot)
+
+		edited := tap(mockBot, fmt.Sprintf(receiptTagsCallbackFmt, expense.ID))
+		data := requireInlineKeyboard(t, edited.ReplyMarkup).InlineKeyboard[0][0].CallbackData
+		tap(mockBot, data)
+		require.Equal(t, []string{"work"}, tagNames(expense.ID))
+		edited = tap(mockBot, data)
+		require.Equal(t, "#work", requireInlineKeyboard(t, edited.ReplyMarkup).InlineKeyboard[0][0].Text)
+		require.Empty(t, tagNames(expense.ID))
+	})
+
+	t.Run("cancel leaves no links", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := newDraft(mockBot)
+
+		edited := tap(mockBot, fmt.Sprintf(receiptTagsCallbackFmt, expense.ID))
+		tap(mockBot, requireInlineKeyboard(t, edited.ReplyMarkup).InlineKeyboard[0][0].CallbackData)
+		require.Equal(t, []string{"work"}, tagNames(expense.ID))
+
+		edited = tap(mockBot, fmt.Sprintf("receipt_cancel_%d", expense.ID))
+		require.Contains(t, edited.Text, "canceled")
+
+		var links int
+		require.NoError(t, pool.QueryRow(ctx,
+			`SELECT COUNT(*) FROM expense_tags WHERE expense_id = $1`, expense.ID).Scan(&links))
+		require.Zero(t, links)
+	})
+}
@@ -8,8 +8,10 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
 	"github.com/stretchr/testify/require"
 	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
 	"gitlab.com/yelinaung/expense-bot/internal/gemini"
@@ -29,10 +31,10 @@ func TestBuildReceiptConfirmationKeyboard(t *testing.T) {
 
 	t.Run("creates keyboard with correct buttons", func(t *testing.T) {
 		t.Parallel()
-		keyboard := buildReceiptConfirmationKeyboard(123)
+		keyboard := buildReceiptConfirmationKeyboard(123, currencyCodeSGD)
 
 		require.NotNil(t, keyboard)
-		require.Len(t, keyboard.InlineKeyboard, 1)
+		require.Len(t, keyboard.InlineKeyboard, 3)
 		require.Len(t, keyboard.InlineKeyboard[0], 3)
 
 		require.Equal(t, "✅ Confirm", keyboard.InlineKeyboard[0][0].Text)
@@ -43,6 +45,9 @@ func TestBuildReceiptConfirmationKeyboard(t *testing.T) {
 
 		require.Equal(t, "❌ Cancel", keyboard.InlineKeyboard[0][2].Text)
 		require.Equal(t, "receipt_cancel_123", keyboard.InlineKeyboard[0][2].CallbackData)
+
+		require.Equal(t, buildReceiptAdjustRow(123, currencyCodeSGD), keyboard.InlineKeyboard[1])
+		require.Equal(t, "merchant_history_123", keyboard.InlineKeyboard[2][0].CallbackData)
 	})
 }
 
@@ -85,7 +90,7 @@ func TestHandleReceiptCallbackCore(t *testing.T) {
 		require.Len(t, mockBot.AnsweredCallbacks, 1)
 	})
 
-	t.Run("expense not found shows error", func(t *testing.T) {
+	t.Run("expense not found answers with toast", func(t *testing.T) {
 		mockBot := mocks.NewMockBot()
 		update := &models.Update{
 			CallbackQuery: &models.CallbackQuery{
@@ -101,8 +106,10 @@ func TestHandleReceiptCallbackCore(t *testing.T) {
 			},
 		}
 		b.handleReceiptCallbackCore(ctx, mockBot, update)
-		require.Len(t, mockBot.EditedMessages, 1)
-		require.Contains(t, mockBot.EditedMessages[0].Text, "no longer available")
+		require.Len(t, mockBot.AnsweredCallbacks, 1)
+		require.Equal(t, staleExpenseGoneText, mockBot.AnsweredCallbacks[0].Text)
+		require.Len(t, mockBot.EditedReplyMarkups, 1)
+		require.Zero(t, mockBot.EditedMessageCount())
 	})
 
 	t.Run("invalid expense id returns early", func(t *testing.T) {
@@ -380,9 +387,11 @@ func TestHandlePhotoCore_DownloadError(t *testing.T) {
 
 	b.handlePhotoCore(context.Background(), mockBot, update)
 
-	require.Equal(t, 2, mockBot.SentMessageCount())
+	require.Equal(t, 1, mockBot.SentMessageCount())
 	require.Contains(t, mockBot.SentMessages[0].Text, testProcessingReceiptText)
-	require.Contains(t, mockBot.SentMessages[1].Text, "Failed to download photo")
+	edited := mockBot.LastEditedMessage()
+	require.Equal(t, testPlaceholderMessageID, edited.MessageID, "the placeholder becomes the reply")
+	require.Contains(t, edited.Text, "Failed to download photo")
 }
 
 func TestHandlePhotoCore_ParseError(t *testing.T) {
@@ -407,9 +416,11 @@ func TestHandlePhotoCore_ParseError(t *testing.T) {
 
 	b.handlePhotoCore(context.Background(), mockBot, update)
 
-	require.Equal(t, 2, mockBot.SentMessageCount())
+	require.Equal(t, 1, mockBot.SentMessageCount())
 	require.Contains(t, mockBot.SentMessages[0].Text, testProcessingReceiptText)
-	require.Contains(t, mockBot.SentMessages[1].Text, "Could not read this receipt")
+	edited := mockBot.LastEditedMessage()
+	require.Equal(t, testPlaceholderMessageID, edited.MessageID, "the placeholder becomes the reply")
+	require.Contains(t, edited.Text, "Could not read this receipt")
 }
 
 func TestHandlePhotoCore_Success(t *testing.T) {
@@ -423,6 +434,7 @@ func TestHandlePhotoCore_Success(t *testing.T) {
 	}))
 	b.geminiClient = gemini.NewClientWithGenerator(&botTestGenerator{
 		response: &genai.GenerateContentResponse{
+			ModelVersion: "gemini-2.5-flash-001",
 			Candidates: []*genai.Candidate{
 				{
 					Content: &genai.Content{
@@ -450,7 +462,131 @@ func TestHandlePhotoCore_Success(t *testing.T) {
 
 	b.handlePhotoCore(ctx, mockBot, update)
 
-	require.Equal(t, 2, mockBot.SentMessageCount())
+	require.Equal(t, 1, mockBot.SentMessageCount())
+	require.Contains(t, mockBot.SentMessages[0].Text, testProcessingReceiptText)
+	edited := mockBot.LastEditedMessage()
+	require.Equal(t, testPlaceholderMessageID, edited.MessageID, "the placeholder becomes the reply")
+	require.Contains(t, edited.Text, "Receipt Scanned")
+
+	t.Run("draft records the model and prompt version", func(t *testing.T) {
+		var draftID int
+		err := pool.QueryRow(ctx,
+			`SELECT id FROM expenses WHERE user_id = $1 AND status = 'draft'`, 100).Scan(&draftID)
+		require.NoError(t, err)
+
+		scan, err := b.receiptScanRepo.GetByExpenseID(ctx, draftID)
+		require.NoError(t, err)
+		require.Equal(t, "gemini-2.5-flash-001", scan.Model)
+		require.Equal(t, gemini.ReceiptPromptVersion, scan.PromptVersion)
+	})
+
+	t.Run("manual expense has no scan metadata", func(t *testing.T) {
+		b.handleAddCore(ctx, mocks.NewMockBot(), mocks.CommandUpdate(12345, 100, "/add 4.50 Kopi"))
+
+		expenses, err := b.expenseRepo.GetByUserID(ctx, 100, 10)
+		require.NoError(t, err)
+		require.Len(t, expenses, 1)
+
+		_, err = b.receiptScanRepo.GetByExpenseID(ctx, expenses[0].ID)
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+}
+
+func albumPhotoUpdate(chatID, userID int64, fileID, groupID string) *models.Update {
+	update := mocks.PhotoUpdate(chatID, userID, fileID)
+	update.Message.MediaGroupID = groupID
+	return update
+}
+
+func TestHandlePhotoCore_MediaGroupSinglePhoto(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{
+		geminiClient: gemini.NewClientWithGenerator(&botTestGenerator{
+			err: errors.New("parse failed"),
+		}),
+		httpClient: &http.Client{
+			Transport: receiptRoundTripperFunc(func(*http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("fake-image-bytes")),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		},
+		mediaGroupWait: 10 * time.Millisecond,
+	}
+	mockBot := mocks.NewMockBot()
+
+	b.handlePhotoCore(context.Background(), mockBot, albumPhotoUpdate(12345, 100, testPhotoFileID, "album-1"))
+	require.Equal(t, 0, mockBot.SentMessageCount())
+
+	b.mediaGroups.wait()
+
+	require.Equal(t, 0, b.mediaGroups.pending())
+	require.Equal(t, 1, mockBot.SentMessageCount())
 	require.Contains(t, mockBot.SentMessages[0].Text, testProcessingReceiptText)
-	require.Contains(t, mockBot.SentMessages[1].Text, "Receipt Scanned")
+	edited := mockBot.LastEditedMessage()
+	require.Equal(t, testPlaceholderMessageID, edited.MessageID, "the placeholder becomes the reply")
+	require.Contains(t, edited.Text, "Could not read this receipt")
+}
+
+func TestHandlePhotoCore_MediaGroup(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	userID := int64(378001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{
+		ID:        userID,
+		FirstName: "Album",
+	}))
+	b.geminiClient = gemini.NewClientWithGenerator(&botTestGenerator{
+		response: &genai.GenerateContentResponse{
+			Candidates: []*genai.Candidate{
+				{
+					Content: &genai.Content{
+						Parts: []*genai.Part{
+							{
+								Text: `{"amount":"48.20","currency":"SGD","merchant":"Market","date":"2026-02-26","suggested_category":"Food - Dining Out","confidence":0.9}`,
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+	b.httpClient = &http.Client{
+		Transport: receiptRoundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("fake-image-bytes")),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+	b.mediaGroupWait = 50 * time.Millisecond
+	mockBot := mocks.NewMockBot()
+
+	b.handlePhotoCore(ctx, mockBot, albumPhotoUpdate(userID, userID, "receipt-top", "album-2"))
+	b.handlePhotoCore(ctx, mockBot, albumPhotoUpdate(userID, userID, "receipt-bottom", "album-2"))
+	b.mediaGroups.wait()
+
+	require.Equal(t, 1, mockBot.SentMessageCount())
+	require.Contains(t, mockBot.SentMessages[0].Text, testProcessingReceiptText)
+	edited := mockBot.LastEditedMessage()
+	require.Equal(t, testPlaceholderMessageID, edited.MessageID, "the placeholder becomes the reply")
+	require.Contains(t, edited.Text, "Receipt Scanned")
+	require.Contains(t, edited.Text, "2 images scanned")
+
+	var draftID int
+	var fileID string
+	err := pool.QueryRow(ctx,
+		`SELECT id, receipt_file_id FROM expenses WHERE user_id = $1 AND status = 'draft'`,
+		userID).Scan(&draftID, &fileID)
+	require.NoError(t, err)
+	require.Equal(t, "receipt-top", fileID)
+
+	scan, err := b.receiptScanRepo.GetByExpenseID(ctx, draftID)
+	require.NoError(t, err)
+	require.Equal(t, gemini.ReceiptMultiImagePromptVersion, scan.PromptVersion)
 }
@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	editTypeTipCB         = "tip"
+	receiptTipAction      = "tip"
+	receiptTipCallbackFmt = "receipt_tip_%d"
+	receiptTipButtonText  = "➕ Add tip"
+)
+
+// receiptChargedAmount picks the amount a receipt draft is saved with. The
+// model's amount is used unless the separate parts disagree with it:
+//   - a grand total wins over a different amount;
+//   - without a total, an amount equal to the subtotal (or missing) becomes
+//     subtotal plus tax and tip;
+//   - a subtotal alone stands in for a missing amount.
+func receiptChargedAmount(data *gemini.ReceiptData) decimal.Decimal {
+	if data.Total.IsPositive() {
+		return data.Total
+	}
+	if !data.Subtotal.IsPositive() {
+		return data.Amount
+	}
+	if !data.Amount.IsZero() && !data.Amount.Equal(data.Subtotal) {
+		return data.Amount
+	}
+	return data.Subtotal.Add(data.Tax).Add(data.Tip)
+}
+
+// receiptHasOnlySubtotal reports whether the receipt showed a subtotal but
+// neither a tip nor a grand total, so the user may still want to add a tip.
+func receiptHasOnlySubtotal(data *gemini.ReceiptData) bool {
+	return data.Subtotal.IsPositive() && !data.Tip.IsPositive() && !data.Total.IsPositive()
+}
+
+// receiptBreakdownLine renders the receipt's separate amounts in the
+// receipt's own currency, falling back to the draft's currency.
+func receiptBreakdownLine(data *gemini.ReceiptData, currency string) string {
+	if data.Currency != "" {
+		currency = strings.ToUpper(data.Currency)
+	}
+	return botfmt.ReceiptBreakdownLine(botfmt.ReceiptBreakdown{
+		Subtotal: data.Subtotal,
+		Tax:      data.Tax,
+		Tip:      data.Tip,
+		Total:    data.Total,
+		Currency: currency,
+	})
+}
+
+// buildReceiptTipKeyboard is the receipt confirmation keyboard with an extra
+// row for adding a tip to the subtotal.
+func buildReceiptTipKeyboard(expenseID int, currency string) *models.InlineKeyboardMarkup {
+	keyboard := buildReceiptConfirmationKeyboard(expenseID, currency)
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []models.InlineKeyboardButton{
+		{Text: receiptTipButtonText, CallbackData: fmt.Sprintf(receiptTipCallbackFmt, expenseID)},
+	})
+	return keyboard
+}
+
+// promptReceiptTipCore asks for the tip to add to a receipt draft.
+func (b *Bot) promptReceiptTipCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	messageID int,
+	expense *appmodels.Expense,
+) {
+	b.pendingEditsMu.Lock()
+	b.pendingEdits[chatID] = &pendingEdit{
+		ExpenseID: expense.ID,
+		EditType:  editTypeTipCB,
+		MessageID: messageID,
+	}
+	b.pendingEditsMu.Unlock()
+
+	text := fmt.Sprintf(`➕ <b>Add Tip</b>
+
+Current total: %s
+
+Please type the tip amount (e.g., <code>6.60</code>):`,
+		botfmt.Money(expense.Amount, expense.Currency))
+
+	keyboard := &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{Text: editCancelText, CallbackData: fmt.Sprintf(cancelEditCallback, expense.ID)},
+			},
+		},
+	}
+
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   messageID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: keyboard,
+	})
+}
+
+// processTipEditCore adds the typed tip to a receipt draft's amount. A tip
+// that would take the amount above the hard cap is refused, and a recorded
+// conversion to the default currency is kept for the new amount.
+func (b *Bot) processTipEditCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	userID int64,
+	pending *pendingEdit,
+	input string,
+) bool {
+	b.pendingEditsMu.Lock()
+	delete(b.pendingEdits, chatID)
+	b.pendingEditsMu.Unlock()
+
+	input = strings.TrimSpace(input)
+	input = strings.TrimPrefix(input, "$")
+	input = strings.TrimSpace(input)
+
+	tip, err := parseAmount(input)
+	if err != nil {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Invalid tip. Please enter a valid number (e.g., 6.60).",
+		})
+		return true
+	}
+
+	expense, err := b.expenseRepo.GetByID(ctx, pending.ExpenseID)
+	if err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, pending.ExpenseID).Msg(expenseNotFoundForEditLogMsgCB)
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   expenseNotFoundMsgCB,
+		})
+		return true
+	}
+
+	if expense.UserID != userID {
+		logger.Log.Warn().Str(logFieldUserHashCB, logger.HashUserID(userID)).Int(logFieldExpenseIDCB, pending.ExpenseID).Msg(userMismatchOnEditMsgCB)
+		return true
+	}
+
+	before := *expense
+	expense.Amount = expense.Amount.Add(tip)
+	if b.aboveAmountHardCap(expense.Amount) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text: fmt.Sprintf("❌ %s is above the maximum per expense, so the tip was not added.",
+				botfmt.Money(expense.Amount, expense.Currency)),
+			ParseMode: models.ParseModeHTML,
+		})
+		return true
+	}
+	if err := b.expenses().EditFields(ctx, expense); err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to add tip")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to add tip. Please try again.",
+		})
+		return true
+	}
+
+	logger.Log.Info().
+		Int(logFieldExpenseIDCB, expense.ID).
+		Str("tip", tip.String()).
+		Msg("Tip added via pending edit")
+	b.keepConversion(ctx, before, expense)
+	b.auditExpenseUpdate(ctx, tg, userID, before, expense)
+
+	b.loadExpenseCategory(ctx, expense)
+	b.loadExpenseTags(ctx, expense)
+
+	// The amount before the tip may already include tax, so it is not
+	// labelled as the subtotal.
+	text := botfmt.ReceiptDraftCard(expense, botfmt.DraftTipAdded) + "\n\n" +
+		fmt.Sprintf("🧾 Before tip %s + tip %s = %s",
+			symbolAmount(before.Amount, expense.Currency),
+			symbolAmount(tip, expense.Currency),
+			symbolAmount(expense.Amount, expense.Currency))
+
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   pending.MessageID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: buildReceiptConfirmationKeyboard(expense.ID, expense.Currency),
+	})
+
+	return true
+}
@@ -0,0 +1,182 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestReceiptChargedAmount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		amount       string
+		subtotal     string
+		tax          string
+		tip          string
+		total        string
+		want         string
+		onlySubtotal bool
+	}{
+		{name: "amount only", amount: "54.60", want: "54.60"},
+		{name: "consistent total", amount: "54.60", subtotal: "48", tip: "6.60", total: "54.60", want: "54.60"},
+		{name: "total wins over subtotal amount", amount: "48", subtotal: "48", tip: "6.60", total: "54.60", want: "54.60"},
+		{name: "total fills missing amount", total: "54.60", want: "54.60"},
+		{name: "parts added to subtotal amount", amount: "48", subtotal: "48", tax: "3.84", tip: "6.60", want: "58.44"},
+		{name: "parts fill missing amount", subtotal: "48", tip: "6.60", want: "54.60"},
+		{name: "amount differing from subtotal is kept", amount: "60", subtotal: "48", tip: "6.60", want: "60"},
+		{name: "subtotal only", amount: "48", subtotal: "48", want: "48", onlySubtotal: true},
+		{name: "subtotal and tax only", amount: "48", subtotal: "48", tax: "3.84", want: "51.84", onlySubtotal: true},
+		{name: "nothing found", want: "0"},
+	}
+
+	optional := func(s string) decimal.Decimal {
+		if s == "" {
+			return decimal.Zero
+		}
+		return mustParseDecimal(s)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := &gemini.ReceiptData{
+				Amount:   optional(tt.amount),
+				Subtotal: optional(tt.subtotal),
+				Tax:      optional(tt.tax),
+				Tip:      optional(tt.tip),
+				Total:    optional(tt.total),
+			}
+			got := receiptChargedAmount(data)
+			require.True(t, mustParseDecimal(tt.want).Equal(got), "got %s", got)
+			require.Equal(t, tt.onlySubtotal, receiptHasOnlySubtotal(data))
+		})
+	}
+}
+
+func TestBuildReceiptTipKeyboard(t *testing.T) {
+	t.Parallel()
+
+	keyboard := buildReceiptTipKeyboard(7, currencyCodeSGD)
+	require.Len(t, keyboard.InlineKeyboard, 4)
+	require.Len(t, keyboard.InlineKeyboard[0], 3)
+	require.Equal(t, receiptTipButtonText, keyboard.InlineKeyboard[3][0].Text)
+	require.Equal(t, "receipt_tip_7", keyboard.InlineKeyboard[3][0].CallbackData)
+}
+
+func TestReceiptTip(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(386001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Tipper"}))
+
+	t.Run("breakdown is shown when a total was found", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := b.saveReceiptDraft(ctx, mockBot, userID, userID, "", &gemini.ReceiptData{
+			Amount:   mustParseDecimal("48.00"),
+			Currency: currencyCodeSGD,
+			Merchant: "Bistro",
+			Subtotal: mustParseDecimal("48.00"),
+			Tip:      mustParseDecimal("6.60"),
+			Total:    mustParseDecimal("54.60"),
+		})
+		require.NotNil(t, expense)
+		require.True(t, mustParseDecimal("54.60").Equal(expense.Amount))
+
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, "Subtotal S$48.00 + tip S$6.60 = S$54.60")
+		require.Len(t, requireInlineKeyboard(t, msg.ReplyMarkup).InlineKeyboard, 3)
+	})
+
+	t.Run("tip is added to a subtotal", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := b.saveReceiptDraft(ctx, mockBot, userID, userID, "", &gemini.ReceiptData{
+			Amount:   mustParseDecimal("48.00"),
+			Currency: currencyCodeSGD,
+			Merchant: "Bistro",
+			Subtotal: mustParseDecimal("48.00"),
+		})
+		require.NotNil(t, expense)
+
+		keyboard := requireInlineKeyboard(t, mockBot.LastSentMessage().ReplyMarkup)
+		require.Len(t, keyboard.InlineKeyboard, 4)
+		data := keyboard.InlineKeyboard[3][0].CallbackData
+		require.Equal(t, fmt.Sprintf(receiptTipCallbackFmt, expense.ID), data)
+
+		b.handleReceiptCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 5, data))
+		require.Contains(t, mockBot.LastEditedMessage().Text, "Add Tip")
+
+		require.True(t, b.handlePendingEditCore(ctx, mockBot, mocks.MessageUpdate(userID, userID, "abc")))
+		require.Contains(t, mockBot.LastSentMessage().Text, "Invalid tip")
+
+		b.handleReceiptCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 5, data))
+		require.True(t, b.handlePendingEditCore(ctx, mockBot, mocks.MessageUpdate(userID, userID, "6.60")))
+
+		edited := mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, "Tip Added")
+		require.Contains(t, edited.Text, "Before tip S$48.00 + tip S$6.60 = S$54.60")
+		require.Len(t, requireInlineKeyboard(t, edited.ReplyMarkup).InlineKeyboard, 3)
+
+		stored, err := b.expenseRepo.GetByID(ctx, expense.ID)
+		require.NoError(t, err)
+		require.True(t, mustParseDecimal("54.60").Equal(stored.Amount))
+		require.Equal(t, appmodels.ExpenseStatusDraft, stored.Status)
+	})
+
+	tipDraft := func(t *testing.T, mockBot *mocks.MockBot, data *gemini.ReceiptData) *appmodels.Expense {
+		t.Helper()
+		expense := b.saveReceiptDraft(ctx, mockBot, userID, userID, "", data)
+		require.NotNil(t, expense)
+		b.handleReceiptCallbackCore(ctx, mockBot,
+			mocks.CallbackQueryUpdate(userID, userID, 5, fmt.Sprintf(receiptTipCallbackFmt, expense.ID)))
+		return expense
+	}
+
+	t.Run("a tip above the hard cap is refused", func(t *testing.T) {
+		b.cfg.AmountHardCap = decimal.NewFromInt(50)
+		t.Cleanup(func() { b.cfg.AmountHardCap = decimal.Zero })
+		mockBot := mocks.NewMockBot()
+		expense := tipDraft(t, mockBot, &gemini.ReceiptData{
+			Amount:   mustParseDecimal("48.00"),
+			Currency: currencyCodeSGD,
+			Merchant: "Bistro",
+			Subtotal: mustParseDecimal("48.00"),
+		})
+
+		require.True(t, b.handlePendingEditCore(ctx, mockBot, mocks.MessageUpdate(userID, userID, "6.60")))
+		require.Contains(t, mockBot.LastSentMessage().Text, "the tip was not added")
+		stored, err := b.expenseRepo.GetByID(ctx, expense.ID)
+		require.NoError(t, err)
+		require.True(t, mustParseDecimal("48.00").Equal(stored.Amount))
+	})
+
+	t.Run("a converted draft keeps its conversion", func(t *testing.T) {
+		b.exchangeService = rateTable{"USD/SGD": "1.35"}
+		t.Cleanup(func() { b.exchangeService = nil })
+		mockBot := mocks.NewMockBot()
+		expense := tipDraft(t, mockBot, &gemini.ReceiptData{
+			Amount:   mustParseDecimal("40.00"),
+			Currency: "USD",
+			Merchant: "Diner",
+			Subtotal: mustParseDecimal("40.00"),
+		})
+		require.True(t, mustParseDecimal("54.00").Equal(expense.Amount))
+
+		require.True(t, b.handlePendingEditCore(ctx, mockBot, mocks.MessageUpdate(userID, userID, "6.00")))
+		stored, err := b.expenseRepo.GetByID(ctx, expense.ID)
+		require.NoError(t, err)
+		require.True(t, mustParseDecimal("60.00").Equal(stored.Amount))
+		require.True(t, stored.ConvertedAmount.Valid)
+		require.True(t, mustParseDecimal("60.00").Equal(stored.ConvertedAmount.Decimal))
+		require.Equal(t, currencyCodeSGD, stored.ConvertedCurrency)
+		require.True(t, mustParseDecimal("1.35").Equal(stored.RateToDefault.Decimal))
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"testing"
-	"time"
 
 	"github.com/go-telegram/bot/models"
 	"github.com/shopspring/decimal"
@@ -83,38 +82,17 @@ func TestFindCategoryByName(t *testing.T) {
 	require.Nil(t, cat)
 }
 
-func TestBuildReceiptConfirmationText(t *testing.T) {
-	t.Parallel()
-
-	expense := &appmodels.Expense{
-		Amount:   decimal.RequireFromString("24.30"),
-		Currency: testCurrencySGD,
-		Merchant: "Cafe",
-		Category: &appmodels.Category{Name: testCategoryFood},
-	}
-	date := time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)
-
-	partial := buildReceiptConfirmationText(expense, date, true)
-	require.Contains(t, partial, "Partial Extraction")
-	require.Contains(t, partial, "24.30")
-	require.Contains(t, partial, testCategoryFood)
-
-	full := buildReceiptConfirmationText(expense, date, false)
-	require.Contains(t, full, "Receipt Scanned")
-	require.Contains(t, full, "15 Feb 2026")
-}
-
 func TestSendReceiptParseError(t *testing.T) {
 	t.Parallel()
 
 	mockBot := mocks.NewMockBot()
 	ctx := context.Background()
 
-	sendReceiptParseError(ctx, mockBot, 123, gemini.ErrParseTimeout)
+	sendReceiptParseError(ctx, mockBot, 123, 0, gemini.ErrParseTimeout)
 	require.Equal(t, 1, mockBot.SentMessageCount())
 	require.Contains(t, mockBot.LastSentMessage().Text, "timed out")
 
-	sendReceiptParseError(ctx, mockBot, 123, gemini.ErrNoData)
+	sendReceiptParseError(ctx, mockBot, 123, 0, gemini.ErrNoData)
 	require.Equal(t, 2, mockBot.SentMessageCount())
 	require.Contains(t, mockBot.LastSentMessage().Text, "Could not read this receipt")
 }
@@ -134,48 +112,6 @@ func TestSendVoiceParseError(t *testing.T) {
 	require.Contains(t, mockBot.LastSentMessage().Text, "Could not extract expense information")
 }
 
-func TestBuildVoiceConfirmationText(t *testing.T) {
-	t.Parallel()
-
-	expense := &appmodels.Expense{
-		Amount:      decimal.RequireFromString("8.90"),
-		Currency:    testCurrencySGD,
-		Description: "Taxi",
-		Category:    &appmodels.Category{Name: testCategoryTransport},
-	}
-	text := buildVoiceConfirmationText(expense)
-	require.Contains(t, text, "Voice Expense Detected")
-	require.Contains(t, text, "Taxi")
-	require.Contains(t, text, testCategoryTransport)
-}
-
-func TestApplyMatchedSuggestion(t *testing.T) {
-	t.Parallel()
-
-	b := &Bot{}
-	expense := &appmodels.Expense{}
-	categories := []appmodels.Category{
-		{ID: 1, Name: testCategoryFood},
-		{ID: 2, Name: testCategoryTransport},
-	}
-
-	ok := b.applyMatchedSuggestion(expense, "lunch", &gemini.CategorySuggestion{
-		Category:   "food",
-		Confidence: 0.9,
-		Reasoning:  "restaurant expense",
-	}, categories)
-	require.True(t, ok)
-	require.NotNil(t, expense.CategoryID)
-	require.Equal(t, 1, *expense.CategoryID)
-
-	expense = &appmodels.Expense{}
-	ok = b.applyMatchedSuggestion(expense, "lunch", &gemini.CategorySuggestion{
-		Category: "unknown",
-	}, categories)
-	require.False(t, ok)
-	require.Nil(t, expense.CategoryID)
-}
-
 func TestSendEditConfirmation(t *testing.T) {
 	t.Parallel()
 
@@ -188,7 +124,9 @@ func TestSendEditConfirmation(t *testing.T) {
 		Category:          &appmodels.Category{Name: testCategoryFood},
 	}
 
-	sendEditConfirmation(context.Background(), mockBot, 100, expense)
+	before := *expense
+	before.Amount = decimal.RequireFromString("9.00")
+	sendEditConfirmation(context.Background(), mockBot, 100, &before, expense, "")
 
 	require.Equal(t, 1, mockBot.SentMessageCount())
 	msg := mockBot.LastSentMessage()
@@ -196,7 +134,9 @@ func TestSendEditConfirmation(t *testing.T) {
 	require.Equal(t, models.ParseModeHTML, msg.ParseMode)
 	require.Contains(t, msg.Text, "Expense Updated")
 	require.Contains(t, msg.Text, "#7")
+	require.Contains(t, msg.Text, "9.00 SGD → S$11.50 SGD")
 	require.Contains(t, msg.Text, "Breakfast")
+	require.NotContains(t, msg.Text, "Breakfast →")
 	require.Contains(t, msg.Text, testCategoryFood)
 }
 
@@ -275,22 +215,13 @@ func TestSendEmptyExpenseList(t *testing.T) {
 	require.Contains(t, mockBot.LastSentMessage().Text, "No expenses found")
 }
 
-func TestIsValidAutoCreatedCategoryName(t *testing.T) {
-	t.Parallel()
-
-	require.False(t, isValidAutoCreatedCategoryName(""))
-	require.False(t, isValidAutoCreatedCategoryName(" \t "))
-	require.False(t, isValidAutoCreatedCategoryName("bad\nname"))
-	require.True(t, isValidAutoCreatedCategoryName("Travel"))
-}
-
-func TestApplyNewCategorySuggestion(t *testing.T) {
+func TestApplyNewCategory(t *testing.T) {
 	ctx := context.Background()
 	pool := testDB(ctx, t)
 	b := setupTestBot(t, pool)
 
 	expense := &appmodels.Expense{}
-	ok := b.applyNewCategorySuggestion(ctx, expense, "desc", &gemini.CategorySuggestion{
+	ok := b.expenses().ApplyNewCategory(ctx, expense, "desc", &gemini.CategorySuggestion{
 		NewCategoryName: "bad\nname",
 		Confidence:      0.95,
 	}, nil)
@@ -299,7 +230,7 @@ func TestApplyNewCategorySuggestion(t *testing.T) {
 
 	categories := []appmodels.Category{{ID: 42, Name: testCategoryFood}}
 	expense = &appmodels.Expense{}
-	ok = b.applyNewCategorySuggestion(ctx, expense, "desc", &gemini.CategorySuggestion{
+	ok = b.expenses().ApplyNewCategory(ctx, expense, "desc", &gemini.CategorySuggestion{
 		NewCategoryName: "food",
 		Confidence:      0.95,
 	}, categories)
@@ -308,7 +239,7 @@ func TestApplyNewCategorySuggestion(t *testing.T) {
 	require.Equal(t, 42, *expense.CategoryID)
 
 	expense = &appmodels.Expense{}
-	ok = b.applyNewCategorySuggestion(ctx, expense, "desc", &gemini.CategorySuggestion{
+	ok = b.expenses().ApplyNewCategory(ctx, expense, "desc", &gemini.CategorySuggestion{
 		NewCategoryName: "NewCategory",
 		Confidence:      0.95,
 	}, nil)
@@ -317,7 +248,7 @@ func TestApplyNewCategorySuggestion(t *testing.T) {
 	require.Equal(t, "NewCategory", expense.Category.Name)
 }
 
-func TestSaveInlineTags(t *testing.T) {
+func TestSaveTags(t *testing.T) {
 	ctx := context.Background()
 	pool := testDB(ctx, t)
 	b := setupTestBot(t, pool)
@@ -340,7 +271,7 @@ func TestSaveInlineTags(t *testing.T) {
 	}
 	require.NoError(t, b.expenseRepo.Create(ctx, exp))
 
-	b.saveInlineTags(ctx, exp.ID, []string{"food", "snack"})
+	b.expenses().SaveTags(ctx, exp.ID, []string{"food", "snack"})
 	tags, err := b.tagRepo.GetByExpenseID(ctx, exp.ID)
 	require.NoError(t, err)
 	require.Len(t, tags, 2)
@@ -372,13 +303,13 @@ func TestHandleVoiceCore_GeminiNotConfigured(t *testing.T) {
 	require.Contains(t, mockBot.LastSentMessage().Text, "Voice expense input is not configured")
 }
 
-func TestSaveInlineTags_NoTags(t *testing.T) {
+func TestSaveTags_NoTags(t *testing.T) {
 	t.Parallel()
 	b := &Bot{}
-	b.saveInlineTags(context.Background(), 1, nil)
+	b.expenses().SaveTags(context.Background(), 1, nil)
 }
 
-func TestApplyNewCategorySuggestion_CreateError(t *testing.T) {
+func TestApplyNewCategory_CreateError(t *testing.T) {
 	ctx := context.Background()
 	pool := testDB(ctx, t)
 	b := setupTestBot(t, pool)
@@ -388,7 +319,7 @@ func TestApplyNewCategorySuggestion_CreateError(t *testing.T) {
 	require.NoError(t, err)
 
 	expense := &appmodels.Expense{}
-	ok := b.applyNewCategorySuggestion(ctx, expense, "desc", &gemini.CategorySuggestion{
+	ok := b.expenses().ApplyNewCategory(ctx, expense, "desc", &gemini.CategorySuggestion{
 		NewCategoryName: "DupCat",
 		Confidence:      0.95,
 	}, nil)
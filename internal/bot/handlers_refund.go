@@ -0,0 +1,22 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const refundUsageMsg = "❌ Invalid format. Use: <code>/refund 25.90 Amazon return [category]</code>"
+
+// handleRefund handles the /refund command.
+func (b *Bot) handleRefund(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleRefundCore(ctx, tgBot, update)
+}
+
+// handleRefundCore records a refund, such as money back for a returned
+// order. It is saved like /add but with a negative amount, so it lowers
+// the totals and budget spending of its category.
+func (b *Bot) handleRefundCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	b.addFromCommand(ctx, tg, update, ParseRefundCommandWithCategories, refundUsageMsg)
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestParseRefund(t *testing.T) {
+	t.Parallel()
+
+	parsed := ParseExpenseInput("refund 25.90 Amazon return")
+	require.NotNil(t, parsed)
+	require.True(t, parsed.Refund)
+	require.True(t, mustParseDecimal("25.90").Equal(parsed.Amount), "the amount stays positive until saved")
+	require.Equal(t, "Amazon return", parsed.Description)
+
+	parsed = ParseExpenseInput("Refund Taxi S$12")
+	require.NotNil(t, parsed)
+	require.True(t, parsed.Refund)
+	require.Equal(t, currencyCodeSGD, parsed.Currency)
+	require.Equal(t, "Taxi", parsed.Description)
+
+	parsed = ParseRefundCommandWithCategories("/refund@expense_bot 8 Socks [Shopping]", []string{"Shopping"})
+	require.NotNil(t, parsed)
+	require.True(t, parsed.Refund)
+	require.Equal(t, "Socks", parsed.Description)
+	require.Equal(t, "Shopping", parsed.CategoryName)
+
+	require.False(t, ParseExpenseInput("refunded 5").Refund, "only the whole word starts a refund")
+	require.False(t, ParseAddCommand("/add 5.50 Coffee").Refund)
+	require.Nil(t, ParseExpenseInput("refund"))
+	require.Nil(t, ParseRefundCommand("/refund Amazon"))
+}
+
+func TestHandleRefundCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(448001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Shopper"}))
+	_, err := b.categoryRepo.Create(ctx, "Sneakers")
+	require.NoError(t, err)
+	b.invalidateCategoryCache()
+
+	command := func(text string) *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		update := mocks.CommandUpdate(userID, userID, text)
+		switch {
+		case strings.HasPrefix(text, "/budget"):
+			b.handleBudgetCore(ctx, mockBot, update)
+		case strings.HasPrefix(text, "/refund"):
+			b.handleRefundCore(ctx, mockBot, update)
+		default:
+			b.handleAddCore(ctx, mockBot, update)
+		}
+		return mockBot
+	}
+	total := func() string {
+		start := time.Now().AddDate(0, 0, -1)
+		sum, err := b.expenseRepo.GetTotalByUserIDAndDateRange(ctx, userID, start, start.AddDate(0, 0, 2))
+		require.NoError(t, err)
+		return sum.StringFixed(2)
+	}
+
+	require.Contains(t, command("/refund Amazon").LastSentMessage().Text, refundUsageMsg)
+
+	require.Contains(t, command("/budget set Sneakers 50 hard").LastSentMessage().Text, "set to")
+	require.Contains(t, command("/add 50 Runners [Sneakers]").LastSentMessage().Text, "Expense Added")
+	require.Contains(t, command("/add 5 Laces [Sneakers]").LastSentMessage().Text, "Sneakers budget reached")
+
+	msg := command("/refund 20 Runners return [Sneakers]").LastSentMessage()
+	require.Contains(t, msg.Text, "Refund Added", "a full budget does not block refunds")
+	require.Contains(t, msg.Text, "-S$20.00")
+	require.NotContains(t, msg.Text, "budget", "refunds get no budget warning")
+	require.Equal(t, "30.00", total())
+
+	expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 10)
+	require.NoError(t, err)
+	require.True(t, expenses[0].IsRefund())
+	require.True(t, mustParseDecimal("-20").Equal(expenses[0].Amount))
+
+	require.Contains(t, command("/add 5 Laces [Sneakers]").LastSentMessage().Text, "Expense Added",
+		"the refund freed budget room")
+	require.Equal(t, "35.00", total())
+}
@@ -0,0 +1,193 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	// reimbursementsListLimit is how many outstanding expenses
+	// /reimbursements shows, oldest first.
+	reimbursementsListLimit = 20
+
+	editTypeReimbursableCB   = "reimb"
+	reimbursedCallbackPrefix = "reimbursed_"
+	reimbursedCallbackFmt    = reimbursedCallbackPrefix + "%d"
+
+	reimbursementsNoneMsg = "✅ No reimbursements outstanding."
+)
+
+// reimbursableButtonText labels the edit menu toggle with the current
+// state.
+func reimbursableButtonText(expense *appmodels.Expense) string {
+	if expense.Reimbursable {
+		return "💼 Reimbursable ✓"
+	}
+	return "💼 Reimbursable"
+}
+
+// hiddenReimbursementsNote tells how many outstanding reimbursable expenses
+// a personal spend view left out, and how to see them.
+func hiddenReimbursementsNote(hidden int, allCommand string) string {
+	noun := "expenses"
+	if hidden == 1 {
+		noun = "expense"
+	}
+	return fmt.Sprintf("💼 %d reimbursable %s awaiting reimbursement not included; <code>%s</code> shows them.",
+		hidden, noun, allCommand)
+}
+
+// excludeAwaitingReimbursement drops the reimbursable expenses not paid
+// back yet and returns the rest with the number dropped.
+func excludeAwaitingReimbursement(expenses []appmodels.Expense) ([]appmodels.Expense, int) {
+	kept := make([]appmodels.Expense, 0, len(expenses))
+	for i := range expenses {
+		if !expenses[i].AwaitingReimbursement() {
+			kept = append(kept, expenses[i])
+		}
+	}
+	return kept, len(expenses) - len(kept)
+}
+
+// buildReimbursementsKeyboard has one "Mark reimbursed" button per
+// expense.
+func buildReimbursementsKeyboard(expenses []appmodels.Expense) *models.InlineKeyboardMarkup {
+	rows := make([][]models.InlineKeyboardButton, 0, len(expenses))
+	for i := range expenses {
+		rows = append(rows, []models.InlineKeyboardButton{{
+			Text:         fmt.Sprintf("✅ Mark #%d reimbursed", expenses[i].UserExpenseNumber),
+			CallbackData: fmt.Sprintf(reimbursedCallbackFmt, expenses[i].ID),
+		}})
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// renderReimbursements returns the /reimbursements text and keyboard, or
+// reimbursementsNoneMsg and no keyboard when nothing is outstanding.
+func (b *Bot) renderReimbursements(ctx context.Context, userID int64) (string, *models.InlineKeyboardMarkup, error) {
+	expenses, err := b.expenseRepo.GetOutstandingReimbursements(ctx, userID, reimbursementsListLimit)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch outstanding reimbursements: %w", err)
+	}
+	if len(expenses) == 0 {
+		return reimbursementsNoneMsg, nil, nil
+	}
+
+	header := "💼 <b>Awaiting reimbursement</b> (" +
+		formatInlineCurrencyTotals(sumExpenseAmountsByCurrency(expenses)) + ")"
+	text := botfmt.ExpenseListMessage(header, expenses, nil, b.displayLocation)
+	return strings.TrimRight(text, "\n"), buildReimbursementsKeyboard(expenses), nil
+}
+
+// handleReimbursements handles the /reimbursements command.
+func (b *Bot) handleReimbursements(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleReimbursementsCore(ctx, tgBot, update)
+}
+
+// handleReimbursementsCore lists the user's reimbursable expenses that were
+// not paid back yet, each with a button to mark it reimbursed.
+func (b *Bot) handleReimbursementsCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	text, keyboard, err := b.renderReimbursements(ctx, update.Message.From.ID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to list reimbursements")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: failedFetchExpensesMsg})
+		return
+	}
+
+	params := &bot.SendMessageParams{ChatID: chatID, Text: text, ParseMode: models.ParseModeHTML}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+	_, _ = tg.SendMessage(ctx, params)
+}
+
+// handleReimbursedCallback handles the "Mark reimbursed" buttons.
+func (b *Bot) handleReimbursedCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleReimbursedCallbackCore(ctx, tgBot, update)
+}
+
+// handleReimbursedCallbackCore records the reimbursement and refreshes the
+// list. Only the owner of the expense can mark it.
+func (b *Bot) handleReimbursedCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	expenseID, err := strconv.Atoi(strings.TrimPrefix(query.Data, reimbursedCallbackPrefix))
+	if err != nil {
+		answerCallback(ctx, tg, query)
+		return
+	}
+	expense, ok := b.loadCallbackExpense(ctx, tg, query, expenseID, false)
+	if !ok {
+		return
+	}
+
+	userID := query.From.ID
+	updated, err := b.expenses().MarkReimbursed(ctx, userID, expenseID, b.now())
+	if err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expenseID).Msg("Failed to mark expense reimbursed")
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            "❌ Failed to mark the expense reimbursed.",
+		})
+		return
+	}
+	answer := fmt.Sprintf("✅ #%d marked reimbursed.", expense.UserExpenseNumber)
+	if !updated {
+		answer = fmt.Sprintf("#%d is not awaiting reimbursement.", expense.UserExpenseNumber)
+	}
+	_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID, Text: answer})
+
+	text, keyboard, err := b.renderReimbursements(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to refresh reimbursements")
+		return
+	}
+	params := &bot.EditMessageTextParams{
+		ChatID:    query.Message.Message.Chat.ID,
+		MessageID: query.Message.Message.ID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+	_, _ = tg.EditMessageText(ctx, params)
+}
+
+// toggleReimbursableCore flips the reimbursable flag of an expense from its
+// edit menu and shows the menu again.
+func (b *Bot) toggleReimbursableCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	messageID int,
+	expense *appmodels.Expense,
+) {
+	reimbursable := !expense.Reimbursable
+	if _, err := b.expenses().SetReimbursable(ctx, expense.UserID, expense.ID, reimbursable); err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to toggle reimbursable")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: "❌ Failed to update the expense."})
+		return
+	}
+	expense.Reimbursable = reimbursable
+	if !reimbursable {
+		expense.ReimbursedAt = nil
+	}
+	b.handleInlineEditExpenseCore(ctx, tg, chatID, messageID, expense)
+}
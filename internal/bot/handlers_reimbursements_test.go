@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestExcludeAwaitingReimbursement(t *testing.T) {
+	t.Parallel()
+
+	reimbursedAt := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	expenses := []appmodels.Expense{
+		{ID: 1},
+		{ID: 2, Reimbursable: true},
+		{ID: 3, Reimbursable: true, ReimbursedAt: &reimbursedAt},
+	}
+
+	kept, hidden := excludeAwaitingReimbursement(expenses)
+	require.Equal(t, 1, hidden)
+	require.Len(t, kept, 2)
+	require.Equal(t, 1, kept[0].ID)
+	require.Equal(t, 3, kept[1].ID)
+}
+
+func TestHiddenReimbursementsNote(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t,
+		"💼 1 reimbursable expense awaiting reimbursement not included; <code>/today all</code> shows them.",
+		hiddenReimbursementsNote(1, "/today all"))
+	require.Contains(t, hiddenReimbursementsNote(3, "/week all"), "3 reimbursable expenses")
+}
+
+func TestReimbursementKeyboards(t *testing.T) {
+	t.Parallel()
+
+	kb := buildReimbursementsKeyboard([]appmodels.Expense{
+		{ID: 10, UserExpenseNumber: 4},
+		{ID: 11, UserExpenseNumber: 5},
+	})
+	require.Len(t, kb.InlineKeyboard, 2)
+	require.Equal(t, "✅ Mark #4 reimbursed", kb.InlineKeyboard[0][0].Text)
+	require.Equal(t, "reimbursed_11", kb.InlineKeyboard[1][0].CallbackData)
+
+	period := buildPeriodKeyboard(periodWeek, 0, 42, true)
+	require.Equal(t, "period_week_-1_42_all", period.InlineKeyboard[0][0].CallbackData)
+
+	require.Equal(t, "💼 Reimbursable", reimbursableButtonText(&appmodels.Expense{}))
+	require.Equal(t, "💼 Reimbursable ✓", reimbursableButtonText(&appmodels.Expense{Reimbursable: true}))
+}
+
+func TestReimbursementsFlow(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.displayLocation = time.UTC
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	b.nowFunc = func() time.Time { return now }
+
+	userID := int64(423101)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Reimburse"}))
+
+	lunch := createHabitTestExpense(t, ctx, b, userID, "Lunch", "10.00", now.Add(-2*time.Hour))
+	hotel := createHabitTestExpense(t, ctx, b, userID, "Hotel", "200.00", now.Add(-time.Hour))
+	today := func(command string) string {
+		mockBot := mocks.NewMockBot()
+		b.handleTodayCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, command))
+		return mockBot.LastSentMessage().Text
+	}
+
+	t.Run("edit menu toggle", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		data := fmt.Sprintf("edit_%s_%d", editTypeReimbursableCB, hotel.ID)
+		b.handleEditCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 7, data))
+
+		fetched, err := b.expenseRepo.GetByID(ctx, hotel.ID)
+		require.NoError(t, err)
+		require.True(t, fetched.AwaitingReimbursement())
+		edited := mockBot.LastEditedMessage()
+		require.NotNil(t, edited)
+		require.Contains(t, edited.Text, "Reimbursable: awaiting reimbursement")
+	})
+
+	t.Run("today leaves it out unless all is given", func(t *testing.T) {
+		text := today("/today")
+		require.Contains(t, text, "Total: S$10.00 SGD")
+		require.NotContains(t, text, "Hotel")
+		require.Contains(t, text, "<code>/today all</code>")
+
+		text = today("/today all")
+		require.Contains(t, text, "Total: S$210.00 SGD")
+		require.Contains(t, text, "Hotel")
+		require.NotContains(t, text, "not included")
+	})
+
+	t.Run("tag marks new expenses", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleAddCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/add 30 Taxi #reimbursable"))
+
+		expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 1)
+		require.NoError(t, err)
+		require.Len(t, expenses, 1)
+		require.Equal(t, "Taxi", expenses[0].Description)
+		require.True(t, expenses[0].Reimbursable)
+	})
+
+	t.Run("list and mark reimbursed", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleReimbursementsCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/reimbursements"))
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, "Awaiting reimbursement")
+		require.Contains(t, msg.Text, "Hotel")
+		require.Contains(t, msg.Text, "Taxi")
+		require.NotContains(t, msg.Text, "Lunch")
+		kb := requireInlineKeyboard(t, msg.ReplyMarkup)
+		require.Len(t, kb.InlineKeyboard, 2)
+
+		mockBot = mocks.NewMockBot()
+		data := fmt.Sprintf(reimbursedCallbackFmt, hotel.ID)
+		b.handleReimbursedCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 8, data))
+		require.Len(t, mockBot.AnsweredCallbacks, 1)
+		require.Contains(t, mockBot.AnsweredCallbacks[0].Text, "marked reimbursed")
+		edited := mockBot.LastEditedMessage()
+		require.NotNil(t, edited)
+		require.NotContains(t, edited.Text, "Hotel")
+		require.Contains(t, edited.Text, "Taxi")
+
+		require.Contains(t, today("/today"), "Hotel", "reimbursed expenses count again")
+	})
+
+	t.Run("other users cannot mark it", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		data := fmt.Sprintf(reimbursedCallbackFmt, lunch.ID)
+		b.handleReimbursedCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID+1, userID+1, 9, data))
+		require.Empty(t, mockBot.EditedMessages)
+	})
+
+	t.Run("nothing outstanding", func(t *testing.T) {
+		otherID := int64(423102)
+		require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: otherID, FirstName: "Nobody"}))
+		mockBot := mocks.NewMockBot()
+		b.handleReimbursementsCore(ctx, mockBot, mocks.CommandUpdate(otherID, otherID, "/reimbursements"))
+		require.Equal(t, reimbursementsNoneMsg, mockBot.LastSentMessage().Text)
+		require.Nil(t, mockBot.LastSentMessage().ReplyMarkup)
+	})
+
+	t.Run("nil message", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleReimbursementsCore(ctx, mockBot, &tgmodels.Update{})
+		require.Zero(t, mockBot.SentMessageCount())
+	})
+}
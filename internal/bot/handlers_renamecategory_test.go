@@ -106,7 +106,7 @@ func TestHandleRenameCategoryCore(t *testing.T) {
 	})
 
 	t.Run("renames category successfully", func(t *testing.T) {
-		_, err := b.categoryRepo.Create(ctx, "Rename Me 900")
+		_, err := b.categoryRepo.CreateOwned(ctx, userID, "Rename Me 900")
 		require.NoError(t, err)
 		b.invalidateCategoryCache()
 
@@ -122,9 +122,9 @@ func TestHandleRenameCategoryCore(t *testing.T) {
 	})
 
 	t.Run("returns error when new name already exists", func(t *testing.T) {
-		_, err := b.categoryRepo.Create(ctx, "Existing A 900")
+		_, err := b.categoryRepo.CreateOwned(ctx, userID, "Existing A 900")
 		require.NoError(t, err)
-		_, err = b.categoryRepo.Create(ctx, "Existing B 900")
+		_, err = b.categoryRepo.CreateOwned(ctx, userID, "Existing B 900")
 		require.NoError(t, err)
 		b.invalidateCategoryCache()
 
@@ -138,8 +138,23 @@ func TestHandleRenameCategoryCore(t *testing.T) {
 		require.Contains(t, msg.Text, "already exists")
 	})
 
+	t.Run("refuses to rename a shared category", func(t *testing.T) {
+		_, err := b.categoryRepo.Create(ctx, "Shared Rename 900")
+		require.NoError(t, err)
+		b.invalidateCategoryCache()
+
+		mockBot := mocks.NewMockBot()
+		update := mocks.CommandUpdate(chatID, userID, "/renamecategory Shared Rename 900 -> Mine 900")
+
+		b.handleRenameCategoryCore(ctx, mockBot, update)
+
+		require.Contains(t, mockBot.LastSentMessage().Text, "is a shared category")
+		_, err = b.findUserCategory(ctx, userID, "Shared Rename 900")
+		require.NoError(t, err)
+	})
+
 	t.Run("handles bot mention in command", func(t *testing.T) {
-		_, err := b.categoryRepo.Create(ctx, "Mention Cat 900")
+		_, err := b.categoryRepo.CreateOwned(ctx, userID, "Mention Cat 900")
 		require.NoError(t, err)
 		b.invalidateCategoryCache()
 
@@ -92,7 +92,7 @@ func TestHandleReportCore(t *testing.T) {
 		require.Equal(t, 1, mockBot.SentDocumentCount())
 		doc := mockBot.LastSentDocument()
 		require.NotNil(t, doc)
-		require.Contains(t, doc.Filename, "expenses_week_")
+		require.Regexp(t, `^expenses_\d{4}-W\d{2}\.csv$`, doc.Filename)
 		require.Contains(t, doc.Caption, "Weekly Expenses")
 	})
 
@@ -161,9 +161,9 @@ func TestHandleReportCore(t *testing.T) {
 		require.Equal(t, 1, mockBot.SentDocumentCount())
 		doc := mockBot.LastSentDocument()
 		require.NotNil(t, doc)
-		require.Equal(t, "expenses_week_2026-02-23.csv", doc.Filename)
-		require.Contains(t, doc.Caption, "Weekly Expenses (Feb 23 to Mar 1, 2026)")
-		require.Contains(t, doc.Caption, "Total Expenses: $5.00 SGD")
+		require.Equal(t, "expenses_2026-W09.csv", doc.Filename)
+		require.Contains(t, doc.Caption, "Weekly Expenses (Week 9 · Feb 23–Mar 1, 2026)")
+		require.Contains(t, doc.Caption, "Total Expenses:\n  SGD: S$5.00\n")
 		require.Contains(t, doc.Caption, "Count: 2")
 	})
 
@@ -221,7 +221,7 @@ func TestHandleReportCore(t *testing.T) {
 		require.NotNil(t, doc)
 		require.Equal(t, "expenses_month_2026-02.csv", doc.Filename)
 		require.Contains(t, doc.Caption, "Monthly Expenses (February 2026)")
-		require.Contains(t, doc.Caption, "Total Expenses: $50.00 SGD")
+		require.Contains(t, doc.Caption, "Total Expenses:\n  SGD: S$50.00\n")
 		require.Contains(t, doc.Caption, "Count: 2")
 	})
 
@@ -281,6 +281,100 @@ func TestHandleReportCore(t *testing.T) {
 	})
 }
 
+func TestParseReportArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		args, period, category string
+	}{
+		{"", "", ""},
+		{" month", "month", ""},
+		{"month Work Travel", "month", "Work Travel"},
+		{`month "Work Travel"`, "month", "Work Travel"},
+		{"week “Work Travel ”", "week", "Work Travel"},
+		{`month "`, "month", `"`},
+	}
+	for _, tt := range tests {
+		period, category := parseReportArgs(tt.args)
+		require.Equal(t, tt.period, period, tt.args)
+		require.Equal(t, tt.category, category, tt.args)
+	}
+}
+
+func TestHandleReportCore_CategoryFilter(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.displayLocation = time.UTC
+	b.nowFunc = func() time.Time { return time.Date(2026, 3, 20, 10, 0, 0, 0, time.UTC) }
+
+	userID := int64(436001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Claimer"}))
+	travel, err := b.categoryRepo.Create(ctx, "Work Travel")
+	require.NoError(t, err)
+	food, err := b.categoryRepo.Create(ctx, "Report Filter Food")
+	require.NoError(t, err)
+	b.invalidateCategoryCache()
+
+	for _, e := range []struct {
+		amount, desc string
+		categoryID   int
+		day          int
+	}{
+		{"42.00", "Taxi to client", travel.ID, 3},
+		{"120.50", "Hotel night", travel.ID, 12},
+		{"9.90", "Lunch", food.ID, 12},
+	} {
+		expense := &appmodels.Expense{
+			UserID:      userID,
+			Amount:      decimal.RequireFromString(e.amount),
+			Currency:    "SGD",
+			Description: e.desc,
+			CategoryID:  &e.categoryID,
+			Status:      appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		_, err := b.expenseRepo.Pool().Exec(ctx, testUpdateExpenseTimeSQL,
+			time.Date(2026, 3, e.day, 9, 0, 0, 0, time.UTC), expense.ID)
+		require.NoError(t, err)
+	}
+
+	t.Run("only the category's rows and totals", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleReportCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, `/report month "work travel"`))
+
+		require.Equal(t, 1, mockBot.SentDocumentCount())
+		doc := mockBot.LastSentDocument()
+		require.Equal(t, "expenses_month_2026-03_work-travel.csv", doc.Filename)
+		require.Contains(t, doc.Caption, "Monthly Expenses (March 2026) · Work Travel")
+		require.Contains(t, doc.Caption, "Total Expenses:\n  SGD: S$162.50\n")
+		require.Contains(t, doc.Caption, "Count: 2")
+
+		data := string(doc.Data)
+		require.Contains(t, data, "Taxi to client")
+		require.Contains(t, data, "Hotel night")
+		require.NotContains(t, data, "Lunch")
+	})
+
+	t.Run("a trailing category is fuzzy-matched", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleReportCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/report month travel"))
+
+		require.Equal(t, 1, mockBot.SentDocumentCount())
+		require.Equal(t, "expenses_month_2026-03_work-travel.csv", mockBot.LastSentDocument().Filename)
+	})
+
+	t.Run("unknown categories suggest the closest one", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleReportCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/report month Wrok Trvel"))
+
+		require.Zero(t, mockBot.SentDocumentCount())
+		text := mockBot.LastSentMessage().Text
+		require.Contains(t, text, `Category "Wrok Trvel" not found.`)
+		require.Contains(t, text, "Did you mean <code>Work Travel</code>?")
+	})
+}
+
 func TestHandleReportWrapper(t *testing.T) {
 	t.Parallel()
 
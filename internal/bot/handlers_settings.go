@@ -0,0 +1,323 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+const (
+	settingsMaxAmountArg   = "maxamount"
+	settingsMaxAmountReset = "default"
+	settingsAutoTagArg     = "autotag"
+	settingsCategoryArg    = "categoryorder"
+	settingsCategoryUsage  = "usage"
+	settingsCategoryShared = "shared"
+	settingsFormatArg      = "format"
+	settingsAIArg          = "ai"
+
+	settingsUsage = "Usage:\n" +
+		"<code>/settings maxamount &lt;amount&gt;</code> - Ask before saving expenses above this amount (0 never asks)\n" +
+		"<code>/settings maxamount default</code> - Go back to the default limit\n" +
+		"<code>/settings autotag on|off</code> - Apply suggested tags to new expenses without asking\n" +
+		"<code>/settings categoryorder usage|shared</code> - Show your most used categories first, " +
+		"or the /reordercategories order\n" +
+		"<code>/settings format html|mono</code> - Show lists, totals and statements as aligned columns (mono)\n" +
+		"<code>/settings ai on|off</code> - Let Gemini read your receipts, voice messages and descriptions"
+)
+
+// handleSettings handles the /settings command.
+func (b *Bot) handleSettings(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleSettingsCore(ctx, tgBot, update)
+}
+
+// handleSettingsCore is the testable implementation of handleSettings.
+func (b *Bot) handleSettingsCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	args := strings.Fields(extractCommandArgs(update.Message.Text, "/settings"))
+
+	if len(args) == 0 {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      b.settingsSummary(ctx, userID) + "\n\n" + settingsUsage,
+			ParseMode: models.ParseModeHTML,
+		})
+		return
+	}
+
+	if strings.EqualFold(args[0], settingsMaxAmountArg) && len(args) == 2 {
+		b.setMaxAmountCore(ctx, tg, chatID, userID, args[1])
+		return
+	}
+
+	if strings.EqualFold(args[0], settingsAutoTagArg) && len(args) == 2 {
+		if enabled, ok := parseOnOff(args[1]); ok {
+			b.setAutoTagCore(ctx, tg, chatID, userID, enabled)
+			return
+		}
+	}
+
+	if strings.EqualFold(args[0], settingsCategoryArg) && len(args) == 2 {
+		switch strings.ToLower(args[1]) {
+		case settingsCategoryUsage:
+			b.setCategoriesByUsageCore(ctx, tg, chatID, userID, true)
+			return
+		case settingsCategoryShared:
+			b.setCategoriesByUsageCore(ctx, tg, chatID, userID, false)
+			return
+		}
+	}
+
+	if strings.EqualFold(args[0], settingsAIArg) && len(args) == 2 {
+		if enabled, ok := parseOnOff(args[1]); ok {
+			b.setAIEnabledCore(ctx, tg, chatID, userID, enabled)
+			return
+		}
+	}
+
+	if strings.EqualFold(args[0], settingsFormatArg) && len(args) == 2 {
+		if format, ok := botfmt.ParseFormat(args[1]); ok {
+			b.setDisplayFormatCore(ctx, tg, chatID, userID, format)
+			return
+		}
+	}
+
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      settingsUsage,
+		ParseMode: models.ParseModeHTML,
+	})
+}
+
+// settingsSummary describes the user's current settings.
+func (b *Bot) settingsSummary(ctx context.Context, userID int64) string {
+	limit := b.expenses().SoftLimit(ctx, userID)
+	limitText := "ask before saving expenses above " + limit.StringFixed(2)
+	if limit.IsZero() {
+		limitText = "never ask"
+	}
+
+	source := "default"
+	if override, err := b.userRepo.GetMaxAmount(ctx, userID); err == nil && override.Valid {
+		source = "custom"
+	}
+
+	autoTagText := "off"
+	if b.autoTagEnabled(ctx, userID) {
+		autoTagText = "on"
+	}
+
+	categoryText := "shared order"
+	if byUsage, err := b.userRepo.GetCategoriesByUsage(ctx, userID); err == nil && byUsage {
+		categoryText = "most used first"
+	}
+
+	aiText := "on"
+	if !b.aiEnabled(ctx, userID) {
+		aiText = "off, nothing is sent to Gemini"
+	}
+
+	return fmt.Sprintf("⚙️ <b>Settings</b>\n\n💰 Max amount: %s (%s)\n🏷️ Auto-tag: %s\n📁 Category order: %s\n"+
+		"🖥️ Format: %s\n🔒 AI: %s\n🤖 AI categories: %s", limitText, source, autoTagText, categoryText,
+		b.displayFormat(ctx, userID), aiText, b.aiSuggestSettingsText(ctx, userID))
+}
+
+// setMaxAmountCore updates or clears the user's soft amount limit.
+func (b *Bot) setMaxAmountCore(ctx context.Context, tg TelegramAPI, chatID, userID int64, arg string) {
+	var maxAmount decimal.NullDecimal
+	if !strings.EqualFold(arg, settingsMaxAmountReset) {
+		value, err := decimal.NewFromString(strings.ReplaceAll(arg, ",", ""))
+		if err != nil || value.IsNegative() || value.GreaterThan(b.maxAmountSettingLimit()) {
+			_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatID,
+				Text: fmt.Sprintf("❌ Max amount must be a number from 0 to %s.",
+					b.maxAmountSettingLimit().StringFixed(2)),
+			})
+			return
+		}
+		maxAmount = decimal.NewNullDecimal(value.Round(2))
+	}
+
+	if err := b.userRepo.UpdateMaxAmount(ctx, userID, maxAmount); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to update max amount")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to update max amount. Please try again.",
+		})
+		return
+	}
+
+	var text string
+	switch {
+	case !maxAmount.Valid:
+		text = "✅ Max amount reset to the default."
+	case maxAmount.Decimal.IsZero():
+		text = "✅ Large expenses will be saved without asking."
+	default:
+		text = fmt.Sprintf("✅ You'll be asked before saving expenses above %s.", maxAmount.Decimal.StringFixed(2))
+	}
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	})
+}
+
+// maxAmountSettingLimit is the largest allowed /settings maxamount value:
+// the hard cap, or the largest storable amount when there is none.
+func (b *Bot) maxAmountSettingLimit() decimal.Decimal {
+	if hardCap := b.expenses().HardCap(); !hardCap.IsZero() {
+		return hardCap
+	}
+	return maxExpenseAmount
+}
+
+// parseOnOff reads an "on" or "off" setting value.
+func parseOnOff(arg string) (enabled, ok bool) {
+	switch strings.ToLower(arg) {
+	case "on":
+		return true, true
+	case "off":
+		return false, true
+	}
+	return false, false
+}
+
+// setAutoTagCore turns automatic tagging of new expenses on or off.
+func (b *Bot) setAutoTagCore(ctx context.Context, tg TelegramAPI, chatID, userID int64, enabled bool) {
+	if err := b.userRepo.UpdateAutoTag(ctx, userID, enabled); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to update auto tag")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to update auto-tag. Please try again.",
+		})
+		return
+	}
+
+	text := "✅ Suggested tags will be offered with a button."
+	if enabled {
+		text = "✅ Suggested tags will be applied to new expenses automatically."
+	}
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	})
+}
+
+// aiEnabled reports whether the user's receipts, voice messages and
+// descriptions may be sent to Gemini. Every Gemini call for a user checks
+// it. A user without a row yet has the default, on; any other lookup
+// failure counts as off, so a database hiccup never sends data the user
+// kept back.
+func (b *Bot) aiEnabled(ctx context.Context, userID int64) bool {
+	if b.userRepo == nil {
+		return true
+	}
+	enabled, err := b.userRepo.GetAIEnabled(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return true
+		}
+		logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to get AI setting")
+		return false
+	}
+	return enabled
+}
+
+// setAIEnabledCore turns the user's AI features on or off.
+func (b *Bot) setAIEnabledCore(ctx context.Context, tg TelegramAPI, chatID, userID int64, enabled bool) {
+	if err := b.userRepo.UpdateAIEnabled(ctx, userID, enabled); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to update AI setting")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to update the AI setting. Please try again.",
+		})
+		return
+	}
+
+	text := "✅ AI is off. Receipts, voice messages and descriptions will not be sent to Gemini."
+	if enabled {
+		text = "✅ AI is on. Receipts and voice messages will be read and categories suggested by Gemini."
+	}
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	})
+}
+
+// setCategoriesByUsageCore switches the user's category keyboards between
+// most used first and the shared order.
+func (b *Bot) setCategoriesByUsageCore(ctx context.Context, tg TelegramAPI, chatID, userID int64, byUsage bool) {
+	if err := b.userRepo.UpdateCategoriesByUsage(ctx, userID, byUsage); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to update category order")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to update the category order. Please try again.",
+		})
+		return
+	}
+	b.categoryUsage.invalidate(userID)
+
+	text := "✅ Categories will be shown in the shared order."
+	if byUsage {
+		text = "✅ Categories you used most in the last 90 days will be shown first."
+	}
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	})
+}
+
+// displayFormat returns the layout the user picked with /settings format.
+// Lookup failures and unknown values fall back to HTML.
+func (b *Bot) displayFormat(ctx context.Context, userID int64) botfmt.Format {
+	value, err := b.userRepo.GetDisplayFormat(ctx, userID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to get display format")
+		return botfmt.FormatHTML
+	}
+	format, ok := botfmt.ParseFormat(value)
+	if !ok {
+		return botfmt.FormatHTML
+	}
+	return format
+}
+
+// setDisplayFormatCore switches the user's lists and summaries between HTML
+// and aligned monospace columns.
+func (b *Bot) setDisplayFormatCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID, userID int64,
+	format botfmt.Format,
+) {
+	if err := b.userRepo.UpdateDisplayFormat(ctx, userID, string(format)); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to update display format")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to update the format. Please try again.",
+		})
+		return
+	}
+
+	text := "✅ Lists and summaries will use the regular layout."
+	if format == botfmt.FormatMono {
+		text = "✅ Lists and summaries will be shown as aligned columns."
+	}
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	})
+}
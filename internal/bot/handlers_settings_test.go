@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestHandleSettingsCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.cfg.AmountSoftLimit = decimal.NewFromInt(1000)
+	b.cfg.AmountHardCap = decimal.NewFromInt(1_000_000)
+
+	userID := int64(372101)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Settings"}))
+
+	send := func(text string) string {
+		mockBot := mocks.NewMockBot()
+		b.handleSettingsCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, text))
+		return mockBot.LastSentMessage().Text
+	}
+
+	t.Run("shows the default limit", func(t *testing.T) {
+		text := send("/settings")
+		require.Contains(t, text, "ask before saving expenses above 1000.00 (default)")
+		require.Contains(t, text, settingsUsage)
+	})
+
+	t.Run("sets a custom limit", func(t *testing.T) {
+		require.Contains(t, send("/settings maxamount 5,000"), "above 5000.00")
+		require.Contains(t, send("/settings"), "above 5000.00 (custom)")
+	})
+
+	t.Run("zero never asks", func(t *testing.T) {
+		require.Contains(t, send("/settings maxamount 0"), "without asking")
+		require.Contains(t, send("/settings"), "never ask (custom)")
+	})
+
+	t.Run("rejects values above the hard cap", func(t *testing.T) {
+		require.Contains(t, send("/settings maxamount 2000000"), "from 0 to 1000000.00")
+		require.Contains(t, send("/settings maxamount -5"), "from 0 to 1000000.00")
+	})
+
+	t.Run("resets to default", func(t *testing.T) {
+		require.Contains(t, send("/settings maxamount default"), "reset to the default")
+		require.Contains(t, send("/settings"), "(default)")
+	})
+
+	t.Run("toggles auto-tag", func(t *testing.T) {
+		require.Contains(t, send("/settings"), "Auto-tag: off")
+		require.Contains(t, send("/settings autotag on"), "applied to new expenses automatically")
+		require.Contains(t, send("/settings"), "Auto-tag: on")
+		require.Contains(t, send("/settings autotag OFF"), "offered with a button")
+		require.Equal(t, settingsUsage, send("/settings autotag maybe"))
+	})
+
+	t.Run("switches the category order", func(t *testing.T) {
+		require.Contains(t, send("/settings"), "Category order: shared order")
+		require.Contains(t, send("/settings categoryorder usage"), "used most")
+		require.Contains(t, send("/settings"), "Category order: most used first")
+		require.Contains(t, send("/settings categoryorder SHARED"), "shared order")
+		require.Equal(t, settingsUsage, send("/settings categoryorder alphabetical"))
+	})
+
+	t.Run("switches the display format", func(t *testing.T) {
+		require.Contains(t, send("/settings"), "Format: html")
+		require.Contains(t, send("/settings format mono"), "aligned columns")
+		require.Contains(t, send("/settings"), "Format: mono")
+
+		expense := &appmodels.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal("8.50"),
+			Currency:    currencyCodeSGD,
+			Description: "ラーメン",
+			Status:      appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		mockBot := mocks.NewMockBot()
+		b.handleListCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/list"))
+		require.Contains(t, mockBot.LastSentMessage().Text, "<pre>")
+		require.Contains(t, mockBot.LastSentMessage().Text, "S$8.50 SGD  ラーメン")
+
+		require.Contains(t, send("/settings format HTML"), "regular layout")
+		require.Equal(t, settingsUsage, send("/settings format markdown"))
+	})
+
+	t.Run("usage on unknown setting", func(t *testing.T) {
+		require.Equal(t, settingsUsage, send("/settings colour blue"))
+	})
+}
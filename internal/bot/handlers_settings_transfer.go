@@ -0,0 +1,490 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	exportSettingsCommand = "/exportsettings"
+	importSettingsCommand = "/importsettings"
+
+	// settingsVersion is the version of the document /exportsettings
+	// writes. /importsettings reads it and refuses newer versions.
+	settingsVersion = 1
+
+	// settingsMaxBytes caps the size of an /importsettings file.
+	settingsMaxBytes = 64 << 10
+
+	importSettingsUsageMsg = "⚙️ Send a file from /exportsettings with the caption <code>/importsettings</code>, " +
+		"or reply to one with <code>/importsettings</code>.\n\n" +
+		"Budgets need their categories, so run /importtaxonomy first."
+	importSettingsFailedMsg = "❌ Failed to import settings. Please try again."
+)
+
+var (
+	errSettingsFormat  = errors.New("not a settings document")
+	errSettingsVersion = errors.New("unsupported settings version")
+)
+
+// settingsDocument is the JSON file /exportsettings sends: a user's
+// preferences and budgets, without IDs, so they can be loaded on another
+// instance before the account is deleted here. Budgets name their
+// category; categories themselves travel with /exporttaxonomy.
+type settingsDocument struct {
+	Version                int              `json:"version"`
+	Currency               string           `json:"currency,omitempty"`
+	Timezone               string           `json:"timezone,omitempty"`
+	Format                 string           `json:"format,omitempty"`
+	AI                     *bool            `json:"ai,omitempty"`
+	AutoTag                *bool            `json:"auto_tag,omitempty"`
+	CategoryOrder          string           `json:"category_order,omitempty"`
+	UncategorizedThreshold *int             `json:"uncategorized_threshold,omitempty"`
+	MaxAmount              string           `json:"max_amount,omitempty"`
+	Budgets                []settingsBudget `json:"budgets,omitempty"`
+}
+
+// settingsBudget is a monthly category budget in a settingsDocument.
+type settingsBudget struct {
+	Category string `json:"category"`
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+	Enforce  bool   `json:"enforce,omitempty"`
+}
+
+// settingsImport is the outcome of an /importsettings: the items that
+// were changed, those already set to the file's value, and those that
+// could not be used, with the reason.
+type settingsImport struct {
+	Applied []string
+	Skipped []string
+	Invalid []string
+}
+
+// buildSettings collects a user's preferences and budgets.
+func (b *Bot) buildSettings(ctx context.Context, userID int64) (*settingsDocument, error) {
+	settings, err := b.userRepo.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+	budgets, err := b.budgetRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load budgets: %w", err)
+	}
+
+	categoryOrder := settingsCategoryShared
+	if settings.CategoriesByUsage {
+		categoryOrder = settingsCategoryUsage
+	}
+	maxAmount := settingsMaxAmountReset
+	if settings.MaxAmount.Valid {
+		maxAmount = settings.MaxAmount.Decimal.StringFixed(2)
+	}
+	format, ok := botfmt.ParseFormat(settings.DisplayFormat)
+	if !ok {
+		format = botfmt.FormatHTML
+	}
+	doc := &settingsDocument{
+		Version:                settingsVersion,
+		Currency:               settings.DefaultCurrency,
+		Timezone:               settings.Timezone,
+		Format:                 string(format),
+		AI:                     &settings.AIEnabled,
+		AutoTag:                &settings.AutoTag,
+		CategoryOrder:          categoryOrder,
+		UncategorizedThreshold: &settings.UncategorizedThreshold,
+		MaxAmount:              maxAmount,
+		Budgets:                make([]settingsBudget, 0, len(budgets)),
+	}
+	for i := range budgets {
+		doc.Budgets = append(doc.Budgets, settingsBudget{
+			Category: budgets[i].CategoryName,
+			Amount:   budgets[i].Amount.StringFixed(2),
+			Currency: budgetCurrency(&budgets[i], settings.DefaultCurrency),
+			Enforce:  budgets[i].Enforce,
+		})
+	}
+	return doc, nil
+}
+
+// parseSettings reads an /exportsettings file. The version is read first,
+// so a file from a newer release is reported as such even when it has
+// fields this one does not know.
+func parseSettings(data []byte) (*settingsDocument, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSettingsFormat, err)
+	}
+	if header.Version > settingsVersion {
+		return nil, fmt.Errorf("%w: %d", errSettingsVersion, header.Version)
+	}
+	if header.Version < 1 {
+		return nil, fmt.Errorf("%w: no version", errSettingsFormat)
+	}
+
+	var doc settingsDocument
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSettingsFormat, err)
+	}
+	return &doc, nil
+}
+
+// importSettings applies the preferences and budgets of doc that differ
+// from the user's. Fields missing from the file are left alone, and
+// importing the same file twice changes nothing.
+func (b *Bot) importSettings(ctx context.Context, userID int64, doc *settingsDocument) (settingsImport, error) {
+	var result settingsImport
+	current, err := b.userRepo.GetSettings(ctx, userID)
+	if err != nil {
+		return result, fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	// apply records one item: invalid when reason is set, skipped when
+	// the value is already the user's, or else stored with update.
+	apply := func(item, reason string, same bool, update func() error) error {
+		switch {
+		case reason != "":
+			result.Invalid = append(result.Invalid, item+": "+reason)
+		case same:
+			result.Skipped = append(result.Skipped, item)
+		default:
+			if err := update(); err != nil {
+				return err
+			}
+			result.Applied = append(result.Applied, item)
+		}
+		return nil
+	}
+
+	if doc.Currency != "" {
+		currency := strings.ToUpper(strings.TrimSpace(doc.Currency))
+		reason := ""
+		if _, ok := appmodels.SupportedCurrencies[currency]; !ok {
+			reason = "unknown currency"
+		}
+		if err := apply("currency "+currency, reason, currency == current.DefaultCurrency, func() error {
+			return b.userRepo.UpdateDefaultCurrency(ctx, userID, currency)
+		}); err != nil {
+			return result, err
+		}
+	}
+
+	if doc.Timezone != "" {
+		loc, ok := resolveTimezone(strings.TrimSpace(doc.Timezone))
+		item, reason, same := "timezone "+doc.Timezone, "unknown timezone", false
+		if ok {
+			item, reason, same = "timezone "+loc.String(), "", loc.String() == current.Timezone
+		}
+		if err := apply(item, reason, same, func() error {
+			return b.userRepo.UpdateTimezone(ctx, userID, loc.String())
+		}); err != nil {
+			return result, err
+		}
+	}
+
+	if doc.Format != "" {
+		format, ok := botfmt.ParseFormat(doc.Format)
+		reason := ""
+		if !ok {
+			reason = "use html or mono"
+		}
+		if err := apply("format "+doc.Format, reason, string(format) == current.DisplayFormat, func() error {
+			return b.userRepo.UpdateDisplayFormat(ctx, userID, string(format))
+		}); err != nil {
+			return result, err
+		}
+	}
+
+	if doc.AI != nil {
+		enabled := *doc.AI
+		if err := apply("ai "+onOff(enabled), "", enabled == current.AIEnabled, func() error {
+			return b.userRepo.UpdateAIEnabled(ctx, userID, enabled)
+		}); err != nil {
+			return result, err
+		}
+	}
+
+	if doc.AutoTag != nil {
+		enabled := *doc.AutoTag
+		if err := apply("autotag "+onOff(enabled), "", enabled == current.AutoTag, func() error {
+			return b.userRepo.UpdateAutoTag(ctx, userID, enabled)
+		}); err != nil {
+			return result, err
+		}
+	}
+
+	if doc.CategoryOrder != "" {
+		order := strings.ToLower(doc.CategoryOrder)
+		byUsage := order == settingsCategoryUsage
+		reason := ""
+		if !byUsage && order != settingsCategoryShared {
+			reason = "use usage or shared"
+		}
+		if err := apply("categoryorder "+doc.CategoryOrder, reason, byUsage == current.CategoriesByUsage, func() error {
+			if err := b.userRepo.UpdateCategoriesByUsage(ctx, userID, byUsage); err != nil {
+				return err
+			}
+			b.categoryUsage.invalidate(userID)
+			return nil
+		}); err != nil {
+			return result, err
+		}
+	}
+
+	if doc.UncategorizedThreshold != nil {
+		threshold := *doc.UncategorizedThreshold
+		reason := ""
+		if threshold < 0 || threshold > uncategorizedMaxThreshold {
+			reason = fmt.Sprintf("must be from 0 to %d", uncategorizedMaxThreshold)
+		}
+		item := fmt.Sprintf("uncategorized threshold %d", threshold)
+		if err := apply(item, reason, threshold == current.UncategorizedThreshold, func() error {
+			return b.userRepo.UpdateUncategorizedThreshold(ctx, userID, threshold)
+		}); err != nil {
+			return result, err
+		}
+	}
+
+	if doc.MaxAmount != "" {
+		maxAmount, reason := b.parseSettingsMaxAmount(doc.MaxAmount)
+		same := maxAmount.Valid == current.MaxAmount.Valid &&
+			(!maxAmount.Valid || maxAmount.Decimal.Equal(current.MaxAmount.Decimal))
+		if err := apply("maxamount "+doc.MaxAmount, reason, same, func() error {
+			return b.userRepo.UpdateMaxAmount(ctx, userID, maxAmount)
+		}); err != nil {
+			return result, err
+		}
+	}
+
+	for _, budget := range doc.Budgets {
+		if err := b.importSettingsBudget(ctx, userID, budget, apply); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// parseSettingsMaxAmount reads the max_amount of a settings document:
+// "default" or an amount /settings maxamount accepts. The reason is set
+// when the value is invalid.
+func (b *Bot) parseSettingsMaxAmount(raw string) (decimal.NullDecimal, string) {
+	if strings.EqualFold(raw, settingsMaxAmountReset) {
+		return decimal.NullDecimal{}, ""
+	}
+	value, err := decimal.NewFromString(raw)
+	if err != nil || value.IsNegative() || value.GreaterThan(b.maxAmountSettingLimit()) {
+		return decimal.NullDecimal{}, "must be default or an amount from 0 to " + b.maxAmountSettingLimit().StringFixed(2)
+	}
+	return decimal.NewNullDecimal(value.Round(2)), ""
+}
+
+// importSettingsBudget sets one budget of a settings document on the
+// category of that name the user sees.
+func (b *Bot) importSettingsBudget(
+	ctx context.Context,
+	userID int64,
+	budget settingsBudget,
+	apply func(item, reason string, same bool, update func() error) error,
+) error {
+	item := "budget " + budget.Category
+	amount, err := decimal.NewFromString(budget.Amount)
+	if err != nil || !amount.IsPositive() || amount.GreaterThan(maxExpenseAmount) {
+		return apply(item, "invalid amount", false, nil)
+	}
+	amount = amount.Round(2)
+	currency := strings.ToUpper(budget.Currency)
+	if _, ok := appmodels.SupportedCurrencies[currency]; !ok {
+		return apply(item, "unknown currency", false, nil)
+	}
+	category, err := b.findUserCategory(ctx, userID, strings.TrimSpace(budget.Category))
+	if errors.Is(err, errCategoryNotFound) {
+		return apply(item, "no such category, run /importtaxonomy first", false, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up category %q: %w", budget.Category, err)
+	}
+
+	item = "budget " + category.Name
+	existing, err := b.budgetRepo.GetByCategory(ctx, userID, category.ID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to load budget of %q: %w", category.Name, err)
+	}
+	same := err == nil && existing.Amount.Equal(amount) && existing.Currency == currency &&
+		existing.Enforce == budget.Enforce
+	return apply(item, "", same, func() error {
+		return b.budgetRepo.Set(ctx, userID, category.ID, amount, currency, budget.Enforce)
+	})
+}
+
+// onOff writes a toggle the way /settings takes it.
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
+// formatSettingsImport reports an /importsettings.
+func formatSettingsImport(result settingsImport) string {
+	var sb strings.Builder
+	sb.WriteString("⚙️ <b>Settings imported</b>\n")
+	sections := []struct {
+		title string
+		items []string
+	}{
+		{"✅ <b>Applied:</b>", result.Applied},
+		{"⏭️ <b>Already set:</b>", result.Skipped},
+		{"❌ <b>Invalid:</b>", result.Invalid},
+	}
+	for _, section := range sections {
+		if len(section.items) == 0 {
+			continue
+		}
+		sb.WriteString("\n" + section.title)
+		for _, item := range section.items {
+			sb.WriteString("\n• " + botfmt.EscapeHTML(item))
+		}
+		sb.WriteString("\n")
+	}
+	if len(result.Applied)+len(result.Skipped)+len(result.Invalid) == 0 {
+		sb.WriteString("\nThe file has no settings.")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// handleExportSettings handles the /exportsettings command.
+func (b *Bot) handleExportSettings(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleExportSettingsCore(ctx, tgBot, update)
+}
+
+// handleExportSettingsCore is the testable implementation of
+// handleExportSettings. It sends the user's preferences and budgets as a
+// JSON file for /importsettings.
+func (b *Bot) handleExportSettingsCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil || !b.sensitiveCommandAllowed(ctx, tg, update.Message) {
+		return
+	}
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	sendText := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: text})
+	}
+
+	doc, err := b.buildSettings(ctx, userID)
+	var data []byte
+	if err == nil {
+		data, err = json.MarshalIndent(doc, "", "  ")
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to export settings")
+		sendText("❌ Failed to export settings. Please try again.")
+		return
+	}
+
+	filename := fmt.Sprintf("settings_%s.json", b.now().In(b.locationForUser(ctx, userID)).Format("2006-01-02"))
+	if _, err := tg.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID:   chatID,
+		Document: &models.InputFileUpload{Filename: filename, Data: bytes.NewReader(data)},
+		Caption: fmt.Sprintf("⚙️ <b>Settings</b>\n\nBudgets: %d\n\n"+
+			"Send this file with the caption <code>%s</code> to load them on another bot. "+
+			"Budgets need their categories, so send the <code>%s</code> file first.",
+			len(doc.Budgets), importSettingsCommand, exportTaxonomyCommand),
+		ParseMode: models.ParseModeHTML,
+	}); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to send settings document")
+		sendText("❌ Failed to send the file. Please try again.")
+		return
+	}
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(userID)).
+		Int("budgets", len(doc.Budgets)).
+		Msg("Settings exported")
+}
+
+// handleImportSettings handles the /importsettings command.
+func (b *Bot) handleImportSettings(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleImportSettingsCore(ctx, tgBot, update)
+}
+
+// handleImportSettingsCore is the testable implementation of
+// handleImportSettings. It downloads the file the command came with and
+// applies its settings.
+func (b *Bot) handleImportSettingsCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil || !b.sensitiveCommandAllowed(ctx, tg, update.Message) {
+		return
+	}
+	msg := update.Message
+	sendHTML := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: msg.Chat.ID, Text: text, ParseMode: models.ParseModeHTML})
+	}
+
+	doc := importDocument(msg)
+	if doc == nil {
+		sendHTML(importSettingsUsageMsg)
+		return
+	}
+	if doc.FileSize > settingsMaxBytes {
+		sendHTML("❌ The file is too large for a settings file.")
+		return
+	}
+	data, err := b.downloadFile(ctx, tg, doc.FileID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to download settings file")
+		sendHTML(importSettingsFailedMsg)
+		return
+	}
+	b.importSettingsCore(ctx, tg, msg.Chat.ID, msg.From.ID, data)
+}
+
+// importSettingsCore applies the settings of a settings file for a user
+// and reports what happened.
+func (b *Bot) importSettingsCore(ctx context.Context, tg TelegramAPI, chatID, userID int64, data []byte) {
+	sendHTML := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: text, ParseMode: models.ParseModeHTML})
+	}
+
+	doc, err := parseSettings(data)
+	switch {
+	case errors.Is(err, errSettingsVersion):
+		sendHTML(fmt.Sprintf("❌ This file was made by a newer version of the bot. "+
+			"This one reads settings files up to version %d; update it, or export again with <code>%s</code> "+
+			"on a bot of the same version.", settingsVersion, exportSettingsCommand))
+		return
+	case err != nil:
+		logger.Log.Warn().Err(err).Msg("Failed to parse settings file")
+		sendHTML("❌ Could not read the file. Send the JSON file /exportsettings made.")
+		return
+	}
+
+	result, err := b.importSettings(ctx, userID, doc)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to import settings")
+		sendHTML(importSettingsFailedMsg)
+		return
+	}
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(userID)).
+		Int("applied", len(result.Applied)).
+		Int("skipped", len(result.Skipped)).
+		Int("invalid", len(result.Invalid)).
+		Msg("Settings imported")
+	sendHTML(formatSettingsImport(result))
+}
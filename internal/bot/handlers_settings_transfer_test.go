@@ -0,0 +1,150 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestParseSettings(t *testing.T) {
+	t.Parallel()
+
+	doc, err := parseSettings([]byte(`{"version":1,"currency":"EUR","ai":false,` +
+		`"budgets":[{"category":"Food","amount":"300.00","currency":"EUR","enforce":true}]}`))
+	require.NoError(t, err)
+	ai := false
+	require.Equal(t, &settingsDocument{
+		Version:  1,
+		Currency: "EUR",
+		AI:       &ai,
+		Budgets:  []settingsBudget{{Category: "Food", Amount: "300.00", Currency: "EUR", Enforce: true}},
+	}, doc)
+
+	_, err = parseSettings([]byte(`{"version":2,"week_start":"monday"}`))
+	require.ErrorIs(t, err, errSettingsVersion, "a newer file is reported as newer, not as unknown fields")
+	_, err = parseSettings([]byte(`{"currency":"EUR"}`))
+	require.ErrorIs(t, err, errSettingsFormat)
+	_, err = parseSettings([]byte("Date,Amount\n2026-03-01,5.50"))
+	require.ErrorIs(t, err, errSettingsFormat)
+	_, err = parseSettings([]byte(`{"version":1,"quiet_hours":"22-07"}`))
+	require.ErrorIs(t, err, errSettingsFormat, "unknown fields are refused")
+}
+
+func TestFormatSettingsImport(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "⚙️ <b>Settings imported</b>\n\nThe file has no settings.", formatSettingsImport(settingsImport{}))
+
+	text := formatSettingsImport(settingsImport{
+		Applied: []string{"currency EUR"},
+		Skipped: []string{"ai on"},
+		Invalid: []string{"budget <Gifts>: no such category, run /importtaxonomy first"},
+	})
+	require.Contains(t, text, "✅ <b>Applied:</b>\n• currency EUR\n")
+	require.Contains(t, text, "⏭️ <b>Already set:</b>\n• ai on\n")
+	require.Contains(t, text, "❌ <b>Invalid:</b>\n• budget &lt;Gifts&gt;: no such category")
+}
+
+func TestSettingsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(459001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Mover"}))
+
+	food, err := b.categoryRepo.CreateOwned(ctx, userID, "Settings Food")
+	require.NoError(t, err)
+	travel, err := b.categoryRepo.CreateOwned(ctx, userID, "Settings Travel")
+	require.NoError(t, err)
+	b.invalidateCategoryCache()
+
+	require.NoError(t, b.userRepo.UpdateDefaultCurrency(ctx, userID, "EUR"))
+	require.NoError(t, b.userRepo.UpdateTimezone(ctx, userID, "Europe/Berlin"))
+	require.NoError(t, b.userRepo.UpdateDisplayFormat(ctx, userID, "mono"))
+	require.NoError(t, b.userRepo.UpdateAIEnabled(ctx, userID, false))
+	require.NoError(t, b.userRepo.UpdateAutoTag(ctx, userID, true))
+	require.NoError(t, b.userRepo.UpdateCategoriesByUsage(ctx, userID, false))
+	require.NoError(t, b.userRepo.UpdateUncategorizedThreshold(ctx, userID, 7))
+	require.NoError(t, b.userRepo.UpdateMaxAmount(ctx, userID, decimal.NewNullDecimal(decimal.NewFromInt(250))))
+	require.NoError(t, b.budgetRepo.Set(ctx, userID, food.ID, decimal.NewFromInt(400), "EUR", true))
+	require.NoError(t, b.budgetRepo.Set(ctx, userID, travel.ID, decimal.RequireFromString("120.50"), "USD", false))
+
+	export := func() (*settingsDocument, []byte) {
+		mockBot := mocks.NewMockBot()
+		b.handleExportSettingsCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, exportSettingsCommand))
+		sent := mockBot.LastSentDocument()
+		require.NotNil(t, sent)
+		require.Contains(t, sent.Filename, "settings_")
+		doc, err := parseSettings(sent.Data)
+		require.NoError(t, err)
+		return doc, sent.Data
+	}
+	importFile := func(data []byte) string {
+		mockBot := mocks.NewMockBot()
+		b.importSettingsCore(ctx, mockBot, userID, userID, data)
+		return mockBot.LastSentMessage().Text
+	}
+
+	before, data := export()
+	require.Equal(t, "EUR", before.Currency)
+	require.Equal(t, "Europe/Berlin", before.Timezone)
+	require.Equal(t, "mono", before.Format)
+	require.Equal(t, settingsCategoryShared, before.CategoryOrder)
+	require.Equal(t, "250.00", before.MaxAmount)
+	require.Equal(t, []settingsBudget{
+		{Category: "Settings Food", Amount: "400.00", Currency: "EUR", Enforce: true},
+		{Category: "Settings Travel", Amount: "120.50", Currency: "USD"},
+	}, before.Budgets)
+
+	require.NoError(t, b.userRepo.UpdateDefaultCurrency(ctx, userID, appmodels.DefaultCurrency))
+	require.NoError(t, b.userRepo.UpdateTimezone(ctx, userID, appmodels.DefaultTimezone))
+	require.NoError(t, b.userRepo.UpdateDisplayFormat(ctx, userID, "html"))
+	require.NoError(t, b.userRepo.UpdateAIEnabled(ctx, userID, true))
+	require.NoError(t, b.userRepo.UpdateAutoTag(ctx, userID, false))
+	require.NoError(t, b.userRepo.UpdateCategoriesByUsage(ctx, userID, true))
+	require.NoError(t, b.userRepo.UpdateUncategorizedThreshold(ctx, userID, 0))
+	require.NoError(t, b.userRepo.UpdateMaxAmount(ctx, userID, decimal.NullDecimal{}))
+	_, err = b.budgetRepo.Delete(ctx, userID, food.ID)
+	require.NoError(t, err)
+	_, err = b.budgetRepo.Delete(ctx, userID, travel.ID)
+	require.NoError(t, err)
+
+	text := importFile(data)
+	require.Contains(t, text, "Applied:")
+	require.NotContains(t, text, "Already set:")
+	require.NotContains(t, text, "Invalid:")
+
+	after, _ := export()
+	require.Equal(t, before, after)
+
+	t.Run("importing again changes nothing", func(t *testing.T) {
+		text := importFile(data)
+		require.NotContains(t, text, "Applied:")
+		require.Contains(t, text, "Already set:")
+		require.Contains(t, text, "budget Settings Food")
+	})
+
+	t.Run("invalid items are reported and the rest applied", func(t *testing.T) {
+		text := importFile([]byte(`{"version":1,"currency":"XXX","timezone":"Asia/Tokyo",` +
+			`"uncategorized_threshold":5000,"budgets":[{"category":"Settings Gifts","amount":"50","currency":"EUR"}]}`))
+		require.Contains(t, text, "• timezone Asia/Tokyo")
+		require.Contains(t, text, "• currency XXX: unknown currency")
+		require.Contains(t, text, "• uncategorized threshold 5000: must be from 0 to 1000")
+		require.Contains(t, text, "• budget Settings Gifts: no such category, run /importtaxonomy first")
+
+		settings, err := b.userRepo.GetSettings(ctx, userID)
+		require.NoError(t, err)
+		require.Equal(t, "Asia/Tokyo", settings.Timezone)
+		require.Equal(t, "EUR", settings.DefaultCurrency)
+	})
+
+	t.Run("a newer version is refused", func(t *testing.T) {
+		text := importFile([]byte(`{"version":9,"week_start":"monday"}`))
+		require.Contains(t, text, "newer version of the bot")
+	})
+}
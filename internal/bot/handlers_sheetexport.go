@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+const (
+	sheetExportOn  = "on"
+	sheetExportOff = "off"
+
+	sheetExportUsage = "Usage:\n" +
+		"<code>/sheetexport on</code> - Every day, send the previous day's new expenses as a CSV to append " +
+		"to a spreadsheet\n" +
+		"<code>/sheetexport off</code> - Stop the daily export\n\n" +
+		"Files go to your /archivechat when you have one, otherwise to you."
+	sheetExportFailedMsg = "❌ Failed to update the sheet export. Please try again."
+)
+
+// handleSheetExport handles the /sheetexport command.
+func (b *Bot) handleSheetExport(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleSheetExportCore(ctx, tgBot, update)
+}
+
+// handleSheetExportCore is the testable implementation of
+// handleSheetExport. It shows, starts or stops the user's daily sheet
+// export. Starting it skips the expenses recorded so far, so only new ones
+// are exported.
+func (b *Bot) handleSheetExportCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || !b.sensitiveCommandAllowed(ctx, tg, update.Message) {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	switch strings.ToLower(strings.TrimSpace(extractCommandArgs(update.Message.Text, "/sheetexport"))) {
+	case "":
+		send(b.sheetExportStatus(ctx, userID))
+	case sheetExportOn:
+		maxID, err := b.expenseRepo.GetMaxID(ctx, userID)
+		if err == nil {
+			err = b.sheetExportRepo.Enable(ctx, userID, maxID)
+		}
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to enable sheet export")
+			send(sheetExportFailedMsg)
+			return
+		}
+		send("📤 Daily sheet export is on. Each day after midnight you get the previous day's new " +
+			"expenses as a CSV to append to your spreadsheet.")
+	case sheetExportOff:
+		if _, err := b.sheetExportRepo.Disable(ctx, userID); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to disable sheet export")
+			send(sheetExportFailedMsg)
+			return
+		}
+		send("📤 Daily sheet export is off.")
+	default:
+		send(sheetExportUsage)
+	}
+}
+
+// sheetExportStatus describes the user's sheet export and how to change it.
+func (b *Bot) sheetExportStatus(ctx context.Context, userID int64) string {
+	export, err := b.sheetExportRepo.Get(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrSheetExportNotFound) {
+		logger.Log.Error().Err(err).Msg("Failed to get sheet export")
+		return "❌ Failed to load your sheet export. Please try again."
+	}
+	status := "📤 Daily sheet export is off."
+	if export != nil && export.Enabled {
+		status = fmt.Sprintf("📤 Daily sheet export is on. %d row(s) sent so far.", export.RowCount)
+	}
+	return status + "\n\n" + sheetExportUsage
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const showUsage = "Usage: <code>/show &lt;expense number&gt;</code>, e.g. <code>/show 42</code>"
+
+// expenseDetailText renders the detail card of an expense with its
+// category, tags and location.
+func (b *Bot) expenseDetailText(ctx context.Context, expense *appmodels.Expense) string {
+	b.loadExpenseCategory(ctx, expense)
+
+	tags, err := b.tagRepo.GetByExpenseID(ctx, expense.ID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expense.ID).Msg("Failed to load tags for expense detail")
+	}
+
+	text := botfmt.ExpenseDetailCard(expense, tags, b.displayLocation)
+	loc, ok, err := b.expenseRepo.GetLocation(ctx, expense.ID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expense.ID).Msg("Failed to load location for expense detail")
+	}
+	if ok {
+		text += "\n" + botfmt.LocationLine(loc)
+	}
+	return text
+}
+
+// handleShow handles the /show command.
+func (b *Bot) handleShow(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleShowCore(ctx, tgBot, update)
+}
+
+// handleShowCore is the testable implementation of handleShow. It shows one
+// of the sender's expenses by its number, with the merchant history button
+// when it has a merchant.
+func (b *Bot) handleShowCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	args := strings.TrimPrefix(extractCommandArgs(update.Message.Text, "/show"), "#")
+	number, err := strconv.ParseInt(args, 10, 64)
+	if err != nil || number <= 0 {
+		send(showUsage)
+		return
+	}
+
+	expense, err := b.expenseRepo.GetByUserAndNumber(ctx, update.Message.From.ID, number)
+	if errors.Is(err, pgx.ErrNoRows) {
+		send(fmt.Sprintf("❌ Expense #%d not found.", number))
+		return
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Int64("expense_number", number).Msg("Failed to load expense for /show")
+		send("❌ Failed to load the expense. Please try again.")
+		return
+	}
+	params := &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      b.expenseDetailText(ctx, expense),
+		ParseMode: models.ParseModeHTML,
+	}
+	if strings.TrimSpace(expense.Merchant) != "" {
+		params.ReplyMarkup = &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{buildMerchantHistoryRow(expense.ID)},
+		}
+	}
+	_, _ = tg.SendMessage(ctx, params)
+	b.sendReceiptImage(ctx, tg, chatID, expense)
+}
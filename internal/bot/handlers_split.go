@@ -0,0 +1,547 @@
+package bot
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+const (
+	// splitRecentWindow is how far back a plain "@split" looks for the
+	// people who logged expenses in the chat.
+	splitRecentWindow = 30 * 24 * time.Hour
+	// maxSplitPeople caps how many people one expense is split between.
+	maxSplitPeople = 20
+
+	splitGroupOnlyMsg = "❌ Splitting only works in group chats."
+	splitRefundMsg    = "❌ Refunds can't be split."
+	settleUsageMsg    = "Usage: <code>/settle @user &lt;amount&gt; [currency]</code>"
+)
+
+// splitDirectiveRegex matches a trailing "@split", "@split 3" or
+// "@split @alice @bob".
+var splitDirectiveRegex = regexp.MustCompile(`(?i)(?:^|\s)@split((?:\s+@[a-z0-9_]+)+|\s+\d+)?\s*$`)
+
+// splitDirective is the "@split" suffix of a group expense message.
+type splitDirective struct {
+	// Count is N for "@split N", zero otherwise.
+	Count int
+	// Usernames are the people mentioned after "@split", without "@".
+	Usernames []string
+}
+
+// extractSplitDirective removes a trailing split directive from an expense
+// message. The directive is nil when the message has none.
+func extractSplitDirective(text string) (string, *splitDirective) {
+	loc := splitDirectiveRegex.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return text, nil
+	}
+
+	directive := &splitDirective{}
+	if loc[2] >= 0 {
+		for _, field := range strings.Fields(text[loc[2]:loc[3]]) {
+			if name, ok := strings.CutPrefix(field, "@"); ok {
+				directive.Usernames = append(directive.Usernames, name)
+				continue
+			}
+			directive.Count, _ = strconv.Atoi(field)
+			if directive.Count == 0 {
+				// Mark zero and unparsable counts so they are rejected.
+				directive.Count = -1
+			}
+		}
+	}
+	return strings.TrimSpace(text[:loc[0]]), directive
+}
+
+// splitShares divides amount equally between userIDs in the currency's
+// minor units. The shares always add up to amount: leftover minor units go
+// one each to the lowest user IDs, so a split never depends on the order
+// people were named in.
+func splitShares(amount decimal.Decimal, currency string, userIDs []int64) []repository.ExpenseShare {
+	ids := slices.Clone(userIDs)
+	slices.Sort(ids)
+
+	places := appmodels.CurrencyMinorUnits(currency)
+	unit := decimal.New(1, -places)
+	count := decimal.NewFromInt(int64(len(ids)))
+	base := amount.Div(count).Truncate(places)
+	extra := amount.Sub(base.Mul(count)).Div(unit).IntPart()
+
+	shares := make([]repository.ExpenseShare, len(ids))
+	sum := decimal.Zero
+	for i, id := range ids {
+		share := base
+		if int64(i) < extra {
+			share = share.Add(unit)
+		}
+		shares[i] = repository.ExpenseShare{UserID: id, Amount: share}
+		sum = sum.Add(share)
+	}
+	// Amounts finer than the minor unit stay with the first share.
+	shares[0].Amount = shares[0].Amount.Add(amount.Sub(sum))
+	return shares
+}
+
+// splitSummaryLine describes how an expense was split for its
+// confirmation.
+func splitSummaryLine(expense *appmodels.Expense, userIDs []int64) string {
+	shares := splitShares(expense.Amount, expense.Currency, userIDs)
+	low, high := shares[len(shares)-1].Amount, shares[0].Amount
+	text := fmt.Sprintf("➗ Split %d ways, %s each", len(shares), botfmt.Money(low, expense.Currency))
+	if !high.Equal(low) {
+		rounded := 0
+		for _, share := range shares {
+			if share.Amount.Equal(high) {
+				rounded++
+			}
+		}
+		text += fmt.Sprintf(" (%s for %d)", botfmt.Money(high, expense.Currency), rounded)
+	}
+	return text + "."
+}
+
+// applySplit resolves who shares an expense logged with a split directive
+// and stores them on parsed. The payer is always included. Mentioned users
+// must have used the bot before; "@split N" adds the N-1 people who most
+// recently logged expenses in the chat, and a plain "@split" everyone who
+// did so within splitRecentWindow. Refunds cannot be split. It reports
+// false after telling the user why the split cannot be made.
+func (b *Bot) applySplit(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	userID int64,
+	directive *splitDirective,
+	parsed *ParsedExpense,
+) bool {
+	var userIDs []int64
+	problem := splitRefundMsg
+	if !parsed.Refund {
+		userIDs, problem = b.resolveSplitUsers(ctx, tg, chatID, userID, directive)
+	}
+	if problem != "" {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      problem,
+			ParseMode: models.ParseModeHTML,
+		})
+		return false
+	}
+	parsed.SplitUserIDs = userIDs
+	return true
+}
+
+// resolveSplitUsers returns the people sharing a split expense, or a
+// message explaining why there is no one to split with. Mentioned users
+// must be in the chat.
+func (b *Bot) resolveSplitUsers(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	userID int64,
+	directive *splitDirective,
+) ([]int64, string) {
+	if !isGroupChat(chatID) {
+		return nil, splitGroupOnlyMsg
+	}
+	if directive.Count < 0 || directive.Count == 1 || directive.Count > maxSplitPeople {
+		return nil, fmt.Sprintf("❌ Split between 2 and %d people.", maxSplitPeople)
+	}
+
+	userIDs := []int64{userID}
+	if len(directive.Usernames) > 0 {
+		for _, name := range directive.Usernames {
+			user, err := b.userRepo.GetUserByUsername(ctx, name)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, fmt.Sprintf(
+					"❌ I don't know @%s yet. They need to message me once before expenses can be split with them.",
+					botfmt.EscapeHTML(name))
+			}
+			if err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to look up split participant")
+				return nil, failedSaveExpenseMsg
+			}
+			if user.ID != userID && !chatHasMember(ctx, tg, chatID, user.ID) {
+				return nil, fmt.Sprintf("❌ @%s is not in this chat.", botfmt.EscapeHTML(name))
+			}
+			if !slices.Contains(userIDs, user.ID) {
+				userIDs = append(userIDs, user.ID)
+			}
+		}
+		if len(userIDs) > maxSplitPeople {
+			return nil, fmt.Sprintf("❌ Split between 2 and %d people.", maxSplitPeople)
+		}
+		return userIDs, ""
+	}
+
+	want := maxSplitPeople
+	if directive.Count > 0 {
+		want = directive.Count
+	}
+	recent, err := b.splitRepo.RecentParticipants(ctx, chatID, b.now().Add(-splitRecentWindow))
+	if err != nil {
+		logger.Log.Error().Err(err).Int64("chat_id", chatID).Msg("Failed to load chat participants")
+		return nil, failedSaveExpenseMsg
+	}
+	for _, id := range recent {
+		if len(userIDs) == want {
+			break
+		}
+		if id != userID {
+			userIDs = append(userIDs, id)
+		}
+	}
+	if len(userIDs) < 2 || (directive.Count > 0 && len(userIDs) < directive.Count) {
+		return nil, "❌ Not enough people have logged expenses here in the last 30 days. " +
+			"Mention who to split with: <code>@split @alice @bob</code>"
+	}
+	return userIDs, ""
+}
+
+// recordSplit stores the shares of a split expense logged in a chat. It
+// reports whether they were saved.
+func (b *Bot) recordSplit(ctx context.Context, chatID int64, expense *appmodels.Expense, userIDs []int64) bool {
+	shares := splitShares(expense.Amount, expense.Currency, userIDs)
+	if err := b.splitRepo.CreateShares(ctx, expense.ID, chatID, shares); err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, expense.ID).Msg("Failed to record expense shares")
+		return false
+	}
+	logger.Log.Info().
+		Int(logFieldExpenseIDCB, expense.ID).
+		Int("share_count", len(shares)).
+		Msg("Expense split")
+	return true
+}
+
+// resplitExpense divides the new amount of an edited split expense between
+// the same people.
+func (b *Bot) resplitExpense(ctx context.Context, before appmodels.Expense, after *appmodels.Expense) {
+	if b.splitRepo == nil || (before.Amount.Equal(after.Amount) && before.Currency == after.Currency) {
+		return
+	}
+	shares, err := b.splitRepo.GetShares(ctx, after.ID)
+	if err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, after.ID).Msg("Failed to load expense shares")
+		return
+	}
+	if len(shares) == 0 {
+		return
+	}
+
+	userIDs := make([]int64, len(shares))
+	for i, share := range shares {
+		userIDs[i] = share.UserID
+	}
+	shares = splitShares(after.Amount, after.Currency, userIDs)
+	if err := b.splitRepo.UpdateShareAmounts(ctx, after.ID, shares); err != nil {
+		logger.Log.Error().Err(err).Int(logFieldExpenseIDCB, after.ID).Msg("Failed to update expense shares")
+	}
+}
+
+// pairKey identifies two chat members and a currency, lower user ID first.
+type pairKey struct {
+	Low, High int64
+	Currency  string
+}
+
+// netChatDebts nets what members owe each other from their shares of split
+// expenses and the repayments between them. Each pair and currency ends up
+// with at most one debt, in whichever direction is still owed; settled
+// pairs are left out. The result is ordered by currency, then user IDs.
+func netChatDebts(debts, settlements []repository.ChatDebt) []repository.ChatDebt {
+	// A positive balance means Low owes High.
+	balances := make(map[pairKey]decimal.Decimal)
+	add := func(from, to int64, currency string, amount decimal.Decimal) {
+		if from < to {
+			key := pairKey{Low: from, High: to, Currency: currency}
+			balances[key] = balances[key].Add(amount)
+			return
+		}
+		key := pairKey{Low: to, High: from, Currency: currency}
+		balances[key] = balances[key].Sub(amount)
+	}
+	for _, debt := range debts {
+		add(debt.FromUserID, debt.ToUserID, debt.Currency, debt.Amount)
+	}
+	for _, payment := range settlements {
+		add(payment.FromUserID, payment.ToUserID, payment.Currency, payment.Amount.Neg())
+	}
+
+	net := make([]repository.ChatDebt, 0, len(balances))
+	for key, amount := range balances {
+		switch amount.Sign() {
+		case 1:
+			net = append(net, repository.ChatDebt{
+				FromUserID: key.Low, ToUserID: key.High, Currency: key.Currency, Amount: amount,
+			})
+		case -1:
+			net = append(net, repository.ChatDebt{
+				FromUserID: key.High, ToUserID: key.Low, Currency: key.Currency, Amount: amount.Neg(),
+			})
+		}
+	}
+	slices.SortFunc(net, func(a, b repository.ChatDebt) int {
+		return cmp.Or(
+			cmp.Compare(a.Currency, b.Currency),
+			cmp.Compare(a.FromUserID, b.FromUserID),
+			cmp.Compare(a.ToUserID, b.ToUserID),
+		)
+	})
+	return net
+}
+
+// chatBalances returns who owes whom in a chat.
+func (b *Bot) chatBalances(ctx context.Context, chatID int64) ([]repository.ChatDebt, error) {
+	debts, err := b.splitRepo.ShareDebts(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load share debts: %w", err)
+	}
+	settlements, err := b.splitRepo.SettlementTotals(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settlements: %w", err)
+	}
+	return netChatDebts(debts, settlements), nil
+}
+
+// memberName returns how a chat member is shown in balances: their
+// @username, else their first name, both HTML-escaped.
+func (b *Bot) memberName(ctx context.Context, names map[int64]string, userID int64) string {
+	if name, ok := names[userID]; ok {
+		return name
+	}
+	name := fmt.Sprintf("User %d", userID)
+	if user, err := b.userRepo.GetUserByID(ctx, userID); err == nil {
+		switch {
+		case user.Username != "":
+			name = "@" + user.Username
+		case user.FirstName != "":
+			name = user.FirstName
+		}
+	}
+	name = botfmt.EscapeHTML(name)
+	names[userID] = name
+	return name
+}
+
+// debtLine renders one debt, e.g. "@bob owes @alice S$23.40 SGD".
+func (b *Bot) debtLine(ctx context.Context, names map[int64]string, debt repository.ChatDebt) string {
+	return fmt.Sprintf("%s owes %s %s",
+		b.memberName(ctx, names, debt.FromUserID),
+		b.memberName(ctx, names, debt.ToUserID),
+		botfmt.Money(debt.Amount, debt.Currency))
+}
+
+// handleBalance handles the /balance command that shows who owes whom in a
+// group chat.
+func (b *Bot) handleBalance(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleBalanceCore(ctx, tgBot, update)
+}
+
+// handleBalanceCore is the testable implementation of handleBalance.
+func (b *Bot) handleBalanceCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	if !isGroupChat(chatID) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   splitGroupOnlyMsg,
+		})
+		return
+	}
+
+	balances, err := b.chatBalances(ctx, chatID)
+	if err != nil {
+		logger.Log.Error().Err(err).Int64("chat_id", chatID).Msg("Failed to load chat balances")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to load balances. Please try again.",
+		})
+		return
+	}
+	if len(balances) == 0 {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "✅ Everyone is settled up.",
+		})
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("⚖️ <b>Balances</b>\n")
+	names := make(map[int64]string)
+	for _, debt := range balances {
+		sb.WriteString("\n" + b.debtLine(ctx, names, debt))
+	}
+	sb.WriteString("\n\nRecord a repayment with <code>/settle @user &lt;amount&gt;</code>.")
+
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      sb.String(),
+		ParseMode: models.ParseModeHTML,
+	})
+}
+
+// handleSettle handles the /settle command that records a repayment
+// between two members of a group chat.
+func (b *Bot) handleSettle(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleSettleCore(ctx, tgBot, update)
+}
+
+// handleSettleCore is the testable implementation of handleSettle. The
+// sender is the one who paid. Without a currency the repayment is in the
+// only currency the sender owes the other person in, else the sender's
+// default currency.
+func (b *Bot) handleSettleCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	reply := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	if !isGroupChat(chatID) {
+		reply(splitGroupOnlyMsg)
+		return
+	}
+
+	args := strings.TrimPrefix(update.Message.Text, "/settle")
+	if strings.HasPrefix(args, "@") {
+		// Drop the bot name of "/settle@botname".
+		_, args, _ = strings.Cut(args, " ")
+	}
+	fields := strings.Fields(args)
+	if len(fields) < 2 || len(fields) > 3 || !strings.HasPrefix(fields[0], "@") {
+		reply(settleUsageMsg)
+		return
+	}
+	amount, err := parseAmount(fields[1])
+	if err != nil || !amount.IsPositive() {
+		reply("❌ Please enter a positive amount.\n\n" + settleUsageMsg)
+		return
+	}
+	currency := ""
+	if len(fields) == 3 {
+		currency = strings.ToUpper(fields[2])
+		if _, ok := appmodels.SupportedCurrencies[currency]; !ok {
+			reply(fmt.Sprintf("❌ Unknown currency: %s", botfmt.EscapeHTML(echoText(fields[2]))))
+			return
+		}
+	}
+
+	name := strings.TrimPrefix(fields[0], "@")
+	target, err := b.userRepo.GetUserByUsername(ctx, name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		reply(fmt.Sprintf("❌ I don't know @%s yet.", botfmt.EscapeHTML(echoText(name))))
+		return
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to look up settlement recipient")
+		reply("❌ Failed to record the repayment. Please try again.")
+		return
+	}
+	if target.ID == userID {
+		reply("❌ You can't settle up with yourself.")
+		return
+	}
+
+	balances, err := b.chatBalances(ctx, chatID)
+	if err != nil {
+		logger.Log.Error().Err(err).Int64("chat_id", chatID).Msg("Failed to load chat balances")
+		reply("❌ Failed to record the repayment. Please try again.")
+		return
+	}
+	if currency == "" {
+		currency = settleCurrency(balances, userID, target.ID)
+	}
+	if currency == "" {
+		currency = b.getUserDefaultCurrency(ctx, userID)
+	}
+
+	payment := repository.ChatDebt{
+		FromUserID: userID,
+		ToUserID:   target.ID,
+		Currency:   currency,
+		Amount:     amount.Round(appmodels.CurrencyMinorUnits(currency)),
+	}
+	if err := b.splitRepo.CreateSettlement(ctx, chatID, payment); err != nil {
+		logger.Log.Error().Err(err).Int64("chat_id", chatID).Msg("Failed to record settlement")
+		reply("❌ Failed to record the repayment. Please try again.")
+		return
+	}
+
+	logger.Log.Info().
+		Int64("chat_id", chatID).
+		Str(logFieldUserHashCB, logger.HashUserID(userID)).
+		Str("currency", currency).
+		Msg("Settlement recorded")
+
+	names := make(map[int64]string)
+	text := fmt.Sprintf("✅ Recorded: %s paid %s %s.",
+		b.memberName(ctx, names, userID),
+		b.memberName(ctx, names, target.ID),
+		botfmt.Money(payment.Amount, currency))
+
+	remaining := netChatDebts(append(balances, repository.ChatDebt{
+		FromUserID: target.ID, ToUserID: userID, Currency: currency, Amount: payment.Amount,
+	}), nil)
+	settled := true
+	for _, debt := range remaining {
+		if debt.Currency == currency && pairMatches(debt, userID, target.ID) {
+			text += "\n" + b.debtLine(ctx, names, debt) + "."
+			settled = false
+		}
+	}
+	if settled {
+		text += fmt.Sprintf("\nYou're settled up in %s.", botfmt.EscapeHTML(currency))
+	}
+	reply(text)
+}
+
+// settleCurrency returns the currency fromID owes toID in when it is the
+// only one, or "".
+func settleCurrency(balances []repository.ChatDebt, fromID, toID int64) string {
+	currency := ""
+	for _, debt := range balances {
+		if debt.FromUserID != fromID || debt.ToUserID != toID {
+			continue
+		}
+		if currency != "" {
+			return ""
+		}
+		currency = debt.Currency
+	}
+	return currency
+}
+
+// pairMatches reports whether a debt is between the two users, in either
+// direction.
+func pairMatches(debt repository.ChatDebt, a, b int64) bool {
+	return (debt.FromUserID == a && debt.ToUserID == b) || (debt.FromUserID == b && debt.ToUserID == a)
+}
@@ -0,0 +1,255 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+func TestExtractSplitDirective(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input string
+		rest  string
+		want  *splitDirective
+	}{
+		{input: "30 Dinner", rest: "30 Dinner"},
+		{input: "30 Dinner @split", rest: "30 Dinner", want: &splitDirective{}},
+		{input: "/add 30 Dinner @Split 3", rest: "/add 30 Dinner", want: &splitDirective{Count: 3}},
+		{input: "Dinner 30 @split 0", rest: "Dinner 30", want: &splitDirective{Count: -1}},
+		{
+			input: "30 Dinner #team @split @alice @bob_2",
+			rest:  "30 Dinner #team",
+			want:  &splitDirective{Usernames: []string{"alice", "bob_2"}},
+		},
+		{input: "30 Dinner @split later", rest: "30 Dinner @split later"},
+		{input: "30 Dinner@split", rest: "30 Dinner@split"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+			rest, got := extractSplitDirective(tt.input)
+			require.Equal(t, tt.rest, rest)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSplitShares(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		amount   string
+		currency string
+		userIDs  []int64
+		want     []string
+	}{
+		{name: "even", amount: "30.00", currency: currencyCodeSGD, userIDs: []int64{1, 2, 3}, want: []string{"10", "10", "10"}},
+		{
+			name: "remainder goes to the lowest user IDs", amount: "10.00", currency: currencyCodeSGD,
+			userIDs: []int64{30, 10, 20}, want: []string{"3.34", "3.33", "3.33"},
+		},
+		{
+			name: "two cents over three", amount: "0.02", currency: currencyCodeSGD,
+			userIDs: []int64{3, 2, 1}, want: []string{"0.01", "0.01", "0"},
+		},
+		{
+			name: "zero decimal currency", amount: "1000", currency: currencyCodeJPY,
+			userIDs: []int64{1, 2, 3}, want: []string{"334", "333", "333"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			amount := decimal.RequireFromString(tt.amount)
+			shares := splitShares(amount, tt.currency, tt.userIDs)
+			require.Len(t, shares, len(tt.want))
+
+			sum := decimal.Zero
+			for i, share := range shares {
+				require.True(t, decimal.RequireFromString(tt.want[i]).Equal(share.Amount),
+					"share %d is %s", i, share.Amount)
+				if i > 0 {
+					require.Less(t, shares[i-1].UserID, share.UserID)
+				}
+				sum = sum.Add(share.Amount)
+			}
+			require.True(t, amount.Equal(sum), "shares sum to %s", sum)
+		})
+	}
+}
+
+func TestSplitSummaryLine(t *testing.T) {
+	t.Parallel()
+
+	expense := &appmodels.Expense{Amount: decimal.RequireFromString("10.00"), Currency: currencyCodeSGD}
+	require.Equal(t, "➗ Split 3 ways, S$3.33 SGD each (S$3.34 SGD for 1).", splitSummaryLine(expense, []int64{1, 2, 3}))
+	require.Equal(t, "➗ Split 2 ways, S$5.00 SGD each.", splitSummaryLine(expense, []int64{1, 2}))
+}
+
+func TestNetChatDebts(t *testing.T) {
+	t.Parallel()
+
+	debt := func(from, to int64, currency, amount string) repository.ChatDebt {
+		return repository.ChatDebt{
+			FromUserID: from, ToUserID: to, Currency: currency, Amount: decimal.RequireFromString(amount),
+		}
+	}
+	const alice, bob, carol = int64(1), int64(2), int64(3)
+
+	net := netChatDebts([]repository.ChatDebt{
+		debt(bob, alice, currencyCodeSGD, "30.00"),
+		debt(alice, bob, currencyCodeSGD, "6.60"),
+		debt(carol, alice, currencyCodeSGD, "10.00"),
+		debt(alice, carol, currencyCodeSGD, "10.00"),
+		debt(alice, bob, currencyCodeTHB, "100"),
+	}, []repository.ChatDebt{
+		debt(carol, bob, currencyCodeTHB, "50"),
+	})
+
+	require.Len(t, net, 3)
+	require.Equal(t, bob, net[0].FromUserID)
+	require.Equal(t, alice, net[0].ToUserID)
+	require.True(t, decimal.RequireFromString("23.40").Equal(net[0].Amount))
+	require.Equal(t, currencyCodeTHB, net[1].Currency)
+	require.Equal(t, alice, net[1].FromUserID)
+	require.True(t, decimal.RequireFromString("100").Equal(net[1].Amount))
+	require.Equal(t, bob, net[2].FromUserID, "overpaying turns the debt around")
+	require.Equal(t, carol, net[2].ToUserID)
+	require.True(t, decimal.RequireFromString("50").Equal(net[2].Amount))
+
+	settled := netChatDebts(
+		[]repository.ChatDebt{debt(bob, alice, currencyCodeSGD, "5")},
+		[]repository.ChatDebt{debt(bob, alice, currencyCodeSGD, "5")},
+	)
+	require.Empty(t, settled)
+}
+
+func TestSplitExpenses(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	groupID := int64(-396001)
+	alice := int64(396001)
+	bob := int64(396002)
+	carol := int64(396003)
+	for id, name := range map[int64]string{alice: "alice", bob: "bob", carol: "carol"} {
+		require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{
+			ID: id, Username: name, FirstName: name, DefaultCurrency: currencyCodeSGD,
+		}))
+	}
+
+	messageID := 0
+	send := func(chatID, userID int64, text string, handle func(context.Context, TelegramAPI, *models.Update)) string {
+		messageID++
+		mockBot := mocks.NewMockBot()
+		update := mocks.CommandUpdate(chatID, userID, text)
+		update.Message.ID = messageID
+		handle(ctx, mockBot, update)
+		return mockBot.LastSentMessage().Text
+	}
+	add := func(chatID, userID int64, text string) string {
+		return send(chatID, userID, text, b.handleAddCore)
+	}
+	balance := func() string {
+		return send(groupID, alice, "/balance", b.handleBalanceCore)
+	}
+	latestShares := func(userID int64) []repository.ExpenseShare {
+		expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 1)
+		require.NoError(t, err)
+		require.Len(t, expenses, 1)
+		shares, err := b.splitRepo.GetShares(ctx, expenses[0].ID)
+		require.NoError(t, err)
+		return shares
+	}
+
+	t.Run("splits need a group and someone to split with", func(t *testing.T) {
+		require.Equal(t, splitGroupOnlyMsg, add(alice, alice, "/add 30 Dinner @split"))
+		require.Contains(t, add(groupID, alice, "/add 30 Dinner @split"), "Not enough people")
+		require.Contains(t, add(groupID, alice, "/add 30 Dinner @split @nobody"), "I don't know @nobody")
+		require.Contains(t, add(groupID, alice, "/add 30 Dinner @split 1"), "Split between 2")
+
+		mockBot := mocks.NewMockBot()
+		mockBot.ChatMemberStatus = map[int64]models.ChatMemberType{bob: models.ChatMemberTypeLeft}
+		b.handleAddCore(ctx, mockBot, mocks.CommandUpdate(groupID, alice, "/add 30 Dinner @split @bob"))
+		require.Equal(t, "❌ @bob is not in this chat.", mockBot.LastSentMessage().Text)
+
+		require.Equal(t, "✅ Everyone is settled up.", balance())
+	})
+
+	t.Run("mentions split with the payer", func(t *testing.T) {
+		text := add(groupID, alice, "/add 10 Dinner @split @bob @carol")
+		require.Contains(t, text, "➗ Split 3 ways")
+		shares := latestShares(alice)
+		require.Len(t, shares, 3)
+		require.Equal(t, alice, shares[0].UserID)
+		require.True(t, decimal.RequireFromString("3.34").Equal(shares[0].Amount))
+		require.True(t, decimal.RequireFromString("3.33").Equal(shares[2].Amount))
+
+		expenses, err := b.expenseRepo.GetByUserID(ctx, alice, 1)
+		require.NoError(t, err)
+		require.Equal(t, "Dinner", expenses[0].Description)
+	})
+
+	t.Run("a count picks the most recently active", func(t *testing.T) {
+		add(groupID, carol, "/add 5 Snacks")
+		add(groupID, bob, "/add 4 Coffee @split 2")
+		shares := latestShares(bob)
+		require.Len(t, shares, 2)
+		require.Equal(t, []int64{bob, carol}, []int64{shares[0].UserID, shares[1].UserID})
+	})
+
+	t.Run("balance lists what people owe each other", func(t *testing.T) {
+		text := balance()
+		require.Contains(t, text, "@bob owes @alice S$3.33 SGD")
+		require.Contains(t, text, "@carol owes @alice S$3.33 SGD")
+		require.Contains(t, text, "@carol owes @bob S$2.00 SGD")
+	})
+
+	t.Run("settle records repayments", func(t *testing.T) {
+		settle := func(chatID, userID int64, text string) string {
+			return send(chatID, userID, text, b.handleSettleCore)
+		}
+		require.Equal(t, splitGroupOnlyMsg, settle(bob, bob, "/settle @alice 3.33"))
+		require.Equal(t, settleUsageMsg, settle(groupID, bob, "/settle alice 3.33"))
+		require.Contains(t, settle(groupID, bob, "/settle @alice -1"), "positive amount")
+		require.Contains(t, settle(groupID, bob, "/settle @alice 1 XYZ"), "Unknown currency")
+		require.Contains(t, settle(groupID, bob, "/settle @nobody 1"), "I don't know @nobody")
+		require.Contains(t, settle(groupID, bob, "/settle @bob 1"), "yourself")
+
+		text := settle(groupID, bob, "/settle@expensebot @alice 3.33")
+		require.Contains(t, text, "@bob paid @alice S$3.33 SGD")
+		require.Contains(t, text, "settled up in SGD")
+
+		text = settle(groupID, carol, "/settle @alice 5")
+		require.Contains(t, text, "@alice owes @carol S$1.67 SGD")
+
+		text = balance()
+		require.NotContains(t, text, "@bob owes @alice")
+		require.Contains(t, text, "@alice owes @carol S$1.67 SGD")
+		require.Contains(t, text, "@carol owes @bob S$2.00 SGD")
+	})
+
+	t.Run("editing the amount re-splits it", func(t *testing.T) {
+		expenses, err := b.expenseRepo.GetByUserID(ctx, bob, 1)
+		require.NoError(t, err)
+		before := expenses[0]
+		after := before
+		after.Amount = decimal.RequireFromString("5.01")
+		require.NoError(t, b.expenseRepo.Update(ctx, &after))
+		b.auditExpenseUpdate(ctx, mocks.NewMockBot(), bob, before, &after)
+
+		shares := latestShares(bob)
+		require.True(t, decimal.RequireFromString("2.51").Equal(shares[0].Amount))
+		require.True(t, decimal.RequireFromString("2.50").Equal(shares[1].Amount))
+	})
+}
@@ -0,0 +1,309 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	statementUsageMsg = "❌ Invalid month.\n\nUsage: <code>/statement</code> or <code>/statement YYYY-MM</code>"
+	statementFailMsg  = "❌ Failed to generate statement. Please try again."
+
+	statementTopCategories = 3
+	statementMonthLayout   = "January 2006"
+	// statementMonoCategoryWidth is how many columns a category name may
+	// take in the mono statement before it is cut.
+	statementMonoCategoryWidth = 20
+
+	statementPartCSV        = "CSV export"
+	statementPartChart      = "category chart"
+	statementPartComparison = "previous month comparison"
+)
+
+// categoryTotal is a category name with its summed amount in one currency.
+type categoryTotal struct {
+	Name     string
+	Currency string
+	Total    decimal.Decimal
+}
+
+// handleStatement handles the /statement command to send a monthly bundle.
+func (b *Bot) handleStatement(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleStatementCore(ctx, tgBot, update)
+}
+
+// handleStatementCore is the testable implementation of handleStatement.
+// It sends the monthly CSV, the category chart and a text summary. A failure
+// in one part is reported in the summary instead of aborting the others.
+func (b *Bot) handleStatementCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || !b.sensitiveCommandAllowed(ctx, tg, update.Message) {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	current := b.now().In(normalizeLocation(b.displayLocation))
+
+	startDate, endDate, err := resolveMonthArgAt(extractCommandArgs(update.Message.Text, "/statement"), current)
+	if err != nil {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      statementUsageMsg,
+			ParseMode: models.ParseModeHTML,
+		})
+		return
+	}
+
+	release, ok := b.beginReport(ctx, tg, update.Message, "statement")
+	if !ok {
+		return
+	}
+	defer release()
+
+	expenses, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, userID, startDate, endDate)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch expenses for statement")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   statementFailMsg,
+		})
+		return
+	}
+
+	monthLabel := startDate.Format(statementMonthLayout)
+	if len(expenses) == 0 {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("📊 No expenses found for %s.", monthLabel),
+		})
+		return
+	}
+
+	var failed []string
+	if err := b.sendStatementCSV(ctx, tg, chatID, expenses, startDate); err != nil {
+		logger.Log.Error().Err(err).Msg("Statement CSV failed")
+		failed = append(failed, statementPartCSV)
+	}
+	if err := b.sendStatementChart(ctx, tg, chatID, expenses, startDate); err != nil {
+		logger.Log.Error().Err(err).Msg("Statement chart failed")
+		failed = append(failed, statementPartChart)
+	}
+
+	prevStart := startDate.AddDate(0, -1, 0)
+	previous, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, userID, prevStart, startDate)
+	hasPrevious := err == nil
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch previous month for statement")
+		failed = append(failed, statementPartComparison)
+	}
+
+	b.applyLiveConversions(ctx, userID, expenses)
+	b.applyLiveConversions(ctx, userID, previous)
+	text := b.monthlyReviewHeader(ctx, userID, startDate) +
+		buildStatementSummary(startDate, expenses, previous, hasPrevious, failed, b.displayFormat(ctx, userID))
+	_, err = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to send statement summary")
+	}
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(userID)).
+		Str("month", startDate.Format("2006-01")).
+		Int("expense_count", len(expenses)).
+		Int("failed_parts", len(failed)).
+		Msg("Statement sent")
+}
+
+// sendStatementCSV generates and sends the statement CSV.
+func (b *Bot) sendStatementCSV(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	expenses []appmodels.Expense,
+	month time.Time,
+) error {
+	return sendExpensesCSV(ctx, tg, chatID, expenses,
+		fmt.Sprintf("statement_%s.csv", month.Format("2006-01")),
+		fmt.Sprintf("📄 Expenses for %s", month.Format(statementMonthLayout)))
+}
+
+// sendExpensesCSV generates a CSV of expenses and sends it as a document.
+func sendExpensesCSV(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	expenses []appmodels.Expense,
+	filename, caption string,
+) error {
+	csvData, err := GenerateExpensesCSV(expenses)
+	if err != nil {
+		return fmt.Errorf("failed to generate CSV: %w", err)
+	}
+
+	_, err = tg.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID: chatID,
+		Document: &models.InputFileUpload{
+			Filename: filename,
+			Data:     bytes.NewReader(csvData),
+		},
+		Caption:   caption,
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send CSV: %w", err)
+	}
+	return nil
+}
+
+// sendStatementChart generates and sends the statement category chart.
+func (b *Bot) sendStatementChart(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	expenses []appmodels.Expense,
+	month time.Time,
+) error {
+	chartData, err := b.generateChart(ctx, expenses, month.Format(statementMonthLayout))
+	if err != nil {
+		return fmt.Errorf("failed to generate chart: %w", err)
+	}
+	if len(chartData) == 0 {
+		return errors.New("chart generator returned no data")
+	}
+
+	_, err = tg.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID: chatID,
+		Document: &models.InputFileUpload{
+			Filename: fmt.Sprintf("statement_%s.png", month.Format("2006-01")),
+			Data:     bytes.NewReader(chartData),
+		},
+		Caption:   fmt.Sprintf("📊 Categories for %s", month.Format(statementMonthLayout)),
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send chart: %w", err)
+	}
+	return nil
+}
+
+// topCategoryTotals returns the n largest category totals.
+func topCategoryTotals(expenses []appmodels.Expense, n int) []categoryTotal {
+	result := aggregateByCategory(expenses)
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// formatMonthChange describes the change from previous to current.
+func formatMonthChange(current, previous decimal.Decimal, prevLabel string) string {
+	if previous.IsZero() {
+		return "no spending in " + prevLabel
+	}
+
+	pct := current.Sub(previous).Div(previous).Mul(decimal.NewFromInt(100))
+	switch pct.Sign() {
+	case 1:
+		return fmt.Sprintf("▲ %s%% vs %s", pct.StringFixed(1), prevLabel)
+	case -1:
+		return fmt.Sprintf("▼ %s%% vs %s", pct.Abs().StringFixed(1), prevLabel)
+	default:
+		return "same as " + prevLabel
+	}
+}
+
+// buildStatementSummary renders the statement text. When hasPrevious is
+// false the month-over-month comparison is omitted. failed lists the parts
+// of the statement that could not be produced. The mono format shows the
+// totals and top categories as aligned tables.
+func buildStatementSummary(
+	month time.Time,
+	expenses []appmodels.Expense,
+	previous []appmodels.Expense,
+	hasPrevious bool,
+	failed []string,
+	format botfmt.Format,
+) string {
+	prevLabel := month.AddDate(0, -1, 0).Format("January")
+	totals := sumExpenseAmountsByCurrency(expenses)
+	prevTotals := sumExpenseAmountsByCurrency(previous)
+	topCategories := topCategoryTotals(expenses, statementTopCategories)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🧾 <b>Statement for %s</b>\n%d expenses\n\n<b>Total</b>",
+		month.Format(statementMonthLayout), len(expenses))
+	if format == botfmt.FormatMono {
+		writeStatementTablesMono(&sb, totals, prevTotals, hasPrevious, prevLabel, topCategories)
+	} else {
+		for _, cur := range sortedCurrencyKeys(totals) {
+			fmt.Fprintf(&sb, "\n  %s: %s%s",
+				botfmt.EscapeHTML(cur),
+				botfmt.EscapeHTML(botfmt.CurrencySymbol(cur)),
+				botfmt.FormatAmount(totals[cur], cur))
+			if hasPrevious {
+				fmt.Fprintf(&sb, " (%s)", formatMonthChange(totals[cur], prevTotals[cur], prevLabel))
+			}
+		}
+
+		sb.WriteString("\n\n<b>Top categories</b>")
+		for i, cat := range topCategories {
+			fmt.Fprintf(&sb, "\n%d. %s: %s", i+1, botfmt.EscapeHTML(cat.Name), botfmt.Money(cat.Total, cat.Currency))
+		}
+	}
+
+	if len(failed) > 0 {
+		fmt.Fprintf(&sb, "\n\n⚠️ Could not include: %s.", strings.Join(failed, ", "))
+	}
+
+	return sb.String()
+}
+
+// writeStatementTablesMono writes the statement totals, with the change
+// from the previous month when hasPrevious is set, and the top categories
+// as MonoTables.
+func writeStatementTablesMono(
+	sb *strings.Builder,
+	totals, prevTotals map[string]decimal.Decimal,
+	hasPrevious bool,
+	prevLabel string,
+	topCategories []categoryTotal,
+) {
+	totalRows := make([][]string, 0, len(totals))
+	for _, cur := range sortedCurrencyKeys(totals) {
+		row := []string{cur, botfmt.CurrencySymbol(cur) + botfmt.FormatAmount(totals[cur], cur)}
+		if hasPrevious {
+			row = append(row, formatMonthChange(totals[cur], prevTotals[cur], prevLabel))
+		}
+		totalRows = append(totalRows, row)
+	}
+	sb.WriteString("\n")
+	sb.WriteString(botfmt.MonoTable(totalRows, []botfmt.Align{botfmt.AlignLeft, botfmt.AlignRight}))
+
+	categoryRows := make([][]string, len(topCategories))
+	for i := range topCategories {
+		categoryRows[i] = []string{
+			strconv.Itoa(i+1) + ".",
+			botfmt.TruncateWidth(topCategories[i].Name, statementMonoCategoryWidth),
+			botfmt.Money(topCategories[i].Total, topCategories[i].Currency),
+		}
+	}
+	sb.WriteString("\n<b>Top categories</b>\n")
+	sb.WriteString(botfmt.MonoTable(categoryRows,
+		[]botfmt.Align{botfmt.AlignRight, botfmt.AlignLeft, botfmt.AlignRight}))
+}
@@ -0,0 +1,203 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	statementCommandTest = "/statement 2026-05"
+	statementMonthTest   = "May 2026"
+)
+
+func TestResolveMonthArgAt(t *testing.T) {
+	t.Parallel()
+
+	current := time.Date(2026, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	t.Run("empty selects current month", func(t *testing.T) {
+		t.Parallel()
+		start, end, err := resolveMonthArgAt("", current)
+		require.NoError(t, err)
+		require.Equal(t, time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), start)
+		require.Equal(t, time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), end)
+	})
+
+	t.Run("past month", func(t *testing.T) {
+		t.Parallel()
+		start, end, err := resolveMonthArgAt("2025-12", current)
+		require.NoError(t, err)
+		require.Equal(t, time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), start)
+		require.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), end)
+	})
+
+	t.Run("future month rejected", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := resolveMonthArgAt("2026-07", current)
+		require.Error(t, err)
+	})
+
+	t.Run("invalid format rejected", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := resolveMonthArgAt("june", current)
+		require.Error(t, err)
+	})
+}
+
+func TestBuildStatementSummary(t *testing.T) {
+	t.Parallel()
+
+	month := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	food := &appmodels.Category{Name: testCategoryFood}
+	transport := &appmodels.Category{Name: testCategoryTransport}
+	expenses := []appmodels.Expense{
+		{Amount: mustParseDecimal("30.00"), Currency: testCurrencySGD, Category: food},
+		{Amount: mustParseDecimal("20.00"), Currency: testCurrencySGD, Category: transport},
+		{Amount: mustParseDecimal("10.00"), Currency: testCurrencySGD, Category: food},
+	}
+	previous := []appmodels.Expense{
+		{Amount: mustParseDecimal("40.00"), Currency: testCurrencySGD, Category: food},
+	}
+
+	t.Run("includes totals, comparison and top categories", func(t *testing.T) {
+		t.Parallel()
+		text := buildStatementSummary(month, expenses, previous, true, nil, botfmt.FormatHTML)
+		require.Contains(t, text, statementMonthTest)
+		require.Contains(t, text, "3 expenses")
+		require.Contains(t, text, "SGD: S$60.00")
+		require.Contains(t, text, "▲ 50.0% vs April")
+		require.Contains(t, text, "1. Food: S$40.00 SGD")
+		require.Contains(t, text, "2. Transport: S$20.00 SGD")
+		require.NotContains(t, text, "Could not include")
+	})
+
+	t.Run("notes failed parts and skips comparison", func(t *testing.T) {
+		t.Parallel()
+		text := buildStatementSummary(month, expenses, nil, false, []string{statementPartChart}, botfmt.FormatHTML)
+		require.NotContains(t, text, "vs April")
+		require.Contains(t, text, "Could not include: category chart.")
+	})
+
+	t.Run("mono format aligns totals and categories", func(t *testing.T) {
+		t.Parallel()
+		text := buildStatementSummary(month, expenses, previous, true, nil, botfmt.FormatMono)
+		require.Contains(t, text, "<b>Total</b>\n<pre>SGD  S$60.00  ▲ 50.0% vs April</pre>")
+		require.Contains(t, text, "<b>Top categories</b>\n<pre>1.  Food       S$40.00 SGD\n2.  Transport  S$20.00 SGD</pre>")
+	})
+}
+
+func TestTopCategoryTotals(t *testing.T) {
+	t.Parallel()
+
+	food := &appmodels.Category{Name: "Food"}
+	expenses := []appmodels.Expense{
+		{Amount: mustParseDecimal("30.00"), Currency: testCurrencySGD, Category: food},
+		{Amount: mustParseDecimal("50.00"), Currency: "EUR", Category: food},
+		{Amount: mustParseDecimal("10.00"), Currency: testCurrencySGD, Category: food},
+	}
+
+	top := topCategoryTotals(expenses, 3)
+	require.Len(t, top, 2, "currencies are not added together")
+	require.Equal(t, "EUR", top[0].Currency)
+	require.True(t, top[0].Total.Equal(mustParseDecimal("50.00")))
+	require.Equal(t, testCurrencySGD, top[1].Currency)
+	require.True(t, top[1].Total.Equal(mustParseDecimal("40.00")))
+	require.Len(t, topCategoryTotals(expenses, 1), 1)
+}
+
+func TestFormatMonthChange(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "no spending in April",
+		formatMonthChange(mustParseDecimal("10"), mustParseDecimal("0"), "April"))
+	require.Equal(t, "▼ 25.0% vs April",
+		formatMonthChange(mustParseDecimal("30"), mustParseDecimal("40"), "April"))
+	require.Equal(t, "same as April",
+		formatMonthChange(mustParseDecimal("40"), mustParseDecimal("40"), "April"))
+}
+
+func TestHandleStatementCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.nowFunc = func() time.Time { return time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC) }
+	userID := int64(500311)
+
+	err := b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Statement"})
+	require.NoError(t, err)
+
+	statementUpdate := func(text string) *models.Update {
+		return &models.Update{
+			Message: &models.Message{
+				Text: text,
+				Chat: models.Chat{ID: userID},
+				From: &models.User{ID: userID},
+			},
+		}
+	}
+
+	t.Run("invalid month shows usage", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleStatementCore(ctx, mockBot, statementUpdate("/statement 2026-13"))
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		require.Contains(t, mockBot.LastSentMessage().Text, "Usage")
+	})
+
+	t.Run("empty month reports no expenses", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleStatementCore(ctx, mockBot, statementUpdate(statementCommandTest))
+		require.Empty(t, mockBot.SentDocuments)
+		require.Contains(t, mockBot.LastSentMessage().Text, "No expenses found for May 2026")
+	})
+
+	for _, amount := range []string{"12.00", "8.00"} {
+		expense := &appmodels.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal(amount),
+			Currency:    testCurrencySGD,
+			Description: testLunchDesc,
+			Status:      appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		_, err := pool.Exec(ctx, testUpdateExpenseTimeSQL, time.Date(2026, 5, 12, 9, 0, 0, 0, time.UTC), expense.ID)
+		require.NoError(t, err)
+	}
+
+	t.Run("sends csv, chart and summary", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.chartGenerator = func([]appmodels.Expense, string) ([]byte, error) {
+			return []byte("png"), nil
+		}
+		b.handleStatementCore(ctx, mockBot, statementUpdate(statementCommandTest))
+
+		require.Len(t, mockBot.SentDocuments, 2)
+		require.Equal(t, "statement_2026-05.csv", mockBot.SentDocuments[0].Filename)
+		require.Equal(t, "statement_2026-05.png", mockBot.SentDocuments[1].Filename)
+		summary := mockBot.LastSentMessage().Text
+		require.Contains(t, summary, statementMonthTest)
+		require.Contains(t, summary, "S$20.00")
+		require.NotContains(t, summary, "Could not include")
+	})
+
+	t.Run("chart failure degrades gracefully", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.chartGenerator = func([]appmodels.Expense, string) ([]byte, error) {
+			return nil, errors.New("render failed")
+		}
+		b.handleStatementCore(ctx, mockBot, statementUpdate(statementCommandTest))
+
+		require.Len(t, mockBot.SentDocuments, 1)
+		require.Equal(t, "statement_2026-05.csv", mockBot.SentDocuments[0].Filename)
+		summary := mockBot.LastSentMessage().Text
+		require.Contains(t, summary, statementMonthTest)
+		require.Contains(t, summary, "Could not include: category chart.")
+	})
+}
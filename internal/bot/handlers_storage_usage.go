@@ -0,0 +1,160 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+// storageUsageDateLayout formats the oldest expense of /storage.
+const storageUsageDateLayout = "2 Jan 2006"
+
+// storageUsage is what /storage reports, for one user or for everyone.
+type storageUsage struct {
+	expenses *repository.ExpenseUsage
+	sources  map[appmodels.ExpenseSource]int
+	tags     int
+	audit    int
+	// receipts is nil when receipt storage is off.
+	receipts *repository.ReceiptFileStats
+}
+
+// loadStorageUsage runs the /storage counts for userID, or for every user
+// when userID is zero.
+func (b *Bot) loadStorageUsage(ctx context.Context, userID int64) (*storageUsage, error) {
+	expenses, err := b.expenseRepo.UsageStats(ctx, userID, b.now().Add(-b.draftExpiration()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count expenses: %w", err)
+	}
+	usage := &storageUsage{expenses: expenses}
+	if usage.sources, err = b.expenseRepo.CountBySource(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to count expenses by source: %w", err)
+	}
+	if usage.tags, err = b.tagRepo.Count(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", err)
+	}
+	if usage.audit, err = b.expenseAuditRepo.Count(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+	if b.receiptStore != nil {
+		if usage.receipts, err = b.receiptFileRepo.StatsByUser(ctx, userID); err != nil {
+			return nil, fmt.Errorf("failed to count stored receipts: %w", err)
+		}
+	}
+	return usage, nil
+}
+
+// formatStorageUsage renders /storage, each count followed by the command
+// that manages it. all switches the wording to the instance-wide variant.
+func formatStorageUsage(usage *storageUsage, all bool, loc *time.Location) string {
+	expenses := usage.expenses
+	var sb strings.Builder
+	if all {
+		fmt.Fprintf(&sb, "🗄️ <b>Data of all users</b> (%d with expenses)\n", expenses.Users)
+	} else {
+		sb.WriteString("🗄️ <b>Your data</b>\n")
+	}
+
+	fmt.Fprintf(&sb, "\n🧾 Expenses: %d · /dedupe, /delete", expenses.Expenses)
+	if by := formatSourceCounts(usage.sources); by != "" {
+		fmt.Fprintf(&sb, "\n   via %s", by)
+	}
+	fmt.Fprintf(&sb, "\n📝 Drafts: %d · /drafts", expenses.Drafts)
+	fmt.Fprintf(&sb, "\n🏷️ Tags: %d · /tags", usage.tags)
+	fmt.Fprintf(&sb, "\n📷 Receipts: %d", expenses.Receipts)
+	if usage.receipts != nil {
+		fmt.Fprintf(&sb, ", %d stored (%s)", usage.receipts.Files, formatByteSize(usage.receipts.Bytes))
+	}
+	sb.WriteString(" · /show")
+	fmt.Fprintf(&sb, "\n📜 Audit entries: %d · /history", usage.audit)
+	if !expenses.Oldest.IsZero() {
+		fmt.Fprintf(&sb, "\n📅 Oldest expense: %s · /export",
+			expenses.Oldest.In(loc).Format(storageUsageDateLayout))
+	}
+
+	var cleanup []string
+	if expenses.ExpiredDrafts > 0 {
+		cleanup = append(cleanup, fmt.Sprintf("%d expired drafts", expenses.ExpiredDrafts))
+	}
+	if usage.receipts != nil && usage.receipts.Orphans > 0 {
+		cleanup = append(cleanup, fmt.Sprintf("%d receipt images of deleted expenses (%s)",
+			usage.receipts.Orphans, formatByteSize(usage.receipts.OrphanBytes)))
+	}
+	if len(cleanup) > 0 {
+		fmt.Fprintf(&sb, "\n\n🧹 Removed by the next cleanup: %s", strings.Join(cleanup, ", "))
+	}
+	return sb.String()
+}
+
+// formatSourceCounts lists how many expenses came in through each source,
+// e.g. "receipt scan 12, command 3", or returns "" when there are none.
+func formatSourceCounts(counts map[appmodels.ExpenseSource]int) string {
+	parts := make([]string, 0, len(counts))
+	for _, source := range appmodels.ExpenseSources {
+		n := counts[source]
+		if n == 0 {
+			continue
+		}
+		label := botfmt.ExpenseSourceLabel(source)
+		if label == "" {
+			label = string(source)
+		}
+		parts = append(parts, fmt.Sprintf("%s %d", label, n))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// handleStorageUsage handles the /storage command.
+func (b *Bot) handleStorageUsage(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleStorageUsageCore(ctx, tgBot, update)
+}
+
+// handleStorageUsageCore is the testable implementation of
+// handleStorageUsage. /storage counts the sender's own data; superadmins
+// can add "all" to count everyone's.
+func (b *Bot) handleStorageUsageCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	args := extractCommandArgs(update.Message.Text, "/storage")
+	all := strings.EqualFold(args, "all")
+	switch {
+	case args != "" && !all:
+		send("Usage: <code>/storage</code> or, for superadmins, <code>/storage all</code>")
+		return
+	case all && !b.cfg.IsSuperAdmin(userID, update.Message.From.Username):
+		send(onlySuperadminsMsg)
+		return
+	}
+
+	scope := userID
+	if all {
+		scope = 0
+	}
+	usage, err := b.loadStorageUsage(ctx, scope)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to load storage usage")
+		send("❌ Failed to load your data usage.")
+		return
+	}
+	send(formatStorageUsage(usage, all, b.locationForUser(ctx, userID)))
+}
@@ -0,0 +1,162 @@
+package bot
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+	"gitlab.com/yelinaung/expense-bot/internal/storage"
+)
+
+func TestFormatStorageUsage(t *testing.T) {
+	t.Parallel()
+
+	usage := &storageUsage{
+		expenses: &repository.ExpenseUsage{
+			Users: 3, Expenses: 12, Drafts: 2, ExpiredDrafts: 1, Receipts: 4,
+			Oldest: time.Date(2025, 3, 9, 12, 0, 0, 0, time.UTC),
+		},
+		sources:  map[appmodels.ExpenseSource]int{appmodels.ExpenseSourceReceipt: 3, appmodels.ExpenseSourceText: 9},
+		tags:     5,
+		audit:    7,
+		receipts: &repository.ReceiptFileStats{Files: 3, Bytes: 3072, Orphans: 1, OrphanBytes: 512},
+	}
+	text := formatStorageUsage(usage, false, time.UTC)
+	require.Contains(t, text, "<b>Your data</b>")
+	require.Contains(t, text, "Expenses: 12 · /dedupe, /delete")
+	require.Contains(t, text, "via chat message 9, receipt scan 3")
+	require.Contains(t, text, "Drafts: 2 · /drafts")
+	require.Contains(t, text, "Tags: 5 · /tags")
+	require.Contains(t, text, "Receipts: 4, 3 stored (3.0 KiB) · /show")
+	require.Contains(t, text, "Audit entries: 7 · /history")
+	require.Contains(t, text, "Oldest expense: 9 Mar 2025 · /export")
+	require.Contains(t, text, "1 expired drafts, 1 receipt images of deleted expenses (512 B)")
+
+	require.Contains(t, formatStorageUsage(usage, true, time.UTC), "<b>Data of all users</b> (3 with expenses)")
+
+	empty := formatStorageUsage(&storageUsage{expenses: &repository.ExpenseUsage{}}, false, time.UTC)
+	require.Contains(t, empty, "Receipts: 0 · /show")
+	require.NotContains(t, empty, "stored")
+	require.NotContains(t, empty, "Oldest expense")
+	require.NotContains(t, empty, "cleanup")
+	require.NotContains(t, empty, "via")
+}
+
+func TestStorageUsage(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	store, err := storage.NewDiskStore(filepath.Join(t.TempDir(), "receipts"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	b.receiptStore = store
+
+	adminID := int64(123456)
+	userID := int64(442001)
+	otherID := int64(442002)
+	for _, id := range []int64{adminID, userID, otherID} {
+		require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: id, FirstName: "Stored"}))
+	}
+
+	// Everything already in the database, so the instance-wide counts can
+	// be checked by difference.
+	baseline, err := b.loadStorageUsage(ctx, 0)
+	require.NoError(t, err)
+
+	now := b.now()
+	newExpense := func(owner int64, status appmodels.ExpenseStatus, fileID string, createdAt time.Time) {
+		source := appmodels.ExpenseSourceCommand
+		if fileID != "" {
+			source = appmodels.ExpenseSourceReceipt
+		}
+		expense := &appmodels.Expense{
+			Source:        source,
+			UserID:        owner,
+			Amount:        mustParseDecimal("4.20"),
+			Currency:      currencyCodeSGD,
+			Description:   "Stored",
+			ReceiptFileID: fileID,
+			Status:        status,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		_, err := pool.Exec(ctx, testUpdateExpenseTimeSQL, createdAt, expense.ID)
+		require.NoError(t, err)
+		if fileID != "" {
+			b.storeReceiptImage(ctx, expense, []byte("jpeg-bytes"))
+		}
+		if status == appmodels.ExpenseStatusConfirmed {
+			tag, err := b.tagRepo.GetOrCreate(ctx, "storage442")
+			require.NoError(t, err)
+			require.NoError(t, b.tagRepo.AddTagsToExpense(ctx, expense.ID, []int{tag.ID}))
+			require.NoError(t, b.expenseAuditRepo.Record(ctx, []repository.ExpenseAuditEntry{{
+				ExpenseID: expense.ID, UserID: owner, ExpenseNumber: expense.UserExpenseNumber,
+				ActorID: owner, Field: repository.ExpenseAuditAmount, OldValue: "4.00", NewValue: "4.20",
+			}}))
+		}
+	}
+
+	oldest := time.Date(2024, 2, 3, 12, 0, 0, 0, time.UTC)
+	newExpense(userID, appmodels.ExpenseStatusConfirmed, "", oldest)
+	newExpense(userID, appmodels.ExpenseStatusConfirmed, "tg-receipt", now.Add(-time.Hour))
+	newExpense(userID, appmodels.ExpenseStatusDraft, "", now.Add(-time.Hour))
+	newExpense(userID, appmodels.ExpenseStatusDraft, "", now.Add(-b.draftExpiration()-time.Hour))
+	newExpense(otherID, appmodels.ExpenseStatusConfirmed, "tg-other", now.Add(-time.Hour))
+
+	storageCommand := func(from int64, text string) string {
+		mockBot := mocks.NewMockBot()
+		b.handleStorageUsageCore(ctx, mockBot, mocks.CommandUpdate(from, from, text))
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		return mockBot.LastSentMessage().Text
+	}
+
+	t.Run("counts only the sender's data", func(t *testing.T) {
+		usage, err := b.loadStorageUsage(ctx, userID)
+		require.NoError(t, err)
+		require.Equal(t, 2, usage.expenses.Expenses)
+		require.Equal(t, 2, usage.expenses.Drafts)
+		require.Equal(t, 1, usage.expenses.ExpiredDrafts)
+		require.Equal(t, 1, usage.expenses.Receipts)
+		require.True(t, oldest.Equal(usage.expenses.Oldest))
+		require.Equal(t, 1, usage.tags)
+		require.Equal(t, 2, usage.audit)
+		require.Equal(t, map[appmodels.ExpenseSource]int{
+			appmodels.ExpenseSourceCommand: 1, appmodels.ExpenseSourceReceipt: 1,
+		}, usage.sources)
+		require.Equal(t, 1, usage.receipts.Files)
+		require.Equal(t, int64(len("jpeg-bytes")), usage.receipts.Bytes)
+
+		text := storageCommand(userID, "/storage")
+		require.Contains(t, text, "<b>Your data</b>")
+		require.Contains(t, text, "Expenses: 2 ·")
+		require.Contains(t, text, "via command 1, receipt scan 1")
+		require.Contains(t, text, "Oldest expense: 3 Feb 2024")
+		require.Contains(t, text, "1 expired drafts")
+	})
+
+	t.Run("all counts every user", func(t *testing.T) {
+		usage, err := b.loadStorageUsage(ctx, 0)
+		require.NoError(t, err)
+		require.Equal(t, baseline.expenses.Expenses+3, usage.expenses.Expenses)
+		require.Equal(t, baseline.expenses.Drafts+2, usage.expenses.Drafts)
+		require.Equal(t, baseline.expenses.Receipts+2, usage.expenses.Receipts)
+		require.Equal(t, baseline.audit+3, usage.audit)
+		require.Equal(t, baseline.receipts.Files+2, usage.receipts.Files)
+
+		text := storageCommand(adminID, "/storage all")
+		require.Contains(t, text, "<b>Data of all users</b>")
+	})
+
+	t.Run("all is for superadmins", func(t *testing.T) {
+		require.Equal(t, onlySuperadminsMsg, storageCommand(userID, "/storage all"))
+	})
+
+	t.Run("unknown argument shows usage", func(t *testing.T) {
+		require.Contains(t, storageCommand(userID, "/storage mine"), "Usage:")
+	})
+}
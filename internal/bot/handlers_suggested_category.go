@@ -0,0 +1,291 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+const (
+	// suggestedCategoryDailyCap is how many categories one user may create
+	// from receipt suggestions per day, so odd suggestions do not pile up.
+	suggestedCategoryDailyCap = 3
+
+	suggestedCategoryCallbackPrefix = "sugcat_"
+	suggestedCategoryUseFmt         = "sugcat_use_%d"
+	suggestedCategoryIgnoreFmt      = "sugcat_ignore_%d"
+	suggestedCategoryUse            = "use"
+	suggestedCategoryIgnore         = "ignore"
+)
+
+// errSuggestedCategoryCap is returned when a user already created
+// suggestedCategoryDailyCap categories from receipts today.
+var errSuggestedCategoryCap = errors.New("suggested category daily cap reached")
+
+// newCategorySuggestion returns the category Gemini suggested for a receipt
+// when it is a valid name that matches none of the existing categories,
+// not even loosely, else "".
+func newCategorySuggestion(suggested string, categories []appmodels.Category) string {
+	suggested = strings.TrimSpace(suggested)
+	if suggested == "" || categoryNameError(suggested) != "" {
+		return ""
+	}
+	if MatchCategory(suggested, categories) != nil {
+		return ""
+	}
+	return suggested
+}
+
+// suggestedCategoryHint is the line added to a receipt draft that offers a
+// new category.
+func suggestedCategoryHint(name string) string {
+	return fmt.Sprintf("💡 Suggested new category: <b>%s</b>", botfmt.EscapeHTML(name))
+}
+
+// withSuggestedCategoryButtons adds the Create & use and Ignore buttons for
+// a suggested category below a draft keyboard.
+func withSuggestedCategoryButtons(keyboard *models.InlineKeyboardMarkup, expenseID int) *models.InlineKeyboardMarkup {
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []models.InlineKeyboardButton{
+		{Text: "➕ Create & use", CallbackData: fmt.Sprintf(suggestedCategoryUseFmt, expenseID)},
+		{Text: "Ignore", CallbackData: fmt.Sprintf(suggestedCategoryIgnoreFmt, expenseID)},
+	})
+	return keyboard
+}
+
+// handleSuggestedCategoryCallback handles the buttons of a suggested
+// category on a receipt draft.
+func (b *Bot) handleSuggestedCategoryCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleSuggestedCategoryCallbackCore(ctx, tgBot, update)
+}
+
+// handleSuggestedCategoryCallbackCore is the testable implementation of
+// handleSuggestedCategoryCallback. Callback data is sugcat_use_<expenseID>
+// or sugcat_ignore_<expenseID>; the suggestion itself is read from the
+// draft's receipt scan.
+func (b *Bot) handleSuggestedCategoryCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(query.Data, suggestedCategoryCallbackPrefix), "_")
+	if len(parts) != 2 {
+		answerCallback(ctx, tg, query)
+		return
+	}
+	expenseID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		answerCallback(ctx, tg, query)
+		return
+	}
+
+	userID := query.From.ID
+	expense, err := b.expenseRepo.GetByID(ctx, expenseID)
+	if err != nil || expense.UserID != userID || expense.Status != appmodels.ExpenseStatusDraft {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            "This draft is no longer open.",
+		})
+		return
+	}
+
+	scan, err := b.receiptScanRepo.GetByExpenseID(ctx, expenseID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Log.Error().Err(err).Int("expense_id", expenseID).Msg("Failed to load suggested category")
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            "❌ Something went wrong. Please try again.",
+		})
+		return
+	}
+
+	draftUpdate := botfmt.DraftUnchanged
+	switch {
+	case scan == nil || scan.SuggestedCategory == "":
+		answerCallback(ctx, tg, query)
+	case parts[0] == suggestedCategoryIgnore:
+		b.clearSuggestedCategory(ctx, expenseID)
+		answerCallback(ctx, tg, query)
+	case parts[0] == suggestedCategoryUse:
+		if !b.useSuggestedCategory(ctx, tg, query, expense, scan.SuggestedCategory) {
+			return
+		}
+		draftUpdate = botfmt.DraftCategoryCreated
+	default:
+		answerCallback(ctx, tg, query)
+		return
+	}
+
+	b.loadExpenseCategory(ctx, expense)
+	b.loadExpenseTags(ctx, expense)
+	current := withoutSuggestedCategoryButtons(query.Message.Message.ReplyMarkup, expense.ID)
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      query.Message.Message.Chat.ID,
+		MessageID:   query.Message.Message.ID,
+		Text:        botfmt.ReceiptDraftCard(expense, draftUpdate) + receiptDraftNotes(query.Message.Message.Text),
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: rebuildReceiptKeyboard(expense, current),
+	})
+}
+
+// receiptDraftNotePrefixes start the lines below a receipt draft card that
+// describe the scan: the breakdown, the image count, a shortened merchant
+// and the warnings.
+var receiptDraftNotePrefixes = []string{"🧾 ", "🖼 ", "✂️ ", "⚠️ "}
+
+// receiptDraftNotes returns the scan lines of a receipt draft card's text,
+// so a re-rendered card keeps them. Telegram returns the text without its
+// HTML, so the lines are escaped again.
+func receiptDraftNotes(text string) string {
+	sections := strings.Split(text, "\n\n")
+	var notes strings.Builder
+	for _, section := range sections[1:] {
+		for _, prefix := range receiptDraftNotePrefixes {
+			if strings.HasPrefix(section, prefix) {
+				notes.WriteString("\n\n" + botfmt.EscapeHTML(section))
+				break
+			}
+		}
+	}
+	return notes.String()
+}
+
+// withoutSuggestedCategoryButtons returns keyboard without the row of a
+// suggested category's buttons.
+func withoutSuggestedCategoryButtons(keyboard *models.InlineKeyboardMarkup, expenseID int) *models.InlineKeyboardMarkup {
+	if keyboard == nil {
+		return nil
+	}
+	use := fmt.Sprintf(suggestedCategoryUseFmt, expenseID)
+	rows := make([][]models.InlineKeyboardButton, 0, len(keyboard.InlineKeyboard))
+	for _, row := range keyboard.InlineKeyboard {
+		if !keyboardHasCallback(&models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{row}}, use) {
+			rows = append(rows, row)
+		}
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// useSuggestedCategory creates the suggested category, or reuses one of the
+// same name, and assigns it to the draft. It answers the callback and
+// reports whether the draft changed. Users at suggestedCategoryDailyCap
+// are told to pick a category instead.
+func (b *Bot) useSuggestedCategory(
+	ctx context.Context,
+	tg TelegramAPI,
+	query *models.CallbackQuery,
+	expense *appmodels.Expense,
+	name string,
+) bool {
+	userID := query.From.ID
+	alert := func(text string) {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            text,
+			ShowAlert:       true,
+		})
+	}
+
+	category, err := b.findUserCategory(ctx, userID, name)
+	if err != nil {
+		category, err = b.unhideSharedCategory(ctx, userID, name)
+	}
+	if err != nil && !errors.Is(err, errCategoryNotFound) {
+		logger.Log.Error().Err(err).Str("name", name).Msg("Failed to show suggested category")
+		alert("❌ Failed to create category. Please try again.")
+		return false
+	}
+	if err != nil {
+		if errText := categoryNameError(name); errText != "" {
+			alert(errText)
+			return false
+		}
+		category, err = b.createSuggestedCategory(ctx, userID, name)
+		if errors.Is(err, errSuggestedCategoryCap) {
+			alert(fmt.Sprintf("You can create up to %d categories from receipts per day. "+
+				"Use ✏️ Edit to pick an existing one.", suggestedCategoryDailyCap))
+			return false
+		}
+		if err != nil {
+			logger.Log.Error().Err(err).Str("name", name).Msg("Failed to create suggested category")
+			alert("❌ Failed to create category. It may already exist.")
+			return false
+		}
+		b.cacheCategory(category)
+		logger.Log.Info().
+			Str("user_hash", logger.HashUserID(userID)).
+			Int("category_id", category.ID).
+			Str("name", category.Name).
+			Msg("Category created from receipt suggestion")
+	}
+
+	expense.CategoryID = &category.ID
+	expense.Category = category
+	if err := b.expenses().EditFields(ctx, expense); err != nil {
+		logger.Log.Error().Err(err).Int("expense_id", expense.ID).Msg("Failed to assign suggested category")
+		alert("❌ Category created but failed to assign it. Please select it from the list.")
+		return false
+	}
+	b.clearSuggestedCategory(ctx, expense.ID)
+	answerCallback(ctx, tg, query)
+	return true
+}
+
+// createSuggestedCategory creates a category from a receipt suggestion
+// unless the user reached suggestedCategoryDailyCap today, in which case it
+// returns errSuggestedCategoryCap. The count and the insert run in one
+// transaction that holds the user's row, so taps on two drafts at once
+// cannot both pass the cap.
+func (b *Bot) createSuggestedCategory(ctx context.Context, userID int64, name string) (*appmodels.Category, error) {
+	categories := b.categoryRepo
+	var tx pgx.Tx
+	if beginner, ok := b.db.(database.TxBeginner); ok {
+		var err error
+		if tx, err = beginner.Begin(ctx); err != nil {
+			return nil, fmt.Errorf("begin tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		categories = repository.NewCategoryRepository(tx)
+	}
+
+	if err := categories.LockCreator(ctx, userID); err != nil {
+		return nil, err
+	}
+	startOfDay, _ := getDayDateRangeAt(b.now().In(b.locationForUser(ctx, userID)))
+	created, err := categories.CountCreatedBySince(ctx, userID, startOfDay)
+	if err != nil {
+		return nil, err
+	}
+	if created >= suggestedCategoryDailyCap {
+		return nil, errSuggestedCategoryCap
+	}
+	category, err := categories.CreateSuggested(ctx, name, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tx != nil {
+		if err := tx.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("commit tx: %w", err)
+		}
+	}
+	return category, nil
+}
+
+// clearSuggestedCategory drops the suggestion of a draft. Failures are
+// logged only; the buttons then stay harmless.
+func (b *Bot) clearSuggestedCategory(ctx context.Context, expenseID int) {
+	if err := b.receiptScanRepo.ClearSuggestedCategory(ctx, expenseID); err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expenseID).Msg("Failed to clear suggested category")
+	}
+}
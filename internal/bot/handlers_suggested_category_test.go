@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestNewCategorySuggestion(t *testing.T) {
+	t.Parallel()
+
+	categories := []appmodels.Category{{ID: 1, Name: "Food - Dining Out"}, {ID: 2, Name: "Transportation"}}
+	require.Equal(t, "Pharmacy", newCategorySuggestion(" Pharmacy ", categories))
+	require.Empty(t, newCategorySuggestion("", categories))
+	require.Empty(t, newCategorySuggestion("transportation", categories), "exact match")
+	require.Empty(t, newCategorySuggestion("Dining", categories), "near match")
+	require.Empty(t, newCategorySuggestion("Food Delivery", categories), "word match")
+	require.Empty(t, newCategorySuggestion(strings.Repeat("x", appmodels.MaxCategoryNameLength+1), categories))
+}
+
+func TestReceiptDraftNotes(t *testing.T) {
+	t.Parallel()
+
+	text := "⚠️ Partial Extraction - Please Verify\n\n💰 Amount: $54.60\n\n" +
+		"🧾 Subtotal $48.00 + tip $6.60 = $54.60\n\n" +
+		"⚠️ $54.60 is above your usual limit. Check the amount before confirming.\n\n" +
+		"💡 Suggested new category: Pharmacy"
+	require.Equal(t, "\n\n🧾 Subtotal $48.00 + tip $6.60 = $54.60"+
+		"\n\n⚠️ $54.60 is above your usual limit. Check the amount before confirming.", receiptDraftNotes(text))
+	require.Empty(t, receiptDraftNotes("📸 Receipt Scanned!\n\n💰 Amount: $5.00"))
+	require.Equal(t, "\n\n🖼 2 images &lt;scanned&gt;", receiptDraftNotes("title\n\n🖼 2 images <scanned>"))
+}
+
+func TestWithoutSuggestedCategoryButtons(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, withoutSuggestedCategoryButtons(nil, 7))
+
+	tip := buildReceiptTipKeyboard(7, currencyCodeSGD)
+	suggested := withSuggestedCategoryButtons(buildReceiptTipKeyboard(7, currencyCodeSGD), 7)
+	require.Equal(t, tip, withoutSuggestedCategoryButtons(suggested, 7))
+	require.Equal(t, tip, rebuildReceiptKeyboard(&appmodels.Expense{ID: 7, Currency: currencyCodeSGD},
+		withoutSuggestedCategoryButtons(suggested, 7)))
+}
+
+func TestSuggestedCategory(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(414100)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Suggest"}))
+
+	newDraft := func(mockBot *mocks.MockBot, suggested string) *appmodels.Expense {
+		expense := b.saveReceiptDraft(ctx, mockBot, userID, userID, "", &gemini.ReceiptData{
+			Amount:            mustParseDecimal("14.20"),
+			Currency:          currencyCodeSGD,
+			Merchant:          "Guardian",
+			SuggestedCategory: suggested,
+		})
+		require.NotNil(t, expense)
+		return expense
+	}
+	tap := func(mockBot *mocks.MockBot, format string, expenseID int) {
+		update := mocks.CallbackQueryUpdate(userID, userID, 5, fmt.Sprintf(format, expenseID))
+		b.handleSuggestedCategoryCallbackCore(ctx, mockBot, update)
+	}
+	reload := func(expenseID int) *appmodels.Expense {
+		expense, err := b.expenseRepo.GetByID(ctx, expenseID)
+		require.NoError(t, err)
+		return expense
+	}
+
+	t.Run("create and use", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := newDraft(mockBot, "Pharmacy")
+		sent := mockBot.LastSentMessage()
+		require.Contains(t, sent.Text, "Suggested new category: <b>Pharmacy</b>")
+		keyboard := requireInlineKeyboard(t, sent.ReplyMarkup)
+		last := keyboard.InlineKeyboard[len(keyboard.InlineKeyboard)-1]
+		require.Equal(t, fmt.Sprintf(suggestedCategoryUseFmt, expense.ID), last[0].CallbackData)
+		require.Equal(t, fmt.Sprintf(suggestedCategoryIgnoreFmt, expense.ID), last[1].CallbackData)
+
+		tap(mockBot, suggestedCategoryUseFmt, expense.ID)
+		edited := mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, "Category Created")
+		require.Contains(t, edited.Text, "Category: Pharmacy")
+		require.Len(t, requireInlineKeyboard(t, edited.ReplyMarkup).InlineKeyboard, 3)
+
+		category, err := b.categoryRepo.GetByName(ctx, "Pharmacy")
+		require.NoError(t, err)
+		require.Equal(t, category.ID, *reload(expense.ID).CategoryID)
+
+		categories, err := b.getCategoriesWithCache(ctx)
+		require.NoError(t, err)
+		_, cached := findCategoryByName(categories, "Pharmacy")
+		require.NotNil(t, cached, "cache was invalidated")
+
+		scan, err := b.receiptScanRepo.GetByExpenseID(ctx, expense.ID)
+		require.NoError(t, err)
+		require.Empty(t, scan.SuggestedCategory)
+	})
+
+	t.Run("ignore", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		expense := newDraft(mockBot, "Pet Supplies")
+
+		tap(mockBot, suggestedCategoryIgnoreFmt, expense.ID)
+		edited := mockBot.LastEditedMessage()
+		require.NotContains(t, edited.Text, "Suggested new category")
+		require.Len(t, requireInlineKeyboard(t, edited.ReplyMarkup).InlineKeyboard, 3)
+		require.Nil(t, reload(expense.ID).CategoryID)
+
+		_, err := b.categoryRepo.GetByName(ctx, "Pet Supplies")
+		require.Error(t, err)
+
+		tap(mockBot, suggestedCategoryUseFmt, expense.ID)
+		require.Nil(t, reload(expense.ID).CategoryID, "an ignored suggestion cannot be used later")
+	})
+
+	t.Run("near match shows no hint", func(t *testing.T) {
+		_, err := b.categoryRepo.Create(ctx, "Books & Magazines")
+		require.NoError(t, err)
+		b.invalidateCategoryCache()
+
+		mockBot := mocks.NewMockBot()
+		newDraft(mockBot, "Magazines")
+		sent := mockBot.LastSentMessage()
+		require.NotContains(t, sent.Text, "Suggested new category")
+		require.Len(t, requireInlineKeyboard(t, sent.ReplyMarkup).InlineKeyboard, 3)
+	})
+
+	t.Run("daily cap", func(t *testing.T) {
+		for i := range suggestedCategoryDailyCap {
+			_, err := b.categoryRepo.CreateSuggested(ctx, fmt.Sprintf("Capped %d", i), userID)
+			require.NoError(t, err)
+		}
+
+		mockBot := mocks.NewMockBot()
+		expense := newDraft(mockBot, "Stationery")
+		tap(mockBot, suggestedCategoryUseFmt, expense.ID)
+
+		require.Empty(t, mockBot.EditedMessages)
+		require.NotEmpty(t, mockBot.AnsweredCallbacks)
+		answer := mockBot.AnsweredCallbacks[len(mockBot.AnsweredCallbacks)-1]
+		require.Contains(t, answer.Text, fmt.Sprintf("up to %d categories", suggestedCategoryDailyCap))
+		require.Nil(t, reload(expense.ID).CategoryID)
+
+		_, err := b.categoryRepo.GetByName(ctx, "Stationery")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,178 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	// tagSuggestionMinUses is how many earlier expenses with the same
+	// description must carry a tag before it is suggested.
+	tagSuggestionMinUses = 3
+
+	tagSuggestCallbackPrefix = "tagsuggest_"
+	tagSuggestCallbackFmt    = "tagsuggest_%d"
+	tagSuggestButtonText     = "🏷️ Apply suggested tags"
+	tagSuggestAppliedText    = "Suggested tags are already applied."
+)
+
+// suggestTagsForExpense returns the tags the user usually gives expenses
+// with the same description, or nil when there is no strong association.
+func (b *Bot) suggestTagsForExpense(ctx context.Context, expense *appmodels.Expense) []string {
+	if strings.TrimSpace(expense.Description) == "" {
+		return nil
+	}
+
+	tags, err := b.tagRepo.GetFrequentTagsForDescription(ctx, expense.UserID, expense.Description, tagSuggestionMinUses)
+	if err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expense.ID).Msg("Failed to look up tag suggestions")
+		return nil
+	}
+
+	names := make([]string, len(tags))
+	for i := range tags {
+		names[i] = tags[i].Name
+	}
+	return names
+}
+
+// autoTagEnabled reports whether the user turned on /settings autotag.
+// Lookup failures count as off.
+func (b *Bot) autoTagEnabled(ctx context.Context, userID int64) bool {
+	autoTag, err := b.userRepo.GetAutoTag(ctx, userID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to get auto tag setting")
+		return false
+	}
+	return autoTag
+}
+
+// applyTagSuggestions handles a new expense saved without inline tags. With
+// auto-tagging on, the suggested tags are added and returned as applied;
+// otherwise they are returned as suggestions for the user to tap.
+func (b *Bot) applyTagSuggestions(ctx context.Context, expense *appmodels.Expense) (applied, suggested []string) {
+	suggested = b.suggestTagsForExpense(ctx, expense)
+	if len(suggested) == 0 {
+		return nil, nil
+	}
+	if !b.autoTagEnabled(ctx, expense.UserID) {
+		return nil, suggested
+	}
+	if err := b.addTagsByName(ctx, expense.ID, suggested); err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expense.ID).Msg("Failed to auto-apply suggested tags")
+		return nil, suggested
+	}
+	return suggested, nil
+}
+
+// addTagsByName adds tags to an expense, creating missing ones. Tags the
+// expense already has are left alone.
+func (b *Bot) addTagsByName(ctx context.Context, expenseID int, names []string) error {
+	tagIDs := make([]int, 0, len(names))
+	for _, name := range names {
+		tag, err := b.tagRepo.GetOrCreate(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to get tag %q: %w", name, err)
+		}
+		tagIDs = append(tagIDs, tag.ID)
+	}
+	if err := b.tagRepo.AddTagsToExpense(ctx, expenseID, tagIDs); err != nil {
+		return fmt.Errorf("failed to add suggested tags: %w", err)
+	}
+	return nil
+}
+
+// tagSuggestionLine is the line added to the confirmation card when tags
+// are suggested.
+func tagSuggestionLine(tags []string) string {
+	return "💡 Suggested tags: " + describeParsedTags(tags) + " — tap to apply"
+}
+
+// addTagSuggestionButton appends the button that applies suggested tags.
+func addTagSuggestionButton(keyboard *models.InlineKeyboardMarkup, expenseID int) {
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []models.InlineKeyboardButton{
+		{Text: tagSuggestButtonText, CallbackData: fmt.Sprintf(tagSuggestCallbackFmt, expenseID)},
+	})
+}
+
+// handleTagSuggestCallback handles taps on the "Apply suggested tags"
+// button.
+func (b *Bot) handleTagSuggestCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleTagSuggestCallbackCore(ctx, tgBot, update)
+}
+
+// handleTagSuggestCallbackCore is the testable implementation of
+// handleTagSuggestCallback. Suggestions are looked up again on every tap
+// and only missing tags are added, so tapping twice is harmless. The button
+// is removed once the tags are applied.
+func (b *Bot) handleTagSuggestCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil {
+		return
+	}
+
+	expenseID, err := strconv.Atoi(strings.TrimPrefix(query.Data, tagSuggestCallbackPrefix))
+	if err != nil {
+		answerCallback(ctx, tg, query)
+		return
+	}
+
+	expense, ok := b.loadCallbackExpense(ctx, tg, query, expenseID, false)
+	if !ok {
+		return
+	}
+
+	existing, err := b.tagRepo.GetByExpenseID(ctx, expense.ID)
+	if err != nil {
+		logger.Log.Error().Err(err).Int("expense_id", expense.ID).Msg("Failed to load expense tags")
+		respondStaleCallback(ctx, tg, query, staleLookupFailed, false)
+		return
+	}
+	has := make(map[string]bool, len(existing))
+	for _, tag := range existing {
+		has[tag.Name] = true
+	}
+
+	var missing []string
+	for _, name := range b.suggestTagsForExpense(ctx, expense) {
+		if !has[name] {
+			missing = append(missing, name)
+		}
+	}
+
+	text := tagSuggestAppliedText
+	if len(missing) > 0 {
+		if err := b.addTagsByName(ctx, expense.ID, missing); err != nil {
+			logger.Log.Error().Err(err).Int("expense_id", expense.ID).Msg("Failed to apply suggested tags")
+			respondStaleCallback(ctx, tg, query, staleLookupFailed, false)
+			return
+		}
+		text = "🏷️ Tagged " + describeParsedTags(missing)
+		logger.Log.Info().
+			Str("user_hash", logger.HashUserID(expense.UserID)).
+			Int("expense_id", expense.ID).
+			Int("tag_count", len(missing)).
+			Msg("Suggested tags applied")
+	}
+
+	_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: query.ID,
+		Text:            text,
+	})
+
+	if query.Message.Message == nil {
+		return
+	}
+	_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+		ChatID:      query.Message.Message.Chat.ID,
+		MessageID:   query.Message.Message.ID,
+		ReplyMarkup: buildExpenseReflectionKeyboard(expense.ID),
+	})
+}
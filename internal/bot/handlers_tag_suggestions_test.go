@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	tagSuggestLineTest   = "Suggested tags: #work — tap to apply"
+	tagSuggestHeaderTest = "Suggested tags"
+)
+
+func TestBuildTagSuggestionKeyboard(t *testing.T) {
+	t.Parallel()
+
+	keyboard := buildExpenseReflectionKeyboard(7)
+	addTagSuggestionButton(keyboard, 7)
+
+	last := keyboard.InlineKeyboard[len(keyboard.InlineKeyboard)-1]
+	require.Len(t, last, 1)
+	require.Equal(t, tagSuggestButtonText, last[0].Text)
+	require.Equal(t, "tagsuggest_7", last[0].CallbackData)
+	require.Equal(t, "💡 "+tagSuggestLineTest, tagSuggestionLine([]string{"work"}))
+}
+
+func TestTagSuggestions(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(379001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Tagger"}))
+
+	add := func(text string) *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		b.handleAddCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, text))
+		return mockBot
+	}
+	latestExpense := func() appmodels.Expense {
+		expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 1)
+		require.NoError(t, err)
+		require.Len(t, expenses, 1)
+		return expenses[0]
+	}
+	tagNames := func(expenseID int) []string {
+		tags, err := b.tagRepo.GetByExpenseID(ctx, expenseID)
+		require.NoError(t, err)
+		names := make([]string, len(tags))
+		for i := range tags {
+			names[i] = tags[i].Name
+		}
+		return names
+	}
+
+	for range 2 {
+		add("/add 12 Team lunch #work")
+	}
+
+	t.Run("no suggestion below three prior uses", func(t *testing.T) {
+		msg := add("/add 12 Team lunch").LastSentMessage()
+		require.NotContains(t, msg.Text, tagSuggestHeaderTest)
+		add("/add 12 Team lunch #work")
+	})
+
+	t.Run("no suggestion for a novel description", func(t *testing.T) {
+		msg := add("/add 30 Dentist").LastSentMessage()
+		require.NotContains(t, msg.Text, tagSuggestHeaderTest)
+		require.Empty(t, tagNames(latestExpense().ID))
+	})
+
+	t.Run("suggestion button applies tags once", func(t *testing.T) {
+		msg := add("/add 14 team lunch").LastSentMessage()
+		require.Contains(t, msg.Text, tagSuggestLineTest)
+		keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+		last := keyboard.InlineKeyboard[len(keyboard.InlineKeyboard)-1]
+		require.Equal(t, tagSuggestButtonText, last[0].Text)
+
+		expense := latestExpense()
+		require.Empty(t, tagNames(expense.ID))
+
+		tap := func() *mocks.MockBot {
+			mockBot := mocks.NewMockBot()
+			b.handleTagSuggestCallbackCore(ctx, mockBot,
+				mocks.CallbackQueryUpdate(userID, userID, 1, last[0].CallbackData))
+			return mockBot
+		}
+
+		first := tap()
+		require.Equal(t, "🏷️ Tagged #work", first.AnsweredCallbacks[0].Text)
+		require.Len(t, first.EditedReplyMarkups, 1)
+		require.Equal(t, []string{"work"}, tagNames(expense.ID))
+
+		second := tap()
+		require.Equal(t, tagSuggestAppliedText, second.AnsweredCallbacks[0].Text)
+		require.Equal(t, []string{"work"}, tagNames(expense.ID))
+	})
+
+	t.Run("other users cannot apply suggestions", func(t *testing.T) {
+		expense := latestExpense()
+		mockBot := mocks.NewMockBot()
+		b.handleTagSuggestCallbackCore(ctx, mockBot,
+			mocks.CallbackQueryUpdate(1, 1, 1, fmt.Sprintf(tagSuggestCallbackFmt, expense.ID)))
+		require.Equal(t, staleNotOwnerText, mockBot.AnsweredCallbacks[0].Text)
+	})
+
+	t.Run("autotag applies suggestions without asking", func(t *testing.T) {
+		require.NoError(t, b.userRepo.UpdateAutoTag(ctx, userID, true))
+
+		msg := add("/add 9 TEAM LUNCH").LastSentMessage()
+		require.NotContains(t, msg.Text, tagSuggestHeaderTest)
+		require.Contains(t, msg.Text, "🏷️ work")
+		keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+		require.Equal(t, buildExpenseReflectionKeyboard(0).InlineKeyboard[1][0].Text,
+			keyboard.InlineKeyboard[len(keyboard.InlineKeyboard)-1][0].Text)
+		require.Equal(t, []string{"work"}, tagNames(latestExpense().ID))
+	})
+
+	t.Run("inline tags win over suggestions", func(t *testing.T) {
+		msg := add("/add 9 team lunch #client").LastSentMessage()
+		require.Contains(t, msg.Text, "🏷️ client")
+		require.Equal(t, []string{"client"}, tagNames(latestExpense().ID))
+	})
+}
@@ -0,0 +1,384 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	// tagRangeMaxDays is the longest range /tagrange accepts.
+	tagRangeMaxDays = 90
+	// tagRangeConfirmThreshold is how many expenses /tagrange tags without
+	// asking first.
+	tagRangeConfirmThreshold = 20
+	// tagRangePreviewLimit is how many expenses the confirmation lists.
+	tagRangePreviewLimit = 10
+	// tagRangeTTL is how long a confirmation prompt stays valid.
+	tagRangeTTL = 10 * time.Minute
+
+	tagRangeCallbackPrefix = "tagrange_"
+	tagRangeYesFmt         = "tagrange_yes_%s"
+	tagRangeNoFmt          = "tagrange_no_%s"
+	tagRangeDayLayout      = "2 Jan 2006"
+
+	tagRangeUsageMsg = "❌ Usage: <code>/tagrange YYYY-MM-DD YYYY-MM-DD #tag</code>\n\n" +
+		"Example: <code>/tagrange 2024-03-04 2024-03-08 #trip_tokyo</code>"
+	tagRangeFailMsg      = "❌ Failed to tag expenses. Please try again."
+	tagRangeCancelledMsg = "Tagging cancelled."
+	tagRangeExpiredMsg   = "This tagging has expired. Run /tagrange again."
+)
+
+var (
+	errTagRangeExpired  = errors.New("tag range expired or already used")
+	errTagRangeNotOwner = errors.New("tag range belongs to another user")
+)
+
+// pendingTagRange is a /tagrange run waiting for the user to confirm it.
+// The arguments are kept rather than the plan, so confirming tags what is
+// in the range then.
+type pendingTagRange struct {
+	UserID    int64
+	Start     string
+	End       string
+	Tag       string
+	ExpiresAt time.Time
+}
+
+// tagRangePlan is what a /tagrange run would do: the expenses to tag and
+// how many in the range already have the tag. tagID is 0 when the tag does
+// not exist yet; it is only created when the expenses are tagged.
+type tagRangePlan struct {
+	tagID   int
+	tagName string
+	start   time.Time
+	end     time.Time
+	targets []appmodels.Expense
+	skipped int
+}
+
+// rangeLabel renders the inclusive range of the plan, e.g.
+// "4 Mar 2024 – 8 Mar 2024".
+func (p *tagRangePlan) rangeLabel() string {
+	return p.start.Format(tagRangeDayLayout) + " – " + p.end.AddDate(0, 0, -1).Format(tagRangeDayLayout)
+}
+
+// targetIDs returns the IDs of the expenses to tag.
+func (p *tagRangePlan) targetIDs() []int {
+	ids := make([]int, len(p.targets))
+	for i := range p.targets {
+		ids[i] = p.targets[i].ID
+	}
+	return ids
+}
+
+// tagRangeError turns a date range error into the reply for the user.
+func tagRangeError(err error) string {
+	switch {
+	case errors.Is(err, errDateRangeReversed):
+		return "❌ The end date is before the start date."
+	case errors.Is(err, errDateRangeTooLong):
+		return fmt.Sprintf("❌ The range can be at most %d days.", tagRangeMaxDays)
+	}
+	return tagRangeUsageMsg
+}
+
+// planTagRange finds the user's confirmed expenses from startArg to endArg
+// in their timezone and splits off those that already have tagName. The
+// returned string is the reply to send when there is no plan.
+func (b *Bot) planTagRange(
+	ctx context.Context,
+	userID int64,
+	startArg, endArg, tagName string,
+) (*tagRangePlan, string) {
+	start, end, err := resolveDateRangeArgsAt(startArg, endArg, b.locationForUser(ctx, userID), tagRangeMaxDays)
+	if err != nil {
+		return nil, tagRangeError(err)
+	}
+
+	name := strings.ToLower(strings.TrimPrefix(tagName, "#"))
+	if name == "" {
+		return nil, tagRangeUsageMsg
+	}
+	if !isValidTagName(name) {
+		return nil, fmt.Sprintf(
+			"❌ Invalid tag name '%s'. Tags must start with a letter, contain only letters/numbers/underscores, and be at most %d characters",
+			echoText(name),
+			appmodels.MaxTagNameLength,
+		)
+	}
+	plan := &tagRangePlan{tagName: "#" + name, start: start, end: end}
+	tag, err := b.tagRepo.GetByName(ctx, name)
+	switch {
+	case err == nil:
+		plan.tagID = tag.ID
+	case !errors.Is(err, pgx.ErrNoRows):
+		logger.Log.Error().Err(err).Msg("Failed to look up tag for tag range")
+		return nil, tagRangeFailMsg
+	}
+
+	expenses, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, userID, start, end)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch expenses for tag range")
+		return nil, tagRangeFailMsg
+	}
+	ids := make([]int, len(expenses))
+	for i := range expenses {
+		ids[i] = expenses[i].ID
+	}
+	tagsByExpense, err := b.tagRepo.GetByExpenseIDs(ctx, ids)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch tags for tag range")
+		return nil, tagRangeFailMsg
+	}
+
+	for i := range expenses {
+		if hasTagID(tagsByExpense[expenses[i].ID], plan.tagID) {
+			plan.skipped++
+			continue
+		}
+		plan.targets = append(plan.targets, expenses[i])
+	}
+	return plan, ""
+}
+
+// hasTagID reports whether tags contains the tag with tagID.
+func hasTagID(tags []appmodels.Tag, tagID int) bool {
+	for i := range tags {
+		if tags[i].ID == tagID {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTagRange tags the planned expenses, creating the tag if needed, and
+// returns the reply.
+func (b *Bot) applyTagRange(ctx context.Context, userID int64, plan *tagRangePlan) string {
+	if plan.tagID == 0 {
+		tag, err := b.tagRepo.GetOrCreate(ctx, strings.TrimPrefix(plan.tagName, "#"))
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to create tag for tag range")
+			return tagRangeFailMsg
+		}
+		plan.tagID = tag.ID
+	}
+	tagged, err := b.tagRepo.BulkAttach(ctx, plan.targetIDs(), plan.tagID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to tag expense range")
+		return tagRangeFailMsg
+	}
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(userID)).
+		Int("tag_id", plan.tagID).
+		Int64("tagged", tagged).
+		Int("skipped", plan.skipped).
+		Msg("Tagged expense range")
+
+	text := fmt.Sprintf("✅ Tagged %d expense(s) from %s with %s.",
+		tagged, plan.rangeLabel(), botfmt.EscapeHTML(plan.tagName))
+	if plan.skipped > 0 {
+		text += fmt.Sprintf(" %d already had it.", plan.skipped)
+	}
+	return text
+}
+
+// formatTagRangePreview asks before tagging more than
+// tagRangeConfirmThreshold expenses and lists the first few.
+func formatTagRangePreview(plan *tagRangePlan, loc *time.Location) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🏷️ <b>Tag %d expenses with %s?</b>\n%s",
+		len(plan.targets), botfmt.EscapeHTML(plan.tagName), plan.rangeLabel())
+	if plan.skipped > 0 {
+		fmt.Fprintf(&sb, " · %d already tagged", plan.skipped)
+	}
+	sb.WriteString("\n\n")
+	for i := range plan.targets[:min(len(plan.targets), tagRangePreviewLimit)] {
+		sb.WriteString(botfmt.ExpenseListItem(&plan.targets[i], nil, loc))
+	}
+	if more := len(plan.targets) - tagRangePreviewLimit; more > 0 {
+		fmt.Fprintf(&sb, "…and %d more.", more)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// storePendingTagRange remembers a /tagrange run under a new nonce.
+func (b *Bot) storePendingTagRange(pending *pendingTagRange) (string, error) {
+	nonce, err := newCallbackNonce()
+	if err != nil {
+		return "", err
+	}
+
+	b.pendingTagRangesMu.Lock()
+	defer b.pendingTagRangesMu.Unlock()
+	if b.pendingTagRanges == nil {
+		b.pendingTagRanges = make(map[string]*pendingTagRange)
+	}
+	now := b.now()
+	for key, p := range b.pendingTagRanges {
+		if now.After(p.ExpiresAt) {
+			delete(b.pendingTagRanges, key)
+		}
+	}
+	b.pendingTagRanges[nonce] = pending
+	return nonce, nil
+}
+
+// takePendingTagRange returns and forgets the run stored under nonce. Only
+// the user who ran /tagrange can take it.
+func (b *Bot) takePendingTagRange(nonce string, userID int64) (*pendingTagRange, error) {
+	b.pendingTagRangesMu.Lock()
+	defer b.pendingTagRangesMu.Unlock()
+
+	pending, ok := b.pendingTagRanges[nonce]
+	if !ok {
+		return nil, errTagRangeExpired
+	}
+	if pending.UserID != userID {
+		return nil, errTagRangeNotOwner
+	}
+	delete(b.pendingTagRanges, nonce)
+	if b.now().After(pending.ExpiresAt) {
+		return nil, errTagRangeExpired
+	}
+	return pending, nil
+}
+
+// handleTagRange handles the /tagrange command.
+func (b *Bot) handleTagRange(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleTagRangeCore(ctx, tgBot, update)
+}
+
+// handleTagRangeCore is the testable implementation of handleTagRange. It
+// tags all of the user's confirmed expenses between two dates, asking first
+// when more than tagRangeConfirmThreshold would be tagged.
+func (b *Bot) handleTagRangeCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	params := &bot.SendMessageParams{ChatID: chatID, ParseMode: models.ParseModeHTML}
+
+	args := strings.Fields(extractCommandArgs(update.Message.Text, "/tagrange"))
+	if len(args) != 3 {
+		params.Text = tagRangeUsageMsg
+		_, _ = tg.SendMessage(ctx, params)
+		return
+	}
+
+	plan, msg := b.planTagRange(ctx, userID, args[0], args[1], args[2])
+	switch {
+	case plan == nil:
+		params.Text = msg
+	case len(plan.targets) == 0:
+		params.Text = fmt.Sprintf("No expenses from %s to tag with %s.",
+			plan.rangeLabel(), botfmt.EscapeHTML(plan.tagName))
+		if plan.skipped > 0 {
+			params.Text += fmt.Sprintf(" %d already have it.", plan.skipped)
+		}
+	case len(plan.targets) > tagRangeConfirmThreshold:
+		nonce, err := b.storePendingTagRange(&pendingTagRange{
+			UserID:    userID,
+			Start:     args[0],
+			End:       args[1],
+			Tag:       args[2],
+			ExpiresAt: b.now().Add(tagRangeTTL),
+		})
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to store tag range preview")
+			params.Text = tagRangeFailMsg
+			break
+		}
+		params.Text = formatTagRangePreview(plan, b.locationForUser(ctx, userID))
+		params.ReplyMarkup = &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{{
+				{Text: "✅ Tag", CallbackData: fmt.Sprintf(tagRangeYesFmt, nonce)},
+				{Text: "❌ Cancel", CallbackData: fmt.Sprintf(tagRangeNoFmt, nonce)},
+			}},
+		}
+	default:
+		params.Text = b.applyTagRange(ctx, userID, plan)
+	}
+	_, _ = tg.SendMessage(ctx, params)
+}
+
+// handleTagRangeCallback handles the /tagrange confirmation buttons.
+func (b *Bot) handleTagRangeCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleTagRangeCallbackCore(ctx, tgBot, update)
+}
+
+// handleTagRangeCallbackCore is the testable implementation of
+// handleTagRangeCallback. Callback data is tagrange_yes_<nonce> or
+// tagrange_no_<nonce>, and only the user who ran /tagrange can press them.
+// The expenses are looked up again on confirmation, so the tap tags what is
+// in the range now.
+func (b *Bot) handleTagRangeCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	action, nonce, ok := strings.Cut(strings.TrimPrefix(query.Data, tagRangeCallbackPrefix), "_")
+	if !ok || (action != "yes" && action != "no") {
+		answerCallback(ctx, tg, query)
+		return
+	}
+
+	chatID := query.Message.Message.Chat.ID
+	messageID := query.Message.Message.ID
+
+	pending, err := b.takePendingTagRange(nonce, query.From.ID)
+	if errors.Is(err, errTagRangeNotOwner) {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            staleNotOwnerText,
+		})
+		return
+	}
+	if err != nil {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            tagRangeExpiredMsg,
+		})
+		// The buttons are dead for good, so remove them.
+		_, _ = tg.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+		})
+		return
+	}
+	answerCallback(ctx, tg, query)
+
+	edit := func(text string) {
+		_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: messageID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	if action == "no" {
+		edit(tagRangeCancelledMsg)
+		return
+	}
+
+	plan, msg := b.planTagRange(ctx, pending.UserID, pending.Start, pending.End, pending.Tag)
+	if plan == nil {
+		edit(msg)
+		return
+	}
+	edit(b.applyTagRange(ctx, pending.UserID, plan))
+}
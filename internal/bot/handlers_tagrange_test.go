@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestResolveDateRangeArgsAt(t *testing.T) {
+	t.Parallel()
+
+	loc := time.FixedZone("SGT", 8*60*60)
+
+	t.Run("inclusive range", func(t *testing.T) {
+		t.Parallel()
+		start, end, err := resolveDateRangeArgsAt("2024-03-04", "2024-03-08", loc, 90)
+		require.NoError(t, err)
+		require.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, loc), start)
+		require.Equal(t, time.Date(2024, 3, 9, 0, 0, 0, 0, loc), end)
+	})
+
+	t.Run("single day", func(t *testing.T) {
+		t.Parallel()
+		start, end, err := resolveDateRangeArgsAt("2024-03-04", "2024-03-04", loc, 90)
+		require.NoError(t, err)
+		require.Equal(t, 24*time.Hour, end.Sub(start))
+	})
+
+	t.Run("longest allowed range", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := resolveDateRangeArgsAt("2024-01-01", "2024-03-30", loc, 90)
+		require.NoError(t, err)
+	})
+
+	t.Run("too long", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := resolveDateRangeArgsAt("2024-01-01", "2024-03-31", loc, 90)
+		require.ErrorIs(t, err, errDateRangeTooLong)
+	})
+
+	t.Run("end before start", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := resolveDateRangeArgsAt("2024-03-08", "2024-03-04", loc, 90)
+		require.ErrorIs(t, err, errDateRangeReversed)
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := resolveDateRangeArgsAt("2024-02-30", "2024-03-04", loc, 90)
+		require.Error(t, err)
+		_, _, err = resolveDateRangeArgsAt("2024-03-04", "tomorrow", loc, 90)
+		require.Error(t, err)
+	})
+}
+
+func TestHandleTagRangeCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(412001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Trip"}))
+
+	newExpense := func(day time.Time, status appmodels.ExpenseStatus) *appmodels.Expense {
+		expense := &appmodels.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal("12.00"),
+			Currency:    currencyCodeSGD,
+			Description: "Ramen",
+			Status:      status,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		_, err := pool.Exec(ctx, testUpdateExpenseTimeSQL, day, expense.ID)
+		require.NoError(t, err)
+		return expense
+	}
+	tagRange := func(text string) *mocks.MockBot {
+		mockBot := mocks.NewMockBot()
+		b.handleTagRangeCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, text))
+		return mockBot
+	}
+	tagCount := func(expenses ...*appmodels.Expense) int {
+		ids := make([]int, len(expenses))
+		for i := range expenses {
+			ids[i] = expenses[i].ID
+		}
+		tags, err := b.tagRepo.GetByExpenseIDs(ctx, ids)
+		require.NoError(t, err)
+		count := 0
+		for _, expenseTags := range tags {
+			count += len(expenseTags)
+		}
+		return count
+	}
+
+	t.Run("validates arguments", func(t *testing.T) {
+		require.Equal(t, tagRangeUsageMsg, tagRange("/tagrange 2024-03-04 #trip").LastSentMessage().Text)
+		require.Equal(t, tagRangeUsageMsg, tagRange("/tagrange 2024-03-04 soon #trip").LastSentMessage().Text)
+		require.Contains(t, tagRange("/tagrange 2024-03-08 2024-03-04 #trip").LastSentMessage().Text,
+			"end date is before the start date")
+		require.Contains(t, tagRange("/tagrange 2024-01-01 2024-06-01 #trip").LastSentMessage().Text,
+			"at most 90 days")
+		require.Contains(t, tagRange("/tagrange 2024-03-04 2024-03-08 #trip-tokyo").LastSentMessage().Text,
+			"Invalid tag name")
+	})
+
+	t.Run("tags the range and skips already tagged expenses on rerun", func(t *testing.T) {
+		before := newExpense(time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC), appmodels.ExpenseStatusConfirmed)
+		first := newExpense(time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC), appmodels.ExpenseStatusConfirmed)
+		last := newExpense(time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC), appmodels.ExpenseStatusConfirmed)
+		draft := newExpense(time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC), appmodels.ExpenseStatusDraft)
+
+		text := tagRange("/tagrange 2024-03-04 2024-03-08 #trip_tokyo").LastSentMessage().Text
+		require.Contains(t, text, "Tagged 2 expense(s) from 4 Mar 2024 – 8 Mar 2024 with #trip_tokyo")
+		require.Equal(t, 2, tagCount(first, last))
+		require.Zero(t, tagCount(before, draft))
+
+		text = tagRange("/tagrange 2024-03-04 2024-03-08 #trip_tokyo").LastSentMessage().Text
+		require.Contains(t, text, "No expenses from 4 Mar 2024 – 8 Mar 2024 to tag with #trip_tokyo")
+		require.Contains(t, text, "2 already have it")
+		require.Equal(t, 2, tagCount(first, last))
+	})
+
+	t.Run("asks before tagging more than the threshold", func(t *testing.T) {
+		expenses := make([]*appmodels.Expense, 0, tagRangeConfirmThreshold+1)
+		for i := range tagRangeConfirmThreshold + 1 {
+			day := time.Date(2024, 5, 1+i, 12, 0, 0, 0, time.UTC)
+			expenses = append(expenses, newExpense(day, appmodels.ExpenseStatusConfirmed))
+		}
+
+		mockBot := tagRange("/tagrange 2024-05-01 2024-05-31 #holiday412")
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, fmt.Sprintf("Tag %d expenses with #holiday412?", tagRangeConfirmThreshold+1))
+		require.Contains(t, msg.Text, fmt.Sprintf("…and %d more.", tagRangeConfirmThreshold+1-tagRangePreviewLimit))
+		require.Zero(t, tagCount(expenses...))
+
+		keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+		require.Len(t, keyboard.InlineKeyboard[0], 2)
+		cancel := keyboard.InlineKeyboard[0][1].CallbackData
+		require.True(t, strings.HasPrefix(cancel, "tagrange_no_"))
+		_, err := b.tagRepo.GetByName(ctx, "holiday412")
+		require.ErrorIs(t, err, pgx.ErrNoRows, "the tag is not created before confirming")
+
+		mockBot = mocks.NewMockBot()
+		b.handleTagRangeCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 1, cancel))
+		require.Equal(t, tagRangeCancelledMsg, mockBot.LastEditedMessage().Text)
+		require.Zero(t, tagCount(expenses...))
+		_, err = b.tagRepo.GetByName(ctx, "holiday412")
+		require.ErrorIs(t, err, pgx.ErrNoRows, "cancelling leaves no tag behind")
+
+		keyboard = requireInlineKeyboard(t, tagRange("/tagrange 2024-05-01 2024-05-31 #holiday412").LastSentMessage().ReplyMarkup)
+		confirm := keyboard.InlineKeyboard[0][0].CallbackData
+		require.True(t, strings.HasPrefix(confirm, "tagrange_yes_"))
+
+		strangerID := int64(412002)
+		mockBot = mocks.NewMockBot()
+		b.handleTagRangeCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, strangerID, 1, confirm))
+		require.Equal(t, staleNotOwnerText, mockBot.AnsweredCallbacks[0].Text)
+		require.Empty(t, mockBot.EditedMessages)
+		require.Zero(t, tagCount(expenses...))
+
+		mockBot = mocks.NewMockBot()
+		b.handleTagRangeCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 1, confirm))
+		require.Contains(t, mockBot.LastEditedMessage().Text,
+			fmt.Sprintf("Tagged %d expense(s)", tagRangeConfirmThreshold+1))
+		require.Equal(t, tagRangeConfirmThreshold+1, tagCount(expenses...))
+
+		mockBot = mocks.NewMockBot()
+		b.handleTagRangeCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(userID, userID, 1, confirm))
+		require.Equal(t, tagRangeExpiredMsg, mockBot.AnsweredCallbacks[0].Text, "a prompt is used once")
+	})
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
 	"gitlab.com/yelinaung/expense-bot/internal/logger"
 	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
 )
@@ -16,6 +17,11 @@ import (
 // maxTagsPerCommand is the maximum number of tags allowed in a single /tag command.
 const maxTagsPerCommand = 10
 
+const (
+	tagUsageMsg   = "❌ Usage: <code>/tag &lt;id&gt; #tag1 [#tag2] ...</code>\n\nSee /help tag"
+	untagUsageMsg = "❌ Usage: <code>/untag &lt;id&gt; #tag</code>\n\nSee /help untag"
+)
+
 // validTagNameRegex validates a bare tag name (without the # prefix).
 var validTagNameRegex = regexp.MustCompile(`^[a-zA-Z]\w{0,29}$`)
 
@@ -24,14 +30,6 @@ func isValidTagName(name string) bool {
 	return len(name) <= appmodels.MaxTagNameLength && validTagNameRegex.MatchString(name)
 }
 
-// escapeHTML escapes HTML special characters for safe interpolation in Telegram HTML messages.
-func escapeHTML(s string) string {
-	s = strings.ReplaceAll(s, "&", "&amp;")
-	s = strings.ReplaceAll(s, "<", "&lt;")
-	s = strings.ReplaceAll(s, ">", "&gt;")
-	return s
-}
-
 // handleTag handles the /tag command to add tags to an expense.
 func (b *Bot) handleTag(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
 	b.handleTagCore(ctx, tgBot, update)
@@ -127,11 +125,11 @@ func (b *Bot) handleTagCore(ctx context.Context, tg TelegramAPI, update *models.
 func parseTagCommand(text string) (int64, []string, string) {
 	args := extractCommandArgs(text, "/tag")
 	if args == "" {
-		return 0, nil, "❌ Usage: <code>/tag &lt;id&gt; #tag1 [#tag2] ...</code>"
+		return 0, nil, tagUsageMsg
 	}
 	parts := strings.Fields(args)
 	if len(parts) < 2 {
-		return 0, nil, "❌ Usage: <code>/tag &lt;id&gt; #tag1 [#tag2] ...</code>"
+		return 0, nil, tagUsageMsg
 	}
 	expenseNum, err := strconv.ParseInt(parts[0], 10, 64)
 	if err != nil {
@@ -151,7 +149,7 @@ func (b *Bot) resolveTagIDs(ctx context.Context, tagNames []string) ([]int, []st
 		if !isValidTagName(name) {
 			return nil, nil, fmt.Errorf(
 				"❌ Invalid tag name '%s'. Tags must start with a letter, contain only letters/numbers/underscores, and be at most %d characters",
-				name,
+				echoText(name),
 				appmodels.MaxTagNameLength,
 			)
 		}
@@ -179,7 +177,7 @@ func buildTagConfirmationText(
 
 	currentNames := make([]string, 0, len(currentTags))
 	for i := range currentTags {
-		currentNames = append(currentNames, "#"+escapeHTML(currentTags[i].Name))
+		currentNames = append(currentNames, "#"+botfmt.EscapeHTML(currentTags[i].Name))
 	}
 	return fmt.Sprintf("✅ Added %s to expense #%d.\n🏷️ Tags: %s",
 		strings.Join(addedNames, ", "),
@@ -206,7 +204,7 @@ func (b *Bot) handleUntagCore(ctx context.Context, tg TelegramAPI, update *model
 	if args == "" {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:    chatID,
-			Text:      "❌ Usage: <code>/untag &lt;id&gt; #tag</code>",
+			Text:      untagUsageMsg,
 			ParseMode: models.ParseModeHTML,
 		})
 		return
@@ -216,7 +214,7 @@ func (b *Bot) handleUntagCore(ctx context.Context, tg TelegramAPI, update *model
 	if len(parts) < 2 {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:    chatID,
-			Text:      "❌ Usage: <code>/untag &lt;id&gt; #tag</code>",
+			Text:      untagUsageMsg,
 			ParseMode: models.ParseModeHTML,
 		})
 		return
@@ -262,7 +260,7 @@ func (b *Bot) handleUntagCore(ctx context.Context, tg TelegramAPI, update *model
 	if err != nil {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
-			Text:   fmt.Sprintf("❌ Tag '%s' not found.", tagName),
+			Text:   fmt.Sprintf("❌ Tag '%s' not found.", echoText(tagName)),
 		})
 		return
 	}
@@ -328,7 +326,7 @@ func (b *Bot) handleTagsCore(ctx context.Context, tg TelegramAPI, update *models
 		var sb strings.Builder
 		sb.WriteString("🏷️ <b>Tags</b>\n\n")
 		for i := range tags {
-			fmt.Fprintf(&sb, "%d. #%s\n", i+1, escapeHTML(tags[i].Name))
+			fmt.Fprintf(&sb, "%d. #%s\n", i+1, botfmt.EscapeHTML(tags[i].Name))
 		}
 
 		_, err = tg.SendMessage(ctx, &bot.SendMessageParams{
@@ -356,7 +354,7 @@ func (b *Bot) handleTagsCore(ctx context.Context, tg TelegramAPI, update *models
 	if err != nil {
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
-			Text:   fmt.Sprintf("❌ Tag '%s' not found.\n\nUse /tags to see all tags.", tagName),
+			Text:   fmt.Sprintf("❌ Tag '%s' not found.\n\nUse /tags to see all tags.", echoText(tagName)),
 		})
 		return
 	}
@@ -371,6 +369,6 @@ func (b *Bot) handleTagsCore(ctx context.Context, tg TelegramAPI, update *models
 		return
 	}
 
-	header := fmt.Sprintf("🏷️ <b>Expenses tagged #%s</b>", escapeHTML(tag.Name))
-	b.sendExpenseListCore(ctx, tg, chatID, expenses, header)
+	header := fmt.Sprintf("🏷️ <b>Expenses tagged #%s</b>", botfmt.EscapeHTML(tag.Name))
+	b.sendExpenseListCore(ctx, tg, chatID, userID, expenses, header)
 }
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
 	"gitlab.com/yelinaung/expense-bot/internal/models"
 )
 
@@ -100,7 +101,7 @@ func FuzzEscapeHTML(f *testing.F) {
 	f.Add("☕🍕")
 
 	f.Fuzz(func(t *testing.T, input string) {
-		result := escapeHTML(input)
+		result := botfmt.EscapeHTML(input)
 
 		// Invariant 1: Result must not contain unescaped < or > characters.
 		// After escaping, any literal < or > means the function failed.
@@ -108,7 +109,7 @@ func FuzzEscapeHTML(f *testing.F) {
 		temp = strings.ReplaceAll(temp, "&gt;", "")
 		temp = strings.ReplaceAll(temp, "&amp;", "")
 		if strings.ContainsAny(temp, "<>") {
-			t.Errorf("escapeHTML(%q) contains unescaped < or >: %q", input, result)
+			t.Errorf("botfmt.EscapeHTML(%q) contains unescaped < or >: %q", input, result)
 		}
 
 		// Invariant 2: Result must not contain bare & that is not part of &amp;, &lt;, or &gt;.
@@ -117,7 +118,7 @@ func FuzzEscapeHTML(f *testing.F) {
 		check = strings.ReplaceAll(check, "&lt;", "\x00")
 		check = strings.ReplaceAll(check, "&gt;", "\x00")
 		if strings.Contains(check, "&") {
-			t.Errorf("escapeHTML(%q) contains bare &: %q", input, result)
+			t.Errorf("botfmt.EscapeHTML(%q) contains bare &: %q", input, result)
 		}
 	})
 }
@@ -0,0 +1,433 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+const (
+	exportTaxonomyCommand = "/exporttaxonomy"
+	importTaxonomyCommand = "/importtaxonomy"
+
+	// taxonomyVersion is the version of the document /exporttaxonomy
+	// writes. /importtaxonomy refuses other versions.
+	taxonomyVersion = 1
+
+	// taxonomyMaxBytes caps the size of an /importtaxonomy file.
+	taxonomyMaxBytes = 256 << 10
+
+	importTaxonomyUsageMsg = "🗂️ Send a file from /exporttaxonomy with the caption <code>/importtaxonomy</code>, " +
+		"or reply to one with <code>/importtaxonomy</code>.\n\n" +
+		"Missing categories and tags are created. Existing ones are kept as they are."
+	importTaxonomyFailedMsg = "❌ Failed to import categories and tags. Please try again."
+)
+
+var (
+	errTaxonomyFormat  = errors.New("not a taxonomy document")
+	errTaxonomyVersion = errors.New("unsupported taxonomy version")
+)
+
+// taxonomyDocument is the JSON file /exporttaxonomy sends: a user's
+// categories with their attributes and the tags they use, without any
+// expenses or IDs, so it can be loaded on another instance.
+type taxonomyDocument struct {
+	Version    int                `json:"version"`
+	Categories []taxonomyCategory `json:"categories"`
+	Tags       []string           `json:"tags"`
+}
+
+// taxonomyCategory is a category in a taxonomyDocument. Emojis are part of
+// the name. Color is the chart color set with /setcategorycolor, if any.
+type taxonomyCategory struct {
+	Name        string   `json:"name"`
+	Color       string   `json:"color,omitempty"`
+	DefaultTags []string `json:"default_tags,omitempty"`
+}
+
+// taxonomySummary is the outcome of an /importtaxonomy. Conflicts lists
+// existing categories whose attributes differ from the file and Skipped
+// the entries that could not be used.
+type taxonomySummary struct {
+	CategoriesAdded int
+	CategoriesKept  int
+	TagsAdded       int
+	Conflicts       []string
+	Skipped         []string
+}
+
+// buildTaxonomy collects the categories a user sees, in their list order,
+// with their default tags, and the tags the user has used or set as
+// defaults.
+func (b *Bot) buildTaxonomy(ctx context.Context, userID int64) (*taxonomyDocument, error) {
+	categories, err := b.visibleCategories(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load categories: %w", err)
+	}
+	defaults, err := b.tagRepo.GetAllCategoryDefaults(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load category default tags: %w", err)
+	}
+	used, err := b.tagRepo.GetAllByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tags: %w", err)
+	}
+
+	tags := make(map[string]bool, len(used))
+	for _, tag := range used {
+		tags[tag.Name] = true
+	}
+	doc := &taxonomyDocument{Version: taxonomyVersion, Categories: make([]taxonomyCategory, 0, len(categories))}
+	for _, category := range categories {
+		entry := taxonomyCategory{Name: category.Name, Color: category.Color}
+		for _, tag := range defaults[category.ID] {
+			entry.DefaultTags = append(entry.DefaultTags, tag.Name)
+			tags[tag.Name] = true
+		}
+		doc.Categories = append(doc.Categories, entry)
+	}
+	doc.Tags = make([]string, 0, len(tags))
+	for name := range tags {
+		doc.Tags = append(doc.Tags, name)
+	}
+	sort.Strings(doc.Tags)
+	return doc, nil
+}
+
+// parseTaxonomy reads an /exporttaxonomy file.
+func parseTaxonomy(data []byte) (*taxonomyDocument, error) {
+	var doc taxonomyDocument
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", errTaxonomyFormat, err)
+	}
+	if doc.Version != taxonomyVersion {
+		return nil, fmt.Errorf("%w: %d", errTaxonomyVersion, doc.Version)
+	}
+	return &doc, nil
+}
+
+// normalizeTaxonomyTag turns a tag of a taxonomy document into the stored
+// form, or returns false when it is not a valid tag name.
+func normalizeTaxonomyTag(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	return name, isValidTagName(name)
+}
+
+// importTaxonomy creates the categories and tags of doc the user does not
+// have yet. New categories get the file's color and default tags. Existing
+// categories are never changed; when their attributes differ from the
+// file the difference is reported as a conflict. Importing the same file
+// twice changes nothing.
+func (b *Bot) importTaxonomy(ctx context.Context, userID int64, doc *taxonomyDocument) (taxonomySummary, error) {
+	var summary taxonomySummary
+	defer b.invalidateCategoryCache()
+
+	tagIDs := make(map[string]int)
+	resolveTag := func(raw string) (int, bool, error) {
+		name, ok := normalizeTaxonomyTag(raw)
+		if !ok {
+			summary.Skipped = append(summary.Skipped, "#"+raw+": invalid tag name")
+			return 0, false, nil
+		}
+		if id, ok := tagIDs[name]; ok {
+			return id, true, nil
+		}
+		if tag, err := b.tagRepo.GetByName(ctx, name); err == nil {
+			tagIDs[name] = tag.ID
+			return tag.ID, true, nil
+		}
+		tag, err := b.tagRepo.GetOrCreate(ctx, name)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to create tag %q: %w", name, err)
+		}
+		tagIDs[name] = tag.ID
+		summary.TagsAdded++
+		return tag.ID, true, nil
+	}
+
+	for _, raw := range doc.Tags {
+		if _, _, err := resolveTag(raw); err != nil {
+			return summary, err
+		}
+	}
+	for _, entry := range doc.Categories {
+		if err := b.importTaxonomyCategory(ctx, userID, entry, resolveTag, &summary); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// importTaxonomyCategory adds one category of a taxonomy document, or
+// compares it with the category of that name the user already has.
+func (b *Bot) importTaxonomyCategory(
+	ctx context.Context,
+	userID int64,
+	entry taxonomyCategory,
+	resolveTag func(string) (int, bool, error),
+	summary *taxonomySummary,
+) error {
+	name := strings.TrimSpace(entry.Name)
+	if name == "" || categoryNameError(name) != "" {
+		summary.Skipped = append(summary.Skipped, echoText(name)+": invalid category name")
+		return nil
+	}
+
+	color := ""
+	if entry.Color != "" {
+		parsed, err := parseCategoryColor(entry.Color)
+		if err != nil {
+			summary.Skipped = append(summary.Skipped, name+": color "+echoText(entry.Color)+" ignored")
+		}
+		color = parsed
+	}
+	tagIDs := make([]int, 0, len(entry.DefaultTags))
+	tagNames := make([]string, 0, len(entry.DefaultTags))
+	for _, raw := range entry.DefaultTags {
+		id, ok, err := resolveTag(raw)
+		if err != nil {
+			return err
+		}
+		if ok {
+			tag, _ := normalizeTaxonomyTag(raw)
+			tagIDs = append(tagIDs, id)
+			tagNames = append(tagNames, tag)
+		}
+	}
+
+	existing, err := b.findUserCategory(ctx, userID, name)
+	switch {
+	case err == nil:
+		summary.CategoriesKept++
+		return b.noteTaxonomyConflict(ctx, existing.ID, existing.Name, existing.Color, color, tagNames, summary)
+	case !errors.Is(err, errCategoryNotFound):
+		return fmt.Errorf("failed to look up category %q: %w", name, err)
+	}
+
+	category, err := b.createUserCategory(ctx, userID, name)
+	if err != nil {
+		return fmt.Errorf("failed to create category %q: %w", name, err)
+	}
+	summary.CategoriesAdded++
+	if category.OwnerID == 0 {
+		// A shared category the user had hidden is shown again, but it is
+		// not theirs to change.
+		return b.noteTaxonomyConflict(ctx, category.ID, category.Name, category.Color, color, tagNames, summary)
+	}
+	if color != "" {
+		if err := b.categoryRepo.SetColor(ctx, category.ID, color); err != nil {
+			return fmt.Errorf("failed to set color of %q: %w", name, err)
+		}
+	}
+	if len(tagIDs) > 0 {
+		if err := b.tagRepo.SetCategoryDefaults(ctx, category.ID, tagIDs); err != nil {
+			return fmt.Errorf("failed to set default tags of %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// noteTaxonomyConflict records how an existing category differs from its
+// entry in a taxonomy document, if it does.
+func (b *Bot) noteTaxonomyConflict(
+	ctx context.Context,
+	categoryID int,
+	name, color, fileColor string,
+	fileTags []string,
+	summary *taxonomySummary,
+) error {
+	current, err := b.tagRepo.GetCategoryDefaults(ctx, categoryID)
+	if err != nil {
+		return fmt.Errorf("failed to load default tags of %q: %w", name, err)
+	}
+	currentTags := make([]string, 0, len(current))
+	for _, tag := range current {
+		currentTags = append(currentTags, tag.Name)
+	}
+	fileTags = slices.Clone(fileTags)
+	sort.Strings(fileTags)
+	fileTags = slices.Compact(fileTags)
+
+	var diffs []string
+	if !strings.EqualFold(color, fileColor) {
+		diffs = append(diffs, fmt.Sprintf("color %s here, %s in the file", orNone(color), orNone(fileColor)))
+	}
+	if !slices.Equal(currentTags, fileTags) {
+		diffs = append(diffs, fmt.Sprintf("default tags %s here, %s in the file",
+			orNone(hashTags(currentTags)), orNone(hashTags(fileTags))))
+	}
+	if len(diffs) > 0 {
+		summary.Conflicts = append(summary.Conflicts, name+": "+strings.Join(diffs, "; "))
+	}
+	return nil
+}
+
+// hashTags writes tag names as "#a #b".
+func hashTags(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return "#" + strings.Join(names, " #")
+}
+
+// orNone returns s, or "none" when it is empty.
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// formatTaxonomySummary reports an /importtaxonomy.
+func formatTaxonomySummary(summary taxonomySummary) string {
+	var sb strings.Builder
+	sb.WriteString("🗂️ <b>Taxonomy imported</b>\n\n")
+	fmt.Fprintf(&sb, "Categories added: %d\n", summary.CategoriesAdded)
+	fmt.Fprintf(&sb, "Categories already there: %d\n", summary.CategoriesKept)
+	fmt.Fprintf(&sb, "Tags added: %d", summary.TagsAdded)
+	if len(summary.Conflicts) > 0 {
+		sb.WriteString("\n\n⚠️ <b>Kept as they are:</b>")
+		for _, conflict := range summary.Conflicts {
+			sb.WriteString("\n• " + botfmt.EscapeHTML(conflict))
+		}
+	}
+	if len(summary.Skipped) > 0 {
+		sb.WriteString("\n\n<b>Skipped:</b>")
+		for _, skipped := range summary.Skipped {
+			sb.WriteString("\n• " + botfmt.EscapeHTML(skipped))
+		}
+	}
+	return sb.String()
+}
+
+// handleExportTaxonomy handles the /exporttaxonomy command.
+func (b *Bot) handleExportTaxonomy(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleExportTaxonomyCore(ctx, tgBot, update)
+}
+
+// handleExportTaxonomyCore is the testable implementation of
+// handleExportTaxonomy. It sends the user's categories and tags as a JSON
+// file for /importtaxonomy.
+func (b *Bot) handleExportTaxonomyCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil || !b.sensitiveCommandAllowed(ctx, tg, update.Message) {
+		return
+	}
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	sendText := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: text})
+	}
+
+	doc, err := b.buildTaxonomy(ctx, userID)
+	var data []byte
+	if err == nil {
+		data, err = json.MarshalIndent(doc, "", "  ")
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to export taxonomy")
+		sendText("❌ Failed to export categories and tags. Please try again.")
+		return
+	}
+
+	filename := fmt.Sprintf("taxonomy_%s.json", b.now().In(b.locationForUser(ctx, userID)).Format("2006-01-02"))
+	if _, err := tg.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID:   chatID,
+		Document: &models.InputFileUpload{Filename: filename, Data: bytes.NewReader(data)},
+		Caption: fmt.Sprintf("🗂️ <b>Categories and tags</b>\n\nCategories: %d\nTags: %d\n\n"+
+			"Send this file with the caption <code>%s</code> to load them on another bot.",
+			len(doc.Categories), len(doc.Tags), importTaxonomyCommand),
+		ParseMode: models.ParseModeHTML,
+	}); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to send taxonomy document")
+		sendText("❌ Failed to send the file. Please try again.")
+		return
+	}
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(userID)).
+		Int("categories", len(doc.Categories)).
+		Int("tags", len(doc.Tags)).
+		Msg("Taxonomy exported")
+}
+
+// handleImportTaxonomy handles the /importtaxonomy command.
+func (b *Bot) handleImportTaxonomy(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleImportTaxonomyCore(ctx, tgBot, update)
+}
+
+// handleImportTaxonomyCore is the testable implementation of
+// handleImportTaxonomy. It downloads the file the command came with and
+// imports its categories and tags.
+func (b *Bot) handleImportTaxonomyCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil || !b.sensitiveCommandAllowed(ctx, tg, update.Message) {
+		return
+	}
+	msg := update.Message
+	sendHTML := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: msg.Chat.ID, Text: text, ParseMode: models.ParseModeHTML})
+	}
+
+	doc := importDocument(msg)
+	if doc == nil {
+		sendHTML(importTaxonomyUsageMsg)
+		return
+	}
+	if doc.FileSize > taxonomyMaxBytes {
+		sendHTML("❌ The file is too large for a taxonomy.")
+		return
+	}
+	data, err := b.downloadFile(ctx, tg, doc.FileID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to download taxonomy file")
+		sendHTML(importTaxonomyFailedMsg)
+		return
+	}
+	b.importTaxonomyCore(ctx, tg, msg.Chat.ID, msg.From.ID, data)
+}
+
+// importTaxonomyCore imports the categories and tags of a taxonomy file for
+// a user and reports what happened.
+func (b *Bot) importTaxonomyCore(ctx context.Context, tg TelegramAPI, chatID, userID int64, data []byte) {
+	sendHTML := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatID, Text: text, ParseMode: models.ParseModeHTML})
+	}
+
+	doc, err := parseTaxonomy(data)
+	switch {
+	case errors.Is(err, errTaxonomyVersion):
+		sendHTML(fmt.Sprintf("❌ This file is from an unknown version. Export it again with <code>%s</code>.",
+			exportTaxonomyCommand))
+		return
+	case err != nil:
+		logger.Log.Warn().Err(err).Msg("Failed to parse taxonomy file")
+		sendHTML("❌ Could not read the file. Send the JSON file /exporttaxonomy made.")
+		return
+	}
+
+	summary, err := b.importTaxonomy(ctx, userID, doc)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to import taxonomy")
+		sendHTML(importTaxonomyFailedMsg)
+		return
+	}
+
+	logger.Log.Info().
+		Str("user_hash", logger.HashUserID(userID)).
+		Int("categories_added", summary.CategoriesAdded).
+		Int("tags_added", summary.TagsAdded).
+		Int("conflicts", len(summary.Conflicts)).
+		Msg("Taxonomy imported")
+	sendHTML(formatTaxonomySummary(summary))
+}
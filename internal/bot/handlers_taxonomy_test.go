@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestParseTaxonomy(t *testing.T) {
+	t.Parallel()
+
+	doc, err := parseTaxonomy([]byte(`{"version":1,"categories":[{"name":"🧳 Travel","color":"#1F77B4",` +
+		`"default_tags":["trip"]}],"tags":["trip","work"]}`))
+	require.NoError(t, err)
+	require.Equal(t, &taxonomyDocument{
+		Version:    1,
+		Categories: []taxonomyCategory{{Name: "🧳 Travel", Color: "#1F77B4", DefaultTags: []string{"trip"}}},
+		Tags:       []string{"trip", "work"},
+	}, doc)
+
+	_, err = parseTaxonomy([]byte(`{"version":2,"categories":[],"tags":[]}`))
+	require.ErrorIs(t, err, errTaxonomyVersion)
+	_, err = parseTaxonomy([]byte("Date,Amount\n2026-03-01,5.50"))
+	require.ErrorIs(t, err, errTaxonomyFormat)
+	_, err = parseTaxonomy([]byte(`{"version":1,"expenses":[]}`))
+	require.ErrorIs(t, err, errTaxonomyFormat, "unknown fields are refused")
+}
+
+func TestFormatTaxonomySummary(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "🗂️ <b>Taxonomy imported</b>\n\nCategories added: 2\nCategories already there: 1\nTags added: 3",
+		formatTaxonomySummary(taxonomySummary{CategoriesAdded: 2, CategoriesKept: 1, TagsAdded: 3}))
+
+	text := formatTaxonomySummary(taxonomySummary{
+		Conflicts: []string{"Food: color #D62728 here, #1F77B4 in the file"},
+		Skipped:   []string{"#1x: invalid tag name"},
+	})
+	require.Contains(t, text, "⚠️ <b>Kept as they are:</b>\n• Food: color #D62728 here, #1F77B4 in the file")
+	require.Contains(t, text, "<b>Skipped:</b>\n• #1x: invalid tag name")
+}
+
+func TestTaxonomyRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(438001)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Curator"}))
+
+	travel, err := b.categoryRepo.CreateOwned(ctx, userID, "🧳 Taxonomy Travel")
+	require.NoError(t, err)
+	require.NoError(t, b.categoryRepo.SetColor(ctx, travel.ID, "#1F77B4"))
+	gifts, err := b.categoryRepo.CreateOwned(ctx, userID, "Taxonomy Gifts")
+	require.NoError(t, err)
+	work, err := b.tagRepo.GetOrCreate(ctx, "taxwork")
+	require.NoError(t, err)
+	trip, err := b.tagRepo.GetOrCreate(ctx, "taxtrip")
+	require.NoError(t, err)
+	require.NoError(t, b.tagRepo.SetCategoryDefaults(ctx, travel.ID, []int{work.ID, trip.ID}))
+	b.invalidateCategoryCache()
+
+	export := func() (*taxonomyDocument, []byte) {
+		mockBot := mocks.NewMockBot()
+		b.handleExportTaxonomyCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, exportTaxonomyCommand))
+		sent := mockBot.LastSentDocument()
+		require.NotNil(t, sent)
+		require.Contains(t, sent.Filename, "taxonomy_")
+		doc, err := parseTaxonomy(sent.Data)
+		require.NoError(t, err)
+		return doc, sent.Data
+	}
+	importFile := func(data []byte) string {
+		mockBot := mocks.NewMockBot()
+		b.importTaxonomyCore(ctx, mockBot, userID, userID, data)
+		return mockBot.LastSentMessage().Text
+	}
+
+	before, data := export()
+	require.Contains(t, before.Categories, taxonomyCategory{
+		Name:        "🧳 Taxonomy Travel",
+		Color:       "#1F77B4",
+		DefaultTags: []string{"taxtrip", "taxwork"},
+	})
+	require.Contains(t, before.Categories, taxonomyCategory{Name: "Taxonomy Gifts"})
+	require.Subset(t, before.Tags, []string{"taxtrip", "taxwork"})
+
+	require.NoError(t, b.categoryRepo.Delete(ctx, travel.ID))
+	require.NoError(t, b.categoryRepo.Delete(ctx, gifts.ID))
+	require.NoError(t, b.tagRepo.Delete(ctx, work.ID))
+	require.NoError(t, b.tagRepo.Delete(ctx, trip.ID))
+	b.invalidateCategoryCache()
+
+	text := importFile(data)
+	require.Contains(t, text, "Categories added: 2")
+	require.Contains(t, text, "Tags added: 2")
+	require.NotContains(t, text, "Kept as they are")
+
+	after, _ := export()
+	require.Equal(t, before, after)
+
+	t.Run("importing again adds nothing", func(t *testing.T) {
+		text := importFile(data)
+		require.Contains(t, text, "Categories added: 0")
+		require.Contains(t, text, "Tags added: 0")
+		require.NotContains(t, text, "Kept as they are")
+	})
+
+	t.Run("differing categories are kept and reported", func(t *testing.T) {
+		existing, err := b.findUserCategory(ctx, userID, "Taxonomy Gifts")
+		require.NoError(t, err)
+		require.NoError(t, b.categoryRepo.SetColor(ctx, existing.ID, "#D62728"))
+		b.invalidateCategoryCache()
+
+		text := importFile([]byte(`{"version":1,"categories":[{"name":"taxonomy gifts","color":"#1F77B4"},` +
+			`{"name":"Taxonomy Bad\nName"}],"tags":["1bad"]}`))
+		require.Contains(t, text, "Categories already there: 1")
+		require.Contains(t, text, "Taxonomy Gifts: color #D62728 here, #1F77B4 in the file")
+		require.Contains(t, text, "#1bad: invalid tag name")
+		require.Contains(t, text, "invalid category name")
+
+		stored, err := b.categoryRepo.GetByID(ctx, existing.ID)
+		require.NoError(t, err)
+		require.Equal(t, "#D62728", stored.Color)
+	})
+
+	t.Run("unknown version is refused", func(t *testing.T) {
+		text := importFile([]byte(`{"version":9,"categories":[],"tags":[]}`))
+		require.Contains(t, text, "unknown version")
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"html"
+	"slices"
 	"strings"
 	"time"
 
@@ -13,6 +14,13 @@ import (
 	"gitlab.com/yelinaung/expense-bot/internal/logger"
 )
 
+const (
+	setTimezoneCallbackPrefix = "settz_"
+	// setTimezoneCallbackFmt carries the user the suggestion is for and
+	// the timezone.
+	setTimezoneCallbackFmt = setTimezoneCallbackPrefix + "%d_%s"
+)
+
 // handleSetTimezone handles the /settimezone command.
 func (b *Bot) handleSetTimezone(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
 	b.handleSetTimezoneCore(ctx, tgBot, update)
@@ -57,31 +65,140 @@ Use IANA timezone names.`
 
 	tz := strings.TrimSpace(args)
 
-	loc, err := time.LoadLocation(tz)
-	if err != nil {
-		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:    chatID,
-			Text:      fmt.Sprintf("Unknown timezone: <code>%s</code>\n\nUse /settimezone to see common timezones.", html.EscapeString(tz)),
-			ParseMode: models.ParseModeHTML,
-		})
+	loc, ok := resolveTimezone(tz)
+	if !ok {
+		text := fmt.Sprintf("Unknown timezone: <code>%s</code>", html.EscapeString(echoText(tz)))
+		params := &bot.SendMessageParams{ChatID: chatID, ParseMode: models.ParseModeHTML}
+		if suggestions := suggestTimezones(tz); len(suggestions) > 0 {
+			params.Text = text + "\n\nDid you mean one of these?"
+			params.ReplyMarkup = inputSuggestionKeyboard(setTimezoneCallbackFmt, userID, suggestions)
+		} else {
+			params.Text = text + "\n\nUse /settimezone to see common timezones."
+		}
+		_, _ = tg.SendMessage(ctx, params)
 		return
 	}
 
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      b.setTimezone(ctx, userID, loc),
+		ParseMode: models.ParseModeHTML,
+	})
+}
+
+// setTimezone stores loc as the user's timezone and returns the reply.
+func (b *Bot) setTimezone(ctx context.Context, userID int64, loc *time.Location) string {
 	if err := b.userRepo.UpdateTimezone(ctx, userID, loc.String()); err != nil {
-		logger.Log.Error().Err(err).Int64("user_id", userID).Str("timezone", tz).Msg("Failed to update timezone")
-		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: chatID,
-			Text:   "Failed to update timezone. Please try again.",
-		})
-		return
+		logger.Log.Error().Err(err).Int64("user_id", userID).Str("timezone", loc.String()).Msg("Failed to update timezone")
+		return "Failed to update timezone. Please try again."
 	}
 
 	localNow := time.Now().In(loc)
 	logger.Log.Info().Int64("user_id", userID).Str("timezone", loc.String()).Msg("Timezone updated")
 
-	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:    chatID,
-		Text:      fmt.Sprintf("Timezone set to <b>%s</b>\n\nYour local time: %s", html.EscapeString(loc.String()), localNow.Format("Mon, 02 Jan 2006 15:04")),
+	return fmt.Sprintf("Timezone set to <b>%s</b>\n\nYour local time: %s",
+		html.EscapeString(loc.String()), localNow.Format("Mon, 02 Jan 2006 15:04"))
+}
+
+// resolveTimezone loads tz as given, or else the listed timezone it names
+// in other case, so "asia/singapore" works too.
+func resolveTimezone(tz string) (*time.Location, bool) {
+	if loc, err := time.LoadLocation(tz); err == nil {
+		return loc, true
+	}
+	for _, name := range timezoneNames {
+		if strings.EqualFold(name, tz) {
+			loc, err := time.LoadLocation(name)
+			return loc, err == nil
+		}
+	}
+	return nil, false
+}
+
+// timezoneCity is the last part of a timezone name, as people write it:
+// "New York" for America/New_York.
+func timezoneCity(zone string) string {
+	return strings.ReplaceAll(zone[strings.LastIndex(zone, "/")+1:], "_", " ")
+}
+
+// suggestTimezones returns up to maxInputSuggestions timezones for query.
+// Timezones whose name or city starts with it come first, ignoring case, so
+// "Asia/K" lists Asia/Kabul, Asia/Kamchatka and Asia/Karachi. Without any,
+// it falls back to the names, or for a query without a slash the cities,
+// that query is a likely typo of.
+func suggestTimezones(query string) []string {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+	spaced := strings.ReplaceAll(query, "_", " ")
+
+	var prefixed []string
+	for _, zone := range timezoneNames {
+		if strings.HasPrefix(strings.ToLower(zone), query) ||
+			strings.HasPrefix(strings.ToLower(timezoneCity(zone)), spaced) {
+			prefixed = append(prefixed, zone)
+			if len(prefixed) == maxInputSuggestions {
+				break
+			}
+		}
+	}
+	if len(prefixed) > 0 {
+		return prefixed
+	}
+
+	if strings.Contains(query, "/") {
+		return closestNames(query, timezoneNames, maxInputSuggestions)
+	}
+	cities := make([]string, len(timezoneNames))
+	zoneByCity := make(map[string]string, len(timezoneNames))
+	for i, zone := range timezoneNames {
+		cities[i] = timezoneCity(zone)
+		zoneByCity[cities[i]] = zone
+	}
+	suggestions := closestNames(spaced, cities, maxInputSuggestions)
+	for i, city := range suggestions {
+		suggestions[i] = zoneByCity[city]
+	}
+	return suggestions
+}
+
+// handleSetTimezoneCallback handles the suggestion buttons of /settimezone.
+func (b *Bot) handleSetTimezoneCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleSetTimezoneCallbackCore(ctx, tgBot, update)
+}
+
+// handleSetTimezoneCallbackCore sets the picked timezone. Only the user who
+// got the suggestions can pick one.
+func (b *Bot) handleSetTimezoneCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	userID, zone, ok := parseInputSuggestionCallback(query.Data, setTimezoneCallbackPrefix)
+	if !ok || !slices.Contains(timezoneNames, zone) {
+		answerCallback(ctx, tg, query)
+		return
+	}
+	if userID != query.From.ID {
+		_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            staleNotOwnerText,
+		})
+		return
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		answerCallback(ctx, tg, query)
+		return
+	}
+	answerCallback(ctx, tg, query)
+
+	_, _ = tg.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    query.Message.Message.Chat.ID,
+		MessageID: query.Message.Message.ID,
+		Text:      b.setTimezone(ctx, userID, loc),
 		ParseMode: models.ParseModeHTML,
 	})
 }
@@ -2,7 +2,12 @@ package bot
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"slices"
 	"testing"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/go-telegram/bot"
 	tgmodels "github.com/go-telegram/bot/models"
@@ -61,6 +66,43 @@ func TestHandleSetTimezoneCore(t *testing.T) {
 		require.Contains(t, msg.Text, "Invalid/Zone")
 	})
 
+	t.Run("sets a listed timezone in any case", func(t *testing.T) {
+		mockBot.Reset()
+
+		b.handleSetTimezoneCore(ctx, mockBot, mocks.CommandUpdate(12345, user.ID, "/settimezone asia/singapore"))
+
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, "Timezone set to <b>Asia/Singapore</b>")
+		require.Nil(t, msg.ReplyMarkup)
+		tz, err := userRepo.GetTimezone(ctx, user.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Asia/Singapore", tz)
+	})
+
+	t.Run("suggests corrections and applies the picked one", func(t *testing.T) {
+		mockBot.Reset()
+
+		b.handleSetTimezoneCore(ctx, mockBot, mocks.CommandUpdate(12345, user.ID, "/settimezone Asia/Tokio"))
+
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, "Unknown timezone: <code>Asia/Tokio</code>")
+		require.Contains(t, msg.Text, "Did you mean")
+		keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+		require.Equal(t, "Asia/Tokyo", keyboard.InlineKeyboard[0][0].Text)
+		data := keyboard.InlineKeyboard[0][0].CallbackData
+
+		stranger := mocks.NewMockBot()
+		b.handleSetTimezoneCallbackCore(ctx, stranger, mocks.CallbackQueryUpdate(12345, 999, 1, data))
+		require.Equal(t, staleNotOwnerText, stranger.AnsweredCallbacks[0].Text)
+
+		mockBot.Reset()
+		b.handleSetTimezoneCallbackCore(ctx, mockBot, mocks.CallbackQueryUpdate(12345, user.ID, 1, data))
+		require.Contains(t, mockBot.LastEditedMessage().Text, "Timezone set to <b>Asia/Tokyo</b>")
+		tz, err := userRepo.GetTimezone(ctx, user.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Asia/Tokyo", tz)
+	})
+
 	t.Run("shows usage when no arguments", func(t *testing.T) {
 		mockBot.Reset()
 
@@ -194,3 +236,49 @@ func TestTimezoneHandlerWrappers(t *testing.T) {
 		b.handleShowTimezone(ctx, tgBot, &tgmodels.Update{})
 	})
 }
+
+func TestResolveTimezone(t *testing.T) {
+	t.Parallel()
+
+	loc, ok := resolveTimezone("America/New_York")
+	require.True(t, ok)
+	require.Equal(t, "America/New_York", loc.String())
+
+	loc, ok = resolveTimezone("AMERICA/NEW_YORK")
+	require.True(t, ok)
+	require.Equal(t, "America/New_York", loc.String())
+
+	_, ok = resolveTimezone("Asia/Singapor")
+	require.False(t, ok)
+}
+
+func TestSuggestTimezones(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"Asia/Singapor", []string{"Asia/Singapore"}},
+		{"Amerca/New_York", []string{"America/New_York"}},
+		{"Asia/K", []string{"Asia/Kabul", "Asia/Kamchatka", "Asia/Karachi"}},
+		{"new york", []string{"America/New_York"}},
+		{"Tokio", []string{"Asia/Tokyo"}},
+		{"xyzzy", []string{}},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, suggestTimezones(tt.query), tt.query)
+	}
+}
+
+func TestTimezoneNamesLoad(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, slices.IsSorted(timezoneNames[:len(timezoneNames)-1]))
+	for _, name := range timezoneNames {
+		_, err := time.LoadLocation(name)
+		require.NoError(t, err, name)
+		data := fmt.Sprintf(setTimezoneCallbackFmt, int64(math.MaxInt64), name)
+		require.LessOrEqual(t, len(data), 64, name)
+	}
+}
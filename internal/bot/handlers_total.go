@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// quickTotalTrigger is the bare message that asks for today's and this
+// week's totals, the same as /total.
+const quickTotalTrigger = "?"
+
+// isQuickTotalTrigger reports whether a free-text message asks for the quick
+// totals.
+func isQuickTotalTrigger(text string) bool {
+	return strings.TrimSpace(text) == quickTotalTrigger
+}
+
+// formatInlineCurrencyTotals renders totals on one line, e.g.
+// "S$12.50 SGD, $4.00 USD", or "nothing yet" when empty.
+func formatInlineCurrencyTotals(totals map[string]decimal.Decimal) string {
+	if len(totals) == 0 {
+		return "nothing yet"
+	}
+	parts := make([]string, 0, len(totals))
+	for _, currency := range sortedCurrencyKeys(totals) {
+		parts = append(parts, botfmt.Money(totals[currency], currency))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// monoTotalRows appends one MonoTable row per currency of totals, with
+// label on the first, or a "nothing yet" row when totals is empty.
+func monoTotalRows(rows [][]string, label string, totals map[string]decimal.Decimal) [][]string {
+	if len(totals) == 0 {
+		return append(rows, []string{label, "nothing yet"})
+	}
+	for _, currency := range sortedCurrencyKeys(totals) {
+		rows = append(rows, []string{label, botfmt.PlainMoney(totals[currency], currency)})
+		label = ""
+	}
+	return rows
+}
+
+// buildQuickTotalText renders today's and this week's totals in two lines,
+// or as a table with one row per currency in the mono format. weekExpenses
+// must cover the current week; today's total is taken from the ones created
+// on or after startOfDay.
+func buildQuickTotalText(
+	weekExpenses []appmodels.Expense,
+	startOfDay, endOfDay time.Time,
+	format botfmt.Format,
+) string {
+	var today []appmodels.Expense
+	for i := range weekExpenses {
+		createdAt := weekExpenses[i].CreatedAt
+		if !createdAt.Before(startOfDay) && createdAt.Before(endOfDay) {
+			today = append(today, weekExpenses[i])
+		}
+	}
+	todayTotals := sumExpenseAmountsByCurrency(today)
+	weekTotals := sumExpenseAmountsByCurrency(weekExpenses)
+
+	if format == botfmt.FormatMono {
+		rows := monoTotalRows(nil, "Today", todayTotals)
+		rows = monoTotalRows(rows, "This week", weekTotals)
+		return botfmt.MonoTable(rows, []botfmt.Align{botfmt.AlignLeft, botfmt.AlignRight})
+	}
+	return "📅 Today: " + formatInlineCurrencyTotals(todayTotals) +
+		"\n📆 This week: " + formatInlineCurrencyTotals(weekTotals)
+}
+
+// handleTotal handles the /total command to show today's and this week's
+// totals without listing expenses.
+func (b *Bot) handleTotal(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleTotalCore(ctx, tgBot, update)
+}
+
+// handleTotalCore is the testable implementation of handleTotal.
+func (b *Bot) handleTotalCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+
+	current := b.now().In(normalizeLocation(b.displayLocation))
+	startOfDay, endOfDay := getDayDateRangeAt(current)
+	startOfWeek, endOfWeek := getWeekDateRangeAt(current)
+
+	expenses, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, userID, startOfWeek, endOfWeek)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch expenses for quick total")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   failedFetchExpensesMsg,
+		})
+		return
+	}
+
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      buildQuickTotalText(expenses, startOfDay, endOfDay, b.displayFormat(ctx, userID)),
+		ParseMode: models.ParseModeHTML,
+	})
+}
+
+// handleQuickTotal answers a bare "?" with the /total output. It returns
+// false for any other message so free-text parsing can continue.
+func (b *Bot) handleQuickTotal(ctx context.Context, tg TelegramAPI, update *models.Update) bool {
+	if update.Message == nil || !isQuickTotalTrigger(update.Message.Text) {
+		return false
+	}
+	b.handleTotalCore(ctx, tg, update)
+	return true
+}
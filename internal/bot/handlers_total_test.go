@@ -0,0 +1,152 @@
+package bot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	quickTotalTodayTest = "📅 Today: "
+	quickTotalWeekTest  = "📆 This week: "
+)
+
+func TestIsQuickTotalTrigger(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, isQuickTotalTrigger("?"))
+	require.True(t, isQuickTotalTrigger("  ?\n"))
+	require.False(t, isQuickTotalTrigger("??"))
+	require.False(t, isQuickTotalTrigger("5 coffee?"))
+	require.False(t, isQuickTotalTrigger(""))
+}
+
+func TestBuildQuickTotalText(t *testing.T) {
+	t.Parallel()
+
+	startOfDay := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	endOfDay := startOfDay.AddDate(0, 0, 1)
+	expenses := []appmodels.Expense{
+		{Amount: mustParseDecimal("10.00"), Currency: currencyCodeSGD, CreatedAt: startOfDay.Add(-time.Hour)},
+		{Amount: mustParseDecimal("2.50"), Currency: currencyCodeSGD, CreatedAt: startOfDay.Add(time.Hour)},
+		{Amount: mustParseDecimal("4.00"), Currency: currencyCodeUSD, CreatedAt: startOfDay.Add(2 * time.Hour)},
+	}
+
+	require.Equal(t,
+		quickTotalTodayTest+"S$2.50 SGD, $4.00 USD\n"+quickTotalWeekTest+"S$12.50 SGD, $4.00 USD",
+		buildQuickTotalText(expenses, startOfDay, endOfDay, botfmt.FormatHTML))
+	require.Equal(t,
+		quickTotalTodayTest+"nothing yet\n"+quickTotalWeekTest+"nothing yet",
+		buildQuickTotalText(nil, startOfDay, endOfDay, botfmt.FormatHTML))
+
+	require.Equal(t,
+		"<pre>Today       S$2.50 SGD\n"+
+			"             $4.00 USD\n"+
+			"This week  S$12.50 SGD\n"+
+			"             $4.00 USD</pre>",
+		buildQuickTotalText(expenses, startOfDay, endOfDay, botfmt.FormatMono))
+	require.Equal(t,
+		"<pre>Today      nothing yet\nThis week  nothing yet</pre>",
+		buildQuickTotalText(nil, startOfDay, endOfDay, botfmt.FormatMono))
+}
+
+func TestHandleQuickTotalIgnoresOtherText(t *testing.T) {
+	t.Parallel()
+
+	b := &Bot{}
+	mockBot := mocks.NewMockBot()
+	require.False(t, b.handleQuickTotal(context.Background(), mockBot, mocks.MessageUpdate(1, 1, "5 coffee")))
+	require.False(t, b.handleQuickTotal(context.Background(), mockBot, &tgmodels.Update{}))
+	require.Equal(t, 0, mockBot.SentMessageCount())
+}
+
+// recordingHTTPClient captures request bodies sent to the Telegram API.
+type recordingHTTPClient struct {
+	bodies []string
+}
+
+func (c *recordingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		body, _ := io.ReadAll(req.Body)
+		c.bodies = append(c.bodies, string(body))
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(`{"ok":true,"result":{"message_id":1}}`)),
+		Header:     make(http.Header),
+	}, nil
+}
+
+func TestQuickTotal(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.displayLocation = time.UTC
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC) // Wednesday.
+	b.nowFunc = func() time.Time { return now }
+
+	userID := int64(370001)
+	err := b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Total"})
+	require.NoError(t, err)
+
+	createAt := func(amount, currency string, at time.Time) {
+		expense := &appmodels.Expense{
+			UserID:   userID,
+			Amount:   mustParseDecimal(amount),
+			Currency: currency,
+			Status:   appmodels.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		_, err := pool.Exec(ctx, testUpdateExpenseTimeSQL, at, expense.ID)
+		require.NoError(t, err)
+	}
+	createAt("3.00", currencyCodeSGD, now.Add(-time.Hour))
+	createAt("7.00", currencyCodeSGD, now.AddDate(0, 0, -2))
+	createAt("5.00", currencyCodeUSD, now.AddDate(0, 0, -7))
+
+	want := quickTotalTodayTest + "S$3.00 SGD\n" + quickTotalWeekTest + "S$10.00 SGD"
+
+	t.Run("/total command", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleTotalCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/total"))
+		require.Equal(t, want, mockBot.LastSentMessage().Text)
+		require.Nil(t, mockBot.LastSentMessage().ReplyMarkup)
+	})
+
+	t.Run("bare question mark", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		require.True(t, b.handleQuickTotal(ctx, mockBot, mocks.MessageUpdate(userID, userID, " ? ")))
+		require.Equal(t, want, mockBot.LastSentMessage().Text)
+	})
+
+	t.Run("question mark wins over free-text parsing", func(t *testing.T) {
+		client := &recordingHTTPClient{}
+		tgBot, err := tgbot.New(
+			"123:TESTTOKEN",
+			tgbot.WithSkipGetMe(),
+			tgbot.WithHTTPClient(time.Second, client),
+			tgbot.WithServerURL("http://example.com"),
+		)
+		require.NoError(t, err)
+
+		b.defaultHandler(ctx, tgBot, mocks.MessageUpdate(userID, userID, "?"))
+
+		require.Len(t, client.bodies, 1)
+		require.Contains(t, client.bodies[0], "This week")
+		require.NotContains(t, client.bodies[0], "I didn't understand")
+
+		expenses, err := b.expenseRepo.GetByUserID(ctx, userID, 10)
+		require.NoError(t, err)
+		require.Len(t, expenses, 3, "no expense is created from \"?\"")
+	})
+}
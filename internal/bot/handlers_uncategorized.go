@@ -0,0 +1,341 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+const (
+	// categoryModeUncategorized marks category callbacks started from the
+	// uncategorized list so the handlers return to that list.
+	categoryModeUncategorized = "unc"
+	// categoryModeUncertain does the same for /uncategorized --uncertain.
+	categoryModeUncertain = "uncu"
+
+	uncategorizedWindowDays      = 30
+	uncategorizedPageSize        = 5
+	uncategorizedMaxThreshold    = 1000
+	uncategorizedThresholdArg    = "threshold"
+	uncategorizedUncertainArg    = "uncertain"
+	uncategorizedPageCallbackFmt = "uncat_page_%d"
+	uncertainPageCallbackFmt     = "uncat_upage_%d"
+	uncategorizedSetCategoryFmt  = "edit_category_%d_" + categoryModeUncategorized
+	uncertainSetCategoryFmt      = "edit_category_%d_" + categoryModeUncertain
+	uncategorizedPrevButtonText  = "⬅️ Prev"
+	uncategorizedNextButtonText  = "Next ➡️"
+	uncategorizedThresholdUsage  = "Usage: <code>/uncategorized threshold &lt;n&gt;</code> (0 turns the weekly warning off)"
+	uncategorizedNoneMsg         = "✅ No uncategorized expenses in the last 30 days."
+	uncertainNoneMsg             = "✅ No uncategorized or uncertain expenses in the last 30 days."
+)
+
+// shouldWarnUncategorized reports whether count is above a user's threshold.
+// A threshold of zero disables the warning.
+func shouldWarnUncategorized(count, threshold int) bool {
+	return threshold > 0 && count > threshold
+}
+
+// uncategorizedSince returns the start of the uncategorized lookback window.
+func uncategorizedSince(now time.Time) time.Time {
+	return now.AddDate(0, 0, -uncategorizedWindowDays)
+}
+
+// buildUncategorizedKeyboard builds per-expense "Set category" buttons and
+// the page navigation row. uncertain keeps the buttons in the
+// --uncertain view.
+func buildUncategorizedKeyboard(
+	expenses []appmodels.Expense,
+	page, totalPages int,
+	uncertain bool,
+) *models.InlineKeyboardMarkup {
+	setCategoryFmt, pageFmt := uncategorizedSetCategoryFmt, uncategorizedPageCallbackFmt
+	if uncertain {
+		setCategoryFmt, pageFmt = uncertainSetCategoryFmt, uncertainPageCallbackFmt
+	}
+
+	rows := make([][]models.InlineKeyboardButton, 0, len(expenses)+1)
+	for i := range expenses {
+		rows = append(rows, []models.InlineKeyboardButton{{
+			Text:         fmt.Sprintf(listSetCategoryButtonFmt, expenses[i].UserExpenseNumber),
+			CallbackData: fmt.Sprintf(setCategoryFmt, expenses[i].ID),
+		}})
+	}
+
+	var nav []models.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, models.InlineKeyboardButton{
+			Text:         uncategorizedPrevButtonText,
+			CallbackData: fmt.Sprintf(pageFmt, page-1),
+		})
+	}
+	if page < totalPages-1 {
+		nav = append(nav, models.InlineKeyboardButton{
+			Text:         uncategorizedNextButtonText,
+			CallbackData: fmt.Sprintf(pageFmt, page+1),
+		})
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// buildUncategorizedView loads one page of uncategorized expenses and renders
+// it below header. With uncertain set, expenses Gemini categorized with
+// medium confidence are listed too. The page is clamped to the available
+// range. A nil keyboard means there is nothing to categorize.
+func (b *Bot) buildUncategorizedView(
+	ctx context.Context,
+	userID int64,
+	header string,
+	page int,
+	uncertain bool,
+) (string, *models.InlineKeyboardMarkup, error) {
+	var band *repository.ConfidenceBand
+	if uncertain {
+		band = b.aiUncertainBand()
+	}
+
+	since := uncategorizedSince(b.now())
+	count, err := b.expenseRepo.CountUncategorizedByUserID(ctx, userID, since, band)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to count uncategorized expenses: %w", err)
+	}
+	if count == 0 {
+		if uncertain {
+			return uncertainNoneMsg, nil, nil
+		}
+		return uncategorizedNoneMsg, nil, nil
+	}
+
+	totalPages := (count + uncategorizedPageSize - 1) / uncategorizedPageSize
+	page = max(0, min(page, totalPages-1))
+
+	expenses, err := b.expenseRepo.GetUncategorizedByUserID(
+		ctx, userID, since, band, uncategorizedPageSize, page*uncategorizedPageSize)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch uncategorized expenses: %w", err)
+	}
+
+	summary := "without a category"
+	if uncertain {
+		summary = "without a category or auto-categorized with medium confidence"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(header)
+	fmt.Fprintf(&sb, "\n%d %s. Page %d/%d\n\n", count, summary, page+1, totalPages)
+	for i := range expenses {
+		sb.WriteString(botfmt.ExpenseListItem(&expenses[i], nil, b.displayLocation))
+	}
+
+	return sb.String(), buildUncategorizedKeyboard(expenses, page, totalPages, uncertain), nil
+}
+
+// uncategorizedHeader is the header of the on-demand uncategorized list.
+func uncategorizedHeader(uncertain bool) string {
+	if uncertain {
+		return fmt.Sprintf("🗂 <b>Uncategorized &amp; Uncertain Expenses</b> (last %d days)", uncategorizedWindowDays)
+	}
+	return fmt.Sprintf("🗂 <b>Uncategorized Expenses</b> (last %d days)", uncategorizedWindowDays)
+}
+
+// handleUncategorized handles the /uncategorized command.
+func (b *Bot) handleUncategorized(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleUncategorizedCore(ctx, tgBot, update)
+}
+
+// handleUncategorizedCore is the testable implementation of handleUncategorized.
+func (b *Bot) handleUncategorizedCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	args := strings.Fields(extractCommandArgs(update.Message.Text, "/uncategorized"))
+
+	if len(args) > 0 && strings.EqualFold(args[0], uncategorizedThresholdArg) {
+		b.setUncategorizedThresholdCore(ctx, tg, chatID, userID, args[1:])
+		return
+	}
+
+	// Phones often turn "--" into an em dash, so accept any leading dashes.
+	uncertain := len(args) > 0 && strings.EqualFold(strings.TrimLeft(args[0], "-—"), uncategorizedUncertainArg)
+	text, keyboard, err := b.buildUncategorizedView(ctx, userID, uncategorizedHeader(uncertain), 0, uncertain)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to build uncategorized list")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   failedFetchExpensesMsg,
+		})
+		return
+	}
+
+	params := &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+	_, _ = tg.SendMessage(ctx, params)
+}
+
+// setUncategorizedThresholdCore updates the user's warning threshold.
+func (b *Bot) setUncategorizedThresholdCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID, userID int64,
+	args []string,
+) {
+	if len(args) != 1 {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      uncategorizedThresholdUsage,
+			ParseMode: models.ParseModeHTML,
+		})
+		return
+	}
+
+	threshold, err := strconv.Atoi(args[0])
+	if err != nil || threshold < 0 || threshold > uncategorizedMaxThreshold {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      fmt.Sprintf("❌ Threshold must be a number from 0 to %d.", uncategorizedMaxThreshold),
+			ParseMode: models.ParseModeHTML,
+		})
+		return
+	}
+
+	if err := b.userRepo.UpdateUncategorizedThreshold(ctx, userID, threshold); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to update uncategorized threshold")
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "❌ Failed to update threshold. Please try again.",
+		})
+		return
+	}
+
+	text := fmt.Sprintf("✅ You'll be warned when more than %d expenses are uncategorized.", threshold)
+	if threshold == 0 {
+		text = "✅ Uncategorized expense warnings turned off."
+	}
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	})
+}
+
+// refreshUncategorizedCore re-renders the uncategorized list in place.
+func (b *Bot) refreshUncategorizedCore(
+	ctx context.Context,
+	tg TelegramAPI,
+	chatID int64,
+	messageID int,
+	userID int64,
+	page int,
+	uncertain bool,
+) {
+	text, keyboard, err := b.buildUncategorizedView(ctx, userID, uncategorizedHeader(uncertain), page, uncertain)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to refresh uncategorized list")
+		return
+	}
+
+	params := &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+	_, _ = tg.EditMessageText(ctx, params)
+}
+
+// handleUncategorizedCallback handles uncategorized list pagination.
+func (b *Bot) handleUncategorizedCallback(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleUncategorizedCallbackCore(ctx, tgBot, update)
+}
+
+// handleUncategorizedCallbackCore is the testable implementation of
+// handleUncategorizedCallback.
+func (b *Bot) handleUncategorizedCallbackCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+
+	_, _ = tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+	})
+
+	data := update.CallbackQuery.Data
+	uncertain := strings.HasPrefix(data, "uncat_upage_")
+	page, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(data, "uncat_page_"), "uncat_upage_"))
+	if err != nil {
+		return
+	}
+
+	b.refreshUncategorizedCore(
+		ctx, tg,
+		update.CallbackQuery.Message.Message.Chat.ID,
+		update.CallbackQuery.Message.Message.ID,
+		update.CallbackQuery.From.ID,
+		page,
+		uncertain,
+	)
+}
+
+// queueUncategorizedWarning queues the largest uncategorized expenses for
+// the outbox when the user's count is above their threshold, at most once
+// per period. It returns whether a warning was queued.
+func (b *Bot) queueUncategorizedWarning(ctx context.Context, userID int64, period string) (bool, error) {
+	threshold, err := b.userRepo.GetUncategorizedThreshold(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get uncategorized threshold: %w", err)
+	}
+	if threshold == 0 {
+		return false, nil
+	}
+
+	count, err := b.expenseRepo.CountUncategorizedByUserID(ctx, userID, uncategorizedSince(b.now()), nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to count uncategorized expenses: %w", err)
+	}
+	if !shouldWarnUncategorized(count, threshold) {
+		return false, nil
+	}
+
+	header := fmt.Sprintf(
+		"⚠️ <b>%d uncategorized expenses</b> in the last %d days.\n"+
+			"Reports are less useful without categories. Here are the largest:",
+		count, uncategorizedWindowDays)
+	text, keyboard, err := b.buildUncategorizedView(ctx, userID, header, 0, false)
+	if err != nil {
+		return false, err
+	}
+	if keyboard == nil {
+		return false, nil
+	}
+
+	return b.queueMessage(ctx, b.outboxRepo, &bot.SendMessageParams{
+		ChatID:      userID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: keyboard,
+	}, outboxDedupeKey("uncategorized_warning", userID, period))
+}
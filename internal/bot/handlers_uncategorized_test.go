@@ -0,0 +1,205 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const uncategorizedHeaderTest = "Uncategorized Expenses"
+
+func TestShouldWarnUncategorized(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		count     int
+		threshold int
+		want      bool
+	}{
+		{name: "below threshold", count: 5, threshold: 10, want: false},
+		{name: "at threshold", count: 10, threshold: 10, want: false},
+		{name: "above threshold", count: 11, threshold: 10, want: true},
+		{name: "zero threshold disables", count: 50, threshold: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.want, shouldWarnUncategorized(tt.count, tt.threshold))
+		})
+	}
+}
+
+func TestBuildUncategorizedKeyboard(t *testing.T) {
+	t.Parallel()
+
+	expenses := []appmodels.Expense{{ID: 7, UserExpenseNumber: 3}}
+
+	t.Run("first page has next only", func(t *testing.T) {
+		t.Parallel()
+		keyboard := buildUncategorizedKeyboard(expenses, 0, 2, false)
+		require.Len(t, keyboard.InlineKeyboard, 2)
+		require.Equal(t, "edit_category_7_unc", keyboard.InlineKeyboard[0][0].CallbackData)
+		require.Len(t, keyboard.InlineKeyboard[1], 1)
+		require.Equal(t, "uncat_page_1", keyboard.InlineKeyboard[1][0].CallbackData)
+	})
+
+	t.Run("single page has no navigation", func(t *testing.T) {
+		t.Parallel()
+		keyboard := buildUncategorizedKeyboard(expenses, 0, 1, false)
+		require.Len(t, keyboard.InlineKeyboard, 1)
+	})
+
+	t.Run("last page has prev only", func(t *testing.T) {
+		t.Parallel()
+		keyboard := buildUncategorizedKeyboard(expenses, 1, 2, false)
+		require.Equal(t, "uncat_page_0", keyboard.InlineKeyboard[1][0].CallbackData)
+	})
+
+	t.Run("uncertain view keeps its mode", func(t *testing.T) {
+		t.Parallel()
+		keyboard := buildUncategorizedKeyboard(expenses, 0, 2, true)
+		require.Equal(t, "edit_category_7_uncu", keyboard.InlineKeyboard[0][0].CallbackData)
+		require.Equal(t, "uncat_upage_1", keyboard.InlineKeyboard[1][0].CallbackData)
+	})
+}
+
+func TestUncategorizedFlow(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	userID := int64(500321)
+
+	err := b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, FirstName: "Uncat"})
+	require.NoError(t, err)
+
+	commandUpdate := func(text string) *models.Update {
+		return &models.Update{
+			Message: &models.Message{
+				Text: text,
+				Chat: models.Chat{ID: userID},
+				From: &models.User{ID: userID},
+			},
+		}
+	}
+	callbackUpdate := func(data string) *models.Update {
+		return &models.Update{
+			CallbackQuery: &models.CallbackQuery{
+				ID:   callbackIDHandlers,
+				From: models.User{ID: userID},
+				Data: data,
+				Message: models.MaybeInaccessibleMessage{
+					Message: &models.Message{ID: 300, Chat: models.Chat{ID: userID}},
+				},
+			},
+		}
+	}
+
+	t.Run("shows none when nothing is uncategorized", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleUncategorizedCore(ctx, mockBot, commandUpdate("/uncategorized"))
+		require.Equal(t, uncategorizedNoneMsg, mockBot.LastSentMessage().Text)
+		require.Nil(t, mockBot.LastSentMessage().ReplyMarkup)
+	})
+
+	expenses := make([]*appmodels.Expense, 0, 7)
+	for i := 1; i <= 7; i++ {
+		expense := &appmodels.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal(fmt.Sprintf("%d.00", i)),
+			Currency:    testCurrencySGD,
+			Description: fmt.Sprintf("Uncat %d", i),
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		expenses = append(expenses, expense)
+	}
+	largest := expenses[len(expenses)-1]
+
+	t.Run("lists largest first with pagination", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleUncategorizedCore(ctx, mockBot, commandUpdate("/uncategorized"))
+
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, uncategorizedHeaderTest)
+		require.Contains(t, msg.Text, "Page 1/2")
+		keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+		require.Len(t, keyboard.InlineKeyboard, uncategorizedPageSize+1)
+		require.Equal(t, fmt.Sprintf("edit_category_%d_unc", largest.ID), keyboard.InlineKeyboard[0][0].CallbackData)
+	})
+
+	t.Run("page callback shows second page", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleUncategorizedCallbackCore(ctx, mockBot, callbackUpdate("uncat_page_1"))
+
+		require.Len(t, mockBot.EditedMessages, 1)
+		require.Contains(t, mockBot.EditedMessages[0].Text, "Page 2/2")
+		require.Contains(t, mockBot.EditedMessages[0].Text, "Uncat 1")
+	})
+
+	t.Run("threshold logic sends warning only above threshold", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.messageSender = mockBot
+
+		queued, err := b.queueUncategorizedWarning(ctx, userID, "2026-04-27")
+		require.NoError(t, err)
+		require.False(t, queued)
+		b.deliverOutbox(ctx)
+		require.Equal(t, 0, mockBot.SentMessageCount())
+
+		require.NoError(t, b.userRepo.UpdateUncategorizedThreshold(ctx, userID, 5))
+		queued, err = b.queueUncategorizedWarning(ctx, userID, "2026-04-27")
+		require.NoError(t, err)
+		require.True(t, queued)
+		queued, err = b.queueUncategorizedWarning(ctx, userID, "2026-04-27")
+		require.NoError(t, err)
+		require.False(t, queued, "once per period")
+		b.deliverOutbox(ctx)
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		msg := mockBot.LastSentMessage()
+		require.Contains(t, msg.Text, "7 uncategorized expenses")
+		keyboard := requireInlineKeyboard(t, msg.ReplyMarkup)
+		require.Equal(t, fmt.Sprintf("edit_category_%d_unc", largest.ID), keyboard.InlineKeyboard[0][0].CallbackData)
+	})
+
+	t.Run("set category button returns to refreshed list", func(t *testing.T) {
+		categories, err := b.categoryRepo.GetAll(ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, categories)
+
+		mockBot := mocks.NewMockBot()
+		b.handleEditCallbackCore(ctx, mockBot, callbackUpdate(fmt.Sprintf("edit_category_%d_unc", largest.ID)))
+		keyboard := requireInlineKeyboard(t, mockBot.LastEditedMessage().ReplyMarkup)
+		require.Equal(t,
+			fmt.Sprintf("set_category_%d_%d_unc", largest.ID, categories[0].ID),
+			keyboard.InlineKeyboard[0][0].CallbackData)
+		lastRow := keyboard.InlineKeyboard[len(keyboard.InlineKeyboard)-1]
+		require.Equal(t, "uncat_page_0", lastRow[0].CallbackData)
+
+		mockBot = mocks.NewMockBot()
+		b.handleSetCategoryCallbackCore(ctx, mockBot,
+			callbackUpdate(fmt.Sprintf("set_category_%d_%d_unc", largest.ID, categories[0].ID)))
+		edited := mockBot.LastEditedMessage()
+		require.Contains(t, edited.Text, uncategorizedHeaderTest)
+		require.Contains(t, edited.Text, "6 without a category")
+		require.NotContains(t, edited.Text, largest.Description)
+	})
+
+	t.Run("threshold command validates and saves", func(t *testing.T) {
+		mockBot := mocks.NewMockBot()
+		b.handleUncategorizedCore(ctx, mockBot, commandUpdate("/uncategorized threshold abc"))
+		require.Contains(t, mockBot.LastSentMessage().Text, "Threshold must be")
+
+		b.handleUncategorizedCore(ctx, mockBot, commandUpdate("/uncategorized threshold 0"))
+		require.Contains(t, mockBot.LastSentMessage().Text, "turned off")
+
+		threshold, err := b.userRepo.GetUncategorizedThreshold(ctx, userID)
+		require.NoError(t, err)
+		require.Zero(t, threshold)
+	})
+}
@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+const (
+	// unknownCommandBurst replies are sent per chat within
+	// unknownCommandWindow; further unknown commands are ignored.
+	unknownCommandBurst  = 3
+	unknownCommandWindow = time.Minute
+
+	// maxCommandSuggestions caps the "did you mean" list.
+	maxCommandSuggestions = 3
+)
+
+// unknownCommandLimiter remembers when each chat was last told about an
+// unknown command. The zero value is ready to use.
+type unknownCommandLimiter struct {
+	mu      sync.Mutex
+	replies map[int64][]time.Time
+}
+
+// allow reports whether chatID may get another unknown command reply at
+// now and, if so, records it.
+func (l *unknownCommandLimiter) allow(chatID int64, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recent := l.replies[chatID][:0]
+	for _, at := range l.replies[chatID] {
+		if now.Sub(at) < unknownCommandWindow {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) >= unknownCommandBurst {
+		l.replies[chatID] = recent
+		return false
+	}
+	if l.replies == nil {
+		l.replies = make(map[int64][]time.Time)
+	}
+	l.replies[chatID] = append(recent, now)
+	return true
+}
+
+// isRegisteredCommand reports whether a registered command handler matches
+// text, the same way the Telegram library picks handlers.
+func (b *Bot) isRegisteredCommand(text string) bool {
+	for _, prefix := range b.commandPrefixes {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// closestCommands returns up to maxCommandSuggestions command names close
+// enough to name to be likely typos, nearest first.
+func closestCommands(name string) []string {
+	names := make([]string, len(commandDefs))
+	for i, def := range commandDefs {
+		names[i] = def.Name
+	}
+	return closestNames(name, names, maxCommandSuggestions)
+}
+
+// formatUnknownCommand tells the user command does not exist, suggesting
+// the nearest ones.
+func formatUnknownCommand(command string) string {
+	name := strings.TrimPrefix(command, "/")
+	text := fmt.Sprintf("🤔 Unknown command /%s.", botfmt.EscapeHTML(echoText(name)))
+	suggestions := closestCommands(name)
+	if len(suggestions) == 0 {
+		return text + " Use /help to see available commands."
+	}
+	for i := range suggestions {
+		suggestions[i] = "/" + suggestions[i]
+	}
+	return text + " Did you mean " + strings.Join(suggestions, ", ") + "?"
+}
+
+// handleUnknownCommand answers a command that no handler matched, so a
+// typo like /lisy is not met with silence. It returns false for anything
+// else. In groups, commands addressed to another bot with /cmd@name are
+// left alone, and each chat gets at most unknownCommandBurst replies per
+// unknownCommandWindow.
+func (b *Bot) handleUnknownCommand(ctx context.Context, tg TelegramAPI, update *models.Update) bool {
+	msg := update.Message
+	if msg == nil || !strings.HasPrefix(msg.Text, "/") || b.isRegisteredCommand(msg.Text) {
+		return false
+	}
+
+	// The text starts with "/", so it has a first field.
+	command, mention, mentioned := strings.Cut(strings.Fields(msg.Text)[0], "@")
+	if command == "/" {
+		return false
+	}
+	if mentioned && msg.Chat.Type != models.ChatTypePrivate && !strings.EqualFold(mention, b.botUsername) {
+		return true
+	}
+	if !b.unknownCommands.allow(msg.Chat.ID, b.now()) {
+		logger.Log.Debug().Int64("chat_id", msg.Chat.ID).Msg("Unknown command reply rate limited")
+		return true
+	}
+
+	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    msg.Chat.ID,
+		Text:      formatUnknownCommand(command),
+		ParseMode: models.ParseModeHTML,
+	})
+	return true
+}
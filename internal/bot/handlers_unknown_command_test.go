@@ -0,0 +1,115 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+)
+
+func TestFormatUnknownCommand(t *testing.T) {
+	t.Parallel()
+
+	text := formatUnknownCommand("/lisy")
+	require.Contains(t, text, "Unknown command /lisy.")
+	require.Contains(t, text, "Did you mean /list")
+
+	require.Contains(t, closestCommands("LSIT"), "list")
+	require.LessOrEqual(t, len(closestCommands("sho")), maxCommandSuggestions)
+
+	text = formatUnknownCommand("/qwertyuiop")
+	require.Contains(t, text, "Use /help to see available commands.")
+	require.NotContains(t, text, "Did you mean")
+
+	require.Contains(t, formatUnknownCommand("/<b>x</b>"), "/&lt;b&gt;x&lt;/b&gt;")
+}
+
+func TestUnknownCommandLimiter(t *testing.T) {
+	t.Parallel()
+
+	var limiter unknownCommandLimiter
+	now := time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC)
+	for range unknownCommandBurst {
+		require.True(t, limiter.allow(1, now))
+	}
+	require.False(t, limiter.allow(1, now.Add(time.Second)))
+	require.True(t, limiter.allow(2, now), "chats are limited separately")
+	require.True(t, limiter.allow(1, now.Add(unknownCommandWindow)))
+}
+
+func TestHandleUnknownCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := &Bot{cfg: &config.Config{EnableDemoTools: true}, nowFunc: time.Now, botUsername: "expense_bot"}
+	tgBot, err := tgbot.New("123:TESTTOKEN", tgbot.WithSkipGetMe())
+	require.NoError(t, err)
+	b.bot = tgBot
+	b.registerHandlers()
+
+	t.Run("suggests the closest commands", func(t *testing.T) {
+		t.Parallel()
+
+		mockBot := mocks.NewMockBot()
+		require.True(t, b.handleUnknownCommand(ctx, mockBot, mocks.CommandUpdate(445001, 445001, "/lisy")))
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		require.Contains(t, mockBot.LastSentMessage().Text, "Did you mean /list")
+	})
+
+	t.Run("a burst is rate limited", func(t *testing.T) {
+		t.Parallel()
+
+		mockBot := mocks.NewMockBot()
+		for range unknownCommandBurst + 2 {
+			require.True(t, b.handleUnknownCommand(ctx, mockBot, mocks.CommandUpdate(445002, 445002, "/nope")))
+		}
+		require.Equal(t, unknownCommandBurst, mockBot.SentMessageCount())
+	})
+
+	t.Run("registered commands are never intercepted", func(t *testing.T) {
+		t.Parallel()
+
+		mockBot := mocks.NewMockBot()
+		for _, def := range commandDefs {
+			for _, text := range []string{"/" + def.Name, "/" + def.Name + " 5", "/" + def.Name + "@expense_bot"} {
+				require.False(t, b.handleUnknownCommand(ctx, mockBot, mocks.CommandUpdate(445003, 445003, text)), text)
+			}
+		}
+		require.Zero(t, mockBot.SentMessageCount())
+	})
+
+	t.Run("commands for other bots in groups are ignored", func(t *testing.T) {
+		t.Parallel()
+
+		mockBot := mocks.NewMockBot()
+		update := mocks.CommandUpdate(-445004, 445004, "/lisy@other_bot")
+		update.Message.Chat.Type = models.ChatTypeGroup
+		require.True(t, b.handleUnknownCommand(ctx, mockBot, update))
+		require.Zero(t, mockBot.SentMessageCount())
+	})
+
+	t.Run("commands for this bot in groups are answered", func(t *testing.T) {
+		t.Parallel()
+
+		mockBot := mocks.NewMockBot()
+		update := mocks.CommandUpdate(-445006, 445006, "/lisy@Expense_Bot")
+		update.Message.Chat.Type = models.ChatTypeGroup
+		require.True(t, b.handleUnknownCommand(ctx, mockBot, update))
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		require.Contains(t, mockBot.LastSentMessage().Text, "Did you mean /list")
+	})
+
+	t.Run("other messages pass through", func(t *testing.T) {
+		t.Parallel()
+
+		mockBot := mocks.NewMockBot()
+		require.False(t, b.handleUnknownCommand(ctx, mockBot, mocks.CommandUpdate(445005, 445005, "5.50 Coffee")))
+		require.False(t, b.handleUnknownCommand(ctx, mockBot, mocks.CommandUpdate(445005, 445005, "/")))
+		require.Zero(t, mockBot.SentMessageCount())
+	})
+}
@@ -3,10 +3,10 @@ package bot
 import (
 	"context"
 	"errors"
-	"fmt"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
 	"gitlab.com/yelinaung/expense-bot/internal/gemini"
 	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
 
@@ -43,6 +43,24 @@ func (b *Bot) handleVoiceCore(ctx context.Context, tg TelegramAPI, update *model
 		})
 		return
 	}
+	if !b.aiEnabled(ctx, userID) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text: "🎙️ AI is off for you, so voice messages are not read. Please send text like " +
+				"<code>5.50 Coffee</code>, or turn AI back on with <code>/settings ai on</code>.",
+			ParseMode: models.ParseModeHTML,
+		})
+		return
+	}
+	if b.aiBudgetExhausted(ctx) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text: "🎙️ Voice expense input is not available: " + aiBudgetExhaustedMsg +
+				" Please add expenses manually using /add or send text like <code>5.50 Coffee</code>",
+			ParseMode: models.ParseModeHTML,
+		})
+		return
+	}
 
 	_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID: chatID,
@@ -78,7 +96,7 @@ func (b *Bot) handleVoiceCore(ctx context.Context, tg TelegramAPI, update *model
 		mimeType = "audio/ogg"
 	}
 
-	categories, err := b.getCategoriesWithCache(ctx)
+	categories, err := b.visibleCategories(ctx, userID)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to fetch categories for voice expense")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
@@ -95,7 +113,8 @@ func (b *Bot) handleVoiceCore(ctx context.Context, tg TelegramAPI, update *model
 		categoryNames = gemini.DefaultCategories
 	}
 
-	voiceData, err := b.geminiClient.ParseVoiceExpense(ctx, audioBytes, mimeType, categoryNames)
+	parseCtx := gemini.WithUsageUser(ctx, userID)
+	voiceData, err := b.geminiClient.ParseVoiceExpense(parseCtx, audioBytes, mimeType, categoryNames)
 	if err != nil {
 		logger.Log.Error().Err(err).
 			Int64("chat_id", chatID).
@@ -122,26 +141,21 @@ func (b *Bot) handleVoiceCore(ctx context.Context, tg TelegramAPI, update *model
 		description = "Voice expense"
 	}
 	merchant := description
-	amount, currency, description := b.convertExpenseCurrency(
-		ctx,
-		userID,
-		voiceData.Amount,
-		voiceData.Currency,
-		description,
-	)
+	conversion := b.expenses().ConvertToDefault(ctx, userID, voiceData.Amount, voiceData.Currency, description)
 
 	expense := &appmodels.Expense{
 		UserID:      userID,
-		Amount:      amount,
-		Currency:    currency,
-		Description: description,
+		Amount:      conversion.Amount,
+		Currency:    conversion.Currency,
+		Description: conversion.Description,
 		Merchant:    merchant,
 		CategoryID:  categoryID,
 		Category:    category,
-		Status:      appmodels.ExpenseStatusDraft,
+		Source:      appmodels.ExpenseSourceVoice,
 	}
+	conversion.Record(expense)
 
-	if err := b.expenseRepo.Create(ctx, expense); err != nil {
+	if _, err := b.expenses().CreateDraft(ctx, expense); err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to create draft expense from voice")
 		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: chatID,
@@ -149,10 +163,11 @@ func (b *Bot) handleVoiceCore(ctx context.Context, tg TelegramAPI, update *model
 		})
 		return
 	}
+	b.linkSourceMessage(ctx, expense.ID, chatID, update.Message.ID)
 
-	text := buildVoiceConfirmationText(expense)
+	text := botfmt.VoiceExpenseCard(expense)
 
-	keyboard := buildReceiptConfirmationKeyboard(expense.ID)
+	keyboard := buildReceiptConfirmationKeyboard(expense.ID, expense.Currency)
 
 	msg, err := tg.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:      chatID,
@@ -186,23 +201,3 @@ func sendVoiceParseError(ctx context.Context, tg TelegramAPI, chatID int64, err
 		ParseMode: models.ParseModeHTML,
 	})
 }
-
-func buildVoiceConfirmationText(expense *appmodels.Expense) string {
-	categoryText := categoryUncategorized
-	if expense.Category != nil {
-		categoryText = escapeHTML(expense.Category.Name)
-	}
-	currencySymbol := getCurrencyOrCodeSymbol(expense.Currency)
-	return fmt.Sprintf(`🎙️ <b>Voice Expense Detected!</b>
-
-💰 Amount: %s%s %s
-📝 Description: %s
-📁 Category: %s
-
-Please confirm, edit, or cancel:`,
-		currencySymbol,
-		expense.Amount.StringFixed(2),
-		expense.Currency,
-		escapeHTML(expense.Description),
-		categoryText)
-}
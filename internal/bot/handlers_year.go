@@ -0,0 +1,294 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+const (
+	yearUsageMsg = "❌ Invalid year.\n\nUsage: <code>/year</code> or <code>/year YYYY</code> for a past year"
+
+	// yearTopCategories is how many categories /year lists.
+	yearTopCategories = 5
+	// yearMonoCategoryWidth is how many columns a category name may take
+	// in the /year table before it is cut.
+	yearMonoCategoryWidth = 20
+)
+
+// yearSummary is a user's spending in one year and currency, month by
+// month, next to the year before it.
+type yearSummary struct {
+	Year     int
+	Currency string
+	Months   [12]decimal.Decimal
+	Prior    [12]decimal.Decimal
+	// HasPrior is set when the year before has spending in Currency.
+	HasPrior bool
+	Count    int
+	// Categories are the largest categories of the year, at most
+	// yearTopCategories.
+	Categories []categoryTotal
+	// LastMonth is the last month that has begun; later ones are shown
+	// blank.
+	LastMonth time.Month
+	// OtherCount counts the year's expenses in other currencies, which are
+	// left out.
+	OtherCount int
+}
+
+// Total returns the spending of the whole year.
+func (s *yearSummary) Total() decimal.Decimal {
+	return sumDecimals(s.Months[:], len(s.Months))
+}
+
+// PriorTotal returns the spending of the year before, up to the same
+// month so a year in progress is compared like for like.
+func (s *yearSummary) PriorTotal() decimal.Decimal {
+	return sumDecimals(s.Prior[:], int(s.LastMonth))
+}
+
+// buildYearSummary folds the monthly category totals of year and the year
+// before it into a summary in currency. lastMonth is the last month of
+// year that has begun.
+func buildYearSummary(
+	totals []repository.MonthlyCategoryTotal,
+	year int,
+	currency string,
+	lastMonth time.Month,
+) yearSummary {
+	summary := yearSummary{Year: year, Currency: currency, LastMonth: lastMonth}
+	categories := make(map[string]decimal.Decimal)
+	for _, total := range totals {
+		switch {
+		case total.Year == year && total.Currency != currency:
+			summary.OtherCount += total.Count
+		case total.Year == year:
+			summary.Months[total.Month-1] = summary.Months[total.Month-1].Add(total.Amount)
+			summary.Count += total.Count
+			name := total.Category
+			if name == "" {
+				name = categoryUncategorized
+			}
+			categories[name] = categories[name].Add(total.Amount)
+		case total.Year == year-1 && total.Currency == currency:
+			summary.Prior[total.Month-1] = summary.Prior[total.Month-1].Add(total.Amount)
+			summary.HasPrior = true
+		}
+	}
+
+	for name, amount := range categories {
+		summary.Categories = append(summary.Categories, categoryTotal{Name: name, Total: amount})
+	}
+	sort.Slice(summary.Categories, func(i, j int) bool {
+		if cmp := summary.Categories[i].Total.Cmp(summary.Categories[j].Total); cmp != 0 {
+			return cmp > 0
+		}
+		return summary.Categories[i].Name < summary.Categories[j].Name
+	})
+	if len(summary.Categories) > yearTopCategories {
+		summary.Categories = summary.Categories[:yearTopCategories]
+	}
+	return summary
+}
+
+// formatYearChange describes the change from previous to current as a
+// whole percentage, e.g. "▲12%". It is blank when there is nothing to
+// compare with.
+func formatYearChange(current, previous decimal.Decimal) string {
+	if !previous.IsPositive() {
+		return ""
+	}
+	pct := current.Sub(previous).Div(previous).Mul(decimal.NewFromInt(100)).Round(0)
+	switch pct.Sign() {
+	case 1:
+		return "▲" + pct.String() + "%"
+	case -1:
+		return "▼" + pct.Abs().String() + "%"
+	default:
+		return "="
+	}
+}
+
+// formatYearMonths renders the month-by-month table, with the year before
+// and the change when the summary has one.
+func formatYearMonths(summary *yearSummary) string {
+	amount := func(value decimal.Decimal) string {
+		return botfmt.FormatAmount(value, summary.Currency)
+	}
+
+	header := []string{"Month", strconv.Itoa(summary.Year)}
+	if summary.HasPrior {
+		header = append(header, strconv.Itoa(summary.Year-1), "Change")
+	}
+	rows := [][]string{header}
+	for i := range summary.Months {
+		month := time.Month(i + 1)
+		row := []string{month.String()[:3], ""}
+		if month <= summary.LastMonth {
+			row[1] = amount(summary.Months[i])
+		}
+		if summary.HasPrior {
+			change := ""
+			if month <= summary.LastMonth {
+				change = formatYearChange(summary.Months[i], summary.Prior[i])
+			}
+			row = append(row, amount(summary.Prior[i]), change)
+		}
+		rows = append(rows, row)
+	}
+
+	total := []string{"Total", amount(summary.Total())}
+	if summary.HasPrior {
+		total = append(total, amount(sumDecimals(summary.Prior[:], len(summary.Prior))),
+			formatYearChange(summary.Total(), summary.PriorTotal()))
+	}
+	rows = append(rows, total)
+	return botfmt.MonoTable(rows,
+		[]botfmt.Align{botfmt.AlignLeft, botfmt.AlignRight, botfmt.AlignRight, botfmt.AlignRight})
+}
+
+// formatYearSummary renders /year as message parts: the month table, the
+// top categories and the biggest expense, shown in loc. largest may be
+// nil.
+func formatYearSummary(summary *yearSummary, largest *appmodels.Expense, loc *time.Location) []string {
+	var head strings.Builder
+	fmt.Fprintf(&head, "📅 <b>%d in review</b> (%s)\n%d expenses, %s in total",
+		summary.Year, botfmt.EscapeHTML(summary.Currency), summary.Count,
+		botfmt.Money(summary.Total(), summary.Currency))
+	if summary.HasPrior && summary.LastMonth < time.December {
+		fmt.Fprintf(&head, "\nChange is against %d up to %s.", summary.Year-1, summary.LastMonth)
+	}
+	head.WriteString("\n\n")
+	head.WriteString(formatYearMonths(summary))
+	parts := []string{head.String()}
+
+	if total := summary.Total(); len(summary.Categories) > 0 && total.IsPositive() {
+		rows := make([][]string, len(summary.Categories))
+		for i, category := range summary.Categories {
+			rows[i] = []string{
+				strconv.Itoa(i+1) + ".",
+				botfmt.TruncateWidth(category.Name, yearMonoCategoryWidth),
+				botfmt.FormatAmount(category.Total, summary.Currency),
+				percentOf(category.Total, total) + "%",
+			}
+		}
+		parts = append(parts, "<b>Top categories</b>\n"+botfmt.MonoTable(rows,
+			[]botfmt.Align{botfmt.AlignRight, botfmt.AlignLeft, botfmt.AlignRight, botfmt.AlignRight}))
+	}
+
+	var tail []string
+	if largest != nil {
+		amount, currency := largest.AmountInDefault()
+		desc := largest.Merchant
+		if desc == "" {
+			desc = largest.Description
+		}
+		tail = append(tail, fmt.Sprintf("💸 Biggest expense: #%d %s %s on %s",
+			largest.UserExpenseNumber, botfmt.Money(amount, currency),
+			botfmt.EscapeHTML(echoText(desc)), largest.CreatedAt.In(loc).Format("2 Jan")))
+	}
+	if summary.OtherCount > 0 {
+		tail = append(tail, fmt.Sprintf("%d expenses in other currencies are not included.", summary.OtherCount))
+	}
+	if len(tail) > 0 {
+		parts = append(parts, strings.Join(tail, "\n"))
+	}
+	return parts
+}
+
+// packMessages joins parts, separated by blank lines, into as few messages
+// of at most limit characters as possible. A part longer than limit is
+// sent on its own.
+func packMessages(parts []string, limit int) []string {
+	var messages []string
+	current := ""
+	for _, part := range parts {
+		switch {
+		case current == "":
+			current = part
+		case len([]rune(current))+2+len([]rune(part)) <= limit:
+			current += "\n\n" + part
+		default:
+			messages = append(messages, current)
+			current = part
+		}
+	}
+	if current != "" {
+		messages = append(messages, current)
+	}
+	return messages
+}
+
+// handleYear handles the /year command.
+func (b *Bot) handleYear(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.handleYearCore(ctx, tgBot, update)
+}
+
+// handleYearCore summarizes a year of the user's spending month by month
+// in their default currency, compared with the year before when it has
+// any. The months are taken in the user's timezone.
+func (b *Bot) handleYearCore(ctx context.Context, tg TelegramAPI, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+	send := func(text string) {
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    chatID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		})
+	}
+
+	loc := b.locationForUser(ctx, userID)
+	current := b.now().In(loc)
+	start, end, err := resolveYearArgAt(extractCommandArgs(update.Message.Text, "/year"), current)
+	if err != nil {
+		send(yearUsageMsg + seeHelp("year"))
+		return
+	}
+
+	totals, err := b.expenseRepo.GetMonthlyCategoryTotals(ctx, userID, start.AddDate(-1, 0, 0), end, loc.String())
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch yearly totals")
+		send(failedFetchExpensesMsg)
+		return
+	}
+
+	lastMonth := time.December
+	if start.Year() == current.Year() {
+		lastMonth = current.Month()
+	}
+	summary := buildYearSummary(totals, start.Year(), b.getUserDefaultCurrency(ctx, userID), lastMonth)
+	if summary.Count == 0 && summary.OtherCount == 0 {
+		send(fmt.Sprintf("📅 No expenses found for %d.", summary.Year))
+		return
+	}
+
+	largest, err := b.expenseRepo.GetLargestByUserIDAndDateRange(ctx, userID, start, end, summary.Currency)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Log.Warn().Err(err).Msg("Failed to fetch biggest expense of the year")
+		}
+	}
+
+	for _, text := range packMessages(formatYearSummary(&summary, largest, loc), telegramMessageLimit) {
+		send(text)
+	}
+}
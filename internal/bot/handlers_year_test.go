@@ -0,0 +1,162 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+func TestResolveYearArgAt(t *testing.T) {
+	t.Parallel()
+
+	current := time.Date(2026, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	start, end, err := resolveYearArgAt("", current)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), start)
+	require.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), end)
+
+	start, _, err = resolveYearArgAt("2024", current)
+	require.NoError(t, err)
+	require.Equal(t, 2024, start.Year())
+
+	for _, arg := range []string{"2027", "24", "last", "2025-01"} {
+		_, _, err := resolveYearArgAt(arg, current)
+		require.Error(t, err, arg)
+	}
+}
+
+func TestBuildYearSummary(t *testing.T) {
+	t.Parallel()
+
+	totals := []repository.MonthlyCategoryTotal{
+		{Year: 2024, Month: time.January, Category: "Food", Currency: "SGD", Count: 2, Amount: decimal.NewFromInt(100)},
+		{Year: 2024, Month: time.March, Category: "Food", Currency: "USD", Count: 1, Amount: decimal.NewFromInt(999)},
+		{Year: 2025, Month: time.January, Category: "Food", Currency: "SGD", Count: 3, Amount: decimal.NewFromInt(120)},
+		{Year: 2025, Month: time.January, Category: "", Currency: "SGD", Count: 1, Amount: decimal.NewFromInt(30)},
+		{Year: 2025, Month: time.February, Category: "Travel", Currency: "SGD", Count: 1, Amount: decimal.NewFromInt(200)},
+		{Year: 2025, Month: time.February, Category: "Travel", Currency: "EUR", Count: 4, Amount: decimal.NewFromInt(50)},
+	}
+
+	summary := buildYearSummary(totals, 2025, "SGD", time.December)
+	require.True(t, decimal.NewFromInt(150).Equal(summary.Months[0]))
+	require.True(t, decimal.NewFromInt(200).Equal(summary.Months[1]))
+	require.True(t, decimal.NewFromInt(350).Equal(summary.Total()))
+	require.Equal(t, 5, summary.Count)
+	require.Equal(t, 4, summary.OtherCount)
+	require.True(t, summary.HasPrior)
+	require.True(t, decimal.NewFromInt(100).Equal(summary.Prior[0]))
+	require.True(t, summary.Prior[2].IsZero(), "the year before is compared in the same currency")
+
+	require.Len(t, summary.Categories, 3)
+	require.Equal(t, "Travel", summary.Categories[0].Name)
+	require.Equal(t, "Food", summary.Categories[1].Name)
+	require.Equal(t, categoryUncategorized, summary.Categories[2].Name)
+
+	noPrior := buildYearSummary(totals, 2024, "SGD", time.December)
+	require.False(t, noPrior.HasPrior)
+}
+
+func TestFormatYearChange(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "▲20%", formatYearChange(decimal.NewFromInt(120), decimal.NewFromInt(100)))
+	require.Equal(t, "▼25%", formatYearChange(decimal.NewFromInt(75), decimal.NewFromInt(100)))
+	require.Equal(t, "=", formatYearChange(decimal.NewFromInt(100), decimal.NewFromInt(100)))
+	require.Empty(t, formatYearChange(decimal.NewFromInt(100), decimal.Zero))
+}
+
+func TestPackMessages(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []string{"a\n\nbb"}, packMessages([]string{"a", "bb"}, 10))
+	require.Equal(t, []string{"aaaa", "bbbb"}, packMessages([]string{"aaaa", "bbbb"}, 9))
+	require.Equal(t, []string{"aaaaaaaaaaaa", "b"}, packMessages([]string{"aaaaaaaaaaaa", "b"}, 10))
+	require.Empty(t, packMessages(nil, 10))
+}
+
+func TestHandleYearCore(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	b.nowFunc = func() time.Time { return time.Date(2026, 3, 16, 12, 0, 0, 0, time.UTC) }
+
+	userID := int64(446101)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{
+		ID: userID, FirstName: "Yearly", Timezone: "Asia/Singapore", DefaultCurrency: testCurrencySGD,
+	}))
+	food, err := b.categoryRepo.Create(ctx, "Year Food")
+	require.NoError(t, err)
+
+	create := func(description, amount string, at time.Time, categoryID *int) {
+		expense := createHabitTestExpense(t, ctx, b, userID, description, amount, at)
+		if categoryID != nil {
+			expense.CategoryID = categoryID
+			require.NoError(t, b.expenseRepo.Update(ctx, expense))
+		}
+	}
+	// 2024 is the year before 2025.
+	create("Groceries", "100.00", time.Date(2024, 1, 10, 4, 0, 0, 0, time.UTC), &food.ID)
+	create("Flight", "400.00", time.Date(2024, 7, 10, 4, 0, 0, 0, time.UTC), nil)
+	create("Groceries", "80.00", time.Date(2025, 1, 5, 4, 0, 0, 0, time.UTC), &food.ID)
+	create("Dinner", "40.00", time.Date(2025, 1, 20, 4, 0, 0, 0, time.UTC), &food.ID)
+	// 31 Jan 20:00 UTC is already February in Singapore.
+	create("Laptop", "900.00", time.Date(2025, 1, 31, 20, 0, 0, 0, time.UTC), nil)
+
+	year := func(text string) []string {
+		mockBot := mocks.NewMockBot()
+		b.handleYearCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, text))
+		texts := make([]string, len(mockBot.SentMessages))
+		for i := range mockBot.SentMessages {
+			texts[i] = mockBot.SentMessages[i].Text
+		}
+		return texts
+	}
+
+	t.Run("compares with the year before", func(t *testing.T) {
+		texts := year("/year 2025")
+		require.Len(t, texts, 1)
+		text := texts[0]
+		require.Contains(t, text, "<b>2025 in review</b> (SGD)")
+		require.Contains(t, text, "3 expenses, S$1020.00 SGD in total")
+		require.Regexp(t, `Month\s+2025\s+2024\s+Change`, text)
+		require.Regexp(t, `Jan\s+120\.00\s+100\.00\s+▲20%`, text)
+		require.Regexp(t, `Feb\s+900\.00\s+0\.00\n`, text)
+		require.Regexp(t, `Jul\s+0\.00\s+400\.00\s+▼100%`, text)
+		require.Regexp(t, `Total\s+1020\.00\s+500\.00\s+▲104%`, text)
+		require.Regexp(t, `1\.\s+Uncategorized\s+900\.00\s+88%`, text)
+		require.Regexp(t, `2\.\s+Year Food\s+120\.00\s+12%`, text)
+		require.Contains(t, text, "Biggest expense: #")
+		require.Contains(t, text, "S$900.00 SGD Laptop on 1 Feb")
+	})
+
+	t.Run("a year without the year before", func(t *testing.T) {
+		text := strings.Join(year("/year 2024"), "\n")
+		require.Regexp(t, `Month\s+2024\n`, text)
+		require.NotContains(t, text, "Change")
+		require.Regexp(t, `Jan\s+100\.00\n`, text)
+		require.Contains(t, text, "S$400.00 SGD Flight on 10 Jul")
+	})
+
+	t.Run("the current year stops at this month", func(t *testing.T) {
+		create("Taxi", "25.00", time.Date(2026, 2, 3, 4, 0, 0, 0, time.UTC), nil)
+		text := strings.Join(year("/year"), "\n")
+		require.Contains(t, text, "<b>2026 in review</b>")
+		require.Contains(t, text, "Change is against 2025 up to March.")
+		require.Regexp(t, `Jan\s+0\.00\s+120\.00\s+▼100%`, text)
+		require.Regexp(t, `Feb\s+25\.00\s+900\.00\s+▼97%`, text)
+		require.Regexp(t, `Apr\s+0\.00\n`, text, "months to come are blank")
+	})
+
+	t.Run("future and invalid years are rejected", func(t *testing.T) {
+		require.Contains(t, year("/year 2027")[0], "Invalid year")
+		require.Contains(t, year("/year soon")[0], "Usage:")
+	})
+}
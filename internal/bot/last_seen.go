@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+// lastSeenInterval is how often a user's last_seen_at is written at most.
+const lastSeenInterval = time.Hour
+
+// lastSeenThrottle remembers when each user's activity was last written so
+// most updates skip the database. The zero value is ready to use.
+type lastSeenThrottle struct {
+	mu      sync.Mutex
+	touched map[int64]time.Time
+}
+
+// due reports whether userID's activity should be written at now and, if
+// so, marks it written.
+func (t *lastSeenThrottle) due(userID int64, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.touched[userID]; ok && now.Sub(last) < lastSeenInterval {
+		return false
+	}
+	if t.touched == nil {
+		t.touched = make(map[int64]time.Time)
+	}
+	t.touched[userID] = now
+	return true
+}
+
+// touchLastSeen records that userID is active, at most once per
+// lastSeenInterval. The database check covers restarts and other
+// instances.
+func (b *Bot) touchLastSeen(ctx context.Context, userID int64) {
+	now := b.now()
+	if !b.lastSeen.due(userID, now) {
+		return
+	}
+	if _, err := b.userRepo.TouchLastSeen(ctx, userID, now, now.Add(-lastSeenInterval)); err != nil {
+		logger.Log.Debug().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to update last seen")
+	}
+}
@@ -0,0 +1,21 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastSeenThrottle(t *testing.T) {
+	t.Parallel()
+
+	var throttle lastSeenThrottle
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+
+	require.True(t, throttle.due(1, now))
+	require.False(t, throttle.due(1, now.Add(59*time.Minute)))
+	require.True(t, throttle.due(2, now), "users are throttled separately")
+	require.True(t, throttle.due(1, now.Add(lastSeenInterval)))
+	require.False(t, throttle.due(1, now.Add(lastSeenInterval+time.Minute)))
+}
@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+// mediaGroupWindow is how long the photos of one Telegram album are
+// collected before they are scanned together. Telegram delivers album
+// photos as separate updates, usually within a second of each other.
+const mediaGroupWindow = 2500 * time.Millisecond
+
+// mediaGroupKey identifies an album. Media group IDs are only unique per
+// chat.
+type mediaGroupKey struct {
+	chatID  int64
+	groupID string
+}
+
+// pendingMediaGroup holds the photos of an album received so far.
+// messageID is the message of the first photo. timer runs flush when the
+// window closes.
+type pendingMediaGroup struct {
+	userID    int64
+	messageID int
+	fileIDs   []string
+	timer     *time.Timer
+	flush     func()
+}
+
+// mediaGroupBuffer collects the photos of Telegram albums so one receipt
+// sent as several photos is scanned once. Every group is flushed and
+// removed by its own timer, so abandoned groups cannot pile up. The zero
+// value is ready to use.
+type mediaGroupBuffer struct {
+	mu     sync.Mutex
+	groups map[mediaGroupKey]*pendingMediaGroup
+	// inFlight tracks groups that are buffered or being processed, so
+	// tests can wait for them.
+	inFlight sync.WaitGroup
+}
+
+// add records a photo of an album. It returns true when the photo starts a
+// new group; the caller must then call schedule. Photos beyond maxPhotos
+// are dropped and reported with ok false.
+func (m *mediaGroupBuffer) add(
+	key mediaGroupKey,
+	userID int64,
+	messageID int,
+	fileID string,
+	maxPhotos int,
+) (isNew, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.groups == nil {
+		m.groups = make(map[mediaGroupKey]*pendingMediaGroup)
+	}
+
+	group, exists := m.groups[key]
+	if !exists {
+		m.groups[key] = &pendingMediaGroup{userID: userID, messageID: messageID, fileIDs: []string{fileID}}
+		m.inFlight.Add(1)
+		return true, true
+	}
+	if len(group.fileIDs) >= maxPhotos {
+		return false, false
+	}
+	group.fileIDs = append(group.fileIDs, fileID)
+	return false, true
+}
+
+// schedule runs flush for the group at key once window has passed. It is
+// called once, by the caller that started the group.
+func (m *mediaGroupBuffer) schedule(key mediaGroupKey, window time.Duration, flush func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, ok := m.groups[key]
+	if !ok {
+		return
+	}
+	group.flush = flush
+	group.timer = time.AfterFunc(window, flush)
+}
+
+// flushAll runs the flush of every group still being collected without
+// waiting for its window, so no album is lost on shutdown.
+func (m *mediaGroupBuffer) flushAll() {
+	m.mu.Lock()
+	var flushes []func()
+	for _, group := range m.groups {
+		if group.timer != nil && group.timer.Stop() {
+			flushes = append(flushes, group.flush)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, flush := range flushes {
+		flush()
+	}
+}
+
+// take removes a group and returns its photos. The second result is false
+// when the group was already taken.
+func (m *mediaGroupBuffer) take(key mediaGroupKey) (*pendingMediaGroup, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, ok := m.groups[key]
+	if ok {
+		delete(m.groups, key)
+	}
+	return group, ok
+}
+
+// pending returns the number of groups still being collected.
+func (m *mediaGroupBuffer) pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.groups)
+}
+
+// done marks a taken group as fully processed.
+func (m *mediaGroupBuffer) done() {
+	m.inFlight.Done()
+}
+
+// wait blocks until every buffered group has been flushed and processed.
+func (m *mediaGroupBuffer) wait() {
+	m.inFlight.Wait()
+}
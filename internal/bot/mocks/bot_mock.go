@@ -3,6 +3,7 @@ package mocks
 
 import (
 	"context"
+	"io"
 	"sync"
 
 	"github.com/go-telegram/bot"
@@ -14,10 +15,16 @@ import (
 type TelegramAPI interface {
 	SendMessage(ctx context.Context, params *bot.SendMessageParams) (*models.Message, error)
 	EditMessageText(ctx context.Context, params *bot.EditMessageTextParams) (*models.Message, error)
+	EditMessageReplyMarkup(ctx context.Context, params *bot.EditMessageReplyMarkupParams) (*models.Message, error)
 	AnswerCallbackQuery(ctx context.Context, params *bot.AnswerCallbackQueryParams) (bool, error)
 	GetFile(ctx context.Context, params *bot.GetFileParams) (*models.File, error)
 	FileDownloadLink(f *models.File) string
 	SendDocument(ctx context.Context, params *bot.SendDocumentParams) (*models.Message, error)
+	AnswerInlineQuery(ctx context.Context, params *bot.AnswerInlineQueryParams) (bool, error)
+	GetChatMember(ctx context.Context, params *bot.GetChatMemberParams) (*models.ChatMember, error)
+	GetChatAdministrators(ctx context.Context, params *bot.GetChatAdministratorsParams) ([]models.ChatMember, error)
+	LeaveChat(ctx context.Context, params *bot.LeaveChatParams) (bool, error)
+	SendPhoto(ctx context.Context, params *bot.SendPhotoParams) (*models.Message, error)
 }
 
 // SentMessage captures a message sent via MockBot.
@@ -37,6 +44,13 @@ type EditedMessage struct {
 	ReplyMarkup models.ReplyMarkup
 }
 
+// EditedReplyMarkup captures a reply markup edit via MockBot.
+type EditedReplyMarkup struct {
+	ChatID      any
+	MessageID   int
+	ReplyMarkup models.ReplyMarkup
+}
+
 // AnsweredCallback captures a callback query answer via MockBot.
 type AnsweredCallback struct {
 	CallbackQueryID string
@@ -50,6 +64,25 @@ type SentDocument struct {
 	Filename  string
 	Caption   string
 	ParseMode models.ParseMode
+	// Data holds the uploaded bytes of InputFileUpload documents.
+	Data []byte
+}
+
+// SentPhoto captures a photo sent via MockBot. FileID is set for photos
+// sent by Telegram file ID and Data for uploaded ones.
+type SentPhoto struct {
+	ChatID  any
+	FileID  string
+	Caption string
+	Data    []byte
+}
+
+// AnsweredInlineQuery captures an inline query answer via MockBot.
+type AnsweredInlineQuery struct {
+	InlineQueryID string
+	Results       []models.InlineQueryResult
+	CacheTime     int
+	IsPersonal    bool
 }
 
 // Compile-time check that MockBot implements TelegramAPI.
@@ -59,10 +92,13 @@ var _ TelegramAPI = (*MockBot)(nil)
 type MockBot struct {
 	mu sync.RWMutex
 
-	SentMessages      []SentMessage
-	EditedMessages    []EditedMessage
-	AnsweredCallbacks []AnsweredCallback
-	SentDocuments     []SentDocument
+	SentMessages       []SentMessage
+	EditedMessages     []EditedMessage
+	EditedReplyMarkups []EditedReplyMarkup
+	AnsweredCallbacks  []AnsweredCallback
+	SentDocuments      []SentDocument
+	SentPhotos         []SentPhoto
+	AnsweredInline     []AnsweredInlineQuery
 
 	// SendMessageError allows simulating SendMessage failures.
 	SendMessageError error
@@ -72,6 +108,21 @@ type MockBot struct {
 	GetFileError error
 	// SendDocumentError allows simulating SendDocument failures.
 	SendDocumentError error
+	// SendPhotoError allows simulating SendPhoto failures.
+	SendPhotoError error
+
+	// ChatMemberStatus is the member status GetChatMember reports, keyed by
+	// user ID. Users without an entry are plain members.
+	ChatMemberStatus map[int64]models.ChatMemberType
+	// GetChatMemberError allows simulating GetChatMember failures.
+	GetChatMemberError error
+	// ChatAdministrators is what GetChatAdministrators returns.
+	ChatAdministrators []models.ChatMember
+	// GetChatAdministratorsError allows simulating GetChatAdministrators
+	// failures.
+	GetChatAdministratorsError error
+	// LeftChats records the chats LeaveChat was called for.
+	LeftChats []any
 
 	// FileToReturn is returned by GetFile.
 	FileToReturn *models.File
@@ -85,11 +136,14 @@ type MockBot struct {
 // NewMockBot creates a new MockBot instance.
 func NewMockBot() *MockBot {
 	return &MockBot{
-		SentMessages:      make([]SentMessage, 0),
-		EditedMessages:    make([]EditedMessage, 0),
-		AnsweredCallbacks: make([]AnsweredCallback, 0),
-		SentDocuments:     make([]SentDocument, 0),
-		NextMessageID:     1000,
+		SentMessages:       make([]SentMessage, 0),
+		EditedMessages:     make([]EditedMessage, 0),
+		EditedReplyMarkups: make([]EditedReplyMarkup, 0),
+		AnsweredCallbacks:  make([]AnsweredCallback, 0),
+		SentDocuments:      make([]SentDocument, 0),
+		SentPhotos:         make([]SentPhoto, 0),
+		AnsweredInline:     make([]AnsweredInlineQuery, 0),
+		NextMessageID:      1000,
 	}
 }
 
@@ -147,6 +201,30 @@ func (m *MockBot) EditMessageText(_ context.Context, params *bot.EditMessageText
 	}, nil
 }
 
+// EditMessageReplyMarkup simulates editing a message's inline keyboard.
+func (m *MockBot) EditMessageReplyMarkup(
+	_ context.Context,
+	params *bot.EditMessageReplyMarkupParams,
+) (*models.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.EditMessageError != nil {
+		return nil, m.EditMessageError
+	}
+
+	m.EditedReplyMarkups = append(m.EditedReplyMarkups, EditedReplyMarkup{
+		ChatID:      params.ChatID,
+		MessageID:   params.MessageID,
+		ReplyMarkup: params.ReplyMarkup,
+	})
+
+	return &models.Message{
+		ID:   params.MessageID,
+		Chat: models.Chat{ID: chatIDToInt64(params.ChatID)},
+	}, nil
+}
+
 // AnswerCallbackQuery simulates answering a callback query.
 func (m *MockBot) AnswerCallbackQuery(_ context.Context, params *bot.AnswerCallbackQueryParams) (bool, error) {
 	m.mu.Lock()
@@ -203,8 +281,12 @@ func (m *MockBot) SendDocument(_ context.Context, params *bot.SendDocumentParams
 
 	// Extract filename from InputFileUpload if available
 	filename := ""
+	var data []byte
 	if upload, ok := params.Document.(*models.InputFileUpload); ok {
 		filename = upload.Filename
+		if upload.Data != nil {
+			data, _ = io.ReadAll(upload.Data)
+		}
 	}
 
 	m.SentDocuments = append(m.SentDocuments, SentDocument{
@@ -212,6 +294,7 @@ func (m *MockBot) SendDocument(_ context.Context, params *bot.SendDocumentParams
 		Filename:  filename,
 		Caption:   params.Caption,
 		ParseMode: params.ParseMode,
+		Data:      data,
 	})
 
 	msgID := m.NextMessageID
@@ -228,6 +311,90 @@ func (m *MockBot) SendDocument(_ context.Context, params *bot.SendDocumentParams
 	}, nil
 }
 
+// SendPhoto sends a photo and records it.
+func (m *MockBot) SendPhoto(_ context.Context, params *bot.SendPhotoParams) (*models.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.SendPhotoError != nil {
+		return nil, m.SendPhotoError
+	}
+
+	sent := SentPhoto{ChatID: params.ChatID, Caption: params.Caption}
+	switch photo := params.Photo.(type) {
+	case *models.InputFileString:
+		sent.FileID = photo.Data
+	case *models.InputFileUpload:
+		if photo.Data != nil {
+			sent.Data, _ = io.ReadAll(photo.Data)
+		}
+	}
+	m.SentPhotos = append(m.SentPhotos, sent)
+
+	msgID := m.NextMessageID
+	m.NextMessageID++
+
+	return &models.Message{
+		ID:      msgID,
+		Chat:    models.Chat{ID: chatIDToInt64(params.ChatID)},
+		Caption: params.Caption,
+	}, nil
+}
+
+// AnswerInlineQuery simulates answering an inline query.
+func (m *MockBot) AnswerInlineQuery(_ context.Context, params *bot.AnswerInlineQueryParams) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.AnsweredInline = append(m.AnsweredInline, AnsweredInlineQuery{
+		InlineQueryID: params.InlineQueryID,
+		Results:       params.Results,
+		CacheTime:     params.CacheTime,
+		IsPersonal:    params.IsPersonal,
+	})
+
+	return true, nil
+}
+
+// GetChatMember simulates looking up a chat member's status.
+func (m *MockBot) GetChatMember(_ context.Context, params *bot.GetChatMemberParams) (*models.ChatMember, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.GetChatMemberError != nil {
+		return nil, m.GetChatMemberError
+	}
+
+	status, ok := m.ChatMemberStatus[params.UserID]
+	if !ok {
+		status = models.ChatMemberTypeMember
+	}
+	return &models.ChatMember{Type: status}, nil
+}
+
+// GetChatAdministrators simulates listing a chat's administrators.
+func (m *MockBot) GetChatAdministrators(
+	_ context.Context,
+	_ *bot.GetChatAdministratorsParams,
+) ([]models.ChatMember, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.GetChatAdministratorsError != nil {
+		return nil, m.GetChatAdministratorsError
+	}
+	return m.ChatAdministrators, nil
+}
+
+// LeaveChat simulates the bot leaving a chat.
+func (m *MockBot) LeaveChat(_ context.Context, params *bot.LeaveChatParams) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.LeftChats = append(m.LeftChats, params.ChatID)
+	return true, nil
+}
+
 // Reset clears all recorded interactions.
 func (m *MockBot) Reset() {
 	m.mu.Lock()
@@ -235,12 +402,19 @@ func (m *MockBot) Reset() {
 
 	m.SentMessages = make([]SentMessage, 0)
 	m.EditedMessages = make([]EditedMessage, 0)
+	m.EditedReplyMarkups = make([]EditedReplyMarkup, 0)
 	m.AnsweredCallbacks = make([]AnsweredCallback, 0)
 	m.SentDocuments = make([]SentDocument, 0)
+	m.SentPhotos = make([]SentPhoto, 0)
+	m.AnsweredInline = make([]AnsweredInlineQuery, 0)
+	m.LeftChats = nil
 	m.SendMessageError = nil
 	m.EditMessageError = nil
 	m.GetFileError = nil
 	m.SendDocumentError = nil
+	m.SendPhotoError = nil
+	m.GetChatMemberError = nil
+	m.GetChatAdministratorsError = nil
 }
 
 // LastSentMessage returns the most recently sent message, or nil if none.
@@ -3,6 +3,7 @@ package mocks
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/go-telegram/bot"
@@ -121,6 +122,42 @@ func TestMockBot_EditMessageText(t *testing.T) {
 	})
 }
 
+func TestMockBot_EditMessageReplyMarkup(t *testing.T) {
+	t.Parallel()
+
+	t.Run("captures removed keyboard", func(t *testing.T) {
+		t.Parallel()
+
+		mockBot := NewMockBot()
+
+		msg, err := mockBot.EditMessageReplyMarkup(context.Background(), &bot.EditMessageReplyMarkupParams{
+			ChatID:    int64(12345),
+			MessageID: 100,
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, 100, msg.ID)
+		require.Len(t, mockBot.EditedReplyMarkups, 1)
+		require.Equal(t, int64(12345), mockBot.EditedReplyMarkups[0].ChatID)
+		require.Nil(t, mockBot.EditedReplyMarkups[0].ReplyMarkup)
+	})
+
+	t.Run(testReturnsErrorWhenConfigured, func(t *testing.T) {
+		t.Parallel()
+
+		mockBot := NewMockBot()
+		mockBot.EditMessageError = errors.New("edit failed")
+
+		_, err := mockBot.EditMessageReplyMarkup(context.Background(), &bot.EditMessageReplyMarkupParams{
+			ChatID:    int64(123),
+			MessageID: 1,
+		})
+
+		require.Error(t, err)
+		require.Empty(t, mockBot.EditedReplyMarkups)
+	})
+}
+
 func TestMockBot_AnswerCallbackQuery(t *testing.T) {
 	t.Parallel()
 
@@ -229,6 +266,35 @@ func TestMockBot_SendDocumentAndCounters(t *testing.T) {
 	require.Equal(t, "report", doc.Caption)
 }
 
+func TestMockBot_SendPhoto(t *testing.T) {
+	t.Parallel()
+
+	mockBot := NewMockBot()
+	ctx := context.Background()
+
+	_, err := mockBot.SendPhoto(ctx, &bot.SendPhotoParams{
+		ChatID: int64(1),
+		Photo:  &models.InputFileString{Data: "file-id"},
+	})
+	require.NoError(t, err)
+	_, err = mockBot.SendPhoto(ctx, &bot.SendPhotoParams{
+		ChatID:  int64(1),
+		Photo:   &models.InputFileUpload{Filename: "r.jpg", Data: strings.NewReader("jpeg")},
+		Caption: "receipt",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, mockBot.SentPhotos, 2)
+	require.Equal(t, "file-id", mockBot.SentPhotos[0].FileID)
+	require.Equal(t, []byte("jpeg"), mockBot.SentPhotos[1].Data)
+	require.Equal(t, "receipt", mockBot.SentPhotos[1].Caption)
+
+	mockBot.SendPhotoError = errors.New("error")
+	_, err = mockBot.SendPhoto(ctx, &bot.SendPhotoParams{ChatID: int64(1)})
+	require.Error(t, err)
+	require.Len(t, mockBot.SentPhotos, 2)
+}
+
 func TestMockBot_FileDownloadLink(t *testing.T) {
 	t.Parallel()
 
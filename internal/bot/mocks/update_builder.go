@@ -177,6 +177,21 @@ func (b *UpdateBuilder) WithReplyToMessage(messageID int, text string) *UpdateBu
 	return b
 }
 
+// WithInlineQuery sets an inline query on the update.
+func (b *UpdateBuilder) WithInlineQuery(queryID string, userID int64, query string) *UpdateBuilder {
+	b.update.InlineQuery = &models.InlineQuery{
+		ID: queryID,
+		From: &models.User{
+			ID:        userID,
+			FirstName: defaultFirstName,
+			LastName:  defaultLastName,
+			Username:  defaultUsername,
+		},
+		Query: query,
+	}
+	return b
+}
+
 // Build returns the constructed Update.
 func (b *UpdateBuilder) Build() *models.Update {
 	return b.update
@@ -201,6 +216,13 @@ func CallbackQueryUpdate(chatID, userID int64, messageID int, data string) *mode
 		Build()
 }
 
+// InlineQueryUpdate creates an inline query update.
+func InlineQueryUpdate(userID int64, query string) *models.Update {
+	return NewUpdateBuilder().
+		WithInlineQuery("inline-query-id", userID, query).
+		Build()
+}
+
 // PhotoUpdate creates a photo message update.
 func PhotoUpdate(chatID, userID int64, fileID string) *models.Update {
 	return NewUpdateBuilder().
@@ -0,0 +1,254 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5"
+
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// MonthlyArchiveCheckInterval is how often the monthly archive loop runs.
+	MonthlyArchiveCheckInterval = 30 * time.Minute
+	// MonthlyArchiveTimeout is the maximum time a single check can take.
+	MonthlyArchiveTimeout = 2 * time.Minute
+)
+
+// startMonthlyArchiveLoop runs a periodic loop that sends each user's
+// previous month CSV to their archive chat on the 1st of the month.
+func (b *Bot) startMonthlyArchiveLoop(ctx context.Context) {
+	logger.Log.Info().Msg("Monthly archive loop started (per-user timezone)")
+
+	ticker := time.NewTicker(MonthlyArchiveCheckInterval)
+	defer ticker.Stop()
+
+	select {
+	case <-ctx.Done():
+		logger.Log.Info().Msg("Monthly archive loop stopped")
+		return
+	default:
+	}
+
+	b.checkAndSendMonthlyArchives(ctx, b.now())
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Log.Info().Msg("Monthly archive loop stopped")
+			return
+		case <-ticker.C:
+			b.checkAndSendMonthlyArchives(ctx, b.now())
+		}
+	}
+}
+
+// checkAndSendMonthlyArchives delivers the previous month's CSV to the
+// archive chat of every authorized user for whom it is the 1st.
+func (b *Bot) checkAndSendMonthlyArchives(ctx context.Context, now time.Time) {
+	ctx, span := otel.Tracer("expense-bot/background").Start(ctx, "background.monthly_archive_check")
+	defer span.End()
+	start := time.Now()
+
+	checkCtx, cancel := context.WithTimeout(ctx, MonthlyArchiveTimeout)
+	defer cancel()
+
+	users, err := b.userRepo.GetAuthorizedUsersForArchive(
+		checkCtx,
+		b.cfg.WhitelistedUserIDs,
+		b.cfg.WhitelistedUsernames,
+	)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch users for monthly archive")
+		b.recordMonthlyArchiveMetrics(ctx, start, backgroundJobStatusError)
+		return
+	}
+
+	for i := range users {
+		b.processMonthlyArchiveUser(checkCtx, &users[i], now)
+	}
+
+	b.recordMonthlyArchiveMetrics(ctx, start, backgroundJobStatusOK)
+}
+
+// processMonthlyArchiveUser delivers the user's previous month CSV when it
+// is the 1st in their timezone and that month was not delivered yet.
+// Months without expenses are marked delivered without sending anything.
+func (b *Bot) processMonthlyArchiveUser(ctx context.Context, user *appmodels.User, now time.Time) {
+	loc := b.userLocation(user.Timezone)
+	userNow := now.In(loc)
+	if userNow.Day() != 1 {
+		return
+	}
+
+	endDate := time.Date(userNow.Year(), userNow.Month(), 1, 0, 0, 0, 0, loc)
+	startDate := endDate.AddDate(0, -1, 0)
+	monthKey := startDate.Format("2006-01-02")
+	if user.ArchiveSentMonth == monthKey {
+		return
+	}
+
+	expenses, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, user.ID, startDate, endDate)
+	if err != nil {
+		logger.Log.Warn().Err(err).
+			Str("user_hash", logger.HashUserID(user.ID)).
+			Msg("Failed to fetch expenses for monthly archive")
+		return
+	}
+
+	if len(expenses) > 0 {
+		if err := b.sendMonthlyArchive(ctx, user, expenses, startDate); err != nil {
+			if archiveChatGone(err) {
+				b.disableArchiveChat(ctx, user, startDate)
+				return
+			}
+			logger.Log.Warn().Err(err).
+				Str("user_hash", logger.HashUserID(user.ID)).
+				Msg("Failed to send monthly archive")
+			return
+		}
+	}
+
+	if err := b.userRepo.SetArchiveSentMonth(ctx, user.ID, monthKey); err != nil {
+		logger.Log.Warn().Err(err).
+			Str("user_hash", logger.HashUserID(user.ID)).
+			Msg("Failed to record monthly archive")
+	}
+	logger.Log.Debug().
+		Str("user_hash", logger.HashUserID(user.ID)).
+		Int("expense_count", len(expenses)).
+		Msg("Monthly archive handled")
+}
+
+// sendMonthlyArchive sends a month of expenses as CSV to the user's archive
+// chat, captioned with the user and period.
+func (b *Bot) sendMonthlyArchive(
+	ctx context.Context,
+	user *appmodels.User,
+	expenses []appmodels.Expense,
+	month time.Time,
+) error {
+	caption := fmt.Sprintf("🗄️ %s · Expenses for %s (%d)",
+		archiveUserLabel(user), month.Format(statementMonthLayout), len(expenses))
+	filename := fmt.Sprintf("expenses_%d_%s.csv", user.ID, month.Format("2006-01"))
+	return sendExpensesCSV(ctx, b.messageSender, user.ArchiveChatID, expenses, filename, caption)
+}
+
+// archiveChatGoneReasons are the parts of Telegram's error descriptions
+// that mean the bot can no longer post to a chat.
+var archiveChatGoneReasons = []string{
+	"chat not found",
+	"bot was kicked",
+	"bot is not a member",
+	"bot was blocked by the user",
+}
+
+// archiveChatGone reports whether a send error means the archive chat no
+// longer accepts messages from the bot, e.g. it was removed or the chat
+// was deleted, as opposed to a failure worth retrying. Other bad requests
+// and missing rights are not treated as gone.
+func archiveChatGone(err error) bool {
+	if !errors.Is(err, tgbot.ErrorForbidden) && !errors.Is(err, tgbot.ErrorBadRequest) {
+		return false
+	}
+	description := strings.ToLower(err.Error())
+	for _, reason := range archiveChatGoneReasons {
+		if strings.Contains(description, reason) {
+			return true
+		}
+	}
+	return false
+}
+
+// disableArchiveChat turns archiving off for a user whose archive chat
+// rejected a delivery, and queues a note telling them once. Both happen in
+// one transaction, so a crash cannot turn archiving off without the note.
+func (b *Bot) disableArchiveChat(ctx context.Context, user *appmodels.User, month time.Time) {
+	logger.Log.Warn().
+		Str("user_hash", logger.HashUserID(user.ID)).
+		Int64("chat_id", user.ArchiveChatID).
+		Msg("Archive chat rejected delivery; disabling archiving")
+
+	if err := b.disableArchiveChatTx(ctx, user, month); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to disable archive chat")
+	}
+}
+
+// disableArchiveChatTx clears the archive chat and queues the note.
+func (b *Bot) disableArchiveChatTx(ctx context.Context, user *appmodels.User, month time.Time) error {
+	db := b.db
+	var tx pgx.Tx
+	if beginner, ok := b.db.(database.TxBeginner); ok {
+		var err error
+		tx, err = beginner.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("begin tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		db = tx
+	}
+
+	if err := repository.NewUserRepository(db).SetArchiveChatID(ctx, user.ID, 0); err != nil {
+		return err
+	}
+	_, err := b.queueMessage(ctx, repository.NewOutboxRepository(db), &tgbot.SendMessageParams{
+		ChatID: user.ID,
+		Text: fmt.Sprintf("⚠️ I couldn't send your %s CSV to archive chat <code>%d</code>, "+
+			"so archiving is now off. Add me back and use /archivechat to turn it on again.",
+			month.Format(statementMonthLayout), user.ArchiveChatID),
+		ParseMode: tgmodels.ParseModeHTML,
+	}, outboxDedupeKey("archive_chat_disabled", user.ID, month.Format("2006-01")))
+	if err != nil {
+		return err
+	}
+
+	if tx != nil {
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("commit tx: %w", err)
+		}
+	}
+	return nil
+}
+
+// archiveUserLabel names a user in archive chats, e.g. "Alice Tan (@alice)".
+// The result is HTML-escaped.
+func archiveUserLabel(user *appmodels.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	switch {
+	case name != "" && user.Username != "":
+		name = fmt.Sprintf("%s (@%s)", name, user.Username)
+	case user.Username != "":
+		name = "@" + user.Username
+	case name == "":
+		name = fmt.Sprintf("User %d", user.ID)
+	}
+	return botfmt.EscapeHTML(name)
+}
+
+// recordMonthlyArchiveMetrics records background job metrics for the
+// monthly archive run.
+func (b *Bot) recordMonthlyArchiveMetrics(ctx context.Context, start time.Time, status string) {
+	if b.metrics == nil {
+		return
+	}
+	b.metrics.BackgroundJobRuns.Add(ctx, 1, otelmetric.WithAttributes(
+		attribute.String("job", "monthly_archive"),
+		attribute.String("status", status),
+	))
+	b.metrics.BackgroundJobDuration.Record(ctx, time.Since(start).Seconds(),
+		otelmetric.WithAttributes(attribute.String("job", "monthly_archive")))
+}
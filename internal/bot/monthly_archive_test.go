@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestArchiveUserLabel(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "Alice Tan (@alice)",
+		archiveUserLabel(&models.User{ID: 1, FirstName: "Alice", LastName: "Tan", Username: "alice"}))
+	require.Equal(t, "@bob", archiveUserLabel(&models.User{ID: 2, Username: "bob"}))
+	require.Equal(t, "Carol", archiveUserLabel(&models.User{ID: 3, FirstName: "Carol"}))
+	require.Equal(t, "User 4", archiveUserLabel(&models.User{ID: 4}))
+	require.Equal(t, "&lt;b&gt;", archiveUserLabel(&models.User{ID: 5, FirstName: "<b>"}))
+}
+
+func TestArchiveChatGone(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, archiveChatGone(fmt.Errorf("failed to send CSV: %w",
+		fmt.Errorf("%w, bot was kicked from the channel chat", tgbot.ErrorForbidden))))
+	require.True(t, archiveChatGone(fmt.Errorf("%w, chat not found", tgbot.ErrorBadRequest)))
+	require.True(t, archiveChatGone(fmt.Errorf("%w, Forbidden: bot was blocked by the user", tgbot.ErrorForbidden)))
+	require.False(t, archiveChatGone(fmt.Errorf("%w, Bad Request: file is too big", tgbot.ErrorBadRequest)))
+	require.False(t, archiveChatGone(fmt.Errorf("%w, Bad Request: not enough rights to send documents to the chat",
+		tgbot.ErrorBadRequest)))
+	require.False(t, archiveChatGone(tgbot.ErrorTooManyRequests))
+	require.False(t, archiveChatGone(errors.New("connection reset")))
+}
+
+func TestCheckAndSendMonthlyArchives(t *testing.T) {
+	// 2026-10-01 08:00 GMT+8 = 00:00 UTC.
+	firstOfMonth := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	archiveID := int64(-1004082020000)
+
+	setup := func(t *testing.T, userID int64) (*Bot, *mocks.MockBot) {
+		t.Helper()
+		ctx := context.Background()
+		pool := testDB(ctx, t)
+		b := setupTestBot(t, pool)
+		mockBot := mocks.NewMockBot()
+		b.messageSender = mockBot
+		b.cfg.WhitelistedUserIDs = []int64{userID}
+
+		require.NoError(t, b.userRepo.UpsertUser(ctx, &models.User{
+			ID: userID, Username: "archiver", FirstName: "Alice",
+		}))
+		require.NoError(t, b.userRepo.UpdateTimezone(ctx, userID, "Etc/GMT-8"))
+		require.NoError(t, b.userRepo.SetArchiveChatID(ctx, userID, archiveID))
+
+		expense := &models.Expense{
+			UserID:      userID,
+			Amount:      decimal.NewFromFloat(12.30),
+			Currency:    "SGD",
+			Description: "Groceries",
+			Status:      models.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		_, err := b.db.Exec(ctx, testUpdateExpenseTimeSQL,
+			time.Date(2026, 9, 15, 4, 0, 0, 0, time.UTC), expense.ID)
+		require.NoError(t, err)
+		return b, mockBot
+	}
+
+	t.Run("delivers last month's CSV on the 1st", func(t *testing.T) {
+		ctx := context.Background()
+		b, mockBot := setup(t, 4082001)
+
+		b.checkAndSendMonthlyArchives(ctx, firstOfMonth)
+
+		require.Equal(t, 1, mockBot.SentDocumentCount())
+		doc := mockBot.LastSentDocument()
+		require.Equal(t, archiveID, doc.ChatID)
+		require.Equal(t, "expenses_4082001_2026-09.csv", doc.Filename)
+		require.Contains(t, doc.Caption, "Alice (@archiver)")
+		require.Contains(t, doc.Caption, "September 2026")
+		require.Contains(t, string(doc.Data), "Groceries")
+
+		// A later check the same day does not deliver again.
+		b.checkAndSendMonthlyArchives(ctx, firstOfMonth.Add(time.Hour))
+		require.Equal(t, 1, mockBot.SentDocumentCount())
+	})
+
+	t.Run("skips other days", func(t *testing.T) {
+		ctx := context.Background()
+		b, mockBot := setup(t, 4082002)
+
+		b.checkAndSendMonthlyArchives(ctx, firstOfMonth.AddDate(0, 0, 1))
+
+		require.Zero(t, mockBot.SentDocumentCount())
+	})
+
+	t.Run("disables archiving and notifies once when the chat is gone", func(t *testing.T) {
+		ctx := context.Background()
+		b, mockBot := setup(t, 4082003)
+		mockBot.SendDocumentError = fmt.Errorf("%w, bot was kicked from the channel chat", tgbot.ErrorForbidden)
+
+		b.checkAndSendMonthlyArchives(ctx, firstOfMonth)
+		require.Zero(t, mockBot.SentMessageCount(), "the note waits in the outbox")
+		b.deliverOutbox(ctx)
+
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		msg := mockBot.LastSentMessage()
+		require.Equal(t, int64(4082003), msg.ChatID)
+		require.Contains(t, msg.Text, "archiving is now off")
+		require.Contains(t, msg.Text, "September 2026")
+
+		current, err := b.userRepo.GetArchiveChatID(ctx, 4082003)
+		require.NoError(t, err)
+		require.Zero(t, current)
+
+		b.checkAndSendMonthlyArchives(ctx, firstOfMonth.Add(time.Hour))
+		b.deliverOutbox(ctx)
+		require.Equal(t, 1, mockBot.SentMessageCount())
+	})
+
+	t.Run("keeps archiving after a transient failure", func(t *testing.T) {
+		ctx := context.Background()
+		b, mockBot := setup(t, 4082004)
+		mockBot.SendDocumentError = errors.New("connection reset")
+
+		b.checkAndSendMonthlyArchives(ctx, firstOfMonth)
+
+		require.Zero(t, mockBot.SentMessageCount())
+		current, err := b.userRepo.GetArchiveChatID(ctx, 4082004)
+		require.NoError(t, err)
+		require.Equal(t, archiveID, current)
+
+		mockBot.SendDocumentError = nil
+		b.checkAndSendMonthlyArchives(ctx, firstOfMonth.Add(time.Hour))
+		require.Equal(t, 1, mockBot.SentDocumentCount())
+	})
+}
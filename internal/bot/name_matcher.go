@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot/models"
+)
+
+// maxInputSuggestions is how many corrections /settimezone and
+// /setcurrency offer for input they do not know.
+const maxInputSuggestions = 3
+
+// closestNames returns up to limit names that query is a likely typo of,
+// nearest first, ignoring case. Names at the same distance keep their
+// order in names.
+func closestNames(query string, names []string, limit int) []string {
+	query = strings.ToLower(strings.TrimSpace(query))
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	for _, name := range names {
+		distance := typoDistance([]rune(query), []rune(strings.ToLower(name)))
+		if isLikelyTypo(distance, name) {
+			candidates = append(candidates, candidate{name, distance})
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int { return a.distance - b.distance })
+
+	closest := make([]string, 0, min(limit, len(candidates)))
+	for i := 0; i < len(candidates) && i < limit; i++ {
+		closest = append(closest, candidates[i].name)
+	}
+	return closest
+}
+
+// isLikelyTypo reports whether a query distance edits away from name is
+// likely a typo of it: roughly one typo per three letters, and always at
+// least two.
+func isLikelyTypo(distance int, name string) bool {
+	return distance <= max(2, len([]rune(name))/3)
+}
+
+// typoDistance is the edit distance between a and b where swapping two
+// neighbouring letters, as in "SDG" for "SGD", counts as one edit
+// (optimal string alignment).
+func typoDistance(a, b []rune) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(a)][len(b)]
+}
+
+// inputSuggestionKeyboard has a button per suggested value, one per row,
+// whose callback data is callbackFmt filled with userID and the value.
+func inputSuggestionKeyboard(callbackFmt string, userID int64, values []string) *models.InlineKeyboardMarkup {
+	rows := make([][]models.InlineKeyboardButton, len(values))
+	for i, value := range values {
+		rows[i] = []models.InlineKeyboardButton{{
+			Text:         value,
+			CallbackData: fmt.Sprintf(callbackFmt, userID, value),
+		}}
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// parseInputSuggestionCallback reads <prefix><userID>_<value> as built by
+// inputSuggestionKeyboard. The value may contain underscores.
+func parseInputSuggestionCallback(data, prefix string) (int64, string, bool) {
+	userPart, value, ok := strings.Cut(strings.TrimPrefix(data, prefix), "_")
+	if !ok || value == "" {
+		return 0, "", false
+	}
+	userID, err := strconv.ParseInt(userPart, 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return userID, value, true
+}
@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypoDistance(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"sgd", "sgd", 0},
+		{"sdg", "sgd", 1},
+		{"singapor", "singapore", 1},
+		{"tokio", "tokyo", 1},
+		{"", "abc", 3},
+		{"ca", "abc", 3},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, typoDistance([]rune(tt.a), []rune(tt.b)), "%s/%s", tt.a, tt.b)
+	}
+}
+
+func TestClosestNames(t *testing.T) {
+	t.Parallel()
+
+	names := []string{"Food", "Fuel", "Work Travel", "Electronics"}
+	require.Equal(t, []string{"Food", "Fuel"}, closestNames("FOOL", names, 3), "ties keep their order")
+	require.Equal(t, []string{"Food"}, closestNames("fool", names, 1))
+	require.Equal(t, []string{"Work Travel"}, closestNames("wrok trvel", names, 3))
+	require.Empty(t, closestNames("Groceries", names, 3))
+	require.Empty(t, closestNames("food", nil, 3))
+}
+
+func TestParseInputSuggestionCallback(t *testing.T) {
+	t.Parallel()
+
+	userID, value, ok := parseInputSuggestionCallback("settz_42_America/New_York", setTimezoneCallbackPrefix)
+	require.True(t, ok)
+	require.Equal(t, int64(42), userID)
+	require.Equal(t, "America/New_York", value)
+
+	for _, data := range []string{"settz_42", "settz_42_", "settz_x_UTC"} {
+		_, _, ok := parseInputSuggestionCallback(data, setTimezoneCallbackPrefix)
+		require.False(t, ok, data)
+	}
+}
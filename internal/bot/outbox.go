@@ -0,0 +1,213 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// OutboxCheckInterval is how often the outbox loop delivers due messages.
+	OutboxCheckInterval = 30 * time.Second
+	// OutboxTimeout is the maximum time a single delivery run can take.
+	OutboxTimeout = 2 * time.Minute
+	// outboxBatchSize caps the messages one run claims.
+	outboxBatchSize = 50
+	// outboxLease is how long a claimed message waits for its outcome
+	// before a later run delivers it again. It outlasts OutboxTimeout, so
+	// only a crash lets it run out.
+	outboxLease = 5 * time.Minute
+	// outboxMaxAttempts is how many deliveries a message gets before the
+	// outbox gives up on it.
+	outboxMaxAttempts = 5
+	// outboxRetention is how long sent and failed messages, and with them
+	// their dedupe keys, are kept.
+	outboxRetention = 30 * 24 * time.Hour
+)
+
+// outboxRetryDelay is the wait after the given number of failed attempts:
+// 1, 2, 4 and then 8 minutes.
+func outboxRetryDelay(attempts int) time.Duration {
+	return time.Minute << min(max(attempts-1, 0), 3)
+}
+
+// outboxDedupeKey names a queued message by its job, user and period, e.g.
+// "weekly_report:42:2026-04-27".
+func outboxDedupeKey(job string, userID int64, period string) string {
+	return fmt.Sprintf("%s:%d:%s", job, userID, period)
+}
+
+// outboxJob returns the job part of a dedupe key.
+func outboxJob(dedupeKey string) string {
+	job, _, _ := strings.Cut(dedupeKey, ":")
+	return job
+}
+
+// queueMessage writes a message to the outbox instead of sending it, for
+// delivery by the outbox loop. It reports false when a message with the
+// same dedupe key was queued before, which means an earlier run of the job
+// already handled this period. Only inline keyboards are kept as reply
+// markup.
+func (b *Bot) queueMessage(
+	ctx context.Context,
+	outbox *repository.OutboxRepository,
+	params *tgbot.SendMessageParams,
+	dedupeKey string,
+) (bool, error) {
+	chatID, ok := params.ChatID.(int64)
+	if !ok {
+		return false, fmt.Errorf("outbox message %s has no numeric chat ID", dedupeKey)
+	}
+	msg := &repository.OutboxMessage{
+		ChatID:       chatID,
+		Text:         params.Text,
+		ParseMode:    string(params.ParseMode),
+		DedupeKey:    dedupeKey,
+		ScheduledFor: b.now(),
+	}
+	if keyboard, ok := params.ReplyMarkup.(*tgmodels.InlineKeyboardMarkup); ok && keyboard != nil {
+		markup, err := json.Marshal(keyboard)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode outbox reply markup: %w", err)
+		}
+		msg.ReplyMarkup = string(markup)
+	}
+	queued, err := outbox.Enqueue(ctx, msg)
+	if err != nil {
+		return false, fmt.Errorf("failed to queue %s: %w", outboxJob(dedupeKey), err)
+	}
+	return queued, nil
+}
+
+// startOutboxLoop runs a periodic loop that delivers queued messages.
+func (b *Bot) startOutboxLoop(ctx context.Context) {
+	logger.Log.Info().Msg("Outbox loop started")
+
+	ticker := time.NewTicker(OutboxCheckInterval)
+	defer ticker.Stop()
+
+	select {
+	case <-ctx.Done():
+		logger.Log.Info().Msg("Outbox loop stopped")
+		return
+	default:
+	}
+
+	// Deliver right away what a previous process queued but did not send.
+	b.deliverOutbox(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Log.Info().Msg("Outbox loop stopped")
+			return
+		case <-ticker.C:
+			b.deliverOutbox(ctx)
+		}
+	}
+}
+
+// deliverOutbox sends the due messages of the outbox. A message counts as
+// delivered only once marked sent, so a crash in between sends it again;
+// its dedupe key keeps the job from queueing it twice. Failed sends are
+// retried with backoff up to outboxMaxAttempts, except to users who
+// blocked the bot.
+func (b *Bot) deliverOutbox(ctx context.Context) {
+	ctx, span := otel.Tracer("expense-bot/background").Start(ctx, "background.outbox_delivery")
+	defer span.End()
+	start := time.Now()
+
+	runCtx, cancel := context.WithTimeout(ctx, OutboxTimeout)
+	defer cancel()
+
+	now := b.now()
+	if _, err := b.outboxRepo.DeleteFinishedBefore(runCtx, now.Add(-outboxRetention)); err != nil {
+		logger.Log.Warn().Err(err).Msg("Failed to prune outbox")
+	}
+
+	messages, err := b.outboxRepo.Claim(runCtx, now, now.Add(outboxLease), outboxBatchSize)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to claim outbox messages")
+		b.recordOutboxMetrics(ctx, start, backgroundJobStatusError)
+		return
+	}
+	for i := range messages {
+		b.deliverOutboxMessage(runCtx, &messages[i], now)
+	}
+	span.SetAttributes(attribute.Int("outbox_messages", len(messages)))
+	b.recordOutboxMetrics(ctx, start, backgroundJobStatusOK)
+}
+
+// deliverOutboxMessage sends one claimed message and records the outcome.
+func (b *Bot) deliverOutboxMessage(ctx context.Context, msg *repository.OutboxMessage, now time.Time) {
+	job := outboxJob(msg.DedupeKey)
+	params := &tgbot.SendMessageParams{
+		ChatID:    msg.ChatID,
+		Text:      msg.Text,
+		ParseMode: tgmodels.ParseMode(msg.ParseMode),
+	}
+	if msg.ReplyMarkup != "" {
+		var keyboard tgmodels.InlineKeyboardMarkup
+		if err := json.Unmarshal([]byte(msg.ReplyMarkup), &keyboard); err != nil {
+			logger.Log.Error().Err(err).Str("job", job).Msg("Dropping outbox message with invalid reply markup")
+			b.failOutboxMessage(ctx, msg, err)
+			return
+		}
+		params.ReplyMarkup = &keyboard
+	}
+
+	_, sendErr := b.messageSender.SendMessage(ctx, params)
+	if sendErr == nil {
+		if err := b.outboxRepo.MarkSent(ctx, msg.ID); err != nil {
+			logger.Log.Warn().Err(err).Str("job", job).Msg("Failed to mark outbox message sent")
+		}
+		return
+	}
+
+	b.logProactiveSendError(ctx, msg.ChatID, job, sendErr, "Failed to deliver outbox message")
+	if botBlockedByUser(sendErr) || msg.Attempts >= outboxMaxAttempts {
+		b.failOutboxMessage(ctx, msg, sendErr)
+		return
+	}
+	if err := b.outboxRepo.Retry(ctx, msg.ID, now.Add(outboxRetryDelay(msg.Attempts)), sendErr.Error()); err != nil {
+		logger.Log.Warn().Err(err).Str("job", job).Msg("Failed to reschedule outbox message")
+	}
+}
+
+// failOutboxMessage gives up on a message.
+func (b *Bot) failOutboxMessage(ctx context.Context, msg *repository.OutboxMessage, cause error) {
+	if err := b.outboxRepo.Fail(ctx, msg.ID, cause.Error()); err != nil {
+		logger.Log.Warn().Err(err).Str("job", outboxJob(msg.DedupeKey)).Msg("Failed to give up on outbox message")
+		return
+	}
+	logger.Log.Warn().
+		Str("job", outboxJob(msg.DedupeKey)).
+		Str("user_hash", logger.HashUserID(msg.ChatID)).
+		Int("attempts", msg.Attempts).
+		Msg("Gave up on outbox message")
+}
+
+// recordOutboxMetrics records background job metrics for an outbox run.
+func (b *Bot) recordOutboxMetrics(ctx context.Context, start time.Time, status string) {
+	if b.metrics == nil {
+		return
+	}
+	b.metrics.BackgroundJobRuns.Add(ctx, 1, otelmetric.WithAttributes(
+		attribute.String("job", "outbox"),
+		attribute.String("status", status),
+	))
+	b.metrics.BackgroundJobDuration.Record(ctx, time.Since(start).Seconds(),
+		otelmetric.WithAttributes(attribute.String("job", "outbox")))
+}
@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestOutboxRetryDelay(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, time.Minute, outboxRetryDelay(1))
+	require.Equal(t, 2*time.Minute, outboxRetryDelay(2))
+	require.Equal(t, 4*time.Minute, outboxRetryDelay(3))
+	require.Equal(t, 8*time.Minute, outboxRetryDelay(4))
+	require.Equal(t, 8*time.Minute, outboxRetryDelay(9))
+}
+
+func TestOutboxDedupeKey(t *testing.T) {
+	t.Parallel()
+
+	key := outboxDedupeKey("weekly_report", 42, "2026-04-27")
+	require.Equal(t, "weekly_report:42:2026-04-27", key)
+	require.Equal(t, "weekly_report", outboxJob(key))
+}
+
+func TestOutbox(t *testing.T) {
+	loc := time.FixedZone("GMT+8", 8*60*60)
+	// 2026-05-04 is a Monday. 09:00 GMT+8 = 01:00 UTC.
+	monday9amUTC := time.Date(2026, 5, 4, 1, 0, 0, 0, time.UTC)
+
+	setup := func(t *testing.T, userID int64) (*Bot, *mocks.MockBot) {
+		t.Helper()
+		ctx := context.Background()
+		pool := testDB(ctx, t)
+		b := setupTestBot(t, pool)
+		b.displayLocation = loc
+		mockBot := mocks.NewMockBot()
+		b.messageSender = mockBot
+		b.cfg.WeeklyReportEnabled = true
+		b.cfg.WeeklyReportDay = time.Monday
+		b.cfg.WeeklyReportHour = 9
+		b.cfg.WhitelistedUserIDs = []int64{userID}
+
+		require.NoError(t, b.userRepo.UpsertUser(ctx, &models.User{ID: userID, FirstName: "Outbox"}))
+		require.NoError(t, b.userRepo.UpdateTimezone(ctx, userID, "Etc/GMT-8"))
+		expense := &models.Expense{
+			UserID:      userID,
+			Amount:      decimal.NewFromFloat(8.20),
+			Currency:    "SGD",
+			Description: "Dinner",
+			Status:      models.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		_, err := b.db.Exec(ctx, testUpdateExpenseTimeSQL, time.Date(2026, 4, 29, 12, 0, 0, 0, loc), expense.ID)
+		require.NoError(t, err)
+		return b, mockBot
+	}
+
+	t.Run("a crash between queueing and sending delivers exactly once", func(t *testing.T) {
+		ctx := context.Background()
+		b, mockBot := setup(t, 440101)
+		userNow := monday9amUTC.In(loc)
+
+		// The scheduler queues the summary, then the process dies before
+		// the outbox is delivered.
+		count, queued, err := b.queueWeeklySummary(ctx, &models.User{ID: 440101}, userNow)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+		require.True(t, queued)
+		require.Zero(t, mockBot.SentMessageCount())
+
+		// After the restart the scheduler runs again with an empty sent
+		// map and the sender catches up separately.
+		b.checkAndSendWeeklyReports(ctx, make(map[int64]string), monday9amUTC)
+		b.deliverOutbox(ctx)
+		b.checkAndSendWeeklyReports(ctx, make(map[int64]string), monday9amUTC)
+		b.deliverOutbox(ctx)
+
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		msg := mockBot.LastSentMessage()
+		require.Equal(t, int64(440101), msg.ChatID)
+		require.Contains(t, msg.Text, "Weekly Expenses")
+		require.Contains(t, msg.Text, "Dinner")
+		require.Equal(t, tgmodels.ParseModeHTML, msg.ParseMode)
+	})
+
+	t.Run("a transient send error is retried", func(t *testing.T) {
+		ctx := context.Background()
+		b, mockBot := setup(t, 440102)
+		now := time.Now()
+		b.nowFunc = func() time.Time { return now }
+		mockBot.SendMessageError = errors.New("connection reset")
+
+		b.checkAndSendWeeklyReports(ctx, make(map[int64]string), monday9amUTC)
+		require.Zero(t, mockBot.SentMessageCount())
+
+		mockBot.SendMessageError = nil
+		b.deliverOutbox(ctx)
+		require.Zero(t, mockBot.SentMessageCount(), "the retry waits for its backoff")
+
+		now = now.Add(outboxRetryDelay(1))
+		b.deliverOutbox(ctx)
+		b.deliverOutbox(ctx)
+		require.Equal(t, 1, mockBot.SentMessageCount())
+		require.Contains(t, mockBot.LastSentMessage().Text, "Weekly Expenses")
+	})
+
+	t.Run("a user who blocked the bot is not retried", func(t *testing.T) {
+		ctx := context.Background()
+		b, mockBot := setup(t, 440103)
+		now := time.Now()
+		b.nowFunc = func() time.Time { return now }
+		mockBot.SendMessageError = fmt.Errorf("%w, bot was blocked by the user", tgbot.ErrorForbidden)
+
+		b.checkAndSendWeeklyReports(ctx, make(map[int64]string), monday9amUTC)
+		marked, err := b.userRepo.IsUnreachable(ctx, 440103)
+		require.NoError(t, err)
+		require.True(t, marked)
+
+		mockBot.SendMessageError = nil
+		now = now.Add(time.Hour)
+		b.deliverOutbox(ctx)
+		require.Zero(t, mockBot.SentMessageCount())
+	})
+
+	t.Run("inline keyboards survive the outbox", func(t *testing.T) {
+		ctx := context.Background()
+		b, mockBot := setup(t, 440104)
+
+		queued, err := b.queueMessage(ctx, b.outboxRepo, &tgbot.SendMessageParams{
+			ChatID: int64(440104),
+			Text:   "Pick one",
+			ReplyMarkup: &tgmodels.InlineKeyboardMarkup{InlineKeyboard: [][]tgmodels.InlineKeyboardButton{
+				{{Text: "Food", CallbackData: "pick_1"}},
+			}},
+		}, outboxDedupeKey("test", 440104, "1"))
+		require.NoError(t, err)
+		require.True(t, queued)
+
+		b.deliverOutbox(ctx)
+		keyboard := requireInlineKeyboard(t, mockBot.LastSentMessage().ReplyMarkup)
+		require.Equal(t, "pick_1", keyboard.InlineKeyboard[0][0].CallbackData)
+	})
+}
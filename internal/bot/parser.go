@@ -6,6 +6,8 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/shopspring/decimal"
 	"gitlab.com/yelinaung/expense-bot/internal/models"
@@ -85,6 +87,35 @@ type ParsedExpense struct {
 	CategoryName string
 	Currency     string // Detected currency code (e.g., "USD", "SGD"), empty if not specified
 	Tags         []string
+
+	// AmountExpression is the original arithmetic when the amount was
+	// calculated, e.g. "84.60/3". Empty for plain amounts.
+	AmountExpression string
+	// AmountError is set when AmountExpression could not be evaluated. The
+	// other fields are empty in that case.
+	AmountError error
+
+	// ChatCategoryID is the default category of the group chat the expense
+	// was logged in, used when the message names no category. Zero means
+	// none.
+	ChatCategoryID int
+
+	// SplitUserIDs are the group members sharing the expense equally,
+	// payer included, when the message ended in "@split". Empty when the
+	// expense is not split.
+	SplitUserIDs []int64
+
+	// DescriptionTruncated is set when the description was longer than the
+	// configured limit and was cut before saving.
+	DescriptionTruncated bool
+
+	// Refund is set for "/refund" and input starting with "refund". Amount
+	// stays positive; the expense is saved with it negated.
+	Refund bool
+
+	// Source is the entry path, recorded on the saved expense. The parsers
+	// leave it empty; the handler that received the input sets it.
+	Source models.ExpenseSource
 }
 
 type reorderedExpenseCandidate struct {
@@ -191,9 +222,13 @@ func extractTags(text string) (tags []string, cleaned string) {
 	return tags, cleaned
 }
 
-// parseAmount parses a string into a decimal amount.
+// parseAmount parses a string into a decimal amount. Arithmetic such as
+// "84.60/3" is evaluated with evaluateAmountExpression.
 func parseAmount(input string) (decimal.Decimal, error) {
 	input = strings.TrimSpace(input)
+	if isAmountExpression(input) {
+		return evaluateAmountExpression(input)
+	}
 	input = strings.ReplaceAll(input, ",", ".")
 
 	amount, err := decimal.NewFromString(input)
@@ -213,8 +248,23 @@ func parseAmount(input string) (decimal.Decimal, error) {
 	return amount, nil
 }
 
+// refundPrefix starts free-text input that records a refund, e.g.
+// "refund 25.90 Amazon return".
+const refundPrefix = "refund"
+
+// cutRefundPrefix returns input without a leading "refund" word, ignoring
+// case, and whether it had one.
+func cutRefundPrefix(input string) (string, bool) {
+	word, rest, _ := strings.Cut(input, " ")
+	if !strings.EqualFold(word, refundPrefix) {
+		return input, false
+	}
+	return strings.TrimSpace(rest), true
+}
+
 // ParseExpenseInput parses free-text expense input like "5.50 Coffee", "$10 Lunch", or "50 USD Coffee".
 // It also handles reordered input where the description comes first, e.g. "Coffee 5.50" or "Lunch SGD 10".
+// Input starting with "refund" is parsed the same way and marked as a refund.
 // Returns nil if the input cannot be parsed as an expense.
 func ParseExpenseInput(input string) *ParsedExpense {
 	input = strings.TrimSpace(input)
@@ -222,6 +272,22 @@ func ParseExpenseInput(input string) *ParsedExpense {
 		return nil
 	}
 
+	if rest, ok := cutRefundPrefix(input); ok {
+		parsed := parseExpenseText(rest)
+		if parsed != nil {
+			parsed.Refund = true
+		}
+		return parsed
+	}
+	return parseExpenseText(input)
+}
+
+// parseExpenseText is ParseExpenseInput without the refund prefix.
+func parseExpenseText(input string) *ParsedExpense {
+	if input == "" {
+		return nil
+	}
+
 	if candidate := shouldPreferReorderedParse(input); candidate != nil {
 		if result := parseExpenseReordered(candidate); result != nil {
 			return result
@@ -238,7 +304,18 @@ func ParseExpenseInput(input string) *ParsedExpense {
 // parseExpenseLeadingAmount parses input where the amount comes first.
 func parseExpenseLeadingAmount(input string) *ParsedExpense {
 	detectedCurrency, remaining := parseCurrencyPrefix(input)
-	amount, rest := parseAmountAndRest(remaining)
+
+	expression, rest := splitAmountExpression(remaining)
+	var amount decimal.Decimal
+	if expression != "" {
+		var err error
+		amount, err = evaluateAmountExpression(expression)
+		if err != nil {
+			return &ParsedExpense{AmountExpression: expression, AmountError: err}
+		}
+	} else {
+		amount, rest = parseAmountAndRest(remaining)
+	}
 	if !amount.GreaterThan(decimal.Zero) {
 		return nil
 	}
@@ -250,11 +327,23 @@ func parseExpenseLeadingAmount(input string) *ParsedExpense {
 	}
 
 	return &ParsedExpense{
-		Amount:      amount,
-		Description: extractDescription(rest),
-		Currency:    detectedCurrency,
-		Tags:        tags,
+		Amount:           amount,
+		Description:      extractDescription(rest),
+		Currency:         detectedCurrency,
+		Tags:             tags,
+		AmountExpression: expression,
+	}
+}
+
+// splitAmountExpression splits a leading arithmetic expression such as
+// "84.60/3" from the rest of the input. It returns an empty expression when
+// the input starts with a plain number instead.
+func splitAmountExpression(input string) (expression, rest string) {
+	match := strings.TrimRight(amountExpressionRegex.FindString(input), ".,")
+	if !isAmountExpression(match) {
+		return "", input
 	}
+	return match, strings.TrimSpace(input[len(match):])
 }
 
 // parseExpenseReordered handles input where the description comes before
@@ -455,16 +544,35 @@ func parseSuffixCurrencyCode(detectedCurrency, rest string) (string, string) {
 	return code, trimmed
 }
 
-// extractDescription extracts the description from the input.
-// Category matching is handled separately in ParseAddCommandWithCategories.
+// extractDescription extracts the description from the input, dropping
+// control characters and collapsing whitespace. Length is limited when the
+// expense is saved. Category matching is handled separately in
+// ParseAddCommandWithCategories.
 func extractDescription(input string) string {
-	return strings.TrimSpace(input)
+	description, _ := models.NormalizeText(input, 0)
+	return description
 }
 
 // ParseAddCommand parses the /add command format: /add <amount> <description> [category].
 // Category can be multi-word like "Food - Dining Out".
 func ParseAddCommand(input string) *ParsedExpense {
-	input = strings.TrimPrefix(input, "/add")
+	return parseExpenseCommand(input, "/add")
+}
+
+// ParseRefundCommand parses "/refund <amount> <description> [category]",
+// the /add format for refunds.
+func ParseRefundCommand(input string) *ParsedExpense {
+	parsed := parseExpenseCommand(input, "/refund")
+	if parsed != nil {
+		parsed.Refund = true
+	}
+	return parsed
+}
+
+// parseExpenseCommand parses the arguments of an /add style command,
+// dropping a "@botname" mention.
+func parseExpenseCommand(input, command string) *ParsedExpense {
+	input = strings.TrimPrefix(input, command)
 	input = strings.TrimSpace(input)
 
 	idx := strings.Index(input, "@")
@@ -483,66 +591,129 @@ func ParseAddCommand(input string) *ParsedExpense {
 // ParseAddCommandWithCategories parses /add with category matching.
 // It tries bracket syntax first, then longest suffix match.
 func ParseAddCommandWithCategories(input string, categoryNames []string) *ParsedExpense {
-	parsed := ParseAddCommand(input)
-	if parsed == nil {
-		return nil
-	}
+	return parseWithCategories(input, categoryNames, ParseAddCommand)
+}
+
+// ParseRefundCommandWithCategories parses /refund with category matching.
+func ParseRefundCommandWithCategories(input string, categoryNames []string) *ParsedExpense {
+	return parseWithCategories(input, categoryNames, ParseRefundCommand)
+}
+
+// ParseExpenseInputWithCategories parses free-text with category matching.
+func ParseExpenseInputWithCategories(input string, categoryNames []string) *ParsedExpense {
+	return parseWithCategories(input, categoryNames, ParseExpenseInput)
+}
 
-	if parsed.Description == "" {
+// parseWithCategories parses input with parse and assigns one of
+// categoryNames to it.
+//
+// A category name the input ends with is cut off before parsing, so the
+// name is taken literally: "12.50 Coffee 50 Coffee" with a "50 Coffee"
+// category is 12.50 for Coffee, and a category named "Trip EUR" is not read
+// as a EUR amount. Candidates are tried in categoryPrecedes order and the
+// first whose remainder still parses wins; failing that, the description
+// is matched by matchBracketCategory. Only input that parses as a whole is
+// considered, so a category name never turns other text into an expense.
+func parseWithCategories(input string, categoryNames []string, parse func(string) *ParsedExpense) *ParsedExpense {
+	parsed := parse(input)
+	if parsed == nil || parsed.Description == "" {
 		return parsed
 	}
 
+	trimmed := strings.TrimSpace(input)
+	for _, catName := range sortedCategoryNames(categoryNames) {
+		rest, ok := cutCategorySuffix(trimmed, catName)
+		if !ok {
+			continue
+		}
+		if literal := parse(rest); literal != nil && literal.AmountError == nil {
+			literal.CategoryName = catName
+			return literal
+		}
+	}
+
 	matchBracketCategory(parsed, categoryNames)
 
 	return parsed
 }
 
-// ParseExpenseInputWithCategories parses free-text with category matching.
-func ParseExpenseInputWithCategories(input string, categoryNames []string) *ParsedExpense {
-	parsed := ParseExpenseInput(input)
-	if parsed == nil {
-		return nil
+// categoryPrecedes reports whether category a is tried before b when both
+// could match: the longer name first, then case-insensitive name order,
+// then byte order, so the result never depends on how the list is sorted.
+func categoryPrecedes(a, b string) bool {
+	if la, lb := utf8.RuneCountInString(a), utf8.RuneCountInString(b); la != lb {
+		return la > lb
 	}
-
-	if parsed.Description == "" {
-		return parsed
+	if la, lb := strings.ToLower(a), strings.ToLower(b); la != lb {
+		return la < lb
 	}
+	return a < b
+}
 
-	matchBracketCategory(parsed, categoryNames)
+// sortedCategoryNames returns the non-blank categoryNames in
+// categoryPrecedes order.
+func sortedCategoryNames(categoryNames []string) []string {
+	names := make([]string, 0, len(categoryNames))
+	for _, name := range categoryNames {
+		if strings.TrimSpace(name) != "" {
+			names = append(names, name)
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool { return categoryPrecedes(names[i], names[j]) })
+	return names
+}
 
-	return parsed
+// cutCategorySuffix reports whether text ends with catName, ignoring case,
+// as whole words: the name must be all of text or follow whitespace, so
+// "Food" does not match "Seafood". It returns text before the name.
+func cutCategorySuffix(text, catName string) (string, bool) {
+	catName = strings.TrimSpace(catName)
+	textRunes := []rune(text)
+	n := utf8.RuneCountInString(catName)
+	if n == 0 || n > len(textRunes) {
+		return "", false
+	}
+	if !strings.EqualFold(string(textRunes[len(textRunes)-n:]), catName) {
+		return "", false
+	}
+	rest := textRunes[:len(textRunes)-n]
+	if len(rest) > 0 && !unicode.IsSpace(rest[len(rest)-1]) {
+		return "", false
+	}
+	return strings.TrimSpace(string(rest)), true
 }
 
 // matchBracketCategory extracts a [Category] from the description, falling
-// back to longest-suffix matching against known category names.
+// back to the longest whole-word suffix match against known category names.
+// When several names match equally, an exact-case bracket match wins, then
+// categoryPrecedes order.
 func matchBracketCategory(parsed *ParsedExpense, categoryNames []string) {
+	names := sortedCategoryNames(categoryNames)
+
 	if bracketMatch := bracketCategoryRegex.FindStringSubmatch(parsed.Description); len(bracketMatch) > 1 {
-		bracketName := bracketMatch[1]
-		for _, catName := range categoryNames {
-			if strings.EqualFold(catName, bracketName) {
-				parsed.Description = strings.TrimSpace(parsed.Description[:len(parsed.Description)-len(bracketMatch[0])])
-				parsed.CategoryName = catName
-				return
+		bracketName := strings.TrimSpace(bracketMatch[1])
+		matched := ""
+		for _, catName := range names {
+			if catName == bracketName {
+				matched = catName
+				break
 			}
-		}
-	}
-
-	descLower := strings.ToLower(parsed.Description)
-	var matchedCategory string
-	var matchedLen int
-
-	for _, catName := range categoryNames {
-		catLower := strings.ToLower(catName)
-		if strings.HasSuffix(descLower, catLower) {
-			if len(catName) > matchedLen {
-				matchedCategory = catName
-				matchedLen = len(catName)
+			if matched == "" && strings.EqualFold(catName, bracketName) {
+				matched = catName
 			}
 		}
+		if matched != "" {
+			parsed.Description = strings.TrimSpace(parsed.Description[:len(parsed.Description)-len(bracketMatch[0])])
+			parsed.CategoryName = matched
+			return
+		}
 	}
 
-	if matchedCategory != "" {
-		parsed.Description = strings.TrimSpace(parsed.Description[:len(parsed.Description)-matchedLen])
-		parsed.CategoryName = matchedCategory
+	for _, catName := range names {
+		if rest, ok := cutCategorySuffix(parsed.Description, catName); ok {
+			parsed.Description = rest
+			parsed.CategoryName = catName
+			return
+		}
 	}
 }
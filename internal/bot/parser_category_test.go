@@ -0,0 +1,276 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExpenseInputWithAdversarialCategories(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		input        string
+		categories   []string
+		wantNil      bool
+		wantAmt      string
+		wantDesc     string
+		wantCatName  string
+		wantCurrency string
+	}{
+		{
+			name:        "category starting with a number",
+			input:       "12.50 Coffee 50 Coffee",
+			categories:  []string{"Coffee", "50 Coffee"},
+			wantAmt:     "12.50",
+			wantDesc:    "Coffee",
+			wantCatName: "50 Coffee",
+		},
+		{
+			name:        "shorter category when the longer is not at the end",
+			input:       "12.50 50 Coffee beans Coffee",
+			categories:  []string{"Coffee", "50 Coffee"},
+			wantAmt:     "12.50",
+			wantDesc:    "50 Coffee beans",
+			wantCatName: "Coffee",
+		},
+		{
+			name:        "category that is only a number",
+			input:       "12.50 Lunch 2024",
+			categories:  []string{"2024"},
+			wantAmt:     "12.50",
+			wantDesc:    "Lunch",
+			wantCatName: "2024",
+		},
+		{
+			name:        "category that looks like an amount",
+			input:       "7 Snacks 9.99",
+			categories:  []string{"9.99"},
+			wantAmt:     "7.00",
+			wantDesc:    "Snacks",
+			wantCatName: "9.99",
+		},
+		{
+			name:       "number category is not the amount",
+			input:      "Lunch 2024",
+			categories: []string{"2024"},
+			wantAmt:    "2024.00",
+			wantDesc:   "Lunch",
+		},
+		{
+			name:        "category ending in a currency code",
+			input:       "12.50 Dinner Trip EUR",
+			categories:  []string{"Trip EUR"},
+			wantAmt:     "12.50",
+			wantDesc:    "Dinner",
+			wantCatName: "Trip EUR",
+		},
+		{
+			name:         "currency code without such a category",
+			input:        "12.50 Dinner Trip EUR",
+			categories:   []string{"Trip"},
+			wantAmt:      "12.50",
+			wantDesc:     "Dinner",
+			wantCatName:  "Trip",
+			wantCurrency: "EUR",
+		},
+		{
+			name:        "category with a currency symbol",
+			input:       "4 Coffee $ Stuff",
+			categories:  []string{"$ Stuff"},
+			wantAmt:     "4.00",
+			wantDesc:    "Coffee",
+			wantCatName: "$ Stuff",
+		},
+		{
+			name:        "category with a tag marker",
+			input:       "4 Coffee #work",
+			categories:  []string{"#work"},
+			wantAmt:     "4.00",
+			wantDesc:    "Coffee",
+			wantCatName: "#work",
+		},
+		{
+			name:        "category with regex characters",
+			input:       "4 Parts (a+b)*",
+			categories:  []string{"(a+b)*"},
+			wantAmt:     "4.00",
+			wantDesc:    "Parts",
+			wantCatName: "(a+b)*",
+		},
+		{
+			name:        "category inside brackets with digits",
+			input:       "12.50 Coffee [50 Coffee]",
+			categories:  []string{"Coffee", "50 Coffee"},
+			wantAmt:     "12.50",
+			wantDesc:    "Coffee",
+			wantCatName: "50 Coffee",
+		},
+		{
+			name:        "suffix must start a word",
+			input:       "12 Seafood",
+			categories:  []string{"Food"},
+			wantAmt:     "12.00",
+			wantDesc:    "Seafood",
+			wantCatName: "",
+		},
+		{
+			name:        "suffix with a number must start a word",
+			input:       "12.50 Coffee 150 Coffee",
+			categories:  []string{"50 Coffee"},
+			wantAmt:     "12.50",
+			wantDesc:    "Coffee 150 Coffee",
+			wantCatName: "",
+		},
+		{
+			name:        "whole description is the category",
+			input:       "5 50 Coffee",
+			categories:  []string{"50 Coffee"},
+			wantAmt:     "5.00",
+			wantDesc:    "",
+			wantCatName: "50 Coffee",
+		},
+		{
+			name:        "category taking the amount leaves the description",
+			input:       "50 Coffee",
+			categories:  []string{"50 Coffee"},
+			wantAmt:     "50.00",
+			wantDesc:    "Coffee",
+			wantCatName: "",
+		},
+		{
+			name:        "non-ascii category ignoring case",
+			input:       "3 Brot BÄCKEREI",
+			categories:  []string{"Bäckerei"},
+			wantAmt:     "3.00",
+			wantDesc:    "Brot",
+			wantCatName: "Bäckerei",
+		},
+		{
+			name:        "blank category names are ignored",
+			input:       "3 Bread",
+			categories:  []string{"", "  "},
+			wantAmt:     "3.00",
+			wantDesc:    "Bread",
+			wantCatName: "",
+		},
+		{
+			name:       "category does not make chat an expense",
+			input:      "meet at 5 Food",
+			categories: []string{"Food"},
+			wantNil:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			result := ParseExpenseInputWithCategories(tt.input, tt.categories)
+
+			if tt.wantNil {
+				require.Nil(t, result)
+				return
+			}
+
+			require.NotNil(t, result)
+			require.Equal(t, tt.wantAmt, result.Amount.StringFixed(2))
+			require.Equal(t, tt.wantDesc, result.Description)
+			require.Equal(t, tt.wantCatName, result.CategoryName)
+			require.Equal(t, tt.wantCurrency, result.Currency)
+		})
+	}
+}
+
+func TestParseWithCategoriesPrecedence(t *testing.T) {
+	t.Parallel()
+
+	orders := [][]string{
+		{"Coffee", "50 Coffee", "0 Coffee", "coffee"},
+		{"coffee", "0 Coffee", "50 Coffee", "Coffee"},
+	}
+	for _, categories := range orders {
+		parsed := ParseExpenseInputWithCategories("12.50 Beans 50 Coffee", categories)
+		require.NotNil(t, parsed)
+		require.Equal(t, "50 Coffee", parsed.CategoryName, "the longest whole-word match wins")
+		require.Equal(t, "Beans", parsed.Description)
+
+		parsed = ParseExpenseInputWithCategories("12.50 Beans COFFEE", categories)
+		require.NotNil(t, parsed)
+		require.Equal(t, "Coffee", parsed.CategoryName, "equal names are picked the same way in any order")
+
+		parsed = ParseExpenseInputWithCategories("12.50 Beans [coffee]", categories)
+		require.NotNil(t, parsed)
+		require.Equal(t, "coffee", parsed.CategoryName, "an exact-case bracket match wins")
+	}
+
+	require.True(t, categoryPrecedes("50 Coffee", "Coffee"))
+	require.True(t, categoryPrecedes("Bar", "foo"))
+	require.True(t, categoryPrecedes("Foo", "foo"))
+	require.False(t, categoryPrecedes("foo", "Foo"))
+}
+
+func TestParseCommandsWithAdversarialCategories(t *testing.T) {
+	t.Parallel()
+
+	categories := []string{"Coffee", "50 Coffee", "Trip EUR"}
+
+	parsed := ParseAddCommandWithCategories("/add 12.50 Coffee 50 Coffee", categories)
+	require.NotNil(t, parsed)
+	require.Equal(t, "12.50", parsed.Amount.StringFixed(2))
+	require.Equal(t, "Coffee", parsed.Description)
+	require.Equal(t, "50 Coffee", parsed.CategoryName)
+
+	parsed = ParseAddCommandWithCategories("/add 30 Hotel Trip EUR", categories)
+	require.NotNil(t, parsed)
+	require.Empty(t, parsed.Currency)
+	require.Equal(t, "Trip EUR", parsed.CategoryName)
+
+	parsed = ParseRefundCommandWithCategories("/refund 4 Beans 50 Coffee", categories)
+	require.NotNil(t, parsed)
+	require.True(t, parsed.Refund)
+	require.Equal(t, "Beans", parsed.Description)
+	require.Equal(t, "50 Coffee", parsed.CategoryName)
+
+	parsed = ParseAddCommandWithCategories("/add 50 Coffee", []string{"50 Coffee"})
+	require.NotNil(t, parsed)
+	require.Equal(t, "50.00", parsed.Amount.StringFixed(2), "a name with no amount left is not a category")
+	require.Equal(t, "Coffee", parsed.Description)
+	require.Empty(t, parsed.CategoryName)
+}
+
+func TestCutCategorySuffix(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text     string
+		category string
+		wantRest string
+		wantOK   bool
+	}{
+		{"12.50 Coffee 50 Coffee", "50 Coffee", "12.50 Coffee", true},
+		{"12.50 Coffee 150 Coffee", "50 Coffee", "", false},
+		{"Seafood", "Food", "", false},
+		{"Food", "food", "", true},
+		{"Lunch\tFood", "Food", "Lunch", true},
+		{"Lunch Food", " Food ", "Lunch", true},
+		{"Food", "Lunch Food", "", false},
+		{"Lunch Food", "", "", false},
+		{"Brot BÄCKEREI", "Bäckerei", "Brot", true},
+	}
+
+	for _, tt := range tests {
+		rest, ok := cutCategorySuffix(tt.text, tt.category)
+		require.Equal(t, tt.wantOK, ok, "%q in %q", tt.category, tt.text)
+		require.Equal(t, tt.wantRest, rest, "%q in %q", tt.category, tt.text)
+	}
+}
+
+func TestCategoryNameWarning(t *testing.T) {
+	t.Parallel()
+
+	require.Contains(t, categoryNameWarning("50 Coffee"), "[50 Coffee]")
+	require.Contains(t, categoryNameWarning("2024 <Trip>"), "[2024 &lt;Trip&gt;]")
+	require.Empty(t, categoryNameWarning("Coffee 50"))
+	require.Empty(t, categoryNameWarning(""))
+}
@@ -1,6 +1,7 @@
 package bot
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/shopspring/decimal"
@@ -196,25 +197,31 @@ func TestParseExpenseInput_EdgeCases(t *testing.T) {
 			name:     "description with newlines",
 			input:    "5.50 Coffee\nwith friends",
 			wantAmt:  testAmount550,
-			wantDesc: "Coffee\nwith friends",
+			wantDesc: "Coffee with friends",
 		},
 		{
 			name:     "description with tabs",
 			input:    "5.50 Coffee\twith\ttabs",
 			wantAmt:  testAmount550,
-			wantDesc: "Coffee\twith\ttabs",
+			wantDesc: "Coffee with tabs",
 		},
 		{
 			name:     "very long description",
-			input:    "5.50 " + string(make([]byte, 500)),
+			input:    "5.50 " + strings.Repeat("a", 500),
 			wantAmt:  testAmount550,
-			wantDesc: string(make([]byte, 500)),
+			wantDesc: strings.Repeat("a", 500), // Capped when saved, not parsed.
+		},
+		{
+			name:     "description of control characters",
+			input:    "5.50 " + string(make([]byte, 10)),
+			wantAmt:  testAmount550,
+			wantDesc: "",
 		},
 		{
 			name:     "description with multiple spaces",
 			input:    "5.50   Coffee   with   spaces",
 			wantAmt:  testAmount550,
-			wantDesc: "Coffee   with   spaces",
+			wantDesc: "Coffee with spaces",
 		},
 		{
 			name:     "amount with leading zeros",
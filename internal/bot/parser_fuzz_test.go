@@ -71,6 +71,8 @@ func FuzzParseExpenseInput(f *testing.F) {
 	f.Add("5,50 Coffee")
 	f.Add("10.00")
 	f.Add("5")
+	f.Add("84.60/3 dinner")
+	f.Add("(12+4.5)*2 snacks")
 
 	// Edge cases.
 	f.Add("")
@@ -127,6 +129,11 @@ func assertParsedExpenseInputInvariants(
 	if result == nil {
 		return
 	}
+	if result.AmountError != nil {
+		require.NotEmpty(t, result.AmountExpression,
+			"ParseExpenseInput(%q) returned an amount error without an expression", input)
+		return
+	}
 
 	assertPositiveParsedAmount(t, input, result)
 	assertSupportedParsedCurrency(t, input, result)
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
 )
 
 func TestExtractTags(t *testing.T) {
@@ -151,7 +152,7 @@ func TestIsValidTagName(t *testing.T) {
 }
 
 func TestEscapeHTML(t *testing.T) {
-	require.Equal(t, "hello", escapeHTML("hello"))
-	require.Equal(t, "&lt;b&gt;bold&lt;/b&gt;", escapeHTML("<b>bold</b>"))
-	require.Equal(t, "a &amp; b", escapeHTML("a & b"))
+	require.Equal(t, "hello", botfmt.EscapeHTML("hello"))
+	require.Equal(t, "&lt;b&gt;bold&lt;/b&gt;", botfmt.EscapeHTML("<b>bold</b>"))
+	require.Equal(t, "a &amp; b", botfmt.EscapeHTML("a & b"))
 }
@@ -1,10 +1,12 @@
 package bot
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
 )
 
 const (
@@ -545,6 +547,16 @@ func TestExtractDescription(t *testing.T) {
 			input: "Coffee @ Starbucks - Downtown",
 			want:  "Coffee @ Starbucks - Downtown",
 		},
+		{
+			name:  "inner whitespace collapsed",
+			input: "Lunch \t with\n friends",
+			want:  "Lunch with friends",
+		},
+		{
+			name:  "control characters stripped",
+			input: "Cof\x00fee\x1b",
+			want:  testCoffeeDesc,
+		},
 	}
 
 	for _, tt := range tests {
@@ -679,3 +691,17 @@ func TestParseExpenseInputWithCategoriesEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// BenchmarkParseExpenseInputCapped measures the parser on the longest
+// message that still reaches it, so a slower regex shows up here before it
+// shows up as CPU spent on pasted articles.
+func BenchmarkParseExpenseInputCapped(b *testing.B) {
+	input := "12.50 " + strings.Repeat("lunch with the team at the usual place ", 30)
+	input = input[:config.DefaultMaxFreeTextLength]
+	categories := []string{foodDiningOutParserTest, travelVacationParserTest, "Transportation", "Others"}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		ParseExpenseInputWithCategories(input, categories)
+	}
+}
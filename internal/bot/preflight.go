@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+// preflightTimeout bounds each startup check, so an unreachable API cannot
+// hold up the start.
+const preflightTimeout = 10 * time.Second
+
+// telegramIdentity is the part of the Telegram API the token check uses.
+type telegramIdentity interface {
+	GetMe(ctx context.Context) (*models.User, error)
+}
+
+// geminiPinger is the part of the Gemini client the key check uses.
+type geminiPinger interface {
+	Ping(ctx context.Context) error
+}
+
+// checkTelegram confirms the bot token with getMe and returns the bot's own
+// user.
+func checkTelegram(ctx context.Context, api telegramIdentity) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+	defer cancel()
+
+	me, err := api.GetMe(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("telegram rejected TELEGRAM_BOT_TOKEN or is unreachable (getMe failed): %w", err)
+	}
+	return me, nil
+}
+
+// checkGemini confirms GEMINI_API_KEY with a one-token request.
+func checkGemini(ctx context.Context, client geminiPinger) error {
+	ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+	defer cancel()
+
+	if err := client.Ping(ctx); err != nil {
+		return fmt.Errorf("gemini rejected GEMINI_API_KEY or is unreachable: %w", err)
+	}
+	return nil
+}
+
+// preflightGemini returns client when it passes checkGemini. Otherwise it
+// logs a warning and returns nil, so receipt OCR and voice input answer as
+// if no key were configured instead of failing on every request.
+func preflightGemini(ctx context.Context, client *gemini.Client) *gemini.Client {
+	if client == nil {
+		return nil
+	}
+	if err := checkGemini(ctx, client); err != nil {
+		logger.Log.Warn().
+			Err(err).
+			Msg("!!! Gemini preflight failed: receipt OCR and voice input are DISABLED until restart !!!")
+		return nil
+	}
+	return client
+}
+
+// Check runs the startup preflights on their own, for the --check flag:
+// Telegram must accept the bot token, and a configured Gemini key must
+// work. Unlike New, a failing Gemini key is an error here.
+func Check(ctx context.Context, cfg *config.Config) error {
+	telegramBot, err := bot.New(cfg.TelegramBotToken, bot.WithSkipGetMe())
+	if err != nil {
+		return fmt.Errorf("failed to create bot: %w", err)
+	}
+	me, err := checkTelegram(ctx, telegramBot)
+	if err != nil {
+		return err
+	}
+	logger.Log.Info().Str("username", me.Username).Msg("Telegram preflight passed")
+
+	if cfg.GeminiAPIKey == "" {
+		logger.Log.Info().Msg("Gemini preflight skipped: GEMINI_API_KEY is not set")
+		return nil
+	}
+	client, err := gemini.NewClient(ctx, cfg.GeminiAPIKey)
+	if err != nil {
+		return fmt.Errorf("failed to create Gemini client: %w", err)
+	}
+	if err := checkGemini(ctx, client); err != nil {
+		return err
+	}
+	logger.Log.Info().Msg("Gemini preflight passed")
+	return nil
+}
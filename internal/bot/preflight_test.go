@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tgbot "github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	"google.golang.org/genai"
+)
+
+type fakeTelegramIdentity struct {
+	user *tgmodels.User
+	err  error
+}
+
+func (f *fakeTelegramIdentity) GetMe(context.Context) (*tgmodels.User, error) {
+	return f.user, f.err
+}
+
+func TestCheckTelegram(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns the bot user", func(t *testing.T) {
+		t.Parallel()
+		me, err := checkTelegram(context.Background(), &fakeTelegramIdentity{user: &tgmodels.User{Username: "expense_bot"}})
+		require.NoError(t, err)
+		require.Equal(t, "expense_bot", me.Username)
+	})
+
+	t.Run("names the token when getMe fails", func(t *testing.T) {
+		t.Parallel()
+		_, err := checkTelegram(context.Background(), &fakeTelegramIdentity{err: errors.New("unauthorized")})
+		require.ErrorContains(t, err, "TELEGRAM_BOT_TOKEN")
+		require.ErrorContains(t, err, "unauthorized")
+	})
+
+	t.Run("fails fast on a rejected token", func(t *testing.T) {
+		t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":401,"description":"Unauthorized"}`))
+		}))
+		t.Cleanup(server.Close)
+
+		telegramBot, err := tgbot.New("123:typo", tgbot.WithServerURL(server.URL), tgbot.WithSkipGetMe())
+		require.NoError(t, err)
+		_, err = checkTelegram(context.Background(), telegramBot)
+		require.ErrorContains(t, err, "TELEGRAM_BOT_TOKEN")
+	})
+}
+
+func TestPreflightGemini(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	require.Nil(t, preflightGemini(ctx, nil))
+
+	working := gemini.NewClientWithGenerator(&botTestGenerator{response: &genai.GenerateContentResponse{}})
+	require.Same(t, working, preflightGemini(ctx, working))
+
+	t.Run("a failing key disables receipt OCR", func(t *testing.T) {
+		t.Parallel()
+		failing := gemini.NewClientWithGenerator(&botTestGenerator{err: errors.New("API key not valid")})
+		b := &Bot{geminiClient: preflightGemini(ctx, failing)}
+		require.Nil(t, b.geminiClient)
+
+		mockBot := mocks.NewMockBot()
+		b.handlePhotoCore(ctx, mockBot, mocks.PhotoUpdate(12345, 100, testPhotoFileID))
+		require.Contains(t, mockBot.LastSentMessage().Text, "Receipt OCR is not configured")
+	})
+}
@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// receiptQueueSize is how many receipts may wait for a free worker.
+	receiptQueueSize = 64
+	// maxReceiptsPerUser caps the receipts one user can have queued or
+	// being scanned, so one busy chat cannot starve everyone else.
+	maxReceiptsPerUser = 2
+	// receiptDrainTimeout is how long shutdown waits for queued and
+	// running scans before canceling them.
+	receiptDrainTimeout = 30 * time.Second
+)
+
+var (
+	errReceiptUserBusy   = errors.New("too many receipts in progress for user")
+	errReceiptQueueFull  = errors.New("receipt queue is full")
+	errReceiptPoolClosed = errors.New("receipt workers are shutting down")
+)
+
+// receiptJob is one receipt scan waiting for a worker. ctx is the context
+// of the update that queued it; only its values are kept.
+type receiptJob struct {
+	ctx    context.Context
+	userID int64
+	run    func(ctx context.Context)
+}
+
+// receiptWorkerPool scans receipts on a fixed number of goroutines so the
+// photo handler can return as soon as the placeholder is sent. Jobs run
+// until they finish or the pool is shut down.
+type receiptWorkerPool struct {
+	jobs   chan receiptJob
+	ctx    context.Context // Canceled when a drain times out.
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	perUser map[int64]int
+}
+
+// newReceiptWorkerPool starts workers goroutines. At least one is started.
+func newReceiptWorkerPool(workers int) *receiptWorkerPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &receiptWorkerPool{
+		jobs:    make(chan receiptJob, receiptQueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+		perUser: make(map[int64]int),
+	}
+	for range max(workers, 1) {
+		p.wg.Go(p.work)
+	}
+	return p
+}
+
+// enqueue queues a scan for userID. It fails without queuing when the user
+// already has maxReceiptsPerUser scans pending, the queue is full, or the
+// pool is shutting down.
+func (p *receiptWorkerPool) enqueue(ctx context.Context, userID int64, run func(ctx context.Context)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return errReceiptPoolClosed
+	}
+	if p.perUser[userID] >= maxReceiptsPerUser {
+		return errReceiptUserBusy
+	}
+	select {
+	case p.jobs <- receiptJob{ctx: ctx, userID: userID, run: run}:
+		p.perUser[userID]++
+		return nil
+	default:
+		return errReceiptQueueFull
+	}
+}
+
+func (p *receiptWorkerPool) work() {
+	for job := range p.jobs {
+		p.runJob(job)
+	}
+}
+
+// runJob runs one job detached from the update that queued it, so the
+// scan survives the handler returning, but still stops when a drain times
+// out.
+func (p *receiptWorkerPool) runJob(job receiptJob) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(job.ctx))
+	stop := context.AfterFunc(p.ctx, cancel)
+	defer func() {
+		stop()
+		cancel()
+		p.mu.Lock()
+		p.perUser[job.userID]--
+		if p.perUser[job.userID] <= 0 {
+			delete(p.perUser, job.userID)
+		}
+		p.mu.Unlock()
+	}()
+	job.run(ctx)
+}
+
+// shutdown stops accepting receipts and waits for queued and running scans
+// to finish. When ctx ends first the remaining scans are canceled and
+// shutdown still waits for the workers to return.
+func (p *receiptWorkerPool) shutdown(ctx context.Context) {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		p.cancel()
+		<-done
+	}
+	p.cancel()
+}
@@ -0,0 +1,228 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	"google.golang.org/genai"
+)
+
+// slowReceiptGenerator blocks every Gemini request until release is closed.
+type slowReceiptGenerator struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newSlowReceiptGenerator() *slowReceiptGenerator {
+	return &slowReceiptGenerator{started: make(chan struct{}, 8), release: make(chan struct{})}
+}
+
+func (g *slowReceiptGenerator) GenerateContent(
+	ctx context.Context,
+	_ string,
+	_ []*genai.Content,
+	_ *genai.GenerateContentConfig,
+) (*genai.GenerateContentResponse, error) {
+	g.started <- struct{}{}
+	select {
+	case <-g.release:
+		return nil, errors.New("parse failed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func newAsyncReceiptBot(generator gemini.ContentGenerator, workers int) *Bot {
+	return &Bot{
+		geminiClient: gemini.NewClientWithGenerator(generator),
+		httpClient: &http.Client{
+			Transport: receiptRoundTripperFunc(func(*http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("fake-image-bytes")),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		},
+		receiptWorkers: newReceiptWorkerPool(workers),
+	}
+}
+
+func TestHandlePhotoCore_ScansInBackground(t *testing.T) {
+	t.Parallel()
+
+	generator := newSlowReceiptGenerator()
+	b := newAsyncReceiptBot(generator, 1)
+	mockBot := mocks.NewMockBot()
+
+	returned := make(chan struct{})
+	go func() {
+		b.handlePhotoCore(context.Background(), mockBot, mocks.PhotoUpdate(12345, 100, testPhotoFileID))
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler waited for the scan")
+	}
+	<-generator.started
+
+	require.Equal(t, 1, mockBot.SentMessageCount())
+	require.Contains(t, mockBot.LastSentMessage().Text, testProcessingReceiptText)
+	require.Zero(t, mockBot.EditedMessageCount())
+
+	close(generator.release)
+	b.drainReceiptWorkers(context.Background())
+
+	require.Equal(t, 1, mockBot.SentMessageCount())
+	edited := mockBot.LastEditedMessage()
+	require.Equal(t, testPlaceholderMessageID, edited.MessageID)
+	require.Contains(t, edited.Text, "Could not read this receipt")
+}
+
+func TestDrainReceiptWorkers_FlushesMediaGroups(t *testing.T) {
+	t.Parallel()
+
+	generator := newSlowReceiptGenerator()
+	close(generator.release)
+	b := newAsyncReceiptBot(generator, 1)
+	b.mediaGroupWait = time.Hour
+	mockBot := mocks.NewMockBot()
+
+	b.handlePhotoCore(context.Background(), mockBot, albumPhotoUpdate(12345, 100, testPhotoFileID, "album-drain"))
+	require.Equal(t, 1, b.mediaGroups.pending())
+	require.Zero(t, mockBot.SentMessageCount())
+
+	b.drainReceiptWorkers(context.Background())
+
+	require.Zero(t, b.mediaGroups.pending())
+	require.Equal(t, 1, mockBot.SentMessageCount())
+	require.Contains(t, mockBot.LastEditedMessage().Text, "Could not read this receipt",
+		"the album is scanned instead of waiting out its window")
+}
+
+func TestHandlePhotoCore_PerUserReceiptLimit(t *testing.T) {
+	t.Parallel()
+
+	generator := newSlowReceiptGenerator()
+	b := newAsyncReceiptBot(generator, 1)
+	mockBot := mocks.NewMockBot()
+
+	for range maxReceiptsPerUser + 1 {
+		b.handlePhotoCore(context.Background(), mockBot, mocks.PhotoUpdate(12345, 100, testPhotoFileID))
+	}
+
+	require.Equal(t, maxReceiptsPerUser+1, mockBot.SentMessageCount())
+	edited := mockBot.LastEditedMessage()
+	require.Equal(t, testPlaceholderMessageID+maxReceiptsPerUser, edited.MessageID)
+	require.Contains(t, edited.Text, "still being read")
+
+	close(generator.release)
+	b.drainReceiptWorkers(context.Background())
+	require.Equal(t, maxReceiptsPerUser+1, mockBot.EditedMessageCount())
+}
+
+func TestReceiptWorkerPool(t *testing.T) {
+	t.Parallel()
+
+	t.Run("shutdown waits for in-flight jobs", func(t *testing.T) {
+		t.Parallel()
+
+		p := newReceiptWorkerPool(2)
+		started := make(chan struct{})
+		release := make(chan struct{})
+		finished := make(chan struct{})
+		require.NoError(t, p.enqueue(context.Background(), 1, func(context.Context) {
+			close(started)
+			<-release
+			close(finished)
+		}))
+		<-started
+
+		stopped := make(chan struct{})
+		go func() {
+			p.shutdown(context.Background())
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+			t.Fatal("shutdown returned before the job finished")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+		<-stopped
+		select {
+		case <-finished:
+		default:
+			t.Fatal("job did not finish")
+		}
+		require.ErrorIs(t, p.enqueue(context.Background(), 1, func(context.Context) {}), errReceiptPoolClosed)
+	})
+
+	t.Run("drain timeout cancels running jobs", func(t *testing.T) {
+		t.Parallel()
+
+		p := newReceiptWorkerPool(1)
+		started := make(chan struct{})
+		var jobErr error
+		require.NoError(t, p.enqueue(context.Background(), 1, func(ctx context.Context) {
+			close(started)
+			<-ctx.Done()
+			jobErr = ctx.Err()
+		}))
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		p.shutdown(ctx)
+		require.ErrorIs(t, jobErr, context.Canceled)
+	})
+
+	t.Run("jobs outlive the context that queued them", func(t *testing.T) {
+		t.Parallel()
+
+		p := newReceiptWorkerPool(1)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var jobErr error
+		require.NoError(t, p.enqueue(ctx, 1, func(ctx context.Context) {
+			jobErr = ctx.Err()
+		}))
+		p.shutdown(context.Background())
+		require.NoError(t, jobErr)
+	})
+
+	t.Run("per-user limit and full queue", func(t *testing.T) {
+		t.Parallel()
+
+		p := newReceiptWorkerPool(1)
+		release := make(chan struct{})
+		block := func(context.Context) { <-release }
+
+		for range maxReceiptsPerUser {
+			require.NoError(t, p.enqueue(context.Background(), 1, block))
+		}
+		require.ErrorIs(t, p.enqueue(context.Background(), 1, block), errReceiptUserBusy)
+
+		var err error
+		for userID := int64(2); err == nil; userID++ {
+			err = p.enqueue(context.Background(), userID, block)
+		}
+		require.ErrorIs(t, err, errReceiptQueueFull)
+
+		close(release)
+		p.shutdown(context.Background())
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		require.Empty(t, p.perUser, "finished jobs release their slots")
+	})
+}
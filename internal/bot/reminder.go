@@ -3,11 +3,9 @@ package bot
 import (
 	"context"
 	"fmt"
-	"strings"
 	"time"
 
 	tgbot "github.com/go-telegram/bot"
-	tgmodels "github.com/go-telegram/bot/models"
 
 	"gitlab.com/yelinaung/expense-bot/internal/logger"
 	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
@@ -106,21 +104,25 @@ func (b *Bot) checkAndSendReminders(ctx context.Context, reminded map[int64]stri
 		}
 
 		todayStr := userNow.Format("2006-01-02")
-		if reminded[user.ID] == todayStr {
+		if reminded[user.ID] == todayStr || user.LastReminderDate == todayStr {
+			reminded[user.ID] = todayStr
 			continue
 		}
 
 		startOfDay := time.Date(userNow.Year(), userNow.Month(), userNow.Day(), 0, 0, 0, 0, loc)
 		endOfDay := startOfDay.AddDate(0, 0, 1)
 
-		err = b.sendReminderOrDailySummary(checkCtx, user, startOfDay, endOfDay)
+		err = b.sendDailyReminder(checkCtx, user, startOfDay, endOfDay)
 		if err != nil {
-			logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(user.ID)).Msg("Failed to send daily reminder")
+			b.logProactiveSendError(checkCtx, user.ID, "reminder", err, "Failed to send daily reminder")
 			continue
 		}
 
 		reminded[user.ID] = todayStr
-		logger.Log.Debug().Str("user_hash", logger.HashUserID(user.ID)).Str("timezone", loc.String()).Msg("Sent daily reminder")
+		if err := b.userRepo.SetLastReminderDate(checkCtx, user.ID, todayStr); err != nil {
+			logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(user.ID)).Msg("Failed to persist reminder date")
+		}
+		logger.Log.Debug().Str("user_hash", logger.HashUserID(user.ID)).Str("timezone", loc.String()).Msg("Handled daily reminder")
 	}
 
 	if b.metrics != nil {
@@ -143,31 +145,32 @@ func (b *Bot) userLocation(tz string) *time.Location {
 	return loc
 }
 
-func (b *Bot) sendReminderOrDailySummary(
+// sendDailyReminder nudges the user when they have not confirmed any expense
+// between startOfDay and endOfDay. Users who already logged something are
+// skipped, or get a short acknowledgement when ReminderLoggedNote is set.
+func (b *Bot) sendDailyReminder(
 	ctx context.Context,
 	user *appmodels.User,
 	startOfDay, endOfDay time.Time,
 ) error {
-	expenses, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, user.ID, startOfDay, endOfDay)
+	logged, err := b.expenseRepo.HasExpensesForDate(ctx, user.ID, startOfDay, endOfDay)
 	if err != nil {
-		return fmt.Errorf("failed to fetch today's expenses: %w", err)
+		return fmt.Errorf("failed to check today's expenses: %w", err)
 	}
 
-	if len(expenses) == 0 {
+	if !logged {
 		return b.sendNoExpenseReminder(ctx, user)
 	}
+	if !b.cfg.ReminderLoggedNote {
+		logger.Log.Debug().Str("user_hash", logger.HashUserID(user.ID)).Msg("Skipping reminder, expenses already logged")
+		return nil
+	}
 
-	totalsByCurrency := sumExpenseAmountsByCurrency(expenses)
-	currencies := sortedCurrencyKeys(totalsByCurrency)
-	var sb strings.Builder
-	sb.WriteString("\U0001f4c5 <b>Today's Expenses</b>")
-	for _, cur := range currencies {
-		fmt.Fprintf(&sb, "\n  %s: %s%s",
-			escapeHTML(cur),
-			escapeHTML(currencySymbol(cur)),
-			totalsByCurrency[cur].StringFixed(2))
+	count, err := b.expenseRepo.CountExpensesForDate(ctx, user.ID, startOfDay, endOfDay)
+	if err != nil {
+		return fmt.Errorf("failed to count today's expenses: %w", err)
 	}
-	return b.sendTodaySummary(ctx, user.ID, expenses, sb.String())
+	return b.sendLoggedNote(ctx, user.ID, count)
 }
 
 func (b *Bot) sendNoExpenseReminder(ctx context.Context, user *appmodels.User) error {
@@ -191,34 +194,20 @@ func (b *Bot) sendNoExpenseReminder(ctx context.Context, user *appmodels.User) e
 	return nil
 }
 
-func (b *Bot) sendTodaySummary(
-	ctx context.Context,
-	userID int64,
-	expenses []appmodels.Expense,
-	header string,
-) error {
-	expenseIDs := make([]int, len(expenses))
-	for i := range expenses {
-		expenseIDs[i] = expenses[i].ID
-	}
-
-	tagsByExpense := map[int][]appmodels.Tag{}
-	if b.tagRepo != nil {
-		var err error
-		tagsByExpense, err = b.tagRepo.GetByExpenseIDs(ctx, expenseIDs)
-		if err != nil {
-			logger.Log.Warn().Err(err).Msg("Failed to batch-load tags for daily summary")
-		}
+// sendLoggedNote sends the lighter reminder variant for days on which the
+// user has already logged expenses.
+func (b *Bot) sendLoggedNote(ctx context.Context, userID int64, count int) error {
+	noun := "expenses"
+	if count == 1 {
+		noun = "expense"
 	}
 
-	text := b.buildExpenseListMessage(header, expenses, tagsByExpense)
 	_, err := b.messageSender.SendMessage(ctx, &tgbot.SendMessageParams{
-		ChatID:    userID,
-		Text:      text,
-		ParseMode: tgmodels.ParseModeHTML,
+		ChatID: userID,
+		Text:   fmt.Sprintf("✅ Nice, you've logged %d %s today.", count, noun),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to send daily summary: %w", err)
+		return fmt.Errorf("failed to send logged note: %w", err)
 	}
 	return nil
 }
@@ -6,7 +6,6 @@ import (
 	"testing"
 	"time"
 
-	tgmodels "github.com/go-telegram/bot/models"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
@@ -102,7 +101,7 @@ func TestCheckAndSendReminders(t *testing.T) {
 		require.Equal(t, 0, mockBot.SentMessageCount(), "should not send reminder to unapproved user")
 	})
 
-	t.Run("sends summary to user with expenses today", func(t *testing.T) {
+	t.Run("skips user who already logged an expense today", func(t *testing.T) {
 		ctx := context.Background()
 		pool := testDB(ctx, t)
 		b := setupTestBot(t, pool)
@@ -137,16 +136,11 @@ func TestCheckAndSendReminders(t *testing.T) {
 		reminded := make(map[int64]string)
 		b.checkAndSendReminders(ctx, reminded, nowUTC)
 
-		require.Equal(t, 1, mockBot.SentMessageCount(), "should send daily summary to user with expenses")
-		msg := mockBot.LastSentMessage()
-		require.Equal(t, int64(2002), msg.ChatID)
-		require.Contains(t, msg.Text, testTodayExpensesText)
-		require.Contains(t, msg.Text, "Lunch")
-		require.Equal(t, tgmodels.ParseModeHTML, msg.ParseMode)
+		require.Equal(t, 0, mockBot.SentMessageCount(), "should not remind a user who already logged today")
 		require.Equal(t, todayStr, reminded[2002])
 	})
 
-	t.Run("uses per-user timezone day window when deciding summary", func(t *testing.T) {
+	t.Run("sends logged note when enabled using per-user day window", func(t *testing.T) {
 		ctx := context.Background()
 		pool := testDB(ctx, t)
 		b := setupTestBot(t, pool)
@@ -154,6 +148,7 @@ func TestCheckAndSendReminders(t *testing.T) {
 		mockBot := mocks.NewMockBot()
 		b.messageSender = mockBot
 		b.cfg.ReminderHour = 0
+		b.cfg.ReminderLoggedNote = true
 		b.cfg.WhitelistedUserIDs = []int64{2012}
 
 		// 00:30 GMT+8 = 16:30 UTC previous day
@@ -168,71 +163,65 @@ func TestCheckAndSendReminders(t *testing.T) {
 		err = b.userRepo.UpdateTimezone(ctx, 2012, "Etc/GMT-8")
 		require.NoError(t, err)
 
-		expense := &models.Expense{
-			UserID:      2012,
-			Amount:      decimal.NewFromFloat(12.00),
-			Currency:    "SGD",
-			Description: "Breakfast",
-			Status:      models.ExpenseStatusConfirmed,
+		createdAt := []time.Time{
+			time.Date(2026, 2, 10, 16, 45, 0, 0, time.UTC), // 2026-02-11 00:45 GMT+8
+			time.Date(2026, 2, 10, 15, 30, 0, 0, time.UTC), // 2026-02-10 23:30 GMT+8
+		}
+		for _, at := range createdAt {
+			expense := &models.Expense{
+				UserID:      2012,
+				Amount:      decimal.NewFromFloat(12.00),
+				Currency:    "SGD",
+				Description: "Breakfast",
+				Status:      models.ExpenseStatusConfirmed,
+			}
+			err = b.expenseRepo.Create(ctx, expense)
+			require.NoError(t, err)
+			_, err = b.db.Exec(ctx, `UPDATE expenses SET created_at = $2 WHERE id = $1`, expense.ID, at)
+			require.NoError(t, err)
 		}
-		err = b.expenseRepo.Create(ctx, expense)
-		require.NoError(t, err)
-
-		createdAtUTC := time.Date(2026, 2, 10, 16, 45, 0, 0, time.UTC) // 2026-02-11 00:45 GMT+8
-		_, err = b.db.Exec(ctx, `UPDATE expenses SET created_at = $2 WHERE id = $1`, expense.ID, createdAtUTC)
-		require.NoError(t, err)
 
 		reminded := make(map[int64]string)
 		b.checkAndSendReminders(ctx, reminded, nowAtBoundaryUTC)
 
 		require.Equal(t, 1, mockBot.SentMessageCount())
-		msg := mockBot.LastSentMessage()
-		require.Contains(t, msg.Text, testTodayExpensesText)
-		require.Contains(t, msg.Text, "Breakfast")
+		require.Equal(t, "✅ Nice, you've logged 1 expense today.", mockBot.LastSentMessage().Text)
 		expectedDate := nowAtBoundaryUTC.In(loc).Format("2006-01-02")
 		require.Equal(t, expectedDate, reminded[2012])
 	})
 
-	t.Run("sends summary even when tag repository is nil", func(t *testing.T) {
+	t.Run("does not send twice after a restart", func(t *testing.T) {
 		ctx := context.Background()
 		pool := testDB(ctx, t)
 		b := setupTestBot(t, pool)
 		b.displayLocation = loc
 		mockBot := mocks.NewMockBot()
 		b.messageSender = mockBot
-		b.tagRepo = nil
 		b.cfg.ReminderHour = 14
 		b.cfg.WhitelistedUserIDs = []int64{2013}
 
 		err := b.userRepo.UpsertUser(ctx, &models.User{
 			ID:        2013,
-			Username:  "niltagrepo",
+			Username:  "restarted",
 			FirstName: "Nina",
 		})
 		require.NoError(t, err)
 		err = b.userRepo.UpdateTimezone(ctx, 2013, "Etc/GMT-8")
 		require.NoError(t, err)
 
-		expense := &models.Expense{
-			UserID:      2013,
-			Amount:      decimal.NewFromFloat(7.25),
-			Currency:    "SGD",
-			Description: "Tea",
-			Status:      models.ExpenseStatusConfirmed,
-		}
-		err = b.expenseRepo.Create(ctx, expense)
-		require.NoError(t, err)
-		_, err = b.db.Exec(ctx, `UPDATE expenses SET created_at = $2 WHERE id = $1`, expense.ID, nowUTC)
-		require.NoError(t, err)
+		b.checkAndSendReminders(ctx, make(map[int64]string), nowUTC)
+		require.Equal(t, 1, mockBot.SentMessageCount())
 
+		// A fresh map simulates the in-memory state lost on restart; the
+		// persisted marker must still suppress a second reminder.
 		reminded := make(map[int64]string)
-		b.checkAndSendReminders(ctx, reminded, nowUTC)
-
-		require.Equal(t, 1, mockBot.SentMessageCount())
-		msg := mockBot.LastSentMessage()
-		require.Contains(t, msg.Text, testTodayExpensesText)
-		require.Contains(t, msg.Text, "Tea")
+		b.checkAndSendReminders(ctx, reminded, nowUTC.Add(20*time.Minute))
+		require.Equal(t, 1, mockBot.SentMessageCount(), "should not remind again after restart")
 		require.Equal(t, todayStr, reminded[2013])
+
+		// The next day the reminder fires again.
+		b.checkAndSendReminders(ctx, reminded, nowUTC.AddDate(0, 0, 1))
+		require.Equal(t, 2, mockBot.SentMessageCount())
 	})
 
 	t.Run("skips user already reminded today", func(t *testing.T) {
@@ -466,7 +455,7 @@ func TestStartDailyReminderLoop_RunsImmediateCheck(t *testing.T) {
 	require.Equal(t, 1, mockBot.SentMessageCount(), "should send reminder on immediate startup check")
 }
 
-func TestSendReminderOrDailySummary_FetchError(t *testing.T) {
+func TestSendDailyReminder_CheckError(t *testing.T) {
 	ctx := context.Background()
 	pool := testDB(ctx, t)
 	b := setupTestBot(t, pool)
@@ -474,14 +463,25 @@ func TestSendReminderOrDailySummary_FetchError(t *testing.T) {
 	canceledCtx, cancel := context.WithCancel(ctx)
 	cancel()
 
-	err := b.sendReminderOrDailySummary(
+	err := b.sendDailyReminder(
 		canceledCtx,
 		&models.User{ID: 2200, FirstName: "Err"},
 		time.Now().Add(-time.Hour),
 		time.Now(),
 	)
 	require.Error(t, err)
-	require.Contains(t, err.Error(), "failed to fetch today's expenses")
+	require.Contains(t, err.Error(), "failed to check today's expenses")
+}
+
+func TestSendLoggedNote(t *testing.T) {
+	t.Parallel()
+
+	mockBot := mocks.NewMockBot()
+	b := &Bot{messageSender: mockBot}
+
+	require.NoError(t, b.sendLoggedNote(context.Background(), 2400, 3))
+	require.Equal(t, "✅ Nice, you've logged 3 expenses today.", mockBot.LastSentMessage().Text)
+	require.Equal(t, int64(2400), mockBot.LastSentMessage().ChatID)
 }
 
 func TestSendNoExpenseReminder_EmptyFirstNameFallback(t *testing.T) {
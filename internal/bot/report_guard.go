@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// reportGuardTimeout is how long a report may hold its user's guard.
+	// A run older than this is taken to be stuck and no longer blocks the
+	// next one.
+	reportGuardTimeout = 2 * time.Minute
+
+	reportBusyMsg = "⏳ Still working on your previous report…"
+)
+
+// reportRun is the report a user has running, told apart from later runs
+// by its token.
+type reportRun struct {
+	token   uint64
+	started time.Time
+}
+
+// reportGuard lets each user run one heavy report (/report, /chart,
+// /export, /statement) at a time. The zero value is ready to use.
+type reportGuard struct {
+	mu      sync.Mutex
+	running map[int64]reportRun
+	next    uint64
+}
+
+// acquire claims userID's guard at now. It returns a token for release,
+// or false when another run holds it and has not timed out.
+func (g *reportGuard) acquire(userID int64, now time.Time) (uint64, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if run, ok := g.running[userID]; ok && now.Sub(run.started) < reportGuardTimeout {
+		return 0, false
+	}
+	if g.running == nil {
+		g.running = make(map[int64]reportRun)
+	}
+	g.next++
+	g.running[userID] = reportRun{token: g.next, started: now}
+	return g.next, true
+}
+
+// release frees userID's guard if token still holds it, so a run that
+// finishes after timing out does not free a newer run's guard.
+func (g *reportGuard) release(userID int64, token uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.running[userID].token == token {
+		delete(g.running, userID)
+	}
+}
+
+// beginReport claims the report guard for the sender of message. It
+// returns the function that frees it, or tells the user to wait and
+// returns false when their previous report is still running. command names
+// the report for the rejection metric.
+func (b *Bot) beginReport(
+	ctx context.Context,
+	tg TelegramAPI,
+	message *models.Message,
+	command string,
+) (func(), bool) {
+	userID := message.From.ID
+	token, ok := b.reportGuard.acquire(userID, b.now())
+	if !ok {
+		logger.Log.Info().
+			Str("user_hash", logger.HashUserID(userID)).
+			Str("command", command).
+			Msg("Report already running, rejecting duplicate")
+		if b.metrics != nil {
+			b.metrics.ReportsRejected.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("command", command)))
+		}
+		_, _ = tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: message.Chat.ID,
+			Text:   reportBusyMsg,
+		})
+		return nil, false
+	}
+	return func() { b.reportGuard.release(userID, token) }, true
+}
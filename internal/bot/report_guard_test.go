@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestReportGuard(t *testing.T) {
+	t.Parallel()
+
+	var guard reportGuard
+	now := time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC)
+
+	first, ok := guard.acquire(1, now)
+	require.True(t, ok)
+	_, ok = guard.acquire(1, now.Add(time.Second))
+	require.False(t, ok, "a second run for the same user is rejected")
+	other, ok := guard.acquire(2, now)
+	require.True(t, ok, "other users are not affected")
+	guard.release(2, other)
+
+	guard.release(1, first)
+	second, ok := guard.acquire(1, now.Add(2*time.Second))
+	require.True(t, ok, "the guard clears on release")
+
+	third, ok := guard.acquire(1, now.Add(2*time.Second+reportGuardTimeout))
+	require.True(t, ok, "a run past the timeout no longer blocks")
+	guard.release(1, second)
+	_, ok = guard.acquire(1, now.Add(3*time.Second+reportGuardTimeout))
+	require.False(t, ok, "the timed out run must not free the newer one")
+	guard.release(1, third)
+	require.Empty(t, guard.running)
+}
+
+func TestReportGuardConcurrent(t *testing.T) {
+	t.Parallel()
+
+	var guard reportGuard
+	now := time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC)
+	var acquired atomic.Int32
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			if _, ok := guard.acquire(7, now); ok {
+				acquired.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), acquired.Load())
+}
+
+func TestBeginReport(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC)
+	var offset atomic.Int64
+	b := &Bot{nowFunc: func() time.Time { return start.Add(time.Duration(offset.Load())) }}
+	ctx := context.Background()
+	mockBot := mocks.NewMockBot()
+	msg := mocks.CommandUpdate(12345, 12345, "/report week").Message
+
+	release, ok := b.beginReport(ctx, mockBot, msg, "report")
+	require.True(t, ok)
+	require.Zero(t, mockBot.SentMessageCount())
+
+	_, ok = b.beginReport(ctx, mockBot, msg, "chart")
+	require.False(t, ok)
+	require.Equal(t, reportBusyMsg, mockBot.LastSentMessage().Text)
+
+	release()
+	again, ok := b.beginReport(ctx, mockBot, msg, "report")
+	require.True(t, ok, "the guard clears on completion")
+
+	offset.Store(int64(reportGuardTimeout))
+	forced, ok := b.beginReport(ctx, mockBot, msg, "export")
+	require.True(t, ok, "a stuck report is taken over after the timeout")
+	again()
+	forced()
+	require.Equal(t, 1, mockBot.SentMessageCount())
+}
+
+func TestHandleChartCoreRejectsWhileRunning(t *testing.T) {
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+
+	userID := int64(900047)
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &appmodels.User{ID: userID, Username: "guarduser"}))
+	createHabitTestExpense(t, ctx, b, userID, "Lunch", "12.00", time.Now())
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	var calls atomic.Int32
+	b.chartGenerator = func([]appmodels.Expense, string) ([]byte, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-unblock
+		}
+		return []byte("png"), nil
+	}
+
+	mockBot := mocks.NewMockBot()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.handleChartCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/chart month"))
+	}()
+	<-started
+
+	b.handleChartCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/chart week"))
+	require.Equal(t, reportBusyMsg, mockBot.LastSentMessage().Text)
+	require.Zero(t, mockBot.SentDocumentCount())
+
+	close(unblock)
+	<-done
+	require.Equal(t, 1, mockBot.SentDocumentCount())
+
+	b.handleChartCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/chart week"))
+	require.Equal(t, 2, mockBot.SentDocumentCount(), "the guard clears after completion")
+}
@@ -0,0 +1,239 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// SheetExportCheckInterval is how often the sheet export loop runs.
+	SheetExportCheckInterval = 30 * time.Minute
+	// SheetExportTimeout is the maximum time a single check can take.
+	SheetExportTimeout = 2 * time.Minute
+	// sheetExportMaxRows caps the rows of one daily file. Any rest is sent
+	// the next day.
+	sheetExportMaxRows = 1000
+
+	sheetExportDayLayout = "2006-01-02"
+	// sheetChecksumLabel starts the last line of every file, which counts
+	// the rows sent so far so a missing file shows up as a gap.
+	sheetChecksumLabel = "Checksum"
+)
+
+// sheetExportHeader is the fixed column order of sheet export files.
+var sheetExportHeader = []string{
+	csvHeaderID,
+	csvHeaderDate,
+	csvHeaderAmount,
+	csvHeaderCurrency,
+	csvHeaderDescription,
+	csvHeaderMerchant,
+	csvHeaderCategory,
+}
+
+// generateSheetExportCSV writes expenses as rows to append to a
+// spreadsheet: ISO dates in loc and plain amounts in the currency's minor
+// units, with the header only
+// when sentBefore is zero. The last line reads e.g. "Checksum,rows 41-45,45"
+// from the rows sent before.
+func generateSheetExportCSV(expenses []appmodels.Expense, loc *time.Location, sentBefore int64) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	rows := make([][]string, 0, len(expenses)+2)
+	if sentBefore == 0 {
+		rows = append(rows, sheetExportHeader)
+	}
+	for i := range expenses {
+		e := &expenses[i]
+		rows = append(rows, []string{
+			strconv.FormatInt(e.UserExpenseNumber, 10),
+			e.CreatedAt.In(loc).Format(sheetExportDayLayout),
+			botfmt.FormatAmount(e.Amount, e.Currency),
+			e.Currency,
+			sanitizeCSVCell(e.Description),
+			sanitizeCSVCell(e.Merchant),
+			sanitizeCSVCell(csvCategoryName(e)),
+		})
+	}
+	total := sentBefore + int64(len(expenses))
+	rows = append(rows, []string{
+		sheetChecksumLabel,
+		fmt.Sprintf("rows %d-%d", sentBefore+1, total),
+		strconv.FormatInt(total, 10),
+	})
+
+	if err := writer.WriteAll(rows); err != nil {
+		return nil, fmt.Errorf("failed to write sheet export: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// startSheetExportLoop runs a periodic loop that sends each opted-in user
+// yesterday's new expenses once a day.
+func (b *Bot) startSheetExportLoop(ctx context.Context) {
+	logger.Log.Info().Msg("Sheet export loop started (per-user timezone)")
+
+	ticker := time.NewTicker(SheetExportCheckInterval)
+	defer ticker.Stop()
+
+	select {
+	case <-ctx.Done():
+		logger.Log.Info().Msg("Sheet export loop stopped")
+		return
+	default:
+	}
+
+	b.checkAndSendSheetExports(ctx, b.now())
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Log.Info().Msg("Sheet export loop stopped")
+			return
+		case <-ticker.C:
+			b.checkAndSendSheetExports(ctx, b.now())
+		}
+	}
+}
+
+// checkAndSendSheetExports runs the daily export of every authorized user
+// that turned it on.
+func (b *Bot) checkAndSendSheetExports(ctx context.Context, now time.Time) {
+	ctx, span := otel.Tracer("expense-bot/background").Start(ctx, "background.sheet_export_check")
+	defer span.End()
+	start := time.Now()
+
+	checkCtx, cancel := context.WithTimeout(ctx, SheetExportTimeout)
+	defer cancel()
+
+	exports, err := b.sheetExportRepo.ListEnabled(checkCtx, b.cfg.WhitelistedUserIDs, b.cfg.WhitelistedUsernames)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to fetch users for sheet export")
+		b.recordSheetExportMetrics(ctx, start, backgroundJobStatusError)
+		return
+	}
+
+	for i := range exports {
+		b.processSheetExportUser(checkCtx, &exports[i], now)
+	}
+
+	b.recordSheetExportMetrics(ctx, start, backgroundJobStatusOK)
+}
+
+// processSheetExportUser sends the user's confirmed expenses created
+// before today that were not sent yet, once per day in their timezone, and
+// marks them as sent. Expenses confirmed or imported late go out the day
+// after. Days without new expenses send nothing. A failed send marks
+// nothing, so the rows go out with the next check.
+func (b *Bot) processSheetExportUser(ctx context.Context, export *repository.SheetExportUser, now time.Time) {
+	loc := b.userLocation(export.User.Timezone)
+	userNow := now.In(loc)
+	today := time.Date(userNow.Year(), userNow.Month(), userNow.Day(), 0, 0, 0, 0, loc)
+	dayKey := today.Format(sheetExportDayLayout)
+	if export.SentOn == dayKey {
+		return
+	}
+
+	expenses, err := b.expenseRepo.GetSheetExportPending(ctx, export.UserID, export.LastExpenseID, today, sheetExportMaxRows)
+	if err != nil {
+		logger.Log.Warn().Err(err).
+			Str("user_hash", logger.HashUserID(export.UserID)).
+			Msg("Failed to fetch expenses for sheet export")
+		return
+	}
+
+	if len(expenses) > 0 {
+		if err := b.sendSheetExport(ctx, export, expenses, today.AddDate(0, 0, -1), loc); err != nil {
+			if export.User.ArchiveChatID == 0 {
+				b.logProactiveSendError(ctx, export.UserID, "sheet_export", err, "Failed to send sheet export")
+				return
+			}
+			logger.Log.Warn().Err(err).
+				Str("user_hash", logger.HashUserID(export.UserID)).
+				Msg("Failed to send sheet export to archive chat")
+			return
+		}
+	}
+
+	ids := make([]int, len(expenses))
+	for i := range expenses {
+		ids[i] = expenses[i].ID
+	}
+	if err := b.sheetExportRepo.Advance(ctx, export.UserID, ids, dayKey); err != nil {
+		logger.Log.Warn().Err(err).
+			Str("user_hash", logger.HashUserID(export.UserID)).
+			Msg("Failed to record sheet export")
+	}
+	logger.Log.Debug().
+		Str("user_hash", logger.HashUserID(export.UserID)).
+		Int("expense_count", len(expenses)).
+		Msg("Sheet export handled")
+}
+
+// sendSheetExport sends the rows to the user's archive chat, or to the
+// user when they have none.
+func (b *Bot) sendSheetExport(
+	ctx context.Context,
+	export *repository.SheetExportUser,
+	expenses []appmodels.Expense,
+	day time.Time,
+	loc *time.Location,
+) error {
+	data, err := generateSheetExportCSV(expenses, loc, export.RowCount)
+	if err != nil {
+		return err
+	}
+
+	chatID := export.UserID
+	caption := fmt.Sprintf("📤 Sheet export · %s · %d row(s), %d in total",
+		day.Format("Jan 2, 2006"), len(expenses), export.RowCount+int64(len(expenses)))
+	if export.User.ArchiveChatID != 0 {
+		chatID = export.User.ArchiveChatID
+		caption = archiveUserLabel(&export.User) + " · " + caption
+	}
+
+	_, err = b.messageSender.SendDocument(ctx, &tgbot.SendDocumentParams{
+		ChatID: chatID,
+		Document: &tgmodels.InputFileUpload{
+			Filename: fmt.Sprintf("expenses_sheet_%s.csv", day.Format(sheetExportDayLayout)),
+			Data:     bytes.NewReader(data),
+		},
+		Caption:   caption,
+		ParseMode: tgmodels.ParseModeHTML,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send sheet export: %w", err)
+	}
+	return nil
+}
+
+// recordSheetExportMetrics records background job metrics for the sheet
+// export run.
+func (b *Bot) recordSheetExportMetrics(ctx context.Context, start time.Time, status string) {
+	if b.metrics == nil {
+		return
+	}
+	b.metrics.BackgroundJobRuns.Add(ctx, 1, otelmetric.WithAttributes(
+		attribute.String("job", "sheet_export"),
+		attribute.String("status", status),
+	))
+	b.metrics.BackgroundJobDuration.Record(ctx, time.Since(start).Seconds(),
+		otelmetric.WithAttributes(attribute.String("job", "sheet_export")))
+}
@@ -0,0 +1,195 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestGenerateSheetExportCSV(t *testing.T) {
+	t.Parallel()
+
+	loc := time.FixedZone("SGT", 8*60*60)
+	expenses := []models.Expense{
+		{
+			UserExpenseNumber: 7,
+			Amount:            mustParseDecimal("5.5"),
+			Currency:          currencyCodeSGD,
+			Description:       "=Coffee",
+			CreatedAt:         time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC),
+		},
+	}
+
+	first, err := generateSheetExportCSV(expenses, loc, 0)
+	require.NoError(t, err)
+	require.Equal(t, "ID,Date,Amount,Currency,Description,Merchant,Category\n"+
+		"7,2026-03-02,5.50,SGD,'=Coffee,,Uncategorized\n"+
+		"Checksum,rows 1-1,1\n", string(first))
+
+	later, err := generateSheetExportCSV(expenses, loc, 40)
+	require.NoError(t, err)
+	require.Equal(t, "7,2026-03-02,5.50,SGD,'=Coffee,,Uncategorized\n"+
+		"Checksum,rows 41-41,41\n", string(later), "no header after the first export")
+
+	expenses[0].Amount = mustParseDecimal("1200")
+	expenses[0].Currency = "JPY"
+	yen, err := generateSheetExportCSV(expenses, loc, 1)
+	require.NoError(t, err)
+	require.Contains(t, string(yen), "7,2026-03-02,1200,JPY,", "amounts use the currency's minor units")
+}
+
+func TestCheckAndSendSheetExports(t *testing.T) {
+	// 2026-10-02 08:00 GMT+8 = 00:00 UTC.
+	day := time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)
+
+	setup := func(t *testing.T, userID int64) (*Bot, *mocks.MockBot) {
+		t.Helper()
+		ctx := context.Background()
+		pool := testDB(ctx, t)
+		b := setupTestBot(t, pool)
+		mockBot := mocks.NewMockBot()
+		b.messageSender = mockBot
+		b.cfg.WhitelistedUserIDs = []int64{userID}
+
+		require.NoError(t, b.userRepo.UpsertUser(ctx, &models.User{ID: userID, FirstName: "Sheet"}))
+		require.NoError(t, b.userRepo.UpdateTimezone(ctx, userID, "Etc/GMT-8"))
+		require.NoError(t, b.sheetExportRepo.Enable(ctx, userID, 0))
+		return b, mockBot
+	}
+	addExpense := func(t *testing.T, b *Bot, userID int64, desc string, at time.Time) *models.Expense {
+		t.Helper()
+		ctx := context.Background()
+		expense := &models.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal("4.20"),
+			Currency:    currencyCodeSGD,
+			Description: desc,
+			Status:      models.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, expense))
+		_, err := b.db.Exec(ctx, testUpdateExpenseTimeSQL, at, expense.ID)
+		require.NoError(t, err)
+		return expense
+	}
+
+	t.Run("each expense is sent once and the header once", func(t *testing.T) {
+		ctx := context.Background()
+		userID := int64(434101)
+		b, mockBot := setup(t, userID)
+		addExpense(t, b, userID, "Yesterday lunch", day.Add(-12*time.Hour))
+		addExpense(t, b, userID, "Today coffee", day.Add(time.Hour))
+
+		b.checkAndSendSheetExports(ctx, day)
+		require.Equal(t, 1, mockBot.SentDocumentCount())
+		doc := mockBot.LastSentDocument()
+		require.Equal(t, userID, doc.ChatID)
+		require.Equal(t, "expenses_sheet_2026-10-01.csv", doc.Filename)
+		data := string(doc.Data)
+		require.True(t, strings.HasPrefix(data, "ID,Date,"))
+		require.Contains(t, data, "Yesterday lunch")
+		require.NotContains(t, data, "Today coffee", "today's expenses wait for tomorrow")
+		require.Contains(t, data, "Checksum,rows 1-1,1")
+
+		export, err := b.sheetExportRepo.Get(ctx, userID)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), export.RowCount)
+
+		// A restart later the same day sends nothing again.
+		b.checkAndSendSheetExports(ctx, day.Add(time.Hour))
+		require.Equal(t, 1, mockBot.SentDocumentCount())
+
+		b.checkAndSendSheetExports(ctx, day.AddDate(0, 0, 1))
+		require.Equal(t, 2, mockBot.SentDocumentCount())
+		data = string(mockBot.LastSentDocument().Data)
+		require.False(t, strings.HasPrefix(data, "ID,Date,"), "no header after the first export")
+		require.Contains(t, data, "Today coffee")
+		require.NotContains(t, data, "Yesterday lunch")
+		require.Contains(t, data, "Checksum,rows 2-2,2")
+	})
+
+	t.Run("an expense confirmed after a later one was sent still goes out", func(t *testing.T) {
+		ctx := context.Background()
+		userID := int64(434105)
+		b, mockBot := setup(t, userID)
+		draft := &models.Expense{
+			UserID:      userID,
+			Amount:      mustParseDecimal("1500"),
+			Currency:    "JPY",
+			Description: "Late receipt",
+			Status:      models.ExpenseStatusDraft,
+		}
+		require.NoError(t, b.expenseRepo.Create(ctx, draft))
+		_, err := b.db.Exec(ctx, testUpdateExpenseTimeSQL, day.Add(-13*time.Hour), draft.ID)
+		require.NoError(t, err)
+		addExpense(t, b, userID, "Typed lunch", day.Add(-12*time.Hour))
+
+		b.checkAndSendSheetExports(ctx, day)
+		data := string(mockBot.LastSentDocument().Data)
+		require.Contains(t, data, "Typed lunch")
+		require.NotContains(t, data, "Late receipt")
+
+		draft.Status = models.ExpenseStatusConfirmed
+		require.NoError(t, b.expenseRepo.Update(ctx, draft))
+
+		b.checkAndSendSheetExports(ctx, day.AddDate(0, 0, 1))
+		require.Equal(t, 2, mockBot.SentDocumentCount())
+		data = string(mockBot.LastSentDocument().Data)
+		require.Contains(t, data, ",1500,JPY,Late receipt,")
+		require.NotContains(t, data, "Typed lunch")
+	})
+
+	t.Run("a day without new expenses sends nothing", func(t *testing.T) {
+		ctx := context.Background()
+		userID := int64(434102)
+		b, mockBot := setup(t, userID)
+
+		b.checkAndSendSheetExports(ctx, day)
+		require.Zero(t, mockBot.SentDocumentCount())
+		require.Zero(t, mockBot.SentMessageCount())
+
+		export, err := b.sheetExportRepo.Get(ctx, userID)
+		require.NoError(t, err)
+		require.Equal(t, "2026-10-02", export.SentOn)
+		require.Zero(t, export.RowCount)
+	})
+
+	t.Run("a failed send marks nothing as sent", func(t *testing.T) {
+		ctx := context.Background()
+		userID := int64(434103)
+		b, mockBot := setup(t, userID)
+		addExpense(t, b, userID, "Groceries", day.Add(-12*time.Hour))
+		mockBot.SendDocumentError = errors.New("network down")
+
+		b.checkAndSendSheetExports(ctx, day)
+		export, err := b.sheetExportRepo.Get(ctx, userID)
+		require.NoError(t, err)
+		require.Zero(t, export.RowCount)
+		require.Empty(t, export.SentOn)
+
+		mockBot.SendDocumentError = nil
+		b.checkAndSendSheetExports(ctx, day.Add(30*time.Minute))
+		require.Contains(t, string(mockBot.LastSentDocument().Data), "Groceries")
+	})
+
+	t.Run("turning it on skips earlier expenses", func(t *testing.T) {
+		ctx := context.Background()
+		userID := int64(434104)
+		b, mockBot := setup(t, userID)
+		_, err := b.sheetExportRepo.Disable(ctx, userID)
+		require.NoError(t, err)
+		addExpense(t, b, userID, "Old expense", day.Add(-12*time.Hour))
+
+		b.handleSheetExportCore(ctx, mockBot, mocks.CommandUpdate(userID, userID, "/sheetexport on"))
+		require.Contains(t, mockBot.LastSentMessage().Text, "Daily sheet export is on")
+
+		b.checkAndSendSheetExports(ctx, day)
+		require.Zero(t, mockBot.SentDocumentCount())
+	})
+}
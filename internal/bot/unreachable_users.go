@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"context"
+	"errors"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+)
+
+// botBlockedByUser reports whether a send to a user's private chat failed
+// because they blocked the bot or deleted their account.
+func botBlockedByUser(err error) bool {
+	return errors.Is(err, tgbot.ErrorForbidden)
+}
+
+// markUserUnreachable pauses scheduled messages to a user who blocked the
+// bot. source names what noticed it, for the metric.
+func (b *Bot) markUserUnreachable(ctx context.Context, userID int64, source string) {
+	changed, err := b.userRepo.MarkUnreachable(ctx, userID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to mark user unreachable")
+		return
+	}
+	if !changed {
+		return
+	}
+	if b.metrics != nil {
+		b.metrics.BotBlocked.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("source", source)))
+	}
+	logger.Log.Info().Str("user_hash", logger.HashUserID(userID)).Str("source", source).
+		Msg("User blocked the bot; pausing scheduled messages")
+}
+
+// logProactiveSendError handles a failed scheduled send to a user. A block
+// marks the user unreachable instead of being logged as a failure, so the
+// logs do not fill up with it every run.
+func (b *Bot) logProactiveSendError(ctx context.Context, userID int64, job string, err error, msg string) {
+	if botBlockedByUser(err) {
+		b.markUserUnreachable(ctx, userID, job)
+		return
+	}
+	logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg(msg)
+}
+
+// handlePrivateChatMember tracks a user blocking or unblocking the bot in
+// their private chat.
+func (b *Bot) handlePrivateChatMember(ctx context.Context, change *models.ChatMemberUpdated) {
+	userID := change.Chat.ID
+	switch {
+	case botRemoved(change):
+		b.markUserUnreachable(ctx, userID, "my_chat_member")
+	case botJoined(change):
+		b.markUserReachable(ctx, userID)
+	}
+}
+
+// markUserReachable resumes scheduled messages to a user who was marked
+// unreachable.
+func (b *Bot) markUserReachable(ctx context.Context, userID int64) {
+	changed, err := b.userRepo.MarkReachable(ctx, userID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to mark user reachable")
+		return
+	}
+	if changed {
+		logger.Log.Info().Str("user_hash", logger.HashUserID(userID)).
+			Msg("User unblocked the bot; resuming scheduled messages")
+	}
+}
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/bot/mocks"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestBotBlockedByUser(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, botBlockedByUser(fmt.Errorf("failed to send reminder: %w",
+		fmt.Errorf("%w, bot was blocked by the user", tgbot.ErrorForbidden))))
+	require.False(t, botBlockedByUser(tgbot.ErrorTooManyRequests))
+	require.False(t, botBlockedByUser(errors.New("connection reset")))
+}
+
+func TestUnreachableUsers(t *testing.T) {
+	const userID = int64(430101)
+	// 14:30 in GMT+8 = 06:30 UTC.
+	nowUTC := time.Date(2026, 2, 11, 6, 30, 0, 0, time.UTC)
+
+	ctx := context.Background()
+	pool := testDB(ctx, t)
+	b := setupTestBot(t, pool)
+	mockBot := mocks.NewMockBot()
+	b.messageSender = mockBot
+	b.cfg.ReminderHour = 14
+	b.cfg.WhitelistedUserIDs = []int64{userID}
+
+	require.NoError(t, b.userRepo.UpsertUser(ctx, &models.User{ID: userID, Username: "blocker", FirstName: "Alice"}))
+	require.NoError(t, b.userRepo.UpdateTimezone(ctx, userID, "Etc/GMT-8"))
+
+	unreachable := func() bool {
+		marked, err := b.userRepo.IsUnreachable(ctx, userID)
+		require.NoError(t, err)
+		return marked
+	}
+	remind := func(day int) {
+		b.checkAndSendReminders(ctx, map[int64]string{}, nowUTC.AddDate(0, 0, day))
+	}
+
+	t.Run("a blocked reminder marks the user", func(t *testing.T) {
+		mockBot.SendMessageError = fmt.Errorf("%w, bot was blocked by the user", tgbot.ErrorForbidden)
+		remind(0)
+		require.True(t, unreachable())
+	})
+
+	t.Run("marked users are skipped", func(t *testing.T) {
+		mockBot.Reset()
+		remind(1)
+		require.Zero(t, mockBot.SentMessageCount())
+	})
+
+	t.Run("a message from the user resumes reminders", func(t *testing.T) {
+		require.NoError(t, b.ensureUserRegistered(ctx, mocks.CommandUpdate(userID, userID, "/start")))
+		require.False(t, unreachable())
+
+		remind(2)
+		require.Equal(t, 1, mockBot.SentMessageCount())
+	})
+
+	t.Run("other send failures do not mark the user", func(t *testing.T) {
+		mockBot.SendMessageError = errors.New("connection reset")
+		remind(3)
+		require.False(t, unreachable())
+		mockBot.SendMessageError = nil
+	})
+
+	t.Run("blocking and unblocking in the private chat", func(t *testing.T) {
+		update := myChatMemberUpdate(userID, userID, tgmodels.ChatMemberTypeMember, tgmodels.ChatMemberTypeBanned)
+		update.MyChatMember.Chat.Type = tgmodels.ChatTypePrivate
+		b.handleMyChatMemberCore(ctx, mockBot, update)
+		require.True(t, unreachable())
+
+		update = myChatMemberUpdate(userID, userID, tgmodels.ChatMemberTypeBanned, tgmodels.ChatMemberTypeMember)
+		update.MyChatMember.Chat.Type = tgmodels.ChatTypePrivate
+		b.handleMyChatMemberCore(ctx, mockBot, update)
+		require.False(t, unreachable())
+	})
+}
@@ -11,6 +11,7 @@ import (
 	tgmodels "github.com/go-telegram/bot/models"
 	"github.com/shopspring/decimal"
 
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
 	"gitlab.com/yelinaung/expense-bot/internal/logger"
 	appmodels "gitlab.com/yelinaung/expense-bot/internal/models"
 
@@ -136,11 +137,9 @@ func (b *Bot) processWeeklyReportUser(
 		return
 	}
 
-	expenseCount, err := b.sendWeeklySummary(ctx, user, userNow)
+	expenseCount, queued, err := b.queueWeeklySummary(ctx, user, userNow)
 	if err != nil {
-		logger.Log.Warn().Err(err).
-			Str("user_hash", logger.HashUserID(user.ID)).
-			Msg("Failed to send weekly report")
+		logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(user.ID)).Msg("Failed to queue weekly report")
 		return
 	}
 	if expenseCount == 0 {
@@ -151,14 +150,38 @@ func (b *Bot) processWeeklyReportUser(
 	}
 
 	sent[user.ID] = weekKey
+	if !queued {
+		// An earlier run, e.g. before a restart, already handled this
+		// week; the outbox loop delivers whatever it left undelivered.
+		return
+	}
+	// Deliver now rather than on the outbox loop's next tick, so the
+	// summary arrives ahead of the follow-ups below.
+	b.deliverOutbox(ctx)
 	logger.Log.Debug().
 		Str("user_hash", logger.HashUserID(user.ID)).
 		Str("timezone", loc.String()).
-		Msg("Sent weekly report")
+		Msg("Queued weekly report")
 
 	if b.cfg.WeeklyHabitRecapEnabled {
 		b.sendWeeklyHabitRecapForUser(ctx, user, userNow, expenseCount)
 	}
+
+	// Best-effort, like the habit recap: a failure never re-sends the summary.
+	queued, err = b.queueUncategorizedWarning(ctx, user.ID, weekKey)
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(user.ID)).
+			Msg("Failed to queue uncategorized warning")
+	}
+	if queued {
+		b.deliverOutbox(ctx)
+	}
+
+	if b.cfg.WeeklyAIReviewEnabled {
+		if _, err := b.sendAIReviewDigest(ctx, user.ID); err != nil {
+			b.logProactiveSendError(ctx, user.ID, "ai_review_digest", err, "Failed to send AI review digest")
+		}
+	}
 }
 
 // sendWeeklyHabitRecapForUser sends the habit recap best-effort after
@@ -173,9 +196,7 @@ func (b *Bot) sendWeeklyHabitRecapForUser(
 	start := time.Now()
 	recapSent, err := b.sendWeeklyHabitRecap(ctx, user, userNow, totalCount)
 	if err != nil {
-		logger.Log.Warn().Err(err).
-			Str("user_hash", logger.HashUserID(user.ID)).
-			Msg("Failed to send weekly habit recap")
+		b.logProactiveSendError(ctx, user.ID, "weekly_habit_recap", err, "Failed to send weekly habit recap")
 		b.recordHabitRecapMetrics(ctx, start, backgroundJobStatusError)
 		return
 	}
@@ -219,39 +240,40 @@ func (b *Bot) recordWeeklyReportMetrics(ctx context.Context, start time.Time, st
 		otelmetric.WithAttributes(attribute.String("job", "weekly_report")))
 }
 
-// sendWeeklySummary sends a weekly expense summary to the user. It
-// returns the number of expenses in the previous week; a message is
-// only sent when the count is non-zero, so 0 means nothing was sent.
-func (b *Bot) sendWeeklySummary(
+// queueWeeklySummary writes the user's weekly expense summary to the
+// outbox. It returns the number of expenses in the previous week, where 0
+// means nothing was queued, and whether the summary was queued now rather
+// than by an earlier run for the same week.
+func (b *Bot) queueWeeklySummary(
 	ctx context.Context,
 	user *appmodels.User,
 	userNow time.Time,
-) (int, error) {
+) (int, bool, error) {
 	startOfWeek, endOfWeek := getPreviousWeekRangeAt(userNow)
 
 	expenses, err := b.expenseRepo.GetByUserIDAndDateRange(ctx, user.ID, startOfWeek, endOfWeek)
 	if err != nil {
-		return 0, fmt.Errorf("failed to fetch weekly expenses: %w", err)
+		return 0, false, fmt.Errorf("failed to fetch weekly expenses: %w", err)
 	}
 
 	if len(expenses) == 0 {
-		return 0, nil
+		return 0, false, nil
 	}
 
+	b.applyLiveConversions(ctx, user.ID, expenses)
 	totalsByCurrency := sumExpenseAmountsByCurrency(expenses)
 	currencies := sortedCurrencyKeys(totalsByCurrency)
 	var sb strings.Builder
 	fmt.Fprintf(
-		&sb, "📊 <b>Weekly Expenses</b> (%s to %s)\n%d expenses",
-		startOfWeek.Format("Jan 2"),
-		endOfWeek.AddDate(0, 0, -1).Format("Jan 2, 2006"),
+		&sb, "📊 <b>Weekly Expenses</b> (%s)\n%d expenses",
+		formatWeekLabelWithYear(startOfWeek, endOfWeek, userNow.Location()),
 		len(expenses),
 	)
 	for _, cur := range currencies {
 		fmt.Fprintf(&sb, "\n  %s: %s%s",
-			escapeHTML(cur),
-			escapeHTML(currencySymbol(cur)),
-			totalsByCurrency[cur].StringFixed(2))
+			botfmt.EscapeHTML(cur),
+			botfmt.EscapeHTML(botfmt.CurrencySymbol(cur)),
+			botfmt.FormatAmount(totalsByCurrency[cur], cur))
 	}
 	header := sb.String()
 
@@ -269,16 +291,16 @@ func (b *Bot) sendWeeklySummary(
 		}
 	}
 
-	text := b.buildExpenseListMessage(header, expenses, tagsByExpense)
-	_, err = b.messageSender.SendMessage(ctx, &tgbot.SendMessageParams{
+	text := b.expenseListMessage(ctx, user.ID, header, expenses, tagsByExpense)
+	queued, err := b.queueMessage(ctx, b.outboxRepo, &tgbot.SendMessageParams{
 		ChatID:    user.ID,
 		Text:      text,
 		ParseMode: tgmodels.ParseModeHTML,
-	})
+	}, outboxDedupeKey("weekly_report", user.ID, startOfWeek.Format("2006-01-02")))
 	if err != nil {
-		return 0, fmt.Errorf("failed to send weekly summary: %w", err)
+		return 0, false, err
 	}
-	return len(expenses), nil
+	return len(expenses), queued, nil
 }
 
 // sendWeeklyHabitRecap sends the previous week's spending reflection
@@ -321,23 +343,16 @@ func (b *Bot) sendWeeklyHabitRecap(
 }
 
 // sumExpenseAmountsByCurrency returns expense totals grouped by currency.
+// Expenses with a recorded conversion count in the converted currency.
 func sumExpenseAmountsByCurrency(expenses []appmodels.Expense) map[string]decimal.Decimal {
 	totals := make(map[string]decimal.Decimal)
 	for i := range expenses {
-		e := expenses[i]
-		totals[e.Currency] = totals[e.Currency].Add(e.Amount)
+		amount, currency := expenses[i].AmountInDefault()
+		totals[currency] = totals[currency].Add(amount)
 	}
 	return totals
 }
 
-// currencySymbol returns the display symbol for a currency code.
-func currencySymbol(code string) string {
-	if s := appmodels.SupportedCurrencies[code]; s != "" {
-		return s
-	}
-	return code
-}
-
 // sortedCurrencyKeys returns the keys of a currency→amount map sorted
 // alphabetically for deterministic output ordering.
 func sortedCurrencyKeys(totals map[string]decimal.Decimal) []string {
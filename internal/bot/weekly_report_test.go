@@ -253,12 +253,17 @@ func TestCheckAndSendWeeklyReports(t *testing.T) {
 		sent := make(map[int64]string)
 		b.checkAndSendWeeklyReports(ctx, sent, monday9amUTC)
 
-		_, exists := sent[4007]
-		require.False(t, exists, "should not mark as sent on failure")
-		// No message was actually sent because the mock returns an
-		// error before recording. This distinguishes "attempted but
-		// failed" from "skipped entirely."
+		// The summary is queued, so the week counts as handled, and the
+		// failed delivery waits in the outbox for a retry.
+		require.Equal(t, "2026-04-27", sent[4007])
 		require.Equal(t, 0, mockBot.SentMessageCount())
+		var attempts int
+		var lastError string
+		err = b.db.QueryRow(ctx, `SELECT attempts, last_error FROM outbox WHERE dedupe_key = $1`,
+			"weekly_report:4007:2026-04-27").Scan(&attempts, &lastError)
+		require.NoError(t, err)
+		require.Equal(t, 1, attempts)
+		require.Equal(t, "user blocked bot", lastError)
 	})
 
 	t.Run("prunes stale entries from sent map", func(t *testing.T) {
@@ -666,7 +671,7 @@ func TestStartWeeklyReportLoop_RunsImmediateCheck(t *testing.T) {
 	require.Contains(t, msg.Text, "Weekly Expenses")
 }
 
-func TestSendWeeklySummary_FetchError(t *testing.T) {
+func TestQueueWeeklySummary_FetchError(t *testing.T) {
 	ctx := context.Background()
 	pool := testDB(ctx, t)
 	b := setupTestBot(t, pool)
@@ -674,7 +679,7 @@ func TestSendWeeklySummary_FetchError(t *testing.T) {
 	canceledCtx, cancel := context.WithCancel(ctx)
 	cancel()
 
-	count, err := b.sendWeeklySummary(
+	count, queued, err := b.queueWeeklySummary(
 		canceledCtx,
 		&models.User{ID: 5002, FirstName: "Err"},
 		time.Now(),
@@ -682,6 +687,7 @@ func TestSendWeeklySummary_FetchError(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "failed to fetch weekly expenses")
 	require.Zero(t, count)
+	require.False(t, queued)
 }
 
 func TestSendWeeklyHabitRecap_FetchError(t *testing.T) {
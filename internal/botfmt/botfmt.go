@@ -0,0 +1,72 @@
+// Package botfmt renders the HTML messages the bot sends to Telegram.
+package botfmt
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+const (
+	// Uncategorized is shown in place of a missing category.
+	Uncategorized = "Uncategorized"
+	// RefundLabel marks refunds in confirmations and lists.
+	RefundLabel = "↩️ Refund"
+)
+
+// EscapeHTML escapes HTML special characters for safe interpolation in
+// Telegram HTML messages.
+func EscapeHTML(s string) string {
+	s = strings.ReplaceAll(s, "&", "&amp;")
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	s = strings.ReplaceAll(s, ">", "&gt;")
+	return s
+}
+
+// CurrencySymbol returns the display symbol for a currency code, or the code
+// itself for currencies without one.
+func CurrencySymbol(code string) string {
+	if s := models.SupportedCurrencies[code]; s != "" {
+		return s
+	}
+	return code
+}
+
+// FormatAmount rounds an amount to the currency's minor units for display,
+// e.g. "5.50" for SGD and "1200" for JPY. Stored amounts keep their full
+// precision.
+func FormatAmount(amount decimal.Decimal, currency string) string {
+	return amount.StringFixed(models.CurrencyMinorUnits(currency))
+}
+
+// Money formats an amount as symbol, amount and code, e.g. "S$5.50 SGD".
+// Negative amounts such as refunds put the sign first: "-S$5.50 SGD".
+func Money(amount decimal.Decimal, currency string) string {
+	return shortMoney(amount, currency) + " " + EscapeHTML(currency)
+}
+
+// shortMoney formats an amount with its symbol only, e.g. "S$5.50", for
+// lines that show several amounts in one currency.
+func shortMoney(amount decimal.Decimal, currency string) string {
+	sign, amount := splitSign(amount)
+	return sign + EscapeHTML(CurrencySymbol(currency)) + FormatAmount(amount, currency)
+}
+
+// splitSign returns "-" and the absolute value of a negative amount, so
+// the sign can go before the currency symbol.
+func splitSign(amount decimal.Decimal) (string, decimal.Decimal) {
+	if amount.IsNegative() {
+		return "-", amount.Neg()
+	}
+	return "", amount
+}
+
+// CategoryName returns the escaped category name, or Uncategorized when the
+// expense has no category loaded.
+func CategoryName(category *models.Category) string {
+	if category == nil {
+		return Uncategorized
+	}
+	return EscapeHTML(category.Name)
+}
@@ -0,0 +1,408 @@
+package botfmt
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/*.golden with the current output")
+
+const (
+	currencySGD = "SGD"
+	currencyUSD = "USD"
+	currencyXYZ = "XYZ"
+	currencyJPY = "JPY"
+)
+
+var (
+	createdAt   = time.Date(2026, 3, 4, 23, 30, 0, 0, time.UTC)
+	receiptDate = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	gmt8        = time.FixedZone("GMT+8", 8*60*60)
+)
+
+// sampleExpense returns a categorized SGD expense; each golden case varies
+// one aspect of it.
+func sampleExpense() *models.Expense {
+	return &models.Expense{
+		ID:                41,
+		UserExpenseNumber: 7,
+		Amount:            decimal.RequireFromString("12.5"),
+		Currency:          currencySGD,
+		Description:       "Lunch",
+		Merchant:          "Hawker Centre",
+		Category:          &models.Category{ID: 3, Name: "Food - Dining Out"},
+		CreatedAt:         createdAt,
+	}
+}
+
+// refundExpense is money back for a returned order.
+func refundExpense() *models.Expense {
+	e := sampleExpense()
+	e.Amount = decimal.RequireFromString("-25.9")
+	e.Description = "Amazon return"
+	e.Merchant = "Amazon"
+	return e
+}
+
+// escapedExpense has HTML special characters in every free-text field.
+func escapedExpense() *models.Expense {
+	e := sampleExpense()
+	e.Description = "<b>Fish & Chips</b>"
+	e.Merchant = "Tom & Jerry's <Diner>"
+	e.Category = &models.Category{Name: "Food & <Drinks>"}
+	return e
+}
+
+func uncategorizedExpense() *models.Expense {
+	e := sampleExpense()
+	e.Category = nil
+	return e
+}
+
+func usdExpense() *models.Expense {
+	e := sampleExpense()
+	e.Currency = currencyUSD
+	return e
+}
+
+// unknownCurrencyExpense uses a code without a symbol, which falls back to
+// the code itself.
+func unknownCurrencyExpense() *models.Expense {
+	e := sampleExpense()
+	e.Currency = currencyXYZ
+	return e
+}
+
+func requireGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		require.NoError(t, os.MkdirAll("testdata", 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "run go test ./internal/botfmt -update to create %s", path)
+	require.Equal(t, string(want), got)
+}
+
+func TestCardsGolden(t *testing.T) {
+	t.Parallel()
+
+	tags := []models.Tag{{ID: 1, Name: "work"}, {ID: 2, Name: "team"}}
+	listExpenses := []models.Expense{*sampleExpense(), *escapedExpense(), *unknownCurrencyExpense()}
+	listExpenses[1].ID = 42
+	listExpenses[1].UserExpenseNumber = 8
+	listExpenses[2].ID = 43
+	listExpenses[2].UserExpenseNumber = 9
+	listExpenses[2].Merchant = ""
+	listExpenses[2].Category = nil
+	noCurrency := sampleExpense()
+	noCurrency.Currency = ""
+	confirmed := usdExpense()
+	confirmed.Status = models.ExpenseStatusConfirmed
+	confirmed.Source = models.ExpenseSourceReceipt
+	tagged := escapedExpense()
+	tagged.Tags = []models.Tag{{Name: "work"}, {Name: "a<b"}}
+	edited := sampleExpense()
+	edited.Amount = decimal.RequireFromString("20")
+	edited.Category = &models.Category{Name: "Transportation"}
+	monoExpenses := append([]models.Expense{}, listExpenses...)
+	cjk := sampleExpense()
+	cjk.ID = 44
+	cjk.UserExpenseNumber = 10
+	cjk.Amount = decimal.RequireFromString("1280")
+	cjk.Currency = currencyJPY
+	cjk.Merchant = "一蘭ラーメン渋谷センター街店"
+	monoExpenses = append(monoExpenses, *cjk)
+
+	tests := []struct {
+		name string
+		got  string
+	}{
+		{"expense_added", ExpenseAddedCard(sampleExpense(), nil, ExpenseAddedOptions{})},
+		{"expense_added_tags", ExpenseAddedCard(sampleExpense(), []string{"work", "a&b"}, ExpenseAddedOptions{})},
+		{"expense_added_expression", ExpenseAddedCard(usdExpense(), nil, ExpenseAddedOptions{Expression: "25<2"})},
+		{"expense_added_escaped", ExpenseAddedCard(escapedExpense(), nil, ExpenseAddedOptions{})},
+		{"expense_added_uncategorized", ExpenseAddedCard(uncategorizedExpense(), nil, ExpenseAddedOptions{})},
+		{"expense_added_unknown_currency", ExpenseAddedCard(unknownCurrencyExpense(), nil, ExpenseAddedOptions{})},
+		{"expense_added_refund", ExpenseAddedCard(refundExpense(), nil, ExpenseAddedOptions{})},
+		{"expense_updated", ExpenseUpdatedCard(usdExpense(), usdExpense())},
+		{"expense_updated_escaped", ExpenseUpdatedCard(escapedExpense(), escapedExpense())},
+		{"expense_updated_uncategorized", ExpenseUpdatedCard(uncategorizedExpense(), uncategorizedExpense())},
+		{"expense_updated_diff", ExpenseUpdatedCard(uncategorizedExpense(), edited)},
+		{"expense_updated_diff_escaped", ExpenseUpdatedCard(sampleExpense(), escapedExpense())},
+		{"description_updated", DescriptionUpdatedCard(escapedExpense(), escapedExpense())},
+		{"description_updated_uncategorized", DescriptionUpdatedCard(uncategorizedExpense(), uncategorizedExpense())},
+		{"description_updated_diff", DescriptionUpdatedCard(escapedExpense(), sampleExpense())},
+		{"edit_menu", ExpenseEditMenuCard(usdExpense())},
+		{"edit_menu_escaped", ExpenseEditMenuCard(escapedExpense())},
+		{"delete_confirm", DeleteConfirmCard(unknownCurrencyExpense())},
+		{"delete_confirm_escaped", DeleteConfirmCard(escapedExpense())},
+		{"large_amount_confirm", LargeAmountConfirmCard(sampleExpense())},
+		{"large_amount_confirm_escaped", LargeAmountConfirmCard(escapedExpense())},
+		{"large_amount_warning", LargeAmountWarning(usdExpense())},
+		{"voice_expense", VoiceExpenseCard(usdExpense())},
+		{"voice_expense_escaped", VoiceExpenseCard(escapedExpense())},
+		{"voice_expense_uncategorized", VoiceExpenseCard(uncategorizedExpense())},
+		{"receipt_scanned", ReceiptScannedCard(sampleExpense(), receiptDate, false)},
+		{"receipt_scanned_partial", ReceiptScannedCard(uncategorizedExpense(), time.Time{}, true)},
+		{"receipt_scanned_escaped", ReceiptScannedCard(escapedExpense(), receiptDate, false)},
+		{"bank_message", BankMessageCard(sampleExpense(), receiptDate)},
+		{"bank_message_escaped", BankMessageCard(escapedExpense(), time.Time{})},
+		{"receipt_image_count", ReceiptImageCountNote(2)},
+		{"receipt_draft", ReceiptDraftCard(usdExpense(), DraftUnchanged)},
+		{"receipt_draft_amount", ReceiptDraftCard(sampleExpense(), DraftAmountUpdated)},
+		{"receipt_draft_merchant", ReceiptDraftCard(escapedExpense(), DraftMerchantUpdated)},
+		{"receipt_draft_amount_diff", ReceiptDraftEditCard(sampleExpense(), usdExpense(), DraftAmountUpdated)},
+		{"receipt_draft_merchant_diff", ReceiptDraftEditCard(sampleExpense(), escapedExpense(), DraftMerchantUpdated)},
+		{"receipt_draft_category", ReceiptDraftCard(unknownCurrencyExpense(), DraftCategoryUpdated)},
+		{"receipt_draft_category_created", ReceiptDraftCard(escapedExpense(), DraftCategoryCreated)},
+		{"receipt_draft_edit_menu", ReceiptDraftCard(uncategorizedExpense(), DraftEditMenu)},
+		{"receipt_draft_tip", ReceiptDraftCard(usdExpense(), DraftTipAdded)},
+		{"receipt_draft_tags", ReceiptDraftCard(tagged, DraftTagsMenu)},
+		{"receipt_draft_currency_menu", ReceiptDraftCard(sampleExpense(), DraftCurrencyMenu)},
+		{"receipt_draft_currency", ReceiptDraftEditCard(sampleExpense(), usdExpense(), DraftCurrencyUpdated)},
+		{"expense_confirmed", ExpenseConfirmedCard(sampleExpense(), gmt8)},
+		{"expense_confirmed_default_currency", ExpenseConfirmedCard(noCurrency, time.UTC)},
+		{"expense_confirmed_escaped", ExpenseConfirmedCard(escapedExpense(), time.UTC)},
+		{"expense_confirmed_uncategorized", ExpenseConfirmedCard(uncategorizedExpense(), time.UTC)},
+		{"list_item", ExpenseListItem(sampleExpense(), tags, time.UTC)},
+		{"list_item_escaped", ExpenseListItem(escapedExpense(), []models.Tag{{Name: "a<b"}}, gmt8)},
+		{"list_item_refund", ExpenseListItem(refundExpense(), nil, time.UTC)},
+		{"expense_detail_refund", ExpenseDetailCard(refundExpense(), nil, time.UTC)},
+		{"expense_detail", ExpenseDetailCard(confirmed, tags, gmt8)},
+		{"expense_detail_escaped", ExpenseDetailCard(escapedExpense(), []models.Tag{{Name: "a<b"}}, time.UTC)},
+		{"expense_detail_uncategorized", ExpenseDetailCard(uncategorizedExpense(), nil, time.UTC)},
+		{
+			"list_message",
+			ExpenseListMessage("📋 <b>Recent Expenses</b>", listExpenses, map[int][]models.Tag{41: tags}, gmt8),
+		},
+		{"list_message_mono", ExpenseListMono("📋 <b>Recent Expenses</b>", monoExpenses, gmt8)},
+		{"mono_table", MonoTable([][]string{
+			{"Food & <Drinks>", "S$1,240.00", "▲ 5.0%"},
+			{"交通", "S$8.00"},
+			{"Café", "S$12.50", "same"},
+		}, []Align{AlignLeft, AlignRight})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			requireGolden(t, tt.name, tt.got)
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+
+	format, ok := ParseFormat(" MONO ")
+	require.True(t, ok)
+	require.Equal(t, FormatMono, format)
+	format, ok = ParseFormat("html")
+	require.True(t, ok)
+	require.Equal(t, FormatHTML, format)
+	_, ok = ParseFormat("markdown")
+	require.False(t, ok)
+}
+
+func TestDisplayWidth(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 5, DisplayWidth("Lunch"))
+	require.Equal(t, 4, DisplayWidth("ラー"))
+	require.Equal(t, 4, DisplayWidth("ｒｏ"), "fullwidth letters")
+	require.Equal(t, 4, DisplayWidth("Cafe\u0301"), "combining accent")
+	require.Equal(t, 2, DisplayWidth("☕\ufe0f"), "variation selector")
+}
+
+func TestTruncateWidth(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "Lunch", TruncateWidth("Lunch", 5))
+	require.Equal(t, "Lun…", TruncateWidth("Lunch", 4))
+	require.Equal(t, "ラ…", TruncateWidth("ラーメン", 4), "a wide character is never split")
+	require.Equal(t, "ラー…", TruncateWidth("ラーメン", 5))
+	require.Empty(t, TruncateWidth("Lunch", 0))
+}
+
+func TestMonoTableAlignsWideCharacters(t *testing.T) {
+	t.Parallel()
+
+	got := MonoTable([][]string{{"ラーメン", "12.00"}, {"Taxi", "8.50"}}, []Align{AlignLeft, AlignRight})
+	lines := strings.Split(strings.TrimSuffix(strings.TrimPrefix(got, "<pre>"), "</pre>"), "\n")
+	require.Len(t, lines, 2)
+	require.Equal(t, DisplayWidth(lines[0]), DisplayWidth(lines[1]))
+	require.Equal(t, "Taxi       8.50", lines[1])
+}
+
+func TestEscapeHTML(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "a &amp;lt; &lt;b&gt; &amp; c", EscapeHTML("a &lt; <b> & c"))
+	require.Empty(t, EscapeHTML(""))
+}
+
+func TestCurrencySymbol(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "S$", CurrencySymbol(currencySGD))
+	require.Equal(t, "$", CurrencySymbol(currencyUSD))
+	require.Equal(t, "€", CurrencySymbol("EUR"))
+	require.Equal(t, currencyXYZ, CurrencySymbol(currencyXYZ))
+	require.Empty(t, CurrencySymbol(""))
+}
+
+func TestMoney(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "S$5.50 SGD", Money(decimal.RequireFromString("5.5"), currencySGD))
+	require.Equal(t, "XYZ1.00 XYZ", Money(decimal.NewFromInt(1), currencyXYZ))
+	require.Equal(t, "¥1200 JPY", Money(decimal.NewFromInt(1200), currencyJPY))
+	require.Equal(t, "-S$25.90 SGD", Money(decimal.RequireFromString("-25.9"), currencySGD))
+	require.Equal(t, "-S$25.90 SGD", PlainMoney(decimal.RequireFromString("-25.9"), currencySGD))
+}
+
+func TestFormatAmount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		amount   string
+		currency string
+		want     string
+	}{
+		{amount: "1200", currency: currencyJPY, want: "1200"},
+		{amount: "1199.5", currency: currencyJPY, want: "1200"},
+		{amount: "50000.4", currency: "KRW", want: "50000"},
+		{amount: "5.5", currency: currencySGD, want: "5.50"},
+		{amount: "5.555", currency: currencyUSD, want: "5.56"},
+		{amount: "50", currency: "THB", want: "50.00"},
+		{amount: "1.2345", currency: "KWD", want: "1.235"},
+		{amount: "2", currency: "BHD", want: "2.000"},
+		{amount: "-5.5", currency: currencySGD, want: "-5.50"},
+		{amount: "-1200", currency: currencyJPY, want: "-1200"},
+		{amount: "-0.5", currency: "KWD", want: "-0.500"},
+		{amount: "1", currency: currencyXYZ, want: "1.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.want, FormatAmount(decimal.RequireFromString(tt.amount), tt.currency))
+		})
+	}
+}
+
+func TestReceiptBreakdownLine(t *testing.T) {
+	t.Parallel()
+
+	d := decimal.RequireFromString
+	tests := []struct {
+		name string
+		in   ReceiptBreakdown
+		want string
+	}{
+		{
+			name: "subtotal and tip",
+			in:   ReceiptBreakdown{Subtotal: d("48"), Tip: d("6.6"), Total: d("54.6"), Currency: currencyUSD},
+			want: "🧾 Subtotal $48.00 + tip $6.60 = $54.60",
+		},
+		{
+			name: "total computed from parts",
+			in:   ReceiptBreakdown{Subtotal: d("20"), Tax: d("1.8"), Currency: currencySGD},
+			want: "🧾 Subtotal S$20.00 + tax S$1.80 = S$21.80",
+		},
+		{
+			name: "parts that do not add up",
+			in:   ReceiptBreakdown{Subtotal: d("48"), Tip: d("6.6"), Total: d("60"), Currency: currencyUSD},
+			want: "🧾 Subtotal $48.00, tip $6.60, total $60.00",
+		},
+		{
+			name: "tip only with total",
+			in:   ReceiptBreakdown{Tip: d("5"), Total: d("45"), Currency: currencyXYZ},
+			want: "🧾 Tip XYZ5.00, total XYZ45.00",
+		},
+		{
+			name: "subtotal alone",
+			in:   ReceiptBreakdown{Subtotal: d("48"), Currency: currencyUSD},
+		},
+		{name: "nothing found", in: ReceiptBreakdown{Total: d("54.6")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.want, ReceiptBreakdownLine(tt.in))
+		})
+	}
+}
+
+func TestCategoryName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, Uncategorized, CategoryName(nil))
+	require.Equal(t, "Food &amp; Drinks", CategoryName(&models.Category{Name: "Food & Drinks"}))
+}
+
+func TestChange(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "S$10.00 SGD", change("S$10.00 SGD", "S$10.00 SGD"))
+	require.Equal(t, "S$10.00 SGD → S$20.00 SGD", change("S$10.00 SGD", "S$20.00 SGD"))
+	require.Equal(t, "Uncategorized → Transportation", change(Uncategorized, "Transportation"))
+}
+
+func TestExpenseChanged(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		edit   func(e *models.Expense)
+		change bool
+	}{
+		{"no-op", func(*models.Expense) {}, false},
+		{"same amount, other precision", func(e *models.Expense) { e.Amount = decimal.RequireFromString("12.50") }, false},
+		{"same category, reloaded", func(e *models.Expense) { e.Category = &models.Category{Name: e.Category.Name} }, false},
+		{"amount", func(e *models.Expense) { e.Amount = decimal.NewFromInt(20) }, true},
+		{"currency", func(e *models.Expense) { e.Currency = currencyUSD }, true},
+		{"description", func(e *models.Expense) { e.Description = "Dinner" }, true},
+		{"merchant", func(e *models.Expense) { e.Merchant = "Food Court" }, true},
+		{"category", func(e *models.Expense) { e.Category = &models.Category{Name: "Transportation"} }, true},
+		{"uncategorized", func(e *models.Expense) { e.Category = nil }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			after := sampleExpense()
+			tt.edit(after)
+			require.Equal(t, tt.change, ExpenseChanged(sampleExpense(), after))
+		})
+	}
+}
+
+func TestNothingChangedText(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "ℹ️ Nothing changed on expense #7.", NothingChangedText(sampleExpense()))
+}
+
+func TestLocationLine(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t,
+		`📍 <a href="https://www.google.com/maps?q=1.28340,103.86070">1.28340,103.86070</a>`,
+		LocationLine(models.ExpenseLocation{Latitude: 1.2834, Longitude: 103.8607}))
+	require.Equal(t,
+		`📍 <a href="https://www.google.com/maps?q=-33.85680,151.21530">Tom &amp; Jerry's</a>`,
+		LocationLine(models.ExpenseLocation{Latitude: -33.8568, Longitude: 151.2153, Label: "Tom & Jerry's"}))
+}
@@ -0,0 +1,471 @@
+package botfmt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// ExpenseAddedOptions adjusts ExpenseAddedCard.
+type ExpenseAddedOptions struct {
+	// Expression is the arithmetic the amount was calculated from, shown
+	// next to it, e.g. "💰 $28.20 (84.60/3) USD".
+	Expression string
+}
+
+// ExpenseAddedCard renders the confirmation for a newly saved expense, or
+// for a refund.
+func ExpenseAddedCard(expense *models.Expense, tags []string, opts ExpenseAddedOptions) string {
+	expressionText := ""
+	if opts.Expression != "" {
+		expressionText = " (" + EscapeHTML(opts.Expression) + ")"
+	}
+	descText := ""
+	if expense.Description != "" {
+		descText = "\n📝 " + EscapeHTML(expense.Description)
+	}
+	title := "✅ <b>Expense Added</b>"
+	if expense.IsRefund() {
+		title = "↩️ <b>Refund Added</b>"
+	}
+
+	text := fmt.Sprintf(`%s
+
+💰 %s%s %s%s
+📁 %s
+🆔 #%d`,
+		title,
+		shortMoney(expense.Amount, expense.Currency),
+		expressionText,
+		EscapeHTML(expense.Currency),
+		descText,
+		CategoryName(expense.Category),
+		expense.UserExpenseNumber)
+
+	if len(tags) == 0 {
+		return text
+	}
+	escapedTags := make([]string, len(tags))
+	for i, tag := range tags {
+		escapedTags[i] = EscapeHTML(tag)
+	}
+	return text + "\n🏷️ " + strings.Join(escapedTags, ", ")
+}
+
+// change renders one field of an edit confirmation: the new value, preceded
+// by the old one when the edit changed it, e.g. "$10.00 → $20.00". Both
+// values must already be escaped.
+func change(before, after string) string {
+	if before == after {
+		return after
+	}
+	return before + " → " + after
+}
+
+// ExpenseChanged reports whether an edit changed the amount, description,
+// merchant or category of an expense as the confirmations show them.
+func ExpenseChanged(before, after *models.Expense) bool {
+	return Money(before.Amount, before.Currency) != Money(after.Amount, after.Currency) ||
+		before.Description != after.Description ||
+		before.Merchant != after.Merchant ||
+		CategoryName(before.Category) != CategoryName(after.Category)
+}
+
+// NothingChangedText is the reply to an edit that left an expense as it was.
+func NothingChangedText(expense *models.Expense) string {
+	return fmt.Sprintf("ℹ️ Nothing changed on expense #%d.", expense.UserExpenseNumber)
+}
+
+// ExpenseUpdatedCard renders the confirmation for /edit, showing each
+// changed field as before → after.
+func ExpenseUpdatedCard(before, after *models.Expense) string {
+	return fmt.Sprintf(`✅ <b>Expense Updated</b>
+
+🆔 #%d
+💰 %s
+📝 %s
+📁 %s`,
+		after.UserExpenseNumber,
+		change(Money(before.Amount, before.Currency), Money(after.Amount, after.Currency)),
+		change(EscapeHTML(before.Description), EscapeHTML(after.Description)),
+		change(CategoryName(before.Category), CategoryName(after.Category)))
+}
+
+// DescriptionUpdatedCard renders the confirmation for an inline description
+// edit, showing each changed field as before → after.
+func DescriptionUpdatedCard(before, after *models.Expense) string {
+	return fmt.Sprintf(`✅ <b>Description Updated!</b>
+
+💰 Amount: %s
+📝 Description: %s
+📁 Category: %s
+🆔 #%d`,
+		change(Money(before.Amount, before.Currency), Money(after.Amount, after.Currency)),
+		change(EscapeHTML(before.Description), EscapeHTML(after.Description)),
+		change(CategoryName(before.Category), CategoryName(after.Category)),
+		after.UserExpenseNumber)
+}
+
+// ExpenseEditMenuCard renders the details shown above the inline edit menu
+// of a confirmed expense.
+func ExpenseEditMenuCard(expense *models.Expense) string {
+	return fmt.Sprintf(`✏️ <b>Edit Expense #%d</b>
+
+Current Details:
+💰 Amount: %s
+📝 Description: %s
+📁 Category: %s%s
+
+What would you like to edit?`,
+		expense.UserExpenseNumber,
+		Money(expense.Amount, expense.Currency),
+		EscapeHTML(expense.Description),
+		CategoryName(expense.Category),
+		reimbursementLine(expense))
+}
+
+// refundSuffix marks a refund after its amount, or is "" for other
+// expenses.
+func refundSuffix(expense *models.Expense) string {
+	if !expense.IsRefund() {
+		return ""
+	}
+	return " " + RefundLabel
+}
+
+// reimbursementLine renders the reimbursement state of a reimbursable
+// expense on its own line, or "" for other expenses.
+func reimbursementLine(expense *models.Expense) string {
+	switch {
+	case !expense.Reimbursable:
+		return ""
+	case expense.ReimbursedAt == nil:
+		return "\n💼 Reimbursable: awaiting reimbursement"
+	default:
+		return "\n💼 Reimbursable: reimbursed " + expense.ReimbursedAt.Format("Jan 2, 2006")
+	}
+}
+
+// DeleteConfirmCard renders the prompt shown before an inline delete.
+func DeleteConfirmCard(expense *models.Expense) string {
+	return fmt.Sprintf(`🗑️ <b>Delete Expense?</b>
+
+Are you sure you want to delete this expense?
+
+💰 %s
+📝 %s
+🆔 #%d
+
+This action cannot be undone.`,
+		Money(expense.Amount, expense.Currency),
+		EscapeHTML(expense.Description),
+		expense.UserExpenseNumber)
+}
+
+// LargeAmountConfirmCard asks before saving an expense whose amount is above
+// the user's limit.
+func LargeAmountConfirmCard(expense *models.Expense) string {
+	return fmt.Sprintf(`⚠️ <b>That's %s — save anyway?</b>
+
+📝 %s
+📁 Category: %s
+
+This is above your usual limit. Change it with /settings maxamount.`,
+		Money(expense.Amount, expense.Currency),
+		EscapeHTML(expense.Description),
+		CategoryName(expense.Category))
+}
+
+// LargeAmountWarning is the line added to a receipt draft whose amount is
+// above the user's limit.
+func LargeAmountWarning(expense *models.Expense) string {
+	return "⚠️ " + Money(expense.Amount, expense.Currency) +
+		" is above your usual limit. Check the amount before confirming."
+}
+
+// VoiceExpenseCard renders the draft created from a voice message.
+func VoiceExpenseCard(expense *models.Expense) string {
+	return fmt.Sprintf(`🎙️ <b>Voice Expense Detected!</b>
+
+💰 Amount: %s
+📝 Description: %s
+📁 Category: %s
+
+Please confirm, edit, or cancel:`,
+		Money(expense.Amount, expense.Currency),
+		EscapeHTML(expense.Description),
+		CategoryName(expense.Category))
+}
+
+// ReceiptScannedCard renders the draft created from a receipt photo. A zero
+// receiptDate is shown as "Unknown". Partial extractions get a warning
+// title and footer.
+func ReceiptScannedCard(expense *models.Expense, receiptDate time.Time, partial bool) string {
+	dateText := "Unknown"
+	if !receiptDate.IsZero() {
+		dateText = receiptDate.Format("02 Jan 2006")
+	}
+
+	title := "📸 <b>Receipt Scanned!</b>"
+	footer := ""
+	if partial {
+		title = "⚠️ <b>Partial Extraction - Please Verify</b>"
+		footer = "\n\n<i>Some data could not be extracted. Please edit or confirm.</i>"
+	}
+
+	return fmt.Sprintf(`%s
+
+💰 Amount: %s
+🏪 Merchant: %s
+📅 Date: %s
+📁 Category: %s%s`,
+		title,
+		Money(expense.Amount, expense.Currency),
+		EscapeHTML(expense.Merchant),
+		dateText,
+		CategoryName(expense.Category),
+		footer)
+}
+
+// BankMessageCard is the draft card for an expense read from a forwarded
+// bank notification.
+func BankMessageCard(expense *models.Expense, date time.Time) string {
+	dateText := "Unknown"
+	if !date.IsZero() {
+		dateText = date.Format("02 Jan 2006")
+	}
+
+	return fmt.Sprintf(`🏦 <b>Bank Message Read</b>
+
+💰 Amount: %s
+🏪 Merchant: %s
+📅 Date: %s
+📁 Category: %s
+
+<i>Confirm to save it, or edit it first.</i>`,
+		Money(expense.Amount, expense.Currency),
+		EscapeHTML(expense.Merchant),
+		dateText,
+		CategoryName(expense.Category))
+}
+
+// ReceiptImageCountNote is the line added to a receipt draft scanned from
+// several photos of one album.
+func ReceiptImageCountNote(count int) string {
+	return fmt.Sprintf("🖼 %d images scanned", count)
+}
+
+// ReceiptBreakdown holds the separate amounts printed on a receipt. Zero
+// parts were not found on the receipt.
+type ReceiptBreakdown struct {
+	Subtotal decimal.Decimal
+	Tax      decimal.Decimal
+	Tip      decimal.Decimal
+	Total    decimal.Decimal
+	Currency string
+}
+
+// ReceiptBreakdownLine renders the parts of a receipt, e.g.
+// "🧾 Subtotal $48.00 + tip $6.60 = $54.60". When the parts do not add up
+// to the total they are listed separately instead. It returns "" when there
+// is nothing beyond a single amount to show.
+func ReceiptBreakdownLine(b ReceiptBreakdown) string {
+	type part struct {
+		label  string
+		amount decimal.Decimal
+	}
+	var parts []part
+	sum := decimal.Zero
+	for _, p := range []part{{"Subtotal", b.Subtotal}, {"tax", b.Tax}, {"tip", b.Tip}} {
+		if p.amount.IsPositive() {
+			parts = append(parts, p)
+			sum = sum.Add(p.amount)
+		}
+	}
+	if len(parts) == 0 || (len(parts) == 1 && !b.Total.IsPositive()) {
+		return ""
+	}
+
+	labels := make([]string, len(parts))
+	for i, p := range parts {
+		label := p.label
+		if i == 0 {
+			label = strings.ToUpper(label[:1]) + label[1:]
+		}
+		labels[i] = label + " " + shortMoney(p.amount, b.Currency)
+	}
+	total := b.Total
+	if !total.IsPositive() {
+		total = sum
+	}
+	if total.Equal(sum) {
+		return "🧾 " + strings.Join(labels, " + ") + " = " + shortMoney(total, b.Currency)
+	}
+	return "🧾 " + strings.Join(labels, ", ") + ", total " + shortMoney(total, b.Currency)
+}
+
+// DraftUpdate names the change that led to re-rendering a receipt draft.
+type DraftUpdate int
+
+// Receipt draft updates rendered by ReceiptDraftCard.
+const (
+	DraftUnchanged DraftUpdate = iota
+	DraftAmountUpdated
+	DraftMerchantUpdated
+	DraftCategoryUpdated
+	DraftCategoryCreated
+	DraftEditMenu
+	DraftTipAdded
+	DraftTagsMenu
+	DraftCurrencyMenu
+	DraftCurrencyUpdated
+)
+
+// titleAndFooter returns the header and closing line for the update.
+func (u DraftUpdate) titleAndFooter() (string, string) {
+	switch u {
+	case DraftAmountUpdated:
+		return "📸 <b>Amount Updated!</b>", "Amount updated. Confirm to save."
+	case DraftMerchantUpdated:
+		return "📸 <b>Merchant Updated!</b>", "Merchant updated. Confirm to save."
+	case DraftCategoryUpdated:
+		return "📸 <b>Receipt Updated!</b>", "Category updated. Confirm to save."
+	case DraftCategoryCreated:
+		return "📸 <b>Category Created!</b>", "New category created. Confirm to save."
+	case DraftEditMenu:
+		return "✏️ <b>Edit Expense</b>", "Select what to edit:"
+	case DraftTipAdded:
+		return "📸 <b>Tip Added!</b>", "Tip added. Confirm to save."
+	case DraftTagsMenu:
+		return "🏷️ <b>Tags</b>", "Tap a tag to add or remove it:"
+	case DraftCurrencyMenu:
+		return "💱 <b>Currency</b>", "Pick the receipt's currency:"
+	case DraftCurrencyUpdated:
+		return "📸 <b>Currency Updated!</b>", "Currency updated. Confirm to save."
+	case DraftUnchanged:
+	}
+	return "📸 <b>Receipt Scanned!</b>", ""
+}
+
+// ReceiptDraftCard re-renders an unconfirmed receipt or voice draft after an
+// edit, without the receipt date. Tags already attached to the draft are
+// listed.
+func ReceiptDraftCard(expense *models.Expense, update DraftUpdate) string {
+	return ReceiptDraftEditCard(expense, expense, update)
+}
+
+// ReceiptDraftEditCard is ReceiptDraftCard for a draft that was just edited
+// from before, showing each changed field as before → after.
+func ReceiptDraftEditCard(before, after *models.Expense, update DraftUpdate) string {
+	title, footer := update.titleAndFooter()
+	if footer != "" {
+		footer = "\n\n" + footer
+	}
+	tagsText := ""
+	if len(after.Tags) > 0 {
+		names := make([]string, len(after.Tags))
+		for i, tag := range after.Tags {
+			names[i] = "#" + EscapeHTML(tag.Name)
+		}
+		tagsText = "\n🏷️ Tags: " + strings.Join(names, " ")
+	}
+
+	return fmt.Sprintf(`%s
+
+💰 Amount: %s
+🏪 Merchant: %s
+📁 Category: %s%s%s`,
+		title,
+		change(Money(before.Amount, before.Currency), Money(after.Amount, after.Currency)),
+		change(EscapeHTML(before.Merchant), EscapeHTML(after.Merchant)),
+		change(CategoryName(before.Category), CategoryName(after.Category)),
+		tagsText,
+		footer)
+}
+
+// ExpenseConfirmedCard renders the result of confirming a draft. Drafts
+// without a currency are shown in models.DefaultCurrency.
+func ExpenseConfirmedCard(expense *models.Expense, loc *time.Location) string {
+	currency := expense.Currency
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+
+	return fmt.Sprintf(`✅ <b>Expense Confirmed!</b>
+
+💰 Amount: %s
+🏪 Merchant: %s
+📁 Category: %s
+🗓️ Date: %s
+
+Expense #%d has been saved.`,
+		Money(expense.Amount, currency),
+		EscapeHTML(expense.Merchant),
+		CategoryName(expense.Category),
+		expense.CreatedAt.In(loc).Format("02 Jan 2006"),
+		expense.UserExpenseNumber)
+}
+
+// ExpenseDetailCard renders a read-only view of a single expense with its
+// status, creation time in loc and tags.
+func ExpenseDetailCard(expense *models.Expense, tags []models.Tag, loc *time.Location) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🧾 <b>Expense #%d</b>\n\n", expense.UserExpenseNumber)
+	fmt.Fprintf(&sb, "💰 %s%s\n", Money(expense.Amount, expense.Currency), refundSuffix(expense))
+	if expense.Description != "" {
+		fmt.Fprintf(&sb, "📝 %s\n", EscapeHTML(expense.Description))
+	}
+	if expense.Merchant != "" && expense.Merchant != expense.Description {
+		fmt.Fprintf(&sb, "🏪 %s\n", EscapeHTML(expense.Merchant))
+	}
+	fmt.Fprintf(&sb, "📁 %s\n", CategoryName(expense.Category))
+	fmt.Fprintf(&sb, "🗓️ %s", expense.CreatedAt.In(loc).Format("02 Jan 2006 15:04"))
+	if label := ExpenseSourceLabel(expense.Source); label != "" {
+		fmt.Fprintf(&sb, " · <i>via %s</i>", label)
+	}
+	if expense.Status != models.ExpenseStatusUnset {
+		fmt.Fprintf(&sb, "\n📌 %s", EscapeHTML(string(expense.Status)))
+	}
+	if len(tags) > 0 {
+		names := make([]string, len(tags))
+		for i := range tags {
+			names[i] = "#" + EscapeHTML(tags[i].Name)
+		}
+		sb.WriteString("\n🏷️ " + strings.Join(names, " "))
+	}
+	return sb.String()
+}
+
+// expenseSourceLabels names the sources in running text, e.g. "via receipt
+// scan".
+var expenseSourceLabels = map[models.ExpenseSource]string{
+	models.ExpenseSourceText:      "chat message",
+	models.ExpenseSourceCommand:   "command",
+	models.ExpenseSourceReceipt:   "receipt scan",
+	models.ExpenseSourceVoice:     "voice message",
+	models.ExpenseSourceBank:      "bank notification",
+	models.ExpenseSourceAPI:       "API",
+	models.ExpenseSourceImport:    "import",
+	models.ExpenseSourceRecurring: "recurring expense",
+	models.ExpenseSourceDemo:      "demo data",
+}
+
+// ExpenseSourceLabel names how an expense entered the bot, or returns ""
+// when that is unknown.
+func ExpenseSourceLabel(source models.ExpenseSource) string {
+	return expenseSourceLabels[source]
+}
+
+// LocationLine renders where an expense happened as a map link named after
+// the place, or after the coordinates when the place has no name.
+func LocationLine(loc models.ExpenseLocation) string {
+	coords := fmt.Sprintf("%.5f,%.5f", loc.Latitude, loc.Longitude)
+	name := coords
+	if loc.Label != "" {
+		name = loc.Label
+	}
+	return fmt.Sprintf(`📍 <a href="https://www.google.com/maps?q=%s">%s</a>`, coords, EscapeHTML(name))
+}
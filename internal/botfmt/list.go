@@ -0,0 +1,63 @@
+package botfmt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// ExpenseListItem renders one expense line of a list, followed by its
+// creation time in loc and a blank line. The merchant is preferred over the
+// description.
+func ExpenseListItem(exp *models.Expense, tags []models.Tag, loc *time.Location) string {
+	categoryText := ""
+	if exp.Category != nil {
+		categoryText = fmt.Sprintf(" [%s]", EscapeHTML(exp.Category.Name))
+	}
+
+	tagText := ""
+	if len(tags) > 0 {
+		names := make([]string, len(tags))
+		for i := range tags {
+			names[i] = "#" + EscapeHTML(tags[i].Name)
+		}
+		tagText = " " + strings.Join(names, " ")
+	}
+
+	descText := ""
+	if exp.Merchant != "" {
+		descText = " - " + EscapeHTML(exp.Merchant)
+	} else if exp.Description != "" {
+		descText = " - " + EscapeHTML(exp.Description)
+	}
+
+	return fmt.Sprintf(
+		"#%d %s%s%s%s%s\n<i>%s</i>\n\n",
+		exp.UserExpenseNumber,
+		Money(exp.Amount, exp.Currency),
+		refundSuffix(exp),
+		descText,
+		categoryText,
+		tagText,
+		exp.CreatedAt.In(loc).Format("Jan 2 15:04"),
+	)
+}
+
+// ExpenseListMessage renders header followed by one ExpenseListItem per
+// expense. tagsByExpense is keyed by expense ID and may be nil.
+func ExpenseListMessage(
+	header string,
+	expenses []models.Expense,
+	tagsByExpense map[int][]models.Tag,
+	loc *time.Location,
+) string {
+	var sb strings.Builder
+	sb.WriteString(header)
+	sb.WriteString("\n\n")
+	for i := range expenses {
+		sb.WriteString(ExpenseListItem(&expenses[i], tagsByExpense[expenses[i].ID], loc))
+	}
+	return sb.String()
+}
@@ -0,0 +1,170 @@
+package botfmt
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+	"golang.org/x/text/width"
+)
+
+// Format is how a user's lists and summaries are laid out.
+type Format string
+
+const (
+	// FormatHTML is the default layout with bold headers and one expense
+	// per paragraph.
+	FormatHTML Format = "html"
+	// FormatMono renders lists and summaries as fixed-width columns inside
+	// <pre> blocks.
+	FormatMono Format = "mono"
+)
+
+// ParseFormat reads a /settings format value, reporting false for unknown
+// ones.
+func ParseFormat(s string) (Format, bool) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatHTML, FormatMono:
+		return f, true
+	}
+	return "", false
+}
+
+// Align is the side a MonoTable column is padded towards.
+type Align int
+
+const (
+	// AlignLeft pads a column on the right.
+	AlignLeft Align = iota
+	// AlignRight pads a column on the left, e.g. for amounts.
+	AlignRight
+)
+
+const (
+	// monoColumnGap separates the columns of a MonoTable.
+	monoColumnGap = "  "
+	// monoDescriptionWidth is how many columns a description may take in a
+	// mono expense list before it is cut.
+	monoDescriptionWidth = 20
+)
+
+// runeWidth returns how many terminal columns r takes: two for East Asian
+// wide and fullwidth characters, none for combining marks and format
+// characters such as variation selectors, one for everything else.
+func runeWidth(r rune) int {
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// DisplayWidth returns how many columns s takes in a monospace font.
+func DisplayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+// TruncateWidth cuts s to at most maxWidth columns, ending it with "…" when
+// anything was dropped. Wide characters are never split.
+func TruncateWidth(s string, maxWidth int) string {
+	if DisplayWidth(s) <= maxWidth {
+		return s
+	}
+	if maxWidth <= 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	used := 0
+	for _, r := range s {
+		w := runeWidth(r)
+		if used+w > maxWidth-1 {
+			break
+		}
+		sb.WriteRune(r)
+		used += w
+	}
+	return sb.String() + "…"
+}
+
+// padWidth pads s with spaces to columns wide on the side given by align.
+func padWidth(s string, columns int, align Align) string {
+	gap := columns - DisplayWidth(s)
+	if gap <= 0 {
+		return s
+	}
+	if align == AlignRight {
+		return strings.Repeat(" ", gap) + s
+	}
+	return s + strings.Repeat(" ", gap)
+}
+
+// MonoTable renders rows as a <pre> block with every column padded to its
+// widest cell, counted in display columns so wide characters keep the
+// columns aligned. Cells are plain text and escaped here. align has one
+// entry per column; columns without one are left-aligned. Rows may be
+// shorter than others.
+func MonoTable(rows [][]string, align []Align) string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], DisplayWidth(cell))
+		}
+	}
+
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			columnAlign := AlignLeft
+			if j < len(align) {
+				columnAlign = align[j]
+			}
+			cells[j] = EscapeHTML(padWidth(cell, widths[j], columnAlign))
+		}
+		lines[i] = strings.TrimRight(strings.Join(cells, monoColumnGap), " ")
+	}
+	return "<pre>" + strings.Join(lines, "\n") + "</pre>"
+}
+
+// PlainMoney formats an amount like Money but unescaped, for MonoTable
+// cells.
+func PlainMoney(amount decimal.Decimal, currency string) string {
+	sign, amount := splitSign(amount)
+	return sign + CurrencySymbol(currency) + FormatAmount(amount, currency) + " " + currency
+}
+
+// ExpenseListMono renders header followed by the expenses as a MonoTable:
+// number, creation time in loc, amount right-aligned and the merchant or
+// description cut to monoDescriptionWidth.
+func ExpenseListMono(header string, expenses []models.Expense, loc *time.Location) string {
+	rows := make([][]string, len(expenses))
+	for i := range expenses {
+		exp := &expenses[i]
+		desc := exp.Merchant
+		if desc == "" {
+			desc = exp.Description
+		}
+		rows[i] = []string{
+			"#" + strconv.FormatInt(exp.UserExpenseNumber, 10),
+			exp.CreatedAt.In(loc).Format("Jan 2 15:04"),
+			PlainMoney(exp.Amount, exp.Currency),
+			TruncateWidth(desc, monoDescriptionWidth),
+		}
+	}
+	return header + "\n\n" + MonoTable(rows, []Align{AlignRight, AlignLeft, AlignRight})
+}
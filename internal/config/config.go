@@ -2,6 +2,7 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -11,12 +12,81 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/joho/godotenv"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
 )
 
 const envTrue = "true"
 
+// Default expense amount limits.
+const (
+	defaultAmountSoftLimit = 1000
+	defaultAmountHardCap   = 1_000_000
+)
+
+// Bounds of the configurable description and merchant length.
+const (
+	minDescriptionLength = 20
+	maxDescriptionLength = 1000
+)
+
+// DefaultMaxFreeTextLength is the longest message, in characters, that is
+// parsed as a free-text expense or bank notification by default.
+const DefaultMaxFreeTextLength = 1000
+
+// Bounds of the configurable free-text length; Telegram messages are at
+// most 4096 characters.
+const (
+	minFreeTextLength = 100
+	maxFreeTextLength = 4096
+)
+
+// Default and largest number of receipts scanned at the same time.
+const (
+	defaultReceiptWorkers = 4
+	maxReceiptWorkers     = 32
+)
+
+// maxDBConnectAttempts is the largest number of database connection
+// attempts at startup. The defaults are database.DefaultConnectAttempts
+// and database.DefaultConnectMaxWait.
+const maxDBConnectAttempts = 50
+
+// Default limits on AI category suggestions: the smallest amount worth a
+// suggestion and the suggestions each user gets per day.
+const (
+	defaultAISuggestMinAmount = 5
+	defaultAISuggestDailyCap  = 50
+)
+
+// Default confidence band in which AI-picked categories are flagged.
+const (
+	defaultAIUncertainMin = 0.5
+	defaultAIUncertainMax = 0.7
+)
+
+// Receipt storage backends accepted in RECEIPT_STORAGE.
+const (
+	ReceiptStorageNone = "none"
+	ReceiptStorageDisk = "disk"
+	// receiptStorageS3 is reserved for an S3-compatible backend.
+	receiptStorageS3 = "s3"
+
+	defaultReceiptStorageDir = "data/receipts"
+)
+
+// BuildInfo identifies the running build. main sets it from the values
+// linked in with -ldflags.
+type BuildInfo struct {
+	Version string
+	Commit  string
+	Date    string
+}
+
 // Config holds all configuration for the application.
 type Config struct {
 	TelegramBotToken     string
@@ -33,6 +103,7 @@ type Config struct {
 	DailyReminderEnabled bool
 	ReminderHour         int
 	ReminderTimezone     string
+	ReminderLoggedNote   bool
 
 	// Weekly report configuration.
 	WeeklyReportEnabled bool
@@ -42,6 +113,94 @@ type Config struct {
 	// reflection recap together with the weekly report. It only takes
 	// effect when WeeklyReportEnabled is true.
 	WeeklyHabitRecapEnabled bool
+	// WeeklyAIReviewEnabled sends the digest of AI-categorized expenses to
+	// review together with the weekly report. It only takes effect when
+	// WeeklyReportEnabled is true.
+	WeeklyAIReviewEnabled bool
+
+	// AmountSoftLimit is the amount above which a single chat expense needs
+	// an extra confirmation before it is saved. Users can override it with
+	// /settings maxamount. Zero disables the check.
+	AmountSoftLimit decimal.Decimal
+	// AmountHardCap is the largest amount accepted for a single expense.
+	// Zero disables the cap.
+	AmountHardCap decimal.Decimal
+
+	// AIUncertainMin and AIUncertainMax bound the Gemini confidence band
+	// [min, max) in which an applied AI category is flagged as "medium
+	// confidence" on the confirmation. Equal values turn the flag off.
+	AIUncertainMin float64
+	AIUncertainMax float64
+
+	// AISuggestMinAmount is the amount below which chat expenses skip the
+	// AI category suggestion. Zero asks for every amount.
+	AISuggestMinAmount decimal.Decimal
+	// AISuggestDailyCap is how many AI category suggestions each user gets
+	// per day; later expenses that day skip the suggestion. Zero means no
+	// cap.
+	AISuggestDailyCap int
+
+	// MaxDescriptionLength is the longest description or merchant, in
+	// characters, that is stored. Longer text is cut with an ellipsis.
+	MaxDescriptionLength int
+
+	// MaxFreeTextLength is the longest message or caption, in characters,
+	// that is parsed as a free-text expense or bank notification. Longer
+	// messages skip parsing entirely.
+	MaxFreeTextLength int
+
+	// GeminiMonthlyTokenBudget is how many Gemini tokens the bot may use
+	// per calendar month. Once it is used up, receipt OCR, voice input and
+	// AI category suggestions are off until the next month. Zero means no
+	// budget.
+	GeminiMonthlyTokenBudget int64
+
+	// ReceiptWorkers is how many receipt photos are downloaded and scanned
+	// with Gemini at the same time. Further receipts wait in a queue.
+	ReceiptWorkers int
+
+	// DBConnectAttempts and DBConnectMaxWait bound how long startup waits
+	// for the database to accept connections.
+	DBConnectAttempts int
+	DBConnectMaxWait  time.Duration
+
+	// MigrationsAllowChecksumChange lets startup continue when migrations
+	// that were already applied have been edited since. For emergencies.
+	MigrationsAllowChecksumChange bool
+
+	// GeocoderURL is the base URL of a Nominatim-compatible service used to
+	// name the places of expense locations. Empty stores raw coordinates.
+	GeocoderURL string
+
+	// ReceiptStorage selects where receipt images are kept besides
+	// Telegram: ReceiptStorageNone or ReceiptStorageDisk. ReceiptStorageDir
+	// is the directory of the disk backend.
+	ReceiptStorage    string
+	ReceiptStorageDir string
+
+	// HTTPAddr is the listen address of the optional HTTP server serving
+	// the health check and REST API, e.g. ":8080". Empty disables it.
+	HTTPAddr string
+
+	// HTTPTrustedProxyHeader names the header, e.g. X-Forwarded-For or
+	// X-Real-IP, that a reverse proxy in front of the HTTP server sets to
+	// the client address. Empty means clients connect directly and the
+	// connection's address is used.
+	HTTPTrustedProxyHeader string
+
+	// EnableDemoTools registers the superadmin /seeddemo and /wipedemo
+	// commands that fill the database with made-up expenses. Never set it
+	// in production.
+	EnableDemoTools bool
+
+	// SeedCategories are the shared categories created on startup, and
+	// MinimalCategories the subset a new user gets from the "Minimal set"
+	// choice on /start. Both come from SEED_CATEGORIES when it is set.
+	SeedCategories    []string
+	MinimalCategories []string
+
+	// Build is the version of the running binary, shown by /about.
+	Build BuildInfo
 
 	// OpenTelemetry configuration.
 	OTelEnabled         bool
@@ -69,8 +228,25 @@ func Load() (*Config, error) {
 	if err := applyExchangeRateConfig(cfg); err != nil {
 		return nil, err
 	}
+	if err := applyGeocoderConfig(cfg); err != nil {
+		return nil, err
+	}
+	if err := applyReceiptStorageConfig(cfg); err != nil {
+		return nil, err
+	}
+	if err := applySeedCategoriesConfig(cfg); err != nil {
+		return nil, err
+	}
 	applyReminderConfig(cfg)
 	applyWeeklyReportConfig(cfg)
+	applyAmountLimitConfig(cfg)
+	applyAIUncertainConfig(cfg)
+	applyAISuggestLimitConfig(cfg)
+	applyReceiptWorkerConfig(cfg)
+	applyDescriptionLengthConfig(cfg)
+	applyFreeTextLengthConfig(cfg)
+	applyGeminiBudgetConfig(cfg)
+	applyDBConnectConfig(cfg)
 	applyOTelConfig(cfg)
 	cfg.WhitelistedUserIDs = parseWhitelistedUserIDs(os.Getenv("WHITELISTED_USER_IDS"))
 	cfg.WhitelistedUsernames = parseWhitelistedUsernames(os.Getenv("WHITELISTED_USERNAMES"))
@@ -86,16 +262,19 @@ func Load() (*Config, error) {
 
 func newDefaultConfig() *Config {
 	return &Config{
-		TelegramBotToken:      os.Getenv("TELEGRAM_BOT_TOKEN"),
-		DatabaseURL:           os.Getenv("DATABASE_URL"),
-		GeminiAPIKey:          os.Getenv("GEMINI_API_KEY"),
-		ExchangeRateBaseURL:   "https://api.frankfurter.app",
-		ExchangeRateTimeout:   5 * time.Second,
-		ExchangeRateCacheTTL:  12 * time.Hour,
-		DraftExpiration:       24 * time.Hour,
-		LogLevel:              os.Getenv("LOG_LEVEL"),
-		resolvedSuperadmins:   make(map[string]int64),
-		resolvedSuperadminIDs: make(map[int64]struct{}),
+		TelegramBotToken:       os.Getenv("TELEGRAM_BOT_TOKEN"),
+		DatabaseURL:            os.Getenv("DATABASE_URL"),
+		GeminiAPIKey:           os.Getenv("GEMINI_API_KEY"),
+		ExchangeRateBaseURL:    "https://api.frankfurter.app",
+		ExchangeRateTimeout:    5 * time.Second,
+		ExchangeRateCacheTTL:   12 * time.Hour,
+		DraftExpiration:        24 * time.Hour,
+		LogLevel:               os.Getenv("LOG_LEVEL"),
+		HTTPAddr:               strings.TrimSpace(os.Getenv("HTTP_ADDR")),
+		HTTPTrustedProxyHeader: strings.TrimSpace(os.Getenv("HTTP_TRUSTED_PROXY_HEADER")),
+		EnableDemoTools:        os.Getenv("ENABLE_DEMO_TOOLS") == envTrue,
+		resolvedSuperadmins:    make(map[string]int64),
+		resolvedSuperadminIDs:  make(map[int64]struct{}),
 	}
 }
 
@@ -122,6 +301,119 @@ func applyExchangeRateConfig(cfg *Config) error {
 	return nil
 }
 
+func applyGeocoderConfig(cfg *Config) error {
+	geocoderURL := strings.TrimSpace(os.Getenv("GEOCODER_URL"))
+	if geocoderURL == "" {
+		return nil
+	}
+	// Validate URL scheme to prevent SSRF.
+	if !strings.HasPrefix(geocoderURL, "http://") && !strings.HasPrefix(geocoderURL, "https://") {
+		return errors.New("GEOCODER_URL must use http:// or https:// scheme")
+	}
+	cfg.GeocoderURL = geocoderURL
+	return nil
+}
+
+func applyReceiptStorageConfig(cfg *Config) error {
+	cfg.ReceiptStorage = ReceiptStorageNone
+	cfg.ReceiptStorageDir = defaultReceiptStorageDir
+	if dir := strings.TrimSpace(os.Getenv("RECEIPT_STORAGE_DIR")); dir != "" {
+		cfg.ReceiptStorageDir = dir
+	}
+
+	switch backend := strings.ToLower(strings.TrimSpace(os.Getenv("RECEIPT_STORAGE"))); backend {
+	case "", ReceiptStorageNone:
+	case ReceiptStorageDisk:
+		cfg.ReceiptStorage = ReceiptStorageDisk
+	case receiptStorageS3:
+		return errors.New("RECEIPT_STORAGE=s3 is not supported yet; use none or disk")
+	default:
+		return fmt.Errorf("RECEIPT_STORAGE must be none or disk, got %q", backend)
+	}
+	return nil
+}
+
+// seedCategoriesFile is the JSON read from SEED_CATEGORIES.
+type seedCategoriesFile struct {
+	Default []string `json:"default"`
+	Minimal []string `json:"minimal"`
+}
+
+// applySeedCategoriesConfig reads SEED_CATEGORIES, either inline JSON or
+// the path of a JSON file like {"default": [...], "minimal": [...]}.
+// Without "minimal" the built-in minimal names found in "default" are
+// used.
+func applySeedCategoriesConfig(cfg *Config) error {
+	cfg.SeedCategories = models.DefaultCategories
+	cfg.MinimalCategories = models.MinimalCategories
+	raw := strings.TrimSpace(os.Getenv("SEED_CATEGORIES"))
+	if raw == "" {
+		return nil
+	}
+
+	data := []byte(raw)
+	if !strings.HasPrefix(raw, "{") {
+		var err error
+		if data, err = os.ReadFile(raw); err != nil {
+			return fmt.Errorf("failed to read SEED_CATEGORIES file: %w", err)
+		}
+	}
+	var seeds seedCategoriesFile
+	if err := json.Unmarshal(data, &seeds); err != nil {
+		return fmt.Errorf("failed to parse SEED_CATEGORIES: %w", err)
+	}
+	if len(seeds.Default) == 0 {
+		return errors.New("SEED_CATEGORIES must list at least one default category")
+	}
+	if err := validateCategoryNames(seeds.Default); err != nil {
+		return fmt.Errorf("invalid SEED_CATEGORIES default: %w", err)
+	}
+
+	seeded := make(map[string]bool, len(seeds.Default))
+	for _, name := range seeds.Default {
+		seeded[strings.ToLower(name)] = true
+	}
+	if seeds.Minimal == nil {
+		for _, name := range models.MinimalCategories {
+			if seeded[strings.ToLower(name)] {
+				seeds.Minimal = append(seeds.Minimal, name)
+			}
+		}
+	}
+	if err := validateCategoryNames(seeds.Minimal); err != nil {
+		return fmt.Errorf("invalid SEED_CATEGORIES minimal: %w", err)
+	}
+	for _, name := range seeds.Minimal {
+		if !seeded[strings.ToLower(name)] {
+			return fmt.Errorf("SEED_CATEGORIES minimal category %q is not in default", name)
+		}
+	}
+
+	cfg.SeedCategories = seeds.Default
+	cfg.MinimalCategories = seeds.Minimal
+	return nil
+}
+
+// validateCategoryNames rejects blank, overlong and control-character
+// names and names listed twice, ignoring case.
+func validateCategoryNames(names []string) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		switch {
+		case strings.TrimSpace(name) != name || name == "":
+			return fmt.Errorf("category %q is blank or has surrounding spaces", name)
+		case len(name) > models.MaxCategoryNameLength:
+			return fmt.Errorf("category %q is longer than %d characters", name, models.MaxCategoryNameLength)
+		case strings.IndexFunc(name, unicode.IsControl) >= 0:
+			return fmt.Errorf("category %q contains control characters", name)
+		case seen[strings.ToLower(name)]:
+			return fmt.Errorf("category %q is listed twice", name)
+		}
+		seen[strings.ToLower(name)] = true
+	}
+	return nil
+}
+
 func positiveDurationOrDefault(value string, fallback time.Duration) time.Duration {
 	duration, err := time.ParseDuration(value)
 	if err != nil || duration <= 0 {
@@ -132,6 +424,7 @@ func positiveDurationOrDefault(value string, fallback time.Duration) time.Durati
 
 func applyReminderConfig(cfg *Config) {
 	cfg.DailyReminderEnabled = os.Getenv("DAILY_REMINDER_ENABLED") == envTrue
+	cfg.ReminderLoggedNote = os.Getenv("REMINDER_LOGGED_NOTE") == envTrue
 	cfg.ReminderHour = 20
 	if hourStr := os.Getenv("REMINDER_HOUR"); hourStr != "" {
 		if h, err := strconv.Atoi(hourStr); err == nil && h >= 0 && h <= 23 {
@@ -168,6 +461,145 @@ func applyWeeklyReportConfig(cfg *Config) {
 	if cfg.WeeklyHabitRecapEnabled && !cfg.WeeklyReportEnabled {
 		log.Printf("WEEKLY_HABIT_RECAP_ENABLED is set but WEEKLY_REPORT_ENABLED is not; weekly habit recap will not run")
 	}
+	cfg.WeeklyAIReviewEnabled = os.Getenv("WEEKLY_AI_REVIEW_ENABLED") == envTrue
+	if cfg.WeeklyAIReviewEnabled && !cfg.WeeklyReportEnabled {
+		log.Printf("WEEKLY_AI_REVIEW_ENABLED is set but WEEKLY_REPORT_ENABLED is not; weekly AI review will not run")
+	}
+}
+
+func applyAmountLimitConfig(cfg *Config) {
+	cfg.AmountSoftLimit = nonNegativeDecimalOrDefault("AMOUNT_SOFT_LIMIT", decimal.NewFromInt(defaultAmountSoftLimit))
+	cfg.AmountHardCap = nonNegativeDecimalOrDefault("AMOUNT_HARD_CAP", decimal.NewFromInt(defaultAmountHardCap))
+	if !cfg.AmountHardCap.IsZero() && cfg.AmountSoftLimit.GreaterThan(cfg.AmountHardCap) {
+		log.Printf("AMOUNT_SOFT_LIMIT %s is above AMOUNT_HARD_CAP %s; using the hard cap as the soft limit",
+			cfg.AmountSoftLimit, cfg.AmountHardCap)
+		cfg.AmountSoftLimit = cfg.AmountHardCap
+	}
+}
+
+func nonNegativeDecimalOrDefault(env string, fallback decimal.Decimal) decimal.Decimal {
+	raw := strings.TrimSpace(os.Getenv(env))
+	if raw == "" {
+		return fallback
+	}
+	value, err := decimal.NewFromString(raw)
+	if err != nil || value.IsNegative() {
+		log.Printf("invalid %s %q, using default %s", env, raw, fallback)
+		return fallback
+	}
+	return value
+}
+
+func applyAISuggestLimitConfig(cfg *Config) {
+	cfg.AISuggestMinAmount = nonNegativeDecimalOrDefault("AI_SUGGEST_MIN_AMOUNT",
+		decimal.NewFromInt(defaultAISuggestMinAmount))
+	cfg.AISuggestDailyCap = defaultAISuggestDailyCap
+	if raw := strings.TrimSpace(os.Getenv("AI_SUGGEST_DAILY_CAP")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			log.Printf("invalid AI_SUGGEST_DAILY_CAP %q, using default %d", raw, defaultAISuggestDailyCap)
+			return
+		}
+		cfg.AISuggestDailyCap = n
+	}
+}
+
+func applyAIUncertainConfig(cfg *Config) {
+	cfg.AIUncertainMin = confidenceOrDefault("AI_UNCERTAIN_MIN", defaultAIUncertainMin)
+	cfg.AIUncertainMax = confidenceOrDefault("AI_UNCERTAIN_MAX", defaultAIUncertainMax)
+	if cfg.AIUncertainMin > cfg.AIUncertainMax {
+		log.Printf("AI_UNCERTAIN_MIN %.2f is above AI_UNCERTAIN_MAX %.2f; using defaults",
+			cfg.AIUncertainMin, cfg.AIUncertainMax)
+		cfg.AIUncertainMin = defaultAIUncertainMin
+		cfg.AIUncertainMax = defaultAIUncertainMax
+	}
+}
+
+func confidenceOrDefault(env string, fallback float64) float64 {
+	raw := strings.TrimSpace(os.Getenv(env))
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value < 0 || value > 1 {
+		log.Printf("invalid %s %q, using default %.2f", env, raw, fallback)
+		return fallback
+	}
+	return value
+}
+
+func applyReceiptWorkerConfig(cfg *Config) {
+	cfg.ReceiptWorkers = defaultReceiptWorkers
+	raw := strings.TrimSpace(os.Getenv("RECEIPT_WORKERS"))
+	if raw == "" {
+		return
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > maxReceiptWorkers {
+		log.Printf("invalid RECEIPT_WORKERS %q, using default %d", raw, defaultReceiptWorkers)
+		return
+	}
+	cfg.ReceiptWorkers = n
+}
+
+func applyDescriptionLengthConfig(cfg *Config) {
+	cfg.MaxDescriptionLength = models.DefaultMaxDescriptionLength
+	raw := strings.TrimSpace(os.Getenv("MAX_DESCRIPTION_LENGTH"))
+	if raw == "" {
+		return
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < minDescriptionLength || n > maxDescriptionLength {
+		log.Printf("invalid MAX_DESCRIPTION_LENGTH %q, using default %d", raw, models.DefaultMaxDescriptionLength)
+		return
+	}
+	cfg.MaxDescriptionLength = n
+}
+
+func applyFreeTextLengthConfig(cfg *Config) {
+	cfg.MaxFreeTextLength = DefaultMaxFreeTextLength
+	raw := strings.TrimSpace(os.Getenv("MAX_FREE_TEXT_LENGTH"))
+	if raw == "" {
+		return
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < minFreeTextLength || n > maxFreeTextLength {
+		log.Printf("invalid MAX_FREE_TEXT_LENGTH %q, using default %d", raw, DefaultMaxFreeTextLength)
+		return
+	}
+	cfg.MaxFreeTextLength = n
+}
+
+func applyGeminiBudgetConfig(cfg *Config) {
+	raw := strings.TrimSpace(os.Getenv("GEMINI_MONTHLY_TOKEN_BUDGET"))
+	if raw == "" {
+		return
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n < 0 {
+		log.Printf("invalid GEMINI_MONTHLY_TOKEN_BUDGET %q, using no budget", raw)
+		return
+	}
+	cfg.GeminiMonthlyTokenBudget = n
+}
+
+func applyDBConnectConfig(cfg *Config) {
+	cfg.DBConnectAttempts = database.DefaultConnectAttempts
+	if raw := strings.TrimSpace(os.Getenv("DB_CONNECT_ATTEMPTS")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxDBConnectAttempts {
+			log.Printf("invalid DB_CONNECT_ATTEMPTS %q, using default %d", raw, database.DefaultConnectAttempts)
+		} else {
+			cfg.DBConnectAttempts = n
+		}
+	}
+
+	cfg.DBConnectMaxWait = database.DefaultConnectMaxWait
+	if raw := strings.TrimSpace(os.Getenv("DB_CONNECT_MAX_WAIT")); raw != "" {
+		cfg.DBConnectMaxWait = positiveDurationOrDefault(raw, database.DefaultConnectMaxWait)
+	}
+
+	cfg.MigrationsAllowChecksumChange = os.Getenv("MIGRATIONS_ALLOW_CHECKSUM_CHANGE") == envTrue
 }
 
 func applyOTelConfig(cfg *Config) {
@@ -1,10 +1,14 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
 )
 
 const (
@@ -17,6 +21,14 @@ const (
 	envAllowedChatIDs                        = "ALLOWED_CHAT_IDS"
 	envReminderHour                          = "REMINDER_HOUR"
 	envReminderTimezone                      = "REMINDER_TIMEZONE"
+	envAmountSoftLimit                       = "AMOUNT_SOFT_LIMIT"
+	envAIUncertainMin                        = "AI_UNCERTAIN_MIN"
+	envAIUncertainMax                        = "AI_UNCERTAIN_MAX"
+	envAmountHardCap                         = "AMOUNT_HARD_CAP"
+	envAISuggestMinAmount                    = "AI_SUGGEST_MIN_AMOUNT"
+	envAISuggestDailyCap                     = "AI_SUGGEST_DAILY_CAP"
+	envReceiptWorkers                        = "RECEIPT_WORKERS"
+	envMaxDescriptionLength                  = "MAX_DESCRIPTION_LENGTH"
 	adminUsernameConfigTest                  = "admin"
 	aliceUsernameConfigTest                  = "alice"
 	charlieUsernameConfigTest                = "charlie"
@@ -97,6 +109,34 @@ func TestLoad(t *testing.T) {
 		require.Equal(t, testGeminiKeyConfig, cfg.GeminiAPIKey)
 	})
 
+	t.Run("loads HTTP address from env", func(t *testing.T) {
+		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
+		t.Setenv(envDatabaseURL, testDatabaseURLConfig)
+		t.Setenv(envWhitelistedUserIDs, "123")
+		t.Setenv("HTTP_ADDR", " :8080 ")
+		t.Setenv("HTTP_TRUSTED_PROXY_HEADER", " X-Real-IP ")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.Equal(t, ":8080", cfg.HTTPAddr)
+		require.Equal(t, "X-Real-IP", cfg.HTTPTrustedProxyHeader)
+	})
+
+	t.Run("demo tools are off unless enabled", func(t *testing.T) {
+		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
+		t.Setenv(envDatabaseURL, testDatabaseURLConfig)
+		t.Setenv(envWhitelistedUserIDs, "123")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.False(t, cfg.EnableDemoTools)
+
+		t.Setenv("ENABLE_DEMO_TOOLS", "true")
+		cfg, err = Load()
+		require.NoError(t, err)
+		require.True(t, cfg.EnableDemoTools)
+	})
+
 	t.Run("loads exchange config from env", func(t *testing.T) {
 		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
 		t.Setenv(envDatabaseURL, testDatabaseURLConfig)
@@ -231,6 +271,18 @@ func TestLoad_DailyReminder(t *testing.T) {
 		cfg, err := Load()
 		require.NoError(t, err)
 		require.False(t, cfg.DailyReminderEnabled)
+		require.False(t, cfg.ReminderLoggedNote)
+	})
+
+	t.Run("parses REMINDER_LOGGED_NOTE=true", func(t *testing.T) {
+		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
+		t.Setenv(envDatabaseURL, testDatabaseURLConfig)
+		t.Setenv(envWhitelistedUserIDs, "123")
+		t.Setenv("REMINDER_LOGGED_NOTE", "true")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.True(t, cfg.ReminderLoggedNote)
 	})
 
 	t.Run("parses valid REMINDER_HOUR", func(t *testing.T) {
@@ -425,6 +477,22 @@ func TestLoad_WeeklyReport(t *testing.T) {
 		require.True(t, cfg.WeeklyHabitRecapEnabled)
 	})
 
+	t.Run("parses WEEKLY_AI_REVIEW_ENABLED", func(t *testing.T) {
+		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
+		t.Setenv(envDatabaseURL, testDatabaseURLConfig)
+		t.Setenv(envWhitelistedUserIDs, "123")
+		t.Setenv("WEEKLY_REPORT_ENABLED", "true")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.False(t, cfg.WeeklyAIReviewEnabled)
+
+		t.Setenv("WEEKLY_AI_REVIEW_ENABLED", "true")
+		cfg, err = Load()
+		require.NoError(t, err)
+		require.True(t, cfg.WeeklyAIReviewEnabled)
+	})
+
 	t.Run("defaults WEEKLY_HABIT_RECAP_ENABLED to false", func(t *testing.T) {
 		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
 		t.Setenv(envDatabaseURL, testDatabaseURLConfig)
@@ -778,6 +846,260 @@ func TestConfig_CheckSuperAdmin(t *testing.T) {
 	})
 }
 
+func TestLoad_AmountLimits(t *testing.T) {
+	setRequired := func(t *testing.T) {
+		t.Helper()
+		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
+		t.Setenv(envDatabaseURL, testDatabaseURLConfig)
+		t.Setenv(envWhitelistedUserIDs, "123")
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		setRequired(t)
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.Equal(t, "1000", cfg.AmountSoftLimit.String())
+		require.Equal(t, "1000000", cfg.AmountHardCap.String())
+	})
+
+	t.Run("parses custom limits", func(t *testing.T) {
+		setRequired(t)
+		t.Setenv(envAmountSoftLimit, "250.50")
+		t.Setenv(envAmountHardCap, "5000")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.Equal(t, "250.5", cfg.AmountSoftLimit.String())
+		require.Equal(t, "5000", cfg.AmountHardCap.String())
+	})
+
+	t.Run("invalid values fall back to defaults", func(t *testing.T) {
+		setRequired(t)
+		t.Setenv(envAmountSoftLimit, "-1")
+		t.Setenv(envAmountHardCap, "lots")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.Equal(t, "1000", cfg.AmountSoftLimit.String())
+		require.Equal(t, "1000000", cfg.AmountHardCap.String())
+	})
+
+	t.Run("soft limit is capped at the hard cap", func(t *testing.T) {
+		setRequired(t)
+		t.Setenv(envAmountSoftLimit, "900")
+		t.Setenv(envAmountHardCap, "500")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.Equal(t, "500", cfg.AmountSoftLimit.String())
+	})
+}
+
+func TestLoad_AISuggestLimits(t *testing.T) {
+	setRequired := func(t *testing.T) {
+		t.Helper()
+		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
+		t.Setenv(envDatabaseURL, testDatabaseURLConfig)
+		t.Setenv(envWhitelistedUserIDs, "123")
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		setRequired(t)
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.Equal(t, "5", cfg.AISuggestMinAmount.String())
+		require.Equal(t, 50, cfg.AISuggestDailyCap)
+	})
+
+	t.Run("parses custom limits", func(t *testing.T) {
+		setRequired(t)
+		t.Setenv(envAISuggestMinAmount, "2.50")
+		t.Setenv(envAISuggestDailyCap, "0")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.Equal(t, "2.5", cfg.AISuggestMinAmount.String())
+		require.Zero(t, cfg.AISuggestDailyCap)
+	})
+
+	t.Run("invalid values fall back to defaults", func(t *testing.T) {
+		setRequired(t)
+		t.Setenv(envAISuggestMinAmount, "-1")
+		t.Setenv(envAISuggestDailyCap, "many")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.Equal(t, "5", cfg.AISuggestMinAmount.String())
+		require.Equal(t, 50, cfg.AISuggestDailyCap)
+	})
+}
+
+func TestLoad_AIUncertainBand(t *testing.T) {
+	setRequired := func(t *testing.T) {
+		t.Helper()
+		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
+		t.Setenv(envDatabaseURL, testDatabaseURLConfig)
+		t.Setenv(envWhitelistedUserIDs, "123")
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		setRequired(t)
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.InDelta(t, 0.5, cfg.AIUncertainMin, 1e-9)
+		require.InDelta(t, 0.7, cfg.AIUncertainMax, 1e-9)
+	})
+
+	t.Run("parses custom band", func(t *testing.T) {
+		setRequired(t)
+		t.Setenv(envAIUncertainMin, "0.6")
+		t.Setenv(envAIUncertainMax, "0.9")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.InDelta(t, 0.6, cfg.AIUncertainMin, 1e-9)
+		require.InDelta(t, 0.9, cfg.AIUncertainMax, 1e-9)
+	})
+
+	t.Run("invalid values fall back to defaults", func(t *testing.T) {
+		setRequired(t)
+		t.Setenv(envAIUncertainMin, "1.5")
+		t.Setenv(envAIUncertainMax, "high")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.InDelta(t, 0.5, cfg.AIUncertainMin, 1e-9)
+		require.InDelta(t, 0.7, cfg.AIUncertainMax, 1e-9)
+	})
+
+	t.Run("inverted band falls back to defaults", func(t *testing.T) {
+		setRequired(t)
+		t.Setenv(envAIUncertainMin, "0.8")
+		t.Setenv(envAIUncertainMax, "0.6")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.InDelta(t, 0.5, cfg.AIUncertainMin, 1e-9)
+		require.InDelta(t, 0.7, cfg.AIUncertainMax, 1e-9)
+	})
+}
+
+func TestLoad_ReceiptWorkers(t *testing.T) {
+	setRequired := func(t *testing.T) {
+		t.Helper()
+		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
+		t.Setenv(envDatabaseURL, testDatabaseURLConfig)
+		t.Setenv(envWhitelistedUserIDs, "123")
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  int
+	}{
+		{name: "default", value: "", want: 4},
+		{name: "custom", value: "8", want: 8},
+		{name: "zero falls back", value: "0", want: 4},
+		{name: "too many falls back", value: "100", want: 4},
+		{name: "not a number falls back", value: "many", want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequired(t)
+			t.Setenv(envReceiptWorkers, tt.value)
+
+			cfg, err := Load()
+			require.NoError(t, err)
+			require.Equal(t, tt.want, cfg.ReceiptWorkers)
+		})
+	}
+}
+
+func TestLoad_MaxDescriptionLength(t *testing.T) {
+	setRequired := func(t *testing.T) {
+		t.Helper()
+		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
+		t.Setenv(envDatabaseURL, testDatabaseURLConfig)
+		t.Setenv(envWhitelistedUserIDs, "123")
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  int
+	}{
+		{name: "default", value: "", want: 200},
+		{name: "custom", value: "80", want: 80},
+		{name: "too short falls back", value: "5", want: 200},
+		{name: "too long falls back", value: "5000", want: 200},
+		{name: "not a number falls back", value: "long", want: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequired(t)
+			t.Setenv(envMaxDescriptionLength, tt.value)
+
+			cfg, err := Load()
+			require.NoError(t, err)
+			require.Equal(t, tt.want, cfg.MaxDescriptionLength)
+		})
+	}
+}
+
+func TestLoad_MaxFreeTextLength(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  int
+	}{
+		{name: "default", value: "", want: 1000},
+		{name: "custom", value: "500", want: 500},
+		{name: "too short falls back", value: "10", want: 1000},
+		{name: "above Telegram's limit falls back", value: "5000", want: 1000},
+		{name: "not a number falls back", value: "huge", want: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
+			t.Setenv(envDatabaseURL, testDatabaseURLConfig)
+			t.Setenv(envWhitelistedUserIDs, "123")
+			t.Setenv("MAX_FREE_TEXT_LENGTH", tt.value)
+
+			cfg, err := Load()
+			require.NoError(t, err)
+			require.Equal(t, tt.want, cfg.MaxFreeTextLength)
+		})
+	}
+}
+
+func TestLoad_GeminiMonthlyTokenBudget(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  int64
+	}{
+		{name: "default is no budget", value: "", want: 0},
+		{name: "custom", value: "2000000", want: 2_000_000},
+		{name: "negative falls back", value: "-5", want: 0},
+		{name: "not a number falls back", value: "lots", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
+			t.Setenv(envDatabaseURL, testDatabaseURLConfig)
+			t.Setenv(envWhitelistedUserIDs, "123")
+			t.Setenv("GEMINI_MONTHLY_TOKEN_BUDGET", tt.value)
+
+			cfg, err := Load()
+			require.NoError(t, err)
+			require.Equal(t, tt.want, cfg.GeminiMonthlyTokenBudget)
+		})
+	}
+}
+
 func TestLoad_OTelConfig(t *testing.T) {
 	t.Run("uses secure-by-default OTel settings", func(t *testing.T) {
 		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
@@ -828,3 +1150,176 @@ func TestLoad_OTelConfig(t *testing.T) {
 		require.InEpsilon(t, 1.0, cfg.OTelTraceSampleRate, 1e-12)
 	})
 }
+
+func TestLoad_DBConnect(t *testing.T) {
+	setRequired := func(t *testing.T) {
+		t.Helper()
+		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
+		t.Setenv(envDatabaseURL, testDatabaseURLConfig)
+		t.Setenv(envWhitelistedUserIDs, "123")
+	}
+
+	tests := []struct {
+		name         string
+		attempts     string
+		maxWait      string
+		wantAttempts int
+		wantMaxWait  time.Duration
+	}{
+		{name: "defaults", wantAttempts: 8, wantMaxWait: 30 * time.Second},
+		{name: "custom", attempts: "3", maxWait: "1m", wantAttempts: 3, wantMaxWait: time.Minute},
+		{name: "zero attempts fall back", attempts: "0", wantAttempts: 8, wantMaxWait: 30 * time.Second},
+		{name: "too many attempts fall back", attempts: "500", wantAttempts: 8, wantMaxWait: 30 * time.Second},
+		{name: "bad wait falls back", maxWait: "soon", wantAttempts: 8, wantMaxWait: 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequired(t)
+			t.Setenv("DB_CONNECT_ATTEMPTS", tt.attempts)
+			t.Setenv("DB_CONNECT_MAX_WAIT", tt.maxWait)
+
+			cfg, err := Load()
+			require.NoError(t, err)
+			require.Equal(t, tt.wantAttempts, cfg.DBConnectAttempts)
+			require.Equal(t, tt.wantMaxWait, cfg.DBConnectMaxWait)
+			require.False(t, cfg.MigrationsAllowChecksumChange)
+		})
+	}
+
+	t.Run("migration checksum override", func(t *testing.T) {
+		setRequired(t)
+		t.Setenv("MIGRATIONS_ALLOW_CHECKSUM_CHANGE", "true")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.True(t, cfg.MigrationsAllowChecksumChange)
+	})
+}
+
+func TestLoad_Geocoder(t *testing.T) {
+	setRequired := func(t *testing.T) {
+		t.Helper()
+		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
+		t.Setenv(envDatabaseURL, testDatabaseURLConfig)
+		t.Setenv(envWhitelistedUserIDs, "123")
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		setRequired(t)
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.Empty(t, cfg.GeocoderURL)
+	})
+
+	t.Run("loads URL from env", func(t *testing.T) {
+		setRequired(t)
+		t.Setenv("GEOCODER_URL", " https://nominatim.openstreetmap.org ")
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.Equal(t, "https://nominatim.openstreetmap.org", cfg.GeocoderURL)
+	})
+
+	t.Run("rejects other schemes", func(t *testing.T) {
+		setRequired(t)
+		t.Setenv("GEOCODER_URL", "file:///etc/passwd")
+		_, err := Load()
+		require.ErrorContains(t, err, "GEOCODER_URL")
+	})
+}
+
+func TestLoad_ReceiptStorage(t *testing.T) {
+	setRequired := func(t *testing.T) {
+		t.Helper()
+		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
+		t.Setenv(envDatabaseURL, testDatabaseURLConfig)
+		t.Setenv(envWhitelistedUserIDs, "123")
+	}
+
+	t.Run("off by default", func(t *testing.T) {
+		setRequired(t)
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.Equal(t, ReceiptStorageNone, cfg.ReceiptStorage)
+		require.Equal(t, defaultReceiptStorageDir, cfg.ReceiptStorageDir)
+	})
+
+	t.Run("loads the disk backend", func(t *testing.T) {
+		setRequired(t)
+		t.Setenv("RECEIPT_STORAGE", " Disk ")
+		t.Setenv("RECEIPT_STORAGE_DIR", "/var/lib/expense-bot/receipts")
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.Equal(t, ReceiptStorageDisk, cfg.ReceiptStorage)
+		require.Equal(t, "/var/lib/expense-bot/receipts", cfg.ReceiptStorageDir)
+	})
+
+	t.Run("rejects s3 until it is implemented", func(t *testing.T) {
+		setRequired(t)
+		t.Setenv("RECEIPT_STORAGE", "s3")
+		_, err := Load()
+		require.ErrorContains(t, err, "not supported yet")
+	})
+
+	t.Run("rejects unknown backends", func(t *testing.T) {
+		setRequired(t)
+		t.Setenv("RECEIPT_STORAGE", "ftp")
+		_, err := Load()
+		require.ErrorContains(t, err, "RECEIPT_STORAGE")
+	})
+}
+
+func TestLoad_SeedCategories(t *testing.T) {
+	setRequired := func(t *testing.T) {
+		t.Helper()
+		t.Setenv(envTelegramKeyVarConfig, testTokenConfig)
+		t.Setenv(envDatabaseURL, testDatabaseURLConfig)
+		t.Setenv(envWhitelistedUserIDs, "123")
+	}
+
+	t.Run("built-in lists by default", func(t *testing.T) {
+		setRequired(t)
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.Equal(t, models.DefaultCategories, cfg.SeedCategories)
+		require.Equal(t, models.MinimalCategories, cfg.MinimalCategories)
+	})
+
+	t.Run("reads a JSON file", func(t *testing.T) {
+		setRequired(t)
+		path := filepath.Join(t.TempDir(), "categories.json")
+		require.NoError(t, os.WriteFile(path,
+			[]byte(`{"default": ["Rent", "Groceries", "Fun"], "minimal": ["Rent"]}`), 0o600))
+		t.Setenv("SEED_CATEGORIES", path)
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.Equal(t, []string{"Rent", "Groceries", "Fun"}, cfg.SeedCategories)
+		require.Equal(t, []string{"Rent"}, cfg.MinimalCategories)
+	})
+
+	t.Run("inline JSON keeps the built-in minimal names it lists", func(t *testing.T) {
+		setRequired(t)
+		t.Setenv("SEED_CATEGORIES", `{"default": ["Transportation", "Pets", "Others"]}`)
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.Equal(t, []string{"Transportation", "Others"}, cfg.MinimalCategories)
+	})
+
+	for name, tc := range map[string]struct{ value, err string }{
+		"duplicates":      {`{"default": ["Rent", "rent"]}`, "listed twice"},
+		"too long":        {`{"default": ["` + strings.Repeat("x", 51) + `"]}`, "longer than"},
+		"blank":           {`{"default": [" "]}`, "blank"},
+		"empty":           {`{"default": []}`, "at least one"},
+		"minimal outside": {`{"default": ["Rent"], "minimal": ["Fun"]}`, "not in default"},
+		"missing file":    {"/nonexistent/categories.json", "failed to read"},
+		"bad JSON":        {`{"default": "Rent"}`, "failed to parse"},
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			setRequired(t)
+			t.Setenv("SEED_CATEGORIES", tc.value)
+			_, err := Load()
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
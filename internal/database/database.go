@@ -3,15 +3,153 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"time"
 
 	"github.com/exaring/otelpgx"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
 )
 
+const (
+	// DefaultConnectAttempts and DefaultConnectMaxWait bound how long
+	// startup waits for the database.
+	DefaultConnectAttempts = 8
+	DefaultConnectMaxWait  = 30 * time.Second
+
+	defaultInitialBackoff = 500 * time.Millisecond
+	maxBackoff            = 8 * time.Second
+	// maxAttemptTimeout keeps one hanging attempt from using the whole
+	// budget.
+	maxAttemptTimeout = 5 * time.Second
+)
+
+// SQLSTATE codes a starting or busy server answers with; connecting again
+// later may succeed.
+const (
+	sqlStateCannotConnectNow   = "57P03"
+	sqlStateTooManyConnections = "53300"
+)
+
+// RetryPolicy bounds ConnectWithRetry. Zero fields use the defaults.
+type RetryPolicy struct {
+	// Attempts is the most connection attempts made.
+	Attempts int
+	// MaxWait is the total time spent connecting and waiting between
+	// attempts.
+	MaxWait time.Duration
+	// InitialBackoff is the wait after the first failed attempt. It
+	// doubles after each attempt, up to 8 seconds.
+	InitialBackoff time.Duration
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.Attempts <= 0 {
+		p.Attempts = DefaultConnectAttempts
+	}
+	if p.MaxWait <= 0 {
+		p.MaxWait = DefaultConnectMaxWait
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = defaultInitialBackoff
+	}
+	return p
+}
+
 // Connect establishes a connection pool to the PostgreSQL database.
 // When otelEnabled is true, automatic query tracing via otelpgx is attached.
 func Connect(ctx context.Context, databaseURL string, otelEnabled bool) (*pgxpool.Pool, error) {
+	cfg, err := parsePoolConfig(databaseURL, otelEnabled)
+	if err != nil {
+		return nil, err
+	}
+	return connect(ctx, cfg)
+}
+
+// ConnectWithRetry is Connect for startup, when the database may still be
+// coming up. Refused connections, timeouts and "starting up" answers are
+// retried with exponential backoff within policy; bad URLs, failed
+// authentication and other server errors fail at once. After the last
+// attempt the error lists every attempt's failure.
+func ConnectWithRetry(
+	ctx context.Context,
+	databaseURL string,
+	otelEnabled bool,
+	policy RetryPolicy,
+) (*pgxpool.Pool, error) {
+	cfg, err := parsePoolConfig(databaseURL, otelEnabled)
+	if err != nil {
+		return nil, err
+	}
+
+	policy = policy.withDefaults()
+	start := time.Now()
+	deadline := start.Add(policy.MaxWait)
+	backoff := policy.InitialBackoff
+
+	var errs []error
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithDeadline(ctx, earliest(deadline, time.Now().Add(maxAttemptTimeout)))
+		pool, err := connect(attemptCtx, cfg)
+		cancel()
+		if err == nil {
+			if attempt > 1 {
+				logger.Log.Info().Int("attempt", attempt).Msg("Connected to database")
+			}
+			return pool, nil
+		}
+
+		errs = append(errs, fmt.Errorf("attempt %d: %w", attempt, err))
+		if ctx.Err() != nil || !isRetryableConnectError(err) {
+			return nil, errors.Join(errs...)
+		}
+		if attempt >= policy.Attempts || time.Now().Add(backoff).After(deadline) {
+			return nil, fmt.Errorf("unable to connect to database after %d attempts in %s: %w",
+				attempt, time.Since(start).Round(time.Millisecond), errors.Join(errs...))
+		}
+
+		logger.Log.Warn().
+			Err(err).
+			Int("attempt", attempt).
+			Dur("retry_in", backoff).
+			Msg("Database not reachable, retrying")
+		select {
+		case <-ctx.Done():
+			return nil, errors.Join(append(errs, fmt.Errorf("waiting to reconnect: %w", ctx.Err()))...)
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// isRetryableConnectError reports whether err may go away once the
+// database is up: a refused or timed out connection, or a server that is
+// starting or out of connections. Authentication and other server errors
+// are not.
+func isRetryableConnectError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == sqlStateCannotConnectNow || pgErr.Code == sqlStateTooManyConnections
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func earliest(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func parsePoolConfig(databaseURL string, otelEnabled bool) (*pgxpool.Config, error) {
 	cfg, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse database URL: %w", err)
@@ -20,7 +158,10 @@ func Connect(ctx context.Context, databaseURL string, otelEnabled bool) (*pgxpoo
 	if otelEnabled {
 		cfg.ConnConfig.Tracer = otelpgx.NewTracer()
 	}
+	return cfg, nil
+}
 
+func connect(ctx context.Context, cfg *pgxpool.Config) (*pgxpool.Pool, error) {
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create connection pool: %w", err)
@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"gitlab.com/yelinaung/expense-bot/internal/database"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
 	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
 )
 
@@ -64,7 +65,7 @@ func TestSeedCategories_AlreadySeeded(t *testing.T) {
 	dbtest.CleanupTables(ctx, t, pool)
 
 	// First seed
-	err = database.SeedCategories(ctx, pool)
+	err = database.SeedCategories(ctx, pool, models.DefaultCategories)
 	require.NoError(t, err)
 
 	var count int
@@ -73,7 +74,7 @@ func TestSeedCategories_AlreadySeeded(t *testing.T) {
 	require.Equal(t, 16, count)
 
 	// Second seed - should be idempotent
-	err = database.SeedCategories(ctx, pool)
+	err = database.SeedCategories(ctx, pool, models.DefaultCategories)
 	require.NoError(t, err)
 
 	err = pool.QueryRow(ctx, selectCountCategoriesQuery).Scan(&count)
@@ -81,7 +82,7 @@ func TestSeedCategories_AlreadySeeded(t *testing.T) {
 	require.Equal(t, 16, count, "should not duplicate categories")
 
 	// Third seed - verify still idempotent
-	err = database.SeedCategories(ctx, pool)
+	err = database.SeedCategories(ctx, pool, models.DefaultCategories)
 	require.NoError(t, err)
 
 	err = pool.QueryRow(ctx, selectCountCategoriesQuery).Scan(&count)
@@ -102,7 +103,7 @@ func TestSeedCategories_WithContextCancellation(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	err = database.SeedCategories(ctx, pool)
+	err = database.SeedCategories(ctx, pool, models.DefaultCategories)
 	// May succeed or fail depending on timing
 	_ = err
 }
@@ -289,10 +290,10 @@ func TestSeedCategories_CategoryNames(t *testing.T) {
 
 	dbtest.CleanupTables(ctx, t, pool)
 
-	err = database.SeedCategories(ctx, pool)
+	err = database.SeedCategories(ctx, pool, models.DefaultCategories)
 	require.NoError(t, err)
 
-	// Expected categories (must match migrations.go SeedCategories)
+	// Expected categories (must match models.DefaultCategories)
 	expectedCategories := []string{
 		"Food - Dining Out",
 		"Food - Grocery",
@@ -2,11 +2,22 @@ package database
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/require"
 )
 
+const unreachableDatabaseURL = "postgres://localhost:59999/nonexistent?connect_timeout=1"
+
 func TestConnect(t *testing.T) {
 	t.Run("fails with invalid connection string", func(t *testing.T) {
 		ctx := context.Background()
@@ -17,8 +28,93 @@ func TestConnect(t *testing.T) {
 
 	t.Run("fails with unreachable host", func(t *testing.T) {
 		ctx := context.Background()
-		pool, err := Connect(ctx, "postgres://localhost:59999/nonexistent?connect_timeout=1", false)
+		pool, err := Connect(ctx, unreachableDatabaseURL, false)
 		require.Error(t, err)
 		require.Nil(t, pool)
 	})
 }
+
+func TestConnectWithRetry(t *testing.T) {
+	t.Run("retries an unreachable address until attempts run out", func(t *testing.T) {
+		ctx := context.Background()
+		start := time.Now()
+		pool, err := ConnectWithRetry(ctx, unreachableDatabaseURL, false, RetryPolicy{
+			Attempts:       3,
+			MaxWait:        5 * time.Second,
+			InitialBackoff: 50 * time.Millisecond,
+		})
+		elapsed := time.Since(start)
+
+		require.Nil(t, pool)
+		require.ErrorContains(t, err, "after 3 attempts")
+		require.Equal(t, 3, strings.Count(err.Error(), "attempt "))
+		require.GreaterOrEqual(t, elapsed, 150*time.Millisecond, "waits 50ms then 100ms")
+		require.Less(t, elapsed, 5*time.Second)
+	})
+
+	t.Run("stops when the next wait would pass the max wait", func(t *testing.T) {
+		ctx := context.Background()
+		start := time.Now()
+		_, err := ConnectWithRetry(ctx, unreachableDatabaseURL, false, RetryPolicy{
+			Attempts:       10,
+			MaxWait:        350 * time.Millisecond,
+			InitialBackoff: 100 * time.Millisecond,
+		})
+		elapsed := time.Since(start)
+
+		require.ErrorContains(t, err, "after 3 attempts")
+		require.Less(t, elapsed, time.Second)
+	})
+
+	t.Run("invalid URL fails at once", func(t *testing.T) {
+		ctx := context.Background()
+		start := time.Now()
+		_, err := ConnectWithRetry(ctx, "invalid://connection", false, RetryPolicy{InitialBackoff: time.Second})
+		require.ErrorContains(t, err, "unable to parse database URL")
+		require.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("invalid credentials fail at once", func(t *testing.T) {
+		dbURL := os.Getenv("TEST_DATABASE_URL")
+		if dbURL == "" {
+			t.Skip("TEST_DATABASE_URL not set, skipping integration test")
+		}
+		u, err := url.Parse(dbURL)
+		require.NoError(t, err)
+		u.User = url.UserPassword("no_such_user_404", "wrong-password")
+
+		ctx := context.Background()
+		start := time.Now()
+		pool, err := ConnectWithRetry(ctx, u.String(), false, RetryPolicy{InitialBackoff: time.Second})
+		require.Nil(t, pool)
+		require.Error(t, err)
+		require.Equal(t, 1, strings.Count(err.Error(), "attempt "))
+		require.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestIsRetryableConnectError(t *testing.T) {
+	t.Parallel()
+
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "connection refused", err: fmt.Errorf("connect: %w", refused), want: true},
+		{name: "timeout", err: fmt.Errorf("connect: %w", context.DeadlineExceeded), want: true},
+		{name: "server starting up", err: &pgconn.PgError{Code: "57P03"}, want: true},
+		{name: "too many connections", err: &pgconn.PgError{Code: "53300"}, want: true},
+		{name: "password authentication failed", err: &pgconn.PgError{Code: "28P01"}},
+		{name: "unknown role", err: &pgconn.PgError{Code: "28000"}},
+		{name: "unknown database", err: &pgconn.PgError{Code: "3D000"}},
+		{name: "other error", err: errors.New("tls: bad certificate")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.want, isRetryableConnectError(tt.err))
+		})
+	}
+}
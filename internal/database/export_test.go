@@ -0,0 +1,19 @@
+package database
+
+// Exported for the external migration tests.
+const (
+	RenumberDuplicateExpenseNumbersSQL = renumberDuplicateExpenseNumbersSQL
+	SyncExpenseCountersSQL             = syncExpenseCountersSQL
+)
+
+// SchemaLockKey is exported for the external concurrency tests.
+const SchemaLockKey = schemaLockKey
+
+// Exported for the external migration runner tests.
+var (
+	BuildMigrations = buildMigrations
+	MigrationName   = migrationName
+	ApplyMigrations = applyMigrations
+	StatusOf        = migrationStatus
+	SchemaSQL       = schemaSQL
+)
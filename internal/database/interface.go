@@ -22,9 +22,23 @@ type TxBeginner interface {
 	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
+// Pinger can check that the database is reachable. Implemented by
+// pgxpool.Pool.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PoolStatter reports connection pool statistics. Implemented by
+// pgxpool.Pool.
+type PoolStatter interface {
+	Stat() *pgxpool.Stat
+}
+
 // Ensure types implement the interface at compile time.
 var (
-	_ PGXDB      = (*pgxpool.Pool)(nil)
-	_ PGXDB      = pgx.Tx(nil)
-	_ TxBeginner = (*pgxpool.Pool)(nil)
+	_ PGXDB       = (*pgxpool.Pool)(nil)
+	_ PGXDB       = pgx.Tx(nil)
+	_ TxBeginner  = (*pgxpool.Pool)(nil)
+	_ Pinger      = (*pgxpool.Pool)(nil)
+	_ PoolStatter = (*pgxpool.Pool)(nil)
 )
@@ -0,0 +1,338 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+// ErrMigrationChanged is returned when a migration recorded as applied no
+// longer matches its statement.
+var ErrMigrationChanged = errors.New("applied migrations were changed")
+
+// migrationNameMax is the most runes of a statement kept as its name.
+const migrationNameMax = 60
+
+// createSchemaMigrationsSQL creates the table recording every applied
+// migration with the checksum of its statement and how long it took.
+const createSchemaMigrationsSQL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	duration_ms BIGINT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`
+
+// MigrateOptions changes how Migrate treats applied migrations.
+type MigrateOptions struct {
+	// AllowChecksumChange starts anyway when applied migrations were
+	// edited, recording their new checksums. It is meant for emergencies
+	// and set with MIGRATIONS_ALLOW_CHECKSUM_CHANGE.
+	AllowChecksumChange bool
+}
+
+// Migration is one schema statement. Version is its position in the
+// schema, from 1, and Name the start of its first line.
+type Migration struct {
+	Version  int
+	Name     string
+	Checksum string
+	sql      string
+}
+
+// transactional reports whether the migration can run in a transaction.
+// PostgreSQL refuses CONCURRENTLY index builds inside one.
+func (m *Migration) transactional() bool {
+	return !strings.Contains(strings.ToUpper(m.sql), " CONCURRENTLY ")
+}
+
+// MigrationState is a migration as recorded in schema_migrations.
+type MigrationState struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	Duration  time.Duration
+	// Changed is set when the statement differs from the applied one.
+	Changed bool
+	// Unknown is set for a recorded version this build does not have.
+	Unknown bool
+}
+
+// appliedMigration is a row of schema_migrations.
+type appliedMigration struct {
+	name      string
+	checksum  string
+	duration  time.Duration
+	appliedAt time.Time
+}
+
+// buildMigrations numbers statements from 1 and takes their checksums.
+func buildMigrations(statements []string) []Migration {
+	migrations := make([]Migration, len(statements))
+	for i, statement := range statements {
+		sum := sha256.Sum256([]byte(statement))
+		migrations[i] = Migration{
+			Version:  i + 1,
+			Name:     migrationName(statement),
+			Checksum: hex.EncodeToString(sum[:]),
+			sql:      statement,
+		}
+	}
+	return migrations
+}
+
+// migrationName is the first line of a statement with whitespace
+// collapsed, cut to migrationNameMax runes.
+func migrationName(statement string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(statement), "\n")
+	name := strings.Join(strings.Fields(line), " ")
+	if utf8.RuneCountInString(name) > migrationNameMax {
+		name = string([]rune(name)[:migrationNameMax-1]) + "…"
+	}
+	return name
+}
+
+// RunMigrations brings the database schema up to date with the default
+// options.
+func RunMigrations(ctx context.Context, pool *pgxpool.Pool) error {
+	return Migrate(ctx, pool, MigrateOptions{})
+}
+
+// Migrate applies the schema migrations not yet recorded in
+// schema_migrations, in order, each in its own transaction together with
+// its record, so a failed migration leaves the earlier ones recorded and
+// itself absent. It refuses to run when an applied migration was edited,
+// unless opts allows it. Migrations run under the schema advisory lock, so
+// instances starting together apply them one after the other.
+func Migrate(ctx context.Context, pool *pgxpool.Pool, opts MigrateOptions) error {
+	return applyMigrations(ctx, pool, buildMigrations(schemaSQL()), opts)
+}
+
+func applyMigrations(ctx context.Context, pool *pgxpool.Pool, migrations []Migration, opts MigrateOptions) error {
+	var applied, skipped int
+	var took time.Duration
+	waited, err := withSchemaLock(ctx, pool, func(conn *pgxpool.Conn) error {
+		if _, err := conn.Exec(ctx, createSchemaMigrationsSQL); err != nil {
+			return fmt.Errorf("failed to create schema_migrations: %w", err)
+		}
+		recorded, err := loadAppliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkMigrationChecksums(ctx, conn, migrations, recorded, opts); err != nil {
+			return err
+		}
+		if unknown := len(recorded) - countKnown(migrations, recorded); unknown > 0 {
+			logger.Log.Warn().
+				Int("unknown_versions", unknown).
+				Msg("Database has migrations this build does not know; the build may be older than the database")
+		}
+
+		for i := range migrations {
+			if _, ok := recorded[migrations[i].Version]; ok {
+				skipped++
+				continue
+			}
+			duration, err := applyMigration(ctx, conn, &migrations[i])
+			if err != nil {
+				logger.Log.Error().
+					Int("applied", applied).
+					Int("version", migrations[i].Version).
+					Str("name", migrations[i].Name).
+					Msg("Database migrations stopped")
+				return err
+			}
+			applied++
+			took += duration
+			logger.Log.Info().
+				Int("version", migrations[i].Version).
+				Str("name", migrations[i].Name).
+				Dur("duration", duration).
+				Msg("Migration applied")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Log.Info().
+		Int("applied", applied).
+		Int("already_applied", skipped).
+		Int("latest_version", len(migrations)).
+		Dur("duration", took).
+		Dur("lock_wait", waited).
+		Msg("Database migrations up to date")
+	return nil
+}
+
+// applyMigration runs one migration and records it, in a transaction
+// when the statement allows one.
+func applyMigration(ctx context.Context, conn *pgxpool.Conn, m *Migration) (time.Duration, error) {
+	if !m.transactional() {
+		start := time.Now()
+		if _, err := conn.Exec(ctx, m.sql); err != nil {
+			return 0, fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		duration := time.Since(start)
+		return duration, recordMigration(ctx, conn, m, duration)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin migration %d: %w", m.Version, err)
+	}
+	defer func() { _ = tx.Rollback(context.WithoutCancel(ctx)) }()
+
+	start := time.Now()
+	if _, err := tx.Exec(ctx, m.sql); err != nil {
+		return 0, fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+	}
+	duration := time.Since(start)
+	if err := recordMigration(ctx, tx, m, duration); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit migration %d: %w", m.Version, err)
+	}
+	return duration, nil
+}
+
+func recordMigration(ctx context.Context, db PGXDB, m *Migration, duration time.Duration) error {
+	_, err := db.Exec(ctx, `
+		INSERT INTO schema_migrations (version, name, checksum, duration_ms)
+		VALUES ($1, $2, $3, $4)
+	`, m.Version, m.Name, m.Checksum, duration.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+	}
+	return nil
+}
+
+// checkMigrationChecksums fails with ErrMigrationChanged when applied
+// migrations were edited. When opts allows it the new checksums are
+// recorded instead, with a warning for each.
+func checkMigrationChecksums(
+	ctx context.Context,
+	conn *pgxpool.Conn,
+	migrations []Migration,
+	recorded map[int]appliedMigration,
+	opts MigrateOptions,
+) error {
+	var changed []string
+	for i := range migrations {
+		m := &migrations[i]
+		row, ok := recorded[m.Version]
+		if !ok || row.checksum == m.Checksum {
+			continue
+		}
+		if !opts.AllowChecksumChange {
+			changed = append(changed, fmt.Sprintf("%d (%s)", m.Version, m.Name))
+			continue
+		}
+		logger.Log.Warn().
+			Int("version", m.Version).
+			Str("name", m.Name).
+			Msg("Applied migration was changed; recording its new checksum")
+		_, err := conn.Exec(ctx, `UPDATE schema_migrations SET name = $2, checksum = $3 WHERE version = $1`,
+			m.Version, m.Name, m.Checksum)
+		if err != nil {
+			return fmt.Errorf("failed to update checksum of migration %d: %w", m.Version, err)
+		}
+	}
+	if len(changed) > 0 {
+		return fmt.Errorf("%w: %s; restore them, or set MIGRATIONS_ALLOW_CHECKSUM_CHANGE=true to start anyway",
+			ErrMigrationChanged, strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+func countKnown(migrations []Migration, recorded map[int]appliedMigration) int {
+	known := 0
+	for i := range migrations {
+		if _, ok := recorded[migrations[i].Version]; ok {
+			known++
+		}
+	}
+	return known
+}
+
+func loadAppliedMigrations(ctx context.Context, db PGXDB) (map[int]appliedMigration, error) {
+	rows, err := db.Query(ctx, `SELECT version, name, checksum, duration_ms, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	recorded := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var durationMS int64
+		var row appliedMigration
+		if err := rows.Scan(&version, &row.name, &row.checksum, &durationMS, &row.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		row.duration = time.Duration(durationMS) * time.Millisecond
+		recorded[version] = row
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate schema_migrations: %w", err)
+	}
+	return recorded, nil
+}
+
+// MigrationStatus lists every schema migration with whether it was
+// applied, followed by recorded versions this build does not know. It
+// changes nothing, so it works before the first Migrate too.
+func MigrationStatus(ctx context.Context, db PGXDB) ([]MigrationState, error) {
+	return migrationStatus(ctx, db, buildMigrations(schemaSQL()))
+}
+
+func migrationStatus(ctx context.Context, db PGXDB, migrations []Migration) ([]MigrationState, error) {
+	var exists bool
+	if err := db.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up schema_migrations: %w", err)
+	}
+	recorded := map[int]appliedMigration{}
+	if exists {
+		var err error
+		if recorded, err = loadAppliedMigrations(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for i := range migrations {
+		state := MigrationState{Migration: migrations[i]}
+		if row, ok := recorded[state.Version]; ok {
+			state.Applied = true
+			state.AppliedAt = row.appliedAt
+			state.Duration = row.duration
+			state.Changed = row.checksum != state.Checksum
+			delete(recorded, state.Version)
+		}
+		states = append(states, state)
+	}
+
+	unknown := make([]MigrationState, 0, len(recorded))
+	for version, row := range recorded {
+		unknown = append(unknown, MigrationState{
+			Migration: Migration{Version: version, Name: row.name, Checksum: row.checksum},
+			Applied:   true,
+			AppliedAt: row.appliedAt,
+			Duration:  row.duration,
+			Unknown:   true,
+		})
+	}
+	slices.SortFunc(unknown, func(a, b MigrationState) int { return a.Version - b.Version })
+	return append(states, unknown...), nil
+}
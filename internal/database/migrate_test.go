@@ -0,0 +1,200 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
+)
+
+func TestMigrationName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "CREATE TABLE IF NOT EXISTS users (",
+		database.MigrationName("CREATE TABLE IF NOT EXISTS users (\n\t\t\tid BIGINT PRIMARY KEY\n\t\t)"))
+	require.Equal(t, "ALTER TABLE users ADD COLUMN x INT",
+		database.MigrationName("  ALTER TABLE users\tADD   COLUMN x INT  "))
+
+	long := database.MigrationName("ALTER TABLE expenses ADD COLUMN IF NOT EXISTS " + strings.Repeat("x", 80))
+	require.Equal(t, 60, len([]rune(long)))
+	require.True(t, strings.HasSuffix(long, "…"))
+}
+
+func TestBuildMigrations(t *testing.T) {
+	t.Parallel()
+
+	migrations := database.BuildMigrations([]string{"SELECT 1", "SELECT 2", "SELECT 1"})
+	require.Len(t, migrations, 3)
+	for i, m := range migrations {
+		require.Equal(t, i+1, m.Version)
+		require.Len(t, m.Checksum, 64)
+	}
+	require.Equal(t, migrations[0].Checksum, migrations[2].Checksum, "checksums depend only on the statement")
+	require.NotEqual(t, migrations[0].Checksum, migrations[1].Checksum)
+
+	schema := database.BuildMigrations(database.SchemaSQL())
+	require.Equal(t, len(database.SchemaSQL()), len(schema))
+	require.Equal(t, "CREATE TABLE IF NOT EXISTS users (", schema[0].Name)
+}
+
+// migrationSchemaPool returns a pool whose connections use a new, empty
+// schema, so the migrations under test have a schema_migrations table of
+// their own.
+func migrationSchemaPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	ctx := context.Background()
+	pool := dbtest.TestDB(t)
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	_, err := pool.Exec(ctx, "CREATE SCHEMA "+pgx.Identifier{schema}.Sanitize())
+	require.NoError(t, err)
+
+	cfg := pool.Config().Copy()
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	scoped, err := pgxpool.NewWithConfig(ctx, cfg)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		scoped.Close()
+		_, _ = pool.Exec(context.WithoutCancel(ctx), "DROP SCHEMA "+pgx.Identifier{schema}.Sanitize()+" CASCADE")
+	})
+	return scoped
+}
+
+func recordedVersions(ctx context.Context, t *testing.T, pool *pgxpool.Pool) []int {
+	t.Helper()
+
+	rows, err := pool.Query(ctx, `SELECT version FROM schema_migrations ORDER BY version`)
+	require.NoError(t, err)
+	versions, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	require.NoError(t, err)
+	return versions
+}
+
+func TestApplyMigrations(t *testing.T) {
+	ctx := context.Background()
+	pool := migrationSchemaPool(t)
+
+	// Neither statement can run twice, so a re-run that executed them
+	// would fail.
+	statements := []string{
+		`CREATE TABLE notes (id INTEGER PRIMARY KEY)`,
+		`ALTER TABLE notes ADD COLUMN body TEXT NOT NULL DEFAULT ''`,
+	}
+
+	t.Run("status before the first run", func(t *testing.T) {
+		states, err := database.StatusOf(ctx, pool, database.BuildMigrations(statements))
+		require.NoError(t, err)
+		require.Len(t, states, 2)
+		require.False(t, states[0].Applied)
+		require.False(t, states[1].Applied)
+	})
+
+	t.Run("fresh apply", func(t *testing.T) {
+		require.NoError(t, database.ApplyMigrations(ctx, pool, database.BuildMigrations(statements),
+			database.MigrateOptions{}))
+		require.Equal(t, []int{1, 2}, recordedVersions(ctx, t, pool))
+
+		var name, checksum string
+		require.NoError(t, pool.QueryRow(ctx,
+			`SELECT name, checksum FROM schema_migrations WHERE version = 2`).Scan(&name, &checksum))
+		require.Equal(t, database.MigrationName(statements[1]), name)
+		require.Equal(t, database.BuildMigrations(statements)[1].Checksum, checksum)
+
+		_, err := pool.Exec(ctx, `INSERT INTO notes (id, body) VALUES (1, 'hi')`)
+		require.NoError(t, err)
+	})
+
+	t.Run("re-run is a no-op", func(t *testing.T) {
+		require.NoError(t, database.ApplyMigrations(ctx, pool, database.BuildMigrations(statements),
+			database.MigrateOptions{}))
+		require.Equal(t, []int{1, 2}, recordedVersions(ctx, t, pool))
+
+		states, err := database.StatusOf(ctx, pool, database.BuildMigrations(statements))
+		require.NoError(t, err)
+		for _, state := range states {
+			require.True(t, state.Applied)
+			require.False(t, state.Changed)
+		}
+	})
+
+	t.Run("new migrations are appended", func(t *testing.T) {
+		more := append(statements[:2:2], `CREATE INDEX idx_notes_body ON notes(body)`)
+		require.NoError(t, database.ApplyMigrations(ctx, pool, database.BuildMigrations(more),
+			database.MigrateOptions{}))
+		require.Equal(t, []int{1, 2, 3}, recordedVersions(ctx, t, pool))
+
+		states, err := database.StatusOf(ctx, pool, database.BuildMigrations(statements))
+		require.NoError(t, err)
+		require.Len(t, states, 3)
+		require.True(t, states[2].Unknown, "a version missing from the build is reported")
+	})
+}
+
+func TestApplyMigrations_ChecksumChange(t *testing.T) {
+	ctx := context.Background()
+	pool := migrationSchemaPool(t)
+
+	statements := []string{
+		`CREATE TABLE notes (id INTEGER PRIMARY KEY)`,
+		`ALTER TABLE notes ADD COLUMN body TEXT`,
+	}
+	require.NoError(t, database.ApplyMigrations(ctx, pool, database.BuildMigrations(statements),
+		database.MigrateOptions{}))
+
+	edited := []string{statements[0], `ALTER TABLE notes ADD COLUMN body TEXT NOT NULL DEFAULT ''`, `SELECT 1`}
+	err := database.ApplyMigrations(ctx, pool, database.BuildMigrations(edited), database.MigrateOptions{})
+	require.ErrorIs(t, err, database.ErrMigrationChanged)
+	require.Contains(t, err.Error(), "2 (ALTER TABLE notes ADD COLUMN body TEXT")
+	require.Contains(t, err.Error(), "MIGRATIONS_ALLOW_CHECKSUM_CHANGE")
+	require.Equal(t, []int{1, 2}, recordedVersions(ctx, t, pool), "nothing runs after a refusal")
+
+	states, err := database.StatusOf(ctx, pool, database.BuildMigrations(edited))
+	require.NoError(t, err)
+	require.True(t, states[1].Changed)
+
+	require.NoError(t, database.ApplyMigrations(ctx, pool, database.BuildMigrations(edited),
+		database.MigrateOptions{AllowChecksumChange: true}))
+	require.Equal(t, []int{1, 2, 3}, recordedVersions(ctx, t, pool))
+
+	require.NoError(t, database.ApplyMigrations(ctx, pool, database.BuildMigrations(edited),
+		database.MigrateOptions{}), "the override records the new checksum")
+}
+
+func TestApplyMigrations_FailingMigration(t *testing.T) {
+	ctx := context.Background()
+	pool := migrationSchemaPool(t)
+
+	statements := []string{
+		`CREATE TABLE notes (id INTEGER PRIMARY KEY)`,
+		`ALTER TABLE notes ADD COLUMN body TEXT`,
+		`CREATE TABLE half_done (id INTEGER); INSERT INTO missing_table VALUES (1)`,
+		`CREATE TABLE never_reached (id INTEGER)`,
+	}
+	err := database.ApplyMigrations(ctx, pool, database.BuildMigrations(statements), database.MigrateOptions{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "migration 3 (CREATE TABLE half_done")
+	require.Equal(t, []int{1, 2}, recordedVersions(ctx, t, pool))
+
+	var halfDone bool
+	require.NoError(t, pool.QueryRow(ctx, `SELECT to_regclass('half_done') IS NOT NULL`).Scan(&halfDone))
+	require.False(t, halfDone, "the failed migration is rolled back")
+
+	states, err := database.StatusOf(ctx, pool, database.BuildMigrations(statements))
+	require.NoError(t, err)
+	require.True(t, states[1].Applied)
+	require.False(t, states[2].Applied)
+	require.False(t, states[3].Applied)
+
+	statements[2] = `CREATE TABLE half_done (id INTEGER)`
+	require.NoError(t, database.ApplyMigrations(ctx, pool, database.BuildMigrations(statements),
+		database.MigrateOptions{}), "a fixed migration that never applied is not a checksum change")
+	require.Equal(t, []int{1, 2, 3, 4}, recordedVersions(ctx, t, pool))
+}
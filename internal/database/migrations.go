@@ -3,13 +3,87 @@ package database
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
 )
 
-// RunMigrations creates the database schema.
-func RunMigrations(ctx context.Context, pool *pgxpool.Pool) error {
-	migrations := []string{
+// schemaLockKey is the pg_advisory_lock key that serializes migrations and
+// category seeding between bot instances sharing a database.
+const schemaLockKey int64 = 0x6578_7062_6f74 // "expbot"
+
+// withSchemaLock runs fn on a dedicated connection while holding the schema
+// advisory lock. Other instances wait until the lock is released. It
+// returns how long it waited for the lock.
+func withSchemaLock(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	fn func(conn *pgxpool.Conn) error,
+) (time.Duration, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire connection for schema lock: %w", err)
+	}
+	defer conn.Release()
+
+	start := time.Now()
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, schemaLockKey); err != nil {
+		return 0, fmt.Errorf("failed to take schema lock: %w", err)
+	}
+	waited := time.Since(start)
+
+	defer func() {
+		_, unlockErr := conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, schemaLockKey)
+		if unlockErr != nil {
+			// Closing the connection ends its session, which releases the
+			// lock, and keeps it out of the pool.
+			logger.Log.Warn().Err(unlockErr).Msg("Failed to release schema lock; closing connection")
+			_ = conn.Conn().Close(context.WithoutCancel(ctx))
+		}
+	}()
+
+	return waited, fn(conn)
+}
+
+// renumberDuplicateExpenseNumbersSQL repairs users whose expenses share a
+// number, which would stop the unique index from being built. All of such
+// a user's expenses are renumbered by creation time, so the result is the
+// same on every run; users without duplicates keep their numbers.
+const renumberDuplicateExpenseNumbersSQL = `WITH duplicated AS (
+	SELECT DISTINCT user_id
+	FROM expenses
+	WHERE user_expense_number IS NOT NULL
+	GROUP BY user_id, user_expense_number
+	HAVING COUNT(*) > 1
+), numbered AS (
+	SELECT e.id,
+	       row_number() OVER (PARTITION BY e.user_id ORDER BY e.created_at, e.id) AS rn
+	FROM expenses e
+	JOIN duplicated d ON d.user_id = e.user_id
+)
+UPDATE expenses e
+SET user_expense_number = n.rn
+FROM numbered n
+WHERE e.id = n.id AND e.user_expense_number IS DISTINCT FROM n.rn`
+
+// syncExpenseCountersSQL moves every user's next expense number past the
+// highest number in use. Counters never move backwards.
+const syncExpenseCountersSQL = `INSERT INTO user_expense_counters (user_id, next_number)
+SELECT user_id, COALESCE(MAX(user_expense_number), 0) + 1
+FROM expenses
+GROUP BY user_id
+ON CONFLICT (user_id)
+DO UPDATE SET next_number = GREATEST(user_expense_counters.next_number, EXCLUDED.next_number)`
+
+// schemaSQL returns the statements that create the database schema, in
+// order. Statement i is migration version i+1, so new statements are only
+// ever appended and applied ones are never edited: the runner refuses to
+// start when an applied statement's checksum changes. Expense numbers come
+// from the user_expense_counters row of the user, which the insert trigger
+// bumps with a single upsert, so concurrent inserts never share a number.
+func schemaSQL() []string {
+	return []string{
 		`CREATE TABLE IF NOT EXISTS users (
 			id BIGINT PRIMARY KEY,
 			username TEXT,
@@ -64,12 +138,9 @@ func RunMigrations(ctx context.Context, pool *pgxpool.Pool) error {
 		FROM numbered n
 		WHERE e.id = n.id`,
 
-		`INSERT INTO user_expense_counters (user_id, next_number)
-		SELECT user_id, COALESCE(MAX(user_expense_number), 0) + 1
-		FROM expenses
-		GROUP BY user_id
-		ON CONFLICT (user_id)
-		DO UPDATE SET next_number = GREATEST(user_expense_counters.next_number, EXCLUDED.next_number)`,
+		renumberDuplicateExpenseNumbersSQL,
+
+		syncExpenseCountersSQL,
 
 		`CREATE OR REPLACE FUNCTION set_user_expense_number()
 		RETURNS TRIGGER
@@ -143,48 +214,444 @@ func RunMigrations(ctx context.Context, pool *pgxpool.Pool) error {
 		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS worth_it BOOLEAN`,
 		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS spend_driver TEXT`,
 		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMPTZ`,
+
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS uncategorized_threshold INTEGER NOT NULL DEFAULT 10`,
+
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS api_token_hash TEXT`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_api_token_hash
+			ON users(api_token_hash) WHERE api_token_hash IS NOT NULL`,
+
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS last_reminder_date DATE`,
+
+		`CREATE TABLE IF NOT EXISTS receipt_scans (
+			id SERIAL PRIMARY KEY,
+			expense_id INTEGER REFERENCES expenses(id) ON DELETE SET NULL,
+			user_id BIGINT NOT NULL,
+			model TEXT NOT NULL,
+			prompt_version TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_receipt_scans_expense_id ON receipt_scans(expense_id)`,
+
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS max_amount DECIMAL(12, 2)`,
+
+		`CREATE TABLE IF NOT EXISTS expense_source_messages (
+			chat_id BIGINT NOT NULL,
+			message_id BIGINT NOT NULL,
+			expense_id INTEGER NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (chat_id, message_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_expense_source_messages_expense_id ON expense_source_messages(expense_id)`,
+
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS auto_tag BOOLEAN NOT NULL DEFAULT FALSE`,
+
+		`CREATE TABLE IF NOT EXISTS expense_audit (
+			id SERIAL PRIMARY KEY,
+			expense_id INTEGER NOT NULL,
+			user_id BIGINT NOT NULL,
+			expense_number BIGINT NOT NULL,
+			actor_id BIGINT NOT NULL,
+			field TEXT NOT NULL,
+			old_value TEXT NOT NULL DEFAULT '',
+			new_value TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_expense_audit_user_number ON expense_audit(user_id, expense_number)`,
+
+		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS ai_categorized BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS ai_confidence DOUBLE PRECISION`,
+
+		`CREATE TABLE IF NOT EXISTS budgets (
+			id SERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+			amount DECIMAL(12, 2) NOT NULL,
+			enforce BOOLEAN NOT NULL DEFAULT FALSE,
+			override_period TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			UNIQUE (user_id, category_id)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS chats (
+			chat_id BIGINT PRIMARY KEY,
+			default_category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+
+		// Where an expense came from. Only demo data is marked so far, so
+		// /wipedemo can remove it without touching real expenses.
+		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT ''`,
+
+		// bcrypt hash of the user's /pin, NULL when no PIN is set.
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS pin_hash TEXT`,
+
+		// Conversion to the owner's default currency captured when the
+		// expense was created, so reports do not depend on today's rates.
+		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS rate_to_default DECIMAL(18, 8)`,
+		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS converted_amount DECIMAL(12, 2)`,
+		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS converted_currency TEXT NOT NULL DEFAULT ''`,
+
+		`CREATE TABLE IF NOT EXISTS expense_shares (
+			expense_id INTEGER NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+			user_id BIGINT NOT NULL,
+			chat_id BIGINT NOT NULL,
+			share_amount DECIMAL(12, 2) NOT NULL,
+			PRIMARY KEY (expense_id, user_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_expense_shares_chat_id ON expense_shares(chat_id)`,
+		`CREATE TABLE IF NOT EXISTS settlements (
+			id SERIAL PRIMARY KEY,
+			chat_id BIGINT NOT NULL,
+			from_user_id BIGINT NOT NULL,
+			to_user_id BIGINT NOT NULL,
+			amount DECIMAL(12, 2) NOT NULL,
+			currency TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_settlements_chat_id ON settlements(chat_id)`,
+		`ALTER TABLE categories ADD COLUMN IF NOT EXISTS sort_order INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS categories_by_usage BOOLEAN NOT NULL DEFAULT FALSE`,
+
+		// Deleted templates are kept, so seeding does not bring back a
+		// default template an admin removed.
+		`CREATE TABLE IF NOT EXISTS bank_templates (
+			id SERIAL PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			pattern TEXT NOT NULL,
+			created_by BIGINT,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			deleted_at TIMESTAMPTZ
+		)`,
+
+		// When the user last talked to the bot, updated at most once an
+		// hour, for the /users listing.
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ`,
+
+		// Where an expense happened, from a Telegram location the user
+		// sent. The label is empty when no geocoder is configured.
+		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION`,
+		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION`,
+		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS location_label TEXT NOT NULL DEFAULT ''`,
+
+		// Tags added to every expense saved in a category.
+		`CREATE TABLE IF NOT EXISTS category_default_tags (
+			category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+			tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+			PRIMARY KEY (category_id, tag_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_category_default_tags_tag_id ON category_default_tags(tag_id)`,
+
+		// Chat that receives each user's monthly CSV, and the month last
+		// delivered there so a restart does not deliver it twice.
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS archive_chat_id BIGINT`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS archive_sent_month DATE`,
+
+		// Receipt images kept by the optional receipt storage. expense_id
+		// becomes NULL when the expense is deleted, which marks the stored
+		// file for cleanup.
+		`CREATE TABLE IF NOT EXISTS receipt_files (
+			id SERIAL PRIMARY KEY,
+			expense_id INTEGER UNIQUE REFERENCES expenses(id) ON DELETE SET NULL,
+			user_id BIGINT NOT NULL,
+			storage_key TEXT NOT NULL,
+			size_bytes BIGINT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_receipt_files_orphans ON receipt_files(id) WHERE expense_id IS NULL`,
+
+		// Currency of a budget's limit. Budgets from before it existed were
+		// meant in the owner's default currency at the time.
+		`ALTER TABLE budgets ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT ''`,
+		`UPDATE budgets b SET currency = u.default_currency
+		 FROM users u
+		 WHERE b.currency = '' AND u.id = b.user_id`,
+
+		// Category Gemini suggested for a receipt that matched none of the
+		// existing ones, offered on the draft until used or ignored.
+		`ALTER TABLE receipt_scans ADD COLUMN IF NOT EXISTS suggested_category TEXT NOT NULL DEFAULT ''`,
+		// User who created a category from a receipt suggestion, for the
+		// daily cap. NULL for categories created any other way.
+		`ALTER TABLE categories ADD COLUMN IF NOT EXISTS created_by BIGINT`,
+		// Layout of a user's lists and summaries: 'html' or 'mono'.
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS display_format TEXT NOT NULL DEFAULT 'html'`,
+		// The category Gemini picked, kept after ai_categorized is cleared so
+		// the weekly review digest can tell accepted suggestions from changed
+		// ones. Older AI-categorized expenses still carry their pick.
+		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS ai_category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL`,
+		`UPDATE expenses SET ai_category_id = category_id WHERE ai_categorized AND ai_category_id IS NULL`,
+
+		// Tokens used by each Gemini call, for the monthly token budget and
+		// /usagestats. user_id is 0 when the call was made for no one user.
+		`CREATE TABLE IF NOT EXISTS gemini_usage (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL DEFAULT 0,
+			feature TEXT NOT NULL,
+			prompt_tokens INTEGER NOT NULL DEFAULT 0,
+			output_tokens INTEGER NOT NULL DEFAULT 0,
+			total_tokens INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_gemini_usage_created_at ON gemini_usage(created_at)`,
+
+		// Work expenses paid back by someone else. Until reimbursed_at is
+		// set they are left out of personal spend views and budgets.
+		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS reimbursable BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS reimbursed_at TIMESTAMPTZ`,
+		`CREATE INDEX IF NOT EXISTS idx_expenses_outstanding_reimbursements ON expenses(user_id, created_at)
+			WHERE reimbursable AND reimbursed_at IS NULL`,
+
+		// Chart color set with /setcategorycolor as #RRGGBB; empty means
+		// one derived from the category name.
+		`ALTER TABLE categories ADD COLUMN IF NOT EXISTS color TEXT NOT NULL DEFAULT ''`,
+
+		// Whether the bot is still in a group chat: 'pending' until an
+		// authorized user claims a chat a stranger added it to, 'left'
+		// once it was removed. Scheduled deliveries skip left chats.
+		`ALTER TABLE chats ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'`,
+		`ALTER TABLE chats ADD COLUMN IF NOT EXISTS added_by BIGINT`,
+		`ALTER TABLE chats ADD COLUMN IF NOT EXISTS joined_at TIMESTAMPTZ`,
+		`CREATE INDEX IF NOT EXISTS idx_chats_pending ON chats(joined_at) WHERE status = 'pending'`,
+
+		// Categories added by a user are private to them; owner_id 0 marks
+		// the shared ones, which include every category created before.
+		// Names are unique per owner, and users hide shared categories they
+		// do not want in hidden_categories.
+		`ALTER TABLE categories ADD COLUMN IF NOT EXISTS owner_id BIGINT NOT NULL DEFAULT 0`,
+		`ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_name_key`,
+		`DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'categories_owner_id_name_key') THEN
+				ALTER TABLE categories ADD CONSTRAINT categories_owner_id_name_key UNIQUE (owner_id, name);
+			END IF;
+		END $$`,
+		`CREATE TABLE IF NOT EXISTS hidden_categories (
+			user_id BIGINT NOT NULL,
+			category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+			PRIMARY KEY (user_id, category_id)
+		)`,
+		// Whether the user picked a starting category set on /start.
+		// Users that existed before are treated as having picked.
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS categories_chosen BOOLEAN NOT NULL DEFAULT TRUE`,
+		`ALTER TABLE users ALTER COLUMN categories_chosen SET DEFAULT FALSE`,
+		// One-line verdicts users attach to a month with /review YYYY-MM,
+		// keyed by the month as "2006-01".
+		`CREATE TABLE IF NOT EXISTS monthly_reviews (
+			user_id BIGINT NOT NULL,
+			month TEXT NOT NULL,
+			note TEXT NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (user_id, month)
+		)`,
+		// When a proactive message first failed because the user blocked
+		// the bot. Scheduled messages skip these users until they write
+		// again.
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS unreachable_since TIMESTAMPTZ`,
+		// CSV files imported with /import, by the SHA-256 of their
+		// normalized contents, so the same file is not imported twice.
+		`CREATE TABLE IF NOT EXISTS import_batches (
+			id SERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			file_hash TEXT NOT NULL,
+			row_count INTEGER NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_import_batches_user_hash ON import_batches(user_id, file_hash)`,
+		// Daily spreadsheet exports turned on with /sheetexport.
+		// last_expense_id is the watermark of expenses already sent and
+		// row_count the rows sent in total.
+		`CREATE TABLE IF NOT EXISTS sheet_exports (
+			user_id BIGINT PRIMARY KEY,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			last_expense_id INTEGER NOT NULL DEFAULT 0,
+			row_count BIGINT NOT NULL DEFAULT 0,
+			sent_on DATE,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		// Scheduled messages waiting for the outbox loop. dedupe_key names
+		// the job, user and period, so a job that runs again after a
+		// crash does not queue the same message twice. A message is
+		// retried until sent_at or failed_at is set.
+		`CREATE TABLE IF NOT EXISTS outbox (
+			id SERIAL PRIMARY KEY,
+			chat_id BIGINT NOT NULL,
+			text TEXT NOT NULL,
+			parse_mode TEXT NOT NULL DEFAULT '',
+			reply_markup TEXT NOT NULL DEFAULT '',
+			dedupe_key TEXT NOT NULL UNIQUE,
+			scheduled_for TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			sent_at TIMESTAMPTZ,
+			failed_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_outbox_due ON outbox(scheduled_for)
+			WHERE sent_at IS NULL AND failed_at IS NULL`,
+
+		// Optional caller-chosen key that makes creating an expense safe to
+		// retry: a second create with the same key for the same user
+		// returns the first expense. NULLs do not conflict, so expenses
+		// without a key are unaffected.
+		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS idempotency_key TEXT`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_expenses_user_idempotency_key
+			ON expenses(user_id, idempotency_key)`,
+
+		// Whether the user's receipts, voice messages and descriptions may
+		// be sent to Gemini. Turned off with /settings ai off.
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS ai_enabled BOOLEAN NOT NULL DEFAULT TRUE`,
+
+		// Every entry path now records where an expense came from. Older
+		// rows become 'unknown', and the check keeps the column to the
+		// sources of models.ExpenseSources.
+		`UPDATE expenses SET source = 'unknown' WHERE source = ''`,
+		`ALTER TABLE expenses ALTER COLUMN source SET DEFAULT 'unknown'`,
+		`DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'expenses_source_check') THEN
+				ALTER TABLE expenses ADD CONSTRAINT expenses_source_check CHECK (source IN (
+					'unknown', 'text', 'command', 'receipt', 'voice', 'bank', 'api', 'import', 'recurring', 'demo'
+				));
+			END IF;
+		END $$`,
+
+		// Receipt prompt variants managed with /promptvariants. Active
+		// variants take their share, in percent, of receipt scans from the
+		// built-in prompt.
+		`CREATE TABLE IF NOT EXISTS prompt_variants (
+			id SERIAL PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			template TEXT NOT NULL,
+			active BOOLEAN NOT NULL DEFAULT FALSE,
+			share INTEGER NOT NULL DEFAULT 0 CHECK (share BETWEEN 0 AND 100),
+			created_by BIGINT,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		// The variant that read a receipt, empty for the built-in prompt,
+		// and the draft's values as scanned so later corrections show.
+		`ALTER TABLE receipt_scans ADD COLUMN IF NOT EXISTS prompt_variant TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE receipt_scans ADD COLUMN IF NOT EXISTS scanned_amount DECIMAL(12, 2)`,
+		`ALTER TABLE receipt_scans ADD COLUMN IF NOT EXISTS scanned_currency TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE receipt_scans ADD COLUMN IF NOT EXISTS scanned_merchant TEXT NOT NULL DEFAULT ''`,
+
+		// Expense listings filter on user and status and order by creation
+		// time, then ID for expenses created in the same instant.
+		`CREATE INDEX IF NOT EXISTS idx_expenses_user_status_created
+			ON expenses(user_id, status, created_at, id)`,
+
+		// Category names are unique per owner ignoring case, as they are
+		// looked up. Names that only differed in case get their ID appended
+		// before the index replaces the case-sensitive constraint.
+		`UPDATE categories c SET name = c.name || ' (' || c.id || ')'
+			WHERE EXISTS (
+				SELECT 1 FROM categories o
+				WHERE o.owner_id = c.owner_id AND LOWER(o.name) = LOWER(c.name) AND o.id < c.id
+			)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_owner_lower_name
+			ON categories(owner_id, LOWER(name))`,
+		`ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_owner_id_name_key`,
+
+		// When the daily sheet export sent an expense. Expenses confirmed
+		// or imported after a later one was sent still go out, as the
+		// export no longer stops at the highest ID sent. Expenses up to
+		// the old watermark count as sent.
+		`ALTER TABLE expenses ADD COLUMN IF NOT EXISTS sheet_exported_at TIMESTAMPTZ`,
+		`UPDATE expenses e SET sheet_exported_at = s.updated_at
+			FROM sheet_exports s
+			WHERE e.user_id = s.user_id AND e.id <= s.last_expense_id AND e.sheet_exported_at IS NULL`,
+
+		// The daily AI category suggestion cap counts each user's stored
+		// suggestions of the day, so it survives restarts.
+		`CREATE INDEX IF NOT EXISTS idx_gemini_usage_user_feature
+			ON gemini_usage(user_id, feature, created_at)`,
+
+		// Whether a receipt scan recorded its scanned values. The amount
+		// used to be NULL for none, which also dropped scans that read 0.
+		`ALTER TABLE receipt_scans ADD COLUMN IF NOT EXISTS scanned_recorded BOOLEAN NOT NULL DEFAULT FALSE`,
+		`UPDATE receipt_scans SET scanned_recorded = TRUE
+			WHERE scanned_amount IS NOT NULL AND NOT scanned_recorded`,
+
+		// The items sum a receipt draft offered instead of its total, read
+		// back when the user picks it.
+		`ALTER TABLE receipt_scans ADD COLUMN IF NOT EXISTS scanned_items_sum DECIMAL(12, 2)`,
 	}
+}
 
-	for i, migration := range migrations {
-		if _, err := pool.Exec(ctx, migration); err != nil {
-			return fmt.Errorf("migration %d failed: %w", i+1, err)
+// SeedCategories inserts the shared categories in names that do not exist
+// yet. It runs under the schema advisory lock, and a category that already
+// exists under any unique constraint is skipped, so repeated and
+// concurrent runs leave exactly one of each.
+func SeedCategories(ctx context.Context, pool *pgxpool.Pool, names []string) error {
+	_, err := withSchemaLock(ctx, pool, func(conn *pgxpool.Conn) error {
+		for _, cat := range names {
+			_, err := conn.Exec(
+				ctx,
+				`INSERT INTO categories (name) VALUES ($1) ON CONFLICT DO NOTHING`,
+				cat,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to seed category %q: %w", cat, err)
+			}
 		}
-	}
+		return nil
+	})
+	return err
+}
 
-	return nil
+// BankTemplate is a regular expression that reads a bank notification
+// message. Its named groups "amount" (required), "currency", "merchant"
+// and "date" are the parts of the expense.
+type BankTemplate struct {
+	Name    string
+	Pattern string
 }
 
-// SeedCategories inserts the default expense categories.
-func SeedCategories(ctx context.Context, pool *pgxpool.Pool) error {
-	categories := []string{
-		"Food - Dining Out",
-		"Food - Grocery",
-		"Transportation",
-		"Communication",
-		"Housing - Mortgage",
-		"Housing - Others",
-		"Personal Care",
-		"Health and Wellness",
-		"Education",
-		"Entertainment",
-		"Credit/Debt Payments",
-		"Others",
-		"Utilities",
-		"Travel & Vacation",
-		"Subscriptions",
-		"Donations",
-	}
+// The default bank templates are built from these shared parts.
+const (
+	bankAmountGroup   = `(?P<amount>\d[\d,]*(?:\.\d{1,2})?)`
+	bankCurrencyGroup = `(?:(?P<currency>[A-Z]{3}|S\$|US\$|\$)\s?)?`
+	bankMerchantGroup = `\s+(?:at|to)\s+(?P<merchant>.+?)`
+	bankDateGroup     = `(?:\s+on\s+(?P<date>\d{4}-\d{2}-\d{2}|\d{1,2}[/-]\d{1,2}(?:[/-]\d{2,4})?|` +
+		`\d{1,2}\s[A-Za-z]{3}(?:\s\d{4})?))?`
+	bankMessageEnd = `(?:\.?\s*$|\.\s|\n)`
+)
 
-	for _, cat := range categories {
-		_, err := pool.Exec(
-			ctx,
-			`INSERT INTO categories (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`,
-			cat,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to seed category %q: %w", cat, err)
-		}
-	}
+// DefaultBankTemplates are seeded into bank_templates and cover common
+// card and account alert wordings.
+var DefaultBankTemplates = []BankTemplate{
+	{
+		Name: "you-spent",
+		Pattern: `(?i)you(?:'ve| have)? spent ` + bankCurrencyGroup + bankAmountGroup +
+			bankMerchantGroup + bankDateGroup + bankMessageEnd,
+	},
+	{
+		Name: "card-charged",
+		Pattern: `(?i)card ending (?:in )?\d{4} (?:was|has been) charged ` + bankCurrencyGroup + bankAmountGroup +
+			bankMerchantGroup + bankDateGroup + bankMessageEnd,
+	},
+	{
+		Name: "transaction-of",
+		Pattern: `(?i)(?:transaction|purchase|payment) of ` + bankCurrencyGroup + bankAmountGroup +
+			`(?:\s+was made)?` + bankMerchantGroup + bankDateGroup + bankMessageEnd,
+	},
+}
 
-	return nil
+// SeedBankTemplates inserts the default bank templates that were never
+// added. Like SeedCategories it runs under the schema advisory lock.
+func SeedBankTemplates(ctx context.Context, pool *pgxpool.Pool) error {
+	_, err := withSchemaLock(ctx, pool, func(conn *pgxpool.Conn) error {
+		for _, tmpl := range DefaultBankTemplates {
+			_, err := conn.Exec(
+				ctx,
+				`INSERT INTO bank_templates (name, pattern) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING`,
+				tmpl.Name, tmpl.Pattern,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to seed bank template %q: %w", tmpl.Name, err)
+			}
+		}
+		return nil
+	})
+	return err
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"gitlab.com/yelinaung/expense-bot/internal/database"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
 	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
 )
 
@@ -347,7 +348,7 @@ func TestSeedCategories_DuplicateHandling(t *testing.T) {
 	require.Equal(t, 1, countBefore)
 
 	// Run seed - should not error and should not duplicate
-	err = database.SeedCategories(ctx, pool)
+	err = database.SeedCategories(ctx, pool, models.DefaultCategories)
 	require.NoError(t, err)
 
 	var countAfter int
@@ -368,7 +369,9 @@ func TestMigrations_MigrationOrder(t *testing.T) {
 	ctx := context.Background()
 
 	// Drop all tables to test from scratch
-	_, err := pool.Exec(ctx, "DROP TABLE IF EXISTS expenses CASCADE")
+	_, err := pool.Exec(ctx, "DROP TABLE IF EXISTS schema_migrations")
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, "DROP TABLE IF EXISTS expenses CASCADE")
 	require.NoError(t, err)
 	_, err = pool.Exec(ctx, "DROP TABLE IF EXISTS categories CASCADE")
 	require.NoError(t, err)
@@ -402,7 +405,7 @@ func TestSeedCategories_CategoryOrder(t *testing.T) {
 
 	dbtest.CleanupTables(ctx, t, pool)
 
-	err = database.SeedCategories(ctx, pool)
+	err = database.SeedCategories(ctx, pool, models.DefaultCategories)
 	require.NoError(t, err)
 
 	// Verify first category
@@ -0,0 +1,72 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
+)
+
+func TestRenumberDuplicateExpenseNumbers(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	const dupUser, cleanUser = int64(391001), int64(391002)
+	for _, id := range []int64{dupUser, cleanUser} {
+		_, err := tx.Exec(ctx, `INSERT INTO users (id, first_name) VALUES ($1, 'Numbers')`, id)
+		require.NoError(t, err)
+	}
+
+	// Recreate the state an older race could leave behind.
+	_, err := tx.Exec(ctx, `DROP INDEX idx_expenses_user_number`)
+	require.NoError(t, err)
+	insert := func(userID int64, number int, createdAt string) {
+		t.Helper()
+		_, err := tx.Exec(ctx, `
+			INSERT INTO expenses (user_id, amount, currency, description, user_expense_number, created_at)
+			VALUES ($1, 1, 'SGD', 'dup', $2, $3::timestamptz)
+		`, userID, number, createdAt)
+		require.NoError(t, err)
+	}
+	insert(dupUser, 1, "2026-01-01T10:00:00Z")
+	insert(dupUser, 2, "2026-01-02T10:00:00Z")
+	insert(dupUser, 2, "2026-01-02T11:00:00Z")
+	insert(dupUser, 3, "2026-01-03T10:00:00Z")
+	insert(cleanUser, 5, "2026-01-01T10:00:00Z")
+	insert(cleanUser, 9, "2026-01-02T10:00:00Z")
+
+	for range 2 {
+		_, err = tx.Exec(ctx, database.RenumberDuplicateExpenseNumbersSQL)
+		require.NoError(t, err)
+	}
+	_, err = tx.Exec(ctx, database.SyncExpenseCountersSQL)
+	require.NoError(t, err)
+	_, err = tx.Exec(ctx, `CREATE UNIQUE INDEX idx_expenses_user_number ON expenses(user_id, user_expense_number)`)
+	require.NoError(t, err)
+
+	numbers := func(userID int64) []int64 {
+		t.Helper()
+		rows, err := tx.Query(ctx, `
+			SELECT user_expense_number FROM expenses WHERE user_id = $1 ORDER BY created_at
+		`, userID)
+		require.NoError(t, err)
+		defer rows.Close()
+		var got []int64
+		for rows.Next() {
+			var n int64
+			require.NoError(t, rows.Scan(&n))
+			got = append(got, n)
+		}
+		require.NoError(t, rows.Err())
+		return got
+	}
+	require.Equal(t, []int64{1, 2, 3, 4}, numbers(dupUser))
+	require.Equal(t, []int64{5, 9}, numbers(cleanUser), "users without duplicates keep their numbers")
+
+	var next int64
+	require.NoError(t, tx.QueryRow(ctx,
+		`SELECT next_number FROM user_expense_counters WHERE user_id = $1`, dupUser).Scan(&next))
+	require.GreaterOrEqual(t, next, int64(5))
+}
@@ -2,10 +2,15 @@ package database_test
 
 import (
 	"context"
+	"os"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/require"
 	"gitlab.com/yelinaung/expense-bot/internal/database"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
 	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
 )
 
@@ -54,7 +59,7 @@ func TestSeedCategories(t *testing.T) {
 
 	dbtest.CleanupTables(ctx, t, pool)
 
-	err = database.SeedCategories(ctx, pool)
+	err = database.SeedCategories(ctx, pool, models.DefaultCategories)
 	require.NoError(t, err)
 
 	var count int
@@ -62,10 +67,97 @@ func TestSeedCategories(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 16, count)
 
-	err = database.SeedCategories(ctx, pool)
+	err = database.SeedCategories(ctx, pool, models.DefaultCategories)
 	require.NoError(t, err)
 
 	err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM categories").Scan(&count)
 	require.NoError(t, err)
 	require.Equal(t, 16, count, "should not duplicate categories on re-seed")
 }
+
+func TestSeedCategories_CustomList(t *testing.T) {
+	pool := dbtest.TestDB(t)
+	ctx := context.Background()
+
+	err := database.RunMigrations(ctx, pool)
+	require.NoError(t, err)
+
+	dbtest.CleanupTables(ctx, t, pool)
+
+	err = database.SeedCategories(ctx, pool, []string{"Rent", "Groceries"})
+	require.NoError(t, err)
+
+	var names []string
+	rows, err := pool.Query(ctx, "SELECT name FROM categories WHERE owner_id = 0 ORDER BY name")
+	require.NoError(t, err)
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, []string{"Groceries", "Rent"}, names)
+}
+
+func TestStartup_ConcurrentInstances(t *testing.T) {
+	pool := dbtest.TestDB(t)
+	ctx := context.Background()
+	dbtest.CleanupTables(ctx, t, pool)
+
+	// A second pool stands in for another replica booting at the same time.
+	replica, err := database.Connect(ctx, os.Getenv("TEST_DATABASE_URL"), false)
+	require.NoError(t, err)
+	t.Cleanup(replica.Close)
+
+	errs := make(chan error, 2)
+	var wg sync.WaitGroup
+	for _, p := range []*pgxpool.Pool{pool, replica} {
+		wg.Go(func() {
+			if err := database.RunMigrations(ctx, p); err != nil {
+				errs <- err
+				return
+			}
+			errs <- database.SeedCategories(ctx, p, models.DefaultCategories)
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	var count, distinct int
+	err = pool.QueryRow(ctx, "SELECT COUNT(*), COUNT(DISTINCT name) FROM categories").Scan(&count, &distinct)
+	require.NoError(t, err)
+	require.Equal(t, 16, count, "exactly one seed set")
+	require.Equal(t, count, distinct)
+}
+
+func TestSeedCategories_WaitsForSchemaLock(t *testing.T) {
+	pool := dbtest.TestDB(t)
+	ctx := context.Background()
+
+	holder, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	defer holder.Release()
+	_, err = holder.Exec(ctx, "SELECT pg_advisory_lock($1)", database.SchemaLockKey)
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- database.SeedCategories(ctx, pool, models.DefaultCategories) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("seeding finished while another instance held the lock: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	_, err = holder.Exec(ctx, "SELECT pg_advisory_unlock($1)", database.SchemaLockKey)
+	require.NoError(t, err)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("seeding did not resume after the lock was released")
+	}
+}
@@ -0,0 +1,265 @@
+// Package devtools generates made-up data for screenshots, manual testing
+// and test fixtures. Nothing in it should run against a production
+// database.
+package devtools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+// Limits of the demo data generator.
+const (
+	DefaultDemoDays = 30
+	MaxDemoDays     = 365
+
+	// demoDrafts is how many unconfirmed drafts are added on top of the
+	// confirmed expenses.
+	demoDrafts = 2
+	// demoTagChance is the chance that a demo expense gets a tag.
+	demoTagChance = 0.25
+)
+
+var errNoDemoCategories = errors.New("none of the demo categories exist")
+
+// demoItem is one kind of purchase in a category, with the range its
+// amount is drawn from.
+type demoItem struct {
+	Description string
+	Merchant    string
+	Min, Max    float64
+}
+
+// demoCatalog lists plausible purchases for the default categories.
+// Categories missing from the database are skipped.
+var demoCatalog = map[string][]demoItem{
+	"Food - Dining Out": {
+		{Description: "Kopi and kaya toast", Merchant: "Ya Kun", Min: 3, Max: 8},
+		{Description: "Chicken rice", Merchant: "Tian Tian", Min: 4, Max: 9},
+		{Description: "Team lunch", Merchant: "Din Tai Fung", Min: 25, Max: 60},
+		{Description: "Dinner", Merchant: "Jumbo Seafood", Min: 40, Max: 120},
+	},
+	"Food - Grocery": {
+		{Description: "Weekly groceries", Merchant: "FairPrice", Min: 30, Max: 140},
+		{Description: "Fruit and milk", Merchant: "Cold Storage", Min: 8, Max: 25},
+	},
+	"Transportation": {
+		{Description: "Grab ride", Merchant: "Grab", Min: 9, Max: 30},
+		{Description: "MRT top-up", Merchant: "SimplyGo", Min: 10, Max: 30},
+	},
+	"Entertainment": {
+		{Description: "Movie tickets", Merchant: "Golden Village", Min: 13, Max: 30},
+		{Description: "Concert", Merchant: "Sistic", Min: 60, Max: 180},
+	},
+	"Subscriptions": {
+		{Description: "Music streaming", Merchant: "Spotify", Min: 10, Max: 13},
+		{Description: "Video streaming", Merchant: "Netflix", Min: 12, Max: 22},
+	},
+	"Utilities": {
+		{Description: "Electricity bill", Merchant: "SP Group", Min: 60, Max: 160},
+	},
+	"Personal Care": {
+		{Description: "Haircut", Merchant: "QB House", Min: 14, Max: 30},
+		{Description: "Toiletries", Merchant: "Watsons", Min: 8, Max: 40},
+	},
+	"Health and Wellness": {
+		{Description: "Gym class", Merchant: "Anytime Fitness", Min: 20, Max: 45},
+		{Description: "Clinic visit", Merchant: "Raffles Medical", Min: 30, Max: 90},
+	},
+}
+
+// demoTags are the tags sprinkled over demo expenses.
+var demoTags = []string{"work", "family", "weekend"}
+
+// DemoOptions configures SeedDemo.
+type DemoOptions struct {
+	UserID int64
+	// Days is how many days back from Now expenses are spread over,
+	// including today. Zero uses DefaultDemoDays.
+	Days int
+	// Now is the end of the seeded range. Zero uses the current time.
+	Now time.Time
+	// Seed makes the generated data reproducible.
+	Seed uint64
+}
+
+// DemoResult summarizes what SeedDemo inserted.
+type DemoResult struct {
+	Confirmed int
+	Drafts    int
+	Tagged    int
+	From, To  time.Time
+}
+
+// SeedDemo inserts made-up expenses for opts.UserID: one to three
+// confirmed expenses per day over opts.Days days, with amounts drawn from
+// per-category ranges, a few tags, and demoDrafts drafts dated opts.Now.
+// Every row is marked with models.ExpenseSourceDemo so WipeDemo can find
+// it again. When db can begin a transaction everything is inserted in
+// one, so a failed run leaves nothing behind. The user must already exist.
+func SeedDemo(ctx context.Context, db database.PGXDB, opts DemoOptions) (*DemoResult, error) {
+	days := opts.Days
+	if days <= 0 {
+		days = DefaultDemoDays
+	}
+	if days > MaxDemoDays {
+		return nil, fmt.Errorf("at most %d days of demo data can be seeded", MaxDemoDays)
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var tx pgx.Tx
+	if beginner, ok := db.(database.TxBeginner); ok {
+		var err error
+		tx, err = beginner.Begin(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin demo transaction: %w", err)
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		db = tx
+	}
+
+	categories, err := repository.NewCategoryRepository(db).GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load categories: %w", err)
+	}
+	var catalog []demoCategory
+	for _, category := range categories {
+		if items, ok := demoCatalog[category.Name]; ok && category.OwnerID == 0 {
+			catalog = append(catalog, demoCategory{ID: category.ID, Items: items})
+		}
+	}
+	if len(catalog) == 0 {
+		return nil, errNoDemoCategories
+	}
+
+	tagRepo := repository.NewTagRepository(db)
+	tagIDs := make([]int, 0, len(demoTags))
+	for _, name := range demoTags {
+		tag, err := tagRepo.GetOrCreate(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create demo tag %q: %w", name, err)
+		}
+		tagIDs = append(tagIDs, tag.ID)
+	}
+
+	rng := rand.New(rand.NewPCG(opts.Seed, uint64(opts.UserID))) //nolint:gosec // Demo data needs no secure randomness.
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	result := &DemoResult{To: now}
+
+	for day := days - 1; day >= 0; day-- {
+		date := startOfToday.AddDate(0, 0, -day)
+		for range 1 + rng.IntN(3) {
+			createdAt := date.Add(time.Duration(7*60+rng.IntN(15*60)) * time.Minute)
+			if createdAt.After(now) {
+				createdAt = now
+			}
+			id, err := insertDemoExpense(ctx, db, opts.UserID, pickDemoExpense(rng, catalog),
+				models.ExpenseStatusConfirmed, createdAt)
+			if err != nil {
+				return nil, err
+			}
+			result.Confirmed++
+			if result.From.IsZero() || createdAt.Before(result.From) {
+				result.From = createdAt
+			}
+
+			if rng.Float64() < demoTagChance {
+				tagID := tagIDs[rng.IntN(len(tagIDs))]
+				if err := tagRepo.SetExpenseTags(ctx, id, []int{tagID}); err != nil {
+					return nil, fmt.Errorf("failed to tag demo expense: %w", err)
+				}
+				result.Tagged++
+			}
+		}
+	}
+
+	// Drafts are dated now so the draft cleanup does not remove them
+	// straight away.
+	for range demoDrafts {
+		if _, err := insertDemoExpense(ctx, db, opts.UserID, pickDemoExpense(rng, catalog),
+			models.ExpenseStatusDraft, now); err != nil {
+			return nil, err
+		}
+		result.Drafts++
+	}
+
+	if tx != nil {
+		if err := tx.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("failed to commit demo data: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// WipeDemo deletes the demo expenses of userID and reports how many were
+// removed. Expenses the user entered themselves are left alone.
+func WipeDemo(ctx context.Context, db database.PGXDB, userID int64) (int64, error) {
+	tag, err := db.Exec(ctx, `DELETE FROM expenses WHERE user_id = $1 AND source = $2`,
+		userID, models.ExpenseSourceDemo)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete demo expenses: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// demoCategory is a demoCatalog entry resolved to a category ID.
+type demoCategory struct {
+	ID    int
+	Items []demoItem
+}
+
+// demoExpense is one generated expense before it is inserted.
+type demoExpense struct {
+	CategoryID  int
+	Description string
+	Merchant    string
+	Amount      decimal.Decimal
+}
+
+func pickDemoExpense(rng *rand.Rand, catalog []demoCategory) demoExpense {
+	category := catalog[rng.IntN(len(catalog))]
+	item := category.Items[rng.IntN(len(category.Items))]
+	amount := item.Min + rng.Float64()*(item.Max-item.Min)
+	return demoExpense{
+		CategoryID:  category.ID,
+		Description: item.Description,
+		Merchant:    item.Merchant,
+		Amount:      decimal.NewFromFloat(amount).Round(2),
+	}
+}
+
+func insertDemoExpense(
+	ctx context.Context,
+	db database.PGXDB,
+	userID int64,
+	expense demoExpense,
+	status models.ExpenseStatus,
+	createdAt time.Time,
+) (int, error) {
+	var id int
+	err := db.QueryRow(ctx, `
+		INSERT INTO expenses (user_id, amount, currency, description, merchant, category_id, status, source,
+		                      created_at, updated_at)
+		SELECT $1, $2, u.default_currency, $3, $4, $5, $6, $7, $8, $8
+		FROM users u WHERE u.id = $1
+		RETURNING id
+	`, userID, expense.Amount, expense.Description, expense.Merchant, expense.CategoryID,
+		status, models.ExpenseSourceDemo, createdAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert demo expense: %w", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,94 @@
+package devtools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
+)
+
+func TestSeedDemo(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	userID := int64(389001)
+	require.NoError(t, repository.NewUserRepository(tx).UpsertUser(ctx, &models.User{ID: userID, FirstName: "Demo"}))
+	expenseRepo := repository.NewExpenseRepository(tx)
+	organic := &models.Expense{UserID: userID, Amount: decimal.NewFromInt(7), Currency: "SGD", Description: "Real lunch"}
+	require.NoError(t, expenseRepo.Create(ctx, organic))
+
+	now := time.Date(2026, 10, 17, 23, 0, 0, 0, time.UTC)
+	const days = 10
+	result, err := SeedDemo(ctx, tx, DemoOptions{UserID: userID, Days: days, Now: now, Seed: 1})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, result.Confirmed, days)
+	require.LessOrEqual(t, result.Confirmed, 3*days)
+	require.Equal(t, demoDrafts, result.Drafts)
+	require.Equal(t, now, result.To)
+
+	rows, err := tx.Query(ctx, `
+		SELECT status, created_at, amount, category_id IS NOT NULL
+		FROM expenses WHERE user_id = $1 AND source = $2
+	`, userID, models.ExpenseSourceDemo)
+	require.NoError(t, err)
+	defer rows.Close()
+	seededDays := make(map[string]bool)
+	confirmed, drafts := 0, 0
+	for rows.Next() {
+		var status models.ExpenseStatus
+		var createdAt time.Time
+		var amount decimal.Decimal
+		var categorized bool
+		require.NoError(t, rows.Scan(&status, &createdAt, &amount, &categorized))
+		require.True(t, amount.IsPositive())
+		require.True(t, categorized)
+		require.False(t, createdAt.After(now))
+		require.False(t, createdAt.Before(now.AddDate(0, 0, -days)))
+		if status == models.ExpenseStatusDraft {
+			drafts++
+			continue
+		}
+		confirmed++
+		seededDays[createdAt.UTC().Format("2006-01-02")] = true
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, result.Confirmed, confirmed)
+	require.Equal(t, result.Drafts, drafts)
+	require.Len(t, seededDays, days, "every day should have an expense")
+	require.True(t, seededDays["2026-10-08"])
+
+	deleted, err := WipeDemo(ctx, tx, userID)
+	require.NoError(t, err)
+	require.Equal(t, int64(result.Confirmed+result.Drafts), deleted)
+
+	remaining, err := expenseRepo.GetByUserID(ctx, userID, 10)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	require.Equal(t, organic.ID, remaining[0].ID)
+}
+
+func TestSeedDemo_FailureLeavesNothing(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	// The user does not exist, so the first expense fails after the demo
+	// tags were created.
+	_, err := SeedDemo(ctx, tx, DemoOptions{UserID: 389002, Days: 1, Seed: 1})
+	require.Error(t, err)
+
+	var tags int
+	require.NoError(t, tx.QueryRow(ctx, `SELECT COUNT(*) FROM tags WHERE name = ANY($1)`, demoTags).Scan(&tags))
+	require.Zero(t, tags, "the demo tags are rolled back")
+}
+
+func TestSeedDemo_TooManyDays(t *testing.T) {
+	t.Parallel()
+
+	_, err := SeedDemo(context.Background(), nil, DemoOptions{UserID: 1, Days: MaxDemoDays + 1})
+	require.Error(t, err)
+}
@@ -3,6 +3,7 @@ package exchange
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -124,6 +125,25 @@ func (s *CachedService) Convert(
 	return waitForInFlight(ctx, amount, call)
 }
 
+// ConvertOn converts at a past date's rate through the wrapped converter.
+// Historical lookups are not cached.
+func (s *CachedService) ConvertOn(
+	ctx context.Context,
+	amount decimal.Decimal,
+	fromCurrency, toCurrency string,
+	date time.Time,
+) (ConversionResult, error) {
+	historical, ok := s.inner.(HistoricalConverter)
+	if !ok {
+		return ConversionResult{}, ErrHistoricalUnsupported
+	}
+	result, err := historical.ConvertOn(ctx, amount, fromCurrency, toCurrency, date)
+	if err != nil {
+		return ConversionResult{}, fmt.Errorf("historical conversion failed: %w", err)
+	}
+	return result, nil
+}
+
 func (s *CachedService) fetchAndBroadcast(
 	ctx context.Context,
 	key string,
@@ -28,3 +28,24 @@ func TestCachedService_Convert_InnerNil(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "inner exchange service is required")
 }
+
+func TestCachedService_ConvertOn_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	upstream := &countingService{rate: decimal.RequireFromString("1.2")}
+	svc := NewCachedService(upstream, time.Hour, nil)
+	_, err := svc.ConvertOn(context.Background(), decimal.RequireFromString("10"), "USD", "SGD", time.Now())
+	require.ErrorIs(t, err, ErrHistoricalUnsupported)
+}
+
+func TestCachedService_ConvertOn_Delegates(t *testing.T) {
+	t.Parallel()
+
+	inner := NewFrankfurterClient("https://api.frankfurter.app", time.Second, nil)
+	svc := NewCachedService(inner, time.Hour, nil)
+	date := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	got, err := svc.ConvertOn(context.Background(), decimal.RequireFromString("10"), "SGD", "SGD", date)
+	require.NoError(t, err)
+	require.Equal(t, date, got.RateDate)
+	require.Empty(t, svc.rates)
+}
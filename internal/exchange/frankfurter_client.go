@@ -53,6 +53,31 @@ func (c *FrankfurterClient) Convert(
 	ctx context.Context,
 	amount decimal.Decimal,
 	fromCurrency, toCurrency string,
+) (ConversionResult, error) {
+	return c.convert(ctx, "latest", amount, fromCurrency, toCurrency, time.Now().UTC())
+}
+
+// ConvertOn converts amount using the rates published for date. Frankfurter
+// answers with the closest earlier working day when date has no rates.
+func (c *FrankfurterClient) ConvertOn(
+	ctx context.Context,
+	amount decimal.Decimal,
+	fromCurrency, toCurrency string,
+	date time.Time,
+) (ConversionResult, error) {
+	day := date.UTC()
+	return c.convert(ctx, day.Format("2006-01-02"), amount, fromCurrency, toCurrency, day)
+}
+
+// convert fetches the rate from the endpoint at path, "latest" or a
+// YYYY-MM-DD date. sameDate is reported when no lookup is needed because
+// both currencies match.
+func (c *FrankfurterClient) convert(
+	ctx context.Context,
+	path string,
+	amount decimal.Decimal,
+	fromCurrency, toCurrency string,
+	sameDate time.Time,
 ) (ConversionResult, error) {
 	from := strings.ToUpper(strings.TrimSpace(fromCurrency))
 	to := strings.ToUpper(strings.TrimSpace(toCurrency))
@@ -66,13 +91,14 @@ func (c *FrankfurterClient) Convert(
 		return ConversionResult{
 			Amount:   amount,
 			Rate:     decimal.NewFromInt(1),
-			RateDate: time.Now().UTC(),
+			RateDate: sameDate,
 		}, nil
 	}
 
 	endpoint := fmt.Sprintf(
-		"%s/latest?from=%s&to=%s",
+		"%s/%s?from=%s&to=%s",
 		c.baseURL,
+		path,
 		url.QueryEscape(from),
 		url.QueryEscape(to),
 	)
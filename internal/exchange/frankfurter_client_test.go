@@ -35,6 +35,24 @@ func TestFrankfurterClient_Convert(t *testing.T) {
 		require.Equal(t, "2026-02-14", got.RateDate.Format("2006-01-02"))
 	})
 
+	t.Run("converts at a past date", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/2025-01-15", r.URL.Path)
+			assert.Equal(t, "USD", r.URL.Query().Get("from"))
+			_, _ = w.Write([]byte(`{"amount":1,"base":"USD","date":"2025-01-15","rates":{"SGD":1.37}}`))
+		}))
+		defer server.Close()
+
+		client := NewFrankfurterClient(server.URL, time.Second, nil)
+		date := time.Date(2025, 1, 16, 3, 0, 0, 0, time.FixedZone("SGT", 8*60*60))
+		got, err := client.ConvertOn(context.Background(), decimal.RequireFromString("10"), "USD", "SGD", date)
+		require.NoError(t, err)
+		require.Equal(t, decimal.RequireFromString("13.70"), got.Amount)
+		require.Equal(t, "2025-01-15", got.RateDate.Format("2006-01-02"))
+	})
+
 	t.Run("returns error on non 200 response", func(t *testing.T) {
 		t.Parallel()
 
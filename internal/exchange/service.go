@@ -8,7 +8,13 @@ import (
 	"github.com/shopspring/decimal"
 )
 
-var errInvalidNonPositiveRate = errors.New("invalid non-positive rate")
+var (
+	errInvalidNonPositiveRate = errors.New("invalid non-positive rate")
+
+	// ErrHistoricalUnsupported is returned by ConvertOn when the wrapped
+	// converter cannot look up past rates.
+	ErrHistoricalUnsupported = errors.New("historical rates are not supported")
+)
 
 // ConversionResult contains converted amount details.
 type ConversionResult struct {
@@ -22,6 +28,17 @@ type Converter interface {
 	Convert(ctx context.Context, amount decimal.Decimal, fromCurrency, toCurrency string) (ConversionResult, error)
 }
 
+// HistoricalConverter converts amounts at the rate published for a past
+// date.
+type HistoricalConverter interface {
+	ConvertOn(
+		ctx context.Context,
+		amount decimal.Decimal,
+		fromCurrency, toCurrency string,
+		date time.Time,
+	) (ConversionResult, error)
+}
+
 func validateConversionRate(rate decimal.Decimal) error {
 	if !rate.IsPositive() {
 		return errInvalidNonPositiveRate
@@ -191,6 +191,7 @@ func (c *Client) callSuggestCategory(
 			Msg("SuggestCategory: Gemini API call failed")
 		return "", fmt.Errorf("gemini API call failed: %w", err)
 	}
+	c.recordUsage(ctx, FeatureSuggest, resp)
 	if resp == nil {
 		logger.Log.Warn().
 			Str("description_hash", descHash).
@@ -5,6 +5,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand/v2"
+	"sync"
 
 	"google.golang.org/genai"
 )
@@ -12,6 +14,15 @@ import (
 // ModelName is the Gemini model to use for receipt OCR and categorization.
 const ModelName = "gemini-2.5-flash"
 
+// servedModel returns the model version reported by the API response,
+// falling back to the requested model when the response does not name one.
+func servedModel(resp *genai.GenerateContentResponse, requested string) string {
+	if resp != nil && resp.ModelVersion != "" {
+		return resp.ModelVersion
+	}
+	return requested
+}
+
 // ContentGenerator defines the interface for generating content via Gemini.
 // This abstraction enables testing without making actual API calls.
 type ContentGenerator interface {
@@ -49,6 +60,11 @@ func (m *modelsAdapter) GenerateContent(
 type Client struct {
 	client    *genai.Client
 	generator ContentGenerator
+	usage     UsageRecorder // nil when token usage is not recorded.
+
+	variants PromptVariantSource // nil when only the built-in prompt is used.
+	rngMu    sync.Mutex
+	rng      *rand.Rand // picks prompt variants; guarded by rngMu.
 }
 
 // NewClient creates a new Gemini client with the provided API key.
@@ -83,3 +99,15 @@ func NewClientWithGenerator(generator ContentGenerator) *Client {
 func (c *Client) GenerativeClient() *genai.Client {
 	return c.client
 }
+
+// Ping makes the smallest possible request, a one-token reply, to confirm
+// the API key is accepted and the API is reachable.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.generator.GenerateContent(ctx, ModelName, genai.Text("ping"), &genai.GenerateContentConfig{
+		MaxOutputTokens: 1,
+	})
+	if err != nil {
+		return fmt.Errorf("gemini ping failed: %w", err)
+	}
+	return nil
+}
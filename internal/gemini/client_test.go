@@ -2,9 +2,11 @@ package gemini
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
 )
 
 func TestNewClient(t *testing.T) {
@@ -71,3 +73,15 @@ func TestModelsAdapter_GenerateContent_NilModels(t *testing.T) {
 	require.Nil(t, resp)
 	require.Contains(t, err.Error(), "genai models client is nil")
 }
+
+func TestClient_Ping(t *testing.T) {
+	t.Parallel()
+
+	generator := &mockGenerator{response: &genai.GenerateContentResponse{}}
+	require.NoError(t, NewClientWithGenerator(generator).Ping(context.Background()))
+	require.Equal(t, int32(1), generator.lastConfig.MaxOutputTokens)
+
+	generator = &mockGenerator{err: errors.New("API key not valid")}
+	err := NewClientWithGenerator(generator).Ping(context.Background())
+	require.ErrorContains(t, err, "API key not valid")
+}
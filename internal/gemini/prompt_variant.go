@@ -0,0 +1,104 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strings"
+
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+)
+
+// PromptCategoriesPlaceholder is replaced with the category list in a
+// receipt prompt template.
+const PromptCategoriesPlaceholder = "{categories}"
+
+// MaxPromptVariantShare is the total share, in percent, that prompt
+// variants can take from the built-in prompt.
+const MaxPromptVariantShare = 100
+
+// promptTemplateFields are the fields parseReceiptResponse needs, which a
+// prompt template has to ask for.
+var promptTemplateFields = []string{"amount", "currency", "merchant", "date", "suggested_category", "confidence"}
+
+// PromptVariant is an alternative receipt prompt. It serves Share percent
+// of receipt scans; the built-in prompt serves what the variants leave.
+type PromptVariant struct {
+	Name     string
+	Template string
+	Share    int
+}
+
+// PromptVariantSource returns the prompt variants that are active.
+type PromptVariantSource interface {
+	ActivePromptVariants(ctx context.Context) ([]PromptVariant, error)
+}
+
+// SetPromptVariants makes the client pick the receipt prompt of each scan
+// from the variants src returns. Call it before the client is used.
+func (c *Client) SetPromptVariants(src PromptVariantSource) {
+	c.variants = src
+	c.rng = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())) //nolint:gosec // Traffic splitting, not security.
+}
+
+// ValidatePromptTemplate checks that a receipt prompt template has the
+// category placeholder and asks for every field the reply is read with.
+func ValidatePromptTemplate(template string) error {
+	var missing []string
+	if !strings.Contains(template, PromptCategoriesPlaceholder) {
+		missing = append(missing, PromptCategoriesPlaceholder)
+	}
+	for _, field := range promptTemplateFields {
+		if !strings.Contains(template, field) {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.New("the template is missing " + strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// selectPromptVariant returns the variant that serves roll, a number in
+// [0, 100). Variants cover consecutive ranges of their share in order, so
+// rolls past the sum of the shares fall to the built-in prompt and ok is
+// false.
+func selectPromptVariant(variants []PromptVariant, roll int) (PromptVariant, bool) {
+	upper := 0
+	for _, v := range variants {
+		if v.Share <= 0 {
+			continue
+		}
+		upper += v.Share
+		if roll < upper {
+			return v, true
+		}
+	}
+	return PromptVariant{}, false
+}
+
+// pickPromptVariant rolls the client's random source to pick a variant.
+func (c *Client) pickPromptVariant(variants []PromptVariant) (PromptVariant, bool) {
+	if len(variants) == 0 {
+		return PromptVariant{}, false
+	}
+	c.rngMu.Lock()
+	roll := c.rng.IntN(MaxPromptVariantShare)
+	c.rngMu.Unlock()
+	return selectPromptVariant(variants, roll)
+}
+
+// receiptPrompt returns the prompt for a receipt scan and the name of the
+// variant it came from, or an empty name for the built-in prompt. When the
+// variants cannot be loaded the built-in prompt is used.
+func (c *Client) receiptPrompt(ctx context.Context) (string, string) {
+	if c.variants != nil {
+		variants, err := c.variants.ActivePromptVariants(ctx)
+		if err != nil {
+			logger.Log.Warn().Err(err).Msg("Failed to load prompt variants, using the built-in prompt")
+		} else if v, ok := c.pickPromptVariant(variants); ok {
+			return renderReceiptPrompt(v.Template, DefaultCategories), v.Name
+		}
+	}
+	return buildReceiptPrompt(DefaultCategories), ""
+}
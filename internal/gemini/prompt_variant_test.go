@@ -0,0 +1,134 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+// stubVariants implements PromptVariantSource for testing.
+type stubVariants struct {
+	variants []PromptVariant
+	err      error
+}
+
+func (s stubVariants) ActivePromptVariants(context.Context) ([]PromptVariant, error) {
+	return s.variants, s.err
+}
+
+func TestValidatePromptTemplate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, ValidatePromptTemplate(receiptPromptTemplate), "the built-in prompt is a valid template")
+
+	err := ValidatePromptTemplate("Return amount, currency and date as JSON.")
+	require.EqualError(t, err, "the template is missing {categories}, merchant, suggested_category, confidence")
+}
+
+func TestSelectPromptVariant(t *testing.T) {
+	t.Parallel()
+
+	variants := []PromptVariant{
+		{Name: "short", Share: 20},
+		{Name: "off", Share: 0},
+		{Name: "strict", Share: 30},
+	}
+	tests := []struct {
+		roll int
+		want string
+	}{
+		{roll: 0, want: "short"},
+		{roll: 19, want: "short"},
+		{roll: 20, want: "strict"},
+		{roll: 49, want: "strict"},
+		{roll: 50},
+		{roll: 99},
+	}
+	for _, tt := range tests {
+		v, ok := selectPromptVariant(variants, tt.roll)
+		require.Equal(t, tt.want != "", ok, "roll %d", tt.roll)
+		require.Equal(t, tt.want, v.Name, "roll %d", tt.roll)
+	}
+}
+
+func TestClient_PickPromptVariant(t *testing.T) {
+	t.Parallel()
+
+	variants := []PromptVariant{{Name: "a", Share: 25}, {Name: "b", Share: 50}}
+	picks := func(seed uint64) []string {
+		client := NewClientWithGenerator(&mockGenerator{})
+		client.SetPromptVariants(stubVariants{variants: variants})
+		client.rng = rand.New(rand.NewPCG(seed, seed))
+		names := make([]string, 4000)
+		for i := range names {
+			v, _ := client.pickPromptVariant(variants)
+			names[i] = v.Name
+		}
+		return names
+	}
+
+	first := picks(7)
+	require.Equal(t, first, picks(7), "the same seed picks the same variants")
+	require.NotEqual(t, first, picks(8))
+
+	counts := map[string]int{}
+	for _, name := range first {
+		counts[name]++
+	}
+	require.InDelta(t, 1000, counts["a"], 100)
+	require.InDelta(t, 2000, counts["b"], 100)
+	require.InDelta(t, 1000, counts[""], 100, "the built-in prompt serves the rest")
+}
+
+func TestParseReceipt_PromptVariants(t *testing.T) {
+	t.Parallel()
+
+	newMock := func() *mockGenerator {
+		return &mockGenerator{response: &genai.GenerateContentResponse{
+			Candidates: []*genai.Candidate{{Content: &genai.Content{Parts: []*genai.Part{
+				{Text: receiptJSON("12.50", "Kopitiam", "2024-01-15", 0.9)},
+			}}}},
+		}}
+	}
+	promptText := func(mock *mockGenerator) string {
+		parts := mock.lastContents[0].Parts
+		return parts[len(parts)-1].Text
+	}
+	variant := PromptVariant{
+		Name:     "terse",
+		Template: "Terse: amount, currency, merchant, date, confidence and suggested_category from {categories}",
+		Share:    MaxPromptVariantShare,
+	}
+
+	t.Run("a variant replaces the built-in prompt", func(t *testing.T) {
+		t.Parallel()
+		mock := newMock()
+		client := NewClientWithGenerator(mock)
+		client.SetPromptVariants(stubVariants{variants: []PromptVariant{variant}})
+
+		result, err := client.ParseReceipt(context.Background(), []byte("image"), testGeminiImageJPEG)
+		require.NoError(t, err)
+		require.Equal(t, "terse", result.PromptVariant)
+		require.Equal(t, ReceiptPromptVersion, result.PromptVersion)
+		require.True(t, strings.HasPrefix(promptText(mock), "Terse: "))
+		require.Contains(t, promptText(mock), "Food - Dining Out")
+		require.NotContains(t, promptText(mock), PromptCategoriesPlaceholder)
+	})
+
+	t.Run("a failed lookup uses the built-in prompt", func(t *testing.T) {
+		t.Parallel()
+		mock := newMock()
+		client := NewClientWithGenerator(mock)
+		client.SetPromptVariants(stubVariants{err: errors.New("db down")})
+
+		result, err := client.ParseReceipt(context.Background(), []byte("image"), testGeminiImageJPEG)
+		require.NoError(t, err)
+		require.Empty(t, result.PromptVariant)
+		require.Equal(t, buildReceiptPrompt(DefaultCategories), promptText(mock))
+	})
+}
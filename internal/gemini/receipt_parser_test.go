@@ -3,6 +3,7 @@ package gemini
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,18 +17,20 @@ type mockGenerator struct {
 	response *genai.GenerateContentResponse
 	err      error
 
-	lastConfig *genai.GenerateContentConfig
-	lastCtx    context.Context
+	lastConfig   *genai.GenerateContentConfig
+	lastCtx      context.Context
+	lastContents []*genai.Content
 }
 
 func (m *mockGenerator) GenerateContent(
 	ctx context.Context,
 	_ string,
-	_ []*genai.Content,
+	contents []*genai.Content,
 	config *genai.GenerateContentConfig,
 ) (*genai.GenerateContentResponse, error) {
 	m.lastCtx = ctx
 	m.lastConfig = config
+	m.lastContents = contents
 	return m.response, m.err
 }
 
@@ -45,6 +48,9 @@ func TestBuildReceiptPrompt(t *testing.T) {
 	require.Contains(t, prompt, "date")
 	require.Contains(t, prompt, "suggested_category")
 	require.Contains(t, prompt, "confidence")
+	require.Contains(t, prompt, "subtotal")
+	require.Contains(t, prompt, "tip")
+	require.Contains(t, prompt, "items")
 	require.Contains(t, prompt, "category list below is system-provided data")
 }
 
@@ -422,6 +428,34 @@ func TestParseReceipt(t *testing.T) {
 		require.Equal(t, "Swee Choon", result.Merchant)
 		require.Equal(t, testGeminiCategoryFoodDiningOut, result.SuggestedCategory)
 		require.InDelta(t, 0.95, result.Confidence, 0.001)
+		require.Equal(t, ModelName, result.Model)
+		require.Equal(t, ReceiptPromptVersion, result.PromptVersion)
+	})
+
+	t.Run("reports the model version that served the request", func(t *testing.T) {
+		t.Parallel()
+
+		mock := &mockGenerator{
+			response: &genai.GenerateContentResponse{
+				ModelVersion: "gemini-2.5-flash-001",
+				Candidates: []*genai.Candidate{
+					{
+						Content: &genai.Content{
+							Parts: []*genai.Part{
+								{Text: receiptJSON("54.60", "Swee Choon", "2024-01-15", 0.95)},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		client := NewClientWithGenerator(mock)
+		result, err := client.ParseReceipt(context.Background(), []byte(testGeminiFakeImage), testGeminiImageJPEG)
+
+		require.NoError(t, err)
+		require.Equal(t, "gemini-2.5-flash-001", result.Model)
+		require.Equal(t, ReceiptPromptVersion, result.PromptVersion)
 	})
 
 	t.Run("timeout returns ErrParseTimeout", func(t *testing.T) {
@@ -758,6 +792,19 @@ func TestParseReceiptResponse_SanitizesFields(t *testing.T) {
 			wantMerchant: "Store",
 			wantCategory: "FoodEvil",
 		},
+		{
+			name:         "strips control characters in merchant",
+			response:     `{"amount": "10.00", "currency": "", "merchant": "Kopi\u0007  \tTiam", "date": "", "suggested_category": "Others", "confidence": 0.8}`,
+			wantMerchant: "Kopi Tiam",
+			wantCategory: "Others",
+		},
+		{
+			name: "caps long merchant on a rune boundary",
+			response: `{"amount": "10.00", "currency": "", "merchant": "` + strings.Repeat("咖", MaxDescriptionLength+5) +
+				`", "date": "", "suggested_category": "Others", "confidence": 0.8}`,
+			wantMerchant: strings.Repeat("咖", MaxDescriptionLength-1) + "…",
+			wantCategory: "Others",
+		},
 	}
 
 	for _, tt := range tests {
@@ -771,3 +818,206 @@ func TestParseReceiptResponse_SanitizesFields(t *testing.T) {
 		})
 	}
 }
+
+func TestParseReceiptImages(t *testing.T) {
+	t.Parallel()
+
+	newMock := func() *mockGenerator {
+		return &mockGenerator{
+			response: &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{
+					{
+						Content: &genai.Content{
+							Parts: []*genai.Part{
+								{Text: receiptJSON("88.20", "Swee Choon", "2024-01-15", 0.9)},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("sends all images in one request", func(t *testing.T) {
+		t.Parallel()
+
+		mock := newMock()
+		client := NewClientWithGenerator(mock)
+		result, err := client.ParseReceiptImages(context.Background(), []ReceiptImage{
+			{Data: []byte("top-half"), MIMEType: testGeminiImageJPEG},
+			{Data: []byte("bottom-half")},
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, 2, result.ImageCount)
+		require.Equal(t, ReceiptMultiImagePromptVersion, result.PromptVersion)
+		require.Len(t, mock.lastContents, 1)
+		parts := mock.lastContents[0].Parts
+		require.Len(t, parts, 3)
+		require.Equal(t, []byte("top-half"), parts[0].InlineData.Data)
+		require.Equal(t, []byte("bottom-half"), parts[1].InlineData.Data)
+		require.Equal(t, "image/jpeg", parts[1].InlineData.MIMEType)
+		require.Contains(t, parts[2].Text, "ONE long receipt")
+	})
+
+	t.Run("single image keeps the single-image prompt", func(t *testing.T) {
+		t.Parallel()
+
+		mock := newMock()
+		client := NewClientWithGenerator(mock)
+		result, err := client.ParseReceipt(context.Background(), []byte(testGeminiFakeImage), testGeminiImageJPEG)
+
+		require.NoError(t, err)
+		require.Equal(t, 1, result.ImageCount)
+		require.Equal(t, ReceiptPromptVersion, result.PromptVersion)
+		require.NotContains(t, mock.lastContents[0].Parts[1].Text, "ONE long receipt")
+	})
+
+	t.Run("rejects empty and oversized input", func(t *testing.T) {
+		t.Parallel()
+
+		client := NewClientWithGenerator(newMock())
+		_, err := client.ParseReceiptImages(context.Background(), nil)
+		require.Error(t, err)
+
+		tooMany := make([]ReceiptImage, MaxReceiptImages+1)
+		for i := range tooMany {
+			tooMany[i] = ReceiptImage{Data: []byte("x")}
+		}
+		_, err = client.ParseReceiptImages(context.Background(), tooMany)
+		require.Error(t, err)
+	})
+}
+
+func TestParseReceiptResponse_Breakdown(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		fields   string
+		subtotal string
+		tax      string
+		tip      string
+		total    string
+	}{
+		{
+			name:     "all parts",
+			fields:   `"subtotal": "48.00", "tax": "3.84", "tip": "6.60", "total": "58.44"`,
+			subtotal: "48", tax: "3.84", tip: "6.6", total: "58.44",
+		},
+		{
+			name:     "absent parts are zero",
+			fields:   `"subtotal": "48.00", "tax": "0"`,
+			subtotal: "48", tax: "0", tip: "0", total: "0",
+		},
+		{
+			name:     "invalid parts are dropped",
+			fields:   `"subtotal": " 48.00 ", "tax": "abc", "tip": "-5", "total": "1e99"`,
+			subtotal: "48", tax: "0", tip: "0", total: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data, err := parseReceiptResponse(`{"amount": "48.00", "merchant": "Bistro", ` + tt.fields + `}`)
+			require.NoError(t, err)
+			require.True(t, decimal.RequireFromString("48").Equal(data.Amount))
+			require.True(t, decimal.RequireFromString(tt.subtotal).Equal(data.Subtotal), "subtotal %s", data.Subtotal)
+			require.True(t, decimal.RequireFromString(tt.tax).Equal(data.Tax), "tax %s", data.Tax)
+			require.True(t, decimal.RequireFromString(tt.tip).Equal(data.Tip), "tip %s", data.Tip)
+			require.True(t, decimal.RequireFromString(tt.total).Equal(data.Total), "total %s", data.Total)
+		})
+	}
+}
+
+func TestParseReceiptResponse_Items(t *testing.T) {
+	t.Parallel()
+
+	data, err := parseReceiptResponse(`{"amount": "54.60", "merchant": "Bistro", "items": [
+		{"name": "Laksa", "amount": "18.00"},
+		{"name": "Chicken Rice", "amount": "30.00"},
+		{"name": "Free refill", "amount": "0"},
+		{"name": "Smudged", "amount": "abc"},
+		{"name": "Discount", "amount": "-2.00"}
+	]}`)
+	require.NoError(t, err)
+	require.Equal(t, []ReceiptItem{
+		{Name: "Laksa", Amount: decimal.RequireFromString("18.00")},
+		{Name: "Chicken Rice", Amount: decimal.RequireFromString("30.00")},
+	}, data.Items)
+	require.True(t, decimal.RequireFromString("48").Equal(data.ItemsSum()))
+
+	data, err = parseReceiptResponse(`{"amount": "54.60", "merchant": "Bistro"}`)
+	require.NoError(t, err)
+	require.Empty(t, data.Items)
+	require.True(t, data.ItemsSum().IsZero())
+}
+
+func TestReceiptData_ItemsDisagree(t *testing.T) {
+	t.Parallel()
+
+	d := decimal.RequireFromString
+	items := []ReceiptItem{{Name: "Laksa", Amount: d("18.00")}, {Name: "Noodles", Amount: d("27.60")}}
+
+	tests := []struct {
+		name     string
+		data     ReceiptData
+		amount   string
+		wantSum  string
+		disagree bool
+	}{
+		{
+			name:     "cash tendered read as total",
+			data:     ReceiptData{Confidence: 0.5, Items: items},
+			amount:   "54.60",
+			wantSum:  "45.60",
+			disagree: true,
+		},
+		{
+			name:    "items match the amount",
+			data:    ReceiptData{Confidence: 0.5, Items: items},
+			amount:  "45.60",
+			wantSum: "45.60",
+		},
+		{
+			name:    "within tolerance",
+			data:    ReceiptData{Confidence: 0.5, Items: items},
+			amount:  "45.65",
+			wantSum: "45.60",
+		},
+		{
+			name:    "items plus tax and tip match the amount",
+			data:    ReceiptData{Confidence: 0.5, Items: items, Tax: d("4.00"), Tip: d("5.00")},
+			amount:  "54.60",
+			wantSum: "45.60",
+		},
+		{
+			name:    "confident receipts are trusted",
+			data:    ReceiptData{Confidence: LowConfidenceThreshold, Items: items},
+			amount:  "54.60",
+			wantSum: "0",
+		},
+		{
+			name:    "no items",
+			data:    ReceiptData{Confidence: 0.5},
+			amount:  "54.60",
+			wantSum: "0",
+		},
+		{
+			name:    "no amount",
+			data:    ReceiptData{Confidence: 0.5, Items: items},
+			amount:  "0",
+			wantSum: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			sum, disagree := tt.data.ItemsDisagree(d(tt.amount))
+			require.Equal(t, tt.disagree, disagree)
+			require.True(t, d(tt.wantSum).Equal(sum), "sum %s", sum)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package gemini
+
+import (
+	"context"
+
+	"google.golang.org/genai"
+)
+
+// Features a Gemini call is made for, as recorded with its token usage.
+const (
+	FeatureReceipt = "receipt"
+	FeatureVoice   = "voice"
+	FeatureSuggest = "suggest"
+)
+
+// Usage is the token count of one Gemini call.
+type Usage struct {
+	// UserID is the user the call was made for, or zero when unknown.
+	UserID       int64
+	Feature      string
+	PromptTokens int
+	OutputTokens int
+	TotalTokens  int
+}
+
+// UsageRecorder stores the token usage of Gemini calls.
+type UsageRecorder interface {
+	RecordUsage(ctx context.Context, usage Usage)
+}
+
+type usageUserKey struct{}
+
+// WithUsageUser tags ctx with the user a Gemini call is made for, so its
+// token usage is attributed to them.
+func WithUsageUser(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, usageUserKey{}, userID)
+}
+
+// usageUser returns the user set by WithUsageUser, or zero.
+func usageUser(ctx context.Context) int64 {
+	userID, _ := ctx.Value(usageUserKey{}).(int64)
+	return userID
+}
+
+// SetUsageRecorder makes the client report the token usage of every
+// receipt, voice and category call to r. Call it before the client is used.
+func (c *Client) SetUsageRecorder(r UsageRecorder) {
+	c.usage = r
+}
+
+// usageFromResponse reads the token counts of a response. Responses
+// without usage metadata count as zero tokens.
+func usageFromResponse(resp *genai.GenerateContentResponse) Usage {
+	if resp == nil || resp.UsageMetadata == nil {
+		return Usage{}
+	}
+	meta := resp.UsageMetadata
+	usage := Usage{
+		PromptTokens: int(meta.PromptTokenCount),
+		OutputTokens: int(meta.CandidatesTokenCount + meta.ThoughtsTokenCount),
+		TotalTokens:  int(meta.TotalTokenCount),
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.OutputTokens
+	}
+	return usage
+}
+
+// recordUsage reports the tokens of a successful call to the recorder.
+// Tokens are billed whether or not the reply can be parsed, so it runs
+// right after the call.
+func (c *Client) recordUsage(ctx context.Context, feature string, resp *genai.GenerateContentResponse) {
+	if c.usage == nil {
+		return
+	}
+	usage := usageFromResponse(resp)
+	usage.UserID = usageUser(ctx)
+	usage.Feature = feature
+	c.usage.RecordUsage(ctx, usage)
+}
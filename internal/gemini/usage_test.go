@@ -0,0 +1,100 @@
+package gemini
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+type recordedUsage struct {
+	usages []Usage
+}
+
+func (r *recordedUsage) RecordUsage(_ context.Context, usage Usage) {
+	r.usages = append(r.usages, usage)
+}
+
+func TestUsageFromResponse(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, Usage{}, usageFromResponse(nil))
+	require.Equal(t, Usage{}, usageFromResponse(&genai.GenerateContentResponse{}))
+
+	usage := usageFromResponse(&genai.GenerateContentResponse{
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:     120,
+			CandidatesTokenCount: 30,
+			ThoughtsTokenCount:   10,
+			TotalTokenCount:      160,
+		},
+	})
+	require.Equal(t, Usage{PromptTokens: 120, OutputTokens: 40, TotalTokens: 160}, usage)
+
+	usage = usageFromResponse(&genai.GenerateContentResponse{
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 7, CandidatesTokenCount: 3},
+	})
+	require.Equal(t, 10, usage.TotalTokens, "a missing total is the sum of the parts")
+}
+
+func TestClient_RecordsUsage(t *testing.T) {
+	t.Parallel()
+
+	metadata := &genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:     200,
+		CandidatesTokenCount: 25,
+		TotalTokenCount:      225,
+	}
+
+	t.Run("category suggestion", func(t *testing.T) {
+		t.Parallel()
+		response := createMockCategoryResponse(testGeminiCategoryTransport, 0.9, "Taxi")
+		response.UsageMetadata = metadata
+		recorder := &recordedUsage{}
+		client := NewClientWithGenerator(&mockGenerator{response: response})
+		client.SetUsageRecorder(recorder)
+
+		ctx := WithUsageUser(context.Background(), 42)
+		_, err := client.SuggestCategory(ctx, "taxi", []string{testGeminiCategoryTransport})
+		require.NoError(t, err)
+		require.Equal(t, []Usage{{
+			UserID: 42, Feature: FeatureSuggest, PromptTokens: 200, OutputTokens: 25, TotalTokens: 225,
+		}}, recorder.usages)
+	})
+
+	t.Run("unparsable replies still count", func(t *testing.T) {
+		t.Parallel()
+		recorder := &recordedUsage{}
+		client := NewClientWithGenerator(&mockGenerator{
+			response: &genai.GenerateContentResponse{UsageMetadata: metadata},
+		})
+		client.SetUsageRecorder(recorder)
+
+		_, err := client.ParseReceipt(context.Background(), []byte("image"), "image/jpeg")
+		require.Error(t, err)
+		_, err = client.ParseVoiceExpense(context.Background(), []byte("audio"), "audio/ogg", nil)
+		require.Error(t, err)
+
+		require.Len(t, recorder.usages, 2)
+		require.Equal(t, FeatureReceipt, recorder.usages[0].Feature)
+		require.Equal(t, FeatureVoice, recorder.usages[1].Feature)
+		require.Zero(t, recorder.usages[0].UserID, "no user was set on the context")
+		require.Equal(t, 225, recorder.usages[1].TotalTokens)
+	})
+
+	t.Run("failed calls and clients without a recorder record nothing", func(t *testing.T) {
+		t.Parallel()
+		recorder := &recordedUsage{}
+		client := NewClientWithGenerator(&mockGenerator{err: context.DeadlineExceeded})
+		client.SetUsageRecorder(recorder)
+		_, err := client.SuggestCategory(context.Background(), "taxi", []string{testGeminiCategoryTransport})
+		require.Error(t, err)
+		require.Empty(t, recorder.usages)
+
+		response := createMockCategoryResponse(testGeminiCategoryTransport, 0.9, "Taxi")
+		_, err = NewClientWithGenerator(&mockGenerator{response: response}).
+			SuggestCategory(context.Background(), "taxi", []string{testGeminiCategoryTransport})
+		require.NoError(t, err)
+	})
+}
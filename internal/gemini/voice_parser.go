@@ -94,6 +94,7 @@ func (c *Client) ParseVoiceExpense(
 		}
 		return nil, fmt.Errorf("failed to generate content: %w", err)
 	}
+	c.recordUsage(ctx, FeatureVoice, resp)
 
 	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
 		return nil, errors.New("no response from Gemini")
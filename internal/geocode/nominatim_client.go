@@ -0,0 +1,87 @@
+// Package geocode turns coordinates into place names.
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout = 5 * time.Second
+	userAgent      = "expense-bot (+https://gitlab.com/yelinaung/expense-bot)"
+)
+
+// ReverseGeocoder looks up a place name for coordinates.
+type ReverseGeocoder interface {
+	Reverse(ctx context.Context, latitude, longitude float64) (string, error)
+}
+
+// NominatimClient is a reverse geocoder for the Nominatim API or any
+// service that answers its /reverse endpoint.
+type NominatimClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+type nominatimResponse struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// NewNominatimClient creates a Nominatim client for baseURL. An optional
+// http.RoundTripper can be provided for OTel instrumentation; nil uses
+// http.DefaultTransport.
+func NewNominatimClient(baseURL string, timeout time.Duration, transport http.RoundTripper) *NominatimClient {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &NominatimClient{
+		baseURL: strings.TrimRight(strings.TrimSpace(baseURL), "/"),
+		httpClient: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+		},
+	}
+}
+
+// Reverse returns the place name for the coordinates, preferring the short
+// name of the place over its full address. It returns an empty string when
+// the service knows no place there.
+func (c *NominatimClient) Reverse(ctx context.Context, latitude, longitude float64) (string, error) {
+	query := url.Values{}
+	query.Set("format", "jsonv2")
+	query.Set("lat", strconv.FormatFloat(latitude, 'f', -1, 64))
+	query.Set("lon", strconv.FormatFloat(longitude, 'f', -1, 64))
+	endpoint := c.baseURL + "/reverse?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create geocode request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.httpClient.Do(req) // #nosec G704 -- URL is built from a config base URL with encoded params.
+	if err != nil {
+		return "", fmt.Errorf("failed to request geocode: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("geocode API returned status %d", resp.StatusCode)
+	}
+
+	var payload nominatimResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("failed to decode geocode response: %w", err)
+	}
+	if name := strings.TrimSpace(payload.Name); name != "" {
+		return name, nil
+	}
+	return strings.TrimSpace(payload.DisplayName), nil
+}
@@ -0,0 +1,60 @@
+package geocode
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNominatimClient_Reverse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns the place name", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/reverse", r.URL.Path)
+			assert.Equal(t, "jsonv2", r.URL.Query().Get("format"))
+			assert.Equal(t, "1.2834", r.URL.Query().Get("lat"))
+			assert.Equal(t, "103.8607", r.URL.Query().Get("lon"))
+			assert.NotEmpty(t, r.Header.Get("User-Agent"))
+			_, _ = w.Write([]byte(`{"name":"Lau Pa Sat","display_name":"Lau Pa Sat, 18 Raffles Quay, Singapore"}`))
+		}))
+		defer server.Close()
+
+		client := NewNominatimClient(server.URL+"/", time.Second, nil)
+		label, err := client.Reverse(context.Background(), 1.2834, 103.8607)
+		require.NoError(t, err)
+		require.Equal(t, "Lau Pa Sat", label)
+	})
+
+	t.Run("falls back to the address", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`{"name":"","display_name":"18 Raffles Quay, Singapore"}`))
+		}))
+		defer server.Close()
+
+		label, err := NewNominatimClient(server.URL, time.Second, nil).Reverse(context.Background(), 1, 2)
+		require.NoError(t, err)
+		require.Equal(t, "18 Raffles Quay, Singapore", label)
+	})
+
+	t.Run("returns error on non 200 response", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		_, err := NewNominatimClient(server.URL, time.Second, nil).Reverse(context.Background(), 1, 2)
+		require.ErrorContains(t, err, "status 429")
+	})
+}
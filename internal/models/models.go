@@ -2,7 +2,10 @@
 package models
 
 import (
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/shopspring/decimal"
 )
@@ -13,9 +16,68 @@ const DefaultCurrency = "SGD"
 // DefaultTimezone is the default timezone for new users.
 const DefaultTimezone = "Asia/Singapore"
 
+// DefaultUncategorizedThreshold is how many uncategorized expenses in the
+// last 30 days are tolerated before the weekly warning is sent.
+const DefaultUncategorizedThreshold = 10
+
 // MaxCategoryNameLength is the maximum allowed length for category names.
 const MaxCategoryNameLength = 50
 
+// DefaultCategories are the shared categories seeded on startup unless
+// SEED_CATEGORIES names others.
+var DefaultCategories = []string{
+	"Food - Dining Out",
+	"Food - Grocery",
+	"Transportation",
+	"Communication",
+	"Housing - Mortgage",
+	"Housing - Others",
+	"Personal Care",
+	"Health and Wellness",
+	"Education",
+	"Entertainment",
+	"Credit/Debt Payments",
+	"Others",
+	"Utilities",
+	"Travel & Vacation",
+	"Subscriptions",
+	"Donations",
+}
+
+// MinimalCategories is the default "Minimal set" offered to new users, a
+// subset of DefaultCategories.
+var MinimalCategories = []string{
+	"Food - Dining Out",
+	"Food - Grocery",
+	"Transportation",
+	"Utilities",
+	"Others",
+}
+
+// DefaultMaxDescriptionLength is the default maximum length, in characters,
+// of expense descriptions and merchants.
+const DefaultMaxDescriptionLength = 200
+
+// NormalizeText cleans free text such as a description or merchant: control
+// characters are dropped, runs of whitespace (including newlines) become one
+// space, and text longer than maxRunes characters is cut to fit with a
+// trailing ellipsis. It never splits a multi-byte character. maxRunes of 0
+// or less means no limit. The second result reports whether text was cut.
+func NormalizeText(s string, maxRunes int) (string, bool) {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.Join(strings.Fields(s), " ")
+	if maxRunes <= 0 || utf8.RuneCountInString(s) <= maxRunes {
+		return s, false
+	}
+	runes := []rune(s)
+	return strings.TrimRightFunc(string(runes[:maxRunes-1]), unicode.IsSpace) + "…", true
+}
+
 // MaxAmountExponent bounds the base-10 exponent of untrusted decimal amounts.
 // Comparing, rescaling, or formatting a decimal like 1e444444410 materializes
 // 10^exp as a big.Int, which effectively hangs the process (found by fuzzing).
@@ -50,6 +112,28 @@ var SupportedCurrencies = map[string]string{
 	"TWD": "NT$",
 }
 
+// currencyMinorUnits lists the ISO 4217 currencies whose amounts are not
+// written with two decimals.
+var currencyMinorUnits = map[string]int32{
+	"JPY": 0,
+	"KRW": 0,
+	"VND": 0,
+	"BHD": 3,
+	"JOD": 3,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+}
+
+// CurrencyMinorUnits returns how many decimals amounts in a currency are
+// shown with, e.g. 0 for JPY and 2 for SGD or unknown codes.
+func CurrencyMinorUnits(code string) int32 {
+	if units, ok := currencyMinorUnits[code]; ok {
+		return units
+	}
+	return 2
+}
+
 // User represents a Telegram user.
 type User struct {
 	ID              int64
@@ -58,17 +142,56 @@ type User struct {
 	LastName        string
 	DefaultCurrency string
 	Timezone        string
-	CreatedAt       time.Time
-	UpdatedAt       time.Time
+	// LastReminderDate is the user's local date (YYYY-MM-DD) of the last
+	// daily reminder check, or empty. Only loaded for reminder runs.
+	LastReminderDate string
+	// ArchiveChatID is the chat that receives the user's monthly CSV, or
+	// zero. ArchiveSentMonth is the first day (YYYY-MM-DD) of the month
+	// last delivered there. Both are only loaded for archive runs.
+	ArchiveChatID    int64
+	ArchiveSentMonth string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
 }
 
 // Category represents an expense category.
 type Category struct {
-	ID        int
-	Name      string
+	ID   int
+	Name string
+	// SortOrder positions the category in keyboards and lists; ties are
+	// ordered by name.
+	SortOrder int
+	// Color is the chart color set with /setcategorycolor as #RRGGBB, or
+	// empty to derive one from the name.
+	Color string
+	// OwnerID is the user a private category belongs to, or zero for a
+	// shared category everyone sees unless they hide it.
+	OwnerID   int64
 	CreatedAt time.Time
 }
 
+// Visible reports whether the category shows up for a user who hid the
+// shared categories in hidden.
+func (c *Category) Visible(userID int64, hidden map[int]bool) bool {
+	if c.OwnerID != 0 {
+		return c.OwnerID == userID
+	}
+	return !hidden[c.ID]
+}
+
+// ChatStatus is whether the bot is in a group chat.
+type ChatStatus string
+
+const (
+	// ChatStatusActive is a chat the bot is in and serves.
+	ChatStatusActive ChatStatus = "active"
+	// ChatStatusPending is a chat an unknown user added the bot to. The bot
+	// leaves it unless an authorized user claims it in time.
+	ChatStatusPending ChatStatus = "pending"
+	// ChatStatusLeft is a chat the bot was removed from or left.
+	ChatStatusLeft ChatStatus = "left"
+)
+
 // ExpenseStatus represents the status of an expense.
 type ExpenseStatus string
 
@@ -78,6 +201,39 @@ const (
 	ExpenseStatusConfirmed ExpenseStatus = "confirmed"
 )
 
+// ExpenseSource is how an expense entered the bot.
+type ExpenseSource string
+
+const (
+	// ExpenseSourceUnknown marks expenses saved before sources were
+	// recorded.
+	ExpenseSourceUnknown ExpenseSource = "unknown"
+	// ExpenseSourceText is a free-text chat message like "5.50 Coffee".
+	ExpenseSourceText ExpenseSource = "text"
+	// ExpenseSourceCommand is /add, /refund or a saved /preview.
+	ExpenseSourceCommand ExpenseSource = "command"
+	// ExpenseSourceReceipt is a receipt photo, scanned or with a typed total.
+	ExpenseSourceReceipt ExpenseSource = "receipt"
+	// ExpenseSourceVoice is a voice message.
+	ExpenseSourceVoice ExpenseSource = "voice"
+	// ExpenseSourceBank is a forwarded bank notification.
+	ExpenseSourceBank ExpenseSource = "bank"
+	// ExpenseSourceAPI is the REST API.
+	ExpenseSourceAPI ExpenseSource = "api"
+	// ExpenseSourceImport is an /import file.
+	ExpenseSourceImport ExpenseSource = "import"
+	// ExpenseSourceRecurring is a scheduled recurring expense.
+	ExpenseSourceRecurring ExpenseSource = "recurring"
+	// ExpenseSourceDemo marks expenses made up by the demo data generator.
+	ExpenseSourceDemo ExpenseSource = "demo"
+)
+
+// ExpenseSources lists every source in display order.
+var ExpenseSources = []ExpenseSource{
+	ExpenseSourceText, ExpenseSourceCommand, ExpenseSourceReceipt, ExpenseSourceVoice, ExpenseSourceBank,
+	ExpenseSourceAPI, ExpenseSourceImport, ExpenseSourceRecurring, ExpenseSourceDemo, ExpenseSourceUnknown,
+}
+
 // MaxTagNameLength is the maximum allowed length for tag names.
 const MaxTagNameLength = 30
 
@@ -116,4 +272,62 @@ type Expense struct {
 	ReviewedAt        *time.Time
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
+
+	// AICategorized is set when Gemini picked the category, with the
+	// confidence it reported. Changing the category clears it.
+	AICategorized bool
+	AIConfidence  *float64
+
+	// RateToDefault and ConvertedAmount record the conversion of the
+	// entered amount into ConvertedCurrency, the owner's default currency,
+	// at the time the expense was created (or backfilled from that day's
+	// rate). They are invalid when no conversion was recorded.
+	RateToDefault     decimal.NullDecimal
+	ConvertedAmount   decimal.NullDecimal
+	ConvertedCurrency string
+
+	// Reimbursable marks a work expense someone else pays back;
+	// ReimbursedAt is set once they did.
+	Reimbursable bool
+	ReimbursedAt *time.Time
+
+	// IdempotencyKey, when set, makes creating the expense safe to retry:
+	// a create with a key the user already used returns the expense saved
+	// first, with Replayed set, instead of adding another one.
+	IdempotencyKey string
+	Replayed       bool
+
+	// Source is how the expense entered the bot. It is set once, when the
+	// expense is created.
+	Source ExpenseSource
+}
+
+// AwaitingReimbursement reports whether the expense is reimbursable and not
+// paid back yet. Such expenses are left out of personal spend views.
+func (e *Expense) AwaitingReimbursement() bool {
+	return e.Reimbursable && e.ReimbursedAt == nil
+}
+
+// IsRefund reports whether the expense is a refund. Refunds are stored with
+// a negative amount, so they reduce every total they are part of.
+func (e *Expense) IsRefund() bool {
+	return e.Amount.IsNegative()
+}
+
+// ExpenseLocation is where an expense happened. Label is a place name from
+// the geocoder, empty when none is configured or it found nothing.
+type ExpenseLocation struct {
+	Latitude  float64
+	Longitude float64
+	Label     string
+}
+
+// AmountInDefault returns the amount and currency to use when totaling the
+// expense: the stored conversion when there is one, otherwise Amount in
+// Currency.
+func (e *Expense) AmountInDefault() (decimal.Decimal, string) {
+	if e.ConvertedAmount.Valid && e.ConvertedCurrency != "" {
+		return e.ConvertedAmount.Decimal, e.ConvertedCurrency
+	}
+	return e.Amount, e.Currency
 }
@@ -3,6 +3,7 @@ package models
 import (
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
@@ -45,6 +46,18 @@ func TestCategory(t *testing.T) {
 		require.Equal(t, 1, cat.ID)
 		require.Equal(t, "Food - Dining Out", cat.Name)
 	})
+
+	t.Run("visibility", func(t *testing.T) {
+		t.Parallel()
+		shared := Category{ID: 1}
+		private := Category{ID: 2, OwnerID: 42}
+		hidden := map[int]bool{1: true}
+
+		require.True(t, shared.Visible(7, nil))
+		require.False(t, shared.Visible(7, hidden))
+		require.True(t, private.Visible(42, hidden))
+		require.False(t, private.Visible(7, nil))
+	})
 }
 
 func TestExpense(t *testing.T) {
@@ -100,3 +113,37 @@ func TestExpense(t *testing.T) {
 		require.Equal(t, "Food", expense.Category.Name)
 	})
 }
+
+func TestNormalizeText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		input     string
+		maxRunes  int
+		want      string
+		truncated bool
+	}{
+		{name: "short input is untouched", input: "Coffee at Ya Kun", maxRunes: 20, want: "Coffee at Ya Kun"},
+		{name: "exactly at the limit", input: "abcde", maxRunes: 5, want: "abcde"},
+		{name: "one over the limit", input: "abcdef", maxRunes: 5, want: "abcd…", truncated: true},
+		{name: "no limit", input: "abcdef", maxRunes: 0, want: "abcdef"},
+		{name: "collapses whitespace", input: "  Lunch\n\twith   team  ", maxRunes: 50, want: "Lunch with team"},
+		{name: "strips control characters", input: "Caf\x00e\x1b[31m\u007f", maxRunes: 50, want: "Cafe[31m"},
+		{name: "does not split runes", input: "カフェラテとケーキ", maxRunes: 4, want: "カフェ…", truncated: true},
+		{name: "emoji at the boundary", input: "☕☕☕☕", maxRunes: 3, want: "☕☕…", truncated: true},
+		{name: "no space before the ellipsis", input: "ab cd", maxRunes: 4, want: "ab…", truncated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, truncated := NormalizeText(tt.input, tt.maxRunes)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.truncated, truncated)
+			if tt.maxRunes > 0 {
+				require.LessOrEqual(t, utf8.RuneCountInString(got), tt.maxRunes)
+			}
+			require.True(t, utf8.ValidString(got))
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+)
+
+// BankTemplate is a stored regular expression that reads forwarded bank
+// notification messages.
+type BankTemplate struct {
+	ID        int
+	Name      string
+	Pattern   string
+	CreatedAt time.Time
+}
+
+// BankTemplateRepository handles the bank message templates managed with
+// /banktemplates.
+type BankTemplateRepository struct {
+	db database.PGXDB
+}
+
+// NewBankTemplateRepository creates a new BankTemplateRepository.
+func NewBankTemplateRepository(db database.PGXDB) *BankTemplateRepository {
+	return &BankTemplateRepository{db: db}
+}
+
+// List returns the templates that were not deleted, oldest first.
+func (r *BankTemplateRepository) List(ctx context.Context) ([]BankTemplate, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, name, pattern, created_at FROM bank_templates
+		WHERE deleted_at IS NULL
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bank templates: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []BankTemplate
+	for rows.Next() {
+		var tmpl BankTemplate
+		if err := rows.Scan(&tmpl.ID, &tmpl.Name, &tmpl.Pattern, &tmpl.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan bank template: %w", err)
+		}
+		templates = append(templates, tmpl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate bank templates: %w", err)
+	}
+	return templates, nil
+}
+
+// Save adds a template, or replaces the pattern of the template with the
+// same name. A deleted template of that name is restored.
+func (r *BankTemplateRepository) Save(ctx context.Context, name, pattern string, createdBy int64) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO bank_templates (name, pattern, created_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET
+			pattern = EXCLUDED.pattern,
+			created_by = EXCLUDED.created_by,
+			created_at = NOW(),
+			deleted_at = NULL
+	`, name, pattern, createdBy)
+	if err != nil {
+		return fmt.Errorf("failed to save bank template: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a template by name. It reports whether one was removed.
+// The row is kept so the default templates are not seeded again.
+func (r *BankTemplateRepository) Delete(ctx context.Context, name string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE bank_templates SET deleted_at = NOW()
+		WHERE name = $1 AND deleted_at IS NULL
+	`, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete bank template: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
+)
+
+func TestBankTemplateRepository(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+	repo := NewBankTemplateRepository(tx)
+
+	names := func() []string {
+		templates, err := repo.List(ctx)
+		require.NoError(t, err)
+		var names []string
+		for _, tmpl := range templates {
+			names = append(names, tmpl.Name)
+		}
+		return names
+	}
+
+	require.Contains(t, names(), "you-spent", "default templates are seeded")
+
+	require.NoError(t, repo.Save(ctx, "test-bank", `Paid (?P<amount>\d+)`, 401001))
+	require.Contains(t, names(), "test-bank")
+
+	deleted, err := repo.Delete(ctx, "test-bank")
+	require.NoError(t, err)
+	require.True(t, deleted)
+	require.NotContains(t, names(), "test-bank")
+
+	deleted, err = repo.Delete(ctx, "test-bank")
+	require.NoError(t, err)
+	require.False(t, deleted)
+
+	require.NoError(t, repo.Save(ctx, "test-bank", `Spent (?P<amount>\d+)`, 401001))
+	templates, err := repo.List(ctx)
+	require.NoError(t, err)
+	idx := slices.IndexFunc(templates, func(tmpl BankTemplate) bool { return tmpl.Name == "test-bank" })
+	require.GreaterOrEqual(t, idx, 0, "saving a deleted template restores it")
+	require.Equal(t, `Spent (?P<amount>\d+)`, templates[idx].Pattern)
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+)
+
+// Budget is a monthly spending limit for one of a user's categories,
+// denominated in Currency. An enforced budget blocks expenses that would
+// go over the limit unless it was overridden for the current period.
+// OverridePeriod holds the period key (e.g. "2026-10") in which /budget
+// override was last used.
+type Budget struct {
+	UserID         int64
+	CategoryID     int
+	CategoryName   string
+	Amount         decimal.Decimal
+	Currency       string
+	Enforce        bool
+	OverridePeriod string
+}
+
+// BudgetRepository handles budget database operations.
+type BudgetRepository struct {
+	db database.PGXDB
+}
+
+// NewBudgetRepository creates a new BudgetRepository.
+func NewBudgetRepository(db database.PGXDB) *BudgetRepository {
+	return &BudgetRepository{db: db}
+}
+
+// Set creates or replaces the budget of a category with a limit of amount
+// in currency. An existing override is kept.
+func (r *BudgetRepository) Set(
+	ctx context.Context,
+	userID int64,
+	categoryID int,
+	amount decimal.Decimal,
+	currency string,
+	enforce bool,
+) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO budgets (user_id, category_id, amount, currency, enforce)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, category_id)
+		DO UPDATE SET amount = EXCLUDED.amount, currency = EXCLUDED.currency, enforce = EXCLUDED.enforce,
+			updated_at = NOW()
+	`, userID, categoryID, amount, currency, enforce)
+	if err != nil {
+		return fmt.Errorf("failed to set budget: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the budget of a category. It reports whether a budget
+// existed.
+func (r *BudgetRepository) Delete(ctx context.Context, userID int64, categoryID int) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM budgets WHERE user_id = $1 AND category_id = $2`, userID, categoryID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete budget: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetByCategory returns the budget of a category. The error wraps
+// pgx.ErrNoRows when the category has no budget.
+func (r *BudgetRepository) GetByCategory(ctx context.Context, userID int64, categoryID int) (*Budget, error) {
+	budget := &Budget{UserID: userID, CategoryID: categoryID}
+	err := r.db.QueryRow(ctx, `
+		SELECT c.name, b.amount, b.currency, b.enforce, b.override_period
+		FROM budgets b
+		JOIN categories c ON c.id = b.category_id
+		WHERE b.user_id = $1 AND b.category_id = $2
+	`, userID, categoryID).Scan(
+		&budget.CategoryName, &budget.Amount, &budget.Currency, &budget.Enforce, &budget.OverridePeriod,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budget: %w", err)
+	}
+	return budget, nil
+}
+
+// ListByUserID returns all budgets of a user ordered by category name.
+func (r *BudgetRepository) ListByUserID(ctx context.Context, userID int64) ([]Budget, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT b.category_id, c.name, b.amount, b.currency, b.enforce, b.override_period
+		FROM budgets b
+		JOIN categories c ON c.id = b.category_id
+		WHERE b.user_id = $1
+		ORDER BY c.name
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budgets: %w", err)
+	}
+	defer rows.Close()
+
+	var budgets []Budget
+	for rows.Next() {
+		budget := Budget{UserID: userID}
+		if err := rows.Scan(
+			&budget.CategoryID, &budget.CategoryName, &budget.Amount, &budget.Currency, &budget.Enforce,
+			&budget.OverridePeriod,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan budget: %w", err)
+		}
+		budgets = append(budgets, budget)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate budgets: %w", err)
+	}
+	return budgets, nil
+}
+
+// SetOverride lifts the enforcement of a budget for period. It reports
+// false when the budget does not exist or was already overridden in
+// period, so an override can be used once per period.
+func (r *BudgetRepository) SetOverride(ctx context.Context, userID int64, categoryID int, period string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE budgets SET override_period = $3, updated_at = NOW()
+		WHERE user_id = $1 AND category_id = $2 AND override_period <> $3
+	`, userID, categoryID, period)
+	if err != nil {
+		return false, fmt.Errorf("failed to override budget: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
+)
+
+func TestBudgetRepository(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	userRepo := NewUserRepository(tx)
+	categoryRepo := NewCategoryRepository(tx)
+	repo := NewBudgetRepository(tx)
+
+	userID := int64(384101)
+	require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: userID, FirstName: "Budget"}))
+	category, err := categoryRepo.Create(ctx, "Budget Treats")
+	require.NoError(t, err)
+
+	_, err = repo.GetByCategory(ctx, userID, category.ID)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	require.NoError(t, repo.Set(ctx, userID, category.ID, decimal.NewFromInt(20), "SGD", true))
+
+	t.Run("override once per period", func(t *testing.T) {
+		ok, err := repo.SetOverride(ctx, userID, category.ID, "2026-10")
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		ok, err = repo.SetOverride(ctx, userID, category.ID, "2026-10")
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		require.NoError(t, repo.Set(ctx, userID, category.ID, decimal.NewFromInt(30), "USD", true))
+		budget, err := repo.GetByCategory(ctx, userID, category.ID)
+		require.NoError(t, err)
+		require.True(t, decimal.NewFromInt(30).Equal(budget.Amount))
+		require.Equal(t, "2026-10", budget.OverridePeriod)
+		require.Equal(t, "USD", budget.Currency)
+		require.Equal(t, "Budget Treats", budget.CategoryName)
+	})
+
+	t.Run("list and delete", func(t *testing.T) {
+		budgets, err := repo.ListByUserID(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, budgets, 1)
+		require.True(t, budgets[0].Enforce)
+		require.Equal(t, "USD", budgets[0].Currency)
+
+		removed, err := repo.Delete(ctx, userID, category.ID)
+		require.NoError(t, err)
+		require.True(t, removed)
+
+		removed, err = repo.Delete(ctx, userID, category.ID)
+		require.NoError(t, err)
+		require.False(t, removed)
+	})
+}
@@ -4,6 +4,8 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"gitlab.com/yelinaung/expense-bot/internal/database"
 	"gitlab.com/yelinaung/expense-bot/internal/models"
@@ -19,10 +21,10 @@ func NewCategoryRepository(db database.PGXDB) *CategoryRepository {
 	return &CategoryRepository{db: db}
 }
 
-// GetAll retrieves all categories.
+// GetAll retrieves all categories ordered by sort order, then name.
 func (r *CategoryRepository) GetAll(ctx context.Context) ([]models.Category, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT id, name, created_at FROM categories ORDER BY name
+		SELECT id, name, sort_order, color, owner_id, created_at FROM categories ORDER BY sort_order, name
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query categories: %w", err)
@@ -32,7 +34,7 @@ func (r *CategoryRepository) GetAll(ctx context.Context) ([]models.Category, err
 	var categories []models.Category
 	for rows.Next() {
 		var cat models.Category
-		if err := rows.Scan(&cat.ID, &cat.Name, &cat.CreatedAt); err != nil {
+		if err := rows.Scan(&cat.ID, &cat.Name, &cat.SortOrder, &cat.Color, &cat.OwnerID, &cat.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan category: %w", err)
 		}
 		categories = append(categories, cat)
@@ -48,46 +50,106 @@ func (r *CategoryRepository) GetAll(ctx context.Context) ([]models.Category, err
 func (r *CategoryRepository) GetByID(ctx context.Context, id int) (*models.Category, error) {
 	var cat models.Category
 	err := r.db.QueryRow(ctx, `
-		SELECT id, name, created_at FROM categories WHERE id = $1
-	`, id).Scan(&cat.ID, &cat.Name, &cat.CreatedAt)
+		SELECT id, name, sort_order, color, owner_id, created_at FROM categories WHERE id = $1
+	`, id).Scan(&cat.ID, &cat.Name, &cat.SortOrder, &cat.Color, &cat.OwnerID, &cat.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get category: %w", err)
 	}
 	return &cat, nil
 }
 
-// GetByName retrieves a category by name (case-insensitive).
+// GetByName retrieves a category by name (case-insensitive), preferring the
+// shared category when users have private ones of the same name.
 func (r *CategoryRepository) GetByName(ctx context.Context, name string) (*models.Category, error) {
 	var cat models.Category
 	err := r.db.QueryRow(ctx, `
-		SELECT id, name, created_at FROM categories WHERE LOWER(name) = LOWER($1)
-	`, name).Scan(&cat.ID, &cat.Name, &cat.CreatedAt)
+		SELECT id, name, sort_order, color, owner_id, created_at FROM categories WHERE LOWER(name) = LOWER($1)
+		ORDER BY owner_id LIMIT 1
+	`, name).Scan(&cat.ID, &cat.Name, &cat.SortOrder, &cat.Color, &cat.OwnerID, &cat.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get category by name: %w", err)
 	}
 	return &cat, nil
 }
 
-// Create adds a new category.
+// Create adds a new shared category. It is placed after the categories that
+// were reordered, or among them by name when none were.
 func (r *CategoryRepository) Create(ctx context.Context, name string) (*models.Category, error) {
+	return r.CreateOwned(ctx, 0, name)
+}
+
+// CreateOwned adds a new category private to ownerID, or a shared one when
+// ownerID is zero. It is placed like Create places categories.
+func (r *CategoryRepository) CreateOwned(ctx context.Context, ownerID int64, name string) (*models.Category, error) {
 	var cat models.Category
 	err := r.db.QueryRow(ctx, `
-		INSERT INTO categories (name) VALUES ($1)
-		RETURNING id, name, created_at
-	`, name).Scan(&cat.ID, &cat.Name, &cat.CreatedAt)
+		INSERT INTO categories (name, sort_order, owner_id)
+		VALUES ($1, (SELECT CASE WHEN MAX(sort_order) > 0 THEN MAX(sort_order) + 1 ELSE 0 END FROM categories), $2)
+		RETURNING id, name, sort_order, color, owner_id, created_at
+	`, name, ownerID).Scan(&cat.ID, &cat.Name, &cat.SortOrder, &cat.Color, &cat.OwnerID, &cat.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create category: %w", err)
 	}
 	return &cat, nil
 }
 
-// Update modifies an existing category name.
-func (r *CategoryRepository) Update(ctx context.Context, id int, name string) error {
-	_, err := r.db.Exec(ctx, `
+// CreateSuggested adds a category the user created from a receipt
+// suggestion. It is private to them and recorded for CountCreatedBySince.
+func (r *CategoryRepository) CreateSuggested(ctx context.Context, name string, userID int64) (*models.Category, error) {
+	var cat models.Category
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO categories (name, sort_order, created_by, owner_id)
+		VALUES ($1, (SELECT CASE WHEN MAX(sort_order) > 0 THEN MAX(sort_order) + 1 ELSE 0 END FROM categories), $2, $2)
+		RETURNING id, name, sort_order, color, owner_id, created_at
+	`, name, userID).Scan(&cat.ID, &cat.Name, &cat.SortOrder, &cat.Color, &cat.OwnerID, &cat.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create suggested category: %w", err)
+	}
+	return &cat, nil
+}
+
+// LockCreator locks the user's row until the surrounding transaction ends,
+// so concurrent callers counting with CountCreatedBySince before
+// CreateSuggested take turns.
+func (r *CategoryRepository) LockCreator(ctx context.Context, userID int64) error {
+	if _, err := r.db.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return fmt.Errorf("failed to lock category creator: %w", err)
+	}
+	return nil
+}
+
+// CountCreatedBySince counts the categories the user created from receipt
+// suggestions since the given time.
+func (r *CategoryRepository) CountCreatedBySince(ctx context.Context, userID int64, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM categories WHERE created_by = $1 AND created_at >= $2
+	`, userID, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count suggested categories: %w", err)
+	}
+	return count, nil
+}
+
+// Update modifies an existing category name and returns the updated row.
+func (r *CategoryRepository) Update(ctx context.Context, id int, name string) (*models.Category, error) {
+	var cat models.Category
+	err := r.db.QueryRow(ctx, `
 		UPDATE categories SET name = $2 WHERE id = $1
-	`, id, name)
+		RETURNING id, name, sort_order, color, owner_id, created_at
+	`, id, name).Scan(&cat.ID, &cat.Name, &cat.SortOrder, &cat.Color, &cat.OwnerID, &cat.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update category: %w", err)
+	}
+	return &cat, nil
+}
+
+// SetColor sets the chart color of a category, or clears it when color is
+// empty.
+func (r *CategoryRepository) SetColor(ctx context.Context, id int, color string) error {
+	_, err := r.db.Exec(ctx, `UPDATE categories SET color = $2 WHERE id = $1`, id, color)
 	if err != nil {
-		return fmt.Errorf("failed to update category: %w", err)
+		return fmt.Errorf("failed to set category color: %w", err)
 	}
 	return nil
 }
@@ -100,3 +162,130 @@ func (r *CategoryRepository) Delete(ctx context.Context, id int) error {
 	}
 	return nil
 }
+
+// Reorder sets the sort order of the given categories to their position in
+// ids, starting at 1. Categories not listed keep their sort order.
+func (r *CategoryRepository) Reorder(ctx context.Context, ids []int) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE categories c SET sort_order = o.position
+		FROM unnest($1::int[]) WITH ORDINALITY AS o(id, position)
+		WHERE c.id = o.id
+	`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to reorder categories: %w", err)
+	}
+	return nil
+}
+
+// UsageOrder returns the IDs of the categories of a user's confirmed
+// expenses created since the given time, most used first.
+func (r *CategoryRepository) UsageOrder(ctx context.Context, userID int64, since time.Time) ([]int, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT category_id FROM expenses
+		WHERE user_id = $1 AND status = 'confirmed' AND category_id IS NOT NULL AND created_at >= $2
+		GROUP BY category_id
+		ORDER BY COUNT(*) DESC, category_id
+	`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category usage: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan category usage: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating category usage: %w", err)
+	}
+	return ids, nil
+}
+
+// GetHiddenIDs returns the IDs of the shared categories a user hid.
+func (r *CategoryRepository) GetHiddenIDs(ctx context.Context, userID int64) ([]int, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT category_id FROM hidden_categories WHERE user_id = $1 ORDER BY category_id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hidden categories: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan hidden category: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating hidden categories: %w", err)
+	}
+	return ids, nil
+}
+
+// HideSharedExcept hides every shared category from a user except those
+// named in keep (case-insensitive), which are shown again if hidden.
+func (r *CategoryRepository) HideSharedExcept(ctx context.Context, userID int64, keep []string) error {
+	lowered := make([]string, len(keep))
+	for i, name := range keep {
+		lowered[i] = strings.ToLower(name)
+	}
+
+	_, err := r.db.Exec(ctx, `
+		DELETE FROM hidden_categories h USING categories c
+		WHERE h.user_id = $1 AND c.id = h.category_id AND LOWER(c.name) = ANY($2)
+	`, userID, lowered)
+	if err != nil {
+		return fmt.Errorf("failed to show kept categories: %w", err)
+	}
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO hidden_categories (user_id, category_id)
+		SELECT $1, id FROM categories WHERE owner_id = 0 AND LOWER(name) <> ALL($2)
+		ON CONFLICT DO NOTHING
+	`, userID, lowered)
+	if err != nil {
+		return fmt.Errorf("failed to hide categories: %w", err)
+	}
+	return nil
+}
+
+// Hide hides a shared category from a user. It reports whether the
+// category was shown before.
+func (r *CategoryRepository) Hide(ctx context.Context, userID int64, categoryID int) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO hidden_categories (user_id, category_id)
+		SELECT $1, id FROM categories WHERE id = $2 AND owner_id = 0
+		ON CONFLICT DO NOTHING
+	`, userID, categoryID)
+	if err != nil {
+		return false, fmt.Errorf("failed to hide category: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Unhide shows a hidden shared category to a user again. It reports
+// whether the category was hidden.
+func (r *CategoryRepository) Unhide(ctx context.Context, userID int64, categoryID int) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM hidden_categories WHERE user_id = $1 AND category_id = $2
+	`, userID, categoryID)
+	if err != nil {
+		return false, fmt.Errorf("failed to unhide category: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// UnhideAll shows every shared category to a user again.
+func (r *CategoryRepository) UnhideAll(ctx context.Context, userID int64) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM hidden_categories WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unhide categories: %w", err)
+	}
+	return nil
+}
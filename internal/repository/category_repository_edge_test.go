@@ -158,9 +158,9 @@ func TestCategoryRepository_UpdateEdgeCases(t *testing.T) {
 		tx := dbtest.TestTx(ctx, t)
 		repo := NewCategoryRepository(tx)
 
-		// Update doesn't check rows affected, so it succeeds silently
-		err := repo.Update(ctx, 99999, "NewName")
-		require.NoError(t, err) // No error, just no rows affected
+		// There is no row to return.
+		_, err := repo.Update(ctx, 99999, "NewName")
+		require.Error(t, err)
 	})
 
 	t.Run("update to duplicate name", func(t *testing.T) {
@@ -174,7 +174,7 @@ func TestCategoryRepository_UpdateEdgeCases(t *testing.T) {
 		require.NoError(t, err)
 
 		// Try to rename cat1 to cat2's name
-		err = repo.Update(ctx, cat1.ID, "Category2")
+		_, err = repo.Update(ctx, cat1.ID, "Category2")
 		require.Error(t, err)
 	})
 
@@ -185,7 +185,7 @@ func TestCategoryRepository_UpdateEdgeCases(t *testing.T) {
 		cat, err := repo.Create(ctx, "ToBeEmptied")
 		require.NoError(t, err)
 
-		err = repo.Update(ctx, cat.ID, "")
+		_, err = repo.Update(ctx, cat.ID, "")
 		require.NoError(t, err) // Empty name allowed
 
 		// Verify update
@@ -202,8 +202,9 @@ func TestCategoryRepository_UpdateEdgeCases(t *testing.T) {
 		require.NoError(t, err)
 
 		// Update to same name (should succeed)
-		err = repo.Update(ctx, cat.ID, "SameName")
+		updated, err := repo.Update(ctx, cat.ID, "SameName")
 		require.NoError(t, err)
+		require.Equal(t, cat.ID, updated.ID)
 	})
 }
 
@@ -3,8 +3,11 @@ package repository
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
 	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
 )
 
@@ -40,8 +43,9 @@ func TestCategoryRepository_CRUD(t *testing.T) {
 		cat, err := repo.Create(ctx, "Old Name")
 		require.NoError(t, err)
 
-		err = repo.Update(ctx, cat.ID, testCategoryNewName)
+		updated, err := repo.Update(ctx, cat.ID, testCategoryNewName)
 		require.NoError(t, err)
+		require.Equal(t, testCategoryNewName, updated.Name)
 
 		fetched, err := repo.GetByID(ctx, cat.ID)
 		require.NoError(t, err)
@@ -102,9 +106,9 @@ func TestCategoryRepository_UpdateNonExistent(t *testing.T) {
 
 	repo := NewCategoryRepository(tx)
 
-	// Update should succeed even for non-existent ID (no rows affected).
-	err := repo.Update(ctx, 99999, testCategoryNewName)
-	require.NoError(t, err)
+	// Update has no row to return for a non-existent ID.
+	_, err := repo.Update(ctx, 99999, testCategoryNewName)
+	require.Error(t, err)
 }
 
 func TestCategoryRepository_DeleteNonExistent(t *testing.T) {
@@ -153,3 +157,195 @@ func TestCategoryRepository_CreateDuplicate(t *testing.T) {
 		require.Error(t, err)
 	}
 }
+
+func TestCategoryRepository_CreateSuggested(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewCategoryRepository(tx)
+	userID := int64(414001)
+	since := time.Now().Add(-time.Hour)
+
+	_, err := repo.Create(ctx, "Manual Category")
+	require.NoError(t, err)
+	count, err := repo.CountCreatedBySince(ctx, userID, since)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	require.NoError(t, repo.LockCreator(ctx, userID))
+	cat, err := repo.CreateSuggested(ctx, "Pharmacy", userID)
+	require.NoError(t, err)
+	require.Equal(t, "Pharmacy", cat.Name)
+	_, err = repo.CreateSuggested(ctx, "Pet Supplies", userID+1)
+	require.NoError(t, err)
+
+	count, err = repo.CountCreatedBySince(ctx, userID, since)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	count, err = repo.CountCreatedBySince(ctx, userID, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestCategoryRepository_Order(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewCategoryRepository(tx)
+	userRepo := NewUserRepository(tx)
+	expenseRepo := NewExpenseRepository(tx)
+
+	categories, err := repo.GetAll(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(categories), 2)
+	first, last := categories[0], categories[len(categories)-1]
+
+	t.Run("reorder persists the sort order", func(t *testing.T) {
+		require.NoError(t, repo.Reorder(ctx, []int{last.ID, first.ID}))
+
+		reordered, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, reordered, len(categories))
+		require.Equal(t, first.ID, reordered[len(reordered)-1].ID)
+		require.Equal(t, last.ID, reordered[len(reordered)-2].ID)
+		require.Equal(t, 2, reordered[len(reordered)-1].SortOrder)
+	})
+
+	t.Run("usage order counts recent confirmed expenses", func(t *testing.T) {
+		userID := int64(399101)
+		require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: userID, FirstName: testFirstName}))
+		create := func(categoryID int, status models.ExpenseStatus) {
+			require.NoError(t, expenseRepo.Create(ctx, &models.Expense{
+				UserID: userID, Amount: decimal.NewFromInt(1), Currency: testCurrencySGD,
+				CategoryID: &categoryID, Status: status,
+			}))
+		}
+		create(first.ID, models.ExpenseStatusConfirmed)
+		create(last.ID, models.ExpenseStatusConfirmed)
+		create(last.ID, models.ExpenseStatusConfirmed)
+		create(first.ID, models.ExpenseStatusDraft)
+		create(first.ID, models.ExpenseStatusDraft)
+
+		ids, err := repo.UsageOrder(ctx, userID, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, []int{last.ID, first.ID}, ids)
+
+		ids, err = repo.UsageOrder(ctx, userID, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		require.Empty(t, ids)
+	})
+}
+
+func TestCategoryRepository_SetColor(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewCategoryRepository(tx)
+
+	cat, err := repo.Create(ctx, "Colored Category")
+	require.NoError(t, err)
+	require.Empty(t, cat.Color)
+
+	require.NoError(t, repo.SetColor(ctx, cat.ID, "#1F77B4"))
+	fetched, err := repo.GetByName(ctx, "Colored Category")
+	require.NoError(t, err)
+	require.Equal(t, "#1F77B4", fetched.Color)
+
+	require.NoError(t, repo.SetColor(ctx, cat.ID, ""))
+	fetched, err = repo.GetByID(ctx, cat.ID)
+	require.NoError(t, err)
+	require.Empty(t, fetched.Color)
+}
+
+func TestCategoryRepository_Visibility(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewCategoryRepository(tx)
+	const alice, bob = int64(426001), int64(426002)
+
+	shared, err := repo.Create(ctx, "Shared Pets 426")
+	require.NoError(t, err)
+	require.Zero(t, shared.OwnerID)
+	keep, err := repo.Create(ctx, "Shared Rent 426")
+	require.NoError(t, err)
+
+	t.Run("private categories share names across owners", func(t *testing.T) {
+		mine, err := repo.CreateOwned(ctx, alice, "Shared Pets 426")
+		require.NoError(t, err)
+		require.Equal(t, alice, mine.OwnerID)
+
+		_, err = repo.CreateOwned(ctx, alice, "Shared Pets 426")
+		require.Error(t, err, "names are unique per owner")
+
+		fetched, err := repo.GetByName(ctx, "shared pets 426")
+		require.NoError(t, err)
+		require.Equal(t, shared.ID, fetched.ID, "the shared category wins")
+	})
+
+	t.Run("suggested categories are private", func(t *testing.T) {
+		cat, err := repo.CreateSuggested(ctx, "Bob Hobby 426", bob)
+		require.NoError(t, err)
+		require.Equal(t, bob, cat.OwnerID)
+	})
+
+	t.Run("hide all but kept shared categories", func(t *testing.T) {
+		require.NoError(t, repo.HideSharedExcept(ctx, alice, []string{"shared rent 426"}))
+		hidden, err := repo.GetHiddenIDs(ctx, alice)
+		require.NoError(t, err)
+		require.Contains(t, hidden, shared.ID)
+		require.NotContains(t, hidden, keep.ID)
+
+		bobHidden, err := repo.GetHiddenIDs(ctx, bob)
+		require.NoError(t, err)
+		require.Empty(t, bobHidden)
+	})
+
+	t.Run("hide", func(t *testing.T) {
+		require.NoError(t, repo.UnhideAll(ctx, alice))
+		hid, err := repo.Hide(ctx, alice, shared.ID)
+		require.NoError(t, err)
+		require.True(t, hid)
+		hid, err = repo.Hide(ctx, alice, shared.ID)
+		require.NoError(t, err)
+		require.False(t, hid)
+
+		private, err := repo.CreateOwned(ctx, bob, "Bob Private 426")
+		require.NoError(t, err)
+		hid, err = repo.Hide(ctx, bob, private.ID)
+		require.NoError(t, err)
+		require.False(t, hid, "only shared categories are hidden")
+	})
+
+	t.Run("unhide", func(t *testing.T) {
+		unhidden, err := repo.Unhide(ctx, alice, shared.ID)
+		require.NoError(t, err)
+		require.True(t, unhidden)
+		unhidden, err = repo.Unhide(ctx, alice, shared.ID)
+		require.NoError(t, err)
+		require.False(t, unhidden)
+
+		require.NoError(t, repo.HideSharedExcept(ctx, alice, nil))
+		hidden, err := repo.GetHiddenIDs(ctx, alice)
+		require.NoError(t, err)
+		require.Contains(t, hidden, keep.ID)
+
+		require.NoError(t, repo.UnhideAll(ctx, alice))
+		hidden, err = repo.GetHiddenIDs(ctx, alice)
+		require.NoError(t, err)
+		require.Empty(t, hidden)
+	})
+}
+
+func TestCategoryRepository_NameUniqueIgnoringCase(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewCategoryRepository(tx)
+
+	_, err := repo.CreateOwned(ctx, 426003, "Case Pets 426")
+	require.NoError(t, err)
+	_, err = repo.CreateOwned(ctx, 426003, "case pets 426")
+	require.Error(t, err)
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// ChatRepository handles per-chat settings for group chats.
+type ChatRepository struct {
+	db database.PGXDB
+}
+
+// NewChatRepository creates a new ChatRepository.
+func NewChatRepository(db database.PGXDB) *ChatRepository {
+	return &ChatRepository{db: db}
+}
+
+// SetDefaultCategory makes categoryID the default category of expenses
+// logged in a chat.
+func (r *ChatRepository) SetDefaultCategory(ctx context.Context, chatID int64, categoryID int) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO chats (chat_id, default_category_id)
+		VALUES ($1, $2)
+		ON CONFLICT (chat_id)
+		DO UPDATE SET default_category_id = EXCLUDED.default_category_id, updated_at = NOW()
+	`, chatID, categoryID)
+	if err != nil {
+		return fmt.Errorf("failed to set chat default category: %w", err)
+	}
+	return nil
+}
+
+// ClearDefaultCategory removes a chat's default category. It reports
+// whether one was set.
+func (r *ChatRepository) ClearDefaultCategory(ctx context.Context, chatID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE chats SET default_category_id = NULL, updated_at = NOW()
+		WHERE chat_id = $1 AND default_category_id IS NOT NULL
+	`, chatID)
+	if err != nil {
+		return false, fmt.Errorf("failed to clear chat default category: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetDefaultCategoryID returns the default category of a chat. The error
+// wraps pgx.ErrNoRows when the chat has none.
+func (r *ChatRepository) GetDefaultCategoryID(ctx context.Context, chatID int64) (int, error) {
+	var categoryID *int
+	err := r.db.QueryRow(ctx, `SELECT default_category_id FROM chats WHERE chat_id = $1`, chatID).Scan(&categoryID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get chat default category: %w", err)
+	}
+	if categoryID == nil {
+		return 0, fmt.Errorf("chat %d has no default category: %w", chatID, pgx.ErrNoRows)
+	}
+	return *categoryID, nil
+}
+
+// RecordJoin records that addedBy added the bot to a chat, with the status
+// it starts in. A chat the bot rejoins keeps its settings.
+func (r *ChatRepository) RecordJoin(
+	ctx context.Context,
+	chatID, addedBy int64,
+	status models.ChatStatus,
+) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO chats (chat_id, status, added_by, joined_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (chat_id)
+		DO UPDATE SET status = EXCLUDED.status, added_by = EXCLUDED.added_by,
+			joined_at = EXCLUDED.joined_at, updated_at = NOW()
+	`, chatID, status, addedBy)
+	if err != nil {
+		return fmt.Errorf("failed to record chat join: %w", err)
+	}
+	return nil
+}
+
+// MarkLeft records that the bot is no longer in a chat.
+func (r *ChatRepository) MarkLeft(ctx context.Context, chatID int64) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO chats (chat_id, status) VALUES ($1, 'left')
+		ON CONFLICT (chat_id) DO UPDATE SET status = 'left', updated_at = NOW()
+	`, chatID)
+	if err != nil {
+		return fmt.Errorf("failed to mark chat left: %w", err)
+	}
+	return nil
+}
+
+// ClaimPending makes a pending chat active. It reports whether the chat was
+// pending.
+func (r *ChatRepository) ClaimPending(ctx context.Context, chatID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE chats SET status = 'active', updated_at = NOW()
+		WHERE chat_id = $1 AND status = 'pending'
+	`, chatID)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim chat: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetStatus returns a chat's status. Chats without a row are active, as
+// they were before statuses were tracked.
+func (r *ChatRepository) GetStatus(ctx context.Context, chatID int64) (models.ChatStatus, error) {
+	var status models.ChatStatus
+	err := r.db.QueryRow(ctx, `SELECT status FROM chats WHERE chat_id = $1`, chatID).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ChatStatusActive, nil
+		}
+		return "", fmt.Errorf("failed to get chat status: %w", err)
+	}
+	return status, nil
+}
+
+// GetPendingJoinedBefore returns the pending chats the bot joined before
+// the given time.
+func (r *ChatRepository) GetPendingJoinedBefore(ctx context.Context, before time.Time) ([]int64, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT chat_id FROM chats WHERE status = 'pending' AND joined_at < $1 ORDER BY joined_at
+	`, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending chats: %w", err)
+	}
+	defer rows.Close()
+
+	var chatIDs []int64
+	for rows.Next() {
+		var chatID int64
+		if err := rows.Scan(&chatID); err != nil {
+			return nil, fmt.Errorf("failed to scan pending chat: %w", err)
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate pending chats: %w", err)
+	}
+	return chatIDs, nil
+}
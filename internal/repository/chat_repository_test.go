@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
+)
+
+func TestChatRepository(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	categoryRepo := NewCategoryRepository(tx)
+	repo := NewChatRepository(tx)
+
+	chatID := int64(-387101)
+	category, err := categoryRepo.Create(ctx, "Chat Groceries")
+	require.NoError(t, err)
+
+	_, err = repo.GetDefaultCategoryID(ctx, chatID)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	require.NoError(t, repo.SetDefaultCategory(ctx, chatID, category.ID))
+	got, err := repo.GetDefaultCategoryID(ctx, chatID)
+	require.NoError(t, err)
+	require.Equal(t, category.ID, got)
+
+	cleared, err := repo.ClearDefaultCategory(ctx, chatID)
+	require.NoError(t, err)
+	require.True(t, cleared)
+	_, err = repo.GetDefaultCategoryID(ctx, chatID)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	cleared, err = repo.ClearDefaultCategory(ctx, chatID)
+	require.NoError(t, err)
+	require.False(t, cleared)
+}
+
+func TestChatRepository_Status(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewChatRepository(tx)
+	userRepo := NewUserRepository(tx)
+
+	chatID := int64(-425101)
+	status, err := repo.GetStatus(ctx, chatID)
+	require.NoError(t, err)
+	require.Equal(t, models.ChatStatusActive, status, "unknown chats are active")
+
+	require.NoError(t, repo.RecordJoin(ctx, chatID, 425001, models.ChatStatusPending))
+	status, err = repo.GetStatus(ctx, chatID)
+	require.NoError(t, err)
+	require.Equal(t, models.ChatStatusPending, status)
+
+	pending, err := repo.GetPendingJoinedBefore(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Contains(t, pending, chatID)
+	pending, err = repo.GetPendingJoinedBefore(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.NotContains(t, pending, chatID)
+
+	claimed, err := repo.ClaimPending(ctx, chatID)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	claimed, err = repo.ClaimPending(ctx, chatID)
+	require.NoError(t, err)
+	require.False(t, claimed, "only pending chats are claimed")
+
+	user := &models.User{ID: 425001, FirstName: "Archiver"}
+	require.NoError(t, userRepo.UpsertUser(ctx, user))
+	require.NoError(t, userRepo.SetArchiveChatID(ctx, user.ID, chatID))
+	users, err := userRepo.GetAuthorizedUsersForArchive(ctx, []int64{user.ID}, nil)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+
+	require.NoError(t, repo.MarkLeft(ctx, chatID))
+	status, err = repo.GetStatus(ctx, chatID)
+	require.NoError(t, err)
+	require.Equal(t, models.ChatStatusLeft, status)
+	users, err = userRepo.GetAuthorizedUsersForArchive(ctx, []int64{user.ID}, nil)
+	require.NoError(t, err)
+	require.Empty(t, users, "left chats get no archive")
+}
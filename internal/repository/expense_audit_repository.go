@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+)
+
+// Audited expense fields. ExpenseAuditDeleted marks the deletion of the
+// whole expense.
+const (
+	ExpenseAuditAmount      = "amount"
+	ExpenseAuditDescription = "description"
+	ExpenseAuditMerchant    = "merchant"
+	ExpenseAuditCategory    = "category"
+	ExpenseAuditDeleted     = "deleted"
+)
+
+// ExpenseAuditEntry records one change to a confirmed expense. The expense
+// number is kept so the trail stays readable after the expense is deleted.
+type ExpenseAuditEntry struct {
+	ExpenseID     int
+	UserID        int64
+	ExpenseNumber int64
+	ActorID       int64
+	Field         string
+	OldValue      string
+	NewValue      string
+	CreatedAt     time.Time
+}
+
+// ExpenseAuditRepository handles the expense_audit trail.
+type ExpenseAuditRepository struct {
+	db database.PGXDB
+}
+
+// NewExpenseAuditRepository creates a new ExpenseAuditRepository.
+func NewExpenseAuditRepository(db database.PGXDB) *ExpenseAuditRepository {
+	return &ExpenseAuditRepository{db: db}
+}
+
+// Record stores audit entries in one statement, so either all of them are
+// stored or none.
+func (r *ExpenseAuditRepository) Record(ctx context.Context, entries []ExpenseAuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	expenseIDs := make([]int, len(entries))
+	userIDs := make([]int64, len(entries))
+	numbers := make([]int64, len(entries))
+	actorIDs := make([]int64, len(entries))
+	fields := make([]string, len(entries))
+	oldValues := make([]string, len(entries))
+	newValues := make([]string, len(entries))
+	for i, entry := range entries {
+		expenseIDs[i] = entry.ExpenseID
+		userIDs[i] = entry.UserID
+		numbers[i] = entry.ExpenseNumber
+		actorIDs[i] = entry.ActorID
+		fields[i] = entry.Field
+		oldValues[i] = entry.OldValue
+		newValues[i] = entry.NewValue
+	}
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO expense_audit (expense_id, user_id, expense_number, actor_id, field, old_value, new_value)
+		SELECT * FROM unnest($1::INTEGER[], $2::BIGINT[], $3::BIGINT[], $4::BIGINT[], $5::TEXT[], $6::TEXT[], $7::TEXT[])
+	`, expenseIDs, userIDs, numbers, actorIDs, fields, oldValues, newValues)
+	if err != nil {
+		return fmt.Errorf("failed to record expense audit: %w", err)
+	}
+	return nil
+}
+
+// ListByExpenseNumber returns the audit trail of a user's expense, oldest
+// first.
+func (r *ExpenseAuditRepository) ListByExpenseNumber(
+	ctx context.Context,
+	userID int64,
+	expenseNumber int64,
+) ([]ExpenseAuditEntry, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT expense_id, user_id, expense_number, actor_id, field, old_value, new_value, created_at
+		FROM expense_audit
+		WHERE user_id = $1 AND expense_number = $2
+		ORDER BY created_at, id
+	`, userID, expenseNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expense audit: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []ExpenseAuditEntry
+	for rows.Next() {
+		var entry ExpenseAuditEntry
+		if err := rows.Scan(
+			&entry.ExpenseID, &entry.UserID, &entry.ExpenseNumber, &entry.ActorID,
+			&entry.Field, &entry.OldValue, &entry.NewValue, &entry.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan expense audit: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expense audit: %w", err)
+	}
+	return entries, nil
+}
+
+// Count returns how many audit entries the expenses of userID have, or all
+// entries when userID is zero.
+func (r *ExpenseAuditRepository) Count(ctx context.Context, userID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM expense_audit WHERE $1::BIGINT = 0 OR user_id = $1
+	`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count expense audit: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
+)
+
+func TestExpenseAuditRepository(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	userRepo := NewUserRepository(tx)
+	expenseRepo := NewExpenseRepository(tx)
+	repo := NewExpenseAuditRepository(tx)
+
+	userID := int64(380001)
+	require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: userID, FirstName: "Audit"}))
+
+	expense := &models.Expense{
+		UserID:   userID,
+		Amount:   decimal.NewFromFloat(15.50),
+		Currency: "SGD",
+		Status:   models.ExpenseStatusConfirmed,
+	}
+	require.NoError(t, expenseRepo.Create(ctx, expense))
+
+	entry := func(field, oldValue, newValue string) ExpenseAuditEntry {
+		return ExpenseAuditEntry{
+			ExpenseID:     expense.ID,
+			UserID:        userID,
+			ExpenseNumber: expense.UserExpenseNumber,
+			ActorID:       userID,
+			Field:         field,
+			OldValue:      oldValue,
+			NewValue:      newValue,
+		}
+	}
+	require.NoError(t, repo.Record(ctx, []ExpenseAuditEntry{
+		entry(ExpenseAuditAmount, "15.50 SGD", "25.50 SGD"),
+		entry(ExpenseAuditCategory, "Others", "Transportation"),
+	}))
+
+	t.Run("survives deletion of the expense", func(t *testing.T) {
+		require.NoError(t, expenseRepo.Delete(ctx, expense.ID))
+		require.NoError(t, repo.Record(ctx, []ExpenseAuditEntry{entry(ExpenseAuditDeleted, "25.50 SGD", "")}))
+
+		entries, err := repo.ListByExpenseNumber(ctx, userID, expense.UserExpenseNumber)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		require.Equal(t, ExpenseAuditAmount, entries[0].Field)
+		require.Equal(t, "25.50 SGD", entries[0].NewValue)
+		require.Equal(t, ExpenseAuditCategory, entries[1].Field)
+		require.Equal(t, ExpenseAuditDeleted, entries[2].Field)
+		require.False(t, entries[0].CreatedAt.IsZero())
+	})
+
+	t.Run("other users see nothing", func(t *testing.T) {
+		entries, err := repo.ListByExpenseNumber(ctx, userID+1, expense.UserExpenseNumber)
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+}
@@ -14,7 +14,10 @@ import (
 	"gitlab.com/yelinaung/expense-bot/internal/models"
 )
 
-// ExpenseRepository handles expense database operations.
+// ExpenseRepository handles expense database operations. Listings order
+// by creation time and then by ID, so expenses created in the same instant,
+// such as a bulk import, come back in the same order on every call, and
+// their limits apply after that order.
 type ExpenseRepository struct {
 	db database.PGXDB
 }
@@ -29,22 +32,140 @@ func (r *ExpenseRepository) Pool() database.PGXDB {
 	return r.db
 }
 
-// Create adds a new expense.
+// Create adds a new expense. When the expense has an IdempotencyKey the
+// user already used, it is filled with the expense saved first instead, so
+// callers can retry a create whose outcome they did not see.
 func (r *ExpenseRepository) Create(ctx context.Context, expense *models.Expense) error {
 	// Default to confirmed if not specified.
 	if expense.Status == models.ExpenseStatusUnset {
 		expense.Status = models.ExpenseStatusConfirmed
 	}
+	if expense.Source == "" {
+		expense.Source = models.ExpenseSourceUnknown
+	}
+	if replayed, err := r.replayIdempotent(ctx, expense); err != nil || replayed {
+		return err
+	}
 	err := r.db.QueryRow(
 		ctx, `
-		INSERT INTO expenses (user_id, amount, currency, description, merchant, category_id, receipt_file_id, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO expenses (user_id, amount, currency, description, merchant, category_id, receipt_file_id, status,
+		                      ai_categorized, ai_confidence, rate_to_default, converted_amount, converted_currency,
+		                      ai_category_id, reimbursable, idempotency_key, source)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, CASE WHEN $9 THEN $6::integer END, $14,
+		        NULLIF($15, ''), $16)
+		ON CONFLICT (user_id, idempotency_key) DO NOTHING
 		RETURNING id, user_expense_number, created_at, updated_at
 	`, expense.UserID, expense.Amount, expense.Currency, expense.Description,
 		expense.Merchant, expense.CategoryID, expense.ReceiptFileID, expense.Status,
+		expense.AICategorized, expense.AIConfidence,
+		expense.RateToDefault, expense.ConvertedAmount, expense.ConvertedCurrency,
+		expense.Reimbursable, expense.IdempotencyKey, expense.Source,
+	).Scan(&expense.ID, &expense.UserExpenseNumber, &expense.CreatedAt, &expense.UpdatedAt)
+	if err != nil {
+		return r.idempotentConflict(ctx, expense, fmt.Errorf("failed to create expense: %w", err))
+	}
+	return nil
+}
+
+// CreateImported inserts a confirmed expense read from an import file. It
+// keeps the expense's CreatedAt instead of stamping the insert time. Like
+// Create, it honors the expense's IdempotencyKey.
+func (r *ExpenseRepository) CreateImported(ctx context.Context, expense *models.Expense) error {
+	expense.Status = models.ExpenseStatusConfirmed
+	if expense.Source == "" {
+		expense.Source = models.ExpenseSourceImport
+	}
+	if replayed, err := r.replayIdempotent(ctx, expense); err != nil || replayed {
+		return err
+	}
+	err := r.db.QueryRow(
+		ctx, `
+		INSERT INTO expenses (user_id, amount, currency, description, merchant, category_id, status, created_at,
+		                      idempotency_key, source)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10)
+		ON CONFLICT (user_id, idempotency_key) DO NOTHING
+		RETURNING id, user_expense_number, created_at, updated_at
+	`, expense.UserID, expense.Amount, expense.Currency, expense.Description,
+		expense.Merchant, expense.CategoryID, expense.Status, expense.CreatedAt, expense.IdempotencyKey,
+		expense.Source,
 	).Scan(&expense.ID, &expense.UserExpenseNumber, &expense.CreatedAt, &expense.UpdatedAt)
 	if err != nil {
-		return fmt.Errorf("failed to create expense: %w", err)
+		return r.idempotentConflict(ctx, expense, fmt.Errorf("failed to create imported expense: %w", err))
+	}
+	return nil
+}
+
+// GetByIdempotencyKey returns the user's expense created with key. The
+// error wraps pgx.ErrNoRows when there is none.
+func (r *ExpenseRepository) GetByIdempotencyKey(
+	ctx context.Context,
+	userID int64,
+	key string,
+) (*models.Expense, error) {
+	var id int
+	err := r.db.QueryRow(ctx, `
+		SELECT id FROM expenses WHERE user_id = $1 AND idempotency_key = $2
+	`, userID, key).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expense by idempotency key: %w", err)
+	}
+	return r.GetByID(ctx, id)
+}
+
+// GetRecentDuplicate returns the newest expense of the same user and
+// source with the amount, currency and description of expense, created
+// at or after since, or pgx.ErrNoRows when there is none.
+func (r *ExpenseRepository) GetRecentDuplicate(
+	ctx context.Context,
+	expense *models.Expense,
+	since time.Time,
+) (*models.Expense, error) {
+	var id int
+	err := r.db.QueryRow(ctx, `
+		SELECT id FROM expenses
+		WHERE user_id = $1 AND source = $2 AND amount = $3 AND currency = $4
+			AND description = $5 AND created_at >= $6
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`, expense.UserID, expense.Source, expense.Amount, expense.Currency, expense.Description, since).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent duplicate expense: %w", err)
+	}
+	return r.GetByID(ctx, id)
+}
+
+// replayIdempotent fills expense with the one saved before under its
+// idempotency key, if any, and reports whether it did.
+func (r *ExpenseRepository) replayIdempotent(ctx context.Context, expense *models.Expense) (bool, error) {
+	if expense.IdempotencyKey == "" {
+		return false, nil
+	}
+	stored, err := r.GetByIdempotencyKey(ctx, expense.UserID, expense.IdempotencyKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	stored.IdempotencyKey = expense.IdempotencyKey
+	stored.Replayed = true
+	*expense = *stored
+	return true, nil
+}
+
+// idempotentConflict handles a failed insert. An insert that returned no
+// row lost a race with a concurrent create using the same idempotency key,
+// whose expense is then returned; anything else is insertErr.
+func (r *ExpenseRepository) idempotentConflict(ctx context.Context, expense *models.Expense, insertErr error) error {
+	if !errors.Is(insertErr, pgx.ErrNoRows) || expense.IdempotencyKey == "" {
+		return insertErr
+	}
+	replayed, err := r.replayIdempotent(ctx, expense)
+	if err != nil {
+		return err
+	}
+	if !replayed {
+		return insertErr
 	}
 	return nil
 }
@@ -57,14 +178,16 @@ func (r *ExpenseRepository) GetByID(ctx context.Context, id int) (*models.Expens
 	var catCreatedAt *time.Time
 	err := r.db.QueryRow(ctx, `
 		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
-		       e.receipt_file_id, e.status, e.created_at, e.updated_at,
+		       e.receipt_file_id, e.status, e.created_at, e.updated_at, e.ai_categorized, e.ai_confidence,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
 		       c.id, c.name, c.created_at
 		FROM expenses e
 		LEFT JOIN categories c ON e.category_id = c.id
 		WHERE e.id = $1
 	`, id).Scan(&exp.ID, &exp.UserExpenseNumber, &exp.UserID, &exp.Amount, &exp.Currency, &exp.Description,
 		&exp.Merchant, &categoryID, &exp.ReceiptFileID, &exp.Status, &exp.CreatedAt, &exp.UpdatedAt,
-		&catID, &catName, &catCreatedAt)
+		&exp.AICategorized, &exp.AIConfidence, &exp.RateToDefault, &exp.ConvertedAmount, &exp.ConvertedCurrency,
+		&exp.Reimbursable, &exp.ReimbursedAt, &exp.Source, &catID, &catName, &catCreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get expense: %w", err)
 	}
@@ -84,10 +207,11 @@ func (r *ExpenseRepository) GetByUserAndNumber(ctx context.Context, userID, numb
 	var exp models.Expense
 	var categoryID *int
 	err := r.db.QueryRow(ctx, `
-		SELECT id, user_expense_number, user_id, amount, currency, description, merchant, category_id, receipt_file_id, status, created_at, updated_at
+		SELECT id, user_expense_number, user_id, amount, currency, description, merchant, category_id, receipt_file_id, status, created_at, updated_at,
+		       source
 		FROM expenses WHERE user_id = $1 AND user_expense_number = $2
 	`, userID, number).Scan(&exp.ID, &exp.UserExpenseNumber, &exp.UserID, &exp.Amount, &exp.Currency, &exp.Description,
-		&exp.Merchant, &categoryID, &exp.ReceiptFileID, &exp.Status, &exp.CreatedAt, &exp.UpdatedAt)
+		&exp.Merchant, &categoryID, &exp.ReceiptFileID, &exp.Status, &exp.CreatedAt, &exp.UpdatedAt, &exp.Source)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get expense by user number: %w", err)
 	}
@@ -95,11 +219,13 @@ func (r *ExpenseRepository) GetByUserAndNumber(ctx context.Context, userID, numb
 	return &exp, nil
 }
 
-// GetByUserID retrieves all confirmed expenses for a user.
+// GetByUserID retrieves a user's latest confirmed expenses, newest first,
+// with at most limit rows.
 func (r *ExpenseRepository) GetByUserID(ctx context.Context, userID int64, limit int) ([]models.Expense, error) {
 	rows, err := r.db.Query(ctx, `
 		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
 		       e.receipt_file_id, e.status, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
 		       c.id, c.name, c.created_at
 		FROM expenses e
 		LEFT JOIN categories c ON e.category_id = c.id
@@ -115,7 +241,88 @@ func (r *ExpenseRepository) GetByUserID(ctx context.Context, userID int64, limit
 	return scanExpenses(rows)
 }
 
-// GetByUserIDAndDateRange retrieves confirmed expenses for a user within a date range.
+// merchantKey is the form merchants are compared in: lower case, with runs
+// of whitespace collapsed to one space and none at either end. It matches
+// merchantKeySQL.
+func merchantKey(merchant string) string {
+	return strings.ToLower(strings.Join(strings.Fields(merchant), " "))
+}
+
+// merchantKeySQL computes merchantKey of e.merchant in SQL.
+const merchantKeySQL = `LOWER(BTRIM(REGEXP_REPLACE(e.merchant, '\s+', ' ', 'g')))`
+
+// GetByUserAndMerchant retrieves a user's latest confirmed expenses at
+// merchant, newest first. Merchants are matched ignoring case and
+// whitespace, so "Din Tai Fung" matches " din  tai fung".
+func (r *ExpenseRepository) GetByUserAndMerchant(
+	ctx context.Context,
+	userID int64,
+	merchant string,
+	limit int,
+) ([]models.Expense, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
+		       e.receipt_file_id, e.status, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
+		       c.id, c.name, c.created_at
+		FROM expenses e
+		LEFT JOIN categories c ON e.category_id = c.id
+		WHERE e.user_id = $1 AND e.status = 'confirmed' AND `+merchantKeySQL+` = $2
+		ORDER BY e.created_at DESC, e.id DESC
+		LIMIT $3
+	`, userID, merchantKey(merchant), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query merchant expenses: %w", err)
+	}
+	defer rows.Close()
+
+	return scanExpenses(rows)
+}
+
+// MerchantTotal is a user's confirmed spending at one merchant in one
+// currency, the stored conversion's currency when there is one.
+type MerchantTotal struct {
+	Currency string
+	Count    int
+	Amount   decimal.Decimal
+}
+
+// GetMerchantTotals returns a user's confirmed spending at merchant over
+// all time, per currency, matching merchants like GetByUserAndMerchant.
+func (r *ExpenseRepository) GetMerchantTotals(
+	ctx context.Context,
+	userID int64,
+	merchant string,
+) ([]MerchantTotal, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT CASE WHEN e.converted_amount IS NULL THEN e.currency ELSE e.converted_currency END AS total_currency,
+			COUNT(*), SUM(COALESCE(e.converted_amount, e.amount))
+		FROM expenses e
+		WHERE e.user_id = $1 AND e.status = 'confirmed' AND `+merchantKeySQL+` = $2
+		GROUP BY total_currency
+		ORDER BY total_currency
+	`, userID, merchantKey(merchant))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query merchant totals: %w", err)
+	}
+	defer rows.Close()
+
+	var totals []MerchantTotal
+	for rows.Next() {
+		var total MerchantTotal
+		if err := rows.Scan(&total.Currency, &total.Count, &total.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan merchant total: %w", err)
+		}
+		totals = append(totals, total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate merchant totals: %w", err)
+	}
+	return totals, nil
+}
+
+// GetByUserIDAndDateRange retrieves confirmed expenses for a user within a
+// date range, newest first.
 func (r *ExpenseRepository) GetByUserIDAndDateRange(
 	ctx context.Context,
 	userID int64,
@@ -124,6 +331,7 @@ func (r *ExpenseRepository) GetByUserIDAndDateRange(
 	rows, err := r.db.Query(ctx, `
 		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
 		       e.receipt_file_id, e.status, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
 		       c.id, c.name, c.created_at
 		FROM expenses e
 		LEFT JOIN categories c ON e.category_id = c.id
@@ -138,7 +346,8 @@ func (r *ExpenseRepository) GetByUserIDAndDateRange(
 	return scanExpenses(rows)
 }
 
-// GetByUserIDAndCategory retrieves confirmed expenses for a user filtered by category.
+// GetByUserIDAndCategory retrieves a user's latest confirmed expenses in a
+// category, newest first, with at most limit rows.
 func (r *ExpenseRepository) GetByUserIDAndCategory(
 	ctx context.Context,
 	userID int64,
@@ -148,6 +357,7 @@ func (r *ExpenseRepository) GetByUserIDAndCategory(
 	rows, err := r.db.Query(ctx, `
 		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
 		       e.receipt_file_id, e.status, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
 		       c.id, c.name, c.created_at
 		FROM expenses e
 		LEFT JOIN categories c ON e.category_id = c.id
@@ -163,24 +373,71 @@ func (r *ExpenseRepository) GetByUserIDAndCategory(
 	return scanExpenses(rows)
 }
 
-// GetTotalByUserIDAndCategory calculates total spending for confirmed expenses in a category.
-func (r *ExpenseRepository) GetTotalByUserIDAndCategory(
+// GetByUserIDDateRangeAndCategory retrieves confirmed expenses for a user
+// in one category within a date range, newest first.
+func (r *ExpenseRepository) GetByUserIDDateRangeAndCategory(
 	ctx context.Context,
 	userID int64,
+	startDate, endDate time.Time,
 	categoryID int,
-) (decimal.Decimal, error) {
-	var total decimal.Decimal
-	err := r.db.QueryRow(ctx, `
-		SELECT COALESCE(SUM(amount), 0) FROM expenses
+) ([]models.Expense, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
+		       e.receipt_file_id, e.status, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
+		       c.id, c.name, c.created_at
+		FROM expenses e
+		LEFT JOIN categories c ON e.category_id = c.id
+		WHERE e.user_id = $1 AND e.created_at >= $2 AND e.created_at < $3 AND e.category_id = $4
+		  AND e.status = 'confirmed'
+		ORDER BY e.created_at DESC, e.id DESC
+	`, userID, startDate, endDate, categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expenses by date range and category: %w", err)
+	}
+	defer rows.Close()
+
+	return scanExpenses(rows)
+}
+
+// GetTotalsByUserIDAndCategory returns a user's confirmed spending in a
+// category per currency, the stored conversion's currency when there is
+// one.
+func (r *ExpenseRepository) GetTotalsByUserIDAndCategory(
+	ctx context.Context,
+	userID int64,
+	categoryID int,
+) (map[string]decimal.Decimal, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT CASE WHEN converted_amount IS NULL THEN currency ELSE converted_currency END AS total_currency,
+			SUM(COALESCE(converted_amount, amount))
+		FROM expenses
 		WHERE user_id = $1 AND category_id = $2 AND status = 'confirmed'
-	`, userID, categoryID).Scan(&total)
+		GROUP BY total_currency
+	`, userID, categoryID)
 	if err != nil {
-		return decimal.Zero, fmt.Errorf("failed to get total by category: %w", err)
+		return nil, fmt.Errorf("failed to get totals by category: %w", err)
 	}
-	return total, nil
+	defer rows.Close()
+
+	totals := make(map[string]decimal.Decimal)
+	for rows.Next() {
+		var currency string
+		var total decimal.Decimal
+		if err := rows.Scan(&currency, &total); err != nil {
+			return nil, fmt.Errorf("failed to scan category total: %w", err)
+		}
+		totals[currency] = total
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate category totals: %w", err)
+	}
+	return totals, nil
 }
 
-// Update modifies an existing expense.
+// Update modifies an existing expense. Changing the category clears the
+// AI-categorized flag, and changing the amount or currency clears the
+// stored conversion to the default currency.
 func (r *ExpenseRepository) Update(ctx context.Context, expense *models.Expense) error {
 	_, err := r.db.Exec(ctx, `
 		UPDATE expenses SET
@@ -191,6 +448,10 @@ func (r *ExpenseRepository) Update(ctx context.Context, expense *models.Expense)
 			category_id = $6,
 			receipt_file_id = $7,
 			status = $8,
+			ai_categorized = ai_categorized AND category_id IS NOT DISTINCT FROM $6,
+			rate_to_default = CASE WHEN amount = $2 AND currency = $3 THEN rate_to_default END,
+			converted_amount = CASE WHEN amount = $2 AND currency = $3 THEN converted_amount END,
+			converted_currency = CASE WHEN amount = $2 AND currency = $3 THEN converted_currency ELSE '' END,
 			updated_at = NOW()
 		WHERE id = $1
 	`, expense.ID, expense.Amount, expense.Currency, expense.Description,
@@ -254,11 +515,34 @@ func (r *ExpenseRepository) DeleteExpiredDrafts(ctx context.Context, olderThan t
 	return int(result.RowsAffected()), nil
 }
 
+// GetDraftsByUserID retrieves the user's draft expenses, oldest first so
+// the ones closest to expiry lead, with at most limit rows.
+func (r *ExpenseRepository) GetDraftsByUserID(ctx context.Context, userID int64, limit int) ([]models.Expense, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
+		       e.receipt_file_id, e.status, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
+		       c.id, c.name, c.created_at
+		FROM expenses e
+		LEFT JOIN categories c ON e.category_id = c.id
+		WHERE e.user_id = $1 AND e.status = $2
+		ORDER BY e.created_at, e.id
+		LIMIT $3
+	`, userID, models.ExpenseStatusDraft, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query drafts: %w", err)
+	}
+	defer rows.Close()
+
+	return scanExpenses(rows)
+}
+
 // GetUnreviewedByUserID retrieves confirmed expenses that have not been reviewed.
 func (r *ExpenseRepository) GetUnreviewedByUserID(ctx context.Context, userID int64, limit int) ([]models.Expense, error) {
 	rows, err := r.db.Query(ctx, `
 		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
 		       e.receipt_file_id, e.status, e.worth_it, e.spend_driver, e.reviewed_at, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
 		       c.id, c.name, c.created_at
 		FROM expenses e
 		LEFT JOIN categories c ON e.category_id = c.id
@@ -287,6 +571,7 @@ func (r *ExpenseRepository) GetNextUnreviewedByUserID(ctx context.Context, userI
 	rows, err := r.db.Query(ctx, `
 		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
 		       e.receipt_file_id, e.status, e.worth_it, e.spend_driver, e.reviewed_at, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
 		       c.id, c.name, c.created_at
 		FROM expenses e
 		LEFT JOIN categories c ON e.category_id = c.id
@@ -321,6 +606,7 @@ func (r *ExpenseRepository) GetReviewedByUserIDAndDateRange(
 	rows, err := r.db.Query(ctx, `
 		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
 		       e.receipt_file_id, e.status, e.worth_it, e.spend_driver, e.reviewed_at, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
 		       c.id, c.name, c.created_at
 		FROM expenses e
 		LEFT JOIN categories c ON e.category_id = c.id
@@ -339,6 +625,77 @@ func (r *ExpenseRepository) GetReviewedByUserIDAndDateRange(
 	return scanExpensesWithReflection(rows)
 }
 
+// GetAllByUserIDCursor retrieves up to limit confirmed expenses of a user
+// with an ID above afterID, in ID order. Passing the last ID of one page as
+// afterID of the next walks all expenses with keyset pagination, so large
+// exports never hold more than one page in memory.
+func (r *ExpenseRepository) GetAllByUserIDCursor(
+	ctx context.Context,
+	userID int64,
+	afterID, limit int,
+) ([]models.Expense, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
+		       e.receipt_file_id, e.status, e.worth_it, e.spend_driver, e.reviewed_at, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
+		       c.id, c.name, c.created_at
+		FROM expenses e
+		LEFT JOIN categories c ON e.category_id = c.id
+		WHERE e.user_id = $1 AND e.id > $2 AND e.status = $3
+		ORDER BY e.id
+		LIMIT $4
+	`, userID, afterID, models.ExpenseStatusConfirmed, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expenses page: %w", err)
+	}
+	defer rows.Close()
+
+	return scanExpensesWithReflection(rows)
+}
+
+// GetSheetExportPending retrieves up to limit confirmed expenses of a user
+// the daily sheet export has not sent, with an ID above afterID and created
+// before before, in ID order. An expense confirmed or imported after a
+// higher ID was sent is still pending.
+func (r *ExpenseRepository) GetSheetExportPending(
+	ctx context.Context,
+	userID int64,
+	afterID int,
+	before time.Time,
+	limit int,
+) ([]models.Expense, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
+		       e.receipt_file_id, e.status, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
+		       c.id, c.name, c.created_at
+		FROM expenses e
+		LEFT JOIN categories c ON e.category_id = c.id
+		WHERE e.user_id = $1 AND e.id > $2 AND e.created_at < $3 AND e.status = $4
+			AND e.sheet_exported_at IS NULL
+		ORDER BY e.id
+		LIMIT $5
+	`, userID, afterID, before, models.ExpenseStatusConfirmed, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending sheet export expenses: %w", err)
+	}
+	defer rows.Close()
+
+	return scanExpenses(rows)
+}
+
+// GetMaxID returns the highest expense ID of a user, or zero.
+func (r *ExpenseRepository) GetMaxID(ctx context.Context, userID int64) (int, error) {
+	var maxID int
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(MAX(id), 0) FROM expenses WHERE user_id = $1
+	`, userID).Scan(&maxID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get max expense id: %w", err)
+	}
+	return maxID, nil
+}
+
 // GetTotalByUserIDAndDateRange calculates total spending for confirmed expenses in a date range.
 func (r *ExpenseRepository) GetTotalByUserIDAndDateRange(
 	ctx context.Context,
@@ -356,83 +713,871 @@ func (r *ExpenseRepository) GetTotalByUserIDAndDateRange(
 	return total, nil
 }
 
-// NullifyCategoryOnExpenses sets category_id to NULL for all expenses
-// referencing the given category. This must be called before deleting
-// a category to avoid FK constraint violations. Returns the number of
-// affected rows.
-func (r *ExpenseRepository) NullifyCategoryOnExpenses(ctx context.Context, categoryID int) (int64, error) {
-	result, err := r.db.Exec(ctx, `
-		UPDATE expenses SET category_id = NULL, updated_at = NOW()
-		WHERE category_id = $1
-	`, categoryID)
+// GetTotalByUserIDCategoryAndDateRange calculates total spending for
+// confirmed expenses in a category and date range.
+func (r *ExpenseRepository) GetTotalByUserIDCategoryAndDateRange(
+	ctx context.Context,
+	userID int64,
+	categoryID int,
+	startDate, endDate time.Time,
+) (decimal.Decimal, error) {
+	var total decimal.Decimal
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM expenses
+		WHERE user_id = $1 AND category_id = $2 AND created_at >= $3 AND created_at < $4
+		  AND status = 'confirmed'
+	`, userID, categoryID, startDate, endDate).Scan(&total)
 	if err != nil {
-		return 0, fmt.Errorf("failed to nullify category on expenses: %w", err)
+		return decimal.Zero, fmt.Errorf("failed to get category total: %w", err)
 	}
-	return result.RowsAffected(), nil
+	return total, nil
 }
 
-// HasExpensesForDate checks if a user has any confirmed expenses in the given time range.
-func (r *ExpenseRepository) HasExpensesForDate(ctx context.Context, userID int64, startOfDay, endOfDay time.Time) (bool, error) {
-	var exists bool
-	err := r.db.QueryRow(ctx, `
-		SELECT EXISTS(SELECT 1 FROM expenses WHERE user_id = $1 AND created_at >= $2 AND created_at < $3 AND status = 'confirmed')
-	`, userID, startOfDay, endOfDay).Scan(&exists)
+// CategoryCurrencyTotal is a user's confirmed spending in one category and
+// currency. ConvertedAmount sums the conversions stored with those
+// expenses into ConvertedCurrency, which is empty when none was stored.
+type CategoryCurrencyTotal struct {
+	CategoryID        int
+	Currency          string
+	Amount            decimal.Decimal
+	ConvertedCurrency string
+	ConvertedAmount   decimal.Decimal
+}
+
+// GetCategoryCurrencyTotalsByUserIDAndDateRange returns a user's confirmed
+// spending in a date range summed per category, currency and stored
+// conversion. Uncategorized expenses and reimbursable ones not yet paid
+// back are left out.
+func (r *ExpenseRepository) GetCategoryCurrencyTotalsByUserIDAndDateRange(
+	ctx context.Context,
+	userID int64,
+	startDate, endDate time.Time,
+) ([]CategoryCurrencyTotal, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT category_id, currency, SUM(amount),
+			CASE WHEN converted_amount IS NULL THEN '' ELSE converted_currency END AS conv_currency,
+			COALESCE(SUM(converted_amount), 0)
+		FROM expenses
+		WHERE user_id = $1 AND category_id IS NOT NULL AND created_at >= $2 AND created_at < $3
+		  AND status = 'confirmed' AND NOT (reimbursable AND reimbursed_at IS NULL)
+		GROUP BY category_id, currency, conv_currency
+		ORDER BY category_id, currency, conv_currency
+	`, userID, startDate, endDate)
 	if err != nil {
-		return false, fmt.Errorf("failed to check expenses for date: %w", err)
+		return nil, fmt.Errorf("failed to query category totals: %w", err)
 	}
-	return exists, nil
-}
+	defer rows.Close()
 
-// scanExpenses is a helper to scan expense rows with category joins.
-func scanExpenses(rows interface {
-	Next() bool
-	Scan(dest ...any) error
-	Err() error
-},
-) ([]models.Expense, error) {
-	var expenses []models.Expense
+	var totals []CategoryCurrencyTotal
 	for rows.Next() {
-		var exp models.Expense
-		var categoryID, catID *int
-		var catName *string
-		var catCreatedAt *time.Time
-
+		var total CategoryCurrencyTotal
 		if err := rows.Scan(
-			&exp.ID, &exp.UserExpenseNumber, &exp.UserID, &exp.Amount, &exp.Currency, &exp.Description,
-			&exp.Merchant, &categoryID, &exp.ReceiptFileID, &exp.Status, &exp.CreatedAt, &exp.UpdatedAt,
-			&catID, &catName, &catCreatedAt,
+			&total.CategoryID, &total.Currency, &total.Amount, &total.ConvertedCurrency, &total.ConvertedAmount,
 		); err != nil {
-			return nil, fmt.Errorf("failed to scan expense: %w", err)
+			return nil, fmt.Errorf("failed to scan category total: %w", err)
 		}
+		totals = append(totals, total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate category totals: %w", err)
+	}
+	return totals, nil
+}
 
-		exp.CategoryID = categoryID
-		if catID != nil {
-			exp.Category = &models.Category{
-				ID:        *catID,
-				Name:      *catName,
-				CreatedAt: *catCreatedAt,
-			}
+// MonthlyCategoryCurrencyTotal is a CategoryCurrencyTotal for one month,
+// keyed as "2006-01".
+type MonthlyCategoryCurrencyTotal struct {
+	Month string
+	CategoryCurrencyTotal
+}
+
+// GetMonthlyCategoryCurrencyTotals returns a user's confirmed spending in
+// a date range summed per calendar month in timezone, category, currency
+// and stored conversion, with the same expenses left out as in
+// GetCategoryCurrencyTotalsByUserIDAndDateRange.
+func (r *ExpenseRepository) GetMonthlyCategoryCurrencyTotals(
+	ctx context.Context,
+	userID int64,
+	startDate, endDate time.Time,
+	timezone string,
+) ([]MonthlyCategoryCurrencyTotal, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT TO_CHAR(created_at AT TIME ZONE $4, 'YYYY-MM') AS month, category_id, currency, SUM(amount),
+			CASE WHEN converted_amount IS NULL THEN '' ELSE converted_currency END AS conv_currency,
+			COALESCE(SUM(converted_amount), 0)
+		FROM expenses
+		WHERE user_id = $1 AND category_id IS NOT NULL AND created_at >= $2 AND created_at < $3
+		  AND status = 'confirmed' AND NOT (reimbursable AND reimbursed_at IS NULL)
+		GROUP BY month, category_id, currency, conv_currency
+		ORDER BY month, category_id, currency, conv_currency
+	`, userID, startDate, endDate, timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query monthly category totals: %w", err)
+	}
+	defer rows.Close()
+
+	var totals []MonthlyCategoryCurrencyTotal
+	for rows.Next() {
+		var total MonthlyCategoryCurrencyTotal
+		if err := rows.Scan(
+			&total.Month, &total.CategoryID, &total.Currency, &total.Amount,
+			&total.ConvertedCurrency, &total.ConvertedAmount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan monthly category total: %w", err)
 		}
-		expenses = append(expenses, exp)
+		totals = append(totals, total)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating expenses: %w", err)
+		return nil, fmt.Errorf("failed to iterate monthly category totals: %w", err)
 	}
-	return expenses, nil
+	return totals, nil
 }
 
-// scanExpensesWithReflection scans the wider projection used by reflection
-// queries. It remains separate from scanExpenses because their selected column
-// layouts differ.
-func scanExpensesWithReflection(rows interface {
-	Next() bool
-	Scan(dest ...any) error
-	Err() error
-},
-) ([]models.Expense, error) {
-	var expenses []models.Expense
-	for rows.Next() {
-		var exp models.Expense
+// ConfidenceBand is a half-open range [Min, Max) of AI confidence scores.
+type ConfidenceBand struct {
+	Min, Max float64
+}
+
+// bounds returns the band limits; a nil band matches nothing.
+func (b *ConfidenceBand) bounds() (lower, upper float64) {
+	if b == nil {
+		return 0, 0
+	}
+	return b.Min, b.Max
+}
+
+// uncategorizedFilter matches confirmed expenses without a category, plus
+// AI-categorized ones whose confidence lies in [$3, $4).
+const uncategorizedFilter = `e.user_id = $1 AND e.created_at >= $2 AND e.status = 'confirmed'
+		  AND (e.category_id IS NULL
+		       OR (e.ai_categorized AND e.ai_confidence >= $3 AND e.ai_confidence < $4))`
+
+// GetUncategorizedByUserID retrieves confirmed expenses without a category
+// created at or after since, largest amount first, equal amounts by
+// descending ID. When uncertain is set, expenses Gemini categorized with a
+// confidence in that band are included.
+func (r *ExpenseRepository) GetUncategorizedByUserID(
+	ctx context.Context,
+	userID int64,
+	since time.Time,
+	uncertain *ConfidenceBand,
+	limit, offset int,
+) ([]models.Expense, error) {
+	lower, upper := uncertain.bounds()
+	rows, err := r.db.Query(ctx, `
+		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
+		       e.receipt_file_id, e.status, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
+		       c.id, c.name, c.created_at
+		FROM expenses e
+		LEFT JOIN categories c ON e.category_id = c.id
+		WHERE `+uncategorizedFilter+`
+		ORDER BY e.amount DESC, e.id DESC
+		LIMIT $5 OFFSET $6
+	`, userID, since, lower, upper, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query uncategorized expenses: %w", err)
+	}
+	defer rows.Close()
+
+	return scanExpenses(rows)
+}
+
+// CountUncategorizedByUserID counts the expenses GetUncategorizedByUserID
+// returns.
+func (r *ExpenseRepository) CountUncategorizedByUserID(
+	ctx context.Context,
+	userID int64,
+	since time.Time,
+	uncertain *ConfidenceBand,
+) (int, error) {
+	lower, upper := uncertain.bounds()
+	var count int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM expenses e
+		WHERE `+uncategorizedFilter,
+		userID, since, lower, upper).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count uncategorized expenses: %w", err)
+	}
+	return count, nil
+}
+
+// aiReviewFilter matches confirmed expenses created at or after $2 whose
+// category Gemini picked and the user has not reviewed yet.
+const aiReviewFilter = `e.user_id = $1 AND e.created_at >= $2 AND e.status = 'confirmed' AND e.ai_categorized`
+
+// GetAICategorizedByUserID retrieves confirmed expenses created at or after
+// since that still carry the ai_categorized flag, ordered by category name
+// and then oldest first.
+func (r *ExpenseRepository) GetAICategorizedByUserID(
+	ctx context.Context,
+	userID int64,
+	since time.Time,
+	limit int,
+) ([]models.Expense, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
+		       e.receipt_file_id, e.status, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
+		       c.id, c.name, c.created_at
+		FROM expenses e
+		LEFT JOIN categories c ON e.category_id = c.id
+		WHERE `+aiReviewFilter+`
+		ORDER BY c.name NULLS LAST, e.created_at, e.id
+		LIMIT $3
+	`, userID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query AI-categorized expenses: %w", err)
+	}
+	defer rows.Close()
+
+	return scanExpenses(rows)
+}
+
+// CountAICategorizedByUserID counts the expenses GetAICategorizedByUserID
+// returns without a limit.
+func (r *ExpenseRepository) CountAICategorizedByUserID(
+	ctx context.Context,
+	userID int64,
+	since time.Time,
+) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM expenses e
+		WHERE `+aiReviewFilter,
+		userID, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count AI-categorized expenses: %w", err)
+	}
+	return count, nil
+}
+
+// ClearAICategorized marks the AI-picked category of one of the user's
+// expenses as reviewed. It reports whether the expense was still flagged.
+func (r *ExpenseRepository) ClearAICategorized(ctx context.Context, userID int64, expenseID int) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE expenses SET ai_categorized = FALSE, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND ai_categorized
+	`, expenseID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to clear AI-categorized flag: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// AIAcceptance counts a user's expenses whose category Gemini picked, and
+// how many of them still have that category.
+type AIAcceptance struct {
+	Accepted int
+	Total    int
+}
+
+// GetAIAcceptance counts the confirmed expenses created at or after since
+// whose category Gemini picked, and those whose category was not changed
+// since.
+func (r *ExpenseRepository) GetAIAcceptance(ctx context.Context, userID int64, since time.Time) (AIAcceptance, error) {
+	var acceptance AIAcceptance
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE category_id = ai_category_id), COUNT(*)
+		FROM expenses
+		WHERE user_id = $1 AND created_at >= $2 AND status = 'confirmed' AND ai_category_id IS NOT NULL
+	`, userID, since).Scan(&acceptance.Accepted, &acceptance.Total)
+	if err != nil {
+		return AIAcceptance{}, fmt.Errorf("failed to count AI category acceptance: %w", err)
+	}
+	return acceptance, nil
+}
+
+// SpendingPatternTotal is a user's confirmed spending in one local weekday,
+// hour and currency. Currency is the stored conversion's currency when
+// there is one, as in models.Expense.AmountInDefault.
+type SpendingPatternTotal struct {
+	Weekday  time.Weekday
+	Hour     int
+	Currency string
+	Count    int
+	Amount   decimal.Decimal
+}
+
+// GetSpendingPatternTotals returns a user's confirmed spending in
+// [startDate, endDate) summed per weekday, hour and currency, all taken in
+// timezone, an IANA name such as "Asia/Singapore". Reimbursable expenses
+// not yet paid back are left out.
+func (r *ExpenseRepository) GetSpendingPatternTotals(
+	ctx context.Context,
+	userID int64,
+	startDate, endDate time.Time,
+	timezone string,
+) ([]SpendingPatternTotal, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT EXTRACT(DOW FROM created_at AT TIME ZONE $4)::int AS dow,
+			EXTRACT(HOUR FROM created_at AT TIME ZONE $4)::int AS hour,
+			CASE WHEN converted_amount IS NULL THEN currency ELSE converted_currency END AS total_currency,
+			COUNT(*), SUM(COALESCE(converted_amount, amount))
+		FROM expenses
+		WHERE user_id = $1 AND created_at >= $2 AND created_at < $3 AND status = 'confirmed'
+		  AND NOT (reimbursable AND reimbursed_at IS NULL)
+		GROUP BY dow, hour, total_currency
+		ORDER BY dow, hour, total_currency
+	`, userID, startDate, endDate, timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query spending patterns: %w", err)
+	}
+	defer rows.Close()
+
+	var totals []SpendingPatternTotal
+	for rows.Next() {
+		var total SpendingPatternTotal
+		var weekday int
+		if err := rows.Scan(&weekday, &total.Hour, &total.Currency, &total.Count, &total.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan spending pattern: %w", err)
+		}
+		total.Weekday = time.Weekday(weekday)
+		totals = append(totals, total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate spending patterns: %w", err)
+	}
+	return totals, nil
+}
+
+// MonthlyCategoryTotal is a user's confirmed spending in one local month,
+// category and currency. Category is empty for uncategorized expenses;
+// Currency is the stored conversion's currency when there is one, as in
+// models.Expense.AmountInDefault.
+type MonthlyCategoryTotal struct {
+	Year     int
+	Month    time.Month
+	Category string
+	Currency string
+	Count    int
+	Amount   decimal.Decimal
+}
+
+// GetMonthlyCategoryTotals returns a user's confirmed spending in
+// [startDate, endDate) summed per month, category and currency, with
+// months taken in timezone, an IANA name such as "Asia/Singapore".
+// Reimbursable expenses not yet paid back are left out.
+func (r *ExpenseRepository) GetMonthlyCategoryTotals(
+	ctx context.Context,
+	userID int64,
+	startDate, endDate time.Time,
+	timezone string,
+) ([]MonthlyCategoryTotal, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT EXTRACT(YEAR FROM e.created_at AT TIME ZONE $4)::int AS year,
+			EXTRACT(MONTH FROM e.created_at AT TIME ZONE $4)::int AS month,
+			COALESCE(c.name, '') AS category,
+			CASE WHEN e.converted_amount IS NULL THEN e.currency ELSE e.converted_currency END AS total_currency,
+			COUNT(*), SUM(COALESCE(e.converted_amount, e.amount))
+		FROM expenses e
+		LEFT JOIN categories c ON e.category_id = c.id
+		WHERE e.user_id = $1 AND e.created_at >= $2 AND e.created_at < $3 AND e.status = 'confirmed'
+		  AND NOT (e.reimbursable AND e.reimbursed_at IS NULL)
+		GROUP BY year, month, category, total_currency
+		ORDER BY year, month, category, total_currency
+	`, userID, startDate, endDate, timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query monthly category totals: %w", err)
+	}
+	defer rows.Close()
+
+	var totals []MonthlyCategoryTotal
+	for rows.Next() {
+		var total MonthlyCategoryTotal
+		var month int
+		if err := rows.Scan(
+			&total.Year, &month, &total.Category, &total.Currency, &total.Count, &total.Amount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan monthly category total: %w", err)
+		}
+		total.Month = time.Month(month)
+		totals = append(totals, total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate monthly category totals: %w", err)
+	}
+	return totals, nil
+}
+
+// GetLargestByUserIDAndDateRange returns a user's largest confirmed expense
+// in [startDate, endDate) counted in currency, as in
+// models.Expense.AmountInDefault. The error wraps pgx.ErrNoRows when there
+// is none.
+func (r *ExpenseRepository) GetLargestByUserIDAndDateRange(
+	ctx context.Context,
+	userID int64,
+	startDate, endDate time.Time,
+	currency string,
+) (*models.Expense, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
+		       e.receipt_file_id, e.status, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
+		       c.id, c.name, c.created_at
+		FROM expenses e
+		LEFT JOIN categories c ON e.category_id = c.id
+		WHERE e.user_id = $1 AND e.created_at >= $2 AND e.created_at < $3 AND e.status = 'confirmed'
+		  AND NOT (e.reimbursable AND e.reimbursed_at IS NULL)
+		  AND CASE WHEN e.converted_amount IS NULL THEN e.currency ELSE e.converted_currency END = $4
+		ORDER BY COALESCE(e.converted_amount, e.amount) DESC, e.id
+		LIMIT 1
+	`, userID, startDate, endDate, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query largest expense: %w", err)
+	}
+	defer rows.Close()
+
+	expenses, err := scanExpenses(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(expenses) == 0 {
+		return nil, fmt.Errorf("no expenses in range: %w", pgx.ErrNoRows)
+	}
+	return &expenses[0], nil
+}
+
+// SetReimbursable marks a user's expense reimbursable or not. Turning it
+// off also clears the reimbursement date. It reports whether the expense
+// was found.
+func (r *ExpenseRepository) SetReimbursable(
+	ctx context.Context,
+	userID int64,
+	expenseID int,
+	reimbursable bool,
+) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE expenses
+		SET reimbursable = $3,
+			reimbursed_at = CASE WHEN $3 THEN reimbursed_at END,
+			updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`, expenseID, userID, reimbursable)
+	if err != nil {
+		return false, fmt.Errorf("failed to set reimbursable: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MarkReimbursed records that a user's reimbursable expense was paid back
+// at the given time. It reports whether an outstanding expense was
+// updated.
+func (r *ExpenseRepository) MarkReimbursed(
+	ctx context.Context,
+	userID int64,
+	expenseID int,
+	at time.Time,
+) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE expenses SET reimbursed_at = $3, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND reimbursable AND reimbursed_at IS NULL
+	`, expenseID, userID, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark expense reimbursed: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetOutstandingReimbursements retrieves a user's confirmed reimbursable
+// expenses that were not paid back yet, oldest first.
+func (r *ExpenseRepository) GetOutstandingReimbursements(
+	ctx context.Context,
+	userID int64,
+	limit int,
+) ([]models.Expense, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
+		       e.receipt_file_id, e.status, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
+		       c.id, c.name, c.created_at
+		FROM expenses e
+		LEFT JOIN categories c ON e.category_id = c.id
+		WHERE e.user_id = $1 AND e.status = 'confirmed' AND e.reimbursable AND e.reimbursed_at IS NULL
+		ORDER BY e.created_at, e.id
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outstanding reimbursements: %w", err)
+	}
+	defer rows.Close()
+
+	return scanExpenses(rows)
+}
+
+// GetDuplicateCandidates retrieves confirmed expenses in [startDate, endDate)
+// that share amount, currency and local day with at least one other expense
+// of the user. The local day is taken in the named timezone, so days on
+// either side of a DST change group correctly. Results are ordered so that
+// expenses of one candidate group are adjacent.
+func (r *ExpenseRepository) GetDuplicateCandidates(
+	ctx context.Context,
+	userID int64,
+	startDate, endDate time.Time,
+	timezone string,
+) ([]models.Expense, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT d.id, d.user_expense_number, d.user_id, d.amount, d.currency, d.description, d.merchant, d.category_id,
+		       d.receipt_file_id, d.status, d.created_at, d.updated_at,
+		       d.rate_to_default, d.converted_amount, d.converted_currency, d.reimbursable, d.reimbursed_at, d.source,
+		       c.id, c.name, c.created_at
+		FROM (
+			SELECT e.*,
+			       (e.created_at AT TIME ZONE $4)::date AS local_day,
+			       COUNT(*) OVER (
+			           PARTITION BY e.amount, e.currency, (e.created_at AT TIME ZONE $4)::date
+			       ) AS group_size
+			FROM expenses e
+			WHERE e.user_id = $1 AND e.created_at >= $2 AND e.created_at < $3 AND e.status = 'confirmed'
+		) d
+		LEFT JOIN categories c ON d.category_id = c.id
+		WHERE d.group_size > 1
+		ORDER BY d.local_day, d.amount, d.currency, d.id
+	`, userID, startDate, endDate, timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicate candidates: %w", err)
+	}
+	defer rows.Close()
+
+	return scanExpenses(rows)
+}
+
+// DeleteByUserAndIDs deletes the user's expenses with the given IDs and
+// returns how many were removed. IDs owned by other users are ignored.
+func (r *ExpenseRepository) DeleteByUserAndIDs(ctx context.Context, userID int64, ids []int) (int64, error) {
+	result, err := r.db.Exec(ctx, `
+		DELETE FROM expenses WHERE user_id = $1 AND id = ANY($2)
+	`, userID, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expenses: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// MissingConversion is a confirmed expense kept in a currency other than
+// its owner's default currency and without a stored conversion, as found by
+// ListMissingConversions.
+type MissingConversion struct {
+	ExpenseID       int
+	Amount          decimal.Decimal
+	Currency        string
+	DefaultCurrency string
+	CreatedAt       time.Time
+}
+
+// ListMissingConversions retrieves up to limit expenses of any user that
+// lack a stored conversion to the owner's default currency, with an ID
+// above afterID, in ID order.
+func (r *ExpenseRepository) ListMissingConversions(
+	ctx context.Context,
+	afterID, limit int,
+) ([]MissingConversion, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.id, e.amount, e.currency, u.default_currency, e.created_at
+		FROM expenses e
+		JOIN users u ON u.id = e.user_id
+		WHERE e.status = $1 AND e.id > $2
+		  AND e.converted_amount IS NULL AND e.currency <> u.default_currency
+		ORDER BY e.id
+		LIMIT $3
+	`, models.ExpenseStatusConfirmed, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expenses missing conversions: %w", err)
+	}
+	defer rows.Close()
+
+	var missing []MissingConversion
+	for rows.Next() {
+		var m MissingConversion
+		if err := rows.Scan(&m.ExpenseID, &m.Amount, &m.Currency, &m.DefaultCurrency, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan expense missing conversion: %w", err)
+		}
+		missing = append(missing, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate expenses missing conversions: %w", err)
+	}
+	return missing, nil
+}
+
+// SetConversion stores the conversion of an expense to currency. Expenses
+// that already have a stored conversion are left alone; the result reports
+// whether the expense was updated.
+func (r *ExpenseRepository) SetConversion(
+	ctx context.Context,
+	expenseID int,
+	rate, converted decimal.Decimal,
+	currency string,
+) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE expenses
+		SET rate_to_default = $2, converted_amount = $3, converted_currency = $4
+		WHERE id = $1 AND converted_amount IS NULL
+	`, expenseID, rate, converted, currency)
+	if err != nil {
+		return false, fmt.Errorf("failed to set expense conversion: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// NullifyCategoryOnExpenses sets category_id to NULL for all expenses
+// referencing the given category. This must be called before deleting
+// a category to avoid FK constraint violations. Returns the number of
+// affected rows.
+func (r *ExpenseRepository) NullifyCategoryOnExpenses(ctx context.Context, categoryID int) (int64, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE expenses SET category_id = NULL, ai_categorized = FALSE, updated_at = NOW()
+		WHERE category_id = $1
+	`, categoryID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to nullify category on expenses: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// NullifyCategoryForUser sets category_id to NULL on one user's expenses
+// in the given category, e.g. when they hide a shared category. Returns
+// the number of affected rows.
+func (r *ExpenseRepository) NullifyCategoryForUser(ctx context.Context, userID int64, categoryID int) (int64, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE expenses SET category_id = NULL, ai_categorized = FALSE, updated_at = NOW()
+		WHERE user_id = $1 AND category_id = $2
+	`, userID, categoryID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to nullify category on user expenses: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// likePattern turns a plain substring into an ILIKE pattern, escaping the
+// LIKE wildcards so they match literally.
+func likePattern(substring string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(substring)
+	return "%" + escaped + "%"
+}
+
+// GetByDescriptionLike retrieves a user's confirmed expenses whose
+// description or merchant contains substring (case-insensitive) and that are
+// not already in categoryID, newest first.
+func (r *ExpenseRepository) GetByDescriptionLike(
+	ctx context.Context,
+	userID int64,
+	substring string,
+	categoryID int,
+) ([]models.Expense, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.id, e.user_expense_number, e.user_id, e.amount, e.currency, e.description, e.merchant, e.category_id,
+		       e.receipt_file_id, e.status, e.created_at, e.updated_at,
+		       e.rate_to_default, e.converted_amount, e.converted_currency, e.reimbursable, e.reimbursed_at, e.source,
+		       c.id, c.name, c.created_at
+		FROM expenses e
+		LEFT JOIN categories c ON e.category_id = c.id
+		WHERE e.user_id = $1 AND e.status = 'confirmed'
+		  AND (e.category_id IS NULL OR e.category_id <> $2)
+		  AND (e.description ILIKE $3 OR e.merchant ILIKE $3)
+		ORDER BY e.created_at DESC, e.id DESC
+	`, userID, categoryID, likePattern(substring))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expenses by description: %w", err)
+	}
+	defer rows.Close()
+
+	return scanExpenses(rows)
+}
+
+// RecategorizedExpense is one expense moved by
+// UpdateCategoryWhereDescriptionLike. OldCategory is the name of its
+// previous category, "" for uncategorized.
+type RecategorizedExpense struct {
+	ID            int
+	ExpenseNumber int64
+	OldCategory   string
+}
+
+// UpdateCategoryWhereDescriptionLike moves a user's confirmed expenses whose
+// description or merchant contains substring (case-insensitive) into
+// categoryID. It runs as a single statement, so all matches change together,
+// and returns the changed expenses.
+func (r *ExpenseRepository) UpdateCategoryWhereDescriptionLike(
+	ctx context.Context,
+	userID int64,
+	substring string,
+	categoryID int,
+) ([]RecategorizedExpense, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH matched AS (
+			SELECT id, category_id AS old_category_id
+			FROM expenses
+			WHERE user_id = $1 AND status = 'confirmed'
+			  AND (category_id IS NULL OR category_id <> $2)
+			  AND (description ILIKE $3 OR merchant ILIKE $3)
+			FOR UPDATE
+		)
+		UPDATE expenses e
+		SET category_id = $2, ai_categorized = FALSE, updated_at = NOW()
+		FROM matched m
+		LEFT JOIN categories c ON c.id = m.old_category_id
+		WHERE e.id = m.id
+		RETURNING e.id, e.user_expense_number, COALESCE(c.name, '')
+	`, userID, categoryID, likePattern(substring))
+	if err != nil {
+		return nil, fmt.Errorf("failed to recategorize expenses: %w", err)
+	}
+	defer rows.Close()
+
+	var changed []RecategorizedExpense
+	for rows.Next() {
+		var e RecategorizedExpense
+		if err := rows.Scan(&e.ID, &e.ExpenseNumber, &e.OldCategory); err != nil {
+			return nil, fmt.Errorf("failed to scan recategorized expense: %w", err)
+		}
+		changed = append(changed, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate recategorized expenses: %w", err)
+	}
+	return changed, nil
+}
+
+// LinkSourceMessage remembers the chat message an expense was created from,
+// so a later edit of that message can update the expense.
+func (r *ExpenseRepository) LinkSourceMessage(ctx context.Context, expenseID int, chatID int64, messageID int) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO expense_source_messages (chat_id, message_id, expense_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (chat_id, message_id) DO UPDATE SET expense_id = EXCLUDED.expense_id
+	`, chatID, messageID, expenseID)
+	if err != nil {
+		return fmt.Errorf("failed to link source message: %w", err)
+	}
+	return nil
+}
+
+// GetIDBySourceMessage returns the ID of the expense created from a chat
+// message. It wraps pgx.ErrNoRows when the message created no expense or
+// the expense was deleted.
+func (r *ExpenseRepository) GetIDBySourceMessage(ctx context.Context, chatID int64, messageID int) (int, error) {
+	var expenseID int
+	err := r.db.QueryRow(ctx, `
+		SELECT expense_id FROM expense_source_messages WHERE chat_id = $1 AND message_id = $2
+	`, chatID, messageID).Scan(&expenseID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expense by source message: %w", err)
+	}
+	return expenseID, nil
+}
+
+// GetSourceChatID returns the chat an expense was created in. It wraps
+// pgx.ErrNoRows when the expense has no linked source message.
+func (r *ExpenseRepository) GetSourceChatID(ctx context.Context, expenseID int) (int64, error) {
+	var chatID int64
+	err := r.db.QueryRow(ctx, `
+		SELECT chat_id FROM expense_source_messages
+		WHERE expense_id = $1
+		ORDER BY created_at
+		LIMIT 1
+	`, expenseID).Scan(&chatID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get source chat: %w", err)
+	}
+	return chatID, nil
+}
+
+// HasCurrencyHistory reports whether a user has a confirmed expense entered
+// in currency: stored in it, or converted from it, as recorded by the
+// "[orig: 5.50 THB -> ...]" note in the description.
+func (r *ExpenseRepository) HasCurrencyHistory(ctx context.Context, userID int64, currency string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM expenses
+			WHERE user_id = $1 AND status = 'confirmed'
+			  AND (currency = $2 OR description LIKE '%[orig: % ' || $2 || ' -> %')
+		)
+	`, userID, currency).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check currency history: %w", err)
+	}
+	return exists, nil
+}
+
+// HasExpensesForDate checks if a user has any confirmed expenses in the given time range.
+func (r *ExpenseRepository) HasExpensesForDate(ctx context.Context, userID int64, startOfDay, endOfDay time.Time) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM expenses WHERE user_id = $1 AND created_at >= $2 AND created_at < $3 AND status = 'confirmed')
+	`, userID, startOfDay, endOfDay).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check expenses for date: %w", err)
+	}
+	return exists, nil
+}
+
+// CountExpensesForDate counts a user's confirmed expenses in the given time
+// range.
+func (r *ExpenseRepository) CountExpensesForDate(ctx context.Context, userID int64, startOfDay, endOfDay time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM expenses WHERE user_id = $1 AND created_at >= $2 AND created_at < $3 AND status = 'confirmed'
+	`, userID, startOfDay, endOfDay).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count expenses for date: %w", err)
+	}
+	return count, nil
+}
+
+// scanExpenses is a helper to scan expense rows with category joins.
+func scanExpenses(rows interface {
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+},
+) ([]models.Expense, error) {
+	var expenses []models.Expense
+	for rows.Next() {
+		var exp models.Expense
+		var categoryID, catID *int
+		var catName *string
+		var catCreatedAt *time.Time
+
+		if err := rows.Scan(
+			&exp.ID, &exp.UserExpenseNumber, &exp.UserID, &exp.Amount, &exp.Currency, &exp.Description,
+			&exp.Merchant, &categoryID, &exp.ReceiptFileID, &exp.Status, &exp.CreatedAt, &exp.UpdatedAt,
+			&exp.RateToDefault, &exp.ConvertedAmount, &exp.ConvertedCurrency, &exp.Reimbursable, &exp.ReimbursedAt,
+			&exp.Source, &catID, &catName, &catCreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan expense: %w", err)
+		}
+
+		exp.CategoryID = categoryID
+		if catID != nil {
+			exp.Category = &models.Category{
+				ID:        *catID,
+				Name:      *catName,
+				CreatedAt: *catCreatedAt,
+			}
+		}
+		expenses = append(expenses, exp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expenses: %w", err)
+	}
+	return expenses, nil
+}
+
+// scanExpensesWithReflection scans the wider projection used by reflection
+// queries. It remains separate from scanExpenses because their selected column
+// layouts differ.
+func scanExpensesWithReflection(rows interface {
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+},
+) ([]models.Expense, error) {
+	var expenses []models.Expense
+	for rows.Next() {
+		var exp models.Expense
 		var categoryID, catID *int
 		var worthIt *bool
 		var spendDriver *string
@@ -443,7 +1588,8 @@ func scanExpensesWithReflection(rows interface {
 		if err := rows.Scan(
 			&exp.ID, &exp.UserExpenseNumber, &exp.UserID, &exp.Amount, &exp.Currency, &exp.Description,
 			&exp.Merchant, &categoryID, &exp.ReceiptFileID, &exp.Status, &worthIt, &spendDriver, &reviewedAt,
-			&exp.CreatedAt, &exp.UpdatedAt, &catID, &catName, &catCreatedAt,
+			&exp.CreatedAt, &exp.UpdatedAt, &exp.RateToDefault, &exp.ConvertedAmount, &exp.ConvertedCurrency,
+			&exp.Reimbursable, &exp.ReimbursedAt, &exp.Source, &catID, &catName, &catCreatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan expense with reflection: %w", err)
 		}
@@ -466,3 +1612,128 @@ func scanExpensesWithReflection(rows interface {
 	}
 	return expenses, nil
 }
+
+// SetLocation records where an expense happened.
+func (r *ExpenseRepository) SetLocation(ctx context.Context, expenseID int, loc models.ExpenseLocation) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE expenses SET latitude = $2, longitude = $3, location_label = $4, updated_at = NOW()
+		WHERE id = $1
+	`, expenseID, loc.Latitude, loc.Longitude, loc.Label)
+	if err != nil {
+		return fmt.Errorf("failed to set expense location: %w", err)
+	}
+	return nil
+}
+
+// GetLocation returns where an expense happened and whether a location was
+// recorded.
+func (r *ExpenseRepository) GetLocation(ctx context.Context, expenseID int) (models.ExpenseLocation, bool, error) {
+	var lat, lon *float64
+	var loc models.ExpenseLocation
+	err := r.db.QueryRow(ctx, `
+		SELECT latitude, longitude, location_label FROM expenses WHERE id = $1
+	`, expenseID).Scan(&lat, &lon, &loc.Label)
+	if err != nil {
+		return models.ExpenseLocation{}, false, fmt.Errorf("failed to get expense location: %w", err)
+	}
+	if lat == nil || lon == nil {
+		return models.ExpenseLocation{}, false, nil
+	}
+	loc.Latitude, loc.Longitude = *lat, *lon
+	return loc, true, nil
+}
+
+// GetLatestIDInChat returns the ID of a user's most recent expense created
+// since the given time, leaving out expenses sent from another chat. It
+// wraps pgx.ErrNoRows when there is none.
+func (r *ExpenseRepository) GetLatestIDInChat(
+	ctx context.Context,
+	userID, chatID int64,
+	since time.Time,
+) (int, error) {
+	var expenseID int
+	err := r.db.QueryRow(ctx, `
+		SELECT e.id FROM expenses e
+		WHERE e.user_id = $1 AND e.created_at >= $3
+		  AND NOT EXISTS (
+			SELECT 1 FROM expense_source_messages s WHERE s.expense_id = e.id AND s.chat_id != $2
+		  )
+		ORDER BY e.created_at DESC, e.id DESC
+		LIMIT 1
+	`, userID, chatID, since).Scan(&expenseID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest expense in chat: %w", err)
+	}
+	return expenseID, nil
+}
+
+// ExpenseUsage counts the expense rows of one user, or of every user.
+// ExpiredDrafts are drafts the next draft cleanup deletes, and Receipts are
+// confirmed expenses with a receipt photo. Oldest is the date of the oldest
+// confirmed expense, zero when there is none.
+type ExpenseUsage struct {
+	Users         int
+	Expenses      int
+	Drafts        int
+	ExpiredDrafts int
+	Receipts      int
+	Oldest        time.Time
+}
+
+// UsageStats counts the expenses of userID, or of every user when userID
+// is zero. Drafts created before draftCutoff count as expired.
+func (r *ExpenseRepository) UsageStats(
+	ctx context.Context,
+	userID int64,
+	draftCutoff time.Time,
+) (*ExpenseUsage, error) {
+	usage := &ExpenseUsage{}
+	var oldest *time.Time
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT user_id),
+			COUNT(*) FILTER (WHERE status = $2),
+			COUNT(*) FILTER (WHERE status = $3),
+			COUNT(*) FILTER (WHERE status = $3 AND created_at < $4),
+			COUNT(*) FILTER (WHERE status = $2 AND COALESCE(receipt_file_id, '') <> ''),
+			MIN(created_at) FILTER (WHERE status = $2)
+		FROM expenses
+		WHERE $1::BIGINT = 0 OR user_id = $1
+	`, userID, models.ExpenseStatusConfirmed, models.ExpenseStatusDraft, draftCutoff).
+		Scan(&usage.Users, &usage.Expenses, &usage.Drafts, &usage.ExpiredDrafts, &usage.Receipts, &oldest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expense usage: %w", err)
+	}
+	if oldest != nil {
+		usage.Oldest = *oldest
+	}
+	return usage, nil
+}
+
+// CountBySource counts the confirmed expenses of userID, or of every user
+// when userID is zero, by the way they entered the bot.
+func (r *ExpenseRepository) CountBySource(ctx context.Context, userID int64) (map[models.ExpenseSource]int, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT source, COUNT(*)
+		FROM expenses
+		WHERE ($1::BIGINT = 0 OR user_id = $1) AND status = $2
+		GROUP BY source
+	`, userID, models.ExpenseStatusConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count expenses by source: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[models.ExpenseSource]int)
+	for rows.Next() {
+		var source models.ExpenseSource
+		var count int
+		if err := rows.Scan(&source, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan source count: %w", err)
+		}
+		counts[source] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate source counts: %w", err)
+	}
+	return counts, nil
+}
@@ -111,7 +111,7 @@ func TestExpenseRepository_GetByUserIDAndCategory(t *testing.T) {
 	})
 }
 
-func TestExpenseRepository_GetTotalByUserIDAndCategory(t *testing.T) {
+func TestExpenseRepository_GetTotalsByUserIDAndCategory(t *testing.T) {
 	ctx := context.Background()
 	tx := dbtest.TestTx(ctx, t)
 	repo := NewExpenseRepository(tx)
@@ -161,26 +161,48 @@ func TestExpenseRepository_GetTotalByUserIDAndCategory(t *testing.T) {
 	require.NoError(t, err)
 
 	t.Run("calculates total correctly", func(t *testing.T) {
-		total, err := repo.GetTotalByUserIDAndCategory(ctx, userID, category.ID)
+		totals, err := repo.GetTotalsByUserIDAndCategory(ctx, userID, category.ID)
 		require.NoError(t, err)
+		require.Len(t, totals, 1)
 		expected := decimal.NewFromFloat(expectedTotal)
-		require.True(t, expected.Equal(total), "expected %s, got %s", expected, total)
+		require.True(t, expected.Equal(totals[testCurrencySGD]), "expected %s, got %s", expected, totals[testCurrencySGD])
+	})
+
+	t.Run("keeps currencies apart", func(t *testing.T) {
+		eurCategory, err := catRepo.Create(ctx, "Euro Total Category")
+		require.NoError(t, err)
+		for _, currency := range []string{"EUR", testCurrencySGD} {
+			require.NoError(t, repo.Create(ctx, &models.Expense{
+				UserID:      userID,
+				Amount:      decimal.NewFromInt(10),
+				Currency:    currency,
+				Description: "Test",
+				CategoryID:  &eurCategory.ID,
+				Status:      models.ExpenseStatusConfirmed,
+			}))
+		}
+
+		totals, err := repo.GetTotalsByUserIDAndCategory(ctx, userID, eurCategory.ID)
+		require.NoError(t, err)
+		require.Len(t, totals, 2)
+		require.True(t, totals["EUR"].Equal(decimal.NewFromInt(10)))
+		require.True(t, totals[testCurrencySGD].Equal(decimal.NewFromInt(10)))
 	})
 
 	t.Run("returns zero for empty category", func(t *testing.T) {
 		emptyCategory, err := catRepo.Create(ctx, "Empty Total Category")
 		require.NoError(t, err)
 
-		total, err := repo.GetTotalByUserIDAndCategory(ctx, userID, emptyCategory.ID)
+		totals, err := repo.GetTotalsByUserIDAndCategory(ctx, userID, emptyCategory.ID)
 		require.NoError(t, err)
-		require.True(t, total.IsZero())
+		require.Empty(t, totals)
 	})
 
 	t.Run("filters by user ID", func(t *testing.T) {
 		differentUserID := int64(800004)
-		total, err := repo.GetTotalByUserIDAndCategory(ctx, differentUserID, category.ID)
+		totals, err := repo.GetTotalsByUserIDAndCategory(ctx, differentUserID, category.ID)
 		require.NoError(t, err)
-		require.True(t, total.IsZero(), "should return zero for different user")
+		require.Empty(t, totals, "should return nothing for different user")
 	})
 }
 
@@ -240,3 +262,78 @@ func TestExpenseRepository_CategoryFilterEdgeCases(t *testing.T) {
 		require.Equal(t, ids[0], expenses[2].ID, "oldest expense should be last")
 	})
 }
+
+func TestLikePattern(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "%grab%", likePattern("grab"))
+	require.Equal(t, `%50\% off\_sale\\x%`, likePattern(`50% off_sale\x`))
+}
+
+func TestExpenseRepository_UpdateCategoryWhereDescriptionLike(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+	repo := NewExpenseRepository(tx)
+	catRepo := NewCategoryRepository(tx)
+	userRepo := NewUserRepository(tx)
+
+	userID := int64(373001)
+	otherUserID := int64(373002)
+	for _, id := range []int64{userID, otherUserID} {
+		require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: id, FirstName: "Recat"}))
+	}
+
+	transport, err := catRepo.Create(ctx, "Recat Transport")
+	require.NoError(t, err)
+	food, err := catRepo.Create(ctx, "Recat Food")
+	require.NoError(t, err)
+
+	create := func(uid int64, description, merchant string, categoryID *int, status models.ExpenseStatus) {
+		require.NoError(t, repo.Create(ctx, &models.Expense{
+			UserID:      uid,
+			Amount:      decimal.NewFromFloat(12.30),
+			Currency:    "SGD",
+			Description: description,
+			Merchant:    merchant,
+			CategoryID:  categoryID,
+			Status:      status,
+		}))
+	}
+	create(userID, "GRAB to airport", "", nil, models.ExpenseStatusConfirmed)
+	create(userID, "Lunch", "GrabFood", &food.ID, models.ExpenseStatusConfirmed)
+	create(userID, "grab home", "", &transport.ID, models.ExpenseStatusConfirmed)
+	create(userID, "grab draft", "", nil, models.ExpenseStatusDraft)
+	create(userID, "Coffee", "", nil, models.ExpenseStatusConfirmed)
+	create(otherUserID, "grab other user", "", nil, models.ExpenseStatusConfirmed)
+
+	t.Run("preview finds only movable matches", func(t *testing.T) {
+		matches, err := repo.GetByDescriptionLike(ctx, userID, "grab", transport.ID)
+		require.NoError(t, err)
+		require.Len(t, matches, 2)
+	})
+
+	t.Run("update reports previous categories", func(t *testing.T) {
+		changed, err := repo.UpdateCategoryWhereDescriptionLike(ctx, userID, "grab", transport.ID)
+		require.NoError(t, err)
+		oldCategories := make([]string, len(changed))
+		for i := range changed {
+			require.Positive(t, changed[i].ExpenseNumber)
+			oldCategories[i] = changed[i].OldCategory
+		}
+		require.ElementsMatch(t, []string{"", "Recat Food"}, oldCategories)
+
+		inTransport, err := repo.GetByUserIDAndCategory(ctx, userID, transport.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, inTransport, 3)
+
+		others, err := repo.GetByDescriptionLike(ctx, otherUserID, "grab", transport.ID)
+		require.NoError(t, err)
+		require.Len(t, others, 1, "other users' expenses are untouched")
+	})
+
+	t.Run("wildcards match literally", func(t *testing.T) {
+		matches, err := repo.GetByDescriptionLike(ctx, userID, "c_ffee", transport.ID)
+		require.NoError(t, err)
+		require.Empty(t, matches)
+	})
+}
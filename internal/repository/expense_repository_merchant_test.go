@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+func TestMerchantKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		merchant string
+		want     string
+	}{
+		{"Din Tai Fung", "din tai fung"},
+		{"  din   TAI\tfung ", "din tai fung"},
+		{"DinTaiFung", "dintaifung"},
+		{"Café Über", "café über"},
+		{"", ""},
+		{" \t ", ""},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, merchantKey(tt.merchant), "%q", tt.merchant)
+	}
+}
+
+func TestExpenseRepository_GetByUserAndMerchant(t *testing.T) {
+	expenseRepo, userRepo, _, ctx := setupExpenseTest(t)
+
+	const userID, otherUserID = int64(12451), int64(12452)
+	for _, id := range []int64{userID, otherUserID} {
+		require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: id, FirstName: testFirstName}))
+	}
+
+	add := func(userID int64, merchant, amount, currency string, status models.ExpenseStatus) *models.Expense {
+		t.Helper()
+		expense := &models.Expense{
+			UserID:      userID,
+			Amount:      decimal.RequireFromString(amount),
+			Currency:    currency,
+			Description: "Dumplings",
+			Merchant:    merchant,
+			Status:      status,
+		}
+		require.NoError(t, expenseRepo.Create(ctx, expense))
+		return expense
+	}
+
+	add(userID, "Din Tai Fung", "10.00", testCurrencySGD, models.ExpenseStatusConfirmed)
+	add(userID, "  din  tai FUNG ", "20.50", testCurrencySGD, models.ExpenseStatusConfirmed)
+	add(userID, "din tai fung", "7.00", "USD", models.ExpenseStatusConfirmed)
+	latest := add(userID, "DIN TAI FUNG", "4.00", testCurrencySGD, models.ExpenseStatusConfirmed)
+	add(userID, "Din Tai Fung", "99.00", testCurrencySGD, models.ExpenseStatusDraft)
+	add(userID, "Din Tai Fung Express", "99.00", testCurrencySGD, models.ExpenseStatusConfirmed)
+	add(otherUserID, "Din Tai Fung", "99.00", testCurrencySGD, models.ExpenseStatusConfirmed)
+
+	t.Run("matches ignoring case and whitespace", func(t *testing.T) {
+		expenses, err := expenseRepo.GetByUserAndMerchant(ctx, userID, " din tai   fung", 10)
+		require.NoError(t, err)
+		require.Len(t, expenses, 4, "drafts, other merchants and other users are left out")
+		require.Equal(t, latest.ID, expenses[0].ID, "newest first")
+	})
+
+	t.Run("respects the limit", func(t *testing.T) {
+		expenses, err := expenseRepo.GetByUserAndMerchant(ctx, userID, "Din Tai Fung", 2)
+		require.NoError(t, err)
+		require.Len(t, expenses, 2)
+	})
+
+	t.Run("totals per currency", func(t *testing.T) {
+		totals, err := expenseRepo.GetMerchantTotals(ctx, userID, "DIN TAI FUNG")
+		require.NoError(t, err)
+		require.Len(t, totals, 2)
+		require.Equal(t, testCurrencySGD, totals[0].Currency)
+		require.Equal(t, 3, totals[0].Count)
+		require.Equal(t, "34.50", totals[0].Amount.StringFixed(2))
+		require.Equal(t, "USD", totals[1].Currency)
+		require.Equal(t, 1, totals[1].Count)
+	})
+
+	t.Run("unknown merchant", func(t *testing.T) {
+		expenses, err := expenseRepo.GetByUserAndMerchant(ctx, userID, "Nowhere", 10)
+		require.NoError(t, err)
+		require.Empty(t, expenses)
+
+		totals, err := expenseRepo.GetMerchantTotals(ctx, userID, "Nowhere")
+		require.NoError(t, err)
+		require.Empty(t, totals)
+	})
+}
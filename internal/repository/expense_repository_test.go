@@ -2,9 +2,13 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 	"gitlab.com/yelinaung/expense-bot/internal/models"
@@ -63,6 +67,129 @@ func TestExpenseRepository_Create(t *testing.T) {
 	})
 }
 
+func TestExpenseRepository_Source(t *testing.T) {
+	expenseRepo, userRepo, _, ctx := setupExpenseTest(t)
+
+	userID := int64(455101)
+	require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: userID, FirstName: testFirstName}))
+
+	create := func(source models.ExpenseSource) *models.Expense {
+		expense := &models.Expense{
+			UserID:      userID,
+			Amount:      decimal.NewFromInt(3),
+			Currency:    testCurrencySGD,
+			Description: "Sourced",
+			Source:      source,
+		}
+		require.NoError(t, expenseRepo.Create(ctx, expense))
+		return expense
+	}
+
+	receipt := create(models.ExpenseSourceReceipt)
+	create(models.ExpenseSourceReceipt)
+	unset := create("")
+	require.Equal(t, models.ExpenseSourceUnknown, unset.Source)
+
+	got, err := expenseRepo.GetByID(ctx, receipt.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ExpenseSourceReceipt, got.Source)
+
+	imported := &models.Expense{
+		UserID: userID, Amount: decimal.NewFromInt(4), Currency: testCurrencySGD,
+		Description: "Imported", CreatedAt: time.Now(),
+	}
+	require.NoError(t, expenseRepo.CreateImported(ctx, imported))
+	require.Equal(t, models.ExpenseSourceImport, imported.Source)
+
+	counts, err := expenseRepo.CountBySource(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, map[models.ExpenseSource]int{
+		models.ExpenseSourceReceipt: 2,
+		models.ExpenseSourceUnknown: 1,
+		models.ExpenseSourceImport:  1,
+	}, counts)
+}
+
+func TestExpenseRepository_CreateIdempotent(t *testing.T) {
+	expenseRepo, userRepo, _, ctx := setupExpenseTest(t)
+
+	require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: 443, FirstName: testFirstName}))
+	require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: 444, FirstName: testFirstName}))
+	newExpense := func(userID int64, key, description string) *models.Expense {
+		return &models.Expense{
+			UserID:         userID,
+			Amount:         decimal.NewFromFloat(7.25),
+			Currency:       testCurrencySGD,
+			Description:    description,
+			IdempotencyKey: key,
+		}
+	}
+	count := func(userID int64) int {
+		expenses, err := expenseRepo.GetByUserID(ctx, userID, 100)
+		require.NoError(t, err)
+		return len(expenses)
+	}
+
+	first := newExpense(443, "api:abc", "Taxi")
+	require.NoError(t, expenseRepo.Create(ctx, first))
+	require.NotZero(t, first.ID)
+	require.False(t, first.Replayed)
+
+	t.Run("a retry returns the same row", func(t *testing.T) {
+		retry := newExpense(443, "api:abc", "Taxi, sent again")
+		require.NoError(t, expenseRepo.Create(ctx, retry))
+		require.True(t, retry.Replayed)
+		require.Equal(t, first.ID, retry.ID)
+		require.Equal(t, first.UserExpenseNumber, retry.UserExpenseNumber)
+		require.Equal(t, "Taxi", retry.Description)
+		require.Equal(t, "api:abc", retry.IdempotencyKey)
+		require.Equal(t, 1, count(443))
+	})
+
+	t.Run("a different key creates a new row", func(t *testing.T) {
+		other := newExpense(443, "api:def", "Taxi")
+		require.NoError(t, expenseRepo.Create(ctx, other))
+		require.False(t, other.Replayed)
+		require.NotEqual(t, first.ID, other.ID)
+		require.Equal(t, 2, count(443))
+	})
+
+	t.Run("keys are per user", func(t *testing.T) {
+		other := newExpense(444, "api:abc", "Taxi")
+		require.NoError(t, expenseRepo.Create(ctx, other))
+		require.False(t, other.Replayed)
+		require.Equal(t, 1, count(444))
+	})
+
+	t.Run("expenses without a key never conflict", func(t *testing.T) {
+		require.NoError(t, expenseRepo.Create(ctx, newExpense(444, "", "Bus")))
+		require.NoError(t, expenseRepo.Create(ctx, newExpense(444, "", "Bus")))
+		require.Equal(t, 3, count(444))
+	})
+
+	t.Run("imports honor the key", func(t *testing.T) {
+		imported := newExpense(443, "import:hash:0", "Groceries")
+		imported.CreatedAt = time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+		require.NoError(t, expenseRepo.CreateImported(ctx, imported))
+		require.False(t, imported.Replayed)
+
+		again := newExpense(443, "import:hash:0", "Groceries")
+		again.CreatedAt = imported.CreatedAt
+		require.NoError(t, expenseRepo.CreateImported(ctx, again))
+		require.True(t, again.Replayed)
+		require.Equal(t, imported.ID, again.ID)
+	})
+
+	t.Run("lookup by key", func(t *testing.T) {
+		found, err := expenseRepo.GetByIdempotencyKey(ctx, 443, "api:abc")
+		require.NoError(t, err)
+		require.Equal(t, first.ID, found.ID)
+
+		_, err = expenseRepo.GetByIdempotencyKey(ctx, 443, "api:missing")
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+}
+
 func TestExpenseRepository_GetByID(t *testing.T) {
 	expenseRepo, userRepo, categoryRepo, ctx := setupExpenseTest(t)
 
@@ -178,6 +305,41 @@ func TestExpenseRepository_GetByUserIDAndDateRange(t *testing.T) {
 	})
 }
 
+func TestExpenseRepository_GetByUserIDDateRangeAndCategory(t *testing.T) {
+	expenseRepo, userRepo, categoryRepo, ctx := setupExpenseTest(t)
+
+	user := &models.User{ID: 436, Username: "user436", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, userRepo.UpsertUser(ctx, user))
+	travel, err := categoryRepo.Create(ctx, "Work Travel Range Test")
+	require.NoError(t, err)
+	food, err := categoryRepo.Create(ctx, "Food Range Test")
+	require.NoError(t, err)
+
+	for _, categoryID := range []*int{&travel.ID, &food.ID, nil} {
+		require.NoError(t, expenseRepo.Create(ctx, &models.Expense{
+			UserID:      436,
+			Amount:      decimal.NewFromFloat(12.00),
+			Currency:    testCurrencySGD,
+			Description: "Range category test",
+			CategoryID:  categoryID,
+			Status:      models.ExpenseStatusConfirmed,
+		}))
+	}
+
+	now := time.Now()
+	start := now.Add(-time.Hour)
+	end := now.Add(time.Hour)
+
+	expenses, err := expenseRepo.GetByUserIDDateRangeAndCategory(ctx, 436, start, end, travel.ID)
+	require.NoError(t, err)
+	require.Len(t, expenses, 1)
+	require.Equal(t, travel.ID, *expenses[0].CategoryID)
+
+	expenses, err = expenseRepo.GetByUserIDDateRangeAndCategory(ctx, 436, start.Add(-48*time.Hour), start, travel.ID)
+	require.NoError(t, err)
+	require.Empty(t, expenses)
+}
+
 func TestExpenseRepository_Update(t *testing.T) {
 	expenseRepo, userRepo, categoryRepo, ctx := setupExpenseTest(t)
 
@@ -238,6 +400,42 @@ func TestExpenseRepository_Delete(t *testing.T) {
 	})
 }
 
+func TestExpenseRepository_GetDraftsByUserID(t *testing.T) {
+	expenseRepo, userRepo, _, ctx := setupExpenseTest(t)
+
+	user := &models.User{ID: 9419, Username: "draftsuser", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, userRepo.UpsertUser(ctx, user))
+
+	create := func(description string, status models.ExpenseStatus) *models.Expense {
+		expense := &models.Expense{
+			UserID:      user.ID,
+			Amount:      decimal.NewFromInt(10),
+			Currency:    testCurrencySGD,
+			Description: description,
+			Status:      status,
+		}
+		require.NoError(t, expenseRepo.Create(ctx, expense))
+		return expense
+	}
+	first := create("First draft", models.ExpenseStatusDraft)
+	create("Confirmed", models.ExpenseStatusConfirmed)
+	second := create("Second draft", models.ExpenseStatusDraft)
+
+	drafts, err := expenseRepo.GetDraftsByUserID(ctx, user.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, drafts, 2)
+	require.Equal(t, first.ID, drafts[0].ID)
+	require.Equal(t, second.ID, drafts[1].ID)
+
+	drafts, err = expenseRepo.GetDraftsByUserID(ctx, user.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, drafts, 1)
+
+	drafts, err = expenseRepo.GetDraftsByUserID(ctx, user.ID+1, 10)
+	require.NoError(t, err)
+	require.Empty(t, drafts)
+}
+
 func TestExpenseRepository_DeleteExpiredDrafts(t *testing.T) {
 	expenseRepo, userRepo, _, ctx := setupExpenseTest(t)
 
@@ -348,6 +546,132 @@ func TestExpenseRepository_GetTotalByUserIDAndDateRange(t *testing.T) {
 	})
 }
 
+func TestExpenseRepository_GetCategoryCurrencyTotalsByUserIDAndDateRange(t *testing.T) {
+	expenseRepo, userRepo, categoryRepo, ctx := setupExpenseTest(t)
+
+	user := &models.User{ID: 409, Username: "user409", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, userRepo.UpsertUser(ctx, user))
+	food, err := categoryRepo.Create(ctx, "Totals Food")
+	require.NoError(t, err)
+	travel, err := categoryRepo.Create(ctx, "Totals Travel")
+	require.NoError(t, err)
+
+	create := func(amount, currency string, categoryID *int, status models.ExpenseStatus) *models.Expense {
+		expense := &models.Expense{
+			UserID:      409,
+			Amount:      decimal.RequireFromString(amount),
+			Currency:    currency,
+			Description: "Expense",
+			CategoryID:  categoryID,
+			Status:      status,
+		}
+		require.NoError(t, expenseRepo.Create(ctx, expense))
+		return expense
+	}
+	create("10.50", testCurrencySGD, &food.ID, models.ExpenseStatusConfirmed)
+	create("4.50", testCurrencySGD, &food.ID, models.ExpenseStatusConfirmed)
+	create("20", "USD", &food.ID, models.ExpenseStatusConfirmed)
+	backfilled := create("1000", "JPY", &food.ID, models.ExpenseStatusConfirmed)
+	_, err = expenseRepo.SetConversion(ctx, backfilled.ID,
+		decimal.RequireFromString("0.009"), decimal.RequireFromString("9"), testCurrencySGD)
+	require.NoError(t, err)
+	create("30", testCurrencySGD, &travel.ID, models.ExpenseStatusConfirmed)
+	create("99", testCurrencySGD, &travel.ID, models.ExpenseStatusDraft)
+	create("7", testCurrencySGD, nil, models.ExpenseStatusConfirmed)
+
+	now := time.Now()
+	totals, err := expenseRepo.GetCategoryCurrencyTotalsByUserIDAndDateRange(
+		ctx, 409, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, totals, 4)
+
+	byKey := make(map[string]CategoryCurrencyTotal)
+	for _, total := range totals {
+		byKey[fmt.Sprintf("%d/%s", total.CategoryID, total.Currency)] = total
+	}
+	sgd := byKey[fmt.Sprintf("%d/%s", food.ID, testCurrencySGD)]
+	require.True(t, decimal.NewFromInt(15).Equal(sgd.Amount))
+	require.Empty(t, sgd.ConvertedCurrency)
+	require.True(t, decimal.NewFromInt(20).Equal(byKey[fmt.Sprintf("%d/USD", food.ID)].Amount))
+	jpy := byKey[fmt.Sprintf("%d/JPY", food.ID)]
+	require.Equal(t, testCurrencySGD, jpy.ConvertedCurrency)
+	require.True(t, decimal.NewFromInt(9).Equal(jpy.ConvertedAmount))
+	require.True(t, decimal.NewFromInt(30).Equal(byKey[fmt.Sprintf("%d/%s", travel.ID, testCurrencySGD)].Amount))
+
+	totals, err = expenseRepo.GetCategoryCurrencyTotalsByUserIDAndDateRange(ctx, 409,
+		time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Empty(t, totals)
+}
+
+func TestExpenseRepository_GetMonthlyCategoryCurrencyTotals(t *testing.T) {
+	expenseRepo, userRepo, categoryRepo, ctx := setupExpenseTest(t)
+
+	const userID = int64(12453)
+	require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: userID, FirstName: testFirstName}))
+	food, err := categoryRepo.Create(ctx, "Monthly Food")
+	require.NoError(t, err)
+
+	create := func(amount, currency string, categoryID *int, at time.Time) {
+		t.Helper()
+		expense := &models.Expense{
+			UserID:      userID,
+			Amount:      decimal.RequireFromString(amount),
+			Currency:    currency,
+			Description: "Expense",
+			CategoryID:  categoryID,
+		}
+		require.NoError(t, expenseRepo.Create(ctx, expense))
+		_, err := expenseRepo.Pool().Exec(ctx, `UPDATE expenses SET created_at = $1 WHERE id = $2`, at, expense.ID)
+		require.NoError(t, err)
+	}
+	// 2026-03-31 20:00 UTC is already April in Singapore.
+	create("10", testCurrencySGD, &food.ID, time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC))
+	create("5", testCurrencySGD, &food.ID, time.Date(2026, 3, 31, 20, 0, 0, 0, time.UTC))
+	create("7", "USD", &food.ID, time.Date(2026, 4, 2, 12, 0, 0, 0, time.UTC))
+	create("3", testCurrencySGD, nil, time.Date(2026, 4, 2, 12, 0, 0, 0, time.UTC))
+	create("9", testCurrencySGD, &food.ID, time.Date(2026, 5, 2, 12, 0, 0, 0, time.UTC))
+
+	totals, err := expenseRepo.GetMonthlyCategoryCurrencyTotals(ctx, userID,
+		time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), "Asia/Singapore")
+	require.NoError(t, err)
+	require.Len(t, totals, 3)
+	require.Equal(t, "2026-03", totals[0].Month)
+	require.True(t, decimal.NewFromInt(10).Equal(totals[0].Amount))
+	require.Equal(t, "2026-04", totals[1].Month)
+	require.Equal(t, testCurrencySGD, totals[1].Currency)
+	require.True(t, decimal.NewFromInt(5).Equal(totals[1].Amount))
+	require.Equal(t, "2026-04", totals[2].Month)
+	require.Equal(t, "USD", totals[2].Currency)
+	require.Equal(t, food.ID, totals[2].CategoryID)
+}
+
+func TestExpenseRepository_HasCurrencyHistory(t *testing.T) {
+	expenseRepo, userRepo, _, ctx := setupExpenseTest(t)
+
+	user := &models.User{ID: 388, Username: "user388", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, userRepo.UpsertUser(ctx, user))
+
+	create := func(currency, description string, status models.ExpenseStatus) {
+		require.NoError(t, expenseRepo.Create(ctx, &models.Expense{
+			UserID:      388,
+			Amount:      decimal.NewFromInt(5),
+			Currency:    currency,
+			Description: description,
+			Status:      status,
+		}))
+	}
+	create(testCurrencySGD, "Coffee", models.ExpenseStatusConfirmed)
+	create(testCurrencySGD, "Taxi [orig: 100.00 THB -> 3.80 SGD @ 0.0380 (2026-03-01)]", models.ExpenseStatusConfirmed)
+	create("EUR", "Draft", models.ExpenseStatusDraft)
+
+	for currency, want := range map[string]bool{testCurrencySGD: true, "THB": true, "EUR": false, "USD": false} {
+		got, err := expenseRepo.HasCurrencyHistory(ctx, 388, currency)
+		require.NoError(t, err)
+		require.Equal(t, want, got, currency)
+	}
+}
+
 func TestExpenseRepository_GetTotalByUserIDAndDateRange_MixedStatuses(t *testing.T) {
 	expenseRepo, userRepo, _, ctx := setupExpenseTest(t)
 
@@ -636,6 +960,16 @@ func TestExpenseRepository_HasExpensesForDate(t *testing.T) {
 		require.NoError(t, err)
 		require.False(t, has)
 	})
+
+	t.Run("counts only confirmed expenses", func(t *testing.T) {
+		count, err := expenseRepo.CountExpensesForDate(ctx, 970, startOfDay, endOfDay)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+
+		count, err = expenseRepo.CountExpensesForDate(ctx, 971, startOfDay, endOfDay)
+		require.NoError(t, err)
+		require.Zero(t, count)
+	})
 }
 
 func TestExpenseRepository_NullifyCategoryOnExpenses(t *testing.T) {
@@ -811,3 +1145,866 @@ func TestExpenseRepository_ReflectionWorkflow(t *testing.T) {
 	require.NoError(t, err)
 	require.Empty(t, unreviewed)
 }
+
+func TestExpenseRepository_GetAllByUserIDCursor(t *testing.T) {
+	expenseRepo, userRepo, _, ctx := setupExpenseTest(t)
+
+	user := &models.User{ID: 9383, Username: "cursoruser", FirstName: testFirstName, LastName: testLastName}
+	other := &models.User{ID: 9384, Username: "cursorother", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, userRepo.UpsertUser(ctx, user))
+	require.NoError(t, userRepo.UpsertUser(ctx, other))
+
+	create := func(userID int64, status models.ExpenseStatus) int {
+		expense := &models.Expense{
+			UserID:   userID,
+			Amount:   decimal.NewFromInt(1),
+			Currency: testCurrencySGD,
+			Status:   status,
+		}
+		require.NoError(t, expenseRepo.Create(ctx, expense))
+		return expense.ID
+	}
+
+	var want []int
+	for i := range 5 {
+		want = append(want, create(user.ID, models.ExpenseStatusConfirmed))
+		if i == 2 {
+			create(user.ID, models.ExpenseStatusDraft)
+			create(other.ID, models.ExpenseStatusConfirmed)
+		}
+	}
+
+	t.Run("pages in id order without gaps or repeats", func(t *testing.T) {
+		var got []int
+		afterID := 0
+		for {
+			page, err := expenseRepo.GetAllByUserIDCursor(ctx, user.ID, afterID, 2)
+			require.NoError(t, err)
+			if len(page) == 0 {
+				break
+			}
+			require.LessOrEqual(t, len(page), 2)
+			for i := range page {
+				got = append(got, page[i].ID)
+			}
+			afterID = page[len(page)-1].ID
+		}
+		require.Equal(t, want, got)
+	})
+
+	t.Run("cursor is exclusive", func(t *testing.T) {
+		page, err := expenseRepo.GetAllByUserIDCursor(ctx, user.ID, want[1], 10)
+		require.NoError(t, err)
+		require.Len(t, page, 3)
+		require.Equal(t, want[2], page[0].ID)
+	})
+
+	t.Run("past the last id is empty", func(t *testing.T) {
+		page, err := expenseRepo.GetAllByUserIDCursor(ctx, user.ID, want[len(want)-1], 10)
+		require.NoError(t, err)
+		require.Empty(t, page)
+	})
+}
+
+func TestExpenseRepository_AICategorized(t *testing.T) {
+	expenseRepo, userRepo, categoryRepo, ctx := setupExpenseTest(t)
+
+	user := &models.User{ID: 9417, Username: "aireviewuser", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, userRepo.UpsertUser(ctx, user))
+
+	food, err := categoryRepo.Create(ctx, "AI Review Food")
+	require.NoError(t, err)
+	transport, err := categoryRepo.Create(ctx, "AI Review Transport")
+	require.NoError(t, err)
+
+	create := func(categoryID int, aiCategorized bool, status models.ExpenseStatus) *models.Expense {
+		expense := &models.Expense{
+			UserID:        user.ID,
+			Amount:        decimal.NewFromInt(10),
+			Currency:      testCurrencySGD,
+			CategoryID:    &categoryID,
+			AICategorized: aiCategorized,
+			Status:        status,
+		}
+		require.NoError(t, expenseRepo.Create(ctx, expense))
+		return expense
+	}
+	taxi := create(transport.ID, true, models.ExpenseStatusConfirmed)
+	lunch := create(food.ID, true, models.ExpenseStatusConfirmed)
+	dinner := create(food.ID, true, models.ExpenseStatusConfirmed)
+	create(food.ID, false, models.ExpenseStatusConfirmed)
+	create(food.ID, true, models.ExpenseStatusDraft)
+
+	since := time.Now().Add(-time.Hour)
+
+	t.Run("lists flagged confirmed expenses by category", func(t *testing.T) {
+		expenses, err := expenseRepo.GetAICategorizedByUserID(ctx, user.ID, since, 10)
+		require.NoError(t, err)
+		require.Len(t, expenses, 3)
+		require.Equal(t, []int{lunch.ID, dinner.ID, taxi.ID},
+			[]int{expenses[0].ID, expenses[1].ID, expenses[2].ID})
+		require.Equal(t, food.Name, expenses[0].Category.Name)
+
+		count, err := expenseRepo.CountAICategorizedByUserID(ctx, user.ID, since)
+		require.NoError(t, err)
+		require.Equal(t, 3, count)
+
+		expenses, err = expenseRepo.GetAICategorizedByUserID(ctx, user.ID, since, 1)
+		require.NoError(t, err)
+		require.Len(t, expenses, 1)
+
+		count, err = expenseRepo.CountAICategorizedByUserID(ctx, user.ID, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		require.Zero(t, count)
+	})
+
+	t.Run("clears the flag of the owner's expense only", func(t *testing.T) {
+		cleared, err := expenseRepo.ClearAICategorized(ctx, user.ID+1, lunch.ID)
+		require.NoError(t, err)
+		require.False(t, cleared)
+
+		cleared, err = expenseRepo.ClearAICategorized(ctx, user.ID, lunch.ID)
+		require.NoError(t, err)
+		require.True(t, cleared)
+		got, err := expenseRepo.GetByID(ctx, lunch.ID)
+		require.NoError(t, err)
+		require.False(t, got.AICategorized)
+
+		cleared, err = expenseRepo.ClearAICategorized(ctx, user.ID, lunch.ID)
+		require.NoError(t, err)
+		require.False(t, cleared)
+
+		count, err := expenseRepo.CountAICategorizedByUserID(ctx, user.ID, since)
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+	})
+
+	t.Run("counts accepted suggestions", func(t *testing.T) {
+		got, err := expenseRepo.GetByID(ctx, dinner.ID)
+		require.NoError(t, err)
+		got.CategoryID = &transport.ID
+		require.NoError(t, expenseRepo.Update(ctx, got))
+
+		acceptance, err := expenseRepo.GetAIAcceptance(ctx, user.ID, since)
+		require.NoError(t, err)
+		require.Equal(t, AIAcceptance{Accepted: 2, Total: 3}, acceptance)
+
+		count, err := expenseRepo.CountAICategorizedByUserID(ctx, user.ID, since)
+		require.NoError(t, err)
+		require.Equal(t, 1, count, "changing the category clears the flag")
+	})
+}
+
+func TestExpenseRepository_GetUncategorizedByUserID(t *testing.T) {
+	expenseRepo, userRepo, categoryRepo, ctx := setupExpenseTest(t)
+
+	user := &models.User{ID: 9301, Username: "uncatuser", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, userRepo.UpsertUser(ctx, user))
+
+	cat, err := categoryRepo.Create(ctx, "Uncategorized Test Category")
+	require.NoError(t, err)
+
+	for _, amount := range []float64{5, 30, 12} {
+		require.NoError(t, expenseRepo.Create(ctx, &models.Expense{
+			UserID:   user.ID,
+			Amount:   decimal.NewFromFloat(amount),
+			Currency: testCurrencySGD,
+		}))
+	}
+	require.NoError(t, expenseRepo.Create(ctx, &models.Expense{
+		UserID:     user.ID,
+		Amount:     decimal.NewFromFloat(99),
+		Currency:   testCurrencySGD,
+		CategoryID: &cat.ID,
+	}))
+	require.NoError(t, expenseRepo.Create(ctx, &models.Expense{
+		UserID:   user.ID,
+		Amount:   decimal.NewFromFloat(50),
+		Currency: testCurrencySGD,
+		Status:   models.ExpenseStatusDraft,
+	}))
+
+	since := time.Now().Add(-time.Hour)
+
+	t.Run("orders by amount descending", func(t *testing.T) {
+		expenses, err := expenseRepo.GetUncategorizedByUserID(ctx, user.ID, since, nil, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, expenses, 3)
+		require.True(t, expenses[0].Amount.Equal(decimal.NewFromInt(30)))
+		require.True(t, expenses[1].Amount.Equal(decimal.NewFromInt(12)))
+		require.True(t, expenses[2].Amount.Equal(decimal.NewFromInt(5)))
+		for i := range expenses {
+			require.Nil(t, expenses[i].CategoryID)
+		}
+	})
+
+	t.Run("paginates with limit and offset", func(t *testing.T) {
+		expenses, err := expenseRepo.GetUncategorizedByUserID(ctx, user.ID, since, nil, 2, 2)
+		require.NoError(t, err)
+		require.Len(t, expenses, 1)
+		require.True(t, expenses[0].Amount.Equal(decimal.NewFromInt(5)))
+	})
+
+	t.Run("respects since", func(t *testing.T) {
+		expenses, err := expenseRepo.GetUncategorizedByUserID(ctx, user.ID, time.Now().Add(time.Hour), nil, 10, 0)
+		require.NoError(t, err)
+		require.Empty(t, expenses)
+	})
+
+	t.Run("counts confirmed uncategorized only", func(t *testing.T) {
+		count, err := expenseRepo.CountUncategorizedByUserID(ctx, user.ID, since, nil)
+		require.NoError(t, err)
+		require.Equal(t, 3, count)
+	})
+
+	t.Run("includes uncertain AI categories on request", func(t *testing.T) {
+		aiExpense := func(amount, confidence float64) *models.Expense {
+			expense := &models.Expense{
+				UserID:        user.ID,
+				Amount:        decimal.NewFromFloat(amount),
+				Currency:      testCurrencySGD,
+				CategoryID:    &cat.ID,
+				AICategorized: true,
+				AIConfidence:  &confidence,
+			}
+			require.NoError(t, expenseRepo.Create(ctx, expense))
+			return expense
+		}
+		uncertain := aiExpense(40, 0.6)
+		aiExpense(41, 0.7)
+		band := &ConfidenceBand{Min: 0.5, Max: 0.7}
+
+		count, err := expenseRepo.CountUncategorizedByUserID(ctx, user.ID, since, nil)
+		require.NoError(t, err)
+		require.Equal(t, 3, count)
+
+		expenses, err := expenseRepo.GetUncategorizedByUserID(ctx, user.ID, since, band, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, expenses, 4)
+		require.Equal(t, uncertain.ID, expenses[0].ID)
+		require.NotNil(t, expenses[0].Category)
+
+		got, err := expenseRepo.GetByID(ctx, uncertain.ID)
+		require.NoError(t, err)
+		require.True(t, got.AICategorized)
+		require.InDelta(t, 0.6, *got.AIConfidence, 1e-9)
+
+		got.Description = "Still AI"
+		require.NoError(t, expenseRepo.Update(ctx, got))
+		count, err = expenseRepo.CountUncategorizedByUserID(ctx, user.ID, since, band)
+		require.NoError(t, err)
+		require.Equal(t, 4, count)
+
+		got.CategoryID = nil
+		require.NoError(t, expenseRepo.Update(ctx, got))
+		got.CategoryID = &cat.ID
+		require.NoError(t, expenseRepo.Update(ctx, got))
+		got, err = expenseRepo.GetByID(ctx, uncertain.ID)
+		require.NoError(t, err)
+		require.False(t, got.AICategorized)
+	})
+}
+
+func TestExpenseRepository_GetDuplicateCandidates(t *testing.T) {
+	expenseRepo, userRepo, _, ctx := setupExpenseTest(t)
+
+	user := &models.User{ID: 980, Username: "user980", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, userRepo.UpsertUser(ctx, user))
+
+	day := time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC)
+	create := func(amount float64, at time.Time, status models.ExpenseStatus) int {
+		expense := &models.Expense{
+			UserID:      980,
+			Amount:      decimal.NewFromFloat(amount),
+			Currency:    testCurrencySGD,
+			Description: "Coffee",
+			Status:      status,
+		}
+		require.NoError(t, expenseRepo.Create(ctx, expense))
+		_, err := expenseRepo.Pool().Exec(ctx, `UPDATE expenses SET created_at = $2 WHERE id = $1`, expense.ID, at)
+		require.NoError(t, err)
+		return expense.ID
+	}
+
+	first := create(4.5, day, models.ExpenseStatusConfirmed)
+	second := create(4.5, day.Add(time.Hour), models.ExpenseStatusConfirmed)
+	create(4.5, day.Add(2*time.Hour), models.ExpenseStatusDraft)
+	nextDay := create(4.5, day.AddDate(0, 0, 1), models.ExpenseStatusConfirmed)
+	create(7, day, models.ExpenseStatusConfirmed)
+	// 17:00 UTC is the next day in GMT+8, where it pairs with nextDay instead.
+	late := create(4.5, time.Date(2026, 3, 4, 17, 0, 0, 0, time.UTC), models.ExpenseStatusConfirmed)
+
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	candidateIDs := func(timezone string) []int {
+		candidates, err := expenseRepo.GetDuplicateCandidates(ctx, 980, start, end, timezone)
+		require.NoError(t, err)
+		ids := make([]int, 0, len(candidates))
+		for i := range candidates {
+			ids = append(ids, candidates[i].ID)
+		}
+		return ids
+	}
+
+	t.Run("returns confirmed same-day same-amount expenses", func(t *testing.T) {
+		require.Equal(t, []int{first, second, late}, candidateIDs("UTC"))
+	})
+
+	t.Run("takes the day in the timezone", func(t *testing.T) {
+		require.Equal(t, []int{first, second, nextDay, late}, candidateIDs("Asia/Singapore"))
+	})
+
+	t.Run("follows DST within the month", func(t *testing.T) {
+		// New York moves to UTC-4 on 8 March 2026. 04:30 UTC on 20 March
+		// is 00:30 on the 20th there, but 23:30 on the 19th with the
+		// offset of March 1.
+		midnight := create(9, time.Date(2026, 3, 20, 4, 30, 0, 0, time.UTC), models.ExpenseStatusConfirmed)
+		morning := create(9, time.Date(2026, 3, 20, 14, 0, 0, 0, time.UTC), models.ExpenseStatusConfirmed)
+		ids := candidateIDs("America/New_York")
+		require.Contains(t, ids, midnight)
+		require.Contains(t, ids, morning)
+	})
+
+	t.Run("deletes only the user's expenses", func(t *testing.T) {
+		deleted, err := expenseRepo.DeleteByUserAndIDs(ctx, 981, []int{first})
+		require.NoError(t, err)
+		require.Zero(t, deleted)
+
+		deleted, err = expenseRepo.DeleteByUserAndIDs(ctx, 980, []int{first, late})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), deleted)
+	})
+}
+
+func TestExpenseRepository_SourceMessage(t *testing.T) {
+	expenseRepo, userRepo, _, ctx := setupExpenseTest(t)
+
+	user := &models.User{ID: 375, Username: "sourceuser", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, userRepo.UpsertUser(ctx, user))
+
+	expense := &models.Expense{
+		UserID:      375,
+		Amount:      decimal.NewFromFloat(55),
+		Currency:    testCurrencySGD,
+		Description: "Coffee",
+		Status:      models.ExpenseStatusConfirmed,
+	}
+	require.NoError(t, expenseRepo.Create(ctx, expense))
+
+	t.Run("returns the linked expense", func(t *testing.T) {
+		require.NoError(t, expenseRepo.LinkSourceMessage(ctx, expense.ID, -100375, 42))
+
+		id, err := expenseRepo.GetIDBySourceMessage(ctx, -100375, 42)
+		require.NoError(t, err)
+		require.Equal(t, expense.ID, id)
+	})
+
+	t.Run("returns the source chat", func(t *testing.T) {
+		chatID, err := expenseRepo.GetSourceChatID(ctx, expense.ID)
+		require.NoError(t, err)
+		require.Equal(t, int64(-100375), chatID)
+	})
+
+	t.Run("unknown message", func(t *testing.T) {
+		_, err := expenseRepo.GetIDBySourceMessage(ctx, -100375, 43)
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
+	t.Run("link is removed with the expense", func(t *testing.T) {
+		require.NoError(t, expenseRepo.Delete(ctx, expense.ID))
+
+		_, err := expenseRepo.GetIDBySourceMessage(ctx, -100375, 42)
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+
+		_, err = expenseRepo.GetSourceChatID(ctx, expense.ID)
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+}
+
+func TestExpenseRepository_CreateConcurrentNumbering(t *testing.T) {
+	ctx := context.Background()
+	// Parallel creates need their own connections, so this test cannot run
+	// inside a rolled-back transaction.
+	pool := dbtest.TestPool(t)
+
+	userID := int64(391101)
+	t.Cleanup(func() {
+		cleanupCtx := context.WithoutCancel(ctx)
+		_, _ = pool.Exec(cleanupCtx, `DELETE FROM expenses WHERE user_id = $1`, userID)
+		_, _ = pool.Exec(cleanupCtx, `DELETE FROM user_expense_counters WHERE user_id = $1`, userID)
+		_, _ = pool.Exec(cleanupCtx, `DELETE FROM users WHERE id = $1`, userID)
+	})
+	require.NoError(t, NewUserRepository(pool).UpsertUser(ctx, &models.User{ID: userID, FirstName: testFirstName}))
+
+	repo := NewExpenseRepository(pool)
+	const creates = 20
+	numbers := make(chan int64, creates)
+	errs := make(chan error, creates)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for range creates {
+		wg.Go(func() {
+			<-start
+			expense := &models.Expense{
+				UserID:      userID,
+				Amount:      decimal.NewFromInt(1),
+				Currency:    testCurrencySGD,
+				Description: "Concurrent",
+			}
+			if err := repo.Create(ctx, expense); err != nil {
+				errs <- err
+				return
+			}
+			numbers <- expense.UserExpenseNumber
+		})
+	}
+	close(start)
+	wg.Wait()
+	close(numbers)
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	got := make([]int64, 0, creates)
+	for n := range numbers {
+		got = append(got, n)
+	}
+	slices.Sort(got)
+	want := make([]int64, creates)
+	for i := range want {
+		want[i] = int64(i + 1)
+	}
+	require.Equal(t, want, got, "numbers should be unique and dense")
+}
+
+func TestExpenseRepository_Conversions(t *testing.T) {
+	expenseRepo, userRepo, _, ctx := setupExpenseTest(t)
+
+	user := &models.User{ID: 394101, FirstName: testFirstName, DefaultCurrency: testCurrencySGD}
+	require.NoError(t, userRepo.UpsertUser(ctx, user))
+
+	create := func(amount, currency string) *models.Expense {
+		e := &models.Expense{UserID: user.ID, Amount: decimal.RequireFromString(amount), Currency: currency}
+		require.NoError(t, expenseRepo.Create(ctx, e))
+		return e
+	}
+	usd := create("10", "USD")
+	eur := create("20", "EUR")
+	create("5", testCurrencySGD)
+
+	missing, err := expenseRepo.ListMissingConversions(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, missing, 2)
+	require.Equal(t, usd.ID, missing[0].ExpenseID)
+	require.Equal(t, "USD", missing[0].Currency)
+	require.Equal(t, testCurrencySGD, missing[0].DefaultCurrency)
+
+	page, err := expenseRepo.ListMissingConversions(ctx, usd.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, eur.ID, page[0].ExpenseID)
+
+	rate := decimal.RequireFromString("1.35")
+	updated, err := expenseRepo.SetConversion(ctx, usd.ID, rate, decimal.RequireFromString("13.50"), testCurrencySGD)
+	require.NoError(t, err)
+	require.True(t, updated)
+	updated, err = expenseRepo.SetConversion(ctx, usd.ID, rate, decimal.RequireFromString("99"), testCurrencySGD)
+	require.NoError(t, err)
+	require.False(t, updated, "stored conversions are kept")
+
+	got, err := expenseRepo.GetByID(ctx, usd.ID)
+	require.NoError(t, err)
+	require.True(t, rate.Equal(got.RateToDefault.Decimal))
+	require.True(t, decimal.RequireFromString("13.50").Equal(got.ConvertedAmount.Decimal))
+
+	t.Run("editing the amount clears the conversion", func(t *testing.T) {
+		got.Amount = decimal.RequireFromString("11")
+		require.NoError(t, expenseRepo.Update(ctx, got))
+		got, err := expenseRepo.GetByID(ctx, usd.ID)
+		require.NoError(t, err)
+		require.False(t, got.ConvertedAmount.Valid)
+		require.False(t, got.RateToDefault.Valid)
+		require.Empty(t, got.ConvertedCurrency)
+	})
+}
+
+func TestExpenseRepository_Location(t *testing.T) {
+	expenseRepo, userRepo, _, ctx := setupExpenseTest(t)
+
+	require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: 405001, FirstName: testFirstName}))
+	require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: 405002, FirstName: testFirstName}))
+
+	t.Run("sets and gets a location", func(t *testing.T) {
+		expense := &models.Expense{UserID: 405001, Amount: decimal.NewFromInt(5), Currency: testCurrencySGD}
+		require.NoError(t, expenseRepo.Create(ctx, expense))
+
+		_, ok, err := expenseRepo.GetLocation(ctx, expense.ID)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		want := models.ExpenseLocation{Latitude: 1.2834, Longitude: 103.8607, Label: "Lau Pa Sat"}
+		require.NoError(t, expenseRepo.SetLocation(ctx, expense.ID, want))
+		got, ok, err := expenseRepo.GetLocation(ctx, expense.ID)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, want, got)
+	})
+
+	t.Run("latest expense in chat", func(t *testing.T) {
+		since := time.Now().Add(-time.Hour)
+		_, err := expenseRepo.GetLatestIDInChat(ctx, 405002, 405002, since)
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+
+		here := &models.Expense{UserID: 405002, Amount: decimal.NewFromInt(3), Currency: testCurrencySGD}
+		require.NoError(t, expenseRepo.Create(ctx, here))
+		elsewhere := &models.Expense{UserID: 405002, Amount: decimal.NewFromInt(4), Currency: testCurrencySGD}
+		require.NoError(t, expenseRepo.Create(ctx, elsewhere))
+		require.NoError(t, expenseRepo.LinkSourceMessage(ctx, elsewhere.ID, -100405, 7))
+
+		id, err := expenseRepo.GetLatestIDInChat(ctx, 405002, 405002, since)
+		require.NoError(t, err)
+		require.Equal(t, here.ID, id)
+
+		id, err = expenseRepo.GetLatestIDInChat(ctx, 405002, -100405, since)
+		require.NoError(t, err)
+		require.Equal(t, elsewhere.ID, id)
+
+		_, err = expenseRepo.GetLatestIDInChat(ctx, 405002, 405002, time.Now().Add(time.Hour))
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+}
+
+func TestExpenseRepository_GetSpendingPatternTotals(t *testing.T) {
+	expenseRepo, userRepo, _, ctx := setupExpenseTest(t)
+
+	userID := int64(422001)
+	require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: userID, FirstName: testFirstName}))
+
+	create := func(amount, currency string, at time.Time, status models.ExpenseStatus) *models.Expense {
+		expense := &models.Expense{
+			UserID:   userID,
+			Amount:   decimal.RequireFromString(amount),
+			Currency: currency,
+			Status:   status,
+		}
+		require.NoError(t, expenseRepo.Create(ctx, expense))
+		_, err := expenseRepo.Pool().Exec(ctx, `UPDATE expenses SET created_at = $1 WHERE id = $2`, at, expense.ID)
+		require.NoError(t, err)
+		return expense
+	}
+
+	// Friday 2026-01-02 23:30 UTC is Saturday 07:30 in Singapore and Friday
+	// 18:30 in New York.
+	lateFriday := time.Date(2026, 1, 2, 23, 30, 0, 0, time.UTC)
+	create("10.00", testCurrencySGD, lateFriday, models.ExpenseStatusConfirmed)
+	create("5.00", testCurrencySGD, lateFriday.Add(10*time.Minute), models.ExpenseStatusConfirmed)
+	create("99.00", testCurrencySGD, lateFriday, models.ExpenseStatusDraft)
+	converted := create("20.00", "USD", lateFriday, models.ExpenseStatusConfirmed)
+	_, err := expenseRepo.Pool().Exec(ctx,
+		`UPDATE expenses SET converted_amount = 27.00, converted_currency = 'SGD' WHERE id = $1`, converted.ID)
+	require.NoError(t, err)
+	create("7.00", "EUR", lateFriday, models.ExpenseStatusConfirmed)
+	create("50.00", testCurrencySGD, lateFriday.AddDate(0, 0, -30), models.ExpenseStatusConfirmed)
+
+	start := lateFriday.AddDate(0, 0, -7)
+	end := lateFriday.AddDate(0, 0, 1)
+
+	totals, err := expenseRepo.GetSpendingPatternTotals(ctx, userID, start, end, "Asia/Singapore")
+	require.NoError(t, err)
+	require.Len(t, totals, 2)
+	require.Equal(t, time.Saturday, totals[0].Weekday)
+	require.Equal(t, 7, totals[0].Hour)
+	require.Equal(t, "EUR", totals[0].Currency)
+	require.Equal(t, 1, totals[0].Count)
+	require.Equal(t, testCurrencySGD, totals[1].Currency)
+	require.Equal(t, 3, totals[1].Count, "drafts and older expenses are left out")
+	require.True(t, decimal.RequireFromString("42").Equal(totals[1].Amount), totals[1].Amount.String())
+
+	totals, err = expenseRepo.GetSpendingPatternTotals(ctx, userID, start, end, "America/New_York")
+	require.NoError(t, err)
+	require.Len(t, totals, 2)
+	require.Equal(t, time.Friday, totals[1].Weekday)
+	require.Equal(t, 18, totals[1].Hour)
+}
+
+func TestExpenseRepository_GetMonthlyCategoryTotals(t *testing.T) {
+	expenseRepo, userRepo, categoryRepo, ctx := setupExpenseTest(t)
+
+	userID := int64(446001)
+	require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: userID, FirstName: testFirstName}))
+	food, err := categoryRepo.Create(ctx, "Yearly Food")
+	require.NoError(t, err)
+
+	create := func(amount, currency string, categoryID *int, at time.Time) *models.Expense {
+		expense := &models.Expense{
+			UserID:     userID,
+			Amount:     decimal.RequireFromString(amount),
+			Currency:   currency,
+			CategoryID: categoryID,
+			Status:     models.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, expenseRepo.Create(ctx, expense))
+		_, err := expenseRepo.Pool().Exec(ctx, `UPDATE expenses SET created_at = $1 WHERE id = $2`, at, expense.ID)
+		require.NoError(t, err)
+		return expense
+	}
+
+	// 2025-01-31 20:00 UTC is already February in Singapore.
+	create("10.00", testCurrencySGD, &food.ID, time.Date(2025, 1, 31, 20, 0, 0, 0, time.UTC))
+	create("5.00", testCurrencySGD, &food.ID, time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC))
+	create("7.00", testCurrencySGD, nil, time.Date(2025, 2, 11, 12, 0, 0, 0, time.UTC))
+	create("80.00", "USD", &food.ID, time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC))
+	create("99.00", testCurrencySGD, &food.ID, time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC))
+
+	sgt, err := time.LoadLocation("Asia/Singapore")
+	require.NoError(t, err)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, sgt)
+	end := time.Date(2026, 1, 1, 0, 0, 0, 0, sgt)
+	totals, err := expenseRepo.GetMonthlyCategoryTotals(ctx, userID, start, end, "Asia/Singapore")
+	require.NoError(t, err)
+	require.Len(t, totals, 3)
+
+	require.Equal(t, 2025, totals[0].Year)
+	require.Equal(t, time.February, totals[0].Month)
+	require.Empty(t, totals[0].Category)
+	require.Equal(t, time.February, totals[1].Month)
+	require.Equal(t, "Yearly Food", totals[1].Category)
+	require.Equal(t, 2, totals[1].Count)
+	require.True(t, decimal.RequireFromString("15").Equal(totals[1].Amount), totals[1].Amount.String())
+	require.Equal(t, time.March, totals[2].Month)
+	require.Equal(t, "USD", totals[2].Currency)
+
+	largest, err := expenseRepo.GetLargestByUserIDAndDateRange(ctx, userID, start, end, testCurrencySGD)
+	require.NoError(t, err)
+	require.True(t, decimal.RequireFromString("10").Equal(largest.Amount), "other currencies are not compared")
+
+	_, err = expenseRepo.GetLargestByUserIDAndDateRange(ctx, userID, start, end, "EUR")
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestExpenseRepository_Reimbursements(t *testing.T) {
+	expenseRepo, userRepo, categoryRepo, ctx := setupExpenseTest(t)
+
+	userID := int64(423001)
+	require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: userID, FirstName: testFirstName}))
+	travel, err := categoryRepo.Create(ctx, "Reimbursement Travel")
+	require.NoError(t, err)
+
+	create := func(amount string, reimbursable bool) *models.Expense {
+		expense := &models.Expense{
+			UserID:       userID,
+			Amount:       decimal.RequireFromString(amount),
+			Currency:     testCurrencySGD,
+			Description:  "Trip",
+			CategoryID:   &travel.ID,
+			Status:       models.ExpenseStatusConfirmed,
+			Reimbursable: reimbursable,
+		}
+		require.NoError(t, expenseRepo.Create(ctx, expense))
+		return expense
+	}
+	hotel := create("200", true)
+	taxi := create("30", false)
+	create("12", false)
+
+	fetched, err := expenseRepo.GetByID(ctx, hotel.ID)
+	require.NoError(t, err)
+	require.True(t, fetched.AwaitingReimbursement())
+
+	ok, err := expenseRepo.SetReimbursable(ctx, userID, taxi.ID, true)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = expenseRepo.SetReimbursable(ctx, userID+1, taxi.ID, false)
+	require.NoError(t, err)
+	require.False(t, ok, "other users cannot change the flag")
+
+	outstanding, err := expenseRepo.GetOutstandingReimbursements(ctx, userID, 10)
+	require.NoError(t, err)
+	require.Len(t, outstanding, 2)
+	require.Equal(t, hotel.ID, outstanding[0].ID, "oldest first")
+
+	now := time.Now()
+	totals, err := expenseRepo.GetCategoryCurrencyTotalsByUserIDAndDateRange(
+		ctx, userID, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, totals, 1)
+	require.True(t, decimal.NewFromInt(12).Equal(totals[0].Amount), "budgets leave outstanding ones out")
+
+	reimbursedAt := time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)
+	ok, err = expenseRepo.MarkReimbursed(ctx, userID, hotel.ID, reimbursedAt)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = expenseRepo.MarkReimbursed(ctx, userID, hotel.ID, reimbursedAt)
+	require.NoError(t, err)
+	require.False(t, ok, "already reimbursed")
+
+	fetched, err = expenseRepo.GetByID(ctx, hotel.ID)
+	require.NoError(t, err)
+	require.NotNil(t, fetched.ReimbursedAt)
+	require.True(t, reimbursedAt.Equal(*fetched.ReimbursedAt))
+	require.False(t, fetched.AwaitingReimbursement())
+
+	totals, err = expenseRepo.GetCategoryCurrencyTotalsByUserIDAndDateRange(
+		ctx, userID, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, decimal.NewFromInt(212).Equal(totals[0].Amount), "reimbursed ones count again")
+
+	ok, err = expenseRepo.SetReimbursable(ctx, userID, hotel.ID, false)
+	require.NoError(t, err)
+	require.True(t, ok)
+	fetched, err = expenseRepo.GetByID(ctx, hotel.ID)
+	require.NoError(t, err)
+	require.Nil(t, fetched.ReimbursedAt, "turning the flag off clears the reimbursement")
+
+	outstanding, err = expenseRepo.GetOutstandingReimbursements(ctx, userID, 10)
+	require.NoError(t, err)
+	require.Len(t, outstanding, 1)
+	require.Equal(t, taxi.ID, outstanding[0].ID)
+}
+
+func TestExpenseRepository_CreateImported(t *testing.T) {
+	expenseRepo, userRepo, _, ctx := setupExpenseTest(t)
+
+	user := &models.User{ID: 433101, FirstName: testFirstName}
+	require.NoError(t, userRepo.UpsertUser(ctx, user))
+
+	at := time.Date(2025, 3, 14, 12, 30, 0, 0, time.UTC)
+	expense := &models.Expense{
+		UserID:      user.ID,
+		Amount:      decimal.RequireFromString("12.40"),
+		Currency:    testCurrencySGD,
+		Description: "Imported lunch",
+		Status:      models.ExpenseStatusDraft,
+		CreatedAt:   at,
+	}
+	require.NoError(t, expenseRepo.CreateImported(ctx, expense))
+	require.NotZero(t, expense.ID)
+	require.NotZero(t, expense.UserExpenseNumber)
+
+	stored, err := expenseRepo.GetByID(ctx, expense.ID)
+	require.NoError(t, err)
+	require.True(t, at.Equal(stored.CreatedAt), "the imported date is kept")
+	require.Equal(t, models.ExpenseStatusConfirmed, stored.Status)
+}
+
+func TestExpenseRepository_SameTimestampOrder(t *testing.T) {
+	expenseRepo, userRepo, categoryRepo, ctx := setupExpenseTest(t)
+
+	user := &models.User{ID: 458101, FirstName: testFirstName}
+	require.NoError(t, userRepo.UpsertUser(ctx, user))
+	cat, err := categoryRepo.Create(ctx, "Same Second Category")
+	require.NoError(t, err)
+
+	// A bulk import gives every row the same created_at.
+	at := time.Date(2026, 5, 2, 9, 30, 0, 0, time.UTC)
+	var imported []int
+	for i := range 5 {
+		expense := &models.Expense{
+			UserID:      user.ID,
+			Amount:      decimal.NewFromInt(7),
+			Currency:    testCurrencySGD,
+			Description: fmt.Sprintf("Kopi %d", i),
+			Merchant:    "Ya Kun",
+			CreatedAt:   at,
+		}
+		if i%2 == 0 {
+			expense.CategoryID = &cat.ID
+		}
+		require.NoError(t, expenseRepo.CreateImported(ctx, expense))
+		imported = append(imported, expense.ID)
+	}
+	var drafts []int
+	for range 3 {
+		draft := &models.Expense{UserID: user.ID, Amount: decimal.NewFromInt(3), Currency: testCurrencySGD}
+		require.NoError(t, expenseRepo.Create(ctx, draft))
+		drafts = append(drafts, draft.ID)
+	}
+	_, err = expenseRepo.Pool().Exec(ctx, `UPDATE expenses SET created_at = $1 WHERE user_id = $2`, at, user.ID)
+	require.NoError(t, err)
+
+	ids := func(t *testing.T, expenses []models.Expense, err error) []int {
+		t.Helper()
+		require.NoError(t, err)
+		out := make([]int, len(expenses))
+		for i, e := range expenses {
+			out[i] = e.ID
+		}
+		return out
+	}
+	newestFirst := slices.Clone(imported)
+	slices.Reverse(newestFirst)
+	categorized := []int{imported[4], imported[2], imported[0]}
+	uncategorized := []int{imported[3], imported[1]}
+	from, to := at.Add(-time.Hour), at.Add(time.Hour)
+
+	tests := []struct {
+		name string
+		list func() ([]models.Expense, error)
+		want []int
+	}{
+		{
+			name: "list, newest ID first",
+			list: func() ([]models.Expense, error) { return expenseRepo.GetByUserID(ctx, user.ID, 10) },
+			want: newestFirst,
+		},
+		{
+			name: "list limit keeps the first rows of the order",
+			list: func() ([]models.Expense, error) { return expenseRepo.GetByUserID(ctx, user.ID, 2) },
+			want: newestFirst[:2],
+		},
+		{
+			name: "date range",
+			list: func() ([]models.Expense, error) { return expenseRepo.GetByUserIDAndDateRange(ctx, user.ID, from, to) },
+			want: newestFirst,
+		},
+		{
+			name: "category",
+			list: func() ([]models.Expense, error) { return expenseRepo.GetByUserIDAndCategory(ctx, user.ID, cat.ID, 2) },
+			want: categorized[:2],
+		},
+		{
+			name: "date range and category",
+			list: func() ([]models.Expense, error) {
+				return expenseRepo.GetByUserIDDateRangeAndCategory(ctx, user.ID, from, to, cat.ID)
+			},
+			want: categorized,
+		},
+		{
+			name: "merchant",
+			list: func() ([]models.Expense, error) { return expenseRepo.GetByUserAndMerchant(ctx, user.ID, "ya kun", 3) },
+			want: newestFirst[:3],
+		},
+		{
+			name: "search",
+			list: func() ([]models.Expense, error) {
+				return expenseRepo.GetByDescriptionLike(ctx, user.ID, "kopi", cat.ID)
+			},
+			want: uncategorized,
+		},
+		{
+			name: "uncategorized, equal amounts by newest ID",
+			list: func() ([]models.Expense, error) {
+				return expenseRepo.GetUncategorizedByUserID(ctx, user.ID, from, nil, 1, 1)
+			},
+			want: uncategorized[1:],
+		},
+		{
+			name: "drafts, oldest ID first",
+			list: func() ([]models.Expense, error) { return expenseRepo.GetDraftsByUserID(ctx, user.ID, 10) },
+			want: drafts,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expenses, err := tt.list()
+			first := ids(t, expenses, err)
+			require.Equal(t, tt.want, first)
+			for range 3 {
+				expenses, err := tt.list()
+				require.Equal(t, first, ids(t, expenses, err), "repeated calls return the same order")
+			}
+		})
+	}
+}
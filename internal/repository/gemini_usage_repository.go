@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+)
+
+// GeminiUsage is the token count of one Gemini call.
+type GeminiUsage struct {
+	UserID       int64
+	Feature      string
+	PromptTokens int
+	OutputTokens int
+	TotalTokens  int
+}
+
+// GeminiFeatureUsage aggregates the Gemini calls of one feature.
+type GeminiFeatureUsage struct {
+	Feature     string
+	Calls       int
+	TotalTokens int64
+}
+
+// GeminiUsageRepository handles Gemini token usage records.
+type GeminiUsageRepository struct {
+	db database.PGXDB
+}
+
+// NewGeminiUsageRepository creates a new GeminiUsageRepository.
+func NewGeminiUsageRepository(db database.PGXDB) *GeminiUsageRepository {
+	return &GeminiUsageRepository{db: db}
+}
+
+// Record stores the token usage of one call.
+func (r *GeminiUsageRepository) Record(ctx context.Context, usage GeminiUsage) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO gemini_usage (user_id, feature, prompt_tokens, output_tokens, total_tokens)
+		VALUES ($1, $2, $3, $4, $5)
+	`, usage.UserID, usage.Feature, usage.PromptTokens, usage.OutputTokens, usage.TotalTokens)
+	if err != nil {
+		return fmt.Errorf("failed to record gemini usage: %w", err)
+	}
+	return nil
+}
+
+// TotalSince returns the tokens used since the given time.
+func (r *GeminiUsageRepository) TotalSince(ctx context.Context, since time.Time) (int64, error) {
+	var total int64
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(total_tokens), 0) FROM gemini_usage WHERE created_at >= $1
+	`, since).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum gemini usage: %w", err)
+	}
+	return total, nil
+}
+
+// CountForUserSince returns how many calls of feature were made for userID
+// since the given time.
+func (r *GeminiUsageRepository) CountForUserSince(
+	ctx context.Context,
+	userID int64,
+	feature string,
+	since time.Time,
+) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM gemini_usage WHERE user_id = $1 AND feature = $2 AND created_at >= $3
+	`, userID, feature, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count gemini usage: %w", err)
+	}
+	return count, nil
+}
+
+// ByFeatureSince returns the calls and tokens per feature since the given
+// time, largest first.
+func (r *GeminiUsageRepository) ByFeatureSince(ctx context.Context, since time.Time) ([]GeminiFeatureUsage, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT feature, COUNT(*), COALESCE(SUM(total_tokens), 0)
+		FROM gemini_usage
+		WHERE created_at >= $1
+		GROUP BY feature
+		ORDER BY 3 DESC, feature
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gemini usage by feature: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []GeminiFeatureUsage
+	for rows.Next() {
+		var u GeminiFeatureUsage
+		if err := rows.Scan(&u.Feature, &u.Calls, &u.TotalTokens); err != nil {
+			return nil, fmt.Errorf("failed to scan gemini usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate gemini usage: %w", err)
+	}
+	return usage, nil
+}
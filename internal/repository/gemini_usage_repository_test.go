@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
+)
+
+func TestGeminiUsageRepository(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+	repo := NewGeminiUsageRepository(tx)
+
+	start := time.Now().Add(-time.Minute)
+	total, err := repo.TotalSince(ctx, start)
+	require.NoError(t, err)
+	require.Zero(t, total)
+
+	for _, usage := range []GeminiUsage{
+		{UserID: 421001, Feature: "receipt", PromptTokens: 900, OutputTokens: 100, TotalTokens: 1000},
+		{UserID: 421001, Feature: "suggest", PromptTokens: 80, OutputTokens: 20, TotalTokens: 100},
+		{UserID: 421002, Feature: "receipt", PromptTokens: 450, OutputTokens: 50, TotalTokens: 500},
+	} {
+		require.NoError(t, repo.Record(ctx, usage))
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO gemini_usage (user_id, feature, total_tokens, created_at)
+		VALUES (421001, 'voice', 7000, $1)
+	`, start.Add(-48*time.Hour))
+	require.NoError(t, err)
+
+	total, err = repo.TotalSince(ctx, start)
+	require.NoError(t, err)
+	require.Equal(t, int64(1600), total, "older calls are not counted")
+
+	byFeature, err := repo.ByFeatureSince(ctx, start)
+	require.NoError(t, err)
+	require.Equal(t, []GeminiFeatureUsage{
+		{Feature: "receipt", Calls: 2, TotalTokens: 1500},
+		{Feature: "suggest", Calls: 1, TotalTokens: 100},
+	}, byFeature)
+}
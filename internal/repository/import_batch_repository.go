@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+)
+
+// ErrImportBatchNotFound is returned when a user never imported a file.
+var ErrImportBatchNotFound = errors.New("import batch not found")
+
+// ImportBatch records one CSV file a user imported.
+type ImportBatch struct {
+	FileHash  string
+	RowCount  int
+	CreatedAt time.Time
+}
+
+// ImportBatchRepository handles the imported file records.
+type ImportBatchRepository struct {
+	db database.PGXDB
+}
+
+// NewImportBatchRepository creates a new ImportBatchRepository.
+func NewImportBatchRepository(db database.PGXDB) *ImportBatchRepository {
+	return &ImportBatchRepository{db: db}
+}
+
+// Record saves that a user imported rowCount expenses from the file with
+// the given hash.
+func (r *ImportBatchRepository) Record(ctx context.Context, userID int64, fileHash string, rowCount int) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO import_batches (user_id, file_hash, row_count) VALUES ($1, $2, $3)
+	`, userID, fileHash, rowCount)
+	if err != nil {
+		return fmt.Errorf("failed to record import batch: %w", err)
+	}
+	return nil
+}
+
+// GetLatest returns the most recent import of the file with the given hash,
+// or ErrImportBatchNotFound.
+func (r *ImportBatchRepository) GetLatest(ctx context.Context, userID int64, fileHash string) (*ImportBatch, error) {
+	var batch ImportBatch
+	err := r.db.QueryRow(ctx, `
+		SELECT file_hash, row_count, created_at
+		FROM import_batches
+		WHERE user_id = $1 AND file_hash = $2
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`, userID, fileHash).Scan(&batch.FileHash, &batch.RowCount, &batch.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrImportBatchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import batch: %w", err)
+	}
+	return &batch, nil
+}
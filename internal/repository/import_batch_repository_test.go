@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
+)
+
+func TestImportBatchRepository(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+	repo := NewImportBatchRepository(tx)
+
+	const userID = int64(433001)
+
+	_, err := repo.GetLatest(ctx, userID, "abc")
+	require.ErrorIs(t, err, ErrImportBatchNotFound)
+
+	require.NoError(t, repo.Record(ctx, userID, "abc", 12))
+	require.NoError(t, repo.Record(ctx, userID, "abc", 0))
+	require.NoError(t, repo.Record(ctx, userID+1, "def", 3))
+
+	batch, err := repo.GetLatest(ctx, userID, "abc")
+	require.NoError(t, err)
+	require.Equal(t, "abc", batch.FileHash)
+	require.Equal(t, 0, batch.RowCount, "the latest import wins")
+	require.False(t, batch.CreatedAt.IsZero())
+
+	_, err = repo.GetLatest(ctx, userID, "def")
+	require.ErrorIs(t, err, ErrImportBatchNotFound, "other users' files do not count")
+}
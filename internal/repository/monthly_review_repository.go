@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+)
+
+// MonthlyReview is the note a user attached to a month, keyed like
+// "2026-03".
+type MonthlyReview struct {
+	Month string
+	Note  string
+}
+
+// MonthlyReviewRepository handles monthly review notes.
+type MonthlyReviewRepository struct {
+	db database.PGXDB
+}
+
+// NewMonthlyReviewRepository creates a new MonthlyReviewRepository.
+func NewMonthlyReviewRepository(db database.PGXDB) *MonthlyReviewRepository {
+	return &MonthlyReviewRepository{db: db}
+}
+
+// Set creates or replaces the note of a month.
+func (r *MonthlyReviewRepository) Set(ctx context.Context, userID int64, month, note string) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO monthly_reviews (user_id, month, note)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, month) DO UPDATE SET note = EXCLUDED.note, updated_at = NOW()
+	`, userID, month, note)
+	if err != nil {
+		return fmt.Errorf("failed to set monthly review: %w", err)
+	}
+	return nil
+}
+
+// Get returns the note of a month, or an empty string when there is none.
+func (r *MonthlyReviewRepository) Get(ctx context.Context, userID int64, month string) (string, error) {
+	var note string
+	err := r.db.QueryRow(ctx, `
+		SELECT note FROM monthly_reviews WHERE user_id = $1 AND month = $2
+	`, userID, month).Scan(&note)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get monthly review: %w", err)
+	}
+	return note, nil
+}
+
+// Delete removes the note of a month. It reports whether there was one.
+func (r *MonthlyReviewRepository) Delete(ctx context.Context, userID int64, month string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM monthly_reviews WHERE user_id = $1 AND month = $2
+	`, userID, month)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete monthly review: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// List returns all of a user's notes, oldest month first.
+func (r *MonthlyReviewRepository) List(ctx context.Context, userID int64) ([]MonthlyReview, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT month, note FROM monthly_reviews WHERE user_id = $1 ORDER BY month
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list monthly reviews: %w", err)
+	}
+	defer rows.Close()
+
+	var reviews []MonthlyReview
+	for rows.Next() {
+		var review MonthlyReview
+		if err := rows.Scan(&review.Month, &review.Note); err != nil {
+			return nil, fmt.Errorf("failed to scan monthly review: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate monthly reviews: %w", err)
+	}
+	return reviews, nil
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
+)
+
+func TestMonthlyReviewRepository(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+	repo := NewMonthlyReviewRepository(tx)
+
+	const userID = int64(429001)
+
+	note, err := repo.Get(ctx, userID, "2026-03")
+	require.NoError(t, err)
+	require.Empty(t, note)
+
+	require.NoError(t, repo.Set(ctx, userID, "2026-03", "Over on dining"))
+	require.NoError(t, repo.Set(ctx, userID, "2026-03", "Over on dining, fine otherwise"))
+	require.NoError(t, repo.Set(ctx, userID, "2026-01", "Quiet month"))
+	require.NoError(t, repo.Set(ctx, userID+1, "2026-03", "Someone else"))
+
+	note, err = repo.Get(ctx, userID, "2026-03")
+	require.NoError(t, err)
+	require.Equal(t, "Over on dining, fine otherwise", note, "a second note replaces the first")
+
+	reviews, err := repo.List(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, []MonthlyReview{
+		{Month: "2026-01", Note: "Quiet month"},
+		{Month: "2026-03", Note: "Over on dining, fine otherwise"},
+	}, reviews)
+
+	removed, err := repo.Delete(ctx, userID, "2026-03")
+	require.NoError(t, err)
+	require.True(t, removed)
+	removed, err = repo.Delete(ctx, userID, "2026-03")
+	require.NoError(t, err)
+	require.False(t, removed)
+
+	note, err = repo.Get(ctx, userID+1, "2026-03")
+	require.NoError(t, err)
+	require.Equal(t, "Someone else", note)
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+)
+
+// OutboxMessage is a scheduled message waiting in the outbox. DedupeKey
+// names the job, user and period it belongs to. ReplyMarkup is the JSON
+// encoded reply markup, or empty. Attempts counts the deliveries tried so
+// far, including a claimed one.
+type OutboxMessage struct {
+	ID           int
+	ChatID       int64
+	Text         string
+	ParseMode    string
+	ReplyMarkup  string
+	DedupeKey    string
+	ScheduledFor time.Time
+	Attempts     int
+}
+
+// OutboxRepository handles the outbox of scheduled messages.
+type OutboxRepository struct {
+	db database.PGXDB
+}
+
+// NewOutboxRepository creates a new OutboxRepository. Pass a transaction to
+// queue messages together with a job's own bookkeeping.
+func NewOutboxRepository(db database.PGXDB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Enqueue queues msg for delivery at msg.ScheduledFor. A message with the
+// same dedupe key queued before, sent or not, wins: Enqueue then reports
+// false and leaves the outbox as it was.
+func (r *OutboxRepository) Enqueue(ctx context.Context, msg *OutboxMessage) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO outbox (chat_id, text, parse_mode, reply_markup, dedupe_key, scheduled_for)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (dedupe_key) DO NOTHING
+	`, msg.ChatID, msg.Text, msg.ParseMode, msg.ReplyMarkup, msg.DedupeKey, msg.ScheduledFor)
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue outbox message: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Claim returns up to limit messages due at now, oldest first, and holds
+// them until leaseUntil: a claimed message that is neither marked sent nor
+// rescheduled by then is claimed again, so a crash mid-delivery delivers it
+// once more rather than never. Each claim counts as an attempt. Concurrent
+// claims skip each other's messages.
+func (r *OutboxRepository) Claim(
+	ctx context.Context,
+	now, leaseUntil time.Time,
+	limit int,
+) ([]OutboxMessage, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH due AS (
+			SELECT id FROM outbox
+			WHERE sent_at IS NULL AND failed_at IS NULL AND scheduled_for <= $1
+			ORDER BY scheduled_for, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE outbox o SET scheduled_for = $2, attempts = o.attempts + 1
+		FROM due WHERE o.id = due.id
+		RETURNING o.id, o.chat_id, o.text, o.parse_mode, o.reply_markup, o.dedupe_key, o.scheduled_for, o.attempts
+	`, now, leaseUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []OutboxMessage
+	for rows.Next() {
+		var msg OutboxMessage
+		if err := rows.Scan(&msg.ID, &msg.ChatID, &msg.Text, &msg.ParseMode, &msg.ReplyMarkup,
+			&msg.DedupeKey, &msg.ScheduledFor, &msg.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate outbox messages: %w", err)
+	}
+	// RETURNING has no order; deliver in the order the messages were queued.
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, nil
+}
+
+// MarkSent records that a message was delivered.
+func (r *OutboxRepository) MarkSent(ctx context.Context, id int) error {
+	_, err := r.db.Exec(ctx, `UPDATE outbox SET sent_at = NOW(), last_error = '' WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox message sent: %w", err)
+	}
+	return nil
+}
+
+// Retry records a failed delivery and schedules the next attempt at retryAt.
+func (r *OutboxRepository) Retry(ctx context.Context, id int, retryAt time.Time, lastError string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE outbox SET scheduled_for = $2, last_error = $3 WHERE id = $1
+	`, id, retryAt, lastError)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule outbox message: %w", err)
+	}
+	return nil
+}
+
+// Fail gives up on a message after a failed delivery.
+func (r *OutboxRepository) Fail(ctx context.Context, id int, lastError string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE outbox SET failed_at = NOW(), last_error = $2 WHERE id = $1
+	`, id, lastError)
+	if err != nil {
+		return fmt.Errorf("failed to fail outbox message: %w", err)
+	}
+	return nil
+}
+
+// DeleteFinishedBefore removes messages sent or given up on before cutoff
+// and returns how many were removed. Their dedupe keys can be queued again.
+func (r *OutboxRepository) DeleteFinishedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM outbox WHERE COALESCE(sent_at, failed_at) < $1
+	`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete finished outbox messages: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
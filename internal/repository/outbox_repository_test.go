@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
+)
+
+func TestOutboxRepository(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+	repo := NewOutboxRepository(tx)
+
+	now := time.Date(2026, 5, 4, 1, 0, 0, 0, time.UTC)
+	enqueue := func(key string, at time.Time) bool {
+		queued, err := repo.Enqueue(ctx, &OutboxMessage{
+			ChatID:       440001,
+			Text:         "Text of " + key,
+			ParseMode:    "HTML",
+			DedupeKey:    key,
+			ScheduledFor: at,
+		})
+		require.NoError(t, err)
+		return queued
+	}
+	claim := func(at time.Time) []OutboxMessage {
+		messages, err := repo.Claim(ctx, at, at.Add(5*time.Minute), 10)
+		require.NoError(t, err)
+		return messages
+	}
+
+	require.True(t, enqueue("weekly_report:440001:2026-04-27", now))
+	require.True(t, enqueue("uncategorized_warning:440001:2026-04-27", now))
+	require.True(t, enqueue("later:440001:2026-04-27", now.Add(time.Hour)))
+	require.False(t, enqueue("weekly_report:440001:2026-04-27", now), "the dedupe key is queued once")
+
+	messages := claim(now)
+	require.Len(t, messages, 2, "messages scheduled later are not due")
+	require.Equal(t, "weekly_report:440001:2026-04-27", messages[0].DedupeKey)
+	require.Equal(t, "Text of weekly_report:440001:2026-04-27", messages[0].Text)
+	require.Equal(t, int64(440001), messages[0].ChatID)
+	require.Equal(t, "HTML", messages[0].ParseMode)
+	require.Equal(t, 1, messages[0].Attempts)
+	require.Empty(t, claim(now.Add(time.Minute)), "claimed messages are held for the lease")
+
+	t.Run("unfinished claims are delivered again after the lease", func(t *testing.T) {
+		again := claim(now.Add(5 * time.Minute))
+		require.Len(t, again, 2)
+		require.Equal(t, 2, again[0].Attempts)
+	})
+
+	t.Run("sent messages are done", func(t *testing.T) {
+		require.NoError(t, repo.MarkSent(ctx, messages[0].ID))
+		require.NoError(t, repo.Fail(ctx, messages[1].ID, "blocked"))
+		require.Len(t, claim(now.Add(2*time.Hour)), 1, "only the later message is left")
+		require.False(t, enqueue("weekly_report:440001:2026-04-27", now), "a sent message still dedupes")
+	})
+
+	t.Run("retried messages wait until the retry", func(t *testing.T) {
+		require.True(t, enqueue("retry:440001:2026-04-27", now))
+		retried := claim(now)
+		require.Len(t, retried, 1)
+		require.NoError(t, repo.Retry(ctx, retried[0].ID, now.Add(time.Minute), "timeout"))
+		require.Empty(t, claim(now.Add(30*time.Second)))
+		require.Len(t, claim(now.Add(time.Minute)), 1)
+
+		var lastError string
+		require.NoError(t, tx.QueryRow(ctx, `SELECT last_error FROM outbox WHERE id = $1`, retried[0].ID).
+			Scan(&lastError))
+		require.Equal(t, "timeout", lastError)
+	})
+
+	t.Run("finished messages are pruned", func(t *testing.T) {
+		deleted, err := repo.DeleteFinishedBefore(ctx, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, int64(2), deleted)
+		require.True(t, enqueue("weekly_report:440001:2026-04-27", now))
+	})
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+)
+
+// PromptVariant is a stored receipt prompt template. An active variant
+// serves Share percent of receipt scans.
+type PromptVariant struct {
+	ID        int
+	Name      string
+	Template  string
+	Active    bool
+	Share     int
+	CreatedAt time.Time
+}
+
+// PromptVariantRepository handles the receipt prompt variants managed with
+// /promptvariants.
+type PromptVariantRepository struct {
+	db database.PGXDB
+}
+
+// NewPromptVariantRepository creates a new PromptVariantRepository.
+func NewPromptVariantRepository(db database.PGXDB) *PromptVariantRepository {
+	return &PromptVariantRepository{db: db}
+}
+
+// List returns all variants, oldest first.
+func (r *PromptVariantRepository) List(ctx context.Context) ([]PromptVariant, error) {
+	return r.list(ctx, `
+		SELECT id, name, template, active, share, created_at FROM prompt_variants
+		ORDER BY id
+	`)
+}
+
+// ListActive returns the active variants with a share, oldest first.
+func (r *PromptVariantRepository) ListActive(ctx context.Context) ([]PromptVariant, error) {
+	return r.list(ctx, `
+		SELECT id, name, template, active, share, created_at FROM prompt_variants
+		WHERE active AND share > 0
+		ORDER BY id
+	`)
+}
+
+func (r *PromptVariantRepository) list(ctx context.Context, query string) ([]PromptVariant, error) {
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prompt variants: %w", err)
+	}
+	defer rows.Close()
+
+	var variants []PromptVariant
+	for rows.Next() {
+		var v PromptVariant
+		if err := rows.Scan(&v.ID, &v.Name, &v.Template, &v.Active, &v.Share, &v.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan prompt variant: %w", err)
+		}
+		variants = append(variants, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate prompt variants: %w", err)
+	}
+	return variants, nil
+}
+
+// Add stores a new inactive variant with no share. It reports false when
+// a variant of that name exists; its template is not replaced, so scans
+// recorded under a name always used the same prompt.
+func (r *PromptVariantRepository) Add(ctx context.Context, name, template string, createdBy int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO prompt_variants (name, template, created_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO NOTHING
+	`, name, template, createdBy)
+	if err != nil {
+		return false, fmt.Errorf("failed to add prompt variant: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// SetActive turns a variant on or off. It reports whether the variant
+// exists.
+func (r *PromptVariantRepository) SetActive(ctx context.Context, name string, active bool) (bool, error) {
+	tag, err := r.db.Exec(ctx, `UPDATE prompt_variants SET active = $2 WHERE name = $1`, name, active)
+	if err != nil {
+		return false, fmt.Errorf("failed to update prompt variant: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// SetShare sets the percentage of scans a variant serves while active. It
+// reports whether the variant exists.
+func (r *PromptVariantRepository) SetShare(ctx context.Context, name string, share int) (bool, error) {
+	tag, err := r.db.Exec(ctx, `UPDATE prompt_variants SET share = $2 WHERE name = $1`, name, share)
+	if err != nil {
+		return false, fmt.Errorf("failed to update prompt variant share: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+)
+
+// ReceiptFile is a receipt image kept by the receipt storage. ExpenseID is
+// zero once the expense was deleted and the file waits for cleanup.
+type ReceiptFile struct {
+	ID         int
+	ExpenseID  int
+	UserID     int64
+	StorageKey string
+	SizeBytes  int64
+}
+
+// ReceiptFileStats sums up the stored receipt images. Orphans are files
+// whose expense was deleted and that the cleanup has not removed yet.
+type ReceiptFileStats struct {
+	Files       int
+	Bytes       int64
+	Users       int
+	Orphans     int
+	OrphanBytes int64
+}
+
+// ReceiptFileRepository handles stored receipt image records.
+type ReceiptFileRepository struct {
+	db database.PGXDB
+}
+
+// NewReceiptFileRepository creates a new ReceiptFileRepository.
+func NewReceiptFileRepository(db database.PGXDB) *ReceiptFileRepository {
+	return &ReceiptFileRepository{db: db}
+}
+
+// Record stores where the receipt image of an expense is kept, replacing
+// an earlier record of the same expense.
+func (r *ReceiptFileRepository) Record(ctx context.Context, file ReceiptFile) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO receipt_files (expense_id, user_id, storage_key, size_bytes)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (expense_id)
+		DO UPDATE SET storage_key = EXCLUDED.storage_key, size_bytes = EXCLUDED.size_bytes
+	`, file.ExpenseID, file.UserID, file.StorageKey, file.SizeBytes)
+	if err != nil {
+		return fmt.Errorf("failed to record receipt file: %w", err)
+	}
+	return nil
+}
+
+// GetByExpenseID returns the stored receipt image of an expense. The error
+// wraps pgx.ErrNoRows when none is stored.
+func (r *ReceiptFileRepository) GetByExpenseID(ctx context.Context, expenseID int) (*ReceiptFile, error) {
+	file := &ReceiptFile{ExpenseID: expenseID}
+	err := r.db.QueryRow(ctx, `
+		SELECT id, user_id, storage_key, size_bytes
+		FROM receipt_files
+		WHERE expense_id = $1
+	`, expenseID).Scan(&file.ID, &file.UserID, &file.StorageKey, &file.SizeBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt file: %w", err)
+	}
+	return file, nil
+}
+
+// ListOrphans returns up to limit files whose expense was deleted, oldest
+// first.
+func (r *ReceiptFileRepository) ListOrphans(ctx context.Context, limit int) ([]ReceiptFile, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, user_id, storage_key, size_bytes
+		FROM receipt_files
+		WHERE expense_id IS NULL
+		ORDER BY id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orphaned receipt files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []ReceiptFile
+	for rows.Next() {
+		var file ReceiptFile
+		if err := rows.Scan(&file.ID, &file.UserID, &file.StorageKey, &file.SizeBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan receipt file: %w", err)
+		}
+		files = append(files, file)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate receipt files: %w", err)
+	}
+	return files, nil
+}
+
+// Delete removes a receipt file record once its image is gone.
+func (r *ReceiptFileRepository) Delete(ctx context.Context, id int) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM receipt_files WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete receipt file: %w", err)
+	}
+	return nil
+}
+
+// Stats sums up all stored receipt images.
+func (r *ReceiptFileRepository) Stats(ctx context.Context) (*ReceiptFileStats, error) {
+	return r.StatsByUser(ctx, 0)
+}
+
+// StatsByUser sums up the stored receipt images of userID, or of every user
+// when userID is zero.
+func (r *ReceiptFileRepository) StatsByUser(ctx context.Context, userID int64) (*ReceiptFileStats, error) {
+	stats := &ReceiptFileStats{}
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(size_bytes), 0), COUNT(DISTINCT user_id),
+			COUNT(*) FILTER (WHERE expense_id IS NULL),
+			COALESCE(SUM(size_bytes) FILTER (WHERE expense_id IS NULL), 0)
+		FROM receipt_files
+		WHERE $1::BIGINT = 0 OR user_id = $1
+	`, userID).Scan(&stats.Files, &stats.Bytes, &stats.Users, &stats.Orphans, &stats.OrphanBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt file stats: %w", err)
+	}
+	return stats, nil
+}
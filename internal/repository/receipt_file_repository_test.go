@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
+)
+
+func TestReceiptFileRepository(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	userRepo := NewUserRepository(tx)
+	expenseRepo := NewExpenseRepository(tx)
+	repo := NewReceiptFileRepository(tx)
+
+	userID := int64(410001)
+	require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: userID, FirstName: "Stored"}))
+
+	newExpense := func() *models.Expense {
+		expense := &models.Expense{
+			UserID:   userID,
+			Amount:   decimal.NewFromFloat(4.20),
+			Currency: "SGD",
+			Status:   models.ExpenseStatusDraft,
+		}
+		require.NoError(t, expenseRepo.Create(ctx, expense))
+		return expense
+	}
+	kept, deleted := newExpense(), newExpense()
+
+	require.NoError(t, repo.Record(ctx, ReceiptFile{ExpenseID: kept.ID, UserID: userID, StorageKey: "a", SizeBytes: 10}))
+	require.NoError(t, repo.Record(ctx, ReceiptFile{ExpenseID: kept.ID, UserID: userID, StorageKey: "b", SizeBytes: 100}))
+	require.NoError(t, repo.Record(ctx, ReceiptFile{
+		ExpenseID: deleted.ID, UserID: userID, StorageKey: "c", SizeBytes: 50,
+	}))
+
+	file, err := repo.GetByExpenseID(ctx, kept.ID)
+	require.NoError(t, err)
+	require.Equal(t, "b", file.StorageKey, "a new record replaces the old one")
+	require.Equal(t, int64(100), file.SizeBytes)
+
+	require.NoError(t, expenseRepo.Delete(ctx, deleted.ID))
+	_, err = repo.GetByExpenseID(ctx, deleted.ID)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	stats, err := repo.Stats(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, stats.Files, 2)
+	require.GreaterOrEqual(t, stats.Bytes, int64(150))
+	require.GreaterOrEqual(t, stats.Orphans, 1)
+
+	orphans, err := repo.ListOrphans(ctx, 100)
+	require.NoError(t, err)
+	var orphan *ReceiptFile
+	for i := range orphans {
+		if orphans[i].StorageKey == "c" {
+			orphan = &orphans[i]
+		}
+	}
+	require.NotNil(t, orphan)
+	require.Zero(t, orphan.ExpenseID)
+
+	require.NoError(t, repo.Delete(ctx, orphan.ID))
+	orphans, err = repo.ListOrphans(ctx, 100)
+	require.NoError(t, err)
+	for i := range orphans {
+		require.NotEqual(t, "c", orphans[i].StorageKey)
+	}
+}
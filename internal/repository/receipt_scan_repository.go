@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+)
+
+// ReceiptScan records which Gemini model, prompt version and prompt
+// variant parsed a receipt draft. PromptVariant is empty for the built-in
+// prompt. SuggestedCategory is the category Gemini suggested when it
+// matched none of the existing ones, until the user uses or ignores it.
+// Amount, Currency and Merchant are the draft's values as scanned, which
+// tell corrected drafts apart. ValuesRecorded is set when they were
+// recorded, so a scanned amount of zero still counts. ItemsSum is the sum
+// of the line items when it disagreed with the total and was offered
+// instead.
+type ReceiptScan struct {
+	ExpenseID         int
+	UserID            int64
+	Model             string
+	PromptVersion     string
+	PromptVariant     string
+	SuggestedCategory string
+	Amount            decimal.Decimal
+	Currency          string
+	Merchant          string
+	ValuesRecorded    bool
+	ItemsSum          decimal.NullDecimal
+}
+
+// ReceiptScanStats aggregates receipt scans for one model, prompt version
+// and prompt variant.
+type ReceiptScanStats struct {
+	Model         string
+	PromptVersion string
+	PromptVariant string
+	Scans         int
+	Confirmed     int
+	Deleted       int
+	// Corrected counts confirmed expenses whose amount, currency or
+	// merchant differs from what was scanned.
+	Corrected int
+}
+
+// CorrectionRate returns the share of confirmed scans that were
+// corrected, from 0 to 1.
+func (s ReceiptScanStats) CorrectionRate() float64 {
+	if s.Confirmed == 0 {
+		return 0
+	}
+	return float64(s.Corrected) / float64(s.Confirmed)
+}
+
+// ReceiptScanRepository handles receipt scan metadata.
+type ReceiptScanRepository struct {
+	db database.PGXDB
+}
+
+// NewReceiptScanRepository creates a new ReceiptScanRepository.
+func NewReceiptScanRepository(db database.PGXDB) *ReceiptScanRepository {
+	return &ReceiptScanRepository{db: db}
+}
+
+// Record stores the model and prompt used for a receipt draft.
+func (r *ReceiptScanRepository) Record(ctx context.Context, scan ReceiptScan) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO receipt_scans (
+			expense_id, user_id, model, prompt_version, prompt_variant, suggested_category,
+			scanned_amount, scanned_currency, scanned_merchant, scanned_recorded, scanned_items_sum
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, scan.ExpenseID, scan.UserID, scan.Model, scan.PromptVersion, scan.PromptVariant, scan.SuggestedCategory,
+		scan.Amount, scan.Currency, scan.Merchant, scan.ValuesRecorded, scan.ItemsSum)
+	if err != nil {
+		return fmt.Errorf("failed to record receipt scan: %w", err)
+	}
+	return nil
+}
+
+// GetByExpenseID returns the scan recorded for an expense. The error wraps
+// pgx.ErrNoRows when the expense was not created from a receipt.
+func (r *ReceiptScanRepository) GetByExpenseID(ctx context.Context, expenseID int) (*ReceiptScan, error) {
+	scan := &ReceiptScan{ExpenseID: expenseID}
+	err := r.db.QueryRow(ctx, `
+		SELECT user_id, model, prompt_version, prompt_variant, suggested_category,
+			COALESCE(scanned_amount, 0), scanned_currency, scanned_merchant, scanned_recorded,
+			scanned_items_sum
+		FROM receipt_scans
+		WHERE expense_id = $1
+		ORDER BY id DESC
+		LIMIT 1
+	`, expenseID).Scan(&scan.UserID, &scan.Model, &scan.PromptVersion, &scan.PromptVariant, &scan.SuggestedCategory,
+		&scan.Amount, &scan.Currency, &scan.Merchant, &scan.ValuesRecorded, &scan.ItemsSum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt scan: %w", err)
+	}
+	return scan, nil
+}
+
+// ClearSuggestedCategory drops the category suggestion of an expense's
+// receipt once the user used or ignored it.
+func (r *ReceiptScanRepository) ClearSuggestedCategory(ctx context.Context, expenseID int) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE receipt_scans SET suggested_category = '' WHERE expense_id = $1
+	`, expenseID)
+	if err != nil {
+		return fmt.Errorf("failed to clear suggested category: %w", err)
+	}
+	return nil
+}
+
+// StatsByModel returns scan counts grouped by model, prompt version and
+// prompt variant. Confirmed counts drafts the user kept; deleted counts
+// drafts that no longer exist (cancelled or removed later). Scans recorded
+// without their scanned values are never counted as corrected.
+func (r *ReceiptScanRepository) StatsByModel(ctx context.Context) ([]ReceiptScanStats, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT s.model, s.prompt_version, s.prompt_variant,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE e.status = 'confirmed'),
+			COUNT(*) FILTER (WHERE s.expense_id IS NULL),
+			COUNT(*) FILTER (WHERE e.status = 'confirmed' AND s.scanned_recorded AND (
+				e.amount <> s.scanned_amount
+				OR e.currency <> s.scanned_currency
+				OR e.merchant <> s.scanned_merchant
+			))
+		FROM receipt_scans s
+		LEFT JOIN expenses e ON e.id = s.expense_id
+		GROUP BY s.model, s.prompt_version, s.prompt_variant
+		ORDER BY s.model, s.prompt_version, s.prompt_variant
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt scan stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []ReceiptScanStats
+	for rows.Next() {
+		var s ReceiptScanStats
+		if err := rows.Scan(
+			&s.Model, &s.PromptVersion, &s.PromptVariant, &s.Scans, &s.Confirmed, &s.Deleted, &s.Corrected,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan receipt scan stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate receipt scan stats: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
+)
+
+const (
+	testScanModel  = "gemini-2.5-flash"
+	testScanPrompt = "receipt-v1"
+)
+
+func TestReceiptScanRepository(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	userRepo := NewUserRepository(tx)
+	expenseRepo := NewExpenseRepository(tx)
+	repo := NewReceiptScanRepository(tx)
+
+	userID := int64(371001)
+	require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: userID, FirstName: "Scan"}))
+
+	newExpense := func(status models.ExpenseStatus) *models.Expense {
+		expense := &models.Expense{
+			UserID:   userID,
+			Amount:   decimal.NewFromFloat(9.90),
+			Currency: "SGD",
+			Status:   status,
+		}
+		require.NoError(t, expenseRepo.Create(ctx, expense))
+		return expense
+	}
+
+	confirmed := newExpense(models.ExpenseStatusConfirmed)
+	draft := newExpense(models.ExpenseStatusDraft)
+	cancelled := newExpense(models.ExpenseStatusDraft)
+	manual := newExpense(models.ExpenseStatusConfirmed)
+	kept := newExpense(models.ExpenseStatusConfirmed)
+	corrected := newExpense(models.ExpenseStatusConfirmed)
+	corrected.Merchant = "Cold Storage"
+	require.NoError(t, expenseRepo.Update(ctx, corrected))
+	readZero := newExpense(models.ExpenseStatusConfirmed)
+
+	for _, scan := range []ReceiptScan{
+		{ExpenseID: confirmed.ID, UserID: userID, Model: testScanModel, PromptVersion: testScanPrompt},
+		{
+			ExpenseID: kept.ID, UserID: userID, Model: testScanModel, PromptVersion: testScanPrompt,
+			PromptVariant: "terse", Amount: kept.Amount, Currency: "SGD", ValuesRecorded: true,
+		},
+		{
+			ExpenseID: corrected.ID, UserID: userID, Model: testScanModel, PromptVersion: testScanPrompt,
+			PromptVariant: "terse", Amount: corrected.Amount, Currency: "SGD", Merchant: "Cold Stor",
+			ValuesRecorded: true,
+		},
+		{
+			ExpenseID: readZero.ID, UserID: userID, Model: testScanModel, PromptVersion: testScanPrompt,
+			PromptVariant: "terse", Currency: "SGD", ValuesRecorded: true,
+		},
+		{
+			ExpenseID: draft.ID, UserID: userID, Model: testScanModel, PromptVersion: testScanPrompt,
+			SuggestedCategory: "Pharmacy",
+		},
+		{ExpenseID: cancelled.ID, UserID: userID, Model: "gemini-3-flash", PromptVersion: testScanPrompt},
+	} {
+		require.NoError(t, repo.Record(ctx, scan))
+	}
+	require.NoError(t, expenseRepo.Delete(ctx, cancelled.ID))
+
+	t.Run("get by expense", func(t *testing.T) {
+		scan, err := repo.GetByExpenseID(ctx, draft.ID)
+		require.NoError(t, err)
+		require.Equal(t, testScanModel, scan.Model)
+		require.Equal(t, testScanPrompt, scan.PromptVersion)
+		require.Equal(t, userID, scan.UserID)
+		require.Equal(t, "Pharmacy", scan.SuggestedCategory)
+	})
+
+	t.Run("clear suggested category", func(t *testing.T) {
+		require.NoError(t, repo.ClearSuggestedCategory(ctx, draft.ID))
+		scan, err := repo.GetByExpenseID(ctx, draft.ID)
+		require.NoError(t, err)
+		require.Empty(t, scan.SuggestedCategory)
+	})
+
+	t.Run("manual expense has no scan", func(t *testing.T) {
+		_, err := repo.GetByExpenseID(ctx, manual.ID)
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
+	t.Run("get scanned values", func(t *testing.T) {
+		scan, err := repo.GetByExpenseID(ctx, corrected.ID)
+		require.NoError(t, err)
+		require.Equal(t, "terse", scan.PromptVariant)
+		require.True(t, corrected.Amount.Equal(scan.Amount))
+		require.Equal(t, "Cold Stor", scan.Merchant)
+		require.True(t, scan.ValuesRecorded)
+
+		scan, err = repo.GetByExpenseID(ctx, readZero.ID)
+		require.NoError(t, err)
+		require.True(t, scan.Amount.IsZero())
+		require.True(t, scan.ValuesRecorded)
+	})
+
+	t.Run("stats grouped by model, prompt and variant", func(t *testing.T) {
+		stats, err := repo.StatsByModel(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []ReceiptScanStats{
+			{Model: testScanModel, PromptVersion: testScanPrompt, Scans: 2, Confirmed: 1},
+			{Model: testScanModel, PromptVersion: testScanPrompt, PromptVariant: "terse", Scans: 3, Confirmed: 3, Corrected: 2},
+			{Model: "gemini-3-flash", PromptVersion: testScanPrompt, Scans: 1, Deleted: 1},
+		}, stats)
+		require.InDelta(t, 2.0/3, stats[1].CorrectionRate(), 0.001, "a scan that read 0 counts as corrected")
+	})
+}
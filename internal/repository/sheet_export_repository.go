@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// ErrSheetExportNotFound is returned when a user never turned on the daily
+// sheet export.
+var ErrSheetExportNotFound = errors.New("sheet export not found")
+
+// SheetExport is a user's daily spreadsheet export state. LastExpenseID is
+// the highest expense ID when the export was turned on, which is never
+// sent; each expense sent after it is marked in expenses.sheet_exported_at.
+// RowCount is the rows sent in total and SentOn the last day (YYYY-MM-DD)
+// the export ran, or empty.
+type SheetExport struct {
+	UserID        int64
+	Enabled       bool
+	LastExpenseID int
+	RowCount      int64
+	SentOn        string
+}
+
+// SheetExportUser is an enabled sheet export with its user. User holds
+// the ID, names, timezone and archive chat.
+type SheetExportUser struct {
+	SheetExport
+
+	User models.User
+}
+
+// SheetExportRepository handles the daily sheet export state.
+type SheetExportRepository struct {
+	db database.PGXDB
+}
+
+// NewSheetExportRepository creates a new SheetExportRepository.
+func NewSheetExportRepository(db database.PGXDB) *SheetExportRepository {
+	return &SheetExportRepository{db: db}
+}
+
+// Enable turns the export on. Expenses up to lastExpenseID are not sent;
+// a watermark that is already higher is kept.
+func (r *SheetExportRepository) Enable(ctx context.Context, userID int64, lastExpenseID int) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO sheet_exports (user_id, last_expense_id) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET
+			enabled = TRUE,
+			last_expense_id = GREATEST(sheet_exports.last_expense_id, EXCLUDED.last_expense_id),
+			updated_at = NOW()
+	`, userID, lastExpenseID)
+	if err != nil {
+		return fmt.Errorf("failed to enable sheet export: %w", err)
+	}
+	return nil
+}
+
+// Disable turns the export off, keeping its row count. It reports whether
+// it was on.
+func (r *SheetExportRepository) Disable(ctx context.Context, userID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE sheet_exports SET enabled = FALSE, updated_at = NOW() WHERE user_id = $1 AND enabled
+	`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to disable sheet export: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Get returns a user's export state, or ErrSheetExportNotFound.
+func (r *SheetExportRepository) Get(ctx context.Context, userID int64) (*SheetExport, error) {
+	var export SheetExport
+	err := r.db.QueryRow(ctx, `
+		SELECT user_id, enabled, last_expense_id, row_count, COALESCE(TO_CHAR(sent_on, 'YYYY-MM-DD'), '')
+		FROM sheet_exports WHERE user_id = $1
+	`, userID).Scan(&export.UserID, &export.Enabled, &export.LastExpenseID, &export.RowCount, &export.SentOn)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSheetExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sheet export: %w", err)
+	}
+	return &export, nil
+}
+
+// Advance records a daily run on sentOn (YYYY-MM-DD) that sent the
+// expenses of expenseIDs, marking them as sent in the same statement.
+func (r *SheetExportRepository) Advance(
+	ctx context.Context,
+	userID int64,
+	expenseIDs []int,
+	sentOn string,
+) error {
+	if expenseIDs == nil {
+		expenseIDs = []int{}
+	}
+	_, err := r.db.Exec(ctx, `
+		WITH sent AS (
+			UPDATE expenses SET sheet_exported_at = NOW()
+			WHERE user_id = $1 AND id = ANY($2)
+		)
+		UPDATE sheet_exports SET
+			row_count = row_count + $3,
+			sent_on = $4::date,
+			updated_at = NOW()
+		WHERE user_id = $1
+	`, userID, expenseIDs, len(expenseIDs), sentOn)
+	if err != nil {
+		return fmt.Errorf("failed to advance sheet export: %w", err)
+	}
+	return nil
+}
+
+// ListEnabled returns the enabled exports of authorized users: superadmins
+// by ID or username, and approved users. Users who blocked the bot are
+// left out unless they have an archive chat, which is zero when the bot
+// left it.
+func (r *SheetExportRepository) ListEnabled(
+	ctx context.Context,
+	superAdminIDs []int64,
+	superAdminUsernames []string,
+) ([]SheetExportUser, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT s.user_id, s.enabled, s.last_expense_id, s.row_count, COALESCE(TO_CHAR(s.sent_on, 'YYYY-MM-DD'), ''),
+			u.username, u.first_name, u.last_name, u.timezone,
+			CASE WHEN EXISTS (SELECT 1 FROM chats c WHERE c.chat_id = u.archive_chat_id AND c.status = 'left')
+				THEN 0 ELSE COALESCE(u.archive_chat_id, 0) END
+		FROM sheet_exports s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.enabled AND (u.unreachable_since IS NULL OR u.archive_chat_id IS NOT NULL)
+		AND (
+			u.id = ANY($1)
+			OR LOWER(u.username) = ANY($2::text[])
+			OR EXISTS (SELECT 1 FROM approved_users au WHERE au.user_id = u.id AND au.user_id != 0)
+			OR EXISTS (SELECT 1 FROM approved_users au
+				WHERE LOWER(au.username) = LOWER(u.username) AND u.username != '' AND au.username != '')
+		)
+		ORDER BY s.user_id
+	`, superAdminIDs, lowercaseUsernames(superAdminUsernames))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sheet exports: %w", err)
+	}
+	defer rows.Close()
+
+	var exports []SheetExportUser
+	for rows.Next() {
+		var e SheetExportUser
+		if err := rows.Scan(
+			&e.UserID, &e.Enabled, &e.LastExpenseID, &e.RowCount, &e.SentOn,
+			&e.User.Username, &e.User.FirstName, &e.User.LastName, &e.User.Timezone, &e.User.ArchiveChatID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan sheet export: %w", err)
+		}
+		e.User.ID = e.UserID
+		exports = append(exports, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sheet exports: %w", err)
+	}
+	return exports, nil
+}
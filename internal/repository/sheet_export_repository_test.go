@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
+)
+
+func TestSheetExportRepository(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+	repo := NewSheetExportRepository(tx)
+	userRepo := NewUserRepository(tx)
+
+	const userID = int64(434001)
+	require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: userID, FirstName: testFirstName}))
+
+	_, err := repo.Get(ctx, userID)
+	require.ErrorIs(t, err, ErrSheetExportNotFound)
+
+	expenseRepo := NewExpenseRepository(tx)
+	before := time.Now().Add(time.Hour)
+	var ids []int
+	for range 2 {
+		expense := &models.Expense{
+			UserID: userID, Amount: decimal.NewFromInt(5), Currency: "SGD", Status: models.ExpenseStatusConfirmed,
+		}
+		require.NoError(t, expenseRepo.Create(ctx, expense))
+		ids = append(ids, expense.ID)
+	}
+
+	require.NoError(t, repo.Enable(ctx, userID, 0))
+	pending, err := expenseRepo.GetSheetExportPending(ctx, userID, 0, before, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+
+	require.NoError(t, repo.Advance(ctx, userID, ids[1:], "2026-03-02"))
+	export, err := repo.Get(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, SheetExport{UserID: userID, Enabled: true, RowCount: 1, SentOn: "2026-03-02"}, *export)
+
+	pending, err = expenseRepo.GetSheetExportPending(ctx, userID, 0, before, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, ids[0], pending[0].ID, "a lower ID is still sent after a higher one")
+
+	require.NoError(t, repo.Advance(ctx, userID, nil, "2026-03-03"))
+	export, err = repo.Get(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), export.RowCount)
+	require.Equal(t, "2026-03-03", export.SentOn)
+
+	exports, err := repo.ListEnabled(ctx, []int64{userID}, nil)
+	require.NoError(t, err)
+	require.Len(t, exports, 1)
+	require.Equal(t, userID, exports[0].User.ID)
+	require.Equal(t, testFirstName, exports[0].User.FirstName)
+
+	off, err := repo.Disable(ctx, userID)
+	require.NoError(t, err)
+	require.True(t, off)
+	off, err = repo.Disable(ctx, userID)
+	require.NoError(t, err)
+	require.False(t, off)
+
+	exports, err = repo.ListEnabled(ctx, []int64{userID}, nil)
+	require.NoError(t, err)
+	require.Empty(t, exports)
+
+	require.NoError(t, repo.Enable(ctx, userID, 15))
+	require.NoError(t, repo.Enable(ctx, userID, 3))
+	export, err = repo.Get(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, 15, export.LastExpenseID, "turning it on again keeps the higher watermark")
+	require.Equal(t, int64(1), export.RowCount)
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
+)
+
+// ExpenseShare is one person's part of an expense split in a group chat.
+type ExpenseShare struct {
+	UserID int64
+	Amount decimal.Decimal
+}
+
+// ChatDebt is an amount one chat member owes another in one currency.
+type ChatDebt struct {
+	FromUserID int64
+	ToUserID   int64
+	Currency   string
+	Amount     decimal.Decimal
+}
+
+// SplitRepository handles split expense shares and settlements between
+// members of a group chat.
+type SplitRepository struct {
+	db database.PGXDB
+}
+
+// NewSplitRepository creates a new SplitRepository.
+func NewSplitRepository(db database.PGXDB) *SplitRepository {
+	return &SplitRepository{db: db}
+}
+
+// CreateShares records how an expense logged in a chat is split. The shares
+// are stored in one statement, so either all of them are saved or none,
+// and are removed together with the expense.
+func (r *SplitRepository) CreateShares(ctx context.Context, expenseID int, chatID int64, shares []ExpenseShare) error {
+	if len(shares) == 0 {
+		return nil
+	}
+	userIDs, amounts := shareColumns(shares)
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO expense_shares (expense_id, user_id, chat_id, share_amount)
+		SELECT $1, s.user_id, $2, s.share_amount::NUMERIC
+		FROM unnest($3::BIGINT[], $4::TEXT[]) AS s(user_id, share_amount)
+		ON CONFLICT (expense_id, user_id) DO UPDATE SET share_amount = EXCLUDED.share_amount
+	`, expenseID, chatID, userIDs, amounts)
+	if err != nil {
+		return fmt.Errorf("failed to add shares of expense %d: %w", expenseID, err)
+	}
+	return nil
+}
+
+// UpdateShareAmounts changes the amounts of existing shares of an expense,
+// for when the expense amount was edited. All shares change in one
+// statement.
+func (r *SplitRepository) UpdateShareAmounts(ctx context.Context, expenseID int, shares []ExpenseShare) error {
+	if len(shares) == 0 {
+		return nil
+	}
+	userIDs, amounts := shareColumns(shares)
+	_, err := r.db.Exec(ctx, `
+		UPDATE expense_shares es SET share_amount = s.share_amount::NUMERIC
+		FROM unnest($2::BIGINT[], $3::TEXT[]) AS s(user_id, share_amount)
+		WHERE es.expense_id = $1 AND es.user_id = s.user_id
+	`, expenseID, userIDs, amounts)
+	if err != nil {
+		return fmt.Errorf("failed to update shares of expense %d: %w", expenseID, err)
+	}
+	return nil
+}
+
+// shareColumns splits shares into the user ID and amount arrays the bulk
+// statements take.
+func shareColumns(shares []ExpenseShare) ([]int64, []string) {
+	userIDs := make([]int64, len(shares))
+	amounts := make([]string, len(shares))
+	for i, share := range shares {
+		userIDs[i] = share.UserID
+		amounts[i] = share.Amount.String()
+	}
+	return userIDs, amounts
+}
+
+// GetShares returns the shares of an expense ordered by user ID.
+func (r *SplitRepository) GetShares(ctx context.Context, expenseID int) ([]ExpenseShare, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT user_id, share_amount FROM expense_shares
+		WHERE expense_id = $1
+		ORDER BY user_id
+	`, expenseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expense shares: %w", err)
+	}
+	defer rows.Close()
+
+	var shares []ExpenseShare
+	for rows.Next() {
+		var share ExpenseShare
+		if err := rows.Scan(&share.UserID, &share.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan expense share: %w", err)
+		}
+		shares = append(shares, share)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate expense shares: %w", err)
+	}
+	return shares, nil
+}
+
+// RecentParticipants returns the users who logged an expense in a chat
+// since the given time, most recently active first.
+func (r *SplitRepository) RecentParticipants(ctx context.Context, chatID int64, since time.Time) ([]int64, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.user_id FROM expense_source_messages m
+		JOIN expenses e ON e.id = m.expense_id
+		WHERE m.chat_id = $1 AND m.created_at >= $2
+		GROUP BY e.user_id
+		ORDER BY MAX(m.created_at) DESC, e.user_id
+	`, chatID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chat participants: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan chat participant: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate chat participants: %w", err)
+	}
+	return userIDs, nil
+}
+
+// ShareDebts returns, per pair and currency, the total of the shares each
+// member owes whoever paid confirmed split expenses in a chat.
+func (r *SplitRepository) ShareDebts(ctx context.Context, chatID int64) ([]ChatDebt, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT s.user_id, e.user_id, e.currency, SUM(s.share_amount)
+		FROM expense_shares s
+		JOIN expenses e ON e.id = s.expense_id
+		WHERE s.chat_id = $1 AND e.status = 'confirmed' AND s.user_id <> e.user_id
+		GROUP BY s.user_id, e.user_id, e.currency
+		ORDER BY e.currency, s.user_id, e.user_id
+	`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query share debts: %w", err)
+	}
+	defer rows.Close()
+
+	return scanChatDebts(rows)
+}
+
+// SettlementTotals returns, per pair and currency, the total repaid in a
+// chat.
+func (r *SplitRepository) SettlementTotals(ctx context.Context, chatID int64) ([]ChatDebt, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT from_user_id, to_user_id, currency, SUM(amount)
+		FROM settlements
+		WHERE chat_id = $1
+		GROUP BY from_user_id, to_user_id, currency
+		ORDER BY currency, from_user_id, to_user_id
+	`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query settlements: %w", err)
+	}
+	defer rows.Close()
+
+	return scanChatDebts(rows)
+}
+
+// CreateSettlement records that one chat member repaid another.
+func (r *SplitRepository) CreateSettlement(ctx context.Context, chatID int64, payment ChatDebt) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO settlements (chat_id, from_user_id, to_user_id, amount, currency)
+		VALUES ($1, $2, $3, $4, $5)
+	`, chatID, payment.FromUserID, payment.ToUserID, payment.Amount, payment.Currency)
+	if err != nil {
+		return fmt.Errorf("failed to create settlement: %w", err)
+	}
+	return nil
+}
+
+func scanChatDebts(rows pgx.Rows) ([]ChatDebt, error) {
+	var debts []ChatDebt
+	for rows.Next() {
+		var debt ChatDebt
+		if err := rows.Scan(&debt.FromUserID, &debt.ToUserID, &debt.Currency, &debt.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan chat debt: %w", err)
+		}
+		debts = append(debts, debt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate chat debts: %w", err)
+	}
+	return debts, nil
+}
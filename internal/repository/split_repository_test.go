@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
+)
+
+func TestSplitRepository(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	userRepo := NewUserRepository(tx)
+	expenseRepo := NewExpenseRepository(tx)
+	repo := NewSplitRepository(tx)
+
+	chatID := int64(-396101)
+	alice := int64(396101)
+	bob := int64(396102)
+	for _, id := range []int64{alice, bob} {
+		require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: id, FirstName: testFirstName}))
+	}
+
+	create := func(userID int64, amount string, status models.ExpenseStatus) *models.Expense {
+		e := &models.Expense{
+			UserID:   userID,
+			Amount:   decimal.RequireFromString(amount),
+			Currency: testCurrencySGD,
+			Status:   status,
+		}
+		require.NoError(t, expenseRepo.Create(ctx, e))
+		_, err := tx.Exec(ctx,
+			`INSERT INTO expense_source_messages (chat_id, message_id, expense_id) VALUES ($1, $2, $3)`,
+			chatID, e.ID, e.ID)
+		require.NoError(t, err)
+		return e
+	}
+	share := func(userID int64, amount string) ExpenseShare {
+		return ExpenseShare{UserID: userID, Amount: decimal.RequireFromString(amount)}
+	}
+
+	dinner := create(alice, "30.00", models.ExpenseStatusConfirmed)
+	require.NoError(t, repo.CreateShares(ctx, dinner.ID, chatID, []ExpenseShare{
+		share(alice, "15.00"), share(bob, "15.00"),
+	}))
+	taxi := create(bob, "10.00", models.ExpenseStatusConfirmed)
+	require.NoError(t, repo.CreateShares(ctx, taxi.ID, chatID, []ExpenseShare{
+		share(alice, "5.00"), share(bob, "5.00"),
+	}))
+	draft := create(bob, "100.00", models.ExpenseStatusDraft)
+	require.NoError(t, repo.CreateShares(ctx, draft.ID, chatID, []ExpenseShare{
+		share(alice, "50.00"), share(bob, "50.00"),
+	}))
+
+	t.Run("recent participants", func(t *testing.T) {
+		participants, err := repo.RecentParticipants(ctx, chatID, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		require.ElementsMatch(t, []int64{alice, bob}, participants)
+
+		participants, err = repo.RecentParticipants(ctx, chatID, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		require.Empty(t, participants)
+	})
+
+	t.Run("share debts skip drafts and the payer's own share", func(t *testing.T) {
+		debts, err := repo.ShareDebts(ctx, chatID)
+		require.NoError(t, err)
+		require.Len(t, debts, 2)
+		require.Equal(t, alice, debts[0].FromUserID)
+		require.Equal(t, bob, debts[0].ToUserID)
+		require.True(t, decimal.RequireFromString("5").Equal(debts[0].Amount))
+		require.Equal(t, bob, debts[1].FromUserID)
+		require.True(t, decimal.RequireFromString("15").Equal(debts[1].Amount))
+	})
+
+	t.Run("settlements are summed per pair", func(t *testing.T) {
+		for _, amount := range []string{"4.00", "2.50"} {
+			require.NoError(t, repo.CreateSettlement(ctx, chatID, ChatDebt{
+				FromUserID: bob, ToUserID: alice, Currency: testCurrencySGD, Amount: decimal.RequireFromString(amount),
+			}))
+		}
+		totals, err := repo.SettlementTotals(ctx, chatID)
+		require.NoError(t, err)
+		require.Len(t, totals, 1)
+		require.True(t, decimal.RequireFromString("6.50").Equal(totals[0].Amount))
+
+		totals, err = repo.SettlementTotals(ctx, chatID-1)
+		require.NoError(t, err)
+		require.Empty(t, totals)
+	})
+
+	t.Run("deleting the expense removes its shares", func(t *testing.T) {
+		shares, err := repo.GetShares(ctx, dinner.ID)
+		require.NoError(t, err)
+		require.Len(t, shares, 2)
+
+		require.NoError(t, expenseRepo.Delete(ctx, dinner.ID))
+		shares, err = repo.GetShares(ctx, dinner.ID)
+		require.NoError(t, err)
+		require.Empty(t, shares)
+	})
+}
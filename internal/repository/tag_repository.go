@@ -118,6 +118,23 @@ func (r *TagRepository) AddTagsToExpense(ctx context.Context, expenseID int, tag
 	return nil
 }
 
+// BulkAttach adds a tag to many expenses with one insert. Expenses that
+// already have the tag are skipped; the result is how many were tagged.
+func (r *TagRepository) BulkAttach(ctx context.Context, expenseIDs []int, tagID int) (int64, error) {
+	if len(expenseIDs) == 0 {
+		return 0, nil
+	}
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO expense_tags (expense_id, tag_id)
+		SELECT id, $2 FROM unnest($1::int[]) AS id
+		ON CONFLICT DO NOTHING
+	`, expenseIDs, tagID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to attach tag %d: %w", tagID, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
 // RemoveTagFromExpense removes a tag from an expense.
 func (r *TagRepository) RemoveTagFromExpense(ctx context.Context, expenseID, tagID int) error {
 	_, err := r.db.Exec(ctx, `DELETE FROM expense_tags WHERE expense_id = $1 AND tag_id = $2`, expenseID, tagID)
@@ -157,6 +174,26 @@ func (r *TagRepository) GetAllByUserID(ctx context.Context, userID int64) ([]mod
 	return scanTags(rows)
 }
 
+// Count returns how many tags userID has on their expenses, or how many
+// tags exist when userID is zero.
+func (r *TagRepository) Count(ctx context.Context, userID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `
+		SELECT CASE WHEN $1::BIGINT = 0 THEN (SELECT COUNT(*) FROM tags)
+			ELSE (
+				SELECT COUNT(DISTINCT et.tag_id)
+				FROM expense_tags et
+				JOIN expenses e ON e.id = et.expense_id
+				WHERE e.user_id = $1
+			)
+		END
+	`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tags: %w", err)
+	}
+	return count, nil
+}
+
 // GetByName retrieves a tag by name (exact match).
 func (r *TagRepository) GetByName(ctx context.Context, name string) (*models.Tag, error) {
 	var tag models.Tag
@@ -177,6 +214,37 @@ func (r *TagRepository) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
+// GetFrequentTagsForDescription returns the tags a user applied to at least
+// minUses confirmed expenses whose description matches description, ignoring
+// case and extra whitespace. The most used tags come first.
+func (r *TagRepository) GetFrequentTagsForDescription(
+	ctx context.Context,
+	userID int64,
+	description string,
+	minUses int,
+) ([]models.Tag, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT t.id, t.name, t.created_at
+		FROM tags t
+		JOIN expense_tags et ON t.id = et.tag_id
+		JOIN expenses e ON e.id = et.expense_id
+		WHERE e.user_id = $1
+		  AND e.status = 'confirmed'
+		  AND regexp_replace(LOWER(TRIM(e.description)), '\s+', ' ', 'g') =
+		      regexp_replace(LOWER(TRIM($2)), '\s+', ' ', 'g')
+		GROUP BY t.id, t.name, t.created_at
+		HAVING COUNT(*) >= $3
+		ORDER BY COUNT(*) DESC, t.name
+		LIMIT 5
+	`, userID, description, minUses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query frequent tags: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTags(rows)
+}
+
 // GetExpensesByTagID retrieves confirmed expenses that have a specific tag.
 func (r *TagRepository) GetExpensesByTagID(ctx context.Context, userID int64, tagID, limit int) ([]models.Expense, error) {
 	rows, err := r.db.Query(ctx, `
@@ -246,3 +314,72 @@ func scanTags(rows interface {
 	}
 	return tags, nil
 }
+
+// GetCategoryDefaults returns the tags added to every expense in a category.
+func (r *TagRepository) GetCategoryDefaults(ctx context.Context, categoryID int) ([]models.Tag, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT t.id, t.name, t.created_at
+		FROM tags t
+		JOIN category_default_tags cdt ON t.id = cdt.tag_id
+		WHERE cdt.category_id = $1
+		ORDER BY t.name
+	`, categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category default tags: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTags(rows)
+}
+
+// GetAllCategoryDefaults returns the default tags of every category that has
+// some, keyed by category ID.
+func (r *TagRepository) GetAllCategoryDefaults(ctx context.Context) (map[int][]models.Tag, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT cdt.category_id, t.id, t.name, t.created_at
+		FROM tags t
+		JOIN category_default_tags cdt ON t.id = cdt.tag_id
+		ORDER BY cdt.category_id, t.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category default tags: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int][]models.Tag)
+	for rows.Next() {
+		var categoryID int
+		var tag models.Tag
+		if err := rows.Scan(&categoryID, &tag.ID, &tag.Name, &tag.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan category default tag: %w", err)
+		}
+		result[categoryID] = append(result[categoryID], tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating category default tags: %w", err)
+	}
+	return result, nil
+}
+
+// SetCategoryDefaults replaces the default tags of a category. An empty
+// tagIDs clears them. The replacement is one statement, so a failure
+// leaves the old defaults in place.
+func (r *TagRepository) SetCategoryDefaults(ctx context.Context, categoryID int, tagIDs []int) error {
+	if tagIDs == nil {
+		// A nil slice is sent as NULL, which <> ALL never matches.
+		tagIDs = []int{}
+	}
+	_, err := r.db.Exec(ctx, `
+		WITH cleared AS (
+			DELETE FROM category_default_tags
+			WHERE category_id = $1 AND tag_id <> ALL($2::INTEGER[])
+		)
+		INSERT INTO category_default_tags (category_id, tag_id)
+		SELECT $1, unnest($2::INTEGER[])
+		ON CONFLICT DO NOTHING
+	`, categoryID, tagIDs)
+	if err != nil {
+		return fmt.Errorf("failed to set default tags of category %d: %w", categoryID, err)
+	}
+	return nil
+}
@@ -223,6 +223,32 @@ func TestTagRepository_AddTagsToExpense(t *testing.T) {
 	require.Len(t, tags, 2)
 }
 
+func TestTagRepository_BulkAttach(t *testing.T) {
+	tagRepo, expenseRepo, userRepo, ctx := setupTagTest(t)
+	first := createTestExpense(t, userRepo, expenseRepo, ctx, 702)
+	second := createTestExpense(t, userRepo, expenseRepo, ctx, 702)
+
+	tag, err := tagRepo.GetOrCreate(ctx, "trip")
+	require.NoError(t, err)
+	require.NoError(t, tagRepo.AddTagsToExpense(ctx, first.ID, []int{tag.ID}))
+
+	tagged, err := tagRepo.BulkAttach(ctx, []int{first.ID, second.ID}, tag.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), tagged, "expenses that already have the tag are skipped")
+
+	tagged, err = tagRepo.BulkAttach(ctx, []int{first.ID, second.ID}, tag.ID)
+	require.NoError(t, err)
+	require.Zero(t, tagged)
+
+	tagged, err = tagRepo.BulkAttach(ctx, nil, tag.ID)
+	require.NoError(t, err)
+	require.Zero(t, tagged)
+
+	tags, err := tagRepo.GetByExpenseIDs(ctx, []int{first.ID, second.ID})
+	require.NoError(t, err)
+	require.Len(t, tags[second.ID], 1)
+}
+
 func TestTagRepository_RemoveTagFromExpense(t *testing.T) {
 	tagRepo, expenseRepo, userRepo, ctx := setupTagTest(t)
 	expense := createTestExpense(t, userRepo, expenseRepo, ctx, 702)
@@ -373,3 +399,114 @@ func TestTagRepository_GetExpensesByTagID(t *testing.T) {
 		require.Empty(t, expenses)
 	})
 }
+
+func TestTagRepository_GetFrequentTagsForDescription(t *testing.T) {
+	tagRepo, expenseRepo, userRepo, ctx := setupTagTest(t)
+	userID := int64(709)
+
+	work, err := tagRepo.GetOrCreate(ctx, "work")
+	require.NoError(t, err)
+	lunch, err := tagRepo.GetOrCreate(ctx, "lunch")
+	require.NoError(t, err)
+
+	descriptions := []string{"Team Lunch", "team  lunch", " TEAM LUNCH "}
+	for i, desc := range descriptions {
+		expense := createTestExpense(t, userRepo, expenseRepo, ctx, userID)
+		expense.Description = desc
+		require.NoError(t, expenseRepo.Update(ctx, expense))
+		tagIDs := []int{work.ID}
+		if i == 0 {
+			tagIDs = append(tagIDs, lunch.ID)
+		}
+		require.NoError(t, tagRepo.SetExpenseTags(ctx, expense.ID, tagIDs))
+	}
+
+	t.Run("returns tags used at least minUses times", func(t *testing.T) {
+		tags, err := tagRepo.GetFrequentTagsForDescription(ctx, userID, "team lunch", 3)
+		require.NoError(t, err)
+		require.Len(t, tags, 1)
+		require.Equal(t, "work", tags[0].Name)
+	})
+
+	t.Run("lower threshold orders by use", func(t *testing.T) {
+		tags, err := tagRepo.GetFrequentTagsForDescription(ctx, userID, "Team Lunch", 1)
+		require.NoError(t, err)
+		require.Len(t, tags, 2)
+		require.Equal(t, "work", tags[0].Name)
+		require.Equal(t, "lunch", tags[1].Name)
+	})
+
+	t.Run("novel description", func(t *testing.T) {
+		tags, err := tagRepo.GetFrequentTagsForDescription(ctx, userID, "dentist", 1)
+		require.NoError(t, err)
+		require.Empty(t, tags)
+	})
+
+	t.Run("other users' tags are ignored", func(t *testing.T) {
+		require.NoError(t, userRepo.UpsertUser(ctx, &models.User{ID: 710, Username: "othertagger"}))
+		tags, err := tagRepo.GetFrequentTagsForDescription(ctx, 710, "team lunch", 1)
+		require.NoError(t, err)
+		require.Empty(t, tags)
+	})
+}
+
+func TestTagRepository_CategoryDefaults(t *testing.T) {
+	tagRepo, _, _, ctx := setupTagTest(t)
+	categoryRepo := NewCategoryRepository(tagRepo.db)
+
+	cat, err := categoryRepo.Create(ctx, "Work Travel 406")
+	require.NoError(t, err)
+	reimbursable, err := tagRepo.GetOrCreate(ctx, "reimbursable406")
+	require.NoError(t, err)
+	travel, err := tagRepo.GetOrCreate(ctx, "travel406")
+	require.NoError(t, err)
+
+	t.Run("sets and replaces defaults", func(t *testing.T) {
+		require.NoError(t, tagRepo.SetCategoryDefaults(ctx, cat.ID, []int{travel.ID, reimbursable.ID}))
+		tags, err := tagRepo.GetCategoryDefaults(ctx, cat.ID)
+		require.NoError(t, err)
+		require.Len(t, tags, 2)
+		require.Equal(t, "reimbursable406", tags[0].Name)
+
+		all, err := tagRepo.GetAllCategoryDefaults(ctx)
+		require.NoError(t, err)
+		require.Len(t, all[cat.ID], 2)
+
+		require.NoError(t, tagRepo.SetCategoryDefaults(ctx, cat.ID, []int{travel.ID}))
+		tags, err = tagRepo.GetCategoryDefaults(ctx, cat.ID)
+		require.NoError(t, err)
+		require.Len(t, tags, 1)
+		require.Equal(t, travel.ID, tags[0].ID)
+	})
+
+	t.Run("a failed replacement keeps the old defaults", func(t *testing.T) {
+		require.Error(t, tagRepo.SetCategoryDefaults(ctx, cat.ID, []int{reimbursable.ID, -1}))
+		tags, err := tagRepo.GetCategoryDefaults(ctx, cat.ID)
+		require.NoError(t, err)
+		require.Len(t, tags, 1)
+		require.Equal(t, travel.ID, tags[0].ID)
+	})
+
+	t.Run("nil clears the defaults", func(t *testing.T) {
+		require.NoError(t, tagRepo.SetCategoryDefaults(ctx, cat.ID, nil))
+		tags, err := tagRepo.GetCategoryDefaults(ctx, cat.ID)
+		require.NoError(t, err)
+		require.Empty(t, tags)
+	})
+
+	t.Run("deleting a tag removes it from defaults", func(t *testing.T) {
+		require.NoError(t, tagRepo.SetCategoryDefaults(ctx, cat.ID, []int{travel.ID, reimbursable.ID}))
+		require.NoError(t, tagRepo.Delete(ctx, travel.ID))
+		tags, err := tagRepo.GetCategoryDefaults(ctx, cat.ID)
+		require.NoError(t, err)
+		require.Len(t, tags, 1)
+		require.Equal(t, reimbursable.ID, tags[0].ID)
+	})
+
+	t.Run("deleting a category removes its defaults", func(t *testing.T) {
+		require.NoError(t, categoryRepo.Delete(ctx, cat.ID))
+		all, err := tagRepo.GetAllCategoryDefaults(ctx)
+		require.NoError(t, err)
+		require.NotContains(t, all, cat.ID)
+	})
+}
@@ -2,9 +2,13 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/shopspring/decimal"
 	"gitlab.com/yelinaung/expense-bot/internal/database"
 	"gitlab.com/yelinaung/expense-bot/internal/models"
 )
@@ -19,7 +23,8 @@ func NewUserRepository(db database.PGXDB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-// UpsertUser creates or updates a user.
+// UpsertUser creates or updates a user. Users only reach this by talking
+// to the bot, so it also clears their unreachable mark.
 func (r *UserRepository) UpsertUser(ctx context.Context, user *models.User) error {
 	_, err := r.db.Exec(ctx, `
 		INSERT INTO users (id, username, first_name, last_name, created_at, updated_at)
@@ -28,6 +33,7 @@ func (r *UserRepository) UpsertUser(ctx context.Context, user *models.User) erro
 			username = EXCLUDED.username,
 			first_name = EXCLUDED.first_name,
 			last_name = EXCLUDED.last_name,
+			unreachable_since = NULL,
 			updated_at = NOW()
 	`, user.ID, user.Username, user.FirstName, user.LastName)
 	if err != nil {
@@ -49,6 +55,23 @@ func (r *UserRepository) GetUserByID(ctx context.Context, id int64) (*models.Use
 	return &user, nil
 }
 
+// GetUserByUsername retrieves a user by Telegram username, ignoring case and
+// a leading "@".
+func (r *UserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRow(ctx, `
+		SELECT id, username, first_name, last_name, default_currency, timezone, created_at, updated_at
+		FROM users WHERE LOWER(username) = LOWER($1)
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`, strings.TrimPrefix(username, "@")).Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName,
+		&user.DefaultCurrency, &user.Timezone, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by username: %w", err)
+	}
+	return &user, nil
+}
+
 // UpdateDefaultCurrency updates a user's default currency.
 func (r *UserRepository) UpdateDefaultCurrency(ctx context.Context, userID int64, currency string) error {
 	_, err := r.db.Exec(ctx, `
@@ -87,7 +110,7 @@ func (r *UserRepository) GetAllUsers(ctx context.Context) ([]models.User, error)
 
 // GetAuthorizedUsersForReminder returns authorized users. Authorization means
 // the user is either a superadmin (by ID or username) or exists in the
-// approved_users table.
+// approved_users table. Users marked unreachable are left out.
 func (r *UserRepository) GetAuthorizedUsersForReminder(
 	ctx context.Context,
 	superAdminIDs []int64,
@@ -96,7 +119,8 @@ func (r *UserRepository) GetAuthorizedUsersForReminder(
 	lowered := lowercaseUsernames(superAdminUsernames)
 
 	rows, err := r.db.Query(ctx, `
-		SELECT u.id, u.username, u.first_name, u.last_name, u.timezone
+		SELECT u.id, u.username, u.first_name, u.last_name, u.timezone,
+			COALESCE(TO_CHAR(u.last_reminder_date, 'YYYY-MM-DD'), '')
 		FROM users u
 		WHERE (
 			u.id = ANY($1)
@@ -104,6 +128,7 @@ func (r *UserRepository) GetAuthorizedUsersForReminder(
 			OR EXISTS (SELECT 1 FROM approved_users au WHERE au.user_id = u.id AND au.user_id != 0)
 			OR EXISTS (SELECT 1 FROM approved_users au WHERE LOWER(au.username) = LOWER(u.username) AND u.username != '' AND au.username != '')
 		)
+		AND u.unreachable_since IS NULL
 	`, superAdminIDs, lowered)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query authorized users for reminder: %w", err)
@@ -130,7 +155,94 @@ func scanReminderUsers(rows interface {
 	var users []models.User
 	for rows.Next() {
 		var u models.User
-		if err := rows.Scan(&u.ID, &u.Username, &u.FirstName, &u.LastName, &u.Timezone); err != nil {
+		if err := rows.Scan(
+			&u.ID, &u.Username, &u.FirstName, &u.LastName, &u.Timezone, &u.LastReminderDate,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+	return users, nil
+}
+
+// SetLastReminderDate records the local date (YYYY-MM-DD) on which the
+// user's daily reminder was handled, so a restart does not send it twice.
+func (r *UserRepository) SetLastReminderDate(ctx context.Context, userID int64, date string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE users SET last_reminder_date = $2::date WHERE id = $1
+	`, userID, date)
+	if err != nil {
+		return fmt.Errorf("failed to set last reminder date: %w", err)
+	}
+	return nil
+}
+
+// GetArchiveChatID returns the chat that receives a user's monthly CSV, or
+// zero when archiving is off.
+func (r *UserRepository) GetArchiveChatID(ctx context.Context, userID int64) (int64, error) {
+	var chatID *int64
+	err := r.db.QueryRow(ctx, `
+		SELECT archive_chat_id FROM users WHERE id = $1
+	`, userID).Scan(&chatID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get archive chat: %w", err)
+	}
+	if chatID == nil {
+		return 0, nil
+	}
+	return *chatID, nil
+}
+
+// SetArchiveChatID sets the chat that receives a user's monthly CSV. Zero
+// turns archiving off.
+func (r *UserRepository) SetArchiveChatID(ctx context.Context, userID, chatID int64) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE users SET archive_chat_id = NULLIF($2, 0), updated_at = NOW() WHERE id = $1
+	`, userID, chatID)
+	if err != nil {
+		return fmt.Errorf("failed to set archive chat: %w", err)
+	}
+	return nil
+}
+
+// GetAuthorizedUsersForArchive returns authorized users with an archive
+// chat the bot was not removed from, along with the month last delivered
+// there.
+func (r *UserRepository) GetAuthorizedUsersForArchive(
+	ctx context.Context,
+	superAdminIDs []int64,
+	superAdminUsernames []string,
+) ([]models.User, error) {
+	lowered := lowercaseUsernames(superAdminUsernames)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT u.id, u.username, u.first_name, u.last_name, u.timezone, u.archive_chat_id,
+			COALESCE(TO_CHAR(u.archive_sent_month, 'YYYY-MM-DD'), '')
+		FROM users u
+		WHERE u.archive_chat_id IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM chats c WHERE c.chat_id = u.archive_chat_id AND c.status = 'left')
+		AND (
+			u.id = ANY($1)
+			OR LOWER(u.username) = ANY($2::text[])
+			OR EXISTS (SELECT 1 FROM approved_users au WHERE au.user_id = u.id AND au.user_id != 0)
+			OR EXISTS (SELECT 1 FROM approved_users au WHERE LOWER(au.username) = LOWER(u.username) AND u.username != '' AND au.username != '')
+		)
+		ORDER BY u.id
+	`, superAdminIDs, lowered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users for archive: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(
+			&u.ID, &u.Username, &u.FirstName, &u.LastName, &u.Timezone, &u.ArchiveChatID, &u.ArchiveSentMonth,
+		); err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
 		users = append(users, u)
@@ -141,6 +253,18 @@ func scanReminderUsers(rows interface {
 	return users, nil
 }
 
+// SetArchiveSentMonth records the month (its first day, YYYY-MM-DD) last
+// delivered to a user's archive chat.
+func (r *UserRepository) SetArchiveSentMonth(ctx context.Context, userID int64, month string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE users SET archive_sent_month = $2::date WHERE id = $1
+	`, userID, month)
+	if err != nil {
+		return fmt.Errorf("failed to set archive sent month: %w", err)
+	}
+	return nil
+}
+
 // UpdateTimezone updates a user's timezone.
 func (r *UserRepository) UpdateTimezone(ctx context.Context, userID int64, timezone string) error {
 	_, err := r.db.Exec(ctx, `
@@ -181,3 +305,411 @@ func (r *UserRepository) GetDefaultCurrency(ctx context.Context, userID int64) (
 	}
 	return currency, nil
 }
+
+// GetUncategorizedThreshold returns the number of uncategorized expenses a
+// user tolerates before being warned. Zero disables the warning.
+func (r *UserRepository) GetUncategorizedThreshold(ctx context.Context, userID int64) (int, error) {
+	var threshold int
+	err := r.db.QueryRow(ctx, `
+		SELECT uncategorized_threshold FROM users WHERE id = $1
+	`, userID).Scan(&threshold)
+	if err != nil {
+		return models.DefaultUncategorizedThreshold, fmt.Errorf("failed to get uncategorized threshold: %w", err)
+	}
+	return threshold, nil
+}
+
+// UpdateUncategorizedThreshold updates a user's uncategorized warning threshold.
+// It fails when the user does not exist.
+func (r *UserRepository) UpdateUncategorizedThreshold(ctx context.Context, userID int64, threshold int) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE users SET uncategorized_threshold = $2, updated_at = NOW() WHERE id = $1
+	`, userID, threshold)
+	if err != nil {
+		return fmt.Errorf("failed to update uncategorized threshold: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("failed to update uncategorized threshold: no matching user")
+	}
+	return nil
+}
+
+// GetMaxAmount returns a user's soft amount limit override. The result is
+// invalid when the user has not set one.
+func (r *UserRepository) GetMaxAmount(ctx context.Context, userID int64) (decimal.NullDecimal, error) {
+	var maxAmount decimal.NullDecimal
+	err := r.db.QueryRow(ctx, `
+		SELECT max_amount FROM users WHERE id = $1
+	`, userID).Scan(&maxAmount)
+	if err != nil {
+		return decimal.NullDecimal{}, fmt.Errorf("failed to get max amount: %w", err)
+	}
+	return maxAmount, nil
+}
+
+// UpdateMaxAmount sets a user's soft amount limit override. An invalid
+// value clears the override.
+func (r *UserRepository) UpdateMaxAmount(ctx context.Context, userID int64, maxAmount decimal.NullDecimal) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE users SET max_amount = $2, updated_at = NOW() WHERE id = $1
+	`, userID, maxAmount)
+	if err != nil {
+		return fmt.Errorf("failed to update max amount: %w", err)
+	}
+	return nil
+}
+
+// GetAutoTag reports whether suggested tags are applied to a user's new
+// expenses without asking.
+func (r *UserRepository) GetAutoTag(ctx context.Context, userID int64) (bool, error) {
+	var autoTag bool
+	err := r.db.QueryRow(ctx, `
+		SELECT auto_tag FROM users WHERE id = $1
+	`, userID).Scan(&autoTag)
+	if err != nil {
+		return false, fmt.Errorf("failed to get auto tag: %w", err)
+	}
+	return autoTag, nil
+}
+
+// UpdateAutoTag turns automatic tagging of new expenses on or off.
+func (r *UserRepository) UpdateAutoTag(ctx context.Context, userID int64, autoTag bool) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE users SET auto_tag = $2, updated_at = NOW() WHERE id = $1
+	`, userID, autoTag)
+	if err != nil {
+		return fmt.Errorf("failed to update auto tag: %w", err)
+	}
+	return nil
+}
+
+// GetAIEnabled reports whether a user's receipts, voice messages and
+// descriptions may be sent to Gemini.
+func (r *UserRepository) GetAIEnabled(ctx context.Context, userID int64) (bool, error) {
+	var enabled bool
+	err := r.db.QueryRow(ctx, `
+		SELECT ai_enabled FROM users WHERE id = $1
+	`, userID).Scan(&enabled)
+	if err != nil {
+		return false, fmt.Errorf("failed to get ai setting: %w", err)
+	}
+	return enabled, nil
+}
+
+// UpdateAIEnabled turns a user's AI features on or off.
+func (r *UserRepository) UpdateAIEnabled(ctx context.Context, userID int64, enabled bool) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE users SET ai_enabled = $2, updated_at = NOW() WHERE id = $1
+	`, userID, enabled)
+	if err != nil {
+		return fmt.Errorf("failed to update ai setting: %w", err)
+	}
+	return nil
+}
+
+// GetCategoriesByUsage reports whether a user's category keyboards list the
+// most used categories first.
+func (r *UserRepository) GetCategoriesByUsage(ctx context.Context, userID int64) (bool, error) {
+	var byUsage bool
+	err := r.db.QueryRow(ctx, `
+		SELECT categories_by_usage FROM users WHERE id = $1
+	`, userID).Scan(&byUsage)
+	if err != nil {
+		return false, fmt.Errorf("failed to get category order: %w", err)
+	}
+	return byUsage, nil
+}
+
+// UpdateCategoriesByUsage switches a user's category keyboards between the
+// shared order and most used first.
+func (r *UserRepository) UpdateCategoriesByUsage(ctx context.Context, userID int64, byUsage bool) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE users SET categories_by_usage = $2, updated_at = NOW() WHERE id = $1
+	`, userID, byUsage)
+	if err != nil {
+		return fmt.Errorf("failed to update category order: %w", err)
+	}
+	return nil
+}
+
+// ClaimCategoryChoice records that a new user picked a starting category
+// set. It reports false when the user had picked one already.
+func (r *UserRepository) ClaimCategoryChoice(ctx context.Context, userID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE users SET categories_chosen = TRUE, updated_at = NOW() WHERE id = $1 AND NOT categories_chosen
+	`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to record category choice: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// CategoriesChosen reports whether a user picked a starting category set,
+// which users from before the choice existed count as having done.
+func (r *UserRepository) CategoriesChosen(ctx context.Context, userID int64) (bool, error) {
+	var chosen bool
+	err := r.db.QueryRow(ctx, `SELECT categories_chosen FROM users WHERE id = $1`, userID).Scan(&chosen)
+	if err != nil {
+		return false, fmt.Errorf("failed to get category choice: %w", err)
+	}
+	return chosen, nil
+}
+
+// SetAPITokenHash stores the hash of a user's API token, replacing any
+// previous token.
+func (r *UserRepository) SetAPITokenHash(ctx context.Context, userID int64, hash string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE users SET api_token_hash = $2, updated_at = NOW() WHERE id = $1
+	`, userID, hash)
+	if err != nil {
+		return fmt.Errorf("failed to set API token hash: %w", err)
+	}
+	return nil
+}
+
+// ClearAPITokenHash revokes a user's API token. It reports whether a token
+// was set.
+func (r *UserRepository) ClearAPITokenHash(ctx context.Context, userID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE users SET api_token_hash = NULL, updated_at = NOW()
+		WHERE id = $1 AND api_token_hash IS NOT NULL
+	`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to clear API token hash: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetPINHash returns the bcrypt hash of a user's PIN, or an empty string
+// when the user has not set one.
+func (r *UserRepository) GetPINHash(ctx context.Context, userID int64) (string, error) {
+	var hash *string
+	err := r.db.QueryRow(ctx, `
+		SELECT pin_hash FROM users WHERE id = $1
+	`, userID).Scan(&hash)
+	if err != nil {
+		return "", fmt.Errorf("failed to get PIN hash: %w", err)
+	}
+	if hash == nil {
+		return "", nil
+	}
+	return *hash, nil
+}
+
+// SetPINHash stores the bcrypt hash of a user's PIN, replacing any previous
+// PIN.
+func (r *UserRepository) SetPINHash(ctx context.Context, userID int64, hash string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE users SET pin_hash = $2, updated_at = NOW() WHERE id = $1
+	`, userID, hash)
+	if err != nil {
+		return fmt.Errorf("failed to set PIN hash: %w", err)
+	}
+	return nil
+}
+
+// ClearPINHash removes a user's PIN.
+func (r *UserRepository) ClearPINHash(ctx context.Context, userID int64) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE users SET pin_hash = NULL, updated_at = NOW() WHERE id = $1
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to clear PIN hash: %w", err)
+	}
+	return nil
+}
+
+// GetUserByAPITokenHash retrieves the user owning an API token hash.
+func (r *UserRepository) GetUserByAPITokenHash(ctx context.Context, hash string) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRow(ctx, `
+		SELECT id, username, first_name, last_name, default_currency, timezone, created_at, updated_at
+		FROM users WHERE api_token_hash = $1
+	`, hash).Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName, &user.DefaultCurrency, &user.Timezone, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by API token: %w", err)
+	}
+	return &user, nil
+}
+
+// TouchLastSeen sets a user's last_seen_at to now unless it is already at
+// or after staleBefore. It reports whether the row was updated.
+func (r *UserRepository) TouchLastSeen(ctx context.Context, userID int64, now, staleBefore time.Time) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE users SET last_seen_at = $2
+		WHERE id = $1 AND (last_seen_at IS NULL OR last_seen_at < $3)
+	`, userID, now, staleBefore)
+	if err != nil {
+		return false, fmt.Errorf("failed to update last seen: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MarkUnreachable records that a user blocked the bot, keeping the time
+// it was first noticed. It reports whether the user was reachable before.
+func (r *UserRepository) MarkUnreachable(ctx context.Context, userID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE users SET unreachable_since = NOW() WHERE id = $1 AND unreachable_since IS NULL
+	`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark user unreachable: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MarkReachable clears a user's unreachable mark. It reports whether the
+// user was marked.
+func (r *UserRepository) MarkReachable(ctx context.Context, userID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE users SET unreachable_since = NULL WHERE id = $1 AND unreachable_since IS NOT NULL
+	`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark user reachable: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// IsUnreachable reports whether a user is marked as having blocked the bot.
+func (r *UserRepository) IsUnreachable(ctx context.Context, userID int64) (bool, error) {
+	var unreachable bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND unreachable_since IS NOT NULL)
+	`, userID).Scan(&unreachable)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if user is unreachable: %w", err)
+	}
+	return unreachable, nil
+}
+
+// UserStats is an approved user with their activity, for the /users
+// listing. Users approved by username who never talked to the bot have no
+// user ID, first name or last seen time.
+type UserStats struct {
+	UserID       int64
+	Username     string
+	FirstName    string
+	ApprovedAt   time.Time
+	LastSeenAt   *time.Time
+	ExpenseCount int
+}
+
+// userStatsFrom joins approved users to their users row, by ID or, for
+// users approved by username, by username, and to their confirmed expense
+// count. $1 is the filter, $2 its LIKE pattern and $3 the filter as a user
+// ID, 0 when the filter is not a number.
+const userStatsFrom = `
+	FROM approved_users a
+	LEFT JOIN users u ON (a.user_id != 0 AND u.id = a.user_id)
+		OR (a.user_id = 0 AND a.username != '' AND LOWER(u.username) = LOWER(a.username))
+	LEFT JOIN (
+		SELECT user_id, COUNT(*) AS expense_count
+		FROM expenses WHERE status = 'confirmed'
+		GROUP BY user_id
+	) c ON c.user_id = COALESCE(u.id, a.user_id)
+	WHERE $1::text = ''
+		OR a.username ILIKE $2 OR u.username ILIKE $2 OR u.first_name ILIKE $2
+		OR ($3::bigint != 0 AND COALESCE(u.id, a.user_id) = $3::bigint)`
+
+// userFilterArgs returns the query arguments of userStatsFrom for filter.
+func userFilterArgs(filter string) []any {
+	filter = strings.TrimPrefix(strings.TrimSpace(filter), "@")
+	id, err := strconv.ParseInt(filter, 10, 64)
+	if err != nil {
+		id = 0
+	}
+	return []any{filter, likePattern(filter), id}
+}
+
+// CountWithStats counts the approved users ListWithStats returns for filter.
+func (r *UserRepository) CountWithStats(ctx context.Context, filter string) (int, error) {
+	var count int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*)`+userStatsFrom, userFilterArgs(filter)...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// ListWithStats returns a page of approved users with their names, approval
+// date, last activity and confirmed expense count, most recently active
+// first. A non-empty filter keeps users whose username or first name
+// contains it (case-insensitive) or whose ID equals it.
+func (r *UserRepository) ListWithStats(ctx context.Context, filter string, limit, offset int) ([]UserStats, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT COALESCE(u.id, a.user_id), COALESCE(NULLIF(u.username, ''), a.username),
+			COALESCE(u.first_name, ''), a.created_at, u.last_seen_at, COALESCE(c.expense_count, 0)
+	`+userStatsFrom+`
+		ORDER BY u.last_seen_at DESC NULLS LAST, a.created_at DESC, a.id
+		LIMIT $4 OFFSET $5
+	`, append(userFilterArgs(filter), limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []UserStats
+	for rows.Next() {
+		var u UserStats
+		if err := rows.Scan(&u.UserID, &u.Username, &u.FirstName, &u.ApprovedAt, &u.LastSeenAt, &u.ExpenseCount); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+	return users, nil
+}
+
+// GetDisplayFormat returns a user's /settings format, "html" or "mono".
+func (r *UserRepository) GetDisplayFormat(ctx context.Context, userID int64) (string, error) {
+	var format string
+	err := r.db.QueryRow(ctx, `
+		SELECT display_format FROM users WHERE id = $1
+	`, userID).Scan(&format)
+	if err != nil {
+		return "", fmt.Errorf("failed to get display format: %w", err)
+	}
+	return format, nil
+}
+
+// UpdateDisplayFormat sets the layout of a user's lists and summaries.
+func (r *UserRepository) UpdateDisplayFormat(ctx context.Context, userID int64, format string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE users SET display_format = $2, updated_at = NOW() WHERE id = $1
+	`, userID, format)
+	if err != nil {
+		return fmt.Errorf("failed to update display format: %w", err)
+	}
+	return nil
+}
+
+// UserSettings is every per-user preference stored on the users row, as
+// /exportsettings writes them. Empty currency and timezone columns read as
+// the defaults; MaxAmount is invalid when the user has no override.
+type UserSettings struct {
+	DefaultCurrency        string
+	Timezone               string
+	DisplayFormat          string
+	AIEnabled              bool
+	AutoTag                bool
+	CategoriesByUsage      bool
+	UncategorizedThreshold int
+	MaxAmount              decimal.NullDecimal
+}
+
+// GetSettings returns a user's preferences in one query.
+func (r *UserRepository) GetSettings(ctx context.Context, userID int64) (*UserSettings, error) {
+	var s UserSettings
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(NULLIF(default_currency, ''), $2), COALESCE(NULLIF(timezone, ''), $3),
+			display_format, ai_enabled, auto_tag, categories_by_usage, uncategorized_threshold, max_amount
+		FROM users WHERE id = $1
+	`, userID, models.DefaultCurrency, models.DefaultTimezone).Scan(
+		&s.DefaultCurrency, &s.Timezone, &s.DisplayFormat, &s.AIEnabled, &s.AutoTag,
+		&s.CategoriesByUsage, &s.UncategorizedThreshold, &s.MaxAmount,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user settings: %w", err)
+	}
+	return &s, nil
+}
@@ -3,7 +3,10 @@ package repository
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 	"gitlab.com/yelinaung/expense-bot/internal/models"
 	"gitlab.com/yelinaung/expense-bot/internal/testutil/dbtest"
@@ -140,6 +143,23 @@ func TestUserRepository_GetAuthorizedUsersForReminder(t *testing.T) {
 		require.Contains(t, ids, int64(3103))
 		require.NotContains(t, ids, int64(3104))
 	})
+
+	t.Run("loads the persisted last reminder date", func(t *testing.T) {
+		err := userRepo.UpsertUser(ctx, &models.User{ID: 3105, Username: "reminded", FirstName: "Rem"})
+		require.NoError(t, err)
+		err = userRepo.SetLastReminderDate(ctx, 3105, "2026-02-11")
+		require.NoError(t, err)
+
+		users, err := userRepo.GetAuthorizedUsersForReminder(ctx, []int64{3105}, nil)
+		require.NoError(t, err)
+
+		dates := make(map[int64]string, len(users))
+		for _, u := range users {
+			dates[u.ID] = u.LastReminderDate
+		}
+		require.Equal(t, "2026-02-11", dates[3105])
+		require.Empty(t, dates[3101], "users never reminded have no date")
+	})
 }
 
 func TestUserRepository_GetUserByID(t *testing.T) {
@@ -154,6 +174,29 @@ func TestUserRepository_GetUserByID(t *testing.T) {
 	})
 }
 
+func TestUserRepository_GetUserByUsername(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewUserRepository(tx)
+	require.NoError(t, repo.UpsertUser(ctx, &models.User{ID: 368001, Username: "Alice", FirstName: "Alice"}))
+
+	t.Run("matches case-insensitively with or without @", func(t *testing.T) {
+		user, err := repo.GetUserByUsername(ctx, "@alice")
+		require.NoError(t, err)
+		require.Equal(t, int64(368001), user.ID)
+
+		user, err = repo.GetUserByUsername(ctx, "ALICE")
+		require.NoError(t, err)
+		require.Equal(t, int64(368001), user.ID)
+	})
+
+	t.Run("returns error for unknown username", func(t *testing.T) {
+		_, err := repo.GetUserByUsername(ctx, "nobody_here")
+		require.Error(t, err)
+	})
+}
+
 func TestUserRepository_UpsertUser_WithEmptyFields(t *testing.T) {
 	ctx := context.Background()
 	tx := dbtest.TestTx(ctx, t)
@@ -341,3 +384,426 @@ func TestUserRepository_GetTimezone(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestUserRepository_UncategorizedThreshold(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewUserRepository(tx)
+
+	user := &models.User{ID: 12401, Username: "thresholduser", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, repo.UpsertUser(ctx, user))
+
+	t.Run("returns default for new user", func(t *testing.T) {
+		threshold, err := repo.GetUncategorizedThreshold(ctx, user.ID)
+		require.NoError(t, err)
+		require.Equal(t, models.DefaultUncategorizedThreshold, threshold)
+	})
+
+	t.Run("updates threshold", func(t *testing.T) {
+		require.NoError(t, repo.UpdateUncategorizedThreshold(ctx, user.ID, 3))
+
+		threshold, err := repo.GetUncategorizedThreshold(ctx, user.ID)
+		require.NoError(t, err)
+		require.Equal(t, 3, threshold)
+	})
+
+	t.Run("returns error for unknown user", func(t *testing.T) {
+		threshold, err := repo.GetUncategorizedThreshold(ctx, 99999999)
+		require.Error(t, err)
+		require.Equal(t, models.DefaultUncategorizedThreshold, threshold)
+	})
+
+	t.Run("update returns error for unknown user", func(t *testing.T) {
+		require.Error(t, repo.UpdateUncategorizedThreshold(ctx, 99999999, 3))
+	})
+}
+
+func TestUserRepository_MaxAmount(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewUserRepository(tx)
+
+	user := &models.User{ID: 12403, Username: "maxamountuser", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, repo.UpsertUser(ctx, user))
+
+	t.Run("unset for new user", func(t *testing.T) {
+		maxAmount, err := repo.GetMaxAmount(ctx, user.ID)
+		require.NoError(t, err)
+		require.False(t, maxAmount.Valid)
+	})
+
+	t.Run("set and clear", func(t *testing.T) {
+		limit := decimal.NewNullDecimal(decimal.NewFromInt(5000))
+		require.NoError(t, repo.UpdateMaxAmount(ctx, user.ID, limit))
+
+		maxAmount, err := repo.GetMaxAmount(ctx, user.ID)
+		require.NoError(t, err)
+		require.True(t, maxAmount.Valid)
+		require.True(t, decimal.NewFromInt(5000).Equal(maxAmount.Decimal))
+
+		require.NoError(t, repo.UpdateMaxAmount(ctx, user.ID, decimal.NullDecimal{}))
+		maxAmount, err = repo.GetMaxAmount(ctx, user.ID)
+		require.NoError(t, err)
+		require.False(t, maxAmount.Valid)
+	})
+}
+
+func TestUserRepository_AutoTag(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewUserRepository(tx)
+
+	user := &models.User{ID: 12404, Username: "autotaguser", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, repo.UpsertUser(ctx, user))
+
+	autoTag, err := repo.GetAutoTag(ctx, user.ID)
+	require.NoError(t, err)
+	require.False(t, autoTag)
+
+	require.NoError(t, repo.UpdateAutoTag(ctx, user.ID, true))
+	autoTag, err = repo.GetAutoTag(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, autoTag)
+}
+
+func TestUserRepository_AIEnabled(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewUserRepository(tx)
+
+	user := &models.User{ID: 12450, Username: "aioffuser", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, repo.UpsertUser(ctx, user))
+
+	enabled, err := repo.GetAIEnabled(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, enabled, "AI is on by default")
+
+	require.NoError(t, repo.UpdateAIEnabled(ctx, user.ID, false))
+	enabled, err = repo.GetAIEnabled(ctx, user.ID)
+	require.NoError(t, err)
+	require.False(t, enabled)
+}
+
+func TestUserRepository_CategoryChoice(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewUserRepository(tx)
+
+	user := &models.User{ID: 12426, Username: "categorychoice", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, repo.UpsertUser(ctx, user))
+
+	chosen, err := repo.CategoriesChosen(ctx, user.ID)
+	require.NoError(t, err)
+	require.False(t, chosen, "new users still pick a starting set")
+
+	claimed, err := repo.ClaimCategoryChoice(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	claimed, err = repo.ClaimCategoryChoice(ctx, user.ID)
+	require.NoError(t, err)
+	require.False(t, claimed)
+
+	chosen, err = repo.CategoriesChosen(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, chosen)
+}
+
+func TestUserRepository_DisplayFormat(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewUserRepository(tx)
+
+	user := &models.User{ID: 12415, Username: "monouser", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, repo.UpsertUser(ctx, user))
+
+	format, err := repo.GetDisplayFormat(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, "html", format)
+
+	require.NoError(t, repo.UpdateDisplayFormat(ctx, user.ID, "mono"))
+	format, err = repo.GetDisplayFormat(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, "mono", format)
+}
+
+func TestUserRepository_GetSettings(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewUserRepository(tx)
+
+	user := &models.User{ID: 12459, Username: "settingsuser", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, repo.UpsertUser(ctx, user))
+
+	settings, err := repo.GetSettings(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.DefaultCurrency, settings.DefaultCurrency)
+	require.Equal(t, models.DefaultTimezone, settings.Timezone)
+	require.False(t, settings.MaxAmount.Valid)
+
+	require.NoError(t, repo.UpdateDefaultCurrency(ctx, user.ID, "EUR"))
+	require.NoError(t, repo.UpdateTimezone(ctx, user.ID, "Europe/Berlin"))
+	require.NoError(t, repo.UpdateDisplayFormat(ctx, user.ID, "mono"))
+	require.NoError(t, repo.UpdateAutoTag(ctx, user.ID, true))
+	require.NoError(t, repo.UpdateUncategorizedThreshold(ctx, user.ID, 12))
+	require.NoError(t, repo.UpdateMaxAmount(ctx, user.ID, decimal.NewNullDecimal(decimal.NewFromInt(300))))
+
+	settings, err = repo.GetSettings(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, "EUR", settings.DefaultCurrency)
+	require.Equal(t, "Europe/Berlin", settings.Timezone)
+	require.Equal(t, "mono", settings.DisplayFormat)
+	require.True(t, settings.AutoTag)
+	require.Equal(t, 12, settings.UncategorizedThreshold)
+	require.True(t, settings.MaxAmount.Decimal.Equal(decimal.NewFromInt(300)))
+
+	_, err = repo.GetSettings(ctx, 99912459)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestUserRepository_ArchiveChat(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewUserRepository(tx)
+
+	user := &models.User{ID: 12405, Username: "archiveuser", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, repo.UpsertUser(ctx, user))
+	other := &models.User{ID: 12406, Username: "noarchiveuser", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, repo.UpsertUser(ctx, other))
+
+	chatID, err := repo.GetArchiveChatID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Zero(t, chatID)
+
+	require.NoError(t, repo.SetArchiveChatID(ctx, user.ID, -1001234567890))
+	chatID, err = repo.GetArchiveChatID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(-1001234567890), chatID)
+
+	require.NoError(t, repo.SetArchiveSentMonth(ctx, user.ID, "2026-09-01"))
+	users, err := repo.GetAuthorizedUsersForArchive(ctx, []int64{user.ID, other.ID}, nil)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, user.ID, users[0].ID)
+	require.Equal(t, int64(-1001234567890), users[0].ArchiveChatID)
+	require.Equal(t, "2026-09-01", users[0].ArchiveSentMonth)
+
+	require.NoError(t, repo.SetArchiveChatID(ctx, user.ID, 0))
+	users, err = repo.GetAuthorizedUsersForArchive(ctx, []int64{user.ID, other.ID}, nil)
+	require.NoError(t, err)
+	require.Empty(t, users)
+}
+
+func TestUserRepository_APITokenHash(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewUserRepository(tx)
+
+	user := &models.User{ID: 12402, Username: "apitokenuser", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, repo.UpsertUser(ctx, user))
+
+	t.Run("looks up user by hash", func(t *testing.T) {
+		require.NoError(t, repo.SetAPITokenHash(ctx, user.ID, "hash-one"))
+
+		got, err := repo.GetUserByAPITokenHash(ctx, "hash-one")
+		require.NoError(t, err)
+		require.Equal(t, user.ID, got.ID)
+	})
+
+	t.Run("new token replaces old one", func(t *testing.T) {
+		require.NoError(t, repo.SetAPITokenHash(ctx, user.ID, "hash-two"))
+
+		_, err := repo.GetUserByAPITokenHash(ctx, "hash-one")
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
+	t.Run("clear revokes token", func(t *testing.T) {
+		cleared, err := repo.ClearAPITokenHash(ctx, user.ID)
+		require.NoError(t, err)
+		require.True(t, cleared)
+
+		_, err = repo.GetUserByAPITokenHash(ctx, "hash-two")
+		require.ErrorIs(t, err, pgx.ErrNoRows)
+
+		cleared, err = repo.ClearAPITokenHash(ctx, user.ID)
+		require.NoError(t, err)
+		require.False(t, cleared)
+	})
+}
+
+func TestUserRepository_PINHash(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewUserRepository(tx)
+
+	user := &models.User{ID: 12403, Username: "pinuser", FirstName: testFirstName, LastName: testLastName}
+	require.NoError(t, repo.UpsertUser(ctx, user))
+
+	hash, err := repo.GetPINHash(ctx, user.ID)
+	require.NoError(t, err)
+	require.Empty(t, hash)
+
+	require.NoError(t, repo.SetPINHash(ctx, user.ID, "pin-hash"))
+	hash, err = repo.GetPINHash(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, "pin-hash", hash)
+
+	require.NoError(t, repo.ClearPINHash(ctx, user.ID))
+	hash, err = repo.GetPINHash(ctx, user.ID)
+	require.NoError(t, err)
+	require.Empty(t, hash)
+}
+
+func TestUserRepository_TouchLastSeen(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewUserRepository(tx)
+	user := &models.User{ID: 402001, Username: "seenuser", FirstName: testFirstName}
+	require.NoError(t, repo.UpsertUser(ctx, user))
+
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	touched, err := repo.TouchLastSeen(ctx, user.ID, now, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.True(t, touched, "first activity is written")
+
+	later := now.Add(30 * time.Minute)
+	touched, err = repo.TouchLastSeen(ctx, user.ID, later, later.Add(-time.Hour))
+	require.NoError(t, err)
+	require.False(t, touched, "activity within the hour is skipped")
+
+	later = now.Add(2 * time.Hour)
+	touched, err = repo.TouchLastSeen(ctx, user.ID, later, later.Add(-time.Hour))
+	require.NoError(t, err)
+	require.True(t, touched, "activity after an hour is written")
+
+	touched, err = repo.TouchLastSeen(ctx, 402999, now, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.False(t, touched, "unknown users are ignored")
+}
+
+func TestUserRepository_Unreachable(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewUserRepository(tx)
+	user := &models.User{ID: 430001, Username: "blocker", FirstName: testFirstName}
+	require.NoError(t, repo.UpsertUser(ctx, user))
+	whitelist := []int64{user.ID}
+
+	changed, err := repo.MarkUnreachable(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, changed)
+	changed, err = repo.MarkUnreachable(ctx, user.ID)
+	require.NoError(t, err)
+	require.False(t, changed, "already marked")
+
+	unreachable, err := repo.IsUnreachable(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, unreachable)
+	users, err := repo.GetAuthorizedUsersForReminder(ctx, whitelist, nil)
+	require.NoError(t, err)
+	require.Empty(t, users, "unreachable users get no scheduled messages")
+
+	changed, err = repo.MarkReachable(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, changed)
+	users, err = repo.GetAuthorizedUsersForReminder(ctx, whitelist, nil)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+
+	_, err = repo.MarkUnreachable(ctx, user.ID)
+	require.NoError(t, err)
+	require.NoError(t, repo.UpsertUser(ctx, user))
+	unreachable, err = repo.IsUnreachable(ctx, user.ID)
+	require.NoError(t, err)
+	require.False(t, unreachable, "talking to the bot clears the mark")
+}
+
+func TestUserRepository_ListWithStats(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.TestTx(ctx, t)
+
+	repo := NewUserRepository(tx)
+	approvedRepo := NewApprovedUserRepository(tx)
+	expenseRepo := NewExpenseRepository(tx)
+
+	_, err := tx.Exec(ctx, `DELETE FROM approved_users`)
+	require.NoError(t, err)
+
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	alice := &models.User{ID: 402101, Username: "alice", FirstName: "Alice"}
+	bob := &models.User{ID: 402102, Username: "bob", FirstName: "Bob"}
+	for _, u := range []*models.User{alice, bob} {
+		require.NoError(t, repo.UpsertUser(ctx, u))
+	}
+	require.NoError(t, approvedRepo.Approve(ctx, alice.ID, alice.Username, 1))
+	require.NoError(t, approvedRepo.ApproveByUsername(ctx, "Bob", 1))
+	require.NoError(t, approvedRepo.ApproveByUsername(ctx, "carol", 1))
+
+	_, err = repo.TouchLastSeen(ctx, alice.ID, now.Add(-time.Hour), now)
+	require.NoError(t, err)
+	_, err = repo.TouchLastSeen(ctx, bob.ID, now, now)
+	require.NoError(t, err)
+
+	for _, status := range []models.ExpenseStatus{
+		models.ExpenseStatusConfirmed, models.ExpenseStatusConfirmed, models.ExpenseStatusDraft,
+	} {
+		require.NoError(t, expenseRepo.Create(ctx, &models.Expense{
+			UserID: alice.ID, Amount: decimal.NewFromInt(1), Currency: testCurrencySGD, Status: status,
+		}))
+	}
+
+	t.Run("joins activity and counts, most recently active first", func(t *testing.T) {
+		users, err := repo.ListWithStats(ctx, "", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, users, 3)
+
+		require.Equal(t, bob.ID, users[0].UserID, "approved by username, matched by username")
+		require.Equal(t, "Bob", users[0].FirstName)
+		require.NotNil(t, users[0].LastSeenAt)
+		require.Zero(t, users[0].ExpenseCount)
+
+		require.Equal(t, alice.ID, users[1].UserID)
+		require.Equal(t, 2, users[1].ExpenseCount, "only confirmed expenses count")
+		require.True(t, now.Add(-time.Hour).Equal(*users[1].LastSeenAt))
+		require.False(t, users[1].ApprovedAt.IsZero())
+
+		require.Zero(t, users[2].UserID, "never talked to the bot")
+		require.Equal(t, "carol", users[2].Username)
+		require.Nil(t, users[2].LastSeenAt)
+
+		count, err := repo.CountWithStats(ctx, "")
+		require.NoError(t, err)
+		require.Equal(t, 3, count)
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		users, err := repo.ListWithStats(ctx, "", 2, 2)
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		require.Equal(t, "carol", users[0].Username)
+	})
+
+	t.Run("filters by name and ID", func(t *testing.T) {
+		for filter, want := range map[string]string{"ALI": "alice", "@bob": "bob", "402101": "alice", "car": "carol"} {
+			users, err := repo.ListWithStats(ctx, filter, 10, 0)
+			require.NoError(t, err, filter)
+			require.Len(t, users, 1, filter)
+			require.Equal(t, want, users[0].Username, filter)
+		}
+
+		count, err := repo.CountWithStats(ctx, "%")
+		require.NoError(t, err)
+		require.Zero(t, count, "LIKE wildcards are matched literally")
+	})
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// ReimbursableTag is the inline tag, #reimbursable, that marks a new
+// expense reimbursable. It is kept as a regular tag too.
+const ReimbursableTag = "reimbursable"
+
+// Pipeline holds the lookups new chat, bank and REST expenses are built
+// with. A nil field turns its step off: amounts stay in the default
+// currency, Gemini is not asked, suggested categories are not created, no
+// default tags apply, and no budget refuses an expense.
+type Pipeline struct {
+	Currencies CurrencySource
+	AI         CategoryAI
+	Categories CategoryCreator
+	Tags       TagStore
+	Budgets    BudgetSource
+}
+
+// WithPipeline returns a copy of the service that builds expenses with p.
+func (s *ExpenseService) WithPipeline(p Pipeline) *ExpenseService {
+	clone := *s
+	clone.pipeline = p
+	return &clone
+}
+
+// ParsedInput is an expense as read from a message, before it is built.
+type ParsedInput struct {
+	UserID int64
+	Amount decimal.Decimal
+	// Currency is the code given with the amount, or "" for the user's
+	// default currency.
+	Currency    string
+	Description string
+	Refund      bool
+	Source      models.ExpenseSource
+	// CategoryName is a category named in the message.
+	CategoryName string
+	// ChatCategoryID is the default category of the group chat the
+	// expense was logged in, or 0.
+	ChatCategoryID int
+}
+
+// Build converts and categorizes a parsed expense without saving it. The
+// amount is converted into the user's default currency, and refunds are
+// stored negative. The category is decided in the given mode, which is
+// returned with the expense. Amounts above the hard cap return the
+// expense with ErrAboveHardCap, and no category is decided for them.
+func (s *ExpenseService) Build(
+	ctx context.Context,
+	in ParsedInput,
+	categories []models.Category,
+	mode AIMode,
+) (*models.Expense, CategoryDecision, error) {
+	conversion := s.ConvertToDefault(ctx, in.UserID, in.Amount, in.Currency, in.Description)
+	amount := conversion.Amount
+	if in.Refund {
+		amount = amount.Neg()
+	}
+
+	expense := &models.Expense{
+		UserID:      in.UserID,
+		Amount:      amount,
+		Currency:    conversion.Currency,
+		Description: conversion.Description,
+		Merchant:    in.Description,
+		Source:      in.Source,
+	}
+	conversion.Record(expense)
+	if s.AboveHardCap(expense.Amount) {
+		return expense, CategoryDecision{}, ErrAboveHardCap
+	}
+	return expense, s.DecideCategory(ctx, expense, in, categories, mode), nil
+}
+
+// TagStore finds a category's default tags and tags expenses.
+type TagStore interface {
+	GetCategoryDefaults(ctx context.Context, categoryID int) ([]models.Tag, error)
+	GetOrCreate(ctx context.Context, name string) (*models.Tag, error)
+	SetExpenseTags(ctx context.Context, expenseID int, tagIDs []int) error
+}
+
+// DefaultTags returns the names of a category's default tags. Lookup
+// failures are logged and yield none.
+func (s *ExpenseService) DefaultTags(ctx context.Context, categoryID *int) []string {
+	if categoryID == nil || s.pipeline.Tags == nil {
+		return nil
+	}
+	tags, err := s.pipeline.Tags.GetCategoryDefaults(ctx, *categoryID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Int("category_id", *categoryID).Msg("Failed to load category default tags")
+		return nil
+	}
+	names := make([]string, len(tags))
+	for i := range tags {
+		names[i] = tags[i].Name
+	}
+	return names
+}
+
+// MergeTags appends the defaults that are not in tags already.
+func MergeTags(tags, defaults []string) []string {
+	for _, name := range defaults {
+		if !slices.Contains(tags, name) {
+			tags = append(tags, name)
+		}
+	}
+	return tags
+}
+
+// SaveTags tags a saved expense, creating tags that do not exist yet. Tags
+// that cannot be created are logged and left out.
+func (s *ExpenseService) SaveTags(ctx context.Context, expenseID int, tags []string) {
+	if len(tags) == 0 || s.pipeline.Tags == nil {
+		return
+	}
+	tagIDs := make([]int, 0, len(tags))
+	for _, name := range tags {
+		tag, err := s.pipeline.Tags.GetOrCreate(ctx, name)
+		if err != nil {
+			logger.Log.Warn().Err(err).Str("tag", name).Msg("Failed to create tag")
+			continue
+		}
+		tagIDs = append(tagIDs, tag.ID)
+	}
+	if len(tagIDs) == 0 {
+		return
+	}
+	if err := s.pipeline.Tags.SetExpenseTags(ctx, expenseID, tagIDs); err != nil {
+		logger.Log.Warn().Err(err).Int("expense_id", expenseID).Msg("Failed to set expense tags")
+	}
+}
+
+// HardBudget is an enforced category budget with this period's spending
+// and the amount of a new expense, all in the budget's currency.
+type HardBudget struct {
+	CategoryID   int
+	CategoryName string
+	Currency     string
+	Limit        decimal.Decimal
+	Spent        decimal.Decimal
+	Expense      decimal.Decimal
+}
+
+// Remaining returns how much of the budget is left, never below zero.
+func (b *HardBudget) Remaining() decimal.Decimal {
+	return decimal.Max(b.Limit.Sub(b.Spent), decimal.Zero)
+}
+
+// BudgetSource looks up the hard budget of a new expense's category.
+type BudgetSource interface {
+	// HardBudget returns the budget of the expense's category while it
+	// refuses new expenses, or nil when there is none or the expense
+	// cannot be converted into the budget currency.
+	HardBudget(ctx context.Context, expense *models.Expense) *HardBudget
+}
+
+// BlockingBudget returns the hard budget expense would go over, or nil
+// when it may be saved. Uncategorized expenses and refunds, which only
+// lower spending, are never blocked.
+func (s *ExpenseService) BlockingBudget(ctx context.Context, expense *models.Expense) *HardBudget {
+	if s.pipeline.Budgets == nil || expense.CategoryID == nil || expense.IsRefund() {
+		return nil
+	}
+	budget := s.pipeline.Budgets.HardBudget(ctx, expense)
+	if budget == nil || budget.Spent.Add(budget.Expense).LessThanOrEqual(budget.Limit) {
+		return nil
+	}
+	return budget
+}
+
+// OverBudgetError is returned when a new expense would go over a hard
+// category budget and was not saved.
+type OverBudgetError struct {
+	Budget *HardBudget
+}
+
+func (e *OverBudgetError) Error() string {
+	return "expense would go over the " + e.Budget.CategoryName + " budget"
+}
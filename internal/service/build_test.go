@@ -0,0 +1,368 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/exchange"
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// fakeCurrencies gives every user SGD and converts at a fixed rate.
+type fakeCurrencies struct {
+	rate decimal.Decimal
+	err  error
+}
+
+func (f *fakeCurrencies) DefaultCurrency(context.Context, int64) string { return "SGD" }
+
+func (f *fakeCurrencies) Exchange() exchange.Converter {
+	if f.rate.IsZero() && f.err == nil {
+		return nil
+	}
+	return f
+}
+
+func (f *fakeCurrencies) Convert(
+	_ context.Context,
+	amount decimal.Decimal,
+	_, _ string,
+) (exchange.ConversionResult, error) {
+	if f.err != nil {
+		return exchange.ConversionResult{}, f.err
+	}
+	return exchange.ConversionResult{
+		Amount:   amount.Mul(f.rate).Round(2),
+		Rate:     f.rate,
+		RateDate: time.Date(2026, 2, 14, 0, 0, 0, 0, time.UTC),
+	}, nil
+}
+
+// fakeAI answers every request with suggestion and counts the calls.
+type fakeAI struct {
+	unavailable string
+	quota       int
+	suggestion  *gemini.CategorySuggestion
+	calls       int
+	created     []string
+}
+
+func (f *fakeAI) Unavailable(context.Context, *models.Expense) string { return f.unavailable }
+
+func (f *fakeAI) TakeQuota(context.Context, int64) bool {
+	if f.quota == 0 {
+		return false
+	}
+	f.quota--
+	return true
+}
+
+func (f *fakeAI) SuggestCategory(context.Context, int64, string, []string) (*gemini.CategorySuggestion, error) {
+	f.calls++
+	return f.suggestion, nil
+}
+
+func (f *fakeAI) CreateCategory(_ context.Context, _ int64, name string) (*models.Category, error) {
+	f.created = append(f.created, name)
+	return &models.Category{ID: 99, Name: name}, nil
+}
+
+// fakeTags keeps default tags per category and records tagged expenses.
+type fakeTags struct {
+	defaults map[int][]models.Tag
+	tagged   map[int][]int
+	names    map[string]int
+}
+
+func (f *fakeTags) GetCategoryDefaults(_ context.Context, categoryID int) ([]models.Tag, error) {
+	return f.defaults[categoryID], nil
+}
+
+func (f *fakeTags) GetOrCreate(_ context.Context, name string) (*models.Tag, error) {
+	if f.names == nil {
+		f.names = make(map[string]int)
+	}
+	if _, ok := f.names[name]; !ok {
+		f.names[name] = len(f.names) + 1
+	}
+	return &models.Tag{ID: f.names[name], Name: name}, nil
+}
+
+func (f *fakeTags) SetExpenseTags(_ context.Context, expenseID int, tagIDs []int) error {
+	if f.tagged == nil {
+		f.tagged = make(map[int][]int)
+	}
+	f.tagged[expenseID] = tagIDs
+	return nil
+}
+
+// fakeBudgets returns the same hard budget for every category.
+type fakeBudgets struct {
+	budget *HardBudget
+}
+
+func (f *fakeBudgets) HardBudget(_ context.Context, expense *models.Expense) *HardBudget {
+	if f.budget == nil {
+		return nil
+	}
+	budget := *f.budget
+	budget.Expense = expense.Amount
+	return &budget
+}
+
+var testCategories = []models.Category{{ID: 1, Name: "Food"}, {ID: 2, Name: "Others"}, {ID: 3, Name: "Travel"}}
+
+func TestExpenseService_Build(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s := newTestService(&fakeExpenseStore{}, &fakeMaxAmounts{}).
+		WithPipeline(Pipeline{Currencies: &fakeCurrencies{rate: decimal.RequireFromString("1.35")}})
+
+	t.Run("converts into the default currency", func(t *testing.T) {
+		t.Parallel()
+		expense, _, err := s.Build(ctx, ParsedInput{
+			UserID: 7, Amount: decimal.NewFromInt(20), Currency: "usd", Description: "Taxi",
+		}, testCategories, AISkip)
+		require.NoError(t, err)
+		require.Equal(t, "SGD", expense.Currency)
+		require.True(t, decimal.NewFromInt(27).Equal(expense.Amount))
+		require.Equal(t, "Taxi", expense.Merchant)
+		require.Equal(t, "Taxi [orig: 20.00 USD -> 27.00 SGD @ 1.3500 (2026-02-14)]", expense.Description)
+		require.True(t, expense.ConvertedAmount.Valid)
+		require.Equal(t, "SGD", expense.ConvertedCurrency)
+	})
+
+	t.Run("refunds are negative", func(t *testing.T) {
+		t.Parallel()
+		expense, _, err := s.Build(ctx, ParsedInput{
+			UserID: 7, Amount: decimal.NewFromInt(5), Description: "Returned", Refund: true,
+		}, testCategories, AISkip)
+		require.NoError(t, err)
+		require.True(t, decimal.NewFromInt(-5).Equal(expense.Amount))
+	})
+
+	t.Run("the hard cap applies to the converted amount", func(t *testing.T) {
+		t.Parallel()
+		expense, decision, err := s.Build(ctx, ParsedInput{
+			UserID: 7, Amount: decimal.NewFromInt(800_000), Currency: "USD", Description: "Yacht",
+		}, testCategories, AISkip)
+		require.ErrorIs(t, err, ErrAboveHardCap)
+		require.Nil(t, expense.CategoryID)
+		require.Empty(t, decision.Source)
+	})
+
+	t.Run("failed lookups keep the entered currency", func(t *testing.T) {
+		t.Parallel()
+		offline := s.WithPipeline(Pipeline{Currencies: &fakeCurrencies{err: errors.New("rate unavailable")}})
+		expense, _, err := offline.Build(ctx, ParsedInput{
+			UserID: 7, Amount: decimal.NewFromInt(20), Currency: "USD", Description: "Taxi",
+		}, testCategories, AISkip)
+		require.NoError(t, err)
+		require.Equal(t, "USD", expense.Currency)
+		require.Equal(t, "Taxi [fx_unavailable: kept USD, target SGD]", expense.Description)
+		require.False(t, expense.ConvertedAmount.Valid)
+	})
+}
+
+func TestExpenseService_DecideCategory(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	decide := func(ai *fakeAI, in ParsedInput, mode AIMode) (*models.Expense, CategoryDecision) {
+		var pipeline Pipeline
+		if ai != nil {
+			pipeline = Pipeline{AI: ai, Categories: ai}
+		}
+		s := newTestService(&fakeExpenseStore{}, &fakeMaxAmounts{}).WithPipeline(pipeline)
+		expense := &models.Expense{UserID: 7, Amount: decimal.NewFromInt(12)}
+		return expense, s.DecideCategory(ctx, expense, in, testCategories, mode)
+	}
+
+	t.Run("a named category wins", func(t *testing.T) {
+		t.Parallel()
+		ai := &fakeAI{quota: 1}
+		expense, decision := decide(ai, ParsedInput{Description: "Lunch", CategoryName: "food", ChatCategoryID: 3}, AIApply)
+		require.Equal(t, CategorySourceExplicit, decision.Source)
+		require.Equal(t, 1, *expense.CategoryID)
+		require.Zero(t, ai.calls)
+	})
+
+	t.Run("then the chat default", func(t *testing.T) {
+		t.Parallel()
+		expense, decision := decide(&fakeAI{quota: 1}, ParsedInput{Description: "Lunch", ChatCategoryID: 3}, AIApply)
+		require.Equal(t, CategorySourceChat, decision.Source)
+		require.Equal(t, 3, *expense.CategoryID)
+	})
+
+	t.Run("then a confident suggestion", func(t *testing.T) {
+		t.Parallel()
+		ai := &fakeAI{quota: 1, suggestion: &gemini.CategorySuggestion{Category: "Food", Confidence: 0.9, Matched: true}}
+		expense, decision := decide(ai, ParsedInput{Description: "Lunch"}, AIApply)
+		require.Equal(t, CategorySourceAI, decision.Source)
+		require.Empty(t, decision.AIStatus)
+		require.Equal(t, 1, *expense.CategoryID)
+		require.True(t, expense.AICategorized)
+		require.InDelta(t, 0.9, *expense.AIConfidence, 0.001)
+	})
+
+	t.Run("unsure suggestions fall back to Others", func(t *testing.T) {
+		t.Parallel()
+		ai := &fakeAI{quota: 1, suggestion: &gemini.CategorySuggestion{Category: "Food", Confidence: 0.5, Matched: true}}
+		expense, decision := decide(ai, ParsedInput{Description: "Lunch"}, AIApply)
+		require.Equal(t, CategorySourceFallback, decision.Source)
+		require.Equal(t, AIStatusLowConfidence, decision.AIStatus)
+		require.Equal(t, 2, *expense.CategoryID)
+		require.False(t, expense.AICategorized)
+	})
+
+	t.Run("Gemini is not asked when unavailable, without a description or over the quota", func(t *testing.T) {
+		t.Parallel()
+		_, decision := decide(nil, ParsedInput{Description: "Lunch"}, AIApply)
+		require.Equal(t, AIStatusNotConfigured, decision.AIStatus)
+
+		ai := &fakeAI{quota: 1, unavailable: AIStatusTurnedOff}
+		_, decision = decide(ai, ParsedInput{Description: "Lunch"}, AIApply)
+		require.Equal(t, AIStatusTurnedOff, decision.AIStatus)
+
+		ai = &fakeAI{quota: 1}
+		_, decision = decide(ai, ParsedInput{}, AIApply)
+		require.Equal(t, AIStatusNoDescription, decision.AIStatus)
+		require.Equal(t, 1, ai.quota, "no quota is used without a description")
+
+		ai = &fakeAI{}
+		_, decision = decide(ai, ParsedInput{Description: "Lunch"}, AIApply)
+		require.Equal(t, AIStatusDailyCapReached, decision.AIStatus)
+		require.Zero(t, ai.calls)
+
+		_, decision = decide(&fakeAI{quota: 1}, ParsedInput{Description: "Lunch"}, AISkip)
+		require.Equal(t, AIStatusSkippedDryRun, decision.AIStatus)
+		require.Equal(t, CategorySourceFallback, decision.Source)
+	})
+
+	t.Run("new categories are only created outside previews", func(t *testing.T) {
+		t.Parallel()
+		suggestion := &gemini.CategorySuggestion{NewCategoryName: "Pets", Confidence: 0.85}
+
+		ai := &fakeAI{quota: 1, suggestion: suggestion}
+		expense, decision := decide(ai, ParsedInput{Description: "Dog food"}, AIPreview)
+		require.Equal(t, CategorySourceAINew, decision.Source)
+		require.Nil(t, expense.CategoryID)
+		require.Equal(t, "Pets", expense.Category.Name)
+		require.Empty(t, ai.created)
+
+		ai = &fakeAI{quota: 1, suggestion: suggestion}
+		expense, decision = decide(ai, ParsedInput{Description: "Dog food"}, AIApply)
+		require.Equal(t, CategorySourceAINew, decision.Source)
+		require.Equal(t, 99, *expense.CategoryID)
+		require.Equal(t, []string{"Pets"}, ai.created)
+	})
+}
+
+func TestApplyMatchedSuggestion(t *testing.T) {
+	t.Parallel()
+
+	expense := &models.Expense{}
+	require.True(t, applyMatchedSuggestion(expense, "lunch", &gemini.CategorySuggestion{
+		Category:   "food",
+		Confidence: 0.9,
+		Reasoning:  "restaurant expense",
+	}, testCategories))
+	require.Equal(t, 1, *expense.CategoryID)
+
+	expense = &models.Expense{}
+	require.False(t, applyMatchedSuggestion(expense, "lunch", &gemini.CategorySuggestion{
+		Category: "unknown",
+	}, testCategories))
+	require.Nil(t, expense.CategoryID)
+}
+
+func TestValidAutoCreatedCategoryName(t *testing.T) {
+	t.Parallel()
+
+	require.False(t, validAutoCreatedCategoryName(""))
+	require.False(t, validAutoCreatedCategoryName(" \t "))
+	require.False(t, validAutoCreatedCategoryName("bad\nname"))
+	require.True(t, validAutoCreatedCategoryName("Travel"))
+}
+
+func TestMergeTags(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []string{"work", "reimbursable"}, MergeTags([]string{"work"}, []string{"reimbursable", "work"}))
+	require.Equal(t, []string{"travel"}, MergeTags(nil, []string{"travel"}))
+	require.Equal(t, []string{"work"}, MergeTags([]string{"work"}, nil))
+}
+
+func TestExpenseService_CreateFromParsedPipeline(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	travel := 3
+
+	t.Run("adds default tags and marks reimbursable", func(t *testing.T) {
+		t.Parallel()
+		store := &fakeExpenseStore{}
+		tags := &fakeTags{defaults: map[int][]models.Tag{travel: {{Name: ReimbursableTag}}}}
+		s := newTestService(store, &fakeMaxAmounts{}).WithPipeline(Pipeline{Tags: tags})
+
+		expense := newExpense(40)
+		expense.CategoryID = &travel
+		result, err := s.CreateFromParsed(ctx, expense, CreateOptions{Tags: []string{"work"}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"work", ReimbursableTag}, result.Tags)
+		require.True(t, store.created[0].Reimbursable)
+		require.Len(t, tags.tagged[expense.ID], 2)
+	})
+
+	t.Run("refuses expenses over a hard budget", func(t *testing.T) {
+		t.Parallel()
+		store := &fakeExpenseStore{}
+		budgets := &fakeBudgets{budget: &HardBudget{
+			CategoryID: travel, CategoryName: "Travel", Currency: "SGD",
+			Limit: decimal.NewFromInt(100), Spent: decimal.NewFromInt(70),
+		}}
+		s := newTestService(store, &fakeMaxAmounts{}).WithPipeline(Pipeline{Budgets: budgets})
+
+		expense := newExpense(40)
+		expense.CategoryID = &travel
+		_, err := s.CreateFromParsed(ctx, expense, CreateOptions{EnforceBudget: true})
+		var overBudget *OverBudgetError
+		require.ErrorAs(t, err, &overBudget)
+		require.True(t, decimal.NewFromInt(30).Equal(overBudget.Budget.Remaining()))
+		require.Empty(t, store.created)
+
+		_, err = s.CreateFromParsed(ctx, newExpense(40), CreateOptions{EnforceBudget: true})
+		require.NoError(t, err, "uncategorized expenses are not blocked")
+
+		refund := newExpense(-40)
+		refund.CategoryID = &travel
+		_, err = s.CreateFromParsed(ctx, refund, CreateOptions{EnforceBudget: true})
+		require.NoError(t, err, "refunds are not blocked")
+
+		expense = newExpense(40)
+		expense.CategoryID = &travel
+		_, err = s.CreateFromParsed(ctx, expense, CreateOptions{})
+		require.NoError(t, err, "budgets only apply when enforced")
+	})
+}
+
+func TestAppendOriginalAmountDescription_MinorUnits(t *testing.T) {
+	t.Parallel()
+
+	got := appendOriginalAmountDescription(
+		"Ramen",
+		decimal.RequireFromString("1200"),
+		"JPY",
+		decimal.RequireFromString("10.8"),
+		"SGD",
+		decimal.RequireFromString("0.009"),
+		"2026-02-14",
+	)
+	require.Equal(t, "Ramen [orig: 1200 JPY -> 10.80 SGD @ 0.0090 (2026-02-14)]", got)
+}
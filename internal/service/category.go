@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"gitlab.com/yelinaung/expense-bot/internal/gemini"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// AIMode controls how Gemini takes part in picking a category.
+type AIMode int
+
+const (
+	// AIApply asks Gemini and may create a suggested new category.
+	AIApply AIMode = iota
+	// AIPreview asks Gemini but never creates categories.
+	AIPreview
+	// AISkip does not ask Gemini at all.
+	AISkip
+)
+
+// Mechanisms that can decide an expense's category.
+const (
+	CategorySourceExplicit = "named in the message"
+	CategorySourceChat     = "group chat default"
+	CategorySourceAI       = "AI suggestion"
+	CategorySourceAINew    = "AI suggested a new category"
+	CategorySourceFallback = "fallback to Others"
+	CategorySourceNone     = "none (Others category missing)"
+)
+
+// Outcomes of the Gemini category step.
+const (
+	AIStatusNotNeeded       = "not needed"
+	AIStatusSkippedDryRun   = "skipped in dry-run"
+	AIStatusNotConfigured   = "not configured"
+	AIStatusTurnedOff       = "turned off in /settings"
+	AIStatusBudgetExhausted = "monthly AI budget exhausted"
+	AIStatusBelowMinAmount  = "amount below the AI minimum"
+	AIStatusDailyCapReached = "daily AI suggestion cap reached"
+	AIStatusNoDescription   = "no description to classify"
+	AIStatusFailed          = "request failed"
+	AIStatusLowConfidence   = "not confident enough"
+	AIStatusUnknownCategory = "suggested an unusable category"
+)
+
+// CategoryDecision records which mechanism decided an expense's category.
+// Suggestion is set whenever Gemini answered; AIStatus explains why the
+// suggestion was not used, and is empty when it was.
+type CategoryDecision struct {
+	Source     string
+	AIStatus   string
+	Suggestion *gemini.CategorySuggestion
+}
+
+// CategoryAI asks Gemini for the category of a user's expense.
+type CategoryAI interface {
+	// Unavailable returns the AIStatus that keeps Gemini from being asked
+	// about expense, or "" when it may be asked.
+	Unavailable(ctx context.Context, expense *models.Expense) string
+	// TakeQuota uses one of the user's daily suggestions. It reports false
+	// once the daily cap is reached.
+	TakeQuota(ctx context.Context, userID int64) bool
+	SuggestCategory(
+		ctx context.Context,
+		userID int64,
+		description string,
+		categories []string,
+	) (*gemini.CategorySuggestion, error)
+}
+
+// CategoryCreator creates the categories Gemini suggests.
+type CategoryCreator interface {
+	// CreateCategory creates a category for the user, or returns the one
+	// that already has the name.
+	CreateCategory(ctx context.Context, userID int64, name string) (*models.Category, error)
+}
+
+// DecideCategory picks the category of a new expense: a category named in
+// the message, then the group chat's default category, then a Gemini
+// suggestion, then Others. It reports which mechanism decided it. mode
+// controls whether Gemini is asked and whether a suggested new category
+// may be created.
+func (s *ExpenseService) DecideCategory(
+	ctx context.Context,
+	expense *models.Expense,
+	in ParsedInput,
+	categories []models.Category,
+	mode AIMode,
+) CategoryDecision {
+	if in.CategoryName != "" && assignCategory(expense, categories, func(c *models.Category) bool {
+		return strings.EqualFold(c.Name, in.CategoryName)
+	}) {
+		return CategoryDecision{Source: CategorySourceExplicit, AIStatus: AIStatusNotNeeded}
+	}
+	if in.ChatCategoryID != 0 && assignCategory(expense, categories, func(c *models.Category) bool {
+		return c.ID == in.ChatCategoryID
+	}) {
+		return CategoryDecision{Source: CategorySourceChat, AIStatus: AIStatusNotNeeded}
+	}
+
+	var decision CategoryDecision
+	if mode == AISkip {
+		decision.AIStatus = AIStatusSkippedDryRun
+	} else if s.suggestCategory(ctx, expense, in.Description, categories, mode, &decision) {
+		confidence := decision.Suggestion.Confidence
+		expense.AICategorized = true
+		expense.AIConfidence = &confidence
+		return decision
+	}
+
+	decision.Source = CategorySourceNone
+	if assignCategory(expense, categories, func(c *models.Category) bool {
+		return strings.EqualFold(c.Name, gemini.CategoryOthers)
+	}) {
+		decision.Source = CategorySourceFallback
+	}
+	return decision
+}
+
+// assignCategory gives expense the first of categories that match accepts.
+func assignCategory(expense *models.Expense, categories []models.Category, match func(*models.Category) bool) bool {
+	for i := range categories {
+		if match(&categories[i]) {
+			expense.CategoryID = &categories[i].ID
+			expense.Category = &categories[i]
+			return true
+		}
+	}
+	return false
+}
+
+// suggestCategory asks Gemini for a category and applies it when confident
+// enough. Matched categories need more than 50% confidence and new ones
+// 80%. The outcome is recorded in decision.
+func (s *ExpenseService) suggestCategory(
+	ctx context.Context,
+	expense *models.Expense,
+	description string,
+	categories []models.Category,
+	mode AIMode,
+	decision *CategoryDecision,
+) bool {
+	ai := s.pipeline.AI
+	if ai == nil {
+		decision.AIStatus = AIStatusNotConfigured
+		return false
+	}
+	if status := ai.Unavailable(ctx, expense); status != "" {
+		decision.AIStatus = status
+		return false
+	}
+	if description == "" {
+		decision.AIStatus = AIStatusNoDescription
+		return false
+	}
+	if !ai.TakeQuota(ctx, expense.UserID) {
+		decision.AIStatus = AIStatusDailyCapReached
+		return false
+	}
+
+	categoryNames := make([]string, len(categories))
+	for i := range categories {
+		categoryNames[i] = categories[i].Name
+	}
+	suggestion, err := ai.SuggestCategory(ctx, expense.UserID, description, categoryNames)
+	if err != nil {
+		logger.Log.Debug().Err(err).
+			Str("description", logger.SanitizeDescription(description)).
+			Msg("Failed to get AI category suggestion")
+		decision.AIStatus = AIStatusFailed
+		return false
+	}
+	decision.Suggestion = suggestion
+	if suggestion == nil || suggestion.Confidence <= 0.5 {
+		decision.AIStatus = AIStatusLowConfidence
+		return false
+	}
+	if suggestion.Matched {
+		if !applyMatchedSuggestion(expense, description, suggestion, categories) {
+			decision.AIStatus = AIStatusUnknownCategory
+			return false
+		}
+		decision.Source = CategorySourceAI
+		return true
+	}
+	if suggestion.NewCategoryName != "" && suggestion.Confidence >= 0.8 {
+		if mode == AIPreview {
+			return previewNewCategorySuggestion(expense, suggestion, categories, decision)
+		}
+		if !s.ApplyNewCategory(ctx, expense, description, suggestion, categories) {
+			decision.AIStatus = AIStatusUnknownCategory
+			return false
+		}
+		decision.Source = CategorySourceAINew
+		return true
+	}
+	decision.AIStatus = AIStatusLowConfidence
+	return false
+}
+
+// previewNewCategorySuggestion applies a suggested new category without
+// creating it, for dry runs.
+func previewNewCategorySuggestion(
+	expense *models.Expense,
+	suggestion *gemini.CategorySuggestion,
+	categories []models.Category,
+	decision *CategoryDecision,
+) bool {
+	if !validAutoCreatedCategoryName(suggestion.NewCategoryName) {
+		decision.AIStatus = AIStatusUnknownCategory
+		return false
+	}
+	decision.Source = CategorySourceAINew
+	if !assignCategory(expense, categories, func(c *models.Category) bool {
+		return strings.EqualFold(c.Name, suggestion.NewCategoryName)
+	}) {
+		expense.Category = &models.Category{Name: suggestion.NewCategoryName}
+	}
+	return true
+}
+
+func applyMatchedSuggestion(
+	expense *models.Expense,
+	description string,
+	suggestion *gemini.CategorySuggestion,
+	categories []models.Category,
+) bool {
+	if !assignCategory(expense, categories, func(c *models.Category) bool {
+		return strings.EqualFold(c.Name, suggestion.Category)
+	}) {
+		return false
+	}
+	logger.Log.Info().
+		Str("description", logger.SanitizeDescription(description)).
+		Str("suggested_category", suggestion.Category).
+		Float64("confidence", suggestion.Confidence).
+		Str("reasoning", suggestion.Reasoning).
+		Msg("AI category suggestion applied")
+	return true
+}
+
+// ApplyNewCategory gives expense the new category Gemini suggested,
+// creating it unless the user already has one of that name. It reports
+// false when the name is unusable or the category could not be created.
+func (s *ExpenseService) ApplyNewCategory(
+	ctx context.Context,
+	expense *models.Expense,
+	description string,
+	suggestion *gemini.CategorySuggestion,
+	categories []models.Category,
+) bool {
+	newCategory := suggestion.NewCategoryName
+	if !validAutoCreatedCategoryName(newCategory) {
+		logger.Log.Warn().
+			Str("description", logger.SanitizeDescription(description)).
+			Str("new_category", newCategory).
+			Msg("AI suggested invalid new category name; skipping auto-create")
+		return false
+	}
+	if assignCategory(expense, categories, func(c *models.Category) bool {
+		return strings.EqualFold(c.Name, newCategory)
+	}) {
+		return true
+	}
+	if s.pipeline.Categories == nil {
+		return false
+	}
+
+	cat, err := s.pipeline.Categories.CreateCategory(ctx, expense.UserID, newCategory)
+	if err != nil {
+		logger.Log.Warn().Err(err).
+			Str("new_category", newCategory).
+			Msg("Failed to auto-create category from AI suggestion")
+		return false
+	}
+	expense.CategoryID = &cat.ID
+	expense.Category = cat
+	logger.Log.Info().
+		Str("description", logger.SanitizeDescription(description)).
+		Str("new_category", newCategory).
+		Float64("confidence", suggestion.Confidence).
+		Msg("Auto-created category from AI suggestion")
+	return true
+}
+
+// validAutoCreatedCategoryName reports whether Gemini's suggested name may
+// become a category: not blank, not too long and without control
+// characters.
+func validAutoCreatedCategoryName(name string) bool {
+	if strings.TrimSpace(name) == "" || len(name) > models.MaxCategoryNameLength {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/botfmt"
+	"gitlab.com/yelinaung/expense-bot/internal/exchange"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+)
+
+// CurrencySource knows each user's default currency and the exchange
+// rates amounts are converted at.
+type CurrencySource interface {
+	DefaultCurrency(ctx context.Context, userID int64) string
+	// Exchange returns the rate source, or nil when there is none.
+	Exchange() exchange.Converter
+}
+
+// Conversion is an entered amount as it is saved: in the user's default
+// currency when a rate was available, else in the currency it was entered
+// in. Rate is invalid when the amount was not converted.
+type Conversion struct {
+	Amount      decimal.Decimal
+	Currency    string
+	Description string
+	Rate        decimal.NullDecimal
+}
+
+// Record stores the rate of the conversion on expense, whose Amount and
+// Currency must already be the converted ones.
+func (c Conversion) Record(expense *models.Expense) {
+	if !c.Rate.Valid {
+		return
+	}
+	expense.RateToDefault = c.Rate
+	expense.ConvertedAmount = decimal.NewNullDecimal(expense.Amount)
+	expense.ConvertedCurrency = expense.Currency
+}
+
+// NormalizeCurrencyCode upper-cases and trims a currency code.
+func NormalizeCurrencyCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ResolveSourceCurrency returns the currency an amount was entered in:
+// the parsed code, or defaultCurrency when none was given. Unsupported
+// codes also fall back to defaultCurrency and report false.
+func ResolveSourceCurrency(sourceCurrency, defaultCurrency string) (string, bool) {
+	source := NormalizeCurrencyCode(sourceCurrency)
+	if source == "" {
+		return defaultCurrency, true
+	}
+	if _, ok := models.SupportedCurrencies[source]; !ok {
+		return defaultCurrency, false
+	}
+	return source, true
+}
+
+// DefaultCurrency returns the user's default currency.
+func (s *ExpenseService) DefaultCurrency(ctx context.Context, userID int64) string {
+	if s.pipeline.Currencies == nil {
+		return models.DefaultCurrency
+	}
+	return s.pipeline.Currencies.DefaultCurrency(ctx, userID)
+}
+
+// ConvertToDefault converts amount from sourceCurrency into the user's
+// default currency, noting the original amount and rate in the
+// description. When no rate is available the amount is kept in
+// sourceCurrency and the description says so.
+func (s *ExpenseService) ConvertToDefault(
+	ctx context.Context,
+	userID int64,
+	amount decimal.Decimal,
+	sourceCurrency string,
+	description string,
+) Conversion {
+	defaultCurrency := s.DefaultCurrency(ctx, userID)
+	source, supported := ResolveSourceCurrency(sourceCurrency, defaultCurrency)
+	if !supported {
+		logger.Log.Warn().
+			Str("source_currency", NormalizeCurrencyCode(sourceCurrency)).
+			Str("user_hash", logger.HashUserID(userID)).
+			Msg("Unsupported currency from input/LLM; using default currency")
+	}
+	if source == defaultCurrency {
+		return Conversion{Amount: amount, Currency: defaultCurrency, Description: description}
+	}
+	unconverted := Conversion{
+		Amount:      amount,
+		Currency:    source,
+		Description: appendConversionUnavailableDescription(description, source, defaultCurrency),
+	}
+
+	var converter exchange.Converter
+	if s.pipeline.Currencies != nil {
+		converter = s.pipeline.Currencies.Exchange()
+	}
+	if converter == nil {
+		logger.Log.Warn().
+			Str("source_currency", source).
+			Str("target_currency", defaultCurrency).
+			Str("user_hash", logger.HashUserID(userID)).
+			Msg("Exchange service unavailable; saving original currency")
+		return unconverted
+	}
+
+	result, err := converter.Convert(ctx, amount, source, defaultCurrency)
+	if err != nil {
+		logger.Log.Warn().
+			Err(err).
+			Str("source_currency", source).
+			Str("target_currency", defaultCurrency).
+			Str("user_hash", logger.HashUserID(userID)).
+			Msg("Exchange lookup failed; saving original currency")
+		return unconverted
+	}
+
+	return Conversion{
+		Amount:   result.Amount,
+		Currency: defaultCurrency,
+		Description: appendOriginalAmountDescription(
+			description,
+			amount,
+			source,
+			result.Amount,
+			defaultCurrency,
+			result.Rate,
+			result.RateDate.Format("2006-01-02"),
+		),
+		Rate: decimal.NewNullDecimal(result.Rate),
+	}
+}
+
+func appendOriginalAmountDescription(
+	description string,
+	originalAmount decimal.Decimal,
+	originalCurrency string,
+	convertedAmount decimal.Decimal,
+	convertedCurrency string,
+	rate decimal.Decimal,
+	rateDate string,
+) string {
+	metadata := fmt.Sprintf(
+		"[orig: %s %s -> %s %s @ %s (%s)]",
+		botfmt.FormatAmount(originalAmount, originalCurrency),
+		originalCurrency,
+		botfmt.FormatAmount(convertedAmount, convertedCurrency),
+		convertedCurrency,
+		rate.StringFixed(4),
+		rateDate,
+	)
+	if strings.TrimSpace(description) == "" {
+		return metadata
+	}
+	return description + " " + metadata
+}
+
+func appendConversionUnavailableDescription(
+	description, originalCurrency, targetCurrency string,
+) string {
+	metadata := fmt.Sprintf("[fx_unavailable: kept %s, target %s]", originalCurrency, targetCurrency)
+	if strings.TrimSpace(description) == "" {
+		return metadata
+	}
+	return description + " " + metadata
+}
@@ -0,0 +1,378 @@
+// Package service holds the expense business rules shared by the chat,
+// receipt, voice, bank and REST entry points, so handlers only deal with
+// Telegram I/O and rendering.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	"gitlab.com/yelinaung/expense-bot/internal/logger"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+// ErrAboveHardCap is returned when an expense exceeds the configured hard
+// cap and is rejected without saving.
+var ErrAboveHardCap = errors.New("amount is above the hard cap")
+
+// ExpenseStore is the part of the expense repository the service writes
+// through.
+type ExpenseStore interface {
+	Create(ctx context.Context, expense *models.Expense) error
+	CreateImported(ctx context.Context, expense *models.Expense) error
+	Update(ctx context.Context, expense *models.Expense) error
+	UpdateReflection(ctx context.Context, expenseID int, userID int64, worthIt *bool, driver string) error
+	Delete(ctx context.Context, id int) error
+	DeleteByUserAndIDs(ctx context.Context, userID int64, ids []int) (int64, error)
+	DeleteExpiredDrafts(ctx context.Context, olderThan time.Duration) (int, error)
+	ClearAICategorized(ctx context.Context, userID int64, expenseID int) (bool, error)
+	SetConversion(ctx context.Context, expenseID int, rate, converted decimal.Decimal, currency string) (bool, error)
+	SetLocation(ctx context.Context, expenseID int, loc models.ExpenseLocation) error
+	LinkSourceMessage(ctx context.Context, expenseID int, chatID int64, messageID int) error
+	NullifyCategoryOnExpenses(ctx context.Context, categoryID int) (int64, error)
+	NullifyCategoryForUser(ctx context.Context, userID int64, categoryID int) (int64, error)
+	SetReimbursable(ctx context.Context, userID int64, expenseID int, reimbursable bool) (bool, error)
+	MarkReimbursed(ctx context.Context, userID int64, expenseID int, at time.Time) (bool, error)
+	UpdateCategoryWhereDescriptionLike(
+		ctx context.Context,
+		userID int64,
+		substring string,
+		categoryID int,
+	) ([]repository.RecategorizedExpense, error)
+}
+
+// MaxAmountStore looks up a user's /settings maxamount override.
+type MaxAmountStore interface {
+	GetMaxAmount(ctx context.Context, userID int64) (decimal.NullDecimal, error)
+}
+
+// ExpenseService applies the expense rules: currency conversion, the
+// category decision, default tags, the hard cap and hard budgets, the
+// large-amount confirmation, and the draft and confirmed status of new
+// expenses.
+type ExpenseService struct {
+	expenses ExpenseStore
+	users    MaxAmountStore
+	cfg      *config.Config
+	pipeline Pipeline
+}
+
+// NewExpenseService creates a new ExpenseService. cfg may be nil, which
+// means no amount limits.
+func NewExpenseService(expenses ExpenseStore, users MaxAmountStore, cfg *config.Config) *ExpenseService {
+	return &ExpenseService{expenses: expenses, users: users, cfg: cfg}
+}
+
+// WithStore returns a copy of the service that writes through expenses,
+// e.g. a repository bound to a transaction.
+func (s *ExpenseService) WithStore(expenses ExpenseStore) *ExpenseService {
+	clone := *s
+	clone.expenses = expenses
+	return &clone
+}
+
+// CreateOptions control how CreateFromParsed saves an expense.
+type CreateOptions struct {
+	// Tags are the inline tags of the message. The default tags of the
+	// expense's category are added to them.
+	Tags []string
+	// ConfirmLarge saves an amount above the user's soft limit as a draft
+	// that must be confirmed.
+	ConfirmLarge bool
+	// EnforceBudget refuses an expense that would go over a hard category
+	// budget with an *OverBudgetError.
+	EnforceBudget bool
+}
+
+// CreateResult is the outcome of saving a new expense.
+type CreateResult struct {
+	Expense *models.Expense
+	// Tags are the tags the expense was saved with.
+	Tags []string
+	// NeedsConfirmation is set when the amount is above the user's soft
+	// limit. Chat expenses are then saved as a draft to confirm.
+	NeedsConfirmation bool
+}
+
+// ConfirmResult is the outcome of confirming a draft.
+type ConfirmResult struct {
+	Expense *models.Expense
+}
+
+// HardCap returns the configured hard cap, or zero when there is none.
+func (s *ExpenseService) HardCap() decimal.Decimal {
+	if s.cfg == nil {
+		return decimal.Zero
+	}
+	return s.cfg.AmountHardCap
+}
+
+// AboveHardCap reports whether amount exceeds the hard cap. Refunds are
+// held to the same cap by their size.
+func (s *ExpenseService) AboveHardCap(amount decimal.Decimal) bool {
+	hardCap := s.HardCap()
+	return !hardCap.IsZero() && amount.Abs().GreaterThan(hardCap)
+}
+
+// SoftLimit returns the user's /settings maxamount override, or the
+// configured soft limit. Zero means no confirmation is needed.
+func (s *ExpenseService) SoftLimit(ctx context.Context, userID int64) decimal.Decimal {
+	override, err := s.users.GetMaxAmount(ctx, userID)
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("user_hash", logger.HashUserID(userID)).Msg("Failed to load max amount override")
+	}
+	if err == nil && override.Valid {
+		return override.Decimal
+	}
+	if s.cfg == nil {
+		return decimal.Zero
+	}
+	return s.cfg.AmountSoftLimit
+}
+
+// NeedsConfirmation reports whether amount is above the user's soft limit.
+// Limits compare the amount as saved, after currency conversion.
+func (s *ExpenseService) NeedsConfirmation(ctx context.Context, userID int64, amount decimal.Decimal) bool {
+	limit := s.SoftLimit(ctx, userID)
+	return !limit.IsZero() && amount.GreaterThan(limit)
+}
+
+// CreateFromParsed saves a built chat or REST expense with its tags and
+// the default tags of its category; either including #reimbursable marks
+// it reimbursable. Amounts above the hard cap are rejected with
+// ErrAboveHardCap, and opts decide whether hard budgets and the soft limit
+// apply.
+func (s *ExpenseService) CreateFromParsed(
+	ctx context.Context,
+	expense *models.Expense,
+	opts CreateOptions,
+) (*CreateResult, error) {
+	if s.AboveHardCap(expense.Amount) {
+		return nil, ErrAboveHardCap
+	}
+	if opts.EnforceBudget {
+		if budget := s.BlockingBudget(ctx, expense); budget != nil {
+			return nil, &OverBudgetError{Budget: budget}
+		}
+	}
+
+	tags := MergeTags(opts.Tags, s.DefaultTags(ctx, expense.CategoryID))
+	expense.Reimbursable = expense.Reimbursable || slices.Contains(tags, ReimbursableTag)
+	result := &CreateResult{Expense: expense, Tags: tags}
+	if opts.ConfirmLarge && s.NeedsConfirmation(ctx, expense.UserID, expense.Amount) {
+		result.NeedsConfirmation = true
+		expense.Status = models.ExpenseStatusDraft
+	}
+	if err := s.expenses.Create(ctx, expense); err != nil {
+		return nil, fmt.Errorf("failed to save expense: %w", err)
+	}
+	if !expense.Replayed {
+		s.SaveTags(ctx, expense.ID, tags)
+	}
+	return result, nil
+}
+
+// Import saves a confirmed expense read from an /import file, keeping its
+// date. Amounts above the hard cap are rejected with ErrAboveHardCap; the
+// soft limit does not apply, since the expense was already spent.
+func (s *ExpenseService) Import(ctx context.Context, expense *models.Expense) error {
+	if s.AboveHardCap(expense.Amount) {
+		return ErrAboveHardCap
+	}
+	expense.Source = models.ExpenseSourceImport
+	if err := s.expenses.CreateImported(ctx, expense); err != nil {
+		return fmt.Errorf("failed to import expense: %w", err)
+	}
+	return nil
+}
+
+// CreateDraft saves an expense read from a voice note or a bank
+// notification as a draft the user confirms.
+func (s *ExpenseService) CreateDraft(ctx context.Context, expense *models.Expense) (*CreateResult, error) {
+	expense.Status = models.ExpenseStatusDraft
+	if err := s.expenses.Create(ctx, expense); err != nil {
+		return nil, fmt.Errorf("failed to save draft: %w", err)
+	}
+	return &CreateResult{Expense: expense}, nil
+}
+
+// CreateDraftFromReceipt saves a scanned receipt as a draft. Amounts above
+// the hard cap are rejected with ErrAboveHardCap; amounts above the soft
+// limit are saved but flagged, so the draft card can warn about them.
+func (s *ExpenseService) CreateDraftFromReceipt(ctx context.Context, expense *models.Expense) (*CreateResult, error) {
+	if s.AboveHardCap(expense.Amount) {
+		return nil, ErrAboveHardCap
+	}
+	expense.Source = models.ExpenseSourceReceipt
+	result, err := s.CreateDraft(ctx, expense)
+	if err != nil {
+		return nil, err
+	}
+	result.NeedsConfirmation = s.NeedsConfirmation(ctx, expense.UserID, expense.Amount)
+	return result, nil
+}
+
+// Confirm turns a draft into a confirmed expense.
+func (s *ExpenseService) Confirm(ctx context.Context, expense *models.Expense) (*ConfirmResult, error) {
+	expense.Status = models.ExpenseStatusConfirmed
+	if err := s.expenses.Update(ctx, expense); err != nil {
+		return nil, fmt.Errorf("failed to confirm expense: %w", err)
+	}
+	return &ConfirmResult{Expense: expense}, nil
+}
+
+// EditFields saves the edited amount, currency, description, merchant,
+// category or receipt of an expense. A new category clears the
+// AI-categorized flag, and a new amount or currency clears the stored
+// conversion.
+func (s *ExpenseService) EditFields(ctx context.Context, expense *models.Expense) error {
+	if err := s.expenses.Update(ctx, expense); err != nil {
+		return fmt.Errorf("failed to edit expense: %w", err)
+	}
+	return nil
+}
+
+// Delete removes an expense.
+func (s *ExpenseService) Delete(ctx context.Context, expenseID int) error {
+	if err := s.expenses.Delete(ctx, expenseID); err != nil {
+		return fmt.Errorf("failed to delete expense: %w", err)
+	}
+	return nil
+}
+
+// DeleteMany removes the listed expenses that belong to the user and
+// returns how many were removed.
+func (s *ExpenseService) DeleteMany(ctx context.Context, userID int64, expenseIDs []int) (int64, error) {
+	deleted, err := s.expenses.DeleteByUserAndIDs(ctx, userID, expenseIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expenses: %w", err)
+	}
+	return deleted, nil
+}
+
+// DeleteExpiredDrafts removes drafts older than olderThan and returns how
+// many were removed.
+func (s *ExpenseService) DeleteExpiredDrafts(ctx context.Context, olderThan time.Duration) (int, error) {
+	count, err := s.expenses.DeleteExpiredDrafts(ctx, olderThan)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired drafts: %w", err)
+	}
+	return count, nil
+}
+
+// Reflect stores whether a confirmed expense was worth it and what drove
+// it.
+func (s *ExpenseService) Reflect(ctx context.Context, expenseID int, userID int64, worthIt *bool, driver string) error {
+	if err := s.expenses.UpdateReflection(ctx, expenseID, userID, worthIt, driver); err != nil {
+		return fmt.Errorf("failed to save reflection: %w", err)
+	}
+	return nil
+}
+
+// MarkAIReviewed keeps the AI-picked category of a user's expense and takes
+// it off the review digest. It reports whether the expense was flagged.
+func (s *ExpenseService) MarkAIReviewed(ctx context.Context, userID int64, expenseID int) (bool, error) {
+	cleared, err := s.expenses.ClearAICategorized(ctx, userID, expenseID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark AI category reviewed: %w", err)
+	}
+	return cleared, nil
+}
+
+// SetConversion stores the default-currency amount of an expense that has
+// none yet. It reports whether the expense was updated.
+func (s *ExpenseService) SetConversion(
+	ctx context.Context,
+	expenseID int,
+	rate, converted decimal.Decimal,
+	currency string,
+) (bool, error) {
+	updated, err := s.expenses.SetConversion(ctx, expenseID, rate, converted, currency)
+	if err != nil {
+		return false, fmt.Errorf("failed to set conversion: %w", err)
+	}
+	return updated, nil
+}
+
+// SetLocation attaches a location to an expense.
+func (s *ExpenseService) SetLocation(ctx context.Context, expenseID int, loc models.ExpenseLocation) error {
+	if err := s.expenses.SetLocation(ctx, expenseID, loc); err != nil {
+		return fmt.Errorf("failed to set location: %w", err)
+	}
+	return nil
+}
+
+// LinkSourceMessage remembers that a chat message created an expense.
+func (s *ExpenseService) LinkSourceMessage(ctx context.Context, expenseID int, chatID int64, messageID int) error {
+	if err := s.expenses.LinkSourceMessage(ctx, expenseID, chatID, messageID); err != nil {
+		return fmt.Errorf("failed to link source message: %w", err)
+	}
+	return nil
+}
+
+// SetReimbursable marks a user's expense as a work expense to be paid back,
+// or not. It reports whether the expense was found.
+func (s *ExpenseService) SetReimbursable(
+	ctx context.Context,
+	userID int64,
+	expenseID int,
+	reimbursable bool,
+) (bool, error) {
+	found, err := s.expenses.SetReimbursable(ctx, userID, expenseID, reimbursable)
+	if err != nil {
+		return false, fmt.Errorf("failed to set reimbursable: %w", err)
+	}
+	return found, nil
+}
+
+// MarkReimbursed records that a user's reimbursable expense was paid back.
+// It reports whether an outstanding expense was updated.
+func (s *ExpenseService) MarkReimbursed(ctx context.Context, userID int64, expenseID int, at time.Time) (bool, error) {
+	updated, err := s.expenses.MarkReimbursed(ctx, userID, expenseID, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark reimbursed: %w", err)
+	}
+	return updated, nil
+}
+
+// ClearCategory leaves every expense of a category uncategorized, before
+// the category is deleted. It returns how many expenses changed.
+func (s *ExpenseService) ClearCategory(ctx context.Context, categoryID int) (int64, error) {
+	affected, err := s.expenses.NullifyCategoryOnExpenses(ctx, categoryID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear category: %w", err)
+	}
+	return affected, nil
+}
+
+// ClearCategoryForUser uncategorizes one user's expenses in a category,
+// leaving other users' expenses in it alone.
+func (s *ExpenseService) ClearCategoryForUser(ctx context.Context, userID int64, categoryID int) (int64, error) {
+	affected, err := s.expenses.NullifyCategoryForUser(ctx, userID, categoryID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear category: %w", err)
+	}
+	return affected, nil
+}
+
+// Recategorize moves the user's expenses whose description contains
+// substring to a category. It returns the moved expenses with their old
+// category names.
+func (s *ExpenseService) Recategorize(
+	ctx context.Context,
+	userID int64,
+	substring string,
+	categoryID int,
+) ([]repository.RecategorizedExpense, error) {
+	changed, err := s.expenses.UpdateCategoryWhereDescriptionLike(ctx, userID, substring, categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recategorize expenses: %w", err)
+	}
+	return changed, nil
+}
@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/config"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
+	"gitlab.com/yelinaung/expense-bot/internal/repository"
+)
+
+// fakeExpenseStore keeps expenses in memory and records the writes.
+type fakeExpenseStore struct {
+	created  []models.Expense
+	updated  []models.Expense
+	deleted  []int
+	nextID   int
+	cleared  map[int]bool
+	failNext error
+}
+
+func (f *fakeExpenseStore) fail() error {
+	err := f.failNext
+	f.failNext = nil
+	return err
+}
+
+func (f *fakeExpenseStore) Create(_ context.Context, expense *models.Expense) error {
+	if err := f.fail(); err != nil {
+		return err
+	}
+	f.nextID++
+	expense.ID = f.nextID
+	f.created = append(f.created, *expense)
+	return nil
+}
+
+func (f *fakeExpenseStore) CreateImported(ctx context.Context, expense *models.Expense) error {
+	expense.Status = models.ExpenseStatusConfirmed
+	return f.Create(ctx, expense)
+}
+
+func (f *fakeExpenseStore) Update(_ context.Context, expense *models.Expense) error {
+	if err := f.fail(); err != nil {
+		return err
+	}
+	f.updated = append(f.updated, *expense)
+	return nil
+}
+
+func (f *fakeExpenseStore) UpdateReflection(context.Context, int, int64, *bool, string) error {
+	return f.fail()
+}
+
+func (f *fakeExpenseStore) Delete(_ context.Context, id int) error {
+	if err := f.fail(); err != nil {
+		return err
+	}
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+func (f *fakeExpenseStore) DeleteByUserAndIDs(_ context.Context, _ int64, ids []int) (int64, error) {
+	if err := f.fail(); err != nil {
+		return 0, err
+	}
+	f.deleted = append(f.deleted, ids...)
+	return int64(len(ids)), nil
+}
+
+func (f *fakeExpenseStore) DeleteExpiredDrafts(context.Context, time.Duration) (int, error) {
+	return 0, f.fail()
+}
+
+func (f *fakeExpenseStore) ClearAICategorized(_ context.Context, _ int64, expenseID int) (bool, error) {
+	if err := f.fail(); err != nil {
+		return false, err
+	}
+	if f.cleared == nil {
+		f.cleared = make(map[int]bool)
+	}
+	first := !f.cleared[expenseID]
+	f.cleared[expenseID] = true
+	return first, nil
+}
+
+func (f *fakeExpenseStore) SetConversion(context.Context, int, decimal.Decimal, decimal.Decimal, string) (bool, error) {
+	return true, f.fail()
+}
+
+func (f *fakeExpenseStore) SetLocation(context.Context, int, models.ExpenseLocation) error {
+	return f.fail()
+}
+
+func (f *fakeExpenseStore) LinkSourceMessage(context.Context, int, int64, int) error {
+	return f.fail()
+}
+
+func (f *fakeExpenseStore) NullifyCategoryOnExpenses(context.Context, int) (int64, error) {
+	return 0, f.fail()
+}
+
+func (f *fakeExpenseStore) NullifyCategoryForUser(context.Context, int64, int) (int64, error) {
+	return 0, f.fail()
+}
+
+func (f *fakeExpenseStore) SetReimbursable(_ context.Context, _ int64, expenseID int, reimbursable bool) (bool, error) {
+	if err := f.fail(); err != nil {
+		return false, err
+	}
+	for i := range f.created {
+		if expense := &f.created[i]; expense.ID == expenseID {
+			expense.Reimbursable = reimbursable
+			if !reimbursable {
+				expense.ReimbursedAt = nil
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeExpenseStore) MarkReimbursed(_ context.Context, _ int64, expenseID int, at time.Time) (bool, error) {
+	if err := f.fail(); err != nil {
+		return false, err
+	}
+	for i := range f.created {
+		if expense := &f.created[i]; expense.ID == expenseID && expense.AwaitingReimbursement() {
+			expense.ReimbursedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeExpenseStore) UpdateCategoryWhereDescriptionLike(
+	context.Context,
+	int64,
+	string,
+	int,
+) ([]repository.RecategorizedExpense, error) {
+	return nil, f.fail()
+}
+
+// fakeMaxAmounts returns per-user /settings maxamount overrides.
+type fakeMaxAmounts struct {
+	overrides map[int64]decimal.Decimal
+	err       error
+}
+
+func (f *fakeMaxAmounts) GetMaxAmount(_ context.Context, userID int64) (decimal.NullDecimal, error) {
+	if f.err != nil {
+		return decimal.NullDecimal{}, f.err
+	}
+	amount, ok := f.overrides[userID]
+	return decimal.NullDecimal{Decimal: amount, Valid: ok}, nil
+}
+
+func newTestService(store *fakeExpenseStore, users *fakeMaxAmounts) *ExpenseService {
+	return NewExpenseService(store, users, &config.Config{
+		AmountSoftLimit: decimal.NewFromInt(1000),
+		AmountHardCap:   decimal.NewFromInt(1_000_000),
+	})
+}
+
+func newExpense(amount int64) *models.Expense {
+	return &models.Expense{UserID: 7, Amount: decimal.NewFromInt(amount), Currency: "SGD", Description: "Coffee"}
+}
+
+func TestExpenseService_Limits(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	users := &fakeMaxAmounts{overrides: map[int64]decimal.Decimal{8: decimal.NewFromInt(6000), 9: decimal.Zero}}
+	s := newTestService(&fakeExpenseStore{}, users)
+
+	require.False(t, s.AboveHardCap(decimal.NewFromInt(1_000_000)))
+	require.True(t, s.AboveHardCap(decimal.RequireFromString("1000000.01")))
+	require.True(t, s.AboveHardCap(decimal.RequireFromString("-1000000.01")), "refunds are capped by size")
+
+	require.False(t, s.NeedsConfirmation(ctx, 7, decimal.NewFromInt(1000)))
+	require.True(t, s.NeedsConfirmation(ctx, 7, decimal.RequireFromString("1000.01")))
+	require.False(t, s.NeedsConfirmation(ctx, 8, decimal.NewFromInt(5500)), "override raises the limit")
+	require.False(t, s.NeedsConfirmation(ctx, 9, decimal.NewFromInt(900_000)), "zero override never asks")
+
+	users.err = errors.New("db down")
+	require.True(t, s.NeedsConfirmation(ctx, 8, decimal.NewFromInt(5500)), "lookup failure falls back to config")
+
+	unlimited := NewExpenseService(&fakeExpenseStore{}, &fakeMaxAmounts{}, nil)
+	require.False(t, unlimited.AboveHardCap(decimal.NewFromInt(5_000_000)), "no config means no cap")
+	require.False(t, unlimited.NeedsConfirmation(ctx, 7, decimal.NewFromInt(5_000_000)))
+}
+
+func TestExpenseService_CreateFromParsed(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("below the soft limit is confirmed", func(t *testing.T) {
+		t.Parallel()
+		store := &fakeExpenseStore{}
+		result, err := newTestService(store, &fakeMaxAmounts{}).CreateFromParsed(ctx, newExpense(999), CreateOptions{ConfirmLarge: true})
+		require.NoError(t, err)
+		require.False(t, result.NeedsConfirmation)
+		require.Equal(t, 1, result.Expense.ID)
+		require.Len(t, store.created, 1)
+		require.Equal(t, models.ExpenseStatusUnset, store.created[0].Status, "the repository defaults to confirmed")
+	})
+
+	t.Run("above the soft limit is saved as a draft", func(t *testing.T) {
+		t.Parallel()
+		store := &fakeExpenseStore{}
+		result, err := newTestService(store, &fakeMaxAmounts{}).CreateFromParsed(ctx, newExpense(5500), CreateOptions{ConfirmLarge: true})
+		require.NoError(t, err)
+		require.True(t, result.NeedsConfirmation)
+		require.Equal(t, models.ExpenseStatusDraft, store.created[0].Status)
+	})
+
+	t.Run("without confirmLarge the soft limit is ignored", func(t *testing.T) {
+		t.Parallel()
+		store := &fakeExpenseStore{}
+		result, err := newTestService(store, &fakeMaxAmounts{}).CreateFromParsed(ctx, newExpense(5500), CreateOptions{})
+		require.NoError(t, err)
+		require.False(t, result.NeedsConfirmation)
+		require.Equal(t, models.ExpenseStatusUnset, store.created[0].Status)
+	})
+
+	t.Run("above the hard cap is rejected", func(t *testing.T) {
+		t.Parallel()
+		store := &fakeExpenseStore{}
+		_, err := newTestService(store, &fakeMaxAmounts{}).CreateFromParsed(ctx, newExpense(2_000_000), CreateOptions{ConfirmLarge: true})
+		require.ErrorIs(t, err, ErrAboveHardCap)
+		require.Empty(t, store.created)
+	})
+
+	t.Run("store errors are wrapped", func(t *testing.T) {
+		t.Parallel()
+		cause := errors.New("insert failed")
+		store := &fakeExpenseStore{failNext: cause}
+		_, err := newTestService(store, &fakeMaxAmounts{}).CreateFromParsed(ctx, newExpense(10), CreateOptions{ConfirmLarge: true})
+		require.ErrorIs(t, err, cause)
+	})
+}
+
+func TestExpenseService_Import(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := &fakeExpenseStore{}
+	s := newTestService(store, &fakeMaxAmounts{})
+
+	require.NoError(t, s.Import(ctx, newExpense(5500)), "the soft limit does not apply")
+	require.Equal(t, models.ExpenseStatusConfirmed, store.created[0].Status)
+	require.Equal(t, models.ExpenseSourceImport, store.created[0].Source)
+
+	require.ErrorIs(t, s.Import(ctx, newExpense(2_000_000)), ErrAboveHardCap)
+	require.Len(t, store.created, 1)
+
+	cause := errors.New("insert failed")
+	store.failNext = cause
+	require.ErrorIs(t, s.Import(ctx, newExpense(10)), cause)
+}
+
+func TestExpenseService_Drafts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := &fakeExpenseStore{}
+	s := newTestService(store, &fakeMaxAmounts{})
+
+	result, err := s.CreateDraft(ctx, newExpense(2_000_000))
+	require.NoError(t, err, "voice and bank drafts are not capped")
+	require.Equal(t, models.ExpenseStatusDraft, result.Expense.Status)
+
+	_, err = s.CreateDraftFromReceipt(ctx, newExpense(2_000_000))
+	require.ErrorIs(t, err, ErrAboveHardCap)
+	require.Len(t, store.created, 1)
+
+	receipt, err := s.CreateDraftFromReceipt(ctx, newExpense(5500))
+	require.NoError(t, err)
+	require.True(t, receipt.NeedsConfirmation, "large receipts are flagged but still saved")
+	require.Equal(t, models.ExpenseStatusDraft, store.created[1].Status)
+	require.Equal(t, models.ExpenseSourceReceipt, store.created[1].Source)
+
+	confirmed, err := s.Confirm(ctx, receipt.Expense)
+	require.NoError(t, err)
+	require.Equal(t, models.ExpenseStatusConfirmed, confirmed.Expense.Status)
+	require.Len(t, store.updated, 1)
+	require.Equal(t, models.ExpenseStatusConfirmed, store.updated[0].Status)
+	require.Equal(t, models.ExpenseSourceReceipt, store.updated[0].Source, "confirming keeps the source")
+
+	store.failNext = errors.New("update failed")
+	_, err = s.Confirm(ctx, receipt.Expense)
+	require.ErrorContains(t, err, "failed to confirm expense")
+}
+
+func TestExpenseService_Writes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := &fakeExpenseStore{}
+	s := newTestService(store, &fakeMaxAmounts{})
+
+	require.NoError(t, s.Delete(ctx, 3))
+	deleted, err := s.DeleteMany(ctx, 7, []int{4, 5})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), deleted)
+	require.Equal(t, []int{3, 4, 5}, store.deleted)
+
+	cleared, err := s.MarkAIReviewed(ctx, 7, 4)
+	require.NoError(t, err)
+	require.True(t, cleared)
+	cleared, err = s.MarkAIReviewed(ctx, 7, 4)
+	require.NoError(t, err)
+	require.False(t, cleared)
+
+	tx := &fakeExpenseStore{}
+	require.NoError(t, s.WithStore(tx).EditFields(ctx, newExpense(12)))
+	require.Len(t, tx.updated, 1)
+	require.Empty(t, store.updated, "WithStore leaves the original store alone")
+
+	store.failNext = errors.New("delete failed")
+	require.ErrorContains(t, s.Delete(ctx, 6), "failed to delete expense")
+}
+
+func TestExpenseService_Reimbursements(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := &fakeExpenseStore{}
+	s := newTestService(store, &fakeMaxAmounts{})
+	_, err := s.CreateFromParsed(ctx, newExpense(20), CreateOptions{})
+	require.NoError(t, err)
+	id := store.created[0].ID
+	paidAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	updated, err := s.MarkReimbursed(ctx, 7, id, paidAt)
+	require.NoError(t, err)
+	require.False(t, updated, "only reimbursable expenses can be paid back")
+
+	found, err := s.SetReimbursable(ctx, 7, id, true)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.True(t, store.created[0].AwaitingReimbursement())
+
+	updated, err = s.MarkReimbursed(ctx, 7, id, paidAt)
+	require.NoError(t, err)
+	require.True(t, updated)
+	require.Equal(t, paidAt, *store.created[0].ReimbursedAt)
+	updated, err = s.MarkReimbursed(ctx, 7, id, paidAt)
+	require.NoError(t, err)
+	require.False(t, updated, "already reimbursed")
+
+	_, err = s.SetReimbursable(ctx, 7, id, false)
+	require.NoError(t, err)
+	require.False(t, store.created[0].Reimbursable)
+	require.Nil(t, store.created[0].ReimbursedAt)
+
+	found, err = s.SetReimbursable(ctx, 7, 999, true)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	store.failNext = errors.New("db down")
+	_, err = s.MarkReimbursed(ctx, 7, id, paidAt)
+	require.ErrorContains(t, err, "failed to mark reimbursed")
+}
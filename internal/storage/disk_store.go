@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+)
+
+const (
+	diskDirPerm  = 0o750
+	diskFilePerm = 0o640
+)
+
+// DiskStore keeps receipt images as files below a root directory. Access
+// goes through os.Root, so keys cannot reach outside it.
+type DiskStore struct {
+	root *os.Root
+}
+
+// NewDiskStore opens a disk store rooted at dir, creating the directory
+// when it does not exist.
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, diskDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create receipt storage directory: %w", err)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open receipt storage directory: %w", err)
+	}
+	return &DiskStore{root: root}, nil
+}
+
+// Put writes data to a temporary file next to key and renames it into
+// place, so readers never see a partly written image.
+func (s *DiskStore) Put(_ context.Context, key string, data []byte) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	if dir := path.Dir(key); dir != "." {
+		if err := s.root.MkdirAll(dir, diskDirPerm); err != nil {
+			return fmt.Errorf("failed to create receipt directory: %w", err)
+		}
+	}
+
+	tmp := key + ".tmp"
+	if err := s.root.WriteFile(tmp, data, diskFilePerm); err != nil {
+		return fmt.Errorf("failed to write receipt: %w", err)
+	}
+	if err := s.root.Rename(tmp, key); err != nil {
+		_ = s.root.Remove(tmp)
+		return fmt.Errorf("failed to store receipt: %w", err)
+	}
+	return nil
+}
+
+// Get reads the image stored under key.
+func (s *DiskStore) Get(_ context.Context, key string) ([]byte, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	data, err := s.root.ReadFile(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read receipt: %w", err)
+	}
+	return data, nil
+}
+
+// Delete removes the image stored under key.
+func (s *DiskStore) Delete(_ context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	if err := s.root.Remove(key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete receipt: %w", err)
+	}
+	return nil
+}
+
+// Backend returns "disk".
+func (s *DiskStore) Backend() string {
+	return "disk"
+}
+
+// Close releases the root directory.
+func (s *DiskStore) Close() error {
+	if err := s.root.Close(); err != nil {
+		return fmt.Errorf("failed to close receipt storage: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiptKey(t *testing.T) {
+	t.Parallel()
+
+	key := ReceiptKey(42, 1337)
+	require.Equal(t, "42/1337.jpg", key)
+	require.NoError(t, validateKey(key))
+}
+
+func TestValidateKey(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "..", "a/../../b", "a//b", `a\b`, "./a"} {
+		require.ErrorIs(t, validateKey(key), errInvalidKey, key)
+	}
+	require.NoError(t, validateKey("a/b.jpg"))
+}
+
+func TestDiskStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "receipts")
+	store, err := NewDiskStore(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	require.Equal(t, "disk", store.Backend())
+
+	t.Run("round trips an image", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, "7/1.jpg", []byte("first")))
+		require.NoError(t, store.Put(ctx, "7/1.jpg", []byte("second")))
+
+		data, err := store.Get(ctx, "7/1.jpg")
+		require.NoError(t, err)
+		require.Equal(t, []byte("second"), data)
+
+		entries, err := os.ReadDir(filepath.Join(dir, "7"))
+		require.NoError(t, err)
+		require.Len(t, entries, 1, "no temporary files are left behind")
+	})
+
+	t.Run("missing objects", func(t *testing.T) {
+		_, err := store.Get(ctx, "7/404.jpg")
+		require.ErrorIs(t, err, ErrNotFound)
+		require.NoError(t, store.Delete(ctx, "7/404.jpg"))
+	})
+
+	t.Run("deletes", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, "8/2.jpg", []byte("gone")))
+		require.NoError(t, store.Delete(ctx, "8/2.jpg"))
+		_, err := store.Get(ctx, "8/2.jpg")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("rejects keys outside the root", func(t *testing.T) {
+		require.ErrorIs(t, store.Put(ctx, "../escape.jpg", []byte("x")), errInvalidKey)
+		_, err := store.Get(ctx, "/etc/passwd")
+		require.ErrorIs(t, err, errInvalidKey)
+		require.ErrorIs(t, store.Delete(ctx, "../escape.jpg"), errInvalidKey)
+		require.NoFileExists(t, filepath.Join(filepath.Dir(dir), "escape.jpg"))
+	})
+}
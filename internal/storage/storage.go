@@ -0,0 +1,50 @@
+// Package storage keeps receipt images outside Telegram, so they can be
+// served after Telegram stops handing out the original files.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned by Get when no object is stored under the key.
+	ErrNotFound = errors.New("stored object not found")
+
+	errInvalidKey = errors.New("invalid storage key")
+)
+
+// ReceiptStore stores receipt images under keys. Keys are slash-separated
+// relative paths such as "42/1337.jpg", which map onto both files and
+// object storage keys. Implementations must be safe for concurrent use.
+type ReceiptStore interface {
+	// Put stores data under key, replacing any previous object.
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the object stored under key, or an error wrapping
+	// ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the object stored under key. Deleting a missing
+	// object is not an error.
+	Delete(ctx context.Context, key string) error
+	// Backend names the implementation for status output, e.g. "disk".
+	Backend() string
+	// Close releases the store. It must not be used afterwards.
+	Close() error
+}
+
+// ReceiptKey returns the key of an expense's receipt image.
+func ReceiptKey(userID int64, expenseID int) string {
+	return fmt.Sprintf("%d/%d.jpg", userID, expenseID)
+}
+
+// validateKey rejects keys that are empty, absolute or leave the store.
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) || path.Clean(key) != key ||
+		key == ".." || strings.HasPrefix(key, "../") {
+		return fmt.Errorf("%w: %q", errInvalidKey, key)
+	}
+	return nil
+}
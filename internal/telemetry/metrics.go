@@ -24,6 +24,12 @@ type BotMetrics struct {
 	// Cache metrics
 	CacheHits   otelmetric.Int64Counter
 	CacheMisses otelmetric.Int64Counter
+
+	// Users found to have blocked the bot
+	BotBlocked otelmetric.Int64Counter
+
+	// Reports rejected because the user already had one running
+	ReportsRejected otelmetric.Int64Counter
 }
 
 // NewBotMetrics creates and registers all metric instruments.
@@ -92,6 +98,18 @@ func NewBotMetrics() (*BotMetrics, error) {
 		return nil, err
 	}
 
+	botBlocked, err := meter.Int64Counter("telegram.bot_blocked",
+		otelmetric.WithDescription("Number of users found to have blocked the bot"))
+	if err != nil {
+		return nil, err
+	}
+
+	reportsRejected, err := meter.Int64Counter("report.rejected_in_flight",
+		otelmetric.WithDescription("Number of reports rejected while the user's previous one was running"))
+	if err != nil {
+		return nil, err
+	}
+
 	return &BotMetrics{
 		HandlerCount:          handlerCount,
 		HandlerDuration:       handlerDuration,
@@ -103,5 +121,7 @@ func NewBotMetrics() (*BotMetrics, error) {
 		DraftsCleaned:         draftsCleaned,
 		CacheHits:             cacheHits,
 		CacheMisses:           cacheMisses,
+		BotBlocked:            botBlocked,
+		ReportsRejected:       reportsRejected,
 	}, nil
 }
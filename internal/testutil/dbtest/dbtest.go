@@ -11,6 +11,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"gitlab.com/yelinaung/expense-bot/internal/database"
+	"gitlab.com/yelinaung/expense-bot/internal/models"
 )
 
 var (
@@ -42,14 +43,15 @@ func TestDB(t *testing.T) *pgxpool.Pool {
 	return pool
 }
 
-// CleanupTables truncates all tables for a clean test state.
+// CleanupTables truncates all tables for a clean test state. The record of
+// applied migrations is kept, as the schema itself stays.
 func CleanupTables(ctx context.Context, t *testing.T, pool *pgxpool.Pool) {
 	t.Helper()
 
 	rows, err := pool.Query(context.WithoutCancel(ctx), `
 		SELECT tablename
 		FROM pg_tables
-		WHERE schemaname = 'public'
+		WHERE schemaname = 'public' AND tablename <> 'schema_migrations'
 		ORDER BY tablename
 	`)
 	if err != nil {
@@ -123,7 +125,11 @@ func TestPool(t *testing.T) *pgxpool.Pool {
 		if testPoolErr != nil {
 			return
 		}
-		testPoolErr = database.SeedCategories(ctx, testPool)
+		testPoolErr = database.SeedCategories(ctx, testPool, models.DefaultCategories)
+		if testPoolErr != nil {
+			return
+		}
+		testPoolErr = database.SeedBankTemplates(ctx, testPool)
 	})
 
 	if testPoolErr != nil {
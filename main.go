@@ -8,8 +8,13 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
+	// Embed the timezone database, so /settimezone and its suggestions work
+	// on hosts without tzdata installed.
+	_ "time/tzdata"
 
 	"gitlab.com/yelinaung/expense-bot/internal/bot"
 	"gitlab.com/yelinaung/expense-bot/internal/config"
@@ -73,6 +78,7 @@ func run(ctx context.Context, args []string, stdout io.Writer) error {
 	if err != nil {
 		return wrapRunError("Failed to load config", err)
 	}
+	cfg.Build = config.BuildInfo{Version: version, Commit: commit, Date: date}
 
 	logLevel, err := logger.ParseLevel(cfg.LogLevel)
 	if err != nil {
@@ -81,6 +87,20 @@ func run(ctx context.Context, args []string, stdout io.Writer) error {
 	logger.SetLevel(logLevel)
 	logger.InitHashSalt()
 
+	if len(args) > 1 && args[1] == "--check" {
+		// Only the API preflights run, so deploys can gate on the exit code
+		// without a database.
+		if err := bot.Check(runCtx, cfg); err != nil {
+			return wrapRunError("Preflight check failed", err)
+		}
+		_, _ = fmt.Fprintln(stdout, "preflight checks passed")
+		return nil
+	}
+
+	if len(args) > 1 && args[1] == "migrate" {
+		return runMigrate(runCtx, cfg, args[2:], stdout)
+	}
+
 	otelProviders, err := telemetry.Init(runCtx, &telemetry.Config{
 		Enabled:         cfg.OTelEnabled,
 		ServiceName:     cfg.OTelServiceName,
@@ -102,20 +122,28 @@ func run(ctx context.Context, args []string, stdout io.Writer) error {
 		}
 	}()
 
-	pool, err := database.Connect(runCtx, cfg.DatabaseURL, cfg.OTelEnabled)
+	pool, err := database.ConnectWithRetry(runCtx, cfg.DatabaseURL, cfg.OTelEnabled, database.RetryPolicy{
+		Attempts: cfg.DBConnectAttempts,
+		MaxWait:  cfg.DBConnectMaxWait,
+	})
 	if err != nil {
 		return wrapRunError("Failed to connect to database", err)
 	}
 	defer pool.Close()
 
-	if err := database.RunMigrations(runCtx, pool); err != nil {
+	migrateOpts := database.MigrateOptions{AllowChecksumChange: cfg.MigrationsAllowChecksumChange}
+	if err := database.Migrate(runCtx, pool, migrateOpts); err != nil {
 		return wrapRunError("Failed to run migrations", err)
 	}
 
-	if err := database.SeedCategories(runCtx, pool); err != nil {
+	if err := database.SeedCategories(runCtx, pool, cfg.SeedCategories); err != nil {
 		return wrapRunError("Failed to seed categories", err)
 	}
 
+	if err := database.SeedBankTemplates(runCtx, pool); err != nil {
+		return wrapRunError("Failed to seed bank templates", err)
+	}
+
 	logger.Log.Info().Msg("Database initialized successfully")
 
 	telegramBot, err := bot.New(runCtx, cfg, pool)
@@ -134,3 +162,92 @@ func run(ctx context.Context, args []string, stdout io.Writer) error {
 	telegramBot.Start(runCtx)
 	return nil
 }
+
+// runMigrate runs "expense-bot migrate status|up" for operating the schema
+// by hand: status lists every migration and whether it was applied, up
+// applies the pending ones and prints the counts.
+func runMigrate(ctx context.Context, cfg *config.Config, args []string, stdout io.Writer) error {
+	if len(args) != 1 || (args[0] != "status" && args[0] != "up") {
+		return wrapRunError("Invalid migrate command", errors.New("usage: expense-bot migrate status|up"))
+	}
+
+	pool, err := database.ConnectWithRetry(ctx, cfg.DatabaseURL, false, database.RetryPolicy{
+		Attempts: cfg.DBConnectAttempts,
+		MaxWait:  cfg.DBConnectMaxWait,
+	})
+	if err != nil {
+		return wrapRunError("Failed to connect to database", err)
+	}
+	defer pool.Close()
+
+	if args[0] == "up" {
+		opts := database.MigrateOptions{AllowChecksumChange: cfg.MigrationsAllowChecksumChange}
+		if err := database.Migrate(ctx, pool, opts); err != nil {
+			return wrapRunError("Failed to run migrations", err)
+		}
+	}
+
+	states, err := database.MigrationStatus(ctx, pool)
+	if err != nil {
+		return wrapRunError("Failed to read migration status", err)
+	}
+	if args[0] == "status" {
+		writeMigrationTable(stdout, states)
+	}
+	_, _ = fmt.Fprintln(stdout, migrationSummary(states))
+	return nil
+}
+
+// migrationState names the state of a migration in the status table.
+func migrationState(state *database.MigrationState) string {
+	switch {
+	case state.Unknown:
+		return "unknown"
+	case state.Changed:
+		return "changed"
+	case state.Applied:
+		return "applied"
+	default:
+		return "pending"
+	}
+}
+
+// writeMigrationTable prints one row per migration.
+func writeMigrationTable(w io.Writer, states []database.MigrationState) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "VERSION\tSTATE\tAPPLIED\tDURATION\tNAME")
+	for i := range states {
+		applied, duration := "-", "-"
+		if states[i].Applied {
+			applied = states[i].AppliedAt.UTC().Format(time.DateTime)
+			duration = states[i].Duration.String()
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n",
+			states[i].Version, migrationState(&states[i]), applied, duration, states[i].Name)
+	}
+	_ = tw.Flush()
+}
+
+// migrationSummary counts migrations by state, e.g. "143 migrations: 142
+// applied, 1 pending".
+func migrationSummary(states []database.MigrationState) string {
+	counts := make(map[string]int)
+	known := 0
+	for i := range states {
+		counts[migrationState(&states[i])]++
+		if !states[i].Unknown {
+			known++
+		}
+	}
+	parts := []string{
+		fmt.Sprintf("%d applied", counts["applied"]+counts["changed"]),
+		fmt.Sprintf("%d pending", counts["pending"]),
+	}
+	if counts["changed"] > 0 {
+		parts = append(parts, fmt.Sprintf("%d changed since applied", counts["changed"]))
+	}
+	if counts["unknown"] > 0 {
+		parts = append(parts, fmt.Sprintf("%d unknown to this build", counts["unknown"]))
+	}
+	return fmt.Sprintf("%d migrations: %s", known, strings.Join(parts, ", "))
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"gitlab.com/yelinaung/expense-bot/internal/database"
 )
 
 const testMainAppName = "expense-bot"
@@ -78,3 +81,77 @@ func TestMainExitsWhenDatabaseConnectFails(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "Failed to connect to database")
 }
+
+func TestMainCheckFailsWithRejectedToken(t *testing.T) {
+	for _, kv := range []string{
+		"TELEGRAM_BOT_TOKEN=test-token",
+		"DATABASE_URL=postgres://invalid-connection-string",
+		"WHITELISTED_USER_IDS=1",
+		"LOG_HASH_SALT=test-salt-for-main-tests-1234567890",
+		"OTEL_ENABLED=false",
+	} {
+		key, value, _ := strings.Cut(kv, "=")
+		t.Setenv(key, value)
+	}
+
+	err := run(context.Background(), []string{testMainAppName, "--check"}, io.Discard)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Preflight check failed")
+	require.Contains(t, err.Error(), "TELEGRAM_BOT_TOKEN")
+}
+
+func TestMainMigrateRejectsUnknownCommand(t *testing.T) {
+	for _, kv := range []string{
+		"TELEGRAM_BOT_TOKEN=test-token",
+		"DATABASE_URL=postgres://invalid-connection-string",
+		"WHITELISTED_USER_IDS=1",
+		"LOG_HASH_SALT=test-salt-for-main-tests-1234567890",
+		"OTEL_ENABLED=false",
+		"DB_CONNECT_ATTEMPTS=1",
+	} {
+		key, value, _ := strings.Cut(kv, "=")
+		t.Setenv(key, value)
+	}
+
+	err := run(context.Background(), []string{testMainAppName, "migrate", "down"}, io.Discard)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "usage: expense-bot migrate status|up")
+
+	err = run(context.Background(), []string{testMainAppName, "migrate", "status"}, io.Discard)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Failed to connect to database")
+}
+
+func TestMigrationStatusOutput(t *testing.T) {
+	t.Parallel()
+
+	appliedAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	states := []database.MigrationState{
+		{
+			Migration: database.Migration{Version: 1, Name: "CREATE TABLE users ("},
+			Applied:   true, AppliedAt: appliedAt, Duration: 12 * time.Millisecond,
+		},
+		{
+			Migration: database.Migration{Version: 2, Name: "ALTER TABLE users ADD COLUMN x INT"},
+			Applied:   true, AppliedAt: appliedAt, Changed: true,
+		},
+		{Migration: database.Migration{Version: 3, Name: "CREATE INDEX idx_x ON users(x)"}},
+		{Migration: database.Migration{Version: 9, Name: "DROP TABLE old"}, Applied: true, Unknown: true},
+	}
+
+	require.Equal(t, "3 migrations: 2 applied, 1 pending, 1 changed since applied, 1 unknown to this build",
+		migrationSummary(states))
+
+	var out bytes.Buffer
+	writeMigrationTable(&out, states)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 5)
+	require.Equal(t, []string{"VERSION", "STATE", "APPLIED", "DURATION", "NAME"}, strings.Fields(lines[0]))
+	require.Equal(t, []string{"1", "applied", "2026-03-01", "09:30:00", "12ms", "CREATE", "TABLE", "users", "("},
+		strings.Fields(lines[1]))
+	require.Contains(t, lines[2], "changed")
+	require.Equal(t, []string{"3", "pending", "-", "-"}, strings.Fields(lines[3])[:4])
+	require.Contains(t, lines[4], "unknown")
+
+	require.Equal(t, "0 migrations: 0 applied, 0 pending", migrationSummary(nil))
+}